@@ -122,6 +122,9 @@ std::vector<Callback> dispatchers = {
     unpackSyscall<::gvisor::syscall::InotifyRmWatch>,
     unpackSyscall<::gvisor::syscall::SocketPair>,
     unpackSyscall<::gvisor::syscall::Write>,
+    unpack<::gvisor::sentry::CapabilityDeniedInfo>,
+    unpackSyscall<::gvisor::syscall::Mount>,
+    unpackSyscall<::gvisor::syscall::Umount>,
 };
 
 void unpack(absl::string_view buf) {
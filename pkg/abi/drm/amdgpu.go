@@ -0,0 +1,104 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drm
+
+// Driver-specific ioctl numbers, from include/uapi/drm/amdgpu_drm.h.
+const (
+	DRM_AMDGPU_GEM_CREATE      = 0x00
+	DRM_AMDGPU_GEM_MMAP        = 0x01
+	DRM_AMDGPU_CTX             = 0x02
+	DRM_AMDGPU_BO_LIST         = 0x03
+	DRM_AMDGPU_CS              = 0x04
+	DRM_AMDGPU_INFO            = 0x05
+	DRM_AMDGPU_GEM_METADATA    = 0x06
+	DRM_AMDGPU_GEM_WAIT_IDLE   = 0x07
+	DRM_AMDGPU_GEM_VA          = 0x08
+	DRM_AMDGPU_WAIT_CS         = 0x09
+	DRM_AMDGPU_GEM_OP          = 0x10
+	DRM_AMDGPU_WAIT_FENCES     = 0x12
+	DRM_AMDGPU_VM              = 0x13
+	DRM_AMDGPU_FENCE_TO_HANDLE = 0x14
+)
+
+// Sizes of amdgpu ioctl parameter structs.
+const (
+	SizeofAMDGPUGemCreate     = 32
+	SizeofAMDGPUGemMmap       = 8
+	SizeofAMDGPUCtx           = 16
+	SizeofAMDGPUBoList        = 24
+	SizeofAMDGPUCS            = 24
+	SizeofAMDGPUInfo          = 32
+	SizeofAMDGPUGemMetadata   = 288
+	SizeofAMDGPUGemWaitIdle   = 16
+	SizeofAMDGPUGemVA         = 40
+	SizeofAMDGPUWaitCS        = 32
+	SizeofAMDGPUGemOp         = 16
+	SizeofAMDGPUWaitFences    = 24
+	SizeofAMDGPUVM            = 8
+	SizeofAMDGPUFenceToHandle = 32
+
+	// SizeofAMDGPUCSChunk is the size of struct drm_amdgpu_cs_chunk.
+	SizeofAMDGPUCSChunk = 16
+	// SizeofAMDGPUFence is the size of struct drm_amdgpu_fence.
+	SizeofAMDGPUFence = 24
+	// SizeofAMDGPUGemCreateIn is the size of struct drm_amdgpu_gem_create_in,
+	// which is returned by AMDGPU_GEM_OP_GET_GEM_CREATE_INFO.
+	SizeofAMDGPUGemCreateIn = 32
+)
+
+// amdgpu ioctls.
+var (
+	DRM_IOCTL_AMDGPU_GEM_CREATE      = DriverIOWR(DRM_AMDGPU_GEM_CREATE, SizeofAMDGPUGemCreate)
+	DRM_IOCTL_AMDGPU_GEM_MMAP        = DriverIOWR(DRM_AMDGPU_GEM_MMAP, SizeofAMDGPUGemMmap)
+	DRM_IOCTL_AMDGPU_CTX             = DriverIOWR(DRM_AMDGPU_CTX, SizeofAMDGPUCtx)
+	DRM_IOCTL_AMDGPU_BO_LIST         = DriverIOWR(DRM_AMDGPU_BO_LIST, SizeofAMDGPUBoList)
+	DRM_IOCTL_AMDGPU_CS              = DriverIOWR(DRM_AMDGPU_CS, SizeofAMDGPUCS)
+	DRM_IOCTL_AMDGPU_INFO            = DriverIOW(DRM_AMDGPU_INFO, SizeofAMDGPUInfo)
+	DRM_IOCTL_AMDGPU_GEM_METADATA    = DriverIOWR(DRM_AMDGPU_GEM_METADATA, SizeofAMDGPUGemMetadata)
+	DRM_IOCTL_AMDGPU_GEM_WAIT_IDLE   = DriverIOWR(DRM_AMDGPU_GEM_WAIT_IDLE, SizeofAMDGPUGemWaitIdle)
+	DRM_IOCTL_AMDGPU_GEM_VA          = DriverIOW(DRM_AMDGPU_GEM_VA, SizeofAMDGPUGemVA)
+	DRM_IOCTL_AMDGPU_WAIT_CS         = DriverIOWR(DRM_AMDGPU_WAIT_CS, SizeofAMDGPUWaitCS)
+	DRM_IOCTL_AMDGPU_GEM_OP          = DriverIOWR(DRM_AMDGPU_GEM_OP, SizeofAMDGPUGemOp)
+	DRM_IOCTL_AMDGPU_WAIT_FENCES     = DriverIOWR(DRM_AMDGPU_WAIT_FENCES, SizeofAMDGPUWaitFences)
+	DRM_IOCTL_AMDGPU_VM              = DriverIOWR(DRM_AMDGPU_VM, SizeofAMDGPUVM)
+	DRM_IOCTL_AMDGPU_FENCE_TO_HANDLE = DriverIOWR(DRM_AMDGPU_FENCE_TO_HANDLE, SizeofAMDGPUFenceToHandle)
+)
+
+// Operations for DRM_IOCTL_AMDGPU_GEM_OP.
+const (
+	AMDGPU_GEM_OP_GET_GEM_CREATE_INFO = 0
+	AMDGPU_GEM_OP_SET_PLACEMENT       = 1
+)
+
+// Chunk IDs for DRM_IOCTL_AMDGPU_CS.
+const (
+	AMDGPU_CHUNK_ID_IB                      = 0x01
+	AMDGPU_CHUNK_ID_FENCE                   = 0x02
+	AMDGPU_CHUNK_ID_DEPENDENCIES            = 0x03
+	AMDGPU_CHUNK_ID_SYNCOBJ_IN              = 0x04
+	AMDGPU_CHUNK_ID_SYNCOBJ_OUT             = 0x05
+	AMDGPU_CHUNK_ID_BO_HANDLES              = 0x06
+	AMDGPU_CHUNK_ID_SCHEDULED_DEPENDENCIES  = 0x07
+	AMDGPU_CHUNK_ID_SYNCOBJ_TIMELINE_WAIT   = 0x08
+	AMDGPU_CHUNK_ID_SYNCOBJ_TIMELINE_SIGNAL = 0x09
+	AMDGPU_CHUNK_ID_CP_GFX_SHADOW           = 0x0a
+)
+
+// Values of drm_amdgpu_fence_to_handle.in.what.
+const (
+	AMDGPU_FENCE_TO_HANDLE_GET_SYNCOBJ      = 0
+	AMDGPU_FENCE_TO_HANDLE_GET_SYNCOBJ_FD   = 1
+	AMDGPU_FENCE_TO_HANDLE_GET_SYNC_FILE_FD = 2
+)
@@ -0,0 +1,122 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drm describes the userspace interface for Direct Rendering Manager
+// (DRM) devices, as defined by Linux's include/uapi/drm/drm.h and the
+// driver-specific headers in the same directory.
+package drm
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+)
+
+// DRM_MAJOR is the device major number of DRM devices.
+const DRM_MAJOR = 226
+
+// DRM_MINOR_RENDER_BASE is the minor number of the first DRM render node.
+const DRM_MINOR_RENDER_BASE = 128
+
+// DRM_IOCTL_BASE is the ioctl type of DRM ioctls.
+const DRM_IOCTL_BASE = 'd'
+
+// DRM_COMMAND_BASE is the first ioctl number used by driver-specific ioctls.
+const DRM_COMMAND_BASE = 0x40
+
+// Flags for DRM_IOCTL_PRIME_HANDLE_TO_FD.
+const (
+	DRM_CLOEXEC = linux.O_CLOEXEC
+	DRM_RDWR    = linux.O_RDWR
+)
+
+// Flags for DRM_IOCTL_SYNCOBJ_HANDLE_TO_FD and DRM_IOCTL_SYNCOBJ_FD_TO_HANDLE.
+const (
+	DRM_SYNCOBJ_HANDLE_TO_FD_FLAGS_EXPORT_SYNC_FILE = 1 << 0
+	DRM_SYNCOBJ_FD_TO_HANDLE_FLAGS_IMPORT_SYNC_FILE = 1 << 0
+)
+
+// Sizes of core DRM ioctl parameter structs.
+const (
+	SizeofDRMVersion        = 64
+	SizeofDRMUnique         = 16
+	SizeofDRMGemClose       = 8
+	SizeofDRMGetCap         = 16
+	SizeofDRMSetClientCap   = 16
+	SizeofDRMPrimeHandle    = 12
+	SizeofDRMSyncobjCreate  = 8
+	SizeofDRMSyncobjDestroy = 8
+	SizeofDRMSyncobjHandle  = 16
+	SizeofDRMSyncobjWait    = 32
+	// SizeofDRMSyncobjWaitV2 is the size of struct drm_syncobj_wait after
+	// the addition of deadline_nsec.
+	SizeofDRMSyncobjWaitV2       = 40
+	SizeofDRMSyncobjTimelineWait = 40
+	// SizeofDRMSyncobjTimelineWaitV2 is the size of struct
+	// drm_syncobj_timeline_wait after the addition of deadline_nsec.
+	SizeofDRMSyncobjTimelineWaitV2 = 48
+	SizeofDRMSyncobjArray          = 16
+	SizeofDRMSyncobjTimelineArray  = 24
+	SizeofDRMSyncobjTransfer       = 32
+)
+
+// Core DRM ioctls, from include/uapi/drm/drm.h.
+var (
+	DRM_IOCTL_VERSION        = linux.IOWR(DRM_IOCTL_BASE, 0x00, SizeofDRMVersion)
+	DRM_IOCTL_GET_UNIQUE     = linux.IOWR(DRM_IOCTL_BASE, 0x01, SizeofDRMUnique)
+	DRM_IOCTL_GEM_CLOSE      = linux.IOW(DRM_IOCTL_BASE, 0x09, SizeofDRMGemClose)
+	DRM_IOCTL_GET_CAP        = linux.IOWR(DRM_IOCTL_BASE, 0x0c, SizeofDRMGetCap)
+	DRM_IOCTL_SET_CLIENT_CAP = linux.IOW(DRM_IOCTL_BASE, 0x0d, SizeofDRMSetClientCap)
+
+	DRM_IOCTL_PRIME_HANDLE_TO_FD = linux.IOWR(DRM_IOCTL_BASE, 0x2d, SizeofDRMPrimeHandle)
+	DRM_IOCTL_PRIME_FD_TO_HANDLE = linux.IOWR(DRM_IOCTL_BASE, 0x2e, SizeofDRMPrimeHandle)
+
+	DRM_IOCTL_SYNCOBJ_CREATE       = linux.IOWR(DRM_IOCTL_BASE, 0xBF, SizeofDRMSyncobjCreate)
+	DRM_IOCTL_SYNCOBJ_DESTROY      = linux.IOWR(DRM_IOCTL_BASE, 0xC0, SizeofDRMSyncobjDestroy)
+	DRM_IOCTL_SYNCOBJ_HANDLE_TO_FD = linux.IOWR(DRM_IOCTL_BASE, 0xC1, SizeofDRMSyncobjHandle)
+	DRM_IOCTL_SYNCOBJ_FD_TO_HANDLE = linux.IOWR(DRM_IOCTL_BASE, 0xC2, SizeofDRMSyncobjHandle)
+	DRM_IOCTL_SYNCOBJ_WAIT         = linux.IOWR(DRM_IOCTL_BASE, 0xC3, SizeofDRMSyncobjWait)
+	DRM_IOCTL_SYNCOBJ_WAIT_V2      = linux.IOWR(DRM_IOCTL_BASE, 0xC3, SizeofDRMSyncobjWaitV2)
+	DRM_IOCTL_SYNCOBJ_RESET        = linux.IOWR(DRM_IOCTL_BASE, 0xC4, SizeofDRMSyncobjArray)
+	DRM_IOCTL_SYNCOBJ_SIGNAL       = linux.IOWR(DRM_IOCTL_BASE, 0xC5, SizeofDRMSyncobjArray)
+
+	DRM_IOCTL_SYNCOBJ_TIMELINE_WAIT    = linux.IOWR(DRM_IOCTL_BASE, 0xCA, SizeofDRMSyncobjTimelineWait)
+	DRM_IOCTL_SYNCOBJ_TIMELINE_WAIT_V2 = linux.IOWR(DRM_IOCTL_BASE, 0xCA, SizeofDRMSyncobjTimelineWaitV2)
+	DRM_IOCTL_SYNCOBJ_QUERY            = linux.IOWR(DRM_IOCTL_BASE, 0xCB, SizeofDRMSyncobjTimelineArray)
+	DRM_IOCTL_SYNCOBJ_TRANSFER         = linux.IOWR(DRM_IOCTL_BASE, 0xCC, SizeofDRMSyncobjTransfer)
+	DRM_IOCTL_SYNCOBJ_TIMELINE_SIGNAL  = linux.IOWR(DRM_IOCTL_BASE, 0xCD, SizeofDRMSyncobjTimelineArray)
+)
+
+// DriverIOWR returns the number of a driver-specific DRM ioctl that reads and
+// writes its argument.
+func DriverIOWR(nr, size uint32) uint32 {
+	return linux.IOWR(DRM_IOCTL_BASE, DRM_COMMAND_BASE+nr, size)
+}
+
+// DriverIOW returns the number of a driver-specific DRM ioctl that reads its
+// argument.
+func DriverIOW(nr, size uint32) uint32 {
+	return linux.IOW(DRM_IOCTL_BASE, DRM_COMMAND_BASE+nr, size)
+}
+
+// DriverIOR returns the number of a driver-specific DRM ioctl that writes its
+// argument.
+func DriverIOR(nr, size uint32) uint32 {
+	return linux.IOR(DRM_IOCTL_BASE, DRM_COMMAND_BASE+nr, size)
+}
+
+// SizeofDMABufSync is the size of struct dma_buf_sync.
+const SizeofDMABufSync = 8
+
+// DMA_BUF_IOCTL_SYNC brackets CPU access to a mapped dma-buf. From
+// include/uapi/linux/dma-buf.h.
+var DMA_BUF_IOCTL_SYNC = linux.IOW('b', 0, SizeofDMABufSync)
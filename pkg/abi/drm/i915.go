@@ -0,0 +1,155 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drm
+
+// Driver-specific ioctl numbers, from include/uapi/drm/i915_drm.h.
+const (
+	DRM_I915_GETPARAM             = 0x06
+	DRM_I915_GEM_BUSY             = 0x17
+	DRM_I915_GEM_CREATE           = 0x1b
+	DRM_I915_GEM_PREAD            = 0x1c
+	DRM_I915_GEM_PWRITE           = 0x1d
+	DRM_I915_GEM_SET_DOMAIN       = 0x1f
+	DRM_I915_GEM_SW_FINISH        = 0x20
+	DRM_I915_GEM_SET_TILING       = 0x21
+	DRM_I915_GEM_GET_TILING       = 0x22
+	DRM_I915_GEM_GET_APERTURE     = 0x23
+	DRM_I915_GEM_MMAP_GTT         = 0x24
+	DRM_I915_GEM_MADVISE          = 0x26
+	DRM_I915_GEM_EXECBUFFER2      = 0x29
+	DRM_I915_GEM_WAIT             = 0x2c
+	DRM_I915_GEM_CONTEXT_CREATE   = 0x2d
+	DRM_I915_GEM_CONTEXT_DESTROY  = 0x2e
+	DRM_I915_GEM_SET_CACHING      = 0x2f
+	DRM_I915_GEM_GET_CACHING      = 0x30
+	DRM_I915_REG_READ             = 0x31
+	DRM_I915_GET_RESET_STATS      = 0x32
+	DRM_I915_GEM_CONTEXT_GETPARAM = 0x34
+	DRM_I915_GEM_CONTEXT_SETPARAM = 0x35
+	DRM_I915_QUERY                = 0x39
+	DRM_I915_GEM_VM_CREATE        = 0x3a
+	DRM_I915_GEM_VM_DESTROY       = 0x3b
+	DRM_I915_GEM_CREATE_EXT       = 0x3c
+)
+
+// Sizes of i915 ioctl parameter structs.
+const (
+	SizeofI915GetParam            = 16
+	SizeofI915GemExecbuffer2      = 64
+	SizeofI915GemBusy             = 8
+	SizeofI915GemCreate           = 16
+	SizeofI915GemPread            = 32
+	SizeofI915GemPwrite           = 32
+	SizeofI915GemSetDomain        = 12
+	SizeofI915GemSWFinish         = 4
+	SizeofI915GemSetTiling        = 16
+	SizeofI915GemGetTiling        = 16
+	SizeofI915GemGetAperture      = 16
+	SizeofI915GemMmapGTT          = 16
+	SizeofI915GemMmapOffset       = 32
+	SizeofI915GemMadvise          = 12
+	SizeofI915GemWait             = 16
+	SizeofI915GemContextCreate    = 8
+	SizeofI915GemContextCreateExt = 16
+	SizeofI915GemContextDestroy   = 8
+	SizeofI915GemCaching          = 8
+	SizeofI915RegRead             = 16
+	SizeofI915ResetStats          = 24
+	SizeofI915GemContextParam     = 24
+	SizeofI915Query               = 16
+	SizeofI915GemVMControl        = 16
+	SizeofI915GemCreateExt        = 24
+
+	// SizeofI915GemExecObject2 is the size of struct
+	// drm_i915_gem_exec_object2.
+	SizeofI915GemExecObject2 = 56
+	// SizeofI915GemRelocationEntry is the size of struct
+	// drm_i915_gem_relocation_entry.
+	SizeofI915GemRelocationEntry = 32
+	// SizeofI915GemExecFence is the size of struct drm_i915_gem_exec_fence.
+	SizeofI915GemExecFence = 8
+	// SizeofI915QueryItem is the size of struct drm_i915_query_item.
+	SizeofI915QueryItem = 24
+	// SizeofI915UserExtension is the size of struct i915_user_extension.
+	SizeofI915UserExtension = 32
+)
+
+// i915 ioctls.
+var (
+	DRM_IOCTL_I915_GETPARAM               = DriverIOWR(DRM_I915_GETPARAM, SizeofI915GetParam)
+	DRM_IOCTL_I915_GEM_EXECBUFFER2        = DriverIOW(DRM_I915_GEM_EXECBUFFER2, SizeofI915GemExecbuffer2)
+	DRM_IOCTL_I915_GEM_EXECBUFFER2_WR     = DriverIOWR(DRM_I915_GEM_EXECBUFFER2, SizeofI915GemExecbuffer2)
+	DRM_IOCTL_I915_GEM_BUSY               = DriverIOWR(DRM_I915_GEM_BUSY, SizeofI915GemBusy)
+	DRM_IOCTL_I915_GEM_CREATE             = DriverIOWR(DRM_I915_GEM_CREATE, SizeofI915GemCreate)
+	DRM_IOCTL_I915_GEM_PREAD              = DriverIOW(DRM_I915_GEM_PREAD, SizeofI915GemPread)
+	DRM_IOCTL_I915_GEM_PWRITE             = DriverIOW(DRM_I915_GEM_PWRITE, SizeofI915GemPwrite)
+	DRM_IOCTL_I915_GEM_SET_DOMAIN         = DriverIOW(DRM_I915_GEM_SET_DOMAIN, SizeofI915GemSetDomain)
+	DRM_IOCTL_I915_GEM_SW_FINISH          = DriverIOW(DRM_I915_GEM_SW_FINISH, SizeofI915GemSWFinish)
+	DRM_IOCTL_I915_GEM_SET_TILING         = DriverIOWR(DRM_I915_GEM_SET_TILING, SizeofI915GemSetTiling)
+	DRM_IOCTL_I915_GEM_GET_TILING         = DriverIOWR(DRM_I915_GEM_GET_TILING, SizeofI915GemGetTiling)
+	DRM_IOCTL_I915_GEM_GET_APERTURE       = DriverIOR(DRM_I915_GEM_GET_APERTURE, SizeofI915GemGetAperture)
+	DRM_IOCTL_I915_GEM_MMAP_GTT           = DriverIOWR(DRM_I915_GEM_MMAP_GTT, SizeofI915GemMmapGTT)
+	DRM_IOCTL_I915_GEM_MMAP_OFFSET        = DriverIOWR(DRM_I915_GEM_MMAP_GTT, SizeofI915GemMmapOffset)
+	DRM_IOCTL_I915_GEM_MADVISE            = DriverIOWR(DRM_I915_GEM_MADVISE, SizeofI915GemMadvise)
+	DRM_IOCTL_I915_GEM_WAIT               = DriverIOWR(DRM_I915_GEM_WAIT, SizeofI915GemWait)
+	DRM_IOCTL_I915_GEM_CONTEXT_CREATE     = DriverIOWR(DRM_I915_GEM_CONTEXT_CREATE, SizeofI915GemContextCreate)
+	DRM_IOCTL_I915_GEM_CONTEXT_CREATE_EXT = DriverIOWR(DRM_I915_GEM_CONTEXT_CREATE, SizeofI915GemContextCreateExt)
+	DRM_IOCTL_I915_GEM_CONTEXT_DESTROY    = DriverIOW(DRM_I915_GEM_CONTEXT_DESTROY, SizeofI915GemContextDestroy)
+	DRM_IOCTL_I915_GEM_SET_CACHING        = DriverIOW(DRM_I915_GEM_SET_CACHING, SizeofI915GemCaching)
+	DRM_IOCTL_I915_GEM_GET_CACHING        = DriverIOWR(DRM_I915_GEM_GET_CACHING, SizeofI915GemCaching)
+	DRM_IOCTL_I915_REG_READ               = DriverIOWR(DRM_I915_REG_READ, SizeofI915RegRead)
+	DRM_IOCTL_I915_GET_RESET_STATS        = DriverIOWR(DRM_I915_GET_RESET_STATS, SizeofI915ResetStats)
+	DRM_IOCTL_I915_GEM_CONTEXT_GETPARAM   = DriverIOWR(DRM_I915_GEM_CONTEXT_GETPARAM, SizeofI915GemContextParam)
+	DRM_IOCTL_I915_GEM_CONTEXT_SETPARAM   = DriverIOWR(DRM_I915_GEM_CONTEXT_SETPARAM, SizeofI915GemContextParam)
+	DRM_IOCTL_I915_QUERY                  = DriverIOWR(DRM_I915_QUERY, SizeofI915Query)
+	DRM_IOCTL_I915_GEM_VM_CREATE          = DriverIOWR(DRM_I915_GEM_VM_CREATE, SizeofI915GemVMControl)
+	DRM_IOCTL_I915_GEM_VM_DESTROY         = DriverIOW(DRM_I915_GEM_VM_DESTROY, SizeofI915GemVMControl)
+	DRM_IOCTL_I915_GEM_CREATE_EXT         = DriverIOWR(DRM_I915_GEM_CREATE_EXT, SizeofI915GemCreateExt)
+)
+
+// Flags for DRM_IOCTL_I915_GEM_EXECBUFFER2.
+const (
+	I915_EXEC_FENCE_IN       = 1 << 16
+	I915_EXEC_FENCE_OUT      = 1 << 17
+	I915_EXEC_FENCE_ARRAY    = 1 << 19
+	I915_EXEC_FENCE_SUBMIT   = 1 << 20
+	I915_EXEC_USE_EXTENSIONS = 1 << 21
+)
+
+// Extension names.
+const (
+	// DRM_I915_GEM_EXECBUFFER_EXT_TIMELINE_FENCES is the name of the
+	// execbuffer extension struct
+	// drm_i915_gem_execbuffer_ext_timeline_fences.
+	DRM_I915_GEM_EXECBUFFER_EXT_TIMELINE_FENCES = 0
+
+	// I915_CONTEXT_CREATE_EXT_SETPARAM is the name of the context creation
+	// extension struct drm_i915_gem_context_create_ext_setparam.
+	I915_CONTEXT_CREATE_EXT_SETPARAM = 0
+
+	// I915_GEM_CREATE_EXT_MEMORY_REGIONS is the name of the GEM creation
+	// extension struct drm_i915_gem_create_ext_memory_regions.
+	I915_GEM_CREATE_EXT_MEMORY_REGIONS = 0
+	// I915_GEM_CREATE_EXT_PROTECTED_CONTENT is the name of the GEM creation
+	// extension struct drm_i915_gem_create_ext_protected_content.
+	I915_GEM_CREATE_EXT_PROTECTED_CONTENT = 1
+	// I915_GEM_CREATE_EXT_SET_PAT is the name of the GEM creation extension
+	// struct drm_i915_gem_create_ext_set_pat.
+	I915_GEM_CREATE_EXT_SET_PAT = 2
+)
+
+// I915_CONTEXT_PARAM_ENGINES is the context parameter whose value is a struct
+// i915_context_param_engines.
+const I915_CONTEXT_PARAM_ENGINES = 0xa
@@ -45,9 +45,9 @@ const (
 //
 // +marshal
 type IOCallback struct {
-	Data uint64
-	Key  uint32
-	_    uint32
+	Data    uint64
+	Key     uint32
+	RWFlags uint32
 
 	OpCode  uint16
 	ReqPrio int16
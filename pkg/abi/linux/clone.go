@@ -49,10 +49,16 @@ const (
 
 	// Sizeof first published struct.
 	CLONE_ARGS_SIZE_VER0 = 64
-	// Sizeof third published struct.
+	// Sizeof second published struct, which added set_tid and set_tid_size.
+	CLONE_ARGS_SIZE_VER1 = 80
+	// Sizeof third published struct, which added cgroup.
 	CLONE_ARGS_SIZE_VER2 = 88
 )
 
+// MAX_PID_NS_LEVEL is the maximum nesting depth of PID namespaces, from
+// include/linux/pid_namespace.h. It bounds clone_args.set_tid_size.
+const MAX_PID_NS_LEVEL = 32
+
 // CloneArgs is struct clone_args, from include/uapi/linux/sched.h.
 //
 // +marshal
@@ -88,6 +88,9 @@ const (
 
 	// AT_SYSINFO_EHDR is the address of the VDSO.
 	AT_SYSINFO_EHDR = 33
+
+	// AT_MINSIGSTKSZ is the minimal stack size for signal delivery.
+	AT_MINSIGSTKSZ = 51
 )
 
 // ELF ET_CORE and ptrace GETREGSET/SETREGSET register set types.
@@ -100,6 +103,15 @@ const (
 	// NT_PRFPREG is for float point register.
 	NT_PRFPREG = 0x2
 
+	// NT_PRPSINFO is for process information.
+	NT_PRPSINFO = 0x3
+
+	// NT_AUXV is for the auxiliary vector.
+	NT_AUXV = 0x6
+
+	// NT_SIGINFO is for the siginfo of the signal that caused the core dump.
+	NT_SIGINFO = 0x53494749
+
 	// NT_X86_XSTATE is for x86 extended state using xsave.
 	NT_X86_XSTATE = 0x202
 
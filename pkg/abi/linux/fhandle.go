@@ -0,0 +1,29 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// MAX_HANDLE_SZ is the maximum size of the opaque part of a file handle, from
+// include/linux/exportfs.h.
+const MAX_HANDLE_SZ = 128
+
+// FileHandle is the fixed-size header of struct file_handle, from
+// include/linux/fs.h. The opaque handle, HandleBytes long, immediately
+// follows it.
+//
+// +marshal
+type FileHandle struct {
+	HandleBytes uint32
+	HandleType  int32
+}
@@ -278,6 +278,7 @@ const (
 	STATX_ATTR_NODUMP     = 0x00000040
 	STATX_ATTR_ENCRYPTED  = 0x00000800
 	STATX_ATTR_AUTOMOUNT  = 0x00001000
+	STATX_ATTR_VERITY     = 0x00100000
 )
 
 // Statx represents struct statx.
@@ -405,6 +406,25 @@ var fileType = abi.ValueSet{
 const (
 	MFD_CLOEXEC       = 0x0001
 	MFD_ALLOW_SEALING = 0x0002
+	MFD_HUGETLB       = 0x0004
+	MFD_NOEXEC_SEAL   = 0x0008
+	MFD_EXEC          = 0x0010
+)
+
+// Values for /proc/sys/vm/memfd_noexec. Source: include/linux/pid_namespace.h
+const (
+	// MEMFD_NOEXEC_SCOPE_EXEC makes memfd_create(2) without MFD_EXEC or
+	// MFD_NOEXEC_SEAL behave as if MFD_EXEC was specified.
+	MEMFD_NOEXEC_SCOPE_EXEC = 0
+
+	// MEMFD_NOEXEC_SCOPE_NOEXEC_SEAL makes memfd_create(2) without MFD_EXEC
+	// or MFD_NOEXEC_SEAL behave as if MFD_NOEXEC_SEAL was specified.
+	MEMFD_NOEXEC_SCOPE_NOEXEC_SEAL = 1
+
+	// MEMFD_NOEXEC_SCOPE_NOEXEC_ENFORCED behaves like
+	// MEMFD_NOEXEC_SCOPE_NOEXEC_SEAL, and additionally causes memfd_create(2)
+	// with MFD_EXEC to fail.
+	MEMFD_NOEXEC_SCOPE_NOEXEC_ENFORCED = 2
 )
 
 // Constants related to file seals. Source: include/uapi/{asm-generic,linux}/fcntl.h
@@ -417,6 +437,12 @@ const (
 	F_SEAL_SHRINK = 0x0002 // Prevent file from shrinking.
 	F_SEAL_GROW   = 0x0004 // Prevent file from growing.
 	F_SEAL_WRITE  = 0x0008 // Prevent writes.
+
+	F_SEAL_FUTURE_WRITE = 0x0010 // Prevent future writes while mapped.
+	F_SEAL_EXEC         = 0x0020 // Prevent chmod modifying exec bits.
+
+	// F_ALL_SEALS is the set of all supported seals.
+	F_ALL_SEALS = F_SEAL_SEAL | F_SEAL_SHRINK | F_SEAL_GROW | F_SEAL_WRITE | F_SEAL_FUTURE_WRITE | F_SEAL_EXEC
 )
 
 // Constants related to fallocate(2). Source: include/uapi/linux/falloc.h
@@ -19,6 +19,7 @@ package linux
 // See linux/magic.h.
 const (
 	ANON_INODE_FS_MAGIC   = 0x09041934
+	BINFMTFS_MAGIC        = 0x42494e4d
 	CGROUP_SUPER_MAGIC    = 0x27e0eb
 	DEVPTS_SUPER_MAGIC    = 0x00001cd1
 	EXT_SUPER_MAGIC       = 0xef53
@@ -32,6 +33,7 @@ const (
 	SOCKFS_MAGIC          = 0x534F434B
 	SYSFS_MAGIC           = 0x62656572
 	TMPFS_MAGIC           = 0x01021994
+	TRACEFS_MAGIC         = 0x74726163
 	V9FS_MAGIC            = 0x01021997
 )
 
@@ -0,0 +1,56 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// ioctl(2) requests provided by uapi/linux/fsverity.h.
+const (
+	FS_IOC_ENABLE_VERITY  = 0x40806685
+	FS_IOC_MEASURE_VERITY = 0xc0046686
+)
+
+// Hash algorithms, from uapi/linux/fsverity.h.
+const (
+	FS_VERITY_HASH_ALG_SHA256 = 1
+	FS_VERITY_HASH_ALG_SHA512 = 2
+)
+
+// FS_VERITY_FL is the inode flag set on files with fs-verity enabled, from
+// uapi/linux/fs.h.
+const FS_VERITY_FL = 0x00100000
+
+// FsverityEnableArg is struct fsverity_enable_arg, from
+// uapi/linux/fsverity.h.
+//
+// +marshal
+type FsverityEnableArg struct {
+	Version       uint32
+	HashAlgorithm uint32
+	BlockSize     uint32
+	SaltSize      uint32
+	SaltPtr       uint64
+	SigSize       uint32
+	Reserved1     uint32
+	SigPtr        uint64
+	Reserved2     [11]uint64
+}
+
+// FsverityDigest is struct fsverity_digest, from uapi/linux/fsverity.h,
+// without the trailing flexible array holding the digest.
+//
+// +marshal
+type FsverityDigest struct {
+	DigestAlgorithm uint16
+	DigestSize      uint16
+}
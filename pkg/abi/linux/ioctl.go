@@ -70,12 +70,17 @@ const (
 	SIOCGIFNAME    = 0x8910
 	SIOCGIFCONF    = 0x8912
 	SIOCGIFFLAGS   = 0x8913
+	SIOCSIFFLAGS   = 0x8914
 	SIOCGIFADDR    = 0x8915
+	SIOCSIFADDR    = 0x8916
 	SIOCGIFDSTADDR = 0x8917
 	SIOCGIFBRDADDR = 0x8919
+	SIOCSIFBRDADDR = 0x891a
 	SIOCGIFNETMASK = 0x891b
+	SIOCSIFNETMASK = 0x891c
 	SIOCGIFMETRIC  = 0x891d
 	SIOCGIFMTU     = 0x8921
+	SIOCSIFMTU     = 0x8922
 	SIOCGIFMEM     = 0x891f
 	SIOCGIFHWADDR  = 0x8927
 	SIOCGIFINDEX   = 0x8933
@@ -92,6 +97,16 @@ const (
 	SIOCGSTAMP = 0x8906
 )
 
+// ioctl(2) requests provided by uapi/linux/fs.h
+const (
+	BLKROGET     = 0x0000125e
+	BLKGETSIZE   = 0x00001260
+	BLKSSZGET    = 0x00001268
+	BLKGETSIZE64 = 0x80081272
+	FIFREEZE     = 0xc0045877
+	FITHAW       = 0xc0045878
+)
+
 // ioctl(2) directions. Used to calculate requests number.
 // Constants from asm-generic/ioctl.h.
 const (
@@ -138,6 +153,12 @@ func IOWR(typ, nr, size uint32) uint32 {
 	return IOC(IOC_READ|IOC_WRITE, typ, nr, size)
 }
 
+// IOC_DIR outputs the result of _IOC_DIR macro in
+// include/uapi/asm-generic/ioctl.h.
+func IOC_DIR(nr uint32) uint32 {
+	return (nr >> IOC_DIRSHIFT) & ((1 << IOC_DIRBITS) - 1)
+}
+
 // IOC_NR outputs the result of IOC_NR macro in
 // include/uapi/asm-generic/ioctl.h.
 func IOC_NR(nr uint32) uint32 {
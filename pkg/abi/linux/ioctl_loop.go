@@ -0,0 +1,81 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// ioctl(2) requests provided by uapi/linux/loop.h.
+const (
+	LOOP_SET_FD         = 0x4c00
+	LOOP_CLR_FD         = 0x4c01
+	LOOP_SET_STATUS64   = 0x4c04
+	LOOP_GET_STATUS64   = 0x4c05
+	LOOP_SET_CAPACITY   = 0x4c07
+	LOOP_SET_BLOCK_SIZE = 0x4c09
+	LOOP_CONFIGURE      = 0x4c0a
+
+	LOOP_CTL_ADD      = 0x4c80
+	LOOP_CTL_REMOVE   = 0x4c81
+	LOOP_CTL_GET_FREE = 0x4c82
+)
+
+// Loop device flags, from uapi/linux/loop.h.
+const (
+	LO_FLAGS_READ_ONLY = 1
+	LO_FLAGS_AUTOCLEAR = 4
+	LO_FLAGS_PARTSCAN  = 8
+	LO_FLAGS_DIRECT_IO = 16
+)
+
+// Loop device constants, from uapi/linux/loop.h.
+const (
+	LO_NAME_SIZE = 64
+	LO_KEY_SIZE  = 32
+)
+
+// LOOP_MAJOR is the major device number for loop block devices, from
+// uapi/linux/major.h.
+const LOOP_MAJOR = 7
+
+// LOOP_CTRL_MINOR is the minor device number of /dev/loop-control, from
+// include/linux/miscdevice.h.
+const LOOP_CTRL_MINOR = 237
+
+// LoopInfo64 is struct loop_info64, from uapi/linux/loop.h.
+//
+// +marshal
+type LoopInfo64 struct {
+	Device         uint64
+	Inode          uint64
+	Rdevice        uint64
+	Offset         uint64
+	SizeLimit      uint64
+	Number         uint32
+	EncryptType    uint32
+	EncryptKeySize uint32
+	Flags          uint32
+	FileName       [LO_NAME_SIZE]byte
+	CryptName      [LO_NAME_SIZE]byte
+	EncryptKey     [LO_KEY_SIZE]byte
+	Init           [2]uint64
+}
+
+// LoopConfig is struct loop_config, from uapi/linux/loop.h.
+//
+// +marshal
+type LoopConfig struct {
+	FD        uint32
+	BlockSize uint32
+	Info      LoopInfo64
+	_         [8]uint64
+}
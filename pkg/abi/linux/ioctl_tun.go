@@ -16,8 +16,16 @@ package linux
 
 // ioctl(2) request numbers from linux/if_tun.h
 var (
-	TUNSETIFF = IOW('T', 202, 4)
-	TUNGETIFF = IOR('T', 210, 4)
+	TUNSETNOCSUM    = IOW('T', 200, 4)
+	TUNSETIFF       = IOW('T', 202, 4)
+	TUNSETPERSIST   = IOW('T', 203, 4)
+	TUNSETOWNER     = IOW('T', 204, 4)
+	TUNSETGROUP     = IOW('T', 206, 4)
+	TUNGETFEATURES  = IOR('T', 207, 4)
+	TUNSETOFFLOAD   = IOW('T', 208, 4)
+	TUNGETIFF       = IOR('T', 210, 4)
+	TUNGETVNETHDRSZ = IOR('T', 215, 4)
+	TUNSETVNETHDRSZ = IOW('T', 216, 4)
 )
 
 // Flags from net/if_tun.h
@@ -26,7 +34,35 @@ const (
 	IFF_TAP      = 0x0002
 	IFF_NO_PI    = 0x1000
 	IFF_NOFILTER = 0x1000
+	IFF_VNET_HDR = 0x4000
 
 	// According to linux/if_tun.h "This flag has no real effect"
 	IFF_ONE_QUEUE = 0x2000
 )
+
+// Offload flags for TUNSETOFFLOAD, from linux/if_tun.h.
+const (
+	TUN_F_CSUM    = 0x01
+	TUN_F_TSO4    = 0x02
+	TUN_F_TSO6    = 0x04
+	TUN_F_TSO_ECN = 0x08
+	TUN_F_UFO     = 0x10
+	TUN_F_USO4    = 0x20
+	TUN_F_USO6    = 0x40
+)
+
+// Constants from linux/virtio_net.h.
+const (
+	// VIRTIO_NET_HDR_LEN is the size of struct virtio_net_hdr.
+	VIRTIO_NET_HDR_LEN = 10
+
+	VIRTIO_NET_HDR_F_NEEDS_CSUM = 1
+	VIRTIO_NET_HDR_F_DATA_VALID = 2
+
+	VIRTIO_NET_HDR_GSO_NONE   = 0
+	VIRTIO_NET_HDR_GSO_TCPV4  = 1
+	VIRTIO_NET_HDR_GSO_UDP    = 3
+	VIRTIO_NET_HDR_GSO_TCPV6  = 4
+	VIRTIO_NET_HDR_GSO_UDP_L4 = 5
+	VIRTIO_NET_HDR_GSO_ECN    = 0x80
+)
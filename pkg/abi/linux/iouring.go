@@ -34,6 +34,7 @@ const (
 // Constants for IoUringParams.Features. See include/uapi/linux/io_uring.h.
 const (
 	IORING_FEAT_SINGLE_MMAP = (1 << 0)
+	IORING_FEAT_NODROP      = (1 << 1)
 )
 
 // Constants for IO_URING. See include/uapi/linux/io_uring.h.
@@ -60,8 +61,20 @@ const (
 
 // Constants for the IO_URING opcodes. See include/uapi/linux/io_uring.h.
 const (
-	IORING_OP_NOP   = 0
-	IORING_OP_READV = 1
+	IORING_OP_NOP         = 0
+	IORING_OP_READV       = 1
+	IORING_OP_WRITEV      = 2
+	IORING_OP_FSYNC       = 3
+	IORING_OP_READ_FIXED  = 4
+	IORING_OP_WRITE_FIXED = 5
+	IORING_OP_READ        = 22
+	IORING_OP_WRITE       = 23
+)
+
+// Constants for io_uring_register(2). See include/uapi/linux/io_uring.h.
+const (
+	IORING_REGISTER_BUFFERS   = 0
+	IORING_UNREGISTER_BUFFERS = 1
 )
 
 // IORingIndex represents SQE array indexes.
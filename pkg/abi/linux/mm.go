@@ -35,6 +35,8 @@ const (
 const (
 	MAP_SHARED     = 1 << 0
 	MAP_PRIVATE    = 1 << 1
+	MAP_DROPPABLE  = 0x08
+	MAP_TYPE       = 0x0f
 	MAP_FIXED      = 1 << 4
 	MAP_ANONYMOUS  = 1 << 5
 	MAP_32BIT      = 1 << 6 // arch/x86/include/uapi/asm/mman.h
@@ -83,6 +85,8 @@ const (
 	MADV_NOHUGEPAGE   = 15
 	MADV_DONTDUMP     = 16
 	MADV_DODUMP       = 17
+	MADV_WIPEONFORK   = 18
+	MADV_KEEPONFORK   = 19
 	MADV_HWPOISON     = 100
 	MADV_SOFT_OFFLINE = 101
 	MADV_NOMAJFAULT   = 200
@@ -135,6 +139,49 @@ const (
 	MPOL_MF_VALID = MPOL_MF_STRICT | MPOL_MF_MOVE | MPOL_MF_MOVE_ALL
 )
 
+// Page flags reported by /proc/kpageflags, from
+// include/uapi/linux/kernel-page-flags.h.
+const (
+	KPF_LOCKED        = 0
+	KPF_ERROR         = 1
+	KPF_REFERENCED    = 2
+	KPF_UPTODATE      = 3
+	KPF_DIRTY         = 4
+	KPF_LRU           = 5
+	KPF_ACTIVE        = 6
+	KPF_SLAB          = 7
+	KPF_WRITEBACK     = 8
+	KPF_RECLAIM       = 9
+	KPF_BUDDY         = 10
+	KPF_MMAP          = 11
+	KPF_ANON          = 12
+	KPF_SWAPCACHE     = 13
+	KPF_SWAPBACKED    = 14
+	KPF_COMPOUND_HEAD = 15
+	KPF_COMPOUND_TAIL = 16
+	KPF_HUGE          = 17
+	KPF_UNEVICTABLE   = 18
+	KPF_HWPOISON      = 19
+	KPF_NOPAGE        = 20
+	KPF_KSM           = 21
+	KPF_THP           = 22
+	KPF_OFFLINE       = 23
+	KPF_ZERO_PAGE     = 24
+	KPF_IDLE          = 25
+	KPF_PGTABLE       = 26
+)
+
+// Flags for swapon(2), from include/linux/swap.h.
+const (
+	SWAP_FLAG_PREFER        = 0x8000
+	SWAP_FLAG_PRIO_MASK     = 0x7fff
+	SWAP_FLAG_DISCARD       = 0x10000
+	SWAP_FLAG_DISCARD_ONCE  = 0x20000
+	SWAP_FLAG_DISCARD_PAGES = 0x40000
+
+	SWAP_FLAGS_VALID = SWAP_FLAG_PRIO_MASK | SWAP_FLAG_PREFER | SWAP_FLAG_DISCARD | SWAP_FLAG_DISCARD_ONCE | SWAP_FLAG_DISCARD_PAGES
+)
+
 // TaskSize is the address space size.
 var TaskSize = func() uintptr {
 	pageSize := uintptr(unix.Getpagesize())
@@ -91,13 +91,112 @@ type IFConf struct {
 // +marshal
 type EthtoolCmd uint32
 
+// SIOCETHTOOL commands, from <linux/ethtool.h>.
 const (
+	// ETHTOOL_GSET is the command to SIOCETHTOOL to query link settings,
+	// using EthtoolSettings.
+	ETHTOOL_GSET EthtoolCmd = 0x1
+
+	// ETHTOOL_GDRVINFO is the command to SIOCETHTOOL to query driver
+	// information, using EthtoolDrvinfo.
+	ETHTOOL_GDRVINFO EthtoolCmd = 0x3
+
+	// ETHTOOL_GLINK is the command to SIOCETHTOOL to query link status,
+	// using EthtoolValue.
+	ETHTOOL_GLINK EthtoolCmd = 0xa
+
+	// ETHTOOL_GRINGPARAM is the command to SIOCETHTOOL to query ring
+	// sizes, using EthtoolRingparam.
+	ETHTOOL_GRINGPARAM EthtoolCmd = 0x10
+
 	// ETHTOOL_GFEATURES is the command to SIOCETHTOOL to query device
 	// features.
-	// See: <linux/ethtool.h>
 	ETHTOOL_GFEATURES EthtoolCmd = 0x3a
+
+	// ETHTOOL_GLINKSETTINGS is the command to SIOCETHTOOL to query link
+	// settings, superseding ETHTOOL_GSET.
+	ETHTOOL_GLINKSETTINGS EthtoolCmd = 0x4c
+)
+
+// Link settings values, from <linux/ethtool.h>.
+const (
+	SPEED_10000     = 10000
+	DUPLEX_FULL     = 0x1
+	PORT_TP         = 0x0
+	XCVR_INTERNAL   = 0x0
+	AUTONEG_DISABLE = 0x0
 )
 
+// EthtoolSettings is struct ethtool_cmd, used to return link settings.
+// See: <linux/ethtool.h>
+//
+// +marshal
+type EthtoolSettings struct {
+	Cmd           uint32
+	Supported     uint32
+	Advertising   uint32
+	Speed         uint16
+	Duplex        uint8
+	Port          uint8
+	PhyAddress    uint8
+	Transceiver   uint8
+	Autoneg       uint8
+	MdioSupport   uint8
+	Maxtxpkt      uint32
+	Maxrxpkt      uint32
+	SpeedHi       uint16
+	EthTpMdix     uint8
+	EthTpMdixCtrl uint8
+	LpAdvertising uint32
+	Reserved      [2]uint32
+}
+
+// EthtoolDrvinfo is struct ethtool_drvinfo, used to return driver
+// information.
+// See: <linux/ethtool.h>
+//
+// +marshal
+type EthtoolDrvinfo struct {
+	Cmd         uint32
+	Driver      [32]byte
+	Version     [32]byte
+	FwVersion   [32]byte
+	BusInfo     [32]byte
+	EromVersion [32]byte
+	Reserved2   [12]byte
+	NPrivFlags  uint32
+	NStats      uint32
+	TestinfoLen uint32
+	EedumpLen   uint32
+	RegdumpLen  uint32
+}
+
+// EthtoolValue is struct ethtool_value, used to return a single value.
+// See: <linux/ethtool.h>
+//
+// +marshal
+type EthtoolValue struct {
+	Cmd  uint32
+	Data uint32
+}
+
+// EthtoolRingparam is struct ethtool_ringparam, used to return RX/TX ring
+// sizes.
+// See: <linux/ethtool.h>
+//
+// +marshal
+type EthtoolRingparam struct {
+	Cmd               uint32
+	RxMaxPending      uint32
+	RxMiniMaxPending  uint32
+	RxJumboMaxPending uint32
+	TxMaxPending      uint32
+	RxPending         uint32
+	RxMiniPending     uint32
+	RxJumboPending    uint32
+	TxPending         uint32
+}
+
 // EthtoolGFeatures is used to return a list of device features.
 // See: <linux/ethtool.h>
 //
@@ -82,6 +82,9 @@ const (
 	RTM_NEWNSID = 88
 	RTM_DELNSID = 89
 	RTM_GETNSID = 90
+
+	RTM_NEWSTATS = 92
+	RTM_GETSTATS = 94
 )
 
 // InterfaceInfoMessage is struct ifinfomsg, from uapi/linux/rtnetlink.h.
@@ -178,6 +181,66 @@ const (
 	IFLA_INFO_SLAVE_DATA = 5
 )
 
+// InterfaceStatsMessage is struct if_stats_msg, from uapi/linux/if_link.h.
+//
+// +marshal
+type InterfaceStatsMessage struct {
+	Family     uint8
+	_          uint8
+	_          uint16
+	Index      int32
+	FilterMask uint32
+}
+
+// InterfaceStatsMessageSize is the size of InterfaceStatsMessage.
+const InterfaceStatsMessageSize = 12
+
+// Interface stats attributes, from uapi/linux/if_link.h.
+const (
+	IFLA_STATS_UNSPEC              = 0
+	IFLA_STATS_LINK_64             = 1
+	IFLA_STATS_LINK_XSTATS         = 2
+	IFLA_STATS_LINK_XSTATS_SLAVE   = 3
+	IFLA_STATS_LINK_OFFLOAD_XSTATS = 4
+	IFLA_STATS_AF_SPEC             = 5
+)
+
+// IFLAStatsFilterBit returns the bit in InterfaceStatsMessage.FilterMask
+// selecting the given IFLA_STATS_* attribute.
+func IFLAStatsFilterBit(attr uint16) uint32 {
+	return 1 << (attr - 1)
+}
+
+// RtnlLinkStats64 is struct rtnl_link_stats64, from uapi/linux/if_link.h.
+//
+// +marshal
+type RtnlLinkStats64 struct {
+	RxPackets         uint64
+	TxPackets         uint64
+	RxBytes           uint64
+	TxBytes           uint64
+	RxErrors          uint64
+	TxErrors          uint64
+	RxDropped         uint64
+	TxDropped         uint64
+	Multicast         uint64
+	Collisions        uint64
+	RxLengthErrors    uint64
+	RxOverErrors      uint64
+	RxCRCErrors       uint64
+	RxFrameErrors     uint64
+	RxFIFOErrors      uint64
+	RxMissedErrors    uint64
+	TxAbortedErrors   uint64
+	TxCarrierErrors   uint64
+	TxFIFOErrors      uint64
+	TxHeartbeatErrors uint64
+	TxWindowErrors    uint64
+	RxCompressed      uint64
+	TxCompressed      uint64
+	RxNoHandler       uint64
+}
+
 // Virtuall ethernet attributes, from uapi/linux/veth.h.
 const (
 	VETH_INFO_PEER = 1
@@ -43,6 +43,7 @@ const (
 	PTRACE_SETSIGMASK           = 0x420b
 	PTRACE_SECCOMP_GET_FILTER   = 0x420c
 	PTRACE_SECCOMP_GET_METADATA = 0x420d
+	PTRACE_GET_SYSCALL_INFO     = 0x420e
 )
 
 // ptrace commands from arch/x86/include/uapi/asm/ptrace-abi.h.
@@ -88,6 +89,53 @@ const (
 	PTRACE_O_SUSPEND_SECCOMP = 1 << 21
 )
 
+// ptrace(PTRACE_GETEVENTMSG) values at syscall-stops, from
+// include/uapi/linux/ptrace.h.
+const (
+	PTRACE_EVENTMSG_SYSCALL_ENTRY = 1
+	PTRACE_EVENTMSG_SYSCALL_EXIT  = 2
+)
+
+// PtraceSyscallInfo.Op values, from include/uapi/linux/ptrace.h.
+const (
+	PTRACE_SYSCALL_INFO_NONE    = 0
+	PTRACE_SYSCALL_INFO_ENTRY   = 1
+	PTRACE_SYSCALL_INFO_EXIT    = 2
+	PTRACE_SYSCALL_INFO_SECCOMP = 3
+)
+
+// PtraceSyscallInfo is equivalent to struct ptrace_syscall_info, returned by
+// ptrace(PTRACE_GET_SYSCALL_INFO).
+//
+// +marshal
+type PtraceSyscallInfo struct {
+	Op                 uint8
+	_                  [3]uint8
+	Arch               uint32
+	InstructionPointer uint64
+	StackPointer       uint64
+
+	// Data is the union of the entry, exit and seccomp structs, whose
+	// layout depends on Op:
+	//
+	//   - PTRACE_SYSCALL_INFO_ENTRY: Data[0] is the syscall number and
+	//     Data[1:7] are its arguments.
+	//   - PTRACE_SYSCALL_INFO_EXIT: Data[0] is the return value and the low
+	//     byte of Data[1] is 1 if the return value is an error.
+	//   - PTRACE_SYSCALL_INFO_SECCOMP: as for PTRACE_SYSCALL_INFO_ENTRY, and
+	//     the low 32 bits of Data[7] are SECCOMP_RET_DATA.
+	Data [8]uint64
+}
+
+// Sizes of the struct ptrace_syscall_info fields that are valid for each
+// PtraceSyscallInfo.Op, from kernel/ptrace.c:ptrace_get_syscall_info().
+const (
+	PtraceSyscallInfoNoneSize    = 24
+	PtraceSyscallInfoEntrySize   = PtraceSyscallInfoNoneSize + 7*8
+	PtraceSyscallInfoExitSize    = PtraceSyscallInfoNoneSize + 8 + 1
+	PtraceSyscallInfoSeccompSize = PtraceSyscallInfoEntrySize + 4
+)
+
 // YAMA ptrace_scope levels from security/yama/yama_lsm.c.
 const (
 	YAMA_SCOPE_DISABLED   = 0
@@ -184,6 +184,14 @@ const (
 const (
 	SS_ONSTACK = 1
 	SS_DISABLE = 2
+
+	// SS_AUTODISARM disables the signal stack while a signal handler runs
+	// on it.
+	SS_AUTODISARM = 1 << 31
+
+	// SS_FLAG_BITS are the flags that may be combined with the signal stack
+	// mode.
+	SS_FLAG_BITS = SS_AUTODISARM
 )
 
 // SIGPOLL si_codes.
@@ -243,6 +251,28 @@ const (
 	SI_ASYNCNL = -60
 )
 
+// SEGV_* codes are only meaningful for SIGSEGV.
+const (
+	// SEGV_MAPERR indicates that the address isn't mapped.
+	SEGV_MAPERR = 1
+
+	// SEGV_ACCERR indicates that the mapping doesn't permit the access.
+	SEGV_ACCERR = 2
+)
+
+// BUS_* codes are only meaningful for SIGBUS.
+const (
+	// BUS_ADRALN indicates an invalid address alignment.
+	BUS_ADRALN = 1
+
+	// BUS_ADRERR indicates a nonexistent physical address, e.g. beyond the
+	// end of a mapped file.
+	BUS_ADRERR = 2
+
+	// BUS_OBJERR indicates an object-specific hardware error.
+	BUS_OBJERR = 3
+)
+
 // CLD_* codes are only meaningful for SIGCHLD.
 const (
 	// CLD_EXITED indicates that a task exited.
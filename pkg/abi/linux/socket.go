@@ -96,6 +96,7 @@ const (
 	SOL_SOCKET  = 1
 	SOL_TCP     = 6
 	SOL_UDP     = 17
+	SOL_UDPLITE = 136
 	SOL_IPV6    = 41
 	SOL_ICMPV6  = 58
 	SOL_RAW     = 255
@@ -574,6 +575,10 @@ const SizeOfControlMessageTTL = 4
 // SizeOfControlMessageTClass is the size of an IPV6_TCLASS control message.
 const SizeOfControlMessageTClass = 4
 
+// SizeOfControlMessageFlowInfo is the size of an IPV6_FLOWINFO control
+// message.
+const SizeOfControlMessageFlowInfo = 4
+
 // SizeOfControlMessageHopLimit is the size of an IPV6_HOPLIMIT control message.
 const SizeOfControlMessageHopLimit = 4
 
@@ -0,0 +1,201 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// Device numbers for ALSA devices, from include/sound/core.h and
+// include/sound/minors.h.
+const (
+	// SND_MAJOR is the major device number for ALSA devices.
+	SND_MAJOR = 116
+
+	// SNDRV_MINOR_SEQUENCER is the static minor device number for
+	// /dev/snd/seq.
+	SNDRV_MINOR_SEQUENCER = 1
+
+	// SNDRV_MINOR_TIMER is the static minor device number for
+	// /dev/snd/timer.
+	SNDRV_MINOR_TIMER = 33
+)
+
+// Protocol versions, from include/uapi/sound/asound.h.
+const (
+	SNDRV_CTL_VERSION   = 0x00020008
+	SNDRV_PCM_VERSION   = 0x0002000f
+	SNDRV_TIMER_VERSION = 0x00020007
+)
+
+// Sizes of ALSA ioctl parameter structures that the sentry passes through
+// without interpreting, on 64-bit architectures.
+const (
+	SizeofSndCtlCardInfo    = 376
+	SizeofSndCtlElemID      = 64
+	SizeofSndCtlElemInfo    = 272
+	SizeofSndCtlElemValue   = 1224
+	SizeofSndPcmInfo        = 288
+	SizeofSndPcmHWParams    = 608
+	SizeofSndPcmSWParams    = 136
+	SizeofSndPcmStatus      = 152
+	SizeofSndPcmSyncPtr     = 136
+	SizeofSndPcmChannelInfo = 24
+	SizeofSndTimerGInfo     = 248
+	SizeofSndTimerGParams   = 72
+	SizeofSndTimerGStatus   = 80
+	SizeofSndTimerSelect    = 52
+	SizeofSndTimerInfo      = 232
+	SizeofSndTimerParams    = 80
+	SizeofSndTimerStatus    = 96
+	SizeofSndTimerID        = 20
+	SizeofSndCtlElemList    = 80
+	SizeofSndCtlTLV         = 8
+	SizeofSndXferi          = 24
+	SizeofSndPcmUframesT    = 8
+	SizeofSndPcmSframesT    = 8
+)
+
+// Hardware parameters, from include/uapi/sound/asound.h.
+const (
+	SNDRV_PCM_HW_PARAM_FIRST_INTERVAL = 8
+	SNDRV_PCM_HW_PARAM_FRAME_BITS     = 9
+)
+
+// SndPcmHWParamsFrameBitsOffset is the offset in struct snd_pcm_hw_params of
+// the minimum of the SNDRV_PCM_HW_PARAM_FRAME_BITS interval. The intervals
+// array begins at offset 260, and each struct snd_interval is 12 bytes.
+const SndPcmHWParamsFrameBitsOffset = 260 + 12*(SNDRV_PCM_HW_PARAM_FRAME_BITS-SNDRV_PCM_HW_PARAM_FIRST_INTERVAL)
+
+// Timer classes, from include/uapi/sound/asound.h.
+const (
+	// SNDRV_TIMER_CLASS_NONE is the timer device class returned by
+	// SNDRV_TIMER_IOCTL_NEXT_DEVICE when there are no more timers.
+	SNDRV_TIMER_CLASS_NONE = -1
+
+	// SNDRV_TIMER_SCLASS_NONE is the timer slave class of timers that are
+	// not slaves.
+	SNDRV_TIMER_SCLASS_NONE = 0
+)
+
+// Control ioctls, from include/uapi/sound/asound.h.
+var (
+	SNDRV_CTL_IOCTL_PVERSION             = IOR('U', 0x00, 4)
+	SNDRV_CTL_IOCTL_CARD_INFO            = IOR('U', 0x01, SizeofSndCtlCardInfo)
+	SNDRV_CTL_IOCTL_ELEM_LIST            = IOWR('U', 0x10, SizeofSndCtlElemList)
+	SNDRV_CTL_IOCTL_ELEM_INFO            = IOWR('U', 0x11, SizeofSndCtlElemInfo)
+	SNDRV_CTL_IOCTL_ELEM_READ            = IOWR('U', 0x12, SizeofSndCtlElemValue)
+	SNDRV_CTL_IOCTL_ELEM_WRITE           = IOWR('U', 0x13, SizeofSndCtlElemValue)
+	SNDRV_CTL_IOCTL_ELEM_LOCK            = IOW('U', 0x14, SizeofSndCtlElemID)
+	SNDRV_CTL_IOCTL_ELEM_UNLOCK          = IOW('U', 0x15, SizeofSndCtlElemID)
+	SNDRV_CTL_IOCTL_SUBSCRIBE_EVENTS     = IOWR('U', 0x16, 4)
+	SNDRV_CTL_IOCTL_TLV_READ             = IOWR('U', 0x1a, SizeofSndCtlTLV)
+	SNDRV_CTL_IOCTL_PCM_NEXT_DEVICE      = IOR('U', 0x30, 4)
+	SNDRV_CTL_IOCTL_PCM_INFO             = IOWR('U', 0x31, SizeofSndPcmInfo)
+	SNDRV_CTL_IOCTL_PCM_PREFER_SUBDEVICE = IOW('U', 0x32, 4)
+	SNDRV_CTL_IOCTL_RAWMIDI_NEXT_DEVICE  = IOWR('U', 0x40, 4)
+	SNDRV_CTL_IOCTL_POWER_STATE          = IOR('U', 0xd1, 4)
+)
+
+// PCM ioctls, from include/uapi/sound/asound.h.
+var (
+	SNDRV_PCM_IOCTL_PVERSION      = IOR('A', 0x00, 4)
+	SNDRV_PCM_IOCTL_INFO          = IOR('A', 0x01, SizeofSndPcmInfo)
+	SNDRV_PCM_IOCTL_TSTAMP        = IOW('A', 0x02, 4)
+	SNDRV_PCM_IOCTL_TTSTAMP       = IOW('A', 0x03, 4)
+	SNDRV_PCM_IOCTL_USER_PVERSION = IOW('A', 0x04, 4)
+	SNDRV_PCM_IOCTL_HW_REFINE     = IOWR('A', 0x10, SizeofSndPcmHWParams)
+	SNDRV_PCM_IOCTL_HW_PARAMS     = IOWR('A', 0x11, SizeofSndPcmHWParams)
+	SNDRV_PCM_IOCTL_HW_FREE       = IO('A', 0x12)
+	SNDRV_PCM_IOCTL_SW_PARAMS     = IOWR('A', 0x13, SizeofSndPcmSWParams)
+	SNDRV_PCM_IOCTL_STATUS        = IOR('A', 0x20, SizeofSndPcmStatus)
+	SNDRV_PCM_IOCTL_DELAY         = IOR('A', 0x21, SizeofSndPcmSframesT)
+	SNDRV_PCM_IOCTL_HWSYNC        = IO('A', 0x22)
+	SNDRV_PCM_IOCTL_SYNC_PTR      = IOWR('A', 0x23, SizeofSndPcmSyncPtr)
+	SNDRV_PCM_IOCTL_STATUS_EXT    = IOWR('A', 0x24, SizeofSndPcmStatus)
+	SNDRV_PCM_IOCTL_CHANNEL_INFO  = IOR('A', 0x32, SizeofSndPcmChannelInfo)
+	SNDRV_PCM_IOCTL_PREPARE       = IO('A', 0x40)
+	SNDRV_PCM_IOCTL_RESET         = IO('A', 0x41)
+	SNDRV_PCM_IOCTL_START         = IO('A', 0x42)
+	SNDRV_PCM_IOCTL_DROP          = IO('A', 0x43)
+	SNDRV_PCM_IOCTL_DRAIN         = IO('A', 0x44)
+	SNDRV_PCM_IOCTL_PAUSE         = IOW('A', 0x45, 4)
+	SNDRV_PCM_IOCTL_REWIND        = IOW('A', 0x46, SizeofSndPcmUframesT)
+	SNDRV_PCM_IOCTL_RESUME        = IO('A', 0x47)
+	SNDRV_PCM_IOCTL_XRUN          = IO('A', 0x48)
+	SNDRV_PCM_IOCTL_FORWARD       = IOW('A', 0x49, SizeofSndPcmUframesT)
+	SNDRV_PCM_IOCTL_WRITEI_FRAMES = IOW('A', 0x50, SizeofSndXferi)
+	SNDRV_PCM_IOCTL_READI_FRAMES  = IOR('A', 0x51, SizeofSndXferi)
+	SNDRV_PCM_IOCTL_LINK          = IOW('A', 0x60, 4)
+	SNDRV_PCM_IOCTL_UNLINK        = IO('A', 0x61)
+)
+
+// Timer ioctls, from include/uapi/sound/asound.h.
+var (
+	SNDRV_TIMER_IOCTL_PVERSION    = IOR('T', 0x00, 4)
+	SNDRV_TIMER_IOCTL_NEXT_DEVICE = IOWR('T', 0x01, SizeofSndTimerID)
+	SNDRV_TIMER_IOCTL_TREAD_OLD   = IOW('T', 0x02, 4)
+	SNDRV_TIMER_IOCTL_GINFO       = IOWR('T', 0x03, SizeofSndTimerGInfo)
+	SNDRV_TIMER_IOCTL_GPARAMS     = IOW('T', 0x04, SizeofSndTimerGParams)
+	SNDRV_TIMER_IOCTL_GSTATUS     = IOWR('T', 0x05, SizeofSndTimerGStatus)
+	SNDRV_TIMER_IOCTL_SELECT      = IOW('T', 0x10, SizeofSndTimerSelect)
+	SNDRV_TIMER_IOCTL_INFO        = IOR('T', 0x11, SizeofSndTimerInfo)
+	SNDRV_TIMER_IOCTL_PARAMS      = IOW('T', 0x12, SizeofSndTimerParams)
+	SNDRV_TIMER_IOCTL_STATUS      = IOR('T', 0x14, SizeofSndTimerStatus)
+	SNDRV_TIMER_IOCTL_START       = IO('T', 0xa0)
+	SNDRV_TIMER_IOCTL_STOP        = IO('T', 0xa1)
+	SNDRV_TIMER_IOCTL_CONTINUE    = IO('T', 0xa2)
+	SNDRV_TIMER_IOCTL_PAUSE       = IO('T', 0xa3)
+	SNDRV_TIMER_IOCTL_TREAD64     = IOW('T', 0xa4, 4)
+)
+
+// SndCtlElemList is struct snd_ctl_elem_list, from
+// include/uapi/sound/asound.h.
+//
+// +marshal
+type SndCtlElemList struct {
+	Offset uint32
+	Space  uint32
+	Used   uint32
+	Count  uint32
+	Pids   uint64
+	// 50 reserved bytes, then padding to 8-byte alignment.
+	_ [56]byte
+}
+
+// SndCtlTLV is the fixed-size header of struct snd_ctl_tlv, from
+// include/uapi/sound/asound.h. It is followed by Length bytes of TLV data.
+//
+// +marshal
+type SndCtlTLV struct {
+	Numid  uint32
+	Length uint32
+}
+
+// SndXferi is struct snd_xferi, from include/uapi/sound/asound.h.
+//
+// +marshal
+type SndXferi struct {
+	Result int64
+	Buf    uint64
+	Frames uint64
+}
+
+// SndTimerID is struct snd_timer_id, from include/uapi/sound/asound.h.
+//
+// +marshal
+type SndTimerID struct {
+	DevClass  int32
+	DevSClass int32
+	Card      int32
+	Device    int32
+	Subdevice int32
+}
@@ -0,0 +1,48 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// Actions for syslog(2), from include/linux/syslog.h.
+const (
+	SYSLOG_ACTION_CLOSE         = 0
+	SYSLOG_ACTION_OPEN          = 1
+	SYSLOG_ACTION_READ          = 2
+	SYSLOG_ACTION_READ_ALL      = 3
+	SYSLOG_ACTION_READ_CLEAR    = 4
+	SYSLOG_ACTION_CLEAR         = 5
+	SYSLOG_ACTION_CONSOLE_OFF   = 6
+	SYSLOG_ACTION_CONSOLE_ON    = 7
+	SYSLOG_ACTION_CONSOLE_LEVEL = 8
+	SYSLOG_ACTION_SIZE_UNREAD   = 9
+	SYSLOG_ACTION_SIZE_BUFFER   = 10
+)
+
+// Log levels, from include/linux/kern_levels.h.
+const (
+	LOGLEVEL_EMERG   = 0
+	LOGLEVEL_ALERT   = 1
+	LOGLEVEL_CRIT    = 2
+	LOGLEVEL_ERR     = 3
+	LOGLEVEL_WARNING = 4
+	LOGLEVEL_NOTICE  = 5
+	LOGLEVEL_INFO    = 6
+	LOGLEVEL_DEBUG   = 7
+)
+
+// Log facilities, from include/uapi/linux/syslog.h and glibc's syslog.h.
+const (
+	LOG_KERN = 0
+	LOG_USER = 1
+)
@@ -51,6 +51,107 @@ const (
 	TCP_FASTOPEN_NO_COOKIE   = 34
 	TCP_ZEROCOPY_RECEIVE     = 35
 	TCP_INQ                  = 36
+	TCP_TX_DELAY             = 37
+	TCP_AO_ADD_KEY           = 38
+	TCP_AO_DEL_KEY           = 39
+	TCP_AO_INFO              = 40
+	TCP_AO_GET_KEYS          = 41
+	TCP_AO_REPAIR            = 42
+)
+
+// TCP MD5 signature constants from uapi/linux/tcp.h.
+const (
+	TCP_MD5SIG_MAXKEYLEN    = 80
+	TCP_MD5SIG_FLAG_PREFIX  = 0x1
+	TCP_MD5SIG_FLAG_IFINDEX = 0x2
+)
+
+// TCP-AO constants from uapi/linux/tcp.h.
+const (
+	TCP_AO_MAXKEYLEN        = 80
+	TCP_AO_KEYF_IFINDEX     = 0x1
+	TCP_AO_KEYF_EXCLUDE_OPT = 0x2
+)
+
+// Bits of the bitfields in TCPAOAdd, TCPAODel and TCPAOInfoOpt.
+const (
+	TCP_AO_SET_CURRENT  = 1 << 0
+	TCP_AO_SET_RNEXT    = 1 << 1
+	TCP_AO_REQUIRED     = 1 << 2
+	TCP_AO_SET_COUNTERS = 1 << 3
+)
+
+// TCPMD5Sig is struct tcp_md5sig, from uapi/linux/tcp.h.
+//
+// +marshal
+type TCPMD5Sig struct {
+	Addr      [SockAddrMax]byte
+	Flags     uint8
+	PrefixLen uint8
+	KeyLen    uint16
+	IfIndex   int32
+	Key       [TCP_MD5SIG_MAXKEYLEN]byte
+}
+
+// TCPAOAdd is struct tcp_ao_add, from uapi/linux/tcp.h.
+//
+// +marshal
+type TCPAOAdd struct {
+	Addr    [SockAddrMax]byte
+	AlgName [64]byte
+	IfIndex int32
+	// Bits holds the set_current and set_rnext bitfields.
+	Bits     uint32
+	_        uint16
+	Prefix   uint8
+	SndID    uint8
+	RcvID    uint8
+	MACLen   uint8
+	KeyFlags uint8
+	KeyLen   uint8
+	Key      [TCP_AO_MAXKEYLEN]byte
+}
+
+// TCPAODel is struct tcp_ao_del, from uapi/linux/tcp.h.
+//
+// +marshal
+type TCPAODel struct {
+	Addr    [SockAddrMax]byte
+	IfIndex int32
+	// Bits holds the set_current, set_rnext and del_async bitfields.
+	Bits       uint32
+	_          uint16
+	Prefix     uint8
+	SndID      uint8
+	RcvID      uint8
+	CurrentKey uint8
+	RNext      uint8
+	KeyFlags   uint8
+}
+
+// TCPAOInfoOpt is struct tcp_ao_info_opt, from uapi/linux/tcp.h.
+//
+// +marshal
+type TCPAOInfoOpt struct {
+	// Bits holds the set_current, set_rnext, ao_required, set_counters and
+	// accept_icmps bitfields.
+	Bits           uint32
+	_              uint16
+	CurrentKey     uint8
+	RNext          uint8
+	PktGood        uint64
+	PktBad         uint64
+	PktKeyNotFound uint64
+	PktAORequired  uint64
+	PktDroppedICMP uint64
+}
+
+// Sizes of the TCP MD5 and TCP-AO socket option structs.
+var (
+	SizeOfTCPMD5Sig    = (*TCPMD5Sig)(nil).SizeBytes()
+	SizeOfTCPAOAdd     = (*TCPAOAdd)(nil).SizeBytes()
+	SizeOfTCPAODel     = (*TCPAODel)(nil).SizeBytes()
+	SizeOfTCPAOInfoOpt = (*TCPAOInfoOpt)(nil).SizeBytes()
 )
 
 // Socket constants from include/net/tcp.h.
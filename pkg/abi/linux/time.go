@@ -246,6 +246,7 @@ type TimerID int32
 
 // StatxTimestamp represents struct statx_timestamp.
 //
+// +stateify savable
 // +marshal
 type StatxTimestamp struct {
 	Sec  int64
@@ -0,0 +1,31 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// Socket options for SOL_UDP, from uapi/linux/udp.h.
+const (
+	UDP_CORK         = 1
+	UDP_ENCAP        = 100
+	UDP_NO_CHECK6_TX = 101
+	UDP_NO_CHECK6_RX = 102
+	UDP_SEGMENT      = 103
+	UDP_GRO          = 104
+)
+
+// Socket options for SOL_UDPLITE, from include/net/udplite.h.
+const (
+	UDPLITE_SEND_CSCOV = 10
+	UDPLITE_RECV_CSCOV = 11
+)
@@ -34,4 +34,39 @@ const (
 
 	XATTR_USER_PREFIX     = "user."
 	XATTR_USER_PREFIX_LEN = len(XATTR_USER_PREFIX)
+
+	XATTR_NAME_POSIX_ACL_ACCESS  = "system.posix_acl_access"
+	XATTR_NAME_POSIX_ACL_DEFAULT = "system.posix_acl_default"
+)
+
+// POSIX ACL extended attribute format, from
+// include/uapi/linux/posix_acl_xattr.h.
+const (
+	POSIX_ACL_XATTR_VERSION = 0x0002
+
+	// POSIX_ACL_XATTR_HEADER_SIZE is the size of the header containing the
+	// version, which is followed by entries of POSIX_ACL_XATTR_ENTRY_SIZE
+	// bytes, each containing a 16-bit tag, 16-bit permissions, and a 32-bit
+	// ID.
+	POSIX_ACL_XATTR_HEADER_SIZE = 4
+	POSIX_ACL_XATTR_ENTRY_SIZE  = 8
+)
+
+// POSIX ACL entry tags, from include/uapi/linux/posix_acl.h.
+const (
+	ACL_USER_OBJ  = 0x01
+	ACL_USER      = 0x02
+	ACL_GROUP_OBJ = 0x04
+	ACL_GROUP     = 0x08
+	ACL_MASK      = 0x10
+	ACL_OTHER     = 0x20
+)
+
+// POSIX ACL entry permissions, from include/uapi/linux/posix_acl.h.
+const (
+	ACL_READ    = 0x04
+	ACL_WRITE   = 0x02
+	ACL_EXECUTE = 0x01
+
+	ACL_UNDEFINED_ID = 0xffffffff
 )
@@ -0,0 +1,327 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+// Netlink message types for NETLINK_XFRM sockets, from uapi/linux/xfrm.h.
+const (
+	XFRM_MSG_NEWSA       = 0x10
+	XFRM_MSG_DELSA       = 0x11
+	XFRM_MSG_GETSA       = 0x12
+	XFRM_MSG_NEWPOLICY   = 0x13
+	XFRM_MSG_DELPOLICY   = 0x14
+	XFRM_MSG_GETPOLICY   = 0x15
+	XFRM_MSG_ALLOCSPI    = 0x16
+	XFRM_MSG_ACQUIRE     = 0x17
+	XFRM_MSG_EXPIRE      = 0x18
+	XFRM_MSG_UPDPOLICY   = 0x19
+	XFRM_MSG_UPDSA       = 0x1a
+	XFRM_MSG_POLEXPIRE   = 0x1b
+	XFRM_MSG_FLUSHSA     = 0x1c
+	XFRM_MSG_FLUSHPOLICY = 0x1d
+	XFRM_MSG_NEWAE       = 0x1e
+	XFRM_MSG_GETAE       = 0x1f
+	XFRM_MSG_REPORT      = 0x20
+	XFRM_MSG_MIGRATE     = 0x21
+	XFRM_MSG_NEWSADINFO  = 0x22
+	XFRM_MSG_GETSADINFO  = 0x23
+	XFRM_MSG_NEWSPDINFO  = 0x24
+	XFRM_MSG_GETSPDINFO  = 0x25
+)
+
+// Netlink attributes for NETLINK_XFRM sockets, from uapi/linux/xfrm.h.
+const (
+	XFRMA_UNSPEC         = 0
+	XFRMA_ALG_AUTH       = 1
+	XFRMA_ALG_CRYPT      = 2
+	XFRMA_ALG_COMP       = 3
+	XFRMA_ENCAP          = 4
+	XFRMA_TMPL           = 5
+	XFRMA_SA             = 6
+	XFRMA_POLICY         = 7
+	XFRMA_SEC_CTX        = 8
+	XFRMA_LTIME_VAL      = 9
+	XFRMA_REPLAY_VAL     = 10
+	XFRMA_REPLAY_THRESH  = 11
+	XFRMA_ETIMER_THRESH  = 12
+	XFRMA_SRCADDR        = 13
+	XFRMA_COADDR         = 14
+	XFRMA_LASTUSED       = 15
+	XFRMA_POLICY_TYPE    = 16
+	XFRMA_MIGRATE        = 17
+	XFRMA_ALG_AEAD       = 18
+	XFRMA_KMADDRESS      = 19
+	XFRMA_ALG_AUTH_TRUNC = 20
+	XFRMA_MARK           = 21
+	XFRMA_TFCPAD         = 22
+	XFRMA_REPLAY_ESN_VAL = 23
+	XFRMA_SA_EXTRA_FLAGS = 24
+	XFRMA_PROTO          = 25
+	XFRMA_ADDRESS_FILTER = 26
+	XFRMA_PAD            = 27
+	XFRMA_OFFLOAD_DEV    = 28
+	XFRMA_SET_MARK       = 29
+	XFRMA_SET_MARK_MASK  = 30
+	XFRMA_IF_ID          = 31
+)
+
+// Security association modes, from uapi/linux/xfrm.h.
+const (
+	XFRM_MODE_TRANSPORT         = 0
+	XFRM_MODE_TUNNEL            = 1
+	XFRM_MODE_ROUTEOPTIMIZATION = 2
+	XFRM_MODE_IN_TRIGGER        = 3
+	XFRM_MODE_BEET              = 4
+)
+
+// Security policy directions and actions, from uapi/linux/xfrm.h.
+const (
+	XFRM_POLICY_IN  = 0
+	XFRM_POLICY_OUT = 1
+	XFRM_POLICY_FWD = 2
+
+	XFRM_POLICY_ALLOW = 0
+	XFRM_POLICY_BLOCK = 1
+)
+
+// Security association flags, from uapi/linux/xfrm.h.
+const (
+	XFRM_STATE_NOECN      = 1
+	XFRM_STATE_DECAP_DSCP = 2
+	XFRM_STATE_NOPMTUDISC = 4
+	XFRM_STATE_WILDRECV   = 8
+	XFRM_STATE_ICMP       = 16
+	XFRM_STATE_AF_UNSPEC  = 32
+	XFRM_STATE_ALIGN4     = 64
+	XFRM_STATE_ESN        = 128
+)
+
+// XFRM_INF is the infinite lifetime limit, from uapi/linux/xfrm.h.
+const XFRM_INF = ^uint64(0)
+
+// IPSEC_PROTO_ANY matches any IPsec protocol, from uapi/linux/ipsec.h.
+const IPSEC_PROTO_ANY = 255
+
+// XFRMSelector is struct xfrm_selector, from uapi/linux/xfrm.h. Ports are in
+// network byte order.
+//
+// +marshal
+type XFRMSelector struct {
+	Daddr      [16]byte
+	Saddr      [16]byte
+	Dport      uint16
+	DportMask  uint16
+	Sport      uint16
+	SportMask  uint16
+	Family     uint16
+	PrefixlenD uint8
+	PrefixlenS uint8
+	Proto      uint8
+	_          [3]uint8
+	Ifindex    int32
+	User       uint32
+}
+
+// XFRMID is struct xfrm_id, from uapi/linux/xfrm.h. SPI is in network byte
+// order.
+//
+// +marshal
+type XFRMID struct {
+	Daddr [16]byte
+	SPI   uint32
+	Proto uint8
+	_     [3]uint8
+}
+
+// XFRMLifetimeCfg is struct xfrm_lifetime_cfg, from uapi/linux/xfrm.h.
+//
+// +marshal
+type XFRMLifetimeCfg struct {
+	SoftByteLimit         uint64
+	HardByteLimit         uint64
+	SoftPacketLimit       uint64
+	HardPacketLimit       uint64
+	SoftAddExpiresSeconds uint64
+	HardAddExpiresSeconds uint64
+	SoftUseExpiresSeconds uint64
+	HardUseExpiresSeconds uint64
+}
+
+// XFRMLifetimeCur is struct xfrm_lifetime_cur, from uapi/linux/xfrm.h.
+//
+// +marshal
+type XFRMLifetimeCur struct {
+	Bytes   uint64
+	Packets uint64
+	AddTime uint64
+	UseTime uint64
+}
+
+// XFRMStats is struct xfrm_stats, from uapi/linux/xfrm.h.
+//
+// +marshal
+type XFRMStats struct {
+	ReplayWindow    uint32
+	Replay          uint32
+	IntegrityFailed uint32
+}
+
+// XFRMUserSAInfo is struct xfrm_usersa_info, from uapi/linux/xfrm.h.
+//
+// +marshal
+type XFRMUserSAInfo struct {
+	Sel          XFRMSelector
+	ID           XFRMID
+	Saddr        [16]byte
+	Lft          XFRMLifetimeCfg
+	Curlft       XFRMLifetimeCur
+	Stats        XFRMStats
+	Seq          uint32
+	ReqID        uint32
+	Family       uint16
+	Mode         uint8
+	ReplayWindow uint8
+	Flags        uint8
+	_            [7]uint8
+}
+
+// XFRMUserSAID is struct xfrm_usersa_id, from uapi/linux/xfrm.h. SPI is in
+// network byte order.
+//
+// +marshal
+type XFRMUserSAID struct {
+	Daddr  [16]byte
+	SPI    uint32
+	Family uint16
+	Proto  uint8
+	_      uint8
+}
+
+// XFRMUserSPIInfo is struct xfrm_userspi_info, from uapi/linux/xfrm.h.
+//
+// +marshal
+type XFRMUserSPIInfo struct {
+	Info XFRMUserSAInfo
+	Min  uint32
+	Max  uint32
+}
+
+// XFRMUserSAFlush is struct xfrm_usersa_flush, from uapi/linux/xfrm.h.
+//
+// +marshal
+type XFRMUserSAFlush struct {
+	Proto uint8
+}
+
+// XFRMUserPolicyInfo is struct xfrm_userpolicy_info, from
+// uapi/linux/xfrm.h.
+//
+// +marshal
+type XFRMUserPolicyInfo struct {
+	Sel      XFRMSelector
+	Lft      XFRMLifetimeCfg
+	Curlft   XFRMLifetimeCur
+	Priority uint32
+	Index    uint32
+	Dir      uint8
+	Action   uint8
+	Flags    uint8
+	Share    uint8
+	_        [4]uint8
+}
+
+// XFRMUserPolicyID is struct xfrm_userpolicy_id, from uapi/linux/xfrm.h.
+//
+// +marshal
+type XFRMUserPolicyID struct {
+	Sel   XFRMSelector
+	Index uint32
+	Dir   uint8
+	_     [3]uint8
+}
+
+// XFRMUserTmpl is struct xfrm_user_tmpl, from uapi/linux/xfrm.h.
+//
+// +marshal
+type XFRMUserTmpl struct {
+	ID       XFRMID
+	Family   uint16
+	_        [2]uint8
+	Saddr    [16]byte
+	ReqID    uint32
+	Mode     uint8
+	Share    uint8
+	Optional uint8
+	_        uint8
+	Aalgos   uint32
+	Ealgos   uint32
+	Calgos   uint32
+}
+
+// XFRMAlgo is struct xfrm_algo, from uapi/linux/xfrm.h, without the key that
+// follows it. KeyLen is in bits.
+//
+// +marshal
+type XFRMAlgo struct {
+	Name   [64]byte
+	KeyLen uint32
+}
+
+// XFRMAlgoAuth is struct xfrm_algo_auth, from uapi/linux/xfrm.h, without the
+// key that follows it. KeyLen and TruncLen are in bits.
+//
+// +marshal
+type XFRMAlgoAuth struct {
+	Name     [64]byte
+	KeyLen   uint32
+	TruncLen uint32
+}
+
+// XFRMAlgoAEAD is struct xfrm_algo_aead, from uapi/linux/xfrm.h, without the
+// key that follows it. KeyLen and ICVLen are in bits.
+//
+// +marshal
+type XFRMAlgoAEAD struct {
+	Name   [64]byte
+	KeyLen uint32
+	ICVLen uint32
+}
+
+// XFRMReplayStateESN is struct xfrm_replay_state_esn, from
+// uapi/linux/xfrm.h, without the bitmap that follows it.
+//
+// +marshal
+type XFRMReplayStateESN struct {
+	BmpLen       uint32
+	OSeq         uint32
+	Seq          uint32
+	OSeqHi       uint32
+	SeqHi        uint32
+	ReplayWindow uint32
+}
+
+// Sizes of the XFRM structures.
+const (
+	SizeOfXFRMSelector       = 56
+	SizeOfXFRMUserSAInfo     = 224
+	SizeOfXFRMUserSAID       = 24
+	SizeOfXFRMUserSPIInfo    = 232
+	SizeOfXFRMUserSAFlush    = 1
+	SizeOfXFRMUserPolicyInfo = 168
+	SizeOfXFRMUserPolicyID   = 64
+	SizeOfXFRMUserTmpl       = 64
+	SizeOfXFRMAlgo           = 68
+	SizeOfXFRMAlgoAuth       = 72
+	SizeOfXFRMAlgoAEAD       = 72
+)
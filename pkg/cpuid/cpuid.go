@@ -116,6 +116,24 @@ func FeatureFromString(s string) (Feature, bool) {
 	return feature, ok
 }
 
+// ParseFeatures parses a comma-separated list of feature names, as they
+// appear in /proc/cpuinfo.
+func ParseFeatures(s string) ([]Feature, error) {
+	var features []Feature
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		feature, ok := FeatureFromString(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown CPU feature %q", name)
+		}
+		features = append(features, feature)
+	}
+	return features, nil
+}
+
 // AllFeatures returns the full set of all possible features.
 func AllFeatures() (features []Feature) {
 	archFlagOrder(func(f Feature) {
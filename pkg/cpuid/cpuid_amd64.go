@@ -308,8 +308,9 @@ func (fs FeatureSet) HasFeature(feature Feature) bool {
 
 // WriteCPUInfoTo is to generate a section of one cpu in /proc/cpuinfo. This is
 // a minimal /proc/cpuinfo, it is missing some fields like "microcode" that are
-// not always printed in Linux. The bogomips field is simply made up.
-func (fs FeatureSet) WriteCPUInfoTo(cpu, numCPU uint, w io.Writer) {
+// not always printed in Linux. The bogomips field is simply made up. t is the
+// topology that cpu belongs to.
+func (fs FeatureSet) WriteCPUInfoTo(cpu uint, t Topology, w io.Writer) {
 	// Avoid many redundant calls here, since this can occasionally appear
 	// in the hot path. Read all basic information up front, see above.
 	ax, _, _, _ := fs.query(featureInfo)
@@ -322,12 +323,16 @@ func (fs FeatureSet) WriteCPUInfoTo(cpu, numCPU uint, w io.Writer) {
 	fmt.Fprintf(w, "model name\t: %s\n", "unknown") // Unknown for now.
 	fmt.Fprintf(w, "stepping\t: %s\n", "unknown")   // Unknown for now.
 	fmt.Fprintf(w, "cpu MHz\t\t: %.3f\n", cpuFreqMHz)
-	fmt.Fprintf(w, "physical id\t: 0\n") // Pretend all CPUs are in the same socket.
-	fmt.Fprintf(w, "siblings\t: %d\n", numCPU)
-	fmt.Fprintf(w, "core id\t\t: %d\n", cpu)
-	fmt.Fprintf(w, "cpu cores\t: %d\n", numCPU) // Pretend each CPU is a distinct core (rather than a hyperthread).
-	fmt.Fprintf(w, "apicid\t\t: %d\n", cpu)
-	fmt.Fprintf(w, "initial apicid\t: %d\n", cpu)
+	if llc, ok := t.LastLevelCache(); ok {
+		fmt.Fprintf(w, "cache size\t: %d KB\n", llc.Size>>10)
+	}
+	socket, core, _ := t.Locate(cpu)
+	fmt.Fprintf(w, "physical id\t: %d\n", socket)
+	fmt.Fprintf(w, "siblings\t: %d\n", t.CoresPerSocket*t.ThreadsPerCore)
+	fmt.Fprintf(w, "core id\t\t: %d\n", core)
+	fmt.Fprintf(w, "cpu cores\t: %d\n", t.CoresPerSocket)
+	fmt.Fprintf(w, "apicid\t\t: %d\n", t.APICID(cpu))
+	fmt.Fprintf(w, "initial apicid\t: %d\n", t.APICID(cpu))
 	fmt.Fprintf(w, "fpu\t\t: yes\n")
 	fmt.Fprintf(w, "fpu_exception\t: yes\n")
 	fmt.Fprintf(w, "cpuid level\t: %d\n", uint32(xSaveInfo)) // Same as ax in vendorID.
@@ -386,6 +391,19 @@ const (
 	XCR0AMXMask = uint64((1 << 17) | (1 << 18))
 )
 
+// xcr0Features maps features to the XCR0 bits that enable the XSAVE state
+// components they use.
+var xcr0Features = map[Feature]uint64{
+	X86FeatureAVX: 1 << 2, // YMM_Hi128.
+	// Opmask, ZMM_Hi256 and Hi16_ZMM.
+	X86FeatureAVX512F: (1 << 5) | (1 << 6) | (1 << 7),
+}
+
+// HWCAP2 bits on x86, from arch/x86/include/uapi/asm/hwcap2.h.
+const (
+	hwCap2FSGSBASE = 1 << 1
+)
+
 // ExtendedStateSize returns the number of bytes needed to save the "extended
 // state" for the enabled features and the boundary it must be aligned to.
 // Extended state includes floating point registers, and other cpu state that's
@@ -454,8 +472,21 @@ func (fs FeatureSet) UseXsavec() bool {
 
 // UseFSGSBASE returns true if 'fs' supports the (RD|WR)(FS|GS)BASE instructions.
 func (fs FeatureSet) UseFSGSBASE() bool {
-	HWCAP2_FSGSBASE := uint64(1) << 1
-	return fs.HasFeature(X86FeatureFSGSBase) && ((fs.hwCap.hwCap2 & HWCAP2_FSGSBASE) != 0)
+	return fs.HasFeature(X86FeatureFSGSBase) && ((fs.hwCap.hwCap2 & hwCap2FSGSBASE) != 0)
+}
+
+// HWCap returns the values of AT_HWCAP and AT_HWCAP2 for applications.
+//
+// As in Linux, AT_HWCAP holds the features in CPUID.01H:EDX. Of the AT_HWCAP2
+// bits, only HWCAP2_FSGSBASE is supported, if the host kernel allows
+// applications to use the FSGSBASE instructions.
+func (fs FeatureSet) HWCap() (hwCap1, hwCap2 uint64) {
+	_, _, _, dx := fs.query(featureInfo)
+	hwCap1 = uint64(dx)
+	if fs.UseFSGSBASE() {
+		hwCap2 |= hwCap2FSGSBASE
+	}
+	return hwCap1, hwCap2
 }
 
 // archCheckHostCompatible checks for compatibility.
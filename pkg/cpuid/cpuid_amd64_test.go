@@ -18,6 +18,7 @@
 package cpuid
 
 import (
+	"encoding/binary"
 	"testing"
 )
 
@@ -110,3 +111,63 @@ func TestRemove(t *testing.T) {
 		t.Errorf("Remove failed, got %q want %q", testFeatures.FlagString(), justFPU.FlagString())
 	}
 }
+
+func TestWithoutFeatures(t *testing.T) {
+	s := makeFeatureSet(X86FeatureFPU, X86FeaturePAE, X86FeatureXSAVE, X86FeatureAVX).Function.(Static)
+	s[In{Eax: uint32(xSaveInfo)}] = Out{Eax: 0x7}
+	fs := FeatureSet{Function: s, hwCap: hwCap{hwCap2: hwCap2FSGSBASE}}
+
+	fs = fs.WithoutFeatures([]Feature{X86FeaturePAE, X86FeatureAVX})
+	if !fs.HasFeature(X86FeatureFPU) || fs.HasFeature(X86FeaturePAE) || fs.HasFeature(X86FeatureAVX) {
+		t.Errorf("got %q, want %q", fs.FlagString(), "fpu xsave")
+	}
+	if got := fs.ValidXCR0Mask(); got != 0x3 {
+		t.Errorf("got XCR0 mask %#x, want 0x3", got)
+	}
+	if fs.hwCap.hwCap2 != hwCap2FSGSBASE {
+		t.Errorf("got HWCAP2 %#x, want %#x", fs.hwCap.hwCap2, hwCap2FSGSBASE)
+	}
+	if hwCap1, _ := fs.HWCap(); hwCap1 != uint64(X86FeatureFPU.bit()) {
+		t.Errorf("got HWCAP %#x, want %#x", hwCap1, X86FeatureFPU.bit())
+	}
+}
+
+func TestWithTopology(t *testing.T) {
+	s := makeFeatureSet(X86FeatureFPU).Function.(Static)
+	s[In{Eax: uint32(vendorID)}] = Out{
+		Eax: uint32(xSaveInfo),
+		Ebx: binary.LittleEndian.Uint32([]byte("Genu")),
+		Edx: binary.LittleEndian.Uint32([]byte("ineI")),
+		Ecx: binary.LittleEndian.Uint32([]byte("ntel")),
+	}
+	out := s[In{Eax: uint32(featureInfo)}]
+	out.Ebx |= 8 << 8 // 64-byte cache lines.
+	s[In{Eax: uint32(featureInfo)}] = out
+	fs := FeatureSet{Function: s}
+
+	topo, err := ParseTopology("sockets=2,threads=2", 8)
+	if err != nil {
+		t.Fatalf("ParseTopology failed: %v", err)
+	}
+	fs = fs.WithTopology(topo)
+
+	if !fs.HasFeature(X86FeatureHTT) {
+		t.Errorf("HTT not set")
+	}
+	if got := (fs.Query(In{Eax: uint32(featureInfo)}).Ebx >> 16) & 0xff; got != 4 {
+		t.Errorf("got %d logical processors per package, want 4", got)
+	}
+	if got := fs.Query(In{Eax: uint32(intelX2APICInfo), Ecx: 1}).Ebx; got != 4 {
+		t.Errorf("got %d logical processors at core level, want 4", got)
+	}
+	caches := fs.Caches()
+	if len(caches) != len(topo.Caches) {
+		t.Fatalf("got %d caches, want %d", len(caches), len(topo.Caches))
+	}
+	for i, c := range caches {
+		want := topo.Caches[i]
+		if uint(c.Level) != want.Level || uint(c.Ways) != want.Ways || uint64(c.Sets) != want.Sets() {
+			t.Errorf("cache %d: got %+v, want %+v", i, c, want)
+		}
+	}
+}
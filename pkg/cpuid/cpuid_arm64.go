@@ -90,9 +90,52 @@ func (fs FeatureSet) HasFeature(feature Feature) bool {
 	return fs.hwCap.hwCap1&(1<<feature) != 0
 }
 
+// WithoutFeatures returns a copy of fs in which features are not present.
+func (fs FeatureSet) WithoutFeatures(features []Feature) FeatureSet {
+	for _, feature := range features {
+		fs.hwCap.hwCap1 &^= 1 << feature
+	}
+	return fs
+}
+
+// hwCap2Supported are the HWCAP2 bits, from
+// arch/arm64/include/uapi/asm/hwcap.h, of features that don't need any state
+// or interfaces besides those supported by the sentry. In particular, SVE and
+// SME state isn't saved in signal frames, and BTI and MTE need mmap/mprotect
+// flags that aren't supported.
+const hwCap2Supported = 1<<0 | // DCPODP
+	1<<7 | // FLAGM2
+	1<<8 | // FRINT
+	1<<13 | // I8MM
+	1<<14 | // BF16
+	1<<15 | // DGH
+	1<<16 | // RNG
+	1<<19 | // ECV
+	1<<20 | // AFP
+	1<<21 | // RPRES
+	1<<31 | // WFXT
+	1<<32 | // EBF16
+	1<<34 | // CSSC
+	1<<35 | // RPRFM
+	1<<43 | // MOPS
+	1<<44 // HBC
+
+// HWCap returns the values of AT_HWCAP and AT_HWCAP2 for applications.
+//
+// SVE is never reported, since its state isn't saved in signal frames.
+func (fs FeatureSet) HWCap() (hwCap1, hwCap2 uint64) {
+	return fs.hwCap.hwCap1 &^ (1 << ARM64FeatureSVE), fs.hwCap.hwCap2 & hwCap2Supported
+}
+
+// WithTopology returns fs, since the topology isn't described by the feature
+// set on arm64.
+func (fs FeatureSet) WithTopology(Topology) FeatureSet {
+	return fs
+}
+
 // WriteCPUInfoTo is to generate a section of one cpu in /proc/cpuinfo. This is
 // a minimal /proc/cpuinfo, and the bogomips field is simply made up.
-func (fs FeatureSet) WriteCPUInfoTo(cpu, numCPU uint, w io.Writer) {
+func (fs FeatureSet) WriteCPUInfoTo(cpu uint, _ Topology, w io.Writer) {
 	fmt.Fprintf(w, "processor\t: %d\n", cpu)
 	fmt.Fprintf(w, "BogoMIPS\t: %.02f\n", fs.cpuFreqMHz) // It's bogus anyway.
 	fmt.Fprintf(w, "Features\t\t: %s\n", fs.FlagString())
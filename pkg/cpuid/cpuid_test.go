@@ -37,6 +37,21 @@ func TestFeatureFromString(t *testing.T) {
 	}
 }
 
+func TestParseFeatures(t *testing.T) {
+	features := AllFeatures()[:2]
+	got, err := ParseFeatures(" " + features[0].String() + ",," + features[1].String() + " ")
+	if err != nil {
+		t.Fatalf("ParseFeatures failed: %v", err)
+	}
+	if len(got) != 2 || got[0] != features[0] || got[1] != features[1] {
+		t.Errorf("got %v, want %v", got, features)
+	}
+
+	if got, err := ParseFeatures("bad"); err == nil {
+		t.Errorf("got %v, nil want error", got)
+	}
+}
+
 func TestReadHwCap(t *testing.T) {
 	// Make an auxv with fake entries
 	const (
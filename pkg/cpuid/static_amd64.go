@@ -26,7 +26,28 @@ type Static map[In]Out
 
 // Fixed converts the FeatureSet to a fixed set.
 func (fs FeatureSet) Fixed() FeatureSet {
-	return fs.ToStatic().ToFeatureSet()
+	nfs := fs.ToStatic().ToFeatureSet()
+	nfs.hwCap = fs.hwCap
+	return nfs
+}
+
+// WithoutFeatures returns a static copy of fs in which features are not
+// present. The XSAVE state components of masked vector extensions are also
+// removed from the valid XCR0 bits.
+func (fs FeatureSet) WithoutFeatures(features []Feature) FeatureSet {
+	s := fs.ToStatic()
+	for _, feature := range features {
+		feature.set(s, false)
+		if mask, ok := xcr0Features[feature]; ok {
+			in := In{Eax: uint32(xSaveInfo)}
+			out := s[in]
+			out.Eax &^= uint32(mask)
+			s[in] = out
+		}
+	}
+	nfs := s.ToFeatureSet()
+	nfs.hwCap = fs.hwCap
+	return nfs
 }
 
 // ToStatic converts a FeatureSet to a Static function.
@@ -0,0 +1,311 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cpuid
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Topology describes the CPU topology presented to applications: the
+// arrangement of logical CPUs into sockets, cores and hardware threads, the
+// NUMA nodes that they belong to, and the caches that they share.
+//
+// Logical CPUs are numbered as on most Linux x86 systems: CPUs
+// [0, Sockets*CoresPerSocket) are the first hardware threads of each core,
+// ordered by socket and then by core, followed by the second hardware
+// threads of each core, and so on.
+//
+// +stateify savable
+type Topology struct {
+	// Sockets is the number of physical packages.
+	Sockets uint
+
+	// CoresPerSocket is the number of cores in each socket.
+	CoresPerSocket uint
+
+	// ThreadsPerCore is the number of hardware threads in each core.
+	ThreadsPerCore uint
+
+	// NUMANodes is the number of NUMA nodes. Cores are divided evenly
+	// between nodes in order.
+	NUMANodes uint
+
+	// Caches describes the caches of each core, from the lowest level up.
+	// If Caches is empty, cache information is not emulated.
+	Caches []TopologyCache
+}
+
+// TopologyCache describes a cache in a Topology.
+//
+// +stateify savable
+type TopologyCache struct {
+	// Level is the cache level, starting at 1.
+	Level uint
+
+	// Type is "Data", "Instruction" or "Unified", as in
+	// /sys/devices/system/cpu/cpu*/cache/index*/type.
+	Type string
+
+	// Size is the size of the cache in bytes.
+	Size uint64
+
+	// Ways is the number of ways of associativity.
+	Ways uint
+
+	// LineSize is the size of a cache line in bytes.
+	LineSize uint
+
+	// PerSocket is true if the cache is shared by all CPUs in a socket,
+	// rather than by the hardware threads of a single core.
+	PerSocket bool
+}
+
+// Sets returns the number of sets in the cache.
+func (c TopologyCache) Sets() uint64 {
+	return c.Size / uint64(c.Ways*c.LineSize)
+}
+
+// DefaultTopology returns the topology presented to applications when none
+// is configured: a single socket and NUMA node containing numCPU cores
+// without hardware threads, and no cache information.
+func DefaultTopology(numCPU uint) Topology {
+	return Topology{
+		Sockets:        1,
+		CoresPerSocket: numCPU,
+		ThreadsPerCore: 1,
+		NUMANodes:      1,
+	}
+}
+
+// defaultTopologyCaches are the caches of topologies parsed by
+// ParseTopology, unless overridden.
+var defaultTopologyCaches = []TopologyCache{
+	{Level: 1, Type: "Data", Size: 32 << 10, Ways: 8, LineSize: 64},
+	{Level: 1, Type: "Instruction", Size: 32 << 10, Ways: 8, LineSize: 64},
+	{Level: 2, Type: "Unified", Size: 1 << 20, Ways: 16, LineSize: 64},
+	{Level: 3, Type: "Unified", Size: 16 << 20, Ways: 16, LineSize: 64, PerSocket: true},
+}
+
+// ParseTopology parses a topology with numCPU logical CPUs from spec, a
+// comma-separated list of key=value pairs. Valid keys are:
+//
+//   - sockets, cores, threads: the number of sockets, cores per socket and
+//     hardware threads per core. sockets and threads default to 1, and cores
+//     defaults to the value that gives numCPU logical CPUs.
+//   - numa: the number of NUMA nodes, which defaults to 1.
+//   - l1d, l1i, l2, l3: the size of each cache, with an optional K, M or G
+//     suffix. L1 and L2 caches are per core, and L3 caches are per socket. A
+//     size of 0 omits the cache.
+func ParseTopology(spec string, numCPU uint) (Topology, error) {
+	t := Topology{
+		Sockets:        1,
+		ThreadsPerCore: 1,
+		NUMANodes:      1,
+		Caches:         append([]TopologyCache(nil), defaultTopologyCaches...),
+	}
+	cacheIndex := map[string]int{"l1d": 0, "l1i": 1, "l2": 2, "l3": 3}
+	for _, kv := range strings.Split(spec, ",") {
+		if kv == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(kv, "=")
+		if !ok {
+			return Topology{}, fmt.Errorf("invalid topology option %q, want key=value", kv)
+		}
+		if i, ok := cacheIndex[key]; ok {
+			size, err := parseCacheSize(val)
+			if err != nil {
+				return Topology{}, fmt.Errorf("invalid %s size %q: %w", key, val, err)
+			}
+			t.Caches[i].Size = size
+			continue
+		}
+		n, err := strconv.ParseUint(val, 10, 32)
+		if err != nil || n == 0 {
+			return Topology{}, fmt.Errorf("invalid %s %q, want a positive integer", key, val)
+		}
+		switch key {
+		case "sockets":
+			t.Sockets = uint(n)
+		case "cores":
+			t.CoresPerSocket = uint(n)
+		case "threads":
+			t.ThreadsPerCore = uint(n)
+		case "numa":
+			t.NUMANodes = uint(n)
+		default:
+			return Topology{}, fmt.Errorf("unknown topology option %q", key)
+		}
+	}
+	if t.CoresPerSocket == 0 {
+		t.CoresPerSocket = numCPU / (t.Sockets * t.ThreadsPerCore)
+	}
+	caches := t.Caches[:0]
+	for _, c := range t.Caches {
+		if c.Size != 0 {
+			caches = append(caches, c)
+		}
+	}
+	t.Caches = caches
+	if err := t.Validate(numCPU); err != nil {
+		return Topology{}, err
+	}
+	return t, nil
+}
+
+func parseCacheSize(s string) (uint64, error) {
+	shift := 0
+	switch {
+	case strings.HasSuffix(s, "K"):
+		shift = 10
+	case strings.HasSuffix(s, "M"):
+		shift = 20
+	case strings.HasSuffix(s, "G"):
+		shift = 30
+	}
+	if shift != 0 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n > (1<<64-1)>>shift {
+		return 0, fmt.Errorf("size too large")
+	}
+	return n << shift, nil
+}
+
+// Validate returns an error if t is inconsistent or doesn't have numCPU
+// logical CPUs.
+func (t Topology) Validate(numCPU uint) error {
+	if t.Sockets == 0 || t.CoresPerSocket == 0 || t.ThreadsPerCore == 0 || t.NUMANodes == 0 {
+		return fmt.Errorf("topology %+v has no CPUs", t)
+	}
+	if n := t.NumCPUs(); n != numCPU {
+		return fmt.Errorf("topology with %d sockets, %d cores per socket and %d threads per core has %d CPUs, want %d", t.Sockets, t.CoresPerSocket, t.ThreadsPerCore, n, numCPU)
+	}
+	if cores := t.Sockets * t.CoresPerSocket; cores%t.NUMANodes != 0 {
+		return fmt.Errorf("%d cores can't be divided evenly between %d NUMA nodes", cores, t.NUMANodes)
+	}
+	for _, c := range t.Caches {
+		if c.Level == 0 || c.Ways == 0 || c.LineSize == 0 || c.Size%uint64(c.Ways*c.LineSize) != 0 {
+			return fmt.Errorf("invalid L%d %s cache size %d, must be a multiple of %d", c.Level, strings.ToLower(c.Type), c.Size, c.Ways*c.LineSize)
+		}
+	}
+	return nil
+}
+
+// NumCPUs returns the number of logical CPUs in t.
+func (t Topology) NumCPUs() uint {
+	return t.Sockets * t.CoresPerSocket * t.ThreadsPerCore
+}
+
+// Locate returns the socket, core within the socket, and hardware thread
+// within the core of the given logical CPU.
+func (t Topology) Locate(cpu uint) (socket, core, thread uint) {
+	cores := t.Sockets * t.CoresPerSocket
+	thread = cpu / cores
+	socket = (cpu % cores) / t.CoresPerSocket
+	core = cpu % t.CoresPerSocket
+	return
+}
+
+// logicalCPU returns the logical CPU for the given location.
+func (t Topology) logicalCPU(socket, core, thread uint) uint {
+	return thread*t.Sockets*t.CoresPerSocket + socket*t.CoresPerSocket + core
+}
+
+// Node returns the NUMA node containing the given logical CPU.
+func (t Topology) Node(cpu uint) uint {
+	cores := t.Sockets * t.CoresPerSocket
+	return (cpu % cores) / (cores / t.NUMANodes)
+}
+
+// threadIDBits and coreIDBits return the widths of the fields of APIC IDs
+// that identify hardware threads and cores respectively.
+func (t Topology) threadIDBits() uint {
+	return uint(bits.Len(t.ThreadsPerCore - 1))
+}
+
+func (t Topology) coreIDBits() uint {
+	return uint(bits.Len(t.CoresPerSocket - 1))
+}
+
+// APICID returns the APIC ID of the given logical CPU.
+func (t Topology) APICID(cpu uint) uint32 {
+	socket, core, thread := t.Locate(cpu)
+	return uint32(socket<<(t.coreIDBits()+t.threadIDBits()) | core<<t.threadIDBits() | thread)
+}
+
+// ThreadSiblings returns the logical CPUs in the same core as cpu, including
+// cpu, in increasing order.
+func (t Topology) ThreadSiblings(cpu uint) []uint {
+	socket, core, _ := t.Locate(cpu)
+	cpus := make([]uint, 0, t.ThreadsPerCore)
+	for thread := uint(0); thread < t.ThreadsPerCore; thread++ {
+		cpus = append(cpus, t.logicalCPU(socket, core, thread))
+	}
+	return cpus
+}
+
+// CoreSiblings returns the logical CPUs in the same socket as cpu, including
+// cpu, in increasing order.
+func (t Topology) CoreSiblings(cpu uint) []uint {
+	socket, _, _ := t.Locate(cpu)
+	cpus := make([]uint, 0, t.CoresPerSocket*t.ThreadsPerCore)
+	for thread := uint(0); thread < t.ThreadsPerCore; thread++ {
+		for core := uint(0); core < t.CoresPerSocket; core++ {
+			cpus = append(cpus, t.logicalCPU(socket, core, thread))
+		}
+	}
+	return cpus
+}
+
+// NodeCPUs returns the logical CPUs in the given NUMA node in increasing
+// order.
+func (t Topology) NodeCPUs(node uint) []uint {
+	var cpus []uint
+	for cpu := uint(0); cpu < t.NumCPUs(); cpu++ {
+		if t.Node(cpu) == node {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus
+}
+
+// SharedCPUs returns the logical CPUs that share cache c with cpu, including
+// cpu, in increasing order.
+func (t Topology) SharedCPUs(cpu uint, c TopologyCache) []uint {
+	if c.PerSocket {
+		return t.CoreSiblings(cpu)
+	}
+	return t.ThreadSiblings(cpu)
+}
+
+// LastLevelCache returns the cache with the highest level in t, or false if
+// t has no caches.
+func (t Topology) LastLevelCache() (TopologyCache, bool) {
+	var llc TopologyCache
+	for _, c := range t.Caches {
+		if c.Level > llc.Level {
+			llc = c
+		}
+	}
+	return llc, llc.Level != 0
+}
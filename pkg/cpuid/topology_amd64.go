@@ -0,0 +1,173 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build amd64
+// +build amd64
+
+package cpuid
+
+// WithTopology returns a static copy of fs in which the CPUID leaves that
+// describe the processor topology and caches are consistent with t.
+//
+// Since the result of CPUID doesn't depend on the CPU that executes it, the
+// APIC IDs reported by CPUID are always those of CPU 0.
+func (fs FeatureSet) WithTopology(t Topology) FeatureSet {
+	s := fs.ToStatic()
+	threadBits := uint32(t.threadIDBits())
+	coreBits := uint32(t.coreIDBits())
+	perSocket := uint32(t.CoresPerSocket * t.ThreadsPerCore)
+
+	// The extended topology leaf must be enumerated.
+	in := In{Eax: uint32(vendorID)}
+	out := s[in]
+	if out.Eax < uint32(intelX2APICInfo) {
+		out.Eax = uint32(intelX2APICInfo)
+		s[in] = out
+	}
+
+	// Logical processors per package.
+	in = In{Eax: uint32(featureInfo)}
+	out = s[in]
+	out.Ebx = out.Ebx&^0xffff0000 | min(perSocket, 0xff)<<16
+	s[in] = out
+	X86FeatureHTT.set(s, perSocket > 1)
+
+	// Extended topology enumeration: SMT and core levels.
+	s.deleteLeaf(intelX2APICInfo)
+	s[In{Eax: uint32(intelX2APICInfo), Ecx: 0}] = Out{
+		Eax: threadBits,
+		Ebx: min(uint32(t.ThreadsPerCore), 0xffff),
+		Ecx: 1 << 8,
+	}
+	s[In{Eax: uint32(intelX2APICInfo), Ecx: 1}] = Out{
+		Eax: coreBits + threadBits,
+		Ebx: min(perSocket, 0xffff),
+		Ecx: 1 | 2<<8,
+	}
+	s[In{Eax: uint32(intelX2APICInfo), Ecx: 2}] = Out{Ecx: 2}
+
+	if fs.AMD() {
+		// Core count and APIC ID size. The topology extension leaves are
+		// not emulated.
+		in := In{Eax: uint32(addressSizes)}
+		out := s[in]
+		out.Ecx = out.Ecx&^0xf0ff | (coreBits+threadBits)<<12 | (min(perSocket, 0x100) - 1)
+		s[in] = out
+		X86FeatureTOPOLOGY.set(s, false)
+	}
+
+	if len(t.Caches) != 0 {
+		switch {
+		case fs.Intel():
+			s.setIntelCaches(t)
+		case fs.AMD():
+			s.setAMDCaches(t)
+		}
+	}
+
+	nfs := s.ToFeatureSet()
+	nfs.hwCap = fs.hwCap
+	return nfs
+}
+
+// deleteLeaf deletes all subleaves of fn.
+func (s Static) deleteLeaf(fn cpuidFunction) {
+	for in := range s {
+		if in.Eax == fn.eax() {
+			delete(s, in)
+		}
+	}
+}
+
+// setIntelCaches replaces the deterministic cache parameters with the caches
+// of t.
+func (s Static) setIntelCaches(t Topology) {
+	// Descriptor 0xff directs software to the deterministic cache
+	// parameters.
+	s[In{Eax: uint32(intelCacheDescriptors)}] = Out{Eax: 0xff01}
+
+	threadBits := t.threadIDBits()
+	coreBits := t.coreIDBits()
+	s.deleteLeaf(intelDeterministicCacheParams)
+	for i, c := range t.Caches {
+		sharingBits := threadBits
+		if c.PerSocket {
+			sharingBits += coreBits
+		}
+		var typ CacheType
+		switch c.Type {
+		case "Data":
+			typ = CacheData
+		case "Instruction":
+			typ = CacheInstruction
+		default:
+			typ = CacheUnified
+		}
+		s[In{Eax: uint32(intelDeterministicCacheParams), Ecx: uint32(i)}] = Out{
+			// Self-initializing cache of the given type and level.
+			Eax: uint32(typ) | uint32(c.Level&0x7)<<5 | 1<<8 |
+				min(uint32(1)<<sharingBits-1, 0xfff)<<14 |
+				min(uint32(1)<<coreBits-1, 0x3f)<<26,
+			Ebx: uint32(c.LineSize-1)&0xfff | min(uint32(c.Ways-1), 0x3ff)<<22,
+			Ecx: uint32(c.Sets() - 1),
+		}
+	}
+}
+
+// setAMDCaches replaces the legacy L1, L2 and L3 cache identifiers with the
+// caches of t.
+func (s Static) setAMDCaches(t Topology) {
+	var l1d, l1i, l2, l3 Out
+	for _, c := range t.Caches {
+		switch {
+		case c.Level == 1 && c.Type == "Data":
+			l1d.Ecx = min(uint32(c.Size>>10), 0xff)<<24 | min(uint32(c.Ways), 0xff)<<16 | 1<<8 | uint32(c.LineSize)&0xff
+		case c.Level == 1 && c.Type == "Instruction":
+			l1i.Edx = min(uint32(c.Size>>10), 0xff)<<24 | min(uint32(c.Ways), 0xff)<<16 | 1<<8 | uint32(c.LineSize)&0xff
+		case c.Level == 2:
+			l2.Ecx = min(uint32(c.Size>>10), 0xffff)<<16 | amdAssociativity(c.Ways)<<12 | 1<<8 | uint32(c.LineSize)&0xff
+		case c.Level == 3:
+			l3.Edx = min(uint32(c.Size>>19), 0x3fff)<<18 | amdAssociativity(c.Ways)<<12 | 1<<8 | uint32(c.LineSize)&0xff
+		}
+	}
+	in := In{Eax: uint32(l1CacheAndTLBInfo)}
+	out := s[in]
+	out.Ecx = l1d.Ecx
+	out.Edx = l1i.Edx
+	s[in] = out
+	in = In{Eax: uint32(l2CacheInfo)}
+	out = s[in]
+	out.Ecx = l2.Ecx
+	out.Edx = l3.Edx
+	s[in] = out
+}
+
+// amdAssociativity returns the encoding of the given number of ways used by
+// the L2 and L3 cache identifiers, rounding up to the nearest encodable
+// value.
+func amdAssociativity(ways uint) uint32 {
+	for _, a := range []struct {
+		ways uint
+		enc  uint32
+	}{
+		{1, 0x1}, {2, 0x2}, {3, 0x3}, {4, 0x4}, {6, 0x5}, {8, 0x6}, {16, 0x8},
+		{32, 0xa}, {48, 0xb}, {64, 0xc}, {96, 0xd}, {128, 0xe},
+	} {
+		if ways <= a.ways {
+			return a.enc
+		}
+	}
+	// Fully associative.
+	return 0xf
+}
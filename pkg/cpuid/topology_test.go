@@ -0,0 +1,116 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cpuid
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTopology(t *testing.T) {
+	for _, tc := range []struct {
+		spec    string
+		numCPU  uint
+		want    Topology
+		wantErr bool
+	}{
+		{
+			spec:   "",
+			numCPU: 4,
+			want:   Topology{Sockets: 1, CoresPerSocket: 4, ThreadsPerCore: 1, NUMANodes: 1, Caches: defaultTopologyCaches},
+		},
+		{
+			spec:   "sockets=2,threads=2,numa=2,l3=0",
+			numCPU: 8,
+			want:   Topology{Sockets: 2, CoresPerSocket: 2, ThreadsPerCore: 2, NUMANodes: 2, Caches: defaultTopologyCaches[:3]},
+		},
+		{
+			spec:    "cores=3",
+			numCPU:  4,
+			wantErr: true,
+		},
+		{
+			spec:    "threads=2",
+			numCPU:  3,
+			wantErr: true,
+		},
+		{
+			spec:    "numa=3",
+			numCPU:  4,
+			wantErr: true,
+		},
+		{
+			spec:    "l2=1000",
+			numCPU:  4,
+			wantErr: true,
+		},
+		{
+			spec:    "dies=2",
+			numCPU:  4,
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			got, err := ParseTopology(tc.spec, tc.numCPU)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTopology(%q, %d) = %+v, want error", tc.spec, tc.numCPU, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTopology(%q, %d) failed: %v", tc.spec, tc.numCPU, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ParseTopology(%q, %d) = %+v, want %+v", tc.spec, tc.numCPU, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTopologyLayout(t *testing.T) {
+	// 2 sockets with 2 cores each, 2 threads per core, and a NUMA node per
+	// socket.
+	topo := Topology{Sockets: 2, CoresPerSocket: 2, ThreadsPerCore: 2, NUMANodes: 2}
+	for _, tc := range []struct {
+		cpu                  uint
+		socket, core, thread uint
+		apicID               uint32
+		threadSiblings       []uint
+		coreSiblings         []uint
+		node                 uint
+	}{
+		{cpu: 0, socket: 0, core: 0, thread: 0, apicID: 0, threadSiblings: []uint{0, 4}, coreSiblings: []uint{0, 1, 4, 5}, node: 0},
+		{cpu: 3, socket: 1, core: 1, thread: 0, apicID: 6, threadSiblings: []uint{3, 7}, coreSiblings: []uint{2, 3, 6, 7}, node: 1},
+		{cpu: 5, socket: 0, core: 1, thread: 1, apicID: 3, threadSiblings: []uint{1, 5}, coreSiblings: []uint{0, 1, 4, 5}, node: 0},
+	} {
+		socket, core, thread := topo.Locate(tc.cpu)
+		if socket != tc.socket || core != tc.core || thread != tc.thread {
+			t.Errorf("Locate(%d) = %d, %d, %d, want %d, %d, %d", tc.cpu, socket, core, thread, tc.socket, tc.core, tc.thread)
+		}
+		if got := topo.APICID(tc.cpu); got != tc.apicID {
+			t.Errorf("APICID(%d) = %d, want %d", tc.cpu, got, tc.apicID)
+		}
+		if got := topo.ThreadSiblings(tc.cpu); !reflect.DeepEqual(got, tc.threadSiblings) {
+			t.Errorf("ThreadSiblings(%d) = %v, want %v", tc.cpu, got, tc.threadSiblings)
+		}
+		if got := topo.CoreSiblings(tc.cpu); !reflect.DeepEqual(got, tc.coreSiblings) {
+			t.Errorf("CoreSiblings(%d) = %v, want %v", tc.cpu, got, tc.coreSiblings)
+		}
+		if got := topo.Node(tc.cpu); got != tc.node {
+			t.Errorf("Node(%d) = %d, want %d", tc.cpu, got, tc.node)
+		}
+	}
+}
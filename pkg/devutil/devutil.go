@@ -23,6 +23,7 @@ import (
 	"gvisor.dev/gvisor/pkg/fsutil"
 	"gvisor.dev/gvisor/pkg/lisafs"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/unet"
 )
 
@@ -31,6 +32,13 @@ type GoferClient struct {
 	clientFD lisafs.ClientFD
 	hostFD   int
 	contName string
+
+	// devicesMu protects devices.
+	devicesMu sync.Mutex
+
+	// devices maps the names of device files added at runtime with AddDevice
+	// to the root of the connection serving each of them.
+	devices map[string]lisafs.ClientFD
 }
 
 // NewGoferClient establishes the LISAFS connection to the dev gofer server.
@@ -63,6 +71,74 @@ func (g *GoferClient) Close() {
 	if g.hostFD >= 0 {
 		_ = unix.Close(g.hostFD)
 	}
+	g.devicesMu.Lock()
+	defer g.devicesMu.Unlock()
+	for name, deviceFD := range g.devices {
+		deviceFD.Client().Close()
+		delete(g.devices, name)
+	}
+}
+
+// AddDevice makes the device file served by the gofer on the LISAFS
+// connection fd available as /dev/{name}, in addition to the device files in
+// the gofer's /dev. It takes ownership of fd.
+func (g *GoferClient) AddDevice(ctx context.Context, name string, fd int) error {
+	g.devicesMu.Lock()
+	defer g.devicesMu.Unlock()
+	if _, ok := g.devices[name]; ok {
+		_ = unix.Close(fd)
+		return fmt.Errorf("device %q already added", name)
+	}
+
+	ctx.UninterruptibleSleepStart(false)
+	defer ctx.UninterruptibleSleepFinish(false)
+
+	sock, err := unet.NewSocket(fd)
+	if err != nil {
+		_ = unix.Close(fd)
+		return fmt.Errorf("creating socket for device %q: %w", name, err)
+	}
+	client, devInode, devHostFD, err := lisafs.NewClient(sock)
+	if err != nil {
+		return fmt.Errorf("creating gofer client for device %q: %w", name, err)
+	}
+	// Devices are always opened through the gofer, which donates the opened
+	// host FD.
+	if devHostFD >= 0 {
+		_ = unix.Close(devHostFD)
+	}
+	if devInode.Stat.Mode&unix.S_IFMT != unix.S_IFCHR {
+		client.Close()
+		return fmt.Errorf("%q is not a character device", name)
+	}
+	if g.devices == nil {
+		g.devices = make(map[string]lisafs.ClientFD)
+	}
+	g.devices[name] = client.NewFD(devInode.ControlFD)
+	return nil
+}
+
+// RemoveDevice removes a device file added with AddDevice. Files already
+// opened from it are not affected.
+func (g *GoferClient) RemoveDevice(name string) error {
+	g.devicesMu.Lock()
+	defer g.devicesMu.Unlock()
+	deviceFD, ok := g.devices[name]
+	if !ok {
+		return fmt.Errorf("device %q was not added", name)
+	}
+	deviceFD.Client().Close()
+	delete(g.devices, name)
+	return nil
+}
+
+// device returns the root of the connection serving the device file added
+// with AddDevice with the given name.
+func (g *GoferClient) device(name string) (lisafs.ClientFD, bool) {
+	g.devicesMu.Lock()
+	defer g.devicesMu.Unlock()
+	deviceFD, ok := g.devices[name]
+	return deviceFD, ok
 }
 
 // ContainerName returns the name of the container that owns this gofer.
@@ -70,7 +146,8 @@ func (g *GoferClient) ContainerName() string {
 	return g.contName
 }
 
-// DirentNames returns names of all the dirents for /dev on the gofer.
+// DirentNames returns names of all the dirents for /dev on the gofer. Device
+// files added with AddDevice are not included.
 func (g *GoferClient) DirentNames(ctx context.Context) ([]string, error) {
 	if g.hostFD >= 0 {
 		return fsutil.DirentNames(g.hostFD)
@@ -102,6 +179,17 @@ func (g *GoferClient) DirentNames(ctx context.Context) ([]string, error) {
 // OpenAt opens the device file at /dev/{name} on the gofer.
 func (g *GoferClient) OpenAt(ctx context.Context, name string, flags uint32) (int, error) {
 	flags &= unix.O_ACCMODE
+	if deviceFD, ok := g.device(name); ok {
+		openFD, hostFD, err := deviceFD.OpenAt(ctx, flags)
+		if err != nil {
+			return 0, err
+		}
+		deviceFD.Client().CloseFD(ctx, openFD, true /* flush */)
+		if hostFD < 0 {
+			return 0, unix.EIO
+		}
+		return hostFD, nil
+	}
 	if g.hostFD >= 0 {
 		return unix.Openat(g.hostFD, name, int(flags|unix.O_NOFOLLOW), 0)
 	}
@@ -17,7 +17,9 @@
 // The design principle of this package is that, it will just provide the ability
 // to access the contents in the image, and it will never cache any objects internally.
 // The whole disk image is mapped via a read-only/shared mapping, and it relies on
-// host kernel to cache the blocks/pages transparently.
+// host kernel to cache the blocks/pages transparently. Optionally, blocks can be
+// verified against a Merkle tree appended to the image when they are first
+// accessed; see Image.EnableVerity.
 //
 // [1] https://docs.kernel.org/filesystems/erofs.html
 package erofs
@@ -207,6 +209,9 @@ type Image struct {
 	src   *os.File `state:"nosave"`
 	bytes []byte   `state:"nosave"`
 	sb    SuperBlock
+
+	// verity verifies the image if EnableVerity has been called.
+	verity *verityTree `state:"nosave"`
 }
 
 // OpenImage returns an Image providing access to the contents in the image file src.
@@ -319,6 +324,10 @@ func (i *Image) FD() int {
 // checkRange checks whether the range [off, off+n) is valid.
 func (i *Image) checkRange(off, n uint64) bool {
 	size := uint64(len(i.bytes))
+	if i.verity != nil {
+		// Don't allow access to the Merkle tree.
+		size = i.verity.dataSize
+	}
 	end := off + n
 	return off < size && off <= end && end <= size
 }
@@ -329,6 +338,9 @@ func (i *Image) BytesAt(off, n uint64) ([]byte, error) {
 		log.Warningf("Invalid byte range (off: 0x%x, n: 0x%x) for image (size: 0x%x)", off, n, len(i.bytes))
 		return nil, linuxerr.EFAULT
 	}
+	if err := i.verify(off, n); err != nil {
+		return nil, err
+	}
 	return i.bytes[off : off+n], nil
 }
 
@@ -348,6 +360,9 @@ func (i *Image) inodeFormatAt(off uint64) (uint16, error) {
 	if ok := i.checkRange(off, 2); !ok {
 		return 0, linuxerr.EFAULT
 	}
+	if err := i.verify(off, 2); err != nil {
+		return 0, err
+	}
 	return *(*uint16)(i.pointerAt(off)), nil
 }
 
@@ -360,6 +375,9 @@ func (i *Image) inodeCompactAt(off uint64) (*InodeCompact, error) {
 	if ok := i.checkRange(off, InodeCompactSize); !ok {
 		return nil, linuxerr.EFAULT
 	}
+	if err := i.verify(off, InodeCompactSize); err != nil {
+		return nil, err
+	}
 	return (*InodeCompact)(i.pointerAt(off)), nil
 }
 
@@ -372,6 +390,9 @@ func (i *Image) inodeExtendedAt(off uint64) (*InodeExtended, error) {
 	if ok := i.checkRange(off, InodeExtendedSize); !ok {
 		return nil, linuxerr.EFAULT
 	}
+	if err := i.verify(off, InodeExtendedSize); err != nil {
+		return nil, err
+	}
 	return (*InodeExtended)(i.pointerAt(off)), nil
 }
 
@@ -385,6 +406,9 @@ func (i *Image) direntAt(off uint64) (*Dirent, error) {
 	if ok := i.checkRange(off, DirentSize); !ok {
 		return nil, linuxerr.EFAULT
 	}
+	if err := i.verify(off, DirentSize); err != nil {
+		return nil, err
+	}
 	return (*Dirent)(i.pointerAt(off)), nil
 }
 
@@ -633,9 +657,18 @@ func (i *Inode) DataOffset() (uint64, error) {
 
 // Data returns the read-only file data of this inode.
 func (i *Inode) Data() (safemem.BlockSeq, error) {
+	return i.DataRange(0, i.size)
+}
+
+// DataRange returns the read-only file data of this inode in the range
+// [off, off+n), which must be within the file.
+func (i *Inode) DataRange(off, n uint64) (safemem.BlockSeq, error) {
+	if off > i.size || n > i.size-off {
+		return safemem.BlockSeq{}, linuxerr.EFAULT
+	}
 	switch dataLayout := i.DataLayout(); dataLayout {
 	case InodeDataLayoutFlatPlain:
-		bytes, err := i.image.BytesAt(i.dataOff, i.size)
+		bytes, err := i.image.BytesAt(i.dataOff+off, n)
 		if err != nil {
 			return safemem.BlockSeq{}, err
 		}
@@ -644,17 +677,23 @@ func (i *Inode) Data() (safemem.BlockSeq, error) {
 	case InodeDataLayoutFlatInline:
 		sl := make([]safemem.Block, 0, 2)
 		idataSize := i.size & (uint64(i.image.BlockSize()) - 1)
-		if i.size > idataSize {
-			if bytes, err := i.image.BytesAt(i.dataOff, i.size-idataSize); err != nil {
+		plainSize := i.size - idataSize
+		if off < plainSize {
+			plainN := min(n, plainSize-off)
+			if bytes, err := i.image.BytesAt(i.dataOff+off, plainN); err != nil {
 				return safemem.BlockSeq{}, err
 			} else {
 				sl = append(sl, safemem.BlockFromSafeSlice(bytes))
 			}
+			off += plainN
+			n -= plainN
 		}
-		if bytes, err := i.image.BytesAt(i.idataOff, idataSize); err != nil {
-			return safemem.BlockSeq{}, err
-		} else {
-			sl = append(sl, safemem.BlockFromSafeSlice(bytes))
+		if n != 0 {
+			if bytes, err := i.image.BytesAt(i.idataOff+off-plainSize, n); err != nil {
+				return safemem.BlockSeq{}, err
+			} else {
+				sl = append(sl, safemem.BlockFromSafeSlice(bytes))
+			}
 		}
 		return safemem.BlockSeqFromSlice(sl), nil
 
@@ -764,6 +803,10 @@ func (i *Inode) getDirent0(block blockData) (*Dirent, error) {
 		log.Warningf("Invalid nameOff0 %v at inode (nid=%v)", d0.NameOff, i.Nid())
 		return nil, linuxerr.EUCLEAN
 	}
+	// Dirents following d0 are accessed directly.
+	if err := i.image.verify(block.base, uint64(d0.NameOff)); err != nil {
+		return nil, err
+	}
 	return d0, nil
 }
 
@@ -0,0 +1,234 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erofs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/log"
+)
+
+// Parameters of the Merkle tree used to verify images.
+//
+// The tree immediately follows the image's data blocks in the image file, and
+// has the same layout as a dm-verity hash device in format 1 without a salt
+// or superblock: the hashes of data blocks are stored in the tree's bottom
+// level, the hashes of the blocks of each level are stored in the level above
+// it, and levels are stored from the top down. The root hash is the hash of
+// the single block in the top level, or of the single data block if the image
+// only has one.
+const (
+	// VerityBlockSize is the size of both data blocks and hash blocks in the
+	// tree.
+	VerityBlockSize = 4096
+
+	// VerityRootHashSize is the size of a root hash.
+	VerityRootHashSize = sha256.Size
+
+	verityHashesPerBlock = VerityBlockSize / sha256.Size
+)
+
+// verityLevelSizes returns the number of blocks in each level of the Merkle
+// tree for dataBlocks data blocks, from the bottom level up.
+func verityLevelSizes(dataBlocks uint64) []uint64 {
+	var sizes []uint64
+	for n := dataBlocks; n > 1; {
+		n = (n + verityHashesPerBlock - 1) / verityHashesPerBlock
+		sizes = append(sizes, n)
+	}
+	return sizes
+}
+
+// BuildVerityTree returns the Merkle tree for data, which must consist of a
+// non-zero number of whole VerityBlockSize blocks, and the tree's root hash.
+// Appending the returned tree to an EROFS image allows it to be verified by
+// Image.EnableVerity.
+func BuildVerityTree(data []byte) ([]byte, [VerityRootHashSize]byte, error) {
+	if len(data) == 0 || len(data)%VerityBlockSize != 0 {
+		return nil, [VerityRootHashSize]byte{}, fmt.Errorf("data size %d is not a non-zero multiple of %d", len(data), VerityBlockSize)
+	}
+	sizes := verityLevelSizes(uint64(len(data)) / VerityBlockSize)
+	var total uint64
+	for _, size := range sizes {
+		total += size
+	}
+	tree := make([]byte, total*VerityBlockSize)
+	level := data
+	end := uint64(len(tree))
+	for _, size := range sizes {
+		// Levels are stored from the top down, so the bottom level is last.
+		next := tree[end-size*VerityBlockSize : end]
+		end -= size * VerityBlockSize
+		for off := 0; off < len(level); off += VerityBlockSize {
+			sum := sha256.Sum256(level[off : off+VerityBlockSize])
+			copy(next[off/VerityBlockSize*sha256.Size:], sum[:])
+		}
+		level = next
+	}
+	return tree, sha256.Sum256(level[:VerityBlockSize]), nil
+}
+
+// verityTree verifies blocks of an image against a Merkle tree.
+type verityTree struct {
+	rootHash [VerityRootHashSize]byte
+
+	// dataSize is the size in bytes of the data covered by the tree.
+	dataSize uint64
+
+	// levelOffsets[l] is the offset in the image of level l of the tree,
+	// where level 0 contains the hashes of data blocks.
+	levelOffsets []uint64
+
+	// verified[0] is a bitmap of verified data blocks, and verified[l+1] is a
+	// bitmap of verified blocks in level l of the tree.
+	verified [][]atomicbitops.Uint64
+}
+
+// newVerityTree returns a verityTree for dataSize bytes of data, followed by
+// the tree, in an image of size imageSize.
+func newVerityTree(rootHash []byte, dataSize, imageSize uint64) (*verityTree, error) {
+	if len(rootHash) != VerityRootHashSize {
+		return nil, fmt.Errorf("invalid root hash size %d, want %d", len(rootHash), VerityRootHashSize)
+	}
+	if dataSize == 0 || dataSize%VerityBlockSize != 0 {
+		return nil, fmt.Errorf("image data size %d is not a non-zero multiple of %d", dataSize, VerityBlockSize)
+	}
+	dataBlocks := dataSize / VerityBlockSize
+	sizes := verityLevelSizes(dataBlocks)
+	t := &verityTree{
+		dataSize:     dataSize,
+		levelOffsets: make([]uint64, len(sizes)),
+		verified:     make([][]atomicbitops.Uint64, len(sizes)+1),
+	}
+	copy(t.rootHash[:], rootHash)
+	off := dataSize
+	for l := len(sizes) - 1; l >= 0; l-- {
+		t.levelOffsets[l] = off
+		off += sizes[l] * VerityBlockSize
+	}
+	if off > imageSize {
+		return nil, fmt.Errorf("image size %d is too small for %d bytes of data and its Merkle tree", imageSize, dataSize)
+	}
+	t.verified[0] = make([]atomicbitops.Uint64, (dataBlocks+63)/64)
+	for l, size := range sizes {
+		t.verified[l+1] = make([]atomicbitops.Uint64, (size+63)/64)
+	}
+	return t, nil
+}
+
+// verifyRange verifies all blocks overlapping [off, off+n) of image.
+//
+// Preconditions: off+n <= t.dataSize.
+func (t *verityTree) verifyRange(image []byte, off, n uint64) error {
+	if n == 0 {
+		return nil
+	}
+	for b := off / VerityBlockSize; b <= (off+n-1)/VerityBlockSize; b++ {
+		if err := t.verifyBlock(image, 0, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyBlock verifies block idx of the given level, where level 0 contains
+// data blocks and level l+1 contains the blocks of level l of the tree.
+func (t *verityTree) verifyBlock(image []byte, level int, idx uint64) error {
+	word, bit := &t.verified[level][idx/64], uint64(1)<<(idx%64)
+	if word.Load()&bit != 0 {
+		return nil
+	}
+	off := idx * VerityBlockSize
+	if level > 0 {
+		off += t.levelOffsets[level-1]
+	}
+	sum := sha256.Sum256(image[off : off+VerityBlockSize])
+	var want []byte
+	if level == len(t.levelOffsets) {
+		want = t.rootHash[:]
+	} else {
+		// The hash must be read from a verified block.
+		if err := t.verifyBlock(image, level+1, idx/verityHashesPerBlock); err != nil {
+			return err
+		}
+		hashOff := t.levelOffsets[level] + idx*sha256.Size
+		want = image[hashOff : hashOff+sha256.Size]
+	}
+	if !bytes.Equal(sum[:], want) {
+		log.Warningf("Verification of block %d at level %d of image failed", idx, level)
+		return linuxerr.EIO
+	}
+	atomicbitops.OrUint64(word, bit)
+	return nil
+}
+
+// EnableVerity enables verification of the image against the Merkle tree
+// that follows its data blocks, which must have the given root hash. After
+// EnableVerity returns successfully, each block of the image is verified
+// before it's first accessed through i, and accesses to blocks that fail
+// verification return EIO.
+//
+// Since the image is mapped from the image file, verification doesn't
+// protect against modifications of the image file after blocks have been
+// verified.
+func (i *Image) EnableVerity(rootHash []byte) error {
+	dataSize := uint64(i.sb.Blocks) * uint64(i.BlockSize())
+	t, err := newVerityTree(rootHash, dataSize, uint64(len(i.bytes)))
+	if err != nil {
+		return err
+	}
+	// The superblock was read before verification was enabled.
+	if err := t.verifyRange(i.bytes, 0, uint64(i.BlockSize())); err != nil {
+		return fmt.Errorf("superblock verification failed: %w", err)
+	}
+	sb := i.sb
+	if err := i.initSuperBlock(); err != nil {
+		return err
+	}
+	if i.sb != sb {
+		return fmt.Errorf("superblock changed during verification")
+	}
+	i.verity = t
+	return nil
+}
+
+// VerityEnabled returns true if EnableVerity has been called successfully.
+func (i *Image) VerityEnabled() bool {
+	return i.verity != nil
+}
+
+// Verify verifies the blocks overlapping [off, off+n) of the image if
+// verification is enabled.
+func (i *Image) Verify(off, n uint64) error {
+	if !i.checkRange(off, n) {
+		return linuxerr.EFAULT
+	}
+	return i.verify(off, n)
+}
+
+// verify verifies the blocks overlapping [off, off+n) of the image if
+// verification is enabled.
+//
+// Preconditions: i.checkRange(off, n).
+func (i *Image) verify(off, n uint64) error {
+	if i.verity == nil {
+		return nil
+	}
+	return i.verity.verifyRange(i.bytes, off, n)
+}
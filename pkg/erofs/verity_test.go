@@ -0,0 +1,105 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erofs
+
+import (
+	"fmt"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+)
+
+func TestVerityLevelSizes(t *testing.T) {
+	for _, test := range []struct {
+		dataBlocks uint64
+		want       []uint64
+	}{
+		{dataBlocks: 1, want: nil},
+		{dataBlocks: 2, want: []uint64{1}},
+		{dataBlocks: verityHashesPerBlock, want: []uint64{1}},
+		{dataBlocks: verityHashesPerBlock + 1, want: []uint64{2, 1}},
+		{dataBlocks: verityHashesPerBlock * verityHashesPerBlock, want: []uint64{verityHashesPerBlock, 1}},
+		{dataBlocks: verityHashesPerBlock*verityHashesPerBlock + 1, want: []uint64{verityHashesPerBlock + 1, 2, 1}},
+	} {
+		got := verityLevelSizes(test.dataBlocks)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("verityLevelSizes(%d): got %v, want %v", test.dataBlocks, got, test.want)
+		}
+	}
+}
+
+func TestVerityTree(t *testing.T) {
+	for _, dataBlocks := range []int{1, 2, verityHashesPerBlock + 1} {
+		t.Run(fmt.Sprintf("%d blocks", dataBlocks), func(t *testing.T) {
+			data := make([]byte, dataBlocks*VerityBlockSize)
+			for i := range data {
+				data[i] = byte(i * 7 / VerityBlockSize)
+			}
+			tree, rootHash, err := BuildVerityTree(data)
+			if err != nil {
+				t.Fatalf("BuildVerityTree failed: %v", err)
+			}
+			image := append(append([]byte(nil), data...), tree...)
+			dataSize := uint64(len(data))
+
+			// All blocks of an unmodified image should be verified.
+			vt, err := newVerityTree(rootHash[:], dataSize, uint64(len(image)))
+			if err != nil {
+				t.Fatalf("newVerityTree failed: %v", err)
+			}
+			if err := vt.verifyRange(image, 0, dataSize); err != nil {
+				t.Errorf("verifyRange of unmodified image failed: %v", err)
+			}
+
+			// Modifying any data block should fail its verification, but not
+			// that of other blocks.
+			last := dataSize - VerityBlockSize
+			image[last]++
+			vt, err = newVerityTree(rootHash[:], dataSize, uint64(len(image)))
+			if err != nil {
+				t.Fatalf("newVerityTree failed: %v", err)
+			}
+			if last != 0 {
+				if err := vt.verifyRange(image, 0, last); err != nil {
+					t.Errorf("verifyRange of unmodified blocks failed: %v", err)
+				}
+			}
+			if err := vt.verifyRange(image, last+1, 1); !linuxerr.Equals(linuxerr.EIO, err) {
+				t.Errorf("verifyRange of modified block: got error %v, want %v", err, linuxerr.EIO)
+			}
+			image[last]--
+
+			// Modifying the tree should fail verification.
+			if len(tree) != 0 {
+				image[dataSize]++
+				vt, err = newVerityTree(rootHash[:], dataSize, uint64(len(image)))
+				if err != nil {
+					t.Fatalf("newVerityTree failed: %v", err)
+				}
+				if err := vt.verifyRange(image, 0, 1); !linuxerr.Equals(linuxerr.EIO, err) {
+					t.Errorf("verifyRange with modified tree: got error %v, want %v", err, linuxerr.EIO)
+				}
+				image[dataSize]--
+			}
+
+			// A truncated tree should be rejected.
+			if len(tree) != 0 {
+				if _, err := newVerityTree(rootHash[:], dataSize, uint64(len(image)-1)); err == nil {
+					t.Errorf("newVerityTree succeeded with truncated tree")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lisafs
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+)
+
+// maxNegativeEntries is the maximum number of negative entries cached by each
+// directory node. Negative lookups are dominated by searches through a few
+// directories (e.g. those in $PATH), so a small number suffices.
+const maxNegativeEntries = 128
+
+// nodeCache holds the results of operations on a Node, cached by the server
+// on behalf of readonly connections.
+//
+// Cached state is only valid for the server's cache generation in which it
+// was obtained. Operations that may modify the filesystem tree increment the
+// server's cache generation after completing, so that state obtained
+// concurrently with them is never used.
+type nodeCache struct {
+	// gen is the cache generation in which the following fields were
+	// populated.
+	gen uint64
+
+	// stat is the cached result of ControlFDImpl.Stat or OpenFDImpl.Stat.
+	stat    linux.Statx
+	hasStat bool
+
+	// target is the cached result of ControlFDImpl.Readlink.
+	target    string
+	hasTarget bool
+
+	// negative is the set of names that are known not to exist in this
+	// directory.
+	negative map[string]struct{}
+}
+
+// cacheLocked returns n's cached state for generation gen, discarding stale
+// state from other generations.
+//
+// Preconditions: n.cacheMu must be locked.
+func (n *Node) cacheLocked(gen uint64) *nodeCache {
+	if n.cache == nil || n.cache.gen != gen {
+		n.cache = &nodeCache{gen: gen}
+	}
+	return n.cache
+}
+
+// cachedStat returns n's cached stat results for generation gen, if any.
+func (n *Node) cachedStat(gen uint64) (linux.Statx, bool) {
+	n.cacheMu.Lock()
+	defer n.cacheMu.Unlock()
+	if n.cache == nil || n.cache.gen != gen || !n.cache.hasStat {
+		return linux.Statx{}, false
+	}
+	return n.cache.stat, true
+}
+
+// cacheStat caches stat results for n, obtained in generation gen.
+func (n *Node) cacheStat(gen uint64, stat linux.Statx) {
+	n.cacheMu.Lock()
+	defer n.cacheMu.Unlock()
+	cache := n.cacheLocked(gen)
+	cache.stat = stat
+	cache.hasStat = true
+}
+
+// cachedTarget returns n's cached symlink target for generation gen, if any.
+func (n *Node) cachedTarget(gen uint64) (string, bool) {
+	n.cacheMu.Lock()
+	defer n.cacheMu.Unlock()
+	if n.cache == nil || n.cache.gen != gen || !n.cache.hasTarget {
+		return "", false
+	}
+	return n.cache.target, true
+}
+
+// cacheTarget caches n's symlink target, obtained in generation gen.
+func (n *Node) cacheTarget(gen uint64, target string) {
+	n.cacheMu.Lock()
+	defer n.cacheMu.Unlock()
+	cache := n.cacheLocked(gen)
+	cache.target = target
+	cache.hasTarget = true
+}
+
+// isNegative returns true if name is cached as not existing in n in
+// generation gen.
+func (n *Node) isNegative(gen uint64, name string) bool {
+	n.cacheMu.Lock()
+	defer n.cacheMu.Unlock()
+	if n.cache == nil || n.cache.gen != gen {
+		return false
+	}
+	_, ok := n.cache.negative[name]
+	return ok
+}
+
+// cacheNegative caches that name doesn't exist in n, as observed in
+// generation gen.
+func (n *Node) cacheNegative(gen uint64, name string) {
+	n.cacheMu.Lock()
+	defer n.cacheMu.Unlock()
+	cache := n.cacheLocked(gen)
+	if len(cache.negative) >= maxNegativeEntries {
+		return
+	}
+	if cache.negative == nil {
+		cache.negative = make(map[string]struct{})
+	}
+	cache.negative[name] = struct{}{}
+}
+
+// cacheGen returns the server's current cache generation, and true if c may
+// use cached state. The generation must be obtained before performing the
+// operation whose results are cached.
+func (c *Connection) cacheGen() (uint64, bool) {
+	if !c.readonly || !c.server.opts.CacheReadonly {
+		return 0, false
+	}
+	return c.server.cacheGen.Load(), true
+}
+
+// invalidateCache invalidates all state cached by s. It must be called after
+// any operation that may modify the filesystem tree completes.
+func (s *Server) invalidateCache() {
+	if s.opts.CacheReadonly {
+		s.cacheGen.Add(1)
+	}
+}
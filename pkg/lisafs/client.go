@@ -22,6 +22,7 @@ import (
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/flipcall"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/stall"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/unet"
 )
@@ -311,6 +312,19 @@ func (c *Client) SyncFDs(ctx context.Context, fds []FDID) error {
 	return err
 }
 
+// InvalidateCache makes the InvalidateCache RPC.
+func (c *Client) InvalidateCache(ctx context.Context) error {
+	if !c.IsSupported(InvalidateCache) {
+		// If InvalidateCache is not supported, the server doesn't cache.
+		return nil
+	}
+	var req, resp EmptyMessage
+	ctx.UninterruptibleSleepStart(false)
+	err := c.SndRcvMessage(InvalidateCache, uint32(req.SizeBytes()), req.MarshalBytes, resp.CheckedUnmarshal, nil, req.String, resp.String)
+	ctx.UninterruptibleSleepFinish(false)
+	return err
+}
+
 // SndRcvMessage invokes reqMarshal to marshal the request onto the payload
 // buffer, wakes up the server to process the request, waits for the response
 // and invokes respUnmarshal with the response payload. respFDs is populated
@@ -342,7 +356,9 @@ func (c *Client) SndRcvMessage(m MID, payloadLen uint32, reqMarshal marshalFunc,
 
 	// Marshal the request into comm's payload buffer and make the RPC.
 	reqMarshal(comm.PayloadBuf(payloadLen))
+	stallID := stall.Gofer.Begin()
 	respM, respPayloadLen, err := comm.SndRcvMessage(m, payloadLen, uint8(wantFDs))
+	stall.Gofer.End(stallID)
 
 	// Handle FD donation.
 	rcvFDs := comm.ReleaseFDs()
@@ -191,6 +191,22 @@ func (f *ClientFD) Read(ctx context.Context, dst []byte, offset uint64) (uint64,
 	})
 }
 
+// ReadFile makes the ReadFile RPC, reading the entire contents of the regular
+// file represented by f into dst. It returns EFBIG if the file is larger than
+// dst. len(dst) must fit in a single message.
+func (f *ClientFD) ReadFile(ctx context.Context, dst []byte) (uint64, error) {
+	req := ReadFileReq{
+		FD:      f.fd,
+		MaxSize: uint32(len(dst)),
+	}
+	// PReadResp.CheckedUnmarshal expects resp.Buf to be set.
+	resp := PReadResp{Buf: dst}
+	ctx.UninterruptibleSleepStart(false)
+	err := f.client.SndRcvMessage(ReadFile, uint32(req.SizeBytes()), req.MarshalUnsafe, resp.CheckedUnmarshal, nil, req.String, resp.String)
+	ctx.UninterruptibleSleepFinish(false)
+	return uint64(resp.NumBytes), err
+}
+
 // Write makes the PWrite RPC.
 func (f *ClientFD) Write(ctx context.Context, src []byte, offset uint64) (uint64, error) {
 	var req PWriteReq
@@ -412,6 +428,36 @@ func (f *ClientFD) ReadLinkAt(ctx context.Context) (string, error) {
 	return string(resp.Target), err
 }
 
+// NameToHandle makes the NameToHandle RPC.
+func (f *ClientFD) NameToHandle(ctx context.Context) (FileHandle, error) {
+	var resp FileHandle
+	if !f.client.IsSupported(NameToHandle) {
+		return resp, unix.EOPNOTSUPP
+	}
+	req := NameToHandleReq{FD: f.fd}
+	ctx.UninterruptibleSleepStart(false)
+	err := f.client.SndRcvMessage(NameToHandle, uint32(req.SizeBytes()), req.MarshalUnsafe, resp.CheckedUnmarshal, nil, req.String, resp.String)
+	ctx.UninterruptibleSleepFinish(false)
+	return resp, err
+}
+
+// OpenByHandle makes the OpenByHandle RPC. It returns the path of the file
+// identified by h relative to the directory represented by f.
+func (f *ClientFD) OpenByHandle(ctx context.Context, h *FileHandle) (string, error) {
+	if !f.client.IsSupported(OpenByHandle) {
+		return "", unix.EOPNOTSUPP
+	}
+	req := OpenByHandleReq{
+		FD:     f.fd,
+		Handle: *h,
+	}
+	var resp OpenByHandleResp
+	ctx.UninterruptibleSleepStart(false)
+	err := f.client.SndRcvMessage(OpenByHandle, uint32(req.SizeBytes()), req.MarshalUnsafe, resp.CheckedUnmarshal, nil, req.String, resp.String)
+	ctx.UninterruptibleSleepFinish(false)
+	return string(resp.Path), err
+}
+
 // Flush makes the Flush RPC.
 func (f *ClientFD) Flush(ctx context.Context) error {
 	if !f.client.IsSupported(Flush) {
@@ -116,6 +116,11 @@ func (c *Connection) ServerImpl() ServerImpl {
 	return c.server.impl
 }
 
+// Readonly returns true if c is readonly.
+func (c *Connection) Readonly() bool {
+	return c.readonly
+}
+
 // Run defines the lifecycle of a connection.
 func (c *Connection) Run() {
 	defer c.close()
@@ -467,6 +467,20 @@ type ControlFDImpl interface {
 	// On the server, StatFS has read concurrency guarantee.
 	StatFS() (StatFS, error)
 
+	// NameToHandle returns a file handle that identifies the file represented
+	// by this FD, as name_to_handle_at(2) would.
+	//
+	// On the server, NameToHandle has a read concurrency guarantee.
+	NameToHandle() (FileHandle, error)
+
+	// OpenByHandle resolves the given file handle and returns the path of the
+	// file it identifies, relative to the directory represented by this FD.
+	// It returns ESTALE if the file no longer exists or is not reachable from
+	// this directory.
+	//
+	// On the server, OpenByHandle has a read concurrency guarantee.
+	OpenByHandle(h FileHandle) (string, error)
+
 	// Readlink reads the symlink's target and writes the string into the buffer
 	// returned by getLinkBuf which can be used to request buffer for some size.
 	// It returns the number of bytes written into the buffer.
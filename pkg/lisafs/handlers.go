@@ -46,38 +46,42 @@ const (
 type RPCHandler func(c *Connection, comm Communicator, payloadLen uint32) (uint32, error)
 
 var handlers = [...]RPCHandler{
-	Error:        ErrorHandler,
-	Mount:        MountHandler,
-	Channel:      ChannelHandler,
-	FStat:        FStatHandler,
-	SetStat:      SetStatHandler,
-	Walk:         WalkHandler,
-	WalkStat:     WalkStatHandler,
-	OpenAt:       OpenAtHandler,
-	OpenCreateAt: OpenCreateAtHandler,
-	Close:        CloseHandler,
-	FSync:        FSyncHandler,
-	PWrite:       PWriteHandler,
-	PRead:        PReadHandler,
-	MkdirAt:      MkdirAtHandler,
-	MknodAt:      MknodAtHandler,
-	SymlinkAt:    SymlinkAtHandler,
-	LinkAt:       LinkAtHandler,
-	FStatFS:      FStatFSHandler,
-	FAllocate:    FAllocateHandler,
-	ReadLinkAt:   ReadLinkAtHandler,
-	Flush:        FlushHandler,
-	UnlinkAt:     UnlinkAtHandler,
-	RenameAt:     RenameAtHandler,
-	Getdents64:   Getdents64Handler,
-	FGetXattr:    FGetXattrHandler,
-	FSetXattr:    FSetXattrHandler,
-	FListXattr:   FListXattrHandler,
-	FRemoveXattr: FRemoveXattrHandler,
-	Connect:      ConnectHandler,
-	BindAt:       BindAtHandler,
-	Listen:       ListenHandler,
-	Accept:       AcceptHandler,
+	Error:           ErrorHandler,
+	Mount:           MountHandler,
+	Channel:         ChannelHandler,
+	FStat:           FStatHandler,
+	SetStat:         SetStatHandler,
+	Walk:            WalkHandler,
+	WalkStat:        WalkStatHandler,
+	OpenAt:          OpenAtHandler,
+	OpenCreateAt:    OpenCreateAtHandler,
+	Close:           CloseHandler,
+	FSync:           FSyncHandler,
+	PWrite:          PWriteHandler,
+	PRead:           PReadHandler,
+	MkdirAt:         MkdirAtHandler,
+	MknodAt:         MknodAtHandler,
+	SymlinkAt:       SymlinkAtHandler,
+	LinkAt:          LinkAtHandler,
+	FStatFS:         FStatFSHandler,
+	FAllocate:       FAllocateHandler,
+	ReadLinkAt:      ReadLinkAtHandler,
+	Flush:           FlushHandler,
+	UnlinkAt:        UnlinkAtHandler,
+	RenameAt:        RenameAtHandler,
+	Getdents64:      Getdents64Handler,
+	FGetXattr:       FGetXattrHandler,
+	FSetXattr:       FSetXattrHandler,
+	FListXattr:      FListXattrHandler,
+	FRemoveXattr:    FRemoveXattrHandler,
+	Connect:         ConnectHandler,
+	BindAt:          BindAtHandler,
+	Listen:          ListenHandler,
+	Accept:          AcceptHandler,
+	InvalidateCache: InvalidateCacheHandler,
+	ReadFile:        ReadFileHandler,
+	NameToHandle:    NameToHandleHandler,
+	OpenByHandle:    OpenByHandleHandler,
 }
 
 // ErrorHandler handles Error message.
@@ -213,18 +217,38 @@ func FStatHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32,
 	}
 	defer fd.DecRef(nil)
 
-	var resp linux.Statx
+	var (
+		resp   linux.Statx
+		cached bool
+	)
+	gen, cache := c.cacheGen()
 	switch t := fd.(type) {
 	case *ControlFD:
-		t.safelyRead(func() error {
-			resp, err = t.impl.Stat()
-			return err
-		})
+		if cache {
+			resp, cached = t.node.cachedStat(gen)
+		}
+		if !cached {
+			t.safelyRead(func() error {
+				resp, err = t.impl.Stat()
+				return err
+			})
+			if err == nil && cache {
+				t.node.cacheStat(gen, resp)
+			}
+		}
 	case *OpenFD:
-		t.controlFD.safelyRead(func() error {
-			resp, err = t.impl.Stat()
-			return err
-		})
+		if cache {
+			resp, cached = t.controlFD.node.cachedStat(gen)
+		}
+		if !cached {
+			t.controlFD.safelyRead(func() error {
+				resp, err = t.impl.Stat()
+				return err
+			})
+			if err == nil && cache {
+				t.controlFD.node.cacheStat(gen, resp)
+			}
+		}
 	default:
 		panic(fmt.Sprintf("unknown fd type %T", t))
 	}
@@ -241,6 +265,7 @@ func SetStatHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32
 	if c.readonly {
 		return 0, unix.EROFS
 	}
+	defer c.server.invalidateCache()
 
 	var req SetStatReq
 	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
@@ -306,6 +331,7 @@ func WalkHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32, e
 	}
 	payloadBuf := comm.PayloadBuf(uint32(maxPayloadSize))
 	payloadPos := respMetaSize
+	gen, cache := c.cacheGen()
 	if err := c.server.withRenameReadLock(func() error {
 		curDir := startDir
 		cu := cleanup.Make(func() {
@@ -338,7 +364,15 @@ func WalkHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32, e
 				status = WalkComponentDoesNotExist
 				break
 			}
+			if cache && curDir.node.isNegative(gen, name) {
+				curDir.node.opMu.RUnlock()
+				status = WalkComponentDoesNotExist
+				break
+			}
 			child, childStat, err := curDir.impl.Walk(name)
+			if err == unix.ENOENT && cache {
+				curDir.node.cacheNegative(gen, name)
+			}
 			curDir.node.opMu.RUnlock()
 			if err == unix.ENOENT {
 				status = WalkComponentDoesNotExist
@@ -506,6 +540,9 @@ func OpenAtHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32,
 	if c.readonly && (accessMode != unix.O_RDONLY || trunc) {
 		return 0, unix.EROFS
 	}
+	if trunc {
+		defer c.server.invalidateCache()
+	}
 
 	fd, err := c.lookupControlFD(req.FD)
 	if err != nil {
@@ -547,6 +584,7 @@ func OpenCreateAtHandler(c *Connection, comm Communicator, payloadLen uint32) (u
 	if c.readonly {
 		return 0, unix.EROFS
 	}
+	defer c.server.invalidateCache()
 	var req OpenCreateAtReq
 	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
 		return 0, unix.EIO
@@ -653,6 +691,7 @@ func PWriteHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32,
 	if c.readonly {
 		return 0, unix.EROFS
 	}
+	defer c.server.invalidateCache()
 	var req PWriteReq
 	// Note that it is an optimized Unmarshal operation which avoids any buffer
 	// allocation and copying. req.Buf just points to payload. This is safe to do
@@ -722,11 +761,86 @@ func PReadHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32,
 	return respMetaSize + uint32(n), nil
 }
 
+// ReadFileHandler handles the ReadFile RPC.
+func ReadFileHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32, error) {
+	var req ReadFileReq
+	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
+		return 0, unix.EIO
+	}
+
+	fd, err := c.lookupControlFD(req.FD)
+	if err != nil {
+		return 0, err
+	}
+	defer fd.DecRef(nil)
+	if !fd.IsRegular() {
+		return 0, unix.EINVAL
+	}
+
+	// As in PReadHandler, read directly into the payload buffer.
+	var resp PReadResp
+	respMetaSize := uint32(resp.NumBytes.SizeBytes())
+	respPayloadLen := respMetaSize + req.MaxSize
+	if respPayloadLen > c.maxMessageSize {
+		return 0, unix.ENOBUFS
+	}
+	payloadBuf := comm.PayloadBuf(respPayloadLen)
+	buf := payloadBuf[respMetaSize:]
+
+	var (
+		openFD *OpenFD
+		n      uint64
+	)
+	err = fd.safelyRead(func() error {
+		if fd.node.isDeleted() {
+			return unix.EINVAL
+		}
+		var hostOpenFD int
+		openFD, hostOpenFD, err = fd.impl.Open(unix.O_RDONLY)
+		if err != nil {
+			return err
+		}
+		if hostOpenFD >= 0 {
+			// The host FD is not needed since the file is read in its entirety.
+			unix.Close(hostOpenFD)
+		}
+		stat, err := openFD.impl.Stat()
+		if err != nil {
+			return err
+		}
+		if stat.Size > uint64(req.MaxSize) {
+			return unix.EFBIG
+		}
+		for n < uint64(len(buf)) {
+			read, err := openFD.impl.Read(buf[n:], n)
+			if err != nil {
+				return err
+			}
+			if read == 0 {
+				break
+			}
+			n += read
+		}
+		return nil
+	})
+	if openFD != nil {
+		c.removeFD(openFD.id)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	resp.NumBytes = primitive.Uint64(n)
+	resp.NumBytes.MarshalUnsafe(payloadBuf)
+	return respMetaSize + uint32(n), nil
+}
+
 // MkdirAtHandler handles the MkdirAt RPC.
 func MkdirAtHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32, error) {
 	if c.readonly {
 		return 0, unix.EROFS
 	}
+	defer c.server.invalidateCache()
 	var req MkdirAtReq
 	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
 		return 0, unix.EIO
@@ -775,6 +889,7 @@ func MknodAtHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32
 	if c.readonly {
 		return 0, unix.EROFS
 	}
+	defer c.server.invalidateCache()
 	var req MknodAtReq
 	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
 		return 0, unix.EIO
@@ -822,6 +937,7 @@ func SymlinkAtHandler(c *Connection, comm Communicator, payloadLen uint32) (uint
 	if c.readonly {
 		return 0, unix.EROFS
 	}
+	defer c.server.invalidateCache()
 	var req SymlinkAtReq
 	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
 		return 0, unix.EIO
@@ -869,6 +985,7 @@ func LinkAtHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32,
 	if c.readonly {
 		return 0, unix.EROFS
 	}
+	defer c.server.invalidateCache()
 	var req LinkAtReq
 	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
 		return 0, unix.EIO
@@ -954,11 +1071,75 @@ func FStatFSHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32
 	return respLen, nil
 }
 
+// NameToHandleHandler handles the NameToHandle RPC.
+func NameToHandleHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32, error) {
+	var req NameToHandleReq
+	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
+		return 0, unix.EIO
+	}
+
+	fd, err := c.lookupControlFD(req.FD)
+	if err != nil {
+		return 0, err
+	}
+	defer fd.DecRef(nil)
+	var resp FileHandle
+	if err := fd.safelyRead(func() error {
+		if fd.node.isDeleted() {
+			return unix.ESTALE
+		}
+		resp, err = fd.impl.NameToHandle()
+		return err
+	}); err != nil {
+		return 0, err
+	}
+	respLen := uint32(resp.SizeBytes())
+	resp.MarshalUnsafe(comm.PayloadBuf(respLen))
+	return respLen, nil
+}
+
+// OpenByHandleHandler handles the OpenByHandle RPC.
+func OpenByHandleHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32, error) {
+	var req OpenByHandleReq
+	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
+		return 0, unix.EIO
+	}
+	if req.Handle.Size > MaxHandleSize {
+		return 0, unix.EINVAL
+	}
+
+	fd, err := c.lookupControlFD(req.FD)
+	if err != nil {
+		return 0, err
+	}
+	defer fd.DecRef(nil)
+	if !fd.IsDir() {
+		return 0, unix.ENOTDIR
+	}
+	var path string
+	if err := fd.safelyRead(func() error {
+		if fd.node.isDeleted() {
+			return unix.ESTALE
+		}
+		path, err = fd.impl.OpenByHandle(req.Handle)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+	resp := OpenByHandleResp{
+		Path: SizedString(path),
+	}
+	respLen := uint32(resp.SizeBytes())
+	resp.MarshalBytes(comm.PayloadBuf(respLen))
+	return respLen, nil
+}
+
 // FAllocateHandler handles the FAllocate RPC.
 func FAllocateHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32, error) {
 	if c.readonly {
 		return 0, unix.EROFS
 	}
+	defer c.server.invalidateCache()
 	var req FAllocateReq
 	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
 		return 0, unix.EIO
@@ -1005,16 +1186,31 @@ func ReadLinkAtHandler(c *Connection, comm Communicator, payloadLen uint32) (uin
 		n       uint16
 	)
 	respMetaSize := uint32(linkLen.SizeBytes())
-	if fd.safelyRead(func() error {
-		if fd.node.isDeleted() {
-			return unix.EINVAL
+	var (
+		target string
+		cached bool
+	)
+	gen, cache := c.cacheGen()
+	if cache {
+		target, cached = fd.node.cachedTarget(gen)
+	}
+	if cached {
+		n = uint16(copy(comm.PayloadBuf(uint32(len(target)) + respMetaSize)[respMetaSize:], target))
+	} else {
+		if fd.safelyRead(func() error {
+			if fd.node.isDeleted() {
+				return unix.EINVAL
+			}
+			n, err = fd.impl.Readlink(func(dataLen uint32) []byte {
+				return comm.PayloadBuf(dataLen + respMetaSize)[respMetaSize:]
+			})
+			return err
+		}); err != nil {
+			return 0, err
+		}
+		if cache {
+			fd.node.cacheTarget(gen, string(comm.PayloadBuf(uint32(n) + respMetaSize)[respMetaSize:]))
 		}
-		n, err = fd.impl.Readlink(func(dataLen uint32) []byte {
-			return comm.PayloadBuf(dataLen + respMetaSize)[respMetaSize:]
-		})
-		return err
-	}); err != nil {
-		return 0, err
 	}
 	linkLen = primitive.Uint16(n)
 	linkLen.MarshalUnsafe(comm.PayloadBuf(respMetaSize))
@@ -1071,6 +1267,7 @@ func ConnectHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32
 
 // BindAtHandler handles the BindAt RPC.
 func BindAtHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32, error) {
+	defer c.server.invalidateCache()
 	var req BindAtReq
 	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
 		return 0, unix.EIO
@@ -1179,6 +1376,7 @@ func UnlinkAtHandler(c *Connection, comm Communicator, payloadLen uint32) (uint3
 	if c.readonly {
 		return 0, unix.EROFS
 	}
+	defer c.server.invalidateCache()
 	var req UnlinkAtReq
 	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
 		return 0, unix.EIO
@@ -1235,6 +1433,7 @@ func RenameAtHandler(c *Connection, comm Communicator, payloadLen uint32) (uint3
 	if c.readonly {
 		return 0, unix.EROFS
 	}
+	defer c.server.invalidateCache()
 	var req RenameAtReq
 	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
 		return 0, unix.EIO
@@ -1424,6 +1623,7 @@ func FSetXattrHandler(c *Connection, comm Communicator, payloadLen uint32) (uint
 	if c.readonly {
 		return 0, unix.EROFS
 	}
+	defer c.server.invalidateCache()
 	var req FSetXattrReq
 	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
 		return 0, unix.EIO
@@ -1475,6 +1675,7 @@ func FRemoveXattrHandler(c *Connection, comm Communicator, payloadLen uint32) (u
 	if c.readonly {
 		return 0, unix.EROFS
 	}
+	defer c.server.invalidateCache()
 	var req FRemoveXattrReq
 	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
 		return 0, unix.EIO
@@ -1491,6 +1692,16 @@ func FRemoveXattrHandler(c *Connection, comm Communicator, payloadLen uint32) (u
 	})
 }
 
+// InvalidateCacheHandler handles the InvalidateCache RPC.
+func InvalidateCacheHandler(c *Connection, comm Communicator, payloadLen uint32) (uint32, error) {
+	var req EmptyMessage
+	if _, ok := req.CheckedUnmarshal(comm.PayloadBuf(payloadLen)); !ok {
+		return 0, unix.EIO
+	}
+	c.server.invalidateCache()
+	return 0, nil
+}
+
 // checkSafeName validates the name and returns nil or returns an error.
 func checkSafeName(name string) error {
 	if name != "" && !strings.Contains(name, "/") && name != "." && name != ".." {
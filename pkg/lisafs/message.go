@@ -172,6 +172,22 @@ const (
 
 	// Accept is analogous to accept4(2).
 	Accept MID = 31
+
+	// InvalidateCache requests the server to discard all cached filesystem
+	// state, such that modifications made outside of the server become
+	// visible. See ServerOpts.CacheReadonly.
+	InvalidateCache MID = 32
+
+	// ReadFile is analogous to calling open(2), read(2) and close(2) on a
+	// small regular file, reading it in its entirety in a single round trip.
+	ReadFile MID = 33
+
+	// NameToHandle is analogous to name_to_handle_at(2) with AT_EMPTY_PATH.
+	NameToHandle MID = 34
+
+	// OpenByHandle resolves a file handle returned by NameToHandle to a path
+	// relative to the specified directory FD.
+	OpenByHandle MID = 35
 )
 
 const (
@@ -914,6 +930,20 @@ func (r *PReadResp) CheckedUnmarshal(src []byte) ([]byte, bool) {
 	return srcRemain[copy(r.Buf, srcRemain[:r.NumBytes]):], true
 }
 
+// ReadFileReq is used to read the entire contents of a regular file.
+//
+// +marshal boundCheck
+type ReadFileReq struct {
+	FD      FDID
+	MaxSize uint32
+	_       uint32 // Need to make struct packed.
+}
+
+// String implements fmt.Stringer.String.
+func (r *ReadFileReq) String() string {
+	return fmt.Sprintf("ReadFileReq{FD: %d, MaxSize: %d}", r.FD, r.MaxSize)
+}
+
 // PWriteReq is used to pwrite(2) on an FD.
 type PWriteReq struct {
 	Offset   primitive.Uint64
@@ -1273,6 +1303,74 @@ func (r *ReadLinkAtResp) CheckedUnmarshal(src []byte) ([]byte, bool) {
 	return r.Target.CheckedUnmarshal(src)
 }
 
+// MaxHandleSize is the maximum size of a file handle exchanged via the
+// NameToHandle and OpenByHandle RPCs. It matches MAX_HANDLE_SZ in Linux.
+const MaxHandleSize = 128
+
+// FileHandle is a host file handle, as returned by name_to_handle_at(2).
+//
+// +marshal boundCheck
+type FileHandle struct {
+	Type  int32
+	Size  uint32
+	Bytes [MaxHandleSize]byte
+}
+
+// String implements fmt.Stringer.String.
+func (h *FileHandle) String() string {
+	return fmt.Sprintf("FileHandle{Type: %d, Size: %d}", h.Type, h.Size)
+}
+
+// NameToHandleReq is used to obtain a file handle for the specified FD.
+//
+// +marshal boundCheck
+type NameToHandleReq struct {
+	FD FDID
+}
+
+// String implements fmt.Stringer.String.
+func (n *NameToHandleReq) String() string {
+	return fmt.Sprintf("NameToHandleReq{FD: %d}", n.FD)
+}
+
+// OpenByHandleReq is used to resolve a file handle relative to a directory FD.
+//
+// +marshal boundCheck
+type OpenByHandleReq struct {
+	FD     FDID
+	Handle FileHandle
+}
+
+// String implements fmt.Stringer.String.
+func (o *OpenByHandleReq) String() string {
+	return fmt.Sprintf("OpenByHandleReq{FD: %d, Handle: %s}", o.FD, o.Handle.String())
+}
+
+// OpenByHandleResp is used to communicate OpenByHandle results.
+type OpenByHandleResp struct {
+	Path SizedString
+}
+
+// String implements fmt.Stringer.String.
+func (o *OpenByHandleResp) String() string {
+	return fmt.Sprintf("OpenByHandleResp{Path: %s}", o.Path)
+}
+
+// SizeBytes implements marshal.Marshallable.SizeBytes.
+func (o *OpenByHandleResp) SizeBytes() int {
+	return o.Path.SizeBytes()
+}
+
+// MarshalBytes implements marshal.Marshallable.MarshalBytes.
+func (o *OpenByHandleResp) MarshalBytes(dst []byte) []byte {
+	return o.Path.MarshalBytes(dst)
+}
+
+// CheckedUnmarshal implements marshal.CheckedMarshallable.CheckedUnmarshal.
+func (o *OpenByHandleResp) CheckedUnmarshal(src []byte) ([]byte, bool) {
+	return o.Path.CheckedUnmarshal(src)
+}
+
 // FlushReq is used to make Flush requests.
 //
 // +marshal boundCheck
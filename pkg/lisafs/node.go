@@ -99,6 +99,13 @@ type Node struct {
 		node *Node
 	}
 	dynamicChildren map[string]*Node
+
+	// cacheMu protects cache.
+	cacheMu sync.Mutex
+	// cache holds the results of operations on this node cached by the server,
+	// or nil if there are none. Most nodes are never cached, so cache is
+	// allocated lazily to keep nodes small.
+	cache *nodeCache
 }
 
 // DecRef implements refs.RefCounter.DecRef. Note that the context
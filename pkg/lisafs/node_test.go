@@ -84,3 +84,32 @@ func TestDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestNegativeCache(t *testing.T) {
+	var n Node
+	n.InitLocked("", nil)
+
+	const gen = 1
+	if n.isNegative(gen, "foo") {
+		t.Fatalf("uncached name is negative")
+	}
+	n.cacheNegative(gen, "foo")
+	if !n.isNegative(gen, "foo") {
+		t.Errorf("cached name is not negative")
+	}
+	if n.isNegative(gen, "bar") {
+		t.Errorf("uncached name is negative")
+	}
+	// Cached state is stale in other generations.
+	if n.isNegative(gen+1, "foo") {
+		t.Errorf("name cached in generation %d is negative in generation %d", gen, gen+1)
+	}
+
+	// The number of cached names is bounded.
+	for i := 0; i < 2*maxNegativeEntries; i++ {
+		n.cacheNegative(gen+1, fmt.Sprintf("%d", i))
+	}
+	if got := len(n.cache.negative); got != maxNegativeEntries {
+		t.Errorf("got %d negative entries, want %d", got, maxNegativeEntries)
+	}
+}
@@ -16,6 +16,7 @@ package lisafs
 
 import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/sync"
 )
 
@@ -46,6 +47,11 @@ type Server struct {
 	// opts is the server specific options. This dictates how some of the
 	// messages are handled.
 	opts ServerOpts
+
+	// cacheGen is the generation of state cached by Nodes. State cached in
+	// other generations is stale. cacheGen is incremented by operations that
+	// may modify the filesystem tree, and by the InvalidateCache RPC.
+	cacheGen atomicbitops.Uint64
 }
 
 // ServerOpts defines some server implementation specific behavior.
@@ -62,6 +68,14 @@ type ServerOpts struct {
 	// AllocateOnDeleted is set to true if it's safe to call OpenFDImpl.Allocate
 	// for deleted files.
 	AllocateOnDeleted bool
+
+	// CacheReadonly is set to true if the server may cache stat results,
+	// symlink targets and negative lookups on behalf of readonly connections.
+	// This avoids repeatedly hitting the host filesystem for immutable
+	// filesystem trees, such as read-only image mounts. Modifications made
+	// outside of the server are not observed by readonly connections until
+	// the client makes the InvalidateCache RPC.
+	CacheReadonly bool
 }
 
 // Init must be called before first use of the server.
@@ -70,6 +70,7 @@ var localFSTests = map[string]TestFunc{
 	"Stat":            testStat,
 	"RegularFileIO":   testRegularFileIO,
 	"RegularFileOpen": testRegularFileOpen,
+	"ReadFile":        testReadFile,
 	"SetStat":         testSetStat,
 	"Allocate":        testAllocate,
 	"StatFS":          testStatFS,
@@ -392,6 +393,32 @@ func testRegularFileOpen(ctx context.Context, t *testing.T, tester Tester, root
 	}
 }
 
+func testReadFile(ctx context.Context, t *testing.T, tester Tester, root lisafs.ClientFD) {
+	name := "tempFile"
+	controlFile, _, fd, hostFD := openCreateFile(ctx, t, root, name)
+	defer closeFD(ctx, t, controlFile)
+	defer closeFD(ctx, t, fd)
+	defer unix.Close(hostFD)
+
+	data := make([]byte, 4096)
+	rand.Read(data)
+	if err := writeFD(ctx, t, fd, 0, data); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	buf := make([]byte, 2*len(data))
+	if n, err := controlFile.ReadFile(ctx, buf); err != nil {
+		t.Errorf("ReadFile failed: %v", err)
+	} else if !bytes.Equal(buf[:n], data) {
+		t.Errorf("bytes read differ from what was expected: want = %v, got = %v", data, buf[:n])
+	}
+
+	// Files larger than the buffer should not be read.
+	if _, err := controlFile.ReadFile(ctx, buf[:len(data)-1]); err != unix.EFBIG {
+		t.Errorf("ReadFile with a small buffer should generate EFBIG, but got %v", err)
+	}
+}
+
 func testSetStat(ctx context.Context, t *testing.T, tester Tester, root lisafs.ClientFD) {
 	name := "tempFile"
 	controlFile, _, fd, hostFD := openCreateFile(ctx, t, root, name)
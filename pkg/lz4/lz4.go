@@ -0,0 +1,216 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lz4 implements compression and decompression of the LZ4 block
+// format.
+//
+// The LZ4 block format favors compression and decompression speed over
+// compression ratio, which makes it suitable for compressing memory. This
+// package only implements the block format, not the LZ4 frame format, so
+// users must record the uncompressed size of each block themselves.
+package lz4
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// minMatch is the minimum length of a match.
+	minMatch = 4
+
+	// mfLimit is the minimum distance between the start of the last match and
+	// the end of the block.
+	mfLimit = 12
+
+	// lastLiterals is the minimum number of literals at the end of the block.
+	lastLiterals = 5
+
+	// maxOffset is the maximum distance between a match and its reference.
+	maxOffset = 1<<16 - 1
+
+	// hashLog is the log2 of the size of the compressor's hash table.
+	hashLog = 12
+)
+
+// ErrCorrupt is returned by Decompress if its input is not a valid LZ4 block,
+// or if the decompressed block doesn't fit in its output buffer.
+var ErrCorrupt = errors.New("corrupt LZ4 block")
+
+// CompressBound returns the maximum size of a compressed block of n bytes.
+func CompressBound(n int) int {
+	return n + n/255 + 16
+}
+
+func hash(u uint32) uint32 {
+	return (u * 2654435761) >> (32 - hashLog)
+}
+
+// Compress compresses src into dst and returns the size of the compressed
+// block.
+//
+// Preconditions: len(dst) >= CompressBound(len(src)).
+func Compress(dst, src []byte) int {
+	// table maps the hash of 4-byte sequences to 1 + the offset in src at
+	// which they were last seen.
+	var table [1 << hashLog]int32
+	di := 0
+	anchor := 0
+	if len(src) > mfLimit {
+		for si := 0; si < len(src)-mfLimit; {
+			u := binary.LittleEndian.Uint32(src[si:])
+			h := hash(u)
+			ref := int(table[h]) - 1
+			table[h] = int32(si + 1)
+			if ref < 0 || si-ref > maxOffset || binary.LittleEndian.Uint32(src[ref:]) != u {
+				si++
+				continue
+			}
+			n := minMatch
+			for si+n < len(src)-lastLiterals && src[ref+n] == src[si+n] {
+				n++
+			}
+			di = putSequence(dst, di, src[anchor:si], si-ref, n)
+			si += n
+			anchor = si
+		}
+	}
+	// The last sequence consists of only literals.
+	token := di
+	di = putLiterals(dst, di+1, src[anchor:])
+	dst[token] = literalsToken(len(src) - anchor)
+	return di
+}
+
+// putSequence writes a sequence consisting of the given literals followed by
+// a match of length n at distance offset to dst[di:], and returns the offset
+// in dst following the sequence.
+func putSequence(dst []byte, di int, literals []byte, offset, n int) int {
+	token := di
+	di = putLiterals(dst, di+1, literals)
+	binary.LittleEndian.PutUint16(dst[di:], uint16(offset))
+	di += 2
+	n -= minMatch
+	if n >= 0xf {
+		dst[token] = literalsToken(len(literals)) | 0xf
+		return putLength(dst, di, n-0xf)
+	}
+	dst[token] = literalsToken(len(literals)) | byte(n)
+	return di
+}
+
+// literalsToken returns the high 4 bits of the token of a sequence with n
+// literals.
+func literalsToken(n int) byte {
+	return byte(min(n, 0xf)) << 4
+}
+
+// putLiterals writes the optional literal length bytes of a sequence followed
+// by the literals themselves to dst[di:], and returns the offset in dst
+// following the literals.
+func putLiterals(dst []byte, di int, literals []byte) int {
+	if len(literals) >= 0xf {
+		di = putLength(dst, di, len(literals)-0xf)
+	}
+	return di + copy(dst[di:], literals)
+}
+
+// putLength writes the optional length bytes representing n to dst[di:], and
+// returns the offset in dst following them.
+func putLength(dst []byte, di int, n int) int {
+	for ; n >= 0xff; n -= 0xff {
+		dst[di] = 0xff
+		di++
+	}
+	dst[di] = byte(n)
+	return di + 1
+}
+
+// Decompress decompresses the LZ4 block src into dst and returns the size of
+// the decompressed block.
+func Decompress(dst, src []byte) (int, error) {
+	si, di := 0, 0
+	for {
+		if si >= len(src) {
+			return 0, ErrCorrupt
+		}
+		token := src[si]
+		si++
+
+		literals := int(token >> 4)
+		if literals == 0xf {
+			n, ok := getLength(src, &si)
+			if !ok {
+				return 0, ErrCorrupt
+			}
+			literals += n
+		}
+		if literals > len(src)-si || literals > len(dst)-di {
+			return 0, ErrCorrupt
+		}
+		di += copy(dst[di:], src[si:si+literals])
+		si += literals
+		if si == len(src) {
+			// This was the last sequence.
+			return di, nil
+		}
+
+		if len(src)-si < 2 {
+			return 0, ErrCorrupt
+		}
+		offset := int(binary.LittleEndian.Uint16(src[si:]))
+		si += 2
+		if offset == 0 || offset > di {
+			return 0, ErrCorrupt
+		}
+		n := int(token & 0xf)
+		if n == 0xf {
+			m, ok := getLength(src, &si)
+			if !ok {
+				return 0, ErrCorrupt
+			}
+			n += m
+		}
+		n += minMatch
+		if n > len(dst)-di {
+			return 0, ErrCorrupt
+		}
+		if offset >= n {
+			di += copy(dst[di:di+n], dst[di-offset:])
+			continue
+		}
+		// The match overlaps the bytes it produces, so it must be copied
+		// byte-by-byte.
+		for end := di + n; di < end; di++ {
+			dst[di] = dst[di-offset]
+		}
+	}
+}
+
+// getLength reads optional length bytes from src[*si:], advancing *si past
+// them. It returns false if src ends before the last length byte.
+func getLength(src []byte, si *int) (int, bool) {
+	n := 0
+	for {
+		if *si >= len(src) {
+			return 0, false
+		}
+		b := src[*si]
+		*si++
+		n += int(b)
+		if b != 0xff {
+			return n, true
+		}
+	}
+}
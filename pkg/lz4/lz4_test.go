@@ -0,0 +1,90 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lz4
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	random := make([]byte, 1<<16)
+	rand.New(rand.NewSource(0)).Read(random)
+	text := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 1000)
+	mixed := append(bytes.Repeat([]byte{0}, 300), random[:300]...)
+	mixed = append(mixed, bytes.Repeat([]byte("ab"), 300)...)
+
+	for _, test := range []struct {
+		name string
+		data []byte
+	}{
+		{name: "Empty", data: nil},
+		{name: "Short", data: []byte("abc")},
+		{name: "MatchLimit", data: []byte("aaaaaaaaaaaaa")},
+		{name: "Zeroes", data: make([]byte, 4096)},
+		{name: "Random", data: random},
+		{name: "Text", data: text},
+		{name: "Mixed", data: mixed},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			compressed := make([]byte, CompressBound(len(test.data)))
+			n := Compress(compressed, test.data)
+			if n > len(compressed) {
+				t.Fatalf("Compress returned %d, larger than CompressBound %d", n, len(compressed))
+			}
+			got := make([]byte, len(test.data))
+			m, err := Decompress(got, compressed[:n])
+			if err != nil {
+				t.Fatalf("Decompress failed: %v", err)
+			}
+			if !bytes.Equal(got[:m], test.data) {
+				t.Errorf("Decompress(Compress(data)) != data (compressed to %d bytes, decompressed to %d bytes)", n, m)
+			}
+		})
+	}
+}
+
+func TestCompressionRatio(t *testing.T) {
+	data := make([]byte, 4096)
+	compressed := make([]byte, CompressBound(len(data)))
+	if n := Compress(compressed, data); n > 64 {
+		t.Errorf("Compress(zero page): got %d bytes, want <= 64", n)
+	}
+}
+
+func TestDecompressCorrupt(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	compressed := make([]byte, CompressBound(len(data)))
+	compressed = compressed[:Compress(compressed, data)]
+
+	for _, test := range []struct {
+		name string
+		src  []byte
+		dst  []byte
+	}{
+		{name: "Empty", src: nil, dst: make([]byte, len(data))},
+		{name: "Truncated", src: compressed[:len(compressed)-1], dst: make([]byte, len(data))},
+		{name: "ShortOutput", src: compressed, dst: make([]byte, len(data)-1)},
+		{name: "ZeroOffset", src: []byte{0x10, 'a', 0, 0}, dst: make([]byte, 16)},
+		{name: "OffsetBeforeStart", src: []byte{0x10, 'a', 2, 0}, dst: make([]byte, 16)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Decompress(test.dst, test.src); err != ErrCorrupt {
+				t.Errorf("Decompress: got err %v, want %v", err, ErrCorrupt)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package merkletree computes Merkle trees and file digests in the format
+// used by Linux's fs-verity, with SHA-256 hashes and 4096-byte blocks.
+//
+// See Documentation/filesystems/fsverity.rst in the Linux source tree.
+package merkletree
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// BlockSize is the size of both data blocks and hash blocks.
+	BlockSize = 4096
+
+	// DigestSize is the size of hashes and file digests.
+	DigestSize = sha256.Size
+
+	// MaxSaltSize is the maximum size of a salt.
+	MaxSaltSize = 32
+
+	hashesPerBlock = BlockSize / DigestSize
+
+	// logBlockSize is log2(BlockSize).
+	logBlockSize = 12
+
+	// hashAlgSHA256 is FS_VERITY_HASH_ALG_SHA256.
+	hashAlgSHA256 = 1
+
+	// descriptorSize is the size of struct fsverity_descriptor.
+	descriptorSize = 256
+
+	// saltBlockSize is the SHA-256 message block size, to which salts are
+	// padded before being prepended to hashed blocks.
+	saltBlockSize = sha256.BlockSize
+)
+
+// ErrVerification is returned when data doesn't match its Merkle tree.
+var ErrVerification = errors.New("Merkle tree verification failed")
+
+// Tree is the Merkle tree of a file's data.
+//
+// Only the hashes of data blocks are retained; the upper levels of the tree
+// are only needed to compute the root hash.
+type Tree struct {
+	// Size is the size in bytes of the data.
+	Size uint64
+
+	// Salt is prepended to each hashed block, after padding.
+	Salt []byte
+
+	// Leaves contains the hash of each data block.
+	Leaves []byte
+
+	// RootHash is the tree's root hash.
+	RootHash [DigestSize]byte
+}
+
+// Generate returns the Merkle tree for size bytes of data that are read by
+// calling readAt, which must fill buf with the data at offset off or return
+// an error.
+func Generate(size uint64, salt []byte, readAt func(buf []byte, off uint64) error) (*Tree, error) {
+	if len(salt) > MaxSaltSize {
+		return nil, fmt.Errorf("salt size %d exceeds maximum %d", len(salt), MaxSaltSize)
+	}
+	t := &Tree{
+		Size: size,
+		Salt: append([]byte(nil), salt...),
+	}
+	if size == 0 {
+		// The root hash of an empty file is all zeroes.
+		return t, nil
+	}
+
+	numBlocks := (size + BlockSize - 1) / BlockSize
+	t.Leaves = make([]byte, 0, numBlocks*DigestSize)
+	buf := make([]byte, BlockSize)
+	for off := uint64(0); off < size; off += BlockSize {
+		n := min(size-off, BlockSize)
+		if err := readAt(buf[:n], off); err != nil {
+			return nil, err
+		}
+		clear(buf[n:])
+		t.Leaves = t.hashBlock(t.Leaves, buf)
+	}
+
+	// Hash each level into the level above it until a single hash remains.
+	level := t.Leaves
+	for len(level) > DigestSize {
+		next := make([]byte, 0, (len(level)/DigestSize+hashesPerBlock-1)/hashesPerBlock*DigestSize)
+		for off := 0; off < len(level); off += BlockSize {
+			n := copy(buf, level[off:])
+			clear(buf[n:])
+			next = t.hashBlock(next, buf)
+		}
+		level = next
+	}
+	copy(t.RootHash[:], level)
+	return t, nil
+}
+
+// hashBlock appends the salted hash of block to b.
+func (t *Tree) hashBlock(b []byte, block []byte) []byte {
+	h := sha256.New()
+	if len(t.Salt) != 0 {
+		var padded [saltBlockSize]byte
+		copy(padded[:], t.Salt)
+		h.Write(padded[:])
+	}
+	h.Write(block)
+	return h.Sum(b)
+}
+
+// Digest returns the fs-verity file digest of the data, as reported by
+// FS_IOC_MEASURE_VERITY. It is the hash of the file's fsverity_descriptor.
+func (t *Tree) Digest() [DigestSize]byte {
+	var desc [descriptorSize]byte
+	desc[0] = 1 // version
+	desc[1] = hashAlgSHA256
+	desc[2] = logBlockSize
+	desc[3] = uint8(len(t.Salt))
+	// desc[4:8] is sig_size, which is always 0 when computing the digest.
+	binary.LittleEndian.PutUint64(desc[8:], t.Size)
+	copy(desc[16:], t.RootHash[:])
+	copy(desc[80:], t.Salt)
+	return sha256.Sum256(desc[:])
+}
+
+// VerifyBlock verifies data, which must contain data block idx of the file.
+// Only the final block may be shorter than BlockSize.
+func (t *Tree) VerifyBlock(idx uint64, data []byte) error {
+	if idx >= uint64(len(t.Leaves)/DigestSize) {
+		return fmt.Errorf("block %d out of range: %w", idx, ErrVerification)
+	}
+	want := t.Leaves[idx*DigestSize : (idx+1)*DigestSize]
+	if uint64(len(data)) != min(t.Size-idx*BlockSize, BlockSize) {
+		return fmt.Errorf("block %d has size %d: %w", idx, len(data), ErrVerification)
+	}
+	var sum []byte
+	if len(data) == BlockSize {
+		sum = t.hashBlock(nil, data)
+	} else {
+		var buf [BlockSize]byte
+		copy(buf[:], data)
+		sum = t.hashBlock(nil, buf[:])
+	}
+	if string(sum) != string(want) {
+		return fmt.Errorf("block %d: %w", idx, ErrVerification)
+	}
+	return nil
+}
+
+// Verify verifies data, which must contain the file's data starting at the
+// beginning of block firstBlock and end at a block boundary or at the end of
+// the file.
+func (t *Tree) Verify(firstBlock uint64, data []byte) error {
+	for idx := firstBlock; len(data) > 0; idx++ {
+		n := min(len(data), BlockSize)
+		if err := t.VerifyBlock(idx, data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merkletree
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func generate(t *testing.T, data, salt []byte) *Tree {
+	t.Helper()
+	tree, err := Generate(uint64(len(data)), salt, func(buf []byte, off uint64) error {
+		copy(buf, data[off:])
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	return tree
+}
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i*31 + i/BlockSize)
+	}
+	return data
+}
+
+// TestEmptyDigest checks the digest of an empty file against the value
+// reported by `fsverity digest` on Linux.
+func TestEmptyDigest(t *testing.T) {
+	const want = "3d248ca542a24fc62d1c43b916eae5016878e2533c88238480b26128a1f1af95"
+	tree := generate(t, nil, nil)
+	if tree.RootHash != [DigestSize]byte{} {
+		t.Errorf("root hash of empty file: got %x, want zeroes", tree.RootHash)
+	}
+	if got := tree.Digest(); hex.EncodeToString(got[:]) != want {
+		t.Errorf("digest of empty file: got %x, want %s", got, want)
+	}
+}
+
+func TestRootHash(t *testing.T) {
+	salt := []byte("salt")
+	paddedSalt := make([]byte, saltBlockSize)
+	copy(paddedSalt, salt)
+	hash := func(salt, block []byte) []byte {
+		padded := make([]byte, BlockSize)
+		copy(padded, block)
+		sum := sha256.Sum256(append(append([]byte(nil), salt...), padded...))
+		return sum[:]
+	}
+
+	for _, test := range []struct {
+		name string
+		size int
+		// want computes the expected root hash from data, with the given
+		// padded salt.
+		want func(data, salt []byte) []byte
+	}{
+		{
+			name: "partial block",
+			size: 100,
+			want: func(data, salt []byte) []byte {
+				return hash(salt, data)
+			},
+		},
+		{
+			name: "one block",
+			size: BlockSize,
+			want: func(data, salt []byte) []byte {
+				return hash(salt, data)
+			},
+		},
+		{
+			name: "two blocks",
+			size: BlockSize + 1,
+			want: func(data, salt []byte) []byte {
+				leaves := append(hash(salt, data[:BlockSize]), hash(salt, data[BlockSize:])...)
+				return hash(salt, leaves)
+			},
+		},
+		{
+			name: "three levels",
+			size: (hashesPerBlock + 1) * BlockSize,
+			want: func(data, salt []byte) []byte {
+				var leaves []byte
+				for off := 0; off < len(data); off += BlockSize {
+					leaves = append(leaves, hash(salt, data[off:off+BlockSize])...)
+				}
+				level1 := append(hash(salt, leaves[:BlockSize]), hash(salt, leaves[BlockSize:])...)
+				return hash(salt, level1)
+			},
+		},
+	} {
+		for _, salted := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s, salted %t", test.name, salted), func(t *testing.T) {
+				data := testData(test.size)
+				var treeSalt, hashSalt []byte
+				if salted {
+					treeSalt, hashSalt = salt, paddedSalt
+				}
+				tree := generate(t, data, treeSalt)
+				if want := test.want(data, hashSalt); !bytes.Equal(tree.RootHash[:], want) {
+					t.Errorf("got root hash %x, want %x", tree.RootHash, want)
+				}
+				if err := tree.Verify(0, data); err != nil {
+					t.Errorf("Verify failed: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestDigestCoversSizeAndSalt(t *testing.T) {
+	data := testData(BlockSize)
+	digest := generate(t, data, nil).Digest()
+	// Trailing zeroes don't change the root hash, but change the digest.
+	if got := generate(t, data[:BlockSize-1], nil).Digest(); got == digest {
+		t.Errorf("digests of data with different sizes are equal")
+	}
+	if got := generate(t, data, []byte{1}).Digest(); got == digest {
+		t.Errorf("digests with different salts are equal")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	data := testData(3*BlockSize + 10)
+	tree := generate(t, data, nil)
+
+	if err := tree.Verify(1, data[BlockSize:]); err != nil {
+		t.Errorf("Verify of unmodified data failed: %v", err)
+	}
+	if err := tree.Verify(1, data[BlockSize:2*BlockSize-1]); !errors.Is(err, ErrVerification) {
+		t.Errorf("Verify of truncated block: got error %v, want %v", err, ErrVerification)
+	}
+	if err := tree.Verify(4, data[:1]); !errors.Is(err, ErrVerification) {
+		t.Errorf("Verify beyond end of file: got error %v, want %v", err, ErrVerification)
+	}
+
+	modified := append([]byte(nil), data...)
+	modified[len(modified)-1]++
+	if err := tree.Verify(0, modified[:3*BlockSize]); err != nil {
+		t.Errorf("Verify of unmodified blocks failed: %v", err)
+	}
+	if err := tree.Verify(3, modified[3*BlockSize:]); !errors.Is(err, ErrVerification) {
+		t.Errorf("Verify of modified block: got error %v, want %v", err, ErrVerification)
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	want := errors.New("read failed")
+	if _, err := Generate(BlockSize, nil, func([]byte, uint64) error { return want }); err != want {
+		t.Errorf("Generate: got error %v, want %v", err, want)
+	}
+	if _, err := Generate(BlockSize, make([]byte, MaxSaltSize+1), nil); err == nil {
+		t.Errorf("Generate succeeded with oversized salt")
+	}
+}
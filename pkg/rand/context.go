@@ -0,0 +1,39 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rand
+
+import (
+	"io"
+
+	"gvisor.dev/gvisor/pkg/context"
+)
+
+// contextID is the rand package's type for context.Context.Value keys.
+type contextID int
+
+const (
+	// CtxReader is a Context.Value key for an io.Reader that replaces Reader
+	// as the source of random bytes.
+	CtxReader contextID = iota
+)
+
+// ReaderFromContext returns the source of random bytes for ctx, which is
+// Reader unless ctx provides a replacement.
+func ReaderFromContext(ctx context.Context) io.Reader {
+	if v := ctx.Value(CtxReader); v != nil {
+		return v.(io.Reader)
+	}
+	return Reader
+}
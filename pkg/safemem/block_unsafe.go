@@ -147,6 +147,26 @@ func (b Block) TakeFirst64(n uint64) Block {
 	}
 }
 
+// Coalesce returns a Block equivalent to b followed by b2 and true if b2
+// immediately follows b in memory and both Blocks have the same safecopy
+// requirement. Otherwise, Coalesce returns (Block{}, false).
+func (b Block) Coalesce(b2 Block) (Block, bool) {
+	if b.length == 0 {
+		return b2, true
+	}
+	if b2.length == 0 {
+		return b, true
+	}
+	if uintptr(b.start)+uintptr(b.length) != uintptr(b2.start) || b.needSafecopy != b2.needSafecopy {
+		return Block{}, false
+	}
+	return Block{
+		start:        b.start,
+		length:       b.length + b2.length,
+		needSafecopy: b.needSafecopy,
+	}, true
+}
+
 // ToSlice returns a []byte equivalent to b.
 func (b Block) ToSlice() []byte {
 	return gohacks.Slice((*byte)(b.start), b.length)
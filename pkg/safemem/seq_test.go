@@ -215,3 +215,21 @@ func TestBlockSeqDropBeyondLimit(t *testing.T) {
 		t.Errorf("%v.NumBytes(): got %d, wanted %d", bs, got, want)
 	}
 }
+
+func TestBlockCoalesce(t *testing.T) {
+	buf := make([]byte, 8)
+	b1 := BlockFromSafeSlice(buf[:4])
+	b2 := BlockFromSafeSlice(buf[4:])
+	if b, ok := b1.Coalesce(b2); !ok || b != BlockFromSafeSlice(buf) {
+		t.Errorf("Coalesce of adjacent Blocks: got (%v, %t), wanted (%v, true)", b, ok, BlockFromSafeSlice(buf))
+	}
+	if _, ok := b2.Coalesce(b1); ok {
+		t.Errorf("Coalesce of non-adjacent Blocks: got true, wanted false")
+	}
+	if _, ok := b1.Coalesce(BlockFromUnsafeSlice(buf[4:])); ok {
+		t.Errorf("Coalesce of Blocks with different safecopy requirements: got true, wanted false")
+	}
+	if b, ok := b1.Coalesce(Block{}); !ok || b != b1 {
+		t.Errorf("Coalesce with empty Block: got (%v, %t), wanted (%v, true)", b, ok, b1)
+	}
+}
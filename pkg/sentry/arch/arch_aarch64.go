@@ -24,6 +24,7 @@ import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/cpuid"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/sentry/arch/fpu"
 	rpb "gvisor.dev/gvisor/pkg/sentry/arch/registers_go_proto"
 )
@@ -203,18 +204,6 @@ func (s *State) PtraceSetRegs(src io.Reader) (int, error) {
 	return ptraceRegistersSize, nil
 }
 
-// PtraceGetFPRegs implements Context.PtraceGetFPRegs.
-func (s *State) PtraceGetFPRegs(dst io.Writer) (int, error) {
-	// TODO(gvisor.dev/issue/1238): floating-point is not supported.
-	return 0, nil
-}
-
-// PtraceSetFPRegs implements Context.PtraceSetFPRegs.
-func (s *State) PtraceSetFPRegs(src io.Reader) (int, error) {
-	// TODO(gvisor.dev/issue/1238): floating-point is not supported.
-	return 0, nil
-}
-
 // Register sets defined in include/uapi/linux/elf.h.
 const (
 	_NT_PRSTATUS = 1
@@ -230,6 +219,15 @@ func (s *State) PtraceGetRegSet(regset uintptr, dst io.Writer, maxlen int, _ cpu
 			return 0, linuxerr.EFAULT
 		}
 		return s.PtraceGetRegs(dst)
+	case _NT_PRFPREG:
+		return s.fpState.PtraceGetFPRegs(dst, maxlen)
+	case _NT_ARM_TLS:
+		if maxlen < 8 {
+			return 0, linuxerr.EFAULT
+		}
+		var tls [8]byte
+		hostarch.ByteOrder.PutUint64(tls[:], s.Regs.TPIDR_EL0)
+		return dst.Write(tls[:])
 	default:
 		return 0, linuxerr.EINVAL
 	}
@@ -243,6 +241,22 @@ func (s *State) PtraceSetRegSet(regset uintptr, src io.Reader, maxlen int, _ cpu
 			return 0, linuxerr.EFAULT
 		}
 		return s.PtraceSetRegs(src)
+	case _NT_PRFPREG:
+		return s.fpState.PtraceSetFPRegs(src, maxlen)
+	case _NT_ARM_TLS:
+		if maxlen < 8 {
+			return 0, linuxerr.EFAULT
+		}
+		var tls [8]byte
+		if _, err := io.ReadFull(src, tls[:]); err != nil {
+			return 0, err
+		}
+		v := hostarch.ByteOrder.Uint64(tls[:])
+		if v >= uint64(maxAddr64) {
+			return 0, linuxerr.EINVAL
+		}
+		s.Regs.TPIDR_EL0 = v
+		return len(tls), nil
 	default:
 		return 0, linuxerr.EINVAL
 	}
@@ -17,12 +17,33 @@
 
 package fpu
 
+import (
+	"io"
+
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+)
+
 const (
 	// fpsimdMagic is the magic number which is used in fpsimd_context.
 	fpsimdMagic = 0x46508001
 
 	// fpsimdContextSize is the size of fpsimd_context.
 	fpsimdContextSize = 0x210
+
+	// fpsrOffset is the offset of fpsr in fpsimd_context. fpcr immediately
+	// follows fpsr.
+	fpsrOffset = 8
+
+	// vregsOffset is the offset of vregs in fpsimd_context.
+	vregsOffset = 16
+
+	// vregsSize is the size of the V0-V31 registers.
+	vregsSize = 32 * 16
+
+	// ptraceFPRegsSize is the size of struct user_fpsimd_state, the layout
+	// used by ptrace(PTRACE_GETREGSET, NT_PRFPREG): vregs, fpsr, fpcr and 8
+	// reserved bytes.
+	ptraceFPRegsSize = vregsSize + 16
 )
 
 // initAarch64FPState sets up initial state.
@@ -63,3 +84,31 @@ func (s *State) Fork() State {
 func (s *State) BytePointer() *byte {
 	return &(*s)[0]
 }
+
+// PtraceGetFPRegs implements ptrace(PTRACE_GETREGSET, NT_PRFPREG) by writing
+// the floating point registers to dst as a struct user_fpsimd_state.
+func (s *State) PtraceGetFPRegs(dst io.Writer, maxlen int) (int, error) {
+	if maxlen < ptraceFPRegsSize {
+		return 0, linuxerr.EFAULT
+	}
+	var f [ptraceFPRegsSize]byte
+	copy(f[:vregsSize], (*s)[vregsOffset:])
+	copy(f[vregsSize:vregsSize+8], (*s)[fpsrOffset:fpsrOffset+8])
+	return dst.Write(f[:])
+}
+
+// PtraceSetFPRegs implements ptrace(PTRACE_SETREGSET, NT_PRFPREG) by reading
+// a struct user_fpsimd_state from src.
+func (s *State) PtraceSetFPRegs(src io.Reader, maxlen int) (int, error) {
+	if maxlen < ptraceFPRegsSize {
+		return 0, linuxerr.EFAULT
+	}
+	var f [ptraceFPRegsSize]byte
+	n, err := io.ReadFull(src, f[:])
+	if err != nil {
+		return 0, err
+	}
+	copy((*s)[vregsOffset:], f[:vregsSize])
+	copy((*s)[fpsrOffset:fpsrOffset+8], f[vregsSize:vregsSize+8])
+	return n, nil
+}
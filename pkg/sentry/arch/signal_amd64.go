@@ -85,6 +85,25 @@ type UContext64 struct {
 	Sigset   linux.SignalSet
 }
 
+// MinSigStackSize returns the minimum size of a signal stack that can hold
+// the frames set up by SignalSetup, as reported by AT_MINSIGSTKSZ. Compare
+// Linux's arch/x86/kernel/signal.c:init_sigframe_size().
+func MinSigStackSize(featureSet cpuid.FeatureSet) uint64 {
+	// The floating point state is always large enough to hold all host state
+	// (see fpu.NewState), but is aligned as required by featureSet.
+	hostFeatureSet := cpuid.HostFeatureSet()
+	fpSize, _ := hostFeatureSet.ExtendedStateSize()
+	fpSize -= hostFeatureSet.AMXExtendedStateSize()
+	_, fpAlign := featureSet.ExtendedStateSize()
+	var uc UContext64
+	// See SignalSetup: the floating point state is followed by the restorer
+	// address, the UContext64 and the siginfo, each with worst case
+	// alignment padding.
+	size := uint64(fpSize) + fpu.FP_XSTATE_MAGIC2_SIZE + uint64(fpAlign-1) +
+		8 + uint64(uc.SizeBytes()) + 128 + 15 + 8
+	return (size + 15) &^ 15
+}
+
 // SignalSetup implements Context.SignalSetup. (Compare to Linux's
 // arch/x86/kernel/signal.c:__setup_rt_frame().)
 func (c *Context64) SignalSetup(st *Stack, act *linux.SigAction, info *linux.SignalInfo, alt *linux.SignalStack, sigset linux.SignalSet, featureSet cpuid.FeatureSet) error {
@@ -73,6 +73,16 @@ type UContext64 struct {
 	MContext SignalContext64
 }
 
+// MinSigStackSize returns the minimum size of a signal stack that can hold
+// the frames set up by SignalSetup, as reported by AT_MINSIGSTKSZ.
+func MinSigStackSize(cpuid.FeatureSet) uint64 {
+	var uc UContext64
+	// See SignalSetup: the frame holds the UContext64 and the siginfo, with
+	// worst case alignment padding.
+	size := uint64(uc.SizeBytes()) + 128 + 15
+	return (size + 15) &^ 15
+}
+
 // SignalSetup implements Context.SignalSetup.
 func (c *Context64) SignalSetup(st *Stack, act *linux.SigAction, info *linux.SignalInfo, alt *linux.SignalStack, sigset linux.SignalSet, featureSet cpuid.FeatureSet) error {
 	sp := st.Bottom
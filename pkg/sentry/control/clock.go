@@ -0,0 +1,47 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control
+
+import (
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+)
+
+// ClockSkewArgs are the arguments and results of the clock skew commands.
+type ClockSkewArgs struct {
+	// Skew is the skew of the sandbox clocks.
+	Skew kernel.ClockSkew `json:"skew"`
+}
+
+// Clock provides functions to skew the clocks of the sandbox.
+type Clock struct {
+	Kernel *kernel.Kernel
+}
+
+// SetSkew replaces the skew of the sandbox clocks. See
+// kernel.Timekeeper.SetClockSkew.
+func (c *Clock) SetSkew(args *ClockSkewArgs, _ *struct{}) error {
+	if err := c.Kernel.Timekeeper().SetClockSkew(args.Skew); err != nil {
+		return err
+	}
+	log.Infof("Clock skew set: %+v", args.Skew)
+	return nil
+}
+
+// GetSkew returns the skew of the sandbox clocks.
+func (c *Clock) GetSkew(_ *struct{}, out *ClockSkewArgs) error {
+	out.Skew = c.Kernel.Timekeeper().ClockSkew()
+	return nil
+}
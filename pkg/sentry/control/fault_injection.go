@@ -0,0 +1,49 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control
+
+import (
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+)
+
+// FaultInjectionArgs are the arguments and results of the fault injection
+// commands.
+type FaultInjectionArgs struct {
+	// Rules is the list of fault injection rules. An empty list disables
+	// fault injection.
+	Rules []kernel.FaultInjectionRule `json:"rules"`
+}
+
+// FaultInjection provides functions to inject faults into syscalls made by
+// applications in the sandbox.
+type FaultInjection struct {
+	Kernel *kernel.Kernel
+}
+
+// Set replaces the fault injection rules of the sandbox.
+func (f *FaultInjection) Set(args *FaultInjectionArgs, _ *struct{}) error {
+	if err := f.Kernel.SetFaultInjectionRules(args.Rules); err != nil {
+		return err
+	}
+	log.Infof("Fault injection rules set: %+v", args.Rules)
+	return nil
+}
+
+// Get returns the fault injection rules of the sandbox.
+func (f *FaultInjection) Get(_ *struct{}, out *FaultInjectionArgs) error {
+	out.Rules = f.Kernel.FaultInjectionRules()
+	return nil
+}
@@ -15,9 +15,15 @@
 package control
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
 	"runtime"
 	"runtime/pprof"
 	"runtime/trace"
+	"strconv"
+	"strings"
 	"time"
 
 	"gvisor.dev/gvisor/pkg/fd"
@@ -125,6 +131,11 @@ type HeapProfileOpts struct {
 	// not affect the data collected however, as the heap will
 	// continue only the memory associated with the last alloc.
 	Delay time.Duration `json:"delay"`
+
+	// Delta indicates that the profile should only contain the allocations
+	// and frees that happened during Delay. Delta profiles are written in
+	// the legacy text format, with symbolized stacks.
+	Delta bool `json:"delta"`
 }
 
 // Heap generates a heap profile.
@@ -136,6 +147,12 @@ func (p *Profile) Heap(o *HeapProfileOpts, _ *struct{}) error {
 	output := o.FilePayload.Files[0]
 	defer output.Close()
 
+	var before []runtime.MemProfileRecord
+	if o.Delta {
+		runtime.GC()
+		before = memProfile()
+	}
+
 	// Wait for the given delay.
 	select {
 	case <-time.After(o.Delay):
@@ -146,13 +163,94 @@ func (p *Profile) Heap(o *HeapProfileOpts, _ *struct{}) error {
 	runtime.GC()
 
 	// Write the given profile.
+	if o.Delta {
+		return writeHeapProfileDelta(output, before, memProfile())
+	}
 	return pprof.WriteHeapProfile(output)
 }
 
+// memProfile returns all records of the runtime memory profile.
+func memProfile() []runtime.MemProfileRecord {
+	n, _ := runtime.MemProfile(nil, true)
+	for {
+		// Allocate room for a few more records, in case more are added
+		// between calls.
+		records := make([]runtime.MemProfileRecord, n+50)
+		var ok bool
+		n, ok = runtime.MemProfile(records, true)
+		if ok {
+			return records[:n]
+		}
+	}
+}
+
+// writeHeapProfileDelta writes the difference between two memory profiles to
+// w, in the legacy text format written by pprof.Lookup("heap").WriteTo(w, 1).
+// Records that didn't change between before and after are omitted.
+func writeHeapProfileDelta(w io.Writer, before, after []runtime.MemProfileRecord) error {
+	prev := make(map[[32]uintptr]runtime.MemProfileRecord, len(before))
+	for _, r := range before {
+		prev[r.Stack0] = r
+	}
+	var (
+		records []runtime.MemProfileRecord
+		total   runtime.MemProfileRecord
+	)
+	for _, r := range after {
+		if b, ok := prev[r.Stack0]; ok {
+			r.AllocBytes -= b.AllocBytes
+			r.AllocObjects -= b.AllocObjects
+			r.FreeBytes -= b.FreeBytes
+			r.FreeObjects -= b.FreeObjects
+		}
+		if r.AllocObjects == 0 && r.FreeObjects == 0 {
+			continue
+		}
+		// Objects allocated before the profile started may have been freed
+		// during the profile, so in-use counts can't be negative.
+		if r.FreeObjects > r.AllocObjects {
+			r.FreeObjects, r.FreeBytes = r.AllocObjects, r.AllocBytes
+		}
+		records = append(records, r)
+		total.AllocBytes += r.AllocBytes
+		total.AllocObjects += r.AllocObjects
+		total.FreeBytes += r.FreeBytes
+		total.FreeObjects += r.FreeObjects
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "heap profile: %d: %d [%d: %d] @ heap/%d\n", total.InUseObjects(), total.InUseBytes(), total.AllocObjects, total.AllocBytes, 2*runtime.MemProfileRate)
+	for i := range records {
+		r := &records[i]
+		fmt.Fprintf(bw, "%d: %d [%d: %d] @", r.InUseObjects(), r.InUseBytes(), r.AllocObjects, r.AllocBytes)
+		stack := r.Stack()
+		for _, pc := range stack {
+			fmt.Fprintf(bw, " %#x", pc)
+		}
+		bw.WriteString("\n")
+		frames := runtime.CallersFrames(stack)
+		for {
+			frame, more := frames.Next()
+			if frame.Function != "" {
+				fmt.Fprintf(bw, "#\t%#x\t%s+%#x\t%s:%d\n", frame.PC, frame.Function, frame.PC-frame.Entry, frame.File, frame.Line)
+			}
+			if !more {
+				break
+			}
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
+
 // GoroutineProfileOpts contains options specifically for goroutine profiles.
 type GoroutineProfileOpts struct {
 	// FilePayload is the destination for the profiling output.
 	urpc.FilePayload
+
+	// Annotate indicates that task goroutines should be annotated with the
+	// task that they run and the syscall that it is executing, if any.
+	Annotate bool `json:"annotate"`
 }
 
 // Goroutine dumps out the stack trace for all running goroutines.
@@ -164,7 +262,41 @@ func (p *Profile) Goroutine(o *GoroutineProfileOpts, _ *struct{}) error {
 	output := o.FilePayload.Files[0]
 	defer output.Close()
 
-	return pprof.Lookup("goroutine").WriteTo(output, 2)
+	if !o.Annotate {
+		return pprof.Lookup("goroutine").WriteTo(output, 2)
+	}
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+		return err
+	}
+	_, err := output.Write(annotateGoroutines(buf.Bytes(), p.kernel.TaskGoroutineAnnotations()))
+	return err
+}
+
+// annotateGoroutines appends the annotation of each goroutine in the given
+// stack dump to its "goroutine N [state]:" header line.
+func annotateGoroutines(dump []byte, annotations map[int64]string) []byte {
+	var out bytes.Buffer
+	for _, line := range strings.SplitAfter(string(dump), "\n") {
+		rest, ok := strings.CutPrefix(line, "goroutine ")
+		if !ok {
+			out.WriteString(line)
+			continue
+		}
+		id, _, _ := strings.Cut(rest, " ")
+		goid, err := strconv.ParseInt(id, 10, 64)
+		annotation, found := annotations[goid]
+		if err != nil || !found {
+			out.WriteString(line)
+			continue
+		}
+		header := strings.TrimSuffix(line, "\n")
+		fmt.Fprintf(&out, "%s task %s", header, annotation)
+		if len(header) != len(line) {
+			out.WriteString("\n")
+		}
+	}
+	return out.Bytes()
 }
 
 // BlockProfileOpts contains options specifically for block profiles.
@@ -0,0 +1,82 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestAnnotateGoroutines(t *testing.T) {
+	dump := "goroutine 1 [running]:\n" +
+		"main.main()\n" +
+		"\t/main.go:10 +0x1\n" +
+		"\n" +
+		"goroutine 42 [select]:\n" +
+		"gvisor.dev/gvisor/pkg/sentry/kernel.(*Task).block()\n" +
+		"\t/task_block.go:1 +0x1\n"
+	want := "goroutine 1 [running]:\n" +
+		"main.main()\n" +
+		"\t/main.go:10 +0x1\n" +
+		"\n" +
+		"goroutine 42 [select]: task pid=1 tid=2 syscall=read\n" +
+		"gvisor.dev/gvisor/pkg/sentry/kernel.(*Task).block()\n" +
+		"\t/task_block.go:1 +0x1\n"
+	got := string(annotateGoroutines([]byte(dump), map[int64]string{42: "pid=1 tid=2 syscall=read"}))
+	if got != want {
+		t.Errorf("annotateGoroutines():\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteHeapProfileDelta(t *testing.T) {
+	stack := func(pc uintptr) [32]uintptr {
+		var s [32]uintptr
+		s[0] = pc
+		return s
+	}
+	before := []runtime.MemProfileRecord{
+		{AllocBytes: 100, AllocObjects: 1, Stack0: stack(0x1000)},
+		{AllocBytes: 200, AllocObjects: 2, FreeBytes: 200, FreeObjects: 2, Stack0: stack(0x2000)},
+	}
+	after := []runtime.MemProfileRecord{
+		// Unchanged: omitted.
+		{AllocBytes: 100, AllocObjects: 1, Stack0: stack(0x1000)},
+		// Two more allocations, one more free.
+		{AllocBytes: 400, AllocObjects: 4, FreeBytes: 300, FreeObjects: 3, Stack0: stack(0x2000)},
+		// New.
+		{AllocBytes: 50, AllocObjects: 5, Stack0: stack(0x3000)},
+	}
+	var buf bytes.Buffer
+	if err := writeHeapProfileDelta(&buf, before, after); err != nil {
+		t.Fatalf("writeHeapProfileDelta(): %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if !strings.HasPrefix(lines[0], "heap profile: 6: 150 [7: 250] @ heap/") {
+		t.Errorf("unexpected header: %q", lines[0])
+	}
+	for _, want := range []string{
+		"1: 100 [2: 200] @ 0x2000",
+		"5: 50 [5: 50] @ 0x3000",
+	} {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("profile doesn't contain %q:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "0x1000") {
+		t.Errorf("profile contains unchanged record:\n%s", buf.String())
+	}
+}
@@ -25,7 +25,9 @@ import (
 	"time"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/bpf"
 	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/fdimport"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/host"
@@ -142,6 +144,20 @@ type ExecArgs struct {
 
 	// Limits is the limit set for the process being executed.
 	Limits *limits.LimitSet
+
+	// Rlimits overrides limits in Limits for the process being executed.
+	Rlimits map[limits.LimitType]limits.Limit
+
+	// SeccompFilter is an uncompiled seccomp filter to install on the process
+	// being executed, in addition to any filter that it installs itself. If
+	// SeccompFilter is empty, no filter is installed.
+	SeccompFilter []bpf.Instruction
+
+	// CgroupPath is the path of the cgroup in which to place the process being
+	// executed, relative to the container's cgroup in each mounted cgroup
+	// hierarchy. If CgroupPath is empty, the process is placed in the
+	// container's cgroups.
+	CgroupPath string
 }
 
 // String prints the arguments as a string.
@@ -180,6 +196,26 @@ func ExecAsync(proc *Proc, args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadID
 // newly created thread group and its PID. If the stdio FDs are TTYs, then a
 // TTYFileOperations that wraps the TTY is also returned.
 func (proc *Proc) execAsync(args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadID, *host.TTYFileDescription, error) {
+	// Compile the seccomp filter before creating anything, since it is the
+	// argument most likely to be invalid.
+	var seccompFilter bpf.Program
+	if len(args.SeccompFilter) > 0 {
+		var err error
+		seccompFilter, err = bpf.Compile(args.SeccompFilter, true /* optimize */)
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("compiling seccomp filter: %w", err)
+		}
+	}
+	cgroupPath := fspath.Parse(args.CgroupPath)
+	if cgroupPath.Absolute {
+		return nil, 0, nil, fmt.Errorf("cgroup path %q must be relative to the container's cgroup", args.CgroupPath)
+	}
+	for it := cgroupPath.Begin; it.Ok(); it = it.Next() {
+		if it.String() == ".." {
+			return nil, 0, nil, fmt.Errorf("cgroup path %q must be within the container's cgroup", args.CgroupPath)
+		}
+	}
+
 	// Import file descriptors.
 	fdTable := proc.Kernel.NewFDTable()
 
@@ -198,6 +234,9 @@ func (proc *Proc) execAsync(args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadI
 	if limitSet == nil {
 		limitSet = limits.NewLimitSet()
 	}
+	for lt, l := range args.Rlimits {
+		limitSet.SetUnchecked(lt, l)
+	}
 	initArgs := kernel.CreateProcessArgs{
 		Filename:             args.Filename,
 		Argv:                 args.Argv,
@@ -283,6 +322,12 @@ func (proc *Proc) execAsync(args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadI
 			log.Warningf("cgroup mount for controller %v not found", ctrl)
 			continue
 		}
+		if cgroupPath.HasComponents() {
+			cg, err = cg.Walk(ctx, proc.Kernel.VFS(), cgroupPath)
+			if err != nil {
+				return nil, 0, nil, fmt.Errorf("cgroup %q for controller %v not found: %w", args.CgroupPath, ctrl, err)
+			}
+		}
 		initialCgrps[cg] = struct{}{}
 	}
 	if len(initialCgrps) > 0 {
@@ -294,6 +339,12 @@ func (proc *Proc) execAsync(args *ExecArgs) (*kernel.ThreadGroup, kernel.ThreadI
 		return nil, 0, nil, err
 	}
 
+	if seccompFilter.Length() > 0 {
+		if err := tg.Leader().AppendSyscallFilter(seccompFilter, true /* syncAll */); err != nil {
+			return nil, 0, nil, fmt.Errorf("appending seccomp filter: %w", err)
+		}
+	}
+
 	// Set the foreground process group on the TTY before starting the process.
 	if ttyFile != nil {
 		ttyFile.InitForegroundProcessGroup(tg.ProcessGroup())
@@ -422,8 +473,8 @@ func Processes(k *kernel.Kernel, containerID string, out *[]*Process) error {
 			PPID:    ppid,
 			Threads: threads,
 			STime:   formatStartTime(now, tg.Leader().StartTime()),
-			C:       percentCPU(tg.CPUStats(), tg.Leader().StartTime(), now),
-			Time:    tg.CPUStats().SysTime.String(),
+			C:       percentCPU(tg.AdjustedCPUStats(), tg.Leader().StartTime(), now),
+			Time:    tg.AdjustedCPUStats().SysTime.String(),
 			Cmd:     tg.Leader().Name(),
 			TTY:     ttyName(tg.TTY()),
 		})
@@ -480,7 +531,7 @@ func ContainerUsage(kr *kernel.Kernel) map[string]uint64 {
 	for _, tg := range kr.TaskSet().Root.ThreadGroups() {
 		// We want each tg's usage including reaped children.
 		cid := tg.Leader().ContainerID()
-		stats := tg.CPUStats()
+		stats := tg.AdjustedCPUStats()
 		stats.Accumulate(tg.JoinedChildCPUStats())
 		cusage[cid] += uint64(stats.UserTime.Nanoseconds()) + uint64(stats.SysTime.Nanoseconds())
 	}
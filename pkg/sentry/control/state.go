@@ -24,6 +24,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sentry/state"
 	"gvisor.dev/gvisor/pkg/sentry/watchdog"
+	"gvisor.dev/gvisor/pkg/state/statefile"
 	"gvisor.dev/gvisor/pkg/urpc"
 )
 
@@ -35,6 +36,9 @@ var ErrInvalidFiles = errors.New("exactly one file must be provided")
 type State struct {
 	Kernel   *kernel.Kernel
 	Watchdog *watchdog.Watchdog
+
+	// AsyncIO configures asynchronous writes to pages files.
+	AsyncIO statefile.AsyncOpts
 }
 
 // SaveOpts contains options for the Save RPC call.
@@ -61,6 +65,10 @@ type SaveOpts struct {
 	// Resume indicates if the sandbox process should continue running
 	// after checkpointing.
 	Resume bool
+
+	// RateLimit is the maximum rate at which the checkpoint is written, in
+	// bytes per second. If zero, the rate isn't limited.
+	RateLimit uint64 `json:"rate_limit"`
 }
 
 // Save saves the running system.
@@ -84,6 +92,8 @@ func (s *State) Save(o *SaveOpts, _ *struct{}) error {
 		Key:                o.Key,
 		Metadata:           o.Metadata,
 		MemoryFileSaveOpts: o.MemoryFileSaveOpts,
+		RateLimit:          o.RateLimit,
+		AsyncIO:            s.AsyncIO,
 		Callback: func(err error) {
 			if err == nil {
 				log.Infof("Save succeeded: exiting...")
@@ -112,3 +122,17 @@ func (s *State) Save(o *SaveOpts, _ *struct{}) error {
 	}
 	return saveOpts.Save(s.Kernel.SupervisorContext(), s.Kernel, s.Watchdog)
 }
+
+// ErrNoSave is returned by SaveProgress if no save was started.
+var ErrNoSave = errors.New("no save was started")
+
+// SaveProgress returns the progress of the last save. It may be called while
+// Save is ongoing.
+func (s *State) SaveProgress(_ *struct{}, p *state.SaveProgress) error {
+	progress, ok := state.LastSaveProgress()
+	if !ok {
+		return ErrNoSave
+	}
+	*p = progress
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control
+
+import (
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/abi"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/platform"
+)
+
+// SyscallPathArgs are the arguments to SyscallPaths.Set.
+type SyscallPathArgs struct {
+	// Paths maps syscall names to the wait strategy to use after them.
+	Paths map[string]platform.SyscallPath `json:"paths"`
+}
+
+// SyscallPathStat is the wait strategy and statistics of a syscall.
+type SyscallPathStat struct {
+	// Name is the syscall name.
+	Name string `json:"name"`
+
+	platform.SyscallPathStat
+}
+
+// SyscallPathStats are the results of SyscallPaths.Stats.
+type SyscallPathStats struct {
+	// Stats lists syscalls with a non-default path or recorded waits.
+	Stats []SyscallPathStat `json:"stats"`
+}
+
+// SyscallPaths provides functions to select, per syscall, how the platform
+// waits for applications after returning from syscalls. Only some platforms
+// support it, see platform.SyscallPathTuner.
+type SyscallPaths struct {
+	Kernel *kernel.Kernel
+}
+
+func (s *SyscallPaths) tuner() (platform.SyscallPathTuner, *kernel.SyscallTable, error) {
+	tuner, ok := s.Kernel.Platform.(platform.SyscallPathTuner)
+	if !ok {
+		return nil, nil, fmt.Errorf("platform doesn't support per-syscall paths")
+	}
+	table, ok := kernel.LookupSyscallTable(abi.Linux, arch.Host)
+	if !ok {
+		return nil, nil, fmt.Errorf("no syscall table for %v", arch.Host)
+	}
+	return tuner, table, nil
+}
+
+// Set selects the wait strategy of the given syscalls. Other syscalls are
+// left unchanged.
+func (s *SyscallPaths) Set(args *SyscallPathArgs, _ *struct{}) error {
+	tuner, table, err := s.tuner()
+	if err != nil {
+		return err
+	}
+	sysnos := make(map[string]uintptr, len(args.Paths))
+	for name, path := range args.Paths {
+		if err := path.Validate(); err != nil {
+			return fmt.Errorf("syscall %q: %w", name, err)
+		}
+		sysno, err := table.LookupNo(name)
+		if err != nil {
+			return err
+		}
+		sysnos[name] = sysno
+	}
+	for name, path := range args.Paths {
+		if err := tuner.SetSyscallPath(sysnos[name], path); err != nil {
+			return fmt.Errorf("syscall %q: %w", name, err)
+		}
+	}
+	log.Infof("Syscall paths set: %v", args.Paths)
+	return nil
+}
+
+// Stats returns the wait strategy and statistics of syscalls.
+func (s *SyscallPaths) Stats(_ *struct{}, out *SyscallPathStats) error {
+	tuner, table, err := s.tuner()
+	if err != nil {
+		return err
+	}
+	for _, stat := range tuner.SyscallPathStats() {
+		out.Stats = append(out.Stats, SyscallPathStat{
+			Name:            table.LookupName(stat.Sysno),
+			SyscallPathStat: stat,
+		})
+	}
+	return nil
+}
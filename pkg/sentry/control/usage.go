@@ -19,8 +19,11 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strconv"
 
 	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/fsmetric"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/usage"
@@ -157,6 +160,70 @@ func (u *Usage) Reduce(opts *UsageReduceOpts, out *UsageReduceOutput) error {
 	return nil
 }
 
+// ResizeMemoryArgs contains arguments to Usage.ResizeMemory().
+type ResizeMemoryArgs struct {
+	// TotalBytes is the new total memory of the sandbox, in bytes. It is
+	// rounded up to a multiple of the page size.
+	TotalBytes uint64 `json:"total_bytes"`
+}
+
+// ResizeMemoryResult contains output from Usage.ResizeMemory().
+type ResizeMemoryResult struct {
+	// PreviousBytes is the total memory of the sandbox before the resize. The
+	// 0 value indicates no limit.
+	PreviousBytes uint64 `json:"previous_bytes"`
+
+	// Usage is the memory usage of the sandbox once the resize completed.
+	Usage uint64 `json:"usage"`
+}
+
+// ResizeMemory changes the total memory of the sandbox at runtime, similar to
+// memory hotplug on Linux. The new total is reported to the application by
+// /proc/meminfo and sysinfo(2), and set as the memory limit of the root memory
+// cgroup, if the memory controller is mounted in the sandbox. When shrinking
+// below the current usage, ResizeMemory reclaims memory before returning.
+//
+// It does not change the host memory cgroup of the sandbox, which is the
+// caller's responsibility.
+func (u *Usage) ResizeMemory(args *ResizeMemoryArgs, out *ResizeMemoryResult) error {
+	if args.TotalBytes == 0 {
+		return fmt.Errorf("total memory must be greater than 0")
+	}
+	total, ok := hostarch.Addr(args.TotalBytes).RoundUp()
+	if !ok {
+		return fmt.Errorf("total memory %d is too large", args.TotalBytes)
+	}
+	out.PreviousBytes = usage.TotalMemoryLimit()
+	usage.SetTotalMemoryLimit(uint64(total))
+	log.Infof("Sandbox total memory resized from %d to %d bytes", out.PreviousBytes, total)
+
+	ctx := u.Kernel.SupervisorContext()
+	if cg, err := u.Kernel.CgroupRegistry().FindCgroup(ctx, kernel.CgroupControllerMemory, "/"); err != nil {
+		log.Debugf("Not updating memory cgroup limit: %v", err)
+	} else if err := cg.WriteControl(ctx, "memory.limit_in_bytes", strconv.FormatUint(uint64(total), 10)); err != nil {
+		return fmt.Errorf("updating memory cgroup limit: %w", err)
+	}
+
+	mf := u.Kernel.MemoryFile()
+	mfUsage, err := mf.TotalUsage()
+	if err != nil {
+		return err
+	}
+	snapshot, _ := usage.MemoryAccounting.Copy()
+	out.Usage = mfUsage + snapshot.Mapped
+	if out.Usage > uint64(total) {
+		mf.StartEvictions()
+		mf.WaitForEvictions()
+		runtime.GC()
+		if mfUsage, err = mf.TotalUsage(); err != nil {
+			return err
+		}
+		snapshot, _ = usage.MemoryAccounting.Copy()
+		out.Usage = mfUsage + snapshot.Mapped
+	}
+	return nil
+}
+
 // MemoryUsageRecord contains the mapping and platform memory file.
 type MemoryUsageRecord struct {
 	mmap  uintptr
@@ -0,0 +1,183 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drmproxy
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/drm"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+)
+
+// amdgpuIoctlHandlers handles amdgpu ioctls. DRM_IOCTL_AMDGPU_GEM_USERPTR is
+// not supported, since it would require the host driver to pin application
+// memory, which is not mapped at the same address in the sentry.
+var amdgpuIoctlHandlers = map[uint32]ioctlHandler{
+	drm.DRM_IOCTL_AMDGPU_GEM_CREATE:      ioctlSimple,
+	drm.DRM_IOCTL_AMDGPU_GEM_MMAP:        ioctlSimple,
+	drm.DRM_IOCTL_AMDGPU_CTX:             ioctlSimple,
+	drm.DRM_IOCTL_AMDGPU_BO_LIST:         ioctlTranslated(amdgpuBoListIn),
+	drm.DRM_IOCTL_AMDGPU_CS:              ioctlTranslated(amdgpuCS),
+	drm.DRM_IOCTL_AMDGPU_INFO:            ioctlTranslated(amdgpuInfo),
+	drm.DRM_IOCTL_AMDGPU_GEM_METADATA:    ioctlSimple,
+	drm.DRM_IOCTL_AMDGPU_GEM_WAIT_IDLE:   ioctlSimple,
+	drm.DRM_IOCTL_AMDGPU_GEM_VA:          ioctlSimple,
+	drm.DRM_IOCTL_AMDGPU_WAIT_CS:         ioctlSimple,
+	drm.DRM_IOCTL_AMDGPU_GEM_OP:          ioctlTranslated(amdgpuGemOp),
+	drm.DRM_IOCTL_AMDGPU_WAIT_FENCES:     ioctlTranslated(amdgpuWaitFences),
+	drm.DRM_IOCTL_AMDGPU_VM:              ioctlSimple,
+	drm.DRM_IOCTL_AMDGPU_FENCE_TO_HANDLE: amdgpuFenceToHandle,
+}
+
+// Offsets in struct drm_amdgpu_bo_list_in.
+const (
+	amdgpuBoListNumberOffset   = 8
+	amdgpuBoListInfoSizeOffset = 12
+	amdgpuBoListInfoPtrOffset  = 16
+)
+
+// amdgpuBoListIn translates the struct drm_amdgpu_bo_list_in at the start of
+// b.
+func amdgpuBoListIn(p *ioctlParams, b *paramBuf) error {
+	number := uint64(b.uint32(amdgpuBoListNumberOffset))
+	infoSize := uint64(b.uint32(amdgpuBoListInfoSizeOffset))
+	_, err := p.array(b, amdgpuBoListInfoPtrOffset, number, infoSize, false /* out */)
+	return err
+}
+
+// Offsets in struct drm_amdgpu_cs_in.
+const (
+	amdgpuCSNumChunksOffset = 8
+	amdgpuCSChunksOffset    = 16
+)
+
+// Offsets in struct drm_amdgpu_cs_chunk.
+const (
+	amdgpuCSChunkIDOffset       = 0
+	amdgpuCSChunkLengthDWOffset = 4
+	amdgpuCSChunkDataOffset     = 8
+)
+
+func amdgpuCS(p *ioctlParams, arg *paramBuf) error {
+	// drm_amdgpu_cs_in.chunks points to an array of pointers to struct
+	// drm_amdgpu_cs_chunk.
+	numChunks := uint64(arg.uint32(amdgpuCSNumChunksOffset))
+	chunkPtrs, err := p.array(arg, amdgpuCSChunksOffset, numChunks, 8, false /* out */)
+	if err != nil || chunkPtrs == nil {
+		return err
+	}
+	for i := 0; i < int(numChunks); i++ {
+		chunk, err := p.pointee(chunkPtrs, i*8, drm.SizeofAMDGPUCSChunk, false /* out */)
+		if err != nil {
+			return err
+		}
+		if chunk == nil {
+			// Linux fails with EFAULT.
+			return linuxerr.EFAULT
+		}
+		lengthDW := uint64(chunk.uint32(amdgpuCSChunkLengthDWOffset))
+		data, err := p.array(chunk, amdgpuCSChunkDataOffset, lengthDW, 4, false /* out */)
+		if err != nil {
+			return err
+		}
+		if chunk.uint32(amdgpuCSChunkIDOffset) == drm.AMDGPU_CHUNK_ID_BO_HANDLES {
+			// The chunk contains a struct drm_amdgpu_bo_list_in.
+			if data == nil || len(data.data) < drm.SizeofAMDGPUBoList {
+				return linuxerr.EINVAL
+			}
+			if err := amdgpuBoListIn(p, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Offsets in struct drm_amdgpu_info.
+const (
+	amdgpuInfoReturnPointerOffset = 0
+	amdgpuInfoReturnSizeOffset    = 8
+)
+
+func amdgpuInfo(p *ioctlParams, arg *paramBuf) error {
+	size := uint64(arg.uint32(amdgpuInfoReturnSizeOffset))
+	_, err := p.pointee(arg, amdgpuInfoReturnPointerOffset, size, true /* out */)
+	return err
+}
+
+// Offsets in struct drm_amdgpu_gem_op.
+const (
+	amdgpuGemOpOpOffset    = 4
+	amdgpuGemOpValueOffset = 8
+)
+
+func amdgpuGemOp(p *ioctlParams, arg *paramBuf) error {
+	switch arg.uint32(amdgpuGemOpOpOffset) {
+	case drm.AMDGPU_GEM_OP_GET_GEM_CREATE_INFO:
+		// value points to a struct drm_amdgpu_gem_create_in.
+		_, err := p.pointee(arg, amdgpuGemOpValueOffset, drm.SizeofAMDGPUGemCreateIn, true /* out */)
+		return err
+	case drm.AMDGPU_GEM_OP_SET_PLACEMENT:
+		return nil
+	default:
+		return linuxerr.EINVAL
+	}
+}
+
+// Offsets in struct drm_amdgpu_wait_fences_in.
+const (
+	amdgpuWaitFencesFencesOffset = 0
+	amdgpuWaitFencesCountOffset  = 8
+)
+
+func amdgpuWaitFences(p *ioctlParams, arg *paramBuf) error {
+	count := uint64(arg.uint32(amdgpuWaitFencesCountOffset))
+	_, err := p.array(arg, amdgpuWaitFencesFencesOffset, count, drm.SizeofAMDGPUFence, false /* out */)
+	return err
+}
+
+// Offsets in union drm_amdgpu_fence_to_handle.
+const (
+	amdgpuFenceToHandleWhatOffset   = drm.SizeofAMDGPUFence
+	amdgpuFenceToHandleHandleOffset = 0
+)
+
+// amdgpuFenceToHandle handles DRM_IOCTL_AMDGPU_FENCE_TO_HANDLE, which returns
+// a file descriptor for some values of in.what.
+func amdgpuFenceToHandle(s *ioctlState) (uintptr, error) {
+	p := ioctlParams{cc: s.t}
+	arg, err := s.copyInArg(&p)
+	if err != nil {
+		return 0, err
+	}
+	var name string
+	switch arg.uint32(amdgpuFenceToHandleWhatOffset) {
+	case drm.AMDGPU_FENCE_TO_HANDLE_GET_SYNCOBJ:
+		return p.invoke(s.hostFD, s.cmd, arg)
+	case drm.AMDGPU_FENCE_TO_HANDLE_GET_SYNCOBJ_FD:
+		name = syncobjFileName
+	case drm.AMDGPU_FENCE_TO_HANDLE_GET_SYNC_FILE_FD:
+		name = syncFileName
+	default:
+		return 0, linuxerr.EINVAL
+	}
+	n, err := p.invokeHost(s.hostFD, s.cmd, arg)
+	if err != nil {
+		return n, err
+	}
+	// Linux always returns these file descriptors with O_CLOEXEC.
+	if err := exportFD(s.t, arg, amdgpuFenceToHandleHandleOffset, name, true /* cloexec */); err != nil {
+		return 0, err
+	}
+	return n, p.copyOut()
+}
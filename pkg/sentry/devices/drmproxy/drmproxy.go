@@ -0,0 +1,129 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drmproxy implements proxying of DRM render nodes (/dev/dri/renderD*)
+// from the host, for use by Mesa drivers for non-NVIDIA GPUs.
+//
+// Core DRM ioctls are supported for all drivers. Driver-specific ioctls are
+// supported for the amdgpu and i915 drivers, which are identified by
+// DRM_IOCTL_VERSION when the device is opened. Ioctls are forwarded only if
+// they are known to this package, with pointers embedded in their arguments
+// translated by the sentry. GEM buffer objects are mapped by passing mappings
+// of the render node through to the host. PRIME (dma-buf), sync_file and
+// syncobj file descriptors exported by the host driver are represented in the
+// sandbox by files that support mmap and polling, and may be imported back
+// into any proxied render node.
+package drmproxy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/drm"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/devutil"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fdnotifier"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+const (
+	// DevDir is the directory containing DRM devices.
+	DevDir = "/dev/dri"
+
+	drmDeviceGroupName = "drm"
+)
+
+var renderNodeRegex = regexp.MustCompile(`^renderD\d+$`)
+
+// IsSupportedDevice returns true if path is the path of a DRM render node that
+// can be proxied by this package.
+func IsSupportedDevice(path string) bool {
+	name, ok := strings.CutPrefix(path, DevDir+"/")
+	return ok && renderNodeRegex.MatchString(name)
+}
+
+// drmDevice implements vfs.Device for a DRM render node on the host.
+//
+// +stateify savable
+type drmDevice struct {
+	// name is the path of the device on the host, relative to /dev.
+	name string
+}
+
+// Open implements vfs.Device.Open.
+func (dev *drmDevice) Open(ctx context.Context, mnt *vfs.Mount, d *vfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	client := devutil.GoferClientFromContext(ctx)
+	if client == nil {
+		log.Warningf("devutil.CtxDevGoferClient is not set")
+		return nil, linuxerr.ENOENT
+	}
+	hostFD, err := client.OpenAt(ctx, dev.name, opts.Flags)
+	if err != nil {
+		ctx.Warningf("drmproxy: failed to open host %s: %v", dev.name, err)
+		return nil, err
+	}
+	driver, err := hostDriverName(int32(hostFD))
+	if err != nil {
+		ctx.Warningf("drmproxy: DRM_IOCTL_VERSION failed on host %s: %v", dev.name, err)
+		unix.Close(hostFD)
+		return nil, err
+	}
+	driverHandlers, ok := driverIoctlHandlers[driver]
+	if !ok {
+		ctx.Warningf("drmproxy: unsupported driver %q for %s, only core DRM ioctls will be supported", driver, dev.name)
+	}
+	// Waiting for events is implemented by the sentry, so the host FD is
+	// always non-blocking.
+	if err := unix.SetNonblock(hostFD, true); err != nil {
+		unix.Close(hostFD)
+		return nil, err
+	}
+	fd := &drmFD{
+		hostFD:         int32(hostFD),
+		dev:            dev,
+		driverHandlers: driverHandlers,
+	}
+	fd.mappable.file.hostFD = int32(hostFD)
+	if err := fd.vfsfd.Init(fd, opts.Flags, mnt, d, &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+	}); err != nil {
+		unix.Close(hostFD)
+		return nil, err
+	}
+	if err := fdnotifier.AddFD(int32(hostFD), &fd.queue); err != nil {
+		unix.Close(hostFD)
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
+// Register registers a device that proxies the host DRM render node at
+// hostPath with the given minor device number in vfsObj.
+func Register(vfsObj *vfs.VirtualFilesystem, hostPath string, minor uint32) error {
+	if !IsSupportedDevice(hostPath) {
+		return fmt.Errorf("unsupported DRM device %q", hostPath)
+	}
+	name := strings.TrimPrefix(hostPath, "/dev/")
+	return vfsObj.RegisterDevice(vfs.CharDevice, drm.DRM_MAJOR, minor, &drmDevice{
+		name: name,
+	}, &vfs.RegisterDeviceOptions{
+		GroupName: drmDeviceGroupName,
+		Pathname:  name,
+		FilePerms: 0666,
+	})
+}
@@ -0,0 +1,296 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drmproxy
+
+import (
+	"bytes"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/drm"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+)
+
+func TestIsSupportedDevice(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		want bool
+	}{
+		{path: "/dev/dri/renderD128", want: true},
+		{path: "/dev/dri/renderD129", want: true},
+		{path: "/dev/dri/card0", want: false},
+		{path: "/dev/dri/renderD", want: false},
+		{path: "/dev/renderD128", want: false},
+	} {
+		if got := IsSupportedDevice(test.path); got != test.want {
+			t.Errorf("IsSupportedDevice(%q) = %t, want %t", test.path, got, test.want)
+		}
+	}
+}
+
+// TestIoctlNumbers checks ioctl numbers against values computed from
+// include/uapi/drm/*.h on x86_64.
+func TestIoctlNumbers(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		got  uint32
+		want uint32
+	}{
+		{name: "DRM_IOCTL_VERSION", got: drm.DRM_IOCTL_VERSION, want: 0xc0406400},
+		{name: "DRM_IOCTL_GEM_CLOSE", got: drm.DRM_IOCTL_GEM_CLOSE, want: 0x40086409},
+		{name: "DRM_IOCTL_PRIME_HANDLE_TO_FD", got: drm.DRM_IOCTL_PRIME_HANDLE_TO_FD, want: 0xc00c642d},
+		{name: "DRM_IOCTL_SYNCOBJ_WAIT", got: drm.DRM_IOCTL_SYNCOBJ_WAIT, want: 0xc02064c3},
+		{name: "DRM_IOCTL_SYNCOBJ_TIMELINE_WAIT_V2", got: drm.DRM_IOCTL_SYNCOBJ_TIMELINE_WAIT_V2, want: 0xc03064ca},
+		{name: "DRM_IOCTL_AMDGPU_CS", got: drm.DRM_IOCTL_AMDGPU_CS, want: 0xc0186444},
+		{name: "DRM_IOCTL_AMDGPU_INFO", got: drm.DRM_IOCTL_AMDGPU_INFO, want: 0x40206445},
+		{name: "DRM_IOCTL_AMDGPU_GEM_VA", got: drm.DRM_IOCTL_AMDGPU_GEM_VA, want: 0x40286448},
+		{name: "DRM_IOCTL_I915_GETPARAM", got: drm.DRM_IOCTL_I915_GETPARAM, want: 0xc0106446},
+		{name: "DRM_IOCTL_I915_GEM_EXECBUFFER2_WR", got: drm.DRM_IOCTL_I915_GEM_EXECBUFFER2_WR, want: 0xc0406469},
+		{name: "DRM_IOCTL_I915_GEM_MMAP_OFFSET", got: drm.DRM_IOCTL_I915_GEM_MMAP_OFFSET, want: 0xc0206464},
+		{name: "DRM_IOCTL_I915_QUERY", got: drm.DRM_IOCTL_I915_QUERY, want: 0xc0106479},
+		{name: "DMA_BUF_IOCTL_SYNC", got: drm.DMA_BUF_IOCTL_SYNC, want: 0x40086200},
+	} {
+		if test.got != test.want {
+			t.Errorf("%s = %#x, want %#x", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestSupportedIoctls(t *testing.T) {
+	cmds := supportedIoctls()
+	for i := 1; i < len(cmds); i++ {
+		if cmds[i-1] >= cmds[i] {
+			t.Fatalf("supportedIoctls() is not strictly increasing: %#x", cmds)
+		}
+	}
+	// Driver-specific ioctls must not shadow core ioctls.
+	for driver, handlers := range driverIoctlHandlers {
+		for cmd := range handlers {
+			if _, ok := coreIoctlHandlers[cmd]; ok {
+				t.Errorf("%s ioctl %#x is also a core ioctl", driver, cmd)
+			}
+		}
+	}
+}
+
+// testMemory implements marshal.CopyContext for a single region of
+// application memory.
+type testMemory struct {
+	base hostarch.Addr
+	data []byte
+}
+
+const testMemoryBase = 0x10000
+
+func newTestMemory() *testMemory {
+	return &testMemory{
+		base: testMemoryBase,
+		data: make([]byte, hostarch.PageSize),
+	}
+}
+
+func (m *testMemory) slice(addr hostarch.Addr, n int) ([]byte, error) {
+	if addr < m.base || uint64(addr-m.base)+uint64(n) > uint64(len(m.data)) {
+		return nil, linuxerr.EFAULT
+	}
+	return m.data[addr-m.base:][:n], nil
+}
+
+// CopyScratchBuffer implements marshal.CopyContext.CopyScratchBuffer.
+func (m *testMemory) CopyScratchBuffer(size int) []byte {
+	return make([]byte, size)
+}
+
+// CopyInBytes implements marshal.CopyContext.CopyInBytes.
+func (m *testMemory) CopyInBytes(addr hostarch.Addr, b []byte) (int, error) {
+	src, err := m.slice(addr, len(b))
+	if err != nil {
+		return 0, err
+	}
+	return copy(b, src), nil
+}
+
+// CopyOutBytes implements marshal.CopyContext.CopyOutBytes.
+func (m *testMemory) CopyOutBytes(addr hostarch.Addr, b []byte) (int, error) {
+	dst, err := m.slice(addr, len(b))
+	if err != nil {
+		return 0, err
+	}
+	return copy(dst, b), nil
+}
+
+func (m *testMemory) putUint32(off int, v uint32) {
+	hostarch.ByteOrder.PutUint32(m.data[off:], v)
+}
+
+func (m *testMemory) putUint64(off int, v uint64) {
+	hostarch.ByteOrder.PutUint64(m.data[off:], v)
+}
+
+func (m *testMemory) uint64(off int) uint64 {
+	return hostarch.ByteOrder.Uint64(m.data[off:])
+}
+
+func TestTranslateIn(t *testing.T) {
+	m := newTestMemory()
+	// struct drm_syncobj_array at offset 0, pointing to 3 handles at offset
+	// 256.
+	m.putUint64(syncobjArrayHandlesOffset, testMemoryBase+256)
+	m.putUint32(syncobjArrayCountOffset, 3)
+	for i := 0; i < 3; i++ {
+		m.putUint32(256+4*i, uint32(i+1))
+	}
+	orig := bytes.Clone(m.data)
+
+	p := ioctlParams{cc: m}
+	arg, err := p.copyIn(testMemoryBase, drm.SizeofDRMSyncobjArray, true /* out */)
+	if err != nil {
+		t.Fatalf("copyIn failed: %v", err)
+	}
+	if err := syncobjArray(&p, arg); err != nil {
+		t.Fatalf("syncobjArray failed: %v", err)
+	}
+	if len(p.bufs) != 2 {
+		t.Fatalf("got %d buffers, want 2", len(p.bufs))
+	}
+	handles := p.bufs[1]
+	if got, want := arg.uint64(syncobjArrayHandlesOffset), handles.hostAddr(); got != want {
+		t.Errorf("translated handles pointer = %#x, want %#x", got, want)
+	}
+	if got, want := handles.data, orig[256:256+12]; !bytes.Equal(got, want) {
+		t.Errorf("handles = %v, want %v", got, want)
+	}
+	// Modifications to input-only buffers must not be copied out, and the
+	// application's pointer must be restored.
+	handles.putUint32(0, 42)
+	if err := p.copyOut(); err != nil {
+		t.Fatalf("copyOut failed: %v", err)
+	}
+	if !bytes.Equal(m.data, orig) {
+		t.Errorf("application memory changed by ioctl with input-only pointers")
+	}
+}
+
+func TestTranslateOut(t *testing.T) {
+	m := newTestMemory()
+	// struct drm_version at offset 0, with an 8-byte name buffer at offset
+	// 512 and no date or description buffers.
+	m.putUint64(versionNameLenOffset, 8)
+	m.putUint64(versionNameOffset, testMemoryBase+512)
+	m.putUint64(versionDateOffset, testMemoryBase+1024)
+
+	p := ioctlParams{cc: m}
+	arg, err := p.copyIn(testMemoryBase, drm.SizeofDRMVersion, true /* out */)
+	if err != nil {
+		t.Fatalf("copyIn failed: %v", err)
+	}
+	if err := drmVersion(&p, arg); err != nil {
+		t.Fatalf("drmVersion failed: %v", err)
+	}
+	if got := arg.uint64(versionDateOffset); got != 0 {
+		t.Errorf("date pointer with zero length translated to %#x, want 0", got)
+	}
+	// Simulate the host driver.
+	copy(p.bufs[1].data, "amdgpu")
+	arg.putUint64(versionNameLenOffset, 6)
+	if err := p.copyOut(); err != nil {
+		t.Fatalf("copyOut failed: %v", err)
+	}
+	if got, want := m.data[512:520], []byte("amdgpu\x00\x00"); !bytes.Equal(got, want) {
+		t.Errorf("name = %q, want %q", got, want)
+	}
+	if got := m.uint64(versionNameLenOffset); got != 6 {
+		t.Errorf("name_len = %d, want 6", got)
+	}
+	if got, want := m.uint64(versionNameOffset), uint64(testMemoryBase+512); got != want {
+		t.Errorf("name pointer = %#x, want %#x", got, want)
+	}
+	if got, want := m.uint64(versionDateOffset), uint64(testMemoryBase+1024); got != want {
+		t.Errorf("date pointer = %#x, want %#x", got, want)
+	}
+}
+
+func TestTranslateTooLarge(t *testing.T) {
+	m := newTestMemory()
+	m.putUint64(syncobjTimelineWaitHandlesOffset, testMemoryBase+256)
+	m.putUint64(syncobjTimelineWaitPointsOffset, testMemoryBase+256)
+	m.putUint32(syncobjTimelineWaitCountOffset, 0xffffffff)
+	p := ioctlParams{cc: m}
+	arg, err := p.copyIn(testMemoryBase, drm.SizeofDRMSyncobjTimelineWait, false /* out */)
+	if err != nil {
+		t.Fatalf("copyIn failed: %v", err)
+	}
+	if err := syncobjTimelineWait(&p, arg); !linuxerr.Equals(linuxerr.ENOMEM, err) {
+		t.Errorf("syncobjTimelineWait with huge count returned %v, want ENOMEM", err)
+	}
+}
+
+func TestI915Extensions(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		// setup initializes the extension chain starting at offset 256.
+		setup func(m *testMemory)
+		want  error
+	}{
+		{
+			name: "SetPAT",
+			setup: func(m *testMemory) {
+				m.putUint32(256+i915ExtNameOffset, drm.I915_GEM_CREATE_EXT_SET_PAT)
+			},
+		},
+		{
+			name: "Unsupported",
+			setup: func(m *testMemory) {
+				m.putUint32(256+i915ExtNameOffset, 0xff)
+			},
+			want: linuxerr.EINVAL,
+		},
+		{
+			name: "Loop",
+			setup: func(m *testMemory) {
+				m.putUint64(256+i915ExtNextOffset, testMemoryBase+256)
+				m.putUint32(256+i915ExtNameOffset, drm.I915_GEM_CREATE_EXT_SET_PAT)
+			},
+			want: linuxerr.E2BIG,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			m := newTestMemory()
+			m.putUint64(i915GemCreateExtExtensionsOffset, testMemoryBase+256)
+			test.setup(m)
+			p := ioctlParams{cc: m}
+			arg, err := p.copyIn(testMemoryBase, drm.SizeofI915GemCreateExt, true /* out */)
+			if err != nil {
+				t.Fatalf("copyIn failed: %v", err)
+			}
+			err = i915GemCreateExt(&p, arg)
+			if test.want != nil {
+				if err != test.want {
+					t.Errorf("i915GemCreateExt returned %v, want %v", err, test.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("i915GemCreateExt failed: %v", err)
+			}
+			if len(p.bufs) != 2 || len(p.bufs[1].data) != i915GemCreateExtSize {
+				t.Fatalf("extension not copied in")
+			}
+			if got, want := arg.uint64(i915GemCreateExtExtensionsOffset), p.bufs[1].hostAddr(); got != want {
+				t.Errorf("translated extensions pointer = %#x, want %#x", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drmproxy
+
+import (
+	"bytes"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/drm"
+	"gvisor.dev/gvisor/pkg/hostarch"
+)
+
+// ioctlInvokeBuf makes an ioctl syscall on hostFD whose argument is a pointer
+// to buf.
+//
+// Preconditions: len(buf) > 0.
+func ioctlInvokeBuf(hostFD int32, cmd uint32, buf []byte) (uintptr, error) {
+	n, _, errno := unix.RawSyscall(unix.SYS_IOCTL, uintptr(hostFD), uintptr(cmd), uintptr(unsafe.Pointer(&buf[0])))
+	if errno != 0 {
+		return n, errno
+	}
+	return n, nil
+}
+
+// hostAddr returns the address of b's data in the sentry.
+func (b *paramBuf) hostAddr() uint64 {
+	return uint64(uintptr(unsafe.Pointer(&b.data[0])))
+}
+
+// invokeHost makes the ioctl cmd on hostFD with argument top, while keeping
+// all buffers referenced by top alive.
+func (p *ioctlParams) invokeHost(hostFD int32, cmd uint32, top *paramBuf) (uintptr, error) {
+	n, err := ioctlInvokeBuf(hostFD, cmd, top.data)
+	runtime.KeepAlive(p)
+	return n, err
+}
+
+// hostDriverName returns the name of the driver for the host DRM device
+// hostFD.
+func hostDriverName(hostFD int32) (string, error) {
+	var name [64]byte
+	version := make([]byte, drm.SizeofDRMVersion)
+	hostarch.ByteOrder.PutUint64(version[versionNameLenOffset:], uint64(len(name)))
+	hostarch.ByteOrder.PutUint64(version[versionNameOffset:], uint64(uintptr(unsafe.Pointer(&name[0]))))
+	_, err := ioctlInvokeBuf(hostFD, drm.DRM_IOCTL_VERSION, version)
+	runtime.KeepAlive(&name)
+	if err != nil {
+		return "", err
+	}
+	n := min(hostarch.ByteOrder.Uint64(version[versionNameLenOffset:]), uint64(len(name)))
+	return string(bytes.TrimRight(name[:n], "\x00")), nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drmproxy
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/drm"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fdnotifier"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// Names of the anonymous files representing host file descriptors exported by
+// DRM drivers, matching those used by Linux.
+const (
+	dmabufName      = "dmabuf"
+	syncFileName    = "sync_file"
+	syncobjFileName = "syncobj_file"
+)
+
+// exportedFD implements vfs.FileDescriptionImpl for a file descriptor exported
+// by a host DRM driver: a dma-buf, sync_file or syncobj file.
+type exportedFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+
+	hostFD   int32
+	queue    waiter.Queue
+	mappable hostMappable
+}
+
+// newExportedFD installs a file representing hostFD, a file descriptor
+// returned by a host DRM driver, in t's file descriptor table, and returns the
+// application file descriptor. newExportedFD takes ownership of hostFD, even if
+// it returns an error.
+func newExportedFD(t *kernel.Task, hostFD int32, name string, cloexec bool) (int32, error) {
+	fd := &exportedFD{
+		hostFD: hostFD,
+	}
+	fd.mappable.file.hostFD = hostFD
+	if err := fdnotifier.AddFD(hostFD, &fd.queue); err != nil {
+		unix.Close(int(hostFD))
+		return -1, err
+	}
+	vd := t.Kernel().VFS().NewAnonVirtualDentry(name)
+	defer vd.DecRef(t)
+	if err := fd.vfsfd.Init(fd, linux.O_RDWR, vd.Mount(), vd.Dentry(), &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+		DenyPRead:         true,
+		DenyPWrite:        true,
+	}); err != nil {
+		fdnotifier.RemoveFD(hostFD)
+		unix.Close(int(hostFD))
+		return -1, err
+	}
+	// From this point on, fd.Release() closes hostFD.
+	defer fd.vfsfd.DecRef(t)
+	return t.NewFDFrom(0, &fd.vfsfd, kernel.FDFlags{
+		CloseOnExec: cloexec,
+	})
+}
+
+// importHostFD returns the host file descriptor represented by the application
+// file descriptor appFD, which must have been returned by newExportedFD. The
+// host file descriptor remains valid until the caller calls DecRef on the
+// returned file.
+func importHostFD(t *kernel.Task, appFD int32) (int32, *vfs.FileDescription, error) {
+	file := t.GetFile(appFD)
+	if file == nil {
+		return -1, nil, linuxerr.EBADF
+	}
+	fd, ok := file.Impl().(*exportedFD)
+	if !ok {
+		file.DecRef(t)
+		return -1, nil, linuxerr.EINVAL
+	}
+	return fd.hostFD, file, nil
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *exportedFD) Release(context.Context) {
+	fdnotifier.RemoveFD(fd.hostFD)
+	fd.queue.Notify(waiter.EventHUp)
+	unix.Close(int(fd.hostFD))
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (fd *exportedFD) EventRegister(e *waiter.Entry) error {
+	fd.queue.EventRegister(e)
+	if err := fdnotifier.UpdateFD(fd.hostFD); err != nil {
+		fd.queue.EventUnregister(e)
+		return err
+	}
+	return nil
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (fd *exportedFD) EventUnregister(e *waiter.Entry) {
+	fd.queue.EventUnregister(e)
+	if err := fdnotifier.UpdateFD(fd.hostFD); err != nil {
+		panic(fmt.Sprint("UpdateFD:", err))
+	}
+}
+
+// Readiness implements waiter.Waitable.Readiness.
+func (fd *exportedFD) Readiness(mask waiter.EventMask) waiter.EventMask {
+	return fdnotifier.NonBlockingPoll(fd.hostFD, mask)
+}
+
+// Epollable implements vfs.FileDescriptionImpl.Epollable.
+func (fd *exportedFD) Epollable() bool {
+	return true
+}
+
+// ConfigureMMap implements vfs.FileDescriptionImpl.ConfigureMMap.
+func (fd *exportedFD) ConfigureMMap(ctx context.Context, opts *memmap.MMapOpts) error {
+	return vfs.GenericConfigureMMap(&fd.vfsfd, &fd.mappable, opts)
+}
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *exportedFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		panic("Ioctl should be called from a task context")
+	}
+	switch cmd := args[1].Uint(); cmd {
+	case drm.DMA_BUF_IOCTL_SYNC:
+		return ioctlSimple(&ioctlState{
+			t:      t,
+			hostFD: fd.hostFD,
+			cmd:    cmd,
+			arg:    args[2].Pointer(),
+		})
+	default:
+		return 0, linuxerr.ENOTTY
+	}
+}
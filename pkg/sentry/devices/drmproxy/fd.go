@@ -0,0 +1,130 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drmproxy
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fdnotifier"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/hostfd"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// drmFD implements vfs.FileDescriptionImpl for a host DRM render node.
+type drmFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+
+	hostFD   int32
+	dev      *drmDevice
+	queue    waiter.Queue
+	mappable hostMappable
+
+	// driverHandlers handles ioctls specific to the host device's driver. It
+	// is nil if the driver is not supported.
+	driverHandlers map[uint32]ioctlHandler
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *drmFD) Release(context.Context) {
+	fdnotifier.RemoveFD(fd.hostFD)
+	fd.queue.Notify(waiter.EventHUp)
+	unix.Close(int(fd.hostFD))
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (fd *drmFD) EventRegister(e *waiter.Entry) error {
+	fd.queue.EventRegister(e)
+	if err := fdnotifier.UpdateFD(fd.hostFD); err != nil {
+		fd.queue.EventUnregister(e)
+		return err
+	}
+	return nil
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (fd *drmFD) EventUnregister(e *waiter.Entry) {
+	fd.queue.EventUnregister(e)
+	if err := fdnotifier.UpdateFD(fd.hostFD); err != nil {
+		panic(fmt.Sprint("UpdateFD:", err))
+	}
+}
+
+// Readiness implements waiter.Waitable.Readiness.
+func (fd *drmFD) Readiness(mask waiter.EventMask) waiter.EventMask {
+	return fdnotifier.NonBlockingPoll(fd.hostFD, mask)
+}
+
+// Epollable implements vfs.FileDescriptionImpl.Epollable.
+func (fd *drmFD) Epollable() bool {
+	return true
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (fd *drmFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	if opts.Flags != 0 {
+		return 0, linuxerr.EOPNOTSUPP
+	}
+	rw := hostfd.GetReadWriterAt(fd.hostFD, -1, opts.Flags)
+	n, err := dst.CopyOutFrom(ctx, rw)
+	hostfd.PutReadWriterAt(rw)
+	if linuxerr.Equals(linuxerr.EAGAIN, err) {
+		err = linuxerr.ErrWouldBlock
+	}
+	return n, err
+}
+
+// ConfigureMMap implements vfs.FileDescriptionImpl.ConfigureMMap.
+func (fd *drmFD) ConfigureMMap(ctx context.Context, opts *memmap.MMapOpts) error {
+	return vfs.GenericConfigureMMap(&fd.vfsfd, &fd.mappable, opts)
+}
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *drmFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
+	cmd := args[1].Uint()
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		panic("Ioctl should be called from a task context")
+	}
+
+	// Implementors:
+	// - Add the ioctl number to //pkg/abi/drm.
+	// - Add handling to coreIoctlHandlers or driverIoctlHandlers in ioctl.go.
+	// Seccomp filters are derived from the handler tables.
+	handler := coreIoctlHandlers[cmd]
+	if handler == nil {
+		handler = fd.driverHandlers[cmd]
+	}
+	if handler == nil {
+		ctx.Debugf("drmproxy: unsupported ioctl %#x on %s", cmd, fd.dev.name)
+		return 0, linuxerr.ENOTTY
+	}
+	return handler(&ioctlState{
+		t:      t,
+		hostFD: fd.hostFD,
+		cmd:    cmd,
+		arg:    args[2].Pointer(),
+	})
+}
@@ -0,0 +1,409 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drmproxy
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/drm"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+)
+
+// i915IoctlHandlers handles i915 ioctls. DRM_IOCTL_I915_GEM_USERPTR and
+// DRM_IOCTL_I915_GEM_MMAP (which returns a mapping in the caller's address
+// space) are not supported; Mesa uses DRM_IOCTL_I915_GEM_MMAP_OFFSET instead
+// of the latter.
+var i915IoctlHandlers = map[uint32]ioctlHandler{
+	drm.DRM_IOCTL_I915_GETPARAM:               ioctlTranslated(i915GetParam),
+	drm.DRM_IOCTL_I915_GEM_EXECBUFFER2:        i915Execbuffer2,
+	drm.DRM_IOCTL_I915_GEM_EXECBUFFER2_WR:     i915Execbuffer2,
+	drm.DRM_IOCTL_I915_GEM_BUSY:               ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_CREATE:             ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_PREAD:              ioctlTranslated(i915GemPread),
+	drm.DRM_IOCTL_I915_GEM_PWRITE:             ioctlTranslated(i915GemPwrite),
+	drm.DRM_IOCTL_I915_GEM_SET_DOMAIN:         ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_SW_FINISH:          ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_SET_TILING:         ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_GET_TILING:         ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_GET_APERTURE:       ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_MMAP_GTT:           ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_MMAP_OFFSET:        ioctlTranslated(i915NoExtensions(i915GemMmapOffsetExtensionsOffset)),
+	drm.DRM_IOCTL_I915_GEM_MADVISE:            ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_WAIT:               ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_CONTEXT_CREATE:     ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_CONTEXT_CREATE_EXT: ioctlTranslated(i915GemContextCreateExt),
+	drm.DRM_IOCTL_I915_GEM_CONTEXT_DESTROY:    ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_SET_CACHING:        ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_GET_CACHING:        ioctlSimple,
+	drm.DRM_IOCTL_I915_REG_READ:               ioctlSimple,
+	drm.DRM_IOCTL_I915_GET_RESET_STATS:        ioctlSimple,
+	drm.DRM_IOCTL_I915_GEM_CONTEXT_GETPARAM:   ioctlTranslated(i915GemContextParam(true /* out */)),
+	drm.DRM_IOCTL_I915_GEM_CONTEXT_SETPARAM:   ioctlTranslated(i915GemContextParam(false /* out */)),
+	drm.DRM_IOCTL_I915_QUERY:                  ioctlTranslated(i915Query),
+	drm.DRM_IOCTL_I915_GEM_VM_CREATE:          ioctlTranslated(i915NoExtensions(i915GemVMControlExtensionsOffset)),
+	drm.DRM_IOCTL_I915_GEM_VM_DESTROY:         ioctlTranslated(i915NoExtensions(i915GemVMControlExtensionsOffset)),
+	drm.DRM_IOCTL_I915_GEM_CREATE_EXT:         ioctlTranslated(i915GemCreateExt),
+}
+
+// maxI915Extensions is the maximum length of a chain of struct
+// i915_user_extension that will be translated.
+const maxI915Extensions = 16
+
+// Offsets in struct i915_user_extension.
+const (
+	i915ExtNextOffset = 0
+	i915ExtNameOffset = 8
+)
+
+// i915Extensions translates the chain of struct i915_user_extension pointed to
+// by the pointer at off in b. size returns the size of the extension struct
+// with the given name, or false if the extension is not supported. translate,
+// if not nil, is called to translate each extension struct.
+func i915Extensions(p *ioctlParams, b *paramBuf, off int, size func(name uint32) (uint64, bool), translate func(p *ioctlParams, ext *paramBuf) error) error {
+	for i := 0; i < maxI915Extensions; i++ {
+		addr := b.uint64(off)
+		if addr == 0 {
+			return nil
+		}
+		// Read the extension's name to determine its size.
+		var head [drm.SizeofI915UserExtension]byte
+		if _, err := p.cc.CopyInBytes(hostarch.Addr(addr), head[:]); err != nil {
+			return err
+		}
+		extSize, ok := size(hostarch.ByteOrder.Uint32(head[i915ExtNameOffset:]))
+		if !ok {
+			return linuxerr.EINVAL
+		}
+		ext, err := p.pointee(b, off, extSize, false /* out */)
+		if err != nil {
+			return err
+		}
+		if translate != nil {
+			if err := translate(p, ext); err != nil {
+				return err
+			}
+		}
+		b, off = ext, i915ExtNextOffset
+	}
+	if b.uint64(off) != 0 {
+		// Linux fails with E2BIG after an implementation-defined number of
+		// extensions.
+		return linuxerr.E2BIG
+	}
+	return nil
+}
+
+// i915NoExtensions returns a translation function for ioctls whose argument
+// structs contain an extensions pointer at off for which no extensions are
+// supported.
+func i915NoExtensions(off int) func(p *ioctlParams, arg *paramBuf) error {
+	return func(p *ioctlParams, arg *paramBuf) error {
+		if arg.uint64(off) != 0 {
+			return linuxerr.EINVAL
+		}
+		return nil
+	}
+}
+
+// i915GemMmapOffsetExtensionsOffset is the offset of extensions in struct
+// drm_i915_gem_mmap_offset.
+const i915GemMmapOffsetExtensionsOffset = 24
+
+// i915GemVMControlExtensionsOffset is the offset of extensions in struct
+// drm_i915_gem_vm_control.
+const i915GemVMControlExtensionsOffset = 0
+
+// i915GetParamValueOffset is the offset of value in struct drm_i915_getparam.
+const i915GetParamValueOffset = 8
+
+func i915GetParam(p *ioctlParams, arg *paramBuf) error {
+	// value points to an int.
+	_, err := p.pointee(arg, i915GetParamValueOffset, 4, true /* out */)
+	return err
+}
+
+// Offsets in struct drm_i915_gem_pread and struct drm_i915_gem_pwrite.
+const (
+	i915GemPreadSizeOffset = 16
+	i915GemPreadDataOffset = 24
+)
+
+func i915GemPread(p *ioctlParams, arg *paramBuf) error {
+	_, err := p.pointee(arg, i915GemPreadDataOffset, arg.uint64(i915GemPreadSizeOffset), true /* out */)
+	return err
+}
+
+func i915GemPwrite(p *ioctlParams, arg *paramBuf) error {
+	_, err := p.pointee(arg, i915GemPreadDataOffset, arg.uint64(i915GemPreadSizeOffset), false /* out */)
+	return err
+}
+
+// Offsets in struct drm_i915_gem_context_param.
+const (
+	i915ContextParamSizeOffset  = 4
+	i915ContextParamParamOffset = 8
+	i915ContextParamValueOffset = 16
+)
+
+// i915EnginesExtensionsOffset is the offset of extensions in struct
+// i915_context_param_engines.
+const i915EnginesExtensionsOffset = 0
+
+// i915ContextParam translates the struct drm_i915_gem_context_param at off in
+// b. If out is true, the parameter's value is written by the ioctl.
+func i915ContextParam(p *ioctlParams, b *paramBuf, off int, out bool) error {
+	size := uint64(b.uint32(off + i915ContextParamSizeOffset))
+	if size == 0 {
+		// value is not a pointer.
+		return nil
+	}
+	value, err := p.pointee(b, off+i915ContextParamValueOffset, size, out)
+	if err != nil || value == nil {
+		return err
+	}
+	if b.uint64(off+i915ContextParamParamOffset) == drm.I915_CONTEXT_PARAM_ENGINES && !out {
+		// Engine extensions (load balancing, bonding and parallel
+		// submission) are not supported.
+		if len(value.data) >= 8 && value.uint64(i915EnginesExtensionsOffset) != 0 {
+			return linuxerr.EINVAL
+		}
+	}
+	return nil
+}
+
+// i915GemContextParam returns a translation function for
+// DRM_IOCTL_I915_GEM_CONTEXT_GETPARAM (out == true) and
+// DRM_IOCTL_I915_GEM_CONTEXT_SETPARAM (out == false).
+func i915GemContextParam(out bool) func(p *ioctlParams, arg *paramBuf) error {
+	return func(p *ioctlParams, arg *paramBuf) error {
+		return i915ContextParam(p, arg, 0, out)
+	}
+}
+
+// i915ContextCreateExtExtensionsOffset is the offset of extensions in struct
+// drm_i915_gem_context_create_ext.
+const i915ContextCreateExtExtensionsOffset = 8
+
+// i915ContextCreateExtSetparamParamOffset is the offset of param in struct
+// drm_i915_gem_context_create_ext_setparam.
+const i915ContextCreateExtSetparamParamOffset = drm.SizeofI915UserExtension
+
+func i915GemContextCreateExt(p *ioctlParams, arg *paramBuf) error {
+	size := func(name uint32) (uint64, bool) {
+		if name == drm.I915_CONTEXT_CREATE_EXT_SETPARAM {
+			return drm.SizeofI915UserExtension + drm.SizeofI915GemContextParam, true
+		}
+		return 0, false
+	}
+	translate := func(p *ioctlParams, ext *paramBuf) error {
+		return i915ContextParam(p, ext, i915ContextCreateExtSetparamParamOffset, false /* out */)
+	}
+	// Linux ignores extensions unless I915_CONTEXT_CREATE_FLAGS_USE_EXTENSIONS
+	// is set, but it is harmless to translate them anyway.
+	return i915Extensions(p, arg, i915ContextCreateExtExtensionsOffset, size, translate)
+}
+
+// i915GemCreateExtExtensionsOffset is the offset of extensions in struct
+// drm_i915_gem_create_ext.
+const i915GemCreateExtExtensionsOffset = 16
+
+// Offsets in struct drm_i915_gem_create_ext_memory_regions.
+const (
+	i915MemoryRegionsNumRegionsOffset = 36
+	i915MemoryRegionsRegionsOffset    = 40
+)
+
+// i915GemCreateExtMemoryRegionsSize is the size of struct
+// drm_i915_gem_create_ext_memory_regions.
+const i915GemCreateExtMemoryRegionsSize = 48
+
+// i915GemCreateExtSize is the size of struct
+// drm_i915_gem_create_ext_protected_content and struct
+// drm_i915_gem_create_ext_set_pat.
+const i915GemCreateExtSize = 40
+
+// i915MemoryClassInstanceSize is the size of struct
+// drm_i915_gem_memory_class_instance.
+const i915MemoryClassInstanceSize = 4
+
+func i915GemCreateExt(p *ioctlParams, arg *paramBuf) error {
+	size := func(name uint32) (uint64, bool) {
+		switch name {
+		case drm.I915_GEM_CREATE_EXT_MEMORY_REGIONS:
+			return i915GemCreateExtMemoryRegionsSize, true
+		case drm.I915_GEM_CREATE_EXT_PROTECTED_CONTENT, drm.I915_GEM_CREATE_EXT_SET_PAT:
+			return i915GemCreateExtSize, true
+		default:
+			return 0, false
+		}
+	}
+	translate := func(p *ioctlParams, ext *paramBuf) error {
+		if ext.uint32(i915ExtNameOffset) != drm.I915_GEM_CREATE_EXT_MEMORY_REGIONS {
+			return nil
+		}
+		count := uint64(ext.uint32(i915MemoryRegionsNumRegionsOffset))
+		_, err := p.array(ext, i915MemoryRegionsRegionsOffset, count, i915MemoryClassInstanceSize, false /* out */)
+		return err
+	}
+	return i915Extensions(p, arg, i915GemCreateExtExtensionsOffset, size, translate)
+}
+
+// Offsets in struct drm_i915_query.
+const (
+	i915QueryNumItemsOffset = 0
+	i915QueryItemsPtrOffset = 8
+)
+
+// Offsets in struct drm_i915_query_item.
+const (
+	i915QueryItemLengthOffset  = 8
+	i915QueryItemDataPtrOffset = 16
+)
+
+func i915Query(p *ioctlParams, arg *paramBuf) error {
+	numItems := uint64(arg.uint32(i915QueryNumItemsOffset))
+	items, err := p.array(arg, i915QueryItemsPtrOffset, numItems, drm.SizeofI915QueryItem, true /* out */)
+	if err != nil || items == nil {
+		return err
+	}
+	for i := 0; i < int(numItems); i++ {
+		off := i * drm.SizeofI915QueryItem
+		// If length is 0, the kernel returns the required length without
+		// accessing data_ptr. If length is negative, the kernel fails the
+		// query.
+		length := int32(items.uint32(off + i915QueryItemLengthOffset))
+		if _, err := p.pointee(items, off+i915QueryItemDataPtrOffset, uint64(max(length, 0)), true /* out */); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Offsets in struct drm_i915_gem_execbuffer2.
+const (
+	i915ExecbufBuffersPtrOffset   = 0
+	i915ExecbufBufferCountOffset  = 8
+	i915ExecbufNumCliprectsOffset = 28
+	i915ExecbufCliprectsPtrOffset = 32
+	i915ExecbufFlagsOffset        = 40
+	i915ExecbufRsvd2Offset        = 56
+	i915ExecbufFenceInOffset      = i915ExecbufRsvd2Offset
+	i915ExecbufFenceOutOffset     = i915ExecbufRsvd2Offset + 4
+)
+
+// Offsets in struct drm_i915_gem_exec_object2.
+const (
+	i915ExecObjectRelocCountOffset = 4
+	i915ExecObjectRelocsPtrOffset  = 8
+)
+
+// Offsets in struct drm_i915_gem_execbuffer_ext_timeline_fences.
+const (
+	i915TimelineFencesCountOffset      = 32
+	i915TimelineFencesHandlesPtrOffset = 40
+	i915TimelineFencesValuesPtrOffset  = 48
+)
+
+// i915TimelineFencesSize is the size of struct
+// drm_i915_gem_execbuffer_ext_timeline_fences.
+const i915TimelineFencesSize = 56
+
+// i915Execbuffer2 handles DRM_IOCTL_I915_GEM_EXECBUFFER2 and
+// DRM_IOCTL_I915_GEM_EXECBUFFER2_WR, which may take and return sync_file file
+// descriptors.
+func i915Execbuffer2(s *ioctlState) (uintptr, error) {
+	p := ioctlParams{cc: s.t}
+	arg, err := s.copyInArg(&p)
+	if err != nil {
+		return 0, err
+	}
+	if err := i915Execbuffer2Translate(&p, arg); err != nil {
+		return 0, err
+	}
+	flags := arg.uint64(i915ExecbufFlagsOffset)
+	fenceOut := flags&drm.I915_EXEC_FENCE_OUT != 0
+	if fenceOut && s.cmd != drm.DRM_IOCTL_I915_GEM_EXECBUFFER2_WR {
+		// The output fence would be leaked in the sentry, since
+		// DRM_IOCTL_I915_GEM_EXECBUFFER2 doesn't copy it out.
+		return 0, linuxerr.EINVAL
+	}
+	appFenceIn := arg.uint32(i915ExecbufFenceInOffset)
+	if flags&(drm.I915_EXEC_FENCE_IN|drm.I915_EXEC_FENCE_SUBMIT) != 0 {
+		hostFD, file, err := importHostFD(s.t, int32(appFenceIn))
+		if err != nil {
+			return 0, err
+		}
+		defer file.DecRef(s.t)
+		arg.putUint32(i915ExecbufFenceInOffset, uint32(hostFD))
+	}
+	n, err := p.invokeHost(s.hostFD, s.cmd, arg)
+	arg.putUint32(i915ExecbufFenceInOffset, appFenceIn)
+	if err == nil && fenceOut {
+		// Linux returns the output fence with O_CLOEXEC.
+		if err := exportFD(s.t, arg, i915ExecbufFenceOutOffset, syncFileName, true /* cloexec */); err != nil {
+			return 0, err
+		}
+	}
+	if cerr := p.copyOut(); cerr != nil && err == nil {
+		return 0, cerr
+	}
+	return n, err
+}
+
+func i915Execbuffer2Translate(p *ioctlParams, arg *paramBuf) error {
+	// The kernel writes back the offsets of execution objects and the
+	// presumed offsets of relocations.
+	count := uint64(arg.uint32(i915ExecbufBufferCountOffset))
+	objs, err := p.array(arg, i915ExecbufBuffersPtrOffset, count, drm.SizeofI915GemExecObject2, true /* out */)
+	if err != nil {
+		return err
+	}
+	for i := 0; objs != nil && i < int(count); i++ {
+		off := i * drm.SizeofI915GemExecObject2
+		relocCount := uint64(objs.uint32(off + i915ExecObjectRelocCountOffset))
+		if _, err := p.array(objs, off+i915ExecObjectRelocsPtrOffset, relocCount, drm.SizeofI915GemRelocationEntry, true /* out */); err != nil {
+			return err
+		}
+	}
+
+	// cliprects_ptr is overloaded by I915_EXEC_FENCE_ARRAY and
+	// I915_EXEC_USE_EXTENSIONS. Cliprects are otherwise unsupported by
+	// execbuffer2.
+	flags := arg.uint64(i915ExecbufFlagsOffset)
+	switch {
+	case flags&drm.I915_EXEC_USE_EXTENSIONS != 0:
+		size := func(name uint32) (uint64, bool) {
+			if name == drm.DRM_I915_GEM_EXECBUFFER_EXT_TIMELINE_FENCES {
+				return i915TimelineFencesSize, true
+			}
+			return 0, false
+		}
+		translate := func(p *ioctlParams, ext *paramBuf) error {
+			count := ext.uint64(i915TimelineFencesCountOffset)
+			if _, err := p.array(ext, i915TimelineFencesHandlesPtrOffset, count, drm.SizeofI915GemExecFence, false /* out */); err != nil {
+				return err
+			}
+			_, err := p.array(ext, i915TimelineFencesValuesPtrOffset, count, 8, false /* out */)
+			return err
+		}
+		return i915Extensions(p, arg, i915ExecbufCliprectsPtrOffset, size, translate)
+	case flags&drm.I915_EXEC_FENCE_ARRAY != 0:
+		count := uint64(arg.uint32(i915ExecbufNumCliprectsOffset))
+		_, err := p.array(arg, i915ExecbufCliprectsPtrOffset, count, drm.SizeofI915GemExecFence, false /* out */)
+		return err
+	default:
+		arg.patch(i915ExecbufCliprectsPtrOffset, 0)
+		return nil
+	}
+}
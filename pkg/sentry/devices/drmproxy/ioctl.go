@@ -0,0 +1,277 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drmproxy
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/drm"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+)
+
+// ioctlState holds the state of an ioctl being forwarded to the host.
+type ioctlState struct {
+	t      *kernel.Task
+	hostFD int32
+	cmd    uint32
+	arg    hostarch.Addr
+}
+
+// ioctlHandler handles an ioctl.
+type ioctlHandler func(*ioctlState) (uintptr, error)
+
+// copyInArg copies in the ioctl's argument struct, whose size is encoded in
+// the ioctl number. The struct is copied out after the ioctl if the ioctl
+// number says that the kernel writes it.
+func (s *ioctlState) copyInArg(p *ioctlParams) (*paramBuf, error) {
+	return p.copyIn(s.arg, uint64(linux.IOC_SIZE(s.cmd)), linux.IOC_DIR(s.cmd)&linux.IOC_READ != 0)
+}
+
+// ioctlSimple forwards an ioctl whose argument struct contains no pointers or
+// file descriptors.
+func ioctlSimple(s *ioctlState) (uintptr, error) {
+	p := ioctlParams{cc: s.t}
+	arg, err := s.copyInArg(&p)
+	if err != nil {
+		return 0, err
+	}
+	return p.invoke(s.hostFD, s.cmd, arg)
+}
+
+// ioctlTranslated returns a handler for ioctls whose argument structs contain
+// pointers, which are replaced by translate before the ioctl is forwarded.
+func ioctlTranslated(translate func(p *ioctlParams, arg *paramBuf) error) ioctlHandler {
+	return func(s *ioctlState) (uintptr, error) {
+		p := ioctlParams{cc: s.t}
+		arg, err := s.copyInArg(&p)
+		if err != nil {
+			return 0, err
+		}
+		if err := translate(&p, arg); err != nil {
+			return 0, err
+		}
+		return p.invoke(s.hostFD, s.cmd, arg)
+	}
+}
+
+// coreIoctlHandlers handles core DRM ioctls, which are supported for all
+// drivers.
+var coreIoctlHandlers = map[uint32]ioctlHandler{
+	drm.DRM_IOCTL_VERSION:        ioctlTranslated(drmVersion),
+	drm.DRM_IOCTL_GET_UNIQUE:     ioctlTranslated(drmGetUnique),
+	drm.DRM_IOCTL_GEM_CLOSE:      ioctlSimple,
+	drm.DRM_IOCTL_GET_CAP:        ioctlSimple,
+	drm.DRM_IOCTL_SET_CLIENT_CAP: ioctlSimple,
+
+	drm.DRM_IOCTL_PRIME_HANDLE_TO_FD: primeHandleToFD,
+	drm.DRM_IOCTL_PRIME_FD_TO_HANDLE: fdToHandle,
+
+	drm.DRM_IOCTL_SYNCOBJ_CREATE:           ioctlSimple,
+	drm.DRM_IOCTL_SYNCOBJ_DESTROY:          ioctlSimple,
+	drm.DRM_IOCTL_SYNCOBJ_HANDLE_TO_FD:     syncobjHandleToFD,
+	drm.DRM_IOCTL_SYNCOBJ_FD_TO_HANDLE:     fdToHandle,
+	drm.DRM_IOCTL_SYNCOBJ_WAIT:             ioctlTranslated(syncobjWait),
+	drm.DRM_IOCTL_SYNCOBJ_WAIT_V2:          ioctlTranslated(syncobjWait),
+	drm.DRM_IOCTL_SYNCOBJ_RESET:            ioctlTranslated(syncobjArray),
+	drm.DRM_IOCTL_SYNCOBJ_SIGNAL:           ioctlTranslated(syncobjArray),
+	drm.DRM_IOCTL_SYNCOBJ_TIMELINE_WAIT:    ioctlTranslated(syncobjTimelineWait),
+	drm.DRM_IOCTL_SYNCOBJ_TIMELINE_WAIT_V2: ioctlTranslated(syncobjTimelineWait),
+	drm.DRM_IOCTL_SYNCOBJ_QUERY:            ioctlTranslated(syncobjTimelineArray(true /* pointsOut */)),
+	drm.DRM_IOCTL_SYNCOBJ_TRANSFER:         ioctlSimple,
+	drm.DRM_IOCTL_SYNCOBJ_TIMELINE_SIGNAL:  ioctlTranslated(syncobjTimelineArray(false /* pointsOut */)),
+}
+
+// driverIoctlHandlers maps the names of supported drivers to handlers for
+// their driver-specific ioctls.
+var driverIoctlHandlers = map[string]map[uint32]ioctlHandler{
+	"amdgpu": amdgpuIoctlHandlers,
+	"i915":   i915IoctlHandlers,
+}
+
+// Offsets in struct drm_version.
+const (
+	versionNameLenOffset = 16
+	versionNameOffset    = 24
+	versionDateLenOffset = 32
+	versionDateOffset    = 40
+	versionDescLenOffset = 48
+	versionDescOffset    = 56
+)
+
+func drmVersion(p *ioctlParams, arg *paramBuf) error {
+	for _, str := range []struct{ lenOff, ptrOff int }{
+		{versionNameLenOffset, versionNameOffset},
+		{versionDateLenOffset, versionDateOffset},
+		{versionDescLenOffset, versionDescOffset},
+	} {
+		if _, err := p.pointee(arg, str.ptrOff, arg.uint64(str.lenOff), true /* out */); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Offsets in struct drm_unique.
+const (
+	uniqueLenOffset = 0
+	uniqueOffset    = 8
+)
+
+func drmGetUnique(p *ioctlParams, arg *paramBuf) error {
+	_, err := p.pointee(arg, uniqueOffset, arg.uint64(uniqueLenOffset), true /* out */)
+	return err
+}
+
+// Offsets in struct drm_prime_handle, which also apply to struct
+// drm_syncobj_handle.
+const (
+	primeHandleFlagsOffset = 4
+	primeHandleFDOffset    = 8
+)
+
+// primeHandleToFD handles DRM_IOCTL_PRIME_HANDLE_TO_FD, which returns a
+// dma-buf file descriptor.
+func primeHandleToFD(s *ioctlState) (uintptr, error) {
+	p := ioctlParams{cc: s.t}
+	arg, err := s.copyInArg(&p)
+	if err != nil {
+		return 0, err
+	}
+	cloexec := arg.uint32(primeHandleFlagsOffset)&drm.DRM_CLOEXEC != 0
+	return invokeHandleToFD(s, &p, arg, dmabufName, cloexec)
+}
+
+// syncobjHandleToFD handles DRM_IOCTL_SYNCOBJ_HANDLE_TO_FD, which returns a
+// syncobj or sync_file file descriptor.
+func syncobjHandleToFD(s *ioctlState) (uintptr, error) {
+	p := ioctlParams{cc: s.t}
+	arg, err := s.copyInArg(&p)
+	if err != nil {
+		return 0, err
+	}
+	name := syncobjFileName
+	if arg.uint32(primeHandleFlagsOffset)&drm.DRM_SYNCOBJ_HANDLE_TO_FD_FLAGS_EXPORT_SYNC_FILE != 0 {
+		name = syncFileName
+	}
+	// Linux always exports syncobj and sync_file file descriptors with
+	// O_CLOEXEC.
+	return invokeHandleToFD(s, &p, arg, name, true /* cloexec */)
+}
+
+// invokeHandleToFD forwards an ioctl that returns a file descriptor at
+// primeHandleFDOffset in arg.
+func invokeHandleToFD(s *ioctlState, p *ioctlParams, arg *paramBuf, name string, cloexec bool) (uintptr, error) {
+	n, err := p.invokeHost(s.hostFD, s.cmd, arg)
+	if err != nil {
+		return n, err
+	}
+	if err := exportFD(s.t, arg, primeHandleFDOffset, name, cloexec); err != nil {
+		return 0, err
+	}
+	return n, p.copyOut()
+}
+
+// exportFD replaces the host file descriptor at off in arg, which was returned
+// by the host driver, with an application file descriptor representing it.
+func exportFD(t *kernel.Task, arg *paramBuf, off int, name string, cloexec bool) error {
+	appFD, err := newExportedFD(t, int32(arg.uint32(off)), name, cloexec)
+	if err != nil {
+		return err
+	}
+	arg.putUint32(off, uint32(appFD))
+	return nil
+}
+
+// fdToHandle handles DRM_IOCTL_PRIME_FD_TO_HANDLE and
+// DRM_IOCTL_SYNCOBJ_FD_TO_HANDLE, which take a file descriptor.
+func fdToHandle(s *ioctlState) (uintptr, error) {
+	p := ioctlParams{cc: s.t}
+	arg, err := s.copyInArg(&p)
+	if err != nil {
+		return 0, err
+	}
+	appFD := arg.uint32(primeHandleFDOffset)
+	hostFD, file, err := importHostFD(s.t, int32(appFD))
+	if err != nil {
+		return 0, err
+	}
+	defer file.DecRef(s.t)
+	arg.putUint32(primeHandleFDOffset, uint32(hostFD))
+	n, err := p.invokeHost(s.hostFD, s.cmd, arg)
+	arg.putUint32(primeHandleFDOffset, appFD)
+	if cerr := p.copyOut(); cerr != nil && err == nil {
+		return 0, cerr
+	}
+	return n, err
+}
+
+// Offsets in struct drm_syncobj_wait.
+const (
+	syncobjWaitHandlesOffset = 0
+	syncobjWaitCountOffset   = 16
+)
+
+func syncobjWait(p *ioctlParams, arg *paramBuf) error {
+	_, err := p.array(arg, syncobjWaitHandlesOffset, uint64(arg.uint32(syncobjWaitCountOffset)), 4, false /* out */)
+	return err
+}
+
+// Offsets in struct drm_syncobj_timeline_wait.
+const (
+	syncobjTimelineWaitHandlesOffset = 0
+	syncobjTimelineWaitPointsOffset  = 8
+	syncobjTimelineWaitCountOffset   = 24
+)
+
+func syncobjTimelineWait(p *ioctlParams, arg *paramBuf) error {
+	count := uint64(arg.uint32(syncobjTimelineWaitCountOffset))
+	if _, err := p.array(arg, syncobjTimelineWaitHandlesOffset, count, 4, false /* out */); err != nil {
+		return err
+	}
+	_, err := p.array(arg, syncobjTimelineWaitPointsOffset, count, 8, false /* out */)
+	return err
+}
+
+// Offsets in struct drm_syncobj_array.
+const (
+	syncobjArrayHandlesOffset = 0
+	syncobjArrayCountOffset   = 8
+)
+
+func syncobjArray(p *ioctlParams, arg *paramBuf) error {
+	_, err := p.array(arg, syncobjArrayHandlesOffset, uint64(arg.uint32(syncobjArrayCountOffset)), 4, false /* out */)
+	return err
+}
+
+// Offsets in struct drm_syncobj_timeline_array.
+const (
+	syncobjTimelineArrayHandlesOffset = 0
+	syncobjTimelineArrayPointsOffset  = 8
+	syncobjTimelineArrayCountOffset   = 16
+)
+
+// syncobjTimelineArray returns a translation function for ioctls taking a
+// struct drm_syncobj_timeline_array. If pointsOut is true, the ioctl writes
+// the points array.
+func syncobjTimelineArray(pointsOut bool) func(p *ioctlParams, arg *paramBuf) error {
+	return func(p *ioctlParams, arg *paramBuf) error {
+		count := uint64(arg.uint32(syncobjTimelineArrayCountOffset))
+		if _, err := p.array(arg, syncobjTimelineArrayHandlesOffset, count, 4, false /* out */); err != nil {
+			return err
+		}
+		_, err := p.array(arg, syncobjTimelineArrayPointsOffset, count, 8, pointsOut)
+		return err
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drmproxy
+
+import (
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+)
+
+// hostMappable implements memmap.Mappable for a host file whose mappings are
+// passed through to the host, such as a DRM render node (whose offsets are
+// GEM fake offsets) or a dma-buf.
+type hostMappable struct {
+	file hostMemmapFile
+}
+
+// AddMapping implements memmap.Mappable.AddMapping.
+func (m *hostMappable) AddMapping(ctx context.Context, ms memmap.MappingSpace, ar hostarch.AddrRange, offset uint64, writable bool) error {
+	return nil
+}
+
+// RemoveMapping implements memmap.Mappable.RemoveMapping.
+func (m *hostMappable) RemoveMapping(ctx context.Context, ms memmap.MappingSpace, ar hostarch.AddrRange, offset uint64, writable bool) {
+}
+
+// CopyMapping implements memmap.Mappable.CopyMapping.
+func (m *hostMappable) CopyMapping(ctx context.Context, ms memmap.MappingSpace, srcAR, dstAR hostarch.AddrRange, offset uint64, writable bool) error {
+	return nil
+}
+
+// Translate implements memmap.Mappable.Translate.
+func (m *hostMappable) Translate(ctx context.Context, required, optional memmap.MappableRange, at hostarch.AccessType) ([]memmap.Translation, error) {
+	return []memmap.Translation{
+		{
+			Source: optional,
+			File:   &m.file,
+			Offset: optional.Start,
+			Perms:  at,
+		},
+	}, nil
+}
+
+// InvalidateUnsavable implements memmap.Mappable.InvalidateUnsavable.
+func (m *hostMappable) InvalidateUnsavable(ctx context.Context) error {
+	return nil
+}
+
+// hostMemmapFile implements memmap.File for a host file whose pages are mapped
+// directly into application address spaces.
+type hostMemmapFile struct {
+	memmap.NoBufferedIOFallback
+
+	hostFD int32
+}
+
+// IncRef implements memmap.File.IncRef.
+func (mf *hostMemmapFile) IncRef(fr memmap.FileRange, memCgID uint32) {
+}
+
+// DecRef implements memmap.File.DecRef.
+func (mf *hostMemmapFile) DecRef(fr memmap.FileRange) {
+}
+
+// MapInternal implements memmap.File.MapInternal.
+func (mf *hostMemmapFile) MapInternal(fr memmap.FileRange, at hostarch.AccessType) (safemem.BlockSeq, error) {
+	// Device memory may not support the access patterns used by the sentry
+	// for internal mappings (e.g. for process_vm_readv or ptrace).
+	log.Traceback("drmproxy: rejecting hostMemmapFile.MapInternal")
+	return safemem.BlockSeq{}, linuxerr.EINVAL
+}
+
+// FD implements memmap.File.FD.
+func (mf *hostMemmapFile) FD() int {
+	return int(mf.hostFD)
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drmproxy
+
+import (
+	"slices"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/drm"
+	"gvisor.dev/gvisor/pkg/seccomp"
+)
+
+// Filters returns seccomp-bpf filters for this package.
+func Filters() seccomp.SyscallRules {
+	var ioctls seccomp.Or
+	for _, cmd := range supportedIoctls() {
+		ioctls = append(ioctls, seccomp.PerArg{
+			seccomp.NonNegativeFD{},
+			seccomp.EqualTo(cmd),
+		})
+	}
+	return seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
+		unix.SYS_IOCTL: ioctls,
+	})
+}
+
+// supportedIoctls returns the ioctl commands that may be forwarded to host DRM
+// devices and the files they export, in increasing order.
+func supportedIoctls() []uint32 {
+	cmds := []uint32{drm.DMA_BUF_IOCTL_SYNC}
+	for cmd := range coreIoctlHandlers {
+		cmds = append(cmds, cmd)
+	}
+	for _, handlers := range driverIoctlHandlers {
+		for cmd := range handlers {
+			cmds = append(cmds, cmd)
+		}
+	}
+	slices.Sort(cmds)
+	return slices.Compact(cmds)
+}
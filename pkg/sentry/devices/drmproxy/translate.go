@@ -0,0 +1,152 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drmproxy
+
+import (
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal"
+)
+
+// maxParamBytes is the maximum total size of application memory that may be
+// copied into the sentry for a single ioctl.
+const maxParamBytes = 64 << 20
+
+// paramBuf is a sentry copy of application memory referenced by an ioctl.
+type paramBuf struct {
+	// addr is the application address that data was copied from.
+	addr hostarch.Addr
+
+	data []byte
+
+	// out is true if data is copied back to addr after the ioctl.
+	out bool
+
+	// patches records the application pointers in data that have been
+	// replaced by pointers to sentry memory, so that they can be restored
+	// before data is copied out.
+	patches []ptrPatch
+}
+
+// ptrPatch records the original value of a pointer in a paramBuf.
+type ptrPatch struct {
+	off  int
+	orig uint64
+}
+
+func (b *paramBuf) uint32(off int) uint32 {
+	return hostarch.ByteOrder.Uint32(b.data[off:])
+}
+
+func (b *paramBuf) uint64(off int) uint64 {
+	return hostarch.ByteOrder.Uint64(b.data[off:])
+}
+
+func (b *paramBuf) putUint32(off int, v uint32) {
+	hostarch.ByteOrder.PutUint32(b.data[off:], v)
+}
+
+func (b *paramBuf) putUint64(off int, v uint64) {
+	hostarch.ByteOrder.PutUint64(b.data[off:], v)
+}
+
+// patch replaces the pointer at off with v until the buffer is copied out.
+func (b *paramBuf) patch(off int, v uint64) {
+	b.patches = append(b.patches, ptrPatch{off: off, orig: b.uint64(off)})
+	b.putUint64(off, v)
+}
+
+// ioctlParams tracks the sentry copies of application memory used by a single
+// ioctl, which are passed to the host in place of the application's memory.
+type ioctlParams struct {
+	cc    marshal.CopyContext
+	bufs  []*paramBuf
+	total uint64
+}
+
+// copyIn copies size bytes at addr into a new buffer. If out is true, the
+// buffer is copied back to addr by copyOut.
+func (p *ioctlParams) copyIn(addr hostarch.Addr, size uint64, out bool) (*paramBuf, error) {
+	if size > maxParamBytes-p.total {
+		return nil, linuxerr.ENOMEM
+	}
+	p.total += size
+	b := &paramBuf{
+		addr: addr,
+		data: make([]byte, size),
+		out:  out,
+	}
+	if _, err := p.cc.CopyInBytes(addr, b.data); err != nil {
+		return nil, err
+	}
+	p.bufs = append(p.bufs, b)
+	return b, nil
+}
+
+// pointee replaces the application pointer at off in b with a pointer to a
+// sentry copy of the size bytes that it points to, and returns the copy. If
+// the pointer is null or size is 0, the pointer is replaced by null and
+// pointee returns nil.
+func (p *ioctlParams) pointee(b *paramBuf, off int, size uint64, out bool) (*paramBuf, error) {
+	addr := b.uint64(off)
+	if addr == 0 || size == 0 {
+		b.patch(off, 0)
+		return nil, nil
+	}
+	child, err := p.copyIn(hostarch.Addr(addr), size, out)
+	if err != nil {
+		return nil, err
+	}
+	b.patch(off, child.hostAddr())
+	return child, nil
+}
+
+// array is equivalent to pointee for a pointer to an array of count elements
+// of elemSize bytes each.
+func (p *ioctlParams) array(b *paramBuf, off int, count, elemSize uint64, out bool) (*paramBuf, error) {
+	if elemSize != 0 && count > maxParamBytes/elemSize {
+		return nil, linuxerr.ENOMEM
+	}
+	return p.pointee(b, off, count*elemSize, out)
+}
+
+// copyOut restores application pointers replaced by pointee, and copies out
+// buffers for which out is true.
+func (p *ioctlParams) copyOut() error {
+	for _, b := range p.bufs {
+		for i := len(b.patches) - 1; i >= 0; i-- {
+			b.putUint64(b.patches[i].off, b.patches[i].orig)
+		}
+		if !b.out {
+			continue
+		}
+		if _, err := p.cc.CopyOutBytes(b.addr, b.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invoke makes the ioctl cmd on hostFD with argument top, which must be the
+// first buffer copied in by p, then copies out p's buffers.
+func (p *ioctlParams) invoke(hostFD int32, cmd uint32, top *paramBuf) (uintptr, error) {
+	n, err := p.invokeHost(hostFD, cmd, top)
+	// Like Linux's drm_ioctl(), copy out the ioctl's parameters even if the
+	// ioctl fails.
+	if cerr := p.copyOut(); cerr != nil && err == nil {
+		return 0, cerr
+	}
+	return n, err
+}
@@ -0,0 +1,223 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hostdev implements a generic proxy for host character devices that
+// are passed through to the sandbox.
+//
+// Reads, writes and polling are forwarded to the host device. Ioctls are
+// forwarded only if their command is in the device's allowlist. Ioctl
+// arguments are marshalled according to the direction and size encoded in
+// the command number (see include/uapi/asm-generic/ioctl.h): commands that
+// encode neither have their argument passed to the host by value, so only
+// commands whose argument is an integer should be allowlisted. Ioctls whose
+// arguments contain embedded pointers are not supported.
+package hostdev
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/devutil"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fdnotifier"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/hostfd"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// hostDevice implements vfs.Device for a host character device.
+//
+// +stateify savable
+type hostDevice struct {
+	// name is the path of the device on the host, relative to /dev.
+	name string
+
+	// ioctls is the set of ioctl commands that may be forwarded to the
+	// device.
+	ioctls map[uint32]struct{}
+}
+
+// Open implements vfs.Device.Open.
+func (dev *hostDevice) Open(ctx context.Context, mnt *vfs.Mount, d *vfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	client := devutil.GoferClientFromContext(ctx)
+	if client == nil {
+		log.Warningf("devutil.CtxDevGoferClient is not set")
+		return nil, linuxerr.ENOENT
+	}
+	hostFD, err := client.OpenAt(ctx, dev.name, opts.Flags)
+	if err != nil {
+		ctx.Warningf("hostdev: failed to open host %s: %v", dev.name, err)
+		return nil, err
+	}
+	// Blocking is implemented by the sentry, so the host FD is always
+	// non-blocking.
+	if err := unix.SetNonblock(hostFD, true); err != nil {
+		unix.Close(hostFD)
+		return nil, err
+	}
+	fd := &hostDeviceFD{
+		hostFD: int32(hostFD),
+		device: dev,
+	}
+	// Register the host FD before initializing fd.vfsfd, so that failures
+	// don't need to release the references that Init takes.
+	if err := fdnotifier.AddFD(int32(hostFD), &fd.queue); err != nil {
+		unix.Close(hostFD)
+		return nil, err
+	}
+	if err := fd.vfsfd.Init(fd, opts.Flags, mnt, d, &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+	}); err != nil {
+		fdnotifier.RemoveFD(int32(hostFD))
+		unix.Close(hostFD)
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
+// hostDeviceFD implements vfs.FileDescriptionImpl for a host character device.
+type hostDeviceFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+
+	hostFD int32
+	device *hostDevice
+	queue  waiter.Queue
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *hostDeviceFD) Release(context.Context) {
+	fdnotifier.RemoveFD(fd.hostFD)
+	fd.queue.Notify(waiter.EventHUp)
+	unix.Close(int(fd.hostFD))
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (fd *hostDeviceFD) EventRegister(e *waiter.Entry) error {
+	fd.queue.EventRegister(e)
+	if err := fdnotifier.UpdateFD(fd.hostFD); err != nil {
+		fd.queue.EventUnregister(e)
+		return err
+	}
+	return nil
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (fd *hostDeviceFD) EventUnregister(e *waiter.Entry) {
+	fd.queue.EventUnregister(e)
+	if err := fdnotifier.UpdateFD(fd.hostFD); err != nil {
+		panic(fmt.Sprint("UpdateFD:", err))
+	}
+}
+
+// Readiness implements waiter.Waitable.Readiness.
+func (fd *hostDeviceFD) Readiness(mask waiter.EventMask) waiter.EventMask {
+	return fdnotifier.NonBlockingPoll(fd.hostFD, mask)
+}
+
+// Epollable implements vfs.FileDescriptionImpl.Epollable.
+func (fd *hostDeviceFD) Epollable() bool {
+	return true
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (fd *hostDeviceFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	if opts.Flags != 0 {
+		return 0, linuxerr.EOPNOTSUPP
+	}
+	rw := hostfd.GetReadWriterAt(fd.hostFD, -1, opts.Flags)
+	n, err := dst.CopyOutFrom(ctx, rw)
+	hostfd.PutReadWriterAt(rw)
+	if isBlockError(err) {
+		err = linuxerr.ErrWouldBlock
+	}
+	return n, err
+}
+
+// Write implements vfs.FileDescriptionImpl.Write.
+func (fd *hostDeviceFD) Write(ctx context.Context, src usermem.IOSequence, opts vfs.WriteOptions) (int64, error) {
+	if opts.Flags != 0 {
+		return 0, linuxerr.EOPNOTSUPP
+	}
+	rw := hostfd.GetReadWriterAt(fd.hostFD, -1, opts.Flags)
+	n, err := src.CopyInTo(ctx, rw)
+	hostfd.PutReadWriterAt(rw)
+	if isBlockError(err) {
+		err = linuxerr.ErrWouldBlock
+	}
+	return n, err
+}
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *hostDeviceFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
+	cmd := args[1].Uint()
+	if _, ok := fd.device.ioctls[cmd]; !ok {
+		ctx.Debugf("hostdev: ioctl %#x on %s is not allowed", cmd, fd.device.name)
+		return 0, linuxerr.ENOTTY
+	}
+	dir := linux.IOC_DIR(cmd)
+	size := linux.IOC_SIZE(cmd)
+	if dir == linux.IOC_NONE || size == 0 {
+		return ioctlInvoke(fd.hostFD, cmd, args[2].Uint64())
+	}
+	addr := args[2].Pointer()
+	buf := make([]byte, size)
+	if dir&linux.IOC_WRITE != 0 {
+		if _, err := uio.CopyIn(ctx, addr, buf, usermem.IOOpts{}); err != nil {
+			return 0, err
+		}
+	}
+	n, err := ioctlInvokeBuf(fd.hostFD, cmd, buf)
+	if err != nil {
+		return n, err
+	}
+	if dir&linux.IOC_READ != 0 {
+		if _, err := uio.CopyOut(ctx, addr, buf, usermem.IOOpts{}); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+func isBlockError(err error) bool {
+	return linuxerr.Equals(linuxerr.EAGAIN, err) || linuxerr.Equals(linuxerr.EWOULDBLOCK, err)
+}
+
+// Register registers a device that proxies the host character device at
+// hostPath, which must be under /dev, with the given device number in vfsObj.
+// Only the given ioctl commands are forwarded to the host device.
+func Register(vfsObj *vfs.VirtualFilesystem, hostPath string, major, minor uint32, ioctls []uint32) error {
+	name, ok := strings.CutPrefix(hostPath, "/dev/")
+	if !ok || name == "" {
+		return fmt.Errorf("host device path %q is not under /dev", hostPath)
+	}
+	dev := &hostDevice{
+		name:   name,
+		ioctls: make(map[uint32]struct{}, len(ioctls)),
+	}
+	for _, cmd := range ioctls {
+		dev.ioctls[cmd] = struct{}{}
+	}
+	return vfsObj.RegisterDevice(vfs.CharDevice, major, minor, dev, &vfs.RegisterDeviceOptions{
+		GroupName: "hostdev",
+	})
+}
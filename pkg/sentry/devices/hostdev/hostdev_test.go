@@ -0,0 +1,136 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostdev
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/contexttest"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// fsNodumpFl is FS_NODUMP_FL from include/uapi/linux/fs.h.
+const fsNodumpFl = 0x40
+
+// newTestFD returns a hostDeviceFD backed by a host memfd, which supports
+// FS_IOC_GETFLAGS and FS_IOC_SETFLAGS, and allows the given ioctls.
+func newTestFD(t *testing.T, ioctls ...uint32) *hostDeviceFD {
+	t.Helper()
+	hostFD, err := unix.MemfdCreate("hostdev_test", unix.MFD_CLOEXEC)
+	if err != nil {
+		t.Fatalf("memfd_create failed: %v", err)
+	}
+	t.Cleanup(func() { unix.Close(hostFD) })
+	if _, err := unix.IoctlGetUint32(hostFD, unix.FS_IOC_GETFLAGS); err != nil {
+		t.Skipf("FS_IOC_GETFLAGS not supported on memfds: %v", err)
+	}
+	dev := &hostDevice{
+		name:   "test",
+		ioctls: make(map[uint32]struct{}),
+	}
+	for _, cmd := range ioctls {
+		dev.ioctls[cmd] = struct{}{}
+	}
+	return &hostDeviceFD{
+		hostFD: int32(hostFD),
+		device: dev,
+	}
+}
+
+func ioctlArgs(cmd uint32, addr hostarch.Addr) arch.SyscallArguments {
+	return arch.SyscallArguments{
+		{},
+		{Value: uintptr(cmd)},
+		{Value: uintptr(addr)},
+	}
+}
+
+func TestIoctlNotAllowed(t *testing.T) {
+	ctx := contexttest.Context(t)
+	fd := newTestFD(t, unix.FS_IOC_GETFLAGS)
+	uio := &usermem.BytesIO{Bytes: make([]byte, 8)}
+
+	if _, err := fd.Ioctl(ctx, uio, 0, ioctlArgs(unix.FS_IOC_SETFLAGS, 0)); !linuxerr.Equals(linuxerr.ENOTTY, err) {
+		t.Errorf("Ioctl(FS_IOC_SETFLAGS) got error %v, want ENOTTY", err)
+	}
+	flags, err := unix.IoctlGetUint32(int(fd.hostFD), unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		t.Fatalf("FS_IOC_GETFLAGS failed: %v", err)
+	}
+	if flags != 0 {
+		t.Errorf("got host flags %#x after disallowed ioctl, want 0", flags)
+	}
+}
+
+func TestIoctlCopyIn(t *testing.T) {
+	ctx := contexttest.Context(t)
+	fd := newTestFD(t, unix.FS_IOC_SETFLAGS)
+	// Place the argument at a non-zero address to check that it is read from
+	// there.
+	const addr = 8
+	uio := &usermem.BytesIO{Bytes: make([]byte, 16)}
+	hostarch.ByteOrder.PutUint64(uio.Bytes[addr:], fsNodumpFl)
+
+	if _, err := fd.Ioctl(ctx, uio, 0, ioctlArgs(unix.FS_IOC_SETFLAGS, addr)); err != nil {
+		t.Fatalf("Ioctl(FS_IOC_SETFLAGS) failed: %v", err)
+	}
+	flags, err := unix.IoctlGetUint32(int(fd.hostFD), unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		t.Fatalf("FS_IOC_GETFLAGS failed: %v", err)
+	}
+	if flags != fsNodumpFl {
+		t.Errorf("got host flags %#x, want %#x", flags, fsNodumpFl)
+	}
+}
+
+func TestIoctlCopyOut(t *testing.T) {
+	ctx := contexttest.Context(t)
+	fd := newTestFD(t, unix.FS_IOC_GETFLAGS)
+	if err := unix.IoctlSetPointerInt(int(fd.hostFD), unix.FS_IOC_SETFLAGS, fsNodumpFl); err != nil {
+		t.Fatalf("FS_IOC_SETFLAGS failed: %v", err)
+	}
+	const addr = 8
+	uio := &usermem.BytesIO{Bytes: make([]byte, 16)}
+
+	if _, err := fd.Ioctl(ctx, uio, 0, ioctlArgs(unix.FS_IOC_GETFLAGS, addr)); err != nil {
+		t.Fatalf("Ioctl(FS_IOC_GETFLAGS) failed: %v", err)
+	}
+	if got := hostarch.ByteOrder.Uint32(uio.Bytes[addr:]); got != fsNodumpFl {
+		t.Errorf("Ioctl(FS_IOC_GETFLAGS) copied out flags %#x, want %#x", got, fsNodumpFl)
+	}
+	for i := 0; i < addr; i++ {
+		if uio.Bytes[i] != 0 {
+			t.Fatalf("Ioctl(FS_IOC_GETFLAGS) wrote byte %d outside its argument", i)
+		}
+	}
+}
+
+func TestIoctlBadAddress(t *testing.T) {
+	ctx := contexttest.Context(t)
+	fd := newTestFD(t, unix.FS_IOC_GETFLAGS, unix.FS_IOC_SETFLAGS)
+	// The 8-byte argument doesn't fit at addr.
+	const addr = 4
+	uio := &usermem.BytesIO{Bytes: make([]byte, 8)}
+
+	for _, cmd := range []uint32{unix.FS_IOC_GETFLAGS, unix.FS_IOC_SETFLAGS} {
+		if _, err := fd.Ioctl(ctx, uio, 0, ioctlArgs(cmd, addr)); !linuxerr.Equals(linuxerr.EFAULT, err) {
+			t.Errorf("Ioctl(%#x) got error %v, want EFAULT", cmd, err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostdev
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ioctlInvoke makes an ioctl syscall on hostFD with an integer argument.
+func ioctlInvoke(hostFD int32, cmd uint32, arg uint64) (uintptr, error) {
+	n, _, errno := unix.RawSyscall(unix.SYS_IOCTL, uintptr(hostFD), uintptr(cmd), uintptr(arg))
+	if errno != 0 {
+		return n, errno
+	}
+	return n, nil
+}
+
+// ioctlInvokeBuf makes an ioctl syscall on hostFD whose argument is a pointer
+// to buf.
+//
+// Preconditions: len(buf) > 0.
+func ioctlInvokeBuf(hostFD int32, cmd uint32, buf []byte) (uintptr, error) {
+	n, _, errno := unix.RawSyscall(unix.SYS_IOCTL, uintptr(hostFD), uintptr(cmd), uintptr(unsafe.Pointer(&buf[0])))
+	if errno != 0 {
+		return n, errno
+	}
+	return n, nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostdev
+
+import (
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/seccomp"
+)
+
+// Filters returns seccomp-bpf filters that allow forwarding the given ioctl
+// commands to host devices.
+func Filters(ioctls []uint32) seccomp.SyscallRules {
+	if len(ioctls) == 0 {
+		return seccomp.NewSyscallRules()
+	}
+	var rules seccomp.Or
+	for _, cmd := range ioctls {
+		rules = append(rules, seccomp.PerArg{
+			seccomp.NonNegativeFD{},
+			seccomp.EqualTo(cmd),
+		})
+	}
+	return seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
+		unix.SYS_IOCTL: rules,
+	})
+}
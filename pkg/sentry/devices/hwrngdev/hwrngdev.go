@@ -0,0 +1,94 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hwrngdev implements /dev/hwrng, as implemented in Linux by
+// drivers/char/hw_random/core.c. The device behaves like a virtio-rng device
+// backed by the host's getrandom(2).
+package hwrngdev
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/rand"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// hwrngDevMinor is the minor device number of /dev/hwrng, from Linux's
+// include/linux/miscdevice.h:HWRNG_MINOR.
+const hwrngDevMinor = 183
+
+// hwrngDevice implements vfs.Device for /dev/hwrng.
+//
+// +stateify savable
+type hwrngDevice struct{}
+
+// Open implements vfs.Device.Open.
+func (hwrngDevice) Open(ctx context.Context, mnt *vfs.Mount, vfsd *vfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	// Linux: drivers/char/hw_random/core.c:rng_dev_open(): "enforce read-only
+	// access to this chrdev".
+	if ats := vfs.AccessTypesForOpenFlags(&opts); ats != vfs.MayRead {
+		return nil, linuxerr.EINVAL
+	}
+	fd := &hwrngFD{}
+	if err := fd.vfsfd.Init(fd, opts.Flags, mnt, vfsd, &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+	}); err != nil {
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
+// hwrngFD implements vfs.FileDescriptionImpl for /dev/hwrng.
+//
+// +stateify savable
+type hwrngFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *hwrngFD) Release(context.Context) {
+	// noop
+}
+
+// PRead implements vfs.FileDescriptionImpl.PRead.
+func (fd *hwrngFD) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
+	return dst.CopyOutFrom(ctx, safemem.FromIOReader{rand.Reader})
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (fd *hwrngFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	return dst.CopyOutFrom(ctx, safemem.FromIOReader{rand.Reader})
+}
+
+// Seek implements vfs.FileDescriptionImpl.Seek.
+func (fd *hwrngFD) Seek(ctx context.Context, offset int64, whence int32) (int64, error) {
+	// Linux: drivers/char/hw_random/core.c:rng_chrdev_ops.llseek ==
+	// noop_llseek
+	return 0, nil
+}
+
+// Register registers all devices implemented by this package in vfsObj.
+func Register(vfsObj *vfs.VirtualFilesystem) error {
+	return vfsObj.RegisterDevice(vfs.CharDevice, linux.MISC_MAJOR, hwrngDevMinor, hwrngDevice{}, &vfs.RegisterDeviceOptions{
+		GroupName: "misc",
+		Pathname:  "hwrng",
+		FilePerms: 0600,
+	})
+}
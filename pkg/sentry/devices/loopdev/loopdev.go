@@ -0,0 +1,581 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package loopdev implements loop block devices (/dev/loop*) and
+// /dev/loop-control, as implemented in Linux by drivers/block/loop.c. A loop
+// device exposes a range of a backing file as a block device, e.g. so that
+// filesystem images can be mounted.
+package loopdev
+
+import (
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// numLoopDevices is the number of loop devices, from Linux's default
+// CONFIG_BLK_DEV_LOOP_MIN_COUNT. Unlike Linux, loop devices can't be added
+// or removed dynamically.
+const numLoopDevices = 8
+
+// defaultBlockSize is the default logical block size of loop devices.
+const defaultBlockSize = 512
+
+// loopDevice implements vfs.Device for /dev/loopN.
+//
+// +stateify savable
+type loopDevice struct {
+	// minor is the device's minor number. minor is immutable.
+	minor uint32
+
+	// mu protects the following fields.
+	mu sync.Mutex `state:"nosave"`
+
+	// file is the backing file, or nil if the device is unbound.
+	file *vfs.FileDescription
+
+	// offset is the offset in file of the start of the device.
+	offset uint64
+
+	// sizeLimit is the maximum size of the device in bytes, or 0 if the
+	// device extends to the end of file.
+	sizeLimit uint64
+
+	// blockSize is the logical block size of the device.
+	blockSize uint32
+
+	// flags is a bitmask of linux.LO_FLAGS_*.
+	flags uint32
+
+	// fileName is the backing file name reported by LOOP_GET_STATUS64.
+	fileName [linux.LO_NAME_SIZE]byte
+
+	// openers is the number of open file descriptions of the device.
+	openers int
+}
+
+// Open implements vfs.Device.Open.
+func (dev *loopDevice) Open(ctx context.Context, mnt *vfs.Mount, vfsd *vfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	fd := &loopFD{dev: dev}
+	if err := fd.vfsfd.Init(fd, opts.Flags, mnt, vfsd, &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+	}); err != nil {
+		return nil, err
+	}
+	dev.mu.Lock()
+	dev.openers++
+	dev.mu.Unlock()
+	return &fd.vfsfd, nil
+}
+
+// bindLocked binds dev to file.
+//
+// Preconditions: dev.mu must be locked.
+func (dev *loopDevice) bindLocked(ctx context.Context, file *vfs.FileDescription, writable bool) error {
+	if dev.file != nil {
+		return linuxerr.EBUSY
+	}
+	stat, err := file.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_TYPE})
+	if err != nil {
+		return err
+	}
+	if typ := stat.Mode & linux.S_IFMT; typ != linux.S_IFREG && typ != linux.S_IFBLK {
+		return linuxerr.EINVAL
+	}
+	// Linux's drivers/block/loop.c:loop_validate_file() allows stacking loop
+	// devices as long as they don't form a cycle. For simplicity, and since
+	// checking for cycles would require locking multiple loop devices, don't
+	// allow stacking loop devices at all.
+	if _, ok := file.Impl().(*loopFD); ok {
+		return linuxerr.EINVAL
+	}
+	file.IncRef()
+	dev.file = file
+	dev.offset = 0
+	dev.sizeLimit = 0
+	dev.blockSize = defaultBlockSize
+	dev.flags = 0
+	if !writable || !file.IsWritable() {
+		dev.flags |= linux.LO_FLAGS_READ_ONLY
+	}
+	dev.fileName = [linux.LO_NAME_SIZE]byte{}
+	return nil
+}
+
+// clearLocked unbinds dev from its backing file.
+//
+// Preconditions: dev.mu must be locked. dev.file != nil.
+func (dev *loopDevice) clearLocked(ctx context.Context) {
+	dev.file.DecRef(ctx)
+	dev.file = nil
+	dev.offset = 0
+	dev.sizeLimit = 0
+	dev.flags = 0
+	dev.fileName = [linux.LO_NAME_SIZE]byte{}
+}
+
+// setStatusLocked applies info to dev. Only the flags in settable are
+// changed.
+//
+// This corresponds to Linux's drivers/block/loop.c:loop_set_status().
+//
+// Preconditions: dev.mu must be locked. dev.file != nil.
+func (dev *loopDevice) setStatusLocked(info *linux.LoopInfo64, settable uint32) error {
+	if info.EncryptType != 0 || info.EncryptKeySize != 0 {
+		// Loop device encryption was removed from Linux.
+		return linuxerr.EINVAL
+	}
+	dev.offset = info.Offset
+	dev.sizeLimit = info.SizeLimit
+	dev.flags = (dev.flags &^ settable) | (info.Flags & settable)
+	dev.fileName = info.FileName
+	dev.fileName[linux.LO_NAME_SIZE-1] = 0
+	return nil
+}
+
+// sizeLocked returns the size of dev in bytes.
+//
+// Preconditions: dev.mu must be locked.
+func (dev *loopDevice) sizeLocked(ctx context.Context) (uint64, error) {
+	if dev.file == nil {
+		return 0, nil
+	}
+	stat, err := dev.file.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_SIZE})
+	if err != nil {
+		return 0, err
+	}
+	if stat.Size <= dev.offset {
+		return 0, nil
+	}
+	size := stat.Size - dev.offset
+	if dev.sizeLimit != 0 && dev.sizeLimit < size {
+		size = dev.sizeLimit
+	}
+	// Like Linux, round down to a multiple of the sector size.
+	return size &^ (defaultBlockSize - 1), nil
+}
+
+// loopFD implements vfs.FileDescriptionImpl for /dev/loopN.
+//
+// +stateify savable
+type loopFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+
+	dev *loopDevice
+
+	// offMu protects off.
+	offMu sync.Mutex `state:"nosave"`
+
+	// off is the file offset.
+	off int64
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *loopFD) Release(ctx context.Context) {
+	dev := fd.dev
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	dev.openers--
+	if dev.openers == 0 && dev.file != nil && dev.flags&linux.LO_FLAGS_AUTOCLEAR != 0 {
+		dev.clearLocked(ctx)
+	}
+}
+
+// backing returns the backing file of fd's device with an extra reference,
+// the offset of the device in the backing file, the size of the device, and
+// whether the device is read-only. If the device is unbound, backing returns
+// a nil file.
+func (fd *loopFD) backing(ctx context.Context) (*vfs.FileDescription, uint64, uint64, bool, error) {
+	dev := fd.dev
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	if dev.file == nil {
+		return nil, 0, 0, false, nil
+	}
+	size, err := dev.sizeLocked(ctx)
+	if err != nil {
+		return nil, 0, 0, false, err
+	}
+	dev.file.IncRef()
+	return dev.file, dev.offset, size, dev.flags&linux.LO_FLAGS_READ_ONLY != 0, nil
+}
+
+// PRead implements vfs.FileDescriptionImpl.PRead.
+func (fd *loopFD) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
+	if offset < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	file, devOff, size, _, err := fd.backing(ctx)
+	if err != nil || file == nil {
+		return 0, err
+	}
+	defer file.DecRef(ctx)
+	if uint64(offset) >= size {
+		return 0, nil
+	}
+	dst = dst.TakeFirst64(int64(size - uint64(offset)))
+	return file.PRead(ctx, dst, offset+int64(devOff), opts)
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (fd *loopFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	fd.offMu.Lock()
+	defer fd.offMu.Unlock()
+	n, err := fd.PRead(ctx, dst, fd.off, opts)
+	fd.off += n
+	return n, err
+}
+
+// PWrite implements vfs.FileDescriptionImpl.PWrite.
+func (fd *loopFD) PWrite(ctx context.Context, src usermem.IOSequence, offset int64, opts vfs.WriteOptions) (int64, error) {
+	if offset < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	file, devOff, size, readOnly, err := fd.backing(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if file == nil {
+		return 0, linuxerr.ENOSPC
+	}
+	defer file.DecRef(ctx)
+	// Compare Linux's block/fops.c:blkdev_write_iter().
+	if readOnly {
+		return 0, linuxerr.EPERM
+	}
+	if uint64(offset) >= size {
+		return 0, linuxerr.ENOSPC
+	}
+	src = src.TakeFirst64(int64(size - uint64(offset)))
+	return file.PWrite(ctx, src, offset+int64(devOff), opts)
+}
+
+// Write implements vfs.FileDescriptionImpl.Write.
+func (fd *loopFD) Write(ctx context.Context, src usermem.IOSequence, opts vfs.WriteOptions) (int64, error) {
+	fd.offMu.Lock()
+	defer fd.offMu.Unlock()
+	n, err := fd.PWrite(ctx, src, fd.off, opts)
+	fd.off += n
+	return n, err
+}
+
+// Seek implements vfs.FileDescriptionImpl.Seek.
+func (fd *loopFD) Seek(ctx context.Context, offset int64, whence int32) (int64, error) {
+	fd.offMu.Lock()
+	defer fd.offMu.Unlock()
+	switch whence {
+	case linux.SEEK_SET:
+		// use offset as specified
+	case linux.SEEK_CUR:
+		offset += fd.off
+	case linux.SEEK_END:
+		fd.dev.mu.Lock()
+		size, err := fd.dev.sizeLocked(ctx)
+		fd.dev.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+		offset += int64(size)
+	default:
+		return 0, linuxerr.EINVAL
+	}
+	if offset < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	fd.off = offset
+	return offset, nil
+}
+
+// Sync implements vfs.FileDescriptionImpl.Sync.
+func (fd *loopFD) Sync(ctx context.Context) error {
+	file, _, _, _, err := fd.backing(ctx)
+	if err != nil || file == nil {
+		return err
+	}
+	defer file.DecRef(ctx)
+	return file.Sync(ctx)
+}
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *loopFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		panic("Ioctl should be called from a task context")
+	}
+	request := args[1].Uint()
+	argPtr := args[2].Pointer()
+	dev := fd.dev
+
+	switch request {
+	case linux.LOOP_SET_FD:
+		file := t.GetFile(args[2].Int())
+		if file == nil {
+			return 0, linuxerr.EBADF
+		}
+		defer file.DecRef(ctx)
+		dev.mu.Lock()
+		defer dev.mu.Unlock()
+		return 0, dev.bindLocked(ctx, file, fd.vfsfd.IsWritable())
+
+	case linux.LOOP_CONFIGURE:
+		var config linux.LoopConfig
+		if _, err := config.CopyIn(t, argPtr); err != nil {
+			return 0, err
+		}
+		file := t.GetFile(int32(config.FD))
+		if file == nil {
+			return 0, linuxerr.EBADF
+		}
+		defer file.DecRef(ctx)
+		if config.BlockSize != 0 && !validBlockSize(config.BlockSize) {
+			return 0, linuxerr.EINVAL
+		}
+		dev.mu.Lock()
+		defer dev.mu.Unlock()
+		if err := dev.bindLocked(ctx, file, fd.vfsfd.IsWritable()); err != nil {
+			return 0, err
+		}
+		const settable = linux.LO_FLAGS_AUTOCLEAR | linux.LO_FLAGS_PARTSCAN | linux.LO_FLAGS_DIRECT_IO
+		if err := dev.setStatusLocked(&config.Info, settable); err != nil {
+			dev.clearLocked(ctx)
+			return 0, err
+		}
+		dev.flags |= config.Info.Flags & linux.LO_FLAGS_READ_ONLY
+		if config.BlockSize != 0 {
+			dev.blockSize = config.BlockSize
+		}
+		return 0, nil
+
+	case linux.LOOP_CLR_FD:
+		dev.mu.Lock()
+		defer dev.mu.Unlock()
+		if dev.file == nil {
+			return 0, linuxerr.ENXIO
+		}
+		// Like Linux, defer clearing the device until it is no longer in use
+		// by another opener (e.g. a mounted filesystem).
+		if dev.openers > 1 {
+			dev.flags |= linux.LO_FLAGS_AUTOCLEAR
+			return 0, nil
+		}
+		dev.clearLocked(ctx)
+		return 0, nil
+
+	case linux.LOOP_SET_STATUS64:
+		var info linux.LoopInfo64
+		if _, err := info.CopyIn(t, argPtr); err != nil {
+			return 0, err
+		}
+		dev.mu.Lock()
+		defer dev.mu.Unlock()
+		if dev.file == nil {
+			return 0, linuxerr.ENXIO
+		}
+		const settable = linux.LO_FLAGS_AUTOCLEAR | linux.LO_FLAGS_PARTSCAN
+		return 0, dev.setStatusLocked(&info, settable)
+
+	case linux.LOOP_GET_STATUS64:
+		dev.mu.Lock()
+		if dev.file == nil {
+			dev.mu.Unlock()
+			return 0, linuxerr.ENXIO
+		}
+		info := linux.LoopInfo64{
+			Offset:    dev.offset,
+			SizeLimit: dev.sizeLimit,
+			Number:    dev.minor,
+			Flags:     dev.flags,
+			FileName:  dev.fileName,
+		}
+		file := dev.file
+		file.IncRef()
+		dev.mu.Unlock()
+		defer file.DecRef(ctx)
+		stat, err := file.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_INO})
+		if err != nil {
+			return 0, err
+		}
+		info.Device = uint64(linux.MakeDeviceID(uint16(stat.DevMajor), stat.DevMinor))
+		info.Inode = stat.Ino
+		info.Rdevice = uint64(linux.MakeDeviceID(uint16(stat.RdevMajor), stat.RdevMinor))
+		_, err = info.CopyOut(t, argPtr)
+		return 0, err
+
+	case linux.LOOP_SET_CAPACITY:
+		// The size of the device is recomputed on each access.
+		dev.mu.Lock()
+		defer dev.mu.Unlock()
+		if dev.file == nil {
+			return 0, linuxerr.ENXIO
+		}
+		return 0, nil
+
+	case linux.LOOP_SET_BLOCK_SIZE:
+		blockSize := args[2].Uint()
+		if !validBlockSize(blockSize) {
+			return 0, linuxerr.EINVAL
+		}
+		dev.mu.Lock()
+		defer dev.mu.Unlock()
+		if dev.file == nil {
+			return 0, linuxerr.ENXIO
+		}
+		dev.blockSize = blockSize
+		return 0, nil
+
+	case linux.BLKGETSIZE64:
+		dev.mu.Lock()
+		size, err := dev.sizeLocked(ctx)
+		dev.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+		_, err = primitive.CopyUint64Out(t, argPtr, size)
+		return 0, err
+
+	case linux.BLKGETSIZE:
+		dev.mu.Lock()
+		size, err := dev.sizeLocked(ctx)
+		dev.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+		_, err = primitive.CopyUint64Out(t, argPtr, size/512)
+		return 0, err
+
+	case linux.BLKSSZGET:
+		dev.mu.Lock()
+		blockSize := dev.blockSize
+		dev.mu.Unlock()
+		if blockSize == 0 {
+			blockSize = defaultBlockSize
+		}
+		_, err := primitive.CopyInt32Out(t, argPtr, int32(blockSize))
+		return 0, err
+
+	case linux.BLKROGET:
+		dev.mu.Lock()
+		ro := dev.flags&linux.LO_FLAGS_READ_ONLY != 0
+		dev.mu.Unlock()
+		var val int32
+		if ro {
+			val = 1
+		}
+		_, err := primitive.CopyInt32Out(t, argPtr, val)
+		return 0, err
+	}
+	return 0, linuxerr.ENOTTY
+}
+
+// validBlockSize returns true if blockSize is a valid logical block size for
+// a loop device.
+func validBlockSize(blockSize uint32) bool {
+	return blockSize >= 512 && blockSize <= 4096 && blockSize&(blockSize-1) == 0
+}
+
+// loopControlDevice implements vfs.Device for /dev/loop-control.
+//
+// +stateify savable
+type loopControlDevice struct {
+	devs []*loopDevice
+}
+
+// Open implements vfs.Device.Open.
+func (dev *loopControlDevice) Open(ctx context.Context, mnt *vfs.Mount, vfsd *vfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	fd := &loopControlFD{dev: dev}
+	if err := fd.vfsfd.Init(fd, opts.Flags, mnt, vfsd, &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+	}); err != nil {
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
+// loopControlFD implements vfs.FileDescriptionImpl for /dev/loop-control.
+//
+// +stateify savable
+type loopControlFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+
+	dev *loopControlDevice
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *loopControlFD) Release(context.Context) {
+	// noop
+}
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *loopControlFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
+	switch args[1].Uint() {
+	case linux.LOOP_CTL_GET_FREE:
+		for _, dev := range fd.dev.devs {
+			dev.mu.Lock()
+			free := dev.file == nil
+			dev.mu.Unlock()
+			if free {
+				return uintptr(dev.minor), nil
+			}
+		}
+		return 0, linuxerr.ENOSPC
+
+	case linux.LOOP_CTL_ADD:
+		if idx := args[2].Uint(); idx < uint32(len(fd.dev.devs)) {
+			return 0, linuxerr.EEXIST
+		}
+		return 0, linuxerr.EOPNOTSUPP
+
+	case linux.LOOP_CTL_REMOVE:
+		return 0, linuxerr.EOPNOTSUPP
+	}
+	return 0, linuxerr.ENOTTY
+}
+
+// Register registers all devices implemented by this package in vfsObj.
+func Register(vfsObj *vfs.VirtualFilesystem) error {
+	ctl := &loopControlDevice{}
+	for minor := uint32(0); minor < numLoopDevices; minor++ {
+		dev := &loopDevice{
+			minor:     minor,
+			blockSize: defaultBlockSize,
+		}
+		if err := vfsObj.RegisterDevice(vfs.BlockDevice, linux.LOOP_MAJOR, minor, dev, &vfs.RegisterDeviceOptions{
+			GroupName: "loop",
+			Pathname:  fmt.Sprintf("loop%d", minor),
+			FilePerms: 0660,
+		}); err != nil {
+			return err
+		}
+		ctl.devs = append(ctl.devs, dev)
+	}
+	return vfsObj.RegisterDevice(vfs.CharDevice, linux.MISC_MAJOR, linux.LOOP_CTRL_MINOR, ctl, &vfs.RegisterDeviceOptions{
+		GroupName: "misc",
+		Pathname:  "loop-control",
+		FilePerms: 0660,
+	})
+}
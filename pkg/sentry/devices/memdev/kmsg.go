@@ -0,0 +1,224 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memdev
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const kmsgDevMinor = 11
+
+// kmsgDevice implements vfs.Device for /dev/kmsg.
+//
+// +stateify savable
+type kmsgDevice struct{}
+
+// Open implements vfs.Device.Open.
+func (kmsgDevice) Open(ctx context.Context, mnt *vfs.Mount, vfsd *vfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	k := kernel.KernelFromContext(ctx)
+	// As in Linux's kernel/printk/printk.c:devkmsg_open(), opening for writing
+	// only is unprivileged.
+	if opts.Flags&linux.O_ACCMODE != linux.O_WRONLY && k.SyslogActionRestricted(linux.SYSLOG_ACTION_READ_ALL) {
+		if t := kernel.TaskFromContext(ctx); t != nil && !t.MayReadSyslog() {
+			return nil, linuxerr.EPERM
+		}
+	}
+	first, _, _ := k.Syslog().Seqs()
+	fd := &kmsgFD{
+		k:   k,
+		seq: first,
+	}
+	if err := fd.vfsfd.Init(fd, opts.Flags, mnt, vfsd, &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+	}); err != nil {
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
+// kmsgFD implements vfs.FileDescriptionImpl for /dev/kmsg.
+//
+// Each read returns a single record from the kernel log, as in Linux's
+// kernel/printk/printk.c:devkmsg_read(). Each write logs a single record.
+//
+// +stateify savable
+type kmsgFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+
+	k *kernel.Kernel
+
+	// mu protects seq.
+	mu sync.Mutex `state:"nosave"`
+
+	// seq is the sequence number of the next record to be read.
+	seq uint64
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *kmsgFD) Release(context.Context) {
+	// noop
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (fd *kmsgFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	log := fd.k.Syslog()
+	rec, ok := log.Record(fd.seq)
+	if !ok {
+		first, _, _ := log.Seqs()
+		if fd.seq < first {
+			// The next record was discarded before it could be read. Report
+			// the gap and resume from the oldest record.
+			fd.seq = first
+			return 0, linuxerr.EPIPE
+		}
+		return 0, linuxerr.ErrWouldBlock
+	}
+	text := formatKmsgRecord(&rec)
+	if int64(len(text)) > dst.NumBytes() {
+		return 0, linuxerr.EINVAL
+	}
+	n, err := dst.CopyOut(ctx, []byte(text))
+	if err != nil {
+		return 0, err
+	}
+	fd.seq++
+	return int64(n), nil
+}
+
+// Write implements vfs.FileDescriptionImpl.Write.
+func (fd *kmsgFD) Write(ctx context.Context, src usermem.IOSequence, opts vfs.WriteOptions) (int64, error) {
+	size := src.NumBytes()
+	if size > kernel.SyslogRecordMax {
+		return 0, linuxerr.EINVAL
+	}
+	buf := make([]byte, size)
+	if _, err := src.CopyIn(ctx, buf); err != nil {
+		return 0, err
+	}
+	priority, text := parseKmsgWrite(string(buf))
+	fd.k.Printk(priority, text)
+	return size, nil
+}
+
+// Seek implements vfs.FileDescriptionImpl.Seek.
+func (fd *kmsgFD) Seek(ctx context.Context, offset int64, whence int32) (int64, error) {
+	if offset != 0 {
+		return 0, linuxerr.ESPIPE
+	}
+	first, clear, next := fd.k.Syslog().Seqs()
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	switch whence {
+	case linux.SEEK_SET:
+		fd.seq = first
+	case linux.SEEK_DATA:
+		// Seek to the first record that has not been cleared by
+		// SYSLOG_ACTION_CLEAR, which is what "dmesg -c" clears.
+		fd.seq = clear
+	case linux.SEEK_END:
+		fd.seq = next
+	default:
+		return 0, linuxerr.EINVAL
+	}
+	return 0, nil
+}
+
+// Readiness implements waiter.Waitable.Readiness.
+func (fd *kmsgFD) Readiness(mask waiter.EventMask) waiter.EventMask {
+	fd.mu.Lock()
+	seq := fd.seq
+	fd.mu.Unlock()
+	first, _, next := fd.k.Syslog().Seqs()
+	var ready waiter.EventMask
+	if seq < next {
+		ready |= waiter.ReadableEvents
+	}
+	if seq < first {
+		ready |= waiter.EventErr | waiter.EventPri
+	}
+	return (ready | waiter.WritableEvents) & mask
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (fd *kmsgFD) EventRegister(e *waiter.Entry) error {
+	fd.k.Syslog().EventRegister(e)
+	return nil
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (fd *kmsgFD) EventUnregister(e *waiter.Entry) {
+	fd.k.Syslog().EventUnregister(e)
+}
+
+// Epollable implements FileDescriptionImpl.Epollable.
+func (fd *kmsgFD) Epollable() bool {
+	return true
+}
+
+// formatKmsgRecord formats rec as it is read from /dev/kmsg:
+// "priority,sequence,timestamp,flags;message\n", where the timestamp is in
+// microseconds and non-printable bytes in the message are escaped as \xNN.
+func formatKmsgRecord(rec *kernel.SyslogRecord) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d,%d,%d,-;", rec.Priority, rec.Seq, rec.Timestamp/1000)
+	for i := 0; i < len(rec.Text); i++ {
+		c := rec.Text[i]
+		if c < ' ' || c >= 127 || c == '\\' {
+			fmt.Fprintf(&b, "\\x%02x", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// parseKmsgWrite returns the priority and text of the record logged by a
+// write of msg to /dev/kmsg. msg may begin with a "<N>" priority prefix; if
+// it does not, or if the prefix does not specify a facility, the record is
+// logged with facility LOG_USER, and with level LOGLEVEL_WARNING if no level
+// is given.
+func parseKmsgWrite(msg string) (uint32, string) {
+	facility := uint32(linux.LOG_USER)
+	level := uint32(linux.LOGLEVEL_WARNING)
+	if rest, ok := strings.CutPrefix(msg, "<"); ok {
+		if end := strings.IndexByte(rest, '>'); end > 0 {
+			if u, err := strconv.ParseUint(rest[:end], 10, 32); err == nil {
+				level = uint32(u) & 7
+				if u>>3 != 0 {
+					facility = uint32(u) >> 3
+				}
+				msg = rest[end+1:]
+			}
+		}
+	}
+	return facility<<3 | level, strings.TrimSuffix(msg, "\n")
+}
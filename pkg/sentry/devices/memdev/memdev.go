@@ -13,7 +13,8 @@
 // limitations under the License.
 
 // Package memdev implements "mem" character devices, as implemented in Linux
-// by drivers/char/mem.c and drivers/char/random.c.
+// by drivers/char/mem.c and drivers/char/random.c, and /dev/kmsg, as
+// implemented in Linux by kernel/printk/printk.c.
 package memdev
 
 import (
@@ -26,17 +27,19 @@ func Register(vfsObj *vfs.VirtualFilesystem) error {
 	for minor, spec := range map[uint32]struct {
 		dev      vfs.Device
 		pathname string
+		perms    uint16
 	}{
-		nullDevMinor:    {nullDevice{}, "null"},
-		zeroDevMinor:    {zeroDevice{}, "zero"},
-		fullDevMinor:    {fullDevice{}, "full"},
-		randomDevMinor:  {randomDevice{}, "random"},
-		urandomDevMinor: {randomDevice{}, "urandom"},
+		nullDevMinor:    {nullDevice{}, "null", 0666},
+		zeroDevMinor:    {zeroDevice{}, "zero", 0666},
+		fullDevMinor:    {fullDevice{}, "full", 0666},
+		randomDevMinor:  {randomDevice{}, "random", 0666},
+		urandomDevMinor: {randomDevice{}, "urandom", 0666},
+		kmsgDevMinor:    {kmsgDevice{}, "kmsg", 0644},
 	} {
 		if err := vfsObj.RegisterDevice(vfs.CharDevice, linux.MEM_MAJOR, minor, spec.dev, &vfs.RegisterDeviceOptions{
 			GroupName: "mem",
 			Pathname:  spec.pathname,
-			FilePerms: 0666,
+			FilePerms: spec.perms,
 		}); err != nil {
 			return err
 		}
@@ -0,0 +1,313 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sndproxy
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	// maxElemListSpace is the maximum number of element IDs returned by a
+	// single SNDRV_CTL_IOCTL_ELEM_LIST.
+	maxElemListSpace = 1 << 14
+
+	// maxTLVLength is the maximum length of TLV data returned by
+	// SNDRV_CTL_IOCTL_TLV_READ.
+	maxTLVLength = 16 * hostarch.PageSize
+
+	// maxXferBytes is the maximum number of bytes transferred by a single
+	// SNDRV_PCM_IOCTL_WRITEI_FRAMES or SNDRV_PCM_IOCTL_READI_FRAMES.
+	maxXferBytes = 1 << 22
+)
+
+// ioctlState holds the state of a call to sndFD.Ioctl().
+type ioctlState struct {
+	fd  *sndFD
+	t   *kernel.Task
+	cmd uint32
+	arg arch.SyscallArgument
+}
+
+// ioctlHandler handles an ioctl on a sndFD.
+type ioctlHandler func(is *ioctlState) (uintptr, error)
+
+// ioctlHandlers maps each kind of device to the ioctls that it supports.
+var ioctlHandlers = map[deviceKind]map[uint32]ioctlHandler{
+	kindControl: {
+		linux.SNDRV_CTL_IOCTL_PVERSION:             ioctlSimple,
+		linux.SNDRV_CTL_IOCTL_CARD_INFO:            ioctlSimple,
+		linux.SNDRV_CTL_IOCTL_ELEM_LIST:            ctlElemList,
+		linux.SNDRV_CTL_IOCTL_ELEM_INFO:            ioctlSimple,
+		linux.SNDRV_CTL_IOCTL_ELEM_READ:            ioctlSimple,
+		linux.SNDRV_CTL_IOCTL_ELEM_WRITE:           ioctlSimple,
+		linux.SNDRV_CTL_IOCTL_ELEM_LOCK:            ioctlSimple,
+		linux.SNDRV_CTL_IOCTL_ELEM_UNLOCK:          ioctlSimple,
+		linux.SNDRV_CTL_IOCTL_SUBSCRIBE_EVENTS:     ioctlSimple,
+		linux.SNDRV_CTL_IOCTL_TLV_READ:             ctlTLVRead,
+		linux.SNDRV_CTL_IOCTL_PCM_NEXT_DEVICE:      ioctlSimple,
+		linux.SNDRV_CTL_IOCTL_PCM_INFO:             ioctlSimple,
+		linux.SNDRV_CTL_IOCTL_PCM_PREFER_SUBDEVICE: ioctlSimple,
+		linux.SNDRV_CTL_IOCTL_RAWMIDI_NEXT_DEVICE:  ioctlSimple,
+		linux.SNDRV_CTL_IOCTL_POWER_STATE:          ioctlSimple,
+	},
+	kindPCM: {
+		linux.SNDRV_PCM_IOCTL_PVERSION:      ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_INFO:          ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_TSTAMP:        ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_TTSTAMP:       ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_USER_PVERSION: ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_HW_REFINE:     ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_HW_PARAMS:     pcmHWParams,
+		linux.SNDRV_PCM_IOCTL_HW_FREE:       pcmHWFree,
+		linux.SNDRV_PCM_IOCTL_SW_PARAMS:     ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_STATUS:        ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_DELAY:         ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_HWSYNC:        ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_SYNC_PTR:      ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_STATUS_EXT:    ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_CHANNEL_INFO:  ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_PREPARE:       ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_RESET:         ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_START:         ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_DROP:          ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_DRAIN:         pcmDrain,
+		linux.SNDRV_PCM_IOCTL_PAUSE:         ioctlValue,
+		linux.SNDRV_PCM_IOCTL_REWIND:        ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_RESUME:        ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_XRUN:          ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_FORWARD:       ioctlSimple,
+		linux.SNDRV_PCM_IOCTL_WRITEI_FRAMES: pcmXferi,
+		linux.SNDRV_PCM_IOCTL_READI_FRAMES:  pcmXferi,
+	},
+	kindTimer: {
+		linux.SNDRV_TIMER_IOCTL_PVERSION:    ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_NEXT_DEVICE: ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_TREAD_OLD:   ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_GINFO:       ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_GPARAMS:     ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_GSTATUS:     ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_SELECT:      ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_INFO:        ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_PARAMS:      ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_STATUS:      ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_START:       ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_STOP:        ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_CONTINUE:    ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_PAUSE:       ioctlSimple,
+		linux.SNDRV_TIMER_IOCTL_TREAD64:     ioctlSimple,
+	},
+}
+
+// ioctlSimple implements an ioctl whose argument is either unused or a
+// pointer to a structure, of the size encoded in the command, that doesn't
+// contain any pointers or file descriptors.
+func ioctlSimple(is *ioctlState) (uintptr, error) {
+	size := linux.IOC_SIZE(is.cmd)
+	if size == 0 {
+		return ioctlInvoke(is.fd.hostFD, is.cmd, 0)
+	}
+	dir := linux.IOC_DIR(is.cmd)
+	addr := is.arg.Pointer()
+	buf := make([]byte, size)
+	if dir&linux.IOC_WRITE != 0 {
+		if _, err := is.t.CopyInBytes(addr, buf); err != nil {
+			return 0, err
+		}
+	}
+	n, err := ioctlInvokeBuf(is.fd.hostFD, is.cmd, buf)
+	if err != nil {
+		return n, err
+	}
+	if dir&linux.IOC_READ != 0 {
+		if _, err := is.t.CopyOutBytes(addr, buf); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// ioctlValue implements an ioctl whose argument is an integer passed by value,
+// despite the size encoded in the command.
+func ioctlValue(is *ioctlState) (uintptr, error) {
+	return ioctlInvoke(is.fd.hostFD, is.cmd, is.arg.Uint64())
+}
+
+// ctlElemList implements SNDRV_CTL_IOCTL_ELEM_LIST, whose argument contains a
+// pointer to an array of element IDs.
+func ctlElemList(is *ioctlState) (uintptr, error) {
+	var list linux.SndCtlElemList
+	addr := is.arg.Pointer()
+	if _, err := list.CopyIn(is.t, addr); err != nil {
+		return 0, err
+	}
+	pids := hostarch.Addr(list.Pids)
+	space := min(list.Space, maxElemListSpace)
+	var ids []byte
+	if space > 0 {
+		ids = make([]byte, space*linux.SizeofSndCtlElemID)
+	}
+	hostList := list
+	hostList.Space = space
+	n, err := ctlElemListInvoke(is.fd.hostFD, &hostList, ids)
+	if err != nil {
+		return n, err
+	}
+	used := min(hostList.Used, space)
+	if used > 0 {
+		if _, err := is.t.CopyOutBytes(pids, ids[:used*linux.SizeofSndCtlElemID]); err != nil {
+			return 0, err
+		}
+	}
+	list.Used = used
+	list.Count = hostList.Count
+	if _, err := list.CopyOut(is.t, addr); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ctlTLVRead implements SNDRV_CTL_IOCTL_TLV_READ, whose argument is followed
+// by a variable length buffer.
+func ctlTLVRead(is *ioctlState) (uintptr, error) {
+	var hdr linux.SndCtlTLV
+	addr := is.arg.Pointer()
+	if _, err := hdr.CopyIn(is.t, addr); err != nil {
+		return 0, err
+	}
+	if hdr.Length > maxTLVLength {
+		return 0, linuxerr.EINVAL
+	}
+	buf := make([]byte, linux.SizeofSndCtlTLV+hdr.Length)
+	if _, err := is.t.CopyInBytes(addr, buf); err != nil {
+		return 0, err
+	}
+	n, err := ioctlInvokeBuf(is.fd.hostFD, is.cmd, buf)
+	if err != nil {
+		return n, err
+	}
+	if _, err := is.t.CopyOutBytes(addr, buf); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// pcmHWParams implements SNDRV_PCM_IOCTL_HW_PARAMS, recording the configured
+// frame size for use by pcmXferi.
+func pcmHWParams(is *ioctlState) (uintptr, error) {
+	buf := make([]byte, linux.SizeofSndPcmHWParams)
+	addr := is.arg.Pointer()
+	if _, err := is.t.CopyInBytes(addr, buf); err != nil {
+		return 0, err
+	}
+	is.fd.mu.Lock()
+	defer is.fd.mu.Unlock()
+	n, err := ioctlInvokeBuf(is.fd.hostFD, is.cmd, buf)
+	if err != nil {
+		return n, err
+	}
+	frameBits := hostarch.ByteOrder.Uint32(buf[linux.SndPcmHWParamsFrameBitsOffset:])
+	is.fd.frameBytes = frameBits / 8
+	if _, err := is.t.CopyOutBytes(addr, buf); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// pcmHWFree implements SNDRV_PCM_IOCTL_HW_FREE.
+func pcmHWFree(is *ioctlState) (uintptr, error) {
+	is.fd.mu.Lock()
+	defer is.fd.mu.Unlock()
+	n, err := ioctlInvoke(is.fd.hostFD, is.cmd, 0)
+	if err != nil {
+		return n, err
+	}
+	is.fd.frameBytes = 0
+	return n, nil
+}
+
+// pcmDrain implements SNDRV_PCM_IOCTL_DRAIN, which blocks until all pending
+// frames have been played unless the file is non-blocking.
+func pcmDrain(is *ioctlState) (uintptr, error) {
+	return ioctlBlocking(is, waiter.WritableEvents, func() (uintptr, error) {
+		return ioctlInvoke(is.fd.hostFD, is.cmd, 0)
+	})
+}
+
+// pcmXferi implements SNDRV_PCM_IOCTL_WRITEI_FRAMES and
+// SNDRV_PCM_IOCTL_READI_FRAMES, whose argument contains a pointer to a buffer
+// of interleaved frames.
+func pcmXferi(is *ioctlState) (uintptr, error) {
+	var xferi linux.SndXferi
+	addr := is.arg.Pointer()
+	if _, err := xferi.CopyIn(is.t, addr); err != nil {
+		return 0, err
+	}
+	is.fd.mu.Lock()
+	frameBytes := uint64(is.fd.frameBytes)
+	is.fd.mu.Unlock()
+	if frameBytes == 0 {
+		return 0, linuxerr.EBADFD
+	}
+	frames := min(xferi.Frames, maxXferBytes/frameBytes)
+	buf := make([]byte, frames*frameBytes)
+	write := is.cmd == linux.SNDRV_PCM_IOCTL_WRITEI_FRAMES
+	if write && len(buf) > 0 {
+		if _, err := is.t.CopyInBytes(hostarch.Addr(xferi.Buf), buf); err != nil {
+			return 0, err
+		}
+	}
+	mask := waiter.ReadableEvents
+	if write {
+		mask = waiter.WritableEvents
+	}
+	hostXferi := xferi
+	hostXferi.Frames = frames
+	n, err := ioctlBlocking(is, mask, func() (uintptr, error) {
+		return pcmXferiInvoke(is.fd.hostFD, is.cmd, &hostXferi, buf)
+	})
+	if err != nil {
+		return n, err
+	}
+	if !write && hostXferi.Result > 0 {
+		read := min(uint64(hostXferi.Result), frames)
+		if _, err := is.t.CopyOutBytes(hostarch.Addr(xferi.Buf), buf[:read*frameBytes]); err != nil {
+			return 0, err
+		}
+	}
+	xferi.Result = hostXferi.Result
+	if _, err := xferi.CopyOut(is.t, addr); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ioctlBlocking calls invoke until it doesn't fail with EAGAIN, waiting for the
+// events in mask between calls, unless the file is non-blocking.
+func ioctlBlocking(is *ioctlState, mask waiter.EventMask, invoke func() (uintptr, error)) (uintptr, error) {
+	for {
+		n, err := invoke()
+		if !isBlockError(err) || is.fd.isNonBlocking() {
+			return n, err
+		}
+		if err := is.fd.waitEvents(is.t, mask); err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sndproxy
+
+import (
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+)
+
+// ioctlInvoke makes an ioctl syscall on hostFD with an integer argument.
+func ioctlInvoke(hostFD int32, cmd uint32, arg uint64) (uintptr, error) {
+	n, _, errno := unix.RawSyscall(unix.SYS_IOCTL, uintptr(hostFD), uintptr(cmd), uintptr(arg))
+	if errno != 0 {
+		return n, errno
+	}
+	return n, nil
+}
+
+// ioctlInvokePtr makes an ioctl syscall on hostFD whose argument is ptr.
+func ioctlInvokePtr(hostFD int32, cmd uint32, ptr unsafe.Pointer) (uintptr, error) {
+	n, _, errno := unix.RawSyscall(unix.SYS_IOCTL, uintptr(hostFD), uintptr(cmd), uintptr(ptr))
+	if errno != 0 {
+		return n, errno
+	}
+	return n, nil
+}
+
+// ioctlInvokeBuf makes an ioctl syscall on hostFD whose argument is a pointer
+// to buf.
+//
+// Preconditions: len(buf) > 0.
+func ioctlInvokeBuf(hostFD int32, cmd uint32, buf []byte) (uintptr, error) {
+	return ioctlInvokePtr(hostFD, cmd, unsafe.Pointer(&buf[0]))
+}
+
+// ctlElemListInvoke makes a SNDRV_CTL_IOCTL_ELEM_LIST ioctl syscall on hostFD,
+// with list's element ID array pointing to ids.
+//
+// Preconditions: len(ids) == list.Space * linux.SizeofSndCtlElemID.
+func ctlElemListInvoke(hostFD int32, list *linux.SndCtlElemList, ids []byte) (uintptr, error) {
+	list.Pids = 0
+	if len(ids) > 0 {
+		list.Pids = uint64(uintptr(unsafe.Pointer(&ids[0])))
+	}
+	n, err := ioctlInvokePtr(hostFD, linux.SNDRV_CTL_IOCTL_ELEM_LIST, unsafe.Pointer(list))
+	runtime.KeepAlive(ids)
+	return n, err
+}
+
+// pcmXferiInvoke makes a SNDRV_PCM_IOCTL_WRITEI_FRAMES or
+// SNDRV_PCM_IOCTL_READI_FRAMES ioctl syscall on hostFD, with xferi's buffer
+// pointing to buf.
+//
+// Preconditions: len(buf) is xferi.Frames times the frame size.
+func pcmXferiInvoke(hostFD int32, cmd uint32, xferi *linux.SndXferi, buf []byte) (uintptr, error) {
+	xferi.Buf = 0
+	if len(buf) > 0 {
+		xferi.Buf = uint64(uintptr(unsafe.Pointer(&buf[0])))
+	}
+	n, err := ioctlInvokePtr(hostFD, cmd, unsafe.Pointer(xferi))
+	runtime.KeepAlive(buf)
+	return n, err
+}
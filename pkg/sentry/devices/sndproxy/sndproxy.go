@@ -0,0 +1,166 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sndproxy implements proxying of ALSA sound devices from the host.
+//
+// Control, PCM and timer devices under /dev/snd are supported. Reads, writes
+// and polling are forwarded to the host device. Ioctls are forwarded only if
+// they are known to this package, with pointers embedded in their arguments
+// translated by the sentry. Mapping PCM buffers and status pages is not
+// supported; alsa-lib falls back to SNDRV_PCM_IOCTL_SYNC_PTR and read/write
+// transfers when mmap fails.
+//
+// If no host timer device is passed through, a stub /dev/snd/timer that
+// reports no timers may be registered instead, so that applications that
+// enumerate ALSA timers do not fail.
+package sndproxy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/devutil"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fdnotifier"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+const (
+	// DevDir is the directory containing ALSA devices.
+	DevDir = "/dev/snd"
+
+	// TimerPath is the path of the ALSA timer device.
+	TimerPath = DevDir + "/timer"
+
+	sndDeviceGroupName = "snd"
+)
+
+// deviceKind is the kind of an ALSA device, which determines the ioctls that
+// it supports.
+type deviceKind int
+
+const (
+	kindControl deviceKind = iota
+	kindPCM
+	kindTimer
+)
+
+var (
+	controlDeviceRegex = regexp.MustCompile(`^controlC\d+$`)
+	pcmDeviceRegex     = regexp.MustCompile(`^pcmC\d+D\d+[pc]$`)
+)
+
+// kindForPath returns the kind of the ALSA device at the given path, and false
+// if the device is not supported.
+func kindForPath(path string) (deviceKind, bool) {
+	name, ok := strings.CutPrefix(path, DevDir+"/")
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case controlDeviceRegex.MatchString(name):
+		return kindControl, true
+	case pcmDeviceRegex.MatchString(name):
+		return kindPCM, true
+	case name == "timer":
+		return kindTimer, true
+	default:
+		return 0, false
+	}
+}
+
+// IsSupportedDevice returns true if path is the path of an ALSA device that
+// can be proxied by this package.
+func IsSupportedDevice(path string) bool {
+	_, ok := kindForPath(path)
+	return ok
+}
+
+// sndDevice implements vfs.Device for an ALSA device on the host.
+//
+// +stateify savable
+type sndDevice struct {
+	// name is the path of the device on the host, relative to /dev.
+	name string
+
+	kind deviceKind
+}
+
+// Open implements vfs.Device.Open.
+func (dev *sndDevice) Open(ctx context.Context, mnt *vfs.Mount, d *vfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	client := devutil.GoferClientFromContext(ctx)
+	if client == nil {
+		log.Warningf("devutil.CtxDevGoferClient is not set")
+		return nil, linuxerr.ENOENT
+	}
+	hostFD, err := client.OpenAt(ctx, dev.name, opts.Flags)
+	if err != nil {
+		ctx.Warningf("sndproxy: failed to open host %s: %v", dev.name, err)
+		return nil, err
+	}
+	// Blocking is implemented by the sentry, so the host FD is always
+	// non-blocking.
+	if err := unix.SetNonblock(hostFD, true); err != nil {
+		unix.Close(hostFD)
+		return nil, err
+	}
+	fd := &sndFD{
+		hostFD: int32(hostFD),
+		dev:    dev,
+	}
+	if err := fd.vfsfd.Init(fd, opts.Flags, mnt, d, &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+	}); err != nil {
+		unix.Close(hostFD)
+		return nil, err
+	}
+	if err := fdnotifier.AddFD(int32(hostFD), &fd.queue); err != nil {
+		unix.Close(hostFD)
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
+// Register registers a device that proxies the host ALSA device at hostPath
+// with the given minor device number in vfsObj.
+func Register(vfsObj *vfs.VirtualFilesystem, hostPath string, minor uint32) error {
+	kind, ok := kindForPath(hostPath)
+	if !ok {
+		return fmt.Errorf("unsupported ALSA device %q", hostPath)
+	}
+	name := strings.TrimPrefix(hostPath, "/dev/")
+	return vfsObj.RegisterDevice(vfs.CharDevice, linux.SND_MAJOR, minor, &sndDevice{
+		name: name,
+		kind: kind,
+	}, &vfs.RegisterDeviceOptions{
+		GroupName: sndDeviceGroupName,
+		Pathname:  name,
+		FilePerms: 0666,
+	})
+}
+
+// RegisterStubTimer registers a stub ALSA timer device, which reports that no
+// timers exist, in vfsObj.
+func RegisterStubTimer(vfsObj *vfs.VirtualFilesystem) error {
+	return vfsObj.RegisterDevice(vfs.CharDevice, linux.SND_MAJOR, linux.SNDRV_MINOR_TIMER, &stubTimerDevice{}, &vfs.RegisterDeviceOptions{
+		GroupName: sndDeviceGroupName,
+		Pathname:  strings.TrimPrefix(TimerPath, "/dev/"),
+		FilePerms: 0666,
+	})
+}
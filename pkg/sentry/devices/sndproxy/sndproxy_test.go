@@ -0,0 +1,93 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sndproxy
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+)
+
+func TestKindForPath(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		kind deviceKind
+		ok   bool
+	}{
+		{path: "/dev/snd/controlC0", kind: kindControl, ok: true},
+		{path: "/dev/snd/pcmC0D0p", kind: kindPCM, ok: true},
+		{path: "/dev/snd/pcmC1D12c", kind: kindPCM, ok: true},
+		{path: "/dev/snd/timer", kind: kindTimer, ok: true},
+		{path: "/dev/snd/seq", ok: false},
+		{path: "/dev/snd/hwC0D0", ok: false},
+		{path: "/dev/snd/pcmC0D0", ok: false},
+		{path: "/dev/timer", ok: false},
+	} {
+		kind, ok := kindForPath(test.path)
+		if ok != test.ok || (ok && kind != test.kind) {
+			t.Errorf("kindForPath(%q) = %v, %t, want %v, %t", test.path, kind, ok, test.kind, test.ok)
+		}
+	}
+}
+
+// TestIoctlNumbers checks ioctl numbers against values computed from
+// include/uapi/sound/asound.h on x86_64.
+func TestIoctlNumbers(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		got  uint32
+		want uint32
+	}{
+		{name: "SNDRV_CTL_IOCTL_CARD_INFO", got: linux.SNDRV_CTL_IOCTL_CARD_INFO, want: 0x81785501},
+		{name: "SNDRV_CTL_IOCTL_ELEM_LIST", got: linux.SNDRV_CTL_IOCTL_ELEM_LIST, want: 0xc0505510},
+		{name: "SNDRV_CTL_IOCTL_ELEM_READ", got: linux.SNDRV_CTL_IOCTL_ELEM_READ, want: 0xc4c85512},
+		{name: "SNDRV_CTL_IOCTL_PCM_INFO", got: linux.SNDRV_CTL_IOCTL_PCM_INFO, want: 0xc1205531},
+		{name: "SNDRV_PCM_IOCTL_HW_PARAMS", got: linux.SNDRV_PCM_IOCTL_HW_PARAMS, want: 0xc2604111},
+		{name: "SNDRV_PCM_IOCTL_SYNC_PTR", got: linux.SNDRV_PCM_IOCTL_SYNC_PTR, want: 0xc0884123},
+		{name: "SNDRV_PCM_IOCTL_STATUS", got: linux.SNDRV_PCM_IOCTL_STATUS, want: 0x80984120},
+		{name: "SNDRV_PCM_IOCTL_WRITEI_FRAMES", got: linux.SNDRV_PCM_IOCTL_WRITEI_FRAMES, want: 0x40184150},
+		{name: "SNDRV_PCM_IOCTL_READI_FRAMES", got: linux.SNDRV_PCM_IOCTL_READI_FRAMES, want: 0x80184151},
+		{name: "SNDRV_PCM_IOCTL_DRAIN", got: linux.SNDRV_PCM_IOCTL_DRAIN, want: 0x4144},
+		{name: "SNDRV_TIMER_IOCTL_NEXT_DEVICE", got: linux.SNDRV_TIMER_IOCTL_NEXT_DEVICE, want: 0xc0145401},
+		{name: "SNDRV_TIMER_IOCTL_GINFO", got: linux.SNDRV_TIMER_IOCTL_GINFO, want: 0xc0f85403},
+		{name: "SNDRV_TIMER_IOCTL_SELECT", got: linux.SNDRV_TIMER_IOCTL_SELECT, want: 0x40345410},
+		{name: "SNDRV_TIMER_IOCTL_TREAD64", got: linux.SNDRV_TIMER_IOCTL_TREAD64, want: 0x400454a4},
+	} {
+		if test.got != test.want {
+			t.Errorf("%s = %#x, want %#x", test.name, test.got, test.want)
+		}
+	}
+	if got, want := linux.SndPcmHWParamsFrameBitsOffset, 272; got != want {
+		t.Errorf("SndPcmHWParamsFrameBitsOffset = %d, want %d", got, want)
+	}
+}
+
+func TestSupportedIoctls(t *testing.T) {
+	cmds := supportedIoctls()
+	want := 0
+	for _, handlers := range ioctlHandlers {
+		want += len(handlers)
+	}
+	// Ioctl numbers encode the ALSA interface, so no command is supported
+	// by more than one kind of device.
+	if len(cmds) != want {
+		t.Errorf("got %d distinct supported ioctls, want %d", len(cmds), want)
+	}
+	for i := 1; i < len(cmds); i++ {
+		if cmds[i-1] >= cmds[i] {
+			t.Fatalf("supportedIoctls() is not strictly increasing: %#x", cmds)
+		}
+	}
+}
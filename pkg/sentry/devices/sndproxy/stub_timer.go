@@ -0,0 +1,105 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sndproxy
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// stubTimerDevice implements vfs.Device for a /dev/snd/timer with no timers.
+//
+// +stateify savable
+type stubTimerDevice struct{}
+
+// Open implements vfs.Device.Open.
+func (*stubTimerDevice) Open(ctx context.Context, mnt *vfs.Mount, d *vfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	fd := &stubTimerFD{}
+	if err := fd.vfsfd.Init(fd, opts.Flags, mnt, d, &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+	}); err != nil {
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
+// stubTimerFD implements vfs.FileDescriptionImpl for stubTimerDevice.
+//
+// +stateify savable
+type stubTimerFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (*stubTimerFD) Release(context.Context) {}
+
+// Readiness implements waiter.Waitable.Readiness.
+func (*stubTimerFD) Readiness(mask waiter.EventMask) waiter.EventMask {
+	// No events are ever queued.
+	return 0
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (*stubTimerFD) Read(context.Context, usermem.IOSequence, vfs.ReadOptions) (int64, error) {
+	return 0, linuxerr.ErrWouldBlock
+}
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (*stubTimerFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		panic("Ioctl should be called from a task context")
+	}
+	addr := args[2].Pointer()
+	switch args[1].Uint() {
+	case linux.SNDRV_TIMER_IOCTL_PVERSION:
+		version := primitive.Int32(linux.SNDRV_TIMER_VERSION)
+		_, err := version.CopyOut(t, addr)
+		return 0, err
+	case linux.SNDRV_TIMER_IOCTL_NEXT_DEVICE:
+		var id linux.SndTimerID
+		if _, err := id.CopyIn(t, addr); err != nil {
+			return 0, err
+		}
+		// There are no timers, so there is never a next timer. See
+		// sound/core/timer.c:snd_timer_user_next_device().
+		id.DevClass = linux.SNDRV_TIMER_CLASS_NONE
+		id.DevSClass = linux.SNDRV_TIMER_SCLASS_NONE
+		_, err := id.CopyOut(t, addr)
+		return 0, err
+	case linux.SNDRV_TIMER_IOCTL_TREAD_OLD, linux.SNDRV_TIMER_IOCTL_TREAD64:
+		// The event format is irrelevant, since there are no events.
+		return 0, nil
+	case linux.SNDRV_TIMER_IOCTL_GINFO, linux.SNDRV_TIMER_IOCTL_GPARAMS, linux.SNDRV_TIMER_IOCTL_GSTATUS, linux.SNDRV_TIMER_IOCTL_SELECT:
+		// The specified timer doesn't exist.
+		return 0, linuxerr.ENODEV
+	case linux.SNDRV_TIMER_IOCTL_INFO, linux.SNDRV_TIMER_IOCTL_PARAMS, linux.SNDRV_TIMER_IOCTL_STATUS,
+		linux.SNDRV_TIMER_IOCTL_START, linux.SNDRV_TIMER_IOCTL_STOP, linux.SNDRV_TIMER_IOCTL_CONTINUE, linux.SNDRV_TIMER_IOCTL_PAUSE:
+		// No timer has been selected.
+		return 0, linuxerr.EBADFD
+	default:
+		return 0, linuxerr.ENOTTY
+	}
+}
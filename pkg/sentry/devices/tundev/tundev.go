@@ -24,9 +24,11 @@ import (
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netstack"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/tcpip/link/tun"
@@ -79,9 +81,6 @@ func (fd *tunFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args
 
 	switch request {
 	case linux.TUNSETIFF:
-		if !t.HasCapability(linux.CAP_NET_ADMIN) {
-			return 0, linuxerr.EPERM
-		}
 		stack, ok := t.NetworkContext().(*netstack.Stack)
 		if !ok {
 			return 0, linuxerr.EINVAL
@@ -91,6 +90,9 @@ func (fd *tunFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args
 		if _, err := req.CopyIn(t, data); err != nil {
 			return 0, err
 		}
+		if !t.HasCapability(linux.CAP_NET_ADMIN) && !mayAttach(t, stack, req.Name()) {
+			return 0, linuxerr.EPERM
+		}
 
 		// Validate flags.
 		flags, err := netstack.LinuxToTUNFlags(hostarch.ByteOrder.Uint16(req.Data[:]))
@@ -106,6 +108,47 @@ func (fd *tunFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args
 		_, err := req.CopyOut(t, data)
 		return 0, err
 
+	case linux.TUNGETFEATURES:
+		features := primitive.Uint32(linux.IFF_TUN | linux.IFF_TAP | linux.IFF_NO_PI | linux.IFF_ONE_QUEUE | linux.IFF_VNET_HDR)
+		_, err := features.CopyOut(t, data)
+		return 0, err
+
+	case linux.TUNSETNOCSUM:
+		// Deprecated and ignored by Linux.
+		return 0, nil
+
+	case linux.TUNSETPERSIST:
+		return 0, fd.device.SetPersistent(ctx, args[2].Uint64() != 0)
+
+	case linux.TUNSETOWNER:
+		owner := t.UserNamespace().MapToKUID(auth.UID(args[2].Uint()))
+		if !owner.Ok() {
+			return 0, linuxerr.EINVAL
+		}
+		return 0, fd.device.SetOwner(uint32(owner))
+
+	case linux.TUNSETGROUP:
+		group := t.UserNamespace().MapToKGID(auth.GID(args[2].Uint()))
+		if !group.Ok() {
+			return 0, linuxerr.EINVAL
+		}
+		return 0, fd.device.SetGroup(uint32(group))
+
+	case linux.TUNSETOFFLOAD:
+		return 0, fd.device.SetOffload(args[2].Uint())
+
+	case linux.TUNGETVNETHDRSZ:
+		size := primitive.Int32(fd.device.VnetHdrSize())
+		_, err := size.CopyOut(t, data)
+		return 0, err
+
+	case linux.TUNSETVNETHDRSZ:
+		var size primitive.Int32
+		if _, err := size.CopyIn(t, data); err != nil {
+			return 0, err
+		}
+		return 0, fd.device.SetVnetHdrSize(int32(size))
+
 	default:
 		return 0, linuxerr.ENOTTY
 	}
@@ -148,11 +191,11 @@ func (fd *tunFD) Write(ctx context.Context, src usermem.IOSequence, opts vfs.Wri
 	if src.NumBytes() == 0 {
 		return 0, unix.EINVAL
 	}
-	mtu, err := fd.device.MTU()
+	limit, err := fd.device.WriteLimit()
 	if err != nil {
 		return 0, err
 	}
-	if int64(mtu) < src.NumBytes() {
+	if limit < src.NumBytes() {
 		return 0, unix.EMSGSIZE
 	}
 	data := buffer.NewView(int(src.NumBytes()))
@@ -184,6 +227,24 @@ func (fd *tunFD) Epollable() bool {
 	return true
 }
 
+// mayAttach returns true if t may attach to the existing NIC with the given
+// name without CAP_NET_ADMIN, as in Linux's drivers/net/tun.c:tun_not_capable().
+func mayAttach(t *kernel.Task, stack *netstack.Stack, name string) bool {
+	owner, group, ok := tun.Owner(stack.Stack, name)
+	if !ok {
+		// Creating a NIC requires CAP_NET_ADMIN.
+		return false
+	}
+	creds := t.Credentials()
+	if owner != tun.NoOwner && auth.KUID(owner) != creds.EffectiveKUID {
+		return false
+	}
+	if group != tun.NoOwner && !creds.InGroup(auth.KGID(group)) {
+		return false
+	}
+	return true
+}
+
 // IsNetTunSupported returns whether /dev/net/tun device is supported for s.
 func IsNetTunSupported(s inet.Stack) bool {
 	_, ok := s.(*netstack.Stack)
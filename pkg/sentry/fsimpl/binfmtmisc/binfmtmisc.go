@@ -0,0 +1,384 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package binfmtmisc implements the binfmt_misc filesystem, which is used to
+// register interpreters for arbitrary executable formats.
+//
+// All binfmt_misc mounts share the kernel's loader.BinfmtMisc registry.
+package binfmtmisc
+
+import (
+	"bytes"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/loader"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// Name is the user-visible filesystem name.
+const Name = "binfmt_misc"
+
+// FilesystemType implements vfs.FilesystemType.
+//
+// +stateify savable
+type FilesystemType struct{}
+
+// Name implements vfs.FilesystemType.Name.
+func (FilesystemType) Name() string {
+	return Name
+}
+
+// Release implements vfs.FilesystemType.Release.
+func (FilesystemType) Release(ctx context.Context) {}
+
+// GetFilesystem implements vfs.FilesystemType.GetFilesystem.
+func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.VirtualFilesystem, creds *auth.Credentials, source string, opts vfs.GetFilesystemOptions) (*vfs.Filesystem, *vfs.Dentry, error) {
+	k := kernel.KernelFromContext(ctx)
+	if k == nil {
+		return nil, nil, linuxerr.EINVAL
+	}
+	devMinor, err := vfsObj.GetAnonBlockDevMinor()
+	if err != nil {
+		return nil, nil, err
+	}
+	fs := &filesystem{
+		devMinor: devMinor,
+		registry: k.BinfmtMisc(),
+	}
+	fs.VFSFilesystem().Init(vfsObj, &fsType, fs)
+
+	root := &rootInode{
+		fs:        fs,
+		rootCreds: auth.NewRootCredentials(creds.UserNamespace),
+	}
+	root.InodeAttrs.Init(ctx, creds, linux.UNNAMED_MAJOR, devMinor, fs.NextIno(), linux.ModeDirectory|0755)
+	root.OrderedChildren.Init(kernfs.OrderedChildrenOptions{})
+	root.InitRefs()
+	root.IncLinks(root.OrderedChildren.Populate(map[string]kernfs.Inode{
+		"register": fs.newFile(ctx, creds, 0200, &registerData{fs: fs}),
+		"status":   fs.newFile(ctx, creds, 0644, &statusData{fs: fs}),
+	}))
+
+	var rootD kernfs.Dentry
+	rootD.InitRoot(&fs.Filesystem, root)
+	return fs.VFSFilesystem(), rootD.VFSDentry(), nil
+}
+
+// filesystem implements vfs.FilesystemImpl.
+//
+// +stateify savable
+type filesystem struct {
+	kernfs.Filesystem
+
+	devMinor uint32
+
+	// registry is the kernel's set of registered interpreters. registry is
+	// immutable.
+	registry *loader.BinfmtMisc
+}
+
+// Release implements vfs.FilesystemImpl.Release.
+func (fs *filesystem) Release(ctx context.Context) {
+	fs.Filesystem.VFSFilesystem().VirtualFilesystem().PutAnonBlockDevMinor(fs.devMinor)
+	fs.Filesystem.Release(ctx)
+}
+
+// MountOptions implements vfs.FilesystemImpl.MountOptions.
+func (fs *filesystem) MountOptions() string {
+	return ""
+}
+
+// dynamicFile is a kernfs.Inode backed by a vfs.DynamicBytesSource.
+type dynamicFile interface {
+	kernfs.Inode
+	vfs.DynamicBytesSource
+
+	Init(ctx context.Context, creds *auth.Credentials, devMajor, devMinor uint32, ino uint64, data vfs.DynamicBytesSource, perm linux.FileMode)
+}
+
+func (fs *filesystem) newFile(ctx context.Context, creds *auth.Credentials, perm linux.FileMode, inode dynamicFile) kernfs.Inode {
+	inode.Init(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), inode, perm)
+	return inode
+}
+
+// rootInode is the root directory of a binfmt_misc filesystem. In addition to
+// the static "register" and "status" files, it contains one file for each
+// registered entry.
+//
+// +stateify savable
+type rootInode struct {
+	rootInodeRefs
+	kernfs.InodeAlwaysValid
+	kernfs.InodeAttrs
+	kernfs.InodeDirectoryNoNewChildren
+	kernfs.InodeNotAnonymous
+	kernfs.InodeNotSymlink
+	kernfs.InodeTemporary
+	kernfs.InodeWatches
+	kernfs.OrderedChildren
+
+	fs    *filesystem
+	locks vfs.FileLocks
+
+	// rootCreds are the credentials used to create entry files.
+	rootCreds *auth.Credentials
+}
+
+var _ kernfs.Inode = (*rootInode)(nil)
+
+// Lookup implements kernfs.Inode.Lookup.
+func (i *rootInode) Lookup(ctx context.Context, name string) (kernfs.Inode, error) {
+	if d, err := i.OrderedChildren.Lookup(ctx, name); err == nil {
+		return d, nil
+	}
+	e := i.fs.registry.Lookup(name)
+	if e == nil {
+		return nil, linuxerr.ENOENT
+	}
+	return i.fs.newFile(ctx, i.rootCreds, 0644, &entryData{fs: i.fs, entry: e}), nil
+}
+
+// IterDirents implements kernfs.Inode.IterDirents.
+func (i *rootInode) IterDirents(ctx context.Context, mnt *vfs.Mount, cb vfs.IterDirentsCallback, offset, relOffset int64) (int64, error) {
+	names := i.fs.registry.Names()
+	if relOffset >= int64(len(names)) {
+		return offset, nil
+	}
+	for _, name := range names[relOffset:] {
+		dirent := vfs.Dirent{
+			Name:    name,
+			Type:    linux.DT_REG,
+			Ino:     i.fs.NextIno(),
+			NextOff: offset + 1,
+		}
+		if err := cb.Handle(dirent); err != nil {
+			return offset, err
+		}
+		offset++
+	}
+	return offset, nil
+}
+
+// Open implements kernfs.Inode.Open.
+func (i *rootInode) Open(ctx context.Context, rp *vfs.ResolvingPath, d *kernfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	fd, err := kernfs.NewGenericDirectoryFD(rp.Mount(), d, &i.OrderedChildren, &i.locks, &opts, kernfs.GenericDirectoryFDOptions{
+		SeekEnd: kernfs.SeekEndZero,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fd.VFSFileDescription(), nil
+}
+
+// SetStat implements kernfs.Inode.SetStat not allowing inode attributes to be
+// changed.
+func (*rootInode) SetStat(context.Context, *vfs.Filesystem, *auth.Credentials, vfs.SetStatOptions) error {
+	return linuxerr.EPERM
+}
+
+// StatFS implements kernfs.Inode.StatFS.
+func (*rootInode) StatFS(context.Context, *vfs.Filesystem) (linux.Statfs, error) {
+	return vfs.GenericStatFS(linux.BINFMTFS_MAGIC), nil
+}
+
+// DecRef implements kernfs.Inode.DecRef.
+func (i *rootInode) DecRef(ctx context.Context) {
+	i.rootInodeRefs.DecRef(func() { i.Destroy(ctx) })
+}
+
+// parseCommand parses the value written to the "status" file or to an entry
+// file: "1" enables, "0" disables and "-1" removes. From Linux's
+// fs/binfmt_misc.c:parse_command().
+func parseCommand(ctx context.Context, src usermem.IOSequence) (int, int64, error) {
+	if src.NumBytes() > 3 {
+		return 0, 0, linuxerr.EINVAL
+	}
+	buf := make([]byte, src.NumBytes())
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch strings.TrimSuffix(string(buf[:n]), "\n") {
+	case "0":
+		return 0, int64(n), nil
+	case "1":
+		return 1, int64(n), nil
+	case "-1":
+		return -1, int64(n), nil
+	default:
+		return 0, 0, linuxerr.EINVAL
+	}
+}
+
+// registerData implements vfs.WritableDynamicBytesSource for the "register"
+// file.
+//
+// +stateify savable
+type registerData struct {
+	kernfs.DynamicBytesFile
+
+	fs *filesystem
+}
+
+var _ vfs.WritableDynamicBytesSource = (*registerData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*registerData) Generate(context.Context, *bytes.Buffer) error {
+	return linuxerr.EINVAL
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *registerData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	if offset != 0 {
+		return 0, linuxerr.EINVAL
+	}
+	creds := auth.CredentialsFromContext(ctx)
+	if !creds.HasCapability(linux.CAP_SYS_ADMIN) {
+		return 0, linuxerr.EPERM
+	}
+	if src.NumBytes() > loader.MaxBinfmtRegisterLength {
+		return 0, linuxerr.EINVAL
+	}
+	buf := make([]byte, src.NumBytes())
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, err
+	}
+	e, err := loader.ParseBinfmtEntry(string(buf[:n]))
+	if err != nil {
+		return 0, err
+	}
+	if e.FixBinary {
+		// Open the interpreter now, relative to the registering task's
+		// root, as in Linux's fs/binfmt_misc.c:bm_register_write().
+		root := vfs.RootFromContext(ctx)
+		defer root.DecRef(ctx)
+		e.File, err = d.fs.VFSFilesystem().VirtualFilesystem().OpenAt(ctx, creds, &vfs.PathOperation{
+			Root:               root,
+			Start:              root,
+			Path:               fspath.Parse(e.Interpreter),
+			FollowFinalSymlink: true,
+		}, &vfs.OpenOptions{
+			Flags:    linux.O_RDONLY,
+			FileExec: true,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := d.fs.registry.Register(e); err != nil {
+		if e.File != nil {
+			e.File.DecRef(ctx)
+		}
+		return 0, err
+	}
+	return int64(n), nil
+}
+
+// statusData implements vfs.WritableDynamicBytesSource for the "status" file.
+//
+// +stateify savable
+type statusData struct {
+	kernfs.DynamicBytesFile
+
+	fs *filesystem
+}
+
+var _ vfs.WritableDynamicBytesSource = (*statusData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *statusData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	if d.fs.registry.Enabled() {
+		buf.WriteString("enabled\n")
+	} else {
+		buf.WriteString("disabled\n")
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *statusData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	if offset != 0 {
+		return 0, linuxerr.EINVAL
+	}
+	cmd, n, err := parseCommand(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	switch cmd {
+	case -1:
+		if !auth.CredentialsFromContext(ctx).HasCapability(linux.CAP_SYS_ADMIN) {
+			return 0, linuxerr.EPERM
+		}
+		d.fs.registry.Clear(ctx)
+	default:
+		d.fs.registry.SetEnabled(cmd == 1)
+	}
+	return n, nil
+}
+
+// entryData implements vfs.WritableDynamicBytesSource for the file
+// representing a registered entry.
+//
+// +stateify savable
+type entryData struct {
+	kernfs.DynamicBytesFile
+
+	fs    *filesystem
+	entry *loader.BinfmtEntry
+}
+
+var _ vfs.WritableDynamicBytesSource = (*entryData)(nil)
+
+// Valid implements kernfs.Inode.Valid.
+func (d *entryData) Valid(ctx context.Context, parent *kernfs.Dentry, name string) bool {
+	return d.fs.registry.Lookup(name) == d.entry
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *entryData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	buf.WriteString(d.entry.Status())
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *entryData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	if offset != 0 {
+		return 0, linuxerr.EINVAL
+	}
+	cmd, n, err := parseCommand(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	switch cmd {
+	case -1:
+		if !auth.CredentialsFromContext(ctx).HasCapability(linux.CAP_SYS_ADMIN) {
+			return 0, linuxerr.EPERM
+		}
+		if err := d.fs.registry.Remove(ctx, d.entry.Name); err != nil {
+			return 0, err
+		}
+	default:
+		d.entry.SetEnabled(cmd == 1)
+	}
+	return n, nil
+}
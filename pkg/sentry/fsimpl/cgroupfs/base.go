@@ -432,6 +432,21 @@ func (d *cgroupProcsData) Generate(ctx context.Context, buf *bytes.Buffer) error
 	return nil
 }
 
+// checkMigratePermission returns an error if t may not move target to another
+// cgroup. Write access to the cgroup.procs or tasks file has already been
+// checked when the file was opened; like cgroup v1 in Linux, the writer must
+// additionally be root or run as the same user as target. This is what makes
+// a cgroup subtree chowned to an unprivileged user safe to delegate. See
+// kernel/cgroup/cgroup-v1.c:__cgroup1_procs_write().
+func checkMigratePermission(t, target *kernel.Task) error {
+	creds := t.Credentials()
+	tcreds := target.Credentials()
+	if creds.EffectiveKUID != auth.RootKUID && creds.EffectiveKUID != tcreds.RealKUID && creds.EffectiveKUID != tcreds.SavedKUID {
+		return linuxerr.EACCES
+	}
+	return nil
+}
+
 // Write implements vfs.WritableDynamicBytesSource.Write.
 func (d *cgroupProcsData) Write(ctx context.Context, fd *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
 	tgid, n, err := parseInt64FromString(ctx, src)
@@ -451,6 +466,11 @@ func (d *cgroupProcsData) Write(ctx context.Context, fd *vfs.FileDescription, sr
 	if targetTG == nil {
 		return 0, linuxerr.EINVAL
 	}
+	if leader := targetTG.Leader(); leader == nil {
+		return 0, linuxerr.ESRCH
+	} else if err := checkMigratePermission(t, leader); err != nil {
+		return 0, err
+	}
 	return n, targetTG.MigrateCgroup(d.CgroupFromControlFileFD(fd))
 }
 
@@ -498,6 +518,9 @@ func (d *tasksData) Write(ctx context.Context, fd *vfs.FileDescription, src user
 	if targetTask == nil {
 		return 0, linuxerr.EINVAL
 	}
+	if err := checkMigratePermission(t, targetTask); err != nil {
+		return 0, err
+	}
 	return n, targetTask.MigrateCgroup(d.CgroupFromControlFileFD(fd))
 }
 
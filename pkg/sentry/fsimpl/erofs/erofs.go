@@ -82,6 +82,11 @@ type InternalFilesystemOptions struct {
 	// If UniqueID is non-empty, it is an opaque string used to reassociate the
 	// filesystem with a new image FD during restoration from checkpoint.
 	UniqueID vfs.RestoreID
+
+	// If VerityRootHash is non-empty, the image is verified against the Merkle
+	// tree appended to it, which must have this root hash. See
+	// erofs.Image.EnableVerity.
+	VerityRootHash []byte
 }
 
 // Name implements vfs.FilesystemType.Name.
@@ -117,6 +122,12 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		ctx.Warningf("erofs.FilesystemType.GetFilesystem: GetFilesystemOptions.InternalData has type %T, wanted erofs.InternalFilesystemOptions", opts.InternalData)
 		return nil, nil, linuxerr.EINVAL
 	}
+	if len(iopts.VerityRootHash) != 0 {
+		if err := image.EnableVerity(iopts.VerityRootHash); err != nil {
+			ctx.Warningf("erofs.FilesystemType.GetFilesystem: failed to enable image verification: %v", err)
+			return nil, nil, linuxerr.EIO
+		}
+	}
 
 	devMinor, err := vfsObj.GetAnonBlockDevMinor()
 	if err != nil {
@@ -58,28 +58,31 @@ func (fd *regularFileFD) PRead(ctx context.Context, dst usermem.IOSequence, offs
 		return 0, nil
 	}
 
-	data, err := fd.inode().Data()
-	if err != nil {
-		return 0, err
-	}
 	r := &regularFileReader{
-		data: data,
-		off:  uint64(offset),
+		inode: fd.inode(),
+		off:   uint64(offset),
 	}
 	return dst.CopyOutFrom(ctx, r)
 }
 
 type regularFileReader struct {
-	data safemem.BlockSeq
-	off  uint64
+	inode *inode
+	off   uint64
 }
 
 // ReadToBlocks implements safemem.Reader.ReadToBlocks.
 func (r *regularFileReader) ReadToBlocks(dsts safemem.BlockSeq) (uint64, error) {
-	if r.off >= r.data.NumBytes() {
+	size := r.inode.Size()
+	if r.off >= size {
 		return 0, io.EOF
 	}
-	cp, err := safemem.CopySeq(dsts, r.data.DropFirst(int(r.off)))
+	// Only get the data that is read, so that it's not verified unnecessarily
+	// if image verification is enabled.
+	data, err := r.inode.DataRange(r.off, min(dsts.NumBytes(), size-r.off))
+	if err != nil {
+		return 0, err
+	}
+	cp, err := safemem.CopySeq(dsts, data)
 	r.off += cp
 	return cp, err
 }
@@ -170,6 +173,14 @@ func (i *inode) Translate(ctx context.Context, required, optional memmap.Mappabl
 		return nil, &memmap.BusError{err}
 	}
 	mr := optional
+	if image := i.fs.image; image.VerityEnabled() {
+		// Translations map the image file directly, so the translated range
+		// must be verified first. Avoid verifying more than is required.
+		mr = required
+		if err := image.Verify(offset+mr.Start, mr.Length()); err != nil {
+			return nil, &memmap.BusError{err}
+		}
+	}
 	return []memmap.Translation{
 		{
 			Source: mr,
@@ -34,6 +34,11 @@ func (fs *filesystem) afterLoad(ctx context.Context) {
 	if err != nil {
 		panic(fmt.Sprintf("erofs.OpenImage failed: %v", err))
 	}
+	if len(fs.iopts.VerityRootHash) != 0 {
+		if err := newImage.EnableVerity(fs.iopts.VerityRootHash); err != nil {
+			panic(fmt.Sprintf("failed to enable image verification: %v", err))
+		}
+	}
 	if got, want := newImage.SuperBlock(), fs.image.SuperBlock(); got != want {
 		panic(fmt.Sprintf("superblock mismatch detected on restore, got %+v, expected %+v", got, want))
 	}
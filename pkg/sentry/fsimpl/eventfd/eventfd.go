@@ -213,12 +213,6 @@ func (efd *EventFileDescription) write(ctx context.Context, src usermem.IOSequen
 		return err
 	}
 	val := hostarch.ByteOrder.Uint64(buf[:])
-
-	return efd.Signal(val)
-}
-
-// Signal is an internal function to signal the event fd.
-func (efd *EventFileDescription) Signal(val uint64) error {
 	if val == math.MaxUint64 {
 		return unix.EINVAL
 	}
@@ -231,8 +225,9 @@ func (efd *EventFileDescription) Signal(val uint64) error {
 	}
 
 	// We only allow writes that won't cause the value to go over the max
-	// uint64 minus 1.
-	if val > math.MaxUint64-1-efd.val {
+	// uint64 minus 1. Note that efd.val may already be the max uint64 if the
+	// event was signaled by Signal.
+	if val >= math.MaxUint64-efd.val {
 		efd.mu.Unlock()
 		return linuxerr.ErrWouldBlock
 	}
@@ -246,6 +241,30 @@ func (efd *EventFileDescription) Signal(val uint64) error {
 	return nil
 }
 
+// Signal is an internal function to signal the event fd, equivalent to
+// Linux's eventfd_signal(). Unlike write(2), it never blocks: the value
+// saturates at the max uint64, at which point the event reports an error
+// condition to pollers.
+func (efd *EventFileDescription) Signal(val uint64) error {
+	efd.mu.Lock()
+
+	if efd.hostfd >= 0 {
+		defer efd.mu.Unlock()
+		return efd.hostWriteLocked(val)
+	}
+
+	if val > math.MaxUint64-efd.val {
+		val = math.MaxUint64 - efd.val
+	}
+	efd.val += val
+	efd.mu.Unlock()
+
+	// Always trigger a notification.
+	efd.queue.Notify(waiter.ReadableEvents)
+
+	return nil
+}
+
 // Readiness implements waiter.Waitable.Readiness.
 func (efd *EventFileDescription) Readiness(mask waiter.EventMask) waiter.EventMask {
 	efd.mu.Lock()
@@ -264,6 +283,11 @@ func (efd *EventFileDescription) Readiness(mask waiter.EventMask) waiter.EventMa
 		ready |= waiter.WritableEvents
 	}
 
+	// Only Signal can overflow the event; Linux reports it as an error.
+	if efd.val == math.MaxUint64 {
+		ready |= waiter.EventErr
+	}
+
 	return mask & ready
 }
 
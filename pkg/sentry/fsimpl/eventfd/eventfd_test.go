@@ -15,9 +15,12 @@
 package eventfd
 
 import (
+	"math"
 	"testing"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/sentry/contexttest"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/usermem"
@@ -97,3 +100,43 @@ func TestEventFDStat(t *testing.T) {
 		t.Errorf("eventfd size should be 0")
 	}
 }
+
+func TestEventFDSignalSaturates(t *testing.T) {
+	ctx := contexttest.Context(t)
+	vfsObj := &vfs.VirtualFilesystem{}
+	if err := vfsObj.Init(ctx); err != nil {
+		t.Fatalf("VFS init: %v", err)
+	}
+
+	eventfd, err := New(ctx, vfsObj, 1, true /* semMode */, linux.O_RDWR)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer eventfd.DecRef(ctx)
+	efd := eventfd.Impl().(*EventFileDescription)
+
+	// Signal never blocks, unlike write(2).
+	if err := efd.Signal(math.MaxUint64); err != nil {
+		t.Fatalf("Signal(MaxUint64): %v", err)
+	}
+	all := waiter.ReadableEvents | waiter.WritableEvents | waiter.EventErr
+	if got, want := eventfd.Readiness(all), waiter.ReadableEvents|waiter.EventErr; got != want {
+		t.Errorf("Readiness after overflow = %#x, want %#x", got, want)
+	}
+	var buf [8]byte
+	hostarch.ByteOrder.PutUint64(buf[:], 1)
+	if _, err := eventfd.Write(ctx, usermem.BytesIOSequence(buf[:]), vfs.WriteOptions{}); !linuxerr.Equals(linuxerr.ErrWouldBlock, err) {
+		t.Errorf("Write after overflow: got err %v, want %v", err, linuxerr.ErrWouldBlock)
+	}
+
+	// A semaphore read only consumes one from the counter.
+	if _, err := eventfd.Read(ctx, usermem.BytesIOSequence(buf[:]), vfs.ReadOptions{}); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if got := hostarch.ByteOrder.Uint64(buf[:]); got != 1 {
+		t.Errorf("Read got %d, want 1", got)
+	}
+	if got, want := eventfd.Readiness(all), waiter.ReadableEvents; got != want {
+		t.Errorf("Readiness after read = %#x, want %#x", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ext4
+
+import (
+	"sync"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// getDirents returns the directory's entries, and a map from names to indexes
+// in the returned slice.
+//
+// Directories are always scanned linearly; hash tree indexes are ignored,
+// which is possible because index blocks appear as unused entries.
+func (i *inode) getDirents(ctx context.Context) ([]vfs.Dirent, map[string]int, error) {
+	// Fast path.
+	i.dirMu.RLock()
+	dirents, index := i.dirents, i.direntIndex
+	i.dirMu.RUnlock()
+	if dirents != nil {
+		return dirents, index, nil
+	}
+
+	// Slow path.
+	i.dirMu.Lock()
+	defer i.dirMu.Unlock()
+	if i.dirents != nil {
+		return i.dirents, i.direntIndex, nil
+	}
+
+	size := i.size
+	if size%i.fs.blockSize != 0 {
+		ctx.Warningf("ext4.inode.getDirents: directory %d has size %d, not a multiple of the block size", i.ino, size)
+		return nil, nil, linuxerr.EUCLEAN
+	}
+	data := make([]byte, size)
+	if err := i.readAt(ctx, data, 0); err != nil {
+		return nil, nil, err
+	}
+	index = make(map[string]int)
+	off := int64(1)
+	if err := parseDirents(data, i.fs.blockSize, i.fs.sb.featureIncompat&featureIncompatFiletype != 0, func(name string, ino uint32, typ uint8) error {
+		if _, ok := index[name]; ok {
+			return linuxerr.EUCLEAN
+		}
+		index[name] = len(dirents)
+		dirents = append(dirents, vfs.Dirent{
+			Name:    name,
+			Type:    typ,
+			Ino:     uint64(ino),
+			NextOff: off,
+		})
+		off++
+		return nil
+	}); err != nil {
+		ctx.Warningf("ext4.inode.getDirents: directory %d: %v", i.ino, err)
+		return nil, nil, linuxerr.EUCLEAN
+	}
+
+	// "." and ".." should always be present.
+	if len(dirents) < 2 {
+		return nil, nil, linuxerr.EUCLEAN
+	}
+
+	i.dirents = dirents
+	i.direntIndex = index
+	return dirents, index, nil
+}
+
+func (i *inode) lookup(ctx context.Context, name string) (uint32, error) {
+	dirents, index, err := i.getDirents(ctx)
+	if err != nil {
+		return 0, err
+	}
+	idx, ok := index[name]
+	if !ok {
+		return 0, linuxerr.ENOENT
+	}
+	return uint32(dirents[idx].Ino), nil
+}
+
+func (d *dentry) lookup(ctx context.Context, name string) (*dentry, error) {
+	// Fast path, dentry already exists.
+	d.dirMu.RLock()
+	child, ok := d.childMap[name]
+	d.dirMu.RUnlock()
+	if ok {
+		return child, nil
+	}
+
+	// Slow path, create a new dentry.
+	d.dirMu.Lock()
+	defer d.dirMu.Unlock()
+	if child, ok := d.childMap[name]; ok {
+		return child, nil
+	}
+
+	ino, err := d.inode.lookup(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.childMap == nil {
+		d.childMap = make(map[string]*dentry)
+	}
+
+	child, err = d.inode.fs.newDentry(ctx, ino)
+	if err != nil {
+		return nil, err
+	}
+	child.parent.Store(d)
+	child.name = name
+	d.childMap[name] = child
+	return child, nil
+}
+
+// +stateify savable
+type directoryFD struct {
+	fileDescription
+	vfs.DirectoryFileDescriptionDefaultImpl
+
+	// mu protects off.
+	mu sync.Mutex `state:"nosave"`
+	// +checklocks:mu
+	off int64
+}
+
+// IterDirents implements vfs.FileDescriptionImpl.IterDirents.
+func (fd *directoryFD) IterDirents(ctx context.Context, cb vfs.IterDirentsCallback) error {
+	d := fd.dentry()
+	dirents, _, err := d.inode.getDirents(ctx)
+	if err != nil {
+		return err
+	}
+
+	d.InotifyWithParent(ctx, linux.IN_ACCESS, 0, vfs.PathEvent)
+
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+
+	for fd.off < int64(len(dirents)) {
+		if err := cb.Handle(dirents[fd.off]); err != nil {
+			return err
+		}
+		fd.off++
+	}
+	return nil
+}
+
+// Seek implements vfs.FileDescriptionImpl.Seek.
+func (fd *directoryFD) Seek(ctx context.Context, offset int64, whence int32) (int64, error) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+
+	switch whence {
+	case linux.SEEK_SET:
+		// use offset as specified
+	case linux.SEEK_CUR:
+		offset += fd.off
+	default:
+		return 0, linuxerr.EINVAL
+	}
+	if offset < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	fd.off = offset
+	return offset, nil
+}
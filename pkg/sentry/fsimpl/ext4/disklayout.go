@@ -0,0 +1,437 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ext4
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+)
+
+// On-disk layout constants, from Linux's fs/ext4/ext4.h and
+// fs/ext4/ext4_extents.h.
+const (
+	// superBlockOffset is the byte offset of the superblock on the device.
+	superBlockOffset = 1024
+
+	// superBlockSize is the size of the on-disk superblock.
+	superBlockSize = 1024
+
+	// rootIno is the inode number of the root directory.
+	rootIno = 2
+
+	// maxNameLen is the maximum length of a file name.
+	maxNameLen = 255
+
+	// goodOldInodeSize is the inode size used by revision 0 filesystems.
+	goodOldInodeSize = 128
+
+	// descSize is the size of a group descriptor on filesystems without the
+	// 64BIT feature.
+	descSize = 32
+
+	// minDescSize64Bit is the minimum size of a group descriptor on
+	// filesystems with the 64BIT feature.
+	minDescSize64Bit = 64
+
+	// numBlockPointers is the number of block pointers (or the equivalent
+	// space in bytes divided by 4) in an inode's i_block.
+	numBlockPointers = 15
+
+	// numDirectBlocks is the number of direct block pointers in a block
+	// mapped inode.
+	numDirectBlocks = 12
+
+	// fastSymlinkMaxLen is the maximum length of a symlink target that is
+	// stored in i_block rather than in a data block.
+	fastSymlinkMaxLen = numBlockPointers*4 - 1
+
+	// extentMagic is the magic number of an extent tree node header.
+	extentMagic = 0xf30a
+
+	// extentHeaderSize is the size of an extent tree node header, and of each
+	// entry in an extent tree node.
+	extentHeaderSize = 12
+
+	// maxInitExtentLen is the maximum length of an initialized extent. Longer
+	// lengths indicate uninitialized extents, which read as zeroes.
+	maxInitExtentLen = 32768
+
+	// maxExtentDepth is the maximum depth of an extent tree.
+	maxExtentDepth = 5
+
+	// direntHeaderSize is the size of a directory entry without its name.
+	direntHeaderSize = 8
+)
+
+// Superblock feature flags.
+const (
+	featureIncompatCompression = 0x1
+	featureIncompatFiletype    = 0x2
+	featureIncompatRecover     = 0x4
+	featureIncompatJournalDev  = 0x8
+	featureIncompatMetaBG      = 0x10
+	featureIncompatExtents     = 0x40
+	featureIncompat64Bit       = 0x80
+	featureIncompatMMP         = 0x100
+	featureIncompatFlexBG      = 0x200
+	featureIncompatEAInode     = 0x400
+	featureIncompatDirData     = 0x1000
+	featureIncompatCsumSeed    = 0x2000
+	featureIncompatLargeDir    = 0x4000
+	featureIncompatInlineData  = 0x8000
+	featureIncompatEncrypt     = 0x10000
+	featureIncompatCasefold    = 0x20000
+
+	// supportedIncompat is the set of incompatible features that don't
+	// prevent read-only access by this implementation.
+	supportedIncompat = featureIncompatFiletype | featureIncompatRecover |
+		featureIncompatExtents | featureIncompat64Bit | featureIncompatMMP |
+		featureIncompatFlexBG | featureIncompatEAInode |
+		featureIncompatCsumSeed | featureIncompatLargeDir
+
+	featureROCompatHugeFile = 0x8
+)
+
+// Inode flags.
+const (
+	inodeFlagHugeFile   = 0x40000
+	inodeFlagExtents    = 0x80000
+	inodeFlagInlineData = 0x10000000
+)
+
+// superBlock contains the fields of struct ext4_super_block used by the
+// filesystem.
+//
+// +stateify savable
+type superBlock struct {
+	inodesCount     uint32
+	blocksCount     uint64
+	rBlocksCount    uint64
+	freeBlocksCount uint64
+	freeInodesCount uint32
+	firstDataBlock  uint32
+	logBlockSize    uint32
+	blocksPerGroup  uint32
+	inodesPerGroup  uint32
+	magic           uint16
+	revLevel        uint32
+	inodeSize       uint16
+	featureCompat   uint32
+	featureIncompat uint32
+	featureROCompat uint32
+	descSize        uint16
+}
+
+// parseSuperBlock parses and validates the superblock in buf.
+func parseSuperBlock(buf []byte) (superBlock, error) {
+	if len(buf) < superBlockSize {
+		return superBlock{}, fmt.Errorf("superblock too short: %d bytes", len(buf))
+	}
+	le := binary.LittleEndian
+	sb := superBlock{
+		inodesCount:     le.Uint32(buf[0:]),
+		blocksCount:     uint64(le.Uint32(buf[4:])),
+		rBlocksCount:    uint64(le.Uint32(buf[8:])),
+		freeBlocksCount: uint64(le.Uint32(buf[12:])),
+		freeInodesCount: le.Uint32(buf[16:]),
+		firstDataBlock:  le.Uint32(buf[20:]),
+		logBlockSize:    le.Uint32(buf[24:]),
+		blocksPerGroup:  le.Uint32(buf[32:]),
+		inodesPerGroup:  le.Uint32(buf[40:]),
+		magic:           le.Uint16(buf[56:]),
+		revLevel:        le.Uint32(buf[76:]),
+		inodeSize:       goodOldInodeSize,
+	}
+	if sb.magic != linux.EXT_SUPER_MAGIC {
+		return superBlock{}, fmt.Errorf("invalid magic %#x", sb.magic)
+	}
+	if sb.revLevel > 0 {
+		sb.inodeSize = le.Uint16(buf[88:])
+		sb.featureCompat = le.Uint32(buf[92:])
+		sb.featureIncompat = le.Uint32(buf[96:])
+		sb.featureROCompat = le.Uint32(buf[100:])
+	}
+	sb.descSize = descSize
+	if sb.featureIncompat&featureIncompat64Bit != 0 {
+		sb.blocksCount |= uint64(le.Uint32(buf[336:])) << 32
+		sb.rBlocksCount |= uint64(le.Uint32(buf[340:])) << 32
+		sb.freeBlocksCount |= uint64(le.Uint32(buf[344:])) << 32
+		sb.descSize = le.Uint16(buf[254:])
+	}
+
+	if unsupported := sb.featureIncompat &^ supportedIncompat; unsupported != 0 {
+		return superBlock{}, fmt.Errorf("unsupported incompatible features %#x", unsupported)
+	}
+	if sb.logBlockSize > 6 {
+		return superBlock{}, fmt.Errorf("invalid block size: log %d", sb.logBlockSize)
+	}
+	blockSize := sb.blockSize()
+	if sb.inodeSize < goodOldInodeSize || sb.inodeSize&(sb.inodeSize-1) != 0 || uint64(sb.inodeSize) > blockSize {
+		return superBlock{}, fmt.Errorf("invalid inode size %d", sb.inodeSize)
+	}
+	if sb.featureIncompat&featureIncompat64Bit != 0 &&
+		(sb.descSize < minDescSize64Bit || sb.descSize&(sb.descSize-1) != 0 || uint64(sb.descSize) > blockSize) {
+		return superBlock{}, fmt.Errorf("invalid group descriptor size %d", sb.descSize)
+	}
+	if sb.blocksPerGroup == 0 || sb.inodesPerGroup == 0 {
+		return superBlock{}, fmt.Errorf("invalid group size: %d blocks, %d inodes", sb.blocksPerGroup, sb.inodesPerGroup)
+	}
+	if uint64(sb.firstDataBlock) >= sb.blocksCount {
+		return superBlock{}, fmt.Errorf("first data block %d beyond block count %d", sb.firstDataBlock, sb.blocksCount)
+	}
+	if uint64(sb.inodesCount) > uint64(sb.groupCount())*uint64(sb.inodesPerGroup) {
+		return superBlock{}, fmt.Errorf("inode count %d exceeds %d groups of %d inodes", sb.inodesCount, sb.groupCount(), sb.inodesPerGroup)
+	}
+	return sb, nil
+}
+
+// blockSize returns the filesystem block size in bytes.
+func (sb *superBlock) blockSize() uint64 {
+	return 1024 << sb.logBlockSize
+}
+
+// groupCount returns the number of block groups.
+func (sb *superBlock) groupCount() uint32 {
+	return uint32((sb.blocksCount - uint64(sb.firstDataBlock) + uint64(sb.blocksPerGroup) - 1) / uint64(sb.blocksPerGroup))
+}
+
+// parseInodeTables returns the location of each group's inode table, given
+// the group descriptor table in buf.
+func (sb *superBlock) parseInodeTables(buf []byte) []uint64 {
+	le := binary.LittleEndian
+	tables := make([]uint64, sb.groupCount())
+	for i := range tables {
+		desc := buf[i*int(sb.descSize):]
+		tables[i] = uint64(le.Uint32(desc[8:]))
+		if sb.descSize >= minDescSize64Bit {
+			tables[i] |= uint64(le.Uint32(desc[0x28:])) << 32
+		}
+	}
+	return tables
+}
+
+// diskInode contains the fields of struct ext4_inode used by the filesystem.
+//
+// +stateify savable
+type diskInode struct {
+	mode       uint16
+	uid        uint32
+	gid        uint32
+	size       uint64
+	atime      linux.StatxTimestamp
+	ctime      linux.StatxTimestamp
+	mtime      linux.StatxTimestamp
+	btime      linux.StatxTimestamp
+	hasBtime   bool
+	linksCount uint16
+	blocks     uint64
+	flags      uint32
+	block      [numBlockPointers * 4]byte
+}
+
+// parseInode parses the on-disk inode in buf, which is inodeSize bytes long.
+func parseInode(buf []byte, sb *superBlock) diskInode {
+	le := binary.LittleEndian
+	di := diskInode{
+		mode:       le.Uint16(buf[0:]),
+		uid:        uint32(le.Uint16(buf[2:])) | uint32(le.Uint16(buf[120:]))<<16,
+		gid:        uint32(le.Uint16(buf[24:])) | uint32(le.Uint16(buf[122:]))<<16,
+		size:       uint64(le.Uint32(buf[4:])) | uint64(le.Uint32(buf[108:]))<<32,
+		linksCount: le.Uint16(buf[26:]),
+		blocks:     uint64(le.Uint32(buf[28:])),
+		flags:      le.Uint32(buf[32:]),
+	}
+	copy(di.block[:], buf[40:])
+
+	// The extra inode fields are only present if they fit in the on-disk
+	// inode and i_extra_isize covers them.
+	var extraISize uint16
+	if len(buf) > goodOldInodeSize+2 {
+		extraISize = le.Uint16(buf[128:])
+	}
+	extra := func(off int) (uint32, bool) {
+		if off+4 > goodOldInodeSize+int(extraISize) || off+4 > len(buf) {
+			return 0, false
+		}
+		return le.Uint32(buf[off:]), true
+	}
+	ctimeExtra, _ := extra(132)
+	mtimeExtra, _ := extra(136)
+	atimeExtra, _ := extra(140)
+	di.ctime = decodeTime(le.Uint32(buf[12:]), ctimeExtra)
+	di.mtime = decodeTime(le.Uint32(buf[16:]), mtimeExtra)
+	di.atime = decodeTime(le.Uint32(buf[8:]), atimeExtra)
+	if crtime, ok := extra(144); ok {
+		crtimeExtra, _ := extra(148)
+		di.btime = decodeTime(crtime, crtimeExtra)
+		di.hasBtime = true
+	}
+
+	if sb.featureROCompat&featureROCompatHugeFile != 0 {
+		di.blocks |= uint64(le.Uint16(buf[116:])) << 32
+		if di.flags&inodeFlagHugeFile != 0 {
+			// i_blocks is in units of filesystem blocks rather than
+			// 512-byte sectors.
+			di.blocks *= sb.blockSize() / 512
+		}
+	}
+	return di
+}
+
+// decodeTime decodes an inode timestamp from its 32-bit seconds field and the
+// corresponding *_extra field, which contains 2 epoch bits extending the
+// seconds field and 30 bits of nanoseconds.
+//
+// decodeTime is analogous to Linux's fs/ext4/ext4.h:ext4_decode_extra_time().
+func decodeTime(sec, extra uint32) linux.StatxTimestamp {
+	return linux.StatxTimestamp{
+		Sec:  int64(int32(sec)) + int64(extra&3)<<32,
+		Nsec: extra >> 2,
+	}
+}
+
+// rdev returns the device number of a character or block device inode.
+func (di *diskInode) rdev() (major, minor uint32) {
+	le := binary.LittleEndian
+	if old := le.Uint32(di.block[0:]); old != 0 {
+		return (old >> 8) & 0xff, old & 0xff
+	}
+	dev := le.Uint32(di.block[4:])
+	return (dev & 0xfff00) >> 8, (dev & 0xff) | ((dev >> 12) & 0xfff00)
+}
+
+// extent maps a range of logical file blocks to contiguous physical blocks.
+//
+// +stateify savable
+type extent struct {
+	// logical is the first logical block mapped by the extent.
+	logical uint64
+
+	// length is the number of blocks mapped by the extent.
+	length uint64
+
+	// physical is the first physical block mapped by the extent.
+	physical uint64
+
+	// uninit is true if the extent is uninitialized, i.e. reads as zeroes.
+	uninit bool
+}
+
+// end returns the logical block after the last block mapped by e.
+func (e *extent) end() uint64 {
+	return e.logical + e.length
+}
+
+// extentNode is a parsed extent tree node.
+type extentNode struct {
+	depth uint16
+
+	// If depth is 0, extents are the leaf entries of the node.
+	extents []extent
+
+	// If depth is not 0, children are the physical blocks of the node's
+	// children.
+	children []uint64
+}
+
+// parseExtentNode parses the extent tree node in buf.
+func parseExtentNode(buf []byte) (extentNode, error) {
+	le := binary.LittleEndian
+	if len(buf) < extentHeaderSize {
+		return extentNode{}, fmt.Errorf("extent node too short: %d bytes", len(buf))
+	}
+	if magic := le.Uint16(buf[0:]); magic != extentMagic {
+		return extentNode{}, fmt.Errorf("invalid extent magic %#x", magic)
+	}
+	entries := int(le.Uint16(buf[2:]))
+	node := extentNode{depth: le.Uint16(buf[6:])}
+	if node.depth > maxExtentDepth {
+		return extentNode{}, fmt.Errorf("invalid extent tree depth %d", node.depth)
+	}
+	if (entries+1)*extentHeaderSize > len(buf) {
+		return extentNode{}, fmt.Errorf("too many extent entries: %d", entries)
+	}
+	for i := 0; i < entries; i++ {
+		entry := buf[(i+1)*extentHeaderSize:]
+		if node.depth != 0 {
+			// struct ext4_extent_idx.
+			node.children = append(node.children, uint64(le.Uint32(entry[4:]))|uint64(le.Uint16(entry[8:]))<<32)
+			continue
+		}
+		// struct ext4_extent.
+		e := extent{
+			logical:  uint64(le.Uint32(entry[0:])),
+			length:   uint64(le.Uint16(entry[4:])),
+			physical: uint64(le.Uint16(entry[6:]))<<32 | uint64(le.Uint32(entry[8:])),
+		}
+		if e.length > maxInitExtentLen {
+			e.length -= maxInitExtentLen
+			e.uninit = true
+		}
+		node.extents = append(node.extents, e)
+	}
+	return node, nil
+}
+
+// recLen decodes a directory entry's rec_len field.
+//
+// recLen is analogous to Linux's fs/ext4/ext4.h:ext4_rec_len_from_disk().
+func recLen(dlen uint16, blockSize uint64) uint64 {
+	if blockSize < 65536 {
+		return uint64(dlen)
+	}
+	if dlen == 65535 || dlen == 0 {
+		return blockSize
+	}
+	return uint64(dlen&65532) | uint64(dlen&3)<<16
+}
+
+// parseDirents calls cb for each directory entry in data, which contains whole
+// directory blocks. If fileType is false, the filesystem doesn't store file
+// types in directory entries and cb is passed linux.DT_UNKNOWN.
+//
+// Unused entries, including the hash tree index blocks and checksum tails of
+// directories with the dir_index and metadata_csum features, have an inode
+// number of 0 and are skipped.
+func parseDirents(data []byte, blockSize uint64, fileType bool, cb func(name string, ino uint32, typ uint8) error) error {
+	le := binary.LittleEndian
+	for blockOff := uint64(0); blockOff < uint64(len(data)); blockOff += blockSize {
+		block := data[blockOff:min(blockOff+blockSize, uint64(len(data)))]
+		for off := uint64(0); off+direntHeaderSize <= uint64(len(block)); {
+			ino := le.Uint32(block[off:])
+			rlen := recLen(le.Uint16(block[off+4:]), blockSize)
+			nameLen := uint64(block[off+6])
+			typ := uint8(linux.DT_UNKNOWN)
+			if fileType {
+				typ = linux.FileTypeToDirentType(block[off+7])
+			} else {
+				nameLen |= uint64(block[off+7]) << 8
+			}
+			if rlen < direntHeaderSize || off+rlen > uint64(len(block)) || direntHeaderSize+nameLen > rlen {
+				return fmt.Errorf("invalid directory entry at offset %d: rec_len %d, name_len %d", blockOff+off, rlen, nameLen)
+			}
+			if ino != 0 && nameLen != 0 {
+				name := string(block[off+direntHeaderSize : off+direntHeaderSize+nameLen])
+				if err := cb(name, ino, typ); err != nil {
+					return err
+				}
+			}
+			off += rlen
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ext4
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+)
+
+// newSuperBlock returns a valid on-disk superblock for a filesystem with 4096
+// byte blocks.
+func newSuperBlock() []byte {
+	le := binary.LittleEndian
+	buf := make([]byte, superBlockSize)
+	le.PutUint32(buf[0:], 8192)   // s_inodes_count
+	le.PutUint32(buf[4:], 32768)  // s_blocks_count_lo
+	le.PutUint32(buf[24:], 2)     // s_log_block_size
+	le.PutUint32(buf[32:], 32768) // s_blocks_per_group
+	le.PutUint32(buf[40:], 8192)  // s_inodes_per_group
+	le.PutUint16(buf[56:], linux.EXT_SUPER_MAGIC)
+	le.PutUint32(buf[76:], 1)   // s_rev_level
+	le.PutUint16(buf[88:], 256) // s_inode_size
+	le.PutUint32(buf[96:], featureIncompatFiletype|featureIncompatExtents)
+	return buf
+}
+
+func TestParseSuperBlock(t *testing.T) {
+	sb, err := parseSuperBlock(newSuperBlock())
+	if err != nil {
+		t.Fatalf("parseSuperBlock failed: %v", err)
+	}
+	if got, want := sb.blockSize(), uint64(4096); got != want {
+		t.Errorf("got block size %d, want %d", got, want)
+	}
+	if got, want := sb.groupCount(), uint32(1); got != want {
+		t.Errorf("got %d groups, want %d", got, want)
+	}
+	if got, want := sb.descSize, uint16(descSize); got != want {
+		t.Errorf("got descriptor size %d, want %d", got, want)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		modify func(buf []byte)
+	}{
+		{
+			name:   "bad magic",
+			modify: func(buf []byte) { binary.LittleEndian.PutUint16(buf[56:], 0) },
+		},
+		{
+			name:   "inline data",
+			modify: func(buf []byte) { binary.LittleEndian.PutUint32(buf[96:], featureIncompatInlineData) },
+		},
+		{
+			name:   "bad inode size",
+			modify: func(buf []byte) { binary.LittleEndian.PutUint16(buf[88:], 100) },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := newSuperBlock()
+			tc.modify(buf)
+			if _, err := parseSuperBlock(buf); err == nil {
+				t.Errorf("parseSuperBlock succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestDecodeTime(t *testing.T) {
+	for _, tc := range []struct {
+		sec, extra uint32
+		want       linux.StatxTimestamp
+	}{
+		{sec: 1, extra: 0, want: linux.StatxTimestamp{Sec: 1}},
+		{sec: 0xffffffff, extra: 0, want: linux.StatxTimestamp{Sec: -1}},
+		{sec: 0, extra: 1, want: linux.StatxTimestamp{Sec: 1 << 32}},
+		{sec: 2, extra: 5 << 2, want: linux.StatxTimestamp{Sec: 2, Nsec: 5}},
+	} {
+		if got := decodeTime(tc.sec, tc.extra); got != tc.want {
+			t.Errorf("decodeTime(%#x, %#x): got %+v, want %+v", tc.sec, tc.extra, got, tc.want)
+		}
+	}
+}
+
+func TestParseExtentNode(t *testing.T) {
+	le := binary.LittleEndian
+	buf := make([]byte, 60)
+	le.PutUint16(buf[0:], extentMagic)
+	le.PutUint16(buf[2:], 2) // eh_entries
+	le.PutUint16(buf[4:], 4) // eh_max
+	// Initialized extent of 8 blocks at logical 0, physical 0x100000020.
+	le.PutUint32(buf[12:], 0)
+	le.PutUint16(buf[16:], 8)
+	le.PutUint16(buf[18:], 1)
+	le.PutUint32(buf[20:], 0x20)
+	// Uninitialized extent of 4 blocks at logical 16, physical 0x40.
+	le.PutUint32(buf[24:], 16)
+	le.PutUint16(buf[28:], maxInitExtentLen+4)
+	le.PutUint32(buf[32:], 0x40)
+
+	node, err := parseExtentNode(buf)
+	if err != nil {
+		t.Fatalf("parseExtentNode failed: %v", err)
+	}
+	want := []extent{
+		{logical: 0, length: 8, physical: 0x100000020},
+		{logical: 16, length: 4, physical: 0x40, uninit: true},
+	}
+	if len(node.extents) != len(want) {
+		t.Fatalf("got %d extents, want %d", len(node.extents), len(want))
+	}
+	for i := range want {
+		if node.extents[i] != want[i] {
+			t.Errorf("extent %d: got %+v, want %+v", i, node.extents[i], want[i])
+		}
+	}
+
+	le.PutUint16(buf[0:], 0)
+	if _, err := parseExtentNode(buf); err == nil {
+		t.Errorf("parseExtentNode succeeded with bad magic, want error")
+	}
+}
+
+func TestParseDirents(t *testing.T) {
+	const blockSize = 1024
+	le := binary.LittleEndian
+	data := make([]byte, 2*blockSize)
+	putDirent := func(off int, ino uint32, recLen uint16, name string, typ uint8) {
+		le.PutUint32(data[off:], ino)
+		le.PutUint16(data[off+4:], recLen)
+		data[off+6] = uint8(len(name))
+		data[off+7] = typ
+		copy(data[off+direntHeaderSize:], name)
+	}
+	putDirent(0, 2, 12, ".", linux.FT_DIR)
+	putDirent(12, 2, 12, "..", linux.FT_DIR)
+	// Deleted entry, which is skipped.
+	putDirent(24, 0, 16, "gone", linux.FT_REG_FILE)
+	putDirent(40, 12, blockSize-40, "file", linux.FT_REG_FILE)
+	putDirent(blockSize, 13, blockSize, "link", linux.FT_SYMLINK)
+
+	type dirent struct {
+		name string
+		ino  uint32
+		typ  uint8
+	}
+	var got []dirent
+	if err := parseDirents(data, blockSize, true, func(name string, ino uint32, typ uint8) error {
+		got = append(got, dirent{name, ino, typ})
+		return nil
+	}); err != nil {
+		t.Fatalf("parseDirents failed: %v", err)
+	}
+	want := []dirent{
+		{".", 2, linux.DT_DIR},
+		{"..", 2, linux.DT_DIR},
+		{"file", 12, linux.DT_REG},
+		{"link", 13, linux.DT_LNK},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got dirents %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("dirent %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// An entry crossing a block boundary is invalid.
+	putDirent(40, 12, blockSize, "file", linux.FT_REG_FILE)
+	if err := parseDirents(data, blockSize, true, func(string, uint32, uint8) error { return nil }); err == nil {
+		t.Errorf("parseDirents succeeded with invalid rec_len, want error")
+	}
+}
@@ -0,0 +1,664 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ext4 implements read-only access to ext2, ext3 and ext4 filesystems
+// stored on block devices, such as loop devices.
+//
+// The filesystem is always read-only, regardless of mount flags. The journal
+// is not replayed, extended attributes are not supported, and filesystems
+// using incompatible features that affect the on-disk layout of file data or
+// metadata (e.g. inline data, encryption and case folding) can't be mounted.
+package ext4
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/cleanup"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/sentry/fsutil"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// Name is the filesystem name. It is part of the interface used by users,
+// e.g. via mount(2), and shouldn't change.
+const Name = "ext4"
+
+// FilesystemType implements vfs.FilesystemType.
+//
+// +stateify savable
+type FilesystemType struct{}
+
+// filesystem implements vfs.FilesystemImpl.
+//
+// +stateify savable
+type filesystem struct {
+	vfsfs vfs.Filesystem
+
+	// mopts contains the mount options. mopts is immutable.
+	mopts string
+
+	// devMinor is the filesystem's minor device number. devMinor is immutable.
+	devMinor uint32
+
+	// dev is the block device containing the filesystem, opened for reading.
+	// dev is immutable.
+	dev *vfs.FileDescription
+
+	// sb is the superblock. sb is immutable.
+	sb superBlock
+
+	// blockSize is the filesystem block size in bytes. blockSize is
+	// immutable.
+	blockSize uint64
+
+	// inodeTables contains the block number of each group's inode table.
+	// inodeTables is immutable.
+	inodeTables []uint64
+
+	// mf is used to cache file contents for memory mappings.
+	mf *pgalloc.MemoryFile `state:"nosave"`
+
+	// root is the root dentry. root is immutable.
+	root *dentry
+
+	// inodeBuckets contains the inodes in use. Multiple buckets are used to
+	// reduce the lock contention. Bucket is chosen based on the hash calculation
+	// on the inode number in filesystem.inodeBucket.
+	inodeBuckets []inodeBucket
+}
+
+// Name implements vfs.FilesystemType.Name.
+func (FilesystemType) Name() string {
+	return Name
+}
+
+// Release implements vfs.FilesystemType.Release.
+func (FilesystemType) Release(ctx context.Context) {}
+
+// GetFilesystem implements vfs.FilesystemType.GetFilesystem.
+func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.VirtualFilesystem, creds *auth.Credentials, source string, opts vfs.GetFilesystemOptions) (*vfs.Filesystem, *vfs.Dentry, error) {
+	if source == "" {
+		ctx.Warningf("ext4.FilesystemType.GetFilesystem: a block device must be specified")
+		return nil, nil, linuxerr.ENOTBLK
+	}
+
+	var cu cleanup.Cleanup
+	defer cu.Clean()
+
+	vfsroot := vfs.RootFromContext(ctx)
+	if vfsroot.Ok() {
+		defer vfsroot.DecRef(ctx)
+	}
+	dev, err := vfsObj.OpenAt(ctx, creds, &vfs.PathOperation{
+		Root:               vfsroot,
+		Start:              vfsroot,
+		Path:               fspath.Parse(source),
+		FollowFinalSymlink: true,
+	}, &vfs.OpenOptions{
+		Flags: linux.O_RDONLY,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	cu.Add(func() { dev.DecRef(ctx) })
+	stat, err := dev.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_TYPE})
+	if err != nil {
+		return nil, nil, err
+	}
+	if stat.Mode&linux.S_IFMT != linux.S_IFBLK {
+		return nil, nil, linuxerr.ENOTBLK
+	}
+
+	fs := &filesystem{
+		mopts: opts.Data,
+		dev:   dev,
+		mf:    pgalloc.MemoryFileFromContext(ctx),
+	}
+	sbBuf := make([]byte, superBlockSize)
+	if err := fs.readAt(ctx, sbBuf, superBlockOffset); err != nil {
+		return nil, nil, err
+	}
+	fs.sb, err = parseSuperBlock(sbBuf)
+	if err != nil {
+		ctx.Warningf("ext4.FilesystemType.GetFilesystem: %s: %v", source, err)
+		return nil, nil, linuxerr.EINVAL
+	}
+	if fs.sb.featureIncompat&featureIncompatRecover != 0 {
+		ctx.Warningf("ext4.FilesystemType.GetFilesystem: %s: journal needs recovery, which is not supported; file contents may be stale", source)
+	}
+	fs.blockSize = fs.sb.blockSize()
+
+	gdtBuf := make([]byte, uint64(fs.sb.groupCount())*uint64(fs.sb.descSize))
+	if err := fs.readAt(ctx, gdtBuf, (uint64(fs.sb.firstDataBlock)+1)*fs.blockSize); err != nil {
+		return nil, nil, err
+	}
+	fs.inodeTables = fs.sb.parseInodeTables(gdtBuf)
+
+	devMinor, err := vfsObj.GetAnonBlockDevMinor()
+	if err != nil {
+		return nil, nil, err
+	}
+	fs.devMinor = devMinor
+	fs.vfsfs.Init(vfsObj, &fstype, fs)
+	// fs.Release now owns dev.
+	cu.Release()
+	cu.Add(func() { fs.vfsfs.DecRef(ctx) })
+
+	fs.inodeBuckets = make([]inodeBucket, runtime.GOMAXPROCS(0))
+	for i := range fs.inodeBuckets {
+		fs.inodeBuckets[i].init()
+	}
+
+	root, err := fs.newDentry(ctx, rootIno)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !root.inode.isDir() {
+		ctx.Warningf("ext4.FilesystemType.GetFilesystem: %s: root inode is not a directory", source)
+		root.DecRef(ctx)
+		return nil, nil, linuxerr.EUCLEAN
+	}
+
+	// Increase the root's reference count to 2. One reference is returned to
+	// the caller, and the other is held by fs.
+	root.IncRef()
+	fs.root = root
+
+	cu.Release()
+	return &fs.vfsfs, &root.vfsd, nil
+}
+
+// Release implements vfs.FilesystemImpl.Release.
+func (fs *filesystem) Release(ctx context.Context) {
+	// An extra reference was held by the filesystem on the root.
+	if fs.root != nil {
+		fs.root.DecRef(ctx)
+	}
+	fs.dev.DecRef(ctx)
+	fs.vfsfs.VirtualFilesystem().PutAnonBlockDevMinor(fs.devMinor)
+}
+
+// readAt reads len(buf) bytes at offset off of the block device into buf.
+func (fs *filesystem) readAt(ctx context.Context, buf []byte, off uint64) error {
+	for len(buf) > 0 {
+		n, err := fs.dev.PRead(ctx, usermem.BytesIOSequence(buf), int64(off), vfs.ReadOptions{})
+		buf = buf[n:]
+		off += uint64(n)
+		if err != nil && len(buf) != 0 {
+			return err
+		}
+		if n == 0 && len(buf) != 0 {
+			// The filesystem refers to data beyond the end of the device.
+			return linuxerr.EIO
+		}
+	}
+	return nil
+}
+
+func (fs *filesystem) statFS() linux.Statfs {
+	free := fs.sb.freeBlocksCount
+	avail := uint64(0)
+	if free > fs.sb.rBlocksCount {
+		avail = free - fs.sb.rBlocksCount
+	}
+	return linux.Statfs{
+		Type:            linux.EXT_SUPER_MAGIC,
+		NameLength:      maxNameLen,
+		BlockSize:       int64(fs.blockSize),
+		FragmentSize:    int64(fs.blockSize),
+		Blocks:          fs.sb.blocksCount,
+		BlocksFree:      free,
+		BlocksAvailable: avail,
+		Files:           uint64(fs.sb.inodesCount),
+		FilesFree:       uint64(fs.sb.freeInodesCount),
+		Flags:           linux.ST_RDONLY,
+	}
+}
+
+// +stateify savable
+type inodeBucket struct {
+	// mu protects inodeMap.
+	mu sync.RWMutex `state:"nosave"`
+
+	// inodeMap contains the inodes indexed by inode number.
+	// +checklocks:mu
+	inodeMap map[uint32]*inode
+}
+
+func (ib *inodeBucket) init() {
+	ib.inodeMap = make(map[uint32]*inode) // +checklocksignore
+}
+
+// getInode returns the inode identified by ino. A reference on inode is also
+// returned to caller.
+func (ib *inodeBucket) getInode(ino uint32) *inode {
+	ib.mu.RLock()
+	defer ib.mu.RUnlock()
+	i := ib.inodeMap[ino]
+	if i != nil {
+		i.IncRef()
+	}
+	return i
+}
+
+// addInode adds the inode identified by ino into the bucket. It will first
+// check whether the old inode exists. If not, it will call newInode() to get
+// the new inode. The inode eventually saved in the bucket will be returned
+// with a reference for caller.
+func (ib *inodeBucket) addInode(ino uint32, newInode func() *inode) *inode {
+	ib.mu.Lock()
+	defer ib.mu.Unlock()
+	if i, ok := ib.inodeMap[ino]; ok {
+		i.IncRef()
+		return i
+	}
+	i := newInode()
+	ib.inodeMap[ino] = i
+	return i
+}
+
+// removeInode removes the inode identified by ino.
+func (ib *inodeBucket) removeInode(ino uint32) {
+	ib.mu.Lock()
+	delete(ib.inodeMap, ino)
+	ib.mu.Unlock()
+}
+
+func (fs *filesystem) inodeBucket(ino uint32) *inodeBucket {
+	bucket := ino % uint32(len(fs.inodeBuckets))
+	return &fs.inodeBuckets[bucket]
+}
+
+// inode represents a filesystem object.
+//
+// Each dentry holds a reference on the inode it represents. An inode will
+// be dropped once its reference count reaches zero. We do not cache inodes
+// directly. The caching policy is implemented on top of dentries.
+//
+// +stateify savable
+type inode struct {
+	diskInode
+
+	// inodeRefs is the reference count.
+	inodeRefs
+
+	// fs is the owning filesystem.
+	fs *filesystem
+
+	// ino is the inode number.
+	ino uint32
+
+	// extentsMu protects extents. extents is immutable after creation.
+	extentsMu sync.Mutex `state:"nosave"`
+
+	// extents maps the file's logical blocks to physical blocks, sorted by
+	// logical block. Holes are not represented. extents is loaded lazily.
+	// +checklocks:extentsMu
+	extents []extent `state:"nosave"`
+
+	// dirMu protects dirents and direntIndex. They are immutable after
+	// creation.
+	dirMu sync.RWMutex `state:"nosave"`
+	// +checklocks:dirMu
+	dirents []vfs.Dirent `state:"nosave"`
+	// direntIndex maps names to indexes in dirents.
+	// +checklocks:dirMu
+	direntIndex map[string]int `state:"nosave"`
+
+	// mapsMu protects mappings.
+	mapsMu sync.Mutex `state:"nosave"`
+
+	// mappings tracks the mappings of the file into memmap.MappingSpaces
+	// if this inode represents a regular file.
+	// +checklocks:mapsMu
+	mappings memmap.MappingSet
+
+	// dataMu protects cache.
+	dataMu sync.Mutex `state:"nosave"`
+
+	// cache maps offsets in the file to offsets in fs.mf that store the
+	// file's data. cache is only used for memory mappings.
+	// +checklocks:dataMu
+	cache fsutil.FileRangeSet
+
+	// locks supports POSIX and BSD style locks.
+	locks vfs.FileLocks
+
+	// Inotify watches for this inode.
+	watches vfs.Watches
+}
+
+// getInode returns the inode identified by ino. A reference on inode is also
+// returned to caller.
+func (fs *filesystem) getInode(ctx context.Context, ino uint32) (*inode, error) {
+	bucket := fs.inodeBucket(ino)
+
+	// Fast path, inode already exists.
+	if i := bucket.getInode(ino); i != nil {
+		return i, nil
+	}
+
+	// Slow path, create a new inode.
+	//
+	// Read the on-disk inode without taking the bucket lock first to reduce
+	// the contention.
+	di, err := fs.readInode(ctx, ino)
+	if err != nil {
+		return nil, err
+	}
+	return bucket.addInode(ino, func() *inode {
+		i := &inode{
+			diskInode: di,
+			fs:        fs,
+			ino:       ino,
+		}
+		i.InitRefs()
+		return i
+	}), nil
+}
+
+// readInode reads the on-disk inode identified by ino.
+func (fs *filesystem) readInode(ctx context.Context, ino uint32) (diskInode, error) {
+	if ino == 0 || ino > fs.sb.inodesCount {
+		ctx.Warningf("ext4.filesystem.readInode: invalid inode number %d", ino)
+		return diskInode{}, linuxerr.EUCLEAN
+	}
+	group := (ino - 1) / fs.sb.inodesPerGroup
+	index := (ino - 1) % fs.sb.inodesPerGroup
+	buf := make([]byte, fs.sb.inodeSize)
+	if err := fs.readAt(ctx, buf, fs.inodeTables[group]*fs.blockSize+uint64(index)*uint64(fs.sb.inodeSize)); err != nil {
+		return diskInode{}, err
+	}
+	return parseInode(buf, &fs.sb), nil
+}
+
+// DecRef should be called when you're finished with an inode.
+func (i *inode) DecRef(ctx context.Context) {
+	i.inodeRefs.DecRef(func() {
+		i.fs.inodeBucket(i.ino).removeInode(i.ino)
+		i.dataMu.Lock()
+		i.cache.DropAll(i.fs.mf)
+		i.dataMu.Unlock()
+	})
+}
+
+func (i *inode) checkPermissions(creds *auth.Credentials, ats vfs.AccessTypes) error {
+	return vfs.GenericCheckPermissions(creds, ats, linux.FileMode(i.mode), auth.KUID(i.uid), auth.KGID(i.gid))
+}
+
+func (i *inode) statTo(stat *linux.Statx) {
+	stat.Mask = linux.STATX_TYPE | linux.STATX_MODE | linux.STATX_NLINK |
+		linux.STATX_UID | linux.STATX_GID | linux.STATX_INO | linux.STATX_SIZE |
+		linux.STATX_BLOCKS | linux.STATX_ATIME | linux.STATX_CTIME |
+		linux.STATX_MTIME
+	stat.Blksize = uint32(i.fs.blockSize)
+	stat.Nlink = uint32(i.linksCount)
+	stat.UID = i.uid
+	stat.GID = i.gid
+	stat.Mode = i.mode
+	stat.Ino = uint64(i.ino)
+	stat.Size = i.size
+	stat.Blocks = i.blocks
+	stat.Atime = i.atime
+	stat.Ctime = i.ctime
+	stat.Mtime = i.mtime
+	if i.hasBtime {
+		stat.Mask |= linux.STATX_BTIME
+		stat.Btime = i.btime
+	}
+	if ft := i.fileType(); ft == linux.S_IFCHR || ft == linux.S_IFBLK {
+		stat.RdevMajor, stat.RdevMinor = i.rdev()
+	}
+	stat.DevMajor = linux.UNNAMED_MAJOR
+	stat.DevMinor = i.fs.devMinor
+}
+
+func (i *inode) fileType() uint16 {
+	return i.mode & linux.S_IFMT
+}
+
+func (i *inode) isDir() bool {
+	return i.fileType() == linux.S_IFDIR
+}
+
+func (i *inode) isSymlink() bool {
+	return i.fileType() == linux.S_IFLNK
+}
+
+// readlink returns the target of a symlink.
+func (i *inode) readlink(ctx context.Context) (string, error) {
+	if !i.isSymlink() {
+		return "", linuxerr.EINVAL
+	}
+	if i.size <= fastSymlinkMaxLen {
+		// Fast symlinks store the target in i_block.
+		return string(i.block[:i.size]), nil
+	}
+	if i.size >= uint64(i.fs.blockSize) {
+		return "", linuxerr.EUCLEAN
+	}
+	buf := make([]byte, i.size)
+	if err := i.readAt(ctx, buf, 0); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// dentry implements vfs.DentryImpl.
+//
+// The filesystem is read-only and currently we never drop the cached dentries
+// until the filesystem is unmounted. The reference model works like this:
+//
+//   - The initial reference count of each dentry is one, which is the reference
+//     held by the parent (so when the reference count is one, it also means that
+//     this is a cached dentry, i.e. not in use).
+//
+//   - When a dentry is used (e.g. opened by someone), its reference count will
+//     be increased and the new reference is held by caller.
+//
+//   - The reference count of root dentry is two. One reference is returned to
+//     the caller of `GetFilesystem()`, and the other is held by `fs`.
+//
+// +stateify savable
+type dentry struct {
+	vfsd vfs.Dentry
+
+	// dentryRefs is the reference count.
+	dentryRefs
+
+	// parent is this dentry's parent directory. If this dentry is
+	// a file system root, parent is nil.
+	parent atomic.Pointer[dentry] `state:".(*dentry)"`
+
+	// name is this dentry's name in its parent. If this dentry is
+	// a file system root, name is the empty string.
+	name string
+
+	// inode is the inode represented by this dentry.
+	inode *inode
+
+	// dirMu serializes changes to the dentry tree.
+	dirMu sync.RWMutex `state:"nosave"`
+
+	// childMap contains the mappings of child names to dentries if this
+	// dentry represents a directory.
+	// +checklocks:dirMu
+	childMap map[string]*dentry
+}
+
+// The caller is expected to handle dentry insertion into dentry tree.
+func (fs *filesystem) newDentry(ctx context.Context, ino uint32) (*dentry, error) {
+	i, err := fs.getInode(ctx, ino)
+	if err != nil {
+		return nil, err
+	}
+	d := &dentry{
+		inode: i,
+	}
+	d.InitRefs()
+	d.vfsd.Init(d)
+	return d, nil
+}
+
+// DecRef implements vfs.DentryImpl.DecRef.
+func (d *dentry) DecRef(ctx context.Context) {
+	d.dentryRefs.DecRef(func() {
+		d.dirMu.Lock()
+		for _, c := range d.childMap {
+			c.DecRef(ctx)
+		}
+		d.childMap = nil
+		d.dirMu.Unlock()
+		d.inode.DecRef(ctx)
+	})
+}
+
+// InotifyWithParent implements vfs.DentryImpl.InotifyWithParent.
+func (d *dentry) InotifyWithParent(ctx context.Context, events, cookie uint32, et vfs.EventType) {
+	if d.inode.isDir() {
+		events |= linux.IN_ISDIR
+	}
+	// The ordering below is important, Linux always notifies the parent first.
+	if parent := d.parent.Load(); parent != nil {
+		parent.inode.watches.Notify(ctx, d.name, events, cookie, et, false)
+	}
+	d.inode.watches.Notify(ctx, "", events, cookie, et, false)
+}
+
+// Watches implements vfs.DentryImpl.Watches.
+func (d *dentry) Watches() *vfs.Watches {
+	return &d.inode.watches
+}
+
+// OnZeroWatches implements vfs.DentryImpl.OnZeroWatches.
+func (d *dentry) OnZeroWatches(ctx context.Context) {}
+
+func (d *dentry) open(ctx context.Context, rp *vfs.ResolvingPath, opts *vfs.OpenOptions) (*vfs.FileDescription, error) {
+	ats := vfs.AccessTypesForOpenFlags(opts)
+	if err := d.inode.checkPermissions(rp.Credentials(), ats); err != nil {
+		return nil, err
+	}
+
+	switch d.inode.fileType() {
+	case linux.S_IFREG:
+		if ats&vfs.MayWrite != 0 {
+			return nil, linuxerr.EROFS
+		}
+		var fd regularFileFD
+		fd.LockFD.Init(&d.inode.locks)
+		if err := fd.vfsfd.Init(&fd, opts.Flags, rp.Mount(), &d.vfsd, &vfs.FileDescriptionOptions{AllowDirectIO: true}); err != nil {
+			return nil, err
+		}
+		return &fd.vfsfd, nil
+
+	case linux.S_IFDIR:
+		// Can't open directories with O_CREAT.
+		if opts.Flags&linux.O_CREAT != 0 {
+			return nil, linuxerr.EISDIR
+		}
+		// Can't open directories writably.
+		if ats&vfs.MayWrite != 0 {
+			return nil, linuxerr.EISDIR
+		}
+		if opts.Flags&linux.O_DIRECT != 0 {
+			return nil, linuxerr.EINVAL
+		}
+		var fd directoryFD
+		fd.LockFD.Init(&d.inode.locks)
+		if err := fd.vfsfd.Init(&fd, opts.Flags, rp.Mount(), &d.vfsd, &vfs.FileDescriptionOptions{AllowDirectIO: true}); err != nil {
+			return nil, err
+		}
+		return &fd.vfsfd, nil
+
+	case linux.S_IFLNK:
+		// Can't open symlinks without O_PATH, which is handled at the VFS layer.
+		return nil, linuxerr.ELOOP
+
+	default:
+		return nil, linuxerr.ENXIO
+	}
+}
+
+// +stateify savable
+type fileDescription struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.LockFD
+}
+
+func (fd *fileDescription) filesystem() *filesystem {
+	return fd.vfsfd.Mount().Filesystem().Impl().(*filesystem)
+}
+
+func (fd *fileDescription) dentry() *dentry {
+	return fd.vfsfd.Dentry().Impl().(*dentry)
+}
+
+func (fd *fileDescription) inode() *inode {
+	return fd.dentry().inode
+}
+
+// Stat implements vfs.FileDescriptionImpl.Stat.
+func (fd *fileDescription) Stat(ctx context.Context, opts vfs.StatOptions) (linux.Statx, error) {
+	var stat linux.Statx
+	fd.inode().statTo(&stat)
+	return stat, nil
+}
+
+// SetStat implements vfs.FileDescriptionImpl.SetStat.
+func (fd *fileDescription) SetStat(ctx context.Context, opts vfs.SetStatOptions) error {
+	return linuxerr.EROFS
+}
+
+// StatFS implements vfs.FileDescriptionImpl.StatFS.
+func (fd *fileDescription) StatFS(ctx context.Context) (linux.Statfs, error) {
+	return fd.filesystem().statFS(), nil
+}
+
+// ListXattr implements vfs.FileDescriptionImpl.ListXattr.
+func (fd *fileDescription) ListXattr(ctx context.Context, size uint64) ([]string, error) {
+	return nil, linuxerr.ENOTSUP
+}
+
+// GetXattr implements vfs.FileDescriptionImpl.GetXattr.
+func (fd *fileDescription) GetXattr(ctx context.Context, opts vfs.GetXattrOptions) (string, error) {
+	return "", linuxerr.ENOTSUP
+}
+
+// SetXattr implements vfs.FileDescriptionImpl.SetXattr.
+func (fd *fileDescription) SetXattr(ctx context.Context, opts vfs.SetXattrOptions) error {
+	return linuxerr.EROFS
+}
+
+// RemoveXattr implements vfs.FileDescriptionImpl.RemoveXattr.
+func (fd *fileDescription) RemoveXattr(ctx context.Context, name string) error {
+	return linuxerr.EROFS
+}
+
+// Sync implements vfs.FileDescriptionImpl.Sync.
+func (*fileDescription) Sync(context.Context) error {
+	return nil
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (*fileDescription) Release(ctx context.Context) {}
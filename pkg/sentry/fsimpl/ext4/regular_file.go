@@ -0,0 +1,410 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ext4
+
+import (
+	"encoding/binary"
+	"io"
+	"sort"
+	"sync"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// maxReadChunk is the maximum number of bytes read from the block device at
+// once when reading file data.
+const maxReadChunk = 1 << 20
+
+// getExtents returns the extents mapping the file's data blocks.
+func (i *inode) getExtents(ctx context.Context) ([]extent, error) {
+	i.extentsMu.Lock()
+	defer i.extentsMu.Unlock()
+	if i.extents != nil {
+		return i.extents, nil
+	}
+	var (
+		extents []extent
+		err     error
+	)
+	if i.flags&inodeFlagExtents != 0 {
+		extents, err = i.fs.appendExtentTree(ctx, nil, i.block[:], maxExtentDepth)
+	} else {
+		extents, err = i.fs.appendBlockMap(ctx, nil, i.block[:])
+	}
+	if err != nil {
+		return nil, err
+	}
+	if extents == nil {
+		// Cache the absence of extents.
+		extents = []extent{}
+	}
+	i.extents = extents
+	return extents, nil
+}
+
+// appendExtentTree appends the extents in the extent tree rooted at node to
+// extents, which must be sorted by logical block.
+func (fs *filesystem) appendExtentTree(ctx context.Context, extents []extent, node []byte, maxDepth uint16) ([]extent, error) {
+	n, err := parseExtentNode(node)
+	if err != nil {
+		ctx.Warningf("ext4.filesystem.appendExtentTree: %v", err)
+		return nil, linuxerr.EUCLEAN
+	}
+	if n.depth > maxDepth {
+		ctx.Warningf("ext4.filesystem.appendExtentTree: extent tree node depth %d exceeds parent's", n.depth)
+		return nil, linuxerr.EUCLEAN
+	}
+	for _, e := range n.extents {
+		if len(extents) != 0 && e.logical < extents[len(extents)-1].end() {
+			ctx.Warningf("ext4.filesystem.appendExtentTree: unsorted or overlapping extents")
+			return nil, linuxerr.EUCLEAN
+		}
+		extents = append(extents, e)
+	}
+	if len(n.children) == 0 {
+		return extents, nil
+	}
+	buf := make([]byte, fs.blockSize)
+	for _, child := range n.children {
+		if err := fs.readAt(ctx, buf, child*fs.blockSize); err != nil {
+			return nil, err
+		}
+		if extents, err = fs.appendExtentTree(ctx, extents, buf, n.depth-1); err != nil {
+			return nil, err
+		}
+	}
+	return extents, nil
+}
+
+// appendBlockMap appends extents for the blocks referenced by the block map in
+// iblock, the i_block field of an inode that doesn't use extents.
+func (fs *filesystem) appendBlockMap(ctx context.Context, extents []extent, iblock []byte) ([]extent, error) {
+	le := binary.LittleEndian
+	addBlock := func(logical uint64, physical uint32) {
+		if physical == 0 {
+			// Hole.
+			return
+		}
+		if len(extents) != 0 {
+			last := &extents[len(extents)-1]
+			if last.end() == logical && last.physical+last.length == uint64(physical) {
+				last.length++
+				return
+			}
+		}
+		extents = append(extents, extent{
+			logical:  logical,
+			length:   1,
+			physical: uint64(physical),
+		})
+	}
+
+	for j := 0; j < numDirectBlocks; j++ {
+		addBlock(uint64(j), le.Uint32(iblock[j*4:]))
+	}
+
+	// walk adds the blocks referenced by the indirect block at physical with
+	// the given level of indirection, which maps logical blocks starting at
+	// logical. It returns the number of logical blocks covered.
+	perBlock := fs.blockSize / 4
+	var walk func(physical uint32, level int, logical uint64) (uint64, error)
+	walk = func(physical uint32, level int, logical uint64) (uint64, error) {
+		span := uint64(1)
+		for l := 0; l < level; l++ {
+			span *= perBlock
+		}
+		if physical == 0 {
+			return span, nil
+		}
+		buf := make([]byte, fs.blockSize)
+		if err := fs.readAt(ctx, buf, uint64(physical)*fs.blockSize); err != nil {
+			return 0, err
+		}
+		childSpan := span / perBlock
+		for k := uint64(0); k < perBlock; k++ {
+			ptr := le.Uint32(buf[k*4:])
+			if level == 1 {
+				addBlock(logical+k, ptr)
+				continue
+			}
+			if _, err := walk(ptr, level-1, logical+k*childSpan); err != nil {
+				return 0, err
+			}
+		}
+		return span, nil
+	}
+
+	logical := uint64(numDirectBlocks)
+	for level := 1; level <= 3; level++ {
+		span, err := walk(le.Uint32(iblock[(numDirectBlocks+level-1)*4:]), level, logical)
+		if err != nil {
+			return nil, err
+		}
+		logical += span
+	}
+	return extents, nil
+}
+
+// readAt reads len(buf) bytes of the file's data at offset off into buf.
+//
+// Preconditions: off+len(buf) <= i.size.
+func (i *inode) readAt(ctx context.Context, buf []byte, off uint64) error {
+	extents, err := i.getExtents(ctx)
+	if err != nil {
+		return err
+	}
+	bs := i.fs.blockSize
+	for len(buf) > 0 {
+		lblk := off / bs
+		blkOff := off % bs
+		idx := sort.Search(len(extents), func(j int) bool {
+			return extents[j].end() > lblk
+		})
+		var n uint64
+		if idx < len(extents) && extents[idx].logical <= lblk {
+			e := &extents[idx]
+			n = min((e.end()-lblk)*bs-blkOff, uint64(len(buf)))
+			if e.uninit {
+				clear(buf[:n])
+			} else if err := i.fs.readAt(ctx, buf[:n], (e.physical+lblk-e.logical)*bs+blkOff); err != nil {
+				return err
+			}
+		} else {
+			// Holes read as zeroes.
+			n = uint64(len(buf))
+			if idx < len(extents) {
+				n = min((extents[idx].logical-lblk)*bs-blkOff, n)
+			}
+			clear(buf[:n])
+		}
+		buf = buf[n:]
+		off += n
+	}
+	return nil
+}
+
+// readToBlocksAt reads the file's data at offset off into dsts.
+func (i *inode) readToBlocksAt(ctx context.Context, dsts safemem.BlockSeq, off uint64) (uint64, error) {
+	if off >= i.size {
+		return 0, io.EOF
+	}
+	n := min(dsts.NumBytes(), i.size-off)
+	buf := make([]byte, min(n, maxReadChunk))
+	var done uint64
+	for done < n {
+		chunk := buf[:min(n-done, uint64(len(buf)))]
+		if err := i.readAt(ctx, chunk, off+done); err != nil {
+			return done, err
+		}
+		cp, err := safemem.CopySeq(dsts.DropFirst64(done), safemem.BlockSeqOf(safemem.BlockFromSafeSlice(chunk)))
+		done += cp
+		if err != nil {
+			return done, err
+		}
+	}
+	return done, nil
+}
+
+// +stateify savable
+type regularFileFD struct {
+	fileDescription
+
+	// offMu protects off.
+	offMu sync.Mutex `state:"nosave"`
+
+	// off is the file offset.
+	// +checklocks:offMu
+	off int64
+}
+
+// PRead implements vfs.FileDescriptionImpl.PRead.
+func (fd *regularFileFD) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
+	if offset < 0 {
+		return 0, linuxerr.EINVAL
+	}
+
+	// Check that flags are supported.
+	//
+	// TODO(gvisor.dev/issue/2601): Support select preadv2 flags.
+	if opts.Flags&^linux.RWF_HIPRI != 0 {
+		return 0, linuxerr.EOPNOTSUPP
+	}
+
+	if dst.NumBytes() == 0 {
+		return 0, nil
+	}
+
+	r := &regularFileReader{
+		ctx:   ctx,
+		inode: fd.inode(),
+		off:   uint64(offset),
+	}
+	return dst.CopyOutFrom(ctx, r)
+}
+
+type regularFileReader struct {
+	ctx   context.Context
+	inode *inode
+	off   uint64
+}
+
+// ReadToBlocks implements safemem.Reader.ReadToBlocks.
+func (r *regularFileReader) ReadToBlocks(dsts safemem.BlockSeq) (uint64, error) {
+	n, err := r.inode.readToBlocksAt(r.ctx, dsts, r.off)
+	r.off += n
+	return n, err
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (fd *regularFileFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	fd.offMu.Lock()
+	n, err := fd.PRead(ctx, dst, fd.off, opts)
+	fd.off += n
+	fd.offMu.Unlock()
+	return n, err
+}
+
+// PWrite implements vfs.FileDescriptionImpl.PWrite.
+func (fd *regularFileFD) PWrite(ctx context.Context, src usermem.IOSequence, offset int64, opts vfs.WriteOptions) (int64, error) {
+	return 0, linuxerr.EROFS
+}
+
+// Write implements vfs.FileDescriptionImpl.Write.
+func (fd *regularFileFD) Write(ctx context.Context, src usermem.IOSequence, opts vfs.WriteOptions) (int64, error) {
+	return 0, linuxerr.EROFS
+}
+
+// Seek implements vfs.FileDescriptionImpl.Seek.
+func (fd *regularFileFD) Seek(ctx context.Context, offset int64, whence int32) (int64, error) {
+	fd.offMu.Lock()
+	defer fd.offMu.Unlock()
+	switch whence {
+	case linux.SEEK_SET:
+		// use offset as specified
+	case linux.SEEK_CUR:
+		offset += fd.off
+	case linux.SEEK_END:
+		offset += int64(fd.inode().size)
+	default:
+		return 0, linuxerr.EINVAL
+	}
+	if offset < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	fd.off = offset
+	return offset, nil
+}
+
+// ConfigureMMap implements vfs.FileDescriptionImpl.ConfigureMMap.
+func (fd *regularFileFD) ConfigureMMap(ctx context.Context, opts *memmap.MMapOpts) error {
+	return vfs.GenericConfigureMMap(&fd.vfsfd, fd.inode(), opts)
+}
+
+// AddMapping implements memmap.Mappable.AddMapping.
+func (i *inode) AddMapping(ctx context.Context, ms memmap.MappingSpace, ar hostarch.AddrRange, offset uint64, writable bool) error {
+	i.mapsMu.Lock()
+	i.mappings.AddMapping(ms, ar, offset, writable)
+	i.mapsMu.Unlock()
+	return nil
+}
+
+// RemoveMapping implements memmap.Mappable.RemoveMapping.
+func (i *inode) RemoveMapping(ctx context.Context, ms memmap.MappingSpace, ar hostarch.AddrRange, offset uint64, writable bool) {
+	i.mapsMu.Lock()
+	defer i.mapsMu.Unlock()
+	for _, r := range i.mappings.RemoveMapping(ms, ar, offset, writable) {
+		// Drop cached pages that are no longer mapped, since they can be
+		// read from the device again if needed.
+		i.dataMu.Lock()
+		i.cache.Drop(r, i.fs.mf)
+		i.dataMu.Unlock()
+	}
+}
+
+// CopyMapping implements memmap.Mappable.CopyMapping.
+func (i *inode) CopyMapping(ctx context.Context, ms memmap.MappingSpace, srcAR, dstAR hostarch.AddrRange, offset uint64, writable bool) error {
+	return i.AddMapping(ctx, ms, dstAR, offset, writable)
+}
+
+// Translate implements memmap.Mappable.Translate.
+func (i *inode) Translate(ctx context.Context, required, optional memmap.MappableRange, at hostarch.AccessType) ([]memmap.Translation, error) {
+	if at.Write {
+		return nil, &memmap.BusError{linuxerr.EROFS}
+	}
+	pgend, _ := hostarch.PageRoundUp(i.size)
+	var beyondEOF bool
+	if required.End > pgend {
+		if required.Start >= pgend {
+			return nil, &memmap.BusError{io.EOF}
+		}
+		beyondEOF = true
+		required.End = pgend
+	}
+	if optional.End > pgend {
+		optional.End = pgend
+	}
+
+	i.dataMu.Lock()
+	defer i.dataMu.Unlock()
+	mf := i.fs.mf
+	_, cerr := i.cache.Fill(ctx, required, optional, i.size, mf, usage.PageCache, pgalloc.AllocateAndWritePopulate, i.readToBlocksAt)
+
+	var ts []memmap.Translation
+	var translatedEnd uint64
+	for seg := i.cache.FindSegment(required.Start); seg.Ok() && seg.Start() < required.End; seg, _ = seg.NextNonEmpty() {
+		segMR := seg.Range().Intersect(optional)
+		ts = append(ts, memmap.Translation{
+			Source: segMR,
+			File:   mf,
+			Offset: seg.FileRangeOf(segMR).Start,
+			Perms:  hostarch.ReadExecute,
+		})
+		translatedEnd = segMR.End
+	}
+
+	// Don't return the error returned by i.cache.Fill if it occurred outside
+	// of required.
+	if translatedEnd < required.End && cerr != nil {
+		return ts, &memmap.BusError{cerr}
+	}
+	if beyondEOF {
+		return ts, &memmap.BusError{io.EOF}
+	}
+	return ts, nil
+}
+
+// InvalidateUnsavable implements memmap.Mappable.InvalidateUnsavable.
+func (i *inode) InvalidateUnsavable(ctx context.Context) error {
+	i.mapsMu.Lock()
+	defer i.mapsMu.Unlock()
+	i.mappings.InvalidateAll(memmap.InvalidateOpts{})
+
+	// Discard the cache so that it's not stored in saved state. This is safe
+	// because per InvalidateUnsavable invariants, no new translations can have
+	// been returned after we invalidated all existing translations above.
+	i.dataMu.Lock()
+	defer i.dataMu.Unlock()
+	i.cache.DropAll(i.fs.mf)
+	return nil
+}
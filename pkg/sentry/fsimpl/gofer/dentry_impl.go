@@ -19,7 +19,6 @@ import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
-	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/fsutil"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
@@ -293,8 +292,7 @@ func (d *dentry) listXattrImpl(ctx context.Context, size uint64) ([]string, erro
 	case *lisafsDentry:
 		return dt.controlFD.ListXattr(ctx, size)
 	case *directfsDentry:
-		// Consistent with runsc/fsgofer.
-		return nil, linuxerr.EOPNOTSUPP
+		return dt.listXattr()
 	default:
 		panic("unknown dentry implementation")
 	}
@@ -318,8 +316,7 @@ func (d *dentry) setXattrImpl(ctx context.Context, opts *vfs.SetXattrOptions) er
 	case *lisafsDentry:
 		return dt.controlFD.SetXattr(ctx, opts.Name, opts.Value, opts.Flags)
 	case *directfsDentry:
-		// Consistent with runsc/fsgofer.
-		return linuxerr.EOPNOTSUPP
+		return unix.Fsetxattr(dt.controlFD, opts.Name, []byte(opts.Value), int(opts.Flags))
 	default:
 		panic("unknown dentry implementation")
 	}
@@ -331,8 +328,7 @@ func (d *dentry) removeXattrImpl(ctx context.Context, name string) error {
 	case *lisafsDentry:
 		return dt.controlFD.RemoveXattr(ctx, name)
 	case *directfsDentry:
-		// Consistent with runsc/fsgofer.
-		return linuxerr.EOPNOTSUPP
+		return unix.Fremovexattr(dt.controlFD, name)
 	default:
 		panic("unknown dentry implementation")
 	}
@@ -483,13 +479,17 @@ func (d *dentry) unlink(ctx context.Context, name string, flags uint32) error {
 	}
 }
 
+// flags may only contain linux.RENAME_NOREPLACE, which is only passed to the
+// remote filesystem with directfs; lisafs relies on the caller having checked
+// that newName doesn't exist.
+//
 // Precondition: !d.isSynthetic().
-func (d *dentry) rename(ctx context.Context, oldName string, newParent *dentry, newName string) error {
+func (d *dentry) rename(ctx context.Context, oldName string, newParent *dentry, newName string, flags uint32) error {
 	switch dt := d.impl.(type) {
 	case *lisafsDentry:
 		return dt.controlFD.RenameAt(ctx, oldName, newParent.impl.(*lisafsDentry).controlFD.ID(), newName)
 	case *directfsDentry:
-		return fsutil.RenameAt(dt.controlFD, oldName, newParent.impl.(*directfsDentry).controlFD, newName)
+		return fsutil.RenameAt2(dt.controlFD, oldName, newParent.impl.(*directfsDentry).controlFD, newName, flags)
 	default:
 		panic("unknown dentry implementation")
 	}
@@ -19,6 +19,7 @@ import (
 	"math"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/abi/linux"
@@ -431,6 +432,21 @@ func (d *directfsDentry) getXattr(name string, size uint64) (string, error) {
 	return string(data), nil
 }
 
+// listXattr returns all extended attribute names on the host file. The
+// caller filters the list and checks it against the requested size, since
+// names that are filtered out still count towards the host's size limit.
+func (d *directfsDentry) listXattr() ([]string, error) {
+	buf := make([]byte, linux.XATTR_LIST_MAX)
+	n, err := unix.Flistxattr(d.controlFD, buf)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return strings.Split(strings.TrimSuffix(string(buf[:n]), "\x00"), "\x00"), nil
+}
+
 // getCreatedChild opens the newly created child, sets its uid/gid, constructs
 // a disconnected dentry and returns it.
 func (d *directfsDentry) getCreatedChild(name string, uid, gid int, isDir bool) (*dentry, error) {
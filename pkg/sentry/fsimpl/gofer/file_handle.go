@@ -0,0 +1,117 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gofer
+
+import (
+	"encoding/binary"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/lisafs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+var _ vfs.FileHandleFilesystemImpl = (*filesystem)(nil)
+
+// EncodeFileHandle implements vfs.FileHandleFilesystemImpl.EncodeFileHandle.
+// gofer file handles contain the host file handle type followed by the host
+// file handle.
+func (fs *filesystem) EncodeFileHandle(ctx context.Context, vfsd *vfs.Dentry) ([]byte, error) {
+	d := vfsd.Impl().(*dentry)
+	var (
+		handleType int32
+		handle     []byte
+	)
+	switch dt := d.impl.(type) {
+	case *lisafsDentry:
+		h, err := dt.controlFD.NameToHandle(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if h.Size > lisafs.MaxHandleSize {
+			return nil, linuxerr.EOPNOTSUPP
+		}
+		handleType, handle = h.Type, h.Bytes[:h.Size]
+	case *directfsDentry:
+		h, _, err := unix.NameToHandleAt(dt.controlFD, "", unix.AT_EMPTY_PATH)
+		if err != nil {
+			return nil, err
+		}
+		handleType, handle = h.Type(), h.Bytes()
+	case nil: // synthetic dentry
+		return nil, linuxerr.EOPNOTSUPP
+	default:
+		panic("unknown dentry implementation")
+	}
+	fh := make([]byte, 4, 4+len(handle))
+	binary.LittleEndian.PutUint32(fh, uint32(handleType))
+	return append(fh, handle...), nil
+}
+
+// DecodeFileHandle implements vfs.FileHandleFilesystemImpl.DecodeFileHandle.
+// The gofer resolves the host file handle to a path relative to the
+// filesystem root, which is then walked to find the file's dentry.
+func (fs *filesystem) DecodeFileHandle(ctx context.Context, creds *auth.Credentials, root vfs.VirtualDentry, handle []byte) (vfs.VirtualDentry, error) {
+	if len(handle) < 4 || len(handle)-4 > lisafs.MaxHandleSize {
+		return vfs.VirtualDentry{}, linuxerr.ESTALE
+	}
+	h := lisafs.FileHandle{
+		Type: int32(binary.LittleEndian.Uint32(handle)),
+		Size: uint32(len(handle) - 4),
+	}
+	copy(h.Bytes[:], handle[4:])
+
+	// The root is always non-synthetic.
+	var (
+		rel string
+		err error
+	)
+	switch dt := fs.root.impl.(type) {
+	case *lisafsDentry:
+		rel, err = dt.controlFD.OpenByHandle(ctx, &h)
+	case *directfsDentry:
+		rel, err = dt.controlFDLisa.OpenByHandle(ctx, &h)
+	default:
+		panic("unknown dentry implementation")
+	}
+	if err != nil {
+		return vfs.VirtualDentry{}, err
+	}
+	if rel == "" {
+		rel = "."
+	}
+
+	start := vfs.MakeVirtualDentry(root.Mount(), &fs.root.vfsd)
+	vd, err := fs.vfsfs.VirtualFilesystem().GetDentryAt(ctx, creds, &vfs.PathOperation{
+		Root:  start,
+		Start: start,
+		Path:  fspath.Parse(rel),
+	}, &vfs.GetDentryOptions{})
+	if err != nil {
+		if linuxerr.Equals(linuxerr.ENOENT, err) {
+			return vfs.VirtualDentry{}, linuxerr.ESTALE
+		}
+		return vfs.VirtualDentry{}, err
+	}
+	// The file may be hidden by a mount stacked on one of its ancestors.
+	if vd.Mount() != root.Mount() {
+		vd.DecRef(ctx)
+		return vfs.VirtualDentry{}, linuxerr.ESTALE
+	}
+	return vd, nil
+}
@@ -1084,8 +1084,16 @@ func (d *dentry) open(ctx context.Context, rp *vfs.ResolvingPath, opts *vfs.Open
 	switch d.fileType() {
 	case linux.S_IFREG:
 		if !d.fs.opts.regularFilesUseSpecialFileFD {
-			if err := d.ensureSharedHandle(ctx, ats.MayRead(), ats.MayWrite(), trunc); err != nil {
-				return nil, err
+			// Small files opened only for reading may be read in their
+			// entirety instead of opening a handle.
+			prefetched := false
+			if lisafsD, ok := d.impl.(*lisafsDentry); ok && ats == vfs.MayRead && opts.Flags&linux.O_DIRECT == 0 {
+				prefetched = lisafsD.prefetch(ctx)
+			}
+			if !prefetched {
+				if err := d.ensureSharedHandle(ctx, ats.MayRead(), ats.MayWrite(), trunc); err != nil {
+					return nil, err
+				}
 			}
 			fd, err := newRegularFileFD(mnt, d, opts.Flags)
 			if err != nil {
@@ -1360,9 +1368,9 @@ func (fs *filesystem) RenameAt(ctx context.Context, rp *vfs.ResolvingPath, oldPa
 	if opts.Flags&^linux.RENAME_NOREPLACE != 0 {
 		return linuxerr.EINVAL
 	}
-	if fs.opts.interop == InteropModeShared && opts.Flags&linux.RENAME_NOREPLACE != 0 {
-		// Requires 9P support to synchronize with other remote filesystem
-		// users.
+	if fs.opts.interop == InteropModeShared && opts.Flags&linux.RENAME_NOREPLACE != 0 && !fs.opts.directfs.enabled {
+		// Requires lisafs support to synchronize with other remote filesystem
+		// users. With directfs, RENAME_NOREPLACE is passed to the host.
 		return linuxerr.EINVAL
 	}
 
@@ -1475,7 +1483,7 @@ func (fs *filesystem) RenameAt(ctx context.Context, rp *vfs.ResolvingPath, oldPa
 
 	// Update the remote filesystem.
 	if !renamed.isSynthetic() {
-		if err := oldParent.rename(ctx, oldName, newParent, newName); err != nil {
+		if err := oldParent.rename(ctx, oldName, newParent, newName, opts.Flags); err != nil {
 			vfsObj.AbortRenameDentry(&renamed.vfsd, replacedVFSD)
 			return err
 		}
@@ -558,6 +558,7 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	}
 
 	fs.vfsfs.Init(vfsObj, &fstype, fs)
+	fs.vfsfs.SetFreezable()
 
 	rootInode, rootHostFD, err := fs.initClientAndGetRoot(ctx)
 	if err != nil {
@@ -1415,21 +1416,34 @@ func (d *dentry) checkPermissions(creds *auth.Credentials, ats vfs.AccessTypes)
 	return vfs.GenericCheckPermissions(creds, ats, linux.FileMode(d.mode.Load()), auth.KUID(d.uid.Load()), auth.KGID(d.gid.Load()))
 }
 
+// isXattrPassedThrough returns true if extended attributes with the given name
+// are passed through to the remote filesystem.
+func isXattrPassedThrough(name string) bool {
+	// Only pass through the "user" namespace and POSIX ACLs, which are
+	// enforced by the remote filesystem. Deny access to the "system"
+	// namespace since applications may expect these to affect kernel behavior
+	// in unimplemented ways (b/148380782), and to the "security" and
+	// "trusted" namespaces since they control how the host kernel treats the
+	// file. The gofer applies the same restriction, and with directfs this
+	// check is the only one, since seccomp can't filter on the name.
+	return strings.HasPrefix(name, linux.XATTR_USER_PREFIX) || vfs.IsACLXattr(name)
+}
+
 func (d *dentry) checkXattrPermissions(creds *auth.Credentials, name string, ats vfs.AccessTypes) error {
-	// Deny access to the "system" namespaces since applications
-	// may expect these to affect kernel behavior in unimplemented ways
-	// (b/148380782). Allow all other extended attributes to be passed through
-	// to the remote filesystem. This is inconsistent with Linux's 9p client,
-	// but consistent with other filesystems (e.g. FUSE).
-	//
-	// NOTE(b/202533394): Also disallow "trusted" namespace for now. This is
-	// consistent with the VFS1 gofer client.
-	if strings.HasPrefix(name, linux.XATTR_SYSTEM_PREFIX) || strings.HasPrefix(name, linux.XATTR_TRUSTED_PREFIX) {
+	if !isXattrPassedThrough(name) {
 		return linuxerr.EOPNOTSUPP
 	}
 	mode := linux.FileMode(d.mode.Load())
 	kuid := auth.KUID(d.uid.Load())
 	kgid := auth.KGID(d.gid.Load())
+	if vfs.IsACLXattr(name) {
+		// As in Linux, anyone may read a file's ACLs, but only its owner may
+		// change them.
+		if ats.MayWrite() && !vfs.CanActAsOwner(creds, kuid) {
+			return linuxerr.EPERM
+		}
+		return nil
+	}
 	if err := vfs.GenericCheckPermissions(creds, ats, mode, kuid, kgid); err != nil {
 		return err
 	}
@@ -1884,7 +1898,18 @@ func (d *dentry) listXattr(ctx context.Context, size uint64) ([]string, error) {
 		return nil, nil
 	}
 
-	return d.listXattrImpl(ctx, size)
+	names, err := d.listXattrImpl(ctx, size)
+	if err != nil {
+		return nil, err
+	}
+	// Omit names in namespaces that checkXattrPermissions denies access to.
+	visible := names[:0]
+	for _, name := range names {
+		if isXattrPassedThrough(name) {
+			visible = append(visible, name)
+		}
+	}
+	return visible, nil
 }
 
 func (d *dentry) getXattr(ctx context.Context, creds *auth.Credentials, opts *vfs.GetXattrOptions) (string, error) {
@@ -1904,7 +1929,11 @@ func (d *dentry) setXattr(ctx context.Context, creds *auth.Credentials, opts *vf
 	if err := d.checkXattrPermissions(creds, opts.Name, vfs.MayWrite); err != nil {
 		return err
 	}
-	return d.setXattrImpl(ctx, opts)
+	if err := d.setXattrImpl(ctx, opts); err != nil {
+		return err
+	}
+	d.aclChanged(ctx, opts.Name)
+	return nil
 }
 
 func (d *dentry) removeXattr(ctx context.Context, creds *auth.Credentials, name string) error {
@@ -1914,7 +1943,25 @@ func (d *dentry) removeXattr(ctx context.Context, creds *auth.Credentials, name
 	if err := d.checkXattrPermissions(creds, name, vfs.MayWrite); err != nil {
 		return err
 	}
-	return d.removeXattrImpl(ctx, name)
+	if err := d.removeXattrImpl(ctx, name); err != nil {
+		return err
+	}
+	d.aclChanged(ctx, name)
+	return nil
+}
+
+// aclChanged is called after the extended attribute with the given name is
+// changed on the remote filesystem. Setting a POSIX access ACL may change the
+// file's mode, so cached metadata is refreshed.
+//
+// Preconditions: !d.isSynthetic().
+func (d *dentry) aclChanged(ctx context.Context, name string) {
+	if name != linux.XATTR_NAME_POSIX_ACL_ACCESS {
+		return
+	}
+	if err := d.updateMetadata(ctx); err != nil {
+		log.Debugf("Failed to update metadata after setting POSIX ACL: %v", err)
+	}
 }
 
 // Preconditions:
@@ -2030,6 +2077,16 @@ func (d *dentry) ensureSharedHandle(ctx context.Context, read, write, trunc bool
 			if !d.isWriteHandleOk() {
 				invalidateTranslations = readHandleWasOk
 				d.mmapFD.Store(h.fd)
+				if !readHandleWasOk {
+					// The file's contents may have been prefetched into the
+					// page cache (see lisafsDentry.prefetch), which reads
+					// and mappings will now bypass. Since the file has not
+					// been opened for writing, the page cache is clean and
+					// can be dropped.
+					d.dataMu.Lock()
+					d.cache.DropAll(d.fs.mf)
+					d.dataMu.Unlock()
+				}
 			}
 		} else if openWritable && d.writeFD.RacyLoad() < 0 {
 			d.writeFD.Store(h.fd)
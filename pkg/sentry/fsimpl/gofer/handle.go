@@ -37,6 +37,11 @@ type handle struct {
 	fd     int32 // -1 if unavailable
 }
 
+// isOpen returns true if h represents an open file.
+func (h *handle) isOpen() bool {
+	return h.fdLisa.Ok() || h.fd >= 0
+}
+
 func (h *handle) close(ctx context.Context) {
 	if h.fdLisa.Ok() {
 		h.fdLisa.Close(ctx, true /* flush */)
@@ -16,6 +16,7 @@ package gofer
 
 import (
 	"fmt"
+	"io"
 	"strings"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
@@ -25,8 +26,12 @@ import (
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/lisafs"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/safemem"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 )
 
@@ -207,6 +212,71 @@ func (d *lisafsDentry) updateHandles(ctx context.Context, h handle, readable, wr
 	}
 }
 
+// maxPrefetchSize is the size of the largest regular files whose contents are
+// read in their entirety when opened for reading.
+const maxPrefetchSize = 64 << 10
+
+// prefetch reads the contents of the regular file represented by d into its
+// page cache in a single round trip, if it is small enough, such that opening
+// it for reading doesn't require opening a read handle. It returns true if
+// d's contents are entirely cached.
+//
+// If reads later miss the page cache, e.g. because the prefetched contents
+// were evicted, a read handle is opened at that point.
+//
+// Preconditions: d.fs.renameMu must be locked.
+func (d *lisafsDentry) prefetch(ctx context.Context) bool {
+	mf := d.fs.mf
+	if !d.fs.client.IsSupported(lisafs.ReadFile) || !d.cachedMetadataAuthoritative() || !mf.ShouldCacheEvictable() {
+		return false
+	}
+
+	d.handleMu.RLock()
+	defer d.handleMu.RUnlock()
+	if d.isReadHandleOk() {
+		// Reads can fill the page cache on demand.
+		return false
+	}
+	d.dataMu.Lock()
+	defer d.dataMu.Unlock()
+	size := d.size.Load()
+	if size > maxPrefetchSize {
+		return false
+	}
+	end, _ := hostarch.PageRoundUp(size)
+	mr := memmap.MappableRange{0, end}
+	if d.cache.SpanRange(mr) == mr.Length() {
+		// The file's contents were already prefetched.
+		return true
+	}
+	if !d.cache.IsEmpty() {
+		return false
+	}
+	if size == 0 {
+		return true
+	}
+
+	buf := make([]byte, size)
+	n, err := d.controlFD.ReadFile(ctx, buf)
+	if err != nil || n != size {
+		// The file may have been modified on the remote filesystem, in which
+		// case its contents will be read through a read handle instead.
+		return false
+	}
+	readAt := func(ctx context.Context, dsts safemem.BlockSeq, off uint64) (uint64, error) {
+		if off >= size {
+			return 0, io.EOF
+		}
+		return safemem.CopySeq(dsts, safemem.BlockSeqOf(safemem.BlockFromSafeSlice(buf[off:])))
+	}
+	if _, err := d.cache.Fill(ctx, mr, mr, size, mf, usage.PageCache, pgalloc.AllocateAndWritePopulate, readAt); err != nil {
+		d.cache.DropAll(mf)
+		return false
+	}
+	mf.MarkEvictable(&d.dentry, pgalloc.EvictableRange{mr.Start, mr.End})
+	return true
+}
+
 // Precondition: d.metadataMu must be locked.
 //
 // +checklocks:d.metadataMu
@@ -0,0 +1,385 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gofer
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/lisafs"
+	"gvisor.dev/gvisor/pkg/memutil"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/contexttest"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/time"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/unet"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// testServer implements lisafs.ServerImpl. It serves a single regular file
+// with fixed contents as the mount point.
+type testServer struct {
+	lisafs.Server
+
+	data []byte
+}
+
+// Mount implements lisafs.ServerImpl.Mount.
+func (s *testServer) Mount(c *lisafs.Connection, mountNode *lisafs.Node) (*lisafs.ControlFD, linux.Statx, int, error) {
+	fd := &testControlFD{data: s.data}
+	fd.ControlFD.Init(c, mountNode, linux.ModeRegular|0644, fd)
+	stat, err := fd.Stat()
+	return &fd.ControlFD, stat, -1, err
+}
+
+// SupportedMessages implements lisafs.ServerImpl.SupportedMessages.
+func (s *testServer) SupportedMessages() []lisafs.MID {
+	return []lisafs.MID{
+		lisafs.Mount,
+		lisafs.OpenAt,
+		lisafs.Close,
+		lisafs.PRead,
+		lisafs.ReadFile,
+	}
+}
+
+// MaxMessageSize implements lisafs.ServerImpl.MaxMessageSize.
+func (s *testServer) MaxMessageSize() uint32 {
+	return 1 << 20
+}
+
+// testControlFD implements lisafs.ControlFDImpl for the file served by
+// testServer. Methods that the tests don't use are left unimplemented.
+type testControlFD struct {
+	lisafs.ControlFD
+	lisafs.ControlFDImpl
+
+	data []byte
+}
+
+// FD implements lisafs.ControlFDImpl.FD.
+func (fd *testControlFD) FD() *lisafs.ControlFD {
+	return &fd.ControlFD
+}
+
+// Close implements lisafs.ControlFDImpl.Close.
+func (fd *testControlFD) Close() {}
+
+// Stat implements lisafs.ControlFDImpl.Stat.
+func (fd *testControlFD) Stat() (linux.Statx, error) {
+	return testStat(fd.data), nil
+}
+
+// Open implements lisafs.ControlFDImpl.Open.
+func (fd *testControlFD) Open(flags uint32) (*lisafs.OpenFD, int, error) {
+	openFD := &testOpenFD{data: fd.data}
+	openFD.OpenFD.Init(&fd.ControlFD, flags, openFD)
+	return &openFD.OpenFD, -1, nil
+}
+
+// testOpenFD implements lisafs.OpenFDImpl for the file served by testServer.
+// Methods that the tests don't use are left unimplemented.
+type testOpenFD struct {
+	lisafs.OpenFD
+	lisafs.OpenFDImpl
+
+	data []byte
+}
+
+// FD implements lisafs.OpenFDImpl.FD.
+func (fd *testOpenFD) FD() *lisafs.OpenFD {
+	return &fd.OpenFD
+}
+
+// Close implements lisafs.OpenFDImpl.Close.
+func (fd *testOpenFD) Close() {}
+
+// Stat implements lisafs.OpenFDImpl.Stat.
+func (fd *testOpenFD) Stat() (linux.Statx, error) {
+	return testStat(fd.data), nil
+}
+
+// Read implements lisafs.OpenFDImpl.Read.
+func (fd *testOpenFD) Read(buf []byte, off uint64) (uint64, error) {
+	if off >= uint64(len(fd.data)) {
+		return 0, nil
+	}
+	return uint64(copy(buf, fd.data[off:])), nil
+}
+
+func testStat(data []byte) linux.Statx {
+	return linux.Statx{
+		Mask:    linux.STATX_BASIC_STATS,
+		Mode:    uint16(linux.ModeRegular | 0644),
+		Ino:     1,
+		Nlink:   1,
+		Size:    uint64(len(data)),
+		Blksize: hostarch.PageSize,
+	}
+}
+
+// newPrefetchTest returns a dentry for a regular file with the given contents,
+// served over LISAFS, and a mount that it can be opened on.
+func newPrefetchTest(t *testing.T, data []byte) (context.Context, *dentry, *vfs.Mount) {
+	t.Helper()
+	ctx := contexttest.Context(t)
+
+	serverSock, clientSock, err := unet.SocketPair(false)
+	if err != nil {
+		t.Fatalf("socketpair failed: %v", err)
+	}
+	server := &testServer{data: data}
+	server.Server.Init(server, lisafs.ServerOpts{})
+	conn, err := server.CreateConnection(serverSock, "/", false /* readonly */)
+	if err != nil {
+		t.Fatalf("CreateConnection failed: %v", err)
+	}
+	server.StartConnection(conn)
+	client, root, _, err := lisafs.NewClient(clientSock)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Wait()
+	})
+
+	// Prefetched contents may only be cached if they can be evicted, which
+	// the tests do explicitly.
+	memfd, err := memutil.CreateMemFD("gofer-test", 0)
+	if err != nil {
+		t.Fatalf("CreateMemFD failed: %v", err)
+	}
+	mf, err := pgalloc.NewMemoryFile(os.NewFile(uintptr(memfd), "gofer-test"), pgalloc.MemoryFileOpts{
+		DelayedEviction: pgalloc.DelayedEvictionManual,
+	})
+	if err != nil {
+		t.Fatalf("NewMemoryFile failed: %v", err)
+	}
+	t.Cleanup(mf.Destroy)
+
+	vfsObj := &vfs.VirtualFilesystem{}
+	if err := vfsObj.Init(ctx); err != nil {
+		t.Fatalf("VFS init: %v", err)
+	}
+	fs := &filesystem{
+		mf:          mf,
+		inoByKey:    make(map[inoKey]uint64),
+		clock:       time.RealtimeClockFromContext(ctx),
+		dentryCache: &dentryCache{maxCachedDentries: 0},
+		client:      client,
+	}
+	fs.vfsfs.Init(vfsObj, &FilesystemType{}, fs)
+	mnt := vfsObj.NewDisconnectedMount(&fs.vfsfs, nil, &vfs.MountOptions{})
+
+	d, err := fs.newLisafsDentry(ctx, &root)
+	if err != nil {
+		t.Fatalf("newLisafsDentry failed: %v", err)
+	}
+	return ctx, d, mnt
+}
+
+// openForRead opens d read-only as filesystem.openLocked does, and returns
+// whether d's contents were prefetched.
+func openForRead(ctx context.Context, t *testing.T, mnt *vfs.Mount, d *dentry) (*regularFileFD, bool) {
+	t.Helper()
+	d.fs.renameMu.RLock()
+	prefetched := d.impl.(*lisafsDentry).prefetch(ctx)
+	if !prefetched {
+		if err := d.ensureSharedHandle(ctx, true /* read */, false /* write */, false /* trunc */); err != nil {
+			d.fs.renameMu.RUnlock()
+			t.Fatalf("ensureSharedHandle failed: %v", err)
+		}
+	}
+	d.fs.renameMu.RUnlock()
+	fd, err := newRegularFileFD(mnt, d, linux.O_RDONLY)
+	if err != nil {
+		t.Fatalf("newRegularFileFD failed: %v", err)
+	}
+	return fd, prefetched
+}
+
+func hasReadHandle(d *dentry) bool {
+	d.handleMu.RLock()
+	defer d.handleMu.RUnlock()
+	return d.isReadHandleOk()
+}
+
+func cacheIsEmpty(d *dentry) bool {
+	d.dataMu.RLock()
+	defer d.dataMu.RUnlock()
+	return d.cache.IsEmpty()
+}
+
+// evict evicts all of d's cached contents that aren't memory-mapped.
+func evict(t *testing.T, d *dentry) {
+	t.Helper()
+	d.fs.mf.StartEvictions()
+	d.fs.mf.WaitForEvictions()
+	if !cacheIsEmpty(d) {
+		t.Fatalf("file contents still cached after eviction")
+	}
+}
+
+func checkRead(ctx context.Context, t *testing.T, fd *regularFileFD, want []byte) {
+	t.Helper()
+	buf := make([]byte, len(want))
+	n, err := fd.PRead(ctx, usermem.BytesIOSequence(buf), 0, vfs.ReadOptions{})
+	if err != nil {
+		t.Fatalf("PRead failed: %v", err)
+	}
+	if n != int64(len(want)) || !bytes.Equal(buf, want) {
+		t.Errorf("PRead got %d bytes %v, want %v", n, buf[:n], want)
+	}
+}
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return data
+}
+
+func TestPrefetchSkipsReadHandle(t *testing.T) {
+	data := testData(3 * hostarch.PageSize / 2)
+	ctx, d, mnt := newPrefetchTest(t, data)
+
+	fd, prefetched := openForRead(ctx, t, mnt, d)
+	if !prefetched {
+		t.Fatalf("file of %d bytes not prefetched", len(data))
+	}
+	if hasReadHandle(d) {
+		t.Errorf("read handle opened for prefetched file")
+	}
+	checkRead(ctx, t, fd, data)
+	if hasReadHandle(d) {
+		t.Errorf("read handle opened to read prefetched file")
+	}
+
+	// Opening the file again should reuse its cached contents.
+	if _, prefetched := openForRead(ctx, t, mnt, d); !prefetched {
+		t.Errorf("file not prefetched when reopened")
+	}
+	if hasReadHandle(d) {
+		t.Errorf("read handle opened when reopening prefetched file")
+	}
+}
+
+func TestPrefetchReadAfterEviction(t *testing.T) {
+	data := testData(3 * hostarch.PageSize / 2)
+	ctx, d, mnt := newPrefetchTest(t, data)
+
+	fd, prefetched := openForRead(ctx, t, mnt, d)
+	if !prefetched {
+		t.Fatalf("file of %d bytes not prefetched", len(data))
+	}
+	evict(t, d)
+	checkRead(ctx, t, fd, data)
+	if !hasReadHandle(d) {
+		t.Errorf("no read handle after reading evicted file")
+	}
+}
+
+func TestPrefetchMMap(t *testing.T) {
+	data := testData(3 * hostarch.PageSize / 2)
+	ctx, d, mnt := newPrefetchTest(t, data)
+
+	fd, prefetched := openForRead(ctx, t, mnt, d)
+	if !prefetched {
+		t.Fatalf("file of %d bytes not prefetched", len(data))
+	}
+	end, _ := hostarch.PageRoundUp(uint64(len(data)))
+	opts := memmap.MMapOpts{
+		Length:   end,
+		Private:  true,
+		Perms:    hostarch.Read,
+		MaxPerms: hostarch.Read,
+	}
+	if err := fd.ConfigureMMap(ctx, &opts); err != nil {
+		t.Fatalf("ConfigureMMap failed: %v", err)
+	}
+	defer opts.MappingIdentity.DecRef(ctx)
+	if !hasReadHandle(d) {
+		t.Fatalf("no read handle after ConfigureMMap")
+	}
+
+	// Translations must be able to fill the page cache after the prefetched
+	// contents are evicted.
+	evict(t, d)
+	mr := memmap.MappableRange{0, end}
+	ts, err := d.Translate(ctx, mr, mr, hostarch.Read)
+	if err != nil {
+		t.Fatalf("Translate failed: %v", err)
+	}
+	got := make([]byte, 0, end)
+	for _, tr := range ts {
+		ims, err := tr.File.MapInternal(memmap.FileRange{tr.Offset, tr.Offset + tr.Source.Length()}, hostarch.Read)
+		if err != nil {
+			t.Fatalf("MapInternal failed: %v", err)
+		}
+		buf := make([]byte, ims.NumBytes())
+		if _, err := safemem.CopySeq(safemem.BlockSeqOf(safemem.BlockFromSafeSlice(buf)), ims); err != nil {
+			t.Fatalf("CopySeq failed: %v", err)
+		}
+		got = append(got, buf...)
+	}
+	if len(got) < len(data) || !bytes.Equal(got[:len(data)], data) {
+		t.Errorf("mapped contents %v, want %v", got, data)
+	}
+}
+
+func TestPrefetchEmptyFile(t *testing.T) {
+	ctx, d, mnt := newPrefetchTest(t, nil)
+
+	fd, prefetched := openForRead(ctx, t, mnt, d)
+	if !prefetched {
+		t.Fatalf("empty file not prefetched")
+	}
+	if hasReadHandle(d) {
+		t.Errorf("read handle opened for empty file")
+	}
+	if !cacheIsEmpty(d) {
+		t.Errorf("contents cached for empty file")
+	}
+	buf := make([]byte, 1)
+	if n, err := fd.PRead(ctx, usermem.BytesIOSequence(buf), 0, vfs.ReadOptions{}); n != 0 || err != io.EOF {
+		t.Errorf("PRead got (%d, %v), want (0, EOF)", n, err)
+	}
+}
+
+func TestPrefetchLargeFile(t *testing.T) {
+	data := testData(maxPrefetchSize + 1)
+	ctx, d, mnt := newPrefetchTest(t, data)
+
+	fd, prefetched := openForRead(ctx, t, mnt, d)
+	if prefetched {
+		t.Fatalf("file of %d bytes prefetched, want at most %d bytes", len(data), maxPrefetchSize)
+	}
+	if !hasReadHandle(d) {
+		t.Errorf("no read handle for file that wasn't prefetched")
+	}
+	if !cacheIsEmpty(d) {
+		t.Errorf("contents cached before reading file that wasn't prefetched")
+	}
+	checkRead(ctx, t, fd, data)
+}
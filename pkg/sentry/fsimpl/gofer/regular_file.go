@@ -15,6 +15,7 @@
 package gofer
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -105,6 +106,26 @@ func (fd *regularFileFD) Allocate(ctx context.Context, mode, offset, length uint
 	})
 }
 
+// errNoReadHandle is returned by dentryReadWriter.ReadToBlocks when it needs
+// to read from the remote file, but the dentry has no read handle since its
+// contents were prefetched (see lisafsDentry.prefetch) and have since been
+// evicted.
+var errNoReadHandle = errors.New("no read handle")
+
+// ensureReadHandle ensures that d has a read handle, which may not be the case
+// for regular files whose contents were prefetched.
+func (d *dentry) ensureReadHandle(ctx context.Context) error {
+	d.handleMu.RLock()
+	ok := d.isReadHandleOk()
+	d.handleMu.RUnlock()
+	if ok {
+		return nil
+	}
+	d.fs.renameMu.RLock()
+	defer d.fs.renameMu.RUnlock()
+	return d.ensureSharedHandle(ctx, true /* read */, false /* write */, false /* trunc */)
+}
+
 // PRead implements vfs.FileDescriptionImpl.PRead.
 func (fd *regularFileFD) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
 	start := fsmetric.StartReadWait()
@@ -159,6 +180,17 @@ func (fd *regularFileFD) PRead(ctx context.Context, dst usermem.IOSequence, offs
 		rw := getDentryReadWriter(ctx, d, offset)
 		n, readErr = dst.CopyOutFrom(ctx, rw)
 		putDentryReadWriter(rw)
+		if readErr == errNoReadHandle {
+			// dentryReadWriter.ReadToBlocks can't open a read handle itself,
+			// since it may be called with memory manager locks held.
+			if readErr = d.ensureReadHandle(ctx); readErr == nil {
+				var m int64
+				rw := getDentryReadWriter(ctx, d, offset+n)
+				m, readErr = dst.DropFirst64(n).CopyOutFrom(ctx, rw)
+				putDentryReadWriter(rw)
+				n += m
+			}
+		}
 		if d.fs.opts.interop != InteropModeShared {
 			// Compare Linux's mm/filemap.c:do_generic_file_read() => file_accessed().
 			d.touchAtime(fd.vfsfd.Mount())
@@ -197,6 +229,10 @@ func (fd *regularFileFD) pwrite(ctx context.Context, src usermem.IOSequence, off
 	}
 
 	d := fd.dentry()
+	if err := d.fs.vfsfs.StartWrite(ctx); err != nil {
+		return 0, offset, err
+	}
+	defer d.fs.vfsfs.EndWrite()
 
 	d.metadataMu.Lock()
 	defer d.metadataMu.Unlock()
@@ -425,6 +461,11 @@ func (rw *dentryReadWriter) ReadToBlocks(dsts safemem.BlockSeq) (uint64, error)
 			seg, gap = seg.NextNonEmpty()
 
 		case gap.Ok():
+			if !h.isOpen() {
+				dataMuUnlock()
+				rw.d.handleMu.RUnlock()
+				return done, errNoReadHandle
+			}
 			gapMR := gap.Range().Intersect(mr)
 			if fillCache {
 				// Read into the cache, then re-enter the loop to read from the
@@ -671,6 +712,12 @@ func (fd *regularFileFD) Sync(ctx context.Context) error {
 // ConfigureMMap implements vfs.FileDescriptionImpl.ConfigureMMap.
 func (fd *regularFileFD) ConfigureMMap(ctx context.Context, opts *memmap.MMapOpts) error {
 	d := fd.dentry()
+	// d.Translate requires a read handle to fill the page cache.
+	if fd.vfsfd.IsReadable() {
+		if err := d.ensureReadHandle(ctx); err != nil {
+			return err
+		}
+	}
 	// Force sentry page caching at your own risk.
 	if !d.fs.opts.forcePageCache {
 		switch d.fs.opts.interop {
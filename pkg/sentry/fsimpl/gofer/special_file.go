@@ -300,6 +300,11 @@ func (fd *specialFileFD) pwrite(ctx context.Context, src usermem.IOSequence, off
 
 	d := fd.dentry()
 	if fd.isRegularFile {
+		if err := d.fs.vfsfs.StartWrite(ctx); err != nil {
+			return 0, offset, err
+		}
+		defer d.fs.vfsfs.EndWrite()
+
 		// If the regular file fd was opened with O_APPEND, make sure the file
 		// size is updated. There is a possible race here if size is modified
 		// externally after metadata cache is updated.
@@ -36,12 +36,17 @@ func (d *dentry) touchAtime(mnt *vfs.Mount) {
 	if err := mnt.CheckBeginWrite(); err != nil {
 		return
 	}
+	defer mnt.EndWrite()
+	// Like Linux, don't update atime on frozen filesystems.
+	if !d.fs.vfsfs.TryStartWrite() {
+		return
+	}
+	defer d.fs.vfsfs.EndWrite()
 	now := d.fs.clock.Now().Nanoseconds()
 	d.metadataMu.Lock()
 	d.atime.Store(now)
 	d.atimeDirty.Store(1)
 	d.metadataMu.Unlock()
-	mnt.EndWrite()
 }
 
 // Preconditions: d.metadataMu is locked. d.cachedMetadataAuthoritative() == true.
@@ -52,10 +57,14 @@ func (d *dentry) touchAtimeLocked(mnt *vfs.Mount) {
 	if err := mnt.CheckBeginWrite(); err != nil {
 		return
 	}
+	defer mnt.EndWrite()
+	if !d.fs.vfsfs.TryStartWrite() {
+		return
+	}
+	defer d.fs.vfsfs.EndWrite()
 	now := d.fs.clock.Now().Nanoseconds()
 	d.atime.Store(now)
 	d.atimeDirty.Store(1)
-	mnt.EndWrite()
 }
 
 // Preconditions:
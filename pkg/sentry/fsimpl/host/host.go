@@ -117,6 +117,10 @@ type inode struct {
 	// restoreKey is used to identify the `hostFD` after a restore is performed.
 	restoreKey vfs.RestoreID
 
+	// fdInfo describes hostFD as of the last save. It is passed to the
+	// FDResolver if no host FD is available for restoreKey on restore.
+	fdInfo FDInfo
+
 	// ino is an inode number unique within this filesystem.
 	//
 	// This field is initialized at creation time and is immutable.
@@ -155,6 +159,12 @@ type inode struct {
 	// This field is initialized at creation time and is immutable.
 	readonly bool
 
+	// isMemfd is true if hostFD is a memfd created by NewMemfd. Only such
+	// files may be passed to host processes.
+	//
+	// This field is initialized at creation time and is immutable.
+	isMemfd bool
+
 	// Event queue for blocking operations.
 	queue waiter.Queue
 
@@ -237,6 +247,9 @@ type NewFDOptions struct {
 	// If Readonly is true, we disallow operations that can potentially change
 	// the host file associated with the file descriptor.
 	Readonly bool
+
+	// isMemfd is true if hostFD was created by NewMemfd.
+	isMemfd bool
 }
 
 // NewFD returns a vfs.FileDescription representing the given host file
@@ -285,6 +298,7 @@ func NewFD(ctx context.Context, mnt *vfs.Mount, hostFD int, opts *NewFDOptions)
 	if err != nil {
 		return nil, err
 	}
+	i.isMemfd = opts.isMemfd
 	if opts.VirtualOwner {
 		i.virtualOwner.enabled = true
 		i.virtualOwner.uid = atomicbitops.FromUint32(uint32(opts.UID))
@@ -304,6 +318,33 @@ func NewFD(ctx context.Context, mnt *vfs.Mount, hostFD int, opts *NewFDOptions)
 	return i.open(ctx, d, mnt, fileType, flags)
 }
 
+// NewMemfd creates a memfd on the host and returns a vfs.FileDescription
+// representing it, as for memfd_create(2). Unlike tmpfs memfds, host memfds
+// can be passed to host processes, e.g. over host Unix sockets, while keeping
+// their contents shared. mnt must be Kernel.HostMount().
+//
+// File seals are not supported on host memfds.
+func NewMemfd(ctx context.Context, mnt *vfs.Mount, name string) (*vfs.FileDescription, error) {
+	hostFD, err := unix.MemfdCreate(name, unix.MFD_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	creds := auth.CredentialsFromContext(ctx)
+	fd, err := NewFD(ctx, mnt, hostFD, &NewFDOptions{
+		HaveFlags:    true,
+		Flags:        linux.O_RDWR,
+		VirtualOwner: true,
+		UID:          creds.EffectiveKUID,
+		GID:          creds.EffectiveKGID,
+		isMemfd:      true,
+	})
+	if err != nil {
+		unix.Close(hostFD)
+		return nil, err
+	}
+	return fd, nil
+}
+
 // filesystemType implements vfs.FilesystemType.
 //
 // +stateify savable
@@ -736,6 +777,17 @@ type fileDescription struct {
 	offset int64
 }
 
+// HostMemfd returns the host memfd backing f. The returned file descriptor
+// remains owned by f. It returns EINVAL if f was not created by NewMemfd, so
+// that other host files, e.g. stdio, TTYs and sockets, never leave the
+// sandbox.
+func (f *fileDescription) HostMemfd() (int, error) {
+	if !f.inode.isMemfd {
+		return -1, linuxerr.EINVAL
+	}
+	return f.inode.hostFD, nil
+}
+
 // SetStat implements vfs.FileDescriptionImpl.SetStat.
 func (f *fileDescription) SetStat(ctx context.Context, opts vfs.SetStatOptions) error {
 	creds := auth.CredentialsFromContext(ctx)
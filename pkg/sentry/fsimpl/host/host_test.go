@@ -0,0 +1,131 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/contexttest"
+	"gvisor.dev/gvisor/pkg/sentry/socket/control"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// newTestMount returns a host filesystem mount, as returned by
+// Kernel.HostMount().
+func newTestMount(ctx context.Context, t *testing.T) *vfs.Mount {
+	t.Helper()
+	vfsObj := &vfs.VirtualFilesystem{}
+	if err := vfsObj.Init(ctx); err != nil {
+		t.Fatalf("VFS init: %v", err)
+	}
+	fs, err := NewFilesystem(vfsObj)
+	if err != nil {
+		t.Fatalf("NewFilesystem failed: %v", err)
+	}
+	t.Cleanup(func() { fs.DecRef(ctx) })
+	mnt := vfsObj.NewDisconnectedMount(fs, nil, &vfs.MountOptions{})
+	t.Cleanup(func() { mnt.DecRef(ctx) })
+	return mnt
+}
+
+func TestHostFDsMemfd(t *testing.T) {
+	ctx := contexttest.Context(t)
+	mnt := newTestMount(ctx, t)
+
+	fd, err := NewMemfd(ctx, mnt, "test")
+	if err != nil {
+		t.Fatalf("NewMemfd failed: %v", err)
+	}
+	rights := control.RightsFiles{fd}
+	defer rights.Release(ctx)
+
+	fds, err := rights.HostFDs()
+	if err != nil {
+		t.Fatalf("HostFDs() failed: %v", err)
+	}
+	if len(fds) != 1 {
+		t.Fatalf("HostFDs() got %d FDs, want 1", len(fds))
+	}
+	var stat unix.Stat_t
+	if err := unix.Fstat(fds[0], &stat); err != nil {
+		t.Fatalf("fstat(%d) failed: %v", fds[0], err)
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFREG {
+		t.Errorf("HostFDs() returned FD with mode %#o, want regular file", stat.Mode)
+	}
+}
+
+func TestHostFDsNonMemfd(t *testing.T) {
+	ctx := contexttest.Context(t)
+	mnt := newTestMount(ctx, t)
+
+	for _, tc := range []struct {
+		name   string
+		hostFD func(t *testing.T) int
+	}{
+		{
+			name: "memfd",
+			hostFD: func(t *testing.T) int {
+				// A memfd imported from the host rather than created by
+				// NewMemfd, e.g. one donated to the sandbox.
+				fd, err := unix.MemfdCreate("test", unix.MFD_CLOEXEC)
+				if err != nil {
+					t.Fatalf("memfd_create failed: %v", err)
+				}
+				return fd
+			},
+		},
+		{
+			name: "pipe",
+			hostFD: func(t *testing.T) int {
+				var fds [2]int
+				if err := unix.Pipe2(fds[:], unix.O_CLOEXEC); err != nil {
+					t.Fatalf("pipe2 failed: %v", err)
+				}
+				unix.Close(fds[1])
+				return fds[0]
+			},
+		},
+		{
+			name: "socket",
+			hostFD: func(t *testing.T) int {
+				fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+				if err != nil {
+					t.Fatalf("socketpair failed: %v", err)
+				}
+				unix.Close(fds[1])
+				return fds[0]
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hostFD := tc.hostFD(t)
+			fd, err := NewFD(ctx, mnt, hostFD, &NewFDOptions{})
+			if err != nil {
+				unix.Close(hostFD)
+				t.Fatalf("NewFD failed: %v", err)
+			}
+			rights := control.RightsFiles{fd}
+			defer rights.Release(ctx)
+
+			if fds, err := rights.HostFDs(); !linuxerr.Equals(linuxerr.EINVAL, err) {
+				t.Errorf("HostFDs() got (%v, %v), want EINVAL", fds, err)
+			}
+		})
+	}
+}
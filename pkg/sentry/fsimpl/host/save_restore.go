@@ -38,11 +38,74 @@ func MakeRestoreID(containerName string, fd int) vfs.RestoreID {
 	}
 }
 
+// FDInfo describes a host FD when it was saved, to help re-establish an
+// equivalent host FD on restore.
+//
+// +stateify savable
+type FDInfo struct {
+	// FileType is the type of the file (a linux.S_IFMT mask).
+	FileType uint16 `json:"file_type"`
+
+	// Flags are the file status flags of the host FD, as returned by
+	// fcntl(F_GETFL).
+	Flags uint32 `json:"flags"`
+
+	// SocketDomain and SocketType are the domain and type of the socket if
+	// FileType is S_IFSOCK.
+	SocketDomain int32 `json:"socket_domain,omitempty"`
+	SocketType   int32 `json:"socket_type,omitempty"`
+}
+
+// FDResolver re-establishes host FDs on restore.
+type FDResolver interface {
+	// ResolveFD returns a host FD equivalent to the one identified by id
+	// when it was saved, as described by info. Ownership of the returned FD
+	// is transferred to the caller.
+	ResolveFD(ctx context.Context, id vfs.RestoreID, info FDInfo) (int, error)
+}
+
+// contextID is this package's type for context.Context.Value keys.
+type contextID int
+
+const (
+	// CtxRestoreFDResolver is a Context.Value key for the FDResolver used on
+	// restore for host FDs that are missing from
+	// vfs.CtxRestoreFilesystemFDMap.
+	CtxRestoreFDResolver contextID = iota
+)
+
+// FDResolverFromContext returns the FDResolver used by ctx, or nil if ctx is
+// not associated with an FDResolver.
+func FDResolverFromContext(ctx context.Context) FDResolver {
+	if v := ctx.Value(CtxRestoreFDResolver); v != nil {
+		return v.(FDResolver)
+	}
+	return nil
+}
+
+// describeFD returns the FDInfo of hostFD, whose file type is ftype.
+func describeFD(hostFD int, ftype uint16) FDInfo {
+	info := FDInfo{FileType: ftype}
+	if flags, err := unix.FcntlInt(uintptr(hostFD), unix.F_GETFL, 0); err == nil {
+		info.Flags = uint32(flags)
+	}
+	if ftype == unix.S_IFSOCK {
+		if domain, err := unix.GetsockoptInt(hostFD, unix.SOL_SOCKET, unix.SO_DOMAIN); err == nil {
+			info.SocketDomain = int32(domain)
+		}
+		if stype, err := unix.GetsockoptInt(hostFD, unix.SOL_SOCKET, unix.SO_TYPE); err == nil {
+			info.SocketType = int32(stype)
+		}
+	}
+	return info
+}
+
 // beforeSave is invoked by stateify.
 func (i *inode) beforeSave() {
 	if !i.savable {
 		panic("host.inode is not savable")
 	}
+	i.fdInfo = describeFD(i.hostFD, i.ftype)
 	if i.ftype == unix.S_IFIFO {
 		// If this pipe FD is readable, drain it so that bytes in the pipe can
 		// be read after restore. (This is a legacy VFS1 feature.) We don't
@@ -74,7 +137,22 @@ func (i *inode) afterLoad(ctx context.Context) {
 	fdmap := vfs.RestoreFilesystemFDMapFromContext(ctx)
 	fd, ok := fdmap[i.restoreKey]
 	if !ok {
-		panic(fmt.Sprintf("no host FD available for %+v, map: %v", i.restoreKey, fdmap))
+		resolver := FDResolverFromContext(ctx)
+		if resolver == nil {
+			panic(fmt.Sprintf("no host FD available for %+v, map: %v", i.restoreKey, fdmap))
+		}
+		var err error
+		fd, err = resolver.ResolveFD(ctx, i.restoreKey, i.fdInfo)
+		if err != nil {
+			panic(fmt.Sprintf("host.inode.afterLoad: resolving host FD for %+v (%+v) failed: %v", i.restoreKey, i.fdInfo, err))
+		}
+		var stat unix.Stat_t
+		if err := unix.Fstat(fd, &stat); err != nil {
+			panic(fmt.Sprintf("host.inode.afterLoad: fstat(%d) failed: %v", fd, err))
+		}
+		if ftype := uint16(stat.Mode & unix.S_IFMT); ftype != i.ftype {
+			panic(fmt.Sprintf("host.inode.afterLoad: resolved host FD for %+v has file type %#o, want %#o", i.restoreKey, ftype, i.ftype))
+		}
 	}
 	i.hostFD = fd
 
@@ -23,6 +23,7 @@
 package iouringfs
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 
@@ -74,6 +75,7 @@ type FileDescription struct {
 }
 
 var _ vfs.FileDescriptionImpl = (*FileDescription)(nil)
+var _ vfs.FileDescriptionImplFDInfoExtension = (*FileDescription)(nil)
 
 func roundUpPowerOfTwo(n uint32) (uint32, bool) {
 	if n > (1 << 31) {
@@ -218,6 +220,40 @@ func (fd *FileDescription) Release(ctx context.Context) {
 	fd.mf.DecRef(fd.sqemf.fr)
 }
 
+// FDInfo implements vfs.FileDescriptionImplFDInfoExtension.FDInfo.
+func (fd *FileDescription) FDInfo(ctx context.Context, buf *bytes.Buffer) {
+	// Read the ring indexes from a separate mapping of the shared memory,
+	// since fd.ioRingsBuf may only be used while processing submissions.
+	var sqHead, sqTail, cqHead, cqTail uint32
+	rb, err := fd.mf.MapInternal(fd.rbmf.fr, hostarch.Read)
+	if err == nil {
+		view := make([]byte, fd.ioRings.SizeBytes())
+		if _, err := safemem.CopySeq(safemem.BlockSeqOf(safemem.BlockFromSafeSlice(view)), rb); err == nil {
+			sqOff := linux.PreComputedIOSqRingOffsets()
+			cqOff := linux.PreComputedIOCqRingOffsets()
+			sqHead = hostarch.ByteOrder.Uint32(view[sqOff.Head:])
+			sqTail = hostarch.ByteOrder.Uint32(view[sqOff.Tail:])
+			cqHead = hostarch.ByteOrder.Uint32(view[cqOff.Head:])
+			cqTail = hostarch.ByteOrder.Uint32(view[cqOff.Tail:])
+		}
+	}
+
+	// Linux: io_uring/fdinfo.c:io_uring_show_fdinfo(). The submission queue
+	// head isn't cached, and SQPOLL isn't supported.
+	fmt.Fprintf(buf, "SqMask:\t0x%x\n", fd.ioRings.SqRingMask)
+	fmt.Fprintf(buf, "SqHead:\t%d\n", sqHead)
+	fmt.Fprintf(buf, "SqTail:\t%d\n", sqTail)
+	fmt.Fprintf(buf, "CachedSqHead:\t%d\n", sqHead)
+	fmt.Fprintf(buf, "CqMask:\t0x%x\n", fd.ioRings.CqRingMask)
+	fmt.Fprintf(buf, "CqHead:\t%d\n", cqHead)
+	fmt.Fprintf(buf, "CqTail:\t%d\n", cqTail)
+	fmt.Fprintf(buf, "CachedCqTail:\t%d\n", cqTail)
+	fmt.Fprintf(buf, "SQEs:\t%d\n", sqTail-sqHead)
+	fmt.Fprintf(buf, "CQEs:\t%d\n", cqTail-cqHead)
+	buf.WriteString("SqThread:\t-1\n")
+	buf.WriteString("SqThreadCpu:\t-1\n")
+}
+
 // mapSharedBuffers caches internal mappings for the ring's shared memory
 // regions.
 func (fd *FileDescription) mapSharedBuffers() error {
@@ -510,6 +546,10 @@ func (fd *FileDescription) handleReadv(t *kernel.Task, sqe *linux.IOUringSqe, fl
 		return 0, linuxerr.EBADF
 	}
 	defer file.DecRef(t)
+	// Translate the iovecs once, as Linux does by pinning user pages for
+	// io_uring requests.
+	dst, unpin := t.MemoryManager().PinIOSequence(t, dst, hostarch.Write)
+	defer unpin()
 	n, err := file.PRead(t, dst, 0, vfs.ReadOptions{})
 	if err != nil {
 		return 0, err
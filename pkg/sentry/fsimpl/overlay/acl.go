@@ -0,0 +1,132 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package overlay
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// getAccessACL returns the cached POSIX access ACL of d, or nil if d has none.
+func (d *dentry) getAccessACL() *vfs.ACL {
+	if !d.hasAccessACL.Load() {
+		return nil
+	}
+	d.aclMu.Lock()
+	defer d.aclMu.Unlock()
+	return d.accessACL
+}
+
+// setAccessACL sets the cached POSIX access ACL of d.
+func (d *dentry) setAccessACL(acl *vfs.ACL) {
+	d.aclMu.Lock()
+	defer d.aclMu.Unlock()
+	d.accessACL = acl
+	d.hasAccessACL.Store(acl != nil)
+}
+
+// updateACL caches the POSIX access ACL of d's upper layer file, which is used
+// for permission checks on d. ACLs of files that exist only on lower layers
+// are not enforced by the overlay, but are copied up along with other
+// extended attributes.
+//
+// Preconditions: d.upperVD.Ok().
+func (d *dentry) updateACL(ctx context.Context) {
+	vfsObj := d.fs.vfsfs.VirtualFilesystem()
+	value, err := vfsObj.GetXattrAt(ctx, d.fs.creds, &vfs.PathOperation{
+		Root:  d.upperVD,
+		Start: d.upperVD,
+	}, &vfs.GetXattrOptions{Name: linux.XATTR_NAME_POSIX_ACL_ACCESS})
+	var acl *vfs.ACL
+	if err == nil {
+		acl, err = vfs.ParseACL(d.fs.creds.UserNamespace, value)
+	}
+	if err != nil && !linuxerr.Equals(linuxerr.ENODATA, err) && !linuxerr.Equals(linuxerr.EOPNOTSUPP, err) {
+		ctx.Warningf("overlay.dentry.updateACL: failed to get upper layer ACL: %v", err)
+	}
+	d.setAccessACL(acl)
+}
+
+// aclChanged is called after the extended attribute with the given name is
+// changed on d's upper layer file. Setting a POSIX access ACL may also change
+// the file's mode.
+//
+// Preconditions: d.upperVD.Ok().
+func (d *dentry) aclChanged(ctx context.Context, name string) {
+	if name != linux.XATTR_NAME_POSIX_ACL_ACCESS {
+		return
+	}
+	d.copyMu.Lock()
+	defer d.copyMu.Unlock()
+	stat, err := d.fs.vfsfs.VirtualFilesystem().StatAt(ctx, d.fs.creds, &vfs.PathOperation{
+		Root:  d.upperVD,
+		Start: d.upperVD,
+	}, &vfs.StatOptions{Mask: linux.STATX_MODE})
+	if err != nil {
+		ctx.Warningf("overlay.dentry.aclChanged: failed to stat upper layer file: %v", err)
+	} else {
+		d.mode.Store((d.mode.RacyLoad() & linux.S_IFMT) | uint32(stat.Mode&^linux.S_IFMT))
+	}
+	d.updateACL(ctx)
+}
+
+// chmodACL updates the cached POSIX access ACL of d to reflect a change of d's
+// mode to mode, as performed by the upper layer filesystem.
+func (d *dentry) chmodACL(mode linux.FileMode) {
+	if !d.hasAccessACL.Load() {
+		return
+	}
+	d.aclMu.Lock()
+	defer d.aclMu.Unlock()
+	if d.accessACL != nil {
+		d.accessACL = d.accessACL.Chmod(mode)
+	}
+}
+
+// clearInheritedACLsLocked removes POSIX ACLs that d's upper layer file,
+// which has just been created by copy-up, inherited from the default ACL of
+// its parent directory, and restores its mode, such that the copied-up file
+// only has the ACLs of the lower layer file.
+//
+// Preconditions:
+//   - d.copyMu must be locked for writing.
+//   - d.upperVD.Ok().
+//   - parent.upperVD.Ok().
+func (d *dentry) clearInheritedACLsLocked(ctx context.Context, parent *dentry) error {
+	vfsObj := d.fs.vfsfs.VirtualFilesystem()
+	if _, err := vfsObj.GetXattrAt(ctx, d.fs.creds, &vfs.PathOperation{
+		Root:  parent.upperVD,
+		Start: parent.upperVD,
+	}, &vfs.GetXattrOptions{Name: linux.XATTR_NAME_POSIX_ACL_DEFAULT}); err != nil {
+		// The parent directory has no default ACL (ENODATA) or the upper
+		// layer doesn't support ACLs (EOPNOTSUPP).
+		return nil
+	}
+	upperPop := &vfs.PathOperation{Root: d.upperVD, Start: d.upperVD}
+	for _, name := range []string{linux.XATTR_NAME_POSIX_ACL_ACCESS, linux.XATTR_NAME_POSIX_ACL_DEFAULT} {
+		if err := vfsObj.RemoveXattrAt(ctx, d.fs.creds, upperPop, name); err != nil && !linuxerr.Equals(linuxerr.ENODATA, err) {
+			return err
+		}
+	}
+	return vfsObj.SetStatAt(ctx, d.fs.creds, upperPop, &vfs.SetStatOptions{
+		Stat: linux.Statx{
+			Mask: linux.STATX_MODE,
+			// d.mode can be read because d.copyMu is locked.
+			Mode: uint16(d.mode.RacyLoad() &^ linux.S_IFMT),
+		},
+	})
+}
@@ -16,15 +16,20 @@ package overlay
 
 import (
 	"fmt"
+	"io"
+	"math"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/bitmap"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
 )
 
 func (d *dentry) isCopiedUp() bool {
@@ -93,7 +98,7 @@ func (d *dentry) copyUpMaybeSyntheticMountpointLocked(ctx context.Context, forSy
 	}
 	const timestampsMask = linux.STATX_ATIME | linux.STATX_MTIME
 	oldStat, err := vfsObj.StatAt(ctx, d.fs.creds, &oldpop, &vfs.StatOptions{
-		Mask: timestampsMask,
+		Mask: timestampsMask | linux.STATX_SIZE,
 	})
 	if err != nil {
 		return err
@@ -108,6 +113,8 @@ func (d *dentry) copyUpMaybeSyntheticMountpointLocked(ctx context.Context, forSy
 	}
 	// Used during copy-up of memory-mapped regular files.
 	var mmapOpts *memmap.MMapOpts
+	// Used during copy-up of regular files whose data is copied up lazily.
+	var pending *pendingCopyUp
 	cleanupUndoCopyUp := func() {
 		var err error
 		if ftype == linux.S_IFDIR {
@@ -141,9 +148,29 @@ func (d *dentry) copyUpMaybeSyntheticMountpointLocked(ctx context.Context, forSy
 			return err
 		}
 		defer newFD.DecRef(ctx)
-		if _, err := vfs.CopyRegularFileData(ctx, newFD, oldFD); err != nil {
-			cleanupUndoCopyUp()
-			return err
+		stat := linux.Statx{
+			Mask: linux.STATX_UID | linux.STATX_GID | oldStat.Mask&timestampsMask,
+			// d.uid and d.gid can be read because d.copyMu is locked.
+			UID:   d.uid.RacyLoad(),
+			GID:   d.gid.RacyLoad(),
+			Atime: oldStat.Atime,
+			Mtime: oldStat.Mtime,
+		}
+		if d.wrappedMappable == nil && oldStat.Mask&linux.STATX_SIZE != 0 {
+			// Copy up the file's data lazily; see pendingCopyUp.
+			if oldStat.Size != 0 {
+				pending = newPendingCopyUp(oldFD, newFD, oldStat.Size)
+				stat.Mask |= linux.STATX_SIZE
+				stat.Size = oldStat.Size
+			}
+		} else {
+			// We may have memory mappings of the file on the lower layer,
+			// which will be switched to the file on the upper layer below,
+			// so its data must be copied up now.
+			if _, err := vfs.CopyRegularFileData(ctx, newFD, oldFD); err != nil {
+				cleanupUndoCopyUp()
+				return err
+			}
 		}
 		if d.wrappedMappable != nil {
 			// We may have memory mappings of the file on the lower layer.
@@ -163,14 +190,7 @@ func (d *dentry) copyUpMaybeSyntheticMountpointLocked(ctx context.Context, forSy
 			// below for why.
 		}
 		if err := newFD.SetStat(ctx, vfs.SetStatOptions{
-			Stat: linux.Statx{
-				Mask: linux.STATX_UID | linux.STATX_GID | oldStat.Mask&timestampsMask,
-				// d.uid and d.gid can be read because d.copyMu is locked.
-				UID:   d.uid.RacyLoad(),
-				GID:   d.gid.RacyLoad(),
-				Atime: oldStat.Atime,
-				Mtime: oldStat.Mtime,
-			},
+			Stat: stat,
 		}); err != nil {
 			cleanupUndoCopyUp()
 			return err
@@ -269,10 +289,17 @@ func (d *dentry) copyUpMaybeSyntheticMountpointLocked(ctx context.Context, forSy
 		panic(fmt.Sprintf("unexpected file type %o", ftype))
 	}
 
+	if ftype != linux.S_IFLNK {
+		if err := d.clearInheritedACLsLocked(ctx, parent); err != nil {
+			cleanupUndoCopyUp()
+			return err
+		}
+	}
 	if err := d.copyXattrsLocked(ctx); err != nil {
 		cleanupUndoCopyUp()
 		return err
 	}
+	d.updateACL(ctx)
 
 	// Update the dentry's device and inode numbers (except for directories,
 	// for which these remain overlay-assigned).
@@ -359,6 +386,16 @@ func (d *dentry) copyUpMaybeSyntheticMountpointLocked(ctx context.Context, forSy
 		d.lowerMappings.RemoveAll()
 	}
 
+	if pending != nil {
+		// pending must be visible to anything that observes that d is
+		// copied-up.
+		pending.lowerFD.IncRef()
+		pending.upperFD.IncRef()
+		d.pendingMu.Lock()
+		d.pendingData = pending
+		d.hasPendingData.Store(true)
+		d.pendingMu.Unlock()
+	}
 	d.copiedUp.Store(1)
 	return nil
 }
@@ -395,6 +432,11 @@ func (d *dentry) copyXattrsLocked(ctx context.Context) error {
 		}
 
 		if err := vfsObj.SetXattrAt(ctx, d.fs.creds, upperPop, &vfs.SetXattrOptions{Name: name, Value: value}); err != nil {
+			if vfs.IsACLXattr(name) && linuxerr.Equals(linuxerr.EOPNOTSUPP, err) {
+				// The upper layer doesn't support ACLs; the lower layer
+				// file's mode still approximates its ACL.
+				continue
+			}
 			ctx.Infof("failed to copy up xattrs because SetXattrAt failed: %v", err)
 			return err
 		}
@@ -435,3 +477,273 @@ func (d *dentry) copyUpDescendantsLocked(ctx context.Context, ds **[]*dentry) er
 	}
 	return nil
 }
+
+// copyUpChunkSize is the granularity at which the data of regular files is
+// copied up.
+const copyUpChunkSize = 1 << 20
+
+// pendingCopyUp tracks the data of a copied-up regular file that has not yet
+// been copied from the lower layer to the upper layer.
+//
+// Unless the file is memory-mapped, copy-up of a regular file only creates
+// the file on the upper layer with the lower layer file's metadata and size;
+// its data is copied up lazily, in chunks of copyUpChunkSize bytes, when it
+// is first read or partially overwritten. Thus metadata changes such as chmod
+// and chown never copy data, writes only copy the chunks that they partially
+// overwrite, and chunks that are never accessed are never copied up.
+//
+// +stateify savable
+type pendingCopyUp struct {
+	// lowerFD is a readable FD for dentry.lowerVDs[0], and upperFD is a
+	// writable FD for dentry.upperVD. pendingCopyUp holds references on both.
+	lowerFD *vfs.FileDescription
+	upperFD *vfs.FileDescription
+
+	// size is the number of bytes at the beginning of the file that may not
+	// have been copied up. size is initially the size of the lower layer file,
+	// and is reduced when the file is truncated.
+	size uint64
+
+	// chunks contains the indexes of chunks that have not been copied up.
+	chunks bitmap.Bitmap
+}
+
+// newPendingCopyUp returns a pendingCopyUp for a file of the given size whose
+// data has not been copied up. It does not take references on lowerFD or
+// upperFD.
+func newPendingCopyUp(lowerFD, upperFD *vfs.FileDescription, size uint64) *pendingCopyUp {
+	p := &pendingCopyUp{
+		lowerFD: lowerFD,
+		upperFD: upperFD,
+		size:    size,
+	}
+	numChunks := uint32((size + copyUpChunkSize - 1) / copyUpChunkSize)
+	p.chunks = bitmap.New(numChunks)
+	for i := uint32(0); i < numChunks; i++ {
+		p.chunks.Add(i)
+	}
+	return p
+}
+
+// chunkRange returns the range of bytes in chunk i that may not have been
+// copied up.
+func (p *pendingCopyUp) chunkRange(i uint32) (start, end uint64) {
+	start = uint64(i) * copyUpChunkSize
+	end = start + copyUpChunkSize
+	if end > p.size {
+		end = p.size
+	}
+	return start, end
+}
+
+// forEachChunk calls f for each chunk that overlaps [start, end) and has not
+// been copied up, stopping at the first error.
+func (p *pendingCopyUp) forEachChunk(start, end uint64, f func(i uint32) error) error {
+	if end > p.size {
+		end = p.size
+	}
+	if start >= end {
+		return nil
+	}
+	var err error
+	p.chunks.ForEach(uint32(start/copyUpChunkSize), uint32((end-1)/copyUpChunkSize+1), func(i uint32) bool {
+		err = f(i)
+		return err == nil
+	})
+	return err
+}
+
+// copy copies the data in [start, end) from the lower layer to the upper
+// layer.
+func (p *pendingCopyUp) copy(ctx context.Context, buf []byte, start, end uint64) error {
+	// Copy-up is not subject to the file size limit of the task that
+	// triggers it, which may be lower than the file's size.
+	ctx = limits.ContextWithLimits(ctx, limits.NewLimitSet())
+	for start < end {
+		n := end - start
+		if n > uint64(len(buf)) {
+			n = uint64(len(buf))
+		}
+		rn, err := p.lowerFD.PRead(ctx, usermem.BytesIOSequence(buf[:n]), int64(start), vfs.ReadOptions{})
+		if rn == 0 {
+			if err == nil || err == io.EOF {
+				// The remainder of the lower layer file is missing, so it
+				// reads as zeroes on the upper layer.
+				return nil
+			}
+			return err
+		}
+		wn, err := p.upperFD.PWrite(ctx, usermem.BytesIOSequence(buf[:rn]), int64(start), vfs.WriteOptions{})
+		if err != nil {
+			return err
+		}
+		start += uint64(wn)
+	}
+	return nil
+}
+
+// copyUpAllData ensures that all data in the copied-up regular file d has been
+// copied up.
+func (d *dentry) copyUpAllData(ctx context.Context) error {
+	return d.copyUpDataRange(ctx, 0, math.MaxUint64)
+}
+
+// copyUpDataRange ensures that the data in [start, end) of the copied-up
+// regular file d has been copied up.
+func (d *dentry) copyUpDataRange(ctx context.Context, start, end uint64) error {
+	if !d.hasPendingData.Load() {
+		return nil
+	}
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+	return d.copyUpDataRangeLocked(ctx, start, end)
+}
+
+// Preconditions: d.pendingMu must be locked.
+func (d *dentry) copyUpDataRangeLocked(ctx context.Context, start, end uint64) error {
+	p := d.pendingData
+	if p == nil {
+		return nil
+	}
+	var (
+		buf   []byte
+		mtime linux.StatxTimestamp
+	)
+	if err := p.forEachChunk(start, end, func(i uint32) error {
+		if buf == nil {
+			// Writing to the upper layer file changes its modification time,
+			// which should remain that of the overlay file.
+			stat, err := p.upperFD.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_MTIME})
+			if err != nil {
+				return err
+			}
+			mtime = stat.Mtime
+			buf = make([]byte, copyUpChunkSize)
+		}
+		chunkStart, chunkEnd := p.chunkRange(i)
+		if err := p.copy(ctx, buf, chunkStart, chunkEnd); err != nil {
+			return err
+		}
+		p.chunks.Remove(i)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if buf != nil {
+		if err := p.upperFD.SetStat(ctx, vfs.SetStatOptions{
+			Stat: linux.Statx{
+				Mask:  linux.STATX_MTIME,
+				Mtime: mtime,
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	d.checkPendingDataLocked(ctx)
+	return nil
+}
+
+// writeCopiedUp calls write, which writes n bytes at offset off in the
+// copied-up regular file d and returns the number of bytes written, such that
+// data that is not copied up before the write is never copied up over the
+// written data. If off is negative, write appends to the file.
+func (d *dentry) writeCopiedUp(ctx context.Context, off, n int64, write func() (int64, error)) (int64, error) {
+	if off < 0 || n <= 0 || !d.hasPendingData.Load() {
+		// Appending writes never overwrite data that has not been copied up,
+		// since the file's size is at least d.pendingData.size.
+		return write()
+	}
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+	p := d.pendingData
+	if p == nil {
+		return write()
+	}
+
+	// Chunks that the write only overwrites partially must be copied up
+	// first. Chunks that it overwrites completely don't need to be copied up.
+	start, end := uint64(off), uint64(off)+uint64(n)
+	for _, i := range []uint64{start / copyUpChunkSize, (end - 1) / copyUpChunkSize} {
+		if chunkStart, chunkEnd := p.chunkRange(uint32(i)); chunkStart < start || chunkEnd > end {
+			if err := d.copyUpDataRangeLocked(ctx, chunkStart, chunkEnd); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if d.pendingData == nil {
+		return write()
+	}
+
+	written, err := write()
+	if written > 0 {
+		// If the write was short, the chunk that it ended in must still be
+		// copied up, except for the written data.
+		var buf []byte
+		if cerr := p.forEachChunk(start, start+uint64(written), func(i uint32) error {
+			if _, chunkEnd := p.chunkRange(i); chunkEnd > start+uint64(written) {
+				if buf == nil {
+					buf = make([]byte, copyUpChunkSize)
+				}
+				if err := p.copy(ctx, buf, start+uint64(written), chunkEnd); err != nil {
+					return err
+				}
+			}
+			p.chunks.Remove(i)
+			return nil
+		}); cerr != nil {
+			ctx.Warningf("overlay.dentry.writeCopiedUp: failed to copy up data after short write: %v", cerr)
+			if err == nil {
+				err = cerr
+			}
+		}
+		d.checkPendingDataLocked(ctx)
+	}
+	return written, err
+}
+
+// setStatCopiedUp calls setStat, which applies opts to the copied-up file d,
+// such that data beyond the file's new size is never copied up if opts
+// truncates the file.
+func (d *dentry) setStatCopiedUp(ctx context.Context, opts *vfs.SetStatOptions, setStat func() error) error {
+	if opts.Stat.Mask&linux.STATX_SIZE == 0 || !d.hasPendingData.Load() {
+		return setStat()
+	}
+	d.pendingMu.Lock()
+	defer d.pendingMu.Unlock()
+	if err := setStat(); err != nil {
+		return err
+	}
+	if p, size := d.pendingData, opts.Stat.Size; p != nil && size < p.size {
+		p.forEachChunk(size, p.size, func(i uint32) error {
+			if chunkStart, _ := p.chunkRange(i); chunkStart >= size {
+				p.chunks.Remove(i)
+			}
+			return nil
+		})
+		p.size = size
+		d.checkPendingDataLocked(ctx)
+	}
+	return nil
+}
+
+// checkPendingDataLocked releases d.pendingData if all of d's data has been
+// copied up.
+//
+// Preconditions: d.pendingMu must be locked.
+func (d *dentry) checkPendingDataLocked(ctx context.Context) {
+	if p := d.pendingData; p != nil && p.chunks.IsEmpty() {
+		d.releasePendingDataLocked(ctx)
+	}
+}
+
+// Preconditions: d.pendingMu must be locked.
+func (d *dentry) releasePendingDataLocked(ctx context.Context) {
+	p := d.pendingData
+	if p == nil {
+		return
+	}
+	p.lowerFD.DecRef(ctx)
+	p.upperFD.DecRef(ctx)
+	d.pendingData = nil
+	d.hasPendingData.Store(false)
+}
@@ -324,6 +324,9 @@ func (fs *filesystem) lookupLocked(ctx context.Context, parent *dentry, name str
 		child.destroyLocked(ctx)
 		return nil, topLookupLayer, linuxerr.ENOENT
 	}
+	if child.upperVD.Ok() && child.mode.RacyLoad()&linux.S_IFMT != linux.S_IFLNK {
+		child.updateACL(ctx)
+	}
 
 	// Device and inode numbers were copied from the topmost layer above for
 	// non-directories. They were copied from the bottommost layer for
@@ -675,6 +678,11 @@ func (fs *filesystem) LinkAt(ctx context.Context, rp *vfs.ResolvingPath, vd vfs.
 		if err := old.copyUpLocked(ctx); err != nil {
 			return err
 		}
+		// The new link will be a distinct dentry with no lower layer, so the
+		// file's data must be complete on the upper layer.
+		if err := old.copyUpAllData(ctx); err != nil {
+			return err
+		}
 		vfsObj := fs.vfsfs.VirtualFilesystem()
 		newpop := vfs.PathOperation{
 			Root:  parent.upperVD,
@@ -959,10 +967,25 @@ func (d *dentry) openCopiedUp(ctx context.Context, rp *vfs.ResolvingPath, opts *
 	}
 
 	layerVD, isUpper := d.topLayerInfo()
-	layerFD, err := rp.VirtualFilesystem().OpenAt(ctx, d.fs.creds, &vfs.PathOperation{
-		Root:  layerVD,
-		Start: layerVD,
-	}, opts)
+	var layerFD *vfs.FileDescription
+	open := func() error {
+		var err error
+		layerFD, err = rp.VirtualFilesystem().OpenAt(ctx, d.fs.creds, &vfs.PathOperation{
+			Root:  layerVD,
+			Start: layerVD,
+		}, opts)
+		return err
+	}
+	var err error
+	if isUpper && ftype == linux.S_IFREG && opts.Flags&linux.O_TRUNC != 0 {
+		err = d.setStatCopiedUp(ctx, &vfs.SetStatOptions{
+			Stat: linux.Statx{
+				Mask: linux.STATX_SIZE,
+			},
+		}, open)
+	} else {
+		err = open()
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -1021,8 +1044,9 @@ func (fs *filesystem) createAndOpenLocked(ctx context.Context, rp *vfs.Resolving
 	}
 	// Create the file on the upper layer, and get an FD representing it.
 	upperFD, err := vfsObj.OpenAt(ctx, fs.creds, &pop, &vfs.OpenOptions{
-		Flags: opts.Flags&^vfs.FileCreationFlags | linux.O_CREAT | linux.O_EXCL,
-		Mode:  opts.Mode,
+		Flags:   opts.Flags&^vfs.FileCreationFlags | linux.O_CREAT | linux.O_EXCL,
+		Mode:    opts.Mode,
+		Umasked: opts.Umasked,
 	})
 	if err != nil {
 		if haveUpperWhiteout {
@@ -1531,10 +1555,12 @@ func (d *dentry) setStatLocked(ctx context.Context, rp *vfs.ResolvingPath, opts
 	// Changes to d's attributes are serialized by d.copyMu.
 	d.copyMu.Lock()
 	defer d.copyMu.Unlock()
-	if err := d.fs.vfsfs.VirtualFilesystem().SetStatAt(ctx, d.fs.creds, &vfs.PathOperation{
-		Root:  d.upperVD,
-		Start: d.upperVD,
-	}, &opts); err != nil {
+	if err := d.setStatCopiedUp(ctx, &opts, func() error {
+		return d.fs.vfsfs.VirtualFilesystem().SetStatAt(ctx, d.fs.creds, &vfs.PathOperation{
+			Root:  d.upperVD,
+			Start: d.upperVD,
+		}, &opts)
+	}); err != nil {
 		return err
 	}
 	d.updateAfterSetStatLocked(&opts)
@@ -1830,7 +1856,11 @@ func (fs *filesystem) setXattrLocked(ctx context.Context, d *dentry, mnt *vfs.Mo
 		return err
 	}
 	vfsObj := d.fs.vfsfs.VirtualFilesystem()
-	return vfsObj.SetXattrAt(ctx, fs.creds, &vfs.PathOperation{Root: d.upperVD, Start: d.upperVD}, opts)
+	if err := vfsObj.SetXattrAt(ctx, fs.creds, &vfs.PathOperation{Root: d.upperVD, Start: d.upperVD}, opts); err != nil {
+		return err
+	}
+	d.aclChanged(ctx, opts.Name)
+	return nil
 }
 
 // RemoveXattrAt implements vfs.FilesystemImpl.RemoveXattrAt.
@@ -1874,7 +1904,11 @@ func (fs *filesystem) removeXattrLocked(ctx context.Context, d *dentry, mnt *vfs
 		return err
 	}
 	vfsObj := d.fs.vfsfs.VirtualFilesystem()
-	return vfsObj.RemoveXattrAt(ctx, fs.creds, &vfs.PathOperation{Root: d.upperVD, Start: d.upperVD}, name)
+	if err := vfsObj.RemoveXattrAt(ctx, fs.creds, &vfs.PathOperation{Root: d.upperVD, Start: d.upperVD}, name); err != nil {
+		return err
+	}
+	d.aclChanged(ctx, name)
+	return nil
 }
 
 // PrependPath implements vfs.FilesystemImpl.PrependPath.
@@ -23,6 +23,8 @@
 //			dentry.dirMu
 //		    dentry.copyMu
 //		      filesystem.devMu
+//		      dentry.aclMu
+//		      dentry.pendingMu
 //		      *** "memmap.Mappable locks" below this point
 //		      dentry.mapsMu
 //		        *** "memmap.Mappable locks taken by Translate" below this point
@@ -322,6 +324,7 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		fs.opts.UpperRoot.IncRef()
 		root.copiedUp = atomicbitops.FromUint32(1)
 		root.upperVD = fs.opts.UpperRoot
+		root.updateACL(ctx)
 	}
 	for _, lowerRoot := range fs.opts.LowerRoots {
 		lowerRoot.IncRef()
@@ -536,6 +539,23 @@ type dentry struct {
 	upperVD  vfs.VirtualDentry
 	lowerVDs []vfs.VirtualDentry
 
+	// accessACL caches the POSIX access ACL of upperVD, or is nil if upperVD
+	// has no access ACL or !upperVD.Ok(). hasAccessACL is true if accessACL
+	// is not nil, which allows permission checks to avoid locking aclMu in
+	// the common case. accessACL is protected by aclMu.
+	aclMu        sync.Mutex `state:"nosave"`
+	accessACL    *vfs.ACL
+	hasAccessACL atomicbitops.Bool
+
+	// If this dentry represents a copied-up regular file whose data has not
+	// been completely copied up, pendingData tracks the data that remains to
+	// be copied up; otherwise, pendingData is nil. hasPendingData is true if
+	// pendingData is not nil, which allows I/O to avoid locking pendingMu in
+	// the common case. pendingData is protected by pendingMu.
+	pendingMu      sync.Mutex `state:"nosave"`
+	pendingData    *pendingCopyUp
+	hasPendingData atomicbitops.Bool
+
 	// inlineLowerVDs backs lowerVDs in the common case where len(lowerVDs) <=
 	// len(inlineLowerVDs).
 	inlineLowerVDs [1]vfs.VirtualDentry
@@ -677,6 +697,12 @@ func (d *dentry) checkDropLocked(ctx context.Context) {
 		return
 	}
 
+	// Similarly, the upper layer file of a dentry whose data has not been
+	// completely copied up is incomplete without it.
+	if !d.vfsd.IsDead() && d.hasPendingData.Load() {
+		return
+	}
+
 	// Refs is still zero; destroy it.
 	d.destroyLocked(ctx)
 	return
@@ -698,6 +724,9 @@ func (d *dentry) destroyLocked(ctx context.Context) {
 		panic("overlay.dentry.destroyLocked() called with references on the dentry")
 	}
 
+	d.pendingMu.Lock()
+	d.releasePendingDataLocked(ctx)
+	d.pendingMu.Unlock()
 	if d.upperVD.Ok() {
 		d.upperVD.DecRef(ctx)
 	}
@@ -806,13 +835,21 @@ func (d *dentry) topLookupLayer() lookupLayer {
 }
 
 func (d *dentry) checkPermissions(creds *auth.Credentials, ats vfs.AccessTypes) error {
-	return vfs.GenericCheckPermissions(creds, ats, linux.FileMode(d.mode.Load()), auth.KUID(d.uid.Load()), auth.KGID(d.gid.Load()))
+	return vfs.GenericCheckPermissionsACL(creds, ats, linux.FileMode(d.mode.Load()), auth.KUID(d.uid.Load()), auth.KGID(d.gid.Load()), d.getAccessACL())
 }
 
 func (d *dentry) checkXattrPermissions(creds *auth.Credentials, name string, ats vfs.AccessTypes) error {
 	mode := linux.FileMode(d.mode.Load())
 	kuid := auth.KUID(d.uid.Load())
 	kgid := auth.KGID(d.gid.Load())
+	if vfs.IsACLXattr(name) {
+		// As in Linux, anyone may read a file's ACLs, but only its owner may
+		// change them.
+		if ats.MayWrite() && !vfs.CanActAsOwner(creds, kuid) {
+			return linuxerr.EPERM
+		}
+		return nil
+	}
 	if err := vfs.GenericCheckPermissions(creds, ats, mode, kuid, kgid); err != nil {
 		return err
 	}
@@ -846,6 +883,7 @@ func (d *dentry) statInternalTo(ctx context.Context, opts *vfs.StatOptions, stat
 func (d *dentry) updateAfterSetStatLocked(opts *vfs.SetStatOptions) {
 	if opts.Stat.Mask&linux.STATX_MODE != 0 {
 		d.mode.Store((d.mode.RacyLoad() & linux.S_IFMT) | uint32(opts.Stat.Mode&^linux.S_IFMT))
+		d.chmodACL(linux.FileMode(opts.Stat.Mode))
 	}
 	if opts.Stat.Mask&linux.STATX_UID != 0 {
 		d.uid.Store(opts.Stat.UID)
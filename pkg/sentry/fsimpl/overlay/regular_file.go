@@ -160,6 +160,12 @@ func (fd *regularFileFD) Allocate(ctx context.Context, mode, offset, length uint
 		return err
 	}
 	defer wrappedFD.DecRef(ctx)
+	if mode != 0 {
+		// Modes other than the default may move or discard existing data.
+		if err := fd.dentry().copyUpAllData(ctx); err != nil {
+			return err
+		}
+	}
 	return wrappedFD.Allocate(ctx, mode, offset, length)
 }
 
@@ -185,7 +191,9 @@ func (fd *regularFileFD) SetStat(ctx context.Context, opts vfs.SetStatOptions) e
 	if err != nil {
 		return err
 	}
-	if err := wrappedFD.SetStat(ctx, opts); err != nil {
+	if err := d.setStatCopiedUp(ctx, &opts, func() error {
+		return wrappedFD.SetStat(ctx, opts)
+	}); err != nil {
 		return err
 	}
 
@@ -272,6 +280,9 @@ func (fd *regularFileFD) PRead(ctx context.Context, dst usermem.IOSequence, offs
 		return 0, err
 	}
 	defer wrappedFD.DecRef(ctx)
+	if err := fd.dentry().copyUpDataRange(ctx, uint64(offset), uint64(offset+dst.NumBytes())); err != nil {
+		return 0, err
+	}
 	return wrappedFD.PRead(ctx, dst, offset, opts)
 }
 
@@ -284,6 +295,15 @@ func (fd *regularFileFD) Read(ctx context.Context, dst usermem.IOSequence, opts
 	if err != nil {
 		return 0, err
 	}
+	if d := fd.dentry(); d.hasPendingData.Load() {
+		offset, err := wrappedFD.Seek(ctx, 0, linux.SEEK_CUR)
+		if err != nil {
+			return 0, err
+		}
+		if err := d.copyUpDataRange(ctx, uint64(offset), uint64(offset+dst.NumBytes())); err != nil {
+			return 0, err
+		}
+	}
 	return wrappedFD.Read(ctx, dst, opts)
 }
 
@@ -294,7 +314,13 @@ func (fd *regularFileFD) PWrite(ctx context.Context, src usermem.IOSequence, off
 		return 0, err
 	}
 	defer wrappedFD.DecRef(ctx)
-	n, err := wrappedFD.PWrite(ctx, src, offset, opts)
+	writeOffset := offset
+	if fd.vfsfd.StatusFlags()&linux.O_APPEND != 0 {
+		writeOffset = -1
+	}
+	n, err := fd.dentry().writeCopiedUp(ctx, writeOffset, src.NumBytes(), func() (int64, error) {
+		return wrappedFD.PWrite(ctx, src, offset, opts)
+	})
 	if err != nil {
 		return n, err
 	}
@@ -310,7 +336,17 @@ func (fd *regularFileFD) Write(ctx context.Context, src usermem.IOSequence, opts
 	if err != nil {
 		return 0, err
 	}
-	n, err := wrappedFD.Write(ctx, src, opts)
+	d := fd.dentry()
+	writeOffset := int64(-1)
+	if d.hasPendingData.Load() && fd.vfsfd.StatusFlags()&linux.O_APPEND == 0 {
+		writeOffset, err = wrappedFD.Seek(ctx, 0, linux.SEEK_CUR)
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := d.writeCopiedUp(ctx, writeOffset, src.NumBytes(), func() (int64, error) {
+		return wrappedFD.Write(ctx, src, opts)
+	})
 	if err != nil {
 		return n, err
 	}
@@ -343,6 +379,13 @@ func (fd *regularFileFD) Seek(ctx context.Context, offset int64, whence int32) (
 	if err != nil {
 		return 0, err
 	}
+	if whence == linux.SEEK_DATA || whence == linux.SEEK_HOLE {
+		// Data that has not been copied up appears as holes in the upper
+		// layer file.
+		if err := fd.dentry().copyUpAllData(ctx); err != nil {
+			return 0, err
+		}
+	}
 	return wrappedFD.Seek(ctx, offset, whence)
 }
 
@@ -409,6 +452,11 @@ func (fd *regularFileFD) ensureMappable(ctx context.Context, opts *memmap.MMapOp
 	if err != nil {
 		return err
 	}
+	// Mappings of the upper layer file can't observe data that has not been
+	// copied up.
+	if err := d.copyUpAllData(ctx); err != nil {
+		return err
+	}
 	if err := wrappedFD.ConfigureMMap(ctx, opts); err != nil {
 		return err
 	}
@@ -0,0 +1,103 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pidfd implements process file descriptors, as returned by
+// pidfd_open(2).
+package pidfd
+
+import (
+	"bytes"
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// FileDescription implements vfs.FileDescriptionImpl for pidfds.
+//
+// +stateify savable
+type FileDescription struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.DentryMetadataFileDescriptionImpl
+	vfs.NoLockFD
+
+	// tg is the thread group the pidfd refers to. tg is immutable.
+	tg *kernel.ThreadGroup
+}
+
+var _ vfs.FileDescriptionImpl = (*FileDescription)(nil)
+var _ vfs.FileDescriptionImplFDInfoExtension = (*FileDescription)(nil)
+
+// New creates a new pidfd referring to tg.
+func New(ctx context.Context, vfsObj *vfs.VirtualFilesystem, tg *kernel.ThreadGroup, flags uint32) (*vfs.FileDescription, error) {
+	vd := vfsObj.NewAnonVirtualDentry("[pidfd]")
+	defer vd.DecRef(ctx)
+	fd := &FileDescription{tg: tg}
+	if err := fd.vfsfd.Init(fd, flags, vd.Mount(), vd.Dentry(), &vfs.FileDescriptionOptions{
+		UseDentryMetadata: true,
+		DenyPRead:         true,
+		DenyPWrite:        true,
+	}); err != nil {
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
+// ThreadGroup returns the thread group the pidfd refers to.
+func (fd *FileDescription) ThreadGroup() *kernel.ThreadGroup {
+	return fd.tg
+}
+
+// FDInfo implements vfs.FileDescriptionImplFDInfoExtension.FDInfo.
+func (fd *FileDescription) FDInfo(ctx context.Context, buf *bytes.Buffer) {
+	// Linux: kernel/pid.c:pidfd_show_fdinfo()
+	pid := int64(-1)
+	if t := kernel.TaskFromContext(ctx); t != nil && !fd.tg.Exited() {
+		pid = int64(t.PIDNamespace().IDOfThreadGroup(fd.tg))
+	}
+	fmt.Fprintf(buf, "Pid:\t%d\n", pid)
+}
+
+// Readiness implements waiter.Waitable.Readiness.
+func (fd *FileDescription) Readiness(mask waiter.EventMask) waiter.EventMask {
+	// "A PID file descriptor can be monitored using poll(2), select(2), and
+	// epoll(7). When the process that it refers to terminates, these
+	// interfaces indicate the file descriptor as readable." - pidfd_open(2)
+	if fd.tg.Exited() {
+		return mask & waiter.ReadableEvents
+	}
+	return 0
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (fd *FileDescription) EventRegister(e *waiter.Entry) error {
+	fd.tg.EventRegisterExit(e)
+	return nil
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (fd *FileDescription) EventUnregister(e *waiter.Entry) {
+	fd.tg.EventUnregisterExit(e)
+}
+
+// Epollable implements FileDescriptionImpl.Epollable.
+func (fd *FileDescription) Epollable() bool {
+	return true
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *FileDescription) Release(context.Context) {}
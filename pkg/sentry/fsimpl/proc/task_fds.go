@@ -17,7 +17,7 @@ package proc
 import (
 	"bytes"
 	"fmt"
-	"sort"
+	"math"
 	"strconv"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
@@ -64,43 +64,57 @@ type fdDir struct {
 	produceSymlink bool
 }
 
+// fdDirBatchSize is the number of FDs read from the FDTable at a time while
+// iterating over an fd directory, which bounds the cost of each getdents(2)
+// call in processes with many open files.
+const fdDirBatchSize = 256
+
 // IterDirents implements kernfs.inodeDirectory.IterDirents.
 func (i *fdDir) IterDirents(ctx context.Context, mnt *vfs.Mount, cb vfs.IterDirentsCallback, offset, relOffset int64) (int64, error) {
-	var fds []int32
-	i.task.WithMuLocked(func(t *kernel.Task) {
-		if fdTable := t.FDTable(); fdTable != nil {
-			fds = fdTable.GetFDs(ctx)
-		}
-	})
+	if relOffset > math.MaxInt32 {
+		return offset, nil
+	}
 
 	typ := uint8(linux.DT_REG)
 	if i.produceSymlink {
 		typ = linux.DT_LNK
 	}
 
-	// Find the appropriate starting point.
-	idx := sort.Search(len(fds), func(i int) bool { return fds[i] >= int32(relOffset) })
-	if idx >= len(fds) {
-		return offset, nil
-	}
-	for _, fd := range fds[idx:] {
-		dirent := vfs.Dirent{
-			Name:    strconv.FormatUint(uint64(fd), 10),
-			Type:    typ,
-			Ino:     i.fs.NextIno(),
-			NextOff: int64(fd) + 3,
+	next := int32(relOffset)
+	for {
+		var fds []int32
+		i.task.WithMuLocked(func(t *kernel.Task) {
+			if fdTable := t.FDTable(); fdTable != nil {
+				fds = fdTable.GetFDsFrom(ctx, next, fdDirBatchSize)
+			}
+		})
+		if len(fds) == 0 {
+			return offset, nil
+		}
+		for _, fd := range fds {
+			dirent := vfs.Dirent{
+				Name:    strconv.FormatUint(uint64(fd), 10),
+				Type:    typ,
+				Ino:     i.fs.NextIno(),
+				NextOff: int64(fd) + 3,
+			}
+			if err := cb.Handle(dirent); err != nil {
+				// Getdents should iterate correctly despite mutation
+				// of fds, so we return the next fd to serialize plus
+				// 2 (which accounts for the "." and ".." tracked by
+				// kernfs) as the offset.
+				return int64(fd) + 2, err
+			}
+			// The next offset should be higher than the last serialized
+			// fd.
+			offset = int64(fd) + 3
 		}
-		if err := cb.Handle(dirent); err != nil {
-			// Getdents should iterate correctly despite mutation
-			// of fds, so we return the next fd to serialize plus
-			// 2 (which accounts for the "." and ".." tracked by
-			// kernfs) as the offset.
-			return int64(fd) + 2, err
+		last := fds[len(fds)-1]
+		if len(fds) < fdDirBatchSize || last == math.MaxInt32 {
+			return offset, nil
 		}
+		next = last + 1
 	}
-	// We serialized them all.  Next offset should be higher than last
-	// serialized fd.
-	return int64(fds[len(fds)-1]) + 3, nil
 }
 
 // fdDirInode represents the inode for /proc/[pid]/fd directory.
@@ -340,11 +354,20 @@ func (d *fdInfoData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 		return linuxerr.ENOENT
 	}
 	defer d.fs.SafeDecRefFD(ctx, file)
-	// TODO(b/121266871): Include pos, locks, and other data. For now we only
-	// have flags.
+	// TODO(b/121266871): Include locks.
 	// See https://www.kernel.org/doc/Documentation/filesystems/proc.txt
+	//
+	// Linux: fs/proc/fd.c:seq_show()
+	fmt.Fprintf(buf, "pos:\t%d\n", file.FDInfoPos(ctx))
 	flags := uint(file.StatusFlags()) | descriptorFlags.ToLinuxFileFlags()
 	fmt.Fprintf(buf, "flags:\t0%o\n", flags)
+	fmt.Fprintf(buf, "mnt_id:\t%d\n", file.Mount().ID)
+	if stat, err := file.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_INO}); err == nil {
+		fmt.Fprintf(buf, "ino:\t%d\n", stat.Ino)
+	}
+	if ext, ok := file.Impl().(vfs.FileDescriptionImplFDInfoExtension); ok {
+		ext.FDInfo(ctx, buf)
+	}
 	return nil
 }
 
@@ -409,6 +409,55 @@ func (d *idMapData) Write(ctx context.Context, _ *vfs.FileDescription, src userm
 	return int64(srclen), nil
 }
 
+// setgroupsData implements vfs.WritableDynamicBytesSource for
+// /proc/[pid]/setgroups.
+//
+// +stateify savable
+type setgroupsData struct {
+	kernfs.DynamicBytesFile
+
+	task *kernel.Task
+}
+
+var _ dynamicInode = (*setgroupsData)(nil)
+var _ vfs.WritableDynamicBytesSource = (*setgroupsData)(nil)
+
+// Generate implements vfs.WritableDynamicBytesSource.Generate.
+func (d *setgroupsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	if d.task.UserNamespace().SetgroupsDenied() {
+		buf.WriteString("deny\n")
+	} else {
+		buf.WriteString("allow\n")
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *setgroupsData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	// Linux: kernel/user_namespace.c:proc_setgroups_write()
+	srclen := src.NumBytes()
+	if srclen >= 8 || offset != 0 {
+		return 0, linuxerr.EINVAL
+	}
+	b := make([]byte, srclen)
+	if _, err := src.CopyIn(ctx, b); err != nil {
+		return 0, err
+	}
+	var allow bool
+	switch string(bytes.TrimRight(b, " \t\n\x00")) {
+	case "allow":
+		allow = true
+	case "deny":
+		allow = false
+	default:
+		return 0, linuxerr.EINVAL
+	}
+	if err := d.task.UserNamespace().SetSetgroups(auth.CredentialsFromContext(ctx), allow); err != nil {
+		return 0, err
+	}
+	return srclen, nil
+}
+
 var _ kernfs.Inode = (*memInode)(nil)
 
 // memInode implements kernfs.Inode for /proc/[pid]/mem.
@@ -667,9 +716,9 @@ func (s *taskStatData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	fmt.Fprintf(buf, "0 0 0 0 " /* minflt cminflt majflt cmajflt */)
 	var cputime usage.CPUStats
 	if s.tgstats {
-		cputime = s.task.ThreadGroup().CPUStats()
+		cputime = s.task.ThreadGroup().AdjustedCPUStats()
 	} else {
-		cputime = s.task.CPUStats()
+		cputime = s.task.AdjustedCPUStats()
 	}
 	fmt.Fprintf(buf, "%d %d ", linux.ClockTFromDuration(cputime.UserTime), linux.ClockTFromDuration(cputime.SysTime))
 	cputime = s.task.ThreadGroup().JoinedChildCPUStats()
@@ -921,7 +970,27 @@ func (i *ioData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	return nil
 }
 
-// oomScoreAdj is a stub of the /proc/<pid>/oom_score_adj file.
+// oomScore implements the /proc/<pid>/oom_score file.
+//
+// +stateify savable
+type oomScore struct {
+	kernfs.DynamicBytesFile
+
+	task *kernel.Task
+}
+
+var _ dynamicInode = (*oomScore)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (o *oomScore) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	if o.task.ExitState() == kernel.TaskExitDead {
+		return linuxerr.ESRCH
+	}
+	fmt.Fprintf(buf, "%d\n", o.task.OOMScore())
+	return nil
+}
+
+// oomScoreAdj implements the /proc/<pid>/oom_score_adj file.
 //
 // +stateify savable
 type oomScoreAdj struct {
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"strings"
 	"time"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
@@ -55,7 +56,6 @@ func (fs *filesystem) newTaskNetDir(ctx context.Context, task *kernel.Task) kern
 			packet    = "sk       RefCnt Type Proto  Iface R Rmem   User   Inode\n"
 			protocols = "protocol  size sockets  memory press maxhdr  slab module     cl co di ac io in de sh ss gs se re sp bi br ha uh gp em\n"
 			ptype     = "Type Device      Function\n"
-			upd6      = "  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
 		)
 		psched := fmt.Sprintf("%08x %08x %08x %08x\n", uint64(time.Microsecond/time.Nanosecond), 64, 1000000, uint64(time.Second/time.Nanosecond))
 
@@ -70,25 +70,30 @@ func (fs *filesystem) newTaskNetDir(ctx context.Context, task *kernel.Task) kern
 			// otherwise it is an empty file.
 			"arp":       fs.newInode(ctx, root, 0444, newStaticFile(arp)),
 			"netlink":   fs.newInode(ctx, root, 0444, newStaticFile(netlink)),
-			"netstat":   fs.newInode(ctx, root, 0444, &netStatData{}),
+			"netstat":   fs.newInode(ctx, root, 0444, &netStatData{stack: stack}),
 			"packet":    fs.newInode(ctx, root, 0444, newStaticFile(packet)),
 			"protocols": fs.newInode(ctx, root, 0444, newStaticFile(protocols)),
 
 			// Linux sets psched values to: nsec per usec, psched tick in ns, 1000000,
 			// high res timer ticks per sec (ClockGetres returns 1ns resolution).
-			"psched": fs.newInode(ctx, root, 0444, newStaticFile(psched)),
-			"ptype":  fs.newInode(ctx, root, 0444, newStaticFile(ptype)),
-			"route":  fs.newInode(ctx, root, 0444, &netRouteData{stack: stack}),
-			"tcp":    fs.newInode(ctx, root, 0444, &netTCPData{kernel: k}),
-			"udp":    fs.newInode(ctx, root, 0444, &netUDPData{kernel: k}),
-			"unix":   fs.newInode(ctx, root, 0444, &netUnixData{kernel: k}),
+			"psched":  fs.newInode(ctx, root, 0444, newStaticFile(psched)),
+			"ptype":   fs.newInode(ctx, root, 0444, newStaticFile(ptype)),
+			"raw":     fs.newInode(ctx, root, 0444, &netRawData{kernel: k, family: linux.AF_INET}),
+			"route":   fs.newInode(ctx, root, 0444, &netRouteData{stack: stack}),
+			"tcp":     fs.newInode(ctx, root, 0444, &netTCPData{kernel: k}),
+			"udp":     fs.newInode(ctx, root, 0444, &netUDPData{kernel: k, family: linux.AF_INET, protocol: linux.IPPROTO_UDP}),
+			"udplite": fs.newInode(ctx, root, 0444, &netUDPData{kernel: k, family: linux.AF_INET, protocol: linux.IPPROTO_UDPLITE}),
+			"unix":    fs.newInode(ctx, root, 0444, &netUnixData{kernel: k}),
 		}
 
 		if stack.SupportsIPv6() {
 			contents["if_inet6"] = fs.newInode(ctx, root, 0444, &ifinet6{stack: stack})
 			contents["ipv6_route"] = fs.newInode(ctx, root, 0444, newStaticFile(""))
+			contents["raw6"] = fs.newInode(ctx, root, 0444, &netRawData{kernel: k, family: linux.AF_INET6})
+			contents["snmp6"] = fs.newInode(ctx, root, 0444, &netSnmp6Data{stack: stack})
 			contents["tcp6"] = fs.newInode(ctx, root, 0444, &netTCP6Data{kernel: k})
-			contents["udp6"] = fs.newInode(ctx, root, 0444, newStaticFile(upd6))
+			contents["udp6"] = fs.newInode(ctx, root, 0444, &netUDPData{kernel: k, family: linux.AF_INET6, protocol: linux.IPPROTO_UDP})
+			contents["udplite6"] = fs.newInode(ctx, root, 0444, &netUDPData{kernel: k, family: linux.AF_INET6, protocol: linux.IPPROTO_UDPLITE})
 		}
 	}
 
@@ -500,27 +505,80 @@ func (d *netTCP6Data) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	return commonGenerateTCP(ctx, buf, d.kernel, linux.AF_INET6)
 }
 
-// netUDPData implements vfs.DynamicBytesSource for /proc/net/udp.
+// netUDPData implements vfs.DynamicBytesSource for /proc/net/udp,
+// /proc/net/udp6, /proc/net/udplite and /proc/net/udplite6.
 //
 // +stateify savable
 type netUDPData struct {
 	kernfs.DynamicBytesFile
 
 	kernel *kernel.Kernel
+
+	// family is the address family of the listed sockets.
+	family int
+
+	// protocol is either IPPROTO_UDP or IPPROTO_UDPLITE.
+	protocol int
 }
 
 var _ dynamicInode = (*netUDPData)(nil)
 
 // Generate implements vfs.DynamicBytesSource.Generate.
 func (d *netUDPData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	if d.family == linux.AF_INET6 {
+		buf.WriteString("  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops\n")
+	} else {
+		buf.WriteString("  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops             \n")
+	}
+	return commonGenerateDgram(ctx, buf, d.kernel, d.family, func(stype linux.SockType, protocol int) bool {
+		if stype != linux.SOCK_DGRAM {
+			return false
+		}
+		if d.protocol == linux.IPPROTO_UDP {
+			// UDP sockets may be created with protocol 0.
+			return protocol == 0 || protocol == linux.IPPROTO_UDP
+		}
+		return protocol == d.protocol
+	})
+}
+
+// netRawData implements vfs.DynamicBytesSource for /proc/net/raw and
+// /proc/net/raw6.
+//
+// +stateify savable
+type netRawData struct {
+	kernfs.DynamicBytesFile
+
+	kernel *kernel.Kernel
+
+	// family is the address family of the listed sockets.
+	family int
+}
+
+var _ dynamicInode = (*netRawData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *netRawData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	if d.family == linux.AF_INET6 {
+		buf.WriteString("  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops\n")
+	} else {
+		buf.WriteString("  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops\n")
+	}
+	return commonGenerateDgram(ctx, buf, d.kernel, d.family, func(stype linux.SockType, _ int) bool {
+		return stype == linux.SOCK_RAW
+	})
+}
+
+// commonGenerateDgram writes the entries of /proc/net/{udp,udplite,raw} and
+// their IPv6 counterparts for the sockets of family for which match returns
+// true.
+func commonGenerateDgram(ctx context.Context, buf *bytes.Buffer, k *kernel.Kernel, family int, match func(stype linux.SockType, protocol int) bool) error {
 	// t may be nil here if our caller is not part of a task goroutine. This can
 	// happen for example if we're here for "sentryctl cat". When t is nil,
 	// degrade gracefully and retrieve what we can.
 	t := kernel.TaskFromContext(ctx)
 
-	buf.WriteString("  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops             \n")
-
-	for _, se := range d.kernel.ListSockets() {
+	for _, se := range k.ListSockets() {
 		s := se.Sock
 		if !s.TryIncRef() {
 			// Racing with socket destruction, this is ok.
@@ -530,34 +588,45 @@ func (d *netUDPData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 		if !ok {
 			panic(fmt.Sprintf("Found non-socket file in socket table: %+v", s))
 		}
-		if family, stype, _ := sops.Type(); family != linux.AF_INET || stype != linux.SOCK_DGRAM {
+		fa, stype, protocol := sops.Type()
+		if fa != family || !match(stype, protocol) {
 			s.DecRef(ctx)
-			// Not udp4 socket.
 			continue
 		}
 
-		// For Linux's implementation, see net/ipv4/udp.c:udp4_format_sock().
+		// For Linux's implementation, see net/ipv4/udp.c:udp4_format_sock()
+		// and net/ipv4/raw.c:raw_sock_seq_show().
 
 		// Field: sl; entry number.
 		fmt.Fprintf(buf, "%5d: ", se.ID)
 
-		// Field: local_adddress.
-		var localAddr linux.SockAddrInet
+		// Field: local_adddress. Raw sockets report their protocol as the
+		// local port.
+		var localAddr linux.SockAddr
 		if t != nil {
 			if local, _, err := sops.GetSockName(t); err == nil {
-				localAddr = *local.(*linux.SockAddrInet)
+				localAddr = local
+			}
+		}
+		if stype == linux.SOCK_RAW {
+			port := socket.Htons(uint16(protocol))
+			switch a := localAddr.(type) {
+			case *linux.SockAddrInet:
+				a.Port = port
+			case *linux.SockAddrInet6:
+				a.Port = port
 			}
 		}
-		writeInetAddr(buf, linux.AF_INET, &localAddr)
+		writeInetAddr(buf, family, localAddr)
 
 		// Field: rem_address.
-		var remoteAddr linux.SockAddrInet
+		var remoteAddr linux.SockAddr
 		if t != nil {
 			if remote, _, err := sops.GetPeerName(t); err == nil {
-				remoteAddr = *remote.(*linux.SockAddrInet)
+				remoteAddr = remote
 			}
 		}
-		writeInetAddr(buf, linux.AF_INET, &remoteAddr)
+		writeInetAddr(buf, family, remoteAddr)
 
 		// Field: state; socket state.
 		fmt.Fprintf(buf, "%02X ", sops.State())
@@ -566,10 +635,10 @@ func (d *netUDPData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 		// receive queue. Unimplemented.
 		fmt.Fprintf(buf, "%08X:%08X ", 0, 0)
 
-		// Field: tr, tm->when. Always 0 for UDP.
+		// Field: tr, tm->when. Always 0 for datagram sockets.
 		fmt.Fprintf(buf, "%02X:%08X ", 0, 0)
 
-		// Field: retrnsmt. Always 0 for UDP.
+		// Field: retrnsmt. Always 0 for datagram sockets.
 		fmt.Fprintf(buf, "%08X ", 0)
 
 		stat, statErr := s.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_UID | linux.STATX_INO})
@@ -583,7 +652,7 @@ func (d *netUDPData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 			fmt.Fprintf(buf, "%5d ", uint32(auth.KUID(stat.UID).In(creds.UserNamespace).OrOverflow()))
 		}
 
-		// Field: timeout. Always 0 for UDP.
+		// Field: timeout. Always 0 for datagram sockets.
 		fmt.Fprintf(buf, "%8d ", 0)
 
 		// Field: inode.
@@ -706,6 +775,65 @@ func (d *netSnmpData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	return nil
 }
 
+// netSnmp6Data implements vfs.DynamicBytesSource for /proc/net/snmp6.
+//
+// +stateify savable
+type netSnmp6Data struct {
+	kernfs.DynamicBytesFile
+
+	stack inet.Stack
+}
+
+var _ dynamicInode = (*netSnmp6Data)(nil)
+
+// snmp6 holds the counter names of /proc/net/snmp6, grouped like the stats
+// they are read from. See Linux's net/ipv6/proc.c.
+var snmp6 = []struct {
+	prefix string
+	names  []string
+}{
+	{
+		prefix: "Ip6",
+		names:  strings.Fields("Ip6InReceives Ip6InHdrErrors Ip6InTooBigErrors Ip6InNoRoutes Ip6InAddrErrors Ip6InUnknownProtos Ip6InTruncatedPkts Ip6InDiscards Ip6InDelivers Ip6OutForwDatagrams Ip6OutRequests Ip6OutDiscards Ip6OutNoRoutes Ip6ReasmTimeout Ip6ReasmReqds Ip6ReasmOKs Ip6ReasmFails Ip6FragOKs Ip6FragFails Ip6FragCreates Ip6InMcastPkts Ip6OutMcastPkts Ip6InOctets Ip6OutOctets Ip6InMcastOctets Ip6OutMcastOctets Ip6InBcastOctets Ip6OutBcastOctets Ip6InNoECTPkts Ip6InECT1Pkts Ip6InECT0Pkts Ip6InCEPkts"),
+	},
+	{
+		prefix: "Icmp6",
+		names:  strings.Fields("Icmp6InMsgs Icmp6InErrors Icmp6OutMsgs Icmp6OutErrors Icmp6InCsumErrors Icmp6InDestUnreachs Icmp6InPktTooBigs Icmp6InTimeExcds Icmp6InParmProblems Icmp6InEchos Icmp6InEchoReplies Icmp6InGroupMembQueries Icmp6InGroupMembResponses Icmp6InGroupMembReductions Icmp6InRouterSolicits Icmp6InRouterAdvertisements Icmp6InNeighborSolicits Icmp6InNeighborAdvertisements Icmp6InRedirects Icmp6InMLDv2Reports Icmp6OutDestUnreachs Icmp6OutPktTooBigs Icmp6OutTimeExcds Icmp6OutParmProblems Icmp6OutEchos Icmp6OutEchoReplies Icmp6OutGroupMembQueries Icmp6OutGroupMembResponses Icmp6OutGroupMembReductions Icmp6OutRouterSolicits Icmp6OutRouterAdvertisements Icmp6OutNeighborSolicits Icmp6OutNeighborAdvertisements Icmp6OutRedirects Icmp6OutMLDv2Reports"),
+	},
+	{
+		prefix: "Udp6",
+		names:  strings.Fields("Udp6InDatagrams Udp6NoPorts Udp6InErrors Udp6OutDatagrams Udp6RcvbufErrors Udp6SndbufErrors Udp6InCsumErrors Udp6IgnoredMulti Udp6MemErrors"),
+	},
+	{
+		prefix: "UdpLite6",
+		names:  strings.Fields("UdpLite6InDatagrams UdpLite6NoPorts UdpLite6InErrors UdpLite6OutDatagrams UdpLite6RcvbufErrors UdpLite6SndbufErrors UdpLite6InCsumErrors UdpLite6MemErrors"),
+	},
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *netSnmp6Data) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	types := []any{
+		&inet.StatSNMP6IP{},
+		&inet.StatSNMP6ICMP{},
+		&inet.StatSNMP6UDP{},
+		&inet.StatSNMP6UDPLite{},
+	}
+	for i, stat := range types {
+		group := snmp6[i]
+		if err := d.stack.Statistics(stat, group.prefix); err != nil {
+			if linuxerr.Equals(linuxerr.EOPNOTSUPP, err) {
+				log.Infof("Failed to retrieve %s of /proc/net/snmp6: %v", group.prefix, err)
+			} else {
+				log.Warningf("Failed to retrieve %s of /proc/net/snmp6: %v", group.prefix, err)
+			}
+		}
+		for j, v := range toSlice(stat) {
+			fmt.Fprintf(buf, "%-32s\t%d\n", group.names[j], v)
+		}
+	}
+	return nil
+}
+
 // netRouteData implements vfs.DynamicBytesSource for /proc/net/route.
 //
 // +stateify savable
@@ -781,38 +909,69 @@ type netStatData struct {
 
 var _ dynamicInode = (*netStatData)(nil)
 
+var netstat = []snmpLine{
+	{
+		prefix: "TcpExt",
+		header: "SyncookiesSent SyncookiesRecv SyncookiesFailed " +
+			"EmbryonicRsts PruneCalled RcvPruned OfoPruned OutOfWindowIcmps " +
+			"LockDroppedIcmps ArpFilter TW TWRecycled TWKilled PAWSPassive " +
+			"PAWSActive PAWSEstab DelayedACKs DelayedACKLocked DelayedACKLost " +
+			"ListenOverflows ListenDrops TCPPrequeued TCPDirectCopyFromBacklog " +
+			"TCPDirectCopyFromPrequeue TCPPrequeueDropped TCPHPHits TCPHPHitsToUser " +
+			"TCPPureAcks TCPHPAcks TCPRenoRecovery TCPSackRecovery TCPSACKReneging " +
+			"TCPFACKReorder TCPSACKReorder TCPRenoReorder TCPTSReorder TCPFullUndo " +
+			"TCPPartialUndo TCPDSACKUndo TCPLossUndo TCPLostRetransmit " +
+			"TCPRenoFailures TCPSackFailures TCPLossFailures TCPFastRetrans " +
+			"TCPForwardRetrans TCPSlowStartRetrans TCPTimeouts TCPLossProbes " +
+			"TCPLossProbeRecovery TCPRenoRecoveryFail TCPSackRecoveryFail " +
+			"TCPSchedulerFailed TCPRcvCollapsed TCPDSACKOldSent TCPDSACKOfoSent " +
+			"TCPDSACKRecv TCPDSACKOfoRecv TCPAbortOnData TCPAbortOnClose " +
+			"TCPAbortOnMemory TCPAbortOnTimeout TCPAbortOnLinger TCPAbortFailed " +
+			"TCPMemoryPressures TCPSACKDiscard TCPDSACKIgnoredOld " +
+			"TCPDSACKIgnoredNoUndo TCPSpuriousRTOs TCPMD5NotFound TCPMD5Unexpected " +
+			"TCPMD5Failure TCPSackShifted TCPSackMerged TCPSackShiftFallback " +
+			"TCPBacklogDrop TCPMinTTLDrop TCPDeferAcceptDrop IPReversePathFilter " +
+			"TCPTimeWaitOverflow TCPReqQFullDoCookies TCPReqQFullDrop TCPRetransFail " +
+			"TCPRcvCoalesce TCPOFOQueue TCPOFODrop TCPOFOMerge TCPChallengeACK " +
+			"TCPSYNChallenge TCPFastOpenActive TCPFastOpenActiveFail " +
+			"TCPFastOpenPassive TCPFastOpenPassiveFail TCPFastOpenListenOverflow " +
+			"TCPFastOpenCookieReqd TCPSpuriousRtxHostQueues BusyPollRxPackets " +
+			"TCPAutoCorking TCPFromZeroWindowAdv TCPToZeroWindowAdv " +
+			"TCPWantZeroWindowAdv TCPSynRetrans TCPOrigDataSent TCPHystartTrainDetect " +
+			"TCPHystartTrainCwnd TCPHystartDelayDetect TCPHystartDelayCwnd " +
+			"TCPACKSkippedSynRecv TCPACKSkippedPAWS TCPACKSkippedSeq " +
+			"TCPACKSkippedFinWait2 TCPACKSkippedTimeWait TCPACKSkippedChallenge " +
+			"TCPWinProbe TCPKeepAlive TCPMTUPFail TCPMTUPSuccess TCPAORequired " +
+			"TCPAOBad TCPAOKeyNotFound TCPAOGood",
+	},
+	{
+		prefix: "IpExt",
+		header: "InNoRoutes InTruncatedPkts InMcastPkts OutMcastPkts InBcastPkts " +
+			"OutBcastPkts InOctets OutOctets InMcastOctets OutMcastOctets " +
+			"InBcastOctets OutBcastOctets InCsumErrors InNoECTPkts InECT1Pkts " +
+			"InECT0Pkts InCEPkts ReasmOverlaps",
+	},
+}
+
 // Generate implements vfs.DynamicBytesSource.Generate.
-// See Linux's net/ipv4/fib_trie.c:fib_route_seq_show.
+// See Linux's net/ipv4/proc.c:netstat_seq_show.
 func (d *netStatData) Generate(ctx context.Context, buf *bytes.Buffer) error {
-	buf.WriteString("TcpExt: SyncookiesSent SyncookiesRecv SyncookiesFailed " +
-		"EmbryonicRsts PruneCalled RcvPruned OfoPruned OutOfWindowIcmps " +
-		"LockDroppedIcmps ArpFilter TW TWRecycled TWKilled PAWSPassive " +
-		"PAWSActive PAWSEstab DelayedACKs DelayedACKLocked DelayedACKLost " +
-		"ListenOverflows ListenDrops TCPPrequeued TCPDirectCopyFromBacklog " +
-		"TCPDirectCopyFromPrequeue TCPPrequeueDropped TCPHPHits TCPHPHitsToUser " +
-		"TCPPureAcks TCPHPAcks TCPRenoRecovery TCPSackRecovery TCPSACKReneging " +
-		"TCPFACKReorder TCPSACKReorder TCPRenoReorder TCPTSReorder TCPFullUndo " +
-		"TCPPartialUndo TCPDSACKUndo TCPLossUndo TCPLostRetransmit " +
-		"TCPRenoFailures TCPSackFailures TCPLossFailures TCPFastRetrans " +
-		"TCPForwardRetrans TCPSlowStartRetrans TCPTimeouts TCPLossProbes " +
-		"TCPLossProbeRecovery TCPRenoRecoveryFail TCPSackRecoveryFail " +
-		"TCPSchedulerFailed TCPRcvCollapsed TCPDSACKOldSent TCPDSACKOfoSent " +
-		"TCPDSACKRecv TCPDSACKOfoRecv TCPAbortOnData TCPAbortOnClose " +
-		"TCPAbortOnMemory TCPAbortOnTimeout TCPAbortOnLinger TCPAbortFailed " +
-		"TCPMemoryPressures TCPSACKDiscard TCPDSACKIgnoredOld " +
-		"TCPDSACKIgnoredNoUndo TCPSpuriousRTOs TCPMD5NotFound TCPMD5Unexpected " +
-		"TCPMD5Failure TCPSackShifted TCPSackMerged TCPSackShiftFallback " +
-		"TCPBacklogDrop TCPMinTTLDrop TCPDeferAcceptDrop IPReversePathFilter " +
-		"TCPTimeWaitOverflow TCPReqQFullDoCookies TCPReqQFullDrop TCPRetransFail " +
-		"TCPRcvCoalesce TCPOFOQueue TCPOFODrop TCPOFOMerge TCPChallengeACK " +
-		"TCPSYNChallenge TCPFastOpenActive TCPFastOpenActiveFail " +
-		"TCPFastOpenPassive TCPFastOpenPassiveFail TCPFastOpenListenOverflow " +
-		"TCPFastOpenCookieReqd TCPSpuriousRtxHostQueues BusyPollRxPackets " +
-		"TCPAutoCorking TCPFromZeroWindowAdv TCPToZeroWindowAdv " +
-		"TCPWantZeroWindowAdv TCPSynRetrans TCPOrigDataSent TCPHystartTrainDetect " +
-		"TCPHystartTrainCwnd TCPHystartDelayDetect TCPHystartDelayCwnd " +
-		"TCPACKSkippedSynRecv TCPACKSkippedPAWS TCPACKSkippedSeq " +
-		"TCPACKSkippedFinWait2 TCPACKSkippedTimeWait TCPACKSkippedChallenge " +
-		"TCPWinProbe TCPKeepAlive TCPMTUPFail TCPMTUPSuccess\n")
+	types := []any{
+		&inet.StatNetstatTCPExt{},
+		&inet.StatNetstatIPExt{},
+	}
+	for i, stat := range types {
+		line := netstat[i]
+		if err := d.stack.Statistics(stat, line.prefix); err != nil {
+			if linuxerr.Equals(linuxerr.EOPNOTSUPP, err) {
+				log.Infof("Failed to retrieve %s of /proc/net/netstat: %v", line.prefix, err)
+			} else {
+				log.Warningf("Failed to retrieve %s of /proc/net/netstat: %v", line.prefix, err)
+			}
+		}
+
+		fmt.Fprintf(buf, "%s: %s\n", line.prefix, line.header)
+		fmt.Fprintf(buf, "%s: %s\n", line.prefix, sprintSlice(toSlice(stat)))
+	}
 	return nil
 }
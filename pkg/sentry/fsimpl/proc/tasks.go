@@ -75,14 +75,19 @@ func (fs *filesystem) newTasksInode(ctx context.Context, k *kernel.Kernel, pidns
 		"bus":            fs.newStaticDir(ctx, root, map[string]kernfs.Inode{}),
 		"fs":             fs.newStaticDir(ctx, root, map[string]kernfs.Inode{}),
 		"irq":            fs.newStaticDir(ctx, root, map[string]kernfs.Inode{}),
+		"kmsg":           fs.newKmsgInode(ctx, root),
+		"kpagecount":     fs.newKpageInode(ctx, root, true /* counts */),
+		"kpageflags":     fs.newKpageInode(ctx, root, false /* counts */),
 		"meminfo":        fs.newInode(ctx, root, 0444, &meminfoData{}),
 		"mounts":         kernfs.NewStaticSymlink(ctx, root, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), "self/mounts"),
 		"net":            kernfs.NewStaticSymlink(ctx, root, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), "self/net"),
 		"sentry-meminfo": fs.newInode(ctx, root, 0444, &sentryMeminfoData{}),
 		"stat":           fs.newInode(ctx, root, 0444, &statData{}),
+		"swaps":          fs.newInode(ctx, root, 0444, &swapsData{}),
 		"sysrq-trigger":  fs.newInode(ctx, root, 0200, newStaticFile("")),
 		"uptime":         fs.newInode(ctx, root, 0444, &uptimeData{}),
 		"version":        fs.newInode(ctx, root, 0444, &versionData{}),
+		"zoneinfo":       fs.newInode(ctx, root, 0444, &zoneinfoData{}),
 	}
 	// If fakeCgroupControllers are provided, don't create a cgroupfs backed
 	// /proc/cgroup as it will not match the fake controllers.
@@ -265,8 +270,9 @@ func newStaticFileSetStat(data string) *staticFileSetStat {
 func cpuInfoData(k *kernel.Kernel) string {
 	features := k.FeatureSet()
 	var buf bytes.Buffer
+	topology := k.CPUTopology()
 	for i, max := uint(0), k.ApplicationCores(); i < max; i++ {
-		features.WriteCPUInfoTo(i, max, &buf)
+		features.WriteCPUInfoTo(i, topology, &buf)
 	}
 	return buf.String()
 }
@@ -184,14 +184,29 @@ var _ dynamicInode = (*statData)(nil)
 
 // Generate implements vfs.DynamicBytesSource.Generate.
 func (*statData) Generate(ctx context.Context, buf *bytes.Buffer) error {
-	// TODO(b/37226836): We currently export only zero CPU stats. We could
-	// at least provide some aggregate stats.
-	var cpu cpuStats
+	// CPU time isn't accounted per CPU, so divide the total evenly between
+	// CPUs.
+	k := kernel.KernelFromContext(ctx)
+	stats := k.SystemCPUStats()
+	cpu := cpuStats{
+		user:   uint64(linux.ClockTFromDuration(stats.User)),
+		system: uint64(linux.ClockTFromDuration(stats.Sys)),
+		idle:   uint64(linux.ClockTFromDuration(stats.Idle)),
+		ioWait: uint64(linux.ClockTFromDuration(stats.IOWait)),
+		steal:  uint64(linux.ClockTFromDuration(stats.Steal)),
+	}
 	fmt.Fprintf(buf, "cpu  %s\n", cpu)
 
-	k := kernel.KernelFromContext(ctx)
-	for c, max := uint(0), k.ApplicationCores(); c < max; c++ {
-		fmt.Fprintf(buf, "cpu%d %s\n", c, cpu)
+	cores := uint64(k.ApplicationCores())
+	perCPU := cpuStats{
+		user:   cpu.user / cores,
+		system: cpu.system / cores,
+		idle:   cpu.idle / cores,
+		ioWait: cpu.ioWait / cores,
+		steal:  cpu.steal / cores,
+	}
+	for c := uint64(0); c < cores; c++ {
+		fmt.Fprintf(buf, "cpu%d %s\n", c, perCPU)
 	}
 
 	// The total number of interrupts is dependent on the CPUs and PCI
@@ -221,12 +236,10 @@ func (*statData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	fmt.Fprintf(buf, "processes 0\n")
 
 	// Number of runnable tasks.
-	// TODO(b/37226836): Count this.
-	fmt.Fprintf(buf, "procs_running 0\n")
+	fmt.Fprintf(buf, "procs_running %d\n", stats.Running)
 
 	// Number of tasks waiting on IO.
-	// TODO(b/37226836): Count this.
-	fmt.Fprintf(buf, "procs_blocked 0\n")
+	fmt.Fprintf(buf, "procs_blocked %d\n", stats.Blocked)
 
 	// Number of each softirq handled.
 	fmt.Fprintf(buf, "softirq 0") // total
@@ -267,7 +280,8 @@ var _ dynamicInode = (*meminfoData)(nil)
 
 // Generate implements vfs.DynamicBytesSource.Generate.
 func (*meminfoData) Generate(ctx context.Context, buf *bytes.Buffer) error {
-	mf := kernel.KernelFromContext(ctx).MemoryFile()
+	k := kernel.KernelFromContext(ctx)
+	mf := k.MemoryFile()
 	_ = mf.UpdateUsage(nil) // Best effort
 	snapshot, totalUsage := usage.MemoryAccounting.Copy()
 	totalSize := usage.TotalMemory(mf.TotalSize(), totalUsage)
@@ -290,7 +304,7 @@ func (*meminfoData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	fmt.Fprintf(buf, "MemAvailable:   %8d kB\n", memFree/1024)
 	fmt.Fprintf(buf, "Buffers:               0 kB\n") // memory usage by block devices
 	fmt.Fprintf(buf, "Cached:         %8d kB\n", (file+snapshot.Tmpfs)/1024)
-	// Emulate a system with no swap, which disables inactivation of anon pages.
+	// Swapped pages are never also resident, so SwapCache is always 0.
 	fmt.Fprintf(buf, "SwapCache:             0 kB\n")
 	fmt.Fprintf(buf, "Active:         %8d kB\n", (anon+activeFile)/1024)
 	fmt.Fprintf(buf, "Inactive:       %8d kB\n", inactiveFile/1024)
@@ -300,8 +314,9 @@ func (*meminfoData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	fmt.Fprintf(buf, "Inactive(file): %8d kB\n", inactiveFile/1024)
 	fmt.Fprintf(buf, "Unevictable:           0 kB\n") // TODO(b/31823263)
 	fmt.Fprintf(buf, "Mlocked:               0 kB\n") // TODO(b/31823263)
-	fmt.Fprintf(buf, "SwapTotal:             0 kB\n")
-	fmt.Fprintf(buf, "SwapFree:              0 kB\n")
+	swapTotal, swapUsed := k.SwapUsage()
+	fmt.Fprintf(buf, "SwapTotal:      %8d kB\n", swapTotal/1024)
+	fmt.Fprintf(buf, "SwapFree:       %8d kB\n", (swapTotal-swapUsed)/1024)
 	fmt.Fprintf(buf, "Dirty:                 0 kB\n")
 	fmt.Fprintf(buf, "Writeback:             0 kB\n")
 	fmt.Fprintf(buf, "AnonPages:      %8d kB\n", anon/1024)
@@ -310,6 +325,42 @@ func (*meminfoData) Generate(ctx context.Context, buf *bytes.Buffer) error {
 	return nil
 }
 
+// swapsData implements vfs.DynamicBytesSource for /proc/swaps.
+//
+// +stateify savable
+type swapsData struct {
+	dynamicBytesFileSetAttr
+}
+
+var _ dynamicInode = (*swapsData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*swapsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	fmt.Fprintf(buf, "Filename\t\t\t\tType\t\tSize\t\tUsed\t\tPriority\n")
+	total, used := kernel.KernelFromContext(ctx).SwapUsage()
+	if total == 0 {
+		return nil
+	}
+	// Match Linux's mm/swapfile.c:swap_show(). The virtual swap device is
+	// reported like the first swap partition enabled without a priority.
+	name := kernel.SwapDeviceName
+	pad := 1
+	if len(name) < 40 {
+		pad = 40 - len(name)
+	}
+	sizeKB, usedKB := total/1024, used/1024
+	fmt.Fprintf(buf, "%s%*s%s\t%d\t%s%d\t%s%d\n", name, pad, " ", "partition", sizeKB, swapsTab(sizeKB), usedKB, swapsTab(usedKB), -2)
+	return nil
+}
+
+// swapsTab returns the extra separator used after n in /proc/swaps.
+func swapsTab(n uint64) string {
+	if n < 10000000 {
+		return "\t"
+	}
+	return ""
+}
+
 // uptimeData implements vfs.DynamicBytesSource for /proc/uptime.
 //
 // +stateify savable
@@ -0,0 +1,139 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proc
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+var _ kernfs.Inode = (*kmsgInode)(nil)
+
+// kmsgInode implements kernfs.Inode for /proc/kmsg.
+//
+// +stateify savable
+type kmsgInode struct {
+	kernfs.InodeAttrs
+	kernfs.InodeNoStatFS
+	kernfs.InodeNoopRefCount
+	kernfs.InodeNotAnonymous
+	kernfs.InodeNotDirectory
+	kernfs.InodeNotSymlink
+	kernfs.InodeWatches
+
+	locks vfs.FileLocks
+}
+
+func (fs *filesystem) newKmsgInode(ctx context.Context, creds *auth.Credentials) kernfs.Inode {
+	inode := &kmsgInode{}
+	inode.InodeAttrs.Init(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), linux.ModeRegular|0400)
+	return inode
+}
+
+// Open implements kernfs.Inode.Open.
+func (i *kmsgInode) Open(ctx context.Context, rp *vfs.ResolvingPath, d *kernfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	if t := kernel.TaskFromContext(ctx); t != nil && !t.MayReadSyslog() {
+		return nil, linuxerr.EPERM
+	}
+	fd := &kmsgFD{
+		inode: i,
+		k:     kernel.KernelFromContext(ctx),
+	}
+	fd.LockFD.Init(&i.locks)
+	if err := fd.vfsfd.Init(fd, opts.Flags, rp.Mount(), d.VFSDentry(), &vfs.FileDescriptionOptions{}); err != nil {
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
+// SetStat implements kernfs.Inode.SetStat.
+func (*kmsgInode) SetStat(context.Context, *vfs.Filesystem, *auth.Credentials, vfs.SetStatOptions) error {
+	return linuxerr.EPERM
+}
+
+var _ vfs.FileDescriptionImpl = (*kmsgFD)(nil)
+
+// kmsgFD implements vfs.FileDescriptionImpl for /proc/kmsg.
+//
+// Reads from /proc/kmsg consume records from the kernel log, as
+// SYSLOG_ACTION_READ does. All open files share the kernel's read position.
+//
+// +stateify savable
+type kmsgFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.LockFD
+
+	inode *kmsgInode
+	k     *kernel.Kernel
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (fd *kmsgFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	if dst.NumBytes() == 0 {
+		return 0, nil
+	}
+	text, err := fd.k.Syslog().Read(int(min(dst.NumBytes(), kernel.SyslogBufLen)))
+	if err != nil {
+		return 0, err
+	}
+	n, err := dst.CopyOut(ctx, text)
+	return int64(n), err
+}
+
+// Readiness implements waiter.Waitable.Readiness.
+func (fd *kmsgFD) Readiness(mask waiter.EventMask) waiter.EventMask {
+	if fd.k.Syslog().SizeUnread() > 0 {
+		return waiter.ReadableEvents & mask
+	}
+	return 0
+}
+
+// EventRegister implements waiter.Waitable.EventRegister.
+func (fd *kmsgFD) EventRegister(e *waiter.Entry) error {
+	fd.k.Syslog().EventRegister(e)
+	return nil
+}
+
+// EventUnregister implements waiter.Waitable.EventUnregister.
+func (fd *kmsgFD) EventUnregister(e *waiter.Entry) {
+	fd.k.Syslog().EventUnregister(e)
+}
+
+// Epollable implements FileDescriptionImpl.Epollable.
+func (fd *kmsgFD) Epollable() bool {
+	return true
+}
+
+// Stat implements vfs.FileDescriptionImpl.Stat.
+func (fd *kmsgFD) Stat(ctx context.Context, opts vfs.StatOptions) (linux.Statx, error) {
+	fs := fd.vfsfd.VirtualDentry().Mount().Filesystem()
+	return fd.inode.Stat(ctx, fs, opts)
+}
+
+// SetStat implements vfs.FileDescriptionImpl.SetStat.
+func (fd *kmsgFD) SetStat(context.Context, vfs.SetStatOptions) error {
+	return linuxerr.EPERM
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *kmsgFD) Release(context.Context) {}
@@ -0,0 +1,356 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proc
+
+import (
+	"bytes"
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// The sentry has no physical page frames to report. /proc/kpageflags,
+// /proc/kpagecount and /proc/zoneinfo instead describe a synthetic "physical
+// memory" whose size is the total memory reported by /proc/meminfo, and whose
+// frames are assigned, in order, to each usage.MemoryKind tracked by the
+// memory accounting. The remaining frames are reported as free. This keeps the
+// three files consistent with each other and with /proc/meminfo.
+
+// kpageEntrySize is the size of each entry in /proc/kpageflags and
+// /proc/kpagecount, in bytes.
+const kpageEntrySize = 8
+
+// kpageReadChunkSize is the maximum number of bytes of /proc/kpageflags or
+// /proc/kpagecount that are generated at once.
+const kpageReadChunkSize = 512 * kpageEntrySize
+
+// kpageRegion is a run of synthetic page frames that share flags and map
+// count.
+type kpageRegion struct {
+	pages uint64
+	flags uint64
+	count uint64
+}
+
+// kpageLayout is a synthetic physical page frame layout.
+type kpageLayout struct {
+	totalPages uint64
+	regions    []kpageRegion
+}
+
+func kpf(bits ...uint) uint64 {
+	var flags uint64
+	for _, b := range bits {
+		flags |= 1 << b
+	}
+	return flags
+}
+
+// newKpageLayout returns the page frame layout for a system with totalSize
+// bytes of memory and the given memory usage.
+func newKpageLayout(totalSize uint64, snapshot usage.MemoryStats) kpageLayout {
+	l := kpageLayout{totalPages: totalSize / hostarch.PageSize}
+	remaining := l.totalPages
+	add := func(size, flags, count uint64) {
+		pages := (size + hostarch.PageSize - 1) / hostarch.PageSize
+		if pages > remaining {
+			pages = remaining
+		}
+		if pages == 0 {
+			return
+		}
+		remaining -= pages
+		l.regions = append(l.regions, kpageRegion{pages: pages, flags: flags, count: count})
+	}
+	add(snapshot.System, kpf(linux.KPF_SLAB), 0)
+	add(snapshot.Anonymous, kpf(linux.KPF_UPTODATE, linux.KPF_LRU, linux.KPF_ACTIVE, linux.KPF_MMAP, linux.KPF_ANON, linux.KPF_SWAPBACKED), 1)
+	add(snapshot.Tmpfs+snapshot.Ramdiskfs, kpf(linux.KPF_UPTODATE, linux.KPF_LRU, linux.KPF_SWAPBACKED), 0)
+	add(snapshot.Mapped, kpf(linux.KPF_UPTODATE, linux.KPF_LRU, linux.KPF_ACTIVE, linux.KPF_MMAP), 1)
+	add(snapshot.PageCache, kpf(linux.KPF_UPTODATE, linux.KPF_LRU), 0)
+	add(remaining*hostarch.PageSize, kpf(linux.KPF_BUDDY), 0)
+	return l
+}
+
+// size returns the size of /proc/kpageflags and /proc/kpagecount for l, in
+// bytes.
+func (l *kpageLayout) size() int64 {
+	return int64(l.totalPages) * kpageEntrySize
+}
+
+// currentKpageLayout returns the page frame layout for the current memory
+// usage of the kernel in ctx.
+func currentKpageLayout(ctx context.Context) kpageLayout {
+	mf := kernel.KernelFromContext(ctx).MemoryFile()
+	_ = mf.UpdateUsage(nil) // Best effort
+	snapshot, totalUsage := usage.MemoryAccounting.Copy()
+	return newKpageLayout(usage.TotalMemory(mf.TotalSize(), totalUsage), snapshot)
+}
+
+// fill writes the flags (if !counts) or map counts (if counts) of the page
+// frames starting at pfn to dst, in native byte order, and returns the number
+// of bytes written.
+func (l *kpageLayout) fill(dst []byte, pfn uint64, counts bool) int {
+	n := 0
+	start := uint64(0)
+	for _, r := range l.regions {
+		end := start + r.pages
+		val := r.flags
+		if counts {
+			val = r.count
+		}
+		for ; pfn < end && n+kpageEntrySize <= len(dst); pfn++ {
+			hostarch.ByteOrder.PutUint64(dst[n:], val)
+			n += kpageEntrySize
+		}
+		start = end
+	}
+	return n
+}
+
+var _ kernfs.Inode = (*kpageInode)(nil)
+
+// kpageInode implements kernfs.Inode for /proc/kpageflags and
+// /proc/kpagecount.
+//
+// +stateify savable
+type kpageInode struct {
+	kernfs.InodeAttrs
+	kernfs.InodeNoStatFS
+	kernfs.InodeNoopRefCount
+	kernfs.InodeNotAnonymous
+	kernfs.InodeNotDirectory
+	kernfs.InodeNotSymlink
+	kernfs.InodeWatches
+
+	// counts is true for /proc/kpagecount and false for /proc/kpageflags.
+	counts bool
+	locks  vfs.FileLocks
+}
+
+func (fs *filesystem) newKpageInode(ctx context.Context, creds *auth.Credentials, counts bool) kernfs.Inode {
+	inode := &kpageInode{counts: counts}
+	inode.InodeAttrs.Init(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), linux.ModeRegular|0400)
+	return inode
+}
+
+// Open implements kernfs.Inode.Open.
+func (i *kpageInode) Open(ctx context.Context, rp *vfs.ResolvingPath, d *kernfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	fd := &kpageFD{inode: i}
+	fd.LockFD.Init(&i.locks)
+	if err := fd.vfsfd.Init(fd, opts.Flags, rp.Mount(), d.VFSDentry(), &vfs.FileDescriptionOptions{}); err != nil {
+		return nil, err
+	}
+	return &fd.vfsfd, nil
+}
+
+// SetStat implements kernfs.Inode.SetStat.
+func (*kpageInode) SetStat(context.Context, *vfs.Filesystem, *auth.Credentials, vfs.SetStatOptions) error {
+	return linuxerr.EPERM
+}
+
+var _ vfs.FileDescriptionImpl = (*kpageFD)(nil)
+
+// kpageFD implements vfs.FileDescriptionImpl for /proc/kpageflags and
+// /proc/kpagecount.
+//
+// +stateify savable
+type kpageFD struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.LockFD
+
+	inode *kpageInode
+
+	// mu guards the fields below.
+	mu     sync.Mutex `state:"nosave"`
+	offset int64
+}
+
+// Seek implements vfs.FileDescriptionImpl.Seek.
+func (fd *kpageFD) Seek(ctx context.Context, offset int64, whence int32) (int64, error) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	switch whence {
+	case linux.SEEK_SET:
+	case linux.SEEK_CUR:
+		offset += fd.offset
+	case linux.SEEK_END:
+		l := currentKpageLayout(ctx)
+		offset += l.size()
+	default:
+		return 0, linuxerr.EINVAL
+	}
+	if offset < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	fd.offset = offset
+	return offset, nil
+}
+
+// PRead implements vfs.FileDescriptionImpl.PRead.
+func (fd *kpageFD) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
+	// Linux's fs/proc/page.c requires reads to be entry-aligned.
+	if offset < 0 || offset%kpageEntrySize != 0 || dst.NumBytes()%kpageEntrySize != 0 {
+		return 0, linuxerr.EINVAL
+	}
+	if dst.NumBytes() == 0 {
+		return 0, nil
+	}
+	l := currentKpageLayout(ctx)
+	pfn := uint64(offset) / kpageEntrySize
+	if pfn >= l.totalPages {
+		return 0, nil
+	}
+	size := dst.NumBytes()
+	if rem := l.size() - offset; size > rem {
+		size = rem
+	}
+	// Generate the requested range in chunks, so that large reads don't
+	// allocate a buffer as large as the whole table.
+	buf := make([]byte, min(size, kpageReadChunkSize))
+	var total int64
+	for total < size {
+		chunk := buf[:min(size-total, int64(len(buf)))]
+		n := l.fill(chunk, pfn, fd.inode.counts)
+		if n == 0 {
+			break
+		}
+		written, err := dst.CopyOut(ctx, chunk[:n])
+		total += int64(written)
+		if err != nil {
+			return total, err
+		}
+		dst = dst.DropFirst(written)
+		pfn += uint64(n) / kpageEntrySize
+	}
+	return total, nil
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (fd *kpageFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	fd.mu.Lock()
+	n, err := fd.PRead(ctx, dst, fd.offset, opts)
+	fd.offset += n
+	fd.mu.Unlock()
+	return n, err
+}
+
+// Stat implements vfs.FileDescriptionImpl.Stat.
+func (fd *kpageFD) Stat(ctx context.Context, opts vfs.StatOptions) (linux.Statx, error) {
+	fs := fd.vfsfd.VirtualDentry().Mount().Filesystem()
+	return fd.inode.Stat(ctx, fs, opts)
+}
+
+// SetStat implements vfs.FileDescriptionImpl.SetStat.
+func (fd *kpageFD) SetStat(context.Context, vfs.SetStatOptions) error {
+	return linuxerr.EPERM
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (fd *kpageFD) Release(context.Context) {}
+
+// zoneinfoData implements vfs.DynamicBytesSource for /proc/zoneinfo.
+//
+// +stateify savable
+type zoneinfoData struct {
+	dynamicBytesFileSetAttr
+}
+
+var _ dynamicInode = (*zoneinfoData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*zoneinfoData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	l := currentKpageLayout(ctx)
+
+	// Report a single node with a single Normal zone spanning all page frames.
+	var free, anon, shmem, activeFile, inactiveFile, slab, mapped uint64
+	for _, r := range l.regions {
+		switch {
+		case r.flags&kpf(linux.KPF_BUDDY) != 0:
+			free += r.pages
+		case r.flags&kpf(linux.KPF_SLAB) != 0:
+			slab += r.pages
+		case r.flags&kpf(linux.KPF_ANON) != 0:
+			anon += r.pages
+		case r.flags&kpf(linux.KPF_SWAPBACKED) != 0:
+			shmem += r.pages
+		case r.flags&kpf(linux.KPF_ACTIVE) != 0:
+			activeFile += r.pages
+			mapped += r.pages
+		default:
+			inactiveFile += r.pages
+		}
+	}
+	// Watermarks are derived the same way as Linux's defaults for a zone
+	// without min_free_kbytes tuning: min is roughly 1/256 of the zone.
+	minPages := l.totalPages / 256
+	lowPages := minPages + minPages/4
+	highPages := minPages + minPages/2
+
+	fmt.Fprintf(buf, "Node 0, zone   Normal\n")
+	fmt.Fprintf(buf, "  per-node stats\n")
+	fmt.Fprintf(buf, "      nr_inactive_anon 0\n")
+	fmt.Fprintf(buf, "      nr_active_anon %d\n", anon+shmem)
+	fmt.Fprintf(buf, "      nr_inactive_file %d\n", inactiveFile)
+	fmt.Fprintf(buf, "      nr_active_file %d\n", activeFile)
+	fmt.Fprintf(buf, "      nr_unevictable 0\n")
+	fmt.Fprintf(buf, "      nr_anon_pages %d\n", anon)
+	fmt.Fprintf(buf, "      nr_mapped    %d\n", mapped)
+	fmt.Fprintf(buf, "      nr_file_pages %d\n", activeFile+inactiveFile+shmem)
+	fmt.Fprintf(buf, "      nr_dirty     0\n")
+	fmt.Fprintf(buf, "      nr_writeback 0\n")
+	fmt.Fprintf(buf, "      nr_shmem     %d\n", shmem)
+	fmt.Fprintf(buf, "  pages free     %d\n", free)
+	fmt.Fprintf(buf, "        boost    0\n")
+	fmt.Fprintf(buf, "        min      %d\n", minPages)
+	fmt.Fprintf(buf, "        low      %d\n", lowPages)
+	fmt.Fprintf(buf, "        high     %d\n", highPages)
+	fmt.Fprintf(buf, "        spanned  %d\n", l.totalPages)
+	fmt.Fprintf(buf, "        present  %d\n", l.totalPages)
+	fmt.Fprintf(buf, "        managed  %d\n", l.totalPages)
+	fmt.Fprintf(buf, "        cma      0\n")
+	fmt.Fprintf(buf, "        protection: (0, 0, 0, 0)\n")
+	fmt.Fprintf(buf, "      nr_free_pages %d\n", free)
+	fmt.Fprintf(buf, "      nr_zone_inactive_anon 0\n")
+	fmt.Fprintf(buf, "      nr_zone_active_anon %d\n", anon+shmem)
+	fmt.Fprintf(buf, "      nr_zone_inactive_file %d\n", inactiveFile)
+	fmt.Fprintf(buf, "      nr_zone_active_file %d\n", activeFile)
+	fmt.Fprintf(buf, "      nr_zone_unevictable 0\n")
+	fmt.Fprintf(buf, "      nr_zone_write_pending 0\n")
+	fmt.Fprintf(buf, "      nr_mlock     0\n")
+	fmt.Fprintf(buf, "      nr_slab_unreclaimable %d\n", slab)
+	fmt.Fprintf(buf, "  pagesets\n")
+	k := kernel.KernelFromContext(ctx)
+	for cpu := uint(0); cpu < k.ApplicationCores(); cpu++ {
+		fmt.Fprintf(buf, "    cpu: %d\n", cpu)
+		fmt.Fprintf(buf, "              count: 0\n")
+		fmt.Fprintf(buf, "              high:  0\n")
+		fmt.Fprintf(buf, "              batch: 1\n")
+		fmt.Fprintf(buf, "  vm stats threshold: 0\n")
+	}
+	fmt.Fprintf(buf, "  node_unreclaimable:  0\n")
+	fmt.Fprintf(buf, "  start_pfn:           0\n")
+	return nil
+}
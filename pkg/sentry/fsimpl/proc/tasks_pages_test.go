@@ -0,0 +1,68 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proc
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+)
+
+func TestKpageLayout(t *testing.T) {
+	const totalPages = 64
+	l := newKpageLayout(totalPages*hostarch.PageSize, usage.MemoryStats{
+		Anonymous: 4 * hostarch.PageSize,
+		PageCache: 2*hostarch.PageSize + 1, // Rounded up.
+	})
+	if l.totalPages != totalPages {
+		t.Fatalf("got totalPages %d, want %d", l.totalPages, totalPages)
+	}
+	if got, want := l.size(), int64(totalPages*kpageEntrySize); got != want {
+		t.Errorf("got size %d, want %d", got, want)
+	}
+
+	buf := make([]byte, totalPages*kpageEntrySize)
+	if n := l.fill(buf, 0, false /* counts */); n != len(buf) {
+		t.Fatalf("fill returned %d, want %d", n, len(buf))
+	}
+	var anon, cache, free int
+	for pfn := 0; pfn < totalPages; pfn++ {
+		flags := hostarch.ByteOrder.Uint64(buf[pfn*kpageEntrySize:])
+		switch {
+		case flags&kpf(linux.KPF_ANON) != 0:
+			anon++
+		case flags&kpf(linux.KPF_BUDDY) != 0:
+			free++
+		case flags&kpf(linux.KPF_LRU) != 0:
+			cache++
+		}
+	}
+	if anon != 4 || cache != 3 || free != totalPages-7 {
+		t.Errorf("got anon=%d cache=%d free=%d, want anon=4 cache=3 free=%d", anon, cache, free, totalPages-7)
+	}
+
+	// Reads starting mid-layout return the counts of the following frames.
+	if n := l.fill(buf[:2*kpageEntrySize], 3, true /* counts */); n != 2*kpageEntrySize {
+		t.Fatalf("fill returned %d, want %d", n, 2*kpageEntrySize)
+	}
+	if got := hostarch.ByteOrder.Uint64(buf); got != 1 {
+		t.Errorf("got count %d for anonymous frame, want 1", got)
+	}
+	if got := hostarch.ByteOrder.Uint64(buf[kpageEntrySize:]); got != 0 {
+		t.Errorf("got count %d for page cache frame, want 0", got)
+	}
+}
@@ -19,6 +19,8 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"strconv"
+	"strings"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
@@ -48,10 +50,12 @@ const (
 func (fs *filesystem) newSysDir(ctx context.Context, root *auth.Credentials, k *kernel.Kernel) kernfs.Inode {
 	return fs.newStaticDir(ctx, root, map[string]kernfs.Inode{
 		"kernel": fs.newStaticDir(ctx, root, map[string]kernfs.Inode{
-			"cap_last_cap": fs.newInode(ctx, root, 0444, newStaticFile(fmt.Sprintf("%d\n", linux.CAP_LAST_CAP))),
-			"hostname":     fs.newInode(ctx, root, 0444, &hostnameData{}),
-			"overflowgid":  fs.newInode(ctx, root, 0444, newStaticFile(fmt.Sprintf("%d\n", auth.OverflowGID))),
-			"overflowuid":  fs.newInode(ctx, root, 0444, newStaticFile(fmt.Sprintf("%d\n", auth.OverflowUID))),
+			"cap_last_cap":   fs.newInode(ctx, root, 0444, newStaticFile(fmt.Sprintf("%d\n", linux.CAP_LAST_CAP))),
+			"core_pattern":   fs.newInode(ctx, root, 0644, &corePatternData{k: k}),
+			"dmesg_restrict": fs.newInode(ctx, root, 0644, &atomicInt32File{val: &k.DmesgRestrict, min: 0, max: 1}),
+			"hostname":       fs.newInode(ctx, root, 0444, &hostnameData{}),
+			"overflowgid":    fs.newInode(ctx, root, 0444, newStaticFile(fmt.Sprintf("%d\n", auth.OverflowGID))),
+			"overflowuid":    fs.newInode(ctx, root, 0444, newStaticFile(fmt.Sprintf("%d\n", auth.OverflowUID))),
 			"random": fs.newStaticDir(ctx, root, map[string]kernfs.Inode{
 				"boot_id": fs.newInode(ctx, root, 0444, newStaticFile(randUUID())),
 			}),
@@ -67,13 +71,22 @@ func (fs *filesystem) newSysDir(ctx context.Context, root *auth.Credentials, k *
 			}),
 		}),
 		"fs": fs.newStaticDir(ctx, root, map[string]kernfs.Inode{
-			"nr_open": fs.newInode(ctx, root, 0644, &atomicInt32File{val: &k.MaxFDLimit, min: 8, max: kernel.MaxFdLimit}),
+			// Mount point for binfmt_misc, as created by Linux's
+			// fs/binfmt_misc.c:init_misc_binfmt().
+			"binfmt_misc": fs.newStaticDir(ctx, root, map[string]kernfs.Inode{}),
+			"file-max":    fs.newInode(ctx, root, 0644, &atomicInt64File{val: &k.MaxFiles, min: 0, max: math.MaxInt64}),
+			"file-nr":     fs.newInode(ctx, root, 0444, &fileNrData{k: k}),
+			"nr_open":     fs.newInode(ctx, root, 0644, &atomicInt32File{val: &k.MaxFDLimit, min: 8, max: kernel.MaxFdLimit}),
 		}),
 		"vm": fs.newStaticDir(ctx, root, map[string]kernfs.Inode{
 			"max_map_count":     fs.newInode(ctx, root, 0444, newStaticFile("2147483647\n")),
+			"memfd_noexec":      fs.newInode(ctx, root, 0644, &atomicInt32File{val: &k.MemfdNoexecScope, min: linux.MEMFD_NOEXEC_SCOPE_EXEC, max: linux.MEMFD_NOEXEC_SCOPE_NOEXEC_ENFORCED}),
 			"mmap_min_addr":     fs.newInode(ctx, root, 0444, &mmapMinAddrData{k: k}),
 			"overcommit_memory": fs.newInode(ctx, root, 0444, newStaticFile("0\n")),
 		}),
+		"user": fs.newStaticDir(ctx, root, map[string]kernfs.Inode{
+			"max_user_namespaces": fs.newInode(ctx, root, 0644, &atomicInt32File{val: &k.MaxUserNamespaces, min: 0, max: math.MaxInt32}),
+		}),
 		"net": fs.newSysNetDir(ctx, root, k),
 	})
 }
@@ -89,6 +102,7 @@ func (fs *filesystem) newSysNetDir(ctx context.Context, root *auth.Credentials,
 			"ipv4": fs.newStaticDir(ctx, root, map[string]kernfs.Inode{
 				"ip_forward":          fs.newInode(ctx, root, 0444, &ipForwarding{stack: stack}),
 				"ip_local_port_range": fs.newInode(ctx, root, 0644, &portRange{stack: stack}),
+				"tcp_ecn":             fs.newInode(ctx, root, 0644, &tcpECNData{stack: stack}),
 				"tcp_recovery":        fs.newInode(ctx, root, 0644, &tcpRecoveryData{stack: stack}),
 				"tcp_rmem":            fs.newInode(ctx, root, 0644, &tcpMemData{stack: stack, dir: tcpRMem}),
 				"tcp_sack":            fs.newInode(ctx, root, 0644, &tcpSackData{stack: stack}),
@@ -169,6 +183,60 @@ func (d *mmapMinAddrData) Generate(ctx context.Context, buf *bytes.Buffer) error
 	return nil
 }
 
+// corePatternData implements vfs.WritableDynamicBytesSource for
+// /proc/sys/kernel/core_pattern.
+//
+// +stateify savable
+type corePatternData struct {
+	kernfs.DynamicBytesFile
+
+	k *kernel.Kernel
+}
+
+var _ vfs.WritableDynamicBytesSource = (*corePatternData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *corePatternData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	buf.WriteString(d.k.CorePattern())
+	buf.WriteString("\n")
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *corePatternData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	if offset != 0 {
+		// Ignore partial writes.
+		return 0, linuxerr.EINVAL
+	}
+	if src.NumBytes() == 0 {
+		return 0, nil
+	}
+	// core_pattern is global to the sandbox and may specify a program to
+	// run, so unlike Linux, which only requires write access to the file,
+	// writers must be privileged in the root user namespace.
+	if !auth.CredentialsFromContext(ctx).HasCapabilityIn(linux.CAP_SYS_ADMIN, d.k.RootUserNamespace()) {
+		return 0, linuxerr.EPERM
+	}
+	// Limit the amount of memory allocated.
+	src = src.TakeFirst(hostarch.PageSize - 1)
+	buf := make([]byte, src.NumBytes())
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, err
+	}
+	// As in Linux's kernel/sysctl.c:proc_dostring(), the value ends at the
+	// first newline.
+	pattern, _, _ := strings.Cut(string(buf[:n]), "\n")
+	var cid string
+	if t := kernel.TaskFromContext(ctx); t != nil {
+		cid = t.ContainerID()
+	}
+	if err := d.k.SetCorePattern(pattern, cid); err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
+
 // hostnameData implements vfs.DynamicBytesSource for /proc/sys/kernel/hostname.
 //
 // +stateify savable
@@ -279,6 +347,51 @@ func (d *tcpRecoveryData) Write(ctx context.Context, _ *vfs.FileDescription, src
 	return n, nil
 }
 
+// tcpECNData implements vfs.WritableDynamicBytesSource for
+// /proc/sys/net/ipv4/tcp_ecn.
+//
+// +stateify savable
+type tcpECNData struct {
+	kernfs.DynamicBytesFile
+
+	stack inet.Stack `state:"wait"`
+}
+
+var _ vfs.WritableDynamicBytesSource = (*tcpECNData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *tcpECNData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	mode, err := d.stack.TCPECN()
+	if err != nil {
+		return err
+	}
+
+	_, err = buf.WriteString(fmt.Sprintf("%d\n", mode))
+	return err
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *tcpECNData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	if offset != 0 {
+		// No need to handle partial writes thus far.
+		return 0, linuxerr.EINVAL
+	}
+	buf := make([]int32, 1)
+	n, err := ParseInt32Vec(ctx, src, buf)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+	// Linux accepts 0 (disabled), 1 (enabled) and 2 (enabled when requested
+	// by the peer).
+	if buf[0] < 0 || buf[0] > 2 {
+		return 0, linuxerr.EINVAL
+	}
+	if err := d.stack.SetTCPECN(buf[0]); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
 // tcpMemData implements vfs.WritableDynamicBytesSource for
 // /proc/sys/net/ipv4/tcp_rmem and /proc/sys/net/ipv4/tcp_wmem.
 //
@@ -503,6 +616,70 @@ func (f *atomicInt32File) Write(ctx context.Context, _ *vfs.FileDescription, src
 	return n, nil
 }
 
+// atomicInt64File implements vfs.WritableDynamicBytesSource sysctls
+// represented by int64 atomic objects.
+//
+// +stateify savable
+type atomicInt64File struct {
+	kernfs.DynamicBytesFile
+
+	val      *atomicbitops.Int64
+	min, max int64
+}
+
+var _ vfs.WritableDynamicBytesSource = (*atomicInt64File)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (f *atomicInt64File) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	_, err := fmt.Fprintf(buf, "%d\n", f.val.Load())
+	return err
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (f *atomicInt64File) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	if offset != 0 {
+		// Ignore partial writes.
+		return 0, linuxerr.EINVAL
+	}
+	if src.NumBytes() == 0 {
+		return 0, nil
+	}
+
+	// Limit input size so as not to impact performance if input size is large.
+	src = src.TakeFirst(hostarch.PageSize - 1)
+	buf := make([]byte, src.NumBytes())
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(string(buf[:n])), 10, 64)
+	if err != nil || v < f.min || v > f.max {
+		return 0, linuxerr.EINVAL
+	}
+
+	f.val.Store(v)
+	return int64(n), nil
+}
+
+// fileNrData implements vfs.DynamicBytesSource for /proc/sys/fs/file-nr.
+//
+// +stateify savable
+type fileNrData struct {
+	kernfs.DynamicBytesFile
+
+	k *kernel.Kernel
+}
+
+var _ dynamicInode = (*fileNrData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *fileNrData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	// The second field is the number of allocated but unused files, which has
+	// always been 0 since Linux 2.6.
+	_, err := fmt.Fprintf(buf, "%d\t0\t%d\n", d.k.VFS().OpenFiles(), d.k.MaxFiles.Load())
+	return err
+}
+
 // randUUID returns a string containing a randomly-generated UUID followed by a
 // newline.
 func randUUID() string {
@@ -53,6 +53,9 @@ var (
 		"filesystems":    linux.DT_REG,
 		"fs":             linux.DT_DIR,
 		"irq":            linux.DT_DIR,
+		"kmsg":           linux.DT_REG,
+		"kpagecount":     linux.DT_REG,
+		"kpageflags":     linux.DT_REG,
 		"loadavg":        linux.DT_REG,
 		"meminfo":        linux.DT_REG,
 		"mounts":         linux.DT_LNK,
@@ -60,11 +63,13 @@ var (
 		"self":           linux.DT_LNK,
 		"sentry-meminfo": linux.DT_REG,
 		"stat":           linux.DT_REG,
+		"swaps":          linux.DT_REG,
 		"sys":            linux.DT_DIR,
 		"sysrq-trigger":  linux.DT_REG,
 		"thread-self":    linux.DT_LNK,
 		"uptime":         linux.DT_REG,
 		"version":        linux.DT_REG,
+		"zoneinfo":       linux.DT_REG,
 	}
 	tasksStaticFilesNextOffs = map[string]int64{
 		"self":        selfLink.NextOff,
@@ -92,6 +97,7 @@ var (
 		"oom_score":     linux.DT_REG,
 		"oom_score_adj": linux.DT_REG,
 		"root":          linux.DT_LNK,
+		"setgroups":     linux.DT_REG,
 		"smaps":         linux.DT_REG,
 		"stat":          linux.DT_REG,
 		"statm":         linux.DT_REG,
@@ -16,6 +16,9 @@
 package signalfd
 
 import (
+	"bytes"
+	"fmt"
+
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
@@ -56,6 +59,7 @@ type SignalFileDescription struct {
 }
 
 var _ vfs.FileDescriptionImpl = (*SignalFileDescription)(nil)
+var _ vfs.FileDescriptionImplFDInfoExtension = (*SignalFileDescription)(nil)
 
 // New creates a new signal fd.
 func New(vfsObj *vfs.VirtualFilesystem, target *kernel.Task, mask linux.SignalSet, flags uint32) (*vfs.FileDescription, error) {
@@ -84,6 +88,12 @@ func (sfd *SignalFileDescription) Mask() linux.SignalSet {
 	return linux.SignalSet(sfd.entry.Mask())
 }
 
+// FDInfo implements vfs.FileDescriptionImplFDInfoExtension.FDInfo.
+func (sfd *SignalFileDescription) FDInfo(ctx context.Context, buf *bytes.Buffer) {
+	// Linux: fs/signalfd.c:signalfd_show_fdinfo()
+	fmt.Fprintf(buf, "sigmask:\t%016x\n", uint64(sfd.Mask()))
+}
+
 // SetMask sets the signal mask.
 func (sfd *SignalFileDescription) SetMask(mask linux.SignalSet) {
 	sfd.mu.Lock()
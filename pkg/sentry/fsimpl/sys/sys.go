@@ -121,7 +121,8 @@ func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	}
 	devicesSub := map[string]kernfs.Inode{
 		"system": fs.newDir(ctx, creds, defaultSysDirMode, map[string]kernfs.Inode{
-			"cpu": cpuDir(ctx, fs, creds),
+			"cpu":  cpuDir(ctx, fs, creds),
+			"node": fs.nodeDir(ctx, creds, k.CPUTopology()),
 		}),
 	}
 
@@ -205,8 +206,9 @@ func cpuDir(ctx context.Context, fs *filesystem, creds *auth.Credentials) kernfs
 		"possible": fs.newCPUFile(ctx, creds, maxCPUCores, linux.FileMode(0444)),
 		"present":  fs.newCPUFile(ctx, creds, maxCPUCores, linux.FileMode(0444)),
 	}
+	topology := k.CPUTopology()
 	for i := uint(0); i < maxCPUCores; i++ {
-		children[fmt.Sprintf("cpu%d", i)] = fs.newDir(ctx, creds, linux.FileMode(0555), nil)
+		children[fmt.Sprintf("cpu%d", i)] = fs.newDir(ctx, creds, linux.FileMode(0555), fs.cpuTopologyDir(ctx, creds, topology, i))
 	}
 	return fs.newDir(ctx, creds, defaultSysDirMode, children)
 }
@@ -250,6 +252,8 @@ func kernelDir(ctx context.Context, fs *filesystem, creds *auth.Credentials) map
 			"kcov": fs.newKcovFile(ctx, creds),
 		})
 	}
+	// Mount point for tracefs.
+	children["tracing"] = fs.newDir(ctx, creds, linux.FileMode(0700), nil)
 	return children
 }
 
@@ -85,6 +85,40 @@ func TestReadCPUFile(t *testing.T) {
 	}
 }
 
+func TestReadCPUTopologyFiles(t *testing.T) {
+	s := newTestSystem(t, "" /*pciTestDir*/)
+	defer s.Destroy()
+	k := kernel.KernelFromContext(s.Ctx)
+	maxCPUCores := k.ApplicationCores()
+	allCPUs := fmt.Sprintf("0-%d\n", maxCPUCores-1)
+	if maxCPUCores == 1 {
+		allCPUs = "0\n"
+	}
+
+	for fname, expected := range map[string]string{
+		"cpu/cpu0/topology/physical_package_id":  "0\n",
+		"cpu/cpu0/topology/core_id":              "0\n",
+		"cpu/cpu0/topology/thread_siblings_list": "0\n",
+		"cpu/cpu0/topology/core_siblings_list":   allCPUs,
+		"node/online":                            "0\n",
+		"node/node0/cpulist":                     allCPUs,
+	} {
+		pop := s.PathOpAtRoot(fmt.Sprintf("devices/system/%s", fname))
+		fd, err := s.VFS.OpenAt(s.Ctx, s.Creds, pop, &vfs.OpenOptions{})
+		if err != nil {
+			t.Fatalf("OpenAt(pop:%+v) = %+v failed: %v", pop, fd, err)
+		}
+		defer fd.DecRef(s.Ctx)
+		content, err := s.ReadToEnd(fd)
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if diff := cmp.Diff(expected, content); diff != "" {
+			t.Errorf("Read of %s returned unexpected data:\n--- want\n+++ got\n%v", fname, diff)
+		}
+	}
+}
+
 func TestSysRootContainsExpectedEntries(t *testing.T) {
 	s := newTestSystem(t, "" /*pciTestDir*/)
 	defer s.Destroy()
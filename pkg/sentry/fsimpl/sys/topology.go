@@ -0,0 +1,148 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sys
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/cpuid"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+)
+
+// cpuTopologyDir returns the contents of /sys/devices/system/cpu/cpuN for the
+// given CPU in topology t.
+//
+// Linux: drivers/base/topology.c, drivers/base/cacheinfo.c
+func (fs *filesystem) cpuTopologyDir(ctx context.Context, creds *auth.Credentials, t cpuid.Topology, cpu uint) map[string]kernfs.Inode {
+	numCPU := t.NumCPUs()
+	socket, core, _ := t.Locate(cpu)
+	threadSiblings := t.ThreadSiblings(cpu)
+	coreSiblings := t.CoreSiblings(cpu)
+	topology := map[string]kernfs.Inode{
+		"physical_package_id":  fs.newStaticFile(ctx, creds, defaultSysMode, fmt.Sprintf("%d\n", socket)),
+		"die_id":               fs.newStaticFile(ctx, creds, defaultSysMode, "0\n"),
+		"core_id":              fs.newStaticFile(ctx, creds, defaultSysMode, fmt.Sprintf("%d\n", core)),
+		"core_cpus":            fs.newStaticFile(ctx, creds, defaultSysMode, cpuMap(threadSiblings, numCPU)),
+		"core_cpus_list":       fs.newStaticFile(ctx, creds, defaultSysMode, cpuList(threadSiblings)),
+		"thread_siblings":      fs.newStaticFile(ctx, creds, defaultSysMode, cpuMap(threadSiblings, numCPU)),
+		"thread_siblings_list": fs.newStaticFile(ctx, creds, defaultSysMode, cpuList(threadSiblings)),
+		"die_cpus":             fs.newStaticFile(ctx, creds, defaultSysMode, cpuMap(coreSiblings, numCPU)),
+		"die_cpus_list":        fs.newStaticFile(ctx, creds, defaultSysMode, cpuList(coreSiblings)),
+		"package_cpus":         fs.newStaticFile(ctx, creds, defaultSysMode, cpuMap(coreSiblings, numCPU)),
+		"package_cpus_list":    fs.newStaticFile(ctx, creds, defaultSysMode, cpuList(coreSiblings)),
+		"core_siblings":        fs.newStaticFile(ctx, creds, defaultSysMode, cpuMap(coreSiblings, numCPU)),
+		"core_siblings_list":   fs.newStaticFile(ctx, creds, defaultSysMode, cpuList(coreSiblings)),
+	}
+	node := t.Node(cpu)
+	children := map[string]kernfs.Inode{
+		"topology":                  fs.newDir(ctx, creds, defaultSysDirMode, topology),
+		fmt.Sprintf("node%d", node): kernfs.NewStaticSymlink(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), fmt.Sprintf("../../node/node%d", node)),
+	}
+	if len(t.Caches) != 0 {
+		caches := make(map[string]kernfs.Inode)
+		for i, c := range t.Caches {
+			shared := t.SharedCPUs(cpu, c)
+			// Caches are identified by the first CPU that shares them,
+			// which is unique within each level.
+			caches[fmt.Sprintf("index%d", i)] = fs.newDir(ctx, creds, defaultSysDirMode, map[string]kernfs.Inode{
+				"id":                      fs.newStaticFile(ctx, creds, defaultSysMode, fmt.Sprintf("%d\n", shared[0])),
+				"level":                   fs.newStaticFile(ctx, creds, defaultSysMode, fmt.Sprintf("%d\n", c.Level)),
+				"type":                    fs.newStaticFile(ctx, creds, defaultSysMode, c.Type+"\n"),
+				"size":                    fs.newStaticFile(ctx, creds, defaultSysMode, fmt.Sprintf("%dK\n", c.Size>>10)),
+				"ways_of_associativity":   fs.newStaticFile(ctx, creds, defaultSysMode, fmt.Sprintf("%d\n", c.Ways)),
+				"coherency_line_size":     fs.newStaticFile(ctx, creds, defaultSysMode, fmt.Sprintf("%d\n", c.LineSize)),
+				"number_of_sets":          fs.newStaticFile(ctx, creds, defaultSysMode, fmt.Sprintf("%d\n", c.Sets())),
+				"physical_line_partition": fs.newStaticFile(ctx, creds, defaultSysMode, "1\n"),
+				"shared_cpu_map":          fs.newStaticFile(ctx, creds, defaultSysMode, cpuMap(shared, numCPU)),
+				"shared_cpu_list":         fs.newStaticFile(ctx, creds, defaultSysMode, cpuList(shared)),
+			})
+		}
+		children["cache"] = fs.newDir(ctx, creds, defaultSysDirMode, caches)
+	}
+	return children
+}
+
+// nodeDir returns /sys/devices/system/node for topology t.
+//
+// Linux: drivers/base/node.c
+func (fs *filesystem) nodeDir(ctx context.Context, creds *auth.Credentials, t cpuid.Topology) kernfs.Inode {
+	nodes := fmt.Sprintf("0-%d\n", t.NUMANodes-1)
+	if t.NUMANodes == 1 {
+		nodes = "0\n"
+	}
+	children := map[string]kernfs.Inode{
+		"online":   fs.newStaticFile(ctx, creds, defaultSysMode, nodes),
+		"possible": fs.newStaticFile(ctx, creds, defaultSysMode, nodes),
+		"has_cpu":  fs.newStaticFile(ctx, creds, defaultSysMode, nodes),
+	}
+	for node := uint(0); node < t.NUMANodes; node++ {
+		cpus := t.NodeCPUs(node)
+		children[fmt.Sprintf("node%d", node)] = fs.newDir(ctx, creds, defaultSysDirMode, map[string]kernfs.Inode{
+			"cpumap":  fs.newStaticFile(ctx, creds, defaultSysMode, cpuMap(cpus, t.NumCPUs())),
+			"cpulist": fs.newStaticFile(ctx, creds, defaultSysMode, cpuList(cpus)),
+		})
+	}
+	return fs.newDir(ctx, creds, defaultSysDirMode, children)
+}
+
+// cpuList formats the given CPUs, in increasing order, as a list of ranges,
+// as printed by Linux's %*pbl format.
+func cpuList(cpus []uint) string {
+	var b strings.Builder
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if b.Len() != 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatUint(uint64(cpus[i]), 10))
+		if j != i {
+			b.WriteByte('-')
+			b.WriteString(strconv.FormatUint(uint64(cpus[j]), 10))
+		}
+		i = j + 1
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// cpuMap formats the given CPUs as a bitmap of numCPU bits, as printed by
+// Linux's %*pb format: comma-separated 32-bit hexadecimal words, most
+// significant first.
+func cpuMap(cpus []uint, numCPU uint) string {
+	words := make([]uint32, (numCPU+31)/32)
+	for _, cpu := range cpus {
+		words[cpu/32] |= 1 << (cpu % 32)
+	}
+	var b strings.Builder
+	for i := len(words) - 1; i >= 0; i-- {
+		if i == len(words)-1 {
+			// The most significant word is only as wide as its bits.
+			digits := int((numCPU - uint(i)*32 + 3) / 4)
+			fmt.Fprintf(&b, "%0*x", digits, words[i])
+		} else {
+			fmt.Fprintf(&b, ",%08x", words[i])
+		}
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
@@ -16,6 +16,9 @@
 package timerfd
 
 import (
+	"bytes"
+	"fmt"
+
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
@@ -39,6 +42,14 @@ type TimerFileDescription struct {
 	events waiter.Queue
 	timer  *ktime.Timer
 
+	// clockID is the ID of the timer's clock, as passed to timerfd_create(2).
+	// clockID is immutable.
+	clockID int32
+
+	// settimeFlags is the flags argument of the last call to
+	// timerfd_settime(2).
+	settimeFlags atomicbitops.Int32
+
 	// val is the number of timer expirations since the last successful
 	// call to PRead, or SetTime. val must be accessed using atomic memory
 	// operations.
@@ -46,13 +57,16 @@ type TimerFileDescription struct {
 }
 
 var _ vfs.FileDescriptionImpl = (*TimerFileDescription)(nil)
+var _ vfs.FileDescriptionImplFDInfoExtension = (*TimerFileDescription)(nil)
 var _ ktime.Listener = (*TimerFileDescription)(nil)
 
-// New returns a new timer fd.
-func New(ctx context.Context, vfsObj *vfs.VirtualFilesystem, clock ktime.Clock, flags uint32) (*vfs.FileDescription, error) {
+// New returns a new timer fd. clockID is the ID of clock.
+func New(ctx context.Context, vfsObj *vfs.VirtualFilesystem, clockID int32, clock ktime.Clock, flags uint32) (*vfs.FileDescription, error) {
 	vd := vfsObj.NewAnonVirtualDentry("[timerfd]")
 	defer vd.DecRef(ctx)
-	tfd := &TimerFileDescription{}
+	tfd := &TimerFileDescription{
+		clockID: clockID,
+	}
 	tfd.timer = ktime.NewTimer(clock, tfd)
 	if err := tfd.vfsfd.Init(tfd, flags, vd.Mount(), vd.Dentry(), &vfs.FileDescriptionOptions{
 		UseDentryMetadata: true,
@@ -96,9 +110,24 @@ func (tfd *TimerFileDescription) GetTime() (ktime.Time, ktime.Setting) {
 
 // SetTime atomically changes the associated Timer's setting, resets the number
 // of expirations to 0, and returns the previous setting and the time at which
-// it was observed.
-func (tfd *TimerFileDescription) SetTime(s ktime.Setting) (ktime.Time, ktime.Setting) {
-	return tfd.timer.SwapAnd(s, func() { tfd.val.Store(0) })
+// it was observed. flags is the flags argument of timerfd_settime(2).
+func (tfd *TimerFileDescription) SetTime(s ktime.Setting, flags int32) (ktime.Time, ktime.Setting) {
+	return tfd.timer.SwapAnd(s, func() {
+		tfd.val.Store(0)
+		tfd.settimeFlags.Store(flags)
+	})
+}
+
+// FDInfo implements vfs.FileDescriptionImplFDInfoExtension.FDInfo.
+func (tfd *TimerFileDescription) FDInfo(ctx context.Context, buf *bytes.Buffer) {
+	// Linux: fs/timerfd.c:timerfd_show()
+	tm, s := tfd.timer.Get()
+	its := ktime.ItimerspecFromSetting(tm, s)
+	fmt.Fprintf(buf, "clockid: %d\n", tfd.clockID)
+	fmt.Fprintf(buf, "ticks: %d\n", tfd.val.Load())
+	fmt.Fprintf(buf, "settime flags: 0%o\n", tfd.settimeFlags.Load())
+	fmt.Fprintf(buf, "it_value: (%d, %d)\n", its.Value.Sec, its.Value.Nsec)
+	fmt.Fprintf(buf, "it_interval: (%d, %d)\n", its.Interval.Sec, its.Interval.Nsec)
 }
 
 // Readiness implements waiter.Waitable.Readiness.
@@ -0,0 +1,183 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmpfs
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// getAccessACL returns i's access ACL, or nil if i's permissions are fully
+// represented by its mode.
+func (i *inode) getAccessACL() *vfs.ACL {
+	if !i.hasAccessACL.Load() {
+		return nil
+	}
+	i.aclMu.RLock()
+	defer i.aclMu.RUnlock()
+	return i.accessACL
+}
+
+// setAccessACLLocked sets i's access ACL.
+//
+// Preconditions: i.aclMu must be locked.
+func (i *inode) setAccessACLLocked(acl *vfs.ACL) {
+	i.accessACL = acl
+	i.hasAccessACL.Store(acl != nil)
+}
+
+// inheritACLs applies the default ACL of parentDir, if any, to i, which must
+// have just been created in parentDir with the given mode. umasked is the set
+// of mode bits that were removed from mode by the creating process' umask,
+// which the default ACL replaces.
+//
+// This corresponds to Linux's fs/posix_acl.c:posix_acl_create().
+func (i *inode) inheritACLs(parentDir *directory, mode linux.FileMode, umasked linux.FileMode) {
+	parentDir.inode.aclMu.RLock()
+	defaultACL := parentDir.inode.defaultACL
+	parentDir.inode.aclMu.RUnlock()
+	if defaultACL == nil {
+		return
+	}
+	accessACL, perms := defaultACL.Create(mode.Permissions() | umasked)
+	i.aclMu.Lock()
+	defer i.aclMu.Unlock()
+	for {
+		old := i.mode.Load()
+		newMode := (old &^ linux.PermissionsMask) | uint32(perms)
+		if i.mode.CompareAndSwap(old, newMode) {
+			break
+		}
+	}
+	i.setAccessACLLocked(accessACL)
+	if i.isDir() {
+		i.defaultACL = defaultACL
+	}
+}
+
+// chmodACL updates i's access ACL to reflect a change of i's mode to mode.
+//
+// This corresponds to Linux's fs/posix_acl.c:posix_acl_chmod().
+func (i *inode) chmodACL(mode linux.FileMode) {
+	if !i.hasAccessACL.Load() {
+		return
+	}
+	i.aclMu.Lock()
+	defer i.aclMu.Unlock()
+	if i.accessACL != nil {
+		i.setAccessACLLocked(i.accessACL.Chmod(mode))
+	}
+}
+
+// getACLXattr implements inode.getXattr for POSIX ACL extended attributes.
+// Like Linux, it doesn't require permission to read the file.
+func (i *inode) getACLXattr(creds *auth.Credentials, opts *vfs.GetXattrOptions) (string, error) {
+	i.aclMu.RLock()
+	acl := i.accessACL
+	if opts.Name == linux.XATTR_NAME_POSIX_ACL_DEFAULT {
+		acl = i.defaultACL
+	}
+	i.aclMu.RUnlock()
+	if acl == nil {
+		return "", linuxerr.ENODATA
+	}
+	value := acl.Encode(creds.UserNamespace)
+	if opts.Size != 0 && uint64(len(value)) > opts.Size {
+		return "", linuxerr.ERANGE
+	}
+	return value, nil
+}
+
+// setACLXattr implements inode.setXattr and inode.removeXattr (with a nil
+// value) for POSIX ACL extended attributes.
+//
+// This corresponds to Linux's fs/posix_acl.c:set_posix_acl().
+func (i *inode) setACLXattr(creds *auth.Credentials, name string, value *string, flags uint32) error {
+	if _, ok := i.impl.(*symlink); ok {
+		return linuxerr.EOPNOTSUPP
+	}
+	if !vfs.CanActAsOwner(creds, auth.KUID(i.uid.Load())) {
+		return linuxerr.EPERM
+	}
+	var acl *vfs.ACL
+	if value != nil {
+		var err error
+		acl, err = vfs.ParseACL(creds.UserNamespace, *value)
+		if err != nil {
+			return err
+		}
+	}
+	isDefault := name == linux.XATTR_NAME_POSIX_ACL_DEFAULT
+	if isDefault && !i.isDir() {
+		if acl != nil {
+			return linuxerr.EACCES
+		}
+		return nil
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.aclMu.Lock()
+	defer i.aclMu.Unlock()
+	old := i.accessACL
+	if isDefault {
+		old = i.defaultACL
+	}
+	switch {
+	case value == nil && old == nil:
+		return linuxerr.ENODATA
+	case flags&linux.XATTR_CREATE != 0 && old != nil:
+		return linuxerr.EEXIST
+	case flags&linux.XATTR_REPLACE != 0 && old == nil:
+		return linuxerr.ENODATA
+	}
+	if isDefault {
+		i.defaultACL = acl
+	} else {
+		if acl != nil {
+			// Update the mode to reflect the ACL, as in
+			// fs/posix_acl.c:posix_acl_update_mode().
+			mode := linux.FileMode(i.mode.Load())
+			newMode, equiv := acl.Mode(mode)
+			if !creds.InGroup(auth.KGID(i.gid.Load())) && !creds.HasCapability(linux.CAP_FSETID) {
+				newMode &^= linux.S_ISGID
+			}
+			i.mode.Store(uint32(newMode))
+			if equiv {
+				acl = nil
+			}
+		}
+		i.setAccessACLLocked(acl)
+	}
+	i.ctime.Store(i.fs.clock.Now().Nanoseconds())
+	return nil
+}
+
+// listACLXattrs returns the names of the POSIX ACL extended attributes set on
+// i.
+func (i *inode) listACLXattrs() []string {
+	i.aclMu.RLock()
+	defer i.aclMu.RUnlock()
+	var names []string
+	if i.accessACL != nil {
+		names = append(names, linux.XATTR_NAME_POSIX_ACL_ACCESS)
+	}
+	if i.defaultACL != nil {
+		names = append(names, linux.XATTR_NAME_POSIX_ACL_DEFAULT)
+	}
+	return names
+}
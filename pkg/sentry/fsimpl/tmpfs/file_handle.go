@@ -0,0 +1,67 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmpfs
+
+import (
+	"encoding/binary"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+var _ vfs.FileHandleFilesystemImpl = (*filesystem)(nil)
+
+// EncodeFileHandle implements vfs.FileHandleFilesystemImpl.EncodeFileHandle.
+// tmpfs file handles contain the inode number, which is never reused.
+func (fs *filesystem) EncodeFileHandle(ctx context.Context, vfsd *vfs.Dentry) ([]byte, error) {
+	d := vfsd.Impl().(*dentry)
+	fs.handlesMu.Lock()
+	if fs.handles == nil {
+		fs.handles = make(map[uint64]*dentry)
+	}
+	fs.handles[d.inode.ino] = d
+	fs.handlesMu.Unlock()
+	return binary.LittleEndian.AppendUint64(nil, d.inode.ino), nil
+}
+
+// DecodeFileHandle implements vfs.FileHandleFilesystemImpl.DecodeFileHandle.
+func (fs *filesystem) DecodeFileHandle(ctx context.Context, creds *auth.Credentials, root vfs.VirtualDentry, handle []byte) (vfs.VirtualDentry, error) {
+	if len(handle) != 8 {
+		return vfs.VirtualDentry{}, linuxerr.ESTALE
+	}
+	fs.handlesMu.Lock()
+	d := fs.handles[binary.LittleEndian.Uint64(handle)]
+	fs.handlesMu.Unlock()
+	// The dentry used to encode the handle may have been unlinked, even if
+	// its inode is still reachable through another hard link.
+	if d == nil || d.vfsd.IsDead() || !d.TryIncRef() {
+		return vfs.VirtualDentry{}, linuxerr.ESTALE
+	}
+	root.Mount().IncRef()
+	return vfs.MakeVirtualDentry(root.Mount(), &d.vfsd), nil
+}
+
+// forgetFileHandle removes i from the file handles of its filesystem, once
+// it has been destroyed.
+func (i *inode) forgetFileHandle() {
+	fs := i.fs
+	fs.handlesMu.Lock()
+	if d, ok := fs.handles[i.ino]; ok && d.inode == i {
+		delete(fs.handles, i.ino)
+	}
+	fs.handlesMu.Unlock()
+}
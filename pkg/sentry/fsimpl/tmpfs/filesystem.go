@@ -305,6 +305,7 @@ func (fs *filesystem) MkdirAt(ctx context.Context, rp *vfs.ResolvingPath, opts v
 		}
 		parentDir.inode.incLinksLocked() // from child's ".."
 		childDir := fs.newDirectory(creds.EffectiveKUID, creds.EffectiveKGID, opts.Mode, parentDir)
+		childDir.inode.inheritACLs(parentDir, opts.Mode, opts.Umasked)
 		parentDir.insertChildLocked(&childDir.dentry, name)
 		return nil
 	})
@@ -329,6 +330,7 @@ func (fs *filesystem) MknodAt(ctx context.Context, rp *vfs.ResolvingPath, opts v
 		default:
 			return linuxerr.EINVAL
 		}
+		childInode.inheritACLs(parentDir, opts.Mode, opts.Umasked)
 		child := fs.newDentry(childInode)
 		parentDir.insertChildLocked(child, name)
 		return nil
@@ -420,7 +422,9 @@ afterTrailingSymlink:
 		defer rp.Mount().EndWrite()
 		// Create and open the child.
 		creds := rp.Credentials()
-		child := fs.newDentry(fs.newRegularFile(creds.EffectiveKUID, creds.EffectiveKGID, opts.Mode, parentDir))
+		childInode := fs.newRegularFile(creds.EffectiveKUID, creds.EffectiveKGID, opts.Mode, parentDir)
+		childInode.inheritACLs(parentDir, opts.Mode, opts.Umasked)
+		child := fs.newDentry(childInode)
 		parentDir.insertChildLocked(child, name)
 		child.IncRef()
 		defer child.DecRef(ctx)
@@ -145,16 +145,23 @@ func NewZeroFile(ctx context.Context, creds *auth.Credentials, mount *vfs.Mount,
 }
 
 // NewMemfd creates a new regular file and file description as for
-// memfd_create.
+// memfd_create. If noExec is true, the file is created without execute
+// permissions and with F_SEAL_EXEC applied, as for MFD_NOEXEC_SEAL; this
+// implies allowSeals.
 //
 // Preconditions: mount must be a tmpfs mount.
-func NewMemfd(ctx context.Context, creds *auth.Credentials, mount *vfs.Mount, allowSeals bool, name string) (*vfs.FileDescription, error) {
+func NewMemfd(ctx context.Context, creds *auth.Credentials, mount *vfs.Mount, allowSeals, noExec bool, name string) (*vfs.FileDescription, error) {
 	fd, err := newUnlinkedRegularFileDescription(ctx, creds, mount, name)
 	if err != nil {
 		return nil, err
 	}
-	if allowSeals {
-		fd.inode().impl.(*regularFile).seals = 0
+	rf := fd.inode().impl.(*regularFile)
+	switch {
+	case noExec:
+		fd.inode().mode.Store(linux.S_IFREG | 0666)
+		rf.seals = linux.F_SEAL_EXEC
+	case allowSeals:
+		rf.seals = 0
 	}
 	return &fd.vfsfd, nil
 }
@@ -506,6 +513,10 @@ func (fd *regularFileFD) pwrite(ctx context.Context, src usermem.IOSequence, off
 		return 0, offset, nil
 	}
 	f := fd.inode().impl.(*regularFile)
+	if err := f.inode.fs.vfsfs.StartWrite(ctx); err != nil {
+		return 0, offset, err
+	}
+	defer f.inode.fs.vfsfs.EndWrite()
 	f.inode.mu.Lock()
 	defer f.inode.mu.Unlock()
 	// If the file is opened with O_APPEND, update offset to file size.
@@ -574,6 +585,22 @@ func (fd *regularFileFD) Seek(ctx context.Context, offset int64, whence int32) (
 // ConfigureMMap implements vfs.FileDescriptionImpl.ConfigureMMap.
 func (fd *regularFileFD) ConfigureMMap(ctx context.Context, opts *memmap.MMapOpts) error {
 	file := fd.inode().impl.(*regularFile)
+	if !opts.Private {
+		// As in Linux's mm/memfd.c:memfd_check_seals_mmap(),
+		// F_SEAL_FUTURE_WRITE prevents new shared writable mappings,
+		// including those that could later be made writable with
+		// mprotect(2). Existing mappings are unaffected. F_SEAL_WRITE is
+		// enforced by AddMapping.
+		file.dataMu.RLock()
+		writeSealed := file.seals&linux.F_SEAL_FUTURE_WRITE != 0
+		file.dataMu.RUnlock()
+		if writeSealed {
+			if opts.Perms.Write {
+				return linuxerr.EPERM
+			}
+			opts.MaxPerms.Write = false
+		}
+	}
 	opts.SentryOwnedContent = true
 	return vfs.GenericConfigureMMap(&fd.vfsfd, file, opts)
 }
@@ -695,7 +722,7 @@ func (rw *regularFileReadWriter) WriteFromBlocks(srcs safemem.BlockSeq) (uint64,
 
 	// Check if seals prevent either file growth or all writes.
 	switch {
-	case rw.file.seals&linux.F_SEAL_WRITE != 0: // Write sealed
+	case rw.file.seals&(linux.F_SEAL_WRITE|linux.F_SEAL_FUTURE_WRITE) != 0: // Write sealed
 		return 0, linuxerr.EPERM
 	case end > rw.file.size.RacyLoad() && rw.file.seals&linux.F_SEAL_GROW != 0: // Grow sealed
 		// When growth is sealed, Linux effectively allows writes which would
@@ -846,11 +873,22 @@ func AddSeals(fd *vfs.FileDescription, val uint32) error {
 	rf.dataMu.Lock()
 	defer rf.dataMu.Unlock()
 
+	if val&^linux.F_ALL_SEALS != 0 {
+		return linuxerr.EINVAL
+	}
+
 	if rf.seals&linux.F_SEAL_SEAL != 0 {
 		// Seal applied which prevents addition of any new seals.
 		return linuxerr.EPERM
 	}
 
+	// As in Linux's mm/memfd.c:memfd_add_seals(), sealing the execute bits of
+	// an executable file also seals its contents, so that the file remains
+	// W^X.
+	if val&linux.F_SEAL_EXEC != 0 && f.inode().mode.Load()&0111 != 0 {
+		val |= linux.F_SEAL_SHRINK | linux.F_SEAL_GROW | linux.F_SEAL_WRITE | linux.F_SEAL_FUTURE_WRITE
+	}
+
 	// F_SEAL_WRITE can only be added if there are no active writable maps.
 	if rf.seals&linux.F_SEAL_WRITE == 0 && val&linux.F_SEAL_WRITE != 0 {
 		if rf.writableMappingPages > 0 {
@@ -19,6 +19,7 @@
 //
 //	filesystem.mu
 //		inode.mu
+//		  inode.aclMu
 //		  regularFileFD.offMu
 //		    *** "memmap.Mappable locks" below this point
 //		    regularFile.mapsMu
@@ -26,6 +27,7 @@
 //		      regularFile.dataMu
 //		        fs.pagesUsedMu
 //		  directory.iterMu
+//		  fs.handlesMu
 package tmpfs
 
 import (
@@ -46,6 +48,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sentry/vfs/memxattr"
+	"gvisor.dev/gvisor/pkg/sync"
 )
 
 // Name is the default filesystem name.
@@ -99,6 +102,13 @@ type filesystem struct {
 	// allowXattrPrefix is a set of xattr namespace prefixes that this
 	// tmpfs mount will allow. It is immutable.
 	allowXattrPrefix map[string]struct{}
+
+	// handlesMu protects handles.
+	handlesMu handlesMutex `state:"nosave"`
+
+	// handles maps the inode numbers of files for which a file handle was
+	// encoded to the dentry they were encoded from.
+	handles map[uint64]*dentry
 }
 
 // Name implements vfs.FilesystemType.Name.
@@ -178,14 +188,15 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 	disableDefaultSizeLimit := false
 	newFSType := vfs.FilesystemType(&fstype)
 
-	// By default we support only "trusted" and "user" namespaces. Linux
-	// also supports "security" and (if configured) POSIX ACL namespaces
-	// "system.posix_acl_access" and "system.posix_acl_default".
+	// By default we support the "trusted" and "user" namespaces, and POSIX
+	// ACLs. Linux also supports the "security" namespace.
 	allowXattrPrefix := map[string]struct{}{
 		linux.XATTR_TRUSTED_PREFIX: struct{}{},
 		linux.XATTR_USER_PREFIX:    struct{}{},
 		// The "security" namespace is allowed, but it always returns an error.
-		linux.XATTR_SECURITY_PREFIX: struct{}{},
+		linux.XATTR_SECURITY_PREFIX:        struct{}{},
+		linux.XATTR_NAME_POSIX_ACL_ACCESS:  struct{}{},
+		linux.XATTR_NAME_POSIX_ACL_DEFAULT: struct{}{},
 	}
 
 	tmpfsOpts, tmpfsOptsOk := opts.InternalData.(FilesystemOpts)
@@ -295,6 +306,9 @@ func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.Virt
 		allowXattrPrefix: allowXattrPrefix,
 	}
 	fs.vfsfs.Init(vfsObj, newFSType, &fs)
+	if !opts.InternalMount {
+		fs.vfsfs.SetFreezable()
+	}
 	if tmpfsOptsOk && tmpfsOpts.MaxFilenameLen > 0 {
 		fs.maxFilenameLen = tmpfsOpts.MaxFilenameLen
 	}
@@ -471,6 +485,20 @@ type inode struct {
 	// TODO(b/148380782): Support xattrs other than user.*
 	xattrs memxattr.SimpleExtendedAttributes
 
+	// aclMu protects accessACL and defaultACL.
+	aclMu sync.RWMutex `state:"nosave"`
+
+	// accessACL is the POSIX access ACL of the inode, or nil if the inode's
+	// permissions are fully represented by its mode. hasAccessACL is true if
+	// accessACL is not nil, which allows permission checks to avoid locking
+	// aclMu in the common case.
+	accessACL    *vfs.ACL
+	hasAccessACL atomicbitops.Bool
+
+	// defaultACL is the POSIX default ACL of a directory inode, which is
+	// inherited by files created in the directory.
+	defaultACL *vfs.ACL
+
 	// Inode metadata. Writing multiple fields atomically requires holding
 	// mu, otherwise atomic operations can be used.
 	mu    inodeMutex          `state:"nosave"`
@@ -567,6 +595,7 @@ func (i *inode) tryIncRef() bool {
 func (i *inode) decRef(ctx context.Context) {
 	i.refs.DecRef(func() {
 		i.watches.HandleDeletion(ctx)
+		i.forgetFileHandle()
 		// Remove pages used if child being removed is a SymLink or Regular File.
 		switch impl := i.impl.(type) {
 		case *symlink:
@@ -586,7 +615,7 @@ func (i *inode) decRef(ctx context.Context) {
 
 func (i *inode) checkPermissions(creds *auth.Credentials, ats vfs.AccessTypes) error {
 	mode := linux.FileMode(i.mode.Load())
-	return vfs.GenericCheckPermissions(creds, ats, mode, auth.KUID(i.uid.Load()), auth.KGID(i.gid.Load()))
+	return vfs.GenericCheckPermissionsACL(creds, ats, mode, auth.KUID(i.uid.Load()), auth.KGID(i.gid.Load()), i.getAccessACL())
 }
 
 // Go won't inline this function, and returning linux.Statx (which is quite
@@ -655,6 +684,15 @@ func (i *inode) setStat(ctx context.Context, creds *auth.Credentials, opts *vfs.
 	)
 	clearSID := false
 	mask := stat.Mask
+	if rf, ok := i.impl.(*regularFile); ok && mask&linux.STATX_MODE != 0 {
+		// F_SEAL_EXEC prevents changes to the execute bits.
+		rf.dataMu.RLock()
+		execSealed := rf.seals&linux.F_SEAL_EXEC != 0
+		rf.dataMu.RUnlock()
+		if execSealed && (uint16(i.mode.Load())^stat.Mode)&0111 != 0 {
+			return linuxerr.EPERM
+		}
+	}
 	if mask&linux.STATX_SIZE != 0 {
 		switch impl := i.impl.(type) {
 		case *regularFile:
@@ -693,6 +731,7 @@ func (i *inode) setStat(ctx context.Context, creds *auth.Credentials, opts *vfs.
 			}
 			if swapped := i.mode.CompareAndSwap(old, newMode); swapped {
 				clearSID = false
+				i.chmodACL(linux.FileMode(newMode))
 				break
 			}
 		}
@@ -797,11 +836,16 @@ func (i *inode) touchAtime(mnt *vfs.Mount) {
 	if err := mnt.CheckBeginWrite(); err != nil {
 		return
 	}
+	defer mnt.EndWrite()
+	// Like Linux, don't update atime on frozen filesystems.
+	if !i.fs.vfsfs.TryStartWrite() {
+		return
+	}
+	defer i.fs.vfsfs.EndWrite()
 	now := i.fs.clock.Now().Nanoseconds()
 	i.mu.Lock()
 	i.atime.Store(now)
 	i.mu.Unlock()
-	mnt.EndWrite()
 }
 
 // Preconditions: The caller has called vfs.Mount.CheckBeginWrite().
@@ -840,13 +884,36 @@ func (i *inode) checkXattrPrefix(name string) error {
 }
 
 func (i *inode) listXattr(creds *auth.Credentials, size uint64) ([]string, error) {
-	return i.xattrs.ListXattr(creds, size)
+	names, err := i.xattrs.ListXattr(creds, size)
+	if err != nil {
+		return nil, err
+	}
+	aclNames := i.listACLXattrs()
+	if len(aclNames) == 0 {
+		return names, nil
+	}
+	if size != 0 {
+		listSize := uint64(0)
+		for _, name := range names {
+			listSize += uint64(len(name)) + 1
+		}
+		for _, name := range aclNames {
+			listSize += uint64(len(name)) + 1
+		}
+		if listSize > size {
+			return nil, linuxerr.ERANGE
+		}
+	}
+	return append(names, aclNames...), nil
 }
 
 func (i *inode) getXattr(creds *auth.Credentials, opts *vfs.GetXattrOptions) (string, error) {
 	if err := i.checkXattrPrefix(opts.Name); err != nil {
 		return "", err
 	}
+	if vfs.IsACLXattr(opts.Name) {
+		return i.getACLXattr(creds, opts)
+	}
 	mode := linux.FileMode(i.mode.Load())
 	kuid := auth.KUID(i.uid.Load())
 	kgid := auth.KGID(i.gid.Load())
@@ -860,6 +927,9 @@ func (i *inode) setXattr(creds *auth.Credentials, opts *vfs.SetXattrOptions) err
 	if err := i.checkXattrPrefix(opts.Name); err != nil {
 		return err
 	}
+	if vfs.IsACLXattr(opts.Name) {
+		return i.setACLXattr(creds, opts.Name, &opts.Value, opts.Flags)
+	}
 	mode := linux.FileMode(i.mode.Load())
 	kuid := auth.KUID(i.uid.Load())
 	kgid := auth.KGID(i.gid.Load())
@@ -873,6 +943,9 @@ func (i *inode) removeXattr(creds *auth.Credentials, name string) error {
 	if err := i.checkXattrPrefix(name); err != nil {
 		return err
 	}
+	if vfs.IsACLXattr(name) {
+		return i.setACLXattr(creds, name, nil /* value */, 0 /* flags */)
+	}
 	mode := linux.FileMode(i.mode.Load())
 	kuid := auth.KUID(i.uid.Load())
 	kgid := auth.KGID(i.gid.Load())
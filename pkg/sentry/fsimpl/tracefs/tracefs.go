@@ -0,0 +1,748 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracefs implements a minimal tracefs, which exposes the trace events
+// and trace buffer of ftrace.Global.
+//
+// Only the "nop" tracer is available. Events are recorded into a single trace
+// buffer, which can be read through the "trace" and "trace_pipe" files.
+// Reading "trace_pipe" doesn't block when the buffer is empty.
+//
+// All tracefs mounts share the same events and trace buffer.
+package tracefs
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/ftrace"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// Name is the user-visible filesystem name.
+const Name = "tracefs"
+
+// maxWriteSize is the maximum size of a single write to a tracefs file.
+const maxWriteSize = 4096
+
+// FilesystemType implements vfs.FilesystemType.
+//
+// +stateify savable
+type FilesystemType struct{}
+
+// Name implements vfs.FilesystemType.Name.
+func (FilesystemType) Name() string {
+	return Name
+}
+
+// Release implements vfs.FilesystemType.Release.
+func (FilesystemType) Release(ctx context.Context) {}
+
+// GetFilesystem implements vfs.FilesystemType.GetFilesystem.
+func (fsType FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.VirtualFilesystem, creds *auth.Credentials, source string, opts vfs.GetFilesystemOptions) (*vfs.Filesystem, *vfs.Dentry, error) {
+	if st, ok := kernel.LookupSyscallTable(abi.Linux, arch.Host); ok {
+		names := make(map[uintptr]string, len(st.Table))
+		for sysno, sc := range st.Table {
+			names[sysno] = sc.Name
+		}
+		ftrace.Global.RegisterSyscalls(names)
+	}
+
+	devMinor, err := vfsObj.GetAnonBlockDevMinor()
+	if err != nil {
+		return nil, nil, err
+	}
+	fs := &filesystem{
+		devMinor: devMinor,
+	}
+	fs.VFSFilesystem().Init(vfsObj, &fsType, fs)
+
+	events := &eventsDirInode{fs: fs}
+	events.InodeAttrs.Init(ctx, creds, linux.UNNAMED_MAJOR, devMinor, fs.NextIno(), linux.ModeDirectory|0755)
+	events.OrderedChildren.Init(kernfs.OrderedChildrenOptions{})
+	events.InitRefs()
+	events.IncLinks(events.OrderedChildren.Populate(map[string]kernfs.Inode{
+		"enable": fs.newFile(ctx, creds, 0644, &enableData{}),
+	}))
+
+	root := &rootInode{}
+	root.Init(ctx, creds, linux.UNNAMED_MAJOR, devMinor, fs.NextIno(), 0700, kernfs.GenericDirectoryFDOptions{
+		SeekEnd: kernfs.SeekEndStaticEntries,
+	})
+	root.InitRefs()
+	root.OrderedChildren.Init(kernfs.OrderedChildrenOptions{})
+	root.IncLinks(root.OrderedChildren.Populate(map[string]kernfs.Inode{
+		"available_events":  fs.newFile(ctx, creds, 0444, &availableEventsData{}),
+		"available_tracers": fs.newFile(ctx, creds, 0444, &availableTracersData{}),
+		"buffer_size_kb":    fs.newFile(ctx, creds, 0644, &bufferSizeData{}),
+		"current_tracer":    fs.newFile(ctx, creds, 0644, &currentTracerData{}),
+		"events":            events,
+		"kprobe_events":     fs.newFile(ctx, creds, 0644, &kprobeEventsData{}),
+		"set_event":         fs.newFile(ctx, creds, 0644, &setEventData{}),
+		"trace":             fs.newFile(ctx, creds, 0644, &traceData{}),
+		"trace_pipe":        fs.newFile(ctx, creds, 0444, &tracePipeData{}),
+		"tracing_on":        fs.newFile(ctx, creds, 0644, &tracingOnData{}),
+		"uprobe_events":     fs.newFile(ctx, creds, 0644, &uprobeEventsData{}),
+	}))
+
+	var rootD kernfs.Dentry
+	rootD.InitRoot(&fs.Filesystem, root)
+	return fs.VFSFilesystem(), rootD.VFSDentry(), nil
+}
+
+// filesystem implements vfs.FilesystemImpl.
+//
+// +stateify savable
+type filesystem struct {
+	kernfs.Filesystem
+
+	devMinor uint32
+}
+
+// Release implements vfs.FilesystemImpl.Release.
+func (fs *filesystem) Release(ctx context.Context) {
+	fs.Filesystem.VFSFilesystem().VirtualFilesystem().PutAnonBlockDevMinor(fs.devMinor)
+	fs.Filesystem.Release(ctx)
+}
+
+// MountOptions implements vfs.FilesystemImpl.MountOptions.
+func (fs *filesystem) MountOptions() string {
+	return ""
+}
+
+// dynamicFile is a kernfs.Inode backed by a vfs.DynamicBytesSource.
+type dynamicFile interface {
+	kernfs.Inode
+	vfs.DynamicBytesSource
+
+	Init(ctx context.Context, creds *auth.Credentials, devMajor, devMinor uint32, ino uint64, data vfs.DynamicBytesSource, perm linux.FileMode)
+}
+
+func (fs *filesystem) newFile(ctx context.Context, creds *auth.Credentials, perm linux.FileMode, inode dynamicFile) kernfs.Inode {
+	inode.Init(ctx, creds, linux.UNNAMED_MAJOR, fs.devMinor, fs.NextIno(), inode, perm)
+	return inode
+}
+
+// rootInode is the root directory of a tracefs filesystem.
+//
+// +stateify savable
+type rootInode struct {
+	kernfs.StaticDirectory
+}
+
+// StatFS implements kernfs.Inode.StatFS.
+func (*rootInode) StatFS(context.Context, *vfs.Filesystem) (linux.Statfs, error) {
+	return vfs.GenericStatFS(linux.TRACEFS_MAGIC), nil
+}
+
+// eventsDirInode is the "events" directory, if system is empty, or one of its
+// per-subsystem subdirectories. In addition to the static "enable" file, it
+// contains one directory for each subsystem or event.
+//
+// +stateify savable
+type eventsDirInode struct {
+	eventsDirInodeRefs
+	kernfs.InodeAttrs
+	kernfs.InodeDirectoryNoNewChildren
+	kernfs.InodeNoStatFS
+	kernfs.InodeNotAnonymous
+	kernfs.InodeNotSymlink
+	kernfs.InodeTemporary
+	kernfs.InodeWatches
+	kernfs.OrderedChildren
+
+	fs     *filesystem
+	locks  vfs.FileLocks
+	system string
+}
+
+var _ kernfs.Inode = (*eventsDirInode)(nil)
+
+// Valid implements kernfs.Inode.Valid.
+func (i *eventsDirInode) Valid(context.Context, *kernfs.Dentry, string) bool {
+	return i.system == "" || len(ftrace.Global.Events(i.system)) > 0
+}
+
+// names returns the names of the dynamic children of i.
+func (i *eventsDirInode) names() []string {
+	if i.system == "" {
+		return ftrace.Global.Systems()
+	}
+	var names []string
+	for _, e := range ftrace.Global.Events(i.system) {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+// Lookup implements kernfs.Inode.Lookup.
+func (i *eventsDirInode) Lookup(ctx context.Context, name string) (kernfs.Inode, error) {
+	if d, err := i.OrderedChildren.Lookup(ctx, name); err == nil {
+		return d, nil
+	}
+	creds := auth.CredentialsFromContext(ctx)
+	if i.system == "" {
+		if len(ftrace.Global.Events(name)) == 0 {
+			return nil, linuxerr.ENOENT
+		}
+		dir := &eventsDirInode{fs: i.fs, system: name}
+		dir.InodeAttrs.Init(ctx, creds, linux.UNNAMED_MAJOR, i.fs.devMinor, i.fs.NextIno(), linux.ModeDirectory|0755)
+		dir.OrderedChildren.Init(kernfs.OrderedChildrenOptions{})
+		dir.InitRefs()
+		dir.IncLinks(dir.OrderedChildren.Populate(map[string]kernfs.Inode{
+			"enable": i.fs.newFile(ctx, creds, 0644, &enableData{system: name}),
+		}))
+		return dir, nil
+	}
+
+	e := ftrace.Global.Lookup(i.system, name)
+	if e == nil {
+		return nil, linuxerr.ENOENT
+	}
+	dir := &eventDirInode{system: i.system, name: name}
+	dir.Init(ctx, creds, linux.UNNAMED_MAJOR, i.fs.devMinor, i.fs.NextIno(), 0755, kernfs.GenericDirectoryFDOptions{
+		SeekEnd: kernfs.SeekEndStaticEntries,
+	})
+	dir.InitRefs()
+	dir.OrderedChildren.Init(kernfs.OrderedChildrenOptions{})
+	dir.IncLinks(dir.OrderedChildren.Populate(map[string]kernfs.Inode{
+		"enable": i.fs.newFile(ctx, creds, 0644, &eventEnableData{system: i.system, name: name}),
+		"format": i.fs.newFile(ctx, creds, 0444, &formatData{system: i.system, name: name}),
+		"id":     i.fs.newFile(ctx, creds, 0444, &idData{system: i.system, name: name}),
+	}))
+	return dir, nil
+}
+
+// IterDirents implements kernfs.Inode.IterDirents.
+func (i *eventsDirInode) IterDirents(ctx context.Context, mnt *vfs.Mount, cb vfs.IterDirentsCallback, offset, relOffset int64) (int64, error) {
+	names := i.names()
+	if relOffset >= int64(len(names)) {
+		return offset, nil
+	}
+	for _, name := range names[relOffset:] {
+		dirent := vfs.Dirent{
+			Name:    name,
+			Type:    linux.DT_DIR,
+			Ino:     i.fs.NextIno(),
+			NextOff: offset + 1,
+		}
+		if err := cb.Handle(dirent); err != nil {
+			return offset, err
+		}
+		offset++
+	}
+	return offset, nil
+}
+
+// Open implements kernfs.Inode.Open.
+func (i *eventsDirInode) Open(ctx context.Context, rp *vfs.ResolvingPath, d *kernfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	fd, err := kernfs.NewGenericDirectoryFD(rp.Mount(), d, &i.OrderedChildren, &i.locks, &opts, kernfs.GenericDirectoryFDOptions{
+		SeekEnd: kernfs.SeekEndZero,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fd.VFSFileDescription(), nil
+}
+
+// SetStat implements kernfs.Inode.SetStat not allowing inode attributes to be
+// changed.
+func (*eventsDirInode) SetStat(context.Context, *vfs.Filesystem, *auth.Credentials, vfs.SetStatOptions) error {
+	return linuxerr.EPERM
+}
+
+// DecRef implements kernfs.Inode.DecRef.
+func (i *eventsDirInode) DecRef(ctx context.Context) {
+	i.eventsDirInodeRefs.DecRef(func() { i.Destroy(ctx) })
+}
+
+// eventDirInode is the directory of a single event.
+//
+// +stateify savable
+type eventDirInode struct {
+	kernfs.StaticDirectory
+
+	system string
+	name   string
+}
+
+// Valid implements kernfs.Inode.Valid.
+func (i *eventDirInode) Valid(context.Context, *kernfs.Dentry, string) bool {
+	return ftrace.Global.Lookup(i.system, i.name) != nil
+}
+
+// copyInString copies in the value written to a tracefs file.
+func copyInString(ctx context.Context, src usermem.IOSequence) (string, int64, error) {
+	if src.NumBytes() > maxWriteSize {
+		return "", 0, linuxerr.EINVAL
+	}
+	buf := make([]byte, src.NumBytes())
+	n, err := src.CopyIn(ctx, buf)
+	if err != nil {
+		return "", 0, err
+	}
+	return string(buf[:n]), int64(n), nil
+}
+
+// copyInUint copies in an unsigned integer written to a tracefs file.
+func copyInUint(ctx context.Context, src usermem.IOSequence) (uint64, int64, error) {
+	s, n, err := copyInString(ctx, src)
+	if err != nil {
+		return 0, 0, err
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, 0, linuxerr.EINVAL
+	}
+	return v, n, nil
+}
+
+// availableEventsData implements vfs.DynamicBytesSource for the
+// "available_events" file.
+//
+// +stateify savable
+type availableEventsData struct {
+	kernfs.DynamicBytesFile
+}
+
+var _ vfs.DynamicBytesSource = (*availableEventsData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*availableEventsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	for _, e := range ftrace.Global.Events("") {
+		fmt.Fprintf(buf, "%s:%s\n", e.System, e.Name)
+	}
+	return nil
+}
+
+// availableTracersData implements vfs.DynamicBytesSource for the
+// "available_tracers" file.
+//
+// +stateify savable
+type availableTracersData struct {
+	kernfs.DynamicBytesFile
+}
+
+var _ vfs.DynamicBytesSource = (*availableTracersData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*availableTracersData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	buf.WriteString("nop\n")
+	return nil
+}
+
+// currentTracerData implements vfs.WritableDynamicBytesSource for the
+// "current_tracer" file.
+//
+// +stateify savable
+type currentTracerData struct {
+	kernfs.DynamicBytesFile
+}
+
+var _ vfs.WritableDynamicBytesSource = (*currentTracerData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*currentTracerData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	buf.WriteString("nop\n")
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (*currentTracerData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	s, n, err := copyInString(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(s) != "nop" {
+		return 0, linuxerr.EINVAL
+	}
+	return n, nil
+}
+
+// tracingOnData implements vfs.WritableDynamicBytesSource for the
+// "tracing_on" file.
+//
+// +stateify savable
+type tracingOnData struct {
+	kernfs.DynamicBytesFile
+}
+
+var _ vfs.WritableDynamicBytesSource = (*tracingOnData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*tracingOnData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	if ftrace.Global.On() {
+		buf.WriteString("1\n")
+	} else {
+		buf.WriteString("0\n")
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (*tracingOnData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	v, n, err := copyInUint(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	ftrace.Global.SetOn(v != 0)
+	return n, nil
+}
+
+// bufferSizeData implements vfs.WritableDynamicBytesSource for the
+// "buffer_size_kb" file.
+//
+// +stateify savable
+type bufferSizeData struct {
+	kernfs.DynamicBytesFile
+}
+
+var _ vfs.WritableDynamicBytesSource = (*bufferSizeData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*bufferSizeData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	fmt.Fprintf(buf, "%d\n", ftrace.Global.BufferSizeKB())
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (*bufferSizeData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	v, n, err := copyInUint(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	if v == 0 || v > 1<<20 {
+		return 0, linuxerr.EINVAL
+	}
+	ftrace.Global.SetBufferSizeKB(int(v))
+	return n, nil
+}
+
+// traceData implements vfs.WritableDynamicBytesSource for the "trace" file.
+// Opening it with O_TRUNC or writing to it clears the trace buffer.
+//
+// +stateify savable
+type traceData struct {
+	kernfs.DynamicBytesFile
+}
+
+var _ vfs.WritableDynamicBytesSource = (*traceData)(nil)
+
+// Open implements kernfs.Inode.Open.
+func (d *traceData) Open(ctx context.Context, rp *vfs.ResolvingPath, kd *kernfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	if opts.Flags&linux.O_TRUNC != 0 {
+		ftrace.Global.ClearTrace()
+	}
+	return d.DynamicBytesFile.Open(ctx, rp, kd, opts)
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*traceData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	ftrace.Global.Trace(buf)
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (*traceData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	ftrace.Global.ClearTrace()
+	return src.NumBytes(), nil
+}
+
+// tracePipeData implements vfs.DynamicBytesSource for the "trace_pipe" file.
+// Each read of the file consumes the entries that it returns.
+//
+// +stateify savable
+type tracePipeData struct {
+	kernfs.DynamicBytesFile
+}
+
+var _ vfs.DynamicBytesSource = (*tracePipeData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*tracePipeData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	ftrace.Global.ConsumeTrace(buf)
+	return nil
+}
+
+// kprobeEventsData implements vfs.WritableDynamicBytesSource for the
+// "kprobe_events" file. Opening it with O_TRUNC removes all kprobes.
+//
+// +stateify savable
+type kprobeEventsData struct {
+	kernfs.DynamicBytesFile
+}
+
+var _ vfs.WritableDynamicBytesSource = (*kprobeEventsData)(nil)
+
+// Open implements kernfs.Inode.Open.
+func (d *kprobeEventsData) Open(ctx context.Context, rp *vfs.ResolvingPath, kd *kernfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	if opts.Flags&linux.O_TRUNC != 0 {
+		if err := ftrace.Global.ClearKprobes(); err != nil {
+			return nil, err
+		}
+	}
+	return d.DynamicBytesFile.Open(ctx, rp, kd, opts)
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*kprobeEventsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	ftrace.Global.KprobeEvents(buf)
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (*kprobeEventsData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	if !auth.CredentialsFromContext(ctx).HasCapability(linux.CAP_SYS_ADMIN) {
+		return 0, linuxerr.EPERM
+	}
+	s, n, err := copyInString(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(s, "\n") {
+		if err := ftrace.Global.AddKprobe(line); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// uprobeEventsData implements vfs.WritableDynamicBytesSource for the
+// "uprobe_events" file. Uprobes are not supported: the file is always empty
+// and writes to it are discarded.
+//
+// +stateify savable
+type uprobeEventsData struct {
+	kernfs.DynamicBytesFile
+}
+
+var _ vfs.WritableDynamicBytesSource = (*uprobeEventsData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*uprobeEventsData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (*uprobeEventsData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	return src.NumBytes(), nil
+}
+
+// setEventData implements vfs.WritableDynamicBytesSource for the "set_event"
+// file. Writing "SYSTEM:EVENT" enables an event and "!SYSTEM:EVENT" disables
+// it. Either part may be "*" to match all subsystems or events.
+//
+// +stateify savable
+type setEventData struct {
+	kernfs.DynamicBytesFile
+}
+
+var _ vfs.WritableDynamicBytesSource = (*setEventData)(nil)
+
+// Open implements kernfs.Inode.Open.
+func (d *setEventData) Open(ctx context.Context, rp *vfs.ResolvingPath, kd *kernfs.Dentry, opts vfs.OpenOptions) (*vfs.FileDescription, error) {
+	if opts.Flags&linux.O_TRUNC != 0 {
+		ftrace.Global.SetSystemEnabled("", false)
+	}
+	return d.DynamicBytesFile.Open(ctx, rp, kd, opts)
+}
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (*setEventData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	for _, e := range ftrace.Global.Events("") {
+		if e.Enabled() {
+			fmt.Fprintf(buf, "%s:%s\n", e.System, e.Name)
+		}
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (*setEventData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	s, n, err := copyInString(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Fields(s) {
+		enable := true
+		if l, ok := strings.CutPrefix(line, "!"); ok {
+			enable, line = false, l
+		}
+		system, name, ok := strings.Cut(line, ":")
+		if !ok {
+			// A bare name matches an event in any subsystem.
+			system, name = "*", system
+		}
+		matched := false
+		for _, e := range ftrace.Global.Events("") {
+			if (system == "*" || e.System == system) && (name == "*" || e.Name == name) {
+				ftrace.Global.SetEnabled(e, enable)
+				matched = true
+			}
+		}
+		if !matched {
+			return 0, linuxerr.EINVAL
+		}
+	}
+	return n, nil
+}
+
+// enableData implements vfs.WritableDynamicBytesSource for the "enable" file
+// of the events directory, if system is empty, or of a subsystem directory.
+//
+// +stateify savable
+type enableData struct {
+	kernfs.DynamicBytesFile
+
+	system string
+}
+
+var _ vfs.WritableDynamicBytesSource = (*enableData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *enableData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	var enabled, disabled bool
+	for _, e := range ftrace.Global.Events(d.system) {
+		if e.Enabled() {
+			enabled = true
+		} else {
+			disabled = true
+		}
+	}
+	switch {
+	case enabled && disabled:
+		buf.WriteString("X\n")
+	case enabled:
+		buf.WriteString("1\n")
+	default:
+		buf.WriteString("0\n")
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *enableData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	v, n, err := copyInUint(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	if v > 1 {
+		return 0, linuxerr.EINVAL
+	}
+	ftrace.Global.SetSystemEnabled(d.system, v == 1)
+	return n, nil
+}
+
+// eventEnableData implements vfs.WritableDynamicBytesSource for the "enable"
+// file of an event.
+//
+// +stateify savable
+type eventEnableData struct {
+	kernfs.DynamicBytesFile
+
+	system string
+	name   string
+}
+
+var _ vfs.WritableDynamicBytesSource = (*eventEnableData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *eventEnableData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	e := ftrace.Global.Lookup(d.system, d.name)
+	if e == nil {
+		return linuxerr.ENODEV
+	}
+	if e.Enabled() {
+		buf.WriteString("1\n")
+	} else {
+		buf.WriteString("0\n")
+	}
+	return nil
+}
+
+// Write implements vfs.WritableDynamicBytesSource.Write.
+func (d *eventEnableData) Write(ctx context.Context, _ *vfs.FileDescription, src usermem.IOSequence, offset int64) (int64, error) {
+	v, n, err := copyInUint(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	if v > 1 {
+		return 0, linuxerr.EINVAL
+	}
+	e := ftrace.Global.Lookup(d.system, d.name)
+	if e == nil {
+		return 0, linuxerr.ENODEV
+	}
+	ftrace.Global.SetEnabled(e, v == 1)
+	return n, nil
+}
+
+// formatData implements vfs.DynamicBytesSource for the "format" file of an
+// event.
+//
+// +stateify savable
+type formatData struct {
+	kernfs.DynamicBytesFile
+
+	system string
+	name   string
+}
+
+var _ vfs.DynamicBytesSource = (*formatData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *formatData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	e := ftrace.Global.Lookup(d.system, d.name)
+	if e == nil {
+		return linuxerr.ENODEV
+	}
+	fmt.Fprintf(buf, "name: %s\nID: %d\nformat:\n", e.Name, e.ID)
+	for _, field := range strings.Split(e.Format, "\t") {
+		fmt.Fprintf(buf, "\t%s\n", field)
+	}
+	return nil
+}
+
+// idData implements vfs.DynamicBytesSource for the "id" file of an event.
+//
+// +stateify savable
+type idData struct {
+	kernfs.DynamicBytesFile
+
+	system string
+	name   string
+}
+
+var _ vfs.DynamicBytesSource = (*idData)(nil)
+
+// Generate implements vfs.DynamicBytesSource.Generate.
+func (d *idData) Generate(ctx context.Context, buf *bytes.Buffer) error {
+	e := ftrace.Global.Lookup(d.system, d.name)
+	if e == nil {
+		return linuxerr.ENODEV
+	}
+	fmt.Fprintf(buf, "%d\n", e.ID)
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verity
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/merkletree"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// measure measures the file at vd in the lower layer and all of its
+// descendants, and returns the file's node and hash.
+//
+// The hash of a file covers its type, permissions and owner, and:
+//
+//   - For regular files, the file's size and fs-verity digest.
+//
+//   - For symlinks, the symlink's target.
+//
+//   - For directories, the name and hash of each child, in order of names.
+//
+//   - For device special files, the device number.
+//
+// The root hash of a filesystem is the hash of its root directory.
+func (fs *filesystem) measure(ctx context.Context, vd vfs.VirtualDentry) (*node, [merkletree.DigestSize]byte, error) {
+	var sum [merkletree.DigestSize]byte
+	vfsObj := fs.vfsfs.VirtualFilesystem()
+	pop := &vfs.PathOperation{
+		Root:  vd,
+		Start: vd,
+	}
+	stat, err := vfsObj.StatAt(ctx, fs.creds, pop, &vfs.StatOptions{
+		Mask: linux.STATX_TYPE | linux.STATX_MODE | linux.STATX_UID | linux.STATX_GID | linux.STATX_INO | linux.STATX_SIZE,
+	})
+	if err != nil {
+		return nil, sum, err
+	}
+	n := &node{
+		ino:  stat.Ino,
+		mode: stat.Mode,
+		uid:  stat.UID,
+		gid:  stat.GID,
+	}
+
+	le := binary.LittleEndian
+	h := sha256.New()
+	var buf [8]byte
+	le.PutUint16(buf[:], n.mode)
+	h.Write(buf[:2])
+	le.PutUint32(buf[:], n.uid)
+	h.Write(buf[:4])
+	le.PutUint32(buf[:], n.gid)
+	h.Write(buf[:4])
+
+	switch n.mode & linux.S_IFMT {
+	case linux.S_IFREG:
+		n.size = stat.Size
+		fd, err := vfsObj.OpenAt(ctx, fs.creds, pop, &vfs.OpenOptions{
+			Flags: linux.O_RDONLY,
+		})
+		if err != nil {
+			return nil, sum, err
+		}
+		tree, err := generateTree(ctx, fd, n.size, nil /* salt */)
+		fd.DecRef(ctx)
+		if err != nil {
+			return nil, sum, err
+		}
+		digest := tree.Digest()
+		n.digest = digest[:]
+		le.PutUint64(buf[:], n.size)
+		h.Write(buf[:8])
+		h.Write(n.digest)
+
+	case linux.S_IFLNK:
+		target, err := vfsObj.ReadlinkAt(ctx, fs.creds, pop)
+		if err != nil {
+			return nil, sum, err
+		}
+		n.target = target
+		h.Write([]byte(target))
+
+	case linux.S_IFDIR:
+		dirents, err := fs.readLowerDir(ctx, vd)
+		if err != nil {
+			return nil, sum, err
+		}
+		sort.Slice(dirents, func(i, j int) bool {
+			return dirents[i].Name < dirents[j].Name
+		})
+		n.children = make(map[string]*node, len(dirents))
+		for _, dirent := range dirents {
+			childVD, err := vfsObj.GetDentryAt(ctx, fs.creds, &vfs.PathOperation{
+				Root:  vd,
+				Start: vd,
+				Path:  fspath.Parse(dirent.Name),
+			}, &vfs.GetDentryOptions{})
+			if err != nil {
+				return nil, sum, err
+			}
+			child, childSum, err := fs.measure(ctx, childVD)
+			childVD.DecRef(ctx)
+			if err != nil {
+				return nil, sum, err
+			}
+			n.children[dirent.Name] = child
+			le.PutUint32(buf[:], uint32(len(dirent.Name)))
+			h.Write(buf[:4])
+			h.Write([]byte(dirent.Name))
+			h.Write(childSum[:])
+		}
+
+	case linux.S_IFCHR, linux.S_IFBLK:
+		n.rdevMajor = stat.RdevMajor
+		n.rdevMinor = stat.RdevMinor
+		le.PutUint32(buf[:], n.rdevMajor)
+		h.Write(buf[:4])
+		le.PutUint32(buf[:], n.rdevMinor)
+		h.Write(buf[:4])
+	}
+
+	h.Sum(sum[:0])
+	return n, sum, nil
+}
+
+// readLowerDir returns the entries of the directory at vd in the lower layer,
+// excluding "." and "..".
+func (fs *filesystem) readLowerDir(ctx context.Context, vd vfs.VirtualDentry) ([]vfs.Dirent, error) {
+	fd, err := fs.vfsfs.VirtualFilesystem().OpenAt(ctx, fs.creds, &vfs.PathOperation{
+		Root:  vd,
+		Start: vd,
+	}, &vfs.OpenOptions{
+		Flags: linux.O_RDONLY | linux.O_DIRECTORY,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer fd.DecRef(ctx)
+	var dirents []vfs.Dirent
+	err = fd.IterDirents(ctx, vfs.IterDirentsCallbackFunc(func(dirent vfs.Dirent) error {
+		if dirent.Name != "." && dirent.Name != ".." {
+			dirents = append(dirents, dirent)
+		}
+		return nil
+	}))
+	return dirents, err
+}
@@ -0,0 +1,460 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verity
+
+import (
+	"io"
+	"sync"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/merkletree"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// maxReadChunk is the maximum number of bytes read from the lower layer at
+// once when reading file data. It must be a multiple of
+// merkletree.BlockSize.
+const maxReadChunk = 1 << 20
+
+// readFull reads len(buf) bytes at offset off of fd into buf.
+func readFull(ctx context.Context, fd *vfs.FileDescription, buf []byte, off uint64) error {
+	for len(buf) > 0 {
+		n, err := fd.PRead(ctx, usermem.BytesIOSequence(buf), int64(off), vfs.ReadOptions{})
+		buf = buf[n:]
+		off += uint64(n)
+		if len(buf) == 0 {
+			break
+		}
+		if err == io.EOF || (err == nil && n == 0) {
+			// The file in the lower layer is shorter than expected.
+			return linuxerr.EIO
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateTree returns the Merkle tree for the first size bytes of fd.
+func generateTree(ctx context.Context, fd *vfs.FileDescription, size uint64, salt []byte) (*merkletree.Tree, error) {
+	return merkletree.Generate(size, salt, func(buf []byte, off uint64) error {
+		return readFull(ctx, fd, buf, off)
+	})
+}
+
+// getLowerFD returns a read-only file description of the file in the lower
+// layer. It does not take a reference on the returned file description.
+func (d *dentry) getLowerFD(ctx context.Context) (*vfs.FileDescription, error) {
+	d.lowerFDMu.Lock()
+	defer d.lowerFDMu.Unlock()
+	if d.lowerFD != nil {
+		return d.lowerFD, nil
+	}
+	fd, err := d.fs.vfsfs.VirtualFilesystem().OpenAt(ctx, d.fs.creds, &vfs.PathOperation{
+		Root:  d.lowerVD,
+		Start: d.lowerVD,
+	}, &vfs.OpenOptions{
+		Flags: linux.O_RDONLY,
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.lowerFD = fd
+	return fd, nil
+}
+
+// getTree returns the file's Merkle tree, or nil if fs-verity is not enabled
+// for the file.
+func (d *dentry) getTree(ctx context.Context) (*merkletree.Tree, error) {
+	d.verityMu.Lock()
+	defer d.verityMu.Unlock()
+	if d.digest == nil || d.tree != nil {
+		return d.tree, nil
+	}
+	fd, err := d.getLowerFD(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := generateTree(ctx, fd, d.size, d.salt)
+	if err != nil {
+		return nil, err
+	}
+	if digest := tree.Digest(); string(digest[:]) != string(d.digest) {
+		ctx.Warningf("verity.dentry.getTree: digest mismatch for file %q: got %x, want %x", d.name, digest, d.digest)
+		return nil, linuxerr.EIO
+	}
+	d.tree = tree
+	return tree, nil
+}
+
+// enableVerity enables fs-verity for the file.
+func (d *dentry) enableVerity(ctx context.Context, salt []byte) error {
+	d.verityMu.Lock()
+	if d.digest != nil {
+		d.verityMu.Unlock()
+		return linuxerr.EEXIST
+	}
+	fd, err := d.getLowerFD(ctx)
+	if err != nil {
+		d.verityMu.Unlock()
+		return err
+	}
+	tree, err := generateTree(ctx, fd, d.size, salt)
+	if err != nil {
+		d.verityMu.Unlock()
+		return err
+	}
+	digest := tree.Digest()
+	d.digest = digest[:]
+	d.salt = tree.Salt
+	d.tree = tree
+	d.verityMu.Unlock()
+
+	// Data that was cached for memory mappings before fs-verity was enabled
+	// hasn't been verified, so drop it. Data cached from now on is verified.
+	d.mapsMu.Lock()
+	defer d.mapsMu.Unlock()
+	d.mappings.InvalidateAll(memmap.InvalidateOpts{})
+	d.dataMu.Lock()
+	defer d.dataMu.Unlock()
+	d.cache.DropAll(d.fs.mf)
+	return nil
+}
+
+// readToBlocksAt reads the file's data at offset off into dsts, verifying it
+// if fs-verity is enabled for the file.
+func (d *dentry) readToBlocksAt(ctx context.Context, dsts safemem.BlockSeq, off uint64) (uint64, error) {
+	if off >= d.size {
+		return 0, io.EOF
+	}
+	tree, err := d.getTree(ctx)
+	if err != nil {
+		return 0, err
+	}
+	fd, err := d.getLowerFD(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := min(dsts.NumBytes(), d.size-off)
+	// Allow for reading the whole blocks containing the range.
+	buf := make([]byte, min((n+2*merkletree.BlockSize-1)&^(merkletree.BlockSize-1), maxReadChunk))
+	var done uint64
+	for done < n {
+		start := off + done
+		var chunk []byte
+		if tree == nil {
+			chunk = buf[:min(n-done, uint64(len(buf)))]
+			if err := readFull(ctx, fd, chunk, start); err != nil {
+				return done, err
+			}
+		} else {
+			// Read and verify whole blocks.
+			blockStart := start &^ (merkletree.BlockSize - 1)
+			blockEnd := min(blockStart+uint64(len(buf)), d.size)
+			chunk = buf[:blockEnd-blockStart]
+			if err := readFull(ctx, fd, chunk, blockStart); err != nil {
+				return done, err
+			}
+			if err := tree.Verify(blockStart/merkletree.BlockSize, chunk); err != nil {
+				ctx.Warningf("verity.dentry.readToBlocksAt: file %q: %v", d.name, err)
+				return done, linuxerr.EIO
+			}
+			chunk = chunk[start-blockStart:]
+			chunk = chunk[:min(n-done, uint64(len(chunk)))]
+		}
+		cp, err := safemem.CopySeq(dsts.DropFirst64(done), safemem.BlockSeqOf(safemem.BlockFromSafeSlice(chunk)))
+		done += cp
+		if err != nil {
+			return done, err
+		}
+	}
+	return done, nil
+}
+
+// +stateify savable
+type regularFileFD struct {
+	fileDescription
+
+	// offMu protects off.
+	offMu sync.Mutex `state:"nosave"`
+
+	// off is the file offset.
+	// +checklocks:offMu
+	off int64
+}
+
+// PRead implements vfs.FileDescriptionImpl.PRead.
+func (fd *regularFileFD) PRead(ctx context.Context, dst usermem.IOSequence, offset int64, opts vfs.ReadOptions) (int64, error) {
+	if offset < 0 {
+		return 0, linuxerr.EINVAL
+	}
+
+	// Check that flags are supported.
+	//
+	// TODO(gvisor.dev/issue/2601): Support select preadv2 flags.
+	if opts.Flags&^linux.RWF_HIPRI != 0 {
+		return 0, linuxerr.EOPNOTSUPP
+	}
+
+	if dst.NumBytes() == 0 {
+		return 0, nil
+	}
+
+	r := &regularFileReader{
+		ctx: ctx,
+		d:   fd.dentry(),
+		off: uint64(offset),
+	}
+	return dst.CopyOutFrom(ctx, r)
+}
+
+type regularFileReader struct {
+	ctx context.Context
+	d   *dentry
+	off uint64
+}
+
+// ReadToBlocks implements safemem.Reader.ReadToBlocks.
+func (r *regularFileReader) ReadToBlocks(dsts safemem.BlockSeq) (uint64, error) {
+	n, err := r.d.readToBlocksAt(r.ctx, dsts, r.off)
+	r.off += n
+	return n, err
+}
+
+// Read implements vfs.FileDescriptionImpl.Read.
+func (fd *regularFileFD) Read(ctx context.Context, dst usermem.IOSequence, opts vfs.ReadOptions) (int64, error) {
+	fd.offMu.Lock()
+	n, err := fd.PRead(ctx, dst, fd.off, opts)
+	fd.off += n
+	fd.offMu.Unlock()
+	return n, err
+}
+
+// PWrite implements vfs.FileDescriptionImpl.PWrite.
+func (fd *regularFileFD) PWrite(ctx context.Context, src usermem.IOSequence, offset int64, opts vfs.WriteOptions) (int64, error) {
+	return 0, linuxerr.EROFS
+}
+
+// Write implements vfs.FileDescriptionImpl.Write.
+func (fd *regularFileFD) Write(ctx context.Context, src usermem.IOSequence, opts vfs.WriteOptions) (int64, error) {
+	return 0, linuxerr.EROFS
+}
+
+// Seek implements vfs.FileDescriptionImpl.Seek.
+func (fd *regularFileFD) Seek(ctx context.Context, offset int64, whence int32) (int64, error) {
+	fd.offMu.Lock()
+	defer fd.offMu.Unlock()
+	switch whence {
+	case linux.SEEK_SET:
+		// use offset as specified
+	case linux.SEEK_CUR:
+		offset += fd.off
+	case linux.SEEK_END:
+		offset += int64(fd.dentry().size)
+	default:
+		return 0, linuxerr.EINVAL
+	}
+	if offset < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	fd.off = offset
+	return offset, nil
+}
+
+// Ioctl implements vfs.FileDescriptionImpl.Ioctl.
+func (fd *regularFileFD) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
+	switch args[1].Uint() {
+	case linux.FS_IOC_ENABLE_VERITY:
+		return 0, fd.enableVerity(ctx, uio, args[2].Pointer())
+	case linux.FS_IOC_MEASURE_VERITY:
+		return 0, fd.measureVerity(ctx, uio, args[2].Pointer())
+	}
+	return fd.fileDescription.Ioctl(ctx, uio, sysno, args)
+}
+
+// enableVerity implements FS_IOC_ENABLE_VERITY.
+//
+// Unlike Linux, only SHA-256 and 4096-byte blocks are supported, and
+// signatures are not supported.
+func (fd *regularFileFD) enableVerity(ctx context.Context, uio usermem.IO, argPtr hostarch.Addr) error {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		return linuxerr.ENOTTY
+	}
+	var arg linux.FsverityEnableArg
+	if _, err := arg.CopyIn(t, argPtr); err != nil {
+		return err
+	}
+	if arg.Version != 1 || arg.Reserved1 != 0 || arg.Reserved2 != [11]uint64{} {
+		return linuxerr.EINVAL
+	}
+	if arg.HashAlgorithm != linux.FS_VERITY_HASH_ALG_SHA256 || arg.BlockSize != merkletree.BlockSize {
+		return linuxerr.EINVAL
+	}
+	if arg.SaltSize > merkletree.MaxSaltSize {
+		return linuxerr.EMSGSIZE
+	}
+	if arg.SigSize != 0 {
+		return linuxerr.EOPNOTSUPP
+	}
+	// As in Linux, enabling fs-verity requires the file to be opened for
+	// reading and writable by the caller, even though it can't be written.
+	if !fd.vfsfd.IsReadable() {
+		return linuxerr.EBADF
+	}
+	d := fd.dentry()
+	if err := d.checkPermissions(auth.CredentialsFromContext(ctx), vfs.MayWrite); err != nil {
+		return err
+	}
+	salt := make([]byte, arg.SaltSize)
+	if _, err := uio.CopyIn(ctx, hostarch.Addr(arg.SaltPtr), salt, usermem.IOOpts{}); err != nil {
+		return err
+	}
+	return d.enableVerity(ctx, salt)
+}
+
+// measureVerity implements FS_IOC_MEASURE_VERITY.
+func (fd *regularFileFD) measureVerity(ctx context.Context, uio usermem.IO, argPtr hostarch.Addr) error {
+	t := kernel.TaskFromContext(ctx)
+	if t == nil {
+		return linuxerr.ENOTTY
+	}
+	d := fd.dentry()
+	d.verityMu.Lock()
+	digest := d.digest
+	d.verityMu.Unlock()
+	if digest == nil {
+		return linuxerr.ENODATA
+	}
+	var arg linux.FsverityDigest
+	if _, err := arg.CopyIn(t, argPtr); err != nil {
+		return err
+	}
+	if arg.DigestSize < merkletree.DigestSize {
+		return linuxerr.EOVERFLOW
+	}
+	arg.DigestAlgorithm = linux.FS_VERITY_HASH_ALG_SHA256
+	arg.DigestSize = merkletree.DigestSize
+	if _, err := arg.CopyOut(t, argPtr); err != nil {
+		return err
+	}
+	_, err := uio.CopyOut(ctx, argPtr+hostarch.Addr(arg.SizeBytes()), digest, usermem.IOOpts{})
+	return err
+}
+
+// ConfigureMMap implements vfs.FileDescriptionImpl.ConfigureMMap.
+func (fd *regularFileFD) ConfigureMMap(ctx context.Context, opts *memmap.MMapOpts) error {
+	return vfs.GenericConfigureMMap(&fd.vfsfd, fd.dentry(), opts)
+}
+
+// AddMapping implements memmap.Mappable.AddMapping.
+func (d *dentry) AddMapping(ctx context.Context, ms memmap.MappingSpace, ar hostarch.AddrRange, offset uint64, writable bool) error {
+	d.mapsMu.Lock()
+	d.mappings.AddMapping(ms, ar, offset, writable)
+	d.mapsMu.Unlock()
+	return nil
+}
+
+// RemoveMapping implements memmap.Mappable.RemoveMapping.
+func (d *dentry) RemoveMapping(ctx context.Context, ms memmap.MappingSpace, ar hostarch.AddrRange, offset uint64, writable bool) {
+	d.mapsMu.Lock()
+	defer d.mapsMu.Unlock()
+	for _, r := range d.mappings.RemoveMapping(ms, ar, offset, writable) {
+		// Drop cached pages that are no longer mapped, since they can be
+		// read from the lower layer again if needed.
+		d.dataMu.Lock()
+		d.cache.Drop(r, d.fs.mf)
+		d.dataMu.Unlock()
+	}
+}
+
+// CopyMapping implements memmap.Mappable.CopyMapping.
+func (d *dentry) CopyMapping(ctx context.Context, ms memmap.MappingSpace, srcAR, dstAR hostarch.AddrRange, offset uint64, writable bool) error {
+	return d.AddMapping(ctx, ms, dstAR, offset, writable)
+}
+
+// Translate implements memmap.Mappable.Translate.
+func (d *dentry) Translate(ctx context.Context, required, optional memmap.MappableRange, at hostarch.AccessType) ([]memmap.Translation, error) {
+	if at.Write {
+		return nil, &memmap.BusError{linuxerr.EROFS}
+	}
+	pgend, _ := hostarch.PageRoundUp(d.size)
+	var beyondEOF bool
+	if required.End > pgend {
+		if required.Start >= pgend {
+			return nil, &memmap.BusError{io.EOF}
+		}
+		beyondEOF = true
+		required.End = pgend
+	}
+	if optional.End > pgend {
+		optional.End = pgend
+	}
+
+	d.dataMu.Lock()
+	defer d.dataMu.Unlock()
+	mf := d.fs.mf
+	_, cerr := d.cache.Fill(ctx, required, optional, d.size, mf, usage.PageCache, pgalloc.AllocateAndWritePopulate, d.readToBlocksAt)
+
+	var ts []memmap.Translation
+	var translatedEnd uint64
+	for seg := d.cache.FindSegment(required.Start); seg.Ok() && seg.Start() < required.End; seg, _ = seg.NextNonEmpty() {
+		segMR := seg.Range().Intersect(optional)
+		ts = append(ts, memmap.Translation{
+			Source: segMR,
+			File:   mf,
+			Offset: seg.FileRangeOf(segMR).Start,
+			Perms:  hostarch.ReadExecute,
+		})
+		translatedEnd = segMR.End
+	}
+
+	// Don't return the error returned by d.cache.Fill if it occurred outside
+	// of required.
+	if translatedEnd < required.End && cerr != nil {
+		return ts, &memmap.BusError{cerr}
+	}
+	if beyondEOF {
+		return ts, &memmap.BusError{io.EOF}
+	}
+	return ts, nil
+}
+
+// InvalidateUnsavable implements memmap.Mappable.InvalidateUnsavable.
+func (d *dentry) InvalidateUnsavable(ctx context.Context) error {
+	d.mapsMu.Lock()
+	defer d.mapsMu.Unlock()
+	d.mappings.InvalidateAll(memmap.InvalidateOpts{})
+
+	// Discard the cache so that it's not stored in saved state. This is safe
+	// because per InvalidateUnsavable invariants, no new translations can have
+	// been returned after we invalidated all existing translations above.
+	d.dataMu.Lock()
+	defer d.dataMu.Unlock()
+	d.cache.DropAll(d.fs.mf)
+	return nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verity
+
+import (
+	goContext "context"
+
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+)
+
+// afterLoad is called by stateify.
+//
+// Merkle trees are not saved, so they are regenerated from the restored lower
+// layer and checked against the saved digests when files are next read.
+func (fs *filesystem) afterLoad(ctx goContext.Context) {
+	fs.mf = pgalloc.MemoryFileFromContext(ctx)
+}
+
+// saveParent is called by stateify.
+func (d *dentry) saveParent() *dentry {
+	return d.parent.Load()
+}
+
+// loadParent is called by stateify.
+func (d *dentry) loadParent(_ goContext.Context, parent *dentry) {
+	d.parent.Store(parent)
+}
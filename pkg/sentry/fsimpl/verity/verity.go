@@ -0,0 +1,693 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package verity implements a read-only stacking filesystem that verifies the
+// contents of another filesystem, the lower layer, against Merkle trees.
+//
+// Files in a verity filesystem can have fs-verity enabled, either at runtime
+// using the FS_IOC_ENABLE_VERITY ioctl, or by measuring the entire lower
+// layer when the filesystem is mounted. Reads of such files are verified
+// against the file's Merkle tree, and fail with EIO if the data in the lower
+// layer doesn't match the tree. The fs-verity digest of files can be queried
+// with FS_IOC_MEASURE_VERITY, which is compatible with fs-verity tooling.
+//
+// Measuring the lower layer records the metadata, directory structure,
+// symlink targets and file digests of the entire lower layer, and computes a
+// root hash covering all of them, which can be reported to and pinned by the
+// runtime. The filesystem then only exposes the measured state of the lower
+// layer: files that are added, removed or modified in the lower layer after
+// the filesystem is mounted are either hidden or fail verification.
+//
+// The lower layer is expected not to change while the filesystem is mounted.
+// Files that don't have fs-verity enabled, and the metadata of files in
+// unmeasured filesystems, are cached when files are first accessed.
+// Submounts of the lower layer are not visible in the verity filesystem.
+package verity
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/merkletree"
+	"gvisor.dev/gvisor/pkg/sentry/fsutil"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// Name is the filesystem name. It is part of the interface used by users,
+// e.g. via mount(2), and shouldn't change.
+const Name = "verity"
+
+// RootHashSize is the size of a filesystem's root hash.
+const RootHashSize = merkletree.DigestSize
+
+// FilesystemType implements vfs.FilesystemType.
+//
+// +stateify savable
+type FilesystemType struct{}
+
+// InternalFilesystemOptions may be passed as
+// vfs.GetFilesystemOptions.InternalData to FilesystemType.GetFilesystem.
+//
+// +stateify savable
+type InternalFilesystemOptions struct {
+	// If LowerRoot.Ok(), it is the root of the lower layer, and the source
+	// passed to FilesystemType.GetFilesystem must be empty. Otherwise, the
+	// source is the path to the root of the lower layer.
+	LowerRoot vfs.VirtualDentry
+
+	// If Measure is true, the entire lower layer is measured when the
+	// filesystem is mounted, and fs-verity is enabled for all regular files.
+	Measure bool
+
+	// If RootHash is non-empty, it is the expected root hash of the lower
+	// layer, which implies Measure. If the measured root hash differs,
+	// mounting the filesystem fails with EIO.
+	RootHash []byte
+}
+
+// filesystem implements vfs.FilesystemImpl.
+//
+// +stateify savable
+type filesystem struct {
+	vfsfs vfs.Filesystem
+
+	// mopts contains the mount options. mopts is immutable.
+	mopts string
+
+	// creds is a copy of the filesystem's creator's credentials, which are
+	// used for accesses to the lower layer. creds is immutable.
+	creds *auth.Credentials
+
+	// devMinor is the filesystem's minor device number. devMinor is immutable.
+	devMinor uint32
+
+	// lowerRoot is the root of the lower layer. The filesystem holds a
+	// reference on lowerRoot. lowerRoot is immutable.
+	lowerRoot vfs.VirtualDentry
+
+	// rootHash is the root hash of the lower layer if it was measured.
+	// rootHash is immutable.
+	rootHash []byte
+
+	// mf is used to cache file contents for memory mappings.
+	mf *pgalloc.MemoryFile `state:"nosave"`
+
+	// root is the root dentry. root is immutable.
+	root *dentry
+}
+
+// Name implements vfs.FilesystemType.Name.
+func (FilesystemType) Name() string {
+	return Name
+}
+
+// Release implements vfs.FilesystemType.Release.
+func (FilesystemType) Release(ctx context.Context) {}
+
+// GetFilesystem implements vfs.FilesystemType.GetFilesystem.
+func (fstype FilesystemType) GetFilesystem(ctx context.Context, vfsObj *vfs.VirtualFilesystem, creds *auth.Credentials, source string, opts vfs.GetFilesystemOptions) (*vfs.Filesystem, *vfs.Dentry, error) {
+	iopts, ok := opts.InternalData.(InternalFilesystemOptions)
+	if opts.InternalData != nil && !ok {
+		ctx.Warningf("verity.FilesystemType.GetFilesystem: GetFilesystemOptions.InternalData has type %T, wanted verity.InternalFilesystemOptions", opts.InternalData)
+		return nil, nil, linuxerr.EINVAL
+	}
+
+	mopts := vfs.GenericParseMountOptions(opts.Data)
+	if rootHashStr, ok := mopts["root_hash"]; ok {
+		delete(mopts, "root_hash")
+		rootHash, err := hex.DecodeString(rootHashStr)
+		if err != nil {
+			ctx.Warningf("verity.FilesystemType.GetFilesystem: invalid root hash %q: %v", rootHashStr, err)
+			return nil, nil, linuxerr.EINVAL
+		}
+		iopts.RootHash = rootHash
+	}
+	if _, ok := mopts["measure"]; ok {
+		delete(mopts, "measure")
+		iopts.Measure = true
+	}
+	if len(mopts) != 0 {
+		ctx.Warningf("verity.FilesystemType.GetFilesystem: unknown options: %v", mopts)
+		return nil, nil, linuxerr.EINVAL
+	}
+	if len(iopts.RootHash) != 0 {
+		if len(iopts.RootHash) != RootHashSize {
+			ctx.Warningf("verity.FilesystemType.GetFilesystem: root hash has size %d, want %d", len(iopts.RootHash), RootHashSize)
+			return nil, nil, linuxerr.EINVAL
+		}
+		iopts.Measure = true
+	}
+
+	lowerRoot := iopts.LowerRoot
+	if lowerRoot.Ok() {
+		if source != "" {
+			ctx.Warningf("verity.FilesystemType.GetFilesystem: both a source and InternalFilesystemOptions.LowerRoot are specified")
+			return nil, nil, linuxerr.EINVAL
+		}
+		lowerRoot.IncRef()
+	} else {
+		lowerPath := fspath.Parse(source)
+		if !lowerPath.Absolute {
+			ctx.Warningf("verity.FilesystemType.GetFilesystem: lower layer %q must be absolute", source)
+			return nil, nil, linuxerr.EINVAL
+		}
+		vfsroot := vfs.RootFromContext(ctx)
+		if vfsroot.Ok() {
+			defer vfsroot.DecRef(ctx)
+		}
+		var err error
+		lowerRoot, err = vfsObj.GetDentryAt(ctx, creds, &vfs.PathOperation{
+			Root:               vfsroot,
+			Start:              vfsroot,
+			Path:               lowerPath,
+			FollowFinalSymlink: true,
+		}, &vfs.GetDentryOptions{
+			CheckSearchable: true,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	// Use a private read-only bind mount of the lower layer, so that it can't
+	// be modified through the lower layer's mount and its submounts are not
+	// visible. This also prevents the filesystem from seeing itself if it's
+	// mounted on top of the lower layer.
+	privateLowerRoot := clonePrivateMount(vfsObj, lowerRoot)
+	lowerRoot.DecRef(ctx)
+
+	devMinor, err := vfsObj.GetAnonBlockDevMinor()
+	if err != nil {
+		privateLowerRoot.DecRef(ctx)
+		return nil, nil, err
+	}
+	fs := &filesystem{
+		mopts:     opts.Data,
+		creds:     creds.Fork(),
+		devMinor:  devMinor,
+		lowerRoot: privateLowerRoot,
+		mf:        pgalloc.MemoryFileFromContext(ctx),
+	}
+	fs.vfsfs.Init(vfsObj, &fstype, fs)
+
+	var rootNode *node
+	if iopts.Measure {
+		n, rootHash, err := fs.measure(ctx, fs.lowerRoot)
+		if err != nil {
+			ctx.Warningf("verity.FilesystemType.GetFilesystem: failed to measure lower layer: %v", err)
+			fs.vfsfs.DecRef(ctx)
+			return nil, nil, err
+		}
+		if len(iopts.RootHash) != 0 && string(iopts.RootHash) != string(rootHash[:]) {
+			ctx.Warningf("verity.FilesystemType.GetFilesystem: root hash mismatch: got %x, want %x", rootHash, iopts.RootHash)
+			fs.vfsfs.DecRef(ctx)
+			return nil, nil, linuxerr.EIO
+		}
+		rootNode = n
+		fs.rootHash = rootHash[:]
+	}
+
+	fs.lowerRoot.IncRef()
+	root, err := fs.newDentry(ctx, fs.lowerRoot, rootNode)
+	if err != nil {
+		fs.vfsfs.DecRef(ctx)
+		return nil, nil, err
+	}
+	if !root.isDir() {
+		ctx.Warningf("verity.FilesystemType.GetFilesystem: lower layer root is not a directory")
+		root.DecRef(ctx)
+		fs.vfsfs.DecRef(ctx)
+		return nil, nil, linuxerr.ENOTDIR
+	}
+
+	// Increase the root's reference count to 2. One reference is returned to
+	// the caller, and the other is held by fs.
+	root.IncRef()
+	fs.root = root
+	return &fs.vfsfs, &root.vfsd, nil
+}
+
+// clonePrivateMount creates a non-recursive, read-only bind mount rooted at
+// vd, not associated with any MountNamespace, and returns the root of the new
+// mount. (This is required to ensure that the lower layer is not modified
+// through the lower layer's mount and doesn't contain submounts.)
+func clonePrivateMount(vfsObj *vfs.VirtualFilesystem, vd vfs.VirtualDentry) vfs.VirtualDentry {
+	opts := vd.Mount().Options()
+	opts.ReadOnly = true
+	newmnt := vfsObj.NewDisconnectedMount(vd.Mount().Filesystem(), vd.Dentry(), &opts)
+	// Take a reference on the dentry which will be owned by the returned
+	// VirtualDentry.
+	d := vd.Dentry()
+	d.IncRef()
+	return vfs.MakeVirtualDentry(newmnt, d)
+}
+
+// Release implements vfs.FilesystemImpl.Release.
+func (fs *filesystem) Release(ctx context.Context) {
+	// An extra reference was held by the filesystem on the root.
+	if fs.root != nil {
+		fs.root.DecRef(ctx)
+	}
+	fs.lowerRoot.DecRef(ctx)
+	fs.vfsfs.VirtualFilesystem().PutAnonBlockDevMinor(fs.devMinor)
+}
+
+// RootHash returns the root hash of the lower layer of the given verity
+// filesystem, which must have been measured when it was mounted.
+func RootHash(vfsfs *vfs.Filesystem) ([]byte, error) {
+	fs, ok := vfsfs.Impl().(*filesystem)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a %s filesystem", vfsfs.FilesystemType().Name(), Name)
+	}
+	if fs.rootHash == nil {
+		return nil, fmt.Errorf("lower layer was not measured")
+	}
+	return append([]byte(nil), fs.rootHash...), nil
+}
+
+func (fs *filesystem) statFS(ctx context.Context) (linux.Statfs, error) {
+	statfs, err := fs.vfsfs.VirtualFilesystem().StatFSAt(ctx, fs.creds, &vfs.PathOperation{
+		Root:  fs.lowerRoot,
+		Start: fs.lowerRoot,
+	})
+	if err != nil {
+		return linux.Statfs{}, err
+	}
+	statfs.Flags |= linux.ST_RDONLY
+	return statfs, nil
+}
+
+// node is the measured state of a file in the lower layer.
+//
+// +stateify savable
+type node struct {
+	ino       uint64
+	mode      uint16
+	uid       uint32
+	gid       uint32
+	size      uint64
+	rdevMajor uint32
+	rdevMinor uint32
+
+	// digest is the fs-verity digest of a regular file.
+	digest []byte
+
+	// target is the target of a symlink.
+	target string
+
+	// children maps the names of a directory's children to their nodes.
+	children map[string]*node
+}
+
+// dentry implements vfs.DentryImpl.
+//
+// As in the erofs filesystem, cached dentries are never dropped until the
+// filesystem is unmounted. Each dentry represents a distinct file; hard links
+// in the lower layer are not detected.
+//
+// +stateify savable
+type dentry struct {
+	vfsd vfs.Dentry
+
+	// dentryRefs is the reference count.
+	dentryRefs
+
+	// fs is the owning filesystem.
+	fs *filesystem
+
+	// parent is this dentry's parent directory. If this dentry is
+	// a file system root, parent is nil.
+	parent atomic.Pointer[dentry] `state:".(*dentry)"`
+
+	// name is this dentry's name in its parent. If this dentry is
+	// a file system root, name is the empty string.
+	name string
+
+	// lowerVD is the file in the lower layer. The dentry holds a reference on
+	// lowerVD. lowerVD is immutable.
+	lowerVD vfs.VirtualDentry
+
+	// node is the measured state of the file if the lower layer was measured,
+	// and nil otherwise. node is immutable.
+	node *node
+
+	// Metadata of the file, from node if the lower layer was measured and from
+	// the lower layer otherwise. The metadata is immutable.
+	ino       uint64
+	mode      uint16
+	uid       uint32
+	gid       uint32
+	nlink     uint32
+	size      uint64
+	blocks    uint64
+	atime     linux.StatxTimestamp
+	ctime     linux.StatxTimestamp
+	mtime     linux.StatxTimestamp
+	rdevMajor uint32
+	rdevMinor uint32
+
+	// dirMu serializes changes to the dentry tree, and protects dirents.
+	dirMu sync.RWMutex `state:"nosave"`
+
+	// childMap contains the mappings of child names to dentries if this
+	// dentry represents a directory.
+	// +checklocks:dirMu
+	childMap map[string]*dentry
+
+	// dirents caches the directory's entries.
+	// +checklocks:dirMu
+	dirents []vfs.Dirent `state:"nosave"`
+
+	// verityMu protects digest, salt and tree.
+	verityMu sync.Mutex `state:"nosave"`
+
+	// If fs-verity is enabled for the file, digest is its fs-verity digest.
+	// Otherwise, digest is nil. digest is immutable once set.
+	// +checklocks:verityMu
+	digest []byte
+
+	// salt is the salt used to compute digest.
+	// +checklocks:verityMu
+	salt []byte
+
+	// tree is the file's Merkle tree if fs-verity is enabled for the file. It
+	// is generated from the lower layer, and checked against digest, when the
+	// file is first read.
+	// +checklocks:verityMu
+	tree *merkletree.Tree `state:"nosave"`
+
+	// lowerFDMu protects lowerFD.
+	lowerFDMu sync.Mutex `state:"nosave"`
+
+	// lowerFD is a read-only file description of a regular file in the lower
+	// layer, opened when the file is first read.
+	// +checklocks:lowerFDMu
+	lowerFD *vfs.FileDescription
+
+	// mapsMu protects mappings.
+	mapsMu sync.Mutex `state:"nosave"`
+
+	// mappings tracks the mappings of the file into memmap.MappingSpaces
+	// if this dentry represents a regular file.
+	// +checklocks:mapsMu
+	mappings memmap.MappingSet
+
+	// dataMu protects cache.
+	dataMu sync.Mutex `state:"nosave"`
+
+	// cache maps offsets in the file to offsets in fs.mf that store the
+	// file's data. cache is only used for memory mappings.
+	// +checklocks:dataMu
+	cache fsutil.FileRangeSet
+
+	// locks supports POSIX and BSD style locks.
+	locks vfs.FileLocks
+
+	// Inotify watches for this dentry.
+	watches vfs.Watches
+}
+
+// newDentry returns a new dentry for lowerVD, taking ownership of the
+// caller's reference on lowerVD. n is the measured state of the file if the
+// lower layer was measured.
+//
+// The caller is expected to handle dentry insertion into dentry tree.
+func (fs *filesystem) newDentry(ctx context.Context, lowerVD vfs.VirtualDentry, n *node) (*dentry, error) {
+	stat, err := fs.vfsfs.VirtualFilesystem().StatAt(ctx, fs.creds, &vfs.PathOperation{
+		Root:  lowerVD,
+		Start: lowerVD,
+	}, &vfs.StatOptions{
+		Mask: linux.STATX_BASIC_STATS,
+	})
+	if err != nil {
+		lowerVD.DecRef(ctx)
+		return nil, err
+	}
+	d := &dentry{
+		fs:        fs,
+		lowerVD:   lowerVD,
+		node:      n,
+		ino:       stat.Ino,
+		mode:      stat.Mode,
+		uid:       stat.UID,
+		gid:       stat.GID,
+		nlink:     stat.Nlink,
+		size:      stat.Size,
+		blocks:    stat.Blocks,
+		atime:     stat.Atime,
+		ctime:     stat.Ctime,
+		mtime:     stat.Mtime,
+		rdevMajor: stat.RdevMajor,
+		rdevMinor: stat.RdevMinor,
+	}
+	if n != nil {
+		if n.mode&linux.S_IFMT != stat.Mode&linux.S_IFMT {
+			ctx.Warningf("verity.filesystem.newDentry: file type of inode %d changed from %#o to %#o after measurement", n.ino, n.mode&linux.S_IFMT, stat.Mode&linux.S_IFMT)
+			lowerVD.DecRef(ctx)
+			return nil, linuxerr.EIO
+		}
+		d.ino = n.ino
+		d.mode = n.mode
+		d.uid = n.uid
+		d.gid = n.gid
+		d.size = n.size
+		d.rdevMajor = n.rdevMajor
+		d.rdevMinor = n.rdevMinor
+		d.digest = n.digest // +checklocksignore
+	}
+	d.InitRefs()
+	d.vfsd.Init(d)
+	return d, nil
+}
+
+// DecRef implements vfs.DentryImpl.DecRef.
+func (d *dentry) DecRef(ctx context.Context) {
+	d.dentryRefs.DecRef(func() {
+		d.dirMu.Lock()
+		for _, c := range d.childMap {
+			c.DecRef(ctx)
+		}
+		d.childMap = nil
+		d.dirMu.Unlock()
+		d.lowerFDMu.Lock()
+		if d.lowerFD != nil {
+			d.lowerFD.DecRef(ctx)
+			d.lowerFD = nil
+		}
+		d.lowerFDMu.Unlock()
+		d.dataMu.Lock()
+		d.cache.DropAll(d.fs.mf)
+		d.dataMu.Unlock()
+		d.lowerVD.DecRef(ctx)
+	})
+}
+
+// InotifyWithParent implements vfs.DentryImpl.InotifyWithParent.
+func (d *dentry) InotifyWithParent(ctx context.Context, events, cookie uint32, et vfs.EventType) {
+	if d.isDir() {
+		events |= linux.IN_ISDIR
+	}
+	// The ordering below is important, Linux always notifies the parent first.
+	if parent := d.parent.Load(); parent != nil {
+		parent.watches.Notify(ctx, d.name, events, cookie, et, false)
+	}
+	d.watches.Notify(ctx, "", events, cookie, et, false)
+}
+
+// Watches implements vfs.DentryImpl.Watches.
+func (d *dentry) Watches() *vfs.Watches {
+	return &d.watches
+}
+
+// OnZeroWatches implements vfs.DentryImpl.OnZeroWatches.
+func (d *dentry) OnZeroWatches(ctx context.Context) {}
+
+func (d *dentry) checkPermissions(creds *auth.Credentials, ats vfs.AccessTypes) error {
+	return vfs.GenericCheckPermissions(creds, ats, linux.FileMode(d.mode), auth.KUID(d.uid), auth.KGID(d.gid))
+}
+
+func (d *dentry) fileType() uint16 {
+	return d.mode & linux.S_IFMT
+}
+
+func (d *dentry) isDir() bool {
+	return d.fileType() == linux.S_IFDIR
+}
+
+func (d *dentry) isSymlink() bool {
+	return d.fileType() == linux.S_IFLNK
+}
+
+// verityEnabled returns true if fs-verity is enabled for the file.
+func (d *dentry) verityEnabled() bool {
+	d.verityMu.Lock()
+	defer d.verityMu.Unlock()
+	return d.digest != nil
+}
+
+func (d *dentry) statTo(stat *linux.Statx) {
+	stat.Mask = linux.STATX_TYPE | linux.STATX_MODE | linux.STATX_NLINK |
+		linux.STATX_UID | linux.STATX_GID | linux.STATX_INO | linux.STATX_SIZE |
+		linux.STATX_BLOCKS | linux.STATX_ATIME | linux.STATX_CTIME |
+		linux.STATX_MTIME
+	stat.Blksize = merkletree.BlockSize
+	stat.Nlink = d.nlink
+	stat.UID = d.uid
+	stat.GID = d.gid
+	stat.Mode = d.mode
+	stat.Ino = d.ino
+	stat.Size = d.size
+	stat.Blocks = d.blocks
+	stat.Atime = d.atime
+	stat.Ctime = d.ctime
+	stat.Mtime = d.mtime
+	if ft := d.fileType(); ft == linux.S_IFCHR || ft == linux.S_IFBLK {
+		stat.RdevMajor = d.rdevMajor
+		stat.RdevMinor = d.rdevMinor
+	}
+	stat.AttributesMask = linux.STATX_ATTR_VERITY
+	if d.verityEnabled() {
+		stat.Attributes |= linux.STATX_ATTR_VERITY
+	}
+	stat.DevMajor = linux.UNNAMED_MAJOR
+	stat.DevMinor = d.fs.devMinor
+}
+
+// readlink returns the target of a symlink.
+func (d *dentry) readlink(ctx context.Context) (string, error) {
+	if !d.isSymlink() {
+		return "", linuxerr.EINVAL
+	}
+	if d.node != nil {
+		return d.node.target, nil
+	}
+	return d.fs.vfsfs.VirtualFilesystem().ReadlinkAt(ctx, d.fs.creds, &vfs.PathOperation{
+		Root:  d.lowerVD,
+		Start: d.lowerVD,
+	})
+}
+
+func (d *dentry) open(ctx context.Context, rp *vfs.ResolvingPath, opts *vfs.OpenOptions) (*vfs.FileDescription, error) {
+	ats := vfs.AccessTypesForOpenFlags(opts)
+	if err := d.checkPermissions(rp.Credentials(), ats); err != nil {
+		return nil, err
+	}
+
+	switch d.fileType() {
+	case linux.S_IFREG:
+		if ats&vfs.MayWrite != 0 {
+			return nil, linuxerr.EROFS
+		}
+		var fd regularFileFD
+		fd.LockFD.Init(&d.locks)
+		if err := fd.vfsfd.Init(&fd, opts.Flags, rp.Mount(), &d.vfsd, &vfs.FileDescriptionOptions{AllowDirectIO: true}); err != nil {
+			return nil, err
+		}
+		return &fd.vfsfd, nil
+
+	case linux.S_IFDIR:
+		// Can't open directories with O_CREAT.
+		if opts.Flags&linux.O_CREAT != 0 {
+			return nil, linuxerr.EISDIR
+		}
+		// Can't open directories writably.
+		if ats&vfs.MayWrite != 0 {
+			return nil, linuxerr.EISDIR
+		}
+		if opts.Flags&linux.O_DIRECT != 0 {
+			return nil, linuxerr.EINVAL
+		}
+		var fd directoryFD
+		fd.LockFD.Init(&d.locks)
+		if err := fd.vfsfd.Init(&fd, opts.Flags, rp.Mount(), &d.vfsd, &vfs.FileDescriptionOptions{AllowDirectIO: true}); err != nil {
+			return nil, err
+		}
+		return &fd.vfsfd, nil
+
+	case linux.S_IFLNK:
+		// Can't open symlinks without O_PATH, which is handled at the VFS layer.
+		return nil, linuxerr.ELOOP
+
+	default:
+		return nil, linuxerr.ENXIO
+	}
+}
+
+// +stateify savable
+type fileDescription struct {
+	vfsfd vfs.FileDescription
+	vfs.FileDescriptionDefaultImpl
+	vfs.LockFD
+}
+
+func (fd *fileDescription) filesystem() *filesystem {
+	return fd.vfsfd.Mount().Filesystem().Impl().(*filesystem)
+}
+
+func (fd *fileDescription) dentry() *dentry {
+	return fd.vfsfd.Dentry().Impl().(*dentry)
+}
+
+// Stat implements vfs.FileDescriptionImpl.Stat.
+func (fd *fileDescription) Stat(ctx context.Context, opts vfs.StatOptions) (linux.Statx, error) {
+	var stat linux.Statx
+	fd.dentry().statTo(&stat)
+	return stat, nil
+}
+
+// SetStat implements vfs.FileDescriptionImpl.SetStat.
+func (fd *fileDescription) SetStat(ctx context.Context, opts vfs.SetStatOptions) error {
+	return linuxerr.EROFS
+}
+
+// StatFS implements vfs.FileDescriptionImpl.StatFS.
+func (fd *fileDescription) StatFS(ctx context.Context) (linux.Statfs, error) {
+	return fd.filesystem().statFS(ctx)
+}
+
+// ListXattr implements vfs.FileDescriptionImpl.ListXattr.
+func (fd *fileDescription) ListXattr(ctx context.Context, size uint64) ([]string, error) {
+	return nil, linuxerr.ENOTSUP
+}
+
+// GetXattr implements vfs.FileDescriptionImpl.GetXattr.
+func (fd *fileDescription) GetXattr(ctx context.Context, opts vfs.GetXattrOptions) (string, error) {
+	return "", linuxerr.ENOTSUP
+}
+
+// SetXattr implements vfs.FileDescriptionImpl.SetXattr.
+func (fd *fileDescription) SetXattr(ctx context.Context, opts vfs.SetXattrOptions) error {
+	return linuxerr.EROFS
+}
+
+// RemoveXattr implements vfs.FileDescriptionImpl.RemoveXattr.
+func (fd *fileDescription) RemoveXattr(ctx context.Context, name string) error {
+	return linuxerr.EROFS
+}
+
+// Sync implements vfs.FileDescriptionImpl.Sync.
+func (*fileDescription) Sync(context.Context) error {
+	return nil
+}
+
+// Release implements vfs.FileDescriptionImpl.Release.
+func (*fileDescription) Release(ctx context.Context) {}
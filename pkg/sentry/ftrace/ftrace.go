@@ -0,0 +1,488 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ftrace implements the trace events and trace buffer exposed to the
+// application through tracefs.
+//
+// Only a small subset of Linux's trace events is supported: syscall entry and
+// exit, and a few scheduler events. They are emitted by the kernel package at
+// the corresponding points. Kprobes are accepted, but only fire if their
+// symbol can be mapped to a syscall.
+package ftrace
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"gvisor.dev/gvisor/pkg/abi/sentry"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const (
+	numSyscalls = sentry.MaxSyscallNum + 1
+
+	// DefaultBufferSizeKB is the default size of the trace buffer, in KB.
+	DefaultBufferSizeKB = 1408
+)
+
+// Header identifies the task that emitted an event.
+type Header struct {
+	// Comm is the task name.
+	Comm string
+	// PID is the task's thread ID in the root PID namespace.
+	PID int32
+	// CPU is the CPU that the task is running on.
+	CPU int32
+	// TimeNs is the monotonic time at which the event was emitted.
+	TimeNs int64
+}
+
+// Event is a trace event that can be enabled through tracefs.
+type Event struct {
+	// System is the subsystem the event belongs to, e.g. "syscalls".
+	// Immutable.
+	System string
+
+	// Name is the event name, e.g. "sys_enter_openat". Immutable.
+	Name string
+
+	// ID is the unique event identifier. Immutable.
+	ID uint32
+
+	// Format describes the event fields. Immutable.
+	Format string
+
+	// enabled is true if the event should be recorded.
+	enabled atomicbitops.Bool
+
+	// sysno is the syscall the event is attached to, if attached is true.
+	// exit is true if the event fires at syscall exit instead of entry.
+	// Immutable.
+	attached bool
+	sysno    uintptr
+	exit     bool
+}
+
+// Enabled returns true if the event is enabled.
+func (e *Event) Enabled() bool {
+	return e.enabled.Load()
+}
+
+// Tracer holds the set of trace events and the trace buffer.
+type Tracer struct {
+	// on is false if recording into the trace buffer is disabled.
+	on atomicbitops.Bool
+
+	// rawEnter and rawExit are the raw_syscalls events, which fire for all
+	// syscalls.
+	rawEnter *Event
+	rawExit  *Event
+
+	// SchedSwitch, SchedProcessFork, SchedProcessExec and SchedProcessExit are
+	// the sched events. Immutable.
+	SchedSwitch      *Event
+	SchedProcessFork *Event
+	SchedProcessExec *Event
+	SchedProcessExit *Event
+
+	// syscallEnter and syscallExit count the enabled events attached to entry
+	// and exit of each syscall.
+	syscallEnter [numSyscalls]atomicbitops.Int32
+	syscallExit  [numSyscalls]atomicbitops.Int32
+
+	mu sync.Mutex
+
+	// nextID is the ID of the next registered event.
+	//
+	// +checklocks:mu
+	nextID uint32
+
+	// events maps "system:name" to events.
+	//
+	// +checklocks:mu
+	events map[string]*Event
+
+	// syscallNames maps syscall numbers to names.
+	//
+	// +checklocks:mu
+	syscallNames map[uintptr]string
+
+	// kprobes are the kprobe definitions in the order they were added.
+	//
+	// +checklocks:mu
+	kprobes []*kprobe
+
+	// generation is incremented every time the set of events changes.
+	//
+	// +checklocks:mu
+	generation uint64
+
+	// buf is the trace buffer.
+	//
+	// +checklocks:mu
+	buf buffer
+}
+
+// Global is the tracer exposed through tracefs.
+var Global = NewTracer()
+
+// NewTracer returns a Tracer with the static events registered.
+func NewTracer() *Tracer {
+	t := &Tracer{
+		events: make(map[string]*Event),
+	}
+	t.on.Store(true)
+	t.buf.size = DefaultBufferSizeKB * 1024
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rawEnter = t.addEventLocked("raw_syscalls", "sys_enter", "field:long id;\tfield:unsigned long args[6];")
+	t.rawExit = t.addEventLocked("raw_syscalls", "sys_exit", "field:long id;\tfield:long ret;")
+	t.SchedSwitch = t.addEventLocked("sched", "sched_switch", "field:char prev_comm[16];\tfield:pid_t prev_pid;\tfield:long prev_state;\tfield:char next_comm[16];\tfield:pid_t next_pid;")
+	t.SchedProcessFork = t.addEventLocked("sched", "sched_process_fork", "field:char parent_comm[16];\tfield:pid_t parent_pid;\tfield:char child_comm[16];\tfield:pid_t child_pid;")
+	t.SchedProcessExec = t.addEventLocked("sched", "sched_process_exec", "field:__data_loc char[] filename;\tfield:pid_t pid;\tfield:pid_t old_pid;")
+	t.SchedProcessExit = t.addEventLocked("sched", "sched_process_exit", "field:char comm[16];\tfield:pid_t pid;")
+	return t
+}
+
+// +checklocks:t.mu
+func (t *Tracer) addEventLocked(system, name, format string) *Event {
+	t.nextID++
+	e := &Event{
+		System: system,
+		Name:   name,
+		ID:     t.nextID,
+		Format: format,
+	}
+	t.events[system+":"+name] = e
+	t.generation++
+	return e
+}
+
+// RegisterSyscalls registers the syscalls:sys_enter_* and syscalls:sys_exit_*
+// events for the given syscalls. Syscalls that are already registered are
+// ignored.
+func (t *Tracer) RegisterSyscalls(names map[uintptr]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.syscallNames == nil {
+		t.syscallNames = make(map[uintptr]string)
+	}
+	for sysno, name := range names {
+		if sysno >= numSyscalls || name == "" {
+			continue
+		}
+		if _, ok := t.syscallNames[sysno]; ok {
+			continue
+		}
+		t.syscallNames[sysno] = name
+		enter := t.addEventLocked("syscalls", "sys_enter_"+name, "field:int __syscall_nr;\tfield:unsigned long args[6];")
+		enter.attached, enter.sysno = true, sysno
+		exit := t.addEventLocked("syscalls", "sys_exit_"+name, "field:int __syscall_nr;\tfield:long ret;")
+		exit.attached, exit.sysno, exit.exit = true, sysno, true
+	}
+}
+
+// syscallByNameLocked returns the number of the syscall with the given name.
+//
+// +checklocks:t.mu
+func (t *Tracer) syscallByNameLocked(name string) (uintptr, bool) {
+	for sysno, n := range t.syscallNames {
+		if n == name {
+			return sysno, true
+		}
+	}
+	return 0, false
+}
+
+// Generation returns a value that changes every time events are added or
+// removed.
+func (t *Tracer) Generation() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.generation
+}
+
+// Systems returns the names of all subsystems with events, sorted.
+func (t *Tracer) Systems() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	set := make(map[string]struct{})
+	for _, e := range t.events {
+		set[e.System] = struct{}{}
+	}
+	systems := make([]string, 0, len(set))
+	for s := range set {
+		systems = append(systems, s)
+	}
+	sort.Strings(systems)
+	return systems
+}
+
+// Events returns the events in the given subsystem, sorted by name. If system
+// is empty, all events are returned, sorted by subsystem and name.
+func (t *Tracer) Events(system string) []*Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var events []*Event
+	for _, e := range t.events {
+		if system == "" || e.System == system {
+			events = append(events, e)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].System != events[j].System {
+			return events[i].System < events[j].System
+		}
+		return events[i].Name < events[j].Name
+	})
+	return events
+}
+
+// Lookup returns the event with the given subsystem and name, or nil.
+func (t *Tracer) Lookup(system, name string) *Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.events[system+":"+name]
+}
+
+// SetEnabled enables or disables an event.
+func (t *Tracer) SetEnabled(e *Event, enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.setEnabledLocked(e, enabled)
+}
+
+// +checklocks:t.mu
+func (t *Tracer) setEnabledLocked(e *Event, enabled bool) {
+	if e.enabled.Load() == enabled {
+		return
+	}
+	e.enabled.Store(enabled)
+	if !e.attached {
+		return
+	}
+	delta := int32(1)
+	if !enabled {
+		delta = -1
+	}
+	if e.exit {
+		t.syscallExit[e.sysno].Add(delta)
+	} else {
+		t.syscallEnter[e.sysno].Add(delta)
+	}
+}
+
+// SetSystemEnabled enables or disables all events in the given subsystem, or
+// all events if system is empty.
+func (t *Tracer) SetSystemEnabled(system string, enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, e := range t.events {
+		if system == "" || e.System == system {
+			t.setEnabledLocked(e, enabled)
+		}
+	}
+}
+
+// On returns true if events are being recorded.
+func (t *Tracer) On() bool {
+	return t.on.Load()
+}
+
+// SetOn enables or disables recording events.
+func (t *Tracer) SetOn(on bool) {
+	t.on.Store(on)
+}
+
+// SyscallEnterEnabled returns true if any event fires at entry of syscall
+// sysno.
+func (t *Tracer) SyscallEnterEnabled(sysno uintptr) bool {
+	if sysno >= numSyscalls {
+		return false
+	}
+	return t.rawEnter.Enabled() || t.syscallEnter[sysno].Load() != 0
+}
+
+// SyscallExitEnabled returns true if any event fires at exit of syscall
+// sysno.
+func (t *Tracer) SyscallExitEnabled(sysno uintptr) bool {
+	if sysno >= numSyscalls {
+		return false
+	}
+	return t.rawExit.Enabled() || t.syscallExit[sysno].Load() != 0
+}
+
+// SyscallEnter records the entry of syscall sysno with the given arguments.
+func (t *Tracer) SyscallEnter(h Header, sysno uintptr, args [6]uint64) {
+	argStr := fmt.Sprintf("%x, %x, %x, %x, %x, %x", args[0], args[1], args[2], args[3], args[4], args[5])
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rawEnter.Enabled() {
+		t.emitLocked(h, t.rawEnter, fmt.Sprintf("NR %d (%s)", sysno, argStr))
+	}
+	if t.syscallEnter[sysno].Load() == 0 {
+		return
+	}
+	name := t.syscallNames[sysno]
+	for _, e := range t.attachedLocked(sysno, false) {
+		if e.System == "syscalls" {
+			t.emitLocked(h, e, fmt.Sprintf("sys_%s(%s)", name, argStr))
+		} else {
+			t.emitLocked(h, e, fmt.Sprintf("(%s)", name))
+		}
+	}
+}
+
+// SyscallExit records the exit of syscall sysno with the given return value.
+func (t *Tracer) SyscallExit(h Header, sysno uintptr, ret int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rawExit.Enabled() {
+		t.emitLocked(h, t.rawExit, fmt.Sprintf("NR %d = %d", sysno, ret))
+	}
+	if t.syscallExit[sysno].Load() == 0 {
+		return
+	}
+	name := t.syscallNames[sysno]
+	for _, e := range t.attachedLocked(sysno, true) {
+		if e.System == "syscalls" {
+			t.emitLocked(h, e, fmt.Sprintf("sys_%s -> 0x%x", name, ret))
+		} else {
+			t.emitLocked(h, e, fmt.Sprintf("(%s) arg1=0x%x", name, ret))
+		}
+	}
+}
+
+// attachedLocked returns the enabled events attached to syscall sysno.
+//
+// +checklocks:t.mu
+func (t *Tracer) attachedLocked(sysno uintptr, exit bool) []*Event {
+	var events []*Event
+	for _, e := range t.events {
+		if e.attached && e.sysno == sysno && e.exit == exit && e.Enabled() {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Emit records an event with the given formatted fields, if the event is
+// enabled.
+func (t *Tracer) Emit(h Header, e *Event, format string, a ...any) {
+	if !e.Enabled() {
+		return
+	}
+	fields := fmt.Sprintf(format, a...)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.emitLocked(h, e, fields)
+}
+
+// +checklocks:t.mu
+func (t *Tracer) emitLocked(h Header, e *Event, fields string) {
+	if !t.on.Load() {
+		return
+	}
+	comm := h.Comm
+	if comm == "" {
+		comm = "<...>"
+	}
+	sec, usec := h.TimeNs/1e9, (h.TimeNs%1e9)/1e3
+	t.buf.add(fmt.Sprintf("%16s-%-7d [%03d] ..... %5d.%06d: %s: %s\n", comm, h.PID, h.CPU, sec, usec, e.Name, fields))
+}
+
+// BufferSizeKB returns the size of the trace buffer, in KB.
+func (t *Tracer) BufferSizeKB() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.buf.size / 1024
+}
+
+// SetBufferSizeKB sets the size of the trace buffer, in KB, dropping the
+// oldest entries if needed.
+func (t *Tracer) SetBufferSizeKB(kb int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf.size = kb * 1024
+	t.buf.trim()
+}
+
+// Trace writes the contents of the trace buffer to out, in the format of
+// Linux's "trace" file.
+func (t *Tracer) Trace(out *bytes.Buffer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(out, "# tracer: nop\n#\n# entries-in-buffer/entries-written: %d/%d   #P:1\n#\n", len(t.buf.entries), t.buf.written)
+	out.WriteString("#           TASK-PID     CPU#  |||||  TIMESTAMP  FUNCTION\n")
+	out.WriteString("#              | |         |   |||||     |         |\n")
+	for _, e := range t.buf.entries {
+		out.WriteString(e)
+	}
+}
+
+// ConsumeTrace writes the entries of the trace buffer to out and removes them
+// from the buffer, as reading Linux's "trace_pipe" file does.
+func (t *Tracer) ConsumeTrace(out *bytes.Buffer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, e := range t.buf.entries {
+		out.WriteString(e)
+	}
+	t.buf.clear()
+}
+
+// ClearTrace removes all entries from the trace buffer.
+func (t *Tracer) ClearTrace() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf.clear()
+}
+
+// buffer is a bounded FIFO of formatted trace entries.
+type buffer struct {
+	// entries are the formatted entries, oldest first.
+	entries []string
+	// bytes is the total length of entries.
+	bytes int
+	// size is the maximum value of bytes.
+	size int
+	// written is the number of entries ever added.
+	written uint64
+}
+
+func (b *buffer) add(entry string) {
+	b.entries = append(b.entries, entry)
+	b.bytes += len(entry)
+	b.written++
+	b.trim()
+}
+
+func (b *buffer) trim() {
+	i := 0
+	for b.bytes > b.size && i < len(b.entries) {
+		b.bytes -= len(b.entries[i])
+		i++
+	}
+	if i > 0 {
+		b.entries = append(b.entries[:0], b.entries[i:]...)
+	}
+}
+
+func (b *buffer) clear() {
+	b.entries = nil
+	b.bytes = 0
+}
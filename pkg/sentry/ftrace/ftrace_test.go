@@ -0,0 +1,167 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ftrace
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func newTestTracer() *Tracer {
+	t := NewTracer()
+	t.RegisterSyscalls(map[uintptr]string{0: "read", 257: "openat"})
+	return t
+}
+
+func TestSyscallEvents(t *testing.T) {
+	tr := newTestTracer()
+	if tr.SyscallEnterEnabled(257) {
+		t.Fatalf("SyscallEnterEnabled(257) = true before enabling")
+	}
+	tr.SetEnabled(tr.Lookup("syscalls", "sys_enter_openat"), true)
+	if !tr.SyscallEnterEnabled(257) {
+		t.Fatalf("SyscallEnterEnabled(257) = false after enabling")
+	}
+	if tr.SyscallEnterEnabled(0) || tr.SyscallExitEnabled(257) {
+		t.Fatalf("unrelated syscall events enabled")
+	}
+	h := Header{Comm: "cat", PID: 42, TimeNs: 1500000000}
+	tr.SyscallEnter(h, 257, [6]uint64{1, 2, 3})
+
+	var out bytes.Buffer
+	tr.Trace(&out)
+	if !strings.Contains(out.String(), "cat-42") || !strings.Contains(out.String(), "1.500000: sys_enter_openat: sys_openat(1, 2, 3, 0, 0, 0)") {
+		t.Errorf("unexpected trace:\n%s", out.String())
+	}
+
+	tr.SetSystemEnabled("", false)
+	if tr.SyscallEnterEnabled(257) {
+		t.Errorf("SyscallEnterEnabled(257) = true after disabling all events")
+	}
+}
+
+func TestTracingOff(t *testing.T) {
+	tr := newTestTracer()
+	tr.SetEnabled(tr.SchedProcessExit, true)
+	tr.SetOn(false)
+	tr.Emit(Header{Comm: "sh", PID: 1}, tr.SchedProcessExit, "comm=%s pid=%d", "sh", 1)
+	var out bytes.Buffer
+	tr.ConsumeTrace(&out)
+	if out.Len() != 0 {
+		t.Errorf("event recorded while tracing is off: %q", out.String())
+	}
+}
+
+func TestConsumeTrace(t *testing.T) {
+	tr := newTestTracer()
+	tr.SetEnabled(tr.SchedProcessExit, true)
+	tr.Emit(Header{Comm: "sh", PID: 1}, tr.SchedProcessExit, "comm=%s pid=%d", "sh", 1)
+	var out bytes.Buffer
+	tr.ConsumeTrace(&out)
+	if !strings.Contains(out.String(), "sched_process_exit: comm=sh pid=1") {
+		t.Errorf("unexpected trace_pipe output: %q", out.String())
+	}
+	out.Reset()
+	tr.ConsumeTrace(&out)
+	if out.Len() != 0 {
+		t.Errorf("trace_pipe not consumed: %q", out.String())
+	}
+}
+
+func TestBufferSize(t *testing.T) {
+	tr := newTestTracer()
+	tr.SetBufferSizeKB(1)
+	tr.SetEnabled(tr.SchedProcessExit, true)
+	for i := 0; i < 100; i++ {
+		tr.Emit(Header{Comm: "sh", PID: int32(i)}, tr.SchedProcessExit, "pid=%d", i)
+	}
+	var out bytes.Buffer
+	tr.ConsumeTrace(&out)
+	if out.Len() > 1024 {
+		t.Errorf("trace buffer has %d bytes, want at most 1024", out.Len())
+	}
+	if !strings.Contains(out.String(), "pid=99\n") {
+		t.Errorf("newest entry missing from trace buffer: %q", out.String())
+	}
+}
+
+func TestKprobe(t *testing.T) {
+	tr := newTestTracer()
+	for _, tc := range []struct {
+		line   string
+		system string
+		name   string
+		sysno  uintptr
+		exit   bool
+		attach bool
+	}{
+		{line: "p:myopen __x64_sys_openat", system: "kprobes", name: "myopen", sysno: 257, attach: true},
+		{line: "r:grp/myread ksys_read", system: "grp", name: "myread", sysno: 0, exit: true, attach: true},
+		{line: "p do_unlinkat+4 arg1=%di", system: "kprobes", name: "p_do_unlinkat_4"},
+	} {
+		if err := tr.AddKprobe(tc.line); err != nil {
+			t.Fatalf("AddKprobe(%q): %v", tc.line, err)
+		}
+		e := tr.Lookup(tc.system, tc.name)
+		if e == nil {
+			t.Fatalf("AddKprobe(%q): event %s:%s not found", tc.line, tc.system, tc.name)
+		}
+		if e.attached != tc.attach || e.sysno != tc.sysno || e.exit != tc.exit {
+			t.Errorf("AddKprobe(%q): got attached=%t sysno=%d exit=%t, want %t %d %t", tc.line, e.attached, e.sysno, e.exit, tc.attach, tc.sysno, tc.exit)
+		}
+	}
+	if err := tr.AddKprobe("p:myopen __x64_sys_openat"); err == nil {
+		t.Errorf("AddKprobe() of a duplicate event should have failed")
+	}
+
+	var out bytes.Buffer
+	tr.KprobeEvents(&out)
+	want := "p:kprobes/myopen __x64_sys_openat\nr:grp/myread ksys_read\np:kprobes/p_do_unlinkat_4 do_unlinkat arg1=%di\n"
+	if out.String() != want {
+		t.Errorf("KprobeEvents():\ngot:  %q\nwant: %q", out.String(), want)
+	}
+
+	e := tr.Lookup("kprobes", "myopen")
+	tr.SetEnabled(e, true)
+	if err := tr.AddKprobe("-:myopen"); err == nil {
+		t.Errorf("removing an enabled kprobe should have failed")
+	}
+	if err := tr.ClearKprobes(); err == nil {
+		t.Errorf("clearing an enabled kprobe should have failed")
+	}
+	tr.SetEnabled(e, false)
+	if err := tr.AddKprobe("-:myopen"); err != nil {
+		t.Errorf("AddKprobe(-:myopen): %v", err)
+	}
+	if err := tr.ClearKprobes(); err != nil {
+		t.Errorf("ClearKprobes(): %v", err)
+	}
+	if got := tr.Events("grp"); len(got) != 0 {
+		t.Errorf("events remain after ClearKprobes(): %v", got)
+	}
+}
+
+func TestParseKprobeError(t *testing.T) {
+	for _, line := range []string{
+		"p",
+		"x:foo do_sys_open",
+		"r:foo do_sys_open+4",
+	} {
+		if _, err := parseKprobe(line); err == nil {
+			t.Errorf("parseKprobe(%q) should have failed", line)
+		}
+	}
+}
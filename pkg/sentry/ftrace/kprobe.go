@@ -0,0 +1,214 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ftrace
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/log"
+)
+
+// kprobe is a dynamic event registered through the kprobe_events file.
+type kprobe struct {
+	// def is the definition as it is listed in kprobe_events.
+	def string
+	// event is the event created for the kprobe.
+	event *Event
+}
+
+// syscallSymbolPrefixes are the prefixes of kernel symbols that implement a
+// syscall, e.g. __x64_sys_openat. Kprobes on these symbols are mapped to the
+// syscall's entry or exit.
+var syscallSymbolPrefixes = []string{
+	"__x64_sys_",
+	"__ia32_sys_",
+	"__arm64_sys_",
+	"__se_sys_",
+	"__do_sys_",
+	"do_sys_",
+	"ksys_",
+	"sys_",
+}
+
+// kprobeDef is a parsed kprobe_events line.
+type kprobeDef struct {
+	ret    bool
+	group  string
+	name   string
+	symbol string
+	args   []string
+}
+
+// parseKprobe parses a kprobe definition, as described in Linux's
+// Documentation/trace/kprobetrace.rst:
+//
+//	p[:[GRP/][EVENT]] [MOD:]SYM[+offs]|MEMADDR [FETCHARGS]
+//	r[MAXACTIVE][:[GRP/][EVENT]] [MOD:]SYM[+0] [FETCHARGS]
+func parseKprobe(line string) (kprobeDef, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return kprobeDef{}, linuxerr.EINVAL
+	}
+	var def kprobeDef
+	kind, event, _ := strings.Cut(fields[0], ":")
+	switch {
+	case kind == "p":
+	case strings.HasPrefix(kind, "r"):
+		def.ret = true
+	default:
+		return kprobeDef{}, linuxerr.EINVAL
+	}
+	def.group = "kprobes"
+	if group, name, ok := strings.Cut(event, "/"); ok {
+		if group != "" {
+			def.group = group
+		}
+		event = name
+	}
+	def.symbol = fields[1]
+	if _, sym, ok := strings.Cut(def.symbol, ":"); ok {
+		def.symbol = sym
+	}
+	sym, offset, _ := strings.Cut(def.symbol, "+")
+	def.symbol = sym
+	if def.symbol == "" || (def.ret && offset != "" && offset != "0") {
+		return kprobeDef{}, linuxerr.EINVAL
+	}
+	if event == "" {
+		prefix := "p"
+		if def.ret {
+			prefix = "r"
+		}
+		event = fmt.Sprintf("%s_%s_%s", prefix, strings.ReplaceAll(def.symbol, ".", "_"), orZero(offset))
+	}
+	def.name = event
+	def.args = fields[2:]
+	return def, nil
+}
+
+func orZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
+
+// String returns the definition as listed in kprobe_events.
+func (d kprobeDef) String() string {
+	kind := "p"
+	if d.ret {
+		kind = "r"
+	}
+	s := fmt.Sprintf("%s:%s/%s %s", kind, d.group, d.name, d.symbol)
+	if len(d.args) > 0 {
+		s += " " + strings.Join(d.args, " ")
+	}
+	return s
+}
+
+// AddKprobe processes a line written to kprobe_events. Lines starting with
+// "-:" remove a kprobe, other lines add one. Kprobes whose symbol can't be
+// mapped to a syscall are accepted, but never fire.
+func (t *Tracer) AddKprobe(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	if name, ok := strings.CutPrefix(line, "-:"); ok {
+		return t.removeKprobe(name)
+	}
+	def, err := parseKprobe(line)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.events[def.group+":"+def.name]; ok {
+		return linuxerr.EEXIST
+	}
+	e := t.addEventLocked(def.group, def.name, "field:unsigned long __probe_ip;")
+	for _, prefix := range syscallSymbolPrefixes {
+		name, ok := strings.CutPrefix(def.symbol, prefix)
+		if !ok {
+			continue
+		}
+		if sysno, ok := t.syscallByNameLocked(name); ok {
+			e.attached, e.sysno, e.exit = true, sysno, def.ret
+			break
+		}
+	}
+	if !e.attached {
+		log.Debugf("kprobe %q on %q is not supported and will never fire", def.name, def.symbol)
+	}
+	t.kprobes = append(t.kprobes, &kprobe{def: def.String(), event: e})
+	return nil
+}
+
+// removeKprobe removes the kprobe named "[GRP/]EVENT".
+func (t *Tracer) removeKprobe(name string) error {
+	group := "kprobes"
+	if g, n, ok := strings.Cut(name, "/"); ok {
+		group, name = g, n
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, k := range t.kprobes {
+		if k.event.System != group || k.event.Name != name {
+			continue
+		}
+		if k.event.Enabled() {
+			return linuxerr.EBUSY
+		}
+		t.kprobes = append(t.kprobes[:i], t.kprobes[i+1:]...)
+		delete(t.events, group+":"+name)
+		t.generation++
+		return nil
+	}
+	return linuxerr.ENOENT
+}
+
+// ClearKprobes removes all kprobes, as opening kprobe_events with O_TRUNC
+// does. It fails with EBUSY if any kprobe is enabled.
+func (t *Tracer) ClearKprobes() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, k := range t.kprobes {
+		if k.event.Enabled() {
+			return linuxerr.EBUSY
+		}
+	}
+	for _, k := range t.kprobes {
+		delete(t.events, k.event.System+":"+k.event.Name)
+	}
+	if len(t.kprobes) > 0 {
+		t.generation++
+	}
+	t.kprobes = nil
+	return nil
+}
+
+// KprobeEvents writes the kprobe definitions to out, one per line.
+func (t *Tracer) KprobeEvents(out *bytes.Buffer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, k := range t.kprobes {
+		out.WriteString(k.def)
+		out.WriteString("\n")
+	}
+}
@@ -44,6 +44,10 @@ type AbstractSocketNamespace struct {
 	// destroyed. It is the responsibility of the socket to remove itself from the
 	// abstract socket namespace when it is destroyed.
 	endpoints map[string]abstractEndpoint
+
+	// hostConnector, if not nil, is used to connect to abstract sockets on
+	// the host whose names are not bound in the namespace.
+	hostConnector transport.HostAbstractConnector `state:"nosave"`
 }
 
 // A boundEndpoint wraps a transport.BoundEndpoint to maintain a reference on
@@ -63,6 +67,14 @@ func (a *AbstractSocketNamespace) init() {
 	a.endpoints = make(map[string]abstractEndpoint)
 }
 
+// SetHostConnector sets the HostAbstractConnector used to connect to abstract
+// sockets on the host.
+func (a *AbstractSocketNamespace) SetHostConnector(c transport.HostAbstractConnector) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.hostConnector = c
+}
+
 // BoundEndpoint retrieves the endpoint bound to the given name. The return
 // value is nil if no endpoint was bound.
 func (a *AbstractSocketNamespace) BoundEndpoint(name string) transport.BoundEndpoint {
@@ -71,6 +83,9 @@ func (a *AbstractSocketNamespace) BoundEndpoint(name string) transport.BoundEndp
 
 	ep, ok := a.endpoints[name]
 	if !ok {
+		if a.hostConnector != nil && a.hostConnector.Bridged(name) {
+			return transport.NewHostAbstractEndpoint(a.hostConnector, name)
+		}
 		return nil
 	}
 
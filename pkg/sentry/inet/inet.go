@@ -79,6 +79,14 @@ type Stack interface {
 	// SetTCPRecovery attempts to change TCP loss detection algorithm.
 	SetTCPRecovery(recovery TCPLossRecovery) error
 
+	// TCPECN returns the TCP Explicit Congestion Notification mode, as in
+	// /proc/sys/net/ipv4/tcp_ecn.
+	TCPECN() (int32, error)
+
+	// SetTCPECN attempts to change the TCP Explicit Congestion Notification
+	// mode.
+	SetTCPECN(mode int32) error
+
 	// Statistics reports stack statistics.
 	Statistics(stat any, arg string) error
 
@@ -137,6 +145,9 @@ type Interface struct {
 	// MTU is the maximum transmission unit.
 	MTU uint32
 
+	// Master is the index of the VRF the device is enslaved to, or 0.
+	Master int32
+
 	// Features are the device features queried from the host at
 	// stack creation time. These are immutable after startup.
 	Features []linux.EthtoolGetFeaturesBlock
@@ -237,6 +248,25 @@ type StatSNMPUDP [8]uint64
 // StatSNMPUDPLite describes UdpLite line of /proc/net/snmp.
 type StatSNMPUDPLite [8]uint64
 
+// StatSNMP6IP describes Ip6 lines of /proc/net/snmp6.
+type StatSNMP6IP [32]uint64
+
+// StatSNMP6ICMP describes Icmp6 lines of /proc/net/snmp6, including the
+// per-message type counters of named message types.
+type StatSNMP6ICMP [35]uint64
+
+// StatSNMP6UDP describes Udp6 lines of /proc/net/snmp6.
+type StatSNMP6UDP [9]uint64
+
+// StatSNMP6UDPLite describes UdpLite6 lines of /proc/net/snmp6.
+type StatSNMP6UDPLite [8]uint64
+
+// StatNetstatTCPExt describes TcpExt line of /proc/net/netstat.
+type StatNetstatTCPExt [121]uint64
+
+// StatNetstatIPExt describes IpExt line of /proc/net/netstat.
+type StatNetstatIPExt [18]uint64
+
 // TCPLossRecovery indicates TCP loss detection and recovery methods to use.
 type TCPLossRecovery int32
 
@@ -36,6 +36,7 @@ type TestStack struct {
 	TCPSendBufSize    TCPBufferSize
 	TCPSACKFlag       bool
 	Recovery          TCPLossRecovery
+	ECN               int32
 	IPForwarding      bool
 }
 
@@ -147,6 +148,17 @@ func (s *TestStack) SetTCPRecovery(recovery TCPLossRecovery) error {
 	return nil
 }
 
+// TCPECN implements Stack.
+func (s *TestStack) TCPECN() (int32, error) {
+	return s.ECN, nil
+}
+
+// SetTCPECN implements Stack.
+func (s *TestStack) SetTCPECN(mode int32) error {
+	s.ECN = mode
+	return nil
+}
+
 // Statistics implements Stack.
 func (s *TestStack) Statistics(stat any, arg string) error {
 	return nil
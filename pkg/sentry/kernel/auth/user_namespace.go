@@ -17,6 +17,7 @@ package auth
 import (
 	"math"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 )
 
@@ -51,7 +52,10 @@ type UserNamespace struct {
 	gidMapFromParent idMapSet
 	gidMapToParent   idMapSet
 
-	// TODO(b/27454212): Support disabling setgroups(2).
+	// setgroupsDenied is true if setgroups(2) has been disabled in this
+	// namespace by writing "deny" to /proc/[pid]/setgroups. It is inherited
+	// from the parent namespace on creation.
+	setgroupsDenied bool
 }
 
 // NewRootUserNamespace returns a UserNamespace that is appropriate for a
@@ -90,6 +94,11 @@ func (ns *UserNamespace) Root() *UserNamespace {
 	return ns
 }
 
+// Parent returns the parent of ns, or nil if ns is a root namespace.
+func (ns *UserNamespace) Parent() *UserNamespace {
+	return ns.parent
+}
+
 // "The kernel imposes (since version 3.11) a limit of 32 nested levels of user
 // namespaces." - user_namespaces(7)
 const maxUserNamespaceDepth = 32
@@ -129,5 +138,51 @@ func (c *Credentials) NewChildUserNamespace() (*UserNamespace, error) {
 		// "When a user namespace is created, it starts without a mapping of
 		// user IDs (group IDs) to the parent user namespace." -
 		// user_namespaces(7)
+		//
+		// "The setgroups file in a new user namespace inherits the value of
+		// the file in the parent namespace."
+		setgroupsDenied: c.UserNamespace.SetgroupsDenied(),
 	}, nil
 }
+
+// SetgroupsDenied returns true if setgroups(2) has been disabled in ns by
+// writing "deny" to /proc/[pid]/setgroups.
+func (ns *UserNamespace) SetgroupsDenied() bool {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return ns.setgroupsDenied
+}
+
+// SetgroupsAllowed returns true if setgroups(2) may be called in ns, which
+// requires ns to have a GID mapping and setgroups(2) not to be disabled.
+// This is consistent with Linux's kernel/user_namespace.c:userns_may_setgroups().
+func (ns *UserNamespace) SetgroupsAllowed() bool {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return !ns.gidMapFromParent.IsEmpty() && !ns.setgroupsDenied
+}
+
+// SetSetgroups allows or denies setgroups(2) in ns, as written to
+// /proc/[pid]/setgroups by a caller with credentials c.
+func (ns *UserNamespace) SetSetgroups(c *Credentials, allow bool) error {
+	if !c.HasCapabilityIn(linux.CAP_SYS_ADMIN, ns) {
+		return linuxerr.EPERM
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if allow {
+		// "it is not possible to change the value in this file from "deny"
+		// to "allow"" - user_namespaces(7)
+		if ns.setgroupsDenied {
+			return linuxerr.EPERM
+		}
+		return nil
+	}
+	// "It is not permitted to write to this file after the gid_map has been
+	// written"
+	if !ns.gidMapFromParent.IsEmpty() {
+		return linuxerr.EPERM
+	}
+	ns.setgroupsDenied = true
+	return nil
+}
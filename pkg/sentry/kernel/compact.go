@@ -0,0 +1,51 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"gvisor.dev/gvisor/pkg/metric"
+)
+
+var compactedBytes = metric.MustCreateNewUint64Metric("/kernel/compacted_bytes", false /* sync */, "Number of bytes of application memory migrated by memory compaction.")
+
+// Compact migrates the private memory of all tasks in k out of huge pages of
+// k's MemoryFile in which at most maxUsed bytes are allocated, consolidating
+// free space in the MemoryFile so that it can be returned to the host in
+// larger ranges. It returns the number of bytes migrated. It does nothing if k
+// is paused. See mm.MemoryManager.Compact.
+func (k *Kernel) Compact(maxUsed uint64) uint64 {
+	// Exclude saving, which expects memory not to move.
+	k.extMu.Lock()
+	defer k.extMu.Unlock()
+	k.tasks.mu.RLock()
+	paused := k.tasks.stopCount > 0
+	k.tasks.mu.RUnlock()
+	if paused {
+		return 0
+	}
+
+	frs := k.mf.FragmentedHugePages(maxUsed)
+	if len(frs) == 0 {
+		return 0
+	}
+	ctx := k.SupervisorContext()
+	var migrated uint64
+	for _, m := range k.memoryManagers() {
+		migrated += m.Compact(ctx, frs)
+		m.DecUsers(ctx)
+	}
+	compactedBytes.IncrementBy(migrated)
+	return migrated
+}
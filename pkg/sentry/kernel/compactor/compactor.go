@@ -0,0 +1,108 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compactor implements the sandbox memory compactor, which
+// periodically migrates application memory out of sparsely used huge pages of
+// the kernel's MemoryFile.
+//
+// Over time, the MemoryFile fragments, such that free pages are scattered
+// between allocated pages. This prevents the host from backing the MemoryFile
+// with huge pages, and prevents free pages from being decommitted if they can
+// only be decommitted in huge page units (e.g. if the MemoryFile is backed by
+// hugetlbfs). Compaction consolidates allocated pages at lower offsets, such
+// that free pages can be returned to the host in larger ranges.
+package compactor
+
+import (
+	"time"
+
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const (
+	// DefaultPeriod is the default interval between compaction passes.
+	DefaultPeriod = 10 * time.Second
+
+	// DefaultMaxUsed is the default maximum number of allocated bytes in a
+	// huge page from which memory is migrated.
+	DefaultMaxUsed = hostarch.HugePageSize / 2
+)
+
+// Compactor periodically performs compaction passes over the kernel's
+// MemoryFile.
+type Compactor struct {
+	k *kernel.Kernel
+
+	// maxUsed is the maximum number of allocated bytes in a huge page from
+	// which memory is migrated.
+	maxUsed uint64
+
+	// period is how often compaction passes are performed.
+	period time.Duration
+
+	// Writing to this channel indicates the compactor goroutine should stop.
+	stop chan struct{}
+
+	// done is used to signal when the compactor goroutine has exited.
+	done sync.WaitGroup
+}
+
+// New creates a new Compactor.
+func New(k *kernel.Kernel, maxUsed uint64, period time.Duration) *Compactor {
+	return &Compactor{
+		k:       k,
+		maxUsed: maxUsed,
+		period:  period,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Stop stops the compactor goroutine. Stop must not be called concurrently
+// with Start and may only be called once.
+func (c *Compactor) Stop() {
+	close(c.stop)
+	c.done.Wait()
+}
+
+// Start starts the compactor goroutine. Start must not be called concurrently
+// with Stop and may only be called once.
+func (c *Compactor) Start() {
+	if c.period == 0 {
+		return
+	}
+	log.Infof("Starting memory compactor, max used: %d bytes per huge page, period: %v", c.maxUsed, c.period)
+	c.done.Add(1)
+	go c.run() // S/R-SAFE: Kernel.Compact does nothing while the kernel is paused.
+}
+
+func (c *Compactor) run() {
+	defer c.done.Done()
+
+	ticker := time.NewTicker(c.period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			if migrated := c.k.Compact(c.maxUsed); migrated > 0 {
+				log.Debugf("Memory compaction migrated %d bytes", migrated)
+			}
+		}
+	}
+}
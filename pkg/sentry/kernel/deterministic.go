@@ -0,0 +1,134 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+	"time"
+)
+
+// deterministicQuantum is the wall time for which a task may run application
+// code while other tasks are waiting to do so, before it is preempted.
+const deterministicQuantum = 10 * time.Millisecond
+
+// EnableDeterministicMode makes executions of the sandbox reproducible, for
+// record/replay debugging and reproduction of flaky tests:
+//
+//   - Random bytes returned by getrandom(2), /dev/[u]random and AT_RANDOM are
+//     generated from seed.
+//
+//   - Application code of a single task runs at a time. Tasks take turns in
+//     the order in which they become ready to run application code, and are
+//     preempted after deterministicQuantum if other tasks are waiting.
+//
+//   - If the Timekeeper uses logical clocks (see time.LogicalClocks), time
+//     skips to the next timer deadline when all tasks are blocked.
+//
+// Executions are still affected by external events, such as network traffic
+// and host file changes, and by address space layout randomization.
+//
+// Preconditions: No tasks are running.
+func (k *Kernel) EnableDeterministicMode(seed uint64) {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], seed)
+	k.deterministicRand = &deterministicReader{rng: rand.NewChaCha8(key)}
+	k.deterministicSched = &deterministicScheduler{}
+}
+
+// deterministicReader is an io.Reader that returns a reproducible sequence of
+// pseudorandom bytes.
+type deterministicReader struct {
+	mu deterministicReaderMutex
+
+	// rng generates the bytes. rng is protected by mu.
+	rng *rand.ChaCha8
+}
+
+// Read implements io.Reader.Read.
+func (r *deterministicReader) Read(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var buf [8]byte
+	for i := 0; i < len(b); i += len(buf) {
+		binary.LittleEndian.PutUint64(buf[:], r.rng.Uint64())
+		copy(b[i:], buf[:])
+	}
+	return len(b), nil
+}
+
+// deterministicScheduler serializes the execution of application code by
+// tasks.
+type deterministicScheduler struct {
+	mu deterministicSchedulerMutex
+
+	// running is the task that is allowed to run application code, or nil if
+	// there is none. running is protected by mu.
+	running *Task
+
+	// waiters are the channels of tasks waiting to run application code, in
+	// the order in which they will run. waiters is protected by mu.
+	waiters []chan struct{}
+}
+
+// acquire blocks until t may run application code. If t waits for longer than
+// deterministicQuantum, the running task is preempted.
+//
+// Preconditions: The caller must be running on t's task goroutine.
+func (s *deterministicScheduler) acquire(t *Task) {
+	s.mu.Lock()
+	if s.running == nil {
+		s.running = t
+		s.mu.Unlock()
+		return
+	}
+	ch := make(chan struct{})
+	s.waiters = append(s.waiters, ch)
+	s.mu.Unlock()
+
+	timer := time.NewTimer(deterministicQuantum)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ch:
+			// release() handed over to t.
+			s.mu.Lock()
+			s.running = t
+			s.mu.Unlock()
+			return
+		case <-timer.C:
+			s.mu.Lock()
+			if s.running != nil {
+				s.running.p.Interrupt()
+			}
+			s.mu.Unlock()
+			timer.Reset(deterministicQuantum)
+		}
+	}
+}
+
+// release is called when the running task stops running application code,
+// and lets the next waiting task run.
+func (s *deterministicScheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.waiters) == 0 {
+		s.running = nil
+		return
+	}
+	// s.running is set by the next task once it wakes up.
+	close(s.waiters[0])
+	s.waiters = s.waiters[1:]
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestDeterministicRand(t *testing.T) {
+	read := func(seed uint64) []byte {
+		var k Kernel
+		k.EnableDeterministicMode(seed)
+		// Reads that aren't a multiple of 8 bytes must not lose bytes.
+		b := make([]byte, 35)
+		if _, err := io.ReadFull(k.deterministicRand, b[:13]); err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if _, err := io.ReadFull(k.deterministicRand, b[13:]); err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		return b
+	}
+
+	b1, b2 := read(1), read(1)
+	if !bytes.Equal(b1, b2) {
+		t.Errorf("got different bytes with the same seed: %x and %x", b1, b2)
+	}
+	if b3 := read(2); bytes.Equal(b1, b3) {
+		t.Errorf("got the same bytes with different seeds: %x", b1)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+)
+
+// maxErrno is the largest errno value, as in Linux's
+// include/linux/err.h:MAX_ERRNO.
+const maxErrno = 4095
+
+// FaultInjectionRule describes a fault injected into a syscall, to test how
+// applications in the sandbox cope with failing or slow syscalls.
+type FaultInjectionRule struct {
+	// Syscall is the name of the syscall that the rule applies to.
+	Syscall string `json:"syscall"`
+
+	// ContainerID, if not empty, restricts the rule to tasks in the given
+	// container.
+	ContainerID string `json:"containerID,omitempty"`
+
+	// PIDs, if not empty, restricts the rule to the given processes,
+	// identified by their PID in the root PID namespace.
+	PIDs []ThreadID `json:"pids,omitempty"`
+
+	// Probability is the probability, in [0, 1], that the rule applies to
+	// each invocation of the syscall. If zero, the rule applies to every
+	// invocation.
+	Probability float64 `json:"probability,omitempty"`
+
+	// Errno, if not zero, makes the syscall fail with the given errno without
+	// being executed.
+	Errno int `json:"errno,omitempty"`
+
+	// Delay delays the syscall by the given duration before it's executed, or
+	// fails if Errno is set.
+	Delay time.Duration `json:"delay,omitempty"`
+}
+
+func (r *FaultInjectionRule) validate() error {
+	if r.Probability < 0 || r.Probability > 1 {
+		return fmt.Errorf("syscall %q: probability %v is not in [0, 1]", r.Syscall, r.Probability)
+	}
+	if r.Errno < 0 || r.Errno > maxErrno {
+		return fmt.Errorf("syscall %q: invalid errno %d", r.Syscall, r.Errno)
+	}
+	if r.Delay < 0 {
+		return fmt.Errorf("syscall %q: negative delay %v", r.Syscall, r.Delay)
+	}
+	if r.Errno == 0 && r.Delay == 0 {
+		return fmt.Errorf("syscall %q: rule injects neither an error nor a delay", r.Syscall)
+	}
+	return nil
+}
+
+// matches returns true if r applies to the current syscall of t.
+func (r *FaultInjectionRule) matches(t *Task) bool {
+	if r.ContainerID != "" && r.ContainerID != t.ContainerID() {
+		return false
+	}
+	if len(r.PIDs) != 0 {
+		pid := t.k.tasks.Root.IDOfThreadGroup(t.tg)
+		found := false
+		for _, p := range r.PIDs {
+			if p == pid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return r.Probability == 0 || rand.Float64() < r.Probability
+}
+
+// faultInjector holds the fault injection rules of a Kernel.
+type faultInjector struct {
+	mu faultInjectorRWMutex
+
+	// rules is the list of rules, as passed to SetFaultInjectionRules. rules
+	// is protected by mu.
+	rules []FaultInjectionRule
+
+	// bySyscall maps syscall tables and syscall numbers to the rules that
+	// apply to them. bySyscall is protected by mu.
+	bySyscall map[*SyscallTable]map[uintptr][]*FaultInjectionRule
+}
+
+// SetFaultInjectionRules replaces the fault injection rules of the sandbox.
+// Passing no rules disables fault injection.
+func (k *Kernel) SetFaultInjectionRules(rules []FaultInjectionRule) error {
+	rules = append([]FaultInjectionRule(nil), rules...)
+	bySyscall := make(map[*SyscallTable]map[uintptr][]*FaultInjectionRule)
+	for i := range rules {
+		r := &rules[i]
+		if err := r.validate(); err != nil {
+			return err
+		}
+		found := false
+		for _, table := range SyscallTables() {
+			sysno, err := table.LookupNo(r.Syscall)
+			if err != nil {
+				continue
+			}
+			found = true
+			if bySyscall[table] == nil {
+				bySyscall[table] = make(map[uintptr][]*FaultInjectionRule)
+			}
+			bySyscall[table][sysno] = append(bySyscall[table][sysno], r)
+		}
+		if !found {
+			return fmt.Errorf("syscall %q not found", r.Syscall)
+		}
+	}
+
+	fi := &k.faultInjector
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.rules = rules
+	fi.bySyscall = bySyscall
+	for _, table := range SyscallTables() {
+		enabled := make(map[uintptr]bool)
+		for sysno := range bySyscall[table] {
+			enabled[sysno] = true
+		}
+		table.FeatureEnable.Enable(FaultInjectEnable, enabled, false /* missingEnable */)
+	}
+	return nil
+}
+
+// FaultInjectionRules returns the fault injection rules of the sandbox.
+func (k *Kernel) FaultInjectionRules() []FaultInjectionRule {
+	fi := &k.faultInjector
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+	return append([]FaultInjectionRule(nil), fi.rules...)
+}
+
+// injectSyscallFault applies the first fault injection rule that matches the
+// current syscall of t. It returns the error that the syscall must fail with,
+// or nil if the syscall must be executed.
+func (t *Task) injectSyscallFault(sysno uintptr) error {
+	var rule *FaultInjectionRule
+	fi := &t.k.faultInjector
+	fi.mu.RLock()
+	for _, r := range fi.bySyscall[t.SyscallTable()][sysno] {
+		if r.matches(t) {
+			rule = r
+			break
+		}
+	}
+	fi.mu.RUnlock()
+	if rule == nil {
+		return nil
+	}
+
+	t.Debugf("Injecting fault into syscall %s: errno %d, delay %v", rule.Syscall, rule.Errno, rule.Delay)
+	if rule.Delay > 0 {
+		if _, err := t.BlockWithTimeout(nil, true, rule.Delay); linuxerr.Equals(linuxerr.ErrInterrupted, err) {
+			// Behave like a slow syscall interrupted by a signal.
+			return linuxerr.ERESTARTSYS
+		}
+	}
+	if rule.Errno != 0 {
+		return unix.Errno(rule.Errno)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+)
+
+func TestSetFaultInjectionRules(t *testing.T) {
+	const writeSysno = 1
+	table := &SyscallTable{
+		OS:   abi.Linux,
+		Arch: arch.AMD64,
+		Table: map[uintptr]Syscall{
+			writeSysno: {Name: "write"},
+		},
+	}
+	RegisterSyscallTable(table)
+	defer func() {
+		// Cleanup registered tables to keep tests separate.
+		allSyscallTables = []*SyscallTable{}
+	}()
+
+	var k Kernel
+	for _, tc := range []struct {
+		name    string
+		rule    FaultInjectionRule
+		wantErr bool
+	}{
+		{
+			name: "errno",
+			rule: FaultInjectionRule{Syscall: "write", Errno: int(unix.ENOSPC), Probability: 0.01},
+		},
+		{
+			name: "delay",
+			rule: FaultInjectionRule{Syscall: "write", Delay: 50 * time.Millisecond},
+		},
+		{
+			name:    "unknown syscall",
+			rule:    FaultInjectionRule{Syscall: "foo", Errno: int(unix.EIO)},
+			wantErr: true,
+		},
+		{
+			name:    "invalid probability",
+			rule:    FaultInjectionRule{Syscall: "write", Errno: int(unix.EIO), Probability: 2},
+			wantErr: true,
+		},
+		{
+			name:    "invalid errno",
+			rule:    FaultInjectionRule{Syscall: "write", Errno: maxErrno + 1},
+			wantErr: true,
+		},
+		{
+			name:    "no fault",
+			rule:    FaultInjectionRule{Syscall: "write"},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := k.SetFaultInjectionRules([]FaultInjectionRule{tc.rule})
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("SetFaultInjectionRules(%+v) succeeded, want error", tc.rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetFaultInjectionRules(%+v): %v", tc.rule, err)
+			}
+			if got := table.FeatureEnable.Word(writeSysno); got&FaultInjectEnable == 0 {
+				t.Errorf("fault injection not enabled for write: flags %#x", got)
+			}
+			if got := k.FaultInjectionRules(); len(got) != 1 || got[0].Syscall != "write" {
+				t.Errorf("FaultInjectionRules() = %+v, want the rule that was set", got)
+			}
+		})
+	}
+
+	if err := k.SetFaultInjectionRules(nil); err != nil {
+		t.Fatalf("SetFaultInjectionRules(nil): %v", err)
+	}
+	if got := table.FeatureEnable.Word(writeSysno); got&FaultInjectEnable != 0 {
+		t.Errorf("fault injection still enabled for write: flags %#x", got)
+	}
+}
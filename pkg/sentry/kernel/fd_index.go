@@ -0,0 +1,173 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"math/bits"
+)
+
+// fdIndex tracks which file descriptors in an FDTable are in use.
+//
+// fdIndex is a two-tier bitmap. The lower tier has one bit per file
+// descriptor. The upper tier summarizes each 64-bit word of the lower tier
+// with one bit indicating that the word is non-empty and one bit indicating
+// that the word is full. Searches for used or unused file descriptors consult
+// the upper tier to skip over runs of 4096 file descriptors at a time, so
+// iterating over the used file descriptors in a table costs time proportional
+// to the number of used file descriptors rather than the size of the table,
+// even in tables with hundreds of thousands of entries.
+//
+// The zero value of fdIndex is empty. fdIndex is not synchronized; callers
+// must serialize access, typically with FDTable.mu.
+type fdIndex struct {
+	// used has bit i set iff file descriptor i is in use.
+	used []uint64
+
+	// nonEmpty has bit i set iff used[i] != 0.
+	nonEmpty []uint64
+
+	// full has bit i set iff used[i] is all ones.
+	full []uint64
+
+	// count is the number of bits set in used.
+	count int
+}
+
+// add marks fd as used.
+func (x *fdIndex) add(fd int32) {
+	w, b := int(fd)/64, uint64(1)<<(uint(fd)%64)
+	x.grow(w + 1)
+	if x.used[w]&b != 0 {
+		return
+	}
+	x.used[w] |= b
+	x.count++
+	x.nonEmpty[w/64] |= 1 << (w % 64)
+	if x.used[w] == ^uint64(0) {
+		x.full[w/64] |= 1 << (w % 64)
+	}
+}
+
+// remove marks fd as unused.
+func (x *fdIndex) remove(fd int32) {
+	w, b := int(fd)/64, uint64(1)<<(uint(fd)%64)
+	if w >= len(x.used) || x.used[w]&b == 0 {
+		return
+	}
+	x.used[w] &^= b
+	x.count--
+	x.full[w/64] &^= 1 << (w % 64)
+	if x.used[w] == 0 {
+		x.nonEmpty[w/64] &^= 1 << (w % 64)
+	}
+}
+
+// grow ensures that x can represent at least n words of file descriptors.
+func (x *fdIndex) grow(n int) {
+	if n <= len(x.used) {
+		return
+	}
+	// Grow geometrically so that adding file descriptors in increasing order
+	// takes amortized constant time.
+	if n < 2*len(x.used) {
+		n = 2 * len(x.used)
+	}
+	x.used = append(x.used, make([]uint64, n-len(x.used))...)
+	if s := (n + 63) / 64; s > len(x.nonEmpty) {
+		x.nonEmpty = append(x.nonEmpty, make([]uint64, s-len(x.nonEmpty))...)
+		x.full = append(x.full, make([]uint64, s-len(x.full))...)
+	}
+}
+
+// isEmpty returns true if no file descriptors are used.
+func (x *fdIndex) isEmpty() bool {
+	return x.count == 0
+}
+
+// firstUsed returns the lowest used file descriptor greater than or equal to
+// start, or -1 if no such file descriptor exists.
+func (x *fdIndex) firstUsed(start int32) int32 {
+	w := int(start) / 64
+	if w >= len(x.used) {
+		return -1
+	}
+	if m := x.used[w] >> (uint(start) % 64); m != 0 {
+		return start + int32(bits.TrailingZeros64(m))
+	}
+	if w = nextBit(x.nonEmpty, w+1, true); w < 0 {
+		return -1
+	}
+	return int32(w*64 + bits.TrailingZeros64(x.used[w]))
+}
+
+// firstUnused returns the lowest unused file descriptor greater than or equal
+// to start.
+func (x *fdIndex) firstUnused(start int32) int32 {
+	w := int(start) / 64
+	if w >= len(x.used) {
+		return start
+	}
+	if m := ^x.used[w] >> (uint(start) % 64); m != 0 {
+		return start + int32(bits.TrailingZeros64(m))
+	}
+	w = nextBit(x.full, w+1, false)
+	if w < 0 || w >= len(x.used) {
+		return int32(len(x.used) * 64)
+	}
+	return int32(w*64 + bits.TrailingZeros64(^x.used[w]))
+}
+
+// last returns the highest used file descriptor, or -1 if none are used.
+func (x *fdIndex) last() int32 {
+	for s := len(x.nonEmpty) - 1; s >= 0; s-- {
+		if m := x.nonEmpty[s]; m != 0 {
+			w := s*64 + 63 - bits.LeadingZeros64(m)
+			return int32(w*64 + 63 - bits.LeadingZeros64(x.used[w]))
+		}
+	}
+	return -1
+}
+
+// forEach calls fn for each used file descriptor in [start, end) in increasing
+// order, stopping if fn returns false.
+func (x *fdIndex) forEach(start, end int32, fn func(fd int32) bool) {
+	for fd := x.firstUsed(start); fd >= 0 && fd < end; fd = x.firstUsed(fd + 1) {
+		if !fn(fd) {
+			return
+		}
+	}
+}
+
+// nextBit returns the index of the first bit at or after i in words that is
+// equal to set, or -1 if no such bit exists.
+func nextBit(words []uint64, i int, set bool) int {
+	var flip uint64
+	if !set {
+		flip = ^uint64(0)
+	}
+	w := i / 64
+	if w >= len(words) {
+		return -1
+	}
+	if m := (words[w] ^ flip) >> (uint(i) % 64); m != 0 {
+		return i + bits.TrailingZeros64(m)
+	}
+	for w++; w < len(words); w++ {
+		if m := words[w] ^ flip; m != 0 {
+			return w*64 + bits.TrailingZeros64(m)
+		}
+	}
+	return -1
+}
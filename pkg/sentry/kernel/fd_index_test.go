@@ -0,0 +1,121 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// checkFDIndex verifies that x agrees with the set of fds in want, whose
+// values are all below limit.
+func checkFDIndex(t *testing.T, x *fdIndex, want map[int32]bool, limit int32) {
+	t.Helper()
+	if x.count != len(want) {
+		t.Fatalf("count = %d, want %d", x.count, len(want))
+	}
+	wantLast := int32(-1)
+	for fd := int32(0); fd < limit; fd++ {
+		if want[fd] {
+			wantLast = fd
+		}
+	}
+	if got := x.last(); got != wantLast {
+		t.Fatalf("last() = %d, want %d", got, wantLast)
+	}
+	for start := int32(0); start < limit; start++ {
+		wantUsed, wantUnused := int32(-1), int32(-1)
+		for fd := start; fd < limit && (wantUsed < 0 || wantUnused < 0); fd++ {
+			if want[fd] && wantUsed < 0 {
+				wantUsed = fd
+			}
+			if !want[fd] && wantUnused < 0 {
+				wantUnused = fd
+			}
+		}
+		if got := x.firstUsed(start); got != wantUsed {
+			t.Fatalf("firstUsed(%d) = %d, want %d", start, got, wantUsed)
+		}
+		if got := x.firstUnused(start); wantUnused >= 0 && got != wantUnused {
+			t.Fatalf("firstUnused(%d) = %d, want %d", start, got, wantUnused)
+		}
+	}
+}
+
+func TestFDIndexDense(t *testing.T) {
+	var x fdIndex
+	want := make(map[int32]bool)
+	const n = 3 * 4096
+	for fd := int32(0); fd < n; fd++ {
+		x.add(fd)
+		want[fd] = true
+	}
+	if got := x.firstUnused(0); got != n {
+		t.Errorf("firstUnused(0) = %d, want %d", got, n)
+	}
+	for _, fd := range []int32{4095, 64, 8000} {
+		x.remove(fd)
+		delete(want, fd)
+	}
+	checkFDIndex(t, &x, want, n+64)
+}
+
+func TestFDIndexRandom(t *testing.T) {
+	var x fdIndex
+	want := make(map[int32]bool)
+	const limit = 20000
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		// Cluster fds so that some words fill up completely.
+		fd := int32(r.Intn(limit))
+		if r.Intn(4) == 0 {
+			fd = int32(r.Intn(256))
+		}
+		if r.Intn(3) == 0 {
+			x.remove(fd)
+			delete(want, fd)
+		} else {
+			x.add(fd)
+			want[fd] = true
+		}
+	}
+	checkFDIndex(t, &x, want, limit)
+}
+
+func TestFDIndexForEach(t *testing.T) {
+	var x fdIndex
+	for _, fd := range []int32{1, 63, 64, 5000, 100000} {
+		x.add(fd)
+	}
+	var got []int32
+	x.forEach(2, 100000, func(fd int32) bool {
+		got = append(got, fd)
+		return true
+	})
+	if want := []int32{63, 64, 5000}; !slices.Equal(got, want) {
+		t.Errorf("forEach(2, 100000) visited %v, want %v", got, want)
+	}
+	var empty fdIndex
+	if got := empty.firstUsed(0); got != -1 {
+		t.Errorf("empty firstUsed(0) = %d, want -1", got)
+	}
+	if got := empty.firstUnused(7); got != 7 {
+		t.Errorf("empty firstUnused(7) = %d, want 7", got)
+	}
+	if got := empty.last(); got != -1 {
+		t.Errorf("empty last() = %d, want -1", got)
+	}
+}
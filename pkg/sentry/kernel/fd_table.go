@@ -26,6 +26,7 @@ import (
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/lock"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 )
@@ -71,6 +72,11 @@ type descriptor struct {
 // MaxFdLimit defines the upper limit on the integer value of file descriptors.
 const MaxFdLimit int32 = int32(bitmap.MaxBitEntryLimit)
 
+// DefaultMaxFiles is the default value of /proc/sys/fs/file-max. Linux scales
+// it with the amount of memory, and distributions commonly raise it to the
+// maximum; we don't impose a limit by default.
+const DefaultMaxFiles = math.MaxInt64
+
 // FDTable is used to manage File references and flags.
 //
 // +stateify savable
@@ -82,8 +88,8 @@ type FDTable struct {
 	// mu protects below.
 	mu fdTableMutex `state:"nosave"`
 
-	// fdBitmap shows which fds are already in use.
-	fdBitmap bitmap.Bitmap `state:"nosave"`
+	// fds tracks which fds are already in use.
+	fds fdIndex `state:"nosave"`
 
 	// descriptorTable holds descriptors.
 	descriptorTable `state:".(map[int32]descriptor)"`
@@ -106,7 +112,6 @@ func (f *FDTable) saveDescriptorTable() map[int32]descriptor {
 func (f *FDTable) loadDescriptorTable(_ goContext.Context, m map[int32]descriptor) {
 	ctx := context.Background()
 	f.initNoLeakCheck() // Initialize table.
-	f.fdBitmap = bitmap.New(uint32(math.MaxUint16))
 	for fd, d := range m {
 		if fd < 0 {
 			panic(fmt.Sprintf("FD is not supposed to be negative. FD: %d", fd))
@@ -115,7 +120,7 @@ func (f *FDTable) loadDescriptorTable(_ goContext.Context, m map[int32]descripto
 		if df := f.set(fd, d.file, d.flags); df != nil {
 			panic("file set")
 		}
-		f.fdBitmap.Add(uint32(fd))
+		f.fds.add(fd)
 		// Note that we do _not_ need to acquire a extra table reference here. The
 		// table reference will already be accounted for in the file, so we drop the
 		// reference taken by set above.
@@ -157,9 +162,16 @@ func (f *FDTable) DecRef(ctx context.Context) {
 //
 // It is the caller's responsibility to acquire an appropriate lock.
 func (f *FDTable) forEachUpTo(ctx context.Context, maxFd int32, fn func(fd int32, file *vfs.FileDescription, flags FDFlags) bool) {
-	// Iterate through the fdBitmap.
-	f.fdBitmap.ForEach(0, uint32(maxFd), func(ufd uint32) bool {
-		fd := int32(ufd)
+	f.forEachInRange(ctx, 0, maxFd, fn)
+}
+
+// forEachInRange iterates over all non-nil files in [startFd, endFd) in sorted
+// order. Its cost is proportional to the number of files in the range, not to
+// the size of the range.
+//
+// It is the caller's responsibility to acquire an appropriate lock.
+func (f *FDTable) forEachInRange(ctx context.Context, startFd, endFd int32, fn func(fd int32, file *vfs.FileDescription, flags FDFlags) bool) {
+	f.fds.forEach(startFd, endFd, func(fd int32) bool {
 		file, flags, ok := f.get(fd)
 		if !ok || file == nil {
 			return true
@@ -240,45 +252,31 @@ func (f *FDTable) NewFDs(ctx context.Context, minFD int32, files []*vfs.FileDesc
 	if minFD+int32(len(files)) > end {
 		return nil, unix.EMFILE
 	}
+	if err := f.checkMaxFiles(ctx); err != nil {
+		return nil, err
+	}
 
 	f.mu.Lock()
 
-	// max is used as the largest number in fdBitmap + 1.
-	max := int32(0)
-	if !f.fdBitmap.IsEmpty() {
-		max = int32(f.fdBitmap.Maximum())
-		max++
-	}
-
-	// Adjust max in case it is less than minFD.
-	if max < minFD {
-		max = minFD
-	}
 	// Install all entries.
 	for len(fds) < len(files) {
-		// Try to use free bit in fdBitmap.
-		// If all bits in fdBitmap are used, expand fd to the max.
-		fd, err := f.fdBitmap.FirstZero(uint32(minFD))
-		if err != nil {
-			fd = uint32(max)
-			max++
-		}
-		if fd >= uint32(end) {
+		fd := f.fds.firstUnused(minFD)
+		if fd >= end {
 			break
 		}
-		f.fdBitmap.Add(fd)
-		if df := f.set(int32(fd), files[len(fds)], flags); df != nil {
+		f.fds.add(fd)
+		if df := f.set(fd, files[len(fds)], flags); df != nil {
 			panic("file set")
 		}
-		fds = append(fds, int32(fd))
-		minFD = int32(fd)
+		fds = append(fds, fd)
+		minFD = fd
 	}
 
 	// Failure? Unwind existing FDs.
 	if len(fds) < len(files) {
 		for _, i := range fds {
 			_ = f.set(i, nil, FDFlags{})
-			f.fdBitmap.Remove(uint32(i))
+			f.fds.remove(i)
 		}
 		f.mu.Unlock()
 
@@ -296,6 +294,21 @@ func (f *FDTable) NewFDs(ctx context.Context, minFD int32, files []*vfs.FileDesc
 	return fds, nil
 }
 
+// checkMaxFiles returns ENFILE if the number of open files in the sandbox
+// exceeds /proc/sys/fs/file-max, unless the caller has CAP_SYS_ADMIN. This is
+// analogous to the check in Linux's fs/file_table.c:alloc_empty_file(), but is
+// done when files are installed in the table since vfs.FileDescription.Init()
+// doesn't have a context.
+func (f *FDTable) checkMaxFiles(ctx context.Context) error {
+	if f.k.VFS().OpenFiles() <= f.k.MaxFiles.Load() {
+		return nil
+	}
+	if creds := auth.CredentialsFromContext(ctx); creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, f.k.RootUserNamespace()) {
+		return nil
+	}
+	return unix.ENFILE
+}
+
 // NewFD allocates a file descriptor greater than or equal to minFD for
 // the given file description. If it succeeds, it takes a reference on file.
 func (f *FDTable) NewFD(ctx context.Context, minFD int32, file *vfs.FileDescription, flags FDFlags) (int32, error) {
@@ -332,10 +345,7 @@ func (f *FDTable) NewFDAt(ctx context.Context, fd int32, file *vfs.FileDescripti
 	// Install the entry.
 	f.mu.Lock()
 	df := f.set(fd, file, flags)
-	// Add fd to fdBitmap.
-	if df == nil {
-		f.fdBitmap.Add(uint32(fd))
-	}
+	f.fds.add(fd)
 	f.mu.Unlock()
 
 	if df != nil {
@@ -380,10 +390,13 @@ func (f *FDTable) SetFlagsForRange(ctx context.Context, startFd int32, endFd int
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	for fd, err := f.fdBitmap.FirstOne(uint32(startFd)); err == nil && fd <= uint32(endFd); fd, err = f.fdBitmap.FirstOne(fd + 1) {
-		fdI32 := int32(fd)
-		file, _, _ := f.get(fdI32)
-		if df := f.set(fdI32, file, flags); df != nil {
+	// N.B. fd+1 can't overflow since MaxFdLimit is exclusive.
+	for fd := f.fds.firstUsed(startFd); fd >= 0 && fd <= endFd; fd = f.fds.firstUsed(fd + 1) {
+		file, oldFlags, _ := f.get(fd)
+		if oldFlags == flags {
+			continue
+		}
+		if df := f.set(fd, file, flags); df != nil {
 			panic("file changed")
 		}
 	}
@@ -423,7 +436,7 @@ func (f *FDTable) Get(fd int32) (*vfs.FileDescription, FDFlags) {
 func (f *FDTable) GetFDs(ctx context.Context) []int32 {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	fds := make([]int32, 0, int(f.fdBitmap.GetNumOnes()))
+	fds := make([]int32, 0, f.fds.count)
 	f.ForEach(ctx, func(fd int32, _ *vfs.FileDescription, _ FDFlags) bool {
 		fds = append(fds, fd)
 		return true
@@ -431,6 +444,25 @@ func (f *FDTable) GetFDs(ctx context.Context) []int32 {
 	return fds
 }
 
+// GetFDsFrom returns a sorted list of at most limit valid fds greater than or
+// equal to startFd.
+//
+// Precondition: The caller must be running on the task goroutine, or Task.mu
+// must be locked.
+func (f *FDTable) GetFDsFrom(ctx context.Context, startFd int32, limit int) []int32 {
+	if startFd < 0 {
+		startFd = 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fds := make([]int32, 0, min(limit, f.fds.count))
+	f.forEachInRange(ctx, startFd, MaxFdLimit, func(fd int32, _ *vfs.FileDescription, _ FDFlags) bool {
+		fds = append(fds, fd)
+		return len(fds) < limit
+	})
+	return fds
+}
+
 // Exists returns whether fd is defined in the table. It is inherently racy.
 //
 //go:nosplit
@@ -453,7 +485,7 @@ func (f *FDTable) Fork(ctx context.Context, maxFd int32) *FDTable {
 		if df := clone.set(fd, file, flags); df != nil {
 			panic("file set")
 		}
-		clone.fdBitmap.Add(uint32(fd))
+		clone.fds.add(fd)
 		return true
 	})
 	return clone
@@ -469,9 +501,7 @@ func (f *FDTable) Remove(ctx context.Context, fd int32) *vfs.FileDescription {
 
 	f.mu.Lock()
 	df := f.set(fd, nil, FDFlags{}) // Zap entry.
-	if df != nil {
-		f.fdBitmap.Remove(uint32(fd))
-	}
+	f.fds.remove(fd)
 	f.mu.Unlock()
 
 	if df != nil {
@@ -490,7 +520,7 @@ func (f *FDTable) RemoveIf(ctx context.Context, cond func(*vfs.FileDescription,
 		if cond(file, flags) {
 			// Clear from table.
 			if df := f.set(fd, nil, FDFlags{}); df != nil {
-				f.fdBitmap.Remove(uint32(fd))
+				f.fds.remove(fd)
 				files = append(files, df)
 			}
 		}
@@ -514,16 +544,13 @@ func (f *FDTable) RemoveNextInRange(ctx context.Context, startFd int32, endFd in
 	}
 
 	f.mu.Lock()
-	fdUint, err := f.fdBitmap.FirstOne(uint32(startFd))
-	fd := int32(fdUint)
-	if err != nil || fd > endFd {
+	fd := f.fds.firstUsed(startFd)
+	if fd < 0 || fd > endFd {
 		f.mu.Unlock()
 		return MaxFdLimit, nil
 	}
 	df := f.set(fd, nil, FDFlags{}) // Zap entry.
-	if df != nil {
-		f.fdBitmap.Remove(uint32(fd))
-	}
+	f.fds.remove(fd)
 	f.mu.Unlock()
 
 	if df != nil {
@@ -538,9 +565,8 @@ func (f *FDTable) GetLastFd() int32 {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	last := f.fdBitmap.Maximum()
-	if last > bitmap.MaxBitEntryLimit {
-		return MaxFdLimit
+	if last := f.fds.last(); last >= 0 {
+		return last
 	}
-	return int32(last)
+	return 0
 }
@@ -71,6 +71,7 @@ func runTest(t testing.TB, fn func(ctx context.Context, fdTable *FDTable, fd *vf
 	fdTable := new(FDTable)
 	fdTable.k = &Kernel{}
 	fdTable.k.MaxFDLimit.Store(MaxFdLimit)
+	fdTable.k.MaxFiles.Store(DefaultMaxFiles)
 	fdTable.init()
 
 	// Run the test.
@@ -136,6 +137,32 @@ func TestFDTableOverLimit(t *testing.T) {
 	})
 }
 
+// TestFDTableRaisedLimit checks that raising RLIMIT_NOFILE takes effect on an
+// existing table.
+func TestFDTableRaisedLimit(t *testing.T) {
+	runTest(t, func(ctx context.Context, fdTable *FDTable, fd *vfs.FileDescription, limitSet *limits.LimitSet) {
+		if _, err := fdTable.NewFDs(ctx, maxFD, []*vfs.FileDescription{fd}, FDFlags{}); err == nil {
+			t.Fatalf("fdTable.NewFDs(maxFD, f): got nil, wanted error")
+		}
+
+		if _, err := limitSet.Set(limits.NumberOfFiles, limits.Limit{Cur: 2 * maxFD, Max: 2 * maxFD}, true /* privileged */); err != nil {
+			t.Fatalf("limitSet.Set(NumberOfFiles, 2*maxFD): got %v, wanted nil", err)
+		}
+
+		if fds, err := fdTable.NewFDs(ctx, maxFD, []*vfs.FileDescription{fd}, FDFlags{}); err != nil || fds[0] != maxFD {
+			t.Fatalf("fdTable.NewFDs(maxFD, f) after raising the limit: got %v, %v, wanted [%d], nil", fds, err, maxFD)
+		}
+		if df, err := fdTable.NewFDAt(ctx, 2*maxFD-1, fd, FDFlags{}); err != nil {
+			t.Fatalf("fdTable.NewFDAt(2*maxFD-1, f) after raising the limit: got %v, wanted nil", err)
+		} else if df != nil {
+			t.Fatalf("fdTable.NewFDAt(2*maxFD-1, f) displaced FD")
+		}
+		if _, err := fdTable.NewFDAt(ctx, 2*maxFD, fd, FDFlags{}); err == nil {
+			t.Fatalf("fdTable.NewFDAt(2*maxFD, f): got nil, wanted error")
+		}
+	})
+}
+
 // TestFDTable does a set of simple tests to make sure simple adds, removes,
 // GetRefs, and DecRefs work. The ordering is just weird enough that a
 // table-driven approach seemed clumsy.
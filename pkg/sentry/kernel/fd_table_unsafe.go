@@ -15,9 +15,6 @@
 package kernel
 
 import (
-	"math"
-
-	"gvisor.dev/gvisor/pkg/bitmap"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 )
 
@@ -46,7 +43,6 @@ func (f *FDTable) initNoLeakCheck() {
 func (f *FDTable) init() {
 	f.initNoLeakCheck()
 	f.InitRefs()
-	f.fdBitmap = bitmap.New(uint32(math.MaxUint16))
 }
 
 const (
@@ -220,7 +220,8 @@ type Waiter struct {
 	// waiter is not waiting and is not in any bucket.
 	bucket AtomicPtrBucket
 
-	// C is sent to when the Waiter is woken.
+	// C is sent to when the Waiter is woken. C may be shared by the Waiters
+	// of a MultiWaiter.
 	C chan struct{}
 
 	// key is what this waiter is waiting on.
@@ -279,9 +280,14 @@ func (b *bucket) wakeLocked(key *Key, bitmask uint32, n int) int {
 }
 
 func (b *bucket) wakeWaiterLocked(w *Waiter) {
-	// Remove from the bucket and wake the waiter.
+	// Remove from the bucket and wake the waiter. If w.C is shared with other
+	// Waiters in a MultiWaiter, it may already be full; a single pending
+	// wakeup is enough.
 	b.waiters.Remove(w)
-	w.C <- struct{}{}
+	select {
+	case w.C <- struct{}{}:
+	default:
+	}
 
 	// NOTE: The above channel write establishes a write barrier according
 	// to the memory model, so nothing may be ordered around it. Since
@@ -604,7 +610,15 @@ func (m *Manager) WaitPrepare(w *Waiter, t Target, addr hostarch.Addr, private b
 // WaitComplete must be called when a Waiter previously added by WaitPrepare is
 // no longer eligible to be woken.
 func (m *Manager) WaitComplete(w *Waiter, t Target) {
-	// Remove w from the bucket it's in.
+	w.dequeue()
+
+	// Release references held by the waiter.
+	w.key.release(t)
+}
+
+// dequeue removes w from the bucket it's in. It returns false if w was not in
+// any bucket, i.e. it has been woken.
+func (w *Waiter) dequeue() bool {
 	for {
 		b := w.bucket.Load()
 
@@ -612,7 +626,7 @@ func (m *Manager) WaitComplete(w *Waiter, t Target) {
 		// racy because the waiter can't be concurrently re-queued in another
 		// bucket.
 		if b == nil {
-			break
+			return false
 		}
 
 		// Take the bucket lock. Note that without holding the bucket lock, the
@@ -631,11 +645,105 @@ func (m *Manager) WaitComplete(w *Waiter, t Target) {
 		b.waiters.Remove(w)
 		w.bucket.Store(nil)
 		b.mu.Unlock()
-		break
+		return true
 	}
+}
 
-	// Release references held by the waiter.
-	w.key.release(t)
+// MultiWaiter waits on multiple futexes at once, as for futex_waitv(2).
+type MultiWaiter struct {
+	// C is sent to when any of the futexes is woken.
+	C chan struct{}
+
+	// waiters contains one Waiter per futex. All of them share C.
+	waiters []Waiter
+
+	// queued is the number of Waiters in waiters that were enqueued by
+	// WaitMultiplePrepare.
+	queued int
+}
+
+// NewMultiWaiter returns a new MultiWaiter that can wait on up to n futexes.
+func NewMultiWaiter(n int) *MultiWaiter {
+	mw := &MultiWaiter{
+		C:       make(chan struct{}, 1),
+		waiters: make([]Waiter, n),
+	}
+	for i := range mw.waiters {
+		mw.waiters[i].C = mw.C
+	}
+	return mw
+}
+
+// WaitvFutex describes one of the futexes waited on by WaitMultiplePrepare.
+type WaitvFutex struct {
+	Addr    hostarch.Addr
+	Private bool
+	Val     uint32
+}
+
+// WaitMultiplePrepare checks that each futex in fs contains the expected
+// value, and enqueues a Waiter in mw on it to be woken by a send to mw.C. The
+// checks are atomic with respect to wakeups on each futex, but not across
+// futexes, as in Linux's kernel/futex/waitwake.c:futex_wait_multiple_setup().
+//
+// If a futex does not contain its expected value, WaitMultiplePrepare
+// dequeues all Waiters; if any of them were woken in the meantime, it returns
+// the index of the first woken futex, and otherwise it returns EAGAIN. If
+// WaitMultiplePrepare returns -1 and a nil error, mw must be subsequently
+// dequeued by calling WaitMultipleComplete.
+//
+// Preconditions: len(fs) <= the n passed to NewMultiWaiter.
+func (m *Manager) WaitMultiplePrepare(mw *MultiWaiter, t Target, fs []WaitvFutex) (int, error) {
+	select {
+	case <-mw.C:
+	default:
+	}
+	mw.queued = 0
+	for i, f := range fs {
+		k, err := getKey(t, f.Addr, f.Private)
+		if err != nil {
+			return m.abortWaitMultiple(mw, t, err)
+		}
+		w := &mw.waiters[i]
+		w.key = k
+		w.bitmask = linux.FUTEX_BITSET_MATCH_ANY
+
+		b := m.lockBucket(&k)
+		if err := check(t, f.Addr, f.Val); err != nil {
+			b.mu.Unlock()
+			w.key.release(t)
+			return m.abortWaitMultiple(mw, t, err)
+		}
+		b.waiters.PushBack(w)
+		w.bucket.Store(b)
+		b.mu.Unlock()
+		mw.queued++
+	}
+	return -1, nil
+}
+
+// abortWaitMultiple dequeues mw after WaitMultiplePrepare fails with err.
+func (m *Manager) abortWaitMultiple(mw *MultiWaiter, t Target, err error) (int, error) {
+	if i := m.WaitMultipleComplete(mw, t); i >= 0 {
+		return i, nil
+	}
+	return -1, err
+}
+
+// WaitMultipleComplete must be called when the Waiters previously enqueued by
+// WaitMultiplePrepare are no longer eligible to be woken. It returns the index
+// of the first woken futex, or -1 if none were woken.
+func (m *Manager) WaitMultipleComplete(mw *MultiWaiter, t Target) int {
+	woken := -1
+	for i := range mw.waiters[:mw.queued] {
+		w := &mw.waiters[i]
+		if !w.dequeue() && woken < 0 {
+			woken = i
+		}
+		w.key.release(t)
+	}
+	mw.queued = 0
+	return woken
 }
 
 // LockPI attempts to lock the futex following the Priority-inheritance futex
@@ -643,9 +751,8 @@ func (m *Manager) WaitComplete(w *Waiter, t Target) {
 // calling task is set to 'addr' to indicate the futex is owned. It returns true
 // if the futex was successfully acquired.
 //
-// FUTEX_OWNER_DIED is only set by the Linux when robust lists are in use (see
-// exit_robust_list()). Given we don't support robust lists, although handled
-// below, it's never set.
+// FUTEX_OWNER_DIED is only set when robust lists are in use (see
+// exit_robust_list() and UnlockPIOwnerDied()). The new owner preserves it.
 func (m *Manager) LockPI(w *Waiter, t Target, addr hostarch.Addr, tid uint32, private, try bool) (bool, error) {
 	k, err := getKey(t, addr, private)
 	if err != nil {
@@ -746,14 +853,41 @@ func (m *Manager) UnlockPI(t Target, addr hostarch.Addr, tid uint32, private boo
 	}
 	b := m.lockBucket(&k)
 
-	err = m.unlockPILocked(t, addr, tid, b, &k)
+	err = m.unlockPILocked(t, addr, tid, b, &k, false /* ownerDied */)
 
 	k.release(t)
 	b.mu.Unlock()
 	return err
 }
 
-func (m *Manager) unlockPILocked(t Target, addr hostarch.Addr, tid uint32, b *bucket, key *Key) error {
+// UnlockPIOwnerDied releases the PI futex at addr, which is owned by the
+// exiting task with the given TID, and sets FUTEX_OWNER_DIED so that the next
+// owner can detect that the protected state may be inconsistent. The futex is
+// handed to the first waiter, if any. This is analogous to Linux's
+// kernel/futex/core.c:exit_pi_state_list().
+//
+// Like Linux's robust list handling, UnlockPIOwnerDied uses a shared key.
+func (m *Manager) UnlockPIOwnerDied(t Target, addr hostarch.Addr, tid uint32) error {
+	k, err := getKey(t, addr, false /* private */)
+	if err != nil {
+		return err
+	}
+	b := m.lockBucket(&k)
+
+	for {
+		err = m.unlockPILocked(t, addr, tid, b, &k, true /* ownerDied */)
+		// Unlike user mode, the exiting task can't retry after a CAS race.
+		if !linuxerr.Equals(linuxerr.EAGAIN, err) && !linuxerr.Equals(linuxerr.EINVAL, err) {
+			break
+		}
+	}
+
+	k.release(t)
+	b.mu.Unlock()
+	return err
+}
+
+func (m *Manager) unlockPILocked(t Target, addr hostarch.Addr, tid uint32, b *bucket, key *Key, ownerDied bool) error {
 	cur, err := t.LoadUint32(addr)
 	if err != nil {
 		return err
@@ -780,8 +914,13 @@ func (m *Manager) unlockPILocked(t Target, addr hostarch.Addr, tid uint32, b *bu
 
 	if next == nil {
 		// It's safe to set 0 because there are no waiters, no new owner, and the
-		// executing task is the current owner (no owner died bit).
-		prev, err := t.CompareAndSwapUint32(addr, cur, 0)
+		// executing task is the current owner (no owner died bit). If the
+		// executing task is dying, mark the futex accordingly.
+		var val uint32
+		if ownerDied {
+			val = linux.FUTEX_OWNER_DIED
+		}
+		prev, err := t.CompareAndSwapUint32(addr, cur, val)
 		if err != nil {
 			return err
 		}
@@ -794,11 +933,15 @@ func (m *Manager) unlockPILocked(t Target, addr hostarch.Addr, tid uint32, b *bu
 	}
 
 	// Set next owner's TID, waiters if there are any. Resets owner died bit, if
-	// set, because the executing task takes over as the owner.
+	// set, because the executing task takes over as the owner, unless the
+	// executing task is dying.
 	val := next.tid
 	if next2 != nil {
 		val |= linux.FUTEX_WAITERS
 	}
+	if ownerDied {
+		val |= linux.FUTEX_OWNER_DIED
+	}
 
 	prev, err := t.CompareAndSwapUint32(addr, cur, val)
 	if err != nil {
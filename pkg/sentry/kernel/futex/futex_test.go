@@ -20,6 +20,7 @@ import (
 	"testing"
 	"unsafe"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
@@ -452,6 +453,97 @@ func TestWakeOpSameAddressFailingOp(t *testing.T) {
 	}
 }
 
+func TestWaitMultiple(t *testing.T) {
+	for _, private := range []bool{false, true} {
+		t.Run(futexKind(private), func(t *testing.T) {
+			m := NewManager()
+			d := newTestData(3 * sizeofInt32)
+
+			fs := []WaitvFutex{
+				{Addr: 0, Private: private},
+				{Addr: sizeofInt32, Private: private},
+				{Addr: 2 * sizeofInt32, Private: private},
+			}
+			mw := NewMultiWaiter(len(fs))
+			if i, err := m.WaitMultiplePrepare(mw, d, fs); err != nil || i != -1 {
+				t.Fatalf("WaitMultiplePrepare: got (%d, %v), wanted (-1, nil)", i, err)
+			}
+
+			// Wake the second futex.
+			if n, err := m.Wake(d, sizeofInt32, private, ^uint32(0), 1); err != nil || n != 1 {
+				t.Errorf("Wake: got (%d, %v), wanted (1, nil)", n, err)
+			}
+			select {
+			case <-mw.C:
+			default:
+				t.Error("MultiWaiter not notified")
+			}
+			if i := m.WaitMultipleComplete(mw, d); i != 1 {
+				t.Errorf("WaitMultipleComplete: got %d, wanted 1", i)
+			}
+
+			// All other waiters must have been dequeued.
+			if n, err := m.Wake(d, 0, private, ^uint32(0), 1); err != nil || n != 0 {
+				t.Errorf("Wake after WaitMultipleComplete: got (%d, %v), wanted (0, nil)", n, err)
+			}
+		})
+	}
+}
+
+func TestWaitMultipleMismatch(t *testing.T) {
+	m := NewManager()
+	d := newTestData(2 * sizeofInt32)
+
+	// The second futex does not contain the expected value.
+	fs := []WaitvFutex{
+		{Addr: 0, Private: true},
+		{Addr: sizeofInt32, Private: true, Val: 1},
+	}
+	mw := NewMultiWaiter(len(fs))
+	if i, err := m.WaitMultiplePrepare(mw, d, fs); !linuxerr.Equals(linuxerr.EAGAIN, err) || i != -1 {
+		t.Fatalf("WaitMultiplePrepare: got (%d, %v), wanted (-1, EAGAIN)", i, err)
+	}
+
+	// The first waiter must have been dequeued.
+	if n, err := m.Wake(d, 0, true, ^uint32(0), 1); err != nil || n != 0 {
+		t.Errorf("Wake: got (%d, %v), wanted (0, nil)", n, err)
+	}
+}
+
+func TestUnlockPIOwnerDied(t *testing.T) {
+	const (
+		ownerTID  = 1
+		waiterTID = 2
+	)
+	m := NewManager()
+	d := newTestData(sizeofInt32)
+	(*atomicbitops.Uint32)(unsafe.Pointer(&d.data[0])).Store(ownerTID)
+
+	w := NewWaiter()
+	if locked, err := m.LockPI(w, d, 0, waiterTID, false, false); err != nil || locked {
+		t.Fatalf("LockPI: got (%t, %v), wanted (false, nil)", locked, err)
+	}
+	defer m.WaitComplete(w, d)
+
+	if err := m.UnlockPIOwnerDied(d, 0, ownerTID); err != nil {
+		t.Fatalf("UnlockPIOwnerDied failed: %v", err)
+	}
+	if !w.woken() {
+		t.Error("waiter not woken")
+	}
+	if got, want := (*atomicbitops.Uint32)(unsafe.Pointer(&d.data[0])).Load(), uint32(waiterTID|linux.FUTEX_OWNER_DIED); got != want {
+		t.Errorf("futex word: got %#x, wanted %#x", got, want)
+	}
+
+	// Without waiters, the futex is left unowned with FUTEX_OWNER_DIED set.
+	if err := m.UnlockPIOwnerDied(d, 0, waiterTID); err != nil {
+		t.Fatalf("UnlockPIOwnerDied failed: %v", err)
+	}
+	if got, want := (*atomicbitops.Uint32)(unsafe.Pointer(&d.data[0])).Load(), uint32(linux.FUTEX_OWNER_DIED); got != want {
+		t.Errorf("futex word: got %#x, wanted %#x", got, want)
+	}
+}
+
 const (
 	testMutexSize            = sizeofInt32
 	testMutexLocked   uint32 = 1
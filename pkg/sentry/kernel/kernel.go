@@ -49,6 +49,7 @@ import (
 	"gvisor.dev/gvisor/pkg/fd"
 	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/rand"
 	"gvisor.dev/gvisor/pkg/refs"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/nsfs"
@@ -73,6 +74,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/unimpl"
 	uspb "gvisor.dev/gvisor/pkg/sentry/unimpl/unimplemented_syscall_go_proto"
 	"gvisor.dev/gvisor/pkg/sentry/uniqueid"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/state"
 	"gvisor.dev/gvisor/pkg/state/statefile"
@@ -84,6 +86,11 @@ import (
 // allow easy access everywhere.
 var IOUringEnabled = false
 
+// HostMemfdEnabled is set to true when memfd_create(2) should create memfds on
+// the host, so that they can be shared with host processes. Added as a global
+// to allow easy access everywhere.
+var HostMemfdEnabled = false
+
 // UserCounters is a set of user counters.
 //
 // +stateify savable
@@ -157,6 +164,8 @@ type Kernel struct {
 	rootUserNamespace    *auth.UserNamespace
 	rootNetworkNamespace *inet.Namespace
 	applicationCores     uint
+	cpuTopology          cpuid.Topology // zero if not configured
+	swapSize             uint64
 	useHostCores         bool
 	extraAuxv            []arch.AuxEntry
 	vdso                 *loader.VDSO
@@ -180,6 +189,16 @@ type Kernel struct {
 	// syslog is the kernel log.
 	syslog syslog
 
+	// binfmtMisc is the set of interpreters registered with binfmt_misc.
+	binfmtMisc loader.BinfmtMisc
+
+	// corePattern is the template used to name core dump files, as in
+	// /proc/sys/kernel/core_pattern. corePatternContainerID is the ID of the
+	// container that set it. They are protected by corePatternMu.
+	corePattern            string
+	corePatternContainerID string
+	corePatternMu          sync.Mutex `state:"nosave"`
+
 	runningTasksMu runningTasksMutex `state:"nosave"`
 
 	// runningTasks is the total count of tasks currently in
@@ -190,6 +209,18 @@ type Kernel struct {
 	// further protected by runningTasksMu (see incRunningTasks).
 	runningTasks atomicbitops.Int64
 
+	// uninterruptibleTasks is the total count of tasks currently in
+	// TaskGoroutineBlockedUninterruptible, which Linux reports as blocked on
+	// I/O.
+	//
+	// uninterruptibleTasks must be accessed atomically.
+	uninterruptibleTasks atomicbitops.Int64
+
+	// cpuTasks[i] is the number of running tasks that are assigned to
+	// virtual CPU i (see Task.acquireVirtualCPU). cpuTasks is nil if
+	// useHostCores is true.
+	cpuTasks []atomicbitops.Int32 `state:"nosave"`
+
 	// runningTasksCond is signaled when runningTasks is incremented from 0 to 1.
 	//
 	// Invariant: runningTasksCond.L == &runningTasksMu.
@@ -217,6 +248,14 @@ type Kernel struct {
 	// based on cpuClock, atomic.
 	cpuClockMu cpuClockMutex `state:"nosave"`
 
+	// cpuUsage is the sandbox-wide CPU time accounted by the CPU clock
+	// ticker. cpuUsage is protected by cpuClockMu.
+	cpuUsage cpuUsage
+
+	// prevCPUTime is the sandbox-wide CPU time last reported by
+	// SystemCPUStats.
+	prevCPUTime usage.PrevCPUTime
+
 	// cpuClockTickerRunning is true if the goroutine that increments cpuClock is
 	// running and false if it is blocked in runningTasksCond.Wait() or if it
 	// never started.
@@ -315,6 +354,11 @@ type Kernel struct {
 	// external wait so that the watchdog doesn't report the task stuck.
 	SleepForAddressSpaceActivation bool
 
+	// If set to true, fork(2) defers copying the parent's private memory
+	// mappings to the child until the child accesses them, which speeds up
+	// fork(2) followed by execve(2). See mm.MemoryManager.SetLazyFork.
+	LazyForkMemory bool
+
 	// Exceptions to YAMA ptrace restrictions. Each key-value pair represents a
 	// tracee-tracer relationship. The key is a process (technically, the thread
 	// group leader) that can be traced by any thread that is a descendant of the
@@ -327,6 +371,14 @@ type Kernel struct {
 	// YAMAPtraceScope is the current level of YAMA ptrace restrictions.
 	YAMAPtraceScope atomicbitops.Int32
 
+	// MemfdNoexecScope is the value of /proc/sys/vm/memfd_noexec, one of
+	// linux.MEMFD_NOEXEC_SCOPE_*.
+	MemfdNoexecScope atomicbitops.Int32
+
+	// DmesgRestrict is the value of /proc/sys/kernel/dmesg_restrict. If it is
+	// non-zero, reading the kernel log requires CAP_SYSLOG.
+	DmesgRestrict atomicbitops.Int32
+
 	// cgroupRegistry contains the set of active cgroup controllers on the
 	// system. It is controller by cgroupfs. Nil if cgroupfs is unavailable on
 	// the system.
@@ -347,6 +399,27 @@ type Kernel struct {
 	// used by processes.
 	MaxFDLimit atomicbitops.Int32
 
+	// MaxFiles is the value of /proc/sys/fs/file-max, the maximum number of
+	// open files in the sandbox for tasks without CAP_SYS_ADMIN.
+	MaxFiles atomicbitops.Int64
+
+	// deterministicRand, if not nil, replaces rand.Reader as the source of
+	// random bytes for tasks. See EnableDeterministicMode.
+	deterministicRand io.Reader `state:"nosave"`
+
+	// deterministicSched, if not nil, serializes the execution of application
+	// code. See EnableDeterministicMode.
+	deterministicSched *deterministicScheduler `state:"nosave"`
+
+	// faultInjector holds the rules used to inject faults into syscalls. Fault
+	// injection is a debugging facility, so rules aren't saved.
+	faultInjector faultInjector `state:"nosave"`
+
+	// MaxUserNamespaces is the value of /proc/sys/user/max_user_namespaces,
+	// the maximum number of user namespaces that can be in use in the
+	// sandbox, not counting the root user namespace.
+	MaxUserNamespaces atomicbitops.Int32
+
 	// devGofers maps containers (using its name) to its device gofer client.
 	devGofers   map[string]*devutil.GoferClient `state:"nosave"`
 	devGofersMu sync.Mutex                      `state:"nosave"`
@@ -358,6 +431,11 @@ type Kernel struct {
 	// It's protected by extMu.
 	containerNames map[string]string
 
+	// syscallPolicies maps container IDs to the syscall policy of the
+	// container, if any. It's protected by syscallPoliciesMu.
+	syscallPolicies   map[string]*SyscallPolicy
+	syscallPoliciesMu sync.Mutex `state:"nosave"`
+
 	// additionalCheckpointState stores additional state that needs
 	// to be checkpointed. It's protected by extMu.
 	additionalCheckpointState map[any]any
@@ -390,6 +468,20 @@ type InitKernelArgs struct {
 	// will be overridden.
 	UseHostCores bool
 
+	// CPUTopology is the topology of the logical CPUs visible to sandboxed
+	// applications. If CPUTopology is the zero value,
+	// cpuid.DefaultTopology(ApplicationCores) is used, and FeatureSet is not
+	// modified; otherwise, CPUTopology must have ApplicationCores CPUs, and
+	// the topology and caches described by FeatureSet are made consistent
+	// with it.
+	CPUTopology cpuid.Topology
+
+	// SwapSize is the size in bytes of the virtual swap device reported to
+	// applications, whose usage reflects application memory in the swap and
+	// compressed cache tiers of the Kernel's MemoryFile. If SwapSize is 0, no
+	// swap device is reported.
+	SwapSize uint64
+
 	// ExtraAuxv contains additional auxiliary vector entries that are added to
 	// each process by the ELF loader.
 	ExtraAuxv []arch.AuxEntry
@@ -455,18 +547,32 @@ func (k *Kernel) Init(args InitKernelArgs) error {
 			log.Infof("UseHostCores enabled: increasing ApplicationCores from %d to %d", k.applicationCores, minAppCores)
 			k.applicationCores = minAppCores
 		}
+	} else {
+		k.cpuTasks = make([]atomicbitops.Int32, k.applicationCores)
+	}
+	if args.CPUTopology.NumCPUs() != 0 {
+		if err := args.CPUTopology.Validate(k.applicationCores); err != nil {
+			return fmt.Errorf("invalid CPU topology: %w", err)
+		}
+		k.cpuTopology = args.CPUTopology
+		k.featureSet = k.featureSet.WithTopology(k.cpuTopology)
 	}
+	k.swapSize = args.SwapSize
 	k.extraAuxv = args.ExtraAuxv
 	k.vdso = args.Vdso
 	k.futexes = futex.NewManager()
 	k.netlinkPorts = port.New()
 	k.ptraceExceptions = make(map[*Task]*Task)
 	k.YAMAPtraceScope = atomicbitops.FromInt32(linux.YAMA_SCOPE_RELATIONAL)
+	k.DmesgRestrict = atomicbitops.FromInt32(1)
+	k.corePattern = defaultCorePattern
 	k.userCountersMap = make(map[auth.KUID]*UserCounters)
 	if args.MaxFDLimit == 0 {
 		args.MaxFDLimit = MaxFdLimit
 	}
 	k.MaxFDLimit.Store(args.MaxFDLimit)
+	k.MaxFiles.Store(DefaultMaxFiles)
+	k.MaxUserNamespaces.Store(defaultMaxUserNamespaces)
 	k.containerNames = make(map[string]string)
 
 	ctx := k.SupervisorContext()
@@ -530,6 +636,8 @@ func (k *Kernel) Init(args InitKernelArgs) error {
 	k.sockets = make(map[*vfs.FileDescription]*SocketRecord)
 
 	k.cgroupRegistry = newCgroupRegistry()
+	k.syslog.init(func() int64 { return k.MonotonicClock().Now().Nanoseconds() })
+	metricsKernel.Store(k)
 	return nil
 }
 
@@ -585,10 +693,12 @@ func loadPrivateMFs(ctx context.Context, r io.Reader, pr *statefile.AsyncReader)
 	return nil
 }
 
-// SaveTo saves the state of k to w.
+// SaveTo saves the state of k to w. MemoryFile metadata and pages are saved to
+// pagesMetadata and pagesFile respectively if they are non-nil, and to w
+// otherwise.
 //
 // Preconditions: The kernel must be paused throughout the call to SaveTo.
-func (k *Kernel) SaveTo(ctx context.Context, w io.Writer, pagesMetadata, pagesFile *fd.FD, mfOpts pgalloc.SaveOpts) error {
+func (k *Kernel) SaveTo(ctx context.Context, w io.Writer, pagesMetadata, pagesFile io.Writer, mfOpts pgalloc.SaveOpts) error {
 	saveStart := time.Now()
 
 	// Do not allow other Kernel methods to affect it while it's being saved.
@@ -603,6 +713,12 @@ func (k *Kernel) SaveTo(ctx context.Context, w io.Writer, pagesMetadata, pagesFi
 	k.mf.StartEvictions()
 	k.mf.WaitForEvictions()
 
+	// Migrate memory in the swap tier back to the MemoryFile, since the swap
+	// tier isn't saved.
+	if err := k.swapIn(ctx); err != nil {
+		return fmt.Errorf("failed to swap in memory: %w", err)
+	}
+
 	// Discard unsavable mappings, such as those for host file descriptors.
 	if err := k.invalidateUnsavableMappings(ctx); err != nil {
 		return fmt.Errorf("failed to invalidate unsavable mappings: %v", err)
@@ -702,8 +818,10 @@ func (k *Kernel) invalidateUnsavableMappings(ctx context.Context) error {
 	return nil
 }
 
-// LoadFrom returns a new Kernel loaded from args.
-func (k *Kernel) LoadFrom(ctx context.Context, r io.Reader, pagesMetadata, pagesFile *fd.FD, timeReady chan struct{}, net inet.Stack, clocks sentrytime.Clocks, vfsOpts *vfs.CompleteRestoreOptions) error {
+// LoadFrom returns a new Kernel loaded from args. If pagesFile is non-nil,
+// MemoryFile pages are read from it asynchronously as configured by
+// asyncOpts.
+func (k *Kernel) LoadFrom(ctx context.Context, r io.Reader, pagesMetadata, pagesFile *fd.FD, asyncOpts statefile.AsyncOpts, timeReady chan struct{}, net inet.Stack, clocks sentrytime.Clocks, vfsOpts *vfs.CompleteRestoreOptions) error {
 	loadStart := time.Now()
 
 	var (
@@ -716,7 +834,7 @@ func (k *Kernel) LoadFrom(ctx context.Context, r io.Reader, pagesMetadata, pages
 		mfLoadWg.Add(1)
 		go func() {
 			defer mfLoadWg.Done()
-			mfLoadErr = k.loadMemoryFiles(ctx, r, pagesMetadata, pagesFile)
+			mfLoadErr = k.loadMemoryFiles(ctx, r, pagesMetadata, pagesFile, asyncOpts)
 		}()
 		// Defer a Wait() so we wait for k.loadMemoryFiles() to complete even if we
 		// error out without reaching the other Wait() below.
@@ -759,7 +877,7 @@ func (k *Kernel) LoadFrom(ctx context.Context, r io.Reader, pagesMetadata, pages
 	if parallelMfLoad {
 		mfLoadWg.Wait()
 	} else {
-		mfLoadErr = k.loadMemoryFiles(ctx, r, pagesMetadata, pagesFile)
+		mfLoadErr = k.loadMemoryFiles(ctx, r, pagesMetadata, pagesFile, asyncOpts)
 	}
 	if mfLoadErr != nil {
 		return mfLoadErr
@@ -786,6 +904,7 @@ func (k *Kernel) LoadFrom(ctx context.Context, r io.Reader, pagesMetadata, pages
 	tcpip.AsyncLoading.Wait()
 
 	log.Infof("Overall load took [%s] after async work", time.Since(loadStart))
+	metricsKernel.Store(k)
 
 	// Applications may size per-cpu structures based on k.applicationCores, so
 	// it can't change across save/restore. When we are virtualizing CPU
@@ -796,11 +915,14 @@ func (k *Kernel) LoadFrom(ctx context.Context, r io.Reader, pagesMetadata, pages
 	if k.useHostCores && initAppCores > k.applicationCores {
 		return fmt.Errorf("UseHostCores enabled: can't increase ApplicationCores from %d to %d after restore", k.applicationCores, initAppCores)
 	}
+	if !k.useHostCores {
+		k.cpuTasks = make([]atomicbitops.Int32, k.applicationCores)
+	}
 
 	return nil
 }
 
-func (k *Kernel) loadMemoryFiles(ctx context.Context, r io.Reader, pagesMetadata, pagesFile *fd.FD) error {
+func (k *Kernel) loadMemoryFiles(ctx context.Context, r io.Reader, pagesMetadata, pagesFile *fd.FD, asyncOpts statefile.AsyncOpts) error {
 	// Load the memory files' state.
 	memoryStart := time.Now()
 	pmr := r
@@ -809,7 +931,7 @@ func (k *Kernel) loadMemoryFiles(ctx context.Context, r io.Reader, pagesMetadata
 	}
 	var pr *statefile.AsyncReader
 	if pagesFile != nil {
-		pr = statefile.NewAsyncReader(pagesFile, 0 /* off */)
+		pr = statefile.NewAsyncReader(pagesFile, 0 /* off */, asyncOpts)
 		defer pr.Close()
 	}
 	if err := k.mf.LoadFrom(ctx, pmr, pr); err != nil {
@@ -1327,6 +1449,11 @@ func (k *Kernel) decRunningTasks() {
 		panic(fmt.Sprintf("Invalid running count %d", tasks))
 	}
 
+	if tasks == 0 && k.deterministicSched != nil {
+		// Tasks are idle until the next timer expires.
+		k.timekeeper.advanceIdle()
+	}
+
 	// Nothing to do. The next CPU clock tick will disable the timer if
 	// there is still nothing running. This provides approximately one tick
 	// of slack in which we can switch back and forth between idle and
@@ -1524,6 +1651,15 @@ func (k *Kernel) ApplicationCores() uint {
 	return k.applicationCores
 }
 
+// CPUTopology returns the topology of the CPUs visible to sandboxed
+// applications.
+func (k *Kernel) CPUTopology() cpuid.Topology {
+	if k.cpuTopology.NumCPUs() == 0 {
+		return cpuid.DefaultTopology(k.applicationCores)
+	}
+	return k.cpuTopology
+}
+
 // RealtimeClock returns the application CLOCK_REALTIME clock.
 func (k *Kernel) RealtimeClock() ktime.Clock {
 	return k.timekeeper.realtimeClock
@@ -1544,6 +1680,45 @@ func (k *Kernel) Syslog() *syslog {
 	return &k.syslog
 }
 
+// Printk logs text to the kernel log with the given priority, which encodes a
+// facility and level as facility<<3 | level.
+func (k *Kernel) Printk(priority uint32, text string) {
+	k.syslog.Log(k.MonotonicClock().Now().Nanoseconds(), priority, text)
+}
+
+// BinfmtMisc returns the set of interpreters registered with binfmt_misc.
+func (k *Kernel) BinfmtMisc() *loader.BinfmtMisc {
+	return &k.binfmtMisc
+}
+
+// CorePattern returns the template used to name core dump files.
+func (k *Kernel) CorePattern() string {
+	k.corePatternMu.Lock()
+	defer k.corePatternMu.Unlock()
+	return k.corePattern
+}
+
+// corePatternAndContainer returns the template used to name core dump files
+// and the ID of the container that set it.
+func (k *Kernel) corePatternAndContainer() (string, string) {
+	k.corePatternMu.Lock()
+	defer k.corePatternMu.Unlock()
+	return k.corePattern, k.corePatternContainerID
+}
+
+// SetCorePattern sets the template used to name core dump files. cid is the ID
+// of the container that sets it.
+func (k *Kernel) SetCorePattern(pattern, cid string) error {
+	if len(pattern) >= maxCorePatternLen {
+		return linuxerr.EINVAL
+	}
+	k.corePatternMu.Lock()
+	defer k.corePatternMu.Unlock()
+	k.corePattern = pattern
+	k.corePatternContainerID = cid
+	return nil
+}
+
 // GenerateInotifyCookie generates a unique inotify event cookie.
 //
 // Returned values may overlap with previously returned values if the value
@@ -1750,6 +1925,8 @@ func (ctx *supervisorContext) Value(key any) any {
 		return ctx.Kernel.RootNetworkNamespace().Stack()
 	case ktime.CtxRealtimeClock:
 		return ctx.Kernel.RealtimeClock()
+	case rand.CtxReader:
+		return ctx.Kernel.deterministicRand
 	case limits.CtxLimits:
 		// No limits apply.
 		return limits.NewLimitSet()
@@ -1787,6 +1964,9 @@ func (k *Kernel) EmitUnimplementedEvent(ctx context.Context, sysno uintptr) {
 
 	t := TaskFromContext(ctx)
 	IncrementUnimplementedSyscallCounter(sysno)
+	if k.syslog.warnUnimplementedSyscall(sysno) {
+		k.Printk(linux.LOG_KERN<<3|linux.LOGLEVEL_WARNING, fmt.Sprintf("%s[%d]: unsupported syscall %s (%d)", t.Name(), t.ThreadID(), t.SyscallTable().LookupName(sysno), sysno))
+	}
 	_, _ = k.unimplementedSyscallEmitter.Emit(&uspb.UnimplementedSyscall{
 		Tid:       int32(t.ThreadID()),
 		Registers: t.Arch().StateData().Proto(),
@@ -2073,6 +2253,22 @@ func (k *Kernel) RestoreContainerMapping(containerIDs map[string]string) {
 	k.extMu.Lock()
 	defer k.extMu.Unlock()
 
+	// The core pattern and syscall policies are keyed by container ID, so
+	// they are remapped too.
+	k.corePatternMu.Lock()
+	k.corePatternContainerID = containerIDs[k.containerNames[k.corePatternContainerID]]
+	k.corePatternMu.Unlock()
+
+	k.syscallPoliciesMu.Lock()
+	defer k.syscallPoliciesMu.Unlock()
+	policies := make(map[string]*SyscallPolicy)
+	for oldCID, p := range k.syscallPolicies {
+		if cid, ok := containerIDs[k.containerNames[oldCID]]; ok {
+			policies[cid] = p
+		}
+	}
+	k.syscallPolicies = policies
+
 	// Delete mapping from old session and replace with new values.
 	k.containerNames = make(map[string]string)
 	for name, cid := range containerIDs {
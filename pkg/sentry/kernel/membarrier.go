@@ -0,0 +1,105 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// MembarrierPrivateExpedited blocks until every other task that shares t's
+// MemoryManager and was executing application code has returned to the
+// sentry. This implies both a memory barrier and, since returning to
+// application code is core serializing on all platforms, core
+// serialization for those tasks; tasks that were not executing application
+// code will serialize when they next do so. It corresponds to the IPIs sent
+// by Linux's kernel/sched/membarrier.c:membarrier_private_expedited().
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) MembarrierPrivateExpedited() {
+	m := t.MemoryManager()
+	t.k.waitForRunningTasks(t, func(tm *mm.MemoryManager) bool {
+		return tm == m
+	})
+}
+
+// MembarrierGlobalExpedited is equivalent to MembarrierPrivateExpedited, but
+// waits for tasks whose MemoryManager has registered for
+// MEMBARRIER_CMD_GLOBAL_EXPEDITED instead.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) MembarrierGlobalExpedited() {
+	t.k.waitForRunningTasks(t, (*mm.MemoryManager).IsMembarrierGlobalEnabled)
+}
+
+// MembarrierGlobal is equivalent to MembarrierPrivateExpedited, but waits for
+// all tasks regardless of their MemoryManager.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) MembarrierGlobal() {
+	t.k.waitForRunningTasks(t, func(*mm.MemoryManager) bool {
+		return true
+	})
+}
+
+// waitForRunningTasks interrupts every task other than self that is executing
+// application code with a MemoryManager for which match returns true, and
+// blocks until all of them have left application code.
+//
+// Interrupting the platform context is an IPI-equivalent: on KVM it bounces
+// the vCPU executing the task, and on other platforms it signals the thread
+// executing the task.
+func (k *Kernel) waitForRunningTasks(self *Task, match func(*mm.MemoryManager) bool) {
+	type runningTask struct {
+		t     *Task
+		epoch sync.SeqCountEpoch
+	}
+	var running []runningTask
+
+	k.tasks.mu.RLock()
+	for t := range k.tasks.Root.tids {
+		if t == self {
+			continue
+		}
+		t.mu.Lock()
+		m := t.image.MemoryManager
+		t.mu.Unlock()
+		if m == nil || !match(m) {
+			continue
+		}
+		for {
+			epoch := t.goschedSeq.BeginRead()
+			info, ok := SeqAtomicTryLoadTaskGoroutineSchedInfo(&t.goschedSeq, epoch, &t.gosched)
+			if !ok {
+				continue
+			}
+			if info.State == TaskGoroutineRunningApp {
+				t.p.Interrupt()
+				running = append(running, runningTask{t, epoch})
+			}
+			break
+		}
+	}
+	k.tasks.mu.RUnlock()
+
+	// Any change to the task goroutine's state since epoch means that it has
+	// left application code. The interrupt causes the first following
+	// platform.Context.Switch to return, so this can't wait indefinitely.
+	for _, r := range running {
+		for r.t.goschedSeq.ReadOk(r.epoch) {
+			sync.Goyield()
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"sync/atomic"
+
+	"gvisor.dev/gvisor/pkg/metric"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+)
+
+// metricsKernel is the Kernel whose address spaces are reported by the
+// memory metrics below. It is set by Kernel.Init and Kernel.LoadFrom.
+var metricsKernel atomic.Pointer[Kernel]
+
+func init() {
+	metric.MustRegisterCustomUint64Metric("/memory/rss", false /* cumulative */, false /* sync */, "Sum of the resident set sizes of all application address spaces, in bytes.",
+		func(...*metric.FieldValue) uint64 {
+			rss, _ := metricsKernel.Load().residentSetSizes()
+			return rss
+		})
+	metric.MustRegisterCustomUint64Metric("/memory/max_rss", false /* cumulative */, false /* sync */, "Largest maximum resident set size of any live application address space, in bytes.",
+		func(...*metric.FieldValue) uint64 {
+			_, maxRSS := metricsKernel.Load().residentSetSizes()
+			return maxRSS
+		})
+}
+
+// residentSetSizes returns the sum of the current RSS of every distinct
+// MemoryManager in use by a task in k, and the largest maximum RSS among
+// them. k may be nil, in which case both values are 0.
+func (k *Kernel) residentSetSizes() (rss, maxRSS uint64) {
+	if k == nil {
+		return 0, 0
+	}
+	k.tasks.mu.RLock()
+	defer k.tasks.mu.RUnlock()
+	if k.tasks.Root == nil {
+		return 0, 0
+	}
+	seen := make(map[*mm.MemoryManager]struct{})
+	for t := range k.tasks.Root.tids {
+		mm := t.MemoryManager()
+		if mm == nil {
+			continue
+		}
+		if _, ok := seen[mm]; ok {
+			continue
+		}
+		seen[mm] = struct{}{}
+		rss += mm.ResidentSetSize()
+		if m := mm.MaxResidentSetSize(); m > maxRSS {
+			maxRSS = m
+		}
+	}
+	return rss, maxRSS
+}
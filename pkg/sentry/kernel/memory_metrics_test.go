@@ -0,0 +1,100 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/contexttest"
+	"gvisor.dev/gvisor/pkg/sentry/limits"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/platform"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// newTestMemoryManager returns a MemoryManager in which the given number of
+// pages have been touched.
+func newTestMemoryManager(ctx context.Context, t *testing.T, pages uint64) *mm.MemoryManager {
+	t.Helper()
+	m := mm.NewMemoryManager(platform.FromContext(ctx), pgalloc.MemoryFileFromContext(ctx), false /* sleepForActivation */)
+	if _, err := m.SetMmapLayout(arch.New(arch.Host), limits.NewLimitSet()); err != nil {
+		t.Fatalf("SetMmapLayout failed: %v", err)
+	}
+	addr, err := m.MMap(ctx, memmap.MMapOpts{
+		Length:   pages * hostarch.PageSize,
+		Private:  true,
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.AnyAccess,
+	})
+	if err != nil {
+		t.Fatalf("MMap failed: %v", err)
+	}
+	for i := uint64(0); i < pages; i++ {
+		if _, err := m.CopyOut(ctx, addr+hostarch.Addr(i*hostarch.PageSize), []byte{1}, usermem.IOOpts{}); err != nil {
+			t.Fatalf("CopyOut failed: %v", err)
+		}
+	}
+	return m
+}
+
+func TestResidentSetSizesNoTasks(t *testing.T) {
+	var k *Kernel
+	if rss, maxRSS := k.residentSetSizes(); rss != 0 || maxRSS != 0 {
+		t.Errorf("residentSetSizes() of nil Kernel = (%d, %d), want (0, 0)", rss, maxRSS)
+	}
+
+	k = &Kernel{tasks: newTaskSet(NewRootPIDNamespace(nil))}
+	if rss, maxRSS := k.residentSetSizes(); rss != 0 || maxRSS != 0 {
+		t.Errorf("residentSetSizes() without tasks = (%d, %d), want (0, 0)", rss, maxRSS)
+	}
+}
+
+func TestResidentSetSizes(t *testing.T) {
+	ctx := contexttest.Context(t)
+	small := newTestMemoryManager(ctx, t, 1)
+	defer small.DecUsers(ctx)
+	large := newTestMemoryManager(ctx, t, 4)
+	defer large.DecUsers(ctx)
+	if small.ResidentSetSize() == 0 || large.ResidentSetSize() == 0 {
+		t.Fatalf("ResidentSetSize() = %d, %d, want > 0", small.ResidentSetSize(), large.ResidentSetSize())
+	}
+
+	k := &Kernel{tasks: newTaskSet(NewRootPIDNamespace(nil))}
+	for i, m := range []*mm.MemoryManager{
+		// Threads sharing an address space must only be counted once.
+		small,
+		large,
+		large,
+		// Exited tasks have no address space.
+		nil,
+	} {
+		task := &Task{}
+		task.image.MemoryManager = m
+		k.tasks.Root.tids[task] = ThreadID(i + 1)
+	}
+
+	rss, maxRSS := k.residentSetSizes()
+	if want := small.ResidentSetSize() + large.ResidentSetSize(); rss != want {
+		t.Errorf("residentSetSizes() RSS = %d, want %d", rss, want)
+	}
+	if want := max(small.MaxResidentSetSize(), large.MaxResidentSetSize()); maxRSS != want {
+		t.Errorf("residentSetSizes() max RSS = %d, want %d", maxRSS, want)
+	}
+}
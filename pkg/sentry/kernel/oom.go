@@ -0,0 +1,169 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/metric"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+)
+
+var oomKills = metric.MustCreateNewUint64Metric("/kernel/oom_kills", false /* sync */, "Number of processes killed by the sentry OOM killer.")
+
+// oomTotalPages returns the amount of memory, in pages, that OOM badness is
+// relative to. This is the total memory reported to the sandbox, as in
+// sysinfo(2).
+func (k *Kernel) oomTotalPages() uint64 {
+	mf := k.MemoryFile()
+	mfUsage, err := mf.TotalUsage()
+	if err != nil {
+		log.Warningf("Failed to fetch memory usage for OOM score: %v", err)
+	}
+	memStats, _ := usage.MemoryAccounting.Copy()
+	total := usage.TotalMemory(mf.TotalSize(), mfUsage+memStats.Mapped) / hostarch.PageSize
+	if total == 0 {
+		total = 1
+	}
+	return total
+}
+
+// oomRSSPagesLocked returns the resident set size of tg in pages. ok is false
+// if no task in tg has an address space, e.g. because tg is exiting.
+//
+// Preconditions: The TaskSet mutex must be locked.
+func (tg *ThreadGroup) oomRSSPagesLocked() (rss uint64, ok bool) {
+	for t := tg.tasks.Front(); t != nil; t = t.Next() {
+		var m *mm.MemoryManager
+		t.WithMuLocked(func(t *Task) {
+			m = t.MemoryManager()
+		})
+		if m != nil {
+			return m.ResidentSetSize() / hostarch.PageSize, true
+		}
+	}
+	return 0, false
+}
+
+// oomBadnessLocked returns the OOM badness of tg, which is its resident set
+// size in pages biased by its OOM score adjustment, as in Linux's
+// mm/oom_kill.c:oom_badness(). ok is false if tg may not be killed by the OOM
+// killer.
+//
+// Preconditions: The TaskSet mutex must be locked.
+func (tg *ThreadGroup) oomBadnessLocked(totalPages uint64) (badness int64, ok bool) {
+	adj := int64(tg.oomScoreAdj.Load())
+	if adj == linux.OOM_SCORE_ADJ_MIN {
+		return 0, false
+	}
+	// Like Linux, never kill the global init process.
+	if tg.isInitInLocked(tg.pidns.owner.Root) {
+		return 0, false
+	}
+	rss, ok := tg.oomRSSPagesLocked()
+	if !ok {
+		return 0, false
+	}
+	// Normalize adj to be proportional to the total memory, such that an adj
+	// of 1000 is equivalent to using all of it.
+	return int64(rss) + adj*int64(totalPages/1000), true
+}
+
+// OOMScore returns the OOM score of t's thread group, as reported by
+// /proc/[pid]/oom_score.
+func (t *Task) OOMScore() int64 {
+	totalPages := t.k.oomTotalPages()
+	t.tg.pidns.owner.mu.RLock()
+	badness, ok := t.tg.oomBadnessLocked(totalPages)
+	t.tg.pidns.owner.mu.RUnlock()
+	if !ok {
+		return 0
+	}
+	// Scale badness into the [0, 2000] range, as Linux's
+	// fs/proc/base.c:proc_oom_score().
+	return (1000 + badness*1000/int64(totalPages)) * 2 / 3
+}
+
+// selectOOMVictimLocked returns the thread group with the highest OOM badness.
+// It returns nil if no thread group may be killed, or if a previous victim
+// still holds its memory, in which case the OOM killer should wait for it to
+// exit rather than kill another thread group.
+//
+// Preconditions: ts.mu must be locked.
+func (ts *TaskSet) selectOOMVictimLocked(totalPages uint64) *ThreadGroup {
+	var (
+		victim        *ThreadGroup
+		victimBadness int64
+	)
+	for tg, tgid := range ts.Root.tgids {
+		badness, ok := tg.oomBadnessLocked(totalPages)
+		if !ok {
+			continue
+		}
+		if tg.oomVictim {
+			return nil
+		}
+		// Break ties by lowest TGID so that selection is deterministic.
+		if victim == nil || badness > victimBadness || (badness == victimBadness && tgid < ts.Root.tgids[victim]) {
+			victim = tg
+			victimBadness = badness
+		}
+	}
+	return victim
+}
+
+// SelectOOMVictim returns the thread group the OOM killer would kill, or nil
+// if there is none.
+func (k *Kernel) SelectOOMVictim() *ThreadGroup {
+	totalPages := k.oomTotalPages()
+	k.tasks.mu.RLock()
+	defer k.tasks.mu.RUnlock()
+	return k.tasks.selectOOMVictimLocked(totalPages)
+}
+
+// OOMKill kills the thread group with the highest OOM badness with SIGKILL,
+// and returns it. It returns nil if no thread group was killed, either because
+// none may be killed, a previous victim is still exiting or k is paused.
+func (k *Kernel) OOMKill() *ThreadGroup {
+	totalPages := k.oomTotalPages()
+
+	k.extMu.Lock()
+	defer k.extMu.Unlock()
+
+	ts := k.tasks
+	ts.mu.Lock()
+	if ts.stopCount > 0 {
+		ts.mu.Unlock()
+		return nil
+	}
+	victim := ts.selectOOMVictimLocked(totalPages)
+	if victim == nil {
+		ts.mu.Unlock()
+		return nil
+	}
+	victim.oomVictim = true
+	tgid := ts.Root.tgids[victim]
+	rss, _ := victim.oomRSSPagesLocked()
+	ts.mu.Unlock()
+
+	log.Warningf("Out of memory: Killed process %d (%s) anon-rss:%dkB oom_score_adj:%d", tgid, victim.leader.Name(), rss*hostarch.PageSize/1024, victim.oomScoreAdj.Load())
+	oomKills.Increment()
+	if err := victim.SendSignal(SignalInfoPriv(linux.SIGKILL)); err != nil {
+		log.Warningf("Failed to kill OOM victim %d: %v", tgid, err)
+	}
+	return victim
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oomkiller implements the in-sandbox OOM killer, which kills the
+// process with the highest OOM score when the sandbox's memory usage exceeds
+// its limit.
+//
+// Without it, exceeding the limit causes the host to OOM kill the entire
+// sandbox. Killing a single process instead lets applications protect
+// critical processes using /proc/[pid]/oom_score_adj, as they would on Linux.
+package oomkiller
+
+import (
+	"time"
+
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// DefaultPeriod is the default interval between memory usage checks.
+const DefaultPeriod = 100 * time.Millisecond
+
+// OOMKiller periodically checks the sandbox's memory usage and kills the
+// process selected by kernel.Kernel.OOMKill when it exceeds the limit.
+type OOMKiller struct {
+	k *kernel.Kernel
+
+	// period is how often memory usage is checked.
+	period time.Duration
+
+	// Writing to this channel indicates the OOM killer goroutine should stop.
+	stop chan struct{}
+
+	// done is used to signal when the OOM killer goroutine has exited.
+	done sync.WaitGroup
+}
+
+// New creates a new OOMKiller. Processes are killed when memory usage exceeds
+// usage.TotalMemoryLimit, which may change while the OOM killer is running.
+func New(k *kernel.Kernel, period time.Duration) *OOMKiller {
+	return &OOMKiller{
+		k:      k,
+		period: period,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Stop stops the OOM killer goroutine. Stop must not be called concurrently
+// with Start and may only be called once.
+func (o *OOMKiller) Stop() {
+	close(o.stop)
+	o.done.Wait()
+}
+
+// Start starts the OOM killer goroutine. Start must not be called concurrently
+// with Stop and may only be called once.
+func (o *OOMKiller) Start() {
+	if o.period == 0 {
+		return
+	}
+	log.Infof("Starting OOM killer, limit: %d bytes, period: %v", usage.TotalMemoryLimit(), o.period)
+	o.done.Add(1)
+	go o.run() // S/R-SAFE: OOMKill does nothing while the kernel is paused.
+}
+
+func (o *OOMKiller) run() {
+	defer o.done.Done()
+
+	ticker := time.NewTicker(o.period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-ticker.C:
+			o.check()
+		}
+	}
+}
+
+// check kills a process if memory usage exceeds the limit.
+func (o *OOMKiller) check() {
+	limit := usage.TotalMemoryLimit()
+	if limit == 0 {
+		return
+	}
+	totalPlatform, err := o.k.MemoryFile().TotalUsage()
+	if err != nil {
+		log.Warningf("Failed to fetch memory usage for OOM killer: %v", err)
+		return
+	}
+	snapshot, _ := usage.MemoryAccounting.Copy()
+	if total := totalPlatform + snapshot.Mapped; total > limit {
+		log.Debugf("Memory usage %d exceeds OOM limit %d", total, limit)
+		o.k.OOMKill()
+	}
+}
@@ -703,11 +703,11 @@ func (t *Task) ptraceSyscallEnter() (taskRunState, bool) {
 		return nil, false
 	case ptraceSyscallIntercept:
 		t.Debugf("Entering syscall-enter-stop from PTRACE_SYSCALL")
-		t.ptraceSyscallStopLocked()
+		t.ptraceSyscallStopLocked(linux.PTRACE_EVENTMSG_SYSCALL_ENTRY)
 		return (*runSyscallAfterSyscallEnterStop)(nil), true
 	case ptraceSyscallEmu:
 		t.Debugf("Entering syscall-enter-stop from PTRACE_SYSEMU")
-		t.ptraceSyscallStopLocked()
+		t.ptraceSyscallStopLocked(linux.PTRACE_EVENTMSG_SYSCALL_ENTRY)
 		return (*runSyscallAfterSysemuStop)(nil), true
 	}
 	panic(fmt.Sprintf("Unknown ptraceSyscallMode: %v", t.ptraceSyscallMode))
@@ -725,11 +725,16 @@ func (t *Task) ptraceSyscallExit() {
 		return
 	}
 	t.Debugf("Entering syscall-exit-stop")
-	t.ptraceSyscallStopLocked()
+	t.ptraceSyscallStopLocked(linux.PTRACE_EVENTMSG_SYSCALL_EXIT)
 }
 
+// ptraceSyscallStopLocked enters a syscall-stop. msg is the value returned by
+// PTRACE_GETEVENTMSG during the stop, which indicates whether it is a
+// syscall-enter-stop or syscall-exit-stop.
+//
 // Preconditions: The TaskSet mutex must be locked.
-func (t *Task) ptraceSyscallStopLocked() {
+func (t *Task) ptraceSyscallStopLocked(msg uint64) {
+	t.ptraceEventMsg = msg
 	code := int32(linux.SIGTRAP)
 	if t.ptraceOpts.SysGood {
 		code |= 0x80
@@ -1000,17 +1005,68 @@ func (t *Task) ptraceSetOptionsLocked(opts uintptr) error {
 	return nil
 }
 
-// Ptrace implements the ptrace system call.
-func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
+// ptraceSyscallInfoLocked returns the struct ptrace_syscall_info describing
+// t's current ptrace-stop, and the number of bytes of it that are valid, as in
+// Linux's kernel/ptrace.c:ptrace_get_syscall_info().
+//
+// Preconditions:
+//   - The TaskSet mutex must be locked.
+//   - t must be in a frozen ptrace-stop.
+func (t *Task) ptraceSyscallInfoLocked() (linux.PtraceSyscallInfo, int) {
+	info := linux.PtraceSyscallInfo{
+		Op:                 linux.PTRACE_SYSCALL_INFO_NONE,
+		Arch:               t.SyscallTable().AuditNumber,
+		InstructionPointer: uint64(t.Arch().IP()),
+		StackPointer:       uint64(t.Arch().Stack()),
+	}
+	setSyscall := func() {
+		info.Data[0] = uint64(t.Arch().SyscallNo())
+		for i, arg := range t.Arch().SyscallArgs() {
+			info.Data[1+i] = arg.Uint64()
+		}
+	}
+	var code int32
+	if t.ptraceSiginfo != nil {
+		code = t.ptraceSiginfo.Code
+	}
+	switch code {
+	case int32(linux.SIGTRAP) | 0x80:
+		switch t.ptraceEventMsg {
+		case linux.PTRACE_EVENTMSG_SYSCALL_ENTRY:
+			info.Op = linux.PTRACE_SYSCALL_INFO_ENTRY
+			setSyscall()
+			return info, linux.PtraceSyscallInfoEntrySize
+		case linux.PTRACE_EVENTMSG_SYSCALL_EXIT:
+			info.Op = linux.PTRACE_SYSCALL_INFO_EXIT
+			rval := int64(t.Arch().Return())
+			info.Data[0] = uint64(rval)
+			// include/linux/err.h:IS_ERR_VALUE()
+			if rval < 0 && rval >= -4095 {
+				info.Data[1] = 1
+			}
+			return info, linux.PtraceSyscallInfoExitSize
+		}
+	case int32(linux.SIGTRAP) | linux.PTRACE_EVENT_SECCOMP<<8:
+		info.Op = linux.PTRACE_SYSCALL_INFO_SECCOMP
+		setSyscall()
+		info.Data[7] = uint64(uint32(t.ptraceEventMsg))
+		return info, linux.PtraceSyscallInfoSeccompSize
+	}
+	return info, linux.PtraceSyscallInfoNoneSize
+}
+
+// Ptrace implements the ptrace system call, returning the system call's
+// return value on success.
+func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) (uintptr, error) {
 	// PTRACE_TRACEME ignores all other arguments.
 	if req == linux.PTRACE_TRACEME {
-		return t.ptraceTraceme()
+		return 0, t.ptraceTraceme()
 	}
 	// All other ptrace requests operate on a current or future tracee
 	// specified by pid.
 	target := t.tg.pidns.TaskWithID(pid)
 	if target == nil {
-		return linuxerr.ESRCH
+		return 0, linuxerr.ESRCH
 	}
 
 	// PTRACE_ATTACH and PTRACE_SEIZE do not require that target is not already
@@ -1018,24 +1074,24 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 	if req == linux.PTRACE_ATTACH || req == linux.PTRACE_SEIZE {
 		seize := req == linux.PTRACE_SEIZE
 		if seize && addr != 0 {
-			return linuxerr.EIO
+			return 0, linuxerr.EIO
 		}
-		return t.ptraceAttach(target, seize, uintptr(data))
+		return 0, t.ptraceAttach(target, seize, uintptr(data))
 	}
 	// PTRACE_KILL and PTRACE_INTERRUPT require that the target is a tracee,
 	// but does not require that it is ptrace-stopped.
 	if req == linux.PTRACE_KILL {
-		return t.ptraceKill(target)
+		return 0, t.ptraceKill(target)
 	}
 	if req == linux.PTRACE_INTERRUPT {
-		return t.ptraceInterrupt(target)
+		return 0, t.ptraceInterrupt(target)
 	}
 	// All other ptrace requests require that the target is a ptrace-stopped
 	// tracee, and freeze the ptrace-stop so the tracee can be operated on.
 	t.tg.pidns.owner.mu.RLock()
 	if target.Tracer() != t {
 		t.tg.pidns.owner.mu.RUnlock()
-		return linuxerr.ESRCH
+		return 0, linuxerr.ESRCH
 	}
 	if !target.ptraceFreeze() {
 		t.tg.pidns.owner.mu.RUnlock()
@@ -1043,7 +1099,7 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 		// PTRACE_TRACEME, PTRACE_INTERRUPT, and PTRACE_KILL) require the
 		// tracee to be in a ptrace-stop, otherwise they fail with ESRCH." -
 		// ptrace(2)
-		return linuxerr.ESRCH
+		return 0, linuxerr.ESRCH
 	}
 	t.tg.pidns.owner.mu.RUnlock()
 	// Even if the target has a ptrace-stop active, the tracee's task goroutine
@@ -1065,56 +1121,56 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 	case linux.PTRACE_DETACH:
 		if err := t.ptraceDetach(target, linux.Signal(data)); err != nil {
 			target.ptraceUnfreeze()
-			return err
+			return 0, err
 		}
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_CONT:
 		if err := target.ptraceUnstop(ptraceSyscallNone, false, linux.Signal(data)); err != nil {
 			target.ptraceUnfreeze()
-			return err
+			return 0, err
 		}
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_SYSCALL:
 		if err := target.ptraceUnstop(ptraceSyscallIntercept, false, linux.Signal(data)); err != nil {
 			target.ptraceUnfreeze()
-			return err
+			return 0, err
 		}
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_SINGLESTEP:
 		if err := target.ptraceUnstop(ptraceSyscallNone, true, linux.Signal(data)); err != nil {
 			target.ptraceUnfreeze()
-			return err
+			return 0, err
 		}
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_SYSEMU:
 		if err := target.ptraceUnstop(ptraceSyscallEmu, false, linux.Signal(data)); err != nil {
 			target.ptraceUnfreeze()
-			return err
+			return 0, err
 		}
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_SYSEMU_SINGLESTEP:
 		if err := target.ptraceUnstop(ptraceSyscallEmu, true, linux.Signal(data)); err != nil {
 			target.ptraceUnfreeze()
-			return err
+			return 0, err
 		}
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_LISTEN:
 		t.tg.pidns.owner.mu.RLock()
 		defer t.tg.pidns.owner.mu.RUnlock()
 		if !target.ptraceSeized {
-			return linuxerr.EIO
+			return 0, linuxerr.EIO
 		}
 		if target.ptraceSiginfo == nil {
-			return linuxerr.EIO
+			return 0, linuxerr.EIO
 		}
 		if target.ptraceSiginfo.Code>>8 != linux.PTRACE_EVENT_STOP {
-			return linuxerr.EIO
+			return 0, linuxerr.EIO
 		}
 		target.tg.signalHandlers.mu.Lock()
 		defer target.tg.signalHandlers.mu.Unlock()
@@ -1124,7 +1180,7 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 			target.stop.(*ptraceStop).listen = true
 			target.ptraceUnfreezeLocked()
 		}
-		return nil
+		return 0, nil
 	}
 
 	// All other ptrace requests expect us to unfreeze the stop.
@@ -1138,15 +1194,15 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 		// is the error flag." - ptrace(2)
 		word := t.Arch().Native(0)
 		if _, err := word.CopyIn(target.CopyContext(t, usermem.IOOpts{IgnorePermissions: true}), addr); err != nil {
-			return err
+			return 0, err
 		}
 		_, err := word.CopyOut(t, data)
-		return err
+		return 0, err
 
 	case linux.PTRACE_POKETEXT, linux.PTRACE_POKEDATA:
 		word := t.Arch().Native(uintptr(data))
 		_, err := word.CopyOut(target.CopyContext(t, usermem.IOOpts{IgnorePermissions: true}), addr)
-		return err
+		return 0, err
 
 	case linux.PTRACE_GETREGSET:
 		// "Read the tracee's registers. addr specifies, in an
@@ -1156,7 +1212,7 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 		// to indicate the actual number of bytes returned." - ptrace(2)
 		ars, err := t.CopyInIovecs(data, 1)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		ar := ars.Head()
@@ -1169,7 +1225,7 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 			},
 		}, int(ar.Length()), target.Kernel().FeatureSet())
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		// Update iovecs to represent the range of the written register set.
@@ -1178,12 +1234,12 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 			panic(fmt.Sprintf("%#x + %#x overflows. Invalid reg size > %#x", ar.Start, n, ar.Length()))
 		}
 		ar.End = end
-		return t.CopyOutIovecs(data, hostarch.AddrRangeSeqOf(ar))
+		return 0, t.CopyOutIovecs(data, hostarch.AddrRangeSeqOf(ar))
 
 	case linux.PTRACE_SETREGSET:
 		ars, err := t.CopyInIovecs(data, 1)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		ar := ars.Head()
@@ -1196,68 +1252,81 @@ func (t *Task) Ptrace(req int64, pid ThreadID, addr, data hostarch.Addr) error {
 			},
 		}, int(ar.Length()), target.Kernel().FeatureSet())
 		if err != nil {
-			return err
+			return 0, err
 		}
 		target.p.FullStateChanged()
 		ar.End -= hostarch.Addr(n)
-		return t.CopyOutIovecs(data, hostarch.AddrRangeSeqOf(ar))
+		return 0, t.CopyOutIovecs(data, hostarch.AddrRangeSeqOf(ar))
 
 	case linux.PTRACE_GETSIGINFO:
 		t.tg.pidns.owner.mu.RLock()
 		defer t.tg.pidns.owner.mu.RUnlock()
 		if target.ptraceSiginfo == nil {
-			return linuxerr.EINVAL
+			return 0, linuxerr.EINVAL
 		}
 		_, err := target.ptraceSiginfo.CopyOut(t, data)
-		return err
+		return 0, err
 
 	case linux.PTRACE_SETSIGINFO:
 		var info linux.SignalInfo
 		if _, err := info.CopyIn(t, data); err != nil {
-			return err
+			return 0, err
 		}
 		t.tg.pidns.owner.mu.RLock()
 		defer t.tg.pidns.owner.mu.RUnlock()
 		if target.ptraceSiginfo == nil {
-			return linuxerr.EINVAL
+			return 0, linuxerr.EINVAL
 		}
 		target.ptraceSiginfo = &info
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_GETSIGMASK:
 		if addr != linux.SignalSetSize {
-			return linuxerr.EINVAL
+			return 0, linuxerr.EINVAL
 		}
 		mask := target.SignalMask()
 		_, err := mask.CopyOut(t, data)
-		return err
+		return 0, err
 
 	case linux.PTRACE_SETSIGMASK:
 		if addr != linux.SignalSetSize {
-			return linuxerr.EINVAL
+			return 0, linuxerr.EINVAL
 		}
 		var mask linux.SignalSet
 		if _, err := mask.CopyIn(t, data); err != nil {
-			return err
+			return 0, err
 		}
 		// The target's task goroutine is stopped, so this is safe:
 		target.SetSignalMask(mask &^ UnblockableSignals)
-		return nil
+		return 0, nil
 
 	case linux.PTRACE_SETOPTIONS:
 		t.tg.pidns.owner.mu.Lock()
 		defer t.tg.pidns.owner.mu.Unlock()
-		return target.ptraceSetOptionsLocked(uintptr(data))
+		return 0, target.ptraceSetOptionsLocked(uintptr(data))
 
 	case linux.PTRACE_GETEVENTMSG:
 		t.tg.pidns.owner.mu.RLock()
 		defer t.tg.pidns.owner.mu.RUnlock()
 		_, err := primitive.CopyUint64Out(t, hostarch.Addr(data), target.ptraceEventMsg)
-		return err
+		return 0, err
+
+	case linux.PTRACE_GET_SYSCALL_INFO:
+		t.tg.pidns.owner.mu.RLock()
+		info, size := target.ptraceSyscallInfoLocked()
+		t.tg.pidns.owner.mu.RUnlock()
+		buf := make([]byte, info.SizeBytes())
+		info.MarshalUnsafe(buf)
+		if n := min(uintptr(size), uintptr(addr)); n > 0 {
+			if _, err := t.CopyOutBytes(data, buf[:n]); err != nil {
+				return 0, err
+			}
+		}
+		return uintptr(size), nil
 
 	// PEEKSIGINFO is unimplemented but seems to have no users anywhere.
 
 	default:
-		return t.ptraceArch(target, req, addr, data)
+		return 0, t.ptraceArch(target, req, addr, data)
 	}
 }
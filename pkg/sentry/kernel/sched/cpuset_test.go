@@ -42,3 +42,15 @@ func TestClearAbove(t *testing.T) {
 		}
 	}
 }
+
+func TestIsSet(t *testing.T) {
+	c := NewCPUSet(16)
+	c.Set(3)
+	c.Set(9)
+	for cpu := uint(0); cpu < 24; cpu++ {
+		want := cpu == 3 || cpu == 9
+		if got := c.IsSet(cpu); got != want {
+			t.Errorf("IsSet(%d) = %t, want %t", cpu, got, want)
+		}
+	}
+}
@@ -83,3 +83,19 @@ func LoadSeccheckDataLocked(t *Task, mask seccheck.FieldMask, info *pb.ContextDa
 	}
 	t.Credentials().LoadSeccheckData(mask, info)
 }
+
+// SeccheckThreadGroupID implements seccheck.FilterTarget.
+func (t *Task) SeccheckThreadGroupID() int32 {
+	return int32(t.tg.ID())
+}
+
+// SeccheckCgroupPaths implements seccheck.FilterTarget.
+func (t *Task) SeccheckCgroupPaths() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	paths := make([]string, 0, len(t.cgroups))
+	for c := range t.cgroups {
+		paths = append(paths, c.Path())
+	}
+	return paths
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/metric"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+)
+
+var swapOutBytes = metric.MustCreateNewUint64Metric("/kernel/swap_out_bytes", false /* sync */, "Number of bytes of application memory migrated to the swap tier.")
+
+// memoryManagers returns the distinct MemoryManagers of all tasks in k, with a
+// user reference held on each. The caller must release them using
+// mm.MemoryManager.DecUsers.
+func (k *Kernel) memoryManagers() []*mm.MemoryManager {
+	k.tasks.mu.RLock()
+	defer k.tasks.mu.RUnlock()
+	seen := make(map[*mm.MemoryManager]struct{})
+	var mms []*mm.MemoryManager
+	for t := range k.tasks.Root.tids {
+		var m *mm.MemoryManager
+		t.WithMuLocked(func(t *Task) {
+			m = t.MemoryManager()
+		})
+		if m == nil {
+			continue
+		}
+		if _, ok := seen[m]; ok {
+			continue
+		}
+		if !m.IncUsers() {
+			continue
+		}
+		seen[m] = struct{}{}
+		mms = append(mms, m)
+	}
+	return mms
+}
+
+// SwapOut performs a swap aging pass over the private memory of all tasks in
+// k, and migrates up to about max bytes of memory that wasn't used during the
+// last minAge passes to the swap tier of k's MemoryFile. It returns the number
+// of bytes migrated. It does nothing if k is paused. See
+// mm.MemoryManager.SwapOut.
+func (k *Kernel) SwapOut(minAge uint32, max uint64) uint64 {
+	// Exclude saving, which migrates all memory back from the swap tier.
+	k.extMu.Lock()
+	defer k.extMu.Unlock()
+	k.tasks.mu.RLock()
+	paused := k.tasks.stopCount > 0
+	k.tasks.mu.RUnlock()
+	if paused {
+		return 0
+	}
+
+	ctx := k.SupervisorContext()
+	var swapped uint64
+	for _, m := range k.memoryManagers() {
+		swapped += m.SwapOut(ctx, minAge, max-min(swapped, max))
+		m.DecUsers(ctx)
+	}
+	swapOutBytes.IncrementBy(swapped)
+	return swapped
+}
+
+// swapIn migrates the private memory of all tasks in k from the swap tier of
+// k's MemoryFile back to k's MemoryFile.
+func (k *Kernel) swapIn(ctx context.Context) error {
+	if k.mf.SwapFile() == nil {
+		return nil
+	}
+	var err error
+	for _, m := range k.memoryManagers() {
+		if err == nil {
+			err = m.SwapIn(ctx)
+		}
+		m.DecUsers(ctx)
+	}
+	return err
+}
+
+// SwapDeviceName is the name of the virtual swap device reported to
+// applications.
+const SwapDeviceName = "/dev/gvisor-swap"
+
+// SwapUsage returns the size of the virtual swap device reported to
+// applications, and the number of bytes of it that are in use. The usage of
+// the virtual swap device is the amount of application memory in the swap and
+// compressed cache tiers of k's MemoryFile, capped at its size. If k has no
+// virtual swap device, SwapUsage returns (0, 0).
+func (k *Kernel) SwapUsage() (total, used uint64) {
+	if k.swapSize == 0 {
+		return 0, 0
+	}
+	used, err := k.mf.SwapUsage()
+	if err != nil {
+		log.Warningf("Failed to get swap usage: %v", err)
+	}
+	return k.swapSize, min(used, k.swapSize)
+}
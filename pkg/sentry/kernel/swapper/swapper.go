@@ -0,0 +1,126 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package swapper implements the sandbox swapper, which compresses cold
+// application memory into the compressed cache tier of the kernel's
+// MemoryFile, or migrates it to the swap tier of the kernel's MemoryFile, when
+// the sandbox's memory usage exceeds a watermark.
+//
+// This allows oversubscribed hosts to swap sandbox memory to a file without
+// enabling host swap.
+package swapper
+
+import (
+	"time"
+
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// DefaultPeriod is the default interval between memory usage checks. Each
+// check performed under memory pressure is a swap aging pass.
+const DefaultPeriod = time.Second
+
+// Swapper periodically checks the sandbox's memory usage and migrates cold
+// memory to the swap tier when it exceeds the watermark.
+type Swapper struct {
+	k *kernel.Kernel
+
+	// watermark is the percentage of usage.TotalMemoryLimit above which
+	// memory is swapped out.
+	watermark uint64
+
+	// minAge is the number of aging passes during which memory must not be
+	// used by the application to be swapped out.
+	minAge uint32
+
+	// period is how often memory usage is checked.
+	period time.Duration
+
+	// Writing to this channel indicates the swapper goroutine should stop.
+	stop chan struct{}
+
+	// done is used to signal when the swapper goroutine has exited.
+	done sync.WaitGroup
+}
+
+// New creates a new Swapper. k's MemoryFile must have a swap tier or an
+// enabled compressed cache tier.
+func New(k *kernel.Kernel, watermark uint64, minAge uint32, period time.Duration) *Swapper {
+	return &Swapper{
+		k:         k,
+		watermark: watermark,
+		minAge:    minAge,
+		period:    period,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Stop stops the swapper goroutine. Stop must not be called concurrently with
+// Start and may only be called once.
+func (s *Swapper) Stop() {
+	close(s.stop)
+	s.done.Wait()
+}
+
+// Start starts the swapper goroutine. Start must not be called concurrently
+// with Stop and may only be called once.
+func (s *Swapper) Start() {
+	if s.period == 0 {
+		return
+	}
+	log.Infof("Starting swapper, watermark: %d%%, minimum age: %d, period: %v", s.watermark, s.minAge, s.period)
+	s.done.Add(1)
+	go s.run() // S/R-SAFE: Kernel.SwapOut does nothing while the kernel is paused.
+}
+
+func (s *Swapper) run() {
+	defer s.done.Done()
+
+	ticker := time.NewTicker(s.period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.check()
+		}
+	}
+}
+
+// check swaps out cold memory if memory usage exceeds the watermark.
+func (s *Swapper) check() {
+	limit := usage.TotalMemoryLimit()
+	if limit == 0 {
+		return
+	}
+	high := limit / 100 * s.watermark
+	totalPlatform, err := s.k.MemoryFile().TotalUsage()
+	if err != nil {
+		log.Warningf("Failed to fetch memory usage for swapper: %v", err)
+		return
+	}
+	snapshot, _ := usage.MemoryAccounting.Copy()
+	total := totalPlatform + snapshot.Mapped
+	if total <= high {
+		return
+	}
+	if swapped := s.k.SwapOut(s.minAge, total-high); swapped > 0 {
+		log.Debugf("Memory usage %d exceeds swap watermark %d, swapped out %d bytes", total, high, swapped)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/abi/sentry"
+	"gvisor.dev/gvisor/pkg/bpf"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// SyscallPolicy is a seccomp-bpf program that the sentry applies to the
+// syscalls of all tasks in a container. It's independent of the seccomp
+// filters installed by the application, which can't observe or change it,
+// e.g. through prctl(PR_GET_SECCOMP) or /proc/[pid]/status.
+//
+// +stateify savable
+type SyscallPolicy struct {
+	// program is the filter. It's immutable.
+	program bpf.Program
+
+	// cacheOnce guards the computation of cache and cacheAuditNumber.
+	cacheOnce sync.Once `state:"nosave"`
+
+	// cache maps syscall numbers to the result of program, for syscalls where
+	// it only depends on the architecture and the syscall number. Other
+	// syscalls map to uncacheableBPFAction.
+	cache [sentry.MaxSyscallNum + 1]linux.BPFAction `state:"nosave"`
+
+	// cacheAuditNumber is the AUDIT_ARCH_* constant that cache is valid for.
+	cacheAuditNumber uint32 `state:"nosave"`
+}
+
+// NewSyscallPolicy returns a SyscallPolicy that applies program. program
+// should only return SECCOMP_RET_ALLOW, SECCOMP_RET_ERRNO, SECCOMP_RET_TRAP
+// or SECCOMP_RET_KILL_THREAD; other actions kill the thread too.
+func NewSyscallPolicy(program bpf.Program) *SyscallPolicy {
+	return &SyscallPolicy{program: program}
+}
+
+// populateCache computes p.cache for auditNumber.
+func (p *SyscallPolicy) populateCache(auditNumber uint32) {
+	p.cacheAuditNumber = auditNumber
+	sd := linux.SeccompData{}
+	input := bpf.Input(make([]byte, sd.SizeBytes()))
+	for sysno := int32(0); sysno <= sentry.MaxSyscallNum; sysno++ {
+		sd.Nr = sysno
+		sd.Arch = auditNumber
+		clear(input)
+		sd.MarshalBytes(input)
+		if result, err := checkFilterCacheability(p.program, input); err == nil {
+			p.cache[sysno] = linux.BPFAction(result)
+		} else {
+			p.cache[sysno] = uncacheableBPFAction
+		}
+	}
+}
+
+// evaluate returns the result of p for syscall sysno made by t.
+func (p *SyscallPolicy) evaluate(t *Task, sysno uintptr, args arch.SyscallArguments) linux.BPFAction {
+	auditNumber := t.image.st.AuditNumber
+	p.cacheOnce.Do(func() { p.populateCache(auditNumber) })
+	if auditNumber == p.cacheAuditNumber && sysno <= sentry.MaxSyscallNum {
+		if cached := p.cache[sysno]; cached != uncacheableBPFAction {
+			return cached
+		}
+	}
+
+	data := linux.SeccompData{
+		Nr:                 int32(sysno),
+		Arch:               auditNumber,
+		InstructionPointer: uint64(t.Arch().IP()),
+	}
+	for i, arg := range args {
+		if i >= len(data.Args) {
+			break
+		}
+		data.Args[i] = arg.Uint64()
+	}
+	result, err := bpf.Exec[bpf.NativeEndian](p.program, dataAsBPFInput(t, &data))
+	if err != nil {
+		t.Debugf("Syscall policy returned error: %v", err)
+		return linux.SECCOMP_RET_KILL_THREAD
+	}
+	return linux.BPFAction(result)
+}
+
+// checkSyscallPolicy applies the syscall policy of t's container before the
+// execution of syscall sysno. If the syscall must not be executed, it returns
+// a non-nil control or error, which is the result of the syscall.
+//
+// Preconditions:
+//   - The caller must be running on the task goroutine.
+//   - t.syscallPolicy != nil.
+func (t *Task) checkSyscallPolicy(sysno uintptr, args arch.SyscallArguments) (*SyscallControl, error) {
+	result := t.syscallPolicy.evaluate(t, sysno, args)
+	switch result & linux.SECCOMP_RET_ACTION {
+	case linux.SECCOMP_RET_ALLOW:
+		return nil, nil
+
+	case linux.SECCOMP_RET_ERRNO:
+		t.Debugf("Syscall %d: denied by syscall policy", sysno)
+		return nil, unix.Errno(result.Data())
+
+	case linux.SECCOMP_RET_TRAP:
+		t.Debugf("Syscall %d: trapped by syscall policy", sysno)
+		t.SendSignal(seccompSiginfo(t, int32(result.Data()), int32(sysno), hostarch.Addr(t.Arch().IP())))
+		return nil, unix.ENOSYS
+
+	default:
+		t.Debugf("Syscall %d: killed by syscall policy", sysno)
+		t.PrepareExit(linux.WaitStatusTerminationSignal(linux.SIGSYS))
+		return CtrlDoExit, nil
+	}
+}
+
+// SetSyscallPolicy sets the syscall policy of container cid, which applies to
+// tasks created in the container after the call. A nil policy removes it.
+func (k *Kernel) SetSyscallPolicy(cid string, p *SyscallPolicy) {
+	k.syscallPoliciesMu.Lock()
+	defer k.syscallPoliciesMu.Unlock()
+	if p == nil {
+		delete(k.syscallPolicies, cid)
+		return
+	}
+	if k.syscallPolicies == nil {
+		k.syscallPolicies = make(map[string]*SyscallPolicy)
+	}
+	k.syscallPolicies[cid] = p
+}
+
+// syscallPolicy returns the syscall policy of container cid, if any.
+func (k *Kernel) syscallPolicy(cid string) *SyscallPolicy {
+	k.syscallPoliciesMu.Lock()
+	defer k.syscallPoliciesMu.Unlock()
+	return k.syscallPolicies[cid]
+}
@@ -121,6 +121,10 @@ const (
 
 	// SecCheckRawExit represents raw/exit syscall seccheck event.
 	SecCheckRawExit
+
+	// FaultInjectEnable enables fault injection rules for the syscall. See
+	// Kernel.SetFaultInjectionRules.
+	FaultInjectEnable
 )
 
 // StraceEnableBits combines both strace log and event flags.
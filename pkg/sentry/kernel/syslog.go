@@ -17,36 +17,130 @@ package kernel
 import (
 	"fmt"
 	"math/rand"
+	"strings"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/waiter"
 )
 
+const (
+	// SyslogBufLen is the size of the kernel log buffer in bytes, as reported
+	// by SYSLOG_ACTION_SIZE_BUFFER. This is Linux's default of
+	// 1 << CONFIG_LOG_BUF_SHIFT.
+	SyslogBufLen = 1 << 17
+
+	// SyslogRecordMax is the maximum length of the text of a single record.
+	// From Linux's kernel/printk/printk.c:PRINTKRB_RECORD_MAX.
+	SyslogRecordMax = 1024 - 32
+
+	// syslogRecordOverhead is the number of bytes of the log buffer that each
+	// record consumes in addition to its text, approximating the size of
+	// Linux's struct printk_info.
+	syslogRecordOverhead = 88
+
+	// Console log levels, from Linux's include/linux/printk.h.
+	syslogConsoleLevelMin     = 1
+	syslogConsoleLevelDefault = 7
+)
+
+// SyslogRecord is a single message in the kernel log.
+//
+// +stateify savable
+type SyslogRecord struct {
+	// Seq is the record's sequence number. Sequence numbers start at 0 and
+	// increase by 1 for each record logged.
+	Seq uint64
+
+	// Priority is the record's facility and level, encoded as
+	// facility<<3 | level.
+	Priority uint32
+
+	// Timestamp is the time at which the record was logged, in nanoseconds
+	// since boot.
+	Timestamp int64
+
+	// Text is the text of the record, without a trailing newline.
+	Text string
+}
+
+// SyslogText returns r formatted as it is read by syslog(2) and /proc/kmsg,
+// as in Linux's kernel/printk/printk.c:record_print_text(). Each line of r's
+// text is prefixed with r's priority and timestamp.
+func (r *SyslogRecord) SyslogText() string {
+	usec := r.Timestamp / 1000
+	prefix := fmt.Sprintf("<%d>[%5d.%06d] ", r.Priority, usec/1000000, usec%1000000)
+	var b strings.Builder
+	for _, line := range strings.Split(r.Text, "\n") {
+		b.WriteString(prefix)
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
 // syslog represents a sentry-global kernel log.
 //
-// Currently, it contains only fun messages for a dmesg easter egg.
+// The log is a bounded sequence of records. When logging a record would cause
+// the log to exceed SyslogBufLen bytes, the oldest records are discarded.
 //
 // +stateify savable
 type syslog struct {
+	// queue is notified with waiter.ReadableEvents when a record is logged.
+	queue waiter.Queue
+
 	// mu protects the below.
 	mu sync.Mutex `state:"nosave"`
 
-	// msg is the syslog message buffer. It is lazily initialized.
-	msg []byte
-}
+	// records are the records in the log, ordered by sequence number.
+	//
+	// +checklocks:mu
+	records []SyslogRecord
 
-// Log returns a copy of the syslog.
-func (s *syslog) Log() []byte {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	// size is the number of bytes of the log consumed by records.
+	//
+	// +checklocks:mu
+	size int
 
-	if s.msg != nil {
-		// Already initialized, just return a copy.
-		o := make([]byte, len(s.msg))
-		copy(o, s.msg)
-		return o
-	}
+	// nextSeq is the sequence number of the next record to be logged.
+	//
+	// +checklocks:mu
+	nextSeq uint64
+
+	// clearSeq is the sequence number of the first record that has not been
+	// cleared by SYSLOG_ACTION_CLEAR or SYSLOG_ACTION_READ_CLEAR.
+	//
+	// +checklocks:mu
+	clearSeq uint64
+
+	// readSeq is the sequence number of the first record that has not been
+	// consumed by SYSLOG_ACTION_READ or /proc/kmsg, and readPartial is the
+	// number of bytes of that record's text that have been consumed.
+	//
+	// +checklocks:mu
+	readSeq     uint64
+	readPartial int
+
+	// consoleLevel is the console log level set by SYSLOG_ACTION_CONSOLE_*,
+	// and savedConsoleLevel is the level restored by
+	// SYSLOG_ACTION_CONSOLE_ON, or 0 if the console is on. Records are never
+	// written to a console; these are only reported back to applications.
+	//
+	// +checklocks:mu
+	consoleLevel      int32
+	savedConsoleLevel int32
+
+	// warnedSyscalls contains the system call numbers for which a warning has
+	// been logged by warnUnimplementedSyscall.
+	//
+	// +checklocks:mu
+	warnedSyscalls map[uintptr]struct{}
+}
 
-	// Not initialized, create message.
+// init populates the log with its boot messages, which are fun messages for a
+// dmesg easter egg. now returns the current time in nanoseconds since boot.
+func (s *syslog) init(now func() int64) {
 	allMessages := []string{
 		"Synthesizing system calls...",
 		"Mounting deweydecimalfs...",
@@ -94,26 +188,237 @@ func (s *syslog) Log() []byte {
 		return m
 	}
 
-	const format = "<6>[%11.6f] %s\n"
+	const priority = linux.LOG_KERN<<3 | linux.LOGLEVEL_INFO
 
-	s.msg = append(s.msg, []byte(fmt.Sprintf(format, 0.0, "Starting gVisor..."))...)
-
-	time := 0.1
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.consoleLevel = syslogConsoleLevelDefault
+	s.logLocked(now(), priority, "Starting gVisor...")
 	for i := 0; i < 10; i++ {
-		time += rand.Float64() / 2
-		s.msg = append(s.msg, []byte(fmt.Sprintf(format, time, selectMessage()))...)
+		s.logLocked(now(), priority, selectMessage())
+	}
+	s.logLocked(now(), priority, "Setting up VFS...")
+	s.logLocked(now(), priority, "Setting up FUSE...")
+	s.logLocked(now(), priority, "Ready!")
+}
+
+// Log appends a record with the given timestamp, priority and text to the
+// log. Text longer than SyslogRecordMax bytes is truncated.
+func (s *syslog) Log(timestamp int64, priority uint32, text string) {
+	s.mu.Lock()
+	s.logLocked(timestamp, priority, text)
+	s.mu.Unlock()
+	s.queue.Notify(waiter.ReadableEvents)
+}
+
+// +checklocks:s.mu
+func (s *syslog) logLocked(timestamp int64, priority uint32, text string) {
+	if len(text) > SyslogRecordMax {
+		text = text[:SyslogRecordMax]
+	}
+	s.records = append(s.records, SyslogRecord{
+		Seq:       s.nextSeq,
+		Priority:  priority,
+		Timestamp: timestamp,
+		Text:      text,
+	})
+	s.nextSeq++
+	s.size += len(text) + syslogRecordOverhead
+	for s.size > SyslogBufLen {
+		s.size -= len(s.records[0].Text) + syslogRecordOverhead
+		s.records[0] = SyslogRecord{}
+		s.records = s.records[1:]
+	}
+}
+
+// +checklocks:s.mu
+func (s *syslog) firstSeqLocked() uint64 {
+	return s.nextSeq - uint64(len(s.records))
+}
+
+// Seqs returns the sequence numbers of the oldest record in the log, the
+// oldest record that has not been cleared, and the next record to be logged.
+func (s *syslog) Seqs() (first, clear, next uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first = s.firstSeqLocked()
+	return first, max(first, s.clearSeq), s.nextSeq
+}
+
+// Record returns the record with the given sequence number. It returns false
+// if the record has been discarded or has not yet been logged.
+func (s *syslog) Record(seq uint64) (SyslogRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first := s.firstSeqLocked()
+	if seq < first || seq >= s.nextSeq {
+		return SyslogRecord{}, false
+	}
+	return s.records[seq-first], true
+}
+
+// ReadAll returns the text of the most recent records that have not been
+// cleared, up to size bytes, as for SYSLOG_ACTION_READ_ALL. If clear is true,
+// all records are then cleared, as for SYSLOG_ACTION_READ_CLEAR.
+func (s *syslog) ReadAll(size int, clear bool) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first := s.firstSeqLocked()
+	records := s.records[max(first, s.clearSeq)-first:]
+	texts := make([]string, len(records))
+	total := 0
+	for i := range records {
+		texts[i] = records[i].SyslogText()
+		total += len(texts[i])
+	}
+	// Skip the oldest records until the remainder fits in size.
+	for len(texts) > 0 && total > size {
+		total -= len(texts[0])
+		texts = texts[1:]
+	}
+	buf := make([]byte, 0, total)
+	for _, text := range texts {
+		buf = append(buf, text...)
 	}
+	if clear {
+		s.clearSeq = s.nextSeq
+	}
+	return buf
+}
 
-	time += rand.Float64() / 2
-	s.msg = append(s.msg, []byte(fmt.Sprintf(format, time, "Setting up VFS..."))...)
-	time += rand.Float64() / 2
-	s.msg = append(s.msg, []byte(fmt.Sprintf(format, time, "Setting up FUSE..."))...)
+// Read consumes and returns up to size bytes of text from records that have
+// not already been consumed, as for SYSLOG_ACTION_READ and /proc/kmsg. Only
+// whole records are returned unless the first record is larger than size. Read
+// returns linuxerr.ErrWouldBlock if there are no records to consume.
+func (s *syslog) Read(size int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first := s.firstSeqLocked()
+	if s.readSeq < first {
+		s.readSeq = first
+		s.readPartial = 0
+	}
+	if s.readSeq == s.nextSeq {
+		return nil, linuxerr.ErrWouldBlock
+	}
+	var buf []byte
+	for s.readSeq < s.nextSeq {
+		text := s.records[s.readSeq-first].SyslogText()[s.readPartial:]
+		if len(buf)+len(text) <= size {
+			buf = append(buf, text...)
+			s.readSeq++
+			s.readPartial = 0
+			continue
+		}
+		if len(buf) == 0 {
+			buf = append(buf, text[:size]...)
+			s.readPartial += size
+		}
+		break
+	}
+	return buf, nil
+}
 
-	time += rand.Float64() / 2
-	s.msg = append(s.msg, []byte(fmt.Sprintf(format, time, "Ready!"))...)
+// SizeUnread returns the number of bytes of text that have not been consumed
+// by Read, as for SYSLOG_ACTION_SIZE_UNREAD.
+func (s *syslog) SizeUnread() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first := s.firstSeqLocked()
+	seq, partial := s.readSeq, s.readPartial
+	if seq < first {
+		seq, partial = first, 0
+	}
+	n := -partial
+	for ; seq < s.nextSeq; seq++ {
+		n += len(s.records[seq-first].SyslogText())
+	}
+	return n
+}
+
+// Clear clears all records in the log, as for SYSLOG_ACTION_CLEAR. Cleared
+// records are no longer returned by ReadAll, but may still be read by Read and
+// by readers of /dev/kmsg.
+func (s *syslog) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clearSeq = s.nextSeq
+}
 
-	// Return a copy.
-	o := make([]byte, len(s.msg))
-	copy(o, s.msg)
-	return o
+// ConsoleOff implements SYSLOG_ACTION_CONSOLE_OFF.
+func (s *syslog) ConsoleOff() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.savedConsoleLevel == 0 {
+		s.savedConsoleLevel = s.consoleLevel
+	}
+	s.consoleLevel = syslogConsoleLevelMin
+}
+
+// ConsoleOn implements SYSLOG_ACTION_CONSOLE_ON.
+func (s *syslog) ConsoleOn() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.savedConsoleLevel != 0 {
+		s.consoleLevel = s.savedConsoleLevel
+		s.savedConsoleLevel = 0
+	}
+}
+
+// SetConsoleLevel implements SYSLOG_ACTION_CONSOLE_LEVEL.
+func (s *syslog) SetConsoleLevel(level int32) error {
+	if level < 1 || level > 8 {
+		return linuxerr.EINVAL
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.consoleLevel = max(level, syslogConsoleLevelMin)
+	// Setting the level implicitly turns the console back on.
+	s.savedConsoleLevel = 0
+	return nil
+}
+
+// warnUnimplementedSyscall returns true if a warning about the unimplemented
+// system call sysno should be logged, which is the case the first time it is
+// called for each sysno.
+func (s *syslog) warnUnimplementedSyscall(sysno uintptr) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.warnedSyscalls[sysno]; ok {
+		return false
+	}
+	if s.warnedSyscalls == nil {
+		s.warnedSyscalls = make(map[uintptr]struct{})
+	}
+	s.warnedSyscalls[sysno] = struct{}{}
+	return true
+}
+
+// EventRegister registers e to be notified with waiter.ReadableEvents when
+// records are logged.
+func (s *syslog) EventRegister(e *waiter.Entry) {
+	s.queue.EventRegister(e)
+}
+
+// EventUnregister unregisters e.
+func (s *syslog) EventUnregister(e *waiter.Entry) {
+	s.queue.EventUnregister(e)
+}
+
+// MayReadSyslog returns true if t may perform privileged operations on the
+// kernel log, as in Linux's kernel/printk/printk.c:check_syslog_permissions().
+func (t *Task) MayReadSyslog() bool {
+	root := t.k.RootUserNamespace()
+	return t.HasCapabilityIn(linux.CAP_SYSLOG, root) || t.HasCapabilityIn(linux.CAP_SYS_ADMIN, root)
+}
+
+// SyslogActionRestricted returns true if the given syslog(2) action requires
+// the privileges checked by Task.MayReadSyslog. Reading the kernel log through
+// /dev/kmsg is checked as SYSLOG_ACTION_READ_ALL. This is analogous to Linux's
+// kernel/printk/printk.c:syslog_action_restricted().
+func (k *Kernel) SyslogActionRestricted(action int32) bool {
+	if k.DmesgRestrict.Load() != 0 {
+		return true
+	}
+	return action != linux.SYSLOG_ACTION_READ_ALL && action != linux.SYSLOG_ACTION_SIZE_BUFFER
 }
@@ -0,0 +1,135 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"strings"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+)
+
+func TestSyslogRecordText(t *testing.T) {
+	r := SyslogRecord{Priority: 6, Timestamp: 1234567891, Text: "a\nb"}
+	if got, want := r.SyslogText(), "<6>[    1.234567] a\n<6>[    1.234567] b\n"; got != want {
+		t.Errorf("SyslogText() = %q, want %q", got, want)
+	}
+}
+
+func TestSyslogReadAll(t *testing.T) {
+	var s syslog
+	s.Log(0, 6, "one")
+	s.Log(0, 6, "two")
+	s.Log(0, 6, "three")
+
+	line := func(text string) string { return "<6>[    0.000000] " + text + "\n" }
+	if got, want := string(s.ReadAll(SyslogBufLen, false)), line("one")+line("two")+line("three"); got != want {
+		t.Errorf("ReadAll() = %q, want %q", got, want)
+	}
+	// Only the most recent records that fit are returned.
+	if got, want := string(s.ReadAll(len(line("three"))+1, false)), line("three"); got != want {
+		t.Errorf("ReadAll(small) = %q, want %q", got, want)
+	}
+	if got := s.ReadAll(SyslogBufLen, true); len(got) == 0 {
+		t.Errorf("ReadAll(clear) returned nothing")
+	}
+	if got := s.ReadAll(SyslogBufLen, false); len(got) != 0 {
+		t.Errorf("ReadAll() after clear = %q, want empty", got)
+	}
+	s.Log(0, 6, "four")
+	if got, want := string(s.ReadAll(SyslogBufLen, false)), line("four"); got != want {
+		t.Errorf("ReadAll() = %q, want %q", got, want)
+	}
+}
+
+func TestSyslogRead(t *testing.T) {
+	var s syslog
+	if _, err := s.Read(100); !linuxerr.Equals(linuxerr.ErrWouldBlock, err) {
+		t.Fatalf("Read() on empty log got err %v, want ErrWouldBlock", err)
+	}
+	s.Log(0, 6, "hello")
+	s.Log(0, 6, "world")
+	want := "<6>[    0.000000] hello\n<6>[    0.000000] world\n"
+	if got := s.SizeUnread(); got != len(want) {
+		t.Errorf("SizeUnread() = %d, want %d", got, len(want))
+	}
+
+	// A record that does not fit is returned in parts.
+	var got strings.Builder
+	for {
+		buf, err := s.Read(10)
+		if err != nil {
+			if !linuxerr.Equals(linuxerr.ErrWouldBlock, err) {
+				t.Fatalf("Read() got err %v", err)
+			}
+			break
+		}
+		got.Write(buf)
+	}
+	if got.String() != want {
+		t.Errorf("Read() returned %q, want %q", got.String(), want)
+	}
+	if got := s.SizeUnread(); got != 0 {
+		t.Errorf("SizeUnread() = %d, want 0", got)
+	}
+}
+
+func TestSyslogDiscardsOldRecords(t *testing.T) {
+	var s syslog
+	text := strings.Repeat("x", SyslogRecordMax)
+	n := 2 * SyslogBufLen / SyslogRecordMax
+	for i := 0; i < n; i++ {
+		s.Log(0, 6, text)
+	}
+	first, _, next := s.Seqs()
+	if next != uint64(n) {
+		t.Errorf("next sequence number = %d, want %d", next, n)
+	}
+	if first == 0 {
+		t.Errorf("no records were discarded")
+	}
+	if _, ok := s.Record(first - 1); ok {
+		t.Errorf("Record(%d) succeeded for discarded record", first-1)
+	}
+	if r, ok := s.Record(first); !ok || r.Seq != first {
+		t.Errorf("Record(%d) = %+v, %t", first, r, ok)
+	}
+	if size := int(next-first) * (SyslogRecordMax + syslogRecordOverhead); size > SyslogBufLen {
+		t.Errorf("log holds %d bytes, more than %d", size, SyslogBufLen)
+	}
+}
+
+func TestSyslogActionRestricted(t *testing.T) {
+	var k Kernel
+	for _, tc := range []struct {
+		restrict int32
+		action   int32
+		want     bool
+	}{
+		{restrict: 1, action: linux.SYSLOG_ACTION_READ_ALL, want: true},
+		{restrict: 1, action: linux.SYSLOG_ACTION_SIZE_BUFFER, want: true},
+		{restrict: 1, action: linux.SYSLOG_ACTION_CLEAR, want: true},
+		{restrict: 0, action: linux.SYSLOG_ACTION_READ_ALL, want: false},
+		{restrict: 0, action: linux.SYSLOG_ACTION_SIZE_BUFFER, want: false},
+		{restrict: 0, action: linux.SYSLOG_ACTION_READ, want: true},
+		{restrict: 0, action: linux.SYSLOG_ACTION_CLEAR, want: true},
+	} {
+		k.DmesgRestrict.Store(tc.restrict)
+		if got := k.SyslogActionRestricted(tc.action); got != tc.want {
+			t.Errorf("SyslogActionRestricted(%d) with dmesg_restrict=%d = %t, want %t", tc.action, tc.restrict, got, tc.want)
+		}
+	}
+}
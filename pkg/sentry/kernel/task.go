@@ -18,6 +18,7 @@ import (
 	gocontext "context"
 	"runtime/trace"
 	"sync/atomic"
+	"time"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
@@ -29,6 +30,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/kernel/futex"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/sched"
 	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
 	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
@@ -98,6 +100,12 @@ type Task struct {
 	// haveSyscallReturn is exclusive to the task goroutine.
 	haveSyscallReturn bool
 
+	// syscallNo is one more than the number of the syscall being executed by
+	// the task goroutine, or 0 if the task goroutine isn't executing a
+	// syscall. syscallNo is owned by the task goroutine, but is read by
+	// Kernel.TaskGoroutineAnnotations.
+	syscallNo atomicbitops.Int64 `state:"nosave"`
+
 	// interruptChan is notified whenever the task goroutine is interrupted
 	// (usually by a pending signal). interruptChan is effectively a condition
 	// variable that can be used in select statements.
@@ -121,6 +129,10 @@ type Task struct {
 	// owned by the task goroutine.
 	yieldCount atomicbitops.Uint64
 
+	// prevCPUTime is the CPU time last reported for the task by
+	// AdjustedCPUStats.
+	prevCPUTime usage.PrevCPUTime
+
 	// pendingSignals is the set of pending signals that may be handled only by
 	// this task.
 	//
@@ -466,6 +478,10 @@ type Task struct {
 	// seccomp is owned by the task goroutine.
 	seccomp atomic.Pointer[taskSeccomp] `state:".(*taskSeccomp)"`
 
+	// syscallPolicy is the syscall policy of the task's container, if any.
+	// It's immutable.
+	syscallPolicy *SyscallPolicy
+
 	// If cleartid is non-zero, treat it as a pointer to a ThreadID in the
 	// task's virtual address space; when the task exits, set the pointed-to
 	// ThreadID to 0, and wake any futex waiters.
@@ -482,10 +498,24 @@ type Task struct {
 	// allowedCPUMask is protected by mu.
 	allowedCPUMask sched.CPUSet
 
-	// cpu is the fake cpu number returned by getcpu(2). cpu is ignored
-	// entirely if Kernel.useHostCores is true.
+	// cpu is the virtual CPU number returned by getcpu(2). While the task
+	// goroutine is running, it is the virtual CPU that the task is running
+	// on; otherwise it is the virtual CPU that the task last ran on. cpu is
+	// ignored entirely if Kernel.useHostCores is true.
 	cpu atomicbitops.Int32
 
+	// runningCPU is the virtual CPU accounted for in Kernel.cpuTasks while
+	// the task goroutine is running. It differs from cpu only if cpu has been
+	// changed by SetCPUMask since the task goroutine last became runnable.
+	//
+	// runningCPU is exclusive to the task goroutine.
+	runningCPU int32 `state:"nosave"`
+
+	// allowedCPUs is allowedCPUMask, which may be loaded without holding mu
+	// when choosing a virtual CPU for the task. The CPUSet it points to is
+	// immutable.
+	allowedCPUs atomic.Pointer[sched.CPUSet] `state:"nosave"`
+
 	// This is used to keep track of changes made to a process' priority/niceness.
 	// It is mostly used to provide some reasonable return value from
 	// getpriority(2) after a call to setpriority(2) has been made.
@@ -560,6 +590,10 @@ type Task struct {
 	// copyScratchBuffer is exclusive to the task goroutine.
 	copyScratchBuffer [copyScratchBufferLen]byte `state:"nosave"`
 
+	// pmaCache caches translations of application memory recently used for
+	// I/O by the task goroutine.
+	pmaCache mm.PMACache `state:"nosave"`
+
 	// blockingTimer is used for blocking timeouts. blockingTimerChan is the
 	// channel that is sent to when blockingTimer fires.
 	//
@@ -623,6 +657,12 @@ var (
 	// handle.
 	faultCounter = metric.SentryProfiling.MustCreateNewUint64Metric(
 		"/task/faults", false, "The number of faults the sentry has handled.")
+
+	// syscallLatency is a metric that tracks how long the sentry takes to
+	// execute syscalls, including time spent blocked.
+	syscallLatency = metric.SentryProfiling.MustCreateNewTimerMetric("/task/syscall_latency",
+		metric.NewDurationBucketer(20, time.Microsecond, 10*time.Second),
+		"Duration of syscalls executed by the sentry for the user.")
 )
 
 func (t *Task) savePtraceTracer() *Task {
@@ -654,6 +694,8 @@ func (t *Task) afterLoad(gocontext.Context) {
 	}
 	t.endStopCond.L = &t.tg.signalHandlers.mu
 	t.rseqPreempted = true
+	allowed := t.allowedCPUMask
+	t.allowedCPUs.Store(&allowed)
 	t.futexWaiter = futex.NewWaiter()
 	t.p = t.k.Platform.NewContext(t.AsyncContext())
 }
@@ -675,6 +717,11 @@ func (t *Task) CopyScratchBuffer(size int) []byte {
 	return t.copyScratchBuffer[:size]
 }
 
+// PMACache implements mm.PMACacheProvider.PMACache.
+func (t *Task) PMACache() *mm.PMACache {
+	return &t.pmaCache
+}
+
 // FutexWaiter returns the Task's futex.Waiter.
 func (t *Task) FutexWaiter() *futex.Waiter {
 	return t.futexWaiter
@@ -834,7 +881,7 @@ func (t *Task) OOMScoreAdj() int32 {
 // SetOOMScoreAdj sets the task's thread group's OOM score adjustment. The
 // value should be between -1000 and 1000 inclusive.
 func (t *Task) SetOOMScoreAdj(adj int32) error {
-	if adj > 1000 || adj < -1000 {
+	if adj > linux.OOM_SCORE_ADJ_MAX || adj < linux.OOM_SCORE_ADJ_MIN {
 		return linuxerr.EINVAL
 	}
 	t.tg.oomScoreAdj.Store(adj)
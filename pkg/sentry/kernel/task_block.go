@@ -144,6 +144,8 @@ func (t *Task) block(C <-chan struct{}, timerChan <-chan struct{}) error {
 	default:
 	}
 
+	t.ftraceSchedSwitch()
+
 	// Deactivate our address space, we don't need it.
 	t.prepareSleep()
 	defer t.completeSleep()
@@ -21,7 +21,10 @@ import (
 	"strings"
 
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
 )
 
 // EnterInitialCgroups moves t into an initial set of cgroups.
@@ -214,6 +217,76 @@ func (t *Task) MigrateCgroup(dst Cgroup) error {
 	return nil
 }
 
+// cgroupFromFD returns the cgroup of the cgroupfs directory that fd refers
+// to, for clone3(2)'s CLONE_INTO_CGROUP. As when writing to the cgroup's
+// cgroup.procs file, t must have write permission on that file. The returned
+// cgroup has an extra ref that's transferred to the caller.
+func (t *Task) cgroupFromFD(fd int32) (Cgroup, error) {
+	file := t.GetFile(fd)
+	if file == nil {
+		return Cgroup{}, linuxerr.EBADF
+	}
+	defer file.DecRef(t)
+	d, ok := file.Dentry().Impl().(*kernfs.Dentry)
+	if !ok {
+		return Cgroup{}, linuxerr.EBADF
+	}
+	impl, ok := d.Inode().(CgroupImpl)
+	if !ok {
+		return Cgroup{}, linuxerr.EBADF
+	}
+	procs, err := d.WalkDentryTree(t, t.k.VFS(), fspath.Parse("cgroup.procs"))
+	if err != nil {
+		return Cgroup{}, err
+	}
+	defer procs.DecRef(t)
+	if err := procs.Inode().CheckPermissions(t, t.Credentials(), vfs.MayWrite); err != nil {
+		return Cgroup{}, err
+	}
+	d.IncRef()
+	return Cgroup{
+		Dentry:     d,
+		CgroupImpl: impl,
+	}, nil
+}
+
+// cgroupsWith returns t's cgroups, with dst replacing the cgroup in dst's
+// hierarchy. Each returned cgroup has an extra ref that's transferred to the
+// caller.
+func (t *Task) cgroupsWith(dst Cgroup) map[Cgroup]struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cgs := make(map[Cgroup]struct{}, len(t.cgroups)+1)
+	for c := range t.cgroups {
+		if c.HierarchyID() != dst.HierarchyID() {
+			c.IncRef()
+			cgs[c] = struct{}{}
+		}
+	}
+	dst.IncRef()
+	cgs[dst] = struct{}{}
+	return cgs
+}
+
+// chargeCgroupsFor charges the cgroup in cgs that has a controller of type ctl
+// on behalf of target. Returns the cgroup that's charged if any. Returned
+// cgroup has an extra ref that's transferred to the caller.
+func chargeCgroupsFor(cgs map[Cgroup]struct{}, target *Task, ctl CgroupControllerType, res CgroupResourceType, value int64) (bool, Cgroup, error) {
+	for c := range cgs {
+		for _, cc := range c.Controllers() {
+			if cc.Type() != ctl {
+				continue
+			}
+			if err := c.Charge(target, c.Dentry, ctl, res, value); err != nil {
+				return false, c, err
+			}
+			c.IncRef()
+			return true, c, nil
+		}
+	}
+	return false, Cgroup{}, nil
+}
+
 // TaskCgroupEntry represents a line in /proc/<pid>/cgroup, and is used to
 // format a cgroup for display.
 type TaskCgroupEntry struct {
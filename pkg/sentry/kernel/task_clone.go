@@ -20,9 +20,11 @@ import (
 	"gvisor.dev/gvisor/pkg/cleanup"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/kernfs"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/nsfs"
 	"gvisor.dev/gvisor/pkg/sentry/inet"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
@@ -30,14 +32,14 @@ import (
 )
 
 // SupportedCloneFlags is the bitwise OR of all the supported flags for clone.
-// TODO(b/290826530): Implement CLONE_INTO_CGROUP when cgroups v2 is
-// implemented.
+// CLONE_INTO_CGROUP is supported for the cgroupfs hierarchies of the sentry.
 const SupportedCloneFlags = linux.CLONE_VM | linux.CLONE_FS | linux.CLONE_FILES | linux.CLONE_SYSVSEM |
 	linux.CLONE_THREAD | linux.CLONE_SIGHAND | linux.CLONE_CHILD_SETTID | linux.CLONE_NEWPID |
 	linux.CLONE_CHILD_CLEARTID | linux.CLONE_CHILD_SETTID | linux.CLONE_PARENT |
 	linux.CLONE_PARENT_SETTID | linux.CLONE_SETTLS | linux.CLONE_NEWUSER | linux.CLONE_NEWUTS |
 	linux.CLONE_NEWIPC | linux.CLONE_NEWNET | linux.CLONE_PTRACE | linux.CLONE_UNTRACED |
-	linux.CLONE_IO | linux.CLONE_VFORK | linux.CLONE_DETACHED | linux.CLONE_NEWNS
+	linux.CLONE_IO | linux.CLONE_VFORK | linux.CLONE_DETACHED | linux.CLONE_NEWNS |
+	linux.CLONE_CLEAR_SIGHAND | linux.CLONE_INTO_CGROUP
 
 // Clone implements the clone(2) syscall and returns the thread ID of the new
 // task in t's PID namespace. Clone may return both a non-zero thread ID and a
@@ -55,8 +57,9 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 	if args.Flags&(linux.CLONE_SIGHAND|linux.CLONE_VM) == linux.CLONE_SIGHAND {
 		return 0, nil, linuxerr.EINVAL
 	}
-	if args.SetTID != 0 {
-		return 0, nil, linuxerr.ENOTSUP
+	// Shared signal handlers can't be reset for the new task alone.
+	if args.Flags&(linux.CLONE_SIGHAND|linux.CLONE_CLEAR_SIGHAND) == linux.CLONE_SIGHAND|linux.CLONE_CLEAR_SIGHAND {
+		return 0, nil, linuxerr.EINVAL
 	}
 	// In order for the behavior of thread-group-directed signals to be sane,
 	// all tasks in a thread group must share signal handlers.
@@ -110,7 +113,7 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 		if t.IsChrooted() {
 			return 0, nil, linuxerr.EPERM
 		}
-		userns, err = creds.NewChildUserNamespace()
+		userns, err = t.newChildUserNamespace(creds)
 		if err != nil {
 			return 0, nil, err
 		}
@@ -119,6 +122,26 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 		return 0, nil, linuxerr.EPERM
 	}
 
+	setTIDs, err := t.copyInSetTIDs(args, userns)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var cgroups map[Cgroup]struct{}
+	if args.Flags&linux.CLONE_INTO_CGROUP != 0 {
+		cg, err := t.cgroupFromFD(int32(args.Cgroup))
+		if err != nil {
+			return 0, nil, err
+		}
+		cgroups = t.cgroupsWith(cg)
+		cg.decRef()
+		defer func() {
+			for c := range cgroups {
+				c.decRef()
+			}
+		}()
+	}
+
 	cu := cleanup.Make(func() {})
 	defer cu.Clean()
 
@@ -232,7 +255,11 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 	rseqSignature := uint32(0)
 	if args.Flags&linux.CLONE_THREAD == 0 {
 		sh := t.tg.signalHandlers
-		if args.Flags&linux.CLONE_SIGHAND == 0 {
+		if args.Flags&linux.CLONE_CLEAR_SIGHAND != 0 {
+			// Handled signals are reset to their default actions, as on
+			// execve.
+			sh = sh.CopyForExec()
+		} else if args.Flags&linux.CLONE_SIGHAND == 0 {
 			sh = sh.Fork()
 		}
 		tg = t.k.NewThreadGroup(pidns, sh, linux.Signal(args.ExitSignal), tg.limits.GetCopy())
@@ -266,6 +293,8 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 		UserCounters:     uc,
 		SessionKeyring:   sessionKeyring,
 		Origin:           t.Origin,
+		InitialCgroups:   cgroups,
+		SetTIDs:          setTIDs,
 	}
 	if args.Flags&linux.CLONE_THREAD == 0 {
 		cfg.Parent = t
@@ -301,6 +330,7 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 	// nt that it must receive before its task goroutine starts running.
 	tid := nt.k.tasks.Root.IDOfTask(nt)
 	defer nt.Start(tid)
+	t.ftraceProcessFork(nt, tid)
 
 	if seccheck.Global.Enabled(seccheck.PointClone) {
 		mask, info := getCloneSeccheckInfo(t, nt, args.Flags)
@@ -367,6 +397,53 @@ func (t *Task) Clone(args *linux.CloneArgs) (ThreadID, *SyscallControl, error) {
 	return ntid, nil, nil
 }
 
+// copyInSetTIDs returns the TIDs that a new task created by Clone with args
+// must have in its PID namespace and its ancestors, starting with its own, as
+// given by clone3(2)'s set_tid array. userns is the user namespace that owns
+// a PID namespace created by the clone.
+func (t *Task) copyInSetTIDs(args *linux.CloneArgs, userns *auth.UserNamespace) ([]ThreadID, error) {
+	if args.SetTIDSize == 0 {
+		return nil, nil
+	}
+	tids := make([]int32, args.SetTIDSize)
+	if _, err := primitive.CopyInt32SliceIn(t, hostarch.Addr(args.SetTID), tids); err != nil {
+		return nil, err
+	}
+
+	// Collect the owners of the PID namespaces that the new task will be
+	// in, from the innermost outward.
+	var owners []*auth.UserNamespace
+	ns := t.tg.pidns
+	if t.childPIDNamespace != nil {
+		ns = t.childPIDNamespace
+	} else if args.Flags&linux.CLONE_NEWPID != 0 {
+		owners = append(owners, userns)
+	}
+	for ; ns != nil; ns = ns.parent {
+		owners = append(owners, ns.userns)
+	}
+	if len(tids) > len(owners) {
+		return nil, linuxerr.EINVAL
+	}
+
+	creds := t.Credentials()
+	setTIDs := make([]ThreadID, len(tids))
+	for i := range tids {
+		tid := ThreadID(tids[i])
+		if tid < initTID || tid > TasksLimit {
+			return nil, linuxerr.EINVAL
+		}
+		// "Setting the PID of a process requires CAP_SYS_ADMIN or (since
+		// Linux 5.9) CAP_CHECKPOINT_RESTORE inside the owning user
+		// namespace of the target PID namespace." - clone(2)
+		if !creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, owners[i]) && !creds.HasCapabilityIn(linux.CAP_CHECKPOINT_RESTORE, owners[i]) {
+			return nil, linuxerr.EPERM
+		}
+		setTIDs[i] = tid
+	}
+	return setTIDs, nil
+}
+
 func getCloneSeccheckInfo(t, nt *Task, flags uint64) (seccheck.FieldSet, *pb.CloneInfo) {
 	fields := seccheck.Global.GetFieldSet(seccheck.PointClone)
 	var cwd string
@@ -469,83 +546,204 @@ func (t *Task) Setns(fd *vfs.FileDescription, flags int32) error {
 		if flags != 0 && flags != linux.CLONE_NEWNET {
 			return linuxerr.EINVAL
 		}
-		if !t.HasCapabilityIn(linux.CAP_SYS_ADMIN, ns.UserNamespace()) ||
-			!t.Credentials().HasCapability(linux.CAP_SYS_ADMIN) {
+		if !t.canSetnsIn(ns.UserNamespace()) {
 			return linuxerr.EPERM
 		}
-		oldNS := t.NetworkNamespace()
-		ns.IncRef()
-		t.mu.Lock()
-		t.netns = ns
-		t.mu.Unlock()
-		oldNS.DecRef(t)
+		t.setNetworkNamespace(ns)
 		return nil
 	case *IPCNamespace:
 		if flags != 0 && flags != linux.CLONE_NEWIPC {
 			return linuxerr.EINVAL
 		}
-		if !t.HasCapabilityIn(linux.CAP_SYS_ADMIN, ns.UserNamespace()) ||
-			!t.Credentials().HasCapability(linux.CAP_SYS_ADMIN) {
+		if !t.canSetnsIn(ns.UserNamespace()) {
 			return linuxerr.EPERM
 		}
-		oldNS := t.IPCNamespace()
-		ns.IncRef()
-		t.mu.Lock()
-		t.ipcns = ns
-		t.mu.Unlock()
-		oldNS.DecRef(t)
+		t.setIPCNamespace(ns)
 		return nil
 	case *vfs.MountNamespace:
 		if flags != 0 && flags != linux.CLONE_NEWNS {
 			return linuxerr.EINVAL
 		}
-		if !t.HasCapabilityIn(linux.CAP_SYS_ADMIN, ns.Owner) ||
-			!t.Credentials().HasCapability(linux.CAP_SYS_CHROOT) ||
-			!t.Credentials().HasCapability(linux.CAP_SYS_ADMIN) {
-			return linuxerr.EPERM
-		}
-		oldFSContext := t.fsContext
-		// The current task has to be an exclusive owner of its fs context.
-		if oldFSContext.ReadRefs() != 1 {
-			return linuxerr.EINVAL
+		if err := t.checkSetMountNamespace(ns); err != nil {
+			return err
 		}
-		fsContext := oldFSContext.Fork()
-		fsContext.root.DecRef(t)
-		fsContext.cwd.DecRef(t)
-		vd := ns.Root(t)
-		fsContext.root = vd
-		vd.IncRef()
-		fsContext.cwd = vd
-
-		oldNS := t.mountNamespace
-		ns.IncRef()
-		t.mu.Lock()
-		t.mountNamespace = ns
-		t.fsContext = fsContext
-		t.mu.Unlock()
-		oldNS.DecRef(t)
-		oldFSContext.DecRef(t)
+		t.setMountNamespace(ns)
 		return nil
 	case *UTSNamespace:
 		if flags != 0 && flags != linux.CLONE_NEWUTS {
 			return linuxerr.EINVAL
 		}
-		if !t.HasCapabilityIn(linux.CAP_SYS_ADMIN, ns.UserNamespace()) ||
-			!t.Credentials().HasCapability(linux.CAP_SYS_ADMIN) {
+		if !t.canSetnsIn(ns.UserNamespace()) {
 			return linuxerr.EPERM
 		}
-		oldNS := t.UTSNamespace()
-		ns.IncRef()
-		t.mu.Lock()
-		t.utsns = ns
-		t.mu.Unlock()
-		oldNS.DecRef(t)
+		t.setUTSNamespace(ns)
 		return nil
 	default:
 		return linuxerr.EINVAL
 	}
 }
 
+// SetnsTask reassociates t with the namespaces of target selected by flags,
+// as setns(2) does when given a pidfd. Either all namespaces are changed or
+// none are.
+//
+// Namespace types the sentry has a single instance of (cgroup), or that
+// cannot be changed by setns in the sentry (user and PID), can only be
+// "joined" if they are already shared with target.
+func (t *Task) SetnsTask(target *Task, flags int32) error {
+	const supportedFlags = linux.CLONE_NEWNS | linux.CLONE_NEWCGROUP | linux.CLONE_NEWUTS |
+		linux.CLONE_NEWIPC | linux.CLONE_NEWUSER | linux.CLONE_NEWPID | linux.CLONE_NEWNET
+	if flags == 0 || flags&^supportedFlags != 0 {
+		return linuxerr.EINVAL
+	}
+
+	if flags&linux.CLONE_NEWUSER != 0 && target.UserNamespace() != t.UserNamespace() {
+		return linuxerr.EINVAL
+	}
+	if flags&linux.CLONE_NEWPID != 0 && target.PIDNamespace() != t.PIDNamespace() {
+		return linuxerr.EINVAL
+	}
+
+	// Take references on all namespaces before checking permissions, so that
+	// the checks and the switch see the same namespaces.
+	var (
+		netns  *inet.Namespace
+		ipcns  *IPCNamespace
+		mntns  *vfs.MountNamespace
+		utsns  *UTSNamespace
+		exited bool
+	)
+	if flags&linux.CLONE_NEWNET != 0 {
+		if netns = target.GetNetworkNamespace(); netns == nil {
+			exited = true
+		} else {
+			defer netns.DecRef(t)
+		}
+	}
+	if flags&linux.CLONE_NEWIPC != 0 {
+		if ipcns = target.GetIPCNamespace(); ipcns == nil {
+			exited = true
+		} else {
+			defer ipcns.DecRef(t)
+		}
+	}
+	if flags&linux.CLONE_NEWNS != 0 {
+		if mntns = target.GetMountNamespace(); mntns == nil {
+			exited = true
+		} else {
+			defer mntns.DecRef(t)
+		}
+	}
+	if flags&linux.CLONE_NEWUTS != 0 {
+		if utsns = target.GetUTSNamespace(); utsns == nil {
+			exited = true
+		} else {
+			defer utsns.DecRef(t)
+		}
+	}
+	if exited {
+		return linuxerr.ESRCH
+	}
+
+	if netns != nil && !t.canSetnsIn(netns.UserNamespace()) {
+		return linuxerr.EPERM
+	}
+	if ipcns != nil && !t.canSetnsIn(ipcns.UserNamespace()) {
+		return linuxerr.EPERM
+	}
+	if utsns != nil && !t.canSetnsIn(utsns.UserNamespace()) {
+		return linuxerr.EPERM
+	}
+	if mntns != nil {
+		if err := t.checkSetMountNamespace(mntns); err != nil {
+			return err
+		}
+	}
+
+	if netns != nil {
+		t.setNetworkNamespace(netns)
+	}
+	if ipcns != nil {
+		t.setIPCNamespace(ipcns)
+	}
+	if utsns != nil {
+		t.setUTSNamespace(utsns)
+	}
+	if mntns != nil {
+		t.setMountNamespace(mntns)
+	}
+	return nil
+}
+
+// canSetnsIn returns true if t has the capabilities required to join a
+// namespace owned by userns.
+func (t *Task) canSetnsIn(userns *auth.UserNamespace) bool {
+	return t.HasCapabilityIn(linux.CAP_SYS_ADMIN, userns) &&
+		t.Credentials().HasCapability(linux.CAP_SYS_ADMIN)
+}
+
+// checkSetMountNamespace returns an error if t can't join ns.
+func (t *Task) checkSetMountNamespace(ns *vfs.MountNamespace) error {
+	if !t.HasCapabilityIn(linux.CAP_SYS_ADMIN, ns.Owner) ||
+		!t.Credentials().HasCapability(linux.CAP_SYS_CHROOT) ||
+		!t.Credentials().HasCapability(linux.CAP_SYS_ADMIN) {
+		return linuxerr.EPERM
+	}
+	// The current task has to be an exclusive owner of its fs context.
+	if t.fsContext.ReadRefs() != 1 {
+		return linuxerr.EINVAL
+	}
+	return nil
+}
+
+func (t *Task) setNetworkNamespace(ns *inet.Namespace) {
+	oldNS := t.NetworkNamespace()
+	ns.IncRef()
+	t.mu.Lock()
+	t.netns = ns
+	t.mu.Unlock()
+	oldNS.DecRef(t)
+}
+
+func (t *Task) setIPCNamespace(ns *IPCNamespace) {
+	oldNS := t.IPCNamespace()
+	ns.IncRef()
+	t.mu.Lock()
+	t.ipcns = ns
+	t.mu.Unlock()
+	oldNS.DecRef(t)
+}
+
+func (t *Task) setUTSNamespace(ns *UTSNamespace) {
+	oldNS := t.UTSNamespace()
+	ns.IncRef()
+	t.mu.Lock()
+	t.utsns = ns
+	t.mu.Unlock()
+	oldNS.DecRef(t)
+}
+
+// Preconditions: t.checkSetMountNamespace(ns) == nil.
+func (t *Task) setMountNamespace(ns *vfs.MountNamespace) {
+	oldFSContext := t.fsContext
+	fsContext := oldFSContext.Fork()
+	fsContext.root.DecRef(t)
+	fsContext.cwd.DecRef(t)
+	vd := ns.Root(t)
+	fsContext.root = vd
+	vd.IncRef()
+	fsContext.cwd = vd
+
+	oldNS := t.mountNamespace
+	ns.IncRef()
+	t.mu.Lock()
+	t.mountNamespace = ns
+	t.fsContext = fsContext
+	t.mu.Unlock()
+	oldNS.DecRef(t)
+	oldFSContext.DecRef(t)
+}
+
 // Unshare changes the set of resources t shares with other tasks, as specified
 // by flags.
 //
@@ -589,7 +787,7 @@ func (t *Task) Unshare(flags int32) error {
 		if t.IsChrooted() {
 			return linuxerr.EPERM
 		}
-		newUserNS, err := creds.NewChildUserNamespace()
+		newUserNS, err := t.newChildUserNamespace(creds)
 		if err != nil {
 			return err
 		}
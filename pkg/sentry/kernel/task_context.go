@@ -21,6 +21,7 @@ import (
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/cpuid"
 	"gvisor.dev/gvisor/pkg/devutil"
+	"gvisor.dev/gvisor/pkg/rand"
 	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/ipc"
@@ -29,6 +30,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	"gvisor.dev/gvisor/pkg/sentry/unimpl"
 	"gvisor.dev/gvisor/pkg/sentry/uniqueid"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
@@ -87,6 +89,8 @@ func (t *Task) contextValue(key any, isTaskGoroutine bool) any {
 		return ipcns
 	case CtxTask:
 		return t
+	case rand.CtxReader:
+		return t.k.deterministicRand
 	case auth.CtxCredentials:
 		return t.creds.Load()
 	case auth.CtxThreadGroupID:
@@ -124,6 +128,8 @@ func (t *Task) contextValue(key any, isTaskGoroutine bool) any {
 		return t.k.mf
 	case platform.CtxPlatform:
 		return t.k
+	case seccheck.CtxFilterTarget:
+		return t
 	case shm.CtxDeviceID:
 		return t.k.sysVShmDevID
 	case uniqueid.CtxGlobalUniqueID:
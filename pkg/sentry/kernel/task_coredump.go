@@ -0,0 +1,618 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/pipefs"
+	"gvisor.dev/gvisor/pkg/sentry/limits"
+	"gvisor.dev/gvisor/pkg/sentry/mm"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	// defaultCorePattern is the initial value of
+	// /proc/sys/kernel/core_pattern.
+	defaultCorePattern = "core"
+
+	// maxCorePatternLen is the maximum length of
+	// /proc/sys/kernel/core_pattern, including the terminating NUL. From
+	// Linux's include/linux/binfmts.h:CORENAME_MAX_SIZE.
+	maxCorePatternLen = 128
+
+	// coreDumpChunkSize is the amount of application memory copied into the
+	// core dump at a time.
+	coreDumpChunkSize = 16 * hostarch.PageSize
+
+	// prstatusHeaderSize is the size of the fields of struct elf_prstatus
+	// that precede pr_reg.
+	prstatusHeaderSize = 112
+
+	// prpsinfoSize is sizeof(struct elf_prpsinfo) on 64-bit architectures.
+	prpsinfoSize = 136
+)
+
+// coreDumpAndExit initiates a group exit of t's thread group due to the fatal
+// signal described by info, and writes a core dump of the thread group if
+// possible.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) coreDumpAndExit(info *linux.SignalInfo) {
+	ws := linux.WaitStatusTerminationSignal(linux.Signal(info.Signo))
+	t.tg.signalHandlers.mu.Lock()
+	// As in Linux's fs/coredump.c:zap_threads(), no core dump is produced if
+	// the thread group is already exiting.
+	initiator := !t.tg.exiting && t.tg.execing == nil
+	t.prepareGroupExitLocked(ws)
+	t.tg.signalHandlers.mu.Unlock()
+	if !initiator {
+		return
+	}
+
+	if err := t.dumpCore(info); err != nil {
+		t.Debugf("Core dump not written: %v", err)
+		return
+	}
+	t.tg.signalHandlers.mu.Lock()
+	t.tg.exitStatus = ws.WithCoreDump()
+	t.exitStatus = t.tg.exitStatus
+	t.tg.signalHandlers.mu.Unlock()
+}
+
+// dumpCore writes a core dump of t's thread group to the destination
+// specified by /proc/sys/kernel/core_pattern, as in Linux's
+// fs/coredump.c:do_coredump().
+//
+// Other tasks in the thread group have already been killed, and their
+// register state is not included in the core dump.
+func (t *Task) dumpCore(info *linux.SignalInfo) error {
+	if t.MemoryManager() == nil || t.MemoryManager().Dumpability() == mm.NotDumpable {
+		return linuxerr.EPERM
+	}
+	pattern, cid := t.k.corePatternAndContainer()
+	if pattern == "" {
+		return linuxerr.ENOENT
+	}
+	rlimit := t.tg.Limits().Get(limits.Core).Cur
+	spec := func(c byte) string {
+		return t.corePatternSpecifier(c, info, rlimit)
+	}
+
+	var (
+		fd    *vfs.FileDescription
+		limit uint64
+		err   error
+	)
+	if argv, ok := corePatternArgv(pattern); ok {
+		// "Since kernel 2.6.19, Linux supports an alternate syntax for the
+		// /proc/sys/kernel/core_pattern file. If the first character of this
+		// file is a pipe symbol (|), then the remainder of the line is
+		// interpreted as the command-line for a user-space program (or
+		// script) that is to be executed." - core(5)
+		//
+		// RLIMIT_CORE is not enforced for pipes, except that a limit of 1
+		// disables core dumps entirely.
+		if rlimit == 1 {
+			return linuxerr.EFBIG
+		}
+		// core_pattern is global to the sandbox, but the program runs in t's
+		// container, so containers can't run programs in other containers.
+		if cid != t.ContainerID() {
+			return linuxerr.EPERM
+		}
+		limit = limits.Infinity
+		for i := range argv {
+			argv[i] = expandCorePattern(argv[i], spec)
+		}
+		fd, err = t.coreDumpPipe(argv)
+	} else {
+		// Linux's binfmt_elf requires RLIMIT_CORE to be at least
+		// ELF_EXEC_PAGESIZE.
+		if rlimit < hostarch.PageSize {
+			return linuxerr.EFBIG
+		}
+		limit = rlimit
+		fd, err = t.coreDumpFile(expandCorePattern(pattern, spec))
+	}
+	if err != nil {
+		return err
+	}
+	defer fd.DecRef(t)
+
+	w := &coreDumpWriter{
+		t:     t,
+		fd:    fd,
+		limit: limit,
+	}
+	return t.writeCoreDump(w, info)
+}
+
+// corePatternArgv returns the command line specified by a core_pattern that
+// pipes core dumps to a program, prior to template expansion. If pattern does
+// not specify a pipe, corePatternArgv returns false.
+func corePatternArgv(pattern string) ([]string, bool) {
+	if !strings.HasPrefix(pattern, "|") {
+		return nil, false
+	}
+	return strings.Fields(pattern[1:]), true
+}
+
+// expandCorePattern expands the '%' specifiers in the core_pattern template
+// s. spec returns the expansion of specifiers other than "%%"; unknown
+// specifiers expand to the empty string, as in Linux's
+// fs/coredump.c:format_corename().
+func expandCorePattern(s string, spec func(c byte) string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i == len(s) {
+			break
+		}
+		if s[i] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		b.WriteString(spec(s[i]))
+	}
+	return b.String()
+}
+
+// corePatternSpecifier returns the expansion of the core_pattern specifier c
+// for a core dump of t triggered by info, where rlimit is t's RLIMIT_CORE.
+func (t *Task) corePatternSpecifier(c byte, info *linux.SignalInfo, rlimit uint64) string {
+	// Slashes in strings that are not controlled by the administrator are
+	// replaced, as in Linux's fs/coredump.c:cn_esc_printf().
+	escape := func(s string) string {
+		return strings.ReplaceAll(s, "/", "!")
+	}
+	creds := t.Credentials()
+	switch c {
+	case 'p':
+		return strconv.Itoa(int(t.tg.pidns.IDOfThreadGroup(t.tg)))
+	case 'P':
+		return strconv.Itoa(int(t.k.tasks.Root.IDOfThreadGroup(t.tg)))
+	case 'i':
+		return strconv.Itoa(int(t.tg.pidns.IDOfTask(t)))
+	case 'I':
+		return strconv.Itoa(int(t.k.tasks.Root.IDOfTask(t)))
+	case 'u':
+		return strconv.FormatUint(uint64(creds.RealKUID.In(creds.UserNamespace).OrOverflow()), 10)
+	case 'g':
+		return strconv.FormatUint(uint64(creds.RealKGID.In(creds.UserNamespace).OrOverflow()), 10)
+	case 'd':
+		return strconv.Itoa(int(t.MemoryManager().Dumpability()))
+	case 's':
+		return strconv.Itoa(int(info.Signo))
+	case 't':
+		return strconv.FormatInt(t.k.RealtimeClock().Now().Seconds(), 10)
+	case 'h':
+		return escape(t.UTSNamespace().HostName())
+	case 'e':
+		return escape(t.Name())
+	case 'E':
+		exe := t.MemoryManager().Executable()
+		if exe == nil {
+			return ""
+		}
+		defer exe.DecRef(t)
+		root := t.FSContext().RootDirectory()
+		defer root.DecRef(t)
+		name, _ := t.k.VFS().PathnameWithDeleted(t, root, exe.VirtualDentry())
+		return escape(name)
+	case 'c':
+		return strconv.FormatUint(rlimit, 10)
+	default:
+		return ""
+	}
+}
+
+// coreDumpFile creates the core dump file with the given name, relative to
+// t's working directory.
+func (t *Task) coreDumpFile(name string) (*vfs.FileDescription, error) {
+	root := t.FSContext().RootDirectory()
+	defer root.DecRef(t)
+	wd := t.FSContext().WorkingDirectory()
+	defer wd.DecRef(t)
+	fd, err := t.k.VFS().OpenAt(t, t.Credentials(), &vfs.PathOperation{
+		Root:  root,
+		Start: wd,
+		Path:  fspath.Parse(name),
+	}, &vfs.OpenOptions{
+		Flags: linux.O_CREAT | linux.O_WRONLY | linux.O_TRUNC | linux.O_NOFOLLOW | linux.O_LARGEFILE,
+		Mode:  0600,
+	})
+	if err != nil {
+		return nil, err
+	}
+	stat, err := fd.Stat(t, vfs.StatOptions{Mask: linux.STATX_TYPE})
+	if err != nil {
+		fd.DecRef(t)
+		return nil, err
+	}
+	if stat.Mode&linux.S_IFMT != linux.S_IFREG {
+		fd.DecRef(t)
+		return nil, linuxerr.EACCES
+	}
+	return fd, nil
+}
+
+// coreDumpPipe starts the program specified by argv with a pipe as its
+// standard input, and returns the write end of the pipe.
+//
+// The program runs with t's credentials in t's mount namespace and
+// container, so it can't do anything that t couldn't; Linux instead runs it as
+// root in the initial namespaces.
+func (t *Task) coreDumpPipe(argv []string) (*vfs.FileDescription, error) {
+	if len(argv) == 0 || !strings.HasPrefix(argv[0], "/") {
+		return nil, linuxerr.EINVAL
+	}
+	r, w, err := pipefs.NewConnectedPipeFDs(t, t.k.pipeMount, 0 /* flags */)
+	if err != nil {
+		return nil, err
+	}
+	defer r.DecRef(t)
+
+	fdTable := t.k.NewFDTable()
+	if _, err := fdTable.NewFDAt(t, 0, r, FDFlags{}); err != nil {
+		fdTable.DecRef(t)
+		w.DecRef(t)
+		return nil, err
+	}
+	args := CreateProcessArgs{
+		Filename:             argv[0],
+		Argv:                 argv,
+		Envv:                 []string{"HOME=/", "PATH=/sbin:/bin:/usr/sbin:/usr/bin"},
+		WorkingDirectory:     "/",
+		Credentials:          t.Credentials().Fork(),
+		FDTable:              fdTable,
+		Umask:                0022,
+		Limits:               limits.NewLimitSet(),
+		MaxSymlinkTraversals: linux.MaxSymlinkTraversals,
+		UTSNamespace:         t.k.RootUTSNamespace(),
+		IPCNamespace:         t.k.RootIPCNamespace(),
+		PIDNamespace:         t.k.RootPIDNamespace(),
+		MountNamespace:       t.GetMountNamespace(),
+		ContainerID:          t.ContainerID(),
+		Origin:               OriginExec,
+	}
+	// Kernel.CreateProcess locks Kernel.extMu, which may be held by callers
+	// that wait for t to stop, so the helper is started asynchronously. If
+	// it fails to start, the read end of the pipe is closed and writes to
+	// the core dump fail with EPIPE.
+	go func() {
+		ctx := args.NewContext(t.k)
+		defer fdTable.DecRef(ctx)
+		tg, _, err := t.k.CreateProcess(args)
+		if err != nil {
+			log.Warningf("Failed to start core dump helper %q: %v", argv[0], err)
+			return
+		}
+		t.k.StartProcess(tg)
+	}()
+	return w, nil
+}
+
+// coreDumpWriter is an io.Writer that writes a core dump to a file,
+// subject to RLIMIT_CORE.
+type coreDumpWriter struct {
+	t     *Task
+	fd    *vfs.FileDescription
+	limit uint64
+
+	// written is the number of bytes written so far.
+	written uint64
+}
+
+// Write implements io.Writer.Write.
+func (w *coreDumpWriter) Write(src []byte) (int, error) {
+	if w.written+uint64(len(src)) > w.limit {
+		return 0, linuxerr.EFBIG
+	}
+	var n int
+	for n < len(src) {
+		m, err := w.fd.Write(w.t, usermem.BytesIOSequence(src[n:]), vfs.WriteOptions{})
+		n += int(m)
+		w.written += uint64(m)
+		if linuxerr.Equals(linuxerr.ErrWouldBlock, err) {
+			if err := w.waitWritable(); err != nil {
+				return n, err
+			}
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// waitWritable blocks until w.fd is writable, which is only necessary when
+// writing to a pipe.
+func (w *coreDumpWriter) waitWritable() error {
+	e, ch := waiter.NewChannelEntry(waiter.WritableEvents)
+	if err := w.fd.EventRegister(&e); err != nil {
+		return err
+	}
+	defer w.fd.EventUnregister(&e)
+	if w.fd.Readiness(waiter.WritableEvents) != 0 {
+		return nil
+	}
+	return w.t.Block(ch)
+}
+
+// writeCoreDump writes an ELF core file describing t to w, in the format of
+// Linux's fs/binfmt_elf.c:elf_core_dump().
+func (t *Task) writeCoreDump(w *coreDumpWriter, info *linux.SignalInfo) error {
+	var machine elf.Machine
+	switch t.Arch().Arch() {
+	case arch.AMD64:
+		machine = elf.EM_X86_64
+	case arch.ARM64:
+		machine = elf.EM_AARCH64
+	default:
+		return linuxerr.ENOEXEC
+	}
+
+	segs := t.MemoryManager().CoreDumpSegments(t)
+	notes := t.coreDumpNotes(info)
+
+	ehdrSize := (*linux.ElfHeader64)(nil).SizeBytes()
+	phdrSize := (*linux.ElfProg64)(nil).SizeBytes()
+	phnum := 1 + len(segs)
+	if phnum > math.MaxUint16 {
+		return linuxerr.E2BIG
+	}
+	notesOff := uint64(ehdrSize + phnum*phdrSize)
+	dataOff, _ := hostarch.Addr(notesOff + uint64(len(notes))).RoundUp()
+
+	ehdr := linux.ElfHeader64{
+		Type:      uint16(elf.ET_CORE),
+		Machine:   uint16(machine),
+		Version:   uint32(elf.EV_CURRENT),
+		Phoff:     uint64(ehdrSize),
+		Ehsize:    uint16(ehdrSize),
+		Phentsize: uint16(phdrSize),
+		Phnum:     uint16(phnum),
+	}
+	copy(ehdr.Ident[:], elf.ELFMAG)
+	ehdr.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	ehdr.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	ehdr.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	ehdr.Ident[elf.EI_OSABI] = byte(elf.ELFOSABI_NONE)
+
+	hdrs := make([]byte, dataOff)
+	rem := ehdr.MarshalBytes(hdrs)
+	phdr := linux.ElfProg64{
+		Type:   uint32(elf.PT_NOTE),
+		Off:    notesOff,
+		Filesz: uint64(len(notes)),
+	}
+	rem = phdr.MarshalBytes(rem)
+	off := uint64(dataOff)
+	for _, seg := range segs {
+		phdr := linux.ElfProg64{
+			Type:   uint32(elf.PT_LOAD),
+			Off:    off,
+			Vaddr:  uint64(seg.Range.Start),
+			Filesz: seg.DumpSize,
+			Memsz:  seg.Range.Length(),
+			Align:  hostarch.PageSize,
+		}
+		if seg.Perms.Read {
+			phdr.Flags |= uint32(elf.PF_R)
+		}
+		if seg.Perms.Write {
+			phdr.Flags |= uint32(elf.PF_W)
+		}
+		if seg.Perms.Execute {
+			phdr.Flags |= uint32(elf.PF_X)
+		}
+		rem = phdr.MarshalBytes(rem)
+		off += seg.DumpSize
+	}
+	copy(hdrs[notesOff:], notes)
+	if _, err := w.Write(hdrs); err != nil {
+		return err
+	}
+
+	buf := make([]byte, coreDumpChunkSize)
+	for _, seg := range segs {
+		end := seg.Range.Start + hostarch.Addr(seg.DumpSize)
+		for addr := seg.Range.Start; addr < end; {
+			chunk := buf[:min(uint64(len(buf)), uint64(end-addr))]
+			n, err := t.MemoryManager().CopyIn(t, addr, chunk, usermem.IOOpts{
+				IgnorePermissions: true,
+			})
+			if err != nil {
+				// Pages that can't be read, e.g. because they lie beyond
+				// the end of a file, are dumped as zeroes, as in Linux's
+				// fs/coredump.c:dump_user_range().
+				pageEnd := hostarch.Addr(n).RoundDown() + hostarch.PageSize
+				clear(chunk[n:pageEnd])
+				chunk = chunk[:pageEnd]
+			}
+			if _, err := w.Write(chunk); err != nil {
+				return err
+			}
+			addr += hostarch.Addr(len(chunk))
+		}
+	}
+	return nil
+}
+
+// coreDumpNotes returns the contents of the PT_NOTE segment of a core dump of
+// t, in the order used by Linux's fs/binfmt_elf.c:write_note_info().
+func (t *Task) coreDumpNotes(info *linux.SignalInfo) []byte {
+	var b bytes.Buffer
+	fs := t.k.FeatureSet()
+
+	var fpregs bytes.Buffer
+	_, fpErr := t.Arch().PtraceGetRegSet(linux.NT_PRFPREG, &fpregs, math.MaxInt32, fs)
+
+	writeCoreNote(&b, "CORE", linux.NT_PRSTATUS, t.prstatus(info, fpErr == nil))
+	writeCoreNote(&b, "CORE", linux.NT_PRPSINFO, t.prpsinfo())
+	siginfo := make([]byte, info.SizeBytes())
+	info.MarshalBytes(siginfo)
+	writeCoreNote(&b, "CORE", linux.NT_SIGINFO, siginfo)
+	writeCoreNote(&b, "CORE", linux.NT_AUXV, coreAuxv(t.MemoryManager().Auxv()))
+	if fpErr == nil {
+		writeCoreNote(&b, "CORE", linux.NT_PRFPREG, fpregs.Bytes())
+	}
+	var xstate bytes.Buffer
+	if _, err := t.Arch().PtraceGetRegSet(linux.NT_X86_XSTATE, &xstate, math.MaxInt32, fs); err == nil {
+		writeCoreNote(&b, "LINUX", linux.NT_X86_XSTATE, xstate.Bytes())
+	}
+	return b.Bytes()
+}
+
+// writeCoreNote appends an ELF note to b.
+func writeCoreNote(b *bytes.Buffer, name string, typ uint32, desc []byte) {
+	var hdr [12]byte
+	hostarch.ByteOrder.PutUint32(hdr[0:], uint32(len(name)+1))
+	hostarch.ByteOrder.PutUint32(hdr[4:], uint32(len(desc)))
+	hostarch.ByteOrder.PutUint32(hdr[8:], typ)
+	b.Write(hdr[:])
+	b.WriteString(name)
+	b.WriteByte(0)
+	writeCoreNotePadding(b)
+	b.Write(desc)
+	writeCoreNotePadding(b)
+}
+
+// writeCoreNotePadding pads b to a 4-byte boundary.
+func writeCoreNotePadding(b *bytes.Buffer) {
+	for b.Len()%4 != 0 {
+		b.WriteByte(0)
+	}
+}
+
+// prstatus returns the contents of t's NT_PRSTATUS note, struct elf_prstatus.
+func (t *Task) prstatus(info *linux.SignalInfo, fpvalid bool) []byte {
+	var regs bytes.Buffer
+	if _, err := t.Arch().PtraceGetRegSet(linux.NT_PRSTATUS, &regs, math.MaxInt32, t.k.FeatureSet()); err != nil {
+		panic(fmt.Sprintf("failed to get registers for core dump: %v", err))
+	}
+
+	b := make([]byte, prstatusHeaderSize, prstatusHeaderSize+regs.Len()+8)
+	bo := hostarch.ByteOrder
+	// pr_info is struct elf_siginfo.
+	bo.PutUint32(b[0:], uint32(info.Signo))
+	bo.PutUint32(b[4:], uint32(info.Code))
+	bo.PutUint32(b[8:], uint32(info.Errno))
+	bo.PutUint16(b[12:], uint16(info.Signo))
+	bo.PutUint64(b[16:], uint64(t.PendingSignals()))
+	bo.PutUint64(b[24:], uint64(t.SignalMask()))
+	_, ppid, pgid, sid := t.coreDumpIDs()
+	bo.PutUint32(b[32:], uint32(t.tg.pidns.IDOfTask(t)))
+	bo.PutUint32(b[36:], uint32(ppid))
+	bo.PutUint32(b[40:], uint32(pgid))
+	bo.PutUint32(b[44:], uint32(sid))
+	cpu := t.AdjustedCPUStats()
+	ccpu := t.tg.JoinedChildCPUStats()
+	for i, tv := range []linux.Timeval{
+		linux.DurationToTimeval(cpu.UserTime),
+		linux.DurationToTimeval(cpu.SysTime),
+		linux.DurationToTimeval(ccpu.UserTime),
+		linux.DurationToTimeval(ccpu.SysTime),
+	} {
+		bo.PutUint64(b[48+16*i:], uint64(tv.Sec))
+		bo.PutUint64(b[56+16*i:], uint64(tv.Usec))
+	}
+
+	b = append(b, regs.Bytes()...)
+	var tail [8]byte
+	if fpvalid {
+		bo.PutUint32(tail[:], 1)
+	}
+	return append(b, tail[:]...)
+}
+
+// prpsinfo returns the contents of t's NT_PRPSINFO note, struct elf_prpsinfo.
+func (t *Task) prpsinfo() []byte {
+	b := make([]byte, prpsinfoSize)
+	bo := hostarch.ByteOrder
+	// pr_state, pr_sname, pr_zomb, pr_nice: the task is running.
+	b[1] = 'R'
+	creds := t.Credentials()
+	bo.PutUint32(b[16:], uint32(creds.RealKUID.In(creds.UserNamespace).OrOverflow()))
+	bo.PutUint32(b[20:], uint32(creds.RealKGID.In(creds.UserNamespace).OrOverflow()))
+	pid, ppid, pgid, sid := t.coreDumpIDs()
+	bo.PutUint32(b[24:], uint32(pid))
+	bo.PutUint32(b[28:], uint32(ppid))
+	bo.PutUint32(b[32:], uint32(pgid))
+	bo.PutUint32(b[36:], uint32(sid))
+	copy(b[40:56], t.Name())
+
+	// pr_psargs is the command line, with NULs replaced by spaces.
+	psargs := b[56:prpsinfoSize]
+	mm := t.MemoryManager()
+	if n := mm.ArgvEnd() - mm.ArgvStart(); n > 0 {
+		n = min(n, hostarch.Addr(len(psargs)-1))
+		if c, _ := mm.CopyIn(t, mm.ArgvStart(), psargs[:n], usermem.IOOpts{
+			IgnorePermissions: true,
+		}); c > 0 {
+			for i := range psargs[:c] {
+				if psargs[i] == 0 {
+					psargs[i] = ' '
+				}
+			}
+		}
+	}
+	return b
+}
+
+// coreDumpIDs returns the IDs of t's thread group, parent thread group,
+// process group and session in t's PID namespace.
+func (t *Task) coreDumpIDs() (pid, ppid ThreadID, pgid ProcessGroupID, sid SessionID) {
+	pidns := t.tg.pidns
+	pid = pidns.IDOfThreadGroup(t.tg)
+	if parent := t.tg.Leader().Parent(); parent != nil {
+		ppid = pidns.IDOfThreadGroup(parent.tg)
+	}
+	if pg := t.tg.ProcessGroup(); pg != nil {
+		pgid = pidns.IDOfProcessGroup(pg)
+		sid = pidns.IDOfSession(pg.Session())
+	}
+	return
+}
+
+// coreAuxv returns the contents of an NT_AUXV note for auxv.
+func coreAuxv(auxv arch.Auxv) []byte {
+	b := make([]byte, 0, 16*(len(auxv)+1))
+	for _, e := range auxv {
+		b = hostarch.ByteOrder.AppendUint64(b, e.Key)
+		b = hostarch.ByteOrder.AppendUint64(b, uint64(e.Value))
+	}
+	b = hostarch.ByteOrder.AppendUint64(b, linux.AT_NULL)
+	return hostarch.ByteOrder.AppendUint64(b, 0)
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExpandCorePattern(t *testing.T) {
+	spec := func(c byte) string {
+		switch c {
+		case 'p':
+			return "123"
+		case 'e':
+			return "a!b"
+		default:
+			return ""
+		}
+	}
+	for _, test := range []struct {
+		pattern string
+		want    string
+	}{
+		{pattern: "core", want: "core"},
+		{pattern: "core.%p", want: "core.123"},
+		{pattern: "/tmp/%e-%p.core", want: "/tmp/a!b-123.core"},
+		{pattern: "100%%", want: "100%"},
+		{pattern: "core%z.%p", want: "core.123"},
+		{pattern: "core%", want: "core"},
+	} {
+		if got := expandCorePattern(test.pattern, spec); got != test.want {
+			t.Errorf("expandCorePattern(%q) = %q, want %q", test.pattern, got, test.want)
+		}
+	}
+}
+
+func TestCorePatternArgv(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		want    []string
+		pipe    bool
+	}{
+		{pattern: "core", pipe: false},
+		{pattern: "|/bin/collect %p %s", want: []string{"/bin/collect", "%p", "%s"}, pipe: true},
+		{pattern: "| /bin/collect", want: []string{"/bin/collect"}, pipe: true},
+		{pattern: "|", pipe: true},
+	} {
+		got, pipe := corePatternArgv(test.pattern)
+		if pipe != test.pipe || !slices.Equal(got, test.want) {
+			t.Errorf("corePatternArgv(%q) = %q, %t, want %q, %t", test.pattern, got, pipe, test.want, test.pipe)
+		}
+	}
+}
@@ -164,6 +164,7 @@ func (r *runSyscallAfterExecStop) execute(t *Task) taskRunState {
 	if tracer := t.Tracer(); tracer != nil {
 		oldTID = tracer.tg.pidns.tids[t]
 	}
+	oldRootTID := t.k.tasks.Root.tids[t]
 	t.promoteLocked()
 	// "POSIX timers are not preserved (timer_create(2))." - execve(2). Handle
 	// this first since POSIX timers are protected by the signal mutex, which
@@ -244,6 +245,7 @@ func (r *runSyscallAfterExecStop) execute(t *Task) taskRunState {
 	oldImage := t.image
 	t.image = *r.image
 	t.mu.Unlock()
+	t.pmaCache.Invalidate()
 
 	// Don't hold t.mu while calling t.image.release(), that may
 	// attempt to acquire TaskImage.MemoryManager.mappingMu, a lock order
@@ -255,6 +257,7 @@ func (r *runSyscallAfterExecStop) execute(t *Task) taskRunState {
 	// NOTE(b/30316266): All locks must be dropped prior to calling Activate.
 	t.MemoryManager().Activate(t)
 
+	t.ftraceProcessExec(r.image.Name, oldRootTID)
 	t.ptraceExec(oldTID)
 	return (*runSyscallExit)(nil)
 }
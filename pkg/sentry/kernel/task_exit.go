@@ -220,6 +220,7 @@ type runExitMain struct{}
 
 func (*runExitMain) execute(t *Task) taskRunState {
 	t.traceExitEvent()
+	t.ftraceProcessExit()
 
 	if seccheck.Global.Enabled(seccheck.PointTaskExit) {
 		info := &pb.TaskExit{
@@ -272,6 +273,7 @@ func (*runExitMain) execute(t *Task) taskRunState {
 	t.image.MemoryManager = nil
 	t.image.fu = nil
 	t.mu.Unlock()
+	t.pmaCache.Invalidate()
 	mm.DecUsers(t)
 
 	// Releasing the MM unblocks a blocked CLONE_VFORK parent.
@@ -620,6 +622,11 @@ func (t *Task) exitNotifyLocked(fromPtraceDetach bool) {
 	if t.exitState != TaskExitZombie {
 		return
 	}
+	if t == t.tg.leader && t.tg.tasksCount == 1 {
+		// Every task in the thread group has exited, which makes pidfds
+		// referring to it readable.
+		t.tg.exitQueue.Notify(waiter.EventIn)
+	}
 	if !t.exitTracerNotified {
 		t.exitTracerNotified = true
 		tracer := t.Tracer()
@@ -726,7 +733,9 @@ func (t *Task) exitNotifyLocked(fromPtraceDetach bool) {
 			ns.deleteTask(t)
 		}
 		t.userCounters.decRLimitNProc()
-		t.tg.exitedCPUStats.Accumulate(t.CPUStats())
+		cpuStats := t.CPUStats()
+		t.tg.exitedCPUStats.Accumulate(cpuStats)
+		t.tg.pidns.owner.exitedCPUStats.Accumulate(cpuStats)
 		t.tg.ioUsage.Accumulate(t.ioUsage)
 		t.tg.signalHandlers.mu.Lock()
 		t.tg.tasks.Remove(t)
@@ -816,6 +825,25 @@ func (tg *ThreadGroup) TerminationSignal() linux.Signal {
 	return tg.terminationSignal
 }
 
+// Exited returns true if every task in tg has exited.
+func (tg *ThreadGroup) Exited() bool {
+	tg.pidns.owner.mu.RLock()
+	defer tg.pidns.owner.mu.RUnlock()
+	return tg.leader.exitState >= TaskExitZombie && tg.tasksCount <= 1
+}
+
+// EventRegisterExit registers e to be notified with waiter.EventIn when every
+// task in tg has exited.
+func (tg *ThreadGroup) EventRegisterExit(e *waiter.Entry) {
+	tg.exitQueue.EventRegister(e)
+}
+
+// EventUnregisterExit unregisters e, which must have been registered with
+// EventRegisterExit.
+func (tg *ThreadGroup) EventUnregisterExit(e *waiter.Entry) {
+	tg.exitQueue.EventUnregister(e)
+}
+
 // Task events that can be waited for.
 const (
 	// EventExit represents an exit notification generated for a child thread
@@ -1123,8 +1151,9 @@ func (t *Task) waitCollectZombieLocked(target *Task, opts *WaitOptions, asPtrace
 			// which accounts an exited task's cputime to its thread group in
 			// kernel/exit.c:release_task() => __exit_signal(), and uses
 			// thread_group_cputime_adjusted() in wait_task_zombie().
-			t.tg.childCPUStats.Accumulate(target.CPUStats())
-			t.tg.childCPUStats.Accumulate(target.tg.exitedCPUStats)
+			cpuStats := target.CPUStats()
+			cpuStats.Accumulate(target.tg.exitedCPUStats)
+			t.tg.childCPUStats.Accumulate(target.tg.prevCPUTime.Adjust(cpuStats))
 			t.tg.childCPUStats.Accumulate(target.tg.childCPUStats)
 			// Update t's child max resident set size. The size will be the maximum
 			// of this thread's size and all its childrens' sizes.
@@ -0,0 +1,87 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/ftrace"
+)
+
+// ftraceHeader returns the header of trace events emitted by t.
+func (t *Task) ftraceHeader() ftrace.Header {
+	return ftrace.Header{
+		Comm:   t.Name(),
+		PID:    int32(t.k.tasks.Root.IDOfTask(t)),
+		CPU:    t.CPU(),
+		TimeNs: t.k.MonotonicClock().Now().Nanoseconds(),
+	}
+}
+
+// ftraceSyscallEnter emits the trace events for entry of syscall sysno.
+func (t *Task) ftraceSyscallEnter(sysno uintptr, args arch.SyscallArguments) {
+	if !ftrace.Global.SyscallEnterEnabled(sysno) {
+		return
+	}
+	var raw [6]uint64
+	for i := range raw {
+		raw[i] = args[i].Uint64()
+	}
+	ftrace.Global.SyscallEnter(t.ftraceHeader(), sysno, raw)
+}
+
+// ftraceSyscallExit emits the trace events for exit of syscall sysno.
+func (t *Task) ftraceSyscallExit(sysno uintptr) {
+	if !ftrace.Global.SyscallExitEnabled(sysno) {
+		return
+	}
+	ftrace.Global.SyscallExit(t.ftraceHeader(), sysno, int64(t.Arch().Return()))
+}
+
+// ftraceSchedSwitch emits the sched_switch event for t going to sleep.
+func (t *Task) ftraceSchedSwitch() {
+	if !ftrace.Global.SchedSwitch.Enabled() {
+		return
+	}
+	h := t.ftraceHeader()
+	ftrace.Global.Emit(h, ftrace.Global.SchedSwitch, "prev_comm=%s prev_pid=%d prev_prio=120 prev_state=S ==> next_comm=swapper/%d next_pid=0 next_prio=120", h.Comm, h.PID, h.CPU)
+}
+
+// ftraceProcessFork emits the sched_process_fork event for t creating nt.
+func (t *Task) ftraceProcessFork(nt *Task, ntid ThreadID) {
+	if !ftrace.Global.SchedProcessFork.Enabled() {
+		return
+	}
+	h := t.ftraceHeader()
+	ftrace.Global.Emit(h, ftrace.Global.SchedProcessFork, "comm=%s pid=%d child_comm=%s child_pid=%d", h.Comm, h.PID, nt.Name(), ntid)
+}
+
+// ftraceProcessExec emits the sched_process_exec event for t, whose thread ID
+// in the root PID namespace was oldTID before the exec.
+func (t *Task) ftraceProcessExec(filename string, oldTID ThreadID) {
+	if !ftrace.Global.SchedProcessExec.Enabled() {
+		return
+	}
+	h := t.ftraceHeader()
+	ftrace.Global.Emit(h, ftrace.Global.SchedProcessExec, "filename=%s pid=%d old_pid=%d", filename, h.PID, oldTID)
+}
+
+// ftraceProcessExit emits the sched_process_exit event for t.
+func (t *Task) ftraceProcessExit() {
+	if !ftrace.Global.SchedProcessExit.Enabled() {
+		return
+	}
+	h := t.ftraceHeader()
+	ftrace.Global.Emit(h, ftrace.Global.SchedProcessExit, "comm=%s pid=%d prio=120", h.Comm, h.PID)
+}
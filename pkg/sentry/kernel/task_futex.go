@@ -72,8 +72,8 @@ func (t *Task) SetRobustList(addr hostarch.Addr) {
 }
 
 // exitRobustList walks the robust futex list, marking locks dead and notifying
-// wakers. It corresponds to Linux's exit_robust_list(). Following Linux,
-// errors are silently ignored.
+// wakers. It corresponds to Linux's exit_robust_list(), and is called on both
+// exit and exec. Following Linux, errors are silently ignored.
 func (t *Task) exitRobustList() {
 	t.mu.Lock()
 	addr := t.robustList
@@ -110,7 +110,7 @@ func (t *Task) exitRobustList() {
 
 		// Wakeup the current futex if it's not pending.
 		if thisLockAddr != pendingLockAddr {
-			t.wakeRobustListOne(thisLockAddr)
+			t.wakeRobustListOne(thisLockAddr, false /* pendingOp */)
 		}
 
 		// If there was an error copying the next futex, we must bail.
@@ -129,12 +129,17 @@ func (t *Task) exitRobustList() {
 
 	// Is there a pending entry to wake?
 	if pendingLockAddr != 0 {
-		t.wakeRobustListOne(pendingLockAddr)
+		t.wakeRobustListOne(pendingLockAddr, true /* pendingOp */)
 	}
 }
 
-// wakeRobustListOne wakes a single futex from the robust list.
-func (t *Task) wakeRobustListOne(addr hostarch.Addr) {
+// wakeRobustListOne wakes a single futex from the robust list. pendingOp
+// indicates that addr is the list's pending operation. It corresponds to
+// Linux's handle_futex_death().
+//
+// Like Linux, wakeRobustListOne always uses shared futex keys; glibc always
+// uses shared futex operations for robust mutexes for this reason.
+func (t *Task) wakeRobustListOne(addr hostarch.Addr, pendingOp bool) {
 	// Bit 0 in address signals PI futex.
 	pi := addr&1 == 1
 	addr = addr &^ 1
@@ -147,7 +152,25 @@ func (t *Task) wakeRobustListOne(addr hostarch.Addr) {
 		return
 	}
 
+	// If a regular futex was being unlocked when the task died, it may have
+	// been released without waking a waiter, or a woken waiter may have died
+	// before acquiring it. In both cases, wake a waiter so that it doesn't
+	// block forever.
+	if pendingOp && !pi && f == 0 {
+		t.Futex().Wake(t, addr, false, linux.FUTEX_BITSET_MATCH_ANY, 1)
+		return
+	}
+
 	tid := uint32(t.ThreadID())
+	if pi {
+		// Hand the futex to the next waiter, if any, with the owner died bit
+		// set. This is done by Linux's exit_pi_state_list().
+		if f&linux.FUTEX_TID_MASK == tid {
+			t.Futex().UnlockPIOwnerDied(t, addr, tid)
+		}
+		return
+	}
+
 	for {
 		// Is this held by someone else?
 		if f&linux.FUTEX_TID_MASK != tid {
@@ -167,12 +190,7 @@ func (t *Task) wakeRobustListOne(addr hostarch.Addr) {
 
 		// Wake waiters if there are any.
 		if f&linux.FUTEX_WAITERS != 0 {
-			private := f&linux.FUTEX_PRIVATE_FLAG != 0
-			if pi {
-				t.Futex().UnlockPI(t, addr, tid, private)
-				return
-			}
-			t.Futex().Wake(t, addr, private, linux.FUTEX_BITSET_MATCH_ANY, 1)
+			t.Futex().Wake(t, addr, false, linux.FUTEX_BITSET_MATCH_ANY, 1)
 		}
 
 		// Done.
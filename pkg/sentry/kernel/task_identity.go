@@ -19,6 +19,8 @@ import (
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/mm"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 )
 
 // Credentials returns t's credentials.
@@ -43,6 +45,40 @@ func (t *Task) HasCapability(cp linux.Capability) bool {
 	return t.Credentials().HasCapability(cp)
 }
 
+// CheckCapabilityIn is like HasCapabilityIn, but is meant to be used for
+// checks that deny an operation to tasks lacking the capability, which are
+// reported to the sentry/capability_denied point.
+//
+// Preconditions: The caller must be running on the task goroutine, without
+// any kernel locks held.
+func (t *Task) CheckCapabilityIn(cp linux.Capability, ns *auth.UserNamespace) bool {
+	if t.HasCapabilityIn(cp, ns) {
+		return true
+	}
+	if seccheck.Global.Enabled(seccheck.PointCapabilityDenied) {
+		info := &pb.CapabilityDeniedInfo{
+			Capability: uint32(cp),
+		}
+		fields := seccheck.Global.GetFieldSet(seccheck.PointCapabilityDenied)
+		if !fields.Context.Empty() {
+			info.ContextData = &pb.ContextData{}
+			LoadSeccheckData(t, fields.Context, info.ContextData)
+		}
+		seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
+			return c.CapabilityDenied(t, fields, info)
+		})
+	}
+	return false
+}
+
+// CheckCapability is like HasCapability, but reports denials like
+// CheckCapabilityIn.
+//
+// Preconditions: Same as CheckCapabilityIn.
+func (t *Task) CheckCapability(cp linux.Capability) bool {
+	return t.CheckCapabilityIn(cp, t.UserNamespace())
+}
+
 // SetUID implements the semantics of setuid(2).
 func (t *Task) SetUID(uid auth.UID) error {
 	// setuid considers -1 to be invalid.
@@ -345,6 +381,10 @@ func (t *Task) SetExtraGIDs(gids []auth.GID) error {
 	if !creds.HasCapability(linux.CAP_SETGID) {
 		return linuxerr.EPERM
 	}
+	// Compare Linux's kernel/groups.c:may_setgroups().
+	if !creds.UserNamespace.SetgroupsAllowed() {
+		return linuxerr.EPERM
+	}
 	kgids := make([]auth.KGID, len(gids))
 	for i, gid := range gids {
 		kgid := creds.UserNamespace.MapToKGID(gid)
@@ -614,3 +654,40 @@ func (t *Task) updateCredsForExecLocked() {
 	// is preserved across an execve(2)". So we're done.
 	t.creds.Store(creds)
 }
+
+// defaultMaxUserNamespaces is the default value of
+// /proc/sys/user/max_user_namespaces.
+const defaultMaxUserNamespaces = 65536
+
+// newChildUserNamespace returns a new user namespace created by t with
+// credentials creds, subject to /proc/sys/user/max_user_namespaces.
+func (t *Task) newChildUserNamespace(creds *auth.Credentials) (*auth.UserNamespace, error) {
+	// "ENOSPC: The number of nested user namespaces would exceed the limit
+	// (see user_namespaces(7))... (since Linux 4.9) the limit was exceeded on
+	// the number of user namespaces" - unshare(2)
+	//
+	// User namespaces aren't reference counted, so the ones in use are those
+	// of live tasks and their ancestors.
+	if t.k.tasks.userNamespacesInUse() >= int(t.k.MaxUserNamespaces.Load()) {
+		return nil, linuxerr.ENOSPC
+	}
+	return creds.NewChildUserNamespace()
+}
+
+// userNamespacesInUse returns the number of distinct non-root user
+// namespaces that are used by live tasks in ts, directly or as ancestors of
+// their user namespaces.
+func (ts *TaskSet) userNamespacesInUse() int {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	seen := make(map[*auth.UserNamespace]struct{})
+	for t := range ts.Root.tids {
+		for ns := t.Credentials().UserNamespace; ns.Parent() != nil; ns = ns.Parent() {
+			if _, ok := seen[ns]; ok {
+				break
+			}
+			seen[ns] = struct{}{}
+		}
+	}
+	return len(seen)
+}
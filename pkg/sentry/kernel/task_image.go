@@ -147,8 +147,12 @@ func (t *Task) Stack() *arch.Stack {
 func (k *Kernel) LoadTaskImage(ctx context.Context, args loader.LoadArgs) (*TaskImage, *syserr.Error) {
 	// Prepare a new user address space to load into.
 	m := mm.NewMemoryManager(k, k.mf, k.SleepForAddressSpaceActivation)
+	m.SetLazyFork(k.LazyForkMemory)
 	defer m.DecUsers(ctx)
 	args.MemoryManager = m
+	if args.BinfmtMisc == nil {
+		args.BinfmtMisc = &k.binfmtMisc
+	}
 
 	info, err := loader.Load(ctx, args, k.extraAuxv, k.vdso)
 	if err != nil {
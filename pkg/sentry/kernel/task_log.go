@@ -264,3 +264,27 @@ func (t *Task) traceExecEvent(image *TaskImage) {
 	name := file.MappedName(t.AsyncContext())
 	trace.Logf(t.traceContext, traceCategory, "exec: %s", name)
 }
+
+// TaskGoroutineAnnotations returns a description of each task goroutine,
+// keyed by goroutine ID. It is used to annotate goroutine dumps with the
+// guest tasks that they run, and the syscall that they are executing, if any.
+func (k *Kernel) TaskGoroutineAnnotations() map[int64]string {
+	k.tasks.mu.RLock()
+	defer k.tasks.mu.RUnlock()
+	annotations := make(map[int64]string, len(k.tasks.Root.tids))
+	for t, tid := range k.tasks.Root.tids {
+		goid := t.GoroutineID()
+		if goid == 0 {
+			continue
+		}
+		t.mu.Lock()
+		comm, st := t.image.Name, t.image.st
+		t.mu.Unlock()
+		s := fmt.Sprintf("pid=%d tid=%d comm=%q state=%s", k.tasks.Root.tgids[t.tg], tid, comm, t.TaskGoroutineSchedInfo().State)
+		if sysno := t.syscallNo.Load(); sysno != 0 && st != nil {
+			s += " syscall=" + st.LookupName(uintptr(sysno-1))
+		}
+		annotations[goid] = s
+	}
+	return annotations
+}
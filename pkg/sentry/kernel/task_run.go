@@ -24,7 +24,6 @@ import (
 	"gvisor.dev/gvisor/pkg/goid"
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/refs"
-	"gvisor.dev/gvisor/pkg/sentry/hostcpu"
 	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
@@ -201,7 +200,7 @@ func (app *runApp) execute(t *Task) taskRunState {
 	if t.rseqPreempted {
 		t.rseqPreempted = false
 		if t.rseqAddr != 0 || t.oldRSeqCPUAddr != 0 {
-			t.rseqCPU = int32(hostcpu.GetCPU())
+			t.rseqCPU = t.CPU()
 			if err := t.rseqCopyOutCPU(); err != nil {
 				t.Debugf("Failed to copy CPU to %#x for rseq: %v", t.rseqAddr, err)
 				t.forceSignal(linux.SIGSEGV, false)
@@ -242,9 +241,16 @@ func (app *runApp) execute(t *Task) taskRunState {
 	}
 
 	region := trace.StartRegion(t.traceContext, runRegion)
+	ds := t.k.deterministicSched
+	if ds != nil {
+		ds.acquire(t)
+	}
 	t.accountTaskGoroutineEnter(TaskGoroutineRunningApp)
 	info, at, err := t.p.Switch(t, t.MemoryManager(), t.Arch(), t.rseqCPU)
 	t.accountTaskGoroutineLeave(TaskGoroutineRunningApp)
+	if ds != nil {
+		ds.release()
+	}
 	region.End()
 
 	if clearSinglestep {
@@ -310,9 +316,22 @@ func (app *runApp) execute(t *Task) taskRunState {
 			//
 			// Convert a BusError error to a SIGBUS from a SIGSEGV. All
 			// other info bits stay the same (address, etc.).
+			//
+			// The platform's si_code reflects the host's view of the
+			// fault, which doesn't know about the application's
+			// mappings; derive it from the MemoryManager's instead.
+			// Compare Linux's arch/x86/mm/fault.c:bad_area() and
+			// bad_area_access_error().
 			if _, ok := err.(*memmap.BusError); ok {
 				sig = linux.SIGBUS
 				info.Signo = int32(linux.SIGBUS)
+				info.Code = linux.BUS_ADRERR
+			} else if sig == linux.SIGSEGV {
+				if linuxerr.Equals(linuxerr.EPERM, err) {
+					info.Code = linux.SEGV_ACCERR
+				} else {
+					info.Code = linux.SEGV_MAPERR
+				}
 			}
 		}
 
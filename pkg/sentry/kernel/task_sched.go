@@ -23,6 +23,7 @@ import (
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/gohacks"
 	"gvisor.dev/gvisor/pkg/sentry/hostcpu"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/sched"
 	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
@@ -65,6 +66,26 @@ const (
 	TaskGoroutineStopped
 )
 
+// String implements fmt.Stringer.
+func (s TaskGoroutineState) String() string {
+	switch s {
+	case TaskGoroutineNonexistent:
+		return "nonexistent"
+	case TaskGoroutineRunningSys:
+		return "running-sys"
+	case TaskGoroutineRunningApp:
+		return "running-app"
+	case TaskGoroutineBlockedInterruptible:
+		return "blocked-interruptible"
+	case TaskGoroutineBlockedUninterruptible:
+		return "blocked-uninterruptible"
+	case TaskGoroutineStopped:
+		return "stopped"
+	default:
+		return fmt.Sprintf("TaskGoroutineState(%d)", int(s))
+	}
+}
+
 // TaskGoroutineSchedInfo contains task goroutine scheduling state which must
 // be read and updated atomically.
 //
@@ -84,6 +105,21 @@ type TaskGoroutineSchedInfo struct {
 	// SysTicks is the amount of time the task goroutine has spent executing in
 	// the sentry, in units of linux.ClockTick.
 	SysTicks uint64
+
+	// RunningSince was the value of the host monotonic clock, in nanoseconds,
+	// when the task goroutine last started running, i.e. left a blocked or
+	// stopped state. RunningSince is only meaningful while State is
+	// TaskGoroutineRunningSys or TaskGoroutineRunningApp; it is reset when the
+	// task goroutine is restarted after restore.
+	RunningSince int64
+
+	// Runtime is the amount of time the task goroutine had spent running
+	// (executing either application or sentry code) before RunningSince.
+	// Unlike UserTicks and SysTicks, Runtime is measured precisely, since the
+	// host clock only needs to be sampled when the task goroutine blocks or
+	// stops, which is much rarer than switching between application and
+	// sentry code.
+	Runtime time.Duration
 }
 
 // userTicksAt returns the extrapolated value of ts.UserTicks after
@@ -113,22 +149,42 @@ func (ts *TaskGoroutineSchedInfo) sysTicksAt(now uint64) uint64 {
 	return ts.SysTicks
 }
 
+// runtimeAt returns the extrapolated value of ts.Runtime when the host
+// monotonic clock indicates a time of now.
+func (ts *TaskGoroutineSchedInfo) runtimeAt(now int64) time.Duration {
+	if ts.RunningSince < now && (ts.State == TaskGoroutineRunningApp || ts.State == TaskGoroutineRunningSys) {
+		return ts.Runtime + time.Duration(now-ts.RunningSince)
+	}
+	return ts.Runtime
+}
+
 // Preconditions: The caller must be running on the task goroutine.
 func (t *Task) accountTaskGoroutineEnter(state TaskGoroutineState) {
 	now := t.k.CPUClockNow()
 	if t.gosched.State != TaskGoroutineRunningSys {
 		panic(fmt.Sprintf("Task goroutine switching from state %v (expected %v) to %v", t.gosched.State, TaskGoroutineRunningSys, state))
 	}
+	var nowNS int64
+	if state != TaskGoroutineRunningApp {
+		nowNS = gohacks.Nanotime()
+	}
 	t.goschedSeq.BeginWrite()
 	// This function is very hot; avoid defer.
 	t.gosched.SysTicks += now - t.gosched.Timestamp
 	t.gosched.Timestamp = now
+	if state != TaskGoroutineRunningApp && t.gosched.RunningSince < nowNS {
+		t.gosched.Runtime += time.Duration(nowNS - t.gosched.RunningSince)
+	}
 	t.gosched.State = state
 	t.goschedSeq.EndWrite()
 
 	if state != TaskGoroutineRunningApp {
 		// Task is blocking/stopping.
 		t.k.decRunningTasks()
+		t.releaseVirtualCPU()
+		if state == TaskGoroutineBlockedUninterruptible {
+			t.k.uninterruptibleTasks.Add(1)
+		}
 	}
 }
 
@@ -139,6 +195,10 @@ func (t *Task) accountTaskGoroutineEnter(state TaskGoroutineState) {
 func (t *Task) accountTaskGoroutineLeave(state TaskGoroutineState) {
 	if state != TaskGoroutineRunningApp {
 		// Task is unblocking/continuing.
+		if state == TaskGoroutineBlockedUninterruptible {
+			t.k.uninterruptibleTasks.Add(-1)
+		}
+		t.acquireVirtualCPU()
 		t.k.incRunningTasks()
 	}
 
@@ -146,10 +206,16 @@ func (t *Task) accountTaskGoroutineLeave(state TaskGoroutineState) {
 	if t.gosched.State != state {
 		panic(fmt.Sprintf("Task goroutine switching from state %v (expected %v) to %v", t.gosched.State, state, TaskGoroutineRunningSys))
 	}
+	var nowNS int64
+	if state != TaskGoroutineRunningApp {
+		nowNS = gohacks.Nanotime()
+	}
 	t.goschedSeq.BeginWrite()
 	// This function is very hot; avoid defer.
 	if state == TaskGoroutineRunningApp {
 		t.gosched.UserTicks += now - t.gosched.Timestamp
+	} else {
+		t.gosched.RunningSince = nowNS
 	}
 	t.gosched.Timestamp = now
 	t.gosched.State = TaskGoroutineRunningSys
@@ -185,6 +251,7 @@ func (t *Task) cpuStatsAt(now uint64) usage.CPUStats {
 	return usage.CPUStats{
 		UserTime:          time.Duration(tsched.userTicksAt(now) * uint64(linux.ClockTick)),
 		SysTime:           time.Duration(tsched.sysTicksAt(now) * uint64(linux.ClockTick)),
+		Runtime:           tsched.runtimeAt(gohacks.Nanotime()),
 		VoluntarySwitches: t.yieldCount.Load(),
 	}
 }
@@ -214,6 +281,19 @@ func (tg *ThreadGroup) cpuStatsAtLocked(now uint64) usage.CPUStats {
 	return stats
 }
 
+// AdjustedCPUStats returns the CPU usage statistics of t, with UserTime and
+// SysTime scaled to add up to its precisely measured Runtime. This is the CPU
+// time reported to applications by getrusage(2) and /proc/[pid]/stat.
+func (t *Task) AdjustedCPUStats() usage.CPUStats {
+	return t.prevCPUTime.Adjust(t.CPUStats())
+}
+
+// AdjustedCPUStats is equivalent to Task.AdjustedCPUStats for all past and
+// present threads in tg.
+func (tg *ThreadGroup) AdjustedCPUStats() usage.CPUStats {
+	return tg.prevCPUTime.Adjust(tg.CPUStats())
+}
+
 // JoinedChildCPUStats implements the semantics of RUSAGE_CHILDREN: "Return
 // resource usage statistics for all children of [tg] that have terminated and
 // been waited for. These statistics will include the resources used by
@@ -237,6 +317,10 @@ type taskClock struct {
 	// taskClock includes only time spent executing application code.
 	includeSys bool
 
+	// If sched is true, the taskClock measures the precise amount of time the
+	// task has spent running, and includeSys is ignored.
+	sched bool
+
 	// Implements waiter.Waitable. TimeUntil wouldn't change its estimation
 	// based on either of the clock events, so there's no event to be
 	// notified for.
@@ -262,9 +346,18 @@ func (t *Task) CPUClock() ktime.Clock {
 	return &taskClock{t: t, includeSys: true}
 }
 
+// SchedClock returns a clock measuring the precise CPU time the task has spent
+// running, as used by CLOCK_THREAD_CPUTIME_ID.
+func (t *Task) SchedClock() ktime.Clock {
+	return &taskClock{t: t, sched: true}
+}
+
 // Now implements ktime.Clock.Now.
 func (tc *taskClock) Now() ktime.Time {
 	stats := tc.t.CPUStats()
+	if tc.sched {
+		return ktime.FromNanoseconds(stats.Runtime.Nanoseconds())
+	}
 	if tc.includeSys {
 		return ktime.FromNanoseconds((stats.UserTime + stats.SysTime).Nanoseconds())
 	}
@@ -283,6 +376,10 @@ type tgClock struct {
 	// tgClock includes only time spent executing application code.
 	includeSys bool
 
+	// If sched is true, the tgClock measures the precise amount of time the
+	// thread group has spent running, and includeSys is ignored.
+	sched bool
+
 	// Implements waiter.Waitable.
 	ktime.ClockEventsQueue `state:"nosave"`
 }
@@ -290,6 +387,9 @@ type tgClock struct {
 // Now implements ktime.Clock.Now.
 func (tgc *tgClock) Now() ktime.Time {
 	stats := tgc.tg.CPUStats()
+	if tgc.sched {
+		return ktime.FromNanoseconds(stats.Runtime.Nanoseconds())
+	}
 	if tgc.includeSys {
 		return ktime.FromNanoseconds((stats.UserTime + stats.SysTime).Nanoseconds())
 	}
@@ -335,6 +435,12 @@ func (tg *ThreadGroup) CPUClock() ktime.Clock {
 	return &tgClock{tg: tg, includeSys: true}
 }
 
+// SchedClock returns a ktime.Clock that measures the precise time that a
+// thread group has spent running, as used by CLOCK_PROCESS_CPUTIME_ID.
+func (tg *ThreadGroup) SchedClock() ktime.Clock {
+	return &tgClock{tg: tg, sched: true}
+}
+
 func (k *Kernel) runCPUClockTicker() {
 	rng := rand.New(rand.NewSource(rand.Int63()))
 	var tgs []*ThreadGroup
@@ -356,8 +462,9 @@ func (k *Kernel) runCPUClockTicker() {
 		}
 
 		// Wait for the next CPU clock tick.
+		var tickTime time.Time
 		select {
-		case <-k.cpuClockTickTimer.C:
+		case tickTime = <-k.cpuClockTickTimer.C:
 			k.cpuClockTickTimer.Reset(linux.ClockTick)
 		case <-k.cpuClockTickerWakeCh:
 			continue
@@ -367,6 +474,7 @@ func (k *Kernel) runCPUClockTicker() {
 		// under cpuClockMu.
 		k.cpuClockMu.Lock()
 		now := k.cpuClock.Add(1)
+		k.accountCPUUsageLocked(time.Since(tickTime))
 
 		// Check thread group CPU timers.
 		tgs = k.tasks.Root.ThreadGroupsAppend(tgs)
@@ -460,6 +568,86 @@ func (k *Kernel) runCPUClockTicker() {
 	}
 }
 
+// cpuUsage is the sandbox-wide CPU time accounted by the CPU clock ticker.
+//
+// +stateify savable
+type cpuUsage struct {
+	// busy is the time application CPUs spent running tasks.
+	busy time.Duration
+
+	// ioWait is the time application CPUs spent idle while tasks were
+	// blocked uninterruptibly.
+	ioWait time.Duration
+
+	// steal is the time application CPUs that were running tasks spent
+	// waiting for the host to schedule the sandbox.
+	steal time.Duration
+}
+
+// accountCPUUsageLocked accounts a CPU clock tick to k.cpuUsage. late is the
+// time by which the CPU clock ticker was late to observe the tick.
+//
+// Preconditions: k.cpuClockMu must be locked.
+func (k *Kernel) accountCPUUsageLocked(late time.Duration) {
+	cores := int64(k.applicationCores)
+	busy := min(k.runningTasks.Load(), cores)
+	ioWait := min(k.uninterruptibleTasks.Load(), cores-busy)
+	k.cpuUsage.busy += time.Duration(busy) * linux.ClockTick
+	k.cpuUsage.ioWait += time.Duration(ioWait) * linux.ClockTick
+	// The CPU clock ticker is late to run when the host doesn't schedule the
+	// sandbox, which is the closest equivalent of a hypervisor preempting a
+	// virtual CPU; assume running tasks were delayed just as much.
+	k.cpuUsage.steal += time.Duration(busy) * min(max(late, 0), linux.ClockTick)
+}
+
+// SystemCPUStats is the sandbox-wide CPU usage reported by /proc/stat.
+type SystemCPUStats struct {
+	// User, Sys, Idle, IOWait and Steal are the total time application CPUs
+	// have spent in each state since boot.
+	User   time.Duration
+	Sys    time.Duration
+	Idle   time.Duration
+	IOWait time.Duration
+	Steal  time.Duration
+
+	// Running and Blocked are the number of tasks that are currently
+	// running, and blocked uninterruptibly, respectively.
+	Running int64
+	Blocked int64
+}
+
+// SystemCPUStats returns the sandbox-wide CPU usage.
+func (k *Kernel) SystemCPUStats() SystemCPUStats {
+	ts := k.tasks
+	ts.mu.RLock()
+	stats := ts.exitedCPUStats
+	now := k.CPUClockNow()
+	for t := range ts.Root.tids {
+		stats.Accumulate(t.cpuStatsAt(now))
+	}
+	ts.mu.RUnlock()
+
+	k.cpuClockMu.Lock()
+	u := k.cpuUsage
+	k.cpuClockMu.Unlock()
+
+	// The CPU clock ticker only knows how long CPUs were busy; split it
+	// between user and system time in the proportion accounted to tasks.
+	stats.Runtime = u.busy
+	stats = k.prevCPUTime.Adjust(stats)
+	uptime := k.RealtimeClock().Now().Sub(k.Timekeeper().BootTime())
+	idle := time.Duration(k.applicationCores)*uptime - u.busy - u.ioWait - u.steal
+	return SystemCPUStats{
+		User:    stats.UserTime,
+		Sys:     stats.SysTime,
+		Idle:    max(idle, 0),
+		IOWait:  u.ioWait,
+		Steal:   u.steal,
+		Running: k.runningTasks.Load(),
+		Blocked: k.uninterruptibleTasks.Load(),
+	}
+}
+
 // randInt31n returns a random integer in [0, n).
 //
 // randInt31n is equivalent to math/rand.Rand.int31n(), which is unexported.
@@ -600,11 +788,14 @@ func (t *Task) SetCPUMask(mask sched.CPUSet) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.allowedCPUMask = mask
+	t.allowedCPUs.Store(&mask)
 	t.cpu.Store(assignCPU(mask, rootTID))
 	return nil
 }
 
-// CPU returns the cpu id for a given task.
+// CPU returns the cpu id for a given task. Unless the kernel is using host
+// CPU numbers, this is the virtual CPU that the task is running on, or last
+// ran on if it isn't running.
 func (t *Task) CPU() int32 {
 	if t.k.useHostCores {
 		return int32(hostcpu.GetCPU())
@@ -629,6 +820,52 @@ func assignCPU(allowed sched.CPUSet, tid ThreadID) (cpu int32) {
 	return cpu
 }
 
+// acquireVirtualCPU assigns t a virtual CPU to run on as t becomes runnable.
+// Like a real scheduler, it keeps t on the CPU that it last ran on if that CPU
+// is idle, and otherwise migrates t to an idle CPU that t is allowed to run on.
+// If all allowed CPUs are busy, t shares its previous CPU.
+//
+// Preconditions: The caller must be running on the task goroutine, or t's
+// task goroutine must not have been started.
+func (t *Task) acquireVirtualCPU() {
+	cpuTasks := t.k.cpuTasks
+	if cpuTasks == nil {
+		// Using host CPU numbers.
+		return
+	}
+	cpu := t.cpu.Load()
+	if !cpuTasks[cpu].CompareAndSwap(0, 1) {
+		cpu = t.idleVirtualCPU(cpu)
+		cpuTasks[cpu].Add(1)
+		t.cpu.Store(cpu)
+	}
+	t.runningCPU = cpu
+}
+
+// idleVirtualCPU returns the first idle virtual CPU after prev that t is
+// allowed to run on, or prev if there is no such CPU.
+func (t *Task) idleVirtualCPU(prev int32) int32 {
+	allowed := *t.allowedCPUs.Load()
+	cpuTasks := t.k.cpuTasks
+	n := int32(len(cpuTasks))
+	for i := int32(1); i < n; i++ {
+		cpu := (prev + i) % n
+		if allowed.IsSet(uint(cpu)) && cpuTasks[cpu].Load() == 0 {
+			return cpu
+		}
+	}
+	return prev
+}
+
+// releaseVirtualCPU is called when t stops running on its virtual CPU.
+//
+// Preconditions: The caller must be running on the task goroutine.
+func (t *Task) releaseVirtualCPU() {
+	if cpuTasks := t.k.cpuTasks; cpuTasks != nil {
+		cpuTasks[t.runningCPU].Add(-1)
+	}
+}
+
 // Niceness returns t's niceness.
 func (t *Task) Niceness() int {
 	t.mu.Lock()
@@ -204,7 +204,11 @@ func (t *Task) deliverSignal(info *linux.SignalInfo, act linux.SigAction) taskRu
 		t.Debugf("Signal %d, PID: %d, TID: %d, fault addr: %#x: terminating thread group", info.Signo, ucs.Pid, ucs.Tid, ucs.FaultAddr)
 		eventchannel.Emit(ucs)
 
-		t.PrepareGroupExit(linux.WaitStatusTerminationSignal(sig))
+		if sigact == SignalActionCore {
+			t.coreDumpAndExit(info)
+		} else {
+			t.PrepareGroupExit(linux.WaitStatusTerminationSignal(sig))
+		}
 		return (*runExit)(nil)
 
 	case SignalActionStop:
@@ -250,7 +254,7 @@ func (t *Task) deliverSignalToHandler(info *linux.SignalInfo, act linux.SigActio
 	alt := t.signalStack
 	if act.Flags&linux.SA_ONSTACK != 0 && alt.IsEnabled() {
 		alt.Flags |= linux.SS_ONSTACK
-		if !alt.Contains(sp) {
+		if !t.onSignalStack(alt) {
 			sp = hostarch.Addr(alt.Top())
 		}
 	}
@@ -287,6 +291,11 @@ func (t *Task) deliverSignalToHandler(info *linux.SignalInfo, act linux.SigActio
 	t.p.FullStateChanged()
 	t.haveSavedSignalMask = false
 
+	// The signal stack saved in the frame is restored by sigreturn.
+	if t.signalStack.Flags&linux.SS_AUTODISARM != 0 {
+		t.signalStack = linux.SignalStack{Flags: linux.SS_DISABLE}
+	}
+
 	// Add our signal mask.
 	newMask := linux.SignalSet(t.signalMask.Load()) | act.Mask
 	if act.Flags&linux.SA_NODEFER == 0 {
@@ -313,9 +322,9 @@ func (t *Task) SignalReturn(rt bool) (*SyscallControl, error) {
 	}
 
 	// Attempt to record the given signal stack. Note that we silently
-	// ignore failures here, as does Linux. Only an EFAULT may be
-	// generated, but SignalRestore has already deserialized the entire
-	// frame successfully.
+	// ignore failures here, as does Linux: SignalRestore has already
+	// deserialized the entire frame successfully, and an invalid or
+	// in-use signal stack is simply not restored.
 	t.SetSignalStack(alt)
 
 	// Restore our signal mask. SIGKILL and SIGSTOP should not be blocked.
@@ -675,15 +684,20 @@ func (t *Task) SigaltStack(setaddr hostarch.Addr, oldaddr hostarch.Addr) (*Sysca
 		// on the stack. This is enforced at the lowest level because
 		// these semantics apply to changing the signal stack via a
 		// ucontext during a signal handler.
-		if !t.SetSignalStack(alt) {
-			return nil, linuxerr.EPERM
+		if err := t.SetSignalStack(alt); err != nil {
+			return nil, err
 		}
 	}
 	return nil, nil
 }
 
-// onSignalStack returns true if the task is executing on the given signal stack.
+// onSignalStack returns true if the task is executing on the given signal
+// stack. A stack with SS_AUTODISARM is never considered to be in use, since
+// it is disarmed while a signal handler runs on it.
 func (t *Task) onSignalStack(alt linux.SignalStack) bool {
+	if alt.Flags&linux.SS_AUTODISARM != 0 {
+		return false
+	}
 	sp := hostarch.Addr(t.Arch().Stack())
 	return alt.Contains(sp)
 }
@@ -691,25 +705,32 @@ func (t *Task) onSignalStack(alt linux.SignalStack) bool {
 // SetSignalStack sets the task-private signal stack.
 //
 // This value may not be changed if the task is currently executing on the
-// signal stack, i.e. if t.onSignalStack returns true. In this case, this
-// function will return false. Otherwise, true is returned.
-func (t *Task) SetSignalStack(alt linux.SignalStack) bool {
+// signal stack, i.e. if t.onSignalStack returns true, in which case
+// SetSignalStack returns EPERM. It returns EINVAL if alt.Flags is invalid and
+// ENOMEM if an enabled stack is smaller than MINSIGSTKSZ.
+func (t *Task) SetSignalStack(alt linux.SignalStack) error {
 	// Check that we're not executing on the stack.
 	if t.onSignalStack(t.signalStack) {
-		return false
+		return linuxerr.EPERM
 	}
 
-	if alt.Flags&linux.SS_DISABLE != 0 {
+	switch alt.Flags &^ linux.SS_FLAG_BITS {
+	case linux.SS_DISABLE:
 		// Don't record anything beyond the flags.
 		t.signalStack = linux.SignalStack{
-			Flags: linux.SS_DISABLE,
+			Flags: alt.Flags,
+		}
+	case 0, linux.SS_ONSTACK:
+		if alt.Size < linux.MINSIGSTKSZ {
+			return linuxerr.ENOMEM
 		}
-	} else {
-		// Mask out irrelevant parts: only disable matters.
-		alt.Flags &= linux.SS_DISABLE
+		// Mask out irrelevant parts: SS_ONSTACK is ignored.
+		alt.Flags &= linux.SS_FLAG_BITS
 		t.signalStack = alt
+	default:
+		return linuxerr.EINVAL
 	}
-	return true
+	return nil
 }
 
 // SetSigAction atomically sets the thread group's signal action for signal sig
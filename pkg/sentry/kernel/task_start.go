@@ -94,9 +94,15 @@ type TaskConfig struct {
 	// ContainerID is the container the new task belongs to.
 	ContainerID string
 
-	// InitialCgroups are the cgroups the container is initialised to.
+	// InitialCgroups are the cgroups the container is initialised to. They
+	// are also used for tasks created by clone3(2) with CLONE_INTO_CGROUP.
 	InitialCgroups map[Cgroup]struct{}
 
+	// SetTIDs, if not empty, are the TIDs of the new task in its PID
+	// namespace and its ancestors, starting with its own. TIDs in the
+	// remaining ancestors are allocated as usual.
+	SetTIDs []ThreadID
+
 	// UserCounters is user resource counters.
 	UserCounters *UserCounters
 
@@ -171,6 +177,7 @@ func (ts *TaskSet) newTask(ctx context.Context, cfg *TaskConfig) (*Task, error)
 		rseqSignature:  cfg.RSeqSignature,
 		futexWaiter:    futex.NewWaiter(),
 		containerID:    cfg.ContainerID,
+		syscallPolicy:  cfg.Kernel.syscallPolicy(cfg.ContainerID),
 		cgroups:        make(map[Cgroup]struct{}),
 		userCounters:   cfg.UserCounters,
 		sessionKeyring: cfg.SessionKeyring,
@@ -196,7 +203,12 @@ func (ts *TaskSet) newTask(ctx context.Context, cfg *TaskConfig) (*Task, error)
 	// bypasses pid limits.
 	if srcT != nil {
 		var err error
-		if charged, cg, err = srcT.ChargeFor(t, CgroupControllerPIDs, CgroupResourcePID, 1); err != nil {
+		if cfg.InitialCgroups != nil {
+			charged, cg, err = chargeCgroupsFor(cfg.InitialCgroups, t, CgroupControllerPIDs, CgroupResourcePID, 1)
+		} else {
+			charged, cg, err = srcT.ChargeFor(t, CgroupControllerPIDs, CgroupResourcePID, 1)
+		}
+		if err != nil {
 			return nil, err
 		}
 		if charged {
@@ -226,7 +238,7 @@ func (ts *TaskSet) newTask(ctx context.Context, cfg *TaskConfig) (*Task, error)
 		// we're in uncharted territory and can return whatever we want.
 		return nil, linuxerr.EINTR
 	}
-	if err := ts.assignTIDsLocked(t); err != nil {
+	if err := ts.assignTIDsLocked(t, cfg.SetTIDs); err != nil {
 		return nil, err
 	}
 	// Below this point, newTask is expected not to fail (there is no rollback
@@ -281,6 +293,8 @@ func (ts *TaskSet) newTask(ctx context.Context, cfg *TaskConfig) (*Task, error)
 	defer t.mu.Unlock()
 
 	t.cpu = atomicbitops.FromInt32(assignCPU(t.allowedCPUMask, ts.Root.tids[t]))
+	allowed := t.allowedCPUMask
+	t.allowedCPUs.Store(&allowed)
 
 	t.startTime = t.k.RealtimeClock().Now()
 
@@ -292,10 +306,12 @@ func (ts *TaskSet) newTask(ctx context.Context, cfg *TaskConfig) (*Task, error)
 }
 
 // assignTIDsLocked ensures that new task t is visible in all PID namespaces in
-// which it should be visible.
+// which it should be visible. If setTIDs is not empty, it contains the TIDs
+// that t must have in its PID namespace and its ancestors, starting with its
+// own (see TaskConfig.SetTIDs).
 //
 // Preconditions: ts.mu must be locked for writing.
-func (ts *TaskSet) assignTIDsLocked(t *Task) error {
+func (ts *TaskSet) assignTIDsLocked(t *Task, setTIDs []ThreadID) error {
 	type allocatedTID struct {
 		ns  *PIDNamespace
 		tid ThreadID
@@ -303,8 +319,14 @@ func (ts *TaskSet) assignTIDsLocked(t *Task) error {
 	var allocatedTIDs []allocatedTID
 	var tid ThreadID
 	var err error
-	for ns := t.tg.pidns; ns != nil; ns = ns.parent {
-		if tid, err = ns.allocateTID(); err != nil {
+	for ns, level := t.tg.pidns, 0; ns != nil; ns, level = ns.parent, level+1 {
+		if level < len(setTIDs) {
+			tid = setTIDs[level]
+			err = ns.checkSetTID(tid)
+		} else {
+			tid, err = ns.allocateTID()
+		}
+		if err != nil {
 			break
 		}
 		if err = ns.addTask(t, tid); err != nil {
@@ -344,20 +366,7 @@ func (ns *PIDNamespace) allocateTID() (ThreadID, error) {
 		}
 
 		// Is it available?
-		tidInUse := func() bool {
-			if _, ok := ns.tasks[tid]; ok {
-				return true
-			}
-			if _, ok := ns.processGroups[ProcessGroupID(tid)]; ok {
-				return true
-			}
-			if _, ok := ns.sessions[SessionID(tid)]; ok {
-				return true
-			}
-			return false
-		}()
-
-		if !tidInUse {
+		if !ns.tidInUse(tid) {
 			ns.last = tid
 			return tid, nil
 		}
@@ -370,6 +379,42 @@ func (ns *PIDNamespace) allocateTID() (ThreadID, error) {
 	}
 }
 
+// checkSetTID returns an error if tid, requested by clone3(2)'s set_tid, can't
+// be used in ns.
+//
+// Preconditions: ns.owner.mu must be locked for writing.
+func (ns *PIDNamespace) checkSetTID(tid ThreadID) error {
+	if ns.exiting {
+		// As in allocateTID.
+		return linuxerr.ENOMEM
+	}
+	// The first task in a PID namespace is always its init process.
+	if _, ok := ns.tasks[initTID]; !ok && tid != initTID {
+		return linuxerr.EINVAL
+	}
+	if ns.tidInUse(tid) {
+		return linuxerr.EEXIST
+	}
+	return nil
+}
+
+// tidInUse returns true if tid is used by a task, process group or session in
+// ns.
+//
+// Preconditions: ns.owner.mu must be locked.
+func (ns *PIDNamespace) tidInUse(tid ThreadID) bool {
+	if _, ok := ns.tasks[tid]; ok {
+		return true
+	}
+	if _, ok := ns.processGroups[ProcessGroupID(tid)]; ok {
+		return true
+	}
+	if _, ok := ns.sessions[SessionID(tid)]; ok {
+		return true
+	}
+	return false
+}
+
 // Start starts the task goroutine. Start must be called exactly once for each
 // task returned by NewTask.
 //
@@ -106,11 +106,11 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 			info.ContextData = &pb.ContextData{}
 			LoadSeccheckData(t, fields.Context, info.ContextData)
 		}
-		seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
+		err = seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
 			return c.RawSyscall(t, fields, &info)
 		})
 	}
-	if bits.IsAnyOn32(fe, SecCheckEnter) {
+	if err == nil && bits.IsAnyOn32(fe, SecCheckEnter) {
 		fields := seccheck.Global.GetFieldSet(seccheck.GetPointForSyscall(seccheck.SyscallEnter, sysno))
 		var ctxData *pb.ContextData
 		if !fields.Context.Empty() {
@@ -123,12 +123,23 @@ func (t *Task) executeSyscall(sysno uintptr, args arch.SyscallArguments) (rval u
 		}
 		cb := s.LookupSyscallToProto(sysno)
 		msg, msgType := cb(t, fields, ctxData, info)
-		seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
+		err = seccheck.Global.SentToSinks(func(c seccheck.Sink) error {
 			return c.Syscall(t, fields, ctxData, msgType, msg)
 		})
 	}
 
-	if bits.IsOn32(fe, ExternalBeforeEnable) && (s.ExternalFilterBefore == nil || s.ExternalFilterBefore(t, sysno, args)) {
+	if err == nil && bits.IsOn32(fe, FaultInjectEnable) {
+		err = t.injectSyscallFault(sysno)
+	}
+
+	if err == nil && t.syscallPolicy != nil {
+		ctrl, err = t.checkSyscallPolicy(sysno, args)
+	}
+
+	if ctrl != nil || err != nil {
+		// A sink rejected the syscall, a fault was injected or the syscall
+		// policy denied it, don't execute the syscall.
+	} else if bits.IsOn32(fe, ExternalBeforeEnable) && (s.ExternalFilterBefore == nil || s.ExternalFilterBefore(t, sysno, args)) {
 		t.invokeExternal()
 		// Ensure we check for stops, then invoke the syscall again.
 		ctrl = ctrlStopAndReinvokeSyscall
@@ -255,6 +266,7 @@ func (t *Task) doSyscall() taskRunState {
 	}
 
 	syscallCounter.Increment()
+	t.ftraceSyscallEnter(sysno, args)
 	return t.doSyscallEnter(sysno, args)
 }
 
@@ -320,13 +332,18 @@ func (*runSyscallAfterSysemuStop) execute(t *Task) taskRunState {
 }
 
 func (t *Task) doSyscallInvoke(sysno uintptr, args arch.SyscallArguments) taskRunState {
+	op := syscallLatency.Start()
+	t.syscallNo.Store(int64(sysno) + 1)
 	rval, ctrl, err := t.executeSyscall(sysno, args)
+	t.syscallNo.Store(0)
+	op.Finish()
 
 	if ctrl != nil {
 		if !ctrl.ignoreReturn {
 			t.Arch().SetReturn(rval)
 		}
 		if ctrl.next != nil {
+			t.ftraceSyscallExit(sysno)
 			return ctrl.next
 		}
 	} else if err != nil {
@@ -336,6 +353,7 @@ func (t *Task) doSyscallInvoke(sysno uintptr, args arch.SyscallArguments) taskRu
 		t.Arch().SetReturn(rval)
 	}
 
+	t.ftraceSyscallExit(sysno)
 	return (*runSyscallExit)(nil).execute(t)
 }
 
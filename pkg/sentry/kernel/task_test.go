@@ -17,6 +17,7 @@ package kernel
 import (
 	"testing"
 
+	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/sched"
 )
 
@@ -73,3 +74,49 @@ func TestTaskCPU(t *testing.T) {
 	}
 
 }
+
+func TestVirtualCPUAssignment(t *testing.T) {
+	k := &Kernel{cpuTasks: make([]atomicbitops.Int32, 4)}
+	newTask := func(cpu int32, allowed sched.CPUSet) *Task {
+		task := &Task{k: k, cpu: atomicbitops.FromInt32(cpu)}
+		task.allowedCPUs.Store(&allowed)
+		return task
+	}
+	all := sched.NewFullCPUSet(4)
+
+	// The first task stays on its previous CPU.
+	t1 := newTask(1, all)
+	t1.acquireVirtualCPU()
+	if got := t1.CPU(); got != 1 {
+		t.Errorf("first task CPU = %d, want 1", got)
+	}
+
+	// A second task whose previous CPU is busy migrates to the next idle
+	// CPU.
+	t2 := newTask(1, all)
+	t2.acquireVirtualCPU()
+	if got := t2.CPU(); got != 2 {
+		t.Errorf("second task CPU = %d, want 2", got)
+	}
+
+	// A task that may only run on a busy CPU shares it.
+	t3 := newTask(1, sched.CPUSet{0x2})
+	t3.acquireVirtualCPU()
+	if got := t3.CPU(); got != 1 {
+		t.Errorf("pinned task CPU = %d, want 1", got)
+	}
+
+	// Once the first task blocks, its CPU becomes idle only after all
+	// tasks running on it have released it.
+	t1.releaseVirtualCPU()
+	t4 := newTask(1, sched.CPUSet{0x3})
+	t4.acquireVirtualCPU()
+	if got := t4.CPU(); got != 0 {
+		t.Errorf("fourth task CPU = %d, want 0", got)
+	}
+	t3.releaseVirtualCPU()
+	t1.acquireVirtualCPU()
+	if got := t1.CPU(); got != 1 {
+		t.Errorf("first task CPU after unblocking = %d, want 1", got)
+	}
+}
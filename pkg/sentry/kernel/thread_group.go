@@ -27,6 +27,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/waiter"
 )
 
 // A ThreadGroup is a logical grouping of tasks that has widespread
@@ -152,6 +153,10 @@ type ThreadGroup struct {
 	// terminationSignal is protected by the TaskSet mutex.
 	terminationSignal linux.Signal
 
+	// exitQueue is notified with waiter.EventIn once every task in the thread
+	// group has exited. It is used to poll pidfds.
+	exitQueue waiter.Queue
+
 	// liveGoroutines is the number of non-exited task goroutines in the thread
 	// group.
 	//
@@ -202,6 +207,10 @@ type ThreadGroup struct {
 	// group. childCPUStats is protected by the TaskSet mutex.
 	childCPUStats usage.CPUStats
 
+	// prevCPUTime is the CPU time last reported for the thread group by
+	// AdjustedCPUStats.
+	prevCPUTime usage.PrevCPUTime
+
 	// ioUsage is the I/O usage for all exited tasks in the thread group.
 	// The ioUsage pointer is immutable.
 	ioUsage *usage.IO
@@ -247,11 +256,14 @@ type ThreadGroup struct {
 	// tty is protected by the signal mutex.
 	tty *TTY
 
-	// oomScoreAdj is the thread group's OOM score adjustment. This is
-	// currently not used but is maintained for consistency.
-	// TODO(gvisor.dev/issue/1967)
+	// oomScoreAdj is the thread group's OOM score adjustment, which biases
+	// the OOM killer's victim selection. See oom.go.
 	oomScoreAdj atomicbitops.Int32
 
+	// oomVictim is true if the thread group has been killed by the OOM
+	// killer. oomVictim is protected by the TaskSet mutex.
+	oomVictim bool
+
 	// isChildSubreaper and hasChildSubreaper correspond to Linux's
 	// signal_struct::is_child_subreaper and has_child_subreaper.
 	//
@@ -19,6 +19,7 @@ import (
 
 	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/waiter"
 )
@@ -76,6 +77,10 @@ type TaskSet struct {
 	// always reset to zero after restore.
 	stopCount int32 `state:"nosave"`
 
+	// exitedCPUStats is the CPU usage of all exited tasks in the TaskSet.
+	// exitedCPUStats is protected by mu.
+	exitedCPUStats usage.CPUStats
+
 	// liveGoroutines is the number of non-exited task goroutines in the
 	// TaskSet.
 	//
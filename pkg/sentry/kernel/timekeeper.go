@@ -16,6 +16,7 @@ package kernel
 
 import (
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"gvisor.dev/gvisor/pkg/atomicbitops"
@@ -59,6 +60,11 @@ type Timekeeper struct {
 	// monotonicLowerBound is the lowerBound for monotonic time.
 	monotonicLowerBound atomicbitops.Int64 `state:"nosave"`
 
+	// skew, if not nil, is the skew applied to the clocks. See SetClockSkew.
+	//
+	// skew is not saved, since it is relative to clocks.
+	skew atomic.Pointer[timekeeperSkew] `state:"nosave"`
+
 	// restored, if non-nil, indicates that this Timekeeper was restored
 	// from a state file. The clocks are not set until restored is closed.
 	restored chan struct{} `state:"nosave"`
@@ -72,7 +78,8 @@ type Timekeeper struct {
 	// monotonicOffset.
 	saveMonotonic int64
 
-	// saveRealtime is the value of the realtime clock at the time of save.
+	// saveRealtime is the value of the realtime clock, without skew, at the
+	// time of save.
 	//
 	// It is only valid if restored is non-nil.
 	//
@@ -232,6 +239,7 @@ func (t *Timekeeper) startUpdater() {
 			// Write.
 			if err := t.params.Write(func() vdsoParams {
 				monotonicParams, monotonicOk, realtimeParams, realtimeOk := t.clocks.Update()
+				skew := t.skew.Load()
 
 				var p vdsoParams
 				if monotonicOk {
@@ -239,12 +247,20 @@ func (t *Timekeeper) startUpdater() {
 					p.monotonicBaseCycles = int64(monotonicParams.BaseCycles)
 					p.monotonicBaseRef = int64(monotonicParams.BaseRef) + t.monotonicOffset
 					p.monotonicFrequency = monotonicParams.Frequency
+					if skew != nil {
+						p.monotonicBaseRef = skew.monotonic.apply(p.monotonicBaseRef)
+						p.monotonicFrequency = skew.monotonic.frequency(p.monotonicFrequency)
+					}
 				}
 				if realtimeOk {
 					p.realtimeReady = 1
 					p.realtimeBaseCycles = int64(realtimeParams.BaseCycles)
 					p.realtimeBaseRef = int64(realtimeParams.BaseRef)
 					p.realtimeFrequency = realtimeParams.Frequency
+					if skew != nil {
+						p.realtimeBaseRef = skew.realtime.apply(p.realtimeBaseRef)
+						p.realtimeFrequency = skew.realtime.frequency(p.realtimeFrequency)
+					}
 				}
 				return p
 			}); err != nil {
@@ -306,8 +322,18 @@ func (t *Timekeeper) GetTime(c sentrytime.ClockID) (int64, error) {
 		<-t.restored
 	}
 	now, err := t.clocks.GetTime(c)
-	if err == nil && c == sentrytime.Monotonic {
+	if err != nil {
+		return now, err
+	}
+	skew := t.skew.Load()
+	if c == sentrytime.Realtime && skew != nil {
+		now = skew.realtime.apply(now)
+	}
+	if c == sentrytime.Monotonic {
 		now += t.monotonicOffset
+		if skew != nil {
+			now = skew.monotonic.apply(now)
+		}
 		for {
 			// It's possible that the clock is shaky. This may be due to
 			// platform issues, e.g. the KVM platform relies on the guest
@@ -327,6 +353,14 @@ func (t *Timekeeper) GetTime(c sentrytime.ClockID) (int64, error) {
 	return now, err
 }
 
+// advanceIdle is called when no task is running. If t uses logical clocks, it
+// advances them to the next timer deadline, since tasks are idle until then.
+func (t *Timekeeper) advanceIdle() {
+	if lc, ok := t.clocks.(*sentrytime.LogicalClocks); ok {
+		lc.AdvanceToNextDeadline()
+	}
+}
+
 // BootTime returns the system boot real time.
 func (t *Timekeeper) BootTime() ktime.Time {
 	return t.bootTime
@@ -340,14 +374,43 @@ type timekeeperClock struct {
 	tk *Timekeeper
 	c  sentrytime.ClockID
 
-	// Implements ktime.Clock.WallTimeUntil.
-	ktime.WallRateClock `state:"nosave"`
-
 	// Implements waiter.Waitable. (We have no ability to detect
 	// discontinuities from external changes to CLOCK_REALTIME).
 	ktime.NoClockEvents `state:"nosave"`
 }
 
+// logicalClockPollInterval is the interval at which timers check for
+// expirations when the Timekeeper uses logical clocks, since logical time
+// doesn't elapse at the rate of wall time.
+const logicalClockPollInterval = time.Millisecond
+
+// WallTimeUntil implements ktime.Clock.WallTimeUntil.
+func (tc *timekeeperClock) WallTimeUntil(t, now ktime.Time) time.Duration {
+	lc, ok := tc.tk.clocks.(*sentrytime.LogicalClocks)
+	if !ok {
+		if skew := tc.tk.skew.Load(); skew != nil {
+			return time.Duration(float64(t.Sub(now)) / skew.monotonic.scale)
+		}
+		return t.Sub(now)
+	}
+	if !now.Before(t) {
+		return 0
+	}
+	deadline := t.Nanoseconds()
+	if skew := tc.tk.skew.Load(); skew != nil {
+		if tc.c == sentrytime.Monotonic {
+			deadline = skew.monotonic.invert(deadline)
+		} else {
+			deadline = skew.realtime.invert(deadline)
+		}
+	}
+	if tc.c == sentrytime.Monotonic {
+		deadline -= tc.tk.monotonicOffset
+	}
+	lc.AddDeadline(tc.c, deadline)
+	return logicalClockPollInterval
+}
+
 // Now implements ktime.Clock.Now.
 func (tc *timekeeperClock) Now() ktime.Time {
 	now, err := tc.tk.GetTime(tc.c)
@@ -0,0 +1,139 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kernel
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	sentrytime "gvisor.dev/gvisor/pkg/sentry/time"
+)
+
+// ClockSkew describes how the clocks of a Timekeeper deviate from the clocks
+// that back it.
+type ClockSkew struct {
+	// RealtimeOffset is added to CLOCK_REALTIME.
+	RealtimeOffset time.Duration `json:"realtimeOffset,omitempty"`
+
+	// MonotonicOffset is added to CLOCK_MONOTONIC. It must not be negative.
+	MonotonicOffset time.Duration `json:"monotonicOffset,omitempty"`
+
+	// Scale is the rate at which both clocks elapse relative to the clocks
+	// that back them, e.g. 2 makes time elapse twice as fast. Zero is
+	// equivalent to 1.
+	Scale float64 `json:"scale,omitempty"`
+}
+
+// clockTransform maps the time of a backing clock to the time of a skewed
+// clock.
+type clockTransform struct {
+	// srcBase is a time of the backing clock.
+	srcBase int64
+
+	// dstBase is the skewed time at srcBase.
+	dstBase int64
+
+	// scale is the rate at which the skewed clock elapses relative to the
+	// backing clock.
+	scale float64
+}
+
+// apply returns the skewed time at src.
+func (c *clockTransform) apply(src int64) int64 {
+	return c.dstBase + int64(float64(src-c.srcBase)*c.scale)
+}
+
+// invert returns the time of the backing clock at skewed time dst.
+func (c *clockTransform) invert(dst int64) int64 {
+	return c.srcBase + int64(float64(dst-c.dstBase)/c.scale)
+}
+
+// frequency returns the cycle clock frequency that makes the skewed clock
+// elapse at the right rate for the backing clock frequency f.
+func (c *clockTransform) frequency(f uint64) uint64 {
+	return uint64(float64(f) / c.scale)
+}
+
+// timekeeperSkew is a ClockSkew applied to a Timekeeper.
+type timekeeperSkew struct {
+	skew      ClockSkew
+	monotonic clockTransform
+	realtime  clockTransform
+}
+
+// SetClockSkew skews the clocks of t. Offsets are relative to the backing
+// clocks at the time of the call, and replace any previous skew. The clocks
+// elapse at the given scale from then on.
+//
+// CLOCK_MONOTONIC never goes backwards: if a previous scale made it run ahead
+// of the backing clock by more than skew.MonotonicOffset, it keeps its current
+// value.
+func (t *Timekeeper) SetClockSkew(skew ClockSkew) error {
+	if skew.MonotonicOffset < 0 {
+		return fmt.Errorf("monotonic clock offset %v is negative", skew.MonotonicOffset)
+	}
+	scale := skew.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	if scale < 0 || math.IsNaN(scale) || math.IsInf(scale, 0) {
+		return fmt.Errorf("invalid clock scale %v", skew.Scale)
+	}
+
+	monotonic, err := t.clocks.GetTime(sentrytime.Monotonic)
+	if err != nil {
+		return err
+	}
+	monotonic += t.monotonicOffset
+	realtime, err := t.clocks.GetTime(sentrytime.Realtime)
+	if err != nil {
+		return err
+	}
+	s := &timekeeperSkew{
+		skew: skew,
+		monotonic: clockTransform{
+			srcBase: monotonic,
+			dstBase: monotonic + skew.MonotonicOffset.Nanoseconds(),
+			scale:   scale,
+		},
+		realtime: clockTransform{
+			srcBase: realtime,
+			dstBase: realtime + skew.RealtimeOffset.Nanoseconds(),
+			scale:   scale,
+		},
+	}
+	if last := t.monotonicLowerBound.Load(); s.monotonic.dstBase < last {
+		s.monotonic.dstBase = last
+	}
+	t.skew.Store(s)
+
+	// Restart the updater so that the VDSO picks up the new skew right away.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stop != nil {
+		t.stopUpdater()
+		t.startUpdater()
+	}
+	return nil
+}
+
+// ClockSkew returns the skew of t's clocks.
+func (t *Timekeeper) ClockSkew() ClockSkew {
+	if s := t.skew.Load(); s != nil {
+		return s.skew
+	}
+	return ClockSkew{}
+}
@@ -32,7 +32,9 @@ func (t *Timekeeper) beforeSave() {
 		panic("unable to get current monotonic time: " + err.Error())
 	}
 
-	if t.saveRealtime, err = t.GetTime(time.Realtime); err != nil {
+	// Skew isn't saved, so realtime is only used to compute the time elapsed
+	// until restore.
+	if t.saveRealtime, err = t.clocks.GetTime(time.Realtime); err != nil {
 		panic("unable to get current realtime: " + err.Error())
 	}
 }
@@ -154,3 +154,48 @@ func TestTimekeeperMonotonicJumpBackwards(t *testing.T) {
 		t.Errorf("GetTime got %d want 100000", now)
 	}
 }
+
+// TestTimekeeperClockSkew tests that clock skew offsets and scales the clocks,
+// and that monotonic time doesn't go backwards when the skew changes.
+func TestTimekeeperClockSkew(t *testing.T) {
+	c := &mockClocks{
+		monotonic: 100000,
+		realtime:  400000,
+	}
+
+	tk := stateTestClocklessTimekeeper(t)
+	tk.SetClocks(c)
+	defer tk.Destroy()
+
+	if err := tk.SetClockSkew(ClockSkew{RealtimeOffset: 1000, MonotonicOffset: 500, Scale: 2}); err != nil {
+		t.Fatalf("SetClockSkew failed: %v", err)
+	}
+	c.monotonic += 10
+	c.realtime += 10
+	if now, err := tk.GetTime(sentrytime.Monotonic); err != nil || now != 520 {
+		t.Errorf("GetTime(Monotonic) got (%d, %v) want (520, nil)", now, err)
+	}
+	if now, err := tk.GetTime(sentrytime.Realtime); err != nil || now != 401020 {
+		t.Errorf("GetTime(Realtime) got (%d, %v) want (401020, nil)", now, err)
+	}
+
+	// Removing the skew would make monotonic time go backwards.
+	if err := tk.SetClockSkew(ClockSkew{}); err != nil {
+		t.Fatalf("SetClockSkew failed: %v", err)
+	}
+	if now, err := tk.GetTime(sentrytime.Monotonic); err != nil || now != 520 {
+		t.Errorf("GetTime(Monotonic) got (%d, %v) want (520, nil)", now, err)
+	}
+	if now, err := tk.GetTime(sentrytime.Realtime); err != nil || now != 400010 {
+		t.Errorf("GetTime(Realtime) got (%d, %v) want (400010, nil)", now, err)
+	}
+
+	for _, skew := range []ClockSkew{
+		{MonotonicOffset: -1},
+		{Scale: -1},
+	} {
+		if err := tk.SetClockSkew(skew); err == nil {
+			t.Errorf("SetClockSkew(%+v) succeeded, want error", skew)
+		}
+	}
+}
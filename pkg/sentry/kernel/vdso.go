@@ -40,6 +40,10 @@ type vdsoParams struct {
 	realtimeBaseCycles int64
 	realtimeBaseRef    int64
 	realtimeFrequency  uint64
+
+	// rngGeneration is set by VDSOParamPage.Write and need not be set by
+	// callers.
+	rngGeneration uint64
 }
 
 // VDSOParamPage manages a VDSO parameter page.
@@ -54,7 +58,7 @@ type vdsoParams struct {
 //
 // Everything in the struct is 8 bytes for easy alignment.
 //
-// It must be kept in sync with params in vdso/vdso_time.cc.
+// It must be kept in sync with params in vdso/params.h.
 //
 // +stateify savable
 type VDSOParamPage struct {
@@ -71,6 +75,13 @@ type VDSOParamPage struct {
 	// save / restore.
 	seq uint64
 
+	// rngGeneration is the generation of the VDSO's getrandom() states. The
+	// VDSO discards random state generated under a different generation.
+	// rngGeneration is incremented on restore so that restored sandboxes,
+	// possibly several from the same checkpoint, don't return the same random
+	// bytes.
+	rngGeneration uint64
+
 	// copyScratchBuffer is a temporary buffer used to marshal the params before
 	// copying it to the real parameter page. The parameter page is typically
 	// updated at a moderate frequency of ~O(seconds) throughout the lifetime of
@@ -82,6 +93,7 @@ type VDSOParamPage struct {
 // afterLoad is invoked by stateify.
 func (v *VDSOParamPage) afterLoad(ctx context.Context) {
 	v.mf = pgalloc.MemoryFileFromContext(ctx)
+	v.rngGeneration++
 }
 
 // NewVDSOParamPage returns a VDSOParamPage.
@@ -94,8 +106,10 @@ func (v *VDSOParamPage) afterLoad(ctx context.Context) {
 //   - mf.MapInternal(fr) must return a single safemem.Block.
 func NewVDSOParamPage(mf *pgalloc.MemoryFile, fr memmap.FileRange) *VDSOParamPage {
 	return &VDSOParamPage{
-		mf:                mf,
-		fr:                fr,
+		mf: mf,
+		fr: fr,
+		// States in freshly allocated memory have generation 0.
+		rngGeneration:     1,
 		copyScratchBuffer: make([]byte, (*vdsoParams)(nil).SizeBytes()),
 	}
 }
@@ -151,6 +165,7 @@ func (v *VDSOParamPage) Write(f func() vdsoParams) error {
 
 	// Get the new params.
 	p := f()
+	p.rngGeneration = v.rngGeneration
 	buf := v.copyScratchBuffer[:p.SizeBytes()]
 	p.MarshalUnsafe(buf)
 
@@ -42,3 +42,23 @@ func FromContextOrDie(ctx context.Context) *LimitSet {
 	}
 	panic("failed to create limit set from context")
 }
+
+// ContextWithLimits returns a copy of ctx carrying limits.
+func ContextWithLimits(ctx context.Context, limits *LimitSet) context.Context {
+	return &limitsContext{ctx, limits}
+}
+
+type limitsContext struct {
+	context.Context
+	limits *LimitSet
+}
+
+// Value implements context.Context.
+func (lc *limitsContext) Value(key any) any {
+	switch key {
+	case CtxLimits:
+		return lc.limits
+	default:
+		return lc.Context.Value(key)
+	}
+}
@@ -0,0 +1,384 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const (
+	// binprmBufSize is the number of bytes of an executable that are
+	// available for matching against binfmt_misc magic numbers. From Linux's
+	// include/uapi/linux/binfmts.h:BINPRM_BUF_SIZE.
+	binprmBufSize = 256
+
+	// MaxBinfmtRegisterLength is the maximum length of a binfmt_misc
+	// registration string. From Linux's fs/binfmt_misc.c:MAX_REGISTER_LENGTH.
+	MaxBinfmtRegisterLength = 1920
+)
+
+// BinfmtEntry is an interpreter registered with binfmt_misc. All fields other
+// than enabled are immutable after registration.
+//
+// +stateify savable
+type BinfmtEntry struct {
+	// Name is the name of the entry, as it appears in the binfmt_misc
+	// filesystem.
+	Name string
+
+	// Extension is true if the entry matches files by file name extension
+	// (type 'E') rather than by magic number (type 'M').
+	Extension bool
+
+	// Offset is the offset in the file at which Magic is matched.
+	Offset int
+
+	// Magic is the magic number, or the file name extension if Extension is
+	// true.
+	Magic []byte
+
+	// Mask is the mask applied to the file contents before comparing with
+	// Magic. If nil, all bits are compared.
+	Mask []byte
+
+	// Interpreter is the path of the interpreter.
+	Interpreter string
+
+	// PreserveArgv0 is true if the 'P' flag was set, in which case the
+	// original argv[0] is passed to the interpreter after the file name.
+	PreserveArgv0 bool
+
+	// FixBinary is true if the 'F' flag was set. In this case File is the
+	// interpreter, opened when the entry was registered.
+	FixBinary bool
+
+	// File is the interpreter opened at registration time if FixBinary is
+	// true, and nil otherwise. The entry holds a reference on File.
+	File *vfs.FileDescription
+
+	enabled atomicbitops.Bool
+}
+
+// ParseBinfmtEntry parses a binfmt_misc registration string of the form
+// ":name:type:offset:magic:mask:interpreter:flags", where ':' may be any
+// delimiter character. The returned entry is enabled.
+//
+// If the 'F' flag is set, the caller is responsible for opening the
+// interpreter and setting File before the entry is registered.
+func ParseBinfmtEntry(spec string) (*BinfmtEntry, error) {
+	if len(spec) < 11 || len(spec) > MaxBinfmtRegisterLength {
+		return nil, linuxerr.EINVAL
+	}
+	spec = strings.TrimSuffix(spec, "\n")
+	delim := spec[:1]
+	fields := strings.Split(spec[1:], delim)
+	if len(fields) != 7 {
+		return nil, linuxerr.EINVAL
+	}
+	e := &BinfmtEntry{
+		Name:        fields[0],
+		Interpreter: fields[5],
+	}
+	e.enabled.Store(true)
+	if e.Name == "" || e.Name == "." || e.Name == ".." || strings.Contains(e.Name, "/") {
+		return nil, linuxerr.EINVAL
+	}
+
+	switch fields[1] {
+	case "E":
+		e.Extension = true
+		if fields[2] != "" || fields[4] != "" || fields[3] == "" || strings.Contains(fields[3], "/") {
+			return nil, linuxerr.EINVAL
+		}
+		e.Magic = []byte(fields[3])
+	case "M":
+		if fields[2] != "" {
+			off, err := strconv.ParseUint(fields[2], 10, 32)
+			if err != nil {
+				return nil, linuxerr.EINVAL
+			}
+			e.Offset = int(off)
+		}
+		var err error
+		if e.Magic, err = unescapeBinfmt(fields[3]); err != nil {
+			return nil, err
+		}
+		if len(e.Magic) == 0 || e.Offset+len(e.Magic) > binprmBufSize {
+			return nil, linuxerr.EINVAL
+		}
+		if fields[4] != "" {
+			if e.Mask, err = unescapeBinfmt(fields[4]); err != nil {
+				return nil, err
+			}
+			if len(e.Mask) != len(e.Magic) {
+				return nil, linuxerr.EINVAL
+			}
+		}
+	default:
+		return nil, linuxerr.EINVAL
+	}
+
+	if e.Interpreter == "" {
+		return nil, linuxerr.EINVAL
+	}
+
+	for _, f := range fields[6] {
+		switch f {
+		case 'P':
+			e.PreserveArgv0 = true
+		case 'F':
+			e.FixBinary = true
+		default:
+			// 'O' and 'C' require passing the binary to the interpreter
+			// as an open file descriptor, which is not supported.
+			return nil, linuxerr.EINVAL
+		}
+	}
+	return e, nil
+}
+
+// unescapeBinfmt decodes the "\xHH" and "\\" escapes permitted in binfmt_misc
+// magic and mask fields.
+func unescapeBinfmt(s string) ([]byte, error) {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b = append(b, s[i])
+			continue
+		}
+		switch {
+		case i+1 < len(s) && s[i+1] == '\\':
+			b = append(b, '\\')
+			i++
+		case i+3 < len(s) && s[i+1] == 'x':
+			v, err := strconv.ParseUint(s[i+2:i+4], 16, 8)
+			if err != nil {
+				return nil, linuxerr.EINVAL
+			}
+			b = append(b, byte(v))
+			i += 3
+		default:
+			return nil, linuxerr.EINVAL
+		}
+	}
+	return b, nil
+}
+
+// Enabled returns true if the entry is enabled.
+func (e *BinfmtEntry) Enabled() bool {
+	return e.enabled.Load()
+}
+
+// SetEnabled enables or disables the entry.
+func (e *BinfmtEntry) SetEnabled(enabled bool) {
+	e.enabled.Store(enabled)
+}
+
+// Status returns the contents of the entry's file in the binfmt_misc
+// filesystem, as in Linux's fs/binfmt_misc.c:entry_status().
+func (e *BinfmtEntry) Status() string {
+	if !e.Enabled() {
+		return "disabled\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "enabled\ninterpreter %s\nflags: ", e.Interpreter)
+	if e.PreserveArgv0 {
+		b.WriteByte('P')
+	}
+	if e.FixBinary {
+		b.WriteByte('F')
+	}
+	b.WriteByte('\n')
+	if e.Extension {
+		fmt.Fprintf(&b, "extension .%s\n", e.Magic)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "offset %d\nmagic %x\n", e.Offset, e.Magic)
+	if e.Mask != nil {
+		fmt.Fprintf(&b, "mask %x\n", e.Mask)
+	}
+	return b.String()
+}
+
+// matches returns true if the executable with the given file name and header
+// matches e.
+func (e *BinfmtEntry) matches(filename string, hdr []byte) bool {
+	if e.Extension {
+		base := path.Base(filename)
+		i := strings.LastIndexByte(base, '.')
+		return i >= 0 && base[i+1:] == string(e.Magic)
+	}
+	if e.Offset+len(e.Magic) > len(hdr) {
+		return false
+	}
+	s := hdr[e.Offset : e.Offset+len(e.Magic)]
+	if e.Mask == nil {
+		return bytes.Equal(s, e.Magic)
+	}
+	for i := range s {
+		if (s[i]^e.Magic[i])&e.Mask[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// BinfmtMisc is the set of interpreters registered with binfmt_misc.
+//
+// +stateify savable
+type BinfmtMisc struct {
+	mu sync.Mutex `state:"nosave"`
+
+	// disabled is true if binfmt_misc matching is globally disabled.
+	//
+	// +checklocks:mu
+	disabled bool
+
+	// entries are the registered entries, in the order in which they are
+	// matched.
+	//
+	// +checklocks:mu
+	entries []*BinfmtEntry
+}
+
+// Enabled returns true if binfmt_misc matching is globally enabled.
+func (b *BinfmtMisc) Enabled() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.disabled
+}
+
+// SetEnabled globally enables or disables binfmt_misc matching.
+func (b *BinfmtMisc) SetEnabled(enabled bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.disabled = !enabled
+}
+
+// Register adds e to the registered entries. The registry takes ownership of
+// e.File's reference on success.
+func (b *BinfmtMisc) Register(e *BinfmtEntry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, other := range b.entries {
+		if other.Name == e.Name {
+			return linuxerr.EEXIST
+		}
+	}
+	// Linux inserts new entries at the front of the list, so the most
+	// recently registered entry takes precedence.
+	b.entries = append([]*BinfmtEntry{e}, b.entries...)
+	return nil
+}
+
+// Lookup returns the entry with the given name, or nil if none exists.
+func (b *BinfmtMisc) Lookup(name string) *BinfmtEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, e := range b.entries {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Names returns the names of all registered entries, in match order.
+func (b *BinfmtMisc) Names() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names := make([]string, 0, len(b.entries))
+	for _, e := range b.entries {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+// Remove removes the entry with the given name.
+func (b *BinfmtMisc) Remove(ctx context.Context, name string) error {
+	b.mu.Lock()
+	var removed *BinfmtEntry
+	for i, e := range b.entries {
+		if e.Name == name {
+			removed = e
+			b.entries = append(b.entries[:i:i], b.entries[i+1:]...)
+			break
+		}
+	}
+	b.mu.Unlock()
+	if removed == nil {
+		return linuxerr.ENOENT
+	}
+	if removed.File != nil {
+		removed.File.DecRef(ctx)
+	}
+	return nil
+}
+
+// Clear removes all registered entries.
+func (b *BinfmtMisc) Clear(ctx context.Context) {
+	b.mu.Lock()
+	entries := b.entries
+	b.entries = nil
+	b.mu.Unlock()
+	for _, e := range entries {
+		if e.File != nil {
+			e.File.DecRef(ctx)
+		}
+	}
+}
+
+// match returns the first enabled entry that matches the executable with the
+// given file name and header, or nil if there is none.
+func (b *BinfmtMisc) match(filename string, hdr []byte) *BinfmtEntry {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.disabled {
+		return nil
+	}
+	for _, e := range b.entries {
+		if e.Enabled() && e.matches(filename, hdr) {
+			return e
+		}
+	}
+	return nil
+}
+
+// binfmtArgv returns the argument vector passed to e's interpreter when it is
+// used to execute filename with the given argv, as in Linux's
+// fs/binfmt_misc.c:load_misc_binary().
+func binfmtArgv(e *BinfmtEntry, filename string, argv []string) []string {
+	newargv := []string{e.Interpreter, filename}
+	if e.PreserveArgv0 {
+		return append(newargv, argv...)
+	}
+	if len(argv) > 0 {
+		argv = argv[1:]
+	}
+	return append(newargv, argv...)
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader
+
+import (
+	"slices"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+)
+
+func TestParseBinfmtEntry(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		spec    string
+		wantErr error
+	}{
+		{
+			name: "magic",
+			spec: `:qemu-aarch64:M::\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\xb7\x00:\xff\xff\xff\xff\xff\xff\xff\x00\xff\xff\xff\xff\xff\xff\xff\xff\xfe\xff\xff\xff:/usr/bin/qemu-aarch64:PF` + "\n",
+		},
+		{
+			name: "extension",
+			spec: ":wasm:E::wasm::/usr/bin/wasmtime:",
+		},
+		{
+			name: "other delimiter",
+			spec: "|foo|M|2|ab||/bin/foo|",
+		},
+		{
+			name:    "bad type",
+			spec:    ":foo:X::ab::/bin/foo:",
+			wantErr: linuxerr.EINVAL,
+		},
+		{
+			name:    "mask length mismatch",
+			spec:    `:foo:M::ab:\xff:/bin/foo:`,
+			wantErr: linuxerr.EINVAL,
+		},
+		{
+			name:    "magic too far",
+			spec:    ":foo:M:255:ab::/bin/foo:",
+			wantErr: linuxerr.EINVAL,
+		},
+		{
+			name:    "no interpreter",
+			spec:    ":foo:M::abcdef:::",
+			wantErr: linuxerr.EINVAL,
+		},
+		{
+			name:    "open binary unsupported",
+			spec:    ":foo:M::ab::/bin/foo:O",
+			wantErr: linuxerr.EINVAL,
+		},
+		{
+			name:    "bad name",
+			spec:    ":..:M::ab::/bin/foo:",
+			wantErr: linuxerr.EINVAL,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseBinfmtEntry(tc.spec)
+			if err != tc.wantErr {
+				t.Errorf("ParseBinfmtEntry(%q) got error %v, want %v", tc.spec, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBinfmtMiscMatch(t *testing.T) {
+	var b BinfmtMisc
+	magic, err := ParseBinfmtEntry(`:magic:M:1:\x12\x34:\xff\x0f:/bin/magic:`)
+	if err != nil {
+		t.Fatalf("ParseBinfmtEntry failed: %v", err)
+	}
+	ext, err := ParseBinfmtEntry(":ext:E::py::/bin/python:P")
+	if err != nil {
+		t.Fatalf("ParseBinfmtEntry failed: %v", err)
+	}
+	for _, e := range []*BinfmtEntry{magic, ext} {
+		if err := b.Register(e); err != nil {
+			t.Fatalf("Register(%q) failed: %v", e.Name, err)
+		}
+	}
+	if err := b.Register(ext); err != linuxerr.EEXIST {
+		t.Errorf("Register of duplicate entry got error %v, want EEXIST", err)
+	}
+
+	hdr := make([]byte, binprmBufSize)
+	hdr[1], hdr[2] = 0x12, 0xf4 // Masked bits differ.
+	if got := b.match("/bin/x", hdr); got != magic {
+		t.Errorf("match by magic got %v, want %v", got, magic)
+	}
+	if got := b.match("/tmp/script.py", make([]byte, binprmBufSize)); got != ext {
+		t.Errorf("match by extension got %v, want %v", got, ext)
+	}
+
+	magic.SetEnabled(false)
+	if got := b.match("/bin/x", hdr); got != nil {
+		t.Errorf("match of disabled entry got %v, want nil", got)
+	}
+	b.SetEnabled(false)
+	if got := b.match("/tmp/script.py", hdr); got != nil {
+		t.Errorf("match with binfmt_misc disabled got %v, want nil", got)
+	}
+}
+
+func TestBinfmtArgv(t *testing.T) {
+	e := &BinfmtEntry{Interpreter: "/bin/interp"}
+	if got, want := binfmtArgv(e, "/bin/prog", []string{"prog", "a"}), []string{"/bin/interp", "/bin/prog", "a"}; !slices.Equal(got, want) {
+		t.Errorf("binfmtArgv got %q, want %q", got, want)
+	}
+	e.PreserveArgv0 = true
+	if got, want := binfmtArgv(e, "/bin/prog", []string{"prog", "a"}), []string{"/bin/interp", "/bin/prog", "prog", "a"}; !slices.Equal(got, want) {
+		t.Errorf("binfmtArgv with P got %q, want %q", got, want)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"path"
+	"strings"
 
 	"gvisor.dev/gvisor/pkg/abi"
 	"gvisor.dev/gvisor/pkg/abi/linux"
@@ -65,6 +66,13 @@ type LoadArgs struct {
 	// The caller is responsible for checking that the user can execute this file.
 	File *vfs.FileDescription
 
+	// NameFromFile indicates that the name of the new image should be derived
+	// from the name of the loaded file rather than from Filename. This is the
+	// case for execveat(AT_EMPTY_PATH), where Filename is a synthetic
+	// "/dev/fd/N" path (Linux: fs/exec.c:begin_new_exec() =>
+	// bprm->comm_from_dentry).
+	NameFromFile bool
+
 	// Root is the current filesystem root.
 	Root vfs.VirtualDentry
 
@@ -90,6 +98,10 @@ type LoadArgs struct {
 
 	// Features specifies the CPU feature set for the executable.
 	Features cpuid.FeatureSet
+
+	// BinfmtMisc is the set of interpreters registered with binfmt_misc. If
+	// nil, no binfmt_misc interpreters are considered.
+	BinfmtMisc *BinfmtMisc
 }
 
 // openPath opens args.Filename and checks that it is valid for loading.
@@ -193,10 +205,12 @@ func loadExecutable(ctx context.Context, args LoadArgs) (loadedELF, *arch.Contex
 			}
 		}
 
-		// Check the header. Is this an ELF or interpreter script?
-		var hdr [4]uint8
+		// Check the header. Is this a binfmt_misc format, an ELF or an
+		// interpreter script? As in Linux, bytes past the end of the file
+		// are zero.
+		var buf [binprmBufSize]uint8
 		// N.B. We assume that reading from a regular file cannot block.
-		_, err := args.File.ReadFull(ctx, usermem.BytesIOSequence(hdr[:]), 0)
+		_, err := args.File.ReadFull(ctx, usermem.BytesIOSequence(buf[:]), 0)
 		// Allow unexpected EOF, as a valid executable could be only three bytes
 		// (e.g., #!a).
 		if err != nil && err != io.ErrUnexpectedEOF {
@@ -205,6 +219,27 @@ func loadExecutable(ctx context.Context, args LoadArgs) (loadedELF, *arch.Contex
 			}
 			return loadedELF{}, nil, nil, nil, err
 		}
+		hdr := buf[:4]
+
+		// binfmt_misc formats take precedence over built-in formats, as in
+		// Linux, so that e.g. foreign-architecture ELF binaries can be
+		// handed to an emulator.
+		if e := args.BinfmtMisc.match(args.Filename, buf[:]); e != nil {
+			if args.CloseOnExec {
+				return loadedELF{}, nil, nil, nil, linuxerr.ENOENT
+			}
+			args.Argv = binfmtArgv(e, args.Filename, args.Argv)
+			args.Filename = e.Interpreter
+			args.File = nil
+			if e.FixBinary {
+				args.File = e.File
+				args.File.IncRef()
+				defer args.File.DecRef(ctx)
+			}
+			// Refresh the traversal limit for the interpreter.
+			*args.RemainingTraversals = linux.MaxSymlinkTraversals
+			continue
+		}
 
 		switch {
 		case bytes.Equal(hdr[:], []byte(elfMagic)):
@@ -305,7 +340,7 @@ func Load(ctx context.Context, args LoadArgs, extraAuxv []arch.AuxEntry, vdso *V
 
 	// Push 16 random bytes on the stack which AT_RANDOM will point to.
 	var b [16]byte
-	if _, err := rand.Read(b[:]); err != nil {
+	if _, err := io.ReadFull(rand.ReaderFromContext(ctx), b[:]); err != nil {
 		return ImageInfo{}, syserr.NewDynamic(fmt.Sprintf("Failed to read random bytes: %v", err), syserr.FromError(err).ToLinux())
 	}
 	if _, err = stack.PushNullTerminatedByteSlice(b[:]); err != nil {
@@ -314,9 +349,13 @@ func Load(ctx context.Context, args LoadArgs, extraAuxv []arch.AuxEntry, vdso *V
 	random := stack.Bottom
 
 	c := auth.CredentialsFromContext(ctx)
+	hwCap1, hwCap2 := args.Features.HWCap()
 
 	// Add generic auxv entries.
 	auxv := append(loaded.auxv, arch.Auxv{
+		arch.AuxEntry{linux.AT_HWCAP, hostarch.Addr(hwCap1)},
+		arch.AuxEntry{linux.AT_HWCAP2, hostarch.Addr(hwCap2)},
+		arch.AuxEntry{linux.AT_MINSIGSTKSZ, hostarch.Addr(arch.MinSigStackSize(args.Features))},
 		arch.AuxEntry{linux.AT_UID, hostarch.Addr(c.RealKUID.In(c.UserNamespace).OrOverflow())},
 		arch.AuxEntry{linux.AT_EUID, hostarch.Addr(c.EffectiveKUID.In(c.UserNamespace).OrOverflow())},
 		arch.AuxEntry{linux.AT_GID, hostarch.Addr(c.RealKGID.In(c.UserNamespace).OrOverflow())},
@@ -350,6 +389,9 @@ func Load(ctx context.Context, args LoadArgs, extraAuxv []arch.AuxEntry, vdso *V
 	ac.SetStack(uintptr(stack.Bottom))
 
 	name := path.Base(args.Filename)
+	if args.NameFromFile {
+		name = path.Base(strings.TrimSuffix(file.MappedName(ctx), " (deleted)"))
+	}
 	if len(name) > linux.TASK_COMM_LEN-1 {
 		name = name[:linux.TASK_COMM_LEN-1]
 	}
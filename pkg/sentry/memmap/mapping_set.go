@@ -234,20 +234,60 @@ func (s *MappingSet) RemoveMapping(ms MappingSpace, ar hostarch.AddrRange, offse
 
 // Invalidate calls MappingSpace.Invalidate for all mappings of offsets in mr.
 func (s *MappingSet) Invalidate(mr MappableRange, opts InvalidateOpts) {
+	var bi batchInvalidations
 	for seg := s.LowerBoundSegment(mr.Start); seg.Ok() && seg.Start() < mr.End; seg = seg.NextSegment() {
 		segMR := seg.Range()
 		for m := range seg.Value() {
 			region := subsetMapping(segMR, segMR.Intersect(mr), m.MappingSpace, m.AddrRange.Start, m.Writable)
-			region.invalidate(opts)
+			bi.invalidate(region, opts)
 		}
 	}
+	bi.flush(opts)
 }
 
 // InvalidateAll calls MappingSpace.Invalidate for all mappings of s.
 func (s *MappingSet) InvalidateAll(opts InvalidateOpts) {
+	var bi batchInvalidations
 	for seg := s.FirstSegment(); seg.Ok(); seg = seg.NextSegment() {
 		for m := range seg.Value() {
-			m.invalidate(opts)
+			bi.invalidate(m, opts)
 		}
 	}
+	bi.flush(opts)
+}
+
+// batchInvalidations defers invalidations of MappingSpaces that implement
+// BatchInvalidator, so that each such MappingSpace is invalidated once for all
+// of its affected ranges. Other MappingSpaces are invalidated immediately.
+type batchInvalidations struct {
+	// spaces is a slice rather than a map since Mappables are usually mapped
+	// into few MappingSpaces.
+	spaces []batchInvalidation
+}
+
+type batchInvalidation struct {
+	bi  BatchInvalidator
+	ars []hostarch.AddrRange
+}
+
+func (b *batchInvalidations) invalidate(r MappingOfRange, opts InvalidateOpts) {
+	bi, ok := r.MappingSpace.(BatchInvalidator)
+	if !ok {
+		r.invalidate(opts)
+		return
+	}
+	for i := range b.spaces {
+		if b.spaces[i].bi == bi {
+			b.spaces[i].ars = append(b.spaces[i].ars, r.AddrRange)
+			return
+		}
+	}
+	b.spaces = append(b.spaces, batchInvalidation{bi, []hostarch.AddrRange{r.AddrRange}})
+}
+
+func (b *batchInvalidations) flush(opts InvalidateOpts) {
+	for _, s := range b.spaces {
+		s.bi.InvalidateBatch(s.ars, opts)
+	}
+	b.spaces = nil
 }
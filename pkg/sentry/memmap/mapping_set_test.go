@@ -36,6 +36,19 @@ func (n *testMappingSpace) Invalidate(ar hostarch.AddrRange, opts InvalidateOpts
 	n.inv = append(n.inv, ar)
 }
 
+// testBatchMappingSpace is a testMappingSpace that implements
+// BatchInvalidator.
+type testBatchMappingSpace struct {
+	testMappingSpace
+
+	// batches records the ranges passed to each call to InvalidateBatch.
+	batches [][]hostarch.AddrRange
+}
+
+func (n *testBatchMappingSpace) InvalidateBatch(ars []hostarch.AddrRange, opts InvalidateOpts) {
+	n.batches = append(n.batches, ars)
+}
+
 func TestAddRemoveMapping(t *testing.T) {
 	set := MappingSet{}
 	ms := &testMappingSpace{}
@@ -185,6 +198,34 @@ func TestInvalidateOverlappingMappings(t *testing.T) {
 	}
 }
 
+func TestInvalidateBatchMappings(t *testing.T) {
+	set := MappingSet{}
+	ms1 := &testBatchMappingSpace{}
+	ms2 := &testMappingSpace{}
+
+	set.AddMapping(ms1, hostarch.AddrRange{0x10000, 0x11000}, 0, true)
+	set.AddMapping(ms1, hostarch.AddrRange{0x20000, 0x21000}, 0x2000, true)
+	set.AddMapping(ms2, hostarch.AddrRange{0x30000, 0x33000}, 0, true)
+	// Mappings:
+	// ms1:[0x10000, 0x11000) => [0, 0x1000)
+	// ms1:[0x20000, 0x21000) => [0x2000, 0x3000)
+	// ms2:[0x30000, 0x33000) => [0, 0x3000)
+	t.Log(&set)
+	set.Invalidate(MappableRange{0, 0x3000}, InvalidateOpts{})
+	if got, want := len(ms1.batches), 1; got != want {
+		t.Fatalf("Invalidate: ms1: got %d batches, wanted %d", got, want)
+	}
+	if got, want := ms1.batches[0], []hostarch.AddrRange{{Start: 0x10000, End: 0x11000}, {Start: 0x20000, End: 0x21000}}; !slices.Equal(got, want) {
+		t.Errorf("Invalidate: ms1: got %+v, wanted %+v", got, want)
+	}
+	if len(ms1.inv) != 0 {
+		t.Errorf("Invalidate: ms1: got unbatched invalidations %+v", ms1.inv)
+	}
+	if len(ms2.inv) == 0 {
+		t.Errorf("Invalidate: ms2: got no invalidations")
+	}
+}
+
 func TestMixedWritableMappings(t *testing.T) {
 	set := MappingSet{}
 	ms := &testMappingSpace{}
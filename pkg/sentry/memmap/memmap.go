@@ -226,6 +226,17 @@ type MappingSpace interface {
 	Invalidate(ar hostarch.AddrRange, opts InvalidateOpts)
 }
 
+// BatchInvalidator is an optional interface implemented by MappingSpaces that
+// can invalidate several address ranges at a lower cost than invalidating
+// them one at a time.
+type BatchInvalidator interface {
+	// InvalidateBatch is equivalent to calling Invalidate for each range in
+	// ars, in order.
+	//
+	// Preconditions: Same as MappingSpace.Invalidate, for each range in ars.
+	InvalidateBatch(ars []hostarch.AddrRange, opts InvalidateOpts)
+}
+
 // InvalidateOpts holds options to MappingSpace.Invalidate.
 type InvalidateOpts struct {
 	// InvalidatePrivate is true if private pages in the invalidated region
@@ -361,6 +372,11 @@ type MMapOpts struct {
 	// MLockMode specifies the memory locking behavior of the mapping.
 	MLockMode MLockMode
 
+	// WipeOnFork is true if the mapping should be replaced by zero-filled
+	// memory in child processes created by fork(), as for MADV_WIPEONFORK.
+	// WipeOnFork requires Private and a nil Mappable.
+	WipeOnFork bool
+
 	// Hint is the name used for the mapping in /proc/[pid]/maps. If Hint is
 	// empty, MappingIdentity.MappedName() will be used instead.
 	//
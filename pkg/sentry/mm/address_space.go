@@ -207,6 +207,13 @@ func (mm *MemoryManager) mapASLocked(pseg pmaIterator, ar hostarch.AddrRange, pl
 			perms.Write = false
 		}
 		if perms.Any() { // MapFile precondition
+			// Private memory may be in the compressed cache tier of mm.mf,
+			// which the AddressSpace can't map.
+			if pma.private && pma.file == mm.mf {
+				if err := mm.mf.Decompress(pseg.fileRangeOf(pmaMapAR)); err != nil {
+					return err
+				}
+			}
 			if err := mm.as.MapFile(pmaMapAR.Start, pma.file, pseg.fileRangeOf(pmaMapAR), perms, platformEffect == memmap.PlatformEffectCommit); err != nil {
 				return err
 			}
@@ -242,3 +249,29 @@ func (mm *MemoryManager) unmapASLocked(ar hostarch.AddrRange) {
 	// spaces.
 	mm.as.Unmap(ar.Start, uint64(ar.Length()))
 }
+
+// unmapASBatchLocked removes all AddressSpace mappings for addresses in b, and
+// empties b. Unlike calling unmapASLocked for each range in b, this allows the
+// platform to coalesce adjacent ranges and invalidate TLBs once.
+//
+// Preconditions: mm.activeMu must be locked.
+func (mm *MemoryManager) unmapASBatchLocked(b *platform.UnmapBatch) {
+	if b.Empty() {
+		return
+	}
+	if mm.as == nil {
+		// See unmapASLocked.
+		mm.unmapAllOnActivate = true
+		b.Reset()
+		return
+	}
+	// As in unmapASLocked, ranges may include addresses that can't be mapped
+	// by the application.
+	appAR := mm.applicationAddrRange()
+	var clamped platform.UnmapBatch
+	for _, ar := range b.Ranges() {
+		clamped.Add(ar.Intersect(appAR))
+	}
+	b.Reset()
+	clamped.Flush(mm.as)
+}
@@ -99,6 +99,13 @@ type ioResult struct {
 	ioEntry
 }
 
+// AIOCancelable is a pending request that supports cancellation.
+type AIOCancelable interface {
+	// Cancel requests that the operation complete as soon as possible. The
+	// request must still be finished or cancelled through its AIOContext.
+	Cancel()
+}
+
 // AIOContext is a single asynchronous I/O context.
 //
 // +stateify savable
@@ -123,14 +130,27 @@ type AIOContext struct {
 
 	// dead is set when the context is destroyed.
 	dead bool `state:"zerovalue"`
+
+	// cancelable maps the user address of each pending cancelable request's
+	// iocb to the request. This is analogous to Linux's kioctx.active_reqs.
+	cancelable map[uint64]AIOCancelable
 }
 
 // destroy marks the context dead.
+//
+// Pending cancelable requests are cancelled, as in Linux's
+// fs/aio.c:free_ioctx_users().
 func (aio *AIOContext) destroy() {
 	aio.mu.Lock()
-	defer aio.mu.Unlock()
 	aio.dead = true
+	cancelable := aio.cancelable
+	aio.cancelable = nil
 	aio.checkForDone()
+	aio.mu.Unlock()
+
+	for _, req := range cancelable {
+		req.Cancel()
+	}
 }
 
 // Preconditions: ctx.mu must be held by caller.
@@ -222,6 +242,45 @@ func (aio *AIOContext) CancelPendingRequest() {
 	aio.checkForDone()
 }
 
+// AddCancelable records a pending request, submitted with the iocb at obj, so
+// that it can be cancelled by CancelRequest or by destroying the context.
+func (aio *AIOContext) AddCancelable(obj uint64, req AIOCancelable) {
+	aio.mu.Lock()
+	defer aio.mu.Unlock()
+	if aio.cancelable == nil {
+		aio.cancelable = make(map[uint64]AIOCancelable)
+	}
+	aio.cancelable[obj] = req
+}
+
+// RemoveCancelable forgets a request previously passed to AddCancelable. It
+// must be called before the request is finished.
+func (aio *AIOContext) RemoveCancelable(obj uint64, req AIOCancelable) {
+	aio.mu.Lock()
+	defer aio.mu.Unlock()
+	if aio.cancelable[obj] == req {
+		delete(aio.cancelable, obj)
+	}
+}
+
+// CancelRequest cancels the pending request submitted with the iocb at obj.
+// It returns EINVAL if there is no such request or if it doesn't support
+// cancellation.
+func (aio *AIOContext) CancelRequest(obj uint64) error {
+	aio.mu.Lock()
+	req, ok := aio.cancelable[obj]
+	if ok {
+		delete(aio.cancelable, obj)
+	}
+	aio.mu.Unlock()
+
+	if !ok {
+		return linuxerr.EINVAL
+	}
+	req.Cancel()
+	return nil
+}
+
 // Drain drops all completed requests. Pending requests remain untouched.
 func (aio *AIOContext) Drain() {
 	aio.mu.Lock()
@@ -0,0 +1,88 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mm
+
+import (
+	"sort"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+)
+
+// Compact migrates mm's private memory that overlaps the given ranges of mm's
+// MemoryFile to lower offsets in the MemoryFile, and returns the number of
+// bytes migrated. frs must be non-overlapping and sorted in order of
+// decreasing offset, as returned by pgalloc.MemoryFile.FragmentedHugePages.
+//
+// Only memory with a single reference is migrated, since other users of the
+// MemoryFile may expect memory they hold references on not to move. Migration
+// is transparent to the application.
+func (mm *MemoryManager) Compact(ctx context.Context, frs []memmap.FileRange) uint64 {
+	if len(frs) == 0 {
+		return 0
+	}
+
+	mm.activeMu.Lock()
+	defer mm.activeMu.Unlock()
+	mm.invalidatePMACachesLocked()
+
+	var migrated uint64
+	for pseg := mm.pmas.FirstSegment(); pseg.Ok(); pseg = pseg.NextSegment() {
+		pma := pseg.ValuePtr()
+		if !pma.private || pma.file != mm.mf {
+			continue
+		}
+		fr := pseg.fileRange()
+		// Larger pmas are costly to migrate, and mostly consist of entire
+		// huge pages anyway.
+		if fr.Length() > hostarch.HugePageSize {
+			continue
+		}
+		// Find the first range in frs with Start < fr.End; it is the only one
+		// that can overlap fr if its End > fr.Start.
+		i := sort.Search(len(frs), func(i int) bool {
+			return frs[i].Start < fr.End
+		})
+		if i == len(frs) || !frs[i].Overlaps(fr) {
+			continue
+		}
+		// Memory may be shared by copy-on-write with other MemoryManagers, or
+		// pinned by other users.
+		if !mm.mf.HasUniqueRef(fr) {
+			continue
+		}
+		// AddressSpace mappings must be removed before copying, since the
+		// application could otherwise modify the memory during the copy.
+		mm.unmapASLocked(pseg.Range())
+		newFR, ok, err := mm.mf.Migrate(fr)
+		if err != nil {
+			log.Warningf("Failed to migrate %v for compaction: %v", pseg.Range(), err)
+			break
+		}
+		if !ok {
+			// There is no free space at lower offsets to migrate to, but
+			// there may be for smaller pmas.
+			continue
+		}
+		mm.mf.DecRef(fr)
+		pma.off = newFR.Start
+		pma.internalMappings = safemem.BlockSeq{}
+		migrated += fr.Length()
+	}
+	return migrated
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mm
+
+import (
+	"bytes"
+	"debug/elf"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// CoreDumpSegment describes a vma included in a core dump.
+type CoreDumpSegment struct {
+	// Range is the range of addresses spanned by the vma.
+	Range hostarch.AddrRange
+
+	// Perms are the application-defined permissions of the vma.
+	Perms hostarch.AccessType
+
+	// DumpSize is the number of bytes, starting at Range.Start, whose
+	// contents are written to the core dump. The remainder of the segment is
+	// described by the core dump's program headers, but its contents are
+	// omitted.
+	DumpSize uint64
+}
+
+// CoreDumpSegments returns the segments of mm that are described by a core
+// dump, following Linux's fs/coredump.c:vma_dump_size() with the default
+// coredump_filter: anonymous and private writable memory is dumped in full,
+// and only the first page of other file-backed mappings that begin with an
+// ELF header is dumped.
+func (mm *MemoryManager) CoreDumpSegments(ctx context.Context) []CoreDumpSegment {
+	var (
+		segs []CoreDumpSegment
+		// elfCandidates are the indices of segments whose first page is
+		// dumped only if it contains an ELF header.
+		elfCandidates []int
+	)
+	// FIXME(b/235153601): Need to replace RLockBypass with RLockBypass
+	// after fixing b/235153601.
+	mm.mappingMu.RLockBypass()
+	for vseg := mm.vmas.FirstSegment(); vseg.Ok(); vseg = vseg.NextSegment() {
+		vma := vseg.ValuePtr()
+		seg := CoreDumpSegment{
+			Range: vseg.Range(),
+			Perms: vma.realPerms,
+		}
+		switch {
+		case vma.dontdump || !vma.realPerms.Read:
+			// Nothing is dumped.
+		case vma.mappable == nil || (vma.private && vma.realPerms.Write):
+			seg.DumpSize = uint64(seg.Range.Length())
+		case vma.off == 0:
+			elfCandidates = append(elfCandidates, len(segs))
+		}
+		segs = append(segs, seg)
+	}
+	mm.mappingMu.RUnlockBypass()
+
+	for _, i := range elfCandidates {
+		var magic [len(elf.ELFMAG)]byte
+		if _, err := mm.CopyIn(ctx, segs[i].Range.Start, magic[:], usermem.IOOpts{
+			IgnorePermissions: true,
+		}); err != nil {
+			continue
+		}
+		if bytes.Equal(magic[:], []byte(elf.ELFMAG)) {
+			segs[i].DumpSize = hostarch.PageSize
+		}
+	}
+	return segs
+}
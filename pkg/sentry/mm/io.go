@@ -168,6 +168,11 @@ func (mm *MemoryManager) CopyIn(ctx context.Context, addr hostarch.Addr, dst []b
 		return mm.asCopyIn(ctx, addr, dst)
 	}
 
+	// Try a cached translation, which doesn't require locking.
+	if c := pmaCacheFromContext(ctx); c != nil && mm.cachedCopyIn(c, ar, dst, opts.IgnorePermissions) {
+		return len(dst), nil
+	}
+
 	// Go through internal mappings.
 	// NOTE(gvisor.dev/issue/10331): Using mm.withInternalMappings() here means
 	// that if we encounter any memmap.BufferedIOFallbackErrs, this copy will
@@ -531,8 +536,20 @@ func (mm *MemoryManager) handleASIOFault(ctx context.Context, addr hostarch.Addr
 func (mm *MemoryManager) withInternalMappings(ctx context.Context, ar hostarch.AddrRange, at hostarch.AccessType, ignorePermissions bool, f func(safemem.BlockSeq) (uint64, error)) (int64, error) {
 	// If pmas are already available, we can do IO without touching mm.vmas or
 	// mm.mappingMu.
+	c := pmaCacheFromContext(ctx)
 	mm.activeMu.RLock()
+	if c != nil {
+		// pmaGen can't change while mm.activeMu is locked.
+		if ims, _, ok := c.lookup(mm, ar, at, ignorePermissions); ok {
+			n, err := f(ims)
+			mm.activeMu.RUnlock()
+			return int64(n), err
+		}
+	}
 	if pseg := mm.existingPMAsLocked(ar, at, ignorePermissions, true /* needInternalMappings */); pseg.Ok() {
+		if c != nil && pseg.End() >= ar.End {
+			c.fillLocked(mm, pseg)
+		}
 		n, err := f(mm.internalMappingsLocked(pseg, ar))
 		mm.activeMu.RUnlock()
 		// Do not convert errors returned by f to EFAULT.
@@ -0,0 +1,138 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mm
+
+import (
+	"fmt"
+	"sort"
+
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+)
+
+// Lazy fork
+//
+// By default, Fork copies all of the parent's private pmas into the child's
+// pmas, which dominates the cost of fork(2) for large address spaces. Since
+// most children of fork(2) call execve(2) shortly thereafter, most of this
+// work is wasted (Linux's vfork(2) exists for the same reason).
+//
+// If lazy fork is enabled, Fork still makes the parent's private pmas
+// copy-on-write and takes references on their memory, but records them in a
+// sorted slice (MemoryManager.forkedPMAs) instead of the child's pmaSet.
+// Forked pmas are moved to the child's pmas when the child first needs pmas
+// for the corresponding addresses (getPMAsInternalLocked), or when the child
+// changes them (mprotect, mremap, MADV_DONTNEED, Fork). Invalidation of
+// private memory, e.g. by munmap or by execve releasing the MemoryManager,
+// drops forked pmas without inserting them. Invalidations racing with Fork are
+// captured and replayed as for eager Fork.
+//
+// Forked pmas are accounted in the child's RSS, but are invisible to
+// operations that don't require pmas to exist (e.g. /proc/[pid]/smaps,
+// compaction, and swap aging) until they are moved to pmas.
+
+// forkedPMA is a private pma inherited by lazy Fork.
+//
+// +stateify savable
+type forkedPMA struct {
+	ar  hostarch.AddrRange
+	pma pma
+}
+
+// SetLazyFork sets whether mm.Fork, and Fork of mm's descendants, defers
+// copying private pmas to the child.
+//
+// Preconditions: mm is not used concurrently.
+func (mm *MemoryManager) SetLazyFork(lazy bool) {
+	mm.lazyFork = lazy
+}
+
+// forkedPMAsLowerBoundLocked returns the index of the first forked pma that
+// ends after addr.
+//
+// Preconditions: mm.activeMu must be locked.
+func (mm *MemoryManager) forkedPMAsLowerBoundLocked(addr hostarch.Addr) int {
+	return sort.Search(len(mm.forkedPMAs), func(i int) bool {
+		return mm.forkedPMAs[i].ar.End > addr
+	})
+}
+
+// materializeForkedPMAsLocked moves all forked pmas that overlap ar to
+// mm.pmas. Forked pmas are moved in their entirety, rather than only the
+// parts that overlap ar, to avoid fragmenting mm.pmas.
+//
+// Preconditions: mm.activeMu must be locked for writing.
+func (mm *MemoryManager) materializeForkedPMAsLocked(ar hostarch.AddrRange) {
+	if len(mm.forkedPMAs) == 0 {
+		return
+	}
+	i := mm.forkedPMAsLowerBoundLocked(ar.Start)
+	j := i
+	for ; j < len(mm.forkedPMAs) && mm.forkedPMAs[j].ar.Start < ar.End; j++ {
+		fp := &mm.forkedPMAs[j]
+		pgap := mm.pmas.FindGap(fp.ar.Start)
+		if checkInvariants {
+			if !pgap.Ok() || pgap.End() < fp.ar.End {
+				panic(fmt.Sprintf("forked pma %v overlaps existing pma", fp.ar))
+			}
+		}
+		// RSS was accounted when fp was forked.
+		mm.pmas.Insert(pgap, fp.ar, fp.pma)
+		fp.pma = pma{} // allow GC
+	}
+	if i == j {
+		return
+	}
+	mm.forkedPMAs = append(mm.forkedPMAs[:i], mm.forkedPMAs[j:]...)
+	if len(mm.forkedPMAs) == 0 {
+		mm.forkedPMAs = nil
+	}
+}
+
+// dropForkedPMAsLocked removes all forked pmas that are entirely contained by
+// ar, appending references on their memory to pfdrs. Forked pmas that
+// partially overlap ar are moved to mm.pmas, so that the caller can remove the
+// overlapping parts.
+//
+// Forked pmas have never been mapped into mm's AddressSpace, so they don't
+// need to be unmapped.
+//
+// Preconditions:
+//   - mm.activeMu must be locked for writing.
+//   - ar.Length() != 0.
+func (mm *MemoryManager) dropForkedPMAsLocked(ar hostarch.AddrRange, pfdrs *pendingFileDecRefs) *pendingFileDecRefs {
+	if len(mm.forkedPMAs) == 0 {
+		return pfdrs
+	}
+	// Forked pmas can only partially overlap ar at its boundaries.
+	mm.materializeForkedPMAsLocked(hostarch.AddrRange{ar.Start, ar.Start + 1})
+	mm.materializeForkedPMAsLocked(hostarch.AddrRange{ar.End - 1, ar.End})
+	i := mm.forkedPMAsLowerBoundLocked(ar.Start)
+	j := i
+	for ; j < len(mm.forkedPMAs) && mm.forkedPMAs[j].ar.Start < ar.End; j++ {
+		fp := &mm.forkedPMAs[j]
+		mm.removeRSSLocked(fp.ar)
+		pfdrs = appendPendingFileDecRef(pfdrs, fp.pma.file, memmap.FileRange{fp.pma.off, fp.pma.off + uint64(fp.ar.Length())})
+		fp.pma = pma{} // allow GC
+	}
+	if i == j {
+		return pfdrs
+	}
+	mm.forkedPMAs = append(mm.forkedPMAs[:i], mm.forkedPMAs[j:]...)
+	if len(mm.forkedPMAs) == 0 {
+		mm.forkedPMAs = nil
+	}
+	return pfdrs
+}
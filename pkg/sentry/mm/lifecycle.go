@@ -94,10 +94,14 @@ func (mm *MemoryManager) Fork(ctx context.Context) (*MemoryManager, error) {
 		aioManager:         aioManager{contexts: make(map[uint64]*AIOContext)},
 		sleepForActivation: mm.sleepForActivation,
 		vdsoSigReturnAddr:  mm.vdsoSigReturnAddr,
+		lazyFork:           mm.lazyFork,
 	}
 
 	// Copy vmas.
 	dontforks := false
+	// wipeOnForks is true if any vma has wipeOnFork set, in which case its
+	// pmas are not copied.
+	wipeOnForks := false
 	dstvgap := mm2.vmas.FirstGap()
 	for srcvseg := mm.vmas.FirstSegment(); srcvseg.Ok(); srcvseg = srcvseg.NextSegment() {
 		vma := srcvseg.ValuePtr().copy()
@@ -112,6 +116,9 @@ func (mm *MemoryManager) Fork(ctx context.Context) (*MemoryManager, error) {
 			dontforks = true
 			continue
 		}
+		if vma.wipeOnFork {
+			wipeOnForks = true
+		}
 
 		// Inform the Mappable, if any, of the new mapping.
 		if vma.mappable != nil {
@@ -136,12 +143,17 @@ func (mm *MemoryManager) Fork(ctx context.Context) (*MemoryManager, error) {
 	// immediately followed by execve(2), copying non-private pmas that can be
 	// regenerated by calling memmap.Mappable.Translate is a waste of time.
 	// (Linux does the same; compare kernel/fork.c:dup_mmap() =>
-	// mm/memory.c:copy_page_range().)
+	// mm/memory.c:copy_page_range().) If mm.lazyFork is true, private pmas
+	// are copied to mm2.forkedPMAs instead of mm2.pmas; see lazy_fork.go.
 	mm.activeMu.Lock()
 	defer mm.activeMu.Unlock()
 	mm2.activeMu.NestedLock(activeLockForked)
 	defer mm2.activeMu.NestedUnlock(activeLockForked)
-	if dontforks {
+	// Pmas that mm itself inherited lazily must be copied as well.
+	mm.materializeForkedPMAsLocked(mm.applicationAddrRange())
+	// Private pmas become copy-on-write below.
+	mm.invalidatePMACachesLocked()
+	if dontforks || wipeOnForks {
 		defer mm.pmas.MergeInsideRange(mm.applicationAddrRange())
 	}
 	srcvseg := mm.vmas.FirstSegment()
@@ -154,7 +166,7 @@ func (mm *MemoryManager) Fork(ctx context.Context) (*MemoryManager, error) {
 			continue
 		}
 
-		if dontforks {
+		if dontforks || wipeOnForks {
 			// Find the 'vma' that contains the starting address
 			// associated with the 'pma' (there must be one).
 			srcvseg = srcvseg.seekNextLowerBound(srcpseg.Start())
@@ -168,7 +180,7 @@ func (mm *MemoryManager) Fork(ctx context.Context) (*MemoryManager, error) {
 			}
 
 			srcpseg = mm.pmas.Isolate(srcpseg, srcvseg.Range())
-			if srcvseg.ValuePtr().dontfork {
+			if vma := srcvseg.ValuePtr(); vma.dontfork || vma.wipeOnFork {
 				continue
 			}
 			pma = srcpseg.ValuePtr()
@@ -196,11 +208,16 @@ func (mm *MemoryManager) Fork(ctx context.Context) (*MemoryManager, error) {
 			pma.maxPerms.Write = false
 		}
 		fr := srcpseg.fileRange()
-		// srcpseg.ValuePtr().file == mm.mf since pma.private == true.
-		mm.mf.IncRef(fr, memCgID)
+		// srcpseg.ValuePtr().file is mm.mf or its swap tier since
+		// pma.private == true.
+		pma.file.IncRef(fr, memCgID)
 		addrRange := srcpseg.Range()
 		mm2.addRSSLocked(addrRange)
-		dstpgap = mm2.pmas.Insert(dstpgap, addrRange, *pma).NextGap()
+		if mm.lazyFork {
+			mm2.forkedPMAs = append(mm2.forkedPMAs, forkedPMA{addrRange, *pma})
+		} else {
+			dstpgap = mm2.pmas.Insert(dstpgap, addrRange, *pma).NextGap()
+		}
 	}
 	if unmapAR.Length() != 0 {
 		mm.unmapASLocked(unmapAR)
@@ -141,8 +141,24 @@ type MemoryManager struct {
 	// pmas is protected by activeMu.
 	pmas pmaSet
 
-	// curRSS is pmas.Span(), cached to accelerate updates to maxRSS. It is
-	// reported as the MemoryManager's RSS.
+	// forkedPMAs contains private pmas inherited from the parent
+	// MemoryManager by a lazy Fork, which are moved to pmas when the
+	// corresponding addresses are first accessed. See lazy_fork.go.
+	//
+	// Invariants: forkedPMAs is sorted by address, and its ranges overlap
+	// neither each other nor pmas. If a forked pma exists for a given address,
+	// a vma must also exist for that address.
+	//
+	// forkedPMAs is protected by activeMu.
+	forkedPMAs []forkedPMA
+
+	// pmaGen is incremented before any change to pmas that invalidates
+	// translations cached by PMACaches. It is only modified with activeMu
+	// locked for writing, but may be read without locking.
+	pmaGen atomicbitops.Uint64 `state:"nosave"`
+
+	// curRSS is pmas.Span() plus the span of forkedPMAs, cached to accelerate
+	// updates to maxRSS. It is reported as the MemoryManager's RSS.
 	//
 	// maxRSS should be modified only via insertRSS and removeRSS, not
 	// directly.
@@ -233,18 +249,30 @@ type MemoryManager struct {
 	// activation are not reported as stuck tasks by the watchdog.
 	sleepForActivation bool
 
+	// lazyFork indicates whether Fork should defer copying private pmas to the
+	// child until the child accesses them. It is inherited by children.
+	//
+	// lazyFork is immutable after SetLazyFork.
+	lazyFork bool
+
 	// vdsoSigReturnAddr is the address of 'vdso_sigreturn'.
 	vdsoSigReturnAddr uint64
 
 	// membarrierPrivateEnabled is non-zero if EnableMembarrierPrivate has
-	// previously been called. Since, as of this writing,
-	// MEMBARRIER_CMD_PRIVATE_EXPEDITED is implemented as a global memory
-	// barrier, membarrierPrivateEnabled has no other effect.
+	// previously been called.
 	membarrierPrivateEnabled atomicbitops.Uint32
 
 	// membarrierRSeqEnabled is non-zero if EnableMembarrierRSeq has previously
 	// been called.
 	membarrierRSeqEnabled atomicbitops.Uint32
+
+	// membarrierGlobalEnabled is non-zero if EnableMembarrierGlobal has
+	// previously been called.
+	membarrierGlobalEnabled atomicbitops.Uint32
+
+	// membarrierSyncCoreEnabled is non-zero if EnableMembarrierSyncCore has
+	// previously been called.
+	membarrierSyncCoreEnabled atomicbitops.Uint32
 }
 
 // vma represents a virtual memory area.
@@ -297,6 +325,16 @@ type vma struct {
 	// dontfork is the MADV_DONTFORK setting for this vma configured by madvise().
 	dontfork bool
 
+	// wipeOnFork is the MADV_WIPEONFORK setting for this vma configured by
+	// madvise() or mmap(MAP_DROPPABLE). If wipeOnFork is true, mappable must
+	// be nil, and the vma is mapped in forked MemoryManagers without its
+	// contents.
+	wipeOnFork bool
+
+	// dontdump is the MADV_DONTDUMP setting for this vma configured by
+	// madvise().
+	dontdump bool
+
 	mlockMode memmap.MLockMode
 
 	// numaPolicy is the NUMA policy for this vma set by mbind().
@@ -331,6 +369,8 @@ func (v *vma) copy() vma {
 		private:        v.private,
 		growsDown:      v.growsDown,
 		dontfork:       v.dontfork,
+		wipeOnFork:     v.wipeOnFork,
+		dontdump:       v.dontdump,
 		mlockMode:      v.mlockMode,
 		numaPolicy:     v.numaPolicy,
 		numaNodemask:   v.numaNodemask,
@@ -372,9 +412,9 @@ type pma struct {
 
 	// private is true if this pma represents private memory.
 	//
-	// If private is true, file must be MemoryManager.mfp.MemoryFile(), and
-	// calls to Invalidate for which memmap.InvalidateOpts.InvalidatePrivate is
-	// false should ignore the pma.
+	// If private is true, file must be MemoryManager.mf or its swap tier
+	// (MemoryManager.mf.SwapFile()), and calls to Invalidate for which
+	// memmap.InvalidateOpts.InvalidatePrivate is false should ignore the pma.
 	//
 	// If private is false, this pma caches a translation from the
 	// corresponding vma's memmap.Mappable.Translate.
@@ -383,6 +423,11 @@ type pma struct {
 	// If internalMappings is not empty, it is the cached return value of
 	// file.MapInternal for the memmap.FileRange mapped by this pma.
 	internalMappings safemem.BlockSeq `state:"nosave"`
+
+	// age is the number of swap aging passes since the application last
+	// faulted on this pma. It is only maintained for private pmas. See
+	// MemoryManager.SwapOut.
+	age uint32 `state:"nosave"`
 }
 
 type invalidateArgs struct {
@@ -15,17 +15,22 @@
 package mm
 
 import (
+	"bytes"
+	"math"
+	"os"
 	"testing"
 
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/memutil"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/contexttest"
 	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/usermem"
 )
 
@@ -178,6 +183,185 @@ func TestIOAfterUnmap(t *testing.T) {
 	}
 }
 
+type pmaCacheContext struct {
+	context.Context
+	cache PMACache
+}
+
+// PMACache implements PMACacheProvider.PMACache.
+func (ctx *pmaCacheContext) PMACache() *PMACache {
+	return &ctx.cache
+}
+
+func TestPMACacheInvalidation(t *testing.T) {
+	ctx := &pmaCacheContext{Context: contexttest.Context(t)}
+	mm := testMemoryManager(ctx)
+	defer mm.DecUsers(ctx)
+
+	addr, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   hostarch.PageSize,
+		Private:  true,
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.AnyAccess,
+	})
+	if err != nil {
+		t.Fatalf("MMap got err %v want nil", err)
+	}
+
+	// Repeated IO to the same buffer is served by the cache.
+	want := []byte{1, 2, 3, 4}
+	if _, err := mm.CopyOut(ctx, addr, want, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyOut got err %v want nil", err)
+	}
+	b := make([]byte, len(want))
+	if _, err := mm.CopyIn(ctx, addr, b, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyIn got err %v want nil", err)
+	}
+	if ctx.cache.e.Load() == nil {
+		t.Fatalf("CopyIn did not fill the pma cache")
+	}
+	if _, err := mm.CopyIn(ctx, addr, b, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyIn got err %v want nil", err)
+	}
+	if !bytes.Equal(b, want) {
+		t.Errorf("CopyIn got %v want %v", b, want)
+	}
+
+	// Reducing permissions invalidates the cache.
+	if err := mm.MProtect(addr, hostarch.PageSize, hostarch.Read, false); err != nil {
+		t.Fatalf("MProtect got err %v want nil", err)
+	}
+	if _, err := mm.CopyOut(ctx, addr, want, usermem.IOOpts{}); !linuxerr.Equals(linuxerr.EFAULT, err) {
+		t.Errorf("CopyOut after MProtect got err %v want EFAULT", err)
+	}
+
+	// Unmapping invalidates the cache.
+	if _, err := mm.CopyIn(ctx, addr, b, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyIn got err %v want nil", err)
+	}
+	if err := mm.MUnmap(ctx, addr, hostarch.PageSize); err != nil {
+		t.Fatalf("MUnmap got err %v want nil", err)
+	}
+	if _, err := mm.CopyIn(ctx, addr, b, usermem.IOOpts{}); !linuxerr.Equals(linuxerr.EFAULT, err) {
+		t.Errorf("CopyIn after MUnmap got err %v want EFAULT", err)
+	}
+}
+
+func TestPinIOVecs(t *testing.T) {
+	ctx := contexttest.Context(t)
+	mm := testMemoryManager(ctx)
+	defer mm.DecUsers(ctx)
+
+	const length = 2 * hostarch.PageSize
+	addr, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   length,
+		Private:  true,
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.AnyAccess,
+	})
+	if err != nil {
+		t.Fatalf("MMap got err %v want nil", err)
+	}
+	data := make([]byte, length)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if _, err := mm.CopyOut(ctx, addr, data, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyOut got err %v want nil", err)
+	}
+
+	// Adjacent iovecs spanning the page boundary.
+	ars := hostarch.AddrRangeSeqFromSlice([]hostarch.AddrRange{
+		{addr + 16, addr + hostarch.PageSize},
+		{addr + hostarch.PageSize, addr + hostarch.PageSize + 16},
+	})
+	p, err := mm.PinIOVecs(ctx, ars, hostarch.Read, false /* ignorePermissions */)
+	if err != nil {
+		t.Fatalf("PinIOVecs got err %v want nil", err)
+	}
+	if got, want := p.NumBytes(), ars.NumBytes(); got != want {
+		t.Errorf("PinIOVecs pinned %d bytes, want %d", got, want)
+	}
+	got := make([]byte, p.NumBytes())
+	if _, err := p.IOSequence().CopyIn(ctx, got); err != nil {
+		t.Fatalf("CopyIn from pinned memory got err %v want nil", err)
+	}
+	if want := data[16 : hostarch.PageSize+16]; !bytes.Equal(got, want) {
+		t.Errorf("pinned memory contents differ from mapped memory")
+	}
+
+	// Pinned memory remains accessible after it's unmapped.
+	if err := mm.MUnmap(ctx, addr, length); err != nil {
+		t.Fatalf("MUnmap got err %v want nil", err)
+	}
+	if _, err := p.IOSequence().CopyIn(ctx, got); err != nil {
+		t.Errorf("CopyIn from pinned memory after MUnmap got err %v want nil", err)
+	}
+	p.Unpin()
+}
+
+func TestLazyFork(t *testing.T) {
+	ctx := contexttest.Context(t)
+	mm := testMemoryManager(ctx)
+	defer mm.DecUsers(ctx)
+	mm.SetLazyFork(true)
+
+	const length = 4 * hostarch.PageSize
+	addr, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   length,
+		Private:  true,
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.AnyAccess,
+	})
+	if err != nil {
+		t.Fatalf("MMap got err %v want nil", err)
+	}
+	parentData := bytes.Repeat([]byte{1}, length)
+	if _, err := mm.CopyOut(ctx, addr, parentData, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyOut got err %v want nil", err)
+	}
+
+	mm2, err := mm.Fork(ctx)
+	if err != nil {
+		t.Fatalf("Fork got err %v want nil", err)
+	}
+	defer mm2.DecUsers(ctx)
+	if len(mm2.forkedPMAs) == 0 || mm2.pmas.Span() != 0 {
+		t.Fatalf("Fork copied pmas eagerly: forked pmas %d, pmas span %d", len(mm2.forkedPMAs), mm2.pmas.Span())
+	}
+	if got, want := mm2.curRSS, mm.curRSS; got != want {
+		t.Errorf("child RSS got %d want %d", got, want)
+	}
+
+	// Writes by the parent after the fork must not be visible to the child.
+	if _, err := mm.CopyOut(ctx, addr, bytes.Repeat([]byte{2}, length), usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyOut got err %v want nil", err)
+	}
+	b := make([]byte, length)
+	if _, err := mm2.CopyIn(ctx, addr, b, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyIn got err %v want nil", err)
+	}
+	if !bytes.Equal(b, parentData) {
+		t.Errorf("child memory changed by parent writes")
+	}
+	if len(mm2.forkedPMAs) != 0 {
+		t.Errorf("got %d forked pmas after access, want 0", len(mm2.forkedPMAs))
+	}
+
+	// Unmapping forked pmas that were never accessed releases them.
+	mm3, err := mm2.Fork(ctx)
+	if err != nil {
+		t.Fatalf("Fork got err %v want nil", err)
+	}
+	defer mm3.DecUsers(ctx)
+	if err := mm3.MUnmap(ctx, addr, length); err != nil {
+		t.Fatalf("MUnmap got err %v want nil", err)
+	}
+	if len(mm3.forkedPMAs) != 0 || mm3.curRSS != 0 {
+		t.Errorf("after MUnmap: got %d forked pmas and RSS %d, want 0 and 0", len(mm3.forkedPMAs), mm3.curRSS)
+	}
+}
+
 // TestIOAfterMProtect tests IO interaction with mprotect permissions.
 func TestIOAfterMProtect(t *testing.T) {
 	ctx := contexttest.Context(t)
@@ -272,3 +456,152 @@ func TestAIOLookupAfterDestroy(t *testing.T) {
 		t.Errorf("AIOContext found even after AIOContext manager is destroyed")
 	}
 }
+
+func newTestMemoryFile(t *testing.T, name string) *pgalloc.MemoryFile {
+	memfd, err := memutil.CreateMemFD(name, 0)
+	if err != nil {
+		t.Fatalf("error creating memfd: %v", err)
+	}
+	memfile := os.NewFile(uintptr(memfd), name)
+	mf, err := pgalloc.NewMemoryFile(memfile, pgalloc.MemoryFileOpts{})
+	if err != nil {
+		memfile.Close()
+		t.Fatalf("error creating pgalloc.MemoryFile: %v", err)
+	}
+	return mf
+}
+
+// TestSwap tests migrating private memory to the swap tier and back.
+func TestSwap(t *testing.T) {
+	ctx := contexttest.Context(t)
+	mf := newTestMemoryFile(t, "swap-test-memory")
+	swap := newTestMemoryFile(t, "swap-test-swap")
+	mf.SetSwapFile(swap)
+	p := platform.FromContext(ctx)
+	mm := NewMemoryManager(p, mf, false)
+	mm.layout = arch.MmapLayout{
+		MinAddr:      p.MinUserAddress(),
+		MaxAddr:      p.MaxUserAddress(),
+		BottomUpBase: p.MinUserAddress(),
+		TopDownBase:  p.MaxUserAddress(),
+	}
+	defer mm.DecUsers(ctx)
+
+	addr, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   hostarch.PageSize,
+		Private:  true,
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.AnyAccess,
+	})
+	if err != nil {
+		t.Fatalf("MMap got err %v want nil", err)
+	}
+	want := []byte("swapped")
+	if _, err := mm.CopyOut(ctx, addr, want, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyOut got err %v want nil", err)
+	}
+	fileAt := func() any {
+		mm.activeMu.RLock()
+		defer mm.activeMu.RUnlock()
+		return mm.pmas.FindSegment(addr).ValuePtr().file
+	}
+	check := func() {
+		t.Helper()
+		got := make([]byte, len(want))
+		if _, err := mm.CopyIn(ctx, addr, got, usermem.IOOpts{}); err != nil {
+			t.Fatalf("CopyIn got err %v want nil", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("CopyIn got %q want %q", got, want)
+		}
+	}
+
+	// The first pass only ages the pma.
+	if n := mm.SwapOut(ctx, 1, math.MaxUint64); n != 0 {
+		t.Errorf("SwapOut got %d want 0", n)
+	}
+	if got := fileAt(); got != mf {
+		t.Errorf("pma file after aging pass got %v want %v", got, mf)
+	}
+	if n := mm.SwapOut(ctx, 1, math.MaxUint64); n != hostarch.PageSize {
+		t.Errorf("SwapOut got %d want %d", n, hostarch.PageSize)
+	}
+	if got := fileAt(); got != swap {
+		t.Errorf("pma file after swap out got %v want %v", got, swap)
+	}
+	check()
+
+	// Application faults migrate memory back.
+	mm.activeMu.Lock()
+	ar := hostarch.AddrRange{addr, addr + hostarch.PageSize}
+	mm.swapInLocked(ctx, mm.pmas.FindSegment(addr), ar)
+	mm.activeMu.Unlock()
+	if got := fileAt(); got != mf {
+		t.Errorf("pma file after swap in got %v want %v", got, mf)
+	}
+	check()
+
+	// SwapIn migrates all memory back.
+	if n := mm.SwapOut(ctx, 0, math.MaxUint64); n != hostarch.PageSize {
+		t.Errorf("SwapOut got %d want %d", n, hostarch.PageSize)
+	}
+	if err := mm.SwapIn(ctx); err != nil {
+		t.Fatalf("SwapIn got err %v want nil", err)
+	}
+	if got := fileAt(); got != mf {
+		t.Errorf("pma file after SwapIn got %v want %v", got, mf)
+	}
+	check()
+}
+
+// TestSwapCompressed tests compressing private memory into the compressed
+// cache tier and decompressing it on access.
+func TestSwapCompressed(t *testing.T) {
+	usage.SetTotalMemoryLimit(1 << 30)
+	defer usage.SetTotalMemoryLimit(0)
+
+	ctx := contexttest.Context(t)
+	mf := newTestMemoryFile(t, "swap-compressed-test-memory")
+	mf.SetCompressionPercent(100)
+	p := platform.FromContext(ctx)
+	mm := NewMemoryManager(p, mf, false)
+	mm.layout = arch.MmapLayout{
+		MinAddr:      p.MinUserAddress(),
+		MaxAddr:      p.MaxUserAddress(),
+		BottomUpBase: p.MinUserAddress(),
+		TopDownBase:  p.MaxUserAddress(),
+	}
+	defer mm.DecUsers(ctx)
+
+	addr, err := mm.MMap(ctx, memmap.MMapOpts{
+		Length:   hostarch.PageSize,
+		Private:  true,
+		Perms:    hostarch.ReadWrite,
+		MaxPerms: hostarch.AnyAccess,
+	})
+	if err != nil {
+		t.Fatalf("MMap got err %v want nil", err)
+	}
+	want := bytes.Repeat([]byte("compressed"), 100)
+	if _, err := mm.CopyOut(ctx, addr, want, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyOut got err %v want nil", err)
+	}
+
+	if n := mm.SwapOut(ctx, 0, math.MaxUint64); n != hostarch.PageSize {
+		t.Errorf("SwapOut got %d want %d", n, hostarch.PageSize)
+	}
+	// Memory is already compressed, and there is no swap tier to migrate it
+	// to.
+	if n := mm.SwapOut(ctx, 0, math.MaxUint64); n != 0 {
+		t.Errorf("SwapOut of compressed memory got %d want 0", n)
+	}
+
+	// Accesses decompress memory.
+	got := make([]byte, len(want))
+	if _, err := mm.CopyIn(ctx, addr, got, usermem.IOOpts{}); err != nil {
+		t.Fatalf("CopyIn got err %v want nil", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("CopyIn got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mm
+
+import (
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// PinnedIOVecs is returned by MemoryManager.PinIOVecs. It holds references on
+// the memory mapped by a set of application addresses, along with internal
+// mappings of that memory that remain valid until PinnedIOVecs.Unpin is
+// called.
+//
+// Like MemoryManager.Pin, PinnedIOVecs does not prevent application mappings
+// from changing after PinIOVecs returns; I/O through PinnedIOVecs affects the
+// memory that was mapped at the time of the call. This is consistent with
+// Linux's use of pinned user pages for I/O that may outlive a single
+// traversal of the address space, e.g. io_uring and blocking sendmsg(2).
+type PinnedIOVecs struct {
+	prs []PinnedRange
+	ims safemem.BlockSeq
+}
+
+// BlockSeq returns internal mappings of pinned memory, in the order of the
+// addresses passed to PinIOVecs. Physically contiguous mappings of adjacent
+// addresses are coalesced into a single safemem.Block.
+func (p *PinnedIOVecs) BlockSeq() safemem.BlockSeq {
+	return p.ims
+}
+
+// NumBytes returns the number of bytes of pinned memory.
+func (p *PinnedIOVecs) NumBytes() int64 {
+	return int64(p.ims.NumBytes())
+}
+
+// IOSequence returns a usermem.IOSequence representing pinned memory.
+func (p *PinnedIOVecs) IOSequence() usermem.IOSequence {
+	return usermem.BlockSeqIOSequence(p.ims)
+}
+
+// Unpin releases the references held by p. p must not be used after Unpin is
+// called.
+func (p *PinnedIOVecs) Unpin() {
+	Unpin(p.prs)
+	p.prs = nil
+	p.ims = safemem.BlockSeq{}
+}
+
+// PinIOVecs pins the memory mapped by addresses in ars for access of type
+// (at, ignorePermissions), and returns internal mappings of that memory,
+// traversing mm's vmas and pmas once for the whole set of ranges. The caller
+// must call PinnedIOVecs.Unpin when the returned mappings are no longer in
+// use, which may be after the caller's task goroutine has returned to
+// application code.
+//
+// If not all addresses in ars can be pinned, PinIOVecs returns a non-nil
+// error along with a (possibly empty) PinnedIOVecs representing a prefix of
+// ars that was pinned. This includes memory that doesn't support internal
+// mappings, for which callers must fall back to MemoryManager.CopyInTo and
+// CopyOutFrom.
+//
+// Preconditions: Same as usermem.IO.CopyInTo.
+func (mm *MemoryManager) PinIOVecs(ctx context.Context, ars hostarch.AddrRangeSeq, at hostarch.AccessType, ignorePermissions bool) (*PinnedIOVecs, error) {
+	p := &PinnedIOVecs{}
+	if ars.NumBytes() == 0 {
+		return p, nil
+	}
+
+	// Ensure that we have usable vmas.
+	mm.mappingMu.RLock()
+	vars, verr := mm.getVecVMAsLocked(ctx, ars, at, ignorePermissions)
+	if vars.NumBytes() == 0 {
+		mm.mappingMu.RUnlock()
+		return p, translateIOError(ctx, verr)
+	}
+
+	// Ensure that we have usable pmas.
+	mm.activeMu.Lock()
+	pars, perr := mm.getVecPMAsLocked(ctx, vars, at)
+	mm.mappingMu.RUnlock()
+	if pars.NumBytes() == 0 {
+		mm.activeMu.Unlock()
+		return p, translateIOError(ctx, perr)
+	}
+
+	// Pin pmas and gather their internal mappings.
+	memCgID := pgalloc.MemoryCgroupIDFromContext(ctx)
+	var ims []safemem.Block
+	var imerr error
+parsLoop:
+	for ; !pars.IsEmpty(); pars = pars.Tail() {
+		ar := pars.Head()
+		if ar.Length() == 0 {
+			continue
+		}
+		pseg := mm.pmas.FindSegment(ar.Start)
+		for {
+			if imerr = pseg.getInternalMappingsLocked(); imerr != nil {
+				break parsLoop
+			}
+			psar := pseg.Range().Intersect(ar)
+			pinAR := hostarch.AddrRange{psar.Start.RoundDown(), psar.End.MustRoundUp()}
+			pma := pseg.ValuePtr()
+			fr := pseg.fileRangeOf(pinAR)
+			pma.file.IncRef(fr, memCgID)
+			p.prs = append(p.prs, PinnedRange{
+				Source: pinAR,
+				File:   pma.file,
+				Offset: fr.Start,
+			})
+			for pims := pma.internalMappings.DropFirst64(uint64(psar.Start - pseg.Start())).TakeFirst64(uint64(psar.Length())); !pims.IsEmpty(); pims = pims.Tail() {
+				ims = appendCoalescedBlock(ims, pims.Head())
+			}
+			if ar.End <= pseg.End() {
+				break
+			}
+			pseg = pseg.NextSegment()
+		}
+	}
+	mm.activeMu.Unlock()
+	p.ims = safemem.BlockSeqFromSlice(ims)
+
+	// Return the first error in order of progress through ars.
+	if imerr != nil {
+		return p, translateIOError(ctx, imerr)
+	}
+	if perr != nil {
+		return p, translateIOError(ctx, perr)
+	}
+	return p, translateIOError(ctx, verr)
+}
+
+// appendCoalescedBlock appends b to ims, merging it into the last Block in ims
+// if possible.
+func appendCoalescedBlock(ims []safemem.Block, b safemem.Block) []safemem.Block {
+	if n := len(ims); n != 0 {
+		if cb, ok := ims[n-1].Coalesce(b); ok {
+			ims[n-1] = cb
+			return ims
+		}
+	}
+	return append(ims, b)
+}
+
+// PinIOSequence returns a usermem.IOSequence equivalent to ios whose memory is
+// pinned by PinIOVecs, along with a function that must be called to unpin it
+// when the returned IOSequence is no longer in use. If ios does not represent
+// I/O to mm, or if not all of ios can be pinned, PinIOSequence returns ios and
+// a no-op function.
+func (mm *MemoryManager) PinIOSequence(ctx context.Context, ios usermem.IOSequence, at hostarch.AccessType) (usermem.IOSequence, func()) {
+	if ios.IO != usermem.IO(mm) {
+		return ios, func() {}
+	}
+	p, err := mm.PinIOVecs(ctx, ios.Addrs, at, ios.Opts.IgnorePermissions)
+	if err != nil {
+		p.Unpin()
+		return ios, func() {}
+	}
+	pios := p.IOSequence()
+	pios.Opts = ios.Opts
+	return pios, p.Unpin
+}
+
+// PinIOSequences is equivalent to calling PinIOSequence on each of ioss, but
+// traverses mm's vmas and pmas once for all of them. If not all of ioss can be
+// pinned, PinIOSequences returns ioss and a no-op function.
+func (mm *MemoryManager) PinIOSequences(ctx context.Context, ioss []usermem.IOSequence, at hostarch.AccessType) ([]usermem.IOSequence, func()) {
+	var ars []hostarch.AddrRange
+	for _, ios := range ioss {
+		if ios.IO != usermem.IO(mm) || ios.Opts.IgnorePermissions != ioss[0].Opts.IgnorePermissions {
+			return ioss, func() {}
+		}
+		for seq := ios.Addrs; !seq.IsEmpty(); seq = seq.Tail() {
+			ars = append(ars, seq.Head())
+		}
+	}
+	if len(ars) == 0 {
+		return ioss, func() {}
+	}
+	p, err := mm.PinIOVecs(ctx, hostarch.AddrRangeSeqFromSlice(ars), at, ioss[0].Opts.IgnorePermissions)
+	if err != nil {
+		p.Unpin()
+		return ioss, func() {}
+	}
+
+	// Split the pinned memory back into one IOSequence per element of ioss.
+	bs := p.BlockSeq()
+	pioss := make([]usermem.IOSequence, len(ioss))
+	for i, ios := range ioss {
+		n := uint64(ios.NumBytes())
+		pioss[i] = usermem.BlockSeqIOSequence(bs.TakeFirst64(n))
+		pioss[i].Opts = ios.Opts
+		bs = bs.DropFirst64(n)
+	}
+	return pioss, p.Unpin
+}
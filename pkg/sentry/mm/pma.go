@@ -26,6 +26,7 @@ import (
 	"gvisor.dev/gvisor/pkg/safemem"
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/platform"
 	"gvisor.dev/gvisor/pkg/sentry/usage"
 )
 
@@ -205,6 +206,8 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 			panic(fmt.Sprintf("initial vma %v does not cover start of ar %v", vseg.Range(), ar))
 		}
 	}
+	mm.materializeForkedPMAsLocked(ar)
+
 	var pfdrs *pendingFileDecRefs
 	defer func() { // must be a closure to avoid evaluating pfdrs immediately
 		pfdrs.Cleanup()
@@ -404,6 +407,7 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 						pstart = pmaIterator{} // iterators invalidated
 					}
 					oldpma = pseg.ValuePtr()
+					mm.invalidatePMACachesLocked()
 					unmapAR = joinAddrRanges(unmapAR, copyAR)
 					pfdrs = appendPendingFileDecRef(pfdrs, oldpma.file, pseg.fileRange())
 					oldpma.file = mm.mf
@@ -464,6 +468,7 @@ func (mm *MemoryManager) getPMAsInternalLocked(ctx context.Context, vseg vmaIter
 					transMR := memmap.MappableRange{ts[0].Source.Start, ts[len(ts)-1].Source.End}
 					transAR := vseg.addrRangeOf(transMR)
 					pseg = mm.pmas.Isolate(pseg, transAR)
+					mm.invalidatePMACachesLocked()
 					unmapAR = joinAddrRanges(unmapAR, transAR)
 					pfdrs = appendPendingFileDecRef(pfdrs, pseg.ValuePtr().file, pseg.fileRange())
 					pgap = mm.pmas.Remove(pseg)
@@ -566,7 +571,7 @@ func (mm *MemoryManager) isPMACopyOnWriteLocked(vseg vmaIterator, pseg pmaIterat
 	// ownership of it instead of copying. If we do hold the only reference,
 	// additional references can only be taken by mm.Fork(), which is excluded
 	// by mm.activeMu, so this isn't racy.
-	if mm.mf.HasUniqueRef(pseg.fileRange()) {
+	if pma.file.(*pgalloc.MemoryFile).HasUniqueRef(pseg.fileRange()) {
 		pma.needCOW = false
 		// pma.private => pma.translatePerms == hostarch.AnyAccess
 		vma := vseg.ValuePtr()
@@ -594,6 +599,33 @@ func (mm *MemoryManager) Invalidate(ar hostarch.AddrRange, opts memmap.Invalidat
 	mm.invalidateLocked(ar, opts.InvalidatePrivate, true)
 }
 
+// InvalidateBatch implements memmap.BatchInvalidator.InvalidateBatch.
+func (mm *MemoryManager) InvalidateBatch(ars []hostarch.AddrRange, opts memmap.InvalidateOpts) {
+	if checkInvariants {
+		for _, ar := range ars {
+			if !ar.WellFormed() || ar.Length() == 0 || !ar.IsPageAligned() {
+				panic(fmt.Sprintf("invalid ar: %v", ar))
+			}
+		}
+	}
+
+	mm.activeMu.Lock()
+	defer mm.activeMu.Unlock()
+	if mm.captureInvalidations {
+		for _, ar := range ars {
+			mm.capturedInvalidations = append(mm.capturedInvalidations, invalidateArgs{ar, opts})
+		}
+		return
+	}
+	var b platform.UnmapBatch
+	var pfdrs *pendingFileDecRefs
+	for _, ar := range ars {
+		pfdrs = mm.invalidateBatchLocked(ar, opts.InvalidatePrivate, true, &b, pfdrs)
+	}
+	mm.unmapASBatchLocked(&b)
+	pfdrs.Cleanup()
+}
+
 // invalidateLocked removes pmas and AddressSpace mappings of those pmas for
 // addresses in ar.
 //
@@ -602,12 +634,33 @@ func (mm *MemoryManager) Invalidate(ar hostarch.AddrRange, opts memmap.Invalidat
 //   - ar.Length() != 0.
 //   - ar must be page-aligned.
 func (mm *MemoryManager) invalidateLocked(ar hostarch.AddrRange, invalidatePrivate, invalidateShared bool) {
+	var b platform.UnmapBatch
+	pfdrs := mm.invalidateBatchLocked(ar, invalidatePrivate, invalidateShared, &b, nil)
+	mm.unmapASBatchLocked(&b)
+	pfdrs.Cleanup()
+}
+
+// invalidateBatchLocked is equivalent to invalidateLocked, except that
+// AddressSpace mappings to be removed are added to b, and references on
+// removed pmas' memmap.File ranges are appended to pfdrs. The caller must
+// flush b with mm.unmapASBatchLocked before calling pfdrs.Cleanup().
+//
+// Preconditions:
+//   - mm.activeMu must be locked for writing.
+//   - ar.Length() != 0.
+//   - ar must be page-aligned.
+func (mm *MemoryManager) invalidateBatchLocked(ar hostarch.AddrRange, invalidatePrivate, invalidateShared bool, b *platform.UnmapBatch, pfdrs *pendingFileDecRefs) *pendingFileDecRefs {
 	if checkInvariants {
 		if !ar.WellFormed() || ar.Length() == 0 || !ar.IsPageAligned() {
 			panic(fmt.Sprintf("invalid ar: %v", ar))
 		}
 	}
 
+	if invalidatePrivate {
+		pfdrs = mm.dropForkedPMAsLocked(ar, pfdrs)
+	}
+	mm.invalidatePMACachesLocked()
+
 	var didUnmapAS bool
 	pseg := mm.pmas.LowerBoundSegment(ar.Start)
 	for pseg.Ok() && pseg.Start() < ar.End {
@@ -618,7 +671,7 @@ func (mm *MemoryManager) invalidateLocked(ar hostarch.AddrRange, invalidatePriva
 			if !didUnmapAS {
 				// Unmap all of ar, not just pseg.Range(), to minimize host
 				// syscalls. AddressSpace mappings must be removed before
-				// pma.file.DecRef().
+				// pma.file.DecRef(), which is deferred to pfdrs.Cleanup().
 				//
 				// Note that we do more than just ar here, and extrapolate
 				// to the end of any previous region that we may have mapped.
@@ -639,16 +692,17 @@ func (mm *MemoryManager) invalidateLocked(ar hostarch.AddrRange, invalidatePriva
 				} else {
 					unmapAR.End = mm.layout.MaxAddr
 				}
-				mm.unmapASLocked(unmapAR)
+				b.Add(unmapAR)
 				didUnmapAS = true
 			}
 			mm.removeRSSLocked(pseg.Range())
-			pma.file.DecRef(pseg.fileRange())
+			pfdrs = appendPendingFileDecRef(pfdrs, pma.file, pseg.fileRange())
 			pseg = mm.pmas.Remove(pseg).NextSegment()
 		} else {
 			pseg = pseg.NextSegment()
 		}
 	}
+	return pfdrs
 }
 
 // Pin returns the memmap.File ranges currently mapped by addresses in ar in
@@ -768,6 +822,9 @@ func (mm *MemoryManager) movePMAsLocked(oldAR, newAR hostarch.AddrRange) {
 		// mm.pmas.IsEmptyRange is checked by mm.pmas.Insert.
 	}
 
+	mm.materializeForkedPMAsLocked(oldAR)
+	mm.invalidatePMACachesLocked()
+
 	type movedPMA struct {
 		oldAR hostarch.AddrRange
 		pma   pma
@@ -908,6 +965,9 @@ func (pmaSetFunctions) Merge(ar1 hostarch.AddrRange, pma1 pma, ar2 hostarch.Addr
 	// them requires an allocation and getting them again from the
 	// memmap.File might not.
 	pma1.internalMappings = safemem.BlockSeq{}
+	// Conservatively consider the merged pma as recently used as the most
+	// recently used of the two.
+	pma1.age = min(pma1.age, pma2.age)
 	return pma1, true
 }
 
@@ -0,0 +1,139 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mm
+
+import (
+	"sync/atomic"
+
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/safemem"
+)
+
+// PMACache caches the internal mappings of the pma most recently used for I/O
+// by its owner, so that repeated I/O to the same buffers doesn't need to look
+// up pmas. Cached translations are validated against
+// MemoryManager.pmaGen, which is incremented before any change to pmas that
+// could invalidate them.
+//
+// I/O through a cached translation that copies from application memory to a
+// sentry buffer (CopyIn) doesn't lock MemoryManager.activeMu at all. Instead,
+// pmaGen is rechecked after the copy, and the copied data is discarded if it
+// changed. Other I/O still locks activeMu for reading, since its effects can't
+// be discarded, but skips pma lookup.
+//
+// The zero value of PMACache is empty. PMACache is safe for concurrent use,
+// although it is only effective if used by a single goroutine.
+type PMACache struct {
+	e atomic.Pointer[pmaCacheEntry]
+}
+
+// pmaCacheEntry is an immutable cached translation.
+type pmaCacheEntry struct {
+	mm             *MemoryManager
+	gen            uint64
+	ar             hostarch.AddrRange
+	effectivePerms hostarch.AccessType
+	maxPerms       hostarch.AccessType
+	ims            safemem.BlockSeq
+}
+
+// PMACacheProvider is implemented by contexts that own a PMACache, e.g.
+// kernel.Task.
+type PMACacheProvider interface {
+	// PMACache returns the context's PMACache.
+	PMACache() *PMACache
+}
+
+// Invalidate empties the cache.
+func (c *PMACache) Invalidate() {
+	c.e.Store(nil)
+}
+
+func pmaCacheFromContext(ctx context.Context) *PMACache {
+	if p, ok := ctx.(PMACacheProvider); ok {
+		return p.PMACache()
+	}
+	return nil
+}
+
+// invalidatePMACachesLocked invalidates all cached translations of pmas in mm.
+// It must be called before any change to mm.pmas that removes pmas, reduces
+// their permissions, or changes the memory they map.
+//
+// Preconditions: mm.activeMu must be locked for writing.
+func (mm *MemoryManager) invalidatePMACachesLocked() {
+	mm.pmaGen.Add(1)
+}
+
+// lookup returns internal mappings for ar and the generation of mm.pmas
+// against which they were validated, if c contains a valid translation of ar
+// for accesses of type (at, ignorePermissions).
+func (c *PMACache) lookup(mm *MemoryManager, ar hostarch.AddrRange, at hostarch.AccessType, ignorePermissions bool) (safemem.BlockSeq, uint64, bool) {
+	e := c.e.Load()
+	if e == nil || e.mm != mm || !e.ar.IsSupersetOf(ar) {
+		return safemem.BlockSeq{}, 0, false
+	}
+	perms := e.effectivePerms
+	if ignorePermissions {
+		perms = e.maxPerms
+	}
+	if !perms.SupersetOf(at) {
+		return safemem.BlockSeq{}, 0, false
+	}
+	gen := mm.pmaGen.Load()
+	if gen != e.gen {
+		return safemem.BlockSeq{}, 0, false
+	}
+	return e.ims.DropFirst64(uint64(ar.Start - e.ar.Start)).TakeFirst64(uint64(ar.Length())), gen, true
+}
+
+// fillLocked caches the translation of pseg, if its internal mappings are
+// cached.
+//
+// Preconditions: mm.activeMu must be locked.
+func (c *PMACache) fillLocked(mm *MemoryManager, pseg pmaIterator) {
+	pma := pseg.ValuePtr()
+	if pma.internalMappings.IsEmpty() {
+		return
+	}
+	c.e.Store(&pmaCacheEntry{
+		mm:             mm,
+		gen:            mm.pmaGen.Load(),
+		ar:             pseg.Range(),
+		effectivePerms: pma.effectivePerms,
+		maxPerms:       pma.maxPerms,
+		ims:            pma.internalMappings,
+	})
+}
+
+// cachedCopyIn copies application memory in ar to dst using a cached
+// translation, without locking mm.activeMu. It returns false if no valid
+// translation is cached or if the translation was invalidated during the copy,
+// in which case the contents of dst are unspecified.
+func (mm *MemoryManager) cachedCopyIn(c *PMACache, ar hostarch.AddrRange, dst []byte, ignorePermissions bool) bool {
+	ims, gen, ok := c.lookup(mm, ar, hostarch.Read, ignorePermissions)
+	if !ok {
+		return false
+	}
+	// The memory mapped by ims may be released concurrently, in which case
+	// safemem may observe unrelated data or fail; pmaGen will have changed in
+	// either case.
+	n, err := safemem.CopySeq(safemem.BlockSeqOf(safemem.BlockFromSafeSlice(dst)), ims)
+	if err != nil || n != uint64(len(dst)) {
+		return false
+	}
+	return mm.pmaGen.Load() == gen
+}
@@ -267,5 +267,11 @@ func (mm *MemoryManager) vmaSmapsEntryIntoLocked(ctx context.Context, vseg vmaIt
 	if vma.private && vma.effectivePerms.Write { // VM_ACCOUNT
 		b.WriteString("ac ")
 	}
+	if vma.wipeOnFork { // VM_WIPEONFORK
+		b.WriteString("wf ")
+	}
+	if vma.dontdump { // VM_DONTDUMP
+		b.WriteString("dd ")
+	}
 	b.WriteString("\n")
 }
@@ -0,0 +1,212 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mm
+
+import (
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/sentry/platform"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+)
+
+// SwapOut performs a swap aging pass over mm's private memory, and compresses
+// private memory that the application hasn't used during the last minAge
+// passes into the compressed cache tier of mm's MemoryFile, or migrates it to
+// the swap tier of mm's MemoryFile if it can't be compressed. It stops once at
+// least max bytes have been swapped out, and returns the number of bytes
+// swapped out.
+//
+// The sentry can't observe the accessed bits of host page tables, so aging
+// emulates them in the style of Linux's multi-generational LRU, with one
+// generation per pma: each pass increments the age of private pmas and
+// unmaps them from the AddressSpace, such that the application's next access
+// faults and resets their age (see HandleUserFault). Accesses by the sentry
+// itself don't reset the age of pmas.
+//
+// Pages in the swap tier remain mapped by their pmas, so migration is
+// transparent to the application. Pages that the application faults on are
+// migrated back to mm's MemoryFile. Compressed pages are decompressed when
+// they are next accessed.
+func (mm *MemoryManager) SwapOut(ctx context.Context, minAge uint32, max uint64) uint64 {
+	swap := mm.mf.SwapFile()
+	compress := mm.mf.CompressionEnabled()
+	if swap == nil && !compress {
+		return 0
+	}
+
+	mm.activeMu.Lock()
+	defer mm.activeMu.Unlock()
+
+	// Aging doesn't move memory, so unmapping aged pmas can be deferred and
+	// batched to reduce the number of TLB shootdowns.
+	var aged platform.UnmapBatch
+	defer mm.unmapASBatchLocked(&aged)
+	mm.invalidatePMACachesLocked()
+
+	var swapped uint64
+	for pseg := mm.pmas.FirstSegment(); pseg.Ok(); pseg = pseg.NextSegment() {
+		pma := pseg.ValuePtr()
+		// Copy-on-write pmas share memory with other MemoryManagers, which
+		// would be duplicated by migration.
+		if !pma.private || pma.needCOW || pma.file != mm.mf {
+			continue
+		}
+		if pma.age < minAge {
+			pma.age++
+			aged.Add(pseg.Range())
+			continue
+		}
+		if swapped >= max {
+			continue
+		}
+		fr := pseg.fileRange()
+		// Memory may be pinned by other users, which expect it not to move.
+		if !mm.mf.HasUniqueRef(fr) {
+			continue
+		}
+		// AddressSpace mappings must be removed before copying, since the
+		// application could otherwise modify the memory during the copy.
+		mm.unmapASLocked(pseg.Range())
+		if compress {
+			ok, err := mm.mf.Compress(fr)
+			if err != nil {
+				log.Warningf("Failed to compress %v: %v", pseg.Range(), err)
+				break
+			}
+			if ok {
+				pma.internalMappings = safemem.BlockSeq{}
+				swapped += fr.Length()
+				continue
+			}
+		}
+		// Memory that doesn't compress well, or that remains unused after
+		// being compressed, is migrated to the swap tier.
+		if swap == nil {
+			continue
+		}
+		swapFR, err := mm.mf.CopyTo(swap, fr, pgalloc.AllocOpts{
+			Kind: usage.Anonymous,
+			Mode: pgalloc.AllocateOnly,
+		})
+		if err != nil {
+			log.Warningf("Failed to swap out %v: %v", pseg.Range(), err)
+			break
+		}
+		mm.mf.DecRef(fr)
+		pma.file = swap
+		pma.off = swapFR.Start
+		pma.internalMappings = safemem.BlockSeq{}
+		swapped += fr.Length()
+	}
+	return swapped
+}
+
+// SwapIn migrates all of mm's private memory in the swap tier of mm's
+// MemoryFile back to mm's MemoryFile.
+//
+// Memory shared by copy-on-write with other MemoryManagers is copied once per
+// MemoryManager.
+func (mm *MemoryManager) SwapIn(ctx context.Context) error {
+	swap := mm.mf.SwapFile()
+	if swap == nil {
+		return nil
+	}
+
+	mm.activeMu.Lock()
+	defer mm.activeMu.Unlock()
+	// Forked pmas may also refer to the swap tier.
+	mm.materializeForkedPMAsLocked(mm.applicationAddrRange())
+	mm.invalidatePMACachesLocked()
+
+	memCgID := pgalloc.MemoryCgroupIDFromContext(ctx)
+	for pseg := mm.pmas.FirstSegment(); pseg.Ok(); pseg = pseg.NextSegment() {
+		pma := pseg.ValuePtr()
+		if !pma.private || pma.file == mm.mf {
+			continue
+		}
+		fr := pseg.fileRange()
+		mm.unmapASLocked(pseg.Range())
+		newFR, err := swap.CopyTo(mm.mf, fr, pgalloc.AllocOpts{
+			Kind:    usage.Anonymous,
+			Mode:    pgalloc.AllocateOnly,
+			MemCgID: memCgID,
+		})
+		if err != nil {
+			return err
+		}
+		swap.DecRef(fr)
+		pma.file = mm.mf
+		pma.off = newFR.Start
+		pma.internalMappings = safemem.BlockSeq{}
+	}
+	return nil
+}
+
+// swapInLocked records that the application is using the pma at ar.Start. If
+// that pma is in the swap tier, swapInLocked migrates the part of it that is
+// in privateAligned(ar) back to mm.mf. It returns the pma at ar.Start.
+//
+// Preconditions:
+//   - mm.activeMu must be locked for writing.
+//   - pseg.Range().Contains(ar.Start).
+//   - ar must be page-aligned.
+func (mm *MemoryManager) swapInLocked(ctx context.Context, pseg pmaIterator, ar hostarch.AddrRange) pmaIterator {
+	pma := pseg.ValuePtr()
+	pma.age = 0
+	if !pma.private || pma.file == mm.mf {
+		return pseg
+	}
+	swap := pma.file.(*pgalloc.MemoryFile)
+	swapAR := pseg.Range().Intersect(privateAligned(ar))
+	fr := pseg.fileRangeOf(swapAR)
+	// Memory shared with other MemoryManagers or pinned by other users stays
+	// in the swap tier; copy-on-write will migrate it on write.
+	if !swap.HasUniqueRef(fr) {
+		return pseg
+	}
+	mm.unmapASLocked(swapAR)
+	mm.invalidatePMACachesLocked()
+	newFR, err := swap.CopyTo(mm.mf, fr, pgalloc.AllocOpts{
+		Kind:    usage.Anonymous,
+		Mode:    pgalloc.AllocateAndWritePopulate,
+		MemCgID: pgalloc.MemoryCgroupIDFromContext(ctx),
+	})
+	if err != nil {
+		// The pma is still usable in the swap tier.
+		log.Debugf("Failed to swap in %v: %v", swapAR, err)
+		return pseg
+	}
+	pseg = mm.pmas.Isolate(pseg, swapAR)
+	pma = pseg.ValuePtr()
+	swap.DecRef(fr)
+	pma.file = mm.mf
+	pma.off = newFR.Start
+	pma.internalMappings = safemem.BlockSeq{}
+	// Try to merge the pma with its neighbors.
+	if prev := pseg.PrevSegment(); prev.Ok() {
+		if merged := mm.pmas.Merge(prev, pseg); merged.Ok() {
+			pseg = merged
+		}
+	}
+	if next := pseg.NextSegment(); next.Ok() {
+		if merged := mm.pmas.Merge(pseg, next); merged.Ok() {
+			pseg = merged
+		}
+	}
+	return pseg
+}
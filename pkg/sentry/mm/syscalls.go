@@ -60,6 +60,9 @@ func (mm *MemoryManager) HandleUserFault(ctx context.Context, addr hostarch.Addr
 		return err
 	}
 
+	// The application is using this pma, so it shouldn't be swapped out.
+	pseg = mm.swapInLocked(ctx, pseg, ar)
+
 	// Downgrade to a read-lock on activeMu since we don't need to mutate pmas
 	// anymore.
 	mm.activeMu.DowngradeLock()
@@ -662,6 +665,9 @@ func (mm *MemoryManager) MProtect(addr hostarch.Addr, length uint64, realPerms h
 		mm.pmas.MergeInsideRange(ar)
 		mm.pmas.MergeOutsideRange(ar)
 	}()
+	// Forked pmas must be updated along with vmas below.
+	mm.materializeForkedPMAsLocked(ar)
+	mm.invalidatePMACachesLocked()
 	pseg := mm.pmas.LowerBoundSegment(ar.Start)
 	var didUnmapAS bool
 	for {
@@ -1083,6 +1089,65 @@ func (mm *MemoryManager) SetDontFork(addr hostarch.Addr, length uint64, dontfork
 	return nil
 }
 
+// SetWipeOnFork implements the semantics of madvise MADV_WIPEONFORK and
+// MADV_KEEPONFORK.
+func (mm *MemoryManager) SetWipeOnFork(addr hostarch.Addr, length uint64, wipeOnFork bool) error {
+	ar, ok := addr.ToRange(length)
+	if !ok {
+		return linuxerr.EINVAL
+	}
+
+	mm.mappingMu.Lock()
+	defer mm.mappingMu.Unlock()
+	defer func() {
+		mm.vmas.MergeInsideRange(ar)
+		mm.vmas.MergeOutsideRange(ar)
+	}()
+
+	for vseg := mm.vmas.LowerBoundSegment(ar.Start); vseg.Ok() && vseg.Start() < ar.End; vseg = vseg.NextSegment() {
+		// Linux: mm/madvise.c:madvise_vma_behavior(): "MADV_WIPEONFORK is only
+		// supported on anonymous memory."
+		if wipeOnFork && (vseg.ValuePtr().mappable != nil || !vseg.ValuePtr().private) {
+			return linuxerr.EINVAL
+		}
+		vseg = mm.vmas.Isolate(vseg, ar)
+		vma := vseg.ValuePtr()
+		vma.wipeOnFork = wipeOnFork
+	}
+
+	if mm.vmas.SpanRange(ar) != ar.Length() {
+		return linuxerr.ENOMEM
+	}
+	return nil
+}
+
+// SetDontDump implements the semantics of madvise MADV_DONTDUMP and
+// MADV_DODUMP.
+func (mm *MemoryManager) SetDontDump(addr hostarch.Addr, length uint64, dontdump bool) error {
+	ar, ok := addr.ToRange(length)
+	if !ok {
+		return linuxerr.EINVAL
+	}
+
+	mm.mappingMu.Lock()
+	defer mm.mappingMu.Unlock()
+	defer func() {
+		mm.vmas.MergeInsideRange(ar)
+		mm.vmas.MergeOutsideRange(ar)
+	}()
+
+	for vseg := mm.vmas.LowerBoundSegment(ar.Start); vseg.Ok() && vseg.Start() < ar.End; vseg = vseg.NextSegment() {
+		vseg = mm.vmas.Isolate(vseg, ar)
+		vma := vseg.ValuePtr()
+		vma.dontdump = dontdump
+	}
+
+	if mm.vmas.SpanRange(ar) != ar.Length() {
+		return linuxerr.ENOMEM
+	}
+	return nil
+}
+
 // Decommit implements the semantics of Linux's madvise(MADV_DONTNEED).
 func (mm *MemoryManager) Decommit(addr hostarch.Addr, length uint64) error {
 	ar, ok := addr.ToRange(length)
@@ -1094,6 +1159,8 @@ func (mm *MemoryManager) Decommit(addr hostarch.Addr, length uint64) error {
 	defer mm.mappingMu.RUnlock()
 	mm.activeMu.Lock()
 	defer mm.activeMu.Unlock()
+	mm.materializeForkedPMAsLocked(ar)
+	mm.invalidatePMACachesLocked()
 
 	// This is invalidateLocked(invalidatePrivate=true, invalidateShared=true),
 	// with the additional wrinkle that we must refuse to invalidate pmas under
@@ -1318,6 +1385,30 @@ func (mm *MemoryManager) IsMembarrierRSeqEnabled() bool {
 	return mm.membarrierRSeqEnabled.Load() != 0
 }
 
+// EnableMembarrierGlobal causes future calls to IsMembarrierGlobalEnabled to
+// return true.
+func (mm *MemoryManager) EnableMembarrierGlobal() {
+	mm.membarrierGlobalEnabled.Store(1)
+}
+
+// IsMembarrierGlobalEnabled returns true if mm.EnableMembarrierGlobal() has
+// previously been called.
+func (mm *MemoryManager) IsMembarrierGlobalEnabled() bool {
+	return mm.membarrierGlobalEnabled.Load() != 0
+}
+
+// EnableMembarrierSyncCore causes future calls to IsMembarrierSyncCoreEnabled
+// to return true.
+func (mm *MemoryManager) EnableMembarrierSyncCore() {
+	mm.membarrierSyncCoreEnabled.Store(1)
+}
+
+// IsMembarrierSyncCoreEnabled returns true if mm.EnableMembarrierSyncCore()
+// has previously been called.
+func (mm *MemoryManager) IsMembarrierSyncCoreEnabled() bool {
+	return mm.membarrierSyncCoreEnabled.Load() != 0
+}
+
 // FindVMAByName finds a vma with the specified name and returns its start address and offset.
 func (mm *MemoryManager) FindVMAByName(ar hostarch.AddrRange, hint string) (hostarch.Addr, uint64, error) {
 	mm.mappingMu.RLock()
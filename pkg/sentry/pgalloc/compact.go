@@ -0,0 +1,165 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"slices"
+
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+)
+
+// FragmentedHugePages returns the hugepage-aligned ranges of f that contain
+// both allocated and free pages, with at most maxUsed allocated bytes, in
+// order of decreasing offset.
+//
+// Over time, allocations and deallocations of varying sizes leave allocated
+// pages scattered across the file, such that the host can't back the file
+// with huge pages, and, if ManualZeroing is in effect, such that free pages
+// can't be decommitted. Users of f can consolidate allocated pages by
+// migrating pages in the returned ranges to lower offsets using Migrate.
+func (f *MemoryFile) FragmentedHugePages(maxUsed uint64) []memmap.FileRange {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var (
+		frs []memmap.FileRange
+		// cur is the start of the huge page whose allocated bytes are counted
+		// by used.
+		cur  uint64
+		used uint64
+	)
+	flush := func() {
+		if used != 0 && used < hostarch.HugePageSize && used <= maxUsed {
+			frs = append(frs, memmap.FileRange{cur, cur + hostarch.HugePageSize})
+		}
+		used = 0
+	}
+	for seg := f.usage.FirstSegment(); seg.Ok(); seg = seg.NextSegment() {
+		// Pages with no references are awaiting reclaim.
+		if seg.ValuePtr().refs == 0 {
+			continue
+		}
+		r := seg.Range()
+		for r.Start < r.End {
+			hp := hostarch.HugePageRoundDown(r.Start)
+			if hp != cur {
+				flush()
+				cur = hp
+			}
+			if r.Start == hp && r.Length() >= hostarch.HugePageSize {
+				// Skip huge pages that are entirely allocated.
+				r.Start = hostarch.HugePageRoundDown(r.End)
+				continue
+			}
+			end := min(r.End, hp+hostarch.HugePageSize)
+			used += end - r.Start
+			r.Start = end
+		}
+	}
+	flush()
+	slices.Reverse(frs)
+	return frs
+}
+
+// Migrate allocates a range of the same length as fr at a lower offset in f,
+// with the same accounting kind and memory cgroup, and copies the contents of
+// fr into it. It returns the allocated range with a single reference held by
+// the caller. The reference held by the caller on fr is not released. If f has
+// no available range at a lower offset, Migrate returns ok == false.
+//
+// Preconditions:
+//   - fr must be page-aligned and non-empty.
+//   - At least one reference must be held on all pages in fr.
+func (f *MemoryFile) Migrate(fr memmap.FileRange) (newFR memmap.FileRange, ok bool, err error) {
+	f.mu.Lock()
+	val := f.usage.FindSegment(fr.Start).Value()
+	opts := AllocOpts{
+		Kind:    val.kind,
+		MemCgID: val.memCgID,
+	}
+	newFR, ok = findAvailableRangeBottomUp(&f.usage, fr.Length(), allocationAlignment(fr.Length()))
+	if !ok || newFR.End > fr.Start {
+		f.mu.Unlock()
+		return memmap.FileRange{}, false, nil
+	}
+	err = f.allocateRangeLocked(newFR, &opts)
+	f.mu.Unlock()
+	if err != nil {
+		return memmap.FileRange{}, false, err
+	}
+
+	src, err := f.MapInternal(fr, hostarch.Read)
+	if err != nil {
+		f.DecRef(newFR)
+		return memmap.FileRange{}, false, err
+	}
+	dst, err := f.MapInternal(newFR, hostarch.Write)
+	if err != nil {
+		f.DecRef(newFR)
+		return memmap.FileRange{}, false, err
+	}
+	if _, err := safemem.CopySeq(dst, src); err != nil {
+		f.DecRef(newFR)
+		return memmap.FileRange{}, false, err
+	}
+	return newFR, true, nil
+}
+
+// decommitFreeHugePages decommits the hugepage-aligned part of fr, which is
+// being reclaimed, extended to include the huge pages containing fr's
+// boundaries if all other pages in those huge pages are free. This allows huge
+// pages that are freed piecemeal to be decommitted when ManualZeroing is in
+// effect.
+func (f *MemoryFile) decommitFreeHugePages(fr memmap.FileRange) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// f.mu must remain locked while decommitting to prevent free pages outside
+	// of fr from being allocated concurrently.
+	start, ok := hostarch.HugePageRoundUp(fr.Start)
+	if !ok {
+		return nil
+	}
+	if hp := hostarch.HugePageRoundDown(fr.Start); f.isFreeLocked(memmap.FileRange{hp, fr.Start}) {
+		start = hp
+	}
+	end := hostarch.HugePageRoundDown(fr.End)
+	if hp, ok := hostarch.HugePageRoundUp(fr.End); ok && hp <= uint64(f.fileSize) && f.isFreeLocked(memmap.FileRange{fr.End, hp}) {
+		end = hp
+	}
+	if start >= end {
+		return nil
+	}
+	return f.decommitFile(memmap.FileRange{start, end})
+}
+
+// isFreeLocked returns true if no pages in fr are allocated.
+//
+// Preconditions: f.mu must be locked.
+func (f *MemoryFile) isFreeLocked(fr memmap.FileRange) bool {
+	if fr.Length() == 0 {
+		return true
+	}
+	free := true
+	f.usage.VisitRange(fr, func(seg usageIterator) bool {
+		if seg.ValuePtr().refs != 0 {
+			free = false
+			return false
+		}
+		return true
+	})
+	return free
+}
@@ -0,0 +1,235 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"bytes"
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/lz4"
+	"gvisor.dev/gvisor/pkg/metric"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+	"gvisor.dev/gvisor/pkg/sentry/usage"
+)
+
+var (
+	compressedPagesMetric   = metric.MustCreateNewUint64Metric("/memory/compressed_pages", false /* sync */, "Number of pages compressed into the compressed cache tier.")
+	decompressedPagesMetric = metric.MustCreateNewUint64Metric("/memory/decompressed_pages", false /* sync */, "Number of pages decompressed from the compressed cache tier.")
+)
+
+// SetCompressionPercent enables the compressed cache tier of f if percent is
+// not 0, and disables it otherwise. The compressed cache tier stores the
+// contents of pages compressed by Compress in the sentry's memory, such that
+// at most percent percent of usage.TotalMemoryLimit() is used for compressed
+// contents. Disabling the compressed cache tier doesn't decompress pages that
+// are already compressed.
+func (f *MemoryFile) SetCompressionPercent(percent uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.compressionPercent = percent
+}
+
+// CompressionEnabled returns true if the compressed cache tier of f is
+// enabled.
+func (f *MemoryFile) CompressionEnabled() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.compressionPercent != 0
+}
+
+// maxCompressedSize returns the maximum size of the compressed contents of a
+// range of the given length that is worth storing in the compressed cache
+// tier.
+func maxCompressedSize(length uint64) uint64 {
+	return length / 4 * 3
+}
+
+// Compress compresses the contents of fr into the compressed cache tier of f
+// and decommits fr, trading CPU time for lower memory usage. The contents of
+// fr are transparently decompressed by the next call to MapInternal or
+// Decompress for any page in fr. Compress returns false without compressing
+// fr if the compressed cache tier is disabled or full, if fr doesn't compress
+// well, or if fr is already compressed.
+//
+// Preconditions:
+//   - fr must be page-aligned and non-empty.
+//   - The caller must hold the only reference on fr.
+//   - The caller must ensure that fr is not accessed through existing
+//     mappings returned by MapInternal, or mapped by an AddressSpace, until
+//     Compress returns.
+func (f *MemoryFile) Compress(fr memmap.FileRange) (bool, error) {
+	if !fr.WellFormed() || fr.Length() == 0 || fr.Start%hostarch.PageSize != 0 || fr.End%hostarch.PageSize != 0 {
+		panic(fmt.Sprintf("invalid range: %v", fr))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// FALLOC_FL_PUNCH_HOLE may not decommit pages if ManualZeroing is in
+	// effect, in which case compression wouldn't reduce memory usage.
+	if f.compressionPercent == 0 || f.opts.ManualZeroing {
+		return false, nil
+	}
+	limit := usage.TotalMemoryLimit() / 100 * f.compressionPercent
+	if f.compressedBytes >= limit || f.isCompressedLocked(fr) {
+		return false, nil
+	}
+
+	pages := make(map[uint64][]byte, fr.Length()/hostarch.PageSize)
+	var size uint64
+	buf := make([]byte, lz4.CompressBound(hostarch.PageSize))
+	off := fr.Start
+	err := f.forEachMappingSlice(fr, func(bs []byte) {
+		for ; len(bs) != 0; bs = bs[hostarch.PageSize:] {
+			page := bs[:hostarch.PageSize]
+			if isZeroPage(page) {
+				pages[off] = nil
+			} else {
+				n := lz4.Compress(buf, page)
+				pages[off] = bytes.Clone(buf[:n])
+				size += uint64(n)
+			}
+			off += hostarch.PageSize
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+	if size > maxCompressedSize(fr.Length()) || f.compressedBytes+size > limit {
+		return false, nil
+	}
+
+	if err := f.decommitFile(fr); err != nil {
+		return false, err
+	}
+	f.markDecommittedLocked(fr)
+	f.usage.MutateFullRange(fr, func(seg usageIterator) bool {
+		seg.ValuePtr().compressed = true
+		return true
+	})
+	if f.compressed == nil {
+		f.compressed = make(map[uint64][]byte)
+	}
+	for off, data := range pages {
+		f.compressed[off] = data
+	}
+	f.compressedBytes += size
+	f.compressedPages.Add(uint64(len(pages)))
+	compressedPagesMetric.IncrementBy(uint64(len(pages)))
+	return true, nil
+}
+
+// Decompress decompresses all pages in fr that are in the compressed cache
+// tier of f. Users of f must call Decompress before mapping pages of f that
+// may be compressed into an AddressSpace; MapInternal calls Decompress
+// automatically.
+func (f *MemoryFile) Decompress(fr memmap.FileRange) error {
+	if f.compressedPages.Load() == 0 {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.decompressLocked(fr)
+}
+
+// Preconditions: f.mu must be locked.
+func (f *MemoryFile) decompressLocked(fr memmap.FileRange) error {
+	var err error
+	f.usage.MutateRange(fr, func(seg usageIterator) bool {
+		val := seg.ValuePtr()
+		if !val.compressed {
+			return true
+		}
+		off := seg.Start()
+		err = f.forEachMappingSlice(seg.Range(), func(bs []byte) {
+			for ; len(bs) != 0 && err == nil; bs = bs[hostarch.PageSize:] {
+				// Pages that were zero when compressed have no compressed
+				// contents, and read as zero since they were decommitted.
+				if data := f.compressed[off]; data != nil {
+					n, derr := lz4.Decompress(bs[:hostarch.PageSize], data)
+					if derr == nil && n != hostarch.PageSize {
+						derr = lz4.ErrCorrupt
+					}
+					if derr != nil {
+						err = fmt.Errorf("failed to decompress page at offset %#x: %w", off, derr)
+						return
+					}
+				}
+				off += hostarch.PageSize
+			}
+		})
+		if err != nil {
+			return false
+		}
+		f.forgetCompressedPagesLocked(seg.Range())
+		decompressedPagesMetric.IncrementBy(seg.Range().Length() / hostarch.PageSize)
+		val.compressed = false
+		return true
+	})
+	return err
+}
+
+// isCompressedLocked returns true if any page in fr is in the compressed cache
+// tier.
+//
+// Preconditions: f.mu must be locked.
+func (f *MemoryFile) isCompressedLocked(fr memmap.FileRange) bool {
+	compressed := false
+	f.usage.VisitRange(fr, func(seg usageIterator) bool {
+		compressed = seg.ValuePtr().compressed
+		return !compressed
+	})
+	return compressed
+}
+
+// forgetCompressedLocked discards the compressed contents of all pages in fr,
+// such that they read as zero.
+//
+// Preconditions: f.mu must be locked.
+func (f *MemoryFile) forgetCompressedLocked(fr memmap.FileRange) {
+	f.usage.MutateRange(fr, func(seg usageIterator) bool {
+		if val := seg.ValuePtr(); val.compressed {
+			f.forgetCompressedPagesLocked(seg.Range())
+			val.compressed = false
+		}
+		return true
+	})
+}
+
+// forgetCompressedPagesLocked removes the compressed contents of all pages in
+// fr from f.compressed, without updating f.usage.
+//
+// Preconditions: f.mu must be locked.
+func (f *MemoryFile) forgetCompressedPagesLocked(fr memmap.FileRange) {
+	for off := fr.Start; off < fr.End; off += hostarch.PageSize {
+		data, ok := f.compressed[off]
+		if !ok {
+			continue
+		}
+		delete(f.compressed, off)
+		f.compressedBytes -= uint64(len(data))
+		f.compressedPages.Add(^uint64(0))
+	}
+}
+
+func isZeroPage(page []byte) bool {
+	for _, b := range page {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -39,6 +39,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/hostmm"
 	"gvisor.dev/gvisor/pkg/sentry/memmap"
 	"gvisor.dev/gvisor/pkg/sentry/usage"
+	"gvisor.dev/gvisor/pkg/stall"
 	"gvisor.dev/gvisor/pkg/sync"
 )
 
@@ -189,6 +190,28 @@ type MemoryFile struct {
 	// savable is true if this MemoryFile will be saved via SaveTo() during
 	// the kernel's SaveTo operation. savable is protected by mu.
 	savable bool
+
+	// swapFile is the swap tier of this MemoryFile, or nil if it has none.
+	// swapFile is set by SetSwapFile before the MemoryFile is used, and is
+	// immutable thereafter.
+	swapFile *MemoryFile
+
+	// compressed maps the offset of each page in the compressed cache tier of
+	// the file to its LZ4-compressed contents, or to nil if the page is
+	// entirely zero. compressedBytes is the total size of compressed
+	// contents in compressed. If compressionPercent is not 0, the compressed
+	// cache tier is enabled, and compressedBytes may not exceed
+	// compressionPercent percent of usage.TotalMemoryLimit(). See
+	// MemoryFile.Compress.
+	//
+	// These fields are protected by mu.
+	compressed         map[uint64][]byte
+	compressedBytes    uint64
+	compressionPercent uint64
+
+	// compressedPages is the number of entries in compressed. It allows
+	// MapInternal to avoid locking mu if no pages are compressed.
+	compressedPages atomicbitops.Uint64
 }
 
 // MemoryFileOpts provides options to NewMemoryFile.
@@ -212,6 +235,20 @@ type MemoryFileOpts struct {
 	// zero newly-allocated pages.
 	ManualZeroing bool
 
+	// If AdviseHugepage is true, MemoryFile advises the host that its
+	// mappings of the file should be backed by transparent huge pages, using
+	// madvise(MADV_HUGEPAGE). For shmem-backed files such as memfds, this
+	// takes effect if /sys/kernel/mm/transparent_hugepage/shmem_enabled is
+	// "advise" or "within_size".
+	AdviseHugepage bool
+
+	// If Hugetlb is true, the file is backed by host hugetlbfs pages of size
+	// hostarch.HugePageSize (e.g. it is a memfd created with MFD_HUGETLB and
+	// MFD_HUGE_2MB). Since the host can only deallocate whole huge pages of
+	// such files, Hugetlb implies ManualZeroing, and only whole huge pages
+	// are decommitted.
+	Hugetlb bool
+
 	// If DisableIMAWorkAround is true, NewMemoryFile will not call
 	// IMAWorkAroundForMemFile().
 	DisableIMAWorkAround bool
@@ -270,6 +307,10 @@ type usageInfo struct {
 
 	// memCgID is the memory cgroup id to which this page is committed.
 	memCgID uint32
+
+	// compressed is true if the contents of this page are in the compressed
+	// cache tier, in which case the page is decommitted.
+	compressed bool
 }
 
 // An EvictableMemoryUser represents a user of MemoryFile-allocated memory that
@@ -336,6 +377,10 @@ func NewMemoryFile(file *os.File, opts MemoryFileOpts) (*MemoryFile, error) {
 		return nil, fmt.Errorf("invalid MemoryFileOpts.DelayedEviction: %v", opts.DelayedEviction)
 	}
 
+	if opts.Hugetlb {
+		opts.ManualZeroing = true
+	}
+
 	// Truncate the file to 0 bytes first to ensure that it's empty.
 	if err := file.Truncate(0); err != nil {
 		return nil, err
@@ -539,25 +584,40 @@ func (f *MemoryFile) allocate(length uint64, opts *AllocOpts) (memmap.FileRange,
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	// Align hugepage-and-larger allocations on hugepage boundaries to try
-	// to take advantage of hugetmpfs.
-	alignment := uint64(hostarch.PageSize)
-	if length >= hostarch.HugePageSize {
-		alignment = hostarch.HugePageSize
-	}
-
 	// Find a range in the underlying file.
-	fr, ok := f.findAvailableRange(length, alignment, opts.Dir)
+	fr, ok := f.findAvailableRange(length, allocationAlignment(length), opts.Dir)
 	if !ok {
 		return memmap.FileRange{}, linuxerr.ENOMEM
 	}
+	if err := f.allocateRangeLocked(fr, opts); err != nil {
+		return memmap.FileRange{}, err
+	}
+	return fr, nil
+}
+
+// allocationAlignment returns the alignment of allocations of the given
+// length.
+func allocationAlignment(length uint64) uint64 {
+	// Align hugepage-and-larger allocations on hugepage boundaries to try
+	// to take advantage of hugetmpfs, transparent huge pages, or hugetlbfs,
+	// and to allow reclaim to decommit them without splitting huge pages.
+	if length >= hostarch.HugePageSize {
+		return hostarch.HugePageSize
+	}
+	return hostarch.PageSize
+}
 
+// allocateRangeLocked marks the available range fr as allocated with a single
+// reference, expanding the file if necessary.
+//
+// Preconditions: f.mu must be locked.
+func (f *MemoryFile) allocateRangeLocked(fr memmap.FileRange, opts *AllocOpts) error {
 	// Expand the file if needed.
 	if int64(fr.End) > f.fileSize {
 		// Round the new file size up to be chunk-aligned.
 		newFileSize := (int64(fr.End) + chunkMask) &^ chunkMask
 		if err := f.file.Truncate(newFileSize); err != nil {
-			return memmap.FileRange{}, err
+			return err
 		}
 		f.fileSize = newFileSize
 		f.mappingsMu.Lock()
@@ -570,7 +630,7 @@ func (f *MemoryFile) allocate(length uint64, opts *AllocOpts) (memmap.FileRange,
 
 	if f.opts.ManualZeroing {
 		if err := f.manuallyZero(fr); err != nil {
-			return memmap.FileRange{}, err
+			return err
 		}
 	}
 	// Mark selected pages as in use.
@@ -579,8 +639,7 @@ func (f *MemoryFile) allocate(length uint64, opts *AllocOpts) (memmap.FileRange,
 		refs:    1,
 		memCgID: opts.MemCgID,
 	})
-
-	return fr, nil
+	return nil
 }
 
 // findAvailableRange returns an available range in the usageSet.
@@ -795,7 +854,18 @@ func (f *MemoryFile) Decommit(fr memmap.FileRange) error {
 		panic(fmt.Sprintf("invalid range: %v", fr))
 	}
 
-	if f.opts.ManualZeroing {
+	if f.compressedPages.Load() != 0 {
+		f.mu.Lock()
+		f.forgetCompressedLocked(fr)
+		f.mu.Unlock()
+	}
+
+	if f.opts.Hugetlb {
+		// Only whole huge pages can be decommitted from hugetlbfs files.
+		if err := f.decommitHugePages(fr); err != nil {
+			return err
+		}
+	} else if f.opts.ManualZeroing {
 		// FALLOC_FL_PUNCH_HOLE may not zero pages if ManualZeroing is in
 		// effect.
 		if err := f.manuallyZero(fr); err != nil {
@@ -811,6 +881,29 @@ func (f *MemoryFile) Decommit(fr memmap.FileRange) error {
 	return nil
 }
 
+// decommitHugePages decommits the huge pages contained in fr, and manually
+// zeroes the rest of fr.
+func (f *MemoryFile) decommitHugePages(fr memmap.FileRange) error {
+	start, ok := hostarch.HugePageRoundUp(fr.Start)
+	end := hostarch.HugePageRoundDown(fr.End)
+	if !ok || start >= end {
+		return f.manuallyZero(fr)
+	}
+	if err := f.decommitFile(memmap.FileRange{start, end}); err != nil {
+		log.Warningf("Failed to decommit huge pages in %v: %v", fr, err)
+		return f.manuallyZero(fr)
+	}
+	if fr.Start < start {
+		if err := f.manuallyZero(memmap.FileRange{fr.Start, start}); err != nil {
+			return err
+		}
+	}
+	if end < fr.End {
+		return f.manuallyZero(memmap.FileRange{end, fr.End})
+	}
+	return nil
+}
+
 func (f *MemoryFile) manuallyZero(fr memmap.FileRange) error {
 	return f.forEachMappingSlice(fr, func(bs []byte) {
 		clear(bs)
@@ -841,6 +934,11 @@ func (f *MemoryFile) decommitFile(fr memmap.FileRange) error {
 func (f *MemoryFile) markDecommitted(fr memmap.FileRange) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	f.markDecommittedLocked(fr)
+}
+
+// Preconditions: f.mu must be locked.
+func (f *MemoryFile) markDecommittedLocked(fr memmap.FileRange) {
 	// Since we're changing the knownCommitted attribute, we need to merge
 	// across the entire range to ensure that the usage tree is minimal.
 	f.usage.MutateFullRange(fr, func(seg usageIterator) bool {
@@ -910,6 +1008,10 @@ func (f *MemoryFile) DecRef(fr memmap.FileRange) {
 		}
 		val.refs--
 		if val.refs == 0 {
+			if val.compressed {
+				f.forgetCompressedPagesLocked(seg.Range())
+				val.compressed = false
+			}
 			f.reclaim.InsertRange(seg.Range(), reclaimSetValue{})
 			freed = true
 			// Reclassify memory as System, until it's freed by the reclaim
@@ -936,6 +1038,9 @@ func (f *MemoryFile) MapInternal(fr memmap.FileRange, at hostarch.AccessType) (s
 	if at.Execute {
 		return safemem.BlockSeq{}, linuxerr.EACCES
 	}
+	if err := f.Decompress(fr); err != nil {
+		return safemem.BlockSeq{}, err
+	}
 
 	chunks := ((fr.End + chunkMask) >> chunkShift) - (fr.Start >> chunkShift)
 	if chunks == 1 {
@@ -990,17 +1095,31 @@ func (f *MemoryFile) getChunkMapping(chunk int) ([]uintptr, uintptr, error) {
 	if m := mappings[chunk]; m != 0 {
 		return mappings, m, nil
 	}
+	flags := unix.MAP_SHARED
+	if f.opts.Hugetlb {
+		// Don't reserve host huge pages for the whole chunk, most of which may
+		// never be allocated. Instead, huge pages are taken from the host's
+		// pool as they are faulted in.
+		flags |= unix.MAP_NORESERVE
+	}
 	m, _, errno := unix.Syscall6(
 		unix.SYS_MMAP,
 		0,
 		chunkSize,
 		unix.PROT_READ|unix.PROT_WRITE,
-		unix.MAP_SHARED,
+		uintptr(flags),
 		f.file.Fd(),
 		uintptr(chunk<<chunkShift))
 	if errno != 0 {
 		return nil, 0, errno
 	}
+	if f.opts.AdviseHugepage {
+		// This is advisory, so failure (e.g. due to the host kernel being
+		// built without CONFIG_TRANSPARENT_HUGEPAGE) is not fatal.
+		if _, _, errno := unix.Syscall(unix.SYS_MADVISE, m, chunkSize, unix.MADV_HUGEPAGE); errno != 0 {
+			log.Debugf("madvise(MADV_HUGEPAGE) of MemoryFile chunk %d failed: %v", chunk, errno)
+		}
+	}
 	atomic.StoreUintptr(&mappings[chunk], m)
 	return mappings, m, nil
 }
@@ -1376,18 +1495,14 @@ func (f *MemoryFile) runReclaim() {
 			break
 		}
 
+		stallID := stall.Reclaim.Begin()
 		if f.opts.ManualZeroing {
 			// If ManualZeroing is in effect, only hugepage-aligned regions may
 			// be safely passed to decommitFile. Pages will be zeroed on
 			// reallocation, so we don't need to perform any manual zeroing
 			// here, whether or not decommitFile succeeds.
-			if startAddr, ok := hostarch.Addr(fr.Start).HugeRoundUp(); ok {
-				if endAddr := hostarch.Addr(fr.End).HugeRoundDown(); startAddr < endAddr {
-					decommitFR := memmap.FileRange{uint64(startAddr), uint64(endAddr)}
-					if err := f.decommitFile(decommitFR); err != nil {
-						log.Warningf("Reclaim failed to decommit %v: %v", decommitFR, err)
-					}
-				}
+			if err := f.decommitFreeHugePages(fr); err != nil {
+				log.Warningf("Reclaim failed to decommit huge pages in %v: %v", fr, err)
 			}
 		} else {
 			if err := f.decommitFile(fr); err != nil {
@@ -1399,6 +1514,7 @@ func (f *MemoryFile) runReclaim() {
 				}
 			}
 		}
+		stall.Reclaim.End(stallID)
 		f.markDecommitted(fr)
 		f.markReclaimed(fr)
 	}
@@ -15,10 +15,18 @@
 package pgalloc
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 
+	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/memutil"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
 )
 
 const (
@@ -351,3 +359,237 @@ func TestFindUnallocatedRange(t *testing.T) {
 		})
 	}
 }
+
+func TestFragmentedHugePages(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		usage   []usageFlatSegment
+		maxUsed uint64
+		want    []memmap.FileRange
+	}{
+		{
+			name:    "Empty file",
+			maxUsed: hugepage,
+		},
+		{
+			name: "Fully allocated huge page",
+			usage: []usageFlatSegment{
+				{0, hugepage, usageInfo{refs: 1}},
+			},
+			maxUsed: hugepage,
+		},
+		{
+			name: "Partially allocated huge page",
+			usage: []usageFlatSegment{
+				{page, 2 * page, usageInfo{refs: 1}},
+				{3 * page, 4 * page, usageInfo{refs: 2}},
+			},
+			maxUsed: hugepage,
+			want: []memmap.FileRange{
+				{0, hugepage},
+			},
+		},
+		{
+			name: "Huge page exceeding maxUsed",
+			usage: []usageFlatSegment{
+				{0, 3 * page, usageInfo{refs: 1}},
+			},
+			maxUsed: 2 * page,
+		},
+		{
+			name: "Pages awaiting reclaim are free",
+			usage: []usageFlatSegment{
+				{0, 2 * page, usageInfo{refs: 0}},
+				{2 * page, 3 * page, usageInfo{refs: 1}},
+			},
+			maxUsed: page,
+			want: []memmap.FileRange{
+				{0, hugepage},
+			},
+		},
+		{
+			name: "Allocation spanning huge pages",
+			usage: []usageFlatSegment{
+				{hugepage - page, 3*hugepage + page, usageInfo{refs: 1}},
+			},
+			maxUsed: hugepage,
+			want: []memmap.FileRange{
+				{3 * hugepage, 4 * hugepage},
+				{0, hugepage},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f := MemoryFile{}
+			if err := f.usage.ImportSlice(test.usage); err != nil {
+				t.Fatalf("Failed to initialize usage from %v: %v", test.usage, err)
+			}
+			got := f.FragmentedHugePages(test.maxUsed)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("FragmentedHugePages(%#x): got %v, want %v", test.maxUsed, got, test.want)
+			}
+		})
+	}
+}
+
+// newTestMemoryFile returns a MemoryFile backed by a memfd created with the
+// given flags.
+func newTestMemoryFile(t *testing.T, memfdFlags int, opts MemoryFileOpts) (*MemoryFile, error) {
+	t.Helper()
+	memfd, err := memutil.CreateMemFD("pgalloc-test", memfdFlags)
+	if err != nil {
+		return nil, err
+	}
+	file := os.NewFile(uintptr(memfd), "pgalloc-test")
+	f, err := NewMemoryFile(file, opts)
+	if err != nil {
+		file.Close()
+		t.Fatalf("NewMemoryFile failed: %v", err)
+	}
+	t.Cleanup(f.Destroy)
+	return f, nil
+}
+
+// fill sets all bytes in fr to b.
+func fill(t *testing.T, f *MemoryFile, fr memmap.FileRange, b byte) {
+	t.Helper()
+	if err := f.forEachMappingSlice(fr, func(bs []byte) {
+		for i := range bs {
+			bs[i] = b
+		}
+	}); err != nil {
+		t.Fatalf("Failed to map %v: %v", fr, err)
+	}
+}
+
+// checkFilled checks that all bytes in fr are b.
+func checkFilled(t *testing.T, f *MemoryFile, fr memmap.FileRange, b byte) {
+	t.Helper()
+	off := fr.Start
+	if err := f.forEachMappingSlice(fr, func(bs []byte) {
+		if i := slices.IndexFunc(bs, func(c byte) bool { return c != b }); i >= 0 {
+			t.Errorf("Byte at offset %#x is %d, want %d", off+uint64(i), bs[i], b)
+		}
+		off += uint64(len(bs))
+	}); err != nil {
+		t.Fatalf("Failed to map %v: %v", fr, err)
+	}
+}
+
+// vmFlags returns the VmFlags of the host mapping containing addr.
+func vmFlags(t *testing.T, addr uintptr) []string {
+	t.Helper()
+	smaps, err := os.Open("/proc/self/smaps")
+	if err != nil {
+		t.Fatalf("Failed to open smaps: %v", err)
+	}
+	defer smaps.Close()
+	found := false
+	scanner := bufio.NewScanner(smaps)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if found && fields[0] == "VmFlags:" {
+			return fields[1:]
+		}
+		start, end, ok := strings.Cut(fields[0], "-")
+		if !ok {
+			continue
+		}
+		s, err1 := strconv.ParseUint(start, 16, 64)
+		e, err2 := strconv.ParseUint(end, 16, 64)
+		if err1 == nil && err2 == nil {
+			found = uint64(addr) >= s && uint64(addr) < e
+		}
+	}
+	t.Fatalf("No mapping containing %#x found in smaps", addr)
+	return nil
+}
+
+// freeHugePages returns the number of free pages in the host's pool of
+// huge pages of size hugepage.
+func freeHugePages(t *testing.T) int {
+	t.Helper()
+	path := fmt.Sprintf("/sys/kernel/mm/hugepages/hugepages-%dkB/free_hugepages", hugepage/1024)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	free, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", path, err)
+	}
+	return free
+}
+
+func TestMemoryFileAdviseHugepage(t *testing.T) {
+	if _, err := os.Stat("/sys/kernel/mm/transparent_hugepage"); err != nil {
+		t.Skipf("Host does not support transparent huge pages: %v", err)
+	}
+	f, err := newTestMemoryFile(t, 0, MemoryFileOpts{AdviseHugepage: true})
+	if err != nil {
+		t.Fatalf("Failed to create memfd: %v", err)
+	}
+	fr, err := f.Allocate(2*hugepage, AllocOpts{})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	defer f.DecRef(fr)
+	if fr.Start%hugepage != 0 {
+		t.Errorf("Allocate(%#x) returned %v, want a hugepage-aligned range", 2*hugepage, fr)
+	}
+
+	fill(t, f, fr, 1)
+	m := (*f.mappings.Load())[fr.Start>>chunkShift]
+	if flags := vmFlags(t, m); !slices.Contains(flags, "hg") {
+		t.Errorf("Mapping of %v has VmFlags %v, want MADV_HUGEPAGE (hg)", fr, flags)
+	}
+
+	if err := f.Decommit(fr); err != nil {
+		t.Fatalf("Decommit failed: %v", err)
+	}
+	checkFilled(t, f, fr, 0)
+}
+
+func TestMemoryFileHugetlb(t *testing.T) {
+	if free := freeHugePages(t); free < 2 {
+		t.Skipf("Test requires 2 free host huge pages, got %d", free)
+	}
+	f, err := newTestMemoryFile(t, unix.MFD_HUGETLB|unix.MFD_HUGE_2MB, MemoryFileOpts{Hugetlb: true})
+	if err != nil {
+		t.Skipf("Host does not support hugetlb memfds: %v", err)
+	}
+	if !f.opts.ManualZeroing {
+		t.Errorf("Hugetlb MemoryFile does not use ManualZeroing")
+	}
+
+	// The file is mapped without reserving huge pages for the whole chunk,
+	// so allocating a small part of it succeeds.
+	before := freeHugePages(t)
+	fr, err := f.Allocate(2*hugepage, AllocOpts{})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	defer f.DecRef(fr)
+	if fr.Start%hugepage != 0 {
+		t.Errorf("Allocate(%#x) returned %v, want a hugepage-aligned range", 2*hugepage, fr)
+	}
+	fill(t, f, fr, 1)
+	if got, want := freeHugePages(t), before-2; got != want {
+		t.Errorf("Free host huge pages after allocating %v: got %d, want %d", fr, got, want)
+	}
+
+	// Decommitting all but the first page releases only the second huge
+	// page, and zeroes the rest of the first one.
+	decommit := memmap.FileRange{fr.Start + page, fr.End}
+	if err := f.Decommit(decommit); err != nil {
+		t.Fatalf("Decommit(%v) failed: %v", decommit, err)
+	}
+	if got, want := freeHugePages(t), before-1; got != want {
+		t.Errorf("Free host huge pages after decommitting %v: got %d, want %d", decommit, got, want)
+	}
+	checkFilled(t, f, memmap.FileRange{fr.Start, fr.Start + page}, 1)
+	checkFilled(t, f, decommit, 0)
+}
@@ -63,6 +63,11 @@ func (f *MemoryFile) SaveTo(ctx context.Context, w io.Writer, pw io.Writer, opts
 		panic(fmt.Sprintf("evictions still pending for %d users; call StartEvictions and WaitForEvictions before SaveTo", len(f.evictable)))
 	}
 
+	// The compressed cache tier isn't saved.
+	if err := f.decompressLocked(memmap.FileRange{0, uint64(f.fileSize)}); err != nil {
+		return err
+	}
+
 	// Ensure that all pages that contain non-zero bytes have knownCommitted
 	// set, since we only store knownCommitted pages below.
 	zeroPage := make([]byte, hostarch.PageSize)
@@ -160,6 +165,10 @@ func (f *MemoryFile) SaveTo(ctx context.Context, w io.Writer, pw io.Writer, opts
 			if ioErr != nil {
 				return
 			}
+			if aw, ok := pw.(asyncWriter); ok {
+				aw.WriteAsync(s)
+				return
+			}
 			_, ioErr = pw.Write(s)
 		})
 		if ioErr != nil {
@@ -169,10 +178,25 @@ func (f *MemoryFile) SaveTo(ctx context.Context, w io.Writer, pw io.Writer, opts
 			return err
 		}
 	}
+	if aw, ok := pw.(asyncWriter); ok {
+		return aw.Wait()
+	}
 
 	return nil
 }
 
+// asyncWriter is implemented by writers passed to MemoryFile.SaveTo that
+// support asynchronous writes. Slices passed to WriteAsync remain valid until
+// Wait is called, since MemoryFile mappings don't change during save.
+type asyncWriter interface {
+	// WriteAsync schedules a write of p.
+	WriteAsync(p []byte)
+
+	// Wait blocks until all scheduled writes are complete, and returns any
+	// error that occurred.
+	Wait() error
+}
+
 // MarkSavable marks f as savable.
 func (f *MemoryFile) MarkSavable() {
 	f.mu.Lock()
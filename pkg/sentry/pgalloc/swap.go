@@ -0,0 +1,81 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgalloc
+
+import (
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/safemem"
+	"gvisor.dev/gvisor/pkg/sentry/memmap"
+)
+
+// SetSwapFile sets the swap tier of f to swap. The swap tier is a second
+// MemoryFile, usually backed by a file on disk, to which users of f may
+// migrate cold pages under memory pressure. Since pages in a disk-backed swap
+// tier are written back to disk by the host kernel's page cache rather than
+// kept resident, this allows sandbox memory to be swapped without host swap.
+//
+// Pages in the swap tier remain accessible through their mappings; they are
+// only slower to access.
+//
+// Preconditions: SetSwapFile must be called before f is used, and at most
+// once.
+func (f *MemoryFile) SetSwapFile(swap *MemoryFile) {
+	f.swapFile = swap
+}
+
+// SwapFile returns the swap tier of f, or nil if it has none.
+func (f *MemoryFile) SwapFile() *MemoryFile {
+	return f.swapFile
+}
+
+// SwapUsage returns the number of bytes of f's contents that are currently
+// stored in its compressed cache tier or its swap tier.
+func (f *MemoryFile) SwapUsage() (uint64, error) {
+	used := f.compressedPages.Load() * hostarch.PageSize
+	if f.swapFile != nil {
+		swapUsage, err := f.swapFile.TotalUsage()
+		if err != nil {
+			return 0, err
+		}
+		used += swapUsage
+	}
+	return used, nil
+}
+
+// CopyTo allocates a range in dst of the same length as fr and copies the
+// contents of fr into it. It returns the allocated range with a single
+// reference held by the caller. The reference held by the caller on fr is not
+// released. opts.ReaderFunc is ignored.
+//
+// CopyTo is used to migrate pages between f and its swap tier.
+//
+// Preconditions: fr must be page-aligned and non-empty.
+func (f *MemoryFile) CopyTo(dst *MemoryFile, fr memmap.FileRange, opts AllocOpts) (memmap.FileRange, error) {
+	ims, err := f.MapInternal(fr, hostarch.Read)
+	if err != nil {
+		return memmap.FileRange{}, err
+	}
+	reader := safemem.BlockSeqReader{Blocks: ims}
+	opts.ReaderFunc = reader.ReadToBlocks
+	dstFR, err := dst.Allocate(fr.Length(), opts)
+	if err != nil {
+		// Don't return a partial copy.
+		if dstFR.Length() != 0 {
+			dst.DecRef(dstFR)
+		}
+		return memmap.FileRange{}, err
+	}
+	return dstFR, nil
+}
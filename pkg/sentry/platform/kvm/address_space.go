@@ -227,6 +227,32 @@ func (as *addressSpace) Unmap(addr hostarch.Addr, length uint64) {
 	}
 }
 
+// UnmapBatch implements platform.BatchUnmapper.UnmapBatch. Active vCPUs are
+// invalidated at most once for the whole batch.
+func (as *addressSpace) UnmapBatch(ars []hostarch.AddrRange) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	// See above & bluepill_allocator.go.
+	as.pageTables.Allocator.(*allocator).cpu = as.machine.Get()
+	defer as.machine.Put(as.pageTables.Allocator.(*allocator).cpu)
+	bluepill(as.pageTables.Allocator.(*allocator).cpu)
+
+	prev := false
+	for _, ar := range ars {
+		if as.unmapLocked(ar.Start, uint64(ar.Length())) {
+			prev = true
+		}
+	}
+	if prev {
+		// Invalidate all active vCPUs.
+		as.invalidate()
+
+		// Recycle any freed intermediate pages.
+		as.pageTables.Allocator.Recycle()
+	}
+}
+
 // Release releases the page tables.
 func (as *addressSpace) Release() {
 	as.Unmap(0, ^uint64(0))
@@ -104,6 +104,13 @@ func New(deviceFile *fd.FD) (*KVM, error) {
 	}
 
 	// Create a new VM fd.
+	//
+	// The VM is always created with the default machine type. Confidential
+	// VM types (SEV-SNP, TDX) are not supported: the Sentry executes the same
+	// code and accesses the same memory in both host and guest mode (see
+	// bluepill), which requires guest memory to be readable by the host and
+	// vCPU state to be accessible through KVM_{GET,SET}_REGS. Neither holds
+	// for encrypted guests.
 	var (
 		vm    uintptr
 		errno unix.Errno
@@ -424,7 +424,7 @@ func (c *vCPU) SwitchToUser(switchOpts ring0.SwitchOpts, info *linux.SignalInfo)
 	case ring0.AlignmentCheck:
 		*info = linux.SignalInfo{
 			Signo: int32(unix.SIGBUS),
-			Code:  2, // BUS_ADRERR (physical address does not exist).
+			Code:  linux.BUS_ADRALN,
 		}
 		return hostarch.NoAccess, platform.ErrContextSignal
 
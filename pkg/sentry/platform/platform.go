@@ -15,10 +15,18 @@
 // Package platform provides a Platform abstraction.
 //
 // See Platform for more information.
+//
+// Platforms register a Constructor under a unique name from an init function
+// of their package (see Register), and are selected with the runsc --platform
+// flag. Platforms maintained outside of this repository are linked into runsc
+// by a main package that imports them for their side effects and calls
+// gvisor.dev/gvisor/runsc/cli.Main. Package platformtest provides conformance
+// tests that such platforms should pass.
 package platform
 
 import (
 	"fmt"
+	"sort"
 
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/abi/linux"
@@ -565,16 +573,27 @@ type Constructor interface {
 // platforms contains all available platform types.
 var platforms = map[string]Constructor{}
 
-// Register registers a new platform type.
+// Register registers a new platform type. It must be called from an init
+// function, and panics if name is empty or already registered.
 func Register(name string, platform Constructor) {
+	if name == "" {
+		panic("platform registered with an empty name")
+	}
+	if platform == nil {
+		panic(fmt.Sprintf("platform %q registered with a nil constructor", name))
+	}
+	if _, ok := platforms[name]; ok {
+		panic(fmt.Sprintf("platform %q registered twice", name))
+	}
 	platforms[name] = platform
 }
 
-// List lists available platforms.
+// List lists available platforms, sorted by name.
 func List() (available []string) {
 	for name := range platforms {
 		available = append(available, name)
 	}
+	sort.Strings(available)
 	return
 }
 
@@ -0,0 +1,191 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package platformtest provides conformance tests for platform
+// implementations, including ones maintained outside of this repository.
+//
+// The tests only check properties that hold for any platform, without running
+// application code. Platforms are expected to have their own tests for
+// Context.Switch and AddressSpace mappings.
+package platformtest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/bits"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/platform"
+)
+
+// addressSpaceTimeout bounds how long NewAddressSpace may ask the caller to
+// wait before an AddressSpace becomes available.
+const addressSpaceTimeout = 10 * time.Second
+
+// RunConformanceTests runs the conformance tests against the platform
+// registered as name. Tests that need a platform instance are skipped if the
+// platform's device can't be opened.
+func RunConformanceTests(t *testing.T, name string) {
+	c, err := platform.Lookup(name)
+	if err != nil {
+		t.Fatalf("platform.Lookup(%q) failed: %v", name, err)
+	}
+	t.Run("PrecompiledSeccompInfo", func(t *testing.T) {
+		testPrecompiledSeccompInfo(t, name, c)
+	})
+
+	dev, err := c.OpenDevice("")
+	if err != nil {
+		t.Skipf("Opening the device of platform %q failed: %v", name, err)
+	}
+	p, err := c.New(dev)
+	if err != nil {
+		t.Fatalf("Creating platform %q failed: %v", name, err)
+	}
+	t.Run("SeccompInfo", func(t *testing.T) {
+		testSeccompInfo(t, name, p)
+	})
+	t.Run("UserAddressRange", func(t *testing.T) {
+		testUserAddressRange(t, p)
+	})
+	t.Run("MapUnit", func(t *testing.T) {
+		testMapUnit(t, p)
+	})
+	t.Run("AddressSpace", func(t *testing.T) {
+		testAddressSpace(t, p)
+	})
+	t.Run("Context", func(t *testing.T) {
+		testContext(t, p)
+	})
+	t.Run("GlobalMemoryBarrier", func(t *testing.T) {
+		if !p.HaveGlobalMemoryBarrier() {
+			t.Skipf("Platform %q has no global memory barrier", name)
+		}
+		if err := p.GlobalMemoryBarrier(); err != nil {
+			t.Errorf("GlobalMemoryBarrier() failed: %v", err)
+		}
+	})
+	t.Run("PreemptAllCPUs", func(t *testing.T) {
+		if !p.DetectsCPUPreemption() {
+			t.Skipf("Platform %q doesn't detect CPU preemption", name)
+		}
+		if err := p.PreemptAllCPUs(); err != nil {
+			t.Errorf("PreemptAllCPUs() failed: %v", err)
+		}
+	})
+}
+
+// testPrecompiledSeccompInfo checks that seccomp programs precompiled for the
+// platform can be told apart from those of other platforms.
+func testPrecompiledSeccompInfo(t *testing.T, name string, c platform.Constructor) {
+	keys := make(map[string]struct{})
+	for _, si := range c.PrecompiledSeccompInfo() {
+		key := si.ConfigKey()
+		if !strings.Contains(key, name) {
+			t.Errorf("Precompiled seccomp config key %q doesn't contain the platform name %q", key, name)
+		}
+		if _, ok := keys[key]; ok {
+			t.Errorf("Precompiled seccomp config key %q is not unique", key)
+		}
+		keys[key] = struct{}{}
+		si.SyscallFilters(si.Variables())
+	}
+}
+
+// testSeccompInfo checks the seccomp information of a platform instance.
+func testSeccompInfo(t *testing.T, name string, p platform.Platform) {
+	si := p.SeccompInfo()
+	if si == nil {
+		t.Fatalf("SeccompInfo() returned nil")
+	}
+	if key := si.ConfigKey(); !strings.Contains(key, name) {
+		t.Errorf("Seccomp config key %q doesn't contain the platform name %q", key, name)
+	}
+	si.SyscallFilters(si.Variables())
+}
+
+func testUserAddressRange(t *testing.T, p platform.Platform) {
+	minAddr, maxAddr := p.MinUserAddress(), p.MaxUserAddress()
+	if !minAddr.IsPageAligned() || !maxAddr.IsPageAligned() {
+		t.Errorf("User address range [%#x, %#x) is not page-aligned", minAddr, maxAddr)
+	}
+	if minAddr >= maxAddr {
+		t.Errorf("User address range [%#x, %#x) is empty", minAddr, maxAddr)
+	}
+}
+
+func testMapUnit(t *testing.T, p platform.Platform) {
+	mu := p.MapUnit()
+	if mu == 0 {
+		return
+	}
+	if mu%hostarch.PageSize != 0 || !bits.IsPowerOfTwo64(mu/hostarch.PageSize) {
+		t.Errorf("MapUnit() = %#x, want 0 or a power-of-2 multiple of %#x", mu, hostarch.PageSize)
+	}
+}
+
+// newAddressSpace returns a new AddressSpace, waiting for one to become
+// available if necessary.
+func newAddressSpace(t *testing.T, p platform.Platform) platform.AddressSpace {
+	for {
+		as, wait, err := p.NewAddressSpace(nil)
+		if err != nil {
+			t.Fatalf("NewAddressSpace() failed: %v", err)
+		}
+		if as != nil {
+			return as
+		}
+		if wait == nil {
+			t.Fatalf("NewAddressSpace() returned neither an AddressSpace nor a channel to wait on")
+		}
+		select {
+		case <-wait:
+		case <-time.After(addressSpaceTimeout):
+			t.Fatalf("No AddressSpace became available after %v", addressSpaceTimeout)
+		}
+	}
+}
+
+// testAddressSpace checks that address spaces can be created, unmapped and
+// released independently of each other.
+func testAddressSpace(t *testing.T, p platform.Platform) {
+	as1 := newAddressSpace(t, p)
+	if p.CooperativelySchedulesAddressSpace() {
+		// The platform may only be able to provide a single AddressSpace
+		// at a time.
+		as1.Release()
+		as1 = newAddressSpace(t, p)
+	}
+	as2 := newAddressSpace(t, p)
+
+	// Unmapping a range that was never mapped must be a no-op.
+	as1.Unmap(p.MinUserAddress(), hostarch.PageSize)
+	as2.Unmap(p.MinUserAddress(), hostarch.PageSize)
+
+	as2.Release()
+	as1.Release()
+}
+
+// testContext checks that execution contexts can be created and released
+// without ever being switched to.
+func testContext(t *testing.T, p platform.Platform) {
+	pc := p.NewContext(context.Background())
+	if pc == nil {
+		t.Fatalf("NewContext() returned nil")
+	}
+	pc.Interrupt()
+	pc.Release()
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import "fmt"
+
+// SyscallPath selects how a platform waits for the application to trap again
+// after returning to it from a syscall.
+type SyscallPath string
+
+const (
+	// SyscallPathAuto lets the platform pick the wait strategy adaptively.
+	SyscallPathAuto SyscallPath = "auto"
+
+	// SyscallPathFast makes the platform poll for the next trap, trading
+	// CPU time for lower switch latency.
+	SyscallPathFast SyscallPath = "fast"
+
+	// SyscallPathSlow makes the platform sleep until the next trap.
+	SyscallPathSlow SyscallPath = "slow"
+)
+
+// Validate returns an error if p is not a known syscall path.
+func (p SyscallPath) Validate() error {
+	switch p {
+	case SyscallPathAuto, SyscallPathFast, SyscallPathSlow:
+		return nil
+	default:
+		return fmt.Errorf("unknown syscall path %q", p)
+	}
+}
+
+// SyscallPathStat describes the wait strategy used after a syscall and the
+// switch latencies observed with it.
+type SyscallPathStat struct {
+	// Sysno is the syscall number.
+	Sysno uintptr `json:"sysno"`
+
+	// Path is the wait strategy selected for the syscall.
+	Path SyscallPath `json:"path"`
+
+	// Waits is the number of times the platform waited for the application
+	// after returning from the syscall.
+	Waits uint64 `json:"waits"`
+
+	// SlowWaits is the number of those waits that went to sleep.
+	SlowWaits uint64 `json:"slowWaits"`
+
+	// MeanLatencyCycles is the mean latency, in CPU cycles, between the
+	// application trapping and the Sentry noticing it.
+	MeanLatencyCycles uint64 `json:"meanLatencyCycles"`
+}
+
+// SyscallPathTuner is implemented by platforms that allow the wait strategy
+// to be selected per syscall.
+type SyscallPathTuner interface {
+	// SetSyscallPath sets the wait strategy used after syscall sysno.
+	SetSyscallPath(sysno uintptr, path SyscallPath) error
+
+	// SyscallPathStats returns statistics for all syscalls that have a
+	// non-default path or were waited after at least once.
+	SyscallPathStats() []SyscallPathStat
+}
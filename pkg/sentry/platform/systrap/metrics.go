@@ -121,14 +121,14 @@ func (b *latencyBuckets) reset() {
 }
 
 // recordLatency records the latency of both the sentry->stub and the
-// stub->sentry context switches.
+// stub->sentry context switches, and returns the latter.
 // For the stub->sentry context switch, the final timestamp is taken by this
 // function.
 // Preconditions:
 //   - ctx.isAcked() is true.
 //
 //go:nosplit
-func (sc *sharedContext) recordLatency() {
+func (sc *sharedContext) recordLatency() cpuTicks {
 	// Record stub->sentry latency.
 	sentryBoundLatency := sc.getStateChangedTimeDiff()
 	if sentryBoundLatency != 0 {
@@ -142,6 +142,7 @@ func (sc *sharedContext) recordLatency() {
 	}
 
 	updateDebugMetrics(stubBoundLatency, sentryBoundLatency)
+	return sentryBoundLatency
 }
 
 // When a measurement period ends, the latencies are used to determine the fast
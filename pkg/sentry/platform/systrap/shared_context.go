@@ -60,6 +60,9 @@ type sharedContext struct {
 	sync           syncevent.Waiter
 	startWaitingTS int64
 	kicked         bool
+	// forceFastPath indicates that the dispatcher must poll for the context
+	// even if the sentry fast path is disabled. See syscallPath.
+	forceFastPath bool
 	// The task associated with the context fell asleep.
 	sleeping bool
 }
@@ -303,6 +306,7 @@ func (q *fastPathDispatcher) loop(target *sharedContext) {
 	done := false
 	processed := 0
 	firstTimeout := false
+	// slowPath is set once the dispatcher has been spinning for too long.
 	slowPath := false
 	startedSpinning := cputicks()
 	for {
@@ -323,7 +327,7 @@ func (q *fastPathDispatcher) loop(target *sharedContext) {
 			break
 		}
 
-		slowPath = !fastpath.sentryFastPath() || slowPath
+		adaptiveSlowPath := !fastpath.sentryFastPath()
 		processed = 0
 		now := cputicks()
 		for ctx = q.list.Front(); ctx != nil; ctx = next {
@@ -331,7 +335,7 @@ func (q *fastPathDispatcher) loop(target *sharedContext) {
 
 			event := sharedContextReady
 			if ctx.state() == sysmsg.ContextStateNone {
-				if slowPath {
+				if slowPath || (adaptiveSlowPath && !ctx.forceFastPath) {
 					event = sharedContextSlowPath
 				} else if !ctx.kicked && uint64(now-ctx.startWaitingTS) > handshakeTimeout {
 					if ctx.isAcked() {
@@ -798,7 +798,7 @@ func (s *subprocess) switchToApp(c *platformContext, ac *arch.Context64) (isSysc
 		return false, false, err
 	}
 
-	if err := s.waitOnState(ctx); err != nil {
+	if err := s.waitOnState(ctx, c.lastSyscall); err != nil {
 		return false, false, corruptedSharedMemoryErr(err.Error())
 	}
 
@@ -838,9 +838,17 @@ func (s *subprocess) switchToApp(c *platformContext, ac *arch.Context64) (isSysc
 	return false, false, nil
 }
 
-func (s *subprocess) waitOnState(ctx *sharedContext) error {
+// waitOnState waits until ctx is handed back to the sentry. sysno is the
+// syscall that the context returned from, or noSyscall; it selects the wait
+// strategy (see syscallPath).
+func (s *subprocess) waitOnState(ctx *sharedContext, sysno uintptr) error {
 	ctx.kicked = false
-	slowPath := false
+	path := syscallPathFor(sysno)
+	ctx.forceFastPath = path == syscallPathFast && !neverEnableFastPath
+	slowPath := path == syscallPathSlow
+	if slowPath {
+		ctx.disableSentryFastPath()
+	}
 	if !s.contextQueue.fastPathEnabled() || atomic.LoadUint32(&s.contextQueue.numActiveThreads) == 0 {
 		ctx.kicked = s.kickSysmsgThread()
 	}
@@ -877,7 +885,8 @@ func (s *subprocess) waitOnState(ctx *sharedContext) error {
 		}
 	}
 
-	ctx.recordLatency()
+	latency := ctx.recordLatency()
+	recordSyscallPathWait(sysno, latency, slowPath)
 	ctx.resetLatencyMeasures()
 	ctx.enableSentryFastPath()
 
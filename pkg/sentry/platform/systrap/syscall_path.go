@@ -0,0 +1,127 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systrap
+
+import (
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/abi/sentry"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/sentry/platform"
+)
+
+// syscallPath is the wait strategy that the sentry uses for a context after
+// returning to it from a syscall. See platform.SyscallPath.
+type syscallPath uint32
+
+const (
+	syscallPathAuto syscallPath = iota
+	syscallPathFast
+	syscallPathSlow
+)
+
+// noSyscall is the syscall number of contexts that didn't stop on a syscall
+// the last time they ran.
+const noSyscall = ^uintptr(0)
+
+var syscallPathNames = map[syscallPath]platform.SyscallPath{
+	syscallPathAuto: platform.SyscallPathAuto,
+	syscallPathFast: platform.SyscallPathFast,
+	syscallPathSlow: platform.SyscallPathSlow,
+}
+
+// syscallPathEntry holds the wait strategy selected for a syscall and the
+// statistics collected with it.
+type syscallPathEntry struct {
+	path          atomicbitops.Uint32
+	waits         atomicbitops.Uint64
+	slowWaits     atomicbitops.Uint64
+	latencyCycles atomicbitops.Uint64
+}
+
+// syscallPaths is indexed by syscall number. Entries are updated by task
+// goroutines without synchronization beyond atomics, so statistics are
+// approximate.
+var syscallPaths [sentry.MaxSyscallNum + 1]syscallPathEntry
+
+// syscallPathFor returns the wait strategy selected for sysno.
+//
+//go:nosplit
+func syscallPathFor(sysno uintptr) syscallPath {
+	if sysno > sentry.MaxSyscallNum {
+		return syscallPathAuto
+	}
+	return syscallPath(syscallPaths[sysno].path.Load())
+}
+
+// recordSyscallPathWait records a wait for a context that returned from
+// sysno, where latency is the stub->sentry switch latency and slow indicates
+// that the sentry went to sleep.
+//
+//go:nosplit
+func recordSyscallPathWait(sysno uintptr, latency cpuTicks, slow bool) {
+	if sysno > sentry.MaxSyscallNum {
+		return
+	}
+	e := &syscallPaths[sysno]
+	e.waits.Add(1)
+	if slow {
+		e.slowWaits.Add(1)
+	}
+	e.latencyCycles.Add(uint64(latency))
+}
+
+// SetSyscallPath implements platform.SyscallPathTuner.SetSyscallPath.
+func (*Systrap) SetSyscallPath(sysno uintptr, path platform.SyscallPath) error {
+	if sysno > sentry.MaxSyscallNum {
+		return fmt.Errorf("syscall number %d is out of range", sysno)
+	}
+	for p, name := range syscallPathNames {
+		if name == path {
+			e := &syscallPaths[sysno]
+			e.path.Store(uint32(p))
+			// Statistics are only meaningful for a single path.
+			e.waits.Store(0)
+			e.slowWaits.Store(0)
+			e.latencyCycles.Store(0)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown syscall path %q", path)
+}
+
+// SyscallPathStats implements platform.SyscallPathTuner.SyscallPathStats.
+func (*Systrap) SyscallPathStats() []platform.SyscallPathStat {
+	var stats []platform.SyscallPathStat
+	for sysno := range syscallPaths {
+		e := &syscallPaths[sysno]
+		path := syscallPath(e.path.Load())
+		waits := e.waits.Load()
+		if path == syscallPathAuto && waits == 0 {
+			continue
+		}
+		stat := platform.SyscallPathStat{
+			Sysno:     uintptr(sysno),
+			Path:      syscallPathNames[path],
+			Waits:     waits,
+			SlowWaits: e.slowWaits.Load(),
+		}
+		if waits != 0 {
+			stat.MeanLatencyCycles = e.latencyCycles.Load() / waits
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
@@ -152,6 +152,10 @@ type platformContext struct {
 	// needToPullFullState indicates that the Sentry doesn't have a full
 	// state of the thread.
 	needToPullFullState bool
+
+	// lastSyscall is the syscall number that the last platformContext
+	// switch stopped on, or noSyscall.
+	lastSyscall uintptr
 }
 
 // PullFullState implements platform.Context.PullFullState.
@@ -238,8 +242,10 @@ restart:
 	}
 
 	if isSyscall {
+		c.lastSyscall = ac.SyscallNo()
 		return nil, hostarch.NoAccess, nil
 	}
+	c.lastSyscall = noSyscall
 
 	si := c.signalInfo
 	if faultSP == nil {
@@ -393,6 +399,7 @@ func (*Systrap) NewContext(ctx pkgcontext.Context) platform.Context {
 	return &platformContext{
 		needRestoreFPState:  true,
 		needToPullFullState: false,
+		lastSyscall:         noSyscall,
 	}
 }
 
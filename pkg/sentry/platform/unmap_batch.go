@@ -0,0 +1,106 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"sort"
+
+	"gvisor.dev/gvisor/pkg/hostarch"
+)
+
+// BatchUnmapper is implemented by AddressSpaces that can unmap several ranges
+// at a lower cost than unmapping them one at a time, e.g. by invalidating
+// TLBs only once.
+type BatchUnmapper interface {
+	// UnmapBatch unmaps all given ranges.
+	//
+	// Preconditions:
+	//	* All ranges are page-aligned and non-empty.
+	//	* Ranges are sorted and don't overlap or abut each other.
+	UnmapBatch(ars []hostarch.AddrRange)
+}
+
+// UnmapBatch accumulates ranges to be unmapped from an AddressSpace, so that
+// they can be coalesced and unmapped at once. The zero value is an empty
+// batch.
+type UnmapBatch struct {
+	ars []hostarch.AddrRange
+}
+
+// Add adds ar to the batch. Empty ranges are ignored.
+//
+// Preconditions: ar is page-aligned.
+func (b *UnmapBatch) Add(ar hostarch.AddrRange) {
+	if ar.Length() == 0 {
+		return
+	}
+	// Ranges are commonly added in ascending order, so merge with the last
+	// range eagerly to keep the batch small.
+	if n := len(b.ars); n > 0 && b.ars[n-1].Start <= ar.End && ar.Start <= b.ars[n-1].End {
+		b.ars[n-1] = hostarch.AddrRange{
+			Start: min(b.ars[n-1].Start, ar.Start),
+			End:   max(b.ars[n-1].End, ar.End),
+		}
+		return
+	}
+	b.ars = append(b.ars, ar)
+}
+
+// Empty returns true if the batch contains no ranges.
+func (b *UnmapBatch) Empty() bool {
+	return len(b.ars) == 0
+}
+
+// Ranges coalesces the ranges in the batch and returns them, sorted.
+func (b *UnmapBatch) Ranges() []hostarch.AddrRange {
+	if len(b.ars) < 2 {
+		return b.ars
+	}
+	sort.Slice(b.ars, func(i, j int) bool {
+		return b.ars[i].Start < b.ars[j].Start
+	})
+	merged := b.ars[:1]
+	for _, ar := range b.ars[1:] {
+		last := &merged[len(merged)-1]
+		if ar.Start <= last.End {
+			last.End = max(last.End, ar.End)
+			continue
+		}
+		merged = append(merged, ar)
+	}
+	b.ars = merged
+	return b.ars
+}
+
+// Flush unmaps all ranges in the batch from as, and empties the batch.
+func (b *UnmapBatch) Flush(as AddressSpace) {
+	ars := b.Ranges()
+	if len(ars) == 0 {
+		return
+	}
+	if bu, ok := as.(BatchUnmapper); ok {
+		bu.UnmapBatch(ars)
+	} else {
+		for _, ar := range ars {
+			as.Unmap(ar.Start, uint64(ar.Length()))
+		}
+	}
+	b.Reset()
+}
+
+// Reset empties the batch.
+func (b *UnmapBatch) Reset() {
+	b.ars = b.ars[:0]
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"slices"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/hostarch"
+)
+
+func TestUnmapBatchRanges(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		add  []hostarch.AddrRange
+		want []hostarch.AddrRange
+	}{
+		{
+			name: "empty",
+			add:  []hostarch.AddrRange{{Start: 0x1000, End: 0x1000}},
+		},
+		{
+			name: "ascending abutting",
+			add:  []hostarch.AddrRange{{Start: 0x1000, End: 0x2000}, {Start: 0x2000, End: 0x3000}},
+			want: []hostarch.AddrRange{{Start: 0x1000, End: 0x3000}},
+		},
+		{
+			name: "disjoint",
+			add:  []hostarch.AddrRange{{Start: 0x1000, End: 0x2000}, {Start: 0x3000, End: 0x4000}},
+			want: []hostarch.AddrRange{{Start: 0x1000, End: 0x2000}, {Start: 0x3000, End: 0x4000}},
+		},
+		{
+			name: "unsorted overlapping",
+			add: []hostarch.AddrRange{
+				{Start: 0x5000, End: 0x6000},
+				{Start: 0x1000, End: 0x3000},
+				{Start: 0x2000, End: 0x4000},
+				{Start: 0x4000, End: 0x5000},
+				{Start: 0x8000, End: 0x9000},
+			},
+			want: []hostarch.AddrRange{{Start: 0x1000, End: 0x6000}, {Start: 0x8000, End: 0x9000}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var b UnmapBatch
+			for _, ar := range tc.add {
+				b.Add(ar)
+			}
+			if got := b.Ranges(); !slices.Equal(got, tc.want) {
+				t.Errorf("Ranges() = %v, want %v", got, tc.want)
+			}
+			if got, want := b.Empty(), len(tc.want) == 0; got != want {
+				t.Errorf("Empty() = %t, want %t", got, want)
+			}
+		})
+	}
+}
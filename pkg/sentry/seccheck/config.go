@@ -49,6 +49,9 @@ type SessionConfig struct {
 	//
 	// This field does NOT apply to sinks.
 	IgnoreMissing bool `json:"ignore_missing,omitempty"`
+	// Filter restricts which events from the points above are sent to the
+	// sinks, e.g. to a subset of processes or containers.
+	Filter FilterConfig `json:"filter,omitempty"`
 	// Sinks are the sinks that will process the points enabled above.
 	Sinks []SinkConfig `json:"sinks,omitempty"`
 }
@@ -135,7 +138,7 @@ func Create(conf *SessionConfig, force bool) error {
 		if err != nil {
 			return fmt.Errorf("creating event sink: %w", err)
 		}
-		state.AppendSink(sink, reqs)
+		state.AppendSink(newFilterSink(sink, conf.Filter), reqs)
 	}
 
 	sessions[conf.Name] = state
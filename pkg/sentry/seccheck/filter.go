@@ -0,0 +1,228 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// contextID is the seccheck package's type for context.Context.Value keys.
+type contextID int
+
+const (
+	// CtxFilterTarget is a Context.Value key for a FilterTarget describing the
+	// task that the context represents.
+	CtxFilterTarget contextID = iota
+)
+
+// FilterTarget is implemented by tasks whose events can be matched against a
+// FilterConfig.
+//
+// It is used to filter events by task attributes without introducing a direct
+// dependency on the kernel package.
+type FilterTarget interface {
+	// SeccheckThreadGroupID returns the task's thread group ID in the root PID
+	// namespace.
+	SeccheckThreadGroupID() int32
+
+	// ContainerID returns the ID of the container that the task belongs to.
+	ContainerID() string
+
+	// SeccheckCgroupPaths returns the paths of all cgroups that the task is a
+	// member of.
+	SeccheckCgroupPaths() []string
+}
+
+// FilterConfig restricts which events from the enabled points are delivered to
+// the sinks of a session. Empty fields match every event. Events that are not
+// associated with a task, e.g. container/start, are only subject to sampling.
+type FilterConfig struct {
+	// ThreadGroupIDs, if not empty, limits events to tasks whose thread group
+	// ID in the root PID namespace is in the list.
+	ThreadGroupIDs []int32 `json:"thread_group_ids,omitempty"`
+	// ContainerIDs, if not empty, limits events to tasks belonging to one of
+	// the listed containers.
+	ContainerIDs []string `json:"container_ids,omitempty"`
+	// Cgroups, if not empty, limits events to tasks that are members of one of
+	// the listed cgroups or of one of their descendants.
+	Cgroups []string `json:"cgroups,omitempty"`
+	// SampleEvery, if greater than 1, delivers only one out of every
+	// SampleEvery events that match the other filters.
+	SampleEvery uint64 `json:"sample_every,omitempty"`
+}
+
+// empty returns true if the filter matches every event.
+func (f *FilterConfig) empty() bool {
+	return len(f.ThreadGroupIDs) == 0 && len(f.ContainerIDs) == 0 && len(f.Cgroups) == 0 && f.SampleEvery <= 1
+}
+
+// filterSink wraps a Sink and only forwards events that match a FilterConfig.
+type filterSink struct {
+	Sink
+
+	filter FilterConfig
+
+	// count is the number of events that matched the filter so far, used for
+	// sampling.
+	count atomicbitops.Uint64
+}
+
+var _ Sink = (*filterSink)(nil)
+
+// newFilterSink returns a Sink that forwards to sink the events that match
+// filter. If the filter matches every event, sink is returned unchanged.
+func newFilterSink(sink Sink, filter FilterConfig) Sink {
+	if filter.empty() {
+		return sink
+	}
+	return &filterSink{Sink: sink, filter: filter}
+}
+
+// match returns true if the event generated from ctx should be delivered.
+func (s *filterSink) match(ctx context.Context) bool {
+	if target, ok := ctx.Value(CtxFilterTarget).(FilterTarget); ok {
+		if !s.matchTarget(target) {
+			return false
+		}
+	}
+	if s.filter.SampleEvery > 1 {
+		return s.count.Add(1)%s.filter.SampleEvery == 1
+	}
+	return true
+}
+
+func (s *filterSink) matchTarget(target FilterTarget) bool {
+	if len(s.filter.ThreadGroupIDs) > 0 {
+		tgid := target.SeccheckThreadGroupID()
+		found := false
+		for _, id := range s.filter.ThreadGroupIDs {
+			if id == tgid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(s.filter.ContainerIDs) > 0 {
+		cid := target.ContainerID()
+		found := false
+		for _, id := range s.filter.ContainerIDs {
+			if id == cid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(s.filter.Cgroups) > 0 {
+		found := false
+		for _, path := range target.SeccheckCgroupPaths() {
+			for _, cg := range s.filter.Cgroups {
+				if cgroupContains(cg, path) {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// cgroupContains returns true if path is the cgroup parent or one of its
+// descendants.
+func cgroupContains(parent, path string) bool {
+	parent = strings.TrimSuffix(parent, "/")
+	if parent == "" {
+		// The root cgroup contains everything.
+		return true
+	}
+	return path == parent || strings.HasPrefix(path, parent+"/")
+}
+
+// Clone implements Sink.Clone.
+func (s *filterSink) Clone(ctx context.Context, fields FieldSet, info *pb.CloneInfo) error {
+	if !s.match(ctx) {
+		return nil
+	}
+	return s.Sink.Clone(ctx, fields, info)
+}
+
+// Execve implements Sink.Execve.
+func (s *filterSink) Execve(ctx context.Context, fields FieldSet, info *pb.ExecveInfo) error {
+	if !s.match(ctx) {
+		return nil
+	}
+	return s.Sink.Execve(ctx, fields, info)
+}
+
+// ExitNotifyParent implements Sink.ExitNotifyParent.
+func (s *filterSink) ExitNotifyParent(ctx context.Context, fields FieldSet, info *pb.ExitNotifyParentInfo) error {
+	if !s.match(ctx) {
+		return nil
+	}
+	return s.Sink.ExitNotifyParent(ctx, fields, info)
+}
+
+// TaskExit implements Sink.TaskExit.
+func (s *filterSink) TaskExit(ctx context.Context, fields FieldSet, info *pb.TaskExit) error {
+	if !s.match(ctx) {
+		return nil
+	}
+	return s.Sink.TaskExit(ctx, fields, info)
+}
+
+// CapabilityDenied implements Sink.CapabilityDenied.
+func (s *filterSink) CapabilityDenied(ctx context.Context, fields FieldSet, info *pb.CapabilityDeniedInfo) error {
+	if !s.match(ctx) {
+		return nil
+	}
+	return s.Sink.CapabilityDenied(ctx, fields, info)
+}
+
+// ContainerStart implements Sink.ContainerStart.
+func (s *filterSink) ContainerStart(ctx context.Context, fields FieldSet, info *pb.Start) error {
+	if !s.match(ctx) {
+		return nil
+	}
+	return s.Sink.ContainerStart(ctx, fields, info)
+}
+
+// Syscall implements Sink.Syscall.
+func (s *filterSink) Syscall(ctx context.Context, fields FieldSet, ctxData *pb.ContextData, msgType pb.MessageType, msg proto.Message) error {
+	if !s.match(ctx) {
+		return nil
+	}
+	return s.Sink.Syscall(ctx, fields, ctxData, msgType, msg)
+}
+
+// RawSyscall implements Sink.RawSyscall.
+func (s *filterSink) RawSyscall(ctx context.Context, fields FieldSet, info *pb.Syscall) error {
+	if !s.match(ctx) {
+		return nil
+	}
+	return s.Sink.RawSyscall(ctx, fields, info)
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seccheck
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/context"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+type testFilterTarget struct {
+	tgid    int32
+	cid     string
+	cgroups []string
+}
+
+// SeccheckThreadGroupID implements FilterTarget.SeccheckThreadGroupID.
+func (t *testFilterTarget) SeccheckThreadGroupID() int32 {
+	return t.tgid
+}
+
+// ContainerID implements FilterTarget.ContainerID.
+func (t *testFilterTarget) ContainerID() string {
+	return t.cid
+}
+
+// SeccheckCgroupPaths implements FilterTarget.SeccheckCgroupPaths.
+func (t *testFilterTarget) SeccheckCgroupPaths() []string {
+	return t.cgroups
+}
+
+func TestFilter(t *testing.T) {
+	target := &testFilterTarget{
+		tgid:    10,
+		cid:     "cid1",
+		cgroups: []string{"/", "/pod/cid1"},
+	}
+	for _, tc := range []struct {
+		name   string
+		filter FilterConfig
+		want   bool
+	}{
+		{
+			name: "empty",
+			want: true,
+		},
+		{
+			name:   "tgid-match",
+			filter: FilterConfig{ThreadGroupIDs: []int32{1, 10}},
+			want:   true,
+		},
+		{
+			name:   "tgid-mismatch",
+			filter: FilterConfig{ThreadGroupIDs: []int32{1}},
+		},
+		{
+			name:   "container-match",
+			filter: FilterConfig{ContainerIDs: []string{"cid1"}},
+			want:   true,
+		},
+		{
+			name:   "container-mismatch",
+			filter: FilterConfig{ContainerIDs: []string{"cid2"}},
+		},
+		{
+			name:   "cgroup-exact",
+			filter: FilterConfig{Cgroups: []string{"/pod/cid1"}},
+			want:   true,
+		},
+		{
+			name:   "cgroup-parent",
+			filter: FilterConfig{Cgroups: []string{"/pod/"}},
+			want:   true,
+		},
+		{
+			name:   "cgroup-prefix",
+			filter: FilterConfig{Cgroups: []string{"/pod/cid"}},
+		},
+		{
+			name: "all-match",
+			filter: FilterConfig{
+				ThreadGroupIDs: []int32{10},
+				ContainerIDs:   []string{"cid1"},
+				Cgroups:        []string{"/pod"},
+			},
+			want: true,
+		},
+		{
+			name: "one-mismatch",
+			filter: FilterConfig{
+				ThreadGroupIDs: []int32{10},
+				ContainerIDs:   []string{"cid2"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			sink := newFilterSink(&testSink{
+				onClone: func(context.Context, FieldSet, *pb.CloneInfo) error {
+					called = true
+					return nil
+				},
+			}, tc.filter)
+			ctx := context.WithValue(context.Background(), CtxFilterTarget, target)
+			if err := sink.Clone(ctx, FieldSet{}, &pb.CloneInfo{}); err != nil {
+				t.Fatalf("Clone(): %v", err)
+			}
+			if called != tc.want {
+				t.Errorf("sink called: got %t, want %t", called, tc.want)
+			}
+		})
+	}
+}
+
+func TestFilterNoTarget(t *testing.T) {
+	called := false
+	sink := newFilterSink(&testSink{
+		onClone: func(context.Context, FieldSet, *pb.CloneInfo) error {
+			called = true
+			return nil
+		},
+	}, FilterConfig{ContainerIDs: []string{"cid1"}})
+	if err := sink.Clone(context.Background(), FieldSet{}, &pb.CloneInfo{}); err != nil {
+		t.Fatalf("Clone(): %v", err)
+	}
+	if !called {
+		t.Errorf("sink was not called for event without a task")
+	}
+}
+
+func TestFilterSampling(t *testing.T) {
+	count := 0
+	sink := newFilterSink(&testSink{
+		onClone: func(context.Context, FieldSet, *pb.CloneInfo) error {
+			count++
+			return nil
+		},
+	}, FilterConfig{SampleEvery: 3})
+	for i := 0; i < 10; i++ {
+		if err := sink.Clone(context.Background(), FieldSet{}, &pb.CloneInfo{}); err != nil {
+			t.Fatalf("Clone(): %v", err)
+		}
+	}
+	// Events 1, 4, 7 and 10 are delivered.
+	if want := 4; count != want {
+		t.Errorf("sink called %d times, want %d", count, want)
+	}
+}
@@ -30,6 +30,7 @@ const (
 	PointExecve
 	PointExitNotifyParent
 	PointTaskExit
+	PointCapabilityDenied
 
 	// Add new Points above this line.
 	pointLengthBeforeSyscalls
@@ -53,6 +54,9 @@ const (
 	// FieldContainerStartEnv is an optional field to collect list of environment
 	// variables set for the container start process.
 	FieldContainerStartEnv Field = iota
+	// FieldContainerStartAnnotations is an optional field to collect the OCI
+	// annotations of the container.
+	FieldContainerStartAnnotations
 )
 
 // Fields for sentry/execve point.
@@ -222,6 +226,10 @@ func genericInit() {
 				ID:   FieldContainerStartEnv,
 				Name: "env",
 			},
+			{
+				ID:   FieldContainerStartAnnotations,
+				Name: "annotations",
+			},
 		},
 		ContextFields: defaultContextFields,
 	})
@@ -286,6 +294,11 @@ func genericInit() {
 		Name:          "sentry/task_exit",
 		ContextFields: defaultContextFields,
 	})
+	registerPoint(PointDesc{
+		ID:            PointCapabilityDenied,
+		Name:          "sentry/capability_denied",
+		ContextFields: defaultContextFields,
+	})
 }
 
 var initOnce sync.Once
@@ -130,6 +130,8 @@ func archInit() {
 	addSyscallPoint(117, "setresuid", nil)
 	addSyscallPoint(119, "setresgid", nil)
 	addSyscallPoint(161, "chroot", nil)
+	addSyscallPoint(165, "mount", nil)
+	addSyscallPoint(166, "umount2", nil)
 	addSyscallPoint(253, "inotify_init", nil)
 	addSyscallPoint(254, "inotify_add_watch", []FieldDesc{
 		{
@@ -53,6 +53,8 @@ func archInit() {
 			Name: "fd_path",
 		},
 	})
+	addSyscallPoint(39, "umount2", nil)
+	addSyscallPoint(40, "mount", nil)
 	addSyscallPoint(49, "chdir", nil)
 	addSyscallPoint(50, "fchdir", []FieldDesc{
 		{
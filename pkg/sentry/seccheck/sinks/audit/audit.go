@@ -0,0 +1,241 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit defines a seccheck.Sink that writes points as audit(7)
+// records, in the format of the log files of the Linux audit daemon, so that
+// they can be processed by the usual tools, e.g. ausearch(8).
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const name = "audit"
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:  name,
+		Setup: setupSink,
+		New:   new,
+	})
+}
+
+// audit converts points into audit events, which are written synchronously
+// to a file. Each event consists of one or more records, one per line, that
+// share a timestamp and a serial number:
+//
+//   - sentry/execve generates SYSCALL, EXECVE, CWD and PATH records.
+//   - Exits of setuid(2), setgid(2), setresuid(2), setresgid(2), mount(2) and
+//     umount2(2) generate SYSCALL records, followed by PATH records for
+//     mount(2) and umount2(2). Mount records also report the file system type
+//     and flags, in fstype and flags fields.
+//   - sentry/capability_denied generates AVC records in the format used by
+//     AppArmor for capability checks, since Linux doesn't audit capability
+//     denials by itself.
+//
+// Other points are ignored. Fields are only present in records if the
+// corresponding context fields are enabled for the point.
+type audit struct {
+	seccheck.SinkDefaults
+
+	// endpoint is the file that events are written to, if any.
+	endpoint *fd.FD
+
+	// now returns the time of events without a time context field.
+	now func() time.Time
+
+	droppedCount atomicbitops.Uint32
+
+	mu sync.Mutex
+	// out is where events are written to. It's protected by mu, which ensures
+	// that records of different events aren't interleaved.
+	out io.Writer
+	// serial is the serial number of the last event. Protected by mu.
+	serial uint64
+}
+
+var _ seccheck.Sink = (*audit)(nil)
+
+// setupSink opens the log file, which is created if it doesn't exist and
+// appended to otherwise.
+func setupSink(config map[string]any) (*os.File, error) {
+	pathOpaque, ok := config["path"]
+	if !ok {
+		return nil, fmt.Errorf("path not present in configuration")
+	}
+	path, ok := pathOpaque.(string)
+	if !ok {
+		return nil, fmt.Errorf("path %q is not a string", pathOpaque)
+	}
+	f, err := os.OpenFile(path, unix.O_WRONLY|unix.O_APPEND|unix.O_CREAT|unix.O_CLOEXEC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	return f, nil
+}
+
+// new creates a new audit sink.
+func new(_ map[string]any, endpoint *fd.FD) (seccheck.Sink, error) {
+	if endpoint == nil {
+		return nil, fmt.Errorf("audit sink requires an endpoint")
+	}
+	log.Debugf("Audit sink created, endpoint FD: %d", endpoint.FD())
+	return &audit{
+		endpoint: endpoint,
+		out:      endpoint,
+		now:      time.Now,
+	}, nil
+}
+
+// Name implements seccheck.Sink.
+func (*audit) Name() string {
+	return name
+}
+
+// Status implements seccheck.Sink.
+func (a *audit) Status() seccheck.SinkStatus {
+	return seccheck.SinkStatus{
+		DroppedCount: uint64(a.droppedCount.Load()),
+	}
+}
+
+// Stop implements seccheck.Sink.
+func (a *audit) Stop() {
+	if a.endpoint != nil {
+		a.endpoint.Close()
+	}
+}
+
+// write writes an event consisting of records.
+func (a *audit) write(ctxData *pb.ContextData, records ...*record) {
+	ts := time.Unix(0, ctxData.GetTimeNs())
+	if ctxData.GetTimeNs() == 0 {
+		ts = a.now()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.serial++
+	var buf bytes.Buffer
+	for _, r := range records {
+		fmt.Fprintf(&buf, "type=%s msg=audit(%d.%03d:%d):%s\n", r.typ, ts.Unix(), ts.Nanosecond()/int(time.Millisecond), a.serial, r.fields.String())
+	}
+	if _, err := a.out.Write(buf.Bytes()); err != nil {
+		log.Debugf("Write failed, dropping audit event: %v", err)
+		a.droppedCount.Add(1)
+	}
+}
+
+// Execve implements seccheck.Sink.
+func (a *audit) Execve(_ context.Context, _ seccheck.FieldSet, info *pb.ExecveInfo) error {
+	ctxData := info.GetContextData()
+	items := 0
+	if info.BinaryPath != "" {
+		items = 1
+	}
+	// The point is reached once the new image has been loaded, so execve(2)
+	// is reported as successful.
+	records := []*record{
+		syscallRecord(ctxData, sysnoExecve, &pb.Exit{}, items),
+	}
+
+	execve := newRecord("EXECVE").num("argc", "%d", len(info.Argv))
+	for i, arg := range info.Argv {
+		execve.str(fmt.Sprintf("a%d", i), arg)
+	}
+	records = append(records, execve)
+
+	if cwd := ctxData.GetCwd(); cwd != "" {
+		records = append(records, newRecord("CWD").str("cwd", cwd))
+	}
+	if info.BinaryPath != "" {
+		path := pathRecord(0, info.BinaryPath)
+		if info.BinaryMode != 0 {
+			path.num("mode", "%o", info.BinaryMode).
+				num("ouid", "%d", info.BinaryUid).
+				num("ogid", "%d", info.BinaryGid)
+		}
+		records = append(records, path)
+	}
+	a.write(ctxData, records...)
+	return nil
+}
+
+// CapabilityDenied implements seccheck.Sink.
+func (a *audit) CapabilityDenied(_ context.Context, _ seccheck.FieldSet, info *pb.CapabilityDeniedInfo) error {
+	cp := linux.Capability(info.Capability)
+	r := newRecord("AVC").
+		str("gvisor", "DENIED").
+		str("operation", "capable").
+		str("class", "cap").
+		subject(info.GetContextData()).
+		num("capability", "%d", info.Capability).
+		str("capname", strings.ToLower(strings.TrimPrefix(cp.String(), "CAP_")))
+	a.write(info.GetContextData(), r)
+	return nil
+}
+
+// Syscall implements seccheck.Sink.
+func (a *audit) Syscall(_ context.Context, _ seccheck.FieldSet, ctxData *pb.ContextData, _ pb.MessageType, msg proto.Message) error {
+	switch m := msg.(type) {
+	case *pb.Setid:
+		if m.Exit != nil {
+			a.write(ctxData, syscallRecord(ctxData, m.Sysno, m.Exit, 0, uint64(m.Id)))
+		}
+	case *pb.Setresid:
+		if m.Exit != nil {
+			a.write(ctxData, syscallRecord(ctxData, m.Sysno, m.Exit, 0, uint64(m.Rid), uint64(m.Eid), uint64(m.Sid)))
+		}
+	case *pb.Mount:
+		if m.Exit != nil {
+			records := []*record{
+				nil, // SYSCALL record, once the number of items is known.
+				pathRecord(0, m.Target),
+			}
+			if m.Source != "" {
+				records = append(records, pathRecord(1, m.Source))
+			}
+			// The addresses of the arguments are meaningless outside of the
+			// sandbox, so the file system type and flags are reported in
+			// separate fields instead.
+			records[0] = syscallRecord(ctxData, m.Sysno, m.Exit, len(records)-1).
+				str("fstype", m.Fstype).
+				num("flags", "%x", m.Flags)
+			a.write(ctxData, records...)
+		}
+	case *pb.Umount:
+		if m.Exit != nil {
+			a.write(ctxData,
+				syscallRecord(ctxData, m.Sysno, m.Exit, 1).num("flags", "%x", m.Flags),
+				pathRecord(0, m.Target))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+func newTestSink() (*audit, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return &audit{
+		out: &buf,
+		now: func() time.Time { return time.Unix(1700000000, 123456789) },
+	}, &buf
+}
+
+func testContextData() *pb.ContextData {
+	return &pb.ContextData{
+		TimeNs:        time.Unix(1700000000, 5000000).UnixNano(),
+		ThreadGroupId: 42,
+		ContainerId:   "abc",
+		ProcessName:   "sh",
+		Cwd:           "/root",
+		Credentials: &pb.Credentials{
+			RealUid:      1,
+			EffectiveUid: 2,
+			SavedUid:     3,
+			RealGid:      4,
+			EffectiveGid: 5,
+			SavedGid:     6,
+		},
+	}
+}
+
+var arch = fmt.Sprintf("%x", auditArch)
+
+const testSubject = `pid=42 uid=1 gid=4 euid=2 suid=3 egid=5 sgid=6 comm="sh" container_id="abc"`
+
+func checkOutput(t *testing.T, buf *bytes.Buffer, want []string) {
+	t.Helper()
+	got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d:\n%s", len(got), len(want), buf.String())
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("record %d:\ngot:  %s\nwant: %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: `""`},
+		{in: "/bin/ls", want: `"/bin/ls"`},
+		{in: "a b", want: "612062"},
+		{in: `a"b`, want: "612262"},
+		{in: "\xff", want: "FF"},
+	} {
+		if got := encode(tc.in); got != tc.want {
+			t.Errorf("encode(%q) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestExecve(t *testing.T) {
+	a, buf := newTestSink()
+	info := &pb.ExecveInfo{
+		ContextData: testContextData(),
+		BinaryPath:  "/bin/echo",
+		Argv:        []string{"echo", "hello world"},
+		BinaryMode:  0100755,
+	}
+	if err := a.Execve(context.Background(), seccheck.FieldSet{}, info); err != nil {
+		t.Fatalf("Execve(): %v", err)
+	}
+	checkOutput(t, buf, []string{
+		fmt.Sprintf(`type=SYSCALL msg=audit(1700000000.005:1): arch=%s syscall=%d success=yes exit=0 items=1 %s`, arch, sysnoExecve, testSubject),
+		`type=EXECVE msg=audit(1700000000.005:1): argc=2 a0="echo" a1=68656C6C6F20776F726C64`,
+		`type=CWD msg=audit(1700000000.005:1): cwd="/root"`,
+		`type=PATH msg=audit(1700000000.005:1): item=0 name="/bin/echo" mode=100755 ouid=0 ogid=0`,
+	})
+}
+
+func TestSyscalls(t *testing.T) {
+	a, buf := newTestSink()
+	ctxData := testContextData()
+	ctx := context.Background()
+	// Enter events are ignored.
+	if err := a.Syscall(ctx, seccheck.FieldSet{}, ctxData, pb.MessageType_MESSAGE_SYSCALL_SETID, &pb.Setid{Sysno: 105}); err != nil {
+		t.Fatalf("Syscall(): %v", err)
+	}
+	for _, tc := range []struct {
+		msgType pb.MessageType
+		msg     proto.Message
+	}{
+		{
+			msgType: pb.MessageType_MESSAGE_SYSCALL_SETID,
+			msg:     &pb.Setid{Sysno: 105, Id: 1000, Exit: &pb.Exit{}},
+		},
+		{
+			msgType: pb.MessageType_MESSAGE_SYSCALL_SETRESID,
+			msg:     &pb.Setresid{Sysno: 117, Rid: 1, Eid: 2, Sid: 3, Exit: &pb.Exit{Result: -1, Errorno: 1}},
+		},
+		{
+			msgType: pb.MessageType_MESSAGE_SYSCALL_MOUNT,
+			msg:     &pb.Mount{Sysno: 165, Source: "none", Target: "/mnt", Fstype: "tmpfs", Flags: linux.MS_RDONLY, Exit: &pb.Exit{}},
+		},
+		{
+			msgType: pb.MessageType_MESSAGE_SYSCALL_UMOUNT,
+			msg:     &pb.Umount{Sysno: 166, Target: "/mnt", Exit: &pb.Exit{}},
+		},
+	} {
+		if err := a.Syscall(ctx, seccheck.FieldSet{}, ctxData, tc.msgType, tc.msg); err != nil {
+			t.Fatalf("Syscall(%v): %v", tc.msgType, err)
+		}
+	}
+	checkOutput(t, buf, []string{
+		`type=SYSCALL msg=audit(1700000000.005:1): arch=` + arch + ` syscall=105 success=yes exit=0 a0=3e8 items=0 ` + testSubject,
+		`type=SYSCALL msg=audit(1700000000.005:2): arch=` + arch + ` syscall=117 success=no exit=-1 a0=1 a1=2 a2=3 items=0 ` + testSubject,
+		`type=SYSCALL msg=audit(1700000000.005:3): arch=` + arch + ` syscall=165 success=yes exit=0 items=2 ` + testSubject + ` fstype="tmpfs" flags=1`,
+		`type=PATH msg=audit(1700000000.005:3): item=0 name="/mnt"`,
+		`type=PATH msg=audit(1700000000.005:3): item=1 name="none"`,
+		`type=SYSCALL msg=audit(1700000000.005:4): arch=` + arch + ` syscall=166 success=yes exit=0 items=1 ` + testSubject + ` flags=0`,
+		`type=PATH msg=audit(1700000000.005:4): item=0 name="/mnt"`,
+	})
+}
+
+func TestCapabilityDenied(t *testing.T) {
+	a, buf := newTestSink()
+	// Without context fields, the time of the event is the current time.
+	info := &pb.CapabilityDeniedInfo{Capability: uint32(linux.CAP_SYS_ADMIN)}
+	if err := a.CapabilityDenied(context.Background(), seccheck.FieldSet{}, info); err != nil {
+		t.Fatalf("CapabilityDenied(): %v", err)
+	}
+	checkOutput(t, buf, []string{
+		`type=AVC msg=audit(1700000000.123:1): gvisor="DENIED" operation="capable" class="cap" capability=21 capname="sys_admin"`,
+	})
+}
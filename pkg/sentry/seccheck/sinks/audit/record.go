@@ -0,0 +1,110 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+// record is a single audit record, which consists of a type and a list of
+// key=value fields. Records of the same event share a timestamp and serial
+// number.
+type record struct {
+	typ    string
+	fields strings.Builder
+}
+
+func newRecord(typ string) *record {
+	return &record{typ: typ}
+}
+
+// num adds a field with a value that is printed as is.
+func (r *record) num(key string, format string, v any) *record {
+	fmt.Fprintf(&r.fields, " %s=%s", key, fmt.Sprintf(format, v))
+	return r
+}
+
+// str adds a field with a value that may contain arbitrary bytes.
+func (r *record) str(key, v string) *record {
+	fmt.Fprintf(&r.fields, " %s=%s", key, encode(v))
+	return r
+}
+
+// encode formats s like Linux's audit_log_untrustedstring(): strings that
+// contain spaces, control characters, double quotes or non-ASCII characters
+// are hex-encoded, others are enclosed in double quotes.
+func encode(s string) string {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == '"' || c < 0x21 || c > 0x7e {
+			return strings.ToUpper(hex.EncodeToString([]byte(s)))
+		}
+	}
+	return `"` + s + `"`
+}
+
+// subject adds the fields that identify the task that generated the event,
+// to the extent they were collected in ctxData.
+func (r *record) subject(ctxData *pb.ContextData) *record {
+	if ctxData == nil {
+		return r
+	}
+	if ctxData.ThreadGroupId != 0 {
+		r.num("pid", "%d", ctxData.ThreadGroupId)
+	}
+	if c := ctxData.Credentials; c != nil {
+		r.num("uid", "%d", c.RealUid)
+		r.num("gid", "%d", c.RealGid)
+		r.num("euid", "%d", c.EffectiveUid)
+		r.num("suid", "%d", c.SavedUid)
+		r.num("egid", "%d", c.EffectiveGid)
+		r.num("sgid", "%d", c.SavedGid)
+	}
+	if ctxData.ProcessName != "" {
+		r.str("comm", ctxData.ProcessName)
+	}
+	if ctxData.ContainerId != "" {
+		r.str("container_id", ctxData.ContainerId)
+	}
+	return r
+}
+
+// syscallRecord returns a SYSCALL record for the system call sysno, which returned
+// exit if it's non-nil. args are the arguments of the system call that are
+// known, which are printed in hexadecimal like Linux does.
+func syscallRecord(ctxData *pb.ContextData, sysno uint64, exit *pb.Exit, items int, args ...uint64) *record {
+	r := newRecord("SYSCALL").
+		num("arch", "%x", auditArch).
+		num("syscall", "%d", sysno)
+	if exit != nil {
+		if exit.Errorno != 0 {
+			r.num("success", "%s", "no").num("exit", "%d", -exit.Errorno)
+		} else {
+			r.num("success", "%s", "yes").num("exit", "%d", exit.Result)
+		}
+	}
+	for i, arg := range args {
+		r.num(fmt.Sprintf("a%d", i), "%x", arg)
+	}
+	return r.num("items", "%d", items).subject(ctxData)
+}
+
+// pathRecord returns the PATH record of the item-th path of an event.
+func pathRecord(item int, name string) *record {
+	return newRecord("PATH").num("item", "%d", item).str("name", name)
+}
@@ -0,0 +1,208 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The types below are the subset of the OTLP/JSON trace export request that is
+// used by the sink. See
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto.
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type scopeSpans struct {
+	Scope instrumentationScope `json:"scope"`
+	Spans []span               `json:"spans"`
+}
+
+type instrumentationScope struct {
+	Name string `json:"name"`
+}
+
+// spanKindInternal is SPAN_KIND_INTERNAL.
+const spanKindInternal = 1
+
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+
+	// containerID is the container that generated the span. It's reported as
+	// a resource attribute.
+	containerID string
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func stringAttr(key, value string) keyValue {
+	return keyValue{Key: key, Value: anyValue{StringValue: &value}}
+}
+
+// traceContext is a W3C trace context, as carried by the traceparent header.
+type traceContext struct {
+	traceID [16]byte
+	// parentID is the ID of the span that the container runs under. It's zero
+	// if the container has no parent span.
+	parentID [8]byte
+	sampled  bool
+}
+
+// parseTraceparent parses a W3C traceparent value, e.g.
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func parseTraceparent(value string) (traceContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return traceContext{}, fmt.Errorf("invalid traceparent %q", value)
+	}
+	if len(parts[0]) != 2 || parts[0] == "ff" {
+		return traceContext{}, fmt.Errorf("invalid traceparent version %q", parts[0])
+	}
+	// Version 00 has exactly 4 fields. Later versions may append more.
+	if parts[0] == "00" && len(parts) != 4 {
+		return traceContext{}, fmt.Errorf("invalid traceparent %q", value)
+	}
+	var tc traceContext
+	if err := decodeID(tc.traceID[:], parts[1]); err != nil {
+		return traceContext{}, fmt.Errorf("invalid trace ID: %w", err)
+	}
+	if err := decodeID(tc.parentID[:], parts[2]); err != nil {
+		return traceContext{}, fmt.Errorf("invalid parent ID: %w", err)
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return traceContext{}, fmt.Errorf("invalid trace flags %q", parts[3])
+	}
+	tc.sampled = flags[0]&1 != 0
+	return tc, nil
+}
+
+// decodeID decodes a lowercase hex ID into dst. All-zero IDs are invalid.
+func decodeID(dst []byte, s string) error {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return fmt.Errorf("%q must be %d lowercase hex characters", s, 2*len(dst))
+	}
+	if _, err := hex.Decode(dst, []byte(s)); err != nil {
+		return err
+	}
+	for _, b := range dst {
+		if b != 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is all zeroes", s)
+}
+
+// flattenJSON converts a JSON object into a list of span attributes. Nested
+// objects are flattened by joining keys with ".", and arrays are kept as JSON
+// strings. Attributes are prefixed with prefix and sorted by key.
+func flattenJSON(prefix string, data []byte) ([]keyValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	var attrs []keyValue
+	flatten(prefix, obj, &attrs)
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs, nil
+}
+
+func flatten(prefix string, obj map[string]any, attrs *[]keyValue) {
+	for k, v := range obj {
+		key := prefix + k
+		switch v := v.(type) {
+		case map[string]any:
+			flatten(key+".", v, attrs)
+		case string:
+			*attrs = append(*attrs, stringAttr(key, v))
+		case bool:
+			*attrs = append(*attrs, keyValue{Key: key, Value: anyValue{BoolValue: &v}})
+		case json.Number:
+			if _, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+				s := v.String()
+				*attrs = append(*attrs, keyValue{Key: key, Value: anyValue{IntValue: &s}})
+			} else if f, err := v.Float64(); err == nil {
+				*attrs = append(*attrs, keyValue{Key: key, Value: anyValue{DoubleValue: &f}})
+			}
+		default:
+			out, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			*attrs = append(*attrs, stringAttr(key, string(out)))
+		}
+	}
+}
+
+// newExportRequest groups spans by container into an export request.
+func newExportRequest(serviceName string, spans []span) *exportRequest {
+	req := &exportRequest{}
+	byContainer := make(map[string]int)
+	for _, s := range spans {
+		i, ok := byContainer[s.containerID]
+		if !ok {
+			attrs := []keyValue{stringAttr("service.name", serviceName)}
+			if s.containerID != "" {
+				attrs = append(attrs, stringAttr("container.id", s.containerID))
+			}
+			i = len(req.ResourceSpans)
+			byContainer[s.containerID] = i
+			req.ResourceSpans = append(req.ResourceSpans, resourceSpans{
+				Resource: resource{Attributes: attrs},
+				ScopeSpans: []scopeSpans{
+					{Scope: instrumentationScope{Name: scopeName}},
+				},
+			})
+		}
+		ss := &req.ResourceSpans[i].ScopeSpans[0]
+		ss.Spans = append(ss.Spans, s)
+	}
+	return req
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tc, err := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("parseTraceparent(): %v", err)
+	}
+	if got, want := hex.EncodeToString(tc.traceID[:]), "4bf92f3577b34da6a3ce929d0e0e4736"; got != want {
+		t.Errorf("trace ID: got %q, want %q", got, want)
+	}
+	if got, want := hex.EncodeToString(tc.parentID[:]), "00f067aa0ba902b7"; got != want {
+		t.Errorf("parent ID: got %q, want %q", got, want)
+	}
+	if !tc.sampled {
+		t.Errorf("sampled: got false, want true")
+	}
+
+	tc, err = parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	if err != nil {
+		t.Fatalf("parseTraceparent(): %v", err)
+	}
+	if tc.sampled {
+		t.Errorf("sampled: got true, want false")
+	}
+}
+
+func TestParseTraceparentError(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	} {
+		if _, err := parseTraceparent(value); err == nil {
+			t.Errorf("parseTraceparent(%q) should have failed", value)
+		}
+	}
+}
+
+func TestFlattenJSON(t *testing.T) {
+	in := `{"fd":"3","flags":2,"exit":{"result":"-1","errorno":2},"ok":true,"args":["a","b"],"ratio":0.5}`
+	attrs, err := flattenJSON("p.", []byte(in))
+	if err != nil {
+		t.Fatalf("flattenJSON(): %v", err)
+	}
+	out, err := json.Marshal(attrs)
+	if err != nil {
+		t.Fatalf("json.Marshal(): %v", err)
+	}
+	want := `[` +
+		`{"key":"p.args","value":{"stringValue":"[\"a\",\"b\"]"}},` +
+		`{"key":"p.exit.errorno","value":{"intValue":"2"}},` +
+		`{"key":"p.exit.result","value":{"stringValue":"-1"}},` +
+		`{"key":"p.fd","value":{"stringValue":"3"}},` +
+		`{"key":"p.flags","value":{"intValue":"2"}},` +
+		`{"key":"p.ok","value":{"boolValue":true}},` +
+		`{"key":"p.ratio","value":{"doubleValue":0.5}}` +
+		`]`
+	if string(out) != want {
+		t.Errorf("flattenJSON():\ngot:  %s\nwant: %s", out, want)
+	}
+}
+
+func TestNewExportRequest(t *testing.T) {
+	spans := []span{
+		{Name: "a", containerID: "c1"},
+		{Name: "b", containerID: "c2"},
+		{Name: "c", containerID: "c1"},
+	}
+	req := newExportRequest("svc", spans)
+	if got, want := len(req.ResourceSpans), 2; got != want {
+		t.Fatalf("resource spans: got %d, want %d", got, want)
+	}
+	for i, want := range []struct {
+		cid   string
+		names []string
+	}{
+		{cid: "c1", names: []string{"a", "c"}},
+		{cid: "c2", names: []string{"b"}},
+	} {
+		rs := req.ResourceSpans[i]
+		attrs := rs.Resource.Attributes
+		if len(attrs) != 2 || *attrs[0].Value.StringValue != "svc" || *attrs[1].Value.StringValue != want.cid {
+			t.Errorf("resource %d: unexpected attributes %+v", i, attrs)
+		}
+		var names []string
+		for _, s := range rs.ScopeSpans[0].Spans {
+			names = append(names, s.Name)
+		}
+		if len(names) != len(want.names) {
+			t.Fatalf("resource %d: got spans %v, want %v", i, names, want.names)
+		}
+		for j := range names {
+			if names[j] != want.names[j] {
+				t.Errorf("resource %d: got spans %v, want %v", i, names, want.names)
+			}
+		}
+	}
+}
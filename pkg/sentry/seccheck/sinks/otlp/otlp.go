@@ -0,0 +1,460 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlp defines a seccheck.Sink that exports points as OpenTelemetry
+// spans to an OTLP/HTTP collector, using the JSON encoding. Spans are batched
+// and exported asynchronously.
+package otlp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/rand"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const (
+	name = "otlp"
+
+	// scopeName is the instrumentation scope reported with all spans.
+	scopeName = "gvisor.dev/gvisor/pkg/sentry/seccheck"
+
+	// traceparentEnv is the environment variable used to propagate a W3C
+	// trace context to the container.
+	traceparentEnv = "TRACEPARENT"
+
+	// traceparentAnnotation is the OCI annotation used to propagate a W3C
+	// trace context to the container.
+	traceparentAnnotation = "dev.gvisor.traceparent"
+
+	defaultPath          = "/v1/traces"
+	defaultServiceName   = "gvisor"
+	defaultBatchSize     = 512
+	defaultQueueSize     = 8192
+	defaultFlushInterval = time.Second
+)
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:  name,
+		Setup: setupSink,
+		New:   new,
+	})
+}
+
+// otlp converts points into spans and sends them in batches to a collector.
+// Spans are queued and exported from a separate goroutine. If the queue is
+// full, or the collector cannot be reached, spans are dropped to avoid
+// delaying the application.
+//
+// Each span belongs to the trace of the container that generated it. The trace
+// is taken from the W3C trace context passed to the container, either in the
+// TRACEPARENT environment variable or in the dev.gvisor.traceparent
+// annotation. Containers without a trace context get a new trace.
+type otlp struct {
+	endpoint *fd.FD
+
+	path          string
+	host          string
+	serviceName   string
+	batchSize     int
+	flushInterval time.Duration
+
+	spans chan span
+	stop  chan struct{}
+	done  chan struct{}
+
+	droppedCount atomicbitops.Uint64
+
+	mu sync.Mutex
+	// traces maps container IDs to the trace context of their spans.
+	//
+	// +checklocks:mu
+	traces map[string]traceContext
+}
+
+var _ seccheck.Sink = (*otlp)(nil)
+
+// setupSink connects to the collector and returns a file that can be used to
+// communicate with it. The caller is responsible to close to file.
+func setupSink(config map[string]any) (*os.File, error) {
+	addrOpaque, ok := config["endpoint"]
+	if !ok {
+		return nil, fmt.Errorf("endpoint not present in configuration")
+	}
+	addr, ok := addrOpaque.(string)
+	if !ok {
+		return nil, fmt.Errorf("endpoint %q is not a string", addrOpaque)
+	}
+	network := "tcp"
+	if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+		network, addr = "unix", path
+	}
+	log.Debugf("OTLP sink connecting to %s %q", network, addr)
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %q: %w", addr, err)
+	}
+	defer conn.Close()
+
+	var f *os.File
+	switch c := conn.(type) {
+	case *net.TCPConn:
+		f, err = c.File()
+	case *net.UnixConn:
+		f, err = c.File()
+	default:
+		err = fmt.Errorf("unexpected connection type %T", conn)
+	}
+	return f, err
+}
+
+func configString(config map[string]any, name string) (string, bool, error) {
+	opaque, ok := config[name]
+	if !ok {
+		return "", false, nil
+	}
+	s, ok := opaque.(string)
+	if !ok {
+		return "", false, fmt.Errorf("%s %v is not a string", name, opaque)
+	}
+	return s, true, nil
+}
+
+func configInt(config map[string]any, name string) (int, bool, error) {
+	opaque, ok := config[name]
+	if !ok {
+		return 0, false, nil
+	}
+	f, ok := opaque.(float64)
+	if !ok || f != float64(int(f)) || f <= 0 {
+		return 0, false, fmt.Errorf("%s %v is not a positive int", name, opaque)
+	}
+	return int(f), true, nil
+}
+
+// new creates a new OTLP sink.
+func new(config map[string]any, endpoint *fd.FD) (seccheck.Sink, error) {
+	if endpoint == nil {
+		return nil, fmt.Errorf("otlp sink requires an endpoint")
+	}
+	o := &otlp{
+		endpoint:      endpoint,
+		path:          defaultPath,
+		host:          "localhost",
+		serviceName:   defaultServiceName,
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+		traces:        make(map[string]traceContext),
+	}
+	if addr, ok, err := configString(config, "endpoint"); err != nil {
+		return nil, err
+	} else if ok && !strings.HasPrefix(addr, "unix://") {
+		o.host = addr
+	}
+	if path, ok, err := configString(config, "path"); err != nil {
+		return nil, err
+	} else if ok {
+		o.path = path
+	}
+	if service, ok, err := configString(config, "service_name"); err != nil {
+		return nil, err
+	} else if ok {
+		o.serviceName = service
+	}
+	if size, ok, err := configInt(config, "batch_size"); err != nil {
+		return nil, err
+	} else if ok {
+		o.batchSize = size
+	}
+	queueSize := defaultQueueSize
+	if size, ok, err := configInt(config, "queue_size"); err != nil {
+		return nil, err
+	} else if ok {
+		queueSize = size
+	}
+	if interval, ok, err := configString(config, "flush_interval"); err != nil {
+		return nil, err
+	} else if ok {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, err
+		}
+		o.flushInterval = d
+	}
+	o.spans = make(chan span, queueSize)
+
+	go o.run() // S/R-SAFE: sinks are not saved.
+
+	log.Debugf("OTLP sink created, endpoint FD: %d, %+v", o.endpoint.FD(), o)
+	return o, nil
+}
+
+// Name implements seccheck.Sink.
+func (*otlp) Name() string {
+	return name
+}
+
+// Status implements seccheck.Sink.
+func (o *otlp) Status() seccheck.SinkStatus {
+	return seccheck.SinkStatus{
+		DroppedCount: o.droppedCount.Load(),
+	}
+}
+
+// Stop implements seccheck.Sink.
+func (o *otlp) Stop() {
+	close(o.stop)
+	<-o.done
+	o.endpoint.Close()
+}
+
+// run batches queued spans and exports them until the sink is stopped.
+func (o *otlp) run() {
+	defer close(o.done)
+
+	rw := bufio.NewReadWriter(bufio.NewReader(o.endpoint), bufio.NewWriter(o.endpoint))
+	ticker := time.NewTicker(o.flushInterval)
+	defer ticker.Stop()
+
+	broken := false
+	var batch []span
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if broken {
+			o.droppedCount.Add(uint64(len(batch)))
+		} else if err := o.export(rw, batch); err != nil {
+			// The connection can't be reestablished from inside the sandbox.
+			log.Warningf("OTLP export failed, dropping spans from now on: %v", err)
+			o.droppedCount.Add(uint64(len(batch)))
+			broken = true
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case s := <-o.spans:
+			batch = append(batch, s)
+			if len(batch) >= o.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-o.stop:
+			for {
+				select {
+				case s := <-o.spans:
+					batch = append(batch, s)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// export sends spans to the collector and waits for the response.
+func (o *otlp) export(rw *bufio.ReadWriter, spans []span) error {
+	body, err := json.Marshal(newExportRequest(o.serviceName, spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, "http://"+o.host+o.path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := req.Write(rw); err != nil {
+		return err
+	}
+	if err := rw.Flush(); err != nil {
+		return err
+	}
+	resp, err := http.ReadResponse(rw.Reader, req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %q", resp.Status)
+	}
+	if resp.Close {
+		return fmt.Errorf("collector closed the connection")
+	}
+	return nil
+}
+
+// traceFor returns the trace context for spans generated by the container.
+func (o *otlp) traceFor(cid string) traceContext {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	tc, ok := o.traces[cid]
+	if !ok {
+		_, _ = rand.Read(tc.traceID[:])
+		tc.sampled = true
+		o.traces[cid] = tc
+	}
+	return tc
+}
+
+// setTrace records the trace context passed to a starting container, if any.
+func (o *otlp) setTrace(info *pb.Start) {
+	var value string
+	for _, env := range info.Env {
+		if v, ok := strings.CutPrefix(env, traceparentEnv+"="); ok {
+			value = v
+		}
+	}
+	if v, ok := info.Annotations[traceparentAnnotation]; ok {
+		value = v
+	}
+	if value == "" {
+		return
+	}
+	tc, err := parseTraceparent(value)
+	if err != nil {
+		log.Warningf("Ignoring trace context for container %q: %v", info.Id, err)
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.traces[info.Id] = tc
+}
+
+// write converts a point into a span and queues it for export.
+func (o *otlp) write(ctx context.Context, cid string, ctxData *pb.ContextData, msg proto.Message, msgType pb.MessageType) {
+	if cid == "" {
+		if target, ok := ctx.Value(seccheck.CtxFilterTarget).(seccheck.FilterTarget); ok {
+			cid = target.ContainerID()
+		} else if ctxData != nil {
+			cid = ctxData.ContainerId
+		}
+	}
+	tc := o.traceFor(cid)
+	if !tc.sampled {
+		return
+	}
+
+	out, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		log.Debugf("Marshal(%+v): %v", msg, err)
+		return
+	}
+	attrs, err := flattenJSON("gvisor.", out)
+	if err != nil {
+		log.Debugf("Flattening %s: %v", out, err)
+		return
+	}
+
+	now := time.Now().UnixNano()
+	if ctxData != nil && ctxData.TimeNs != 0 {
+		now = ctxData.TimeNs
+	}
+	ts := strconv.FormatInt(now, 10)
+	var spanID [8]byte
+	_, _ = rand.Read(spanID[:])
+	s := span{
+		TraceID:           hex.EncodeToString(tc.traceID[:]),
+		SpanID:            hex.EncodeToString(spanID[:]),
+		Name:              strings.ToLower(strings.TrimPrefix(msgType.String(), "MESSAGE_")),
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: ts,
+		EndTimeUnixNano:   ts,
+		Attributes:        attrs,
+		containerID:       cid,
+	}
+	if tc.parentID != [8]byte{} {
+		s.ParentSpanID = hex.EncodeToString(tc.parentID[:])
+	}
+
+	select {
+	case o.spans <- s:
+	default:
+		o.droppedCount.Add(1)
+	}
+}
+
+// Clone implements seccheck.Sink.
+func (o *otlp) Clone(ctx context.Context, _ seccheck.FieldSet, info *pb.CloneInfo) error {
+	o.write(ctx, "", info.GetContextData(), info, pb.MessageType_MESSAGE_SENTRY_CLONE)
+	return nil
+}
+
+// Execve implements seccheck.Sink.
+func (o *otlp) Execve(ctx context.Context, _ seccheck.FieldSet, info *pb.ExecveInfo) error {
+	o.write(ctx, "", info.GetContextData(), info, pb.MessageType_MESSAGE_SENTRY_EXEC)
+	return nil
+}
+
+// ExitNotifyParent implements seccheck.Sink.
+func (o *otlp) ExitNotifyParent(ctx context.Context, _ seccheck.FieldSet, info *pb.ExitNotifyParentInfo) error {
+	o.write(ctx, "", info.GetContextData(), info, pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT)
+	return nil
+}
+
+// TaskExit implements seccheck.Sink.
+func (o *otlp) TaskExit(ctx context.Context, _ seccheck.FieldSet, info *pb.TaskExit) error {
+	o.write(ctx, "", info.GetContextData(), info, pb.MessageType_MESSAGE_SENTRY_TASK_EXIT)
+	return nil
+}
+
+// CapabilityDenied implements seccheck.Sink.
+func (o *otlp) CapabilityDenied(ctx context.Context, _ seccheck.FieldSet, info *pb.CapabilityDeniedInfo) error {
+	o.write(ctx, "", info.GetContextData(), info, pb.MessageType_MESSAGE_SENTRY_CAPABILITY_DENIED)
+	return nil
+}
+
+// ContainerStart implements seccheck.Sink.
+func (o *otlp) ContainerStart(ctx context.Context, _ seccheck.FieldSet, info *pb.Start) error {
+	o.setTrace(info)
+	o.write(ctx, info.Id, info.GetContextData(), info, pb.MessageType_MESSAGE_CONTAINER_START)
+	return nil
+}
+
+// RawSyscall implements seccheck.Sink.
+func (o *otlp) RawSyscall(ctx context.Context, _ seccheck.FieldSet, info *pb.Syscall) error {
+	o.write(ctx, "", info.GetContextData(), info, pb.MessageType_MESSAGE_SYSCALL_RAW)
+	return nil
+}
+
+// Syscall implements seccheck.Sink.
+func (o *otlp) Syscall(ctx context.Context, _ seccheck.FieldSet, ctxData *pb.ContextData, msgType pb.MessageType, msg proto.Message) error {
+	o.write(ctx, "", ctxData, msg, msgType)
+	return nil
+}
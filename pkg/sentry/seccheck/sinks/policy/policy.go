@@ -0,0 +1,276 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policy defines a seccheck.Sink that enforces a runtime security
+// policy. The policy is a list of rules that match points by name, by the
+// process and its ancestors, and by the fields of the point, and that log,
+// kill the offending process, or pause the sandbox when they match.
+package policy
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const name = "policy"
+
+func init() {
+	seccheck.RegisterSink(seccheck.SinkDesc{
+		Name:  name,
+		Setup: setupSink,
+		New:   new,
+	})
+}
+
+var (
+	// sinksMu protects sinks.
+	sinksMu sync.Mutex
+	// sinks are the policy sinks that are running, which are updated by
+	// Reload.
+	sinks = map[*policy]struct{}{}
+)
+
+// policy matches points against rules and takes the action of the rules that
+// match. Points are processed synchronously, so that the operation that
+// generated them can be prevented.
+//
+// sentry/exit_notify_parent is ignored, because it's called with kernel locks
+// held that actions require.
+type policy struct {
+	seccheck.SinkDefaults
+
+	mu sync.RWMutex
+	// rules is replaced on Reload. Protected by mu.
+	rules *Rules
+}
+
+var _ seccheck.Sink = (*policy)(nil)
+
+// setupSink opens the rules file, which is read inside the sandbox.
+func setupSink(config map[string]any) (*os.File, error) {
+	pathOpaque, ok := config["rules_file"]
+	if !ok {
+		return nil, fmt.Errorf("rules_file not present in configuration")
+	}
+	path, ok := pathOpaque.(string)
+	if !ok {
+		return nil, fmt.Errorf("rules_file %q is not a string", pathOpaque)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening rules file: %w", err)
+	}
+	return f, nil
+}
+
+// new creates a new policy sink with the rules read from endpoint.
+func new(_ map[string]any, endpoint *fd.FD) (seccheck.Sink, error) {
+	if endpoint == nil {
+		return nil, fmt.Errorf("policy sink requires a rules file")
+	}
+	defer endpoint.Close()
+	rules, err := ParseRules(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Policy sink created with %d rules", len(rules.Rules))
+
+	p := &policy{rules: rules}
+	sinksMu.Lock()
+	sinks[p] = struct{}{}
+	sinksMu.Unlock()
+	return p, nil
+}
+
+// Reload replaces the rules of all running policy sinks with the rules read
+// from r. If the rules are invalid, the sinks keep their current rules.
+func Reload(r io.Reader) error {
+	rules, err := ParseRules(r)
+	if err != nil {
+		return err
+	}
+
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	if len(sinks) == 0 {
+		return fmt.Errorf("no policy sink is running")
+	}
+	for p := range sinks {
+		p.mu.Lock()
+		p.rules = rules
+		p.mu.Unlock()
+	}
+	log.Infof("Policy reloaded with %d rules", len(rules.Rules))
+	return nil
+}
+
+// Name implements seccheck.Sink.
+func (*policy) Name() string {
+	return name
+}
+
+// Status implements seccheck.Sink.
+func (*policy) Status() seccheck.SinkStatus {
+	return seccheck.SinkStatus{}
+}
+
+// Stop implements seccheck.Sink.
+func (p *policy) Stop() {
+	sinksMu.Lock()
+	delete(sinks, p)
+	sinksMu.Unlock()
+}
+
+// check matches the point against the rules and takes their actions. It
+// returns an error if the operation that generated the point must fail.
+func (p *policy) check(ctx context.Context, name string, msg proto.Message) error {
+	p.mu.RLock()
+	rules := p.rules
+	p.mu.RUnlock()
+
+	t := kernel.TaskFromContext(ctx)
+	matched := rules.match(&event{
+		name:    name,
+		msg:     msg,
+		lineage: func() []string { return lineage(t) },
+	})
+
+	var err error
+	for _, r := range matched {
+		if e := p.apply(ctx, t, r, name, msg); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// apply takes the action of rule r, which matched the point.
+func (p *policy) apply(ctx context.Context, t *kernel.Task, r *Rule, name string, msg proto.Message) error {
+	process := "<unknown>"
+	tgid := kernel.ThreadID(0)
+	if t != nil {
+		process = t.Name()
+		tgid = t.ThreadGroup().ID()
+	}
+	var details []byte
+	if msg != nil {
+		details, _ = protojson.Marshal(msg)
+	}
+	log.Warningf("Policy rule %q matched %s from %q (PID %d), action: %s, event: %s", r.Name, name, process, tgid, r.Action, details)
+
+	switch r.Action {
+	case ActionKill:
+		if t == nil {
+			log.Warningf("Policy rule %q: no process to kill", r.Name)
+			return nil
+		}
+		if err := t.SendGroupSignal(kernel.SignalInfoPriv(linux.SIGKILL)); err != nil {
+			log.Warningf("Policy rule %q: killing PID %d: %v", r.Name, tgid, err)
+		}
+		return linuxerr.EPERM
+
+	case ActionPause:
+		k := kernel.KernelFromContext(ctx)
+		if k == nil {
+			log.Warningf("Policy rule %q: no kernel to pause", r.Name)
+			return nil
+		}
+		if k.IsPaused() {
+			return nil
+		}
+		// Kernel.Pause waits for all tasks to stop, including the one that
+		// generated the point, so it can't be called synchronously. The
+		// operation that generated the point isn't prevented, but it's
+		// interrupted if it blocks.
+		go k.Pause() // S/R-SAFE: the kernel is paused before it's saved.
+	}
+	return nil
+}
+
+// lineage returns the names of t and of its ancestors.
+func lineage(t *kernel.Task) []string {
+	var names []string
+	for ; t != nil; t = t.Parent() {
+		names = append(names, t.Name())
+	}
+	return names
+}
+
+// syscallEvent returns the name of the event of a syscall point, e.g.
+// "syscall/openat/enter".
+func syscallEvent(ctx context.Context, msg proto.Message) string {
+	m := msg.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	sysno := uintptr(0)
+	if fd := fields.ByName("sysno"); fd != nil {
+		sysno = uintptr(m.Get(fd).Uint())
+	}
+	syscall := fmt.Sprintf("sys_%d", sysno)
+	if t := kernel.TaskFromContext(ctx); t != nil {
+		syscall = t.SyscallTable().LookupName(sysno)
+	}
+	suffix := "enter"
+	if fd := fields.ByName("exit"); fd != nil && m.Has(fd) {
+		suffix = "exit"
+	}
+	return fmt.Sprintf("syscall/%s/%s", syscall, suffix)
+}
+
+// Clone implements seccheck.Sink.
+func (p *policy) Clone(ctx context.Context, _ seccheck.FieldSet, info *pb.CloneInfo) error {
+	return p.check(ctx, "sentry/clone", info)
+}
+
+// Execve implements seccheck.Sink.
+func (p *policy) Execve(ctx context.Context, _ seccheck.FieldSet, info *pb.ExecveInfo) error {
+	return p.check(ctx, "sentry/execve", info)
+}
+
+// TaskExit implements seccheck.Sink.
+func (p *policy) TaskExit(ctx context.Context, _ seccheck.FieldSet, info *pb.TaskExit) error {
+	return p.check(ctx, "sentry/task_exit", info)
+}
+
+// CapabilityDenied implements seccheck.Sink.
+func (p *policy) CapabilityDenied(ctx context.Context, _ seccheck.FieldSet, info *pb.CapabilityDeniedInfo) error {
+	return p.check(ctx, "sentry/capability_denied", info)
+}
+
+// ContainerStart implements seccheck.Sink.
+func (p *policy) ContainerStart(ctx context.Context, _ seccheck.FieldSet, info *pb.Start) error {
+	return p.check(ctx, "container/start", info)
+}
+
+// RawSyscall implements seccheck.Sink.
+func (p *policy) RawSyscall(ctx context.Context, _ seccheck.FieldSet, info *pb.Syscall) error {
+	return p.check(ctx, syscallEvent(ctx, info), info)
+}
+
+// Syscall implements seccheck.Sink.
+func (p *policy) Syscall(ctx context.Context, _ seccheck.FieldSet, _ *pb.ContextData, _ pb.MessageType, msg proto.Message) error {
+	return p.check(ctx, syscallEvent(ctx, msg), msg)
+}
@@ -0,0 +1,303 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Action is what is done when a rule matches an event.
+type Action string
+
+const (
+	// ActionLog logs the event.
+	ActionLog Action = "log"
+	// ActionKill kills the thread group that generated the event with
+	// SIGKILL, and prevents the operation that generated the event, if
+	// possible.
+	ActionKill Action = "kill"
+	// ActionPause pauses the sandbox.
+	ActionPause Action = "pause"
+)
+
+// Rules is the contents of a rules file.
+type Rules struct {
+	// Rules are matched against every event in order. All matching rules
+	// apply to an event.
+	Rules []Rule `json:"rules"`
+}
+
+// Rule matches events and takes an action on them. An event matches if it
+// satisfies all of the predicates that are set.
+type Rule struct {
+	// Name identifies the rule in logs.
+	Name string `json:"name"`
+	// Events are patterns that match the names of events, as in path.Match.
+	// Events are named after the point that generated them, e.g.
+	// "sentry/execve" or "syscall/openat/enter". Events from raw syscall
+	// points are named like schematized ones.
+	Events []string `json:"events"`
+	// Process are patterns that match the name of the process that generated
+	// the event.
+	Process []string `json:"process,omitempty"`
+	// Ancestors are patterns that match the name of any ancestor of the
+	// process that generated the event, i.e. its parent, grandparent, etc.
+	Ancestors []string `json:"ancestors,omitempty"`
+	// Args are predicates on the fields of the event.
+	Args []Arg `json:"args,omitempty"`
+	// Action is taken when the rule matches.
+	Action Action `json:"action"`
+}
+
+// Arg is a predicate on a field of the event. Exactly one of Glob, Values
+// and MaskAny must be set. Repeated fields match if any of their elements
+// does.
+type Arg struct {
+	// Field is the name of the field in the protobuf message of the event,
+	// e.g. "pathname". Fields of nested messages are separated by dots, e.g.
+	// "exit.errorno".
+	Field string `json:"field"`
+	// Glob are patterns that match string fields, as in path.Match.
+	Glob []string `json:"glob,omitempty"`
+	// Values match integer fields equal to any of them.
+	Values []int64 `json:"values,omitempty"`
+	// MaskAny matches integer fields with any of its bits set.
+	MaskAny uint64 `json:"mask_any,omitempty"`
+	// Not negates the predicate. Events without the field never match.
+	Not bool `json:"not,omitempty"`
+}
+
+// ParseRules reads and validates a rules file.
+func ParseRules(r io.Reader) (*Rules, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var rules Rules
+	if err := dec.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("parsing rules: %w", err)
+	}
+	for i := range rules.Rules {
+		if err := rules.Rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("rule %d (%q): %w", i, rules.Rules[i].Name, err)
+		}
+	}
+	return &rules, nil
+}
+
+func (r *Rule) validate() error {
+	if len(r.Events) == 0 {
+		return fmt.Errorf("no events")
+	}
+	switch r.Action {
+	case ActionLog, ActionKill, ActionPause:
+	default:
+		return fmt.Errorf("invalid action %q", r.Action)
+	}
+	for _, patterns := range [][]string{r.Events, r.Process, r.Ancestors} {
+		if err := validatePatterns(patterns); err != nil {
+			return err
+		}
+	}
+	for _, arg := range r.Args {
+		if arg.Field == "" {
+			return fmt.Errorf("argument without field")
+		}
+		set := 0
+		if len(arg.Glob) > 0 {
+			set++
+		}
+		if len(arg.Values) > 0 {
+			set++
+		}
+		if arg.MaskAny != 0 {
+			set++
+		}
+		if set != 1 {
+			return fmt.Errorf("argument %q must set exactly one of glob, values and mask_any", arg.Field)
+		}
+		if err := validatePatterns(arg.Glob); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// event is an event that rules are matched against.
+type event struct {
+	// name is the name of the event, e.g. "sentry/execve".
+	name string
+	// msg is the protobuf message of the point that generated the event.
+	msg proto.Message
+	// lineage returns the names of the process that generated the event and
+	// of its ancestors, from the closest. It returns nil if the process is
+	// unknown. It's only called if needed.
+	lineage func() []string
+}
+
+// match returns the rules that match ev.
+func (rs *Rules) match(ev *event) []*Rule {
+	var (
+		matched    []*Rule
+		lineage    []string
+		hasLineage bool
+	)
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if !matchAny(r.Events, ev.name) {
+			continue
+		}
+		if len(r.Process) > 0 || len(r.Ancestors) > 0 {
+			if !hasLineage {
+				lineage = ev.lineage()
+				hasLineage = true
+			}
+			if len(lineage) == 0 {
+				continue
+			}
+			if len(r.Process) > 0 && !matchAny(r.Process, lineage[0]) {
+				continue
+			}
+			if len(r.Ancestors) > 0 && !matchAnyOf(r.Ancestors, lineage[1:]) {
+				continue
+			}
+		}
+		if !matchArgs(r.Args, ev.msg) {
+			continue
+		}
+		matched = append(matched, r)
+	}
+	return matched
+}
+
+// matchAny returns true if s matches any of patterns.
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAnyOf returns true if any of values matches any of patterns.
+func matchAnyOf(patterns []string, values []string) bool {
+	for _, v := range values {
+		if matchAny(patterns, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchArgs(args []Arg, msg proto.Message) bool {
+	if len(args) == 0 {
+		return true
+	}
+	if msg == nil {
+		return false
+	}
+	m := msg.ProtoReflect()
+	for i := range args {
+		if !args[i].match(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns true if the field of m satisfies the predicate.
+func (a *Arg) match(m protoreflect.Message) bool {
+	fd, v, ok := lookupField(m, a.Field)
+	if !ok {
+		return false
+	}
+	matched := false
+	if fd.IsList() {
+		l := v.List()
+		for i := 0; i < l.Len() && !matched; i++ {
+			matched = a.matchValue(fd, l.Get(i))
+		}
+	} else {
+		matched = a.matchValue(fd, v)
+	}
+	return matched != a.Not
+}
+
+// lookupField returns the field of m at the dotted path name.
+func lookupField(m protoreflect.Message, name string) (protoreflect.FieldDescriptor, protoreflect.Value, bool) {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		fd := m.Descriptor().Fields().ByName(protoreflect.Name(part))
+		if fd == nil || fd.IsMap() {
+			return nil, protoreflect.Value{}, false
+		}
+		if i == len(parts)-1 {
+			return fd, m.Get(fd), true
+		}
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || !m.Has(fd) {
+			return nil, protoreflect.Value{}, false
+		}
+		m = m.Get(fd).Message()
+	}
+	panic("unreachable")
+}
+
+func (a *Arg) matchValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+	var n int64
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return matchAny(a.Glob, v.String())
+	case protoreflect.BytesKind:
+		return matchAny(a.Glob, string(v.Bytes()))
+	case protoreflect.BoolKind:
+		if v.Bool() {
+			n = 1
+		}
+	case protoreflect.EnumKind:
+		n = int64(v.Enum())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n = v.Int()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n = int64(v.Uint())
+	default:
+		return false
+	}
+	if a.MaskAny != 0 {
+		return uint64(n)&a.MaskAny != 0
+	}
+	for _, want := range a.Values {
+		if n == want {
+			return true
+		}
+	}
+	return false
+}
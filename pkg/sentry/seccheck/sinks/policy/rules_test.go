@@ -0,0 +1,217 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
+)
+
+const testRules = `{
+  "rules": [
+    {
+      "name": "shell in web server",
+      "events": ["sentry/execve"],
+      "process": ["nginx"],
+      "args": [{"field": "binary_path", "glob": ["/bin/*sh", "/usr/bin/*sh"]}],
+      "action": "kill"
+    },
+    {
+      "name": "sensitive file",
+      "events": ["syscall/open*/enter"],
+      "args": [
+        {"field": "pathname", "glob": ["/etc/shadow"]},
+        {"field": "flags", "mask_any": 3}
+      ],
+      "action": "log"
+    },
+    {
+      "name": "failed mount",
+      "events": ["syscall/mount/exit"],
+      "ancestors": ["sshd"],
+      "args": [{"field": "exit.errorno", "values": [0], "not": true}],
+      "action": "pause"
+    },
+    {
+      "name": "argv",
+      "events": ["syscall/execve/*"],
+      "args": [{"field": "argv", "glob": ["--privileged"]}],
+      "action": "log"
+    }
+  ]
+}`
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatalf("ParseRules(): %v", err)
+	}
+	if got, want := len(rules.Rules), 4; got != want {
+		t.Errorf("got %d rules, want %d", got, want)
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		rules string
+	}{
+		{
+			name:  "syntax",
+			rules: `{"rules": [`,
+		},
+		{
+			name:  "unknown field",
+			rules: `{"rules": [{"name": "x", "events": ["*"], "action": "log", "foo": 1}]}`,
+		},
+		{
+			name:  "no events",
+			rules: `{"rules": [{"name": "x", "action": "log"}]}`,
+		},
+		{
+			name:  "action",
+			rules: `{"rules": [{"name": "x", "events": ["*"], "action": "explode"}]}`,
+		},
+		{
+			name:  "pattern",
+			rules: `{"rules": [{"name": "x", "events": ["["], "action": "log"}]}`,
+		},
+		{
+			name:  "arg without predicate",
+			rules: `{"rules": [{"name": "x", "events": ["*"], "action": "log", "args": [{"field": "fd"}]}]}`,
+		},
+		{
+			name:  "arg with two predicates",
+			rules: `{"rules": [{"name": "x", "events": ["*"], "action": "log", "args": [{"field": "fd", "values": [1], "mask_any": 1}]}]}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseRules(strings.NewReader(tc.rules)); err == nil {
+				t.Errorf("ParseRules() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatalf("ParseRules(): %v", err)
+	}
+	for _, tc := range []struct {
+		name    string
+		event   string
+		msg     proto.Message
+		lineage []string
+		want    []string
+	}{
+		{
+			name:    "execve shell",
+			event:   "sentry/execve",
+			msg:     &pb.ExecveInfo{BinaryPath: "/bin/bash"},
+			lineage: []string{"nginx", "init"},
+			want:    []string{"shell in web server"},
+		},
+		{
+			name:    "execve other process",
+			event:   "sentry/execve",
+			msg:     &pb.ExecveInfo{BinaryPath: "/bin/bash"},
+			lineage: []string{"sshd"},
+		},
+		{
+			name:    "execve other binary",
+			event:   "sentry/execve",
+			msg:     &pb.ExecveInfo{BinaryPath: "/bin/ls"},
+			lineage: []string{"nginx"},
+		},
+		{
+			name:  "open for writing",
+			event: "syscall/openat/enter",
+			msg:   &pb.Open{Pathname: "/etc/shadow", Flags: 2},
+			want:  []string{"sensitive file"},
+		},
+		{
+			name:  "open for reading",
+			event: "syscall/openat/enter",
+			msg:   &pb.Open{Pathname: "/etc/shadow"},
+		},
+		{
+			name:  "open exit",
+			event: "syscall/openat/exit",
+			msg:   &pb.Open{Pathname: "/etc/shadow", Flags: 1, Exit: &pb.Exit{}},
+		},
+		{
+			name:    "failed mount",
+			event:   "syscall/mount/exit",
+			msg:     &pb.Mount{Exit: &pb.Exit{Errorno: 1}},
+			lineage: []string{"bash", "sshd", "init"},
+			want:    []string{"failed mount"},
+		},
+		{
+			name:    "successful mount",
+			event:   "syscall/mount/exit",
+			msg:     &pb.Mount{Exit: &pb.Exit{}},
+			lineage: []string{"bash", "sshd", "init"},
+		},
+		{
+			name:    "failed mount process",
+			event:   "syscall/mount/exit",
+			msg:     &pb.Mount{Exit: &pb.Exit{Errorno: 1}},
+			lineage: []string{"sshd", "init"},
+		},
+		{
+			name:    "unknown process",
+			event:   "syscall/mount/exit",
+			msg:     &pb.Mount{Exit: &pb.Exit{Errorno: 1}},
+			lineage: nil,
+		},
+		{
+			name:  "repeated field",
+			event: "syscall/execve/enter",
+			msg:   &pb.Execve{Argv: []string{"docker", "--privileged"}},
+			want:  []string{"argv"},
+		},
+		{
+			name:  "missing field",
+			event: "syscall/execve/enter",
+			msg:   &pb.Syscall{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			lineageCalled := false
+			matched := rules.match(&event{
+				name: tc.event,
+				msg:  tc.msg,
+				lineage: func() []string {
+					if lineageCalled {
+						t.Errorf("lineage called twice")
+					}
+					lineageCalled = true
+					return tc.lineage
+				},
+			})
+			var got []string
+			for _, r := range matched {
+				got = append(got, r.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("match() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -265,6 +265,12 @@ func (r *remote) TaskExit(_ context.Context, _ seccheck.FieldSet, info *pb.TaskE
 	return nil
 }
 
+// CapabilityDenied implements seccheck.Sink.
+func (r *remote) CapabilityDenied(_ context.Context, _ seccheck.FieldSet, info *pb.CapabilityDeniedInfo) error {
+	r.write(info, pb.MessageType_MESSAGE_SENTRY_CAPABILITY_DENIED)
+	return nil
+}
+
 // ContainerStart implements seccheck.Sink.
 func (r *remote) ContainerStart(_ context.Context, _ seccheck.FieldSet, info *pb.Start) error {
 	r.write(info, pb.MessageType_MESSAGE_CONTAINER_START)
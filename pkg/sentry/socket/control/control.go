@@ -17,6 +17,7 @@
 package control
 
 import (
+	"encoding/binary"
 	"math"
 	"time"
 
@@ -29,6 +30,7 @@ import (
 	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/pkg/sentry/socket"
 	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
@@ -166,23 +168,40 @@ func (c *scmCredentials) Credentials(t *kernel.Task) (kernel.ThreadID, auth.UID,
 	return pid, uid, gid
 }
 
+// credentials returns properly namespaced values for the pid, uid and gid in
+// creds, or default credentials if creds is nil.
+func credentials(t *kernel.Task, creds transport.CredentialsControlMessage) (kernel.ThreadID, auth.UID, auth.GID) {
+	switch c := creds.(type) {
+	case SCMCredentials:
+		return c.Credentials(t)
+	case *transport.HostCredentials:
+		// Host processes aren't in any of the sandbox's PID namespaces.
+		return 0, c.UID.In(t.UserNamespace()).OrOverflow(), c.GID.In(t.UserNamespace()).OrOverflow()
+	default:
+		return 0, auth.UID(auth.NobodyKUID), auth.GID(auth.NobodyKGID)
+	}
+}
+
 // PackCredentials packs the credentials in the control message (or default
 // credentials if none) into a buffer.
-func PackCredentials(t *kernel.Task, creds SCMCredentials, buf []byte, flags int) ([]byte, int) {
+func PackCredentials(t *kernel.Task, creds transport.CredentialsControlMessage, buf []byte, flags int) ([]byte, int) {
 	align := t.Arch().Width()
-
-	// Default credentials if none are available.
-	pid := kernel.ThreadID(0)
-	uid := auth.UID(auth.NobodyKUID)
-	gid := auth.GID(auth.NobodyKGID)
-
-	if creds != nil {
-		pid, uid, gid = creds.Credentials(t)
-	}
+	pid, uid, gid := credentials(t, creds)
 	c := []int32{int32(pid), int32(uid), int32(gid)}
 	return putCmsg(buf, flags, linux.SCM_CREDENTIALS, align, c)
 }
 
+// PeerCredentials returns the SO_PEERCRED value for the peer credentials creds
+// (or default credentials if none).
+func PeerCredentials(t *kernel.Task, creds transport.CredentialsControlMessage) *linux.ControlMessageCredentials {
+	pid, uid, gid := credentials(t, creds)
+	return &linux.ControlMessageCredentials{
+		PID: int32(pid),
+		UID: uint32(uid),
+		GID: uint32(gid),
+	}
+}
+
 // alignSlice extends a slice's length (up to the capacity) to align it.
 func alignSlice(buf []byte, align uint) []byte {
 	aligned := bits.AlignUp(len(buf), align)
@@ -240,6 +259,21 @@ func PackTClass(t *kernel.Task, tClass uint32, buf []byte) []byte {
 	)
 }
 
+// PackFlowInfo packs an IPV6_FLOWINFO socket control message. The flow
+// information is passed in network byte order, like the IPv6 header it comes
+// from.
+func PackFlowInfo(t *kernel.Task, flowInfo uint32, buf []byte) []byte {
+	b := make([]byte, linux.SizeOfControlMessageFlowInfo)
+	binary.BigEndian.PutUint32(b, flowInfo)
+	return putCmsgStruct(
+		buf,
+		linux.SOL_IPV6,
+		linux.IPV6_FLOWINFO,
+		t.Arch().Width(),
+		primitive.AsByteSlice(b),
+	)
+}
+
 // PackTTL packs an IP_TTL socket control message.
 func PackTTL(t *kernel.Task, ttl uint32, buf []byte) []byte {
 	return putCmsgStruct(
@@ -341,6 +375,10 @@ func PackControlMessages(t *kernel.Task, cmsgs socket.ControlMessages, buf []byt
 		buf = PackTClass(t, cmsgs.IP.TClass, buf)
 	}
 
+	if cmsgs.IP.HasFlowInfo {
+		buf = PackFlowInfo(t, cmsgs.IP.FlowInfo, buf)
+	}
+
 	if cmsgs.IP.HasHopLimit {
 		buf = PackHopLimit(t, cmsgs.IP.HopLimit, buf)
 	}
@@ -394,6 +432,10 @@ func CmsgsSpace(t *kernel.Task, cmsgs socket.ControlMessages) int {
 		space += cmsgSpace(t, linux.SizeOfControlMessageTClass)
 	}
 
+	if cmsgs.IP.HasFlowInfo {
+		space += cmsgSpace(t, linux.SizeOfControlMessageFlowInfo)
+	}
+
 	if cmsgs.IP.HasHopLimit {
 		space += cmsgSpace(t, linux.SizeOfControlMessageHopLimit)
 	}
@@ -545,11 +587,22 @@ func Parse(t *kernel.Task, socketOrEndpoint any, buf []byte, width uint) (socket
 				if length < linux.SizeOfControlMessageTClass {
 					return socket.ControlMessages{}, linuxerr.EINVAL
 				}
-				cmsgs.IP.HasTClass = true
-				var tclass primitive.Uint32
+				var tclass primitive.Int32
 				tclass.UnmarshalUnsafe(buf)
+				if tclass < -1 || tclass > math.MaxUint8 {
+					return socket.ControlMessages{}, linuxerr.EINVAL
+				}
+				// -1 selects the socket's traffic class.
+				cmsgs.IP.HasTClass = tclass != -1
 				cmsgs.IP.TClass = uint32(tclass)
 
+			case linux.IPV6_FLOWINFO:
+				if length < linux.SizeOfControlMessageFlowInfo {
+					return socket.ControlMessages{}, linuxerr.EINVAL
+				}
+				cmsgs.IP.HasFlowInfo = true
+				cmsgs.IP.FlowInfo = binary.BigEndian.Uint32(buf)
+
 			case linux.IPV6_PKTINFO:
 				if length < linux.SizeOfControlMessageIPv6PacketInfo {
 					return socket.ControlMessages{}, linuxerr.EINVAL
@@ -665,6 +718,10 @@ type RightsFiles []*vfs.FileDescription
 
 // NewSCMRights creates a new SCM_RIGHTS socket control message
 // representation using local sentry FDs.
+//
+// The files are charged to the sender's real user until they are received.
+// Like Linux, unprivileged users can't have more files in flight than their
+// RLIMIT_NOFILE.
 func NewSCMRights(t *kernel.Task, fds []primitive.Int32) (SCMRights, error) {
 	files := make(RightsFiles, 0, len(fds))
 	for _, fd := range fds {
@@ -675,7 +732,21 @@ func NewSCMRights(t *kernel.Task, fds []primitive.Int32) (SCMRights, error) {
 		}
 		files = append(files, file)
 	}
-	return &files, nil
+
+	limit := t.ThreadGroup().Limits().Get(limits.NumberOfFiles).Cur
+	if root := t.Kernel().RootUserNamespace(); t.HasCapabilityIn(linux.CAP_SYS_RESOURCE, root) || t.HasCapabilityIn(linux.CAP_SYS_ADMIN, root) {
+		limit = limits.Infinity
+	}
+	uid := t.Credentials().RealKUID
+	ok, collect := inflight.charge(uid, files, limit)
+	if !ok {
+		files.Release(t)
+		return nil, linuxerr.ETOOMANYREFS
+	}
+	if collect {
+		CollectGarbage(t)
+	}
+	return &inflightRights{RightsFiles: files, uid: uid}, nil
 }
 
 // Files implements SCMRights.Files.
@@ -709,21 +780,51 @@ func (fs *RightsFiles) Release(ctx context.Context) {
 	*fs = nil
 }
 
+// hostMemfdFile is implemented by file descriptions that are backed by a
+// host memfd.
+type hostMemfdFile interface {
+	// HostMemfd returns the host memfd backing the file description. The
+	// returned FD remains owned by the file description.
+	HostMemfd() (int, error)
+}
+
+// HostFDs implements transport.HostRightsControlMessage.HostFDs.
+func (fs *RightsFiles) HostFDs() ([]int, error) {
+	fds := make([]int, 0, len(*fs))
+	for _, f := range *fs {
+		hf, ok := f.Impl().(hostMemfdFile)
+		if !ok {
+			// Only host memfds can be passed to the host.
+			return nil, linuxerr.EINVAL
+		}
+		fd, err := hf.HostMemfd()
+		if err != nil {
+			return nil, err
+		}
+		fds = append(fds, fd)
+	}
+	return fds, nil
+}
+
 // rightsFDs gets up to the specified maximum number of FDs.
 func rightsFDs(t *kernel.Task, rights SCMRights, cloexec bool, max int) ([]int32, bool) {
 	files, trunc := rights.Files(t, max)
 	fds := make([]int32, 0, len(files))
-	for i := 0; i < max && len(files) > 0; i++ {
-		fd, err := t.NewFDFrom(0, files[0], kernel.FDFlags{
+	for i, file := range files {
+		fd, err := t.NewFDFrom(0, file, kernel.FDFlags{
 			CloseOnExec: cloexec,
 		})
-		files[0].DecRef(t)
-		files = files[1:]
 		if err != nil {
 			t.Warningf("Error inserting FD: %v", err)
-			// This is what Linux does.
+			// This is what Linux does: the remaining files are
+			// dropped and the control message is truncated, e.g. when
+			// the receiver reaches its RLIMIT_NOFILE.
+			remaining := files[i:]
+			remaining.Release(t)
+			trunc = true
 			break
 		}
+		file.DecRef(t)
 
 		fds = append(fds, int32(fd))
 	}
@@ -24,7 +24,9 @@ import (
 	"gvisor.dev/gvisor/pkg/binary"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/socket"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
 )
 
 func TestParse(t *testing.T) {
@@ -78,3 +80,33 @@ func TestParseRightsNegativeLength(t *testing.T) {
 	}
 
 }
+
+func TestInflightCharge(t *testing.T) {
+	it := inflightTable{
+		users:   make(map[auth.KUID]int64),
+		sockets: make(map[*vfs.FileDescription]int64),
+	}
+	const uid = auth.KUID(1000)
+	files := RightsFiles{&vfs.FileDescription{}, &vfs.FileDescription{}}
+
+	if ok, _ := it.charge(uid, files, 1); !ok {
+		t.Fatalf("charge(%d, %d files, 1) = false, want true", uid, len(files))
+	}
+	if got, want := it.users[uid], int64(len(files)); got != want {
+		t.Errorf("got %d files in flight, want %d", got, want)
+	}
+
+	// Linux only rejects files once more than the limit are in flight.
+	if ok, _ := it.charge(uid, files[:1], 1); ok {
+		t.Errorf("charge(%d, 1 file, 1) = true, want false", uid)
+	}
+	if ok, _ := it.charge(uid, files[:1], 2); !ok {
+		t.Errorf("charge(%d, 1 file, 2) = false, want true", uid)
+	}
+
+	it.uncharge(uid, files[:1])
+	it.uncharge(uid, files)
+	if len(it.users) != 0 {
+		t.Errorf("got users %v after uncharging all files, want none", it.users)
+	}
+}
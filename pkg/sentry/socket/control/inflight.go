@@ -0,0 +1,240 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control
+
+import (
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// inflightGCThreshold is the number of Unix sockets in flight above which
+// sending more files triggers a garbage collection. It matches Linux's
+// UNIX_INFLIGHT_TRIGGER_GC.
+const inflightGCThreshold = 16000
+
+// inflightTable accounts for files in flight, i.e. sent in SCM_RIGHTS messages
+// that have not been received yet.
+//
+// Files in flight are charged to the user that sent them, which, like in
+// Linux, can't have more files in flight than its RLIMIT_NOFILE unless it is
+// privileged. Unix sockets in flight are also tracked, so that sockets which
+// are only reachable from the receive queues of other unreachable sockets can
+// be garbage collected.
+type inflightTable struct {
+	mu sync.Mutex
+
+	// users maps users to the number of files they have in flight.
+	users map[auth.KUID]int64
+
+	// sockets maps Unix socket files to the number of times they are in
+	// flight.
+	sockets map[*vfs.FileDescription]int64
+
+	// collecting is true while a garbage collection is in progress.
+	collecting atomicbitops.Bool
+}
+
+var inflight = inflightTable{
+	users:   make(map[auth.KUID]int64),
+	sockets: make(map[*vfs.FileDescription]int64),
+}
+
+// rightsQueueOf returns the receive queue of f if f is a Unix socket.
+func rightsQueueOf(f *vfs.FileDescription) (transport.RightsQueue, bool) {
+	s, ok := f.Impl().(interface{ Endpoint() transport.Endpoint })
+	if !ok {
+		return nil, false
+	}
+	q, ok := s.Endpoint().(transport.RightsQueue)
+	return q, ok
+}
+
+// charge charges files to uid if uid has no more than limit files in flight.
+// It returns false if the files weren't charged, and whether a garbage
+// collection should be performed.
+func (it *inflightTable) charge(uid auth.KUID, files RightsFiles, limit uint64) (ok, collect bool) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	if uint64(it.users[uid]) > limit {
+		return false, false
+	}
+	it.chargeLocked(uid, files)
+	return true, len(it.sockets) > inflightGCThreshold
+}
+
+// +checklocks:it.mu
+func (it *inflightTable) chargeLocked(uid auth.KUID, files RightsFiles) {
+	if len(files) == 0 {
+		return
+	}
+	it.users[uid] += int64(len(files))
+	for _, f := range files {
+		if _, ok := rightsQueueOf(f); ok {
+			it.sockets[f]++
+		}
+	}
+}
+
+// uncharge reverts charge.
+func (it *inflightTable) uncharge(uid auth.KUID, files RightsFiles) {
+	if len(files) == 0 {
+		return
+	}
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	if it.users[uid] -= int64(len(files)); it.users[uid] <= 0 {
+		delete(it.users, uid)
+	}
+	for _, f := range files {
+		if n, ok := it.sockets[f]; ok {
+			if n <= 1 {
+				delete(it.sockets, f)
+			} else {
+				it.sockets[f] = n - 1
+			}
+		}
+	}
+}
+
+// CollectGarbage releases the messages queued on Unix sockets that can't be
+// reached anymore because all references to them come from messages queued
+// on other such sockets, e.g. a socket that was sent over itself and closed.
+//
+// This is a simplified version of Linux's unix_gc: listening sockets'
+// pending connections are not inspected, so garbage reachable only from them
+// is collected after they are accepted or closed.
+func CollectGarbage(ctx context.Context) {
+	if !inflight.collecting.CompareAndSwap(false, true) {
+		// Releasing garbage may release more sockets, which must not
+		// start a nested collection.
+		return
+	}
+	defer inflight.collecting.Store(false)
+
+	// Candidates are sockets whose references are all held by messages in
+	// flight. Hold a reference on them while they are inspected.
+	inflight.mu.Lock()
+	candidates := make(map[*vfs.FileDescription]int64)
+	for f, n := range inflight.sockets {
+		if f.ReadRefs() == n {
+			f.IncRef()
+			candidates[f] = n
+		}
+	}
+	inflight.mu.Unlock()
+	if len(candidates) == 0 {
+		return
+	}
+	defer func() {
+		for f := range candidates {
+			f.DecRef(ctx)
+		}
+	}()
+
+	// Find the candidates queued on each candidate. A candidate remains
+	// referenced from outside the candidates if not all of its in-flight
+	// references come from their queues.
+	external := make(map[*vfs.FileDescription]int64, len(candidates))
+	queued := make(map[*vfs.FileDescription][]*vfs.FileDescription, len(candidates))
+	for f, n := range candidates {
+		external[f] += n
+		q, _ := rightsQueueOf(f)
+		q.ForEachQueuedRights(func(rm transport.RightsControlMessage) {
+			files, ok := inflightFiles(rm)
+			if !ok {
+				return
+			}
+			for _, g := range files {
+				if _, ok := candidates[g]; ok {
+					external[g]--
+					queued[f] = append(queued[f], g)
+				}
+			}
+		})
+	}
+
+	// Candidates with external references are reachable, and so is
+	// everything queued on them.
+	var reachable []*vfs.FileDescription
+	for f, n := range external {
+		if n > 0 {
+			reachable = append(reachable, f)
+		}
+	}
+	for len(reachable) > 0 {
+		f := reachable[len(reachable)-1]
+		reachable = reachable[:len(reachable)-1]
+		if _, ok := external[f]; !ok {
+			continue
+		}
+		delete(external, f)
+		reachable = append(reachable, queued[f]...)
+	}
+
+	// The remaining candidates are garbage. Releasing their queues drops
+	// the references they hold on each other.
+	for f := range external {
+		q, _ := rightsQueueOf(f)
+		q.PurgeQueued(ctx)
+	}
+}
+
+// inflightFiles returns the files held by an in-flight rights message.
+func inflightFiles(rm transport.RightsControlMessage) (RightsFiles, bool) {
+	switch r := rm.(type) {
+	case *inflightRights:
+		return r.RightsFiles, true
+	case *RightsFiles:
+		return *r, true
+	default:
+		return nil, false
+	}
+}
+
+// inflightRights is a SCMRights whose files are charged to the user that sent
+// them until they are received or released.
+//
+// +stateify savable
+type inflightRights struct {
+	RightsFiles
+
+	// uid is the user the files are charged to.
+	uid auth.KUID
+}
+
+// Files implements SCMRights.Files.
+func (r *inflightRights) Files(ctx context.Context, max int) (RightsFiles, bool) {
+	rf, trunc := r.RightsFiles.Files(ctx, max)
+	inflight.uncharge(r.uid, rf)
+	return rf, trunc
+}
+
+// Release implements transport.RightsControlMessage.Release.
+func (r *inflightRights) Release(ctx context.Context) {
+	inflight.uncharge(r.uid, r.RightsFiles)
+	r.RightsFiles.Release(ctx)
+}
+
+// afterLoad is invoked by stateify.
+func (r *inflightRights) afterLoad(context.Context) {
+	// The inflight table isn't saved, rebuild it from the messages.
+	inflight.mu.Lock()
+	defer inflight.mu.Unlock()
+	inflight.chargeLocked(r.uid, r.RightsFiles)
+}
@@ -15,6 +15,7 @@
 package hostinet
 
 import (
+	"encoding/binary"
 	"fmt"
 
 	"golang.org/x/sys/unix"
@@ -637,6 +638,11 @@ func parseUnixControlMessages(unixControlMessages []unix.SocketControlMessage) s
 				tclass.UnmarshalUnsafe(unixCmsg.Data)
 				controlMessages.IP.TClass = uint32(tclass)
 
+			case linux.IPV6_FLOWINFO:
+				// The flow information is in network byte order.
+				controlMessages.IP.HasFlowInfo = true
+				controlMessages.IP.FlowInfo = binary.BigEndian.Uint32(unixCmsg.Data)
+
 			case linux.IPV6_PKTINFO:
 				controlMessages.IP.HasIPv6PacketInfo = true
 				var packetInfo linux.ControlMessageIPv6PacketInfo
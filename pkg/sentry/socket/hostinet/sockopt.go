@@ -73,6 +73,8 @@ var SockOpts = []SockOpt{
 	{linux.SOL_IP, linux.IP_TTL, sizeofInt32, true, true},
 
 	{linux.SOL_IPV6, linux.IPV6_CHECKSUM, sizeofInt32, true, true},
+	{linux.SOL_IPV6, linux.IPV6_FLOWINFO, sizeofInt32, true, true},
+	{linux.SOL_IPV6, linux.IPV6_FLOWINFO_SEND, sizeofInt32, true, true},
 	{linux.SOL_IPV6, linux.IPV6_MULTICAST_HOPS, sizeofInt32, true, true},
 	{linux.SOL_IPV6, linux.IPV6_RECVERR, sizeofInt32, true, true},
 	{linux.SOL_IPV6, linux.IPV6_RECVHOPLIMIT, sizeofInt32, true, true},
@@ -55,6 +55,7 @@ type Stack struct {
 	tcpRecvBufSize inet.TCPBufferSize
 	tcpSendBufSize inet.TCPBufferSize
 	tcpSACKEnabled bool
+	tcpECN         int32
 	netDevFile     *os.File
 	netSNMPFile    *os.File
 	// allowedSocketTypes is the list of allowed socket types
@@ -99,6 +100,16 @@ func (s *Stack) Configure(allowRawSockets bool) error {
 		log.Warningf("Failed to read if TCP SACK if enabled, setting to true")
 	}
 
+	// Linux defaults to accepting ECN when requested by the peer.
+	s.tcpECN = 2
+	if ecn, err := ioutil.ReadFile("/proc/sys/net/ipv4/tcp_ecn"); err == nil {
+		if v, err := strconv.ParseInt(strings.TrimSpace(string(ecn)), 10, 32); err == nil {
+			s.tcpECN = int32(v)
+		}
+	} else {
+		log.Warningf("Failed to read TCP ECN mode, setting to 2")
+	}
+
 	if f, err := os.Open("/proc/net/dev"); err != nil {
 		log.Warningf("Failed to open /proc/net/dev: %v", err)
 	} else {
@@ -280,6 +291,16 @@ func (*Stack) SetTCPRecovery(inet.TCPLossRecovery) error {
 	return linuxerr.EACCES
 }
 
+// TCPECN implements inet.Stack.TCPECN.
+func (s *Stack) TCPECN() (int32, error) {
+	return s.tcpECN, nil
+}
+
+// SetTCPECN implements inet.Stack.SetTCPECN.
+func (*Stack) SetTCPECN(int32) error {
+	return linuxerr.EACCES
+}
+
 // getLine reads one line from proc file, with specified prefix.
 // The last argument, withHeader, specifies if it contains line header.
 func getLine(f *os.File, prefix string, withHeader bool) string {
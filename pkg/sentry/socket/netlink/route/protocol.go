@@ -95,7 +95,7 @@ func (p *Protocol) dumpLinks(ctx context.Context, s *netlink.Socket, msg *nlmsg.
 	}
 
 	for idx, i := range stack.Interfaces() {
-		addNewLinkMessage(ms, idx, i)
+		addNewLinkMessage(ms, stack, idx, i)
 	}
 
 	return nil
@@ -152,7 +152,7 @@ func (p *Protocol) getLink(ctx context.Context, s *netlink.Socket, msg *nlmsg.Me
 			return syserr.ErrInvalidArgument
 		}
 
-		addNewLinkMessage(ms, idx, i)
+		addNewLinkMessage(ms, stack, idx, i)
 		found = true
 		break
 	}
@@ -215,7 +215,7 @@ func (p *Protocol) delLink(ctx context.Context, s *netlink.Socket, msg *nlmsg.Me
 
 // addNewLinkMessage appends RTM_NEWLINK message for the given interface into
 // the message set.
-func addNewLinkMessage(ms *nlmsg.MessageSet, idx int32, i inet.Interface) {
+func addNewLinkMessage(ms *nlmsg.MessageSet, stack inet.Stack, idx int32, i inet.Interface) {
 	m := ms.AddMessage(linux.NetlinkMessageHeader{
 		Type: linux.RTM_NEWLINK,
 	})
@@ -229,6 +229,9 @@ func addNewLinkMessage(ms *nlmsg.MessageSet, idx int32, i inet.Interface) {
 
 	m.PutAttrString(linux.IFLA_IFNAME, i.Name)
 	m.PutAttr(linux.IFLA_MTU, primitive.AllocateUint32(i.MTU))
+	if i.Master != 0 {
+		m.PutAttr(linux.IFLA_MASTER, primitive.AllocateUint32(uint32(i.Master)))
+	}
 
 	mac := make([]byte, 6)
 	brd := mac
@@ -239,9 +242,94 @@ func addNewLinkMessage(ms *nlmsg.MessageSet, idx int32, i inet.Interface) {
 	m.PutAttr(linux.IFLA_ADDRESS, primitive.AsByteSlice(mac))
 	m.PutAttr(linux.IFLA_BROADCAST, primitive.AsByteSlice(brd))
 
+	if stats, ok := linkStats(stack, i); ok {
+		m.PutAttr(linux.IFLA_STATS64, &stats)
+	}
+
 	// TODO(gvisor.dev/issue/578): There are many more attributes.
 }
 
+// linkStats returns the statistics of interface i.
+func linkStats(stack inet.Stack, i inet.Interface) (linux.RtnlLinkStats64, bool) {
+	var s inet.StatDev
+	if err := stack.Statistics(&s, i.Name); err != nil {
+		return linux.RtnlLinkStats64{}, false
+	}
+	// inet.StatDev is in the order of /proc/net/dev.
+	return linux.RtnlLinkStats64{
+		RxBytes:         s[0],
+		RxPackets:       s[1],
+		RxErrors:        s[2],
+		RxDropped:       s[3],
+		RxFIFOErrors:    s[4],
+		RxFrameErrors:   s[5],
+		RxCompressed:    s[6],
+		Multicast:       s[7],
+		TxBytes:         s[8],
+		TxPackets:       s[9],
+		TxErrors:        s[10],
+		TxDropped:       s[11],
+		TxFIFOErrors:    s[12],
+		Collisions:      s[13],
+		TxCarrierErrors: s[14],
+		TxCompressed:    s[15],
+	}, true
+}
+
+// getStats handles RTM_GETSTATS requests. If dump is true, statistics are
+// returned for all interfaces; otherwise, only for the interface with the
+// requested index.
+func (p *Protocol) getStats(ctx context.Context, s *netlink.Socket, msg *nlmsg.Message, ms *nlmsg.MessageSet, dump bool) *syserr.Error {
+	var ifsm linux.InterfaceStatsMessage
+	if _, ok := msg.GetData(&ifsm); !ok {
+		return syserr.ErrInvalidArgument
+	}
+	// Linux requires requests to select at least one type of statistics,
+	// and non-dump requests to specify an interface.
+	if ifsm.FilterMask == 0 || (!dump && ifsm.Index <= 0) {
+		return syserr.ErrInvalidArgument
+	}
+
+	if dump {
+		// We always send back an NLMSG_DONE.
+		ms.Multi = true
+	}
+
+	stack := s.Stack()
+	if stack == nil {
+		// No network devices.
+		if dump {
+			return nil
+		}
+		return syserr.ErrNoDevice
+	}
+
+	found := false
+	for idx, i := range stack.Interfaces() {
+		if !dump && idx != ifsm.Index {
+			continue
+		}
+		m := ms.AddMessage(linux.NetlinkMessageHeader{
+			Type: linux.RTM_NEWSTATS,
+		})
+		m.Put(&linux.InterfaceStatsMessage{
+			Family:     linux.AF_UNSPEC,
+			Index:      idx,
+			FilterMask: ifsm.FilterMask,
+		})
+		if ifsm.FilterMask&linux.IFLAStatsFilterBit(linux.IFLA_STATS_LINK_64) != 0 {
+			if stats, ok := linkStats(stack, i); ok {
+				m.PutAttr(linux.IFLA_STATS_LINK_64, &stats)
+			}
+		}
+		found = true
+	}
+	if !dump && !found {
+		return syserr.ErrNoDevice
+	}
+	return nil
+}
+
 // dumpAddrs handles RTM_GETADDR dump requests.
 func (p *Protocol) dumpAddrs(ctx context.Context, s *netlink.Socket, msg *nlmsg.Message, ms *nlmsg.MessageSet) *syserr.Error {
 	// RTM_GETADDR dump requests need not contain anything more than the
@@ -583,6 +671,8 @@ func (p *Protocol) ProcessMessage(ctx context.Context, s *netlink.Socket, msg *n
 			return p.dumpAddrs(ctx, s, msg, ms)
 		case linux.RTM_GETROUTE:
 			return p.dumpRoutes(ctx, s, msg, ms)
+		case linux.RTM_GETSTATS:
+			return p.getStats(ctx, s, msg, ms, true /* dump */)
 		default:
 			return syserr.ErrNotSupported
 		}
@@ -602,6 +692,8 @@ func (p *Protocol) ProcessMessage(ctx context.Context, s *netlink.Socket, msg *n
 			return p.delAddr(ctx, s, msg, ms)
 		case linux.RTM_SETLINK:
 			return nil
+		case linux.RTM_GETSTATS:
+			return p.getStats(ctx, s, msg, ms, false /* dump */)
 		default:
 			return syserr.ErrNotSupported
 		}
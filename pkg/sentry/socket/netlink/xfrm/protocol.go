@@ -0,0 +1,690 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package xfrm provides a NETLINK_XFRM socket protocol.
+//
+// NETLINK_XFRM sockets manage the IPsec security associations and policies of
+// netstack. Only the messages used by IKE daemons to manage ESP transport mode
+// associations are supported; there are no multicast notifications, so
+// XFRM_MSG_ACQUIRE and XFRM_MSG_EXPIRE are never sent.
+package xfrm
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/socket"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netlink/nlmsg"
+	"gvisor.dev/gvisor/pkg/sentry/socket/netstack"
+	"gvisor.dev/gvisor/pkg/syserr"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// Protocol implements netlink.Protocol.
+//
+// +stateify savable
+type Protocol struct{}
+
+var _ netlink.Protocol = (*Protocol)(nil)
+
+// NewProtocol creates a NETLINK_XFRM netlink.Protocol.
+func NewProtocol(t *kernel.Task) (netlink.Protocol, *syserr.Error) {
+	return &Protocol{}, nil
+}
+
+// Protocol implements netlink.Protocol.Protocol.
+func (p *Protocol) Protocol() int {
+	return linux.NETLINK_XFRM
+}
+
+// CanSend implements netlink.Protocol.CanSend.
+func (p *Protocol) CanSend() bool {
+	return true
+}
+
+// ntohl converts a 32-bit number from network byte order to host byte order.
+func ntohl(v uint32) uint32 {
+	var b [4]byte
+	hostarch.ByteOrder.PutUint32(b[:], v)
+	return binary.BigEndian.Uint32(b[:])
+}
+
+// htonl converts a 32-bit number from host byte order to network byte order.
+func htonl(v uint32) uint32 {
+	return ntohl(v)
+}
+
+// familyProtocol returns the network protocol and the address length of an
+// address family.
+func familyProtocol(family uint16) (tcpip.NetworkProtocolNumber, int, bool) {
+	switch family {
+	case linux.AF_INET:
+		return header.IPv4ProtocolNumber, header.IPv4AddressSize, true
+	case linux.AF_INET6:
+		return header.IPv6ProtocolNumber, header.IPv6AddressSize, true
+	default:
+		return 0, 0, false
+	}
+}
+
+// protocolFamily returns the address family of a network protocol.
+func protocolFamily(proto tcpip.NetworkProtocolNumber) uint16 {
+	switch proto {
+	case header.IPv4ProtocolNumber:
+		return linux.AF_INET
+	case header.IPv6ProtocolNumber:
+		return linux.AF_INET6
+	default:
+		return linux.AF_UNSPEC
+	}
+}
+
+func toAddress(b [16]byte, addrLen int) tcpip.Address {
+	return tcpip.AddrFromSlice(b[:addrLen])
+}
+
+func fromAddress(addr tcpip.Address) [16]byte {
+	var b [16]byte
+	copy(b[:], addr.AsSlice())
+	return b
+}
+
+func toSelector(sel *linux.XFRMSelector) (stack.XFRMSelector, *syserr.Error) {
+	s := stack.XFRMSelector{
+		SrcPort:     socket.Ntohs(sel.Sport),
+		SrcPortMask: socket.Ntohs(sel.SportMask),
+		DstPort:     socket.Ntohs(sel.Dport),
+		DstPortMask: socket.Ntohs(sel.DportMask),
+		Protocol:    tcpip.TransportProtocolNumber(sel.Proto),
+	}
+	if sel.Family == linux.AF_UNSPEC {
+		if sel.PrefixlenS != 0 || sel.PrefixlenD != 0 {
+			return stack.XFRMSelector{}, syserr.ErrInvalidArgument
+		}
+		return s, nil
+	}
+	netProto, addrLen, ok := familyProtocol(sel.Family)
+	if !ok || int(sel.PrefixlenS) > addrLen*8 || int(sel.PrefixlenD) > addrLen*8 {
+		return stack.XFRMSelector{}, syserr.ErrInvalidArgument
+	}
+	s.NetProto = netProto
+	s.Src = tcpip.AddressWithPrefix{Address: toAddress(sel.Saddr, addrLen), PrefixLen: int(sel.PrefixlenS)}
+	s.Dst = tcpip.AddressWithPrefix{Address: toAddress(sel.Daddr, addrLen), PrefixLen: int(sel.PrefixlenD)}
+	return s, nil
+}
+
+func fromSelector(s *stack.XFRMSelector) linux.XFRMSelector {
+	return linux.XFRMSelector{
+		Daddr:      fromAddress(s.Dst.Address),
+		Saddr:      fromAddress(s.Src.Address),
+		Dport:      socket.Htons(s.DstPort),
+		DportMask:  socket.Htons(s.DstPortMask),
+		Sport:      socket.Htons(s.SrcPort),
+		SportMask:  socket.Htons(s.SrcPortMask),
+		Family:     protocolFamily(s.NetProto),
+		PrefixlenD: uint8(s.Dst.PrefixLen),
+		PrefixlenS: uint8(s.Src.PrefixLen),
+		Proto:      uint8(s.Protocol),
+	}
+}
+
+// algorithmName converts a NUL-terminated algorithm name.
+func algorithmName(name [64]byte) string {
+	if i := bytes.IndexByte(name[:], 0); i >= 0 {
+		return string(name[:i])
+	}
+	return string(name[:])
+}
+
+// algorithmKey extracts the key of keyBits bits that follows an algorithm
+// header.
+func algorithmKey(v nlmsg.BytesView, keyBits uint32) ([]byte, bool) {
+	key, ok := v.Extract(int((keyBits + 7) / 8))
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), key...), true
+}
+
+// parseAlgorithms fills the algorithms of st from the attributes of an
+// XFRM_MSG_NEWSA or XFRM_MSG_UPDSA message.
+func parseAlgorithms(st *stack.XFRMState, attrs map[uint16]nlmsg.BytesView) *syserr.Error {
+	if v, ok := attrs[linux.XFRMA_ALG_AEAD]; ok {
+		var hdr linux.XFRMAlgoAEAD
+		b, ok := v.Extract(hdr.SizeBytes())
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		hdr.UnmarshalUnsafe(b)
+		key, ok := algorithmKey(v, hdr.KeyLen)
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		st.AEAD = &stack.XFRMAlgorithm{Name: algorithmName(hdr.Name), Key: key, ICVBits: int(hdr.ICVLen)}
+	}
+	if v, ok := attrs[linux.XFRMA_ALG_CRYPT]; ok {
+		var hdr linux.XFRMAlgo
+		b, ok := v.Extract(hdr.SizeBytes())
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		hdr.UnmarshalUnsafe(b)
+		key, ok := algorithmKey(v, hdr.KeyLen)
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		st.Encryption = &stack.XFRMAlgorithm{Name: algorithmName(hdr.Name), Key: key}
+	}
+	// Like Linux, prefer XFRMA_ALG_AUTH_TRUNC, which carries an explicit
+	// truncation length, over XFRMA_ALG_AUTH.
+	if v, ok := attrs[linux.XFRMA_ALG_AUTH_TRUNC]; ok {
+		var hdr linux.XFRMAlgoAuth
+		b, ok := v.Extract(hdr.SizeBytes())
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		hdr.UnmarshalUnsafe(b)
+		key, ok := algorithmKey(v, hdr.KeyLen)
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		st.Auth = &stack.XFRMAlgorithm{Name: algorithmName(hdr.Name), Key: key, ICVBits: int(hdr.TruncLen)}
+	} else if v, ok := attrs[linux.XFRMA_ALG_AUTH]; ok {
+		var hdr linux.XFRMAlgo
+		b, ok := v.Extract(hdr.SizeBytes())
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		hdr.UnmarshalUnsafe(b)
+		key, ok := algorithmKey(v, hdr.KeyLen)
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		st.Auth = &stack.XFRMAlgorithm{Name: algorithmName(hdr.Name), Key: key}
+	}
+	return nil
+}
+
+// toState converts the SA description shared by XFRM_MSG_NEWSA and
+// XFRM_MSG_ALLOCSPI.
+func toState(info *linux.XFRMUserSAInfo) (stack.XFRMState, *syserr.Error) {
+	netProto, addrLen, ok := familyProtocol(info.Family)
+	if !ok {
+		return stack.XFRMState{}, syserr.ErrInvalidArgument
+	}
+	if info.ID.Proto != linux.IPPROTO_ESP || info.Mode != linux.XFRM_MODE_TRANSPORT {
+		return stack.XFRMState{}, syserr.ErrNotSupported
+	}
+	sel, err := toSelector(&info.Sel)
+	if err != nil {
+		return stack.XFRMState{}, err
+	}
+	return stack.XFRMState{
+		Dst:          toAddress(info.ID.Daddr, addrLen),
+		SPI:          ntohl(info.ID.SPI),
+		Protocol:     tcpip.TransportProtocolNumber(info.ID.Proto),
+		Src:          toAddress(info.Saddr, addrLen),
+		NetProto:     netProto,
+		Mode:         stack.XFRMModeTransport,
+		ReqID:        info.ReqID,
+		ReplayWindow: uint32(info.ReplayWindow),
+		Selector:     sel,
+	}, nil
+}
+
+// putAlgorithm adds an algorithm attribute made of hdr followed by key.
+func putAlgorithm(m *nlmsg.Message, atype uint16, hdr marshal.Marshallable, key []byte) {
+	b := append(marshal.Marshal(hdr), key...)
+	m.PutAttr(atype, primitive.AsByteSlice(b))
+}
+
+func algorithmHeaderName(name string) [64]byte {
+	var b [64]byte
+	copy(b[:], name)
+	return b
+}
+
+// addStateMessage adds an XFRM_MSG_NEWSA message describing st to ms.
+func addStateMessage(ms *nlmsg.MessageSet, st *stack.XFRMState) {
+	m := ms.AddMessage(linux.NetlinkMessageHeader{
+		Type: linux.XFRM_MSG_NEWSA,
+	})
+	replayWindow := st.ReplayWindow
+	if replayWindow > 255 {
+		replayWindow = 255
+	}
+	m.Put(&linux.XFRMUserSAInfo{
+		Sel: fromSelector(&st.Selector),
+		ID: linux.XFRMID{
+			Daddr: fromAddress(st.Dst),
+			SPI:   htonl(st.SPI),
+			Proto: uint8(st.Protocol),
+		},
+		Saddr: fromAddress(st.Src),
+		Lft: linux.XFRMLifetimeCfg{
+			SoftByteLimit:   linux.XFRM_INF,
+			HardByteLimit:   linux.XFRM_INF,
+			SoftPacketLimit: linux.XFRM_INF,
+			HardPacketLimit: linux.XFRM_INF,
+		},
+		Curlft: linux.XFRMLifetimeCur{
+			Bytes:   st.Stats.Bytes,
+			Packets: st.Stats.Packets,
+		},
+		Stats: linux.XFRMStats{
+			ReplayWindow:    st.Stats.ReplayWindow,
+			Replay:          st.Stats.Replay,
+			IntegrityFailed: st.Stats.IntegrityFailed,
+		},
+		ReqID:        st.ReqID,
+		Family:       protocolFamily(st.NetProto),
+		Mode:         uint8(st.Mode),
+		ReplayWindow: uint8(replayWindow),
+	})
+	if a := st.AEAD; a != nil {
+		putAlgorithm(m, linux.XFRMA_ALG_AEAD, &linux.XFRMAlgoAEAD{
+			Name:   algorithmHeaderName(a.Name),
+			KeyLen: uint32(len(a.Key) * 8),
+			ICVLen: uint32(a.ICVBits),
+		}, a.Key)
+	}
+	if a := st.Encryption; a != nil {
+		putAlgorithm(m, linux.XFRMA_ALG_CRYPT, &linux.XFRMAlgo{
+			Name:   algorithmHeaderName(a.Name),
+			KeyLen: uint32(len(a.Key) * 8),
+		}, a.Key)
+	}
+	if a := st.Auth; a != nil {
+		putAlgorithm(m, linux.XFRMA_ALG_AUTH_TRUNC, &linux.XFRMAlgoAuth{
+			Name:     algorithmHeaderName(a.Name),
+			KeyLen:   uint32(len(a.Key) * 8),
+			TruncLen: uint32(a.ICVBits),
+		}, a.Key)
+	}
+}
+
+// translateStateError translates errors of the state operations. Like Linux,
+// missing states are reported with ESRCH.
+func translateStateError(err tcpip.Error) *syserr.Error {
+	if _, ok := err.(*tcpip.ErrNoSuchFile); ok {
+		return syserr.ErrNoProcess
+	}
+	return syserr.TranslateNetstackError(err)
+}
+
+// newSA handles XFRM_MSG_NEWSA and XFRM_MSG_UPDSA requests.
+func (p *Protocol) newSA(s *stack.Stack, msg *nlmsg.Message, update bool) *syserr.Error {
+	var info linux.XFRMUserSAInfo
+	attrsView, ok := msg.GetData(&info)
+	if !ok {
+		return syserr.ErrInvalidArgument
+	}
+	attrs, ok := attrsView.Parse()
+	if !ok {
+		return syserr.ErrInvalidArgument
+	}
+	st, err := toState(&info)
+	if err != nil {
+		return err
+	}
+	if info.Flags&linux.XFRM_STATE_ESN != 0 {
+		// Extended sequence numbers are not supported.
+		return syserr.ErrNotSupported
+	}
+	if _, ok := attrs[linux.XFRMA_ENCAP]; ok {
+		// UDP encapsulation for NAT traversal is not supported.
+		return syserr.ErrNotSupported
+	}
+	if v, ok := attrs[linux.XFRMA_REPLAY_ESN_VAL]; ok {
+		// Without XFRM_STATE_ESN, the attribute is only used to request
+		// replay windows larger than 255 packets.
+		var esn linux.XFRMReplayStateESN
+		b, ok := v.Extract(esn.SizeBytes())
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		esn.UnmarshalUnsafe(b)
+		st.ReplayWindow = esn.ReplayWindow
+	}
+	if err := parseAlgorithms(&st, attrs); err != nil {
+		return err
+	}
+	if err := s.AddXFRMState(st, update); err != nil {
+		return translateStateError(err)
+	}
+	return nil
+}
+
+// allocSPI handles XFRM_MSG_ALLOCSPI requests.
+func (p *Protocol) allocSPI(s *stack.Stack, msg *nlmsg.Message, ms *nlmsg.MessageSet) *syserr.Error {
+	var info linux.XFRMUserSPIInfo
+	if _, ok := msg.GetData(&info); !ok {
+		return syserr.ErrInvalidArgument
+	}
+	st, err := toState(&info.Info)
+	if err != nil {
+		return err
+	}
+	spi, tcpipErr := s.AllocXFRMSPI(st, info.Min, info.Max)
+	if tcpipErr != nil {
+		return syserr.TranslateNetstackError(tcpipErr)
+	}
+	larval, tcpipErr := s.GetXFRMState(st.Dst, spi, st.Protocol)
+	if tcpipErr != nil {
+		return translateStateError(tcpipErr)
+	}
+	addStateMessage(ms, &larval)
+	return nil
+}
+
+// parseSAID parses the struct xfrm_usersa_id of XFRM_MSG_DELSA and
+// XFRM_MSG_GETSA requests.
+func parseSAID(msg *nlmsg.Message) (tcpip.Address, uint32, tcpip.TransportProtocolNumber, *syserr.Error) {
+	var id linux.XFRMUserSAID
+	if _, ok := msg.GetData(&id); !ok {
+		return tcpip.Address{}, 0, 0, syserr.ErrInvalidArgument
+	}
+	_, addrLen, ok := familyProtocol(id.Family)
+	if !ok {
+		return tcpip.Address{}, 0, 0, syserr.ErrInvalidArgument
+	}
+	return toAddress(id.Daddr, addrLen), ntohl(id.SPI), tcpip.TransportProtocolNumber(id.Proto), nil
+}
+
+// delSA handles XFRM_MSG_DELSA requests.
+func (p *Protocol) delSA(s *stack.Stack, msg *nlmsg.Message) *syserr.Error {
+	dst, spi, proto, err := parseSAID(msg)
+	if err != nil {
+		return err
+	}
+	if err := s.DeleteXFRMState(dst, spi, proto); err != nil {
+		return translateStateError(err)
+	}
+	return nil
+}
+
+// getSA handles XFRM_MSG_GETSA requests.
+func (p *Protocol) getSA(s *stack.Stack, msg *nlmsg.Message, ms *nlmsg.MessageSet) *syserr.Error {
+	dst, spi, proto, err := parseSAID(msg)
+	if err != nil {
+		return err
+	}
+	st, tcpipErr := s.GetXFRMState(dst, spi, proto)
+	if tcpipErr != nil {
+		return translateStateError(tcpipErr)
+	}
+	addStateMessage(ms, &st)
+	return nil
+}
+
+// dumpSAs handles XFRM_MSG_GETSA dump requests.
+func (p *Protocol) dumpSAs(s *stack.Stack, ms *nlmsg.MessageSet) *syserr.Error {
+	ms.Multi = true
+	for _, st := range s.XFRMStates() {
+		addStateMessage(ms, &st)
+	}
+	return nil
+}
+
+// flushSAs handles XFRM_MSG_FLUSHSA requests.
+func (p *Protocol) flushSAs(s *stack.Stack, msg *nlmsg.Message) *syserr.Error {
+	var flush linux.XFRMUserSAFlush
+	if _, ok := msg.GetData(&flush); !ok {
+		return syserr.ErrInvalidArgument
+	}
+	proto := tcpip.TransportProtocolNumber(flush.Proto)
+	if flush.Proto == linux.IPSEC_PROTO_ANY {
+		proto = 0
+	}
+	s.FlushXFRMStates(proto)
+	return nil
+}
+
+// newPolicy handles XFRM_MSG_NEWPOLICY and XFRM_MSG_UPDPOLICY requests.
+func (p *Protocol) newPolicy(s *stack.Stack, msg *nlmsg.Message, update bool) *syserr.Error {
+	var info linux.XFRMUserPolicyInfo
+	attrsView, ok := msg.GetData(&info)
+	if !ok {
+		return syserr.ErrInvalidArgument
+	}
+	attrs, ok := attrsView.Parse()
+	if !ok {
+		return syserr.ErrInvalidArgument
+	}
+	sel, err := toSelector(&info.Sel)
+	if err != nil {
+		return err
+	}
+	if info.Dir > linux.XFRM_POLICY_FWD || info.Action > linux.XFRM_POLICY_BLOCK {
+		return syserr.ErrInvalidArgument
+	}
+	policy := stack.XFRMPolicy{
+		Selector: sel,
+		Dir:      stack.XFRMDirection(info.Dir),
+		Action:   stack.XFRMAction(info.Action),
+		Priority: info.Priority,
+	}
+	if v, ok := attrs[linux.XFRMA_TMPL]; ok {
+		for len(v) > 0 {
+			var tmpl linux.XFRMUserTmpl
+			b, ok := v.Extract(tmpl.SizeBytes())
+			if !ok {
+				return syserr.ErrInvalidArgument
+			}
+			tmpl.UnmarshalUnsafe(b)
+			family := tmpl.Family
+			if family == linux.AF_UNSPEC {
+				family = info.Sel.Family
+			}
+			_, addrLen, ok := familyProtocol(family)
+			if !ok {
+				return syserr.ErrInvalidArgument
+			}
+			if tmpl.Mode != linux.XFRM_MODE_TRANSPORT {
+				return syserr.ErrNotSupported
+			}
+			t := stack.XFRMTemplate{
+				SPI:      ntohl(tmpl.ID.SPI),
+				Protocol: tcpip.TransportProtocolNumber(tmpl.ID.Proto),
+				Mode:     stack.XFRMModeTransport,
+				ReqID:    tmpl.ReqID,
+				Optional: tmpl.Optional != 0,
+			}
+			// Zero addresses let the stack use the addresses of the packet.
+			if tmpl.ID.Daddr != ([16]byte{}) {
+				t.Dst = toAddress(tmpl.ID.Daddr, addrLen)
+			}
+			if tmpl.Saddr != ([16]byte{}) {
+				t.Src = toAddress(tmpl.Saddr, addrLen)
+			}
+			policy.Templates = append(policy.Templates, t)
+		}
+	}
+	if _, err := s.AddXFRMPolicy(policy, update); err != nil {
+		return syserr.TranslateNetstackError(err)
+	}
+	return nil
+}
+
+// addPolicyMessage adds an XFRM_MSG_NEWPOLICY message describing policy to
+// ms.
+func addPolicyMessage(ms *nlmsg.MessageSet, policy *stack.XFRMPolicy) {
+	m := ms.AddMessage(linux.NetlinkMessageHeader{
+		Type: linux.XFRM_MSG_NEWPOLICY,
+	})
+	m.Put(&linux.XFRMUserPolicyInfo{
+		Sel: fromSelector(&policy.Selector),
+		Lft: linux.XFRMLifetimeCfg{
+			SoftByteLimit:   linux.XFRM_INF,
+			HardByteLimit:   linux.XFRM_INF,
+			SoftPacketLimit: linux.XFRM_INF,
+			HardPacketLimit: linux.XFRM_INF,
+		},
+		Priority: policy.Priority,
+		Index:    policy.Index,
+		Dir:      uint8(policy.Dir),
+		Action:   uint8(policy.Action),
+	})
+	if len(policy.Templates) == 0 {
+		return
+	}
+	var b []byte
+	for _, t := range policy.Templates {
+		tmpl := linux.XFRMUserTmpl{
+			ID: linux.XFRMID{
+				Daddr: fromAddress(t.Dst),
+				SPI:   htonl(t.SPI),
+				Proto: uint8(t.Protocol),
+			},
+			Family: protocolFamily(policy.Selector.NetProto),
+			Saddr:  fromAddress(t.Src),
+			ReqID:  t.ReqID,
+			Mode:   uint8(t.Mode),
+			// Like Linux, allow any algorithm by default.
+			Aalgos: ^uint32(0),
+			Ealgos: ^uint32(0),
+			Calgos: ^uint32(0),
+		}
+		if t.Optional {
+			tmpl.Optional = 1
+		}
+		b = append(b, marshal.Marshal(&tmpl)...)
+	}
+	m.PutAttr(linux.XFRMA_TMPL, primitive.AsByteSlice(b))
+}
+
+// parsePolicyID parses the struct xfrm_userpolicy_id of XFRM_MSG_DELPOLICY
+// and XFRM_MSG_GETPOLICY requests.
+func parsePolicyID(msg *nlmsg.Message) (stack.XFRMDirection, stack.XFRMSelector, uint32, *syserr.Error) {
+	var id linux.XFRMUserPolicyID
+	if _, ok := msg.GetData(&id); !ok {
+		return 0, stack.XFRMSelector{}, 0, syserr.ErrInvalidArgument
+	}
+	if id.Dir > linux.XFRM_POLICY_FWD {
+		return 0, stack.XFRMSelector{}, 0, syserr.ErrInvalidArgument
+	}
+	sel, err := toSelector(&id.Sel)
+	if err != nil {
+		return 0, stack.XFRMSelector{}, 0, err
+	}
+	return stack.XFRMDirection(id.Dir), sel, id.Index, nil
+}
+
+// delPolicy handles XFRM_MSG_DELPOLICY requests.
+func (p *Protocol) delPolicy(s *stack.Stack, msg *nlmsg.Message) *syserr.Error {
+	dir, sel, index, err := parsePolicyID(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := s.DeleteXFRMPolicy(dir, sel, index); err != nil {
+		return syserr.TranslateNetstackError(err)
+	}
+	return nil
+}
+
+// getPolicy handles XFRM_MSG_GETPOLICY requests.
+func (p *Protocol) getPolicy(s *stack.Stack, msg *nlmsg.Message, ms *nlmsg.MessageSet) *syserr.Error {
+	dir, sel, index, err := parsePolicyID(msg)
+	if err != nil {
+		return err
+	}
+	policy, tcpipErr := s.GetXFRMPolicy(dir, sel, index)
+	if tcpipErr != nil {
+		return syserr.TranslateNetstackError(tcpipErr)
+	}
+	addPolicyMessage(ms, &policy)
+	return nil
+}
+
+// dumpPolicies handles XFRM_MSG_GETPOLICY dump requests.
+func (p *Protocol) dumpPolicies(s *stack.Stack, ms *nlmsg.MessageSet) *syserr.Error {
+	ms.Multi = true
+	for _, policy := range s.XFRMPolicies() {
+		addPolicyMessage(ms, &policy)
+	}
+	return nil
+}
+
+// ProcessMessage implements netlink.Protocol.ProcessMessage.
+func (p *Protocol) ProcessMessage(ctx context.Context, s *netlink.Socket, msg *nlmsg.Message, ms *nlmsg.MessageSet) *syserr.Error {
+	// All XFRM messages require CAP_NET_ADMIN, including the dumps, which
+	// expose keys.
+	creds := auth.CredentialsFromContext(ctx)
+	if !creds.HasCapability(linux.CAP_NET_ADMIN) {
+		return syserr.ErrPermissionDenied
+	}
+
+	// IPsec is only implemented by netstack.
+	ns, ok := s.Stack().(*netstack.Stack)
+	if !ok {
+		return syserr.ErrProtocolNotSupported
+	}
+	st := ns.Stack
+
+	hdr := msg.Header()
+	if hdr.Flags&linux.NLM_F_DUMP == linux.NLM_F_DUMP {
+		switch hdr.Type {
+		case linux.XFRM_MSG_GETSA:
+			return p.dumpSAs(st, ms)
+		case linux.XFRM_MSG_GETPOLICY:
+			return p.dumpPolicies(st, ms)
+		default:
+			return syserr.ErrNotSupported
+		}
+	} else if hdr.Flags&linux.NLM_F_REQUEST == linux.NLM_F_REQUEST {
+		switch hdr.Type {
+		case linux.XFRM_MSG_NEWSA:
+			return p.newSA(st, msg, false /* update */)
+		case linux.XFRM_MSG_UPDSA:
+			return p.newSA(st, msg, true /* update */)
+		case linux.XFRM_MSG_DELSA:
+			return p.delSA(st, msg)
+		case linux.XFRM_MSG_GETSA:
+			return p.getSA(st, msg, ms)
+		case linux.XFRM_MSG_ALLOCSPI:
+			return p.allocSPI(st, msg, ms)
+		case linux.XFRM_MSG_FLUSHSA:
+			return p.flushSAs(st, msg)
+		case linux.XFRM_MSG_NEWPOLICY:
+			return p.newPolicy(st, msg, false /* update */)
+		case linux.XFRM_MSG_UPDPOLICY:
+			return p.newPolicy(st, msg, true /* update */)
+		case linux.XFRM_MSG_DELPOLICY:
+			return p.delPolicy(st, msg)
+		case linux.XFRM_MSG_GETPOLICY:
+			return p.getPolicy(st, msg, ms)
+		case linux.XFRM_MSG_FLUSHPOLICY:
+			st.FlushXFRMPolicies()
+			return nil
+		default:
+			return syserr.ErrNotSupported
+		}
+	}
+	return syserr.ErrNotSupported
+}
+
+// init registers the NETLINK_XFRM provider.
+func init() {
+	netlink.RegisterProvider(linux.NETLINK_XFRM, NewProtocol)
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netstack
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/inet"
+	"gvisor.dev/gvisor/pkg/syserr"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+const (
+	// ethtoolDriver is the driver reported by ETHTOOL_GDRVINFO for
+	// non-loopback interfaces. Sandbox interfaces are typically backed by a
+	// veth pair on the host, and tools expect a known driver name.
+	ethtoolDriver = "veth"
+
+	// ethtoolDriverVersion is the driver version reported by
+	// ETHTOOL_GDRVINFO.
+	ethtoolDriverVersion = "1.0"
+
+	// ethtoolRingSize is the RX and TX ring size reported by
+	// ETHTOOL_GRINGPARAM, matching veth.
+	ethtoolRingSize = 256
+)
+
+// ethtoolIoctl implements the SIOCETHTOOL ioctl for iface. The ethtool
+// command and its arguments are in user memory pointed to by ifr_data.
+//
+// As on Linux, the loopback interface only supports querying link status and
+// features.
+//
+// See net/ethtool/ioctl.c:dev_ethtool.
+func ethtoolIoctl(ctx context.Context, io usermem.IO, iface *inet.Interface, ifr *linux.IFReq) *syserr.Error {
+	cc := &usermem.IOCopyContext{
+		Ctx: ctx,
+		IO:  io,
+		Opts: usermem.IOOpts{
+			AddressSpaceActive: true,
+		},
+	}
+	ifrData := hostarch.Addr(hostarch.ByteOrder.Uint64(ifr.Data[:8]))
+	var cmd linux.EthtoolCmd
+	if _, err := cmd.CopyIn(cc, ifrData); err != nil {
+		return syserr.FromError(err)
+	}
+
+	loopback := iface.DeviceType == linux.ARPHRD_LOOPBACK
+	switch cmd {
+	case linux.ETHTOOL_GLINK:
+		v := linux.EthtoolValue{Cmd: uint32(cmd)}
+		if iface.Flags&linux.IFF_RUNNING != 0 {
+			v.Data = 1
+		}
+		_, err := v.CopyOut(cc, ifrData)
+		return syserr.FromError(err)
+
+	case linux.ETHTOOL_GFEATURES:
+		return ethtoolGFeatures(cc, iface, ifrData)
+
+	case linux.ETHTOOL_GDRVINFO:
+		if loopback {
+			return syserr.ErrNotSupported
+		}
+		info := linux.EthtoolDrvinfo{Cmd: uint32(cmd)}
+		copy(info.Driver[:], ethtoolDriver)
+		copy(info.Version[:], ethtoolDriverVersion)
+		_, err := info.CopyOut(cc, ifrData)
+		return syserr.FromError(err)
+
+	case linux.ETHTOOL_GSET:
+		if loopback {
+			return syserr.ErrNotSupported
+		}
+		// Report the fixed settings of a virtual device, like veth.
+		settings := linux.EthtoolSettings{
+			Cmd:         uint32(cmd),
+			Speed:       linux.SPEED_10000,
+			Duplex:      linux.DUPLEX_FULL,
+			Port:        linux.PORT_TP,
+			Transceiver: linux.XCVR_INTERNAL,
+			Autoneg:     linux.AUTONEG_DISABLE,
+		}
+		_, err := settings.CopyOut(cc, ifrData)
+		return syserr.FromError(err)
+
+	case linux.ETHTOOL_GRINGPARAM:
+		if loopback {
+			return syserr.ErrNotSupported
+		}
+		ring := linux.EthtoolRingparam{
+			Cmd:          uint32(cmd),
+			RxMaxPending: ethtoolRingSize,
+			TxMaxPending: ethtoolRingSize,
+			RxPending:    ethtoolRingSize,
+			TxPending:    ethtoolRingSize,
+		}
+		_, err := ring.CopyOut(cc, ifrData)
+		return syserr.FromError(err)
+
+	default:
+		// This includes ETHTOOL_GLINKSETTINGS, for which the ethtool
+		// utility falls back to ETHTOOL_GSET when it is not supported.
+		return syserr.ErrNotSupported
+	}
+}
+
+// ethtoolGFeatures implements ETHTOOL_GFEATURES. The request holds the number
+// of feature blocks the caller has room for; the response holds the number of
+// blocks available, followed by as many blocks as fit.
+func ethtoolGFeatures(cc *usermem.IOCopyContext, iface *inet.Interface, ifrData hostarch.Addr) *syserr.Error {
+	var gfeatures linux.EthtoolGFeatures
+	if _, err := gfeatures.CopyIn(cc, ifrData); err != nil {
+		return syserr.FromError(err)
+	}
+	n := min(int(gfeatures.Size), len(iface.Features))
+	gfeatures.Size = uint32(len(iface.Features))
+	if _, err := gfeatures.CopyOut(cc, ifrData); err != nil {
+		return syserr.FromError(err)
+	}
+	addr := ifrData + hostarch.Addr(gfeatures.SizeBytes())
+	for i := 0; i < n; i++ {
+		if _, err := iface.Features[i].CopyOut(cc, addr); err != nil {
+			return syserr.FromError(err)
+		}
+		addr += hostarch.Addr(iface.Features[i].SizeBytes())
+	}
+	return nil
+}
@@ -31,6 +31,7 @@ import (
 	"io"
 	"io/ioutil"
 	"math"
+	"math/bits"
 	"reflect"
 	"time"
 
@@ -291,6 +292,13 @@ var Metrics = tcpip.Stats{
 		SpuriousRecovery:                   mustCreateMetric("/netstack/tcp/spurious_recovery", "Number of times the connection entered loss recovery spuriously."),
 		SpuriousRTORecovery:                mustCreateMetric("/netstack/tcp/spurious_rto_recovery", "Number of times the connection entered RTO spuriously."),
 		ForwardMaxInFlightDrop:             mustCreateMetric("/netstack/tcp/forward_max_in_flight_drop", "Number of connection requests dropped due to exceeding in-flight limit."),
+		MD5NotFound:                        mustCreateMetric("/netstack/tcp/md5_not_found", "Number of segments dropped because they lacked an expected TCP MD5 signature."),
+		MD5Unexpected:                      mustCreateMetric("/netstack/tcp/md5_unexpected", "Number of segments dropped because they carried an unexpected TCP MD5 signature."),
+		MD5Failure:                         mustCreateMetric("/netstack/tcp/md5_failure", "Number of segments dropped because their TCP MD5 signature was invalid."),
+		AOGood:                             mustCreateMetric("/netstack/tcp/ao_good", "Number of segments with a valid TCP-AO MAC."),
+		AOBad:                              mustCreateMetric("/netstack/tcp/ao_bad", "Number of segments dropped because their TCP-AO MAC was invalid."),
+		AOKeyNotFound:                      mustCreateMetric("/netstack/tcp/ao_key_not_found", "Number of segments dropped because no TCP-AO key matched them."),
+		AORequired:                         mustCreateMetric("/netstack/tcp/ao_required", "Number of segments dropped because they lacked TCP-AO."),
 	},
 	UDP: tcpip.UDPStats{
 		PacketsReceived:          mustCreateMetric("/netstack/udp/packets_received", "Number of UDP datagrams received via HandlePacket."),
@@ -301,6 +309,15 @@ var Metrics = tcpip.Stats{
 		PacketSendErrors:         mustCreateMetric("/netstack/udp/packet_send_errors", "Number of UDP datagrams failed to be sent."),
 		ChecksumErrors:           mustCreateMetric("/netstack/udp/checksum_errors", "Number of UDP datagrams dropped due to bad checksums."),
 	},
+	UDPLite: tcpip.UDPStats{
+		PacketsReceived:          mustCreateMetric("/netstack/udplite/packets_received", "Number of UDP-Lite datagrams received via HandlePacket."),
+		UnknownPortErrors:        mustCreateMetric("/netstack/udplite/unknown_port_errors", "Number of incoming UDP-Lite datagrams dropped because they did not have a known destination port."),
+		ReceiveBufferErrors:      mustCreateMetric("/netstack/udplite/receive_buffer_errors", "Number of incoming UDP-Lite datagrams dropped due to the receiving buffer being in an invalid state."),
+		MalformedPacketsReceived: mustCreateMetric("/netstack/udplite/malformed_packets_received", "Number of incoming UDP-Lite datagrams dropped due to the UDP-Lite header being in a malformed state."),
+		PacketsSent:              mustCreateMetric("/netstack/udplite/packets_sent", "Number of UDP-Lite datagrams sent."),
+		PacketSendErrors:         mustCreateMetric("/netstack/udplite/packet_send_errors", "Number of UDP-Lite datagrams failed to be sent."),
+		ChecksumErrors:           mustCreateMetric("/netstack/udplite/checksum_errors", "Number of UDP-Lite datagrams dropped due to bad checksums or unacceptable checksum coverage."),
+	},
 }
 
 // DefaultTTL is linux's default TTL. All network protocols in all stacks used
@@ -512,6 +529,137 @@ func (s *sock) Write(ctx context.Context, src usermem.IOSequence, opts vfs.Write
 	return n, nil
 }
 
+// SpliceFromFile implements socket.FileSplicer.SpliceFromFile.
+//
+// Data is read from file directly into the send buffer of TCP endpoints.
+func (s *sock) SpliceFromFile(ctx context.Context, file *vfs.FileDescription, offset, count int64) (int64, bool, error) {
+	if _, ok := s.Endpoint.(*tcp.Endpoint); !ok {
+		return 0, false, nil
+	}
+
+	// Limit count to the file's current size, so that the endpoint doesn't
+	// wait for data past the end of the file. This is checked on each call,
+	// so data appended to the file while the caller waits for the endpoint
+	// to become writable is still sent.
+	stat, err := file.Stat(ctx, vfs.StatOptions{Mask: linux.STATX_SIZE})
+	if err != nil {
+		return 0, true, err
+	}
+	pos := offset
+	if offset == -1 {
+		if pos, err = file.Seek(ctx, 0, linux.SEEK_CUR); err != nil {
+			return 0, true, err
+		}
+	}
+	atEOF := false
+	if remaining := int64(stat.Size) - pos; remaining <= count {
+		count = remaining
+		atEOF = true
+	}
+	if count <= 0 {
+		return 0, true, io.EOF
+	}
+
+	r := fileReader{
+		ctx:  ctx,
+		file: file,
+		off:  offset,
+		len:  count,
+	}
+	n, tcpErr := s.Endpoint.Write(&r, tcpip.WriteOptions{})
+	if offset == -1 && r.read > n {
+		// The endpoint discarded data after reading it from file, which
+		// advanced the file offset. Rewind it relative to its current
+		// value, so that concurrent reads and seeks aren't undone.
+		if _, err := file.Seek(ctx, n-r.read, linux.SEEK_CUR); err != nil {
+			log.Warningf("failed to roll back input file offset: %v", err)
+		}
+	}
+	if r.err == io.ErrUnexpectedEOF {
+		return n, true, io.EOF
+	}
+	if r.err != nil {
+		// Errors from file take precedence, since they cause the endpoint to
+		// discard data that has been read.
+		return n, true, r.err
+	}
+	if _, ok := tcpErr.(*tcpip.ErrWouldBlock); ok {
+		return n, true, linuxerr.ErrWouldBlock
+	}
+	if tcpErr != nil {
+		return n, true, syserr.TranslateNetstackError(tcpErr).ToError()
+	}
+	if n < count {
+		return n, true, linuxerr.ErrWouldBlock
+	}
+	if atEOF {
+		return n, true, io.EOF
+	}
+	return n, true, nil
+}
+
+// fileReader implements tcpip.Payloader by reading from a file.
+type fileReader struct {
+	ctx  context.Context
+	file *vfs.FileDescription
+
+	// off is the offset to read file at, or -1 to read at and advance the
+	// file offset.
+	off int64
+
+	// len is the number of bytes left to read.
+	len int64
+
+	// read is the number of bytes read.
+	read int64
+
+	// err is the error that stopped reading, if any.
+	err error
+}
+
+// Read implements io.Reader.Read.
+func (r *fileReader) Read(dst []byte) (int, error) {
+	if r.len == 0 {
+		return 0, io.EOF
+	}
+	if int64(len(dst)) > r.len {
+		dst = dst[:r.len]
+	}
+	if len(dst) == 0 {
+		return 0, nil
+	}
+	var (
+		n   int64
+		err error
+	)
+	if r.off == -1 {
+		// Read holds the file offset lock while reading and advancing the
+		// offset, which races neither with concurrent reads nor seeks.
+		n, err = r.file.Read(r.ctx, usermem.BytesIOSequence(dst), vfs.ReadOptions{})
+	} else {
+		n, err = r.file.PRead(r.ctx, usermem.BytesIOSequence(dst), r.off, vfs.ReadOptions{})
+		r.off += n
+	}
+	r.len -= n
+	r.read += n
+	if n == 0 {
+		if err == nil || err == io.EOF {
+			// The file was truncated. Since Len() promised more data than is
+			// available, an error must be returned to avoid being called
+			// again indefinitely.
+			err = io.ErrUnexpectedEOF
+		}
+		r.err = err
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// Len implements tcpip.Payloader.Len.
+func (r *fileReader) Len() int {
+	return int(r.len)
+}
+
 // Accept implements the linux syscall accept(2) for sockets backed by
 // tcpip.Endpoint.
 func (s *sock) Accept(t *kernel.Task, peerRequested bool, flags int, blocking bool) (int32, linux.SockAddr, uint32, *syserr.Error) {
@@ -898,6 +1046,9 @@ func GetSockOpt(t *kernel.Task, s socket.Socket, ep commonEndpoint, family int,
 	case linux.SOL_ICMPV6:
 		return getSockOptICMPv6(t, s, ep, name, outLen)
 
+	case linux.SOL_UDPLITE:
+		return getSockOptUDPLite(t, s, ep, name, outLen)
+
 	case linux.SOL_UDP,
 		linux.SOL_RAW,
 		linux.SOL_PACKET:
@@ -934,17 +1085,9 @@ func getSockOptSocket(t *kernel.Task, s socket.Socket, ep commonEndpoint, family
 		return &optP, nil
 
 	case linux.SO_PEERCRED:
-		if family != linux.AF_UNIX || outLen < unix.SizeofUcred {
-			return nil, syserr.ErrInvalidArgument
-		}
-
-		tcred := t.Credentials()
-		creds := linux.ControlMessageCredentials{
-			PID: int32(t.ThreadGroup().ID()),
-			UID: uint32(tcred.EffectiveKUID.In(tcred.UserNamespace).OrOverflow()),
-			GID: uint32(tcred.EffectiveKGID.In(tcred.UserNamespace).OrOverflow()),
-		}
-		return &creds, nil
+		// Unix sockets handle SO_PEERCRED themselves, since it reports
+		// the credentials of the connected peer.
+		return nil, syserr.ErrInvalidArgument
 
 	case linux.SO_PASSCRED:
 		if outLen < sizeOfInt32 {
@@ -1237,6 +1380,28 @@ func getSockOptTCP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name, out
 		bufP := primitive.ByteSlice(buf)
 		return &bufP, nil
 
+	case linux.TCP_AO_INFO:
+		var info linux.TCPAOInfoOpt
+		if outLen < info.SizeBytes() {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		var v tcpip.TCPAOInfoOption
+		if err := ep.GetSockOpt(&v); err != nil {
+			return nil, syserr.TranslateNetstackError(err)
+		}
+		info.Bits = linux.TCP_AO_SET_CURRENT | linux.TCP_AO_SET_RNEXT
+		if v.Required {
+			info.Bits |= linux.TCP_AO_REQUIRED
+		}
+		info.CurrentKey = v.CurrentKey
+		info.RNext = v.RNext
+		info.PktGood = v.PacketsGood
+		info.PktBad = v.PacketsBad
+		info.PktKeyNotFound = v.KeyNotFound
+		info.PktAORequired = v.AORequired
+		return &info, nil
+
 	case linux.TCP_CC_INFO,
 		linux.TCP_NOTSENT_LOWAT,
 		linux.TCP_ZEROCOPY_RECEIVE:
@@ -1357,6 +1522,35 @@ func getSockOptICMPv6(t *kernel.Task, s socket.Socket, ep commonEndpoint, name i
 	return nil, syserr.ErrProtocolNotAvailable
 }
 
+// getSockOptUDPLite implements GetSockOpt when level is SOL_UDPLITE.
+func getSockOptUDPLite(t *kernel.Task, s socket.Socket, ep commonEndpoint, name, outLen int) (marshal.Marshallable, *syserr.Error) {
+	if _, ok := ep.(tcpip.Endpoint); !ok {
+		log.Warningf("SOL_UDPLITE options not supported on endpoints other than tcpip.Endpoint: option = %d", name)
+		return nil, syserr.ErrUnknownProtocolOption
+	}
+
+	var opt tcpip.SockOptInt
+	switch name {
+	case linux.UDPLITE_SEND_CSCOV:
+		opt = tcpip.UDPLiteSendCoverageOption
+	case linux.UDPLITE_RECV_CSCOV:
+		opt = tcpip.UDPLiteReceiveCoverageOption
+	default:
+		return nil, syserr.ErrProtocolNotAvailable
+	}
+
+	if outLen < sizeOfInt32 {
+		return nil, syserr.ErrInvalidArgument
+	}
+
+	v, err := ep.GetSockOptInt(opt)
+	if err != nil {
+		return nil, syserr.TranslateNetstackError(err)
+	}
+	vP := primitive.Int32(v)
+	return &vP, nil
+}
+
 func defaultTTL(t *kernel.Task, network tcpip.NetworkProtocolNumber) (primitive.Int32, tcpip.Error) {
 	var opt tcpip.DefaultTTLOption
 	stack := inet.StackFromContext(t)
@@ -1461,6 +1655,22 @@ func getSockOptIPv6(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int
 
 		v := primitive.Int32(boolToInt32(ep.SocketOptions().GetReceiveTClass()))
 		return &v, nil
+
+	case linux.IPV6_FLOWINFO:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v := primitive.Int32(boolToInt32(ep.SocketOptions().GetReceiveFlowInfo()))
+		return &v, nil
+
+	case linux.IPV6_FLOWINFO_SEND:
+		if outLen < sizeOfInt32 {
+			return nil, syserr.ErrInvalidArgument
+		}
+
+		v := primitive.Int32(boolToInt32(ep.SocketOptions().GetSendFlowInfo()))
+		return &v, nil
 	case linux.IPV6_RECVERR:
 		if outLen < sizeOfInt32 {
 			return nil, syserr.ErrInvalidArgument
@@ -1803,6 +2013,9 @@ func SetSockOpt(t *kernel.Task, s socket.Socket, ep commonEndpoint, level int, n
 	case linux.SOL_ICMPV6:
 		return setSockOptICMPv6(t, s, ep, name, optVal)
 
+	case linux.SOL_UDPLITE:
+		return setSockOptUDPLite(t, s, ep, name, optVal)
+
 	case linux.SOL_IPV6:
 		return setSockOptIPv6(t, s, ep, name, optVal)
 
@@ -2158,6 +2371,103 @@ func setSockOptTCP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int,
 
 		return syserr.TranslateNetstackError(ep.SetSockOptInt(tcpip.TCPWindowClampOption, int(v)))
 
+	case linux.TCP_MD5SIG, linux.TCP_MD5SIG_EXT:
+		var sig linux.TCPMD5Sig
+		if len(optVal) < sig.SizeBytes() {
+			return syserr.ErrInvalidArgument
+		}
+		sig.UnmarshalUnsafe(optVal)
+		if int(sig.KeyLen) > linux.TCP_MD5SIG_MAXKEYLEN {
+			return syserr.ErrInvalidArgument
+		}
+		prefixLen := -1
+		if name == linux.TCP_MD5SIG_EXT && sig.Flags&linux.TCP_MD5SIG_FLAG_PREFIX != 0 {
+			prefixLen = int(sig.PrefixLen)
+		}
+		addr, prefixLen, err := tcpAuthAddress(s, sig.Addr[:], prefixLen)
+		if err != nil {
+			return err
+		}
+		opt := tcpip.TCPMD5SigOption{
+			Addr:      addr,
+			PrefixLen: prefixLen,
+			Key:       sig.Key[:sig.KeyLen],
+		}
+		return syserr.TranslateNetstackError(ep.SetSockOpt(&opt))
+
+	case linux.TCP_AO_ADD_KEY:
+		var add linux.TCPAOAdd
+		if len(optVal) < add.SizeBytes() {
+			return syserr.ErrInvalidArgument
+		}
+		add.UnmarshalUnsafe(optVal)
+		if int(add.KeyLen) > linux.TCP_AO_MAXKEYLEN || add.KeyFlags&^linux.TCP_AO_KEYF_EXCLUDE_OPT != 0 {
+			return syserr.ErrInvalidArgument
+		}
+		addr, prefixLen, err := tcpAuthAddress(s, add.Addr[:], int(add.Prefix))
+		if err != nil {
+			return err
+		}
+		alg := add.AlgName[:]
+		if i := bytes.IndexByte(alg, 0); i >= 0 {
+			alg = alg[:i]
+		}
+		opt := tcpip.TCPAOAddKeyOption{
+			Addr:           addr,
+			PrefixLen:      prefixLen,
+			Algorithm:      string(alg),
+			SendID:         add.SndID,
+			RecvID:         add.RcvID,
+			MACLen:         add.MACLen,
+			ExcludeOptions: add.KeyFlags&linux.TCP_AO_KEYF_EXCLUDE_OPT != 0,
+			Key:            add.Key[:add.KeyLen],
+			SetCurrent:     add.Bits&linux.TCP_AO_SET_CURRENT != 0,
+			SetRNext:       add.Bits&linux.TCP_AO_SET_RNEXT != 0,
+		}
+		return syserr.TranslateNetstackError(ep.SetSockOpt(&opt))
+
+	case linux.TCP_AO_DEL_KEY:
+		var del linux.TCPAODel
+		if len(optVal) < del.SizeBytes() {
+			return syserr.ErrInvalidArgument
+		}
+		del.UnmarshalUnsafe(optVal)
+		addr, prefixLen, err := tcpAuthAddress(s, del.Addr[:], int(del.Prefix))
+		if err != nil {
+			return err
+		}
+		opt := tcpip.TCPAODelKeyOption{
+			Addr:       addr,
+			PrefixLen:  prefixLen,
+			SendID:     del.SndID,
+			RecvID:     del.RcvID,
+			SetCurrent: del.Bits&linux.TCP_AO_SET_CURRENT != 0,
+			CurrentKey: del.CurrentKey,
+			SetRNext:   del.Bits&linux.TCP_AO_SET_RNEXT != 0,
+			RNext:      del.RNext,
+		}
+		return syserr.TranslateNetstackError(ep.SetSockOpt(&opt))
+
+	case linux.TCP_AO_INFO:
+		var info linux.TCPAOInfoOpt
+		if len(optVal) < info.SizeBytes() {
+			return syserr.ErrInvalidArgument
+		}
+		info.UnmarshalUnsafe(optVal)
+		opt := tcpip.TCPAOInfoOption{
+			SetCurrent:  info.Bits&linux.TCP_AO_SET_CURRENT != 0,
+			CurrentKey:  info.CurrentKey,
+			SetRNext:    info.Bits&linux.TCP_AO_SET_RNEXT != 0,
+			RNext:       info.RNext,
+			Required:    info.Bits&linux.TCP_AO_REQUIRED != 0,
+			SetCounters: info.Bits&linux.TCP_AO_SET_COUNTERS != 0,
+			PacketsGood: info.PktGood,
+			PacketsBad:  info.PktBad,
+			KeyNotFound: info.PktKeyNotFound,
+			AORequired:  info.PktAORequired,
+		}
+		return syserr.TranslateNetstackError(ep.SetSockOpt(&opt))
+
 	case linux.TCP_REPAIR_OPTIONS:
 		// Not supported.
 	}
@@ -2165,6 +2475,32 @@ func setSockOptTCP(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int,
 	return nil
 }
 
+// tcpAuthAddress parses the peer address of a TCP MD5 or TCP-AO key. A
+// negative prefixLen selects the full length of the address. IPv4-mapped IPv6
+// addresses are converted to IPv4 addresses, with prefixLen applying to the
+// IPv4 address as in Linux.
+func tcpAuthAddress(s socket.Socket, sockAddr []byte, prefixLen int) (tcpip.Address, int, *syserr.Error) {
+	addr, family, err := socket.AddressAndFamily(sockAddr)
+	if err != nil {
+		return tcpip.Address{}, 0, err
+	}
+	if sockFamily, _, _ := s.Type(); int(family) != sockFamily {
+		return tcpip.Address{}, 0, syserr.ErrInvalidArgument
+	}
+	a := addr.Addr
+	if family == linux.AF_INET6 && header.IsV4MappedAddress(a) {
+		v6 := a.As16()
+		a = tcpip.AddrFrom4Slice(v6[12:])
+	}
+	if prefixLen < 0 {
+		prefixLen = a.BitLen()
+	}
+	if prefixLen > a.BitLen() {
+		return tcpip.Address{}, 0, syserr.ErrInvalidArgument
+	}
+	return a, prefixLen, nil
+}
+
 func setSockOptICMPv6(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int, optVal []byte) *syserr.Error {
 	if _, ok := ep.(tcpip.Endpoint); !ok {
 		log.Warningf("SOL_ICMPV6 options not supported on endpoints other than tcpip.Endpoint: option = %d", name)
@@ -2189,6 +2525,31 @@ func setSockOptICMPv6(t *kernel.Task, s socket.Socket, ep commonEndpoint, name i
 	return nil
 }
 
+// setSockOptUDPLite implements SetSockOpt when level is SOL_UDPLITE.
+func setSockOptUDPLite(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int, optVal []byte) *syserr.Error {
+	if _, ok := ep.(tcpip.Endpoint); !ok {
+		log.Warningf("SOL_UDPLITE options not supported on endpoints other than tcpip.Endpoint: option = %d", name)
+		return syserr.ErrUnknownProtocolOption
+	}
+
+	var opt tcpip.SockOptInt
+	switch name {
+	case linux.UDPLITE_SEND_CSCOV:
+		opt = tcpip.UDPLiteSendCoverageOption
+	case linux.UDPLITE_RECV_CSCOV:
+		opt = tcpip.UDPLiteReceiveCoverageOption
+	default:
+		return syserr.ErrProtocolNotAvailable
+	}
+
+	if len(optVal) < sizeOfInt32 {
+		return syserr.ErrInvalidArgument
+	}
+
+	v := int32(hostarch.ByteOrder.Uint32(optVal))
+	return syserr.TranslateNetstackError(ep.SetSockOptInt(opt, int(v)))
+}
+
 // setSockOptIPv6 implements SetSockOpt when level is SOL_IPV6.
 func setSockOptIPv6(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int, optVal []byte) *syserr.Error {
 	if _, ok := ep.(tcpip.Endpoint); !ok {
@@ -2321,6 +2682,24 @@ func setSockOptIPv6(t *kernel.Task, s socket.Socket, ep commonEndpoint, name int
 
 		ep.SocketOptions().SetReceiveTClass(v != 0)
 		return nil
+
+	case linux.IPV6_FLOWINFO:
+		v, err := parseIntOrChar(optVal)
+		if err != nil {
+			return err
+		}
+
+		ep.SocketOptions().SetReceiveFlowInfo(v != 0)
+		return nil
+
+	case linux.IPV6_FLOWINFO_SEND:
+		v, err := parseIntOrChar(optVal)
+		if err != nil {
+			return err
+		}
+
+		ep.SocketOptions().SetSendFlowInfo(v != 0)
+		return nil
 	case linux.IPV6_RECVERR:
 		if len(optVal) == 0 {
 			return nil
@@ -2822,6 +3201,8 @@ func (s *sock) netstackToLinuxControlMessages(cm tcpip.ReceivableControlMessages
 			TOS:                readCM.TOS,
 			HasTClass:          readCM.HasTClass,
 			TClass:             readCM.TClass,
+			HasFlowInfo:        readCM.HasFlowInfo,
+			FlowInfo:           readCM.FlowInfo,
 			HasTTL:             readCM.HasTTL,
 			TTL:                readCM.TTL,
 			HasHopLimit:        readCM.HasHopLimit,
@@ -2838,11 +3219,32 @@ func (s *sock) netstackToLinuxControlMessages(cm tcpip.ReceivableControlMessages
 
 func (s *sock) linuxToNetstackControlMessages(cm socket.ControlMessages) tcpip.SendableControlMessages {
 	return tcpip.SendableControlMessages{
-		HasTTL:      cm.IP.HasTTL,
-		TTL:         uint8(cm.IP.TTL),
-		HasHopLimit: cm.IP.HasHopLimit,
-		HopLimit:    uint8(cm.IP.HopLimit),
+		HasTTL:       cm.IP.HasTTL,
+		TTL:          uint8(cm.IP.TTL),
+		HasHopLimit:  cm.IP.HasHopLimit,
+		HopLimit:     uint8(cm.IP.HopLimit),
+		HasTOS:       cm.IP.HasTOS,
+		TOS:          cm.IP.TOS,
+		HasTClass:    cm.IP.HasTClass,
+		TClass:       uint8(cm.IP.TClass),
+		HasFlowLabel: cm.IP.HasFlowInfo,
+		FlowLabel:    cm.IP.FlowInfo & 0xfffff,
+	}
+}
+
+// maybeSetFlowLabel sets the flow label of outgoing packets to the one of the
+// IPv6 destination address to if IPV6_FLOWINFO_SEND is enabled, unless a
+// control message already set it.
+func (s *sock) maybeSetFlowLabel(to []byte, cm *tcpip.SendableControlMessages) {
+	if cm.HasFlowLabel || s.family != linux.AF_INET6 || !s.Endpoint.SocketOptions().GetSendFlowInfo() {
+		return
+	}
+	if len(to) < (*linux.SockAddrInet6)(nil).SizeBytes() || hostarch.ByteOrder.Uint16(to) != linux.AF_INET6 {
+		return
 	}
+	// sin6_flowinfo is in network byte order.
+	cm.HasFlowLabel = true
+	cm.FlowLabel = binary.BigEndian.Uint32(to[4:8]) & 0xfffff
 }
 
 // updateTimestamp sets the timestamp for SIOCGSTAMP. It should be called after
@@ -2993,18 +3395,9 @@ func (s *sock) SendMsg(t *kernel.Task, src usermem.IOSequence, to []byte, flags
 		return 0, syserr.ErrInvalidArgument
 	}
 
-	var addr *tcpip.FullAddress
-	if len(to) > 0 {
-		addrBuf, family, err := socket.AddressAndFamily(to)
-		if err != nil {
-			return 0, err
-		}
-		if !s.checkFamily(family, false /* exact */) {
-			return 0, syserr.ErrInvalidArgument
-		}
-		addrBuf = s.mapFamily(addrBuf, family)
-
-		addr = &addrBuf
+	addr, err := s.sendAddress(to)
+	if err != nil {
+		return 0, err
 	}
 
 	opts := tcpip.WriteOptions{
@@ -3013,7 +3406,15 @@ func (s *sock) SendMsg(t *kernel.Task, src usermem.IOSequence, to []byte, flags
 		EndOfRecord:     flags&linux.MSG_EOR != 0,
 		ControlMessages: s.linuxToNetstackControlMessages(controlMessages),
 	}
-
+	s.maybeSetFlowLabel(to, &opts.ControlMessages)
+
+	if src.Addrs.NumRanges() > 1 {
+		// Avoid translating each iovec again every time the endpoint
+		// consumes part of src.
+		var unpin func()
+		src, unpin = t.MemoryManager().PinIOSequence(t, src, hostarch.Read)
+		defer unpin()
+	}
 	r := src.Reader(t)
 	var (
 		total int64
@@ -3059,6 +3460,130 @@ func (s *sock) SendMsg(t *kernel.Task, src usermem.IOSequence, to []byte, flags
 	}
 }
 
+// sendAddress parses the destination address passed to sendmsg(2), which is
+// nil if to is empty.
+func (s *sock) sendAddress(to []byte) (*tcpip.FullAddress, *syserr.Error) {
+	if len(to) == 0 {
+		return nil, nil
+	}
+	addr, family, err := socket.AddressAndFamily(to)
+	if err != nil {
+		return nil, err
+	}
+	if !s.checkFamily(family, false /* exact */) {
+		return nil, syserr.ErrInvalidArgument
+	}
+	addr = s.mapFamily(addr, family)
+	return &addr, nil
+}
+
+var _ socket.BatchMessenger = (*sock)(nil)
+
+// RecvMsgBatch implements socket.BatchMessenger.RecvMsgBatch.
+func (s *sock) RecvMsgBatch(t *kernel.Task, dsts []usermem.IOSequence, flags int, haveDeadline bool, deadline ktime.Time, senderRequested bool) ([]socket.BatchMessage, bool, *syserr.Error) {
+	ep, ok := s.Endpoint.(tcpip.EndpointWithBatchRead)
+	if !ok || flags&(linux.MSG_ERRQUEUE|linux.MSG_PEEK) != 0 {
+		return nil, false, nil
+	}
+	for _, dst := range dsts {
+		if dst.NumBytes() == 0 {
+			return nil, false, nil
+		}
+	}
+
+	// Translate all the destination buffers at once rather than each time a
+	// datagram is copied out.
+	dsts, unpin := t.MemoryManager().PinIOSequences(t, dsts, hostarch.Write)
+	defer unpin()
+
+	trunc := flags&linux.MSG_TRUNC != 0
+	msgs, err := s.nonBlockingReadBatch(t, ep, dsts, trunc, senderRequested)
+	if err == syserr.ErrClosedForReceive && flags&linux.MSG_DONTWAIT != 0 {
+		// In this situation we should return EAGAIN.
+		return nil, true, syserr.ErrTryAgain
+	}
+	if err != syserr.ErrWouldBlock || flags&linux.MSG_DONTWAIT != 0 {
+		return msgs, true, err
+	}
+
+	// We'll have to block. Register for notifications and keep trying to
+	// receive the batch.
+	e, ch := waiter.NewChannelEntry(waiter.ReadableEvents)
+	s.EventRegister(&e)
+	defer s.EventUnregister(&e)
+
+	for {
+		msgs, err = s.nonBlockingReadBatch(t, ep, dsts, trunc, senderRequested)
+		if err != syserr.ErrWouldBlock {
+			return msgs, true, err
+		}
+		if err := t.BlockWithDeadline(ch, haveDeadline, deadline); err != nil {
+			if linuxerr.Equals(linuxerr.ETIMEDOUT, err) {
+				return nil, true, syserr.ErrTryAgain
+			}
+			return nil, true, syserr.FromError(err)
+		}
+	}
+}
+
+// nonBlockingReadBatch issues a non-blocking batch read.
+func (s *sock) nonBlockingReadBatch(ctx context.Context, ep tcpip.EndpointWithBatchRead, dsts []usermem.IOSequence, trunc, senderRequested bool) ([]socket.BatchMessage, *syserr.Error) {
+	ws := make([]io.Writer, len(dsts))
+	for i, dst := range dsts {
+		ws[i] = dst.Writer(ctx)
+	}
+
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+
+	res, err := ep.ReadBatch(ws, tcpip.ReadOptions{NeedRemoteAddr: senderRequested})
+	if err != nil {
+		return nil, syserr.TranslateNetstackError(err)
+	}
+	msgs := make([]socket.BatchMessage, len(res))
+	for i, r := range res {
+		s.updateTimestamp(r.ControlMessages)
+		m := &msgs[i]
+		m.N = r.Count
+		if trunc {
+			m.N = r.Total
+		}
+		if r.Total > r.Count {
+			m.MsgFlags |= linux.MSG_TRUNC
+		}
+		if senderRequested {
+			m.SenderAddr, m.SenderAddrLen = socket.ConvertAddress(s.family, r.RemoteAddr)
+		}
+	}
+	return msgs, nil
+}
+
+// SendMsgBatch implements socket.BatchMessenger.SendMsgBatch.
+func (s *sock) SendMsgBatch(t *kernel.Task, srcs []usermem.IOSequence, to []byte, flags int) (int, bool, *syserr.Error) {
+	ep, ok := s.Endpoint.(tcpip.EndpointWithBatchWrite)
+	if !ok {
+		return 0, false, nil
+	}
+	addr, err := s.sendAddress(to)
+	if err != nil {
+		return 0, true, err
+	}
+
+	srcs, unpin := t.MemoryManager().PinIOSequences(t, srcs, hostarch.Read)
+	defer unpin()
+	ps := make([]tcpip.Payloader, len(srcs))
+	for i, src := range srcs {
+		ps[i] = src.Reader(t)
+	}
+	opts := tcpip.WriteOptions{
+		To:   addr,
+		More: flags&linux.MSG_MORE != 0,
+	}
+	s.maybeSetFlowLabel(to, &opts.ControlMessages)
+	n, terr := ep.WriteBatch(ps, opts)
+	return n, true, syserr.TranslateNetstackError(terr)
+}
+
 // Ioctl implements vfs.FileDescriptionImpl.
 func (s *sock) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
 	t := kernel.TaskFromContext(ctx)
@@ -3132,6 +3657,21 @@ func Ioctl(ctx context.Context, ep commonEndpoint, io usermem.IO, sysno uintptr,
 		_, err := ifr.CopyOut(t, args[2].Pointer())
 		return 0, err
 
+	case linux.SIOCSIFFLAGS,
+		linux.SIOCSIFADDR,
+		linux.SIOCSIFBRDADDR,
+		linux.SIOCSIFNETMASK,
+		linux.SIOCSIFMTU:
+
+		var ifr linux.IFReq
+		if _, err := ifr.CopyIn(t, args[2].Pointer()); err != nil {
+			return 0, err
+		}
+		if err := setInterfaceIoctl(ctx, arg, &ifr); err != nil {
+			return 0, err.ToError()
+		}
+		return 0, nil
+
 	case linux.SIOCGIFCONF:
 		// Return a list of interface addresses or the buffer size
 		// necessary to hold the list.
@@ -3184,7 +3724,7 @@ func Ioctl(ctx context.Context, ep commonEndpoint, io usermem.IO, sysno uintptr,
 }
 
 // interfaceIoctl implements interface requests.
-func interfaceIoctl(ctx context.Context, _ usermem.IO, arg int, ifr *linux.IFReq) *syserr.Error {
+func interfaceIoctl(ctx context.Context, io usermem.IO, arg int, ifr *linux.IFReq) *syserr.Error {
 	var (
 		iface inet.Interface
 		index int32
@@ -3306,12 +3846,7 @@ func interfaceIoctl(ctx context.Context, _ usermem.IO, arg int, ifr *linux.IFReq
 		}
 
 	case linux.SIOCETHTOOL:
-		// Stubbed out for now, Ideally we should implement the required
-		// sub-commands for ETHTOOL
-		//
-		// See:
-		// https://github.com/torvalds/linux/blob/aa0c9086b40c17a7ad94425b3b70dd1fdd7497bf/net/core/dev_ioctl.c
-		return syserr.ErrEndpointOperation
+		return ethtoolIoctl(ctx, io, &iface, ifr)
 
 	default:
 		// Not a valid call.
@@ -3321,6 +3856,158 @@ func interfaceIoctl(ctx context.Context, _ usermem.IO, arg int, ifr *linux.IFReq
 	return nil
 }
 
+// setInterfaceIoctl implements interface configuration requests.
+func setInterfaceIoctl(ctx context.Context, arg int, ifr *linux.IFReq) *syserr.Error {
+	stk := inet.StackFromContext(ctx)
+	if stk == nil {
+		return syserr.ErrNoDevice
+	}
+
+	// Linux requires CAP_NET_ADMIN for all of these requests, but fails
+	// address changes with EACCES rather than EPERM.
+	if creds := auth.CredentialsFromContext(ctx); !creds.HasCapability(linux.CAP_NET_ADMIN) {
+		switch arg {
+		case linux.SIOCSIFADDR, linux.SIOCSIFBRDADDR, linux.SIOCSIFNETMASK:
+			return syserr.ErrPermissionDenied
+		default:
+			return syserr.ErrNotPermitted
+		}
+	}
+
+	var (
+		index int32
+		found bool
+	)
+	for idx, iface := range stk.Interfaces() {
+		if iface.Name == ifr.Name() {
+			index = idx
+			found = true
+			break
+		}
+	}
+	if !found {
+		return syserr.ErrNoDevice
+	}
+
+	switch arg {
+	case linux.SIOCSIFFLAGS, linux.SIOCSIFMTU:
+		// We should only ever be passed a netstack.Stack.
+		epstack, ok := stk.(*Stack)
+		if !ok {
+			return errStackType
+		}
+		if arg == linux.SIOCSIFFLAGS {
+			// ifr_flags is a short.
+			return epstack.setInterfaceFlags(index, uint32(hostarch.ByteOrder.Uint16(ifr.Data[:2])))
+		}
+		mtu := int32(hostarch.ByteOrder.Uint32(ifr.Data[:4]))
+		if mtu < 0 {
+			return syserr.ErrInvalidArgument
+		}
+		return epstack.setInterfaceMTU(index, uint32(mtu))
+	}
+
+	// The remaining requests take a struct sockaddr_in and, like
+	// SIOCGIFADDR, operate on the first IPv4 address of the interface.
+	if family := hostarch.ByteOrder.Uint16(ifr.Data[0:2]); family != linux.AF_INET {
+		return syserr.ErrInvalidArgument
+	}
+	addr := make([]byte, header.IPv4AddressSize)
+	copy(addr, ifr.Data[4:8])
+
+	var (
+		cur    inet.InterfaceAddr
+		hasCur bool
+	)
+	for _, a := range stk.InterfaceAddrs()[index] {
+		if a.Family == linux.AF_INET {
+			cur = a
+			hasCur = true
+			break
+		}
+	}
+
+	switch arg {
+	case linux.SIOCSIFADDR:
+		if hasCur && bytes.Equal(cur.Addr, addr) {
+			return nil
+		}
+		prefixLen, ok := classfulPrefixLen(addr)
+		if !ok {
+			return syserr.ErrInvalidArgument
+		}
+		if hasCur {
+			if err := stk.RemoveInterfaceAddr(index, cur); err != nil {
+				return syserr.FromError(err)
+			}
+		}
+		// Setting the unspecified address only removes the current one.
+		if bytes.Equal(addr, header.IPv4Any.AsSlice()) {
+			return nil
+		}
+		return syserr.FromError(stk.AddInterfaceAddr(index, inet.InterfaceAddr{
+			Family:    linux.AF_INET,
+			PrefixLen: prefixLen,
+			Addr:      addr,
+		}))
+
+	case linux.SIOCSIFNETMASK:
+		if !hasCur {
+			return syserr.ErrAddressNotAvailable
+		}
+		mask := binary.BigEndian.Uint32(addr)
+		if ^mask&(^mask+1) != 0 {
+			// The mask isn't contiguous.
+			return syserr.ErrInvalidArgument
+		}
+		prefixLen := uint8(bits.OnesCount32(mask))
+		if prefixLen == cur.PrefixLen {
+			return nil
+		}
+		if err := stk.RemoveInterfaceAddr(index, cur); err != nil {
+			return syserr.FromError(err)
+		}
+		cur.PrefixLen = prefixLen
+		return syserr.FromError(stk.AddInterfaceAddr(index, cur))
+
+	case linux.SIOCSIFBRDADDR:
+		if !hasCur {
+			return syserr.ErrAddressNotAvailable
+		}
+		// Netstack always uses the subnet-directed broadcast address, so
+		// there is nothing to configure.
+		return nil
+	}
+
+	return syserr.ErrInvalidArgument
+}
+
+// classfulPrefixLen returns the prefix length that Linux assigns to an IPv4
+// address configured with SIOCSIFADDR, which is derived from the address
+// class. It returns false for multicast addresses. See
+// net/ipv4/devinet.c:inet_abc_len.
+func classfulPrefixLen(addr []byte) (uint8, bool) {
+	switch {
+	case addr[0] == 0 || bytes.Equal(addr, header.IPv4Broadcast.AsSlice()):
+		return 0, true
+	case addr[0]&0x80 == 0:
+		// Class A.
+		return 8, true
+	case addr[0]&0xc0 == 0x80:
+		// Class B.
+		return 16, true
+	case addr[0]&0xe0 == 0xc0:
+		// Class C.
+		return 24, true
+	case addr[0]&0xf0 == 0xf0:
+		// Class E.
+		return 32, true
+	default:
+		// Class D (multicast).
+		return 0, false
+	}
+}
+
 // ifconfIoctl populates a struct ifconf for the SIOCGIFCONF ioctl.
 func ifconfIoctl(ctx context.Context, t *kernel.Task, _ usermem.IO, ifc *linux.IFConf) error {
 	// If Ptr is NULL, return the necessary buffer size via Len.
@@ -59,6 +59,8 @@ func getTransportProtocol(ctx context.Context, stype linux.SockType, protocol in
 		switch protocol {
 		case 0, unix.IPPROTO_UDP:
 			return udp.ProtocolNumber, true, nil
+		case unix.IPPROTO_UDPLITE:
+			return udp.LiteProtocolNumber, true, nil
 		case unix.IPPROTO_ICMP:
 			return header.ICMPv4ProtocolNumber, true, nil
 		case unix.IPPROTO_ICMPV6:
@@ -76,12 +76,17 @@ func toLinuxARPHardwareType(t header.ARPHardwareType) uint16 {
 func (s *Stack) Interfaces() map[int32]inet.Interface {
 	is := make(map[int32]inet.Interface)
 	for id, ni := range s.Stack.NICInfo() {
+		flags := nicStateFlagsToLinux(ni.Flags)
+		if ni.VRF {
+			flags |= linux.IFF_MASTER | linux.IFF_NOARP
+		}
 		is[int32(id)] = inet.Interface{
 			Name:       ni.Name,
 			Addr:       []byte(ni.LinkAddress),
-			Flags:      uint32(nicStateFlagsToLinux(ni.Flags)),
+			Flags:      flags,
 			DeviceType: toLinuxARPHardwareType(ni.ARPHardwareType),
 			MTU:        ni.MTU,
+			Master:     int32(ni.Master),
 		}
 	}
 	return is
@@ -116,6 +121,7 @@ func (s *Stack) SetInterface(ctx context.Context, msg *nlmsg.Message) *syserr.Er
 		return syserr.ErrInvalidArgument
 	}
 	ifname := ""
+	master, hasMaster := uint32(0), false
 	for attr := range attrs {
 		value := attrs[attr]
 		switch attr {
@@ -135,6 +141,11 @@ func (s *Stack) SetInterface(ctx context.Context, msg *nlmsg.Message) *syserr.Er
 				}
 			}
 		case linux.IFLA_MASTER:
+			master, ok = value.Uint32()
+			if !ok {
+				return syserr.ErrInvalidArgument
+			}
+			hasMaster = true
 		case linux.IFLA_LINKINFO:
 		default:
 			ctx.Warningf("unexpected attribute: %x", attr)
@@ -153,6 +164,12 @@ func (s *Stack) SetInterface(ctx context.Context, msg *nlmsg.Message) *syserr.Er
 		return syserr.ErrExists
 	}
 
+	if hasMaster {
+		if err := s.Stack.SetNICMaster(tcpip.NICID(ifinfomsg.Index), tcpip.NICID(master)); err != nil {
+			return syserr.TranslateNetstackError(err)
+		}
+	}
+
 	if ifinfomsg.Flags != 0 || ifinfomsg.Change != 0 {
 		if ifinfomsg.Change & ^uint32(linux.IFF_UP) != 0 {
 			ctx.Warningf("Unsupported ifi_change flags: %x", ifinfomsg.Change)
@@ -162,12 +179,42 @@ func (s *Stack) SetInterface(ctx context.Context, msg *nlmsg.Message) *syserr.Er
 			ctx.Warningf("Unsupported ifi_flags: %x", ifinfomsg.Change)
 			return syserr.ErrInvalidArgument
 		}
-		// Netstack interfaces are always up.
+		// As in Linux, a zero ifi_change replaces all flags.
+		if ifinfomsg.Change == 0 || ifinfomsg.Change&linux.IFF_UP != 0 {
+			return s.setInterfaceUp(ifinfomsg.Index, ifinfomsg.Flags&linux.IFF_UP != 0)
+		}
 	}
 
 	return nil
 }
 
+// setInterfaceUp brings the interface identified by idx up or down.
+func (s *Stack) setInterfaceUp(idx int32, up bool) *syserr.Error {
+	if up {
+		return syserr.TranslateNetstackError(s.Stack.EnableNIC(tcpip.NICID(idx)))
+	}
+	return syserr.TranslateNetstackError(s.Stack.DisableNIC(tcpip.NICID(idx)))
+}
+
+// setInterfaceFlags sets the flags of the interface identified by idx, as for
+// SIOCSIFFLAGS. Only IFF_UP and IFF_PROMISC can be changed; as in Linux,
+// changes to other flags are silently ignored.
+func (s *Stack) setInterfaceFlags(idx int32, flags uint32) *syserr.Error {
+	if err := s.setInterfaceUp(idx, flags&linux.IFF_UP != 0); err != nil {
+		return err
+	}
+	return syserr.TranslateNetstackError(s.Stack.SetPromiscuousMode(tcpip.NICID(idx), flags&linux.IFF_PROMISC != 0))
+}
+
+// setInterfaceMTU sets the MTU of the interface identified by idx, as for
+// SIOCSIFMTU.
+func (s *Stack) setInterfaceMTU(idx int32, mtu uint32) *syserr.Error {
+	if mtu < header.IPv4MinimumMTU {
+		return syserr.ErrInvalidArgument
+	}
+	return syserr.TranslateNetstackError(s.Stack.SetNICMTU(tcpip.NICID(idx), mtu))
+}
+
 const defaultMTU = 1500
 
 func (s *Stack) newVeth(ctx context.Context, linkAttrs map[uint16]nlmsg.BytesView, linkInfoAttrs map[uint16]nlmsg.BytesView) *syserr.Error {
@@ -248,6 +295,25 @@ func (s *Stack) newVeth(ctx context.Context, linkAttrs map[uint16]nlmsg.BytesVie
 	return nil
 }
 
+// newVRF creates a VRF device. Unlike Linux, VRFs don't own a routing table:
+// traffic scoped to a VRF uses the routes through its members, so the table
+// ID in IFLA_INFO_DATA is ignored.
+func (s *Stack) newVRF(linkAttrs map[uint16]nlmsg.BytesView) *syserr.Error {
+	id := tcpip.NICID(s.Stack.UniqueID())
+	ifname := ""
+	if v, ok := linkAttrs[linux.IFLA_IFNAME]; ok {
+		ifname = v.String()
+	}
+	if ifname == "" {
+		ifname = fmt.Sprintf("vrf%d", id)
+	}
+	err := s.Stack.CreateNICWithOptions(id, newVRFEndpoint(), stack.NICOptions{
+		Name: ifname,
+		VRF:  true,
+	})
+	return syserr.TranslateNetstackError(err)
+}
+
 func (s *Stack) newInterface(ctx context.Context, msg *nlmsg.Message, linkAttrs map[uint16]nlmsg.BytesView) *syserr.Error {
 	var (
 		linkInfoAttrs map[uint16]nlmsg.BytesView
@@ -277,6 +343,8 @@ func (s *Stack) newInterface(ctx context.Context, msg *nlmsg.Message, linkAttrs
 		return syserr.ErrInvalidArgument
 	case "veth":
 		return s.newVeth(ctx, linkAttrs, linkInfoAttrs)
+	case "vrf":
+		return s.newVRF(linkAttrs)
 	}
 	return syserr.ErrNotSupported
 }
@@ -479,6 +547,30 @@ func (s *Stack) SetTCPRecovery(recovery inet.TCPLossRecovery) error {
 	return syserr.TranslateNetstackError(s.Stack.SetTransportProtocolOption(tcp.ProtocolNumber, &opt)).ToError()
 }
 
+// TCPECN implements inet.Stack.TCPECN.
+func (s *Stack) TCPECN() (int32, error) {
+	var ecn tcpip.TCPECNOption
+	err := s.Stack.TransportProtocolOption(tcp.ProtocolNumber, &ecn)
+	return int32(ecn), syserr.TranslateNetstackError(err).ToError()
+}
+
+// SetTCPECN implements inet.Stack.SetTCPECN.
+func (s *Stack) SetTCPECN(mode int32) error {
+	opt := tcpip.TCPECNOption(mode)
+	return syserr.TranslateNetstackError(s.Stack.SetTransportProtocolOption(tcp.ProtocolNumber, &opt)).ToError()
+}
+
+// sumCounters returns the sum of all counters in m.
+func sumCounters(m *tcpip.IntegralStatCounterMap) uint64 {
+	var sum uint64
+	for _, k := range m.Keys() {
+		if c, ok := m.Get(k); ok {
+			sum += c.Value()
+		}
+	}
+	return sum
+}
+
 // Statistics implements inet.Stack.Statistics.
 func (s *Stack) Statistics(stat any, arg string) error {
 	switch stats := stat.(type) {
@@ -487,26 +579,32 @@ func (s *Stack) Statistics(stat any, arg string) error {
 			if ni.Name != arg {
 				continue
 			}
+			// Packets for protocols netstack doesn't handle and packets
+			// received while the NIC is disabled are counted as drops, as
+			// Linux does for packets without a protocol handler.
+			rxDrops := ni.Stats.DisabledRx.Packets.Value() +
+				sumCounters(ni.Stats.UnknownL3ProtocolRcvdPacketCounts) +
+				sumCounters(ni.Stats.UnknownL4ProtocolRcvdPacketCounts)
 			// TODO(gvisor.dev/issue/2103) Support stubbed stats.
 			*stats = inet.StatDev{
 				// Receive section.
-				ni.Stats.Rx.Bytes.Value(),   // bytes.
-				ni.Stats.Rx.Packets.Value(), // packets.
-				0,                           // errs.
-				0,                           // drop.
-				0,                           // fifo.
-				0,                           // frame.
-				0,                           // compressed.
-				0,                           // multicast.
+				ni.Stats.Rx.Bytes.Value(),               // bytes.
+				ni.Stats.Rx.Packets.Value(),             // packets.
+				ni.Stats.MalformedL4RcvdPackets.Value(), // errs.
+				rxDrops,                                 // drop.
+				0,                                       // fifo.
+				0,                                       // frame.
+				0,                                       // compressed.
+				0,                                       // multicast.
 				// Transmit section.
 				ni.Stats.Tx.Bytes.Value(),   // bytes.
 				ni.Stats.Tx.Packets.Value(), // packets.
 				0,                           // errs.
-				0,                           // drop.
-				0,                           // fifo.
-				0,                           // colls.
-				0,                           // carrier.
-				0,                           // compressed.
+				ni.Stats.TxPacketsDroppedNoBufferSpace.Value(), // drop.
+				0, // fifo.
+				0, // colls.
+				0, // carrier.
+				0, // compressed.
 			}
 			break
 		}
@@ -600,12 +698,256 @@ func (s *Stack) Statistics(stat any, arg string) error {
 			udp.ChecksumErrors.Value(),      // Udp/InCsumErrors.
 			0,                               // Udp/IgnoredMulti.
 		}
+	case *inet.StatSNMPUDPLite:
+		udp := Metrics.UDPLite
+		// TODO(gvisor.dev/issue/969) Support stubbed stats.
+		*stats = inet.StatSNMPUDPLite{
+			udp.PacketsReceived.Value(),     // InDatagrams.
+			udp.UnknownPortErrors.Value(),   // NoPorts.
+			0,                               // UdpLite/InErrors.
+			udp.PacketsSent.Value(),         // OutDatagrams.
+			udp.ReceiveBufferErrors.Value(), // RcvbufErrors.
+			0,                               // UdpLite/SndbufErrors.
+			udp.ChecksumErrors.Value(),      // UdpLite/InCsumErrors.
+			0,                               // UdpLite/IgnoredMulti.
+		}
+	case *inet.StatSNMP6IP:
+		// Sum the IPv6 stats of all NICs.
+		*stats = inet.StatSNMP6IP{}
+		for _, ni := range s.Stack.NICInfo() {
+			nicStats, ok := ni.NetworkStats[ipv6.ProtocolNumber].(stack.IPNetworkEndpointStats)
+			if !ok {
+				continue
+			}
+			ip := nicStats.IPStats()
+			// TODO(gvisor.dev/issue/969) Support stubbed stats.
+			nic := inet.StatSNMP6IP{
+				ip.PacketsReceived.Value(),                     // Ip6InReceives.
+				ip.MalformedPacketsReceived.Value(),            // Ip6InHdrErrors.
+				ip.Forwarding.PacketTooBig.Value(),             // Ip6InTooBigErrors.
+				ip.Forwarding.Unrouteable.Value(),              // Ip6InNoRoutes.
+				ip.InvalidDestinationAddressesReceived.Value(), // Ip6InAddrErrors.
+				0,                               // Ip6/Ip6InUnknownProtos.
+				0,                               // Ip6/Ip6InTruncatedPkts.
+				0,                               // Ip6/Ip6InDiscards.
+				ip.PacketsDelivered.Value(),     // Ip6InDelivers.
+				0,                               // Ip6/Ip6OutForwDatagrams.
+				ip.PacketsSent.Value(),          // Ip6OutRequests.
+				ip.OutgoingPacketErrors.Value(), // Ip6OutDiscards.
+			}
+			for i := range nic {
+				stats[i] += nic[i]
+			}
+		}
+	case *inet.StatSNMP6ICMP:
+		in := Metrics.ICMP.V6.PacketsReceived.ICMPv6PacketStats
+		out := Metrics.ICMP.V6.PacketsSent.ICMPv6PacketStats
+		*stats = inet.StatSNMP6ICMP{
+			sumICMPv6(&in), // Icmp6InMsgs.
+			Metrics.ICMP.V6.PacketsReceived.Invalid.Value(), // Icmp6InErrors.
+			sumICMPv6(&out), // Icmp6OutMsgs.
+			Metrics.ICMP.V6.PacketsSent.Dropped.Value(), // Icmp6OutErrors.
+			0,                                     // Icmp6/Icmp6InCsumErrors.
+			in.DstUnreachable.Value(),             // Icmp6InDestUnreachs.
+			in.PacketTooBig.Value(),               // Icmp6InPktTooBigs.
+			in.TimeExceeded.Value(),               // Icmp6InTimeExcds.
+			in.ParamProblem.Value(),               // Icmp6InParmProblems.
+			in.EchoRequest.Value(),                // Icmp6InEchos.
+			in.EchoReply.Value(),                  // Icmp6InEchoReplies.
+			in.MulticastListenerQuery.Value(),     // Icmp6InGroupMembQueries.
+			in.MulticastListenerReport.Value(),    // Icmp6InGroupMembResponses.
+			in.MulticastListenerDone.Value(),      // Icmp6InGroupMembReductions.
+			in.RouterSolicit.Value(),              // Icmp6InRouterSolicits.
+			in.RouterAdvert.Value(),               // Icmp6InRouterAdvertisements.
+			in.NeighborSolicit.Value(),            // Icmp6InNeighborSolicits.
+			in.NeighborAdvert.Value(),             // Icmp6InNeighborAdvertisements.
+			in.RedirectMsg.Value(),                // Icmp6InRedirects.
+			in.MulticastListenerReportV2.Value(),  // Icmp6InMLDv2Reports.
+			out.DstUnreachable.Value(),            // Icmp6OutDestUnreachs.
+			out.PacketTooBig.Value(),              // Icmp6OutPktTooBigs.
+			out.TimeExceeded.Value(),              // Icmp6OutTimeExcds.
+			out.ParamProblem.Value(),              // Icmp6OutParmProblems.
+			out.EchoRequest.Value(),               // Icmp6OutEchos.
+			out.EchoReply.Value(),                 // Icmp6OutEchoReplies.
+			out.MulticastListenerQuery.Value(),    // Icmp6OutGroupMembQueries.
+			out.MulticastListenerReport.Value(),   // Icmp6OutGroupMembResponses.
+			out.MulticastListenerDone.Value(),     // Icmp6OutGroupMembReductions.
+			out.RouterSolicit.Value(),             // Icmp6OutRouterSolicits.
+			out.RouterAdvert.Value(),              // Icmp6OutRouterAdvertisements.
+			out.NeighborSolicit.Value(),           // Icmp6OutNeighborSolicits.
+			out.NeighborAdvert.Value(),            // Icmp6OutNeighborAdvertisements.
+			out.RedirectMsg.Value(),               // Icmp6OutRedirects.
+			out.MulticastListenerReportV2.Value(), // Icmp6OutMLDv2Reports.
+		}
+	case *inet.StatSNMP6UDP:
+		// TODO(gvisor.dev/issue/969) Netstack doesn't keep separate UDP
+		// stats for IPv6.
+		*stats = inet.StatSNMP6UDP{}
+	case *inet.StatNetstatTCPExt:
+		tcp := Metrics.TCP
+		listenDrops := tcp.ListenOverflowSynDrop.Value() + tcp.ListenOverflowAckDrop.Value()
+		// TODO(gvisor.dev/issue/969) Support stubbed stats.
+		*stats = inet.StatNetstatTCPExt{
+			tcp.ListenOverflowSynCookieSent.Value(),        // SyncookiesSent.
+			tcp.ListenOverflowSynCookieRcvd.Value(),        // SyncookiesRecv.
+			tcp.ListenOverflowInvalidSynCookieRcvd.Value(), // SyncookiesFailed.
+			0,           // TcpExt/EmbryonicRsts.
+			0,           // TcpExt/PruneCalled.
+			0,           // TcpExt/RcvPruned.
+			0,           // TcpExt/OfoPruned.
+			0,           // TcpExt/OutOfWindowIcmps.
+			0,           // TcpExt/LockDroppedIcmps.
+			0,           // TcpExt/ArpFilter.
+			0,           // TcpExt/TW.
+			0,           // TcpExt/TWRecycled.
+			0,           // TcpExt/TWKilled.
+			0,           // TcpExt/PAWSPassive.
+			0,           // TcpExt/PAWSActive.
+			0,           // TcpExt/PAWSEstab.
+			0,           // TcpExt/DelayedACKs.
+			0,           // TcpExt/DelayedACKLocked.
+			0,           // TcpExt/DelayedACKLost.
+			listenDrops, // ListenOverflows.
+			listenDrops, // ListenDrops.
+			0,           // TcpExt/TCPPrequeued.
+			0,           // TcpExt/TCPDirectCopyFromBacklog.
+			0,           // TcpExt/TCPDirectCopyFromPrequeue.
+			0,           // TcpExt/TCPPrequeueDropped.
+			0,           // TcpExt/TCPHPHits.
+			0,           // TcpExt/TCPHPHitsToUser.
+			0,           // TcpExt/TCPPureAcks.
+			0,           // TcpExt/TCPHPAcks.
+			tcp.FastRecovery.Value() - tcp.SACKRecovery.Value(), // TCPRenoRecovery.
+			tcp.SACKRecovery.Value(),                            // TCPSackRecovery.
+			0,                                                   // TcpExt/TCPSACKReneging.
+			0,                                                   // TcpExt/TCPFACKReorder.
+			0,                                                   // TcpExt/TCPSACKReorder.
+			0,                                                   // TcpExt/TCPRenoReorder.
+			0,                                                   // TcpExt/TCPTSReorder.
+			0,                                                   // TcpExt/TCPFullUndo.
+			0,                                                   // TcpExt/TCPPartialUndo.
+			0,                                                   // TcpExt/TCPDSACKUndo.
+			0,                                                   // TcpExt/TCPLossUndo.
+			0,                                                   // TcpExt/TCPLostRetransmit.
+			0,                                                   // TcpExt/TCPRenoFailures.
+			0,                                                   // TcpExt/TCPSackFailures.
+			0,                                                   // TcpExt/TCPLossFailures.
+			tcp.FastRetransmit.Value(),                          // TCPFastRetrans.
+			0,                                                   // TcpExt/TCPForwardRetrans.
+			tcp.SlowStartRetransmits.Value(),                    // TCPSlowStartRetrans.
+			tcp.Timeouts.Value(),                                // TCPTimeouts.
+			0,                                                   // TcpExt/TCPLossProbes.
+			tcp.TLPRecovery.Value(),                             // TCPLossProbeRecovery.
+			0,                                                   // TcpExt/TCPRenoRecoveryFail.
+			0,                                                   // TcpExt/TCPSackRecoveryFail.
+			0,                                                   // TcpExt/TCPSchedulerFailed.
+			0,                                                   // TcpExt/TCPRcvCollapsed.
+			0,                                                   // TcpExt/TCPDSACKOldSent.
+			0,                                                   // TcpExt/TCPDSACKOfoSent.
+			tcp.SegmentsAckedWithDSACK.Value(),                  // TCPDSACKRecv.
+			0,                                                   // TcpExt/TCPDSACKOfoRecv.
+			0,                                                   // TcpExt/TCPAbortOnData.
+			0,                                                   // TcpExt/TCPAbortOnClose.
+			0,                                                   // TcpExt/TCPAbortOnMemory.
+			tcp.EstablishedTimedout.Value(),                     // TCPAbortOnTimeout.
+			0,                                                   // TcpExt/TCPAbortOnLinger.
+			0,                                                   // TcpExt/TCPAbortFailed.
+			0,                                                   // TcpExt/TCPMemoryPressures.
+			0,                                                   // TcpExt/TCPSACKDiscard.
+			0,                                                   // TcpExt/TCPDSACKIgnoredOld.
+			0,                                                   // TcpExt/TCPDSACKIgnoredNoUndo.
+			tcp.SpuriousRTORecovery.Value(),                     // TCPSpuriousRTOs.
+			tcp.MD5NotFound.Value(),                             // TCPMD5NotFound.
+			tcp.MD5Unexpected.Value(),                           // TCPMD5Unexpected.
+			tcp.MD5Failure.Value(),                              // TCPMD5Failure.
+			0,                                                   // TcpExt/TCPSackShifted.
+			0,                                                   // TcpExt/TCPSackMerged.
+			0,                                                   // TcpExt/TCPSackShiftFallback.
+			0,                                                   // TcpExt/TCPBacklogDrop.
+			0,                                                   // TcpExt/TCPMinTTLDrop.
+			0,                                                   // TcpExt/TCPDeferAcceptDrop.
+			0,                                                   // TcpExt/IPReversePathFilter.
+			0,                                                   // TcpExt/TCPTimeWaitOverflow.
+			0,                                                   // TcpExt/TCPReqQFullDoCookies.
+			0,                                                   // TcpExt/TCPReqQFullDrop.
+			0,                                                   // TcpExt/TCPRetransFail.
+			0,                                                   // TcpExt/TCPRcvCoalesce.
+			0,                                                   // TcpExt/TCPOFOQueue.
+			0,                                                   // TcpExt/TCPOFODrop.
+			0,                                                   // TcpExt/TCPOFOMerge.
+			0,                                                   // TcpExt/TCPChallengeACK.
+			0,                                                   // TcpExt/TCPSYNChallenge.
+			0,                                                   // TcpExt/TCPFastOpenActive.
+			0,                                                   // TcpExt/TCPFastOpenActiveFail.
+			0,                                                   // TcpExt/TCPFastOpenPassive.
+			0,                                                   // TcpExt/TCPFastOpenPassiveFail.
+			0,                                                   // TcpExt/TCPFastOpenListenOverflow.
+			0,                                                   // TcpExt/TCPFastOpenCookieReqd.
+			0,                                                   // TcpExt/TCPSpuriousRtxHostQueues.
+			0,                                                   // TcpExt/BusyPollRxPackets.
+			0,                                                   // TcpExt/TCPAutoCorking.
+			0,                                                   // TcpExt/TCPFromZeroWindowAdv.
+			0,                                                   // TcpExt/TCPToZeroWindowAdv.
+			0,                                                   // TcpExt/TCPWantZeroWindowAdv.
+			0,                                                   // TcpExt/TCPSynRetrans.
+			0,                                                   // TcpExt/TCPOrigDataSent.
+			0,                                                   // TcpExt/TCPHystartTrainDetect.
+			0,                                                   // TcpExt/TCPHystartTrainCwnd.
+			0,                                                   // TcpExt/TCPHystartDelayDetect.
+			0,                                                   // TcpExt/TCPHystartDelayCwnd.
+			0,                                                   // TcpExt/TCPACKSkippedSynRecv.
+			0,                                                   // TcpExt/TCPACKSkippedPAWS.
+			0,                                                   // TcpExt/TCPACKSkippedSeq.
+			0,                                                   // TcpExt/TCPACKSkippedFinWait2.
+			0,                                                   // TcpExt/TCPACKSkippedTimeWait.
+			0,                                                   // TcpExt/TCPACKSkippedChallenge.
+			0,                                                   // TcpExt/TCPWinProbe.
+			0,                                                   // TcpExt/TCPKeepAlive.
+			0,                                                   // TcpExt/TCPMTUPFail.
+			0,                                                   // TcpExt/TCPMTUPSuccess.
+			tcp.AORequired.Value(),                              // TCPAORequired.
+			tcp.AOBad.Value(),                                   // TCPAOBad.
+			tcp.AOKeyNotFound.Value(),                           // TCPAOKeyNotFound.
+			tcp.AOGood.Value(),                                  // TCPAOGood.
+		}
+	case *inet.StatNetstatIPExt:
+		// TODO(gvisor.dev/issue/969) Support stubbed stats.
+		*stats = inet.StatNetstatIPExt{
+			Metrics.IP.Forwarding.Unrouteable.Value(), // InNoRoutes.
+			0, // IpExt/InTruncatedPkts.
+			0, // IpExt/InMcastPkts.
+			0, // IpExt/OutMcastPkts.
+			0, // IpExt/InBcastPkts.
+			0, // IpExt/OutBcastPkts.
+			0, // IpExt/InOctets.
+			0, // IpExt/OutOctets.
+			0, // IpExt/InMcastOctets.
+			0, // IpExt/OutMcastOctets.
+			0, // IpExt/InBcastOctets.
+			0, // IpExt/OutBcastOctets.
+			0, // IpExt/InCsumErrors.
+			0, // IpExt/InNoECTPkts.
+			0, // IpExt/InECT1Pkts.
+			0, // IpExt/InECT0Pkts.
+			0, // IpExt/InCEPkts.
+			0, // IpExt/ReasmOverlaps.
+		}
 	default:
 		return syserr.ErrEndpointOperation.ToError()
 	}
 	return nil
 }
 
+// sumICMPv6 returns the number of ICMPv6 messages of all types in s.
+func sumICMPv6(s *tcpip.ICMPv6PacketStats) uint64 {
+	return s.EchoRequest.Value() + s.EchoReply.Value() + s.DstUnreachable.Value() +
+		s.PacketTooBig.Value() + s.TimeExceeded.Value() + s.ParamProblem.Value() +
+		s.RouterSolicit.Value() + s.RouterAdvert.Value() + s.NeighborSolicit.Value() +
+		s.NeighborAdvert.Value() + s.RedirectMsg.Value() + s.MulticastListenerQuery.Value() +
+		s.MulticastListenerReport.Value() + s.MulticastListenerReportV2.Value() +
+		s.MulticastListenerDone.Value()
+}
+
 // RouteTable implements inet.Stack.RouteTable.
 func (s *Stack) RouteTable() []inet.Route {
 	var routeTable []inet.Route
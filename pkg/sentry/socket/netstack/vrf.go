@@ -0,0 +1,45 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netstack
+
+import (
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/loopback"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// vrfEndpoint is the link endpoint of a VRF device. As in Linux, packets sent
+// through the VRF device itself are looped back; traffic leaving the sandbox
+// goes through the VRF's members instead.
+//
+// +stateify savable
+type vrfEndpoint struct {
+	stack.LinkEndpoint
+}
+
+func newVRFEndpoint() *vrfEndpoint {
+	return &vrfEndpoint{LinkEndpoint: loopback.New()}
+}
+
+// Capabilities implements stack.LinkEndpoint.Capabilities. A VRF isn't a
+// loopback device even though it loops packets back.
+func (e *vrfEndpoint) Capabilities() stack.LinkEndpointCapabilities {
+	return e.LinkEndpoint.Capabilities() &^ stack.CapabilityLoopback
+}
+
+// ARPHardwareType implements stack.LinkEndpoint.ARPHardwareType.
+func (*vrfEndpoint) ARPHardwareType() header.ARPHardwareType {
+	return header.ARPHardwareNone
+}
@@ -136,6 +136,8 @@ func NewIPControlMessages(family int, cmgs tcpip.ReceivableControlMessages) IPCo
 		HopLimit:           uint32(cmgs.HopLimit),
 		HasTClass:          cmgs.HasTClass,
 		TClass:             cmgs.TClass,
+		HasFlowInfo:        cmgs.HasFlowInfo,
+		FlowInfo:           cmgs.FlowInfo,
 		HasIPPacketInfo:    cmgs.HasIPPacketInfo,
 		PacketInfo:         packetInfoToLinux(cmgs.PacketInfo),
 		HasIPv6PacketInfo:  cmgs.HasIPv6PacketInfo,
@@ -192,6 +194,13 @@ type IPControlMessages struct {
 	// TClass is the IPv6 traffic class of the associated packet.
 	TClass uint32
 
+	// HasFlowInfo indicates whether FlowInfo is valid/set.
+	HasFlowInfo bool
+
+	// FlowInfo is the IPv6 traffic class and flow label of the associated
+	// packet, in host byte order.
+	FlowInfo uint32
+
 	// HasIPPacketInfo indicates whether PacketInfo is set.
 	HasIPPacketInfo bool
 
@@ -300,6 +309,48 @@ type Socket interface {
 	Type() (family int, skType linux.SockType, protocol int)
 }
 
+// FileSplicer is an optional interface implemented by sockets that can write
+// data read from files without copying it into an intermediate buffer, as
+// done by sendfile(2).
+type FileSplicer interface {
+	// SpliceFromFile writes up to count bytes, read from file at offset, to
+	// the socket without blocking. If offset is -1, file is read at and its
+	// offset is advanced by the number of bytes written. file must be a
+	// regular file. SpliceFromFile returns the number of bytes written, and
+	// false if the socket doesn't support splicing from files, in which case
+	// nothing is written. It returns io.EOF if the end of file was reached.
+	SpliceFromFile(ctx context.Context, file *vfs.FileDescription, offset, count int64) (int64, bool, error)
+}
+
+// BatchMessenger is an optional interface implemented by sockets that can
+// receive or send several datagrams at once, as done by recvmmsg(2) and
+// sendmmsg(2). Batches don't carry control messages.
+type BatchMessenger interface {
+	// RecvMsgBatch receives up to len(dsts) datagrams, writing the i-th one
+	// to dsts[i]. Like RecvMsg, it blocks until a datagram is available unless
+	// flags contains MSG_DONTWAIT, but it then only returns the datagrams that
+	// are available without blocking further. It returns false if the socket
+	// can't receive a batch with these arguments, in which case nothing is
+	// received.
+	RecvMsgBatch(t *kernel.Task, dsts []usermem.IOSequence, flags int, haveDeadline bool, deadline ktime.Time, senderRequested bool) ([]BatchMessage, bool, *syserr.Error)
+
+	// SendMsgBatch sends each of srcs as a separate datagram to the address to,
+	// which is empty for connected sockets, without blocking. It returns the
+	// number of datagrams sent, and false if the socket can't send a batch, in
+	// which case nothing is sent.
+	SendMsgBatch(t *kernel.Task, srcs []usermem.IOSequence, to []byte, flags int) (int, bool, *syserr.Error)
+}
+
+// BatchMessage describes a datagram received by
+// BatchMessenger.RecvMsgBatch. Its fields have the same meaning as the
+// corresponding results of Socket.RecvMsg.
+type BatchMessage struct {
+	N             int
+	MsgFlags      int
+	SenderAddr    linux.SockAddr
+	SenderAddrLen uint32
+}
+
 // Provider is the interface implemented by providers of sockets for
 // specific address families (e.g., AF_INET).
 type Provider interface {
@@ -69,6 +69,10 @@ type ConnectingEndpoint interface {
 
 	// WaiterQueue returns a pointer to the endpoint's waiter queue.
 	WaiterQueue() *waiter.Queue
+
+	// CredentialsLocked returns the credentials that SO_PEERCRED reports to
+	// the endpoint's peers.
+	CredentialsLocked() CredentialsControlMessage
 }
 
 // connectionedEndpoint is a Unix-domain connected or connectable endpoint and implements
@@ -145,9 +149,12 @@ func newConnectioned(ctx context.Context, stype linux.SockType, uid uniqueid.Pro
 }
 
 // NewPair allocates a new pair of connected unix-domain connectionedEndpoints.
-func NewPair(ctx context.Context, stype linux.SockType, uid uniqueid.Provider) (Endpoint, Endpoint) {
+// SO_PEERCRED reports cred on both endpoints.
+func NewPair(ctx context.Context, stype linux.SockType, uid uniqueid.Provider, cred CredentialsControlMessage) (Endpoint, Endpoint) {
 	a := newConnectioned(ctx, stype, uid)
 	b := newConnectioned(ctx, stype, uid)
+	a.cred = cred
+	b.cred = cred
 
 	q1 := &queue{ReaderQueue: a.Queue, WriterQueue: b.Queue, limit: defaultBufferSize}
 	q1.InitRefs()
@@ -166,11 +173,13 @@ func NewPair(ctx context.Context, stype linux.SockType, uid uniqueid.Provider) (
 	a.connected = &connectedEndpoint{
 		endpoint:   b,
 		writeQueue: q2,
+		cred:       cred,
 	}
 	q1.IncRef()
 	b.connected = &connectedEndpoint{
 		endpoint:   a,
 		writeQueue: q1,
+		cred:       cred,
 	}
 
 	return a, b
@@ -313,11 +322,14 @@ func (e *connectionedEndpoint) BidirectionalConnect(ctx context.Context, ce Conn
 		return syserr.ErrConnectionRefused
 	}
 
-	// Create a newly bound connectionedEndpoint.
+	// Create a newly bound connectionedEndpoint. Like Linux, the accepted
+	// endpoint reports the listening endpoint's credentials to the
+	// connecting endpoint, and vice versa.
 	ne := &connectionedEndpoint{
 		baseEndpoint: baseEndpoint{
 			path:  e.path,
 			Queue: &waiter.Queue{},
+			cred:  e.cred,
 		},
 		id:          e.idGenerator.UniqueID(),
 		idGenerator: e.idGenerator,
@@ -333,6 +345,7 @@ func (e *connectionedEndpoint) BidirectionalConnect(ctx context.Context, ce Conn
 	ne.connected = &connectedEndpoint{
 		endpoint:   ce,
 		writeQueue: readQueue,
+		cred:       ce.CredentialsLocked(),
 	}
 
 	// Make sure the accepted endpoint inherits this listening socket's SO_SNDBUF.
@@ -351,6 +364,7 @@ func (e *connectionedEndpoint) BidirectionalConnect(ctx context.Context, ce Conn
 		connected := &connectedEndpoint{
 			endpoint:   ne,
 			writeQueue: writeQueue,
+			cred:       ne.cred,
 		}
 		readQueue.IncRef()
 		if e.stype == linux.SOCK_STREAM {
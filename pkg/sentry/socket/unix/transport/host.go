@@ -24,6 +24,7 @@ import (
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/fdnotifier"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/syserr"
 	"gvisor.dev/gvisor/pkg/tcpip"
@@ -50,6 +51,115 @@ func (c *SCMRights) Release(ctx context.Context) {
 	c.FDs = nil
 }
 
+// HostRightsPolicy is a set of types of host FDs that may be received as
+// SCM_RIGHTS from host Unix sockets.
+type HostRightsPolicy uint32
+
+const (
+	// HostRightsFile allows regular files other than memfds.
+	HostRightsFile HostRightsPolicy = 1 << iota
+
+	// HostRightsMemfd allows memfds and other shared memory files.
+	HostRightsMemfd
+
+	// HostRightsSocket allows Unix sockets.
+	HostRightsSocket
+
+	// HostRightsFIFO allows pipes and FIFOs.
+	HostRightsFIFO
+
+	// HostRightsCharDev allows character devices.
+	HostRightsCharDev
+
+	// HostRightsAll allows all types of host FDs that can be imported.
+	HostRightsAll = HostRightsFile | HostRightsMemfd | HostRightsSocket | HostRightsFIFO | HostRightsCharDev
+)
+
+// HostRightsAllowed is the policy applied to FDs received from host Unix
+// sockets. Added as a global to allow easy access everywhere.
+var HostRightsAllowed = HostRightsAll
+
+// AllowsFD returns true if p allows the host FD fd to be received.
+func (p HostRightsPolicy) AllowsFD(fd int) bool {
+	if p == HostRightsAll {
+		return true
+	}
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return false
+	}
+	switch stat.Mode & unix.S_IFMT {
+	case unix.S_IFREG:
+		// Only shared memory files, including memfds, support
+		// F_GET_SEALS.
+		if _, err := unix.FcntlInt(uintptr(fd), unix.F_GET_SEALS, 0); err == nil {
+			return p&HostRightsMemfd != 0
+		}
+		return p&HostRightsFile != 0
+	case unix.S_IFSOCK:
+		return p&HostRightsSocket != 0
+	case unix.S_IFIFO:
+		return p&HostRightsFIFO != 0
+	case unix.S_IFCHR:
+		return p&HostRightsCharDev != 0
+	default:
+		return false
+	}
+}
+
+// HostIDMap maps user or group IDs on the host to IDs in the sandbox's root
+// user namespace.
+type HostIDMap map[uint32]uint32
+
+// HostUIDMap and HostGIDMap translate the credentials of peers of host Unix
+// sockets, as reported by SO_PEERCRED and SCM_CREDENTIALS. Host IDs that
+// aren't mapped appear as nobody. Added as globals to allow easy access
+// everywhere.
+var (
+	HostUIDMap HostIDMap
+	HostGIDMap HostIDMap
+)
+
+// HostCredentialsEnabled returns true if SCM_CREDENTIALS are received from
+// host Unix sockets.
+func HostCredentialsEnabled() bool {
+	return len(HostUIDMap) > 0 || len(HostGIDMap) > 0
+}
+
+// HostCredentials implements CredentialsControlMessage with the credentials
+// of a host process.
+//
+// +stateify savable
+type HostCredentials struct {
+	// UID and GID are the host process' user and group IDs, translated to
+	// the sandbox's root user namespace.
+	UID auth.KUID
+	GID auth.KGID
+}
+
+// newHostCredentials returns HostCredentials for the host credentials ucred.
+func newHostCredentials(ucred *unix.Ucred) *HostCredentials {
+	c := &HostCredentials{
+		UID: auth.NobodyKUID,
+		GID: auth.NobodyKGID,
+	}
+	if uid, ok := HostUIDMap[ucred.Uid]; ok {
+		c.UID = auth.KUID(uid)
+	}
+	if gid, ok := HostGIDMap[ucred.Gid]; ok {
+		c.GID = auth.KGID(gid)
+	}
+	return c
+}
+
+// Equals implements CredentialsControlMessage.Equals.
+func (c *HostCredentials) Equals(oc CredentialsControlMessage) bool {
+	if oc, _ := oc.(*HostCredentials); oc != nil && *c == *oc {
+		return true
+	}
+	return false
+}
+
 // HostConnectedEndpoint is an implementation of ConnectedEndpoint and
 // Receiver. It is backed by a host fd that was imported at sentry startup.
 // This fd is shared with a hostfs inode, which retains ownership of it.
@@ -117,6 +227,14 @@ func (c *HostConnectedEndpoint) initFromOptions() *syserr.Error {
 		return syserr.FromError(err)
 	}
 
+	if HostCredentialsEnabled() {
+		// Ask the host to attach the sender's credentials to received
+		// messages, so that they can be translated.
+		if err := unix.SetsockoptInt(c.fd, unix.SOL_SOCKET, unix.SO_PASSCRED, 1); err != nil {
+			return syserr.FromError(err)
+		}
+	}
+
 	sndbuf, err := unix.GetsockoptInt(c.fd, unix.SOL_SOCKET, unix.SO_SNDBUF)
 	if err != nil {
 		return syserr.FromError(err)
@@ -159,15 +277,29 @@ func (c *HostConnectedEndpoint) Send(ctx context.Context, data [][]byte, control
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	if !controlMessages.Empty() {
-		return 0, false, syserr.ErrInvalidEndpointState
+	// Credentials can't be translated to the host, so they are dropped. If
+	// the peer has set SO_PASSCRED, the host attaches the sentry's
+	// credentials instead.
+	var control []byte
+	if controlMessages.Rights != nil {
+		rights, ok := controlMessages.Rights.(HostRightsControlMessage)
+		if !ok {
+			return 0, false, syserr.ErrInvalidEndpointState
+		}
+		fds, err := rights.HostFDs()
+		if err != nil {
+			return 0, false, syserr.FromError(err)
+		}
+		if len(fds) > 0 {
+			control = unix.UnixRights(fds...)
+		}
 	}
 
 	// Since stream sockets don't preserve message boundaries, we can write
 	// only as much of the message as fits in the send buffer.
 	truncate := c.stype == linux.SOCK_STREAM
 
-	n, totalLen, err := fdWriteVec(c.fd, data, c.SendMaxQueueSize(), truncate)
+	n, totalLen, err := fdWriteVec(c.fd, data, control, c.SendMaxQueueSize(), truncate)
 	if n < totalLen && err == nil {
 		// The host only returns a short write if it would otherwise
 		// block (and only for stream sockets).
@@ -179,6 +311,11 @@ func (c *HostConnectedEndpoint) Send(ctx context.Context, data [][]byte, control
 		// error with a partial write.
 		err = nil
 	}
+	if err == nil {
+		// The host kernel holds its own references on any FDs that were
+		// sent, so they are only sent once.
+		controlMessages.Release(ctx)
+	}
 
 	// There is no need for the callee to call SendNotify because fdWriteVec
 	// uses the host's sendmsg(2) and the host kernel's queue.
@@ -219,10 +356,22 @@ func (c *HostConnectedEndpoint) Writable() bool {
 
 // Passcred implements ConnectedEndpoint.Passcred.
 func (c *HostConnectedEndpoint) Passcred() bool {
-	// We don't support credential passing for host sockets.
+	// The host attaches credentials to messages sent to host sockets.
 	return false
 }
 
+// Credentials implements ConnectedEndpoint.Credentials.
+func (c *HostConnectedEndpoint) Credentials() CredentialsControlMessage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ucred, err := unix.GetsockoptUcred(c.fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return nil
+	}
+	return newHostCredentials(ucred)
+}
+
 // GetLocalAddress implements ConnectedEndpoint.GetLocalAddress.
 func (c *HostConnectedEndpoint) GetLocalAddress() (Address, tcpip.Error) {
 	return Address{Addr: c.addr}, nil
@@ -249,6 +398,11 @@ func (c *HostConnectedEndpoint) Recv(ctx context.Context, data [][]byte, args Re
 	if args.NumRights > 0 {
 		cm.EnableFDs(int(args.NumRights))
 	}
+	if HostCredentialsEnabled() {
+		// SO_PASSCRED is set, so the host attaches credentials to every
+		// message.
+		cm = append(cm, make([]byte, unix.CmsgSpace(unix.SizeofUcred))...)
+	}
 
 	// N.B. Unix sockets don't have a receive buffer, the send buffer
 	// serves both purposes.
@@ -279,20 +433,54 @@ func (c *HostConnectedEndpoint) Recv(ctx context.Context, data [][]byte, args Re
 		return out, false, nil
 	}
 
-	fds, err := cm.ExtractFDs()
+	fds, ucred, err := parseHostControlMessages(cm)
 	if err != nil {
 		return RecvOutput{}, false, syserr.FromError(err)
 	}
 
-	if len(fds) == 0 {
-		return out, false, nil
+	if len(fds) > 0 {
+		out.Control.Rights = &SCMRights{fds}
 	}
-	out.Control = ControlMessages{
-		Rights: &SCMRights{fds},
+	if ucred != nil {
+		out.Control.Credentials = newHostCredentials(ucred)
 	}
 	return out, false, nil
 }
 
+// parseHostControlMessages returns the FDs and credentials in control
+// messages received from the host.
+func parseHostControlMessages(cm []byte) ([]int, *unix.Ucred, error) {
+	msgs, err := unix.ParseSocketControlMessage(cm)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		fds   []int
+		ucred *unix.Ucred
+	)
+	for i := range msgs {
+		msg := &msgs[i]
+		if msg.Header.Level != unix.SOL_SOCKET {
+			continue
+		}
+		switch msg.Header.Type {
+		case unix.SCM_RIGHTS:
+			msgFDs, err := unix.ParseUnixRights(msg)
+			if err != nil {
+				for _, fd := range fds {
+					unix.Close(fd)
+				}
+				return nil, nil, err
+			}
+			fds = append(fds, msgFDs...)
+		case unix.SCM_CREDENTIALS:
+			// Malformed credentials are ignored.
+			ucred, _ = unix.ParseUnixCredentials(msg)
+		}
+	}
+	return fds, ucred, nil
+}
+
 // RecvNotify implements Receiver.RecvNotify.
 func (c *HostConnectedEndpoint) RecvNotify() {}
 
@@ -0,0 +1,125 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/syserr"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// HostAbstractConnector connects to abstract sockets on the host.
+type HostAbstractConnector interface {
+	// Bridged returns true if connections to the abstract socket name
+	// should be made to the host.
+	Bridged(name string) bool
+
+	// Connect returns a host socket of type stype connected to the abstract
+	// socket name on the host.
+	Connect(name string, stype int) (int, error)
+}
+
+// hostAbstractEndpoint is a BoundEndpoint for an abstract socket on the host.
+type hostAbstractEndpoint struct {
+	connector HostAbstractConnector
+
+	// name is the name of the abstract socket, without its leading NUL byte.
+	name string
+}
+
+// NewHostAbstractEndpoint returns a BoundEndpoint that connects to the
+// abstract socket name on the host using connector.
+func NewHostAbstractEndpoint(connector HostAbstractConnector, name string) BoundEndpoint {
+	return &hostAbstractEndpoint{
+		connector: connector,
+		name:      name,
+	}
+}
+
+// BidirectionalConnect implements BoundEndpoint.BidirectionalConnect.
+func (e *hostAbstractEndpoint) BidirectionalConnect(ctx context.Context, ce ConnectingEndpoint, returnConnect func(Receiver, ConnectedEndpoint)) *syserr.Error {
+	// No lock ordering required as only the ConnectingEndpoint has a mutex.
+	ce.Lock()
+
+	// Check connecting state.
+	if ce.Connected() {
+		ce.Unlock()
+		return syserr.ErrAlreadyConnected
+	}
+	if ce.ListeningLocked() {
+		ce.Unlock()
+		return syserr.ErrInvalidEndpointState
+	}
+
+	c, err := e.newConnectedEndpoint(ce.Type(), ce.WaiterQueue())
+	if err != nil {
+		ce.Unlock()
+		return err
+	}
+
+	returnConnect(c, c)
+	ce.Unlock()
+	if err := c.Init(); err != nil {
+		return syserr.FromError(err)
+	}
+
+	return nil
+}
+
+// UnidirectionalConnect implements BoundEndpoint.UnidirectionalConnect.
+func (e *hostAbstractEndpoint) UnidirectionalConnect(ctx context.Context) (ConnectedEndpoint, *syserr.Error) {
+	c, err := e.newConnectedEndpoint(linux.SOCK_DGRAM, &waiter.Queue{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Init(); err != nil {
+		return nil, syserr.FromError(err)
+	}
+
+	// We don't need the receiver.
+	c.CloseRecv()
+	c.Release(ctx)
+
+	return c, nil
+}
+
+func (e *hostAbstractEndpoint) newConnectedEndpoint(sockType linux.SockType, queue *waiter.Queue) (*SCMConnectedEndpoint, *syserr.Error) {
+	hostSockFD, err := e.connector.Connect(e.name, int(sockType))
+	if err != nil {
+		log.Debugf("Connecting to host abstract socket %q failed: %v", e.name, err)
+		return nil, syserr.ErrConnectionRefused
+	}
+
+	// Abstract socket addresses begin with a NUL byte.
+	c, serr := NewSCMEndpoint(hostSockFD, queue, "\x00"+e.name)
+	if serr != nil {
+		unix.Close(hostSockFD)
+		log.Warningf("NewSCMEndpoint failed: name=%q, err=%v", e.name, serr)
+		return nil, serr
+	}
+	return c, nil
+}
+
+// Release implements BoundEndpoint.Release.
+func (e *hostAbstractEndpoint) Release(ctx context.Context) {}
+
+// Passcred implements BoundEndpoint.Passcred.
+func (e *hostAbstractEndpoint) Passcred() bool {
+	return false
+}
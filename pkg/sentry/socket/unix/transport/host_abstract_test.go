@@ -0,0 +1,147 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/sentry/contexttest"
+	"gvisor.dev/gvisor/pkg/syserr"
+)
+
+type testIDProvider struct {
+	next atomicbitops.Uint64
+}
+
+// UniqueID implements uniqueid.Provider.UniqueID.
+func (p *testIDProvider) UniqueID() uint64 {
+	return p.next.Add(1)
+}
+
+// testConnector implements HostAbstractConnector by returning one end of a
+// host socket pair.
+type testConnector struct {
+	// peer is the other end of the last socket pair returned by Connect.
+	peer int
+
+	name  string
+	stype int
+	err   error
+}
+
+// Bridged implements HostAbstractConnector.Bridged.
+func (c *testConnector) Bridged(name string) bool {
+	return true
+}
+
+// Connect implements HostAbstractConnector.Connect.
+func (c *testConnector) Connect(name string, stype int) (int, error) {
+	c.name = name
+	c.stype = stype
+	if c.err != nil {
+		return -1, c.err
+	}
+	fds, err := unix.Socketpair(unix.AF_UNIX, stype|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, err
+	}
+	c.peer = fds[1]
+	return fds[0], nil
+}
+
+func TestHostAbstractEndpointBidirectional(t *testing.T) {
+	ctx := contexttest.Context(t)
+	conn := &testConnector{}
+	ep := NewConnectioned(ctx, linux.SOCK_STREAM, &testIDProvider{})
+	defer ep.Close(ctx)
+
+	if err := ep.Connect(ctx, NewHostAbstractEndpoint(conn, "test")); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer unix.Close(conn.peer)
+	if conn.name != "test" || conn.stype != unix.SOCK_STREAM {
+		t.Errorf("Connect(%q, %d), want Connect(%q, %d)", conn.name, conn.stype, "test", unix.SOCK_STREAM)
+	}
+	if err := ep.Connect(ctx, NewHostAbstractEndpoint(conn, "test")); err != syserr.ErrAlreadyConnected {
+		t.Errorf("second Connect: got %v, want %v", err, syserr.ErrAlreadyConnected)
+	}
+
+	// Data flows in both directions between the endpoint and the host socket.
+	if _, notify, err := ep.SendMsg(ctx, [][]byte{[]byte("ping")}, ControlMessages{}, nil); err != nil {
+		t.Fatalf("SendMsg failed: %v", err)
+	} else if notify != nil {
+		notify()
+	}
+	buf := make([]byte, 16)
+	if n, err := unix.Read(conn.peer, buf); err != nil || string(buf[:n]) != "ping" {
+		t.Errorf("host read: got (%q, %v), want (%q, nil)", buf[:n], err, "ping")
+	}
+	if _, err := unix.Write(conn.peer, []byte("pong")); err != nil {
+		t.Fatalf("host write failed: %v", err)
+	}
+	out, notify, err := ep.RecvMsg(ctx, [][]byte{buf}, RecvArgs{})
+	if err != nil {
+		t.Fatalf("RecvMsg failed: %v", err)
+	}
+	if notify != nil {
+		notify()
+	}
+	if got := string(buf[:out.RecvLen]); got != "pong" {
+		t.Errorf("RecvMsg: got %q, want %q", got, "pong")
+	}
+	// The peer address is the abstract socket name.
+	if got, want := out.Source.Addr, "\x00test"; got != want {
+		t.Errorf("RecvMsg source: got %q, want %q", got, want)
+	}
+}
+
+func TestHostAbstractEndpointRefused(t *testing.T) {
+	ctx := contexttest.Context(t)
+	conn := &testConnector{err: unix.ENOENT}
+	ep := NewConnectioned(ctx, linux.SOCK_STREAM, &testIDProvider{})
+	defer ep.Close(ctx)
+
+	if err := ep.Connect(ctx, NewHostAbstractEndpoint(conn, "missing")); err != syserr.ErrConnectionRefused {
+		t.Errorf("Connect: got %v, want %v", err, syserr.ErrConnectionRefused)
+	}
+	if ep.(ConnectingEndpoint).Connected() {
+		t.Errorf("endpoint is connected after a failed Connect")
+	}
+}
+
+func TestHostAbstractEndpointUnidirectional(t *testing.T) {
+	ctx := contexttest.Context(t)
+	conn := &testConnector{}
+	ce, err := NewHostAbstractEndpoint(conn, "dgram").UnidirectionalConnect(ctx)
+	if err != nil {
+		t.Fatalf("UnidirectionalConnect failed: %v", err)
+	}
+	defer ce.Release(ctx)
+	defer unix.Close(conn.peer)
+	if conn.stype != unix.SOCK_DGRAM {
+		t.Errorf("Connect socket type: got %d, want %d", conn.stype, unix.SOCK_DGRAM)
+	}
+
+	if _, _, err := ce.Send(ctx, [][]byte{[]byte("hello")}, ControlMessages{}, Address{}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	buf := make([]byte, 16)
+	if n, err := unix.Read(conn.peer, buf); err != nil || string(buf[:n]) != "hello" {
+		t.Errorf("host read: got (%q, %v), want (%q, nil)", buf[:n], err, "hello")
+	}
+}
@@ -71,6 +71,18 @@ func (q *queue) Reset(ctx context.Context) {
 	}
 }
 
+// forEachRights calls fn with the rights of each queued message. fn is called
+// with q.mu held.
+func (q *queue) forEachRights(fn func(RightsControlMessage)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for cur := q.dataList.Front(); cur != nil; cur = cur.Next() {
+		if cur.Control.Rights != nil {
+			fn(cur.Control.Rights)
+		}
+	}
+}
+
 // DecRef implements RefCounter.DecRef.
 func (q *queue) DecRef(ctx context.Context) {
 	q.queueRefs.DecRef(func() {
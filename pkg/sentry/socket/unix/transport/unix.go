@@ -46,6 +46,18 @@ type RightsControlMessage interface {
 	Release(ctx context.Context)
 }
 
+// A HostRightsControlMessage is a RightsControlMessage whose FDs can be passed
+// to a host Unix socket.
+type HostRightsControlMessage interface {
+	RightsControlMessage
+
+	// HostFDs returns host FDs representing the FDs in the message. The
+	// returned host FDs remain owned by the RightsControlMessage, and are
+	// valid until it is released. Only host memfds can be passed to the host;
+	// HostFDs returns EINVAL if the message contains any other file.
+	HostFDs() ([]int, error)
+}
+
 // A CredentialsControlMessage is a control message containing Unix credentials.
 type CredentialsControlMessage interface {
 	// Equals returns true iff the two messages are equal.
@@ -228,6 +240,15 @@ type Endpoint interface {
 	// SocketOptions returns the structure which contains all the socket
 	// level options.
 	SocketOptions() *tcpip.SocketOptions
+
+	// SetCredentials sets the credentials that SO_PEERCRED reports to peers
+	// of the endpoint. They are recorded by peers when a connection is
+	// established.
+	SetCredentials(c CredentialsControlMessage)
+
+	// PeerCredentials returns the credentials of the connected peer at the
+	// time the connection was established, or nil if they are unavailable.
+	PeerCredentials() CredentialsControlMessage
 }
 
 // A Credentialer is a socket or endpoint that supports the SO_PASSCRED socket
@@ -398,6 +419,29 @@ type Receiver interface {
 	Release(ctx context.Context)
 }
 
+// RightsQueue is implemented by endpoints whose receive queues may hold
+// SCM_RIGHTS messages. It allows in-flight files to be garbage collected.
+type RightsQueue interface {
+	// ForEachQueuedRights calls fn for each RightsControlMessage waiting to
+	// be received by the endpoint. fn must not block or acquire endpoint
+	// locks.
+	ForEachQueuedRights(fn func(RightsControlMessage))
+
+	// PurgeQueued releases all messages waiting to be received by the
+	// endpoint.
+	PurgeQueued(ctx context.Context)
+}
+
+// rightsReceiver is implemented by Receivers that hold SCM_RIGHTS messages.
+type rightsReceiver interface {
+	// forEachRights calls fn with the rights of each message waiting to be
+	// received.
+	forEachRights(fn func(RightsControlMessage))
+
+	// purge releases all messages waiting to be received.
+	purge(ctx context.Context)
+}
+
 // Address is a unix socket address.
 //
 // +stateify savable
@@ -482,6 +526,18 @@ func (q *queueReceiver) Release(ctx context.Context) {
 	q.readQueue.DecRef(ctx)
 }
 
+// forEachRights implements rightsReceiver.forEachRights.
+func (q *queueReceiver) forEachRights(fn func(RightsControlMessage)) {
+	q.readQueue.forEachRights(fn)
+}
+
+// purge implements rightsReceiver.purge.
+func (q *queueReceiver) purge(ctx context.Context) {
+	q.readQueue.Reset(ctx)
+	q.readQueue.ReaderQueue.Notify(waiter.ReadableEvents)
+	q.readQueue.WriterQueue.Notify(waiter.WritableEvents)
+}
+
 // streamQueueReceiver implements Receiver for stream sockets.
 //
 // +stateify savable
@@ -660,6 +716,27 @@ func (q *streamQueueReceiver) Recv(ctx context.Context, data [][]byte, args Recv
 	return out, notify, nil
 }
 
+// forEachRights implements rightsReceiver.forEachRights.
+func (q *streamQueueReceiver) forEachRights(fn func(RightsControlMessage)) {
+	q.mu.Lock()
+	if q.control.Rights != nil {
+		fn(q.control.Rights)
+	}
+	q.mu.Unlock()
+	q.queueReceiver.forEachRights(fn)
+}
+
+// purge implements rightsReceiver.purge.
+func (q *streamQueueReceiver) purge(ctx context.Context) {
+	q.mu.Lock()
+	c := q.control
+	q.control = ControlMessages{}
+	q.buffer = nil
+	q.mu.Unlock()
+	c.Release(ctx)
+	q.queueReceiver.purge(ctx)
+}
+
 // Release implements Receiver.Release.
 func (q *streamQueueReceiver) Release(ctx context.Context) {
 	q.queueReceiver.Release(ctx)
@@ -726,6 +803,10 @@ type ConnectedEndpoint interface {
 	// SetSendBufferSize is called when the endpoint's send buffer size is
 	// changed.
 	SetSendBufferSize(v int64) (newSz int64)
+
+	// Credentials returns the credentials of the endpoint at the time the
+	// connection was established, or nil if they are unavailable.
+	Credentials() CredentialsControlMessage
 }
 
 // +stateify savable
@@ -746,6 +827,10 @@ type connectedEndpoint struct {
 	}
 
 	writeQueue *queue
+
+	// cred is the credentials of endpoint at the time the connection was
+	// established. cred is immutable.
+	cred CredentialsControlMessage
 }
 
 // Passcred implements ConnectedEndpoint.Passcred.
@@ -753,6 +838,11 @@ func (e *connectedEndpoint) Passcred() bool {
 	return e.endpoint.Passcred()
 }
 
+// Credentials implements ConnectedEndpoint.Credentials.
+func (e *connectedEndpoint) Credentials() CredentialsControlMessage {
+	return e.cred
+}
+
 // GetLocalAddress implements ConnectedEndpoint.GetLocalAddress.
 func (e *connectedEndpoint) GetLocalAddress() (Address, tcpip.Error) {
 	return e.endpoint.GetLocalAddress()
@@ -865,6 +955,31 @@ type baseEndpoint struct {
 
 	// ops is used to get socket level options.
 	ops tcpip.SocketOptions
+
+	// cred is the credentials that SO_PEERCRED reports to peers of the
+	// endpoint. Linux uses the credentials of the task that created the
+	// socket, or that last called connect(2) or listen(2) on it.
+	cred CredentialsControlMessage
+}
+
+// ForEachQueuedRights implements RightsQueue.ForEachQueuedRights.
+func (e *baseEndpoint) ForEachQueuedRights(fn func(RightsControlMessage)) {
+	e.Lock()
+	r, ok := e.receiver.(rightsReceiver)
+	e.Unlock()
+	if ok {
+		r.forEachRights(fn)
+	}
+}
+
+// PurgeQueued implements RightsQueue.PurgeQueued.
+func (e *baseEndpoint) PurgeQueued(ctx context.Context) {
+	e.Lock()
+	r, ok := e.receiver.(rightsReceiver)
+	e.Unlock()
+	if ok {
+		r.purge(ctx)
+	}
 }
 
 // EventRegister implements waiter.Waitable.EventRegister.
@@ -904,6 +1019,31 @@ func (e *baseEndpoint) ConnectedPasscred() bool {
 	return e.connected != nil && e.connected.Passcred()
 }
 
+// SetCredentials implements Endpoint.SetCredentials.
+func (e *baseEndpoint) SetCredentials(c CredentialsControlMessage) {
+	e.Lock()
+	defer e.Unlock()
+	e.cred = c
+}
+
+// CredentialsLocked implements ConnectingEndpoint.CredentialsLocked.
+//
+// Preconditions: e.mu must be held.
+func (e *baseEndpoint) CredentialsLocked() CredentialsControlMessage {
+	return e.cred
+}
+
+// PeerCredentials implements Endpoint.PeerCredentials.
+func (e *baseEndpoint) PeerCredentials() CredentialsControlMessage {
+	e.Lock()
+	c := e.connected
+	e.Unlock()
+	if c != nil {
+		return c.Credentials()
+	}
+	return nil
+}
+
 // Connected implements ConnectingEndpoint.Connected.
 //
 // Preconditions: e.mu must be held.
@@ -122,6 +122,9 @@ func (s *Socket) DecRef(ctx context.Context) {
 		if s.namespace != nil {
 			s.namespace.DecRef(ctx)
 		}
+		// Like Linux, look for unreachable sockets in flight whenever a
+		// Unix socket is released.
+		control.CollectGarbage(ctx)
 	})
 }
 
@@ -135,6 +138,12 @@ func (s *Socket) Release(ctx context.Context) {
 // GetSockOpt implements the linux syscall getsockopt(2) for sockets backed by
 // a transport.Endpoint.
 func (s *Socket) GetSockOpt(t *kernel.Task, level, name int, outPtr hostarch.Addr, outLen int) (marshal.Marshallable, *syserr.Error) {
+	if level == linux.SOL_SOCKET && name == linux.SO_PEERCRED {
+		if outLen < linux.SizeOfControlMessageCredentials {
+			return nil, syserr.ErrInvalidArgument
+		}
+		return control.PeerCredentials(t, s.ep.PeerCredentials()), nil
+	}
 	return netstack.GetSockOpt(t, s, s.ep, linux.AF_UNIX, s.ep.Type(), level, name, outPtr, outLen)
 }
 
@@ -386,6 +395,8 @@ func (*provider) Socket(t *kernel.Task, stype linux.SockType, protocol int) (*vf
 		return nil, syserr.ErrInvalidArgument
 	}
 
+	ep.SetCredentials(control.MakeCreds(t))
+
 	f, err := NewSockfsFile(t, ep, stype)
 	if err != nil {
 		ep.Close(t)
@@ -409,7 +420,7 @@ func (*provider) Pair(t *kernel.Task, stype linux.SockType, protocol int) (*vfs.
 	}
 
 	// Create the endpoints and sockets.
-	ep1, ep2 := transport.NewPair(t, stype, t.Kernel())
+	ep1, ep2 := transport.NewPair(t, stype, t.Kernel(), control.MakeCreds(t))
 	s1, err := NewSockfsFile(t, ep1, stype)
 	if err != nil {
 		ep1.Close(t)
@@ -520,6 +531,8 @@ func (s *Socket) GetSockName(t *kernel.Task) (linux.SockAddr, uint32, *syserr.Er
 // Listen implements the linux syscall listen(2) for sockets backed by
 // a transport.Endpoint.
 func (s *Socket) Listen(t *kernel.Task, backlog int) *syserr.Error {
+	// Connecting sockets see the credentials of the listening task.
+	s.ep.SetCredentials(control.MakeCreds(t))
 	return s.ep.Listen(t, backlog)
 }
 
@@ -579,7 +592,9 @@ func (s *Socket) Connect(t *kernel.Task, sockaddr []byte, blocking bool) *syserr
 	}
 	defer ep.Release(t)
 
-	// Connect the server endpoint.
+	// Connect the server endpoint. The accepted socket sees the credentials
+	// of the connecting task.
+	s.ep.SetCredentials(control.MakeCreds(t))
 	err = s.ep.Connect(t, ep)
 
 	if err == syserr.ErrWrongProtocolForSocket {
@@ -0,0 +1,175 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/pkg/sync"
+
+	pkgstate "gvisor.dev/gvisor/pkg/state"
+)
+
+// SaveProgress reports the progress of the last save.
+type SaveProgress struct {
+	// InProgress is true if the save is ongoing.
+	InProgress bool `json:"in_progress"`
+
+	// Elapsed is the duration of the save so far.
+	Elapsed time.Duration `json:"elapsed"`
+
+	// ObjectsWritten is the number of kernel objects written so far.
+	ObjectsWritten uint64 `json:"objects_written"`
+
+	// ObjectsTotal is the number of kernel objects to be written. It is only
+	// known once the kernel object graph has been encoded, and stays behind
+	// ObjectsWritten until then.
+	ObjectsTotal uint64 `json:"objects_total"`
+
+	// BytesWritten is the number of bytes written to the state file and the
+	// pages files, after compression.
+	BytesWritten uint64 `json:"bytes_written"`
+}
+
+// lastSave tracks the last save. It is nil if no save was started.
+var lastSave atomic.Pointer[saveTracker]
+
+// LastSaveProgress returns the progress of the last save, or false if no save
+// was started.
+func LastSaveProgress() (SaveProgress, bool) {
+	t := lastSave.Load()
+	if t == nil {
+		return SaveProgress{}, false
+	}
+	return t.progress(), true
+}
+
+// saveTracker tracks the progress of a save, and limits the rate at which it
+// is written.
+type saveTracker struct {
+	start time.Time
+
+	// objects is passed to the state encoder.
+	objects pkgstate.Progress
+
+	// bytes is the number of bytes written.
+	bytes atomic.Uint64
+
+	// elapsed is the duration of the save once it is done, or zero if it is
+	// ongoing.
+	elapsed atomic.Int64
+
+	// rate is the maximum rate at which the save is written, in bytes per
+	// second. If zero, the rate isn't limited.
+	rate uint64
+
+	mu sync.Mutex
+
+	// next is the time at which the next write may start.
+	next time.Time
+}
+
+func newSaveTracker(rate uint64) *saveTracker {
+	t := &saveTracker{
+		start: time.Now(),
+		rate:  rate,
+	}
+	t.next = t.start
+	lastSave.Store(t)
+	return t
+}
+
+// done marks the save as done.
+func (t *saveTracker) done() {
+	t.elapsed.Store(int64(max(time.Since(t.start), 1)))
+}
+
+func (t *saveTracker) progress() SaveProgress {
+	p := SaveProgress{
+		Elapsed:      time.Duration(t.elapsed.Load()),
+		BytesWritten: t.bytes.Load(),
+	}
+	if p.Elapsed == 0 {
+		p.InProgress = true
+		p.Elapsed = time.Since(t.start)
+	}
+	p.ObjectsWritten, p.ObjectsTotal = t.objects.Objects()
+	return p
+}
+
+// wait blocks until n bytes may be written without exceeding t.rate.
+func (t *saveTracker) wait(n int) {
+	if t.rate == 0 {
+		return
+	}
+	t.mu.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		// Don't allow bursts after idle periods, e.g. while the object
+		// graph is encoded.
+		t.next = now
+	}
+	delay := t.next.Sub(now)
+	t.next = t.next.Add(time.Duration(float64(n) / float64(t.rate) * float64(time.Second)))
+	t.mu.Unlock()
+	time.Sleep(delay)
+}
+
+// writer returns a writer that writes to w, accounting to t.
+func (t *saveTracker) writer(w io.Writer) io.Writer {
+	return &trackedWriter{w: w, t: t}
+}
+
+// trackedWriter is an io.Writer accounting to a saveTracker.
+type trackedWriter struct {
+	w io.Writer
+	t *saveTracker
+}
+
+// Write implements io.Writer.Write.
+func (w *trackedWriter) Write(p []byte) (int, error) {
+	w.t.wait(len(p))
+	n, err := w.w.Write(p)
+	w.t.bytes.Add(uint64(n))
+	return n, err
+}
+
+// asyncWriter returns a writer that writes to w asynchronously, accounting to
+// t.
+func (t *saveTracker) asyncWriter(w *statefile.AsyncWriter) io.Writer {
+	return &trackedAsyncWriter{trackedWriter{w: w, t: t}, w}
+}
+
+// trackedAsyncWriter is a trackedWriter that also supports asynchronous
+// writes, as used by pgalloc.MemoryFile.SaveTo.
+type trackedAsyncWriter struct {
+	trackedWriter
+	aw *statefile.AsyncWriter
+}
+
+// WriteAsync schedules a write of p, as statefile.AsyncWriter.WriteAsync.
+func (w *trackedAsyncWriter) WriteAsync(p []byte) {
+	w.t.wait(len(p))
+	w.aw.WriteAsync(p)
+	w.t.bytes.Add(uint64(len(p)))
+}
+
+// Wait waits for scheduled writes, as statefile.AsyncWriter.Wait.
+func (w *trackedAsyncWriter) Wait() error {
+	return w.aw.Wait()
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSaveTrackerWaitUnlimited(t *testing.T) {
+	tr := newSaveTracker(0)
+	defer tr.done()
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		tr.wait(1 << 30)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("wait without a rate limit took %v", d)
+	}
+}
+
+func TestSaveTrackerWaitLimited(t *testing.T) {
+	const (
+		rate   = 10000 // bytes per second
+		chunk  = 1000
+		chunks = 6
+	)
+	tr := newSaveTracker(rate)
+	defer tr.done()
+	start := time.Now()
+	for i := 0; i < chunks; i++ {
+		tr.wait(chunk)
+	}
+	// The first chunk is written immediately, and each following one once
+	// the previous chunks' time has passed.
+	want := time.Duration((chunks-1)*chunk) * time.Second / rate
+	if d := time.Since(start); d < want {
+		t.Errorf("writing %d bytes at %d bytes/s took %v, want at least %v", chunks*chunk, rate, d, want)
+	}
+}
+
+func TestSaveTrackerWaitNoBurstAfterIdle(t *testing.T) {
+	const rate = 10000 // bytes per second
+	tr := newSaveTracker(rate)
+	defer tr.done()
+	// Idle for longer than it takes to write a chunk. The time spent idle
+	// must not be credited to the following writes.
+	time.Sleep(500 * time.Millisecond)
+	start := time.Now()
+	tr.wait(2000)
+	tr.wait(2000)
+	want := 2000 * time.Second / rate
+	if d := time.Since(start); d < want {
+		t.Errorf("second write after idle period took %v, want at least %v", d, want)
+	}
+}
+
+func TestSaveTrackerProgress(t *testing.T) {
+	tr := newSaveTracker(0)
+	p, ok := LastSaveProgress()
+	if !ok {
+		t.Fatalf("LastSaveProgress() returned no save")
+	}
+	if !p.InProgress {
+		t.Errorf("LastSaveProgress().InProgress got false, want true")
+	}
+
+	var buf bytes.Buffer
+	w := tr.writer(&buf)
+	for _, s := range []string{"foo", "bar", "baz"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if got, want := buf.String(), "foobarbaz"; got != want {
+		t.Errorf("written data got %q, want %q", got, want)
+	}
+	if p, _ := LastSaveProgress(); p.BytesWritten != uint64(buf.Len()) {
+		t.Errorf("LastSaveProgress().BytesWritten got %d, want %d", p.BytesWritten, buf.Len())
+	}
+
+	tr.done()
+	p, _ = LastSaveProgress()
+	if p.InProgress {
+		t.Errorf("LastSaveProgress().InProgress got true after the save is done, want false")
+	}
+	if p.Elapsed <= 0 {
+		t.Errorf("LastSaveProgress().Elapsed got %v, want > 0", p.Elapsed)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if p2, _ := LastSaveProgress(); p2.Elapsed != p.Elapsed {
+		t.Errorf("LastSaveProgress().Elapsed changed from %v to %v after the save is done", p.Elapsed, p2.Elapsed)
+	}
+
+	// A new save replaces the last one.
+	newSaveTracker(0)
+	if p, _ := LastSaveProgress(); !p.InProgress || p.BytesWritten != 0 {
+		t.Errorf("LastSaveProgress() got %+v after a new save started, want an empty save in progress", p)
+	}
+}
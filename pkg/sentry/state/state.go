@@ -30,6 +30,8 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sentry/watchdog"
 	"gvisor.dev/gvisor/pkg/state/statefile"
+
+	pkgstate "gvisor.dev/gvisor/pkg/state"
 )
 
 var previousMetadata map[string]string
@@ -72,6 +74,13 @@ type SaveOpts struct {
 
 	// Resume indicates if the statefile is used for save-resume.
 	Resume bool
+
+	// RateLimit is the maximum rate at which the state is written, in bytes
+	// per second. If zero, the rate isn't limited.
+	RateLimit uint64
+
+	// AsyncIO configures asynchronous writes to PagesFile.
+	AsyncIO statefile.AsyncOpts
 }
 
 // Save saves the system state.
@@ -93,13 +102,27 @@ func (opts SaveOpts) Save(ctx context.Context, k *kernel.Kernel, w *watchdog.Wat
 	}
 	addSaveMetadata(opts.Metadata)
 
+	// Track progress, and limit the rate, of all writes.
+	t := newSaveTracker(opts.RateLimit)
+	defer t.done()
+	ctx = context.WithValue(ctx, pkgstate.CtxProgress, &t.objects)
+	var pmw, pw io.Writer
+	if opts.PagesMetadata != nil {
+		pmw = t.writer(opts.PagesMetadata)
+	}
+	var aw *statefile.AsyncWriter
+	if opts.PagesFile != nil {
+		aw = statefile.NewAsyncWriter(opts.PagesFile, 0 /* off */, opts.AsyncIO)
+		pw = t.asyncWriter(aw)
+	}
+
 	// Open the statefile.
-	wc, err := statefile.NewWriter(opts.Destination, opts.Key, opts.Metadata)
+	wc, err := statefile.NewWriter(t.writer(opts.Destination), opts.Key, opts.Metadata)
 	if err != nil {
 		err = ErrStateFile{err}
 	} else {
 		// Save the kernel.
-		err = k.SaveTo(ctx, wc, opts.PagesMetadata, opts.PagesFile, opts.MemoryFileSaveOpts)
+		err = k.SaveTo(ctx, wc, pmw, pw, opts.MemoryFileSaveOpts)
 
 		// ENOSPC is a state file error. This error can only come from
 		// writing the state file, and not from fs.FileOperations.Fsync
@@ -112,6 +135,11 @@ func (opts SaveOpts) Save(ctx context.Context, k *kernel.Kernel, w *watchdog.Wat
 			err = ErrStateFile{closeErr}
 		}
 	}
+	if aw != nil {
+		if closeErr := aw.Close(); err == nil && closeErr != nil {
+			err = ErrStateFile{closeErr}
+		}
+	}
 	opts.Callback(err)
 	return err
 }
@@ -131,6 +159,9 @@ type LoadOpts struct {
 
 	// Key is used for state integrity check.
 	Key []byte
+
+	// AsyncIO configures asynchronous reads from PagesFile.
+	AsyncIO statefile.AsyncOpts
 }
 
 // Load loads the given kernel, setting the provided platform and stack.
@@ -144,5 +175,5 @@ func (opts LoadOpts) Load(ctx context.Context, k *kernel.Kernel, timeReady chan
 	previousMetadata = m
 
 	// Restore the Kernel object graph.
-	return k.LoadFrom(ctx, r, opts.PagesMetadata, opts.PagesFile, timeReady, n, clocks, vfsOpts)
+	return k.LoadFrom(ctx, r, opts.PagesMetadata, opts.PagesFile, opts.AsyncIO, timeReady, n, clocks, vfsOpts)
 }
@@ -150,12 +150,12 @@ var AMD64 = &kernel.SyscallTable{
 		95:  syscalls.Supported("umask", Umask),
 		96:  syscalls.Supported("gettimeofday", Gettimeofday),
 		97:  syscalls.Supported("getrlimit", Getrlimit),
-		98:  syscalls.PartiallySupported("getrusage", Getrusage, "Fields ru_maxrss, ru_minflt, ru_majflt, ru_inblock, ru_oublock are not supported. Fields ru_utime and ru_stime have low precision.", nil),
+		98:  syscalls.PartiallySupported("getrusage", Getrusage, "Fields ru_maxrss, ru_minflt, ru_majflt, ru_inblock, ru_oublock are not supported.", nil),
 		99:  syscalls.PartiallySupported("sysinfo", Sysinfo, "Fields loads, sharedram, bufferram, totalswap, freeswap, totalhigh, freehigh not supported.", nil),
 		100: syscalls.Supported("times", Times),
 		101: syscalls.PartiallySupported("ptrace", Ptrace, "Options PTRACE_PEEKSIGINFO, PTRACE_SECCOMP_GET_FILTER not supported.", nil),
 		102: syscalls.Supported("getuid", Getuid),
-		103: syscalls.PartiallySupported("syslog", Syslog, "Outputs a dummy message for security reasons.", nil),
+		103: syscalls.Supported("syslog", Syslog),
 		104: syscalls.Supported("getgid", Getgid),
 		105: syscalls.SupportedPoint("setuid", Setuid, PointSetuid),
 		106: syscalls.SupportedPoint("setgid", Setgid, PointSetgid),
@@ -217,10 +217,10 @@ var AMD64 = &kernel.SyscallTable{
 		162: syscalls.Supported("sync", Sync),
 		163: syscalls.CapError("acct", linux.CAP_SYS_PACCT, "", nil),
 		164: syscalls.CapError("settimeofday", linux.CAP_SYS_TIME, "", nil),
-		165: syscalls.Supported("mount", Mount),
-		166: syscalls.Supported("umount2", Umount2),
-		167: syscalls.CapError("swapon", linux.CAP_SYS_ADMIN, "", nil),
-		168: syscalls.CapError("swapoff", linux.CAP_SYS_ADMIN, "", nil),
+		165: syscalls.SupportedPoint("mount", Mount, PointMount),
+		166: syscalls.SupportedPoint("umount2", Umount2, PointUmount2),
+		167: syscalls.PartiallySupported("swapon", Swapon, "Applications can't configure swap areas; the only swap device is the virtual one configured by the operator.", nil),
+		168: syscalls.PartiallySupported("swapoff", Swapoff, "Applications can't configure swap areas; the only swap device is the virtual one configured by the operator.", nil),
 		169: syscalls.CapError("reboot", linux.CAP_SYS_BOOT, "", nil),
 		170: syscalls.Supported("sethostname", Sethostname),
 		171: syscalls.Supported("setdomainname", Setdomainname),
@@ -254,7 +254,7 @@ var AMD64 = &kernel.SyscallTable{
 		199: syscalls.Supported("fremovexattr", Fremovexattr),
 		200: syscalls.Supported("tkill", Tkill),
 		201: syscalls.Supported("time", Time),
-		202: syscalls.PartiallySupported("futex", Futex, "Robust futexes not supported.", nil),
+		202: syscalls.PartiallySupported("futex", Futex, "FUTEX_WAIT_REQUEUE_PI and FUTEX_CMP_REQUEUE_PI are not supported.", nil),
 		203: syscalls.PartiallySupported("sched_setaffinity", SchedSetaffinity, "Stub implementation.", nil),
 		204: syscalls.PartiallySupported("sched_getaffinity", SchedGetaffinity, "Stub implementation.", nil),
 		205: syscalls.Error("set_thread_area", linuxerr.ENOSYS, "Expected to return ENOSYS on 64-bit", nil),
@@ -355,8 +355,8 @@ var AMD64 = &kernel.SyscallTable{
 		300: syscalls.ErrorWithEvent("fanotify_init", linuxerr.ENOSYS, "Needs CONFIG_FANOTIFY", nil),
 		301: syscalls.ErrorWithEvent("fanotify_mark", linuxerr.ENOSYS, "Needs CONFIG_FANOTIFY", nil),
 		302: syscalls.SupportedPoint("prlimit64", Prlimit64, PointPrlimit64),
-		303: syscalls.Error("name_to_handle_at", linuxerr.EOPNOTSUPP, "Not supported by gVisor filesystems", nil),
-		304: syscalls.Error("open_by_handle_at", linuxerr.EOPNOTSUPP, "Not supported by gVisor filesystems", nil),
+		303: syscalls.PartiallySupported("name_to_handle_at", NameToHandleAt, "Only supported on tmpfs and gofer filesystems. Handles are only valid in the sandbox.", nil),
+		304: syscalls.PartiallySupported("open_by_handle_at", OpenByHandleAt, "Only supported on tmpfs and gofer filesystems. Handles are only valid in the sandbox.", nil),
 		305: syscalls.CapError("clock_adjtime", linux.CAP_SYS_TIME, "", nil),
 		306: syscalls.Supported("syncfs", Syncfs),
 		307: syscalls.Supported("sendmmsg", SendMMsg),
@@ -388,7 +388,7 @@ var AMD64 = &kernel.SyscallTable{
 		330: syscalls.ErrorWithEvent("pkey_alloc", linuxerr.ENOSYS, "", nil),
 		331: syscalls.ErrorWithEvent("pkey_free", linuxerr.ENOSYS, "", nil),
 		332: syscalls.Supported("statx", Statx),
-		333: syscalls.ErrorWithEvent("io_pgetevents", linuxerr.ENOSYS, "", nil),
+		333: syscalls.PartiallySupported("io_pgetevents", IoPgetevents, "Generally supported with exceptions. User ring optimizations are not implemented.", []string{"gvisor.dev/issue/204"}),
 		334: syscalls.PartiallySupported("rseq", RSeq, "Not supported on all platforms.", nil),
 
 		// Linux skips ahead to syscall 424 to sync numbers between arches.
@@ -402,11 +402,12 @@ var AMD64 = &kernel.SyscallTable{
 		431: syscalls.ErrorWithEvent("fsconfig", linuxerr.ENOSYS, "", nil),
 		432: syscalls.ErrorWithEvent("fsmount", linuxerr.ENOSYS, "", nil),
 		433: syscalls.ErrorWithEvent("fspick", linuxerr.ENOSYS, "", nil),
-		434: syscalls.ErrorWithEvent("pidfd_open", linuxerr.ENOSYS, "", nil),
-		435: syscalls.PartiallySupported("clone3", Clone3, "Options CLONE_PIDFD, CLONE_NEWCGROUP, CLONE_INTO_CGROUP, CLONE_NEWTIME, CLONE_CLEAR_SIGHAND, CLONE_PARENT, CLONE_SYSVSEM and, SetTid are not supported.", nil),
+		434: syscalls.PartiallySupported("pidfd_open", PidfdOpen, "PIDFD_THREAD is not supported. pidfds can only be used with setns(2) and poll.", nil),
+		435: syscalls.PartiallySupported("clone3", Clone3, "Options CLONE_PIDFD, CLONE_NEWCGROUP, CLONE_NEWTIME, CLONE_PARENT and CLONE_SYSVSEM are not supported. CLONE_INTO_CGROUP accepts cgroupfs (v1) hierarchies.", nil),
 		436: syscalls.Supported("close_range", CloseRange),
 		439: syscalls.Supported("faccessat2", Faccessat2),
 		441: syscalls.Supported("epoll_pwait2", EpollPwait2),
+		449: syscalls.Supported("futex_waitv", FutexWaitv),
 	},
 	Emulate: map[hostarch.Addr]uintptr{
 		0xffffffffff600000: 96,  // vsyscall gettimeofday(2)
@@ -470,8 +471,8 @@ var ARM64 = &kernel.SyscallTable{
 		36:  syscalls.Supported("symlinkat", Symlinkat),
 		37:  syscalls.Supported("linkat", Linkat),
 		38:  syscalls.Supported("renameat", Renameat),
-		39:  syscalls.Supported("umount2", Umount2),
-		40:  syscalls.Supported("mount", Mount),
+		39:  syscalls.SupportedPoint("umount2", Umount2, PointUmount2),
+		40:  syscalls.SupportedPoint("mount", Mount, PointMount),
 		41:  syscalls.Supported("pivot_root", PivotRoot),
 		42:  syscalls.Error("nfsservctl", linuxerr.ENOSYS, "Removed after Linux 3.1.", nil),
 		43:  syscalls.Supported("statfs", Statfs),
@@ -529,7 +530,7 @@ var ARM64 = &kernel.SyscallTable{
 		95:  syscalls.Supported("waitid", Waitid),
 		96:  syscalls.Supported("set_tid_address", SetTidAddress),
 		97:  syscalls.PartiallySupported("unshare", Unshare, "Mount, cgroup namespaces not supported. Network namespaces supported but must be empty.", nil),
-		98:  syscalls.PartiallySupported("futex", Futex, "Robust futexes not supported.", nil),
+		98:  syscalls.PartiallySupported("futex", Futex, "FUTEX_WAIT_REQUEUE_PI and FUTEX_CMP_REQUEUE_PI are not supported.", nil),
 		99:  syscalls.Supported("set_robust_list", SetRobustList),
 		100: syscalls.Supported("get_robust_list", GetRobustList),
 		101: syscalls.Supported("nanosleep", Nanosleep),
@@ -547,7 +548,7 @@ var ARM64 = &kernel.SyscallTable{
 		113: syscalls.Supported("clock_gettime", ClockGettime),
 		114: syscalls.Supported("clock_getres", ClockGetres),
 		115: syscalls.Supported("clock_nanosleep", ClockNanosleep),
-		116: syscalls.PartiallySupported("syslog", Syslog, "Outputs a dummy message for security reasons.", nil),
+		116: syscalls.Supported("syslog", Syslog),
 		117: syscalls.PartiallySupported("ptrace", Ptrace, "Options PTRACE_PEEKSIGINFO, PTRACE_SECCOMP_GET_FILTER not supported.", nil),
 		118: syscalls.CapError("sched_setparam", linux.CAP_SYS_NICE, "", nil),
 		119: syscalls.PartiallySupported("sched_setscheduler", SchedSetscheduler, "Stub implementation.", nil),
@@ -596,7 +597,7 @@ var ARM64 = &kernel.SyscallTable{
 		162: syscalls.Supported("setdomainname", Setdomainname),
 		163: syscalls.Supported("getrlimit", Getrlimit),
 		164: syscalls.PartiallySupported("setrlimit", Setrlimit, "Not all rlimits are enforced.", nil),
-		165: syscalls.PartiallySupported("getrusage", Getrusage, "Fields ru_maxrss, ru_minflt, ru_majflt, ru_inblock, ru_oublock are not supported. Fields ru_utime and ru_stime have low precision.", nil),
+		165: syscalls.PartiallySupported("getrusage", Getrusage, "Fields ru_maxrss, ru_minflt, ru_majflt, ru_inblock, ru_oublock are not supported.", nil),
 		166: syscalls.Supported("umask", Umask),
 		167: syscalls.PartiallySupported("prctl", Prctl, "Not all options are supported.", nil),
 		168: syscalls.Supported("getcpu", Getcpu),
@@ -655,8 +656,8 @@ var ARM64 = &kernel.SyscallTable{
 		221: syscalls.SupportedPoint("execve", Execve, PointExecve),
 		222: syscalls.Supported("mmap", Mmap),
 		223: syscalls.PartiallySupported("fadvise64", Fadvise64, "Not all options are supported.", nil),
-		224: syscalls.CapError("swapon", linux.CAP_SYS_ADMIN, "", nil),
-		225: syscalls.CapError("swapoff", linux.CAP_SYS_ADMIN, "", nil),
+		224: syscalls.PartiallySupported("swapon", Swapon, "Applications can't configure swap areas; the only swap device is the virtual one configured by the operator.", nil),
+		225: syscalls.PartiallySupported("swapoff", Swapoff, "Applications can't configure swap areas; the only swap device is the virtual one configured by the operator.", nil),
 		226: syscalls.Supported("mprotect", Mprotect),
 		227: syscalls.PartiallySupported("msync", Msync, "Full data flush is not guaranteed at this time.", nil),
 		228: syscalls.PartiallySupported("mlock", Mlock, "Stub implementation. The sandbox lacks appropriate permissions.", nil),
@@ -679,8 +680,8 @@ var ARM64 = &kernel.SyscallTable{
 		261: syscalls.SupportedPoint("prlimit64", Prlimit64, PointPrlimit64),
 		262: syscalls.ErrorWithEvent("fanotify_init", linuxerr.ENOSYS, "Needs CONFIG_FANOTIFY", nil),
 		263: syscalls.ErrorWithEvent("fanotify_mark", linuxerr.ENOSYS, "Needs CONFIG_FANOTIFY", nil),
-		264: syscalls.Error("name_to_handle_at", linuxerr.EOPNOTSUPP, "Not supported by gVisor filesystems", nil),
-		265: syscalls.Error("open_by_handle_at", linuxerr.EOPNOTSUPP, "Not supported by gVisor filesystems", nil),
+		264: syscalls.PartiallySupported("name_to_handle_at", NameToHandleAt, "Only supported on tmpfs and gofer filesystems. Handles are only valid in the sandbox.", nil),
+		265: syscalls.PartiallySupported("open_by_handle_at", OpenByHandleAt, "Only supported on tmpfs and gofer filesystems. Handles are only valid in the sandbox.", nil),
 		266: syscalls.CapError("clock_adjtime", linux.CAP_SYS_TIME, "", nil),
 		267: syscalls.Supported("syncfs", Syncfs),
 		268: syscalls.Supported("setns", Setns),
@@ -709,7 +710,7 @@ var ARM64 = &kernel.SyscallTable{
 		289: syscalls.ErrorWithEvent("pkey_alloc", linuxerr.ENOSYS, "", nil),
 		290: syscalls.ErrorWithEvent("pkey_free", linuxerr.ENOSYS, "", nil),
 		291: syscalls.Supported("statx", Statx),
-		292: syscalls.ErrorWithEvent("io_pgetevents", linuxerr.ENOSYS, "", nil),
+		292: syscalls.PartiallySupported("io_pgetevents", IoPgetevents, "Generally supported with exceptions. User ring optimizations are not implemented.", []string{"gvisor.dev/issue/204"}),
 		293: syscalls.PartiallySupported("rseq", RSeq, "Not supported on all platforms.", nil),
 
 		// Linux skips ahead to syscall 424 to sync numbers between arches.
@@ -723,11 +724,12 @@ var ARM64 = &kernel.SyscallTable{
 		431: syscalls.ErrorWithEvent("fsconfig", linuxerr.ENOSYS, "", nil),
 		432: syscalls.ErrorWithEvent("fsmount", linuxerr.ENOSYS, "", nil),
 		433: syscalls.ErrorWithEvent("fspick", linuxerr.ENOSYS, "", nil),
-		434: syscalls.ErrorWithEvent("pidfd_open", linuxerr.ENOSYS, "", nil),
-		435: syscalls.PartiallySupported("clone3", Clone3, "Options CLONE_PIDFD, CLONE_NEWCGROUP, CLONE_INTO_CGROUP, CLONE_NEWTIME, CLONE_CLEAR_SIGHAND, CLONE_PARENT, CLONE_SYSVSEM and clone_args.set_tid are not supported.", nil),
+		434: syscalls.PartiallySupported("pidfd_open", PidfdOpen, "PIDFD_THREAD is not supported. pidfds can only be used with setns(2) and poll.", nil),
+		435: syscalls.PartiallySupported("clone3", Clone3, "Options CLONE_PIDFD, CLONE_NEWCGROUP, CLONE_NEWTIME, CLONE_PARENT and CLONE_SYSVSEM are not supported. CLONE_INTO_CGROUP accepts cgroupfs (v1) hierarchies.", nil),
 		436: syscalls.Supported("close_range", CloseRange),
 		439: syscalls.Supported("faccessat2", Faccessat2),
 		441: syscalls.Supported("epoll_pwait2", EpollPwait2),
+		449: syscalls.Supported("futex_waitv", FutexWaitv),
 	},
 	Emulate: map[hostarch.Addr]uintptr{},
 	Missing: func(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, error) {
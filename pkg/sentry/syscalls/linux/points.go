@@ -730,6 +730,44 @@ func PointChroot(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextDa
 	return p, pb.MessageType_MESSAGE_SYSCALL_CHROOT
 }
 
+// PointMount converts mount(2) syscall to proto.
+func PointMount(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Mount{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Flags:       info.Args[3].Uint64(),
+	}
+	if addr := info.Args[0].Pointer(); addr != 0 {
+		if source, err := t.CopyInString(addr, linux.PATH_MAX); err == nil { // if NO error
+			p.Source = source
+		}
+	}
+	if target, err := t.CopyInString(info.Args[1].Pointer(), linux.PATH_MAX); err == nil { // if NO error
+		p.Target = target
+	}
+	if addr := info.Args[2].Pointer(); addr != 0 {
+		if fstype, err := t.CopyInString(addr, linux.PATH_MAX); err == nil { // if NO error
+			p.Fstype = fstype
+		}
+	}
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_MOUNT
+}
+
+// PointUmount2 converts umount2(2) syscall to proto.
+func PointUmount2(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
+	p := &pb.Umount{
+		ContextData: cxtData,
+		Sysno:       uint64(info.Sysno),
+		Flags:       info.Args[1].Uint(),
+	}
+	if target, err := t.CopyInString(info.Args[0].Pointer(), linux.PATH_MAX); err == nil { // if NO error
+		p.Target = target
+	}
+	p.Exit = newExitMaybe(info)
+	return p, pb.MessageType_MESSAGE_SYSCALL_UMOUNT
+}
+
 // PointClone converts clone(2) syscall to proto.
 func PointClone(t *kernel.Task, fields seccheck.FieldSet, cxtData *pb.ContextData, info kernel.SyscallInfo) (proto.Message, pb.MessageType) {
 	p := &pb.Clone{
@@ -16,6 +16,7 @@ package linux
 
 import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/hostarch"
@@ -27,6 +28,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/mm"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/usermem"
+	"gvisor.dev/gvisor/pkg/waiter"
 )
 
 // IoSetup implements linux syscall io_setup(2).
@@ -95,14 +97,52 @@ func IoGetevents(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uin
 	eventsAddr := args[3].Pointer()
 	timespecAddr := args[4].Pointer()
 
+	n, err := getEvents(t, id, minEvents, events, eventsAddr, timespecAddr)
+	return n, nil, err
+}
+
+// IoPgetevents implements linux syscall io_pgetevents(2).
+func IoPgetevents(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	id := args[0].Uint64()
+	minEvents := args[1].Int()
+	events := args[2].Int()
+	eventsAddr := args[3].Pointer()
+	timespecAddr := args[4].Pointer()
+	maskWithSizeAddr := args[5].Pointer()
+
+	// The last argument is a struct __aio_sigset, which has the same layout
+	// as pselect6(2)'s sigmask argument.
+	if maskWithSizeAddr != 0 {
+		var maskStruct sigSetWithSize
+		if _, err := maskStruct.CopyIn(t, maskWithSizeAddr); err != nil {
+			return 0, nil, err
+		}
+		if err := setTempSignalSet(t, hostarch.Addr(maskStruct.sigsetAddr), uint(maskStruct.sizeofSigset)); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	n, err := getEvents(t, id, minEvents, events, eventsAddr, timespecAddr)
+	// Like pselect6(2), io_pgetevents(2) is restarted only if no signal
+	// handler was run; this allows the temporary signal mask to be used to
+	// wait for signals.
+	if linuxerr.Equals(linuxerr.EINTR, err) {
+		err = linuxerr.ERESTARTNOHAND
+	}
+	return n, nil, err
+}
+
+// getEvents implements the common parts of io_getevents(2) and
+// io_pgetevents(2).
+func getEvents(t *kernel.Task, id uint64, minEvents, events int32, eventsAddr, timespecAddr hostarch.Addr) (uintptr, error) {
 	// Sanity check arguments.
 	if minEvents < 0 || minEvents > events {
-		return 0, nil, linuxerr.EINVAL
+		return 0, linuxerr.EINVAL
 	}
 
 	ctx, ok := t.MemoryManager().LookupAIOContext(t, id)
 	if !ok {
-		return 0, nil, linuxerr.EINVAL
+		return 0, linuxerr.EINVAL
 	}
 
 	// Setup the timeout.
@@ -111,10 +151,10 @@ func IoGetevents(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uin
 	if timespecAddr != 0 {
 		d, err := copyTimespecIn(t, timespecAddr)
 		if err != nil {
-			return 0, nil, err
+			return 0, err
 		}
 		if !d.Valid() {
-			return 0, nil, linuxerr.EINVAL
+			return 0, linuxerr.EINVAL
 		}
 		deadline = t.Kernel().MonotonicClock().Now().Add(d.ToDuration())
 		haveDeadline = true
@@ -128,16 +168,16 @@ func IoGetevents(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uin
 			var ok bool
 			v, ok = ctx.PopRequest()
 			if !ok {
-				return uintptr(count), nil, nil
+				return uintptr(count), nil
 			}
 		} else {
 			var err error
 			v, err = waitForRequest(ctx, t, haveDeadline, deadline)
 			if err != nil {
 				if count > 0 || linuxerr.Equals(linuxerr.ETIMEDOUT, err) {
-					return uintptr(count), nil, nil
+					return uintptr(count), nil
 				}
-				return 0, nil, linuxerr.ConvertIntr(err, linuxerr.EINTR)
+				return 0, linuxerr.ConvertIntr(err, linuxerr.EINTR)
 			}
 		}
 
@@ -146,10 +186,10 @@ func IoGetevents(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uin
 		// Copy out the result.
 		if _, err := ev.CopyOut(t, eventsAddr); err != nil {
 			if count > 0 {
-				return uintptr(count), nil, nil
+				return uintptr(count), nil
 			}
 			// Nothing done.
-			return 0, nil, err
+			return 0, err
 		}
 
 		// Keep rolling.
@@ -157,7 +197,7 @@ func IoGetevents(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uin
 	}
 
 	// Everything finished.
-	return uintptr(events), nil, nil
+	return uintptr(events), nil
 }
 
 func waitForRequest(ctx *mm.AIOContext, t *kernel.Task, haveDeadline bool, deadline ktime.Time) (any, error) {
@@ -201,7 +241,7 @@ func memoryFor(t *kernel.Task, cb *linux.IOCallback) (usermem.IOSequence, error)
 			AddressSpaceActive: false,
 		})
 
-	case linux.IOCB_CMD_FSYNC, linux.IOCB_CMD_FDSYNC, linux.IOCB_CMD_NOOP:
+	case linux.IOCB_CMD_FSYNC, linux.IOCB_CMD_FDSYNC, linux.IOCB_CMD_POLL, linux.IOCB_CMD_NOOP:
 		return usermem.IOSequence{}, nil
 
 	default:
@@ -212,10 +252,30 @@ func memoryFor(t *kernel.Task, cb *linux.IOCallback) (usermem.IOSequence, error)
 
 // IoCancel implements linux syscall io_cancel(2).
 //
-// It is not presently supported (ENOSYS indicates no support on this
-// architecture).
+// As in Linux, only IOCB_CMD_POLL requests can be cancelled. The result
+// argument is unused; the completion event for a cancelled request is always
+// delivered through the completion queue.
 func IoCancel(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
-	return 0, nil, linuxerr.ENOSYS
+	id := args[0].Uint64()
+	cbAddr := args[1].Pointer()
+
+	var cb linux.IOCallback
+	if _, err := cb.CopyIn(t, cbAddr); err != nil {
+		return 0, nil, err
+	}
+	if cb.Key != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	aioCtx, ok := t.MemoryManager().LookupAIOContext(t, id)
+	if !ok {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if err := aioCtx.CancelRequest(uint64(cbAddr)); err != nil {
+		return 0, nil, err
+	}
+	// Cancellation is in progress; see Linux's fs/aio.c:io_cancel().
+	return 0, nil, linuxerr.EINPROGRESS
 }
 
 // IoSubmit implements linux syscall io_submit(2).
@@ -313,6 +373,12 @@ func submitCallback(t *kernel.Task, id uint64, cb *linux.IOCallback, cbAddr host
 		if cb.Offset < 0 {
 			return linuxerr.EINVAL
 		}
+	case linux.IOCB_CMD_POLL:
+		// Reject events that don't fit in a 16-bit poll mask, and fields
+		// that are not defined for poll.
+		if uint64(uint16(cb.Buf)) != cb.Buf || cb.Offset != 0 || cb.Bytes != 0 || cb.RWFlags != 0 {
+			return linuxerr.EINVAL
+		}
 	}
 
 	// Prepare the request.
@@ -334,6 +400,9 @@ func submitCallback(t *kernel.Task, id uint64, cb *linux.IOCallback, cbAddr host
 
 	// Perform the request asynchronously.
 	fd.IncRef()
+	if cb.OpCode == linux.IOCB_CMD_POLL {
+		return submitPoll(t, fd, eventFD, cbAddr, cb, aioCtx)
+	}
 	t.QueueAIO(getAIOCallback(t, fd, eventFD, cbAddr, cb, ioseq, aioCtx))
 	return nil
 }
@@ -382,3 +451,132 @@ func getAIOCallback(t *kernel.Task, fd, eventFD *vfs.FileDescription, cbAddr hos
 		}
 	}
 }
+
+// Possible values for aioPollRequest.state.
+const (
+	// aioPollArmed indicates that the request is waiting for a notification.
+	aioPollArmed = iota
+
+	// aioPollWoken indicates that a completion attempt has been queued.
+	aioPollWoken
+)
+
+// aioPollRequest is a pending IOCB_CMD_POLL request. Like Linux's
+// fs/aio.c:aio_poll(), it is a one-shot poll that completes with the ready
+// events, or with no events if it is cancelled.
+//
+// +stateify savable
+type aioPollRequest struct {
+	// t is the task that submitted the request.
+	t *kernel.Task
+
+	// fd is the polled file, and eventFD is the eventfd to signal on
+	// completion, if any. The request holds a reference on both.
+	fd      *vfs.FileDescription
+	eventFD *vfs.FileDescription
+
+	aioCtx *mm.AIOContext
+	cbAddr hostarch.Addr
+	data   uint64
+
+	// mask is the set of events to poll for. It always includes EventErr and
+	// EventHUp.
+	mask waiter.EventMask
+
+	// entry is registered with fd while the request is pending.
+	entry waiter.Entry
+
+	// state is aioPollArmed or aioPollWoken.
+	state atomicbitops.Int32
+
+	// cancelled is set by Cancel.
+	cancelled atomicbitops.Bool
+}
+
+// submitPoll starts an IOCB_CMD_POLL request. It takes ownership of the
+// references on fd and eventFD, and of the request reserved in aioCtx.
+func submitPoll(t *kernel.Task, fd, eventFD *vfs.FileDescription, cbAddr hostarch.Addr, cb *linux.IOCallback, aioCtx *mm.AIOContext) error {
+	r := &aioPollRequest{
+		t:       t,
+		fd:      fd,
+		eventFD: eventFD,
+		aioCtx:  aioCtx,
+		cbAddr:  cbAddr,
+		data:    cb.Data,
+		mask:    waiter.EventMaskFromLinux(uint32(cb.Buf)) | waiter.EventErr | waiter.EventHUp,
+	}
+	r.entry.Init(r, r.mask)
+	if err := fd.EventRegister(&r.entry); err != nil {
+		r.release(t)
+		aioCtx.CancelPendingRequest()
+		return err
+	}
+	aioCtx.AddCancelable(uint64(cbAddr), r)
+
+	// The file may already be ready; check asynchronously so that the
+	// request completes as if it had been notified.
+	r.state.Store(aioPollWoken)
+	t.QueueAIO(r.complete)
+	return nil
+}
+
+// NotifyEvent implements waiter.EventListener.NotifyEvent.
+func (r *aioPollRequest) NotifyEvent(waiter.EventMask) {
+	// The waiter queue is locked, so completion (which unregisters r.entry)
+	// must happen elsewhere.
+	if r.state.CompareAndSwap(aioPollArmed, aioPollWoken) {
+		r.t.QueueAIO(r.complete)
+	}
+}
+
+// Cancel implements mm.AIOCancelable.Cancel.
+func (r *aioPollRequest) Cancel() {
+	r.cancelled.Store(true)
+	r.NotifyEvent(0)
+}
+
+// complete finishes the request if r.fd is ready or the request has been
+// cancelled, and otherwise rearms it.
+func (r *aioPollRequest) complete(ctx context.Context) {
+	ready := r.fd.Readiness(r.mask) & r.mask
+	for ready == 0 && !r.cancelled.Load() {
+		// Spurious wakeup. Rearm, then check again for a notification or
+		// cancellation that raced with the rearm.
+		r.state.Store(aioPollArmed)
+		ready = r.fd.Readiness(r.mask) & r.mask
+		if ready == 0 && !r.cancelled.Load() {
+			return
+		}
+		if !r.state.CompareAndSwap(aioPollArmed, aioPollWoken) {
+			// NotifyEvent has already queued another completion attempt.
+			return
+		}
+	}
+
+	r.fd.EventUnregister(&r.entry)
+	r.aioCtx.RemoveCancelable(uint64(r.cbAddr), r)
+	defer r.release(ctx)
+
+	if r.aioCtx.Dead() {
+		r.aioCtx.CancelPendingRequest()
+		return
+	}
+	r.aioCtx.FinishRequest(&linux.IOEvent{
+		Data:   r.data,
+		Obj:    uint64(r.cbAddr),
+		Result: int64(ready.ToLinux()),
+	})
+
+	// As in getAIOCallback, notify the event file after queueing the result.
+	if r.eventFD != nil {
+		r.eventFD.Impl().(*eventfd.EventFileDescription).Signal(1)
+	}
+}
+
+// release drops the request's file references.
+func (r *aioPollRequest) release(ctx context.Context) {
+	r.fd.DecRef(ctx)
+	if r.eventFD != nil {
+		r.eventFD.DecRef(ctx)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/marshal/primitive"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// NameToHandleAt implements Linux syscall name_to_handle_at(2).
+func NameToHandleAt(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	dirfd := args[0].Int()
+	pathAddr := args[1].Pointer()
+	handleAddr := args[2].Pointer()
+	mountIDAddr := args[3].Pointer()
+	flags := args[4].Int()
+
+	if flags&^(linux.AT_SYMLINK_FOLLOW|linux.AT_EMPTY_PATH) != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	var fh linux.FileHandle
+	if _, err := fh.CopyIn(t, handleAddr); err != nil {
+		return 0, nil, err
+	}
+	if fh.HandleBytes > linux.MAX_HANDLE_SZ {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	path, err := copyInPath(t, pathAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+	tpop, err := getTaskPathOperation(t, dirfd, path, shouldAllowEmptyPath(flags&linux.AT_EMPTY_PATH != 0), shouldFollowFinalSymlink(flags&linux.AT_SYMLINK_FOLLOW != 0))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tpop.Release(t)
+	vd, err := t.Kernel().VFS().GetDentryAt(t, t.Credentials(), &tpop.pop, &vfs.GetDentryOptions{})
+	if err != nil {
+		return 0, nil, err
+	}
+	defer vd.DecRef(t)
+
+	handleType, handle, err := t.Kernel().VFS().EncodeFileHandle(t, vd)
+	if err != nil {
+		return 0, nil, err
+	}
+	mountID := primitive.Int32(vd.Mount().ID)
+	if _, err := mountID.CopyOut(t, mountIDAddr); err != nil {
+		return 0, nil, err
+	}
+	// If the handle doesn't fit, only tell the caller how large it is.
+	tooSmall := uint32(len(handle)) > fh.HandleBytes
+	fh.HandleBytes = uint32(len(handle))
+	fh.HandleType = handleType
+	if _, err := fh.CopyOut(t, handleAddr); err != nil {
+		return 0, nil, err
+	}
+	if tooSmall {
+		return 0, nil, linuxerr.EOVERFLOW
+	}
+	if _, err := t.CopyOutBytes(handleAddr+hostarch.Addr(fh.SizeBytes()), handle); err != nil {
+		return 0, nil, err
+	}
+	return 0, nil, nil
+}
+
+// OpenByHandleAt implements Linux syscall open_by_handle_at(2).
+func OpenByHandleAt(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	mountFD := args[0].Int()
+	handleAddr := args[1].Pointer()
+	flags := args[2].Uint()
+
+	// Like Linux, require CAP_DAC_READ_SEARCH in the root user namespace,
+	// since file handles bypass path permission checks.
+	creds := t.Credentials()
+	if !t.CheckCapabilityIn(linux.CAP_DAC_READ_SEARCH, t.Kernel().RootUserNamespace()) {
+		return 0, nil, linuxerr.EPERM
+	}
+	var fh linux.FileHandle
+	if _, err := fh.CopyIn(t, handleAddr); err != nil {
+		return 0, nil, err
+	}
+	if fh.HandleBytes == 0 || fh.HandleBytes > linux.MAX_HANDLE_SZ {
+		return 0, nil, linuxerr.EINVAL
+	}
+	handle := make([]byte, fh.HandleBytes)
+	if _, err := t.CopyInBytes(handleAddr+hostarch.Addr(fh.SizeBytes()), handle); err != nil {
+		return 0, nil, err
+	}
+
+	var mnt *vfs.Mount
+	if mountFD == linux.AT_FDCWD {
+		wd := t.FSContext().WorkingDirectory()
+		defer wd.DecRef(t)
+		mnt = wd.Mount()
+	} else {
+		file := t.GetFile(mountFD)
+		if file == nil {
+			return 0, nil, linuxerr.EBADF
+		}
+		defer file.DecRef(t)
+		mnt = file.Mount()
+	}
+	vd, err := t.Kernel().VFS().DecodeFileHandle(t, creds, mnt, fh.HandleType, handle)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer vd.DecRef(t)
+
+	file, err := t.Kernel().VFS().OpenAt(t, creds, &vfs.PathOperation{
+		Root:  vd,
+		Start: vd,
+	}, &vfs.OpenOptions{
+		Flags: (flags | linux.O_LARGEFILE) &^ linux.O_CREAT,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+
+	fd, err := t.NewFDFrom(0, file, kernel.FDFlags{
+		CloseOnExec: flags&linux.O_CLOEXEC != 0,
+	})
+	return uintptr(fd), nil, err
+}
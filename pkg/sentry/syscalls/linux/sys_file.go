@@ -24,6 +24,7 @@ import (
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/host"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/lock"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/tmpfs"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
@@ -67,8 +68,10 @@ func mknodat(t *kernel.Task, dirfd int32, addr hostarch.Addr, mode linux.FileMod
 		mode |= linux.ModeRegular
 	}
 	major, minor := linux.DecodeDeviceID(dev)
+	umask := linux.FileMode(t.FSContext().Umask())
 	return t.Kernel().VFS().MknodAt(t, t.Credentials(), &tpop.pop, &vfs.MknodOptions{
-		Mode:     mode &^ linux.FileMode(t.FSContext().Umask()),
+		Mode:     mode &^ umask,
+		Umasked:  mode.Permissions() & umask,
 		DevMajor: uint32(major),
 		DevMinor: minor,
 	})
@@ -109,9 +112,11 @@ func openat(t *kernel.Task, dirfd int32, pathAddr hostarch.Addr, flags uint32, m
 	}
 	defer tpop.Release(t)
 
+	umask := uint(t.FSContext().Umask())
 	file, err := t.Kernel().VFS().OpenAt(t, t.Credentials(), &tpop.pop, &vfs.OpenOptions{
-		Flags: flags | linux.O_LARGEFILE,
-		Mode:  linux.FileMode(mode & (0777 | linux.S_ISUID | linux.S_ISGID | linux.S_ISVTX) &^ t.FSContext().Umask()),
+		Flags:   flags | linux.O_LARGEFILE,
+		Mode:    linux.FileMode(mode & (0777 | linux.S_ISUID | linux.S_ISGID | linux.S_ISVTX) &^ umask),
+		Umasked: linux.FileMode(mode & 0777 & umask),
 	})
 	if err != nil {
 		return 0, nil, err
@@ -213,7 +218,7 @@ func Ioctl(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 	}
 
 	// Handle ioctls that apply to all FDs.
-	switch args[1].Int() {
+	switch args[1].Uint() {
 	case linux.FIONCLEX:
 		t.FDTable().SetFlags(t, fd, kernel.FDFlags{
 			CloseOnExec: false,
@@ -281,6 +286,20 @@ func Ioctl(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 			who = -who
 		}
 		return 0, nil, setAsyncOwner(t, int(fd), file, ownerType, who)
+
+	case linux.FIFREEZE:
+		// Compare Linux's fs/ioctl.c:ioctl_fsfreeze().
+		if !t.CheckCapability(linux.CAP_SYS_ADMIN) {
+			return 0, nil, linuxerr.EPERM
+		}
+		return 0, nil, file.Mount().Filesystem().Freeze(t)
+
+	case linux.FITHAW:
+		// Compare Linux's fs/ioctl.c:ioctl_fsthaw().
+		if !t.CheckCapability(linux.CAP_SYS_ADMIN) {
+			return 0, nil, linuxerr.EPERM
+		}
+		return 0, nil, file.Mount().Filesystem().Thaw()
 	}
 
 	ret, err := file.Ioctl(t, t.MemoryManager(), sysno, args)
@@ -369,7 +388,7 @@ func Fchdir(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 func Chroot(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
 	addr := args[0].Pointer()
 
-	if !t.HasCapability(linux.CAP_SYS_CHROOT) {
+	if !t.CheckCapability(linux.CAP_SYS_CHROOT) {
 		return 0, nil, linuxerr.EPERM
 	}
 
@@ -399,7 +418,7 @@ func PivotRoot(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintp
 	addr1 := args[0].Pointer()
 	addr2 := args[1].Pointer()
 
-	if !t.HasCapability(linux.CAP_SYS_ADMIN) {
+	if !t.CheckCapability(linux.CAP_SYS_ADMIN) {
 		return 0, nil, linuxerr.EPERM
 	}
 
@@ -947,8 +966,10 @@ func mkdirat(t *kernel.Task, dirfd int32, addr hostarch.Addr, mode uint) error {
 		return err
 	}
 	defer tpop.Release(t)
+	umask := uint(t.FSContext().Umask())
 	return t.Kernel().VFS().MkdirAt(t, t.Credentials(), &tpop.pop, &vfs.MkdirOptions{
-		Mode: linux.FileMode(mode & (0777 | linux.S_ISVTX) &^ t.FSContext().Umask()),
+		Mode:    linux.FileMode(mode & (0777 | linux.S_ISVTX) &^ umask),
+		Umasked: linux.FileMode(mode & 0777 & umask),
 	})
 }
 
@@ -1667,7 +1688,7 @@ func Flock(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 const (
 	memfdPrefix     = "memfd:"
 	memfdMaxNameLen = linux.NAME_MAX - len(memfdPrefix)
-	memfdAllFlags   = uint32(linux.MFD_CLOEXEC | linux.MFD_ALLOW_SEALING)
+	memfdAllFlags   = uint32(linux.MFD_CLOEXEC | linux.MFD_ALLOW_SEALING | linux.MFD_NOEXEC_SEAL | linux.MFD_EXEC)
 )
 
 // MemfdCreate implements the linux syscall memfd_create(2).
@@ -1680,7 +1701,27 @@ func MemfdCreate(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uin
 		return 0, nil, linuxerr.EINVAL
 	}
 
+	if flags&linux.MFD_NOEXEC_SEAL != 0 && flags&linux.MFD_EXEC != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	// Apply the default from /proc/sys/vm/memfd_noexec if neither
+	// MFD_NOEXEC_SEAL nor MFD_EXEC is specified. Compare Linux's
+	// mm/memfd.c:check_sysctl_memfd_noexec().
+	scope := t.Kernel().MemfdNoexecScope.Load()
+	if flags&(linux.MFD_NOEXEC_SEAL|linux.MFD_EXEC) == 0 {
+		if scope >= linux.MEMFD_NOEXEC_SCOPE_NOEXEC_SEAL {
+			flags |= linux.MFD_NOEXEC_SEAL
+		} else {
+			flags |= linux.MFD_EXEC
+		}
+	}
+	if flags&linux.MFD_EXEC != 0 && scope >= linux.MEMFD_NOEXEC_SCOPE_NOEXEC_ENFORCED {
+		return 0, nil, linuxerr.EACCES
+	}
+
 	allowSeals := flags&linux.MFD_ALLOW_SEALING != 0
+	noExec := flags&linux.MFD_NOEXEC_SEAL != 0
 	cloExec := flags&linux.MFD_CLOEXEC != 0
 
 	name, err := t.CopyInString(addr, memfdMaxNameLen)
@@ -1688,8 +1729,14 @@ func MemfdCreate(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uin
 		return 0, nil, err
 	}
 
-	shmMount := t.Kernel().ShmMount()
-	file, err := tmpfs.NewMemfd(t, t.Credentials(), shmMount, allowSeals, memfdPrefix+name)
+	var file *vfs.FileDescription
+	if kernel.HostMemfdEnabled {
+		// Host memfds don't support seals, so allowSeals and noExec are
+		// ignored.
+		file, err = host.NewMemfd(t, t.Kernel().HostMount(), name)
+	} else {
+		file, err = tmpfs.NewMemfd(t, t.Credentials(), t.Kernel().ShmMount(), allowSeals, noExec, memfdPrefix+name)
+	}
 	if err != nil {
 		return 0, nil, err
 	}
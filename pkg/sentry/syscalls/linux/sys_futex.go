@@ -15,6 +15,7 @@
 package linux
 
 import (
+	"math"
 	"time"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
@@ -22,6 +23,7 @@ import (
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/futex"
 	ktime "gvisor.dev/gvisor/pkg/sentry/kernel/time"
 )
 
@@ -117,7 +119,13 @@ func futexWaitDuration(t *kernel.Task, duration time.Duration, forever bool, add
 	return 0, linuxerr.ERESTART_RESTARTBLOCK
 }
 
-func futexLockPI(t *kernel.Task, ts linux.Timespec, forever bool, addr hostarch.Addr, private bool) error {
+// futexLockPI performs a FUTEX_LOCK_PI or FUTEX_LOCK_PI2, blocking until the
+// futex is acquired.
+//
+// The wait blocks forever if forever is true, otherwise it blocks until ts,
+// measured by CLOCK_REALTIME if clockRealtime is true and CLOCK_MONOTONIC
+// otherwise.
+func futexLockPI(t *kernel.Task, clockRealtime bool, ts linux.Timespec, forever bool, addr hostarch.Addr, private bool) error {
 	w := t.FutexWaiter()
 	locked, err := t.Futex().LockPI(w, t, addr, uint32(t.ThreadID()), private, false)
 	if err != nil {
@@ -130,8 +138,10 @@ func futexLockPI(t *kernel.Task, ts linux.Timespec, forever bool, addr hostarch.
 
 	if forever {
 		err = t.Block(w.C)
-	} else {
+	} else if clockRealtime {
 		err = t.BlockWithDeadlineFrom(w.C, t.Kernel().RealtimeClock(), true, ktime.FromTimespec(ts))
+	} else {
+		err = t.BlockWithDeadline(w.C, true, ktime.FromTimespec(ts))
 	}
 
 	t.Futex().WaitComplete(w, t)
@@ -241,7 +251,7 @@ func Futex(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 		n, err := t.Futex().WakeOp(t, addr, naddr, private, val, nreq, op)
 		return uintptr(n), nil, err
 
-	case linux.FUTEX_LOCK_PI:
+	case linux.FUTEX_LOCK_PI, linux.FUTEX_LOCK_PI2:
 		forever := (timeout == 0)
 
 		var timespec linux.Timespec
@@ -252,7 +262,12 @@ func Futex(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 				return 0, nil, err
 			}
 		}
-		err := futexLockPI(t, timespec, forever, addr, private)
+		// LOCK_PI always uses CLOCK_REALTIME. LOCK_PI2 uses CLOCK_MONOTONIC
+		// unless FUTEX_CLOCK_REALTIME is set.
+		if cmd == linux.FUTEX_LOCK_PI {
+			clockRealtime = true
+		}
+		err := futexLockPI(t, clockRealtime, timespec, forever, addr, private)
 		return 0, nil, err
 
 	case linux.FUTEX_TRYLOCK_PI:
@@ -273,6 +288,88 @@ func Futex(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 	}
 }
 
+// FutexWaitv implements linux syscall futex_waitv(2).
+func FutexWaitv(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	waitersAddr := args[0].Pointer()
+	nrFutexes := args[1].Uint()
+	flags := args[2].Uint()
+	timeout := args[3].Pointer()
+	clockID := args[4].Int()
+
+	// No flags are currently defined.
+	if flags != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if waitersAddr == 0 || nrFutexes == 0 || nrFutexes > linux.FUTEX_WAITV_MAX {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	// The timeout is absolute, and measured by the given clock.
+	forever := timeout == 0
+	var (
+		clock    ktime.Clock
+		timespec linux.Timespec
+	)
+	if !forever {
+		switch clockID {
+		case linux.CLOCK_MONOTONIC:
+			clock = t.Kernel().MonotonicClock()
+		case linux.CLOCK_REALTIME:
+			clock = t.Kernel().RealtimeClock()
+		default:
+			return 0, nil, linuxerr.EINVAL
+		}
+		var err error
+		timespec, err = copyTimespecIn(t, timeout)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !timespec.Valid() {
+			return 0, nil, linuxerr.EINVAL
+		}
+	}
+
+	waiters := make([]linux.FutexWaitv, nrFutexes)
+	if _, err := linux.CopyFutexWaitvSliceIn(t, waitersAddr, waiters); err != nil {
+		return 0, nil, err
+	}
+	fs := make([]futex.WaitvFutex, nrFutexes)
+	for i, w := range waiters {
+		// Only 32-bit futexes are supported, as in Linux.
+		if w.Reserved != 0 || w.Flags&^(linux.FUTEX2_SIZE_MASK|linux.FUTEX2_PRIVATE) != 0 ||
+			w.Flags&linux.FUTEX2_SIZE_MASK != linux.FUTEX2_SIZE_U32 || w.Val > math.MaxUint32 {
+			return 0, nil, linuxerr.EINVAL
+		}
+		fs[i] = futex.WaitvFutex{
+			Addr:    hostarch.Addr(w.Uaddr),
+			Private: w.Flags&linux.FUTEX2_PRIVATE != 0,
+			Val:     uint32(w.Val),
+		}
+	}
+
+	mw := futex.NewMultiWaiter(len(fs))
+	woken, err := t.Futex().WaitMultiplePrepare(mw, t, fs)
+	if err != nil {
+		return 0, nil, err
+	}
+	if woken >= 0 {
+		return uintptr(woken), nil, nil
+	}
+
+	if forever {
+		err = t.Block(mw.C)
+	} else {
+		err = t.BlockWithDeadlineFrom(mw.C, clock, true, ktime.FromTimespec(timespec))
+	}
+
+	// A futex may have been woken even if the wait timed out or was
+	// interrupted; report it in preference to the error, as Linux does.
+	if woken := t.Futex().WaitMultipleComplete(mw, t); woken >= 0 {
+		return uintptr(woken), nil, nil
+	}
+	return 0, nil, linuxerr.ConvertIntr(err, linuxerr.ERESTARTSYS)
+}
+
 // SetRobustList implements linux syscall set_robust_list(2).
 func SetRobustList(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
 	// Despite the syscall using the name 'pid' for this variable, it is
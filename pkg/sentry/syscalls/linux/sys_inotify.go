@@ -115,8 +115,17 @@ func InotifyAddWatch(t *kernel.Task, sysno uintptr, args arch.SyscallArguments)
 		return 0, nil, err
 	}
 	defer d.DecRef(t)
+	stat, err := t.Kernel().VFS().StatAt(t, t.Credentials(), &vfs.PathOperation{
+		Root:  d,
+		Start: d,
+	}, &vfs.StatOptions{
+		Mask: linux.STATX_INO,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
 
-	return uintptr(ino.AddWatch(d.Dentry(), mask)), nil, nil
+	return uintptr(ino.AddWatch(d.Dentry(), mask, &stat)), nil, nil
 }
 
 // InotifyRmWatch implements the inotify_rm_watch() syscall.
@@ -31,47 +31,77 @@ func Membarrier(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uint
 		if flags != 0 {
 			return 0, nil, linuxerr.EINVAL
 		}
-		var supportedCommands uintptr
-		if t.Kernel().Platform.HaveGlobalMemoryBarrier() {
-			supportedCommands |= linux.MEMBARRIER_CMD_GLOBAL |
-				linux.MEMBARRIER_CMD_GLOBAL_EXPEDITED |
-				linux.MEMBARRIER_CMD_REGISTER_GLOBAL_EXPEDITED |
-				linux.MEMBARRIER_CMD_PRIVATE_EXPEDITED |
-				linux.MEMBARRIER_CMD_REGISTER_PRIVATE_EXPEDITED
-		}
+		supportedCommands := uintptr(linux.MEMBARRIER_CMD_GLOBAL |
+			linux.MEMBARRIER_CMD_GLOBAL_EXPEDITED |
+			linux.MEMBARRIER_CMD_REGISTER_GLOBAL_EXPEDITED |
+			linux.MEMBARRIER_CMD_PRIVATE_EXPEDITED |
+			linux.MEMBARRIER_CMD_REGISTER_PRIVATE_EXPEDITED |
+			linux.MEMBARRIER_CMD_PRIVATE_EXPEDITED_SYNC_CORE |
+			linux.MEMBARRIER_CMD_REGISTER_PRIVATE_EXPEDITED_SYNC_CORE)
 		if t.RSeqAvailable() {
 			supportedCommands |= linux.MEMBARRIER_CMD_PRIVATE_EXPEDITED_RSEQ |
 				linux.MEMBARRIER_CMD_REGISTER_PRIVATE_EXPEDITED_RSEQ
 		}
 		return supportedCommands, nil, nil
-	case linux.MEMBARRIER_CMD_GLOBAL, linux.MEMBARRIER_CMD_GLOBAL_EXPEDITED, linux.MEMBARRIER_CMD_PRIVATE_EXPEDITED:
+	case linux.MEMBARRIER_CMD_GLOBAL:
 		if flags != 0 {
 			return 0, nil, linuxerr.EINVAL
 		}
-		if !t.Kernel().Platform.HaveGlobalMemoryBarrier() {
+		if t.Kernel().Platform.HaveGlobalMemoryBarrier() {
+			return 0, nil, t.Kernel().Platform.GlobalMemoryBarrier()
+		}
+		t.MembarrierGlobal()
+		return 0, nil, nil
+	case linux.MEMBARRIER_CMD_GLOBAL_EXPEDITED:
+		if flags != 0 {
 			return 0, nil, linuxerr.EINVAL
 		}
-		if cmd == linux.MEMBARRIER_CMD_PRIVATE_EXPEDITED && !t.MemoryManager().IsMembarrierPrivateEnabled() {
-			return 0, nil, linuxerr.EPERM
+		if t.Kernel().Platform.HaveGlobalMemoryBarrier() {
+			return 0, nil, t.Kernel().Platform.GlobalMemoryBarrier()
 		}
-		return 0, nil, t.Kernel().Platform.GlobalMemoryBarrier()
+		t.MembarrierGlobalExpedited()
+		return 0, nil, nil
 	case linux.MEMBARRIER_CMD_REGISTER_GLOBAL_EXPEDITED:
 		if flags != 0 {
 			return 0, nil, linuxerr.EINVAL
 		}
-		if !t.Kernel().Platform.HaveGlobalMemoryBarrier() {
+		t.MemoryManager().EnableMembarrierGlobal()
+		return 0, nil, nil
+	case linux.MEMBARRIER_CMD_PRIVATE_EXPEDITED:
+		if flags != 0 {
 			return 0, nil, linuxerr.EINVAL
 		}
-		// no-op
+		if !t.MemoryManager().IsMembarrierPrivateEnabled() {
+			return 0, nil, linuxerr.EPERM
+		}
+		if t.Kernel().Platform.HaveGlobalMemoryBarrier() {
+			return 0, nil, t.Kernel().Platform.GlobalMemoryBarrier()
+		}
+		t.MembarrierPrivateExpedited()
 		return 0, nil, nil
 	case linux.MEMBARRIER_CMD_REGISTER_PRIVATE_EXPEDITED:
 		if flags != 0 {
 			return 0, nil, linuxerr.EINVAL
 		}
-		if !t.Kernel().Platform.HaveGlobalMemoryBarrier() {
+		t.MemoryManager().EnableMembarrierPrivate()
+		return 0, nil, nil
+	case linux.MEMBARRIER_CMD_PRIVATE_EXPEDITED_SYNC_CORE:
+		if flags != 0 {
 			return 0, nil, linuxerr.EINVAL
 		}
-		t.MemoryManager().EnableMembarrierPrivate()
+		if !t.MemoryManager().IsMembarrierSyncCoreEnabled() {
+			return 0, nil, linuxerr.EPERM
+		}
+		// A global memory barrier does not serialize instruction fetch on
+		// other cores, so interrupt every task that may be executing
+		// application code in this address space.
+		t.MembarrierPrivateExpedited()
+		return 0, nil, nil
+	case linux.MEMBARRIER_CMD_REGISTER_PRIVATE_EXPEDITED_SYNC_CORE:
+		if flags != 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+		t.MemoryManager().EnableMembarrierSyncCore()
 		return 0, nil, nil
 	case linux.MEMBARRIER_CMD_PRIVATE_EXPEDITED_RSEQ:
 		if flags&^linux.MEMBARRIER_CMD_FLAG_CPU != 0 {
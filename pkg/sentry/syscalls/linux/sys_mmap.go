@@ -46,9 +46,19 @@ func Mmap(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *
 	shared := flags&linux.MAP_SHARED != 0
 	anon := flags&linux.MAP_ANONYMOUS != 0
 	map32bit := flags&linux.MAP_32BIT != 0
-
-	// Require exactly one of MAP_PRIVATE and MAP_SHARED.
-	if private == shared {
+	droppable := flags&linux.MAP_TYPE == linux.MAP_DROPPABLE
+
+	if droppable {
+		// MAP_DROPPABLE mappings are private anonymous mappings that are
+		// wiped on fork. Linux may also drop their contents under memory
+		// pressure; we never do. A locked or stack mapping makes no sense
+		// to be droppable.
+		if !anon || flags&(linux.MAP_LOCKED|linux.MAP_GROWSDOWN) != 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+		private = true
+	} else if private == shared {
+		// Require exactly one of MAP_PRIVATE and MAP_SHARED.
 		return 0, nil, linuxerr.EINVAL
 	}
 
@@ -65,8 +75,9 @@ func Mmap(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *
 			Write:   linux.PROT_WRITE&prot != 0,
 			Execute: linux.PROT_EXEC&prot != 0,
 		},
-		MaxPerms:  hostarch.AnyAccess,
-		GrowsDown: linux.MAP_GROWSDOWN&flags != 0,
+		MaxPerms:   hostarch.AnyAccess,
+		GrowsDown:  linux.MAP_GROWSDOWN&flags != 0,
+		WipeOnFork: droppable,
 	}
 	if linux.MAP_POPULATE&flags != 0 {
 		opts.PlatformEffect = memmap.PlatformEffectCommit
@@ -196,14 +207,18 @@ func Madvise(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr
 		return 0, nil, t.MemoryManager().SetDontFork(addr, length, false)
 	case linux.MADV_DONTFORK:
 		return 0, nil, t.MemoryManager().SetDontFork(addr, length, true)
+	case linux.MADV_WIPEONFORK:
+		return 0, nil, t.MemoryManager().SetWipeOnFork(addr, length, true)
+	case linux.MADV_KEEPONFORK:
+		return 0, nil, t.MemoryManager().SetWipeOnFork(addr, length, false)
+	case linux.MADV_DODUMP:
+		return 0, nil, t.MemoryManager().SetDontDump(addr, length, false)
+	case linux.MADV_DONTDUMP:
+		return 0, nil, t.MemoryManager().SetDontDump(addr, length, true)
 	case linux.MADV_HUGEPAGE, linux.MADV_NOHUGEPAGE:
 		fallthrough
 	case linux.MADV_MERGEABLE, linux.MADV_UNMERGEABLE:
 		fallthrough
-	case linux.MADV_DONTDUMP, linux.MADV_DODUMP:
-		// TODO(b/72045799): Core dumping isn't implemented, so these are
-		// no-ops.
-		fallthrough
 	case linux.MADV_NORMAL, linux.MADV_RANDOM, linux.MADV_SEQUENTIAL, linux.MADV_WILLNEED:
 		// Do nothing, we totally ignore the suggestions above.
 		return 0, nil, nil
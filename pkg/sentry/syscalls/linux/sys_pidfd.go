@@ -0,0 +1,61 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/pidfd"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+)
+
+// PidfdOpen implements linux syscall pidfd_open(2).
+func PidfdOpen(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	pid := kernel.ThreadID(args[0].Int())
+	flags := args[1].Uint()
+
+	if flags&^linux.PIDFD_NONBLOCK != 0 || pid <= 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	target := t.PIDNamespace().TaskWithID(pid)
+	if target == nil {
+		return 0, nil, linuxerr.ESRCH
+	}
+	// Only thread group leaders can be referred to by pidfds.
+	tg := target.ThreadGroup()
+	if tg.Leader() != target {
+		return 0, nil, linuxerr.EINVAL
+	}
+
+	fileFlags := uint32(linux.O_RDWR)
+	if flags&linux.PIDFD_NONBLOCK != 0 {
+		fileFlags |= linux.O_NONBLOCK
+	}
+	file, err := pidfd.New(t, t.Kernel().VFS(), tg, fileFlags)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.DecRef(t)
+
+	// "The close-on-exec flag is set on the file descriptor." - pidfd_open(2)
+	fd, err := t.NewFDFrom(0, file, kernel.FDFlags{
+		CloseOnExec: true,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return uintptr(fd), nil, nil
+}
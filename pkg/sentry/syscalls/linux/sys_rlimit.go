@@ -99,9 +99,11 @@ var setableLimits = map[limits.LimitType]struct{}{
 	limits.ProcessCount: {},
 }
 
-func prlimit64(t *kernel.Task, resource limits.LimitType, newLim *limits.Limit) (limits.Limit, error) {
+// prlimit64 gets, and sets if newLim is not nil, the resource limit of target
+// on behalf of t.
+func prlimit64(t, target *kernel.Task, resource limits.LimitType, newLim *limits.Limit) (limits.Limit, error) {
 	if newLim == nil {
-		return t.ThreadGroup().Limits().Get(resource), nil
+		return target.ThreadGroup().Limits().Get(resource), nil
 	}
 
 	if _, ok := setableLimits[resource]; !ok {
@@ -120,13 +122,13 @@ func prlimit64(t *kernel.Task, resource limits.LimitType, newLim *limits.Limit)
 	// to either limit value."
 	privileged := t.HasCapabilityIn(linux.CAP_SYS_RESOURCE, t.Kernel().RootUserNamespace())
 
-	oldLim, err := t.ThreadGroup().Limits().Set(resource, *newLim, privileged)
+	oldLim, err := target.ThreadGroup().Limits().Set(resource, *newLim, privileged)
 	if err != nil {
 		return limits.Limit{}, err
 	}
 
 	if resource == limits.CPU {
-		t.NotifyRlimitCPUUpdated()
+		target.NotifyRlimitCPUUpdated()
 	}
 	return oldLim, nil
 }
@@ -143,7 +145,7 @@ func Getrlimit(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintp
 	if err != nil {
 		return 0, nil, err
 	}
-	lim, err := prlimit64(t, resource, nil)
+	lim, err := prlimit64(t, t, resource, nil)
 	if err != nil {
 		return 0, nil, err
 	}
@@ -167,7 +169,7 @@ func Setrlimit(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintp
 	if _, err := rlim.CopyIn(t, addr); err != nil {
 		return 0, nil, linuxerr.EFAULT
 	}
-	_, err = prlimit64(t, resource, rlim.toLimit())
+	_, err = prlimit64(t, t, resource, rlim.toLimit())
 	return 0, nil, err
 }
 
@@ -206,7 +208,10 @@ func Prlimit64(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintp
 	// saved set user IDs of the target process must match the real user ID of
 	// the caller and the real, effective, and saved set group IDs of the
 	// target process must match the real group ID of the caller."
-	if ot != t && !t.HasCapabilityIn(linux.CAP_SYS_RESOURCE, t.PIDNamespace().UserNamespace()) {
+	//
+	// As in Linux's kernel/sys.c:check_prlimit_permission(), the capability
+	// is checked in the user namespace of the target.
+	if ot != t && !t.HasCapabilityIn(linux.CAP_SYS_RESOURCE, ot.UserNamespace()) {
 		cred, tcred := t.Credentials(), ot.Credentials()
 		if cred.RealKUID != tcred.RealKUID ||
 			cred.RealKUID != tcred.EffectiveKUID ||
@@ -218,7 +223,7 @@ func Prlimit64(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintp
 		}
 	}
 
-	oldLim, err := prlimit64(ot, resource, newLim)
+	oldLim, err := prlimit64(t, ot, resource, newLim)
 	if err != nil {
 		return 0, nil, err
 	}
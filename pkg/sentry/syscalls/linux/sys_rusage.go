@@ -28,17 +28,17 @@ func getrusage(t *kernel.Task, which int32) linux.Rusage {
 
 	switch which {
 	case linux.RUSAGE_SELF:
-		cs = t.ThreadGroup().CPUStats()
+		cs = t.ThreadGroup().AdjustedCPUStats()
 
 	case linux.RUSAGE_CHILDREN:
 		cs = t.ThreadGroup().JoinedChildCPUStats()
 
 	case linux.RUSAGE_THREAD:
-		cs = t.CPUStats()
+		cs = t.AdjustedCPUStats()
 
 	case linux.RUSAGE_BOTH:
 		tg := t.ThreadGroup()
-		cs = tg.CPUStats()
+		cs = tg.AdjustedCPUStats()
 		cs.Accumulate(tg.JoinedChildCPUStats())
 	}
 
@@ -97,7 +97,7 @@ func Times(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 		return ticks, nil, nil
 	}
 
-	cs1 := t.ThreadGroup().CPUStats()
+	cs1 := t.ThreadGroup().AdjustedCPUStats()
 	cs2 := t.ThreadGroup().JoinedChildCPUStats()
 	r := linux.Tms{
 		UTime:  linux.ClockTFromDuration(cs1.UserTime),
@@ -15,6 +15,7 @@
 package linux
 
 import (
+	"bytes"
 	"fmt"
 	"time"
 
@@ -66,6 +67,10 @@ const flagsOffset = 48
 
 const sizeOfInt32 = 4
 
+// maxMMsgBatch is the maximum number of messages recvmmsg(2) and sendmmsg(2)
+// pass to the socket at once.
+const maxMMsgBatch = 64
+
 // messageHeader64Len is the length of a MessageHeader64 struct.
 var messageHeader64Len = uint64((*MessageHeader64)(nil).SizeBytes())
 
@@ -719,15 +724,31 @@ func RecvMMsg(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintpt
 		}
 	}
 
+	bm, batch := s.(socket.BatchMessenger)
 	var count uint32
 	var err error
-	for i := uint64(0); i < uint64(vlen); i++ {
+	for i := uint64(0); i < uint64(vlen); {
+		// Receive as many messages as possible at once, and fall back to
+		// receiving them one at a time when they can't be batched.
+		var n uint64
+		if batch {
+			n, batch, err = recvMMsgBatch(t, bm, msgPtr, i, uint64(vlen), flags, haveDeadline, deadline)
+			i += n
+			count += uint32(n)
+			if err != nil {
+				break
+			}
+			if n != 0 {
+				continue
+			}
+		}
+
 		mp, ok := msgPtr.AddLength(i * multipleMessageHeader64Len)
 		if !ok {
 			return 0, nil, linuxerr.EFAULT
 		}
-		var n uintptr
-		if n, err = recvSingleMsg(t, s, mp, flags, haveDeadline, deadline); err != nil {
+		var rn uintptr
+		if rn, err = recvSingleMsg(t, s, mp, flags, haveDeadline, deadline); err != nil {
 			break
 		}
 
@@ -736,9 +757,10 @@ func RecvMMsg(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintpt
 		if !ok {
 			return 0, nil, linuxerr.EFAULT
 		}
-		if _, err = primitive.CopyUint32Out(t, lp, uint32(n)); err != nil {
+		if _, err = primitive.CopyUint32Out(t, lp, uint32(rn)); err != nil {
 			break
 		}
+		i++
 		count++
 	}
 
@@ -748,46 +770,164 @@ func RecvMMsg(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintpt
 	return uintptr(count), nil, nil
 }
 
-func getSCMRights(t *kernel.Task, rights transport.RightsControlMessage) control.SCMRights {
+// copyInMultipleMessageHeaders copies in the mmsghdr structs of the array at
+// msgPtr from index start to index end, or to the end of the first batch of
+// maxMMsgBatch messages.
+func copyInMultipleMessageHeaders(t *kernel.Task, msgPtr hostarch.Addr, start, end uint64) ([]multipleMessageHeader64, error) {
+	if end-start > maxMMsgBatch {
+		end = start + maxMMsgBatch
+	}
+	sp, ok := msgPtr.AddLength(start * multipleMessageHeader64Len)
+	if !ok {
+		return nil, linuxerr.EFAULT
+	}
+	buf := make([]byte, (end-start)*multipleMessageHeader64Len)
+	if _, err := t.CopyInBytes(sp, buf); err != nil {
+		return nil, err
+	}
+	hdrs := make([]multipleMessageHeader64, end-start)
+	for i := range hdrs {
+		hdrs[i].UnmarshalBytes(buf[uint64(i)*multipleMessageHeader64Len:])
+	}
+	return hdrs, nil
+}
+
+// recvMMsgBatch receives the messages of the mmsghdr array at msgPtr starting
+// at index start, and ending at index end or at the first message that can't
+// be received in a batch. It returns the number of messages received, which is
+// 0 if the message at index start can't be batched, and false if s can't
+// receive batches at all.
+func recvMMsgBatch(t *kernel.Task, s socket.BatchMessenger, msgPtr hostarch.Addr, start, end uint64, flags int32, haveDeadline bool, deadline ktime.Time) (uint64, bool, error) {
+	hdrs, err := copyInMultipleMessageHeaders(t, msgPtr, start, end)
+	if err != nil {
+		return 0, true, err
+	}
+
+	// Batches don't carry control messages.
+	var dsts []usermem.IOSequence
+	senderRequested := false
+	for _, hdr := range hdrs {
+		msg := &hdr.msgHdr
+		if msg.ControlLen != 0 || msg.IovLen > linux.UIO_MAXIOV {
+			break
+		}
+		dst, err := t.IovecsIOSequence(hostarch.Addr(msg.Iov), int(msg.IovLen), usermem.IOOpts{
+			AddressSpaceActive: true,
+		})
+		if err != nil {
+			break
+		}
+		dsts = append(dsts, dst)
+		senderRequested = senderRequested || msg.NameLen != 0
+	}
+	if len(dsts) == 0 {
+		return 0, true, nil
+	}
+
+	msgs, ok, e := s.RecvMsgBatch(t, dsts, int(flags), haveDeadline, deadline, senderRequested)
+	if !ok {
+		return 0, false, nil
+	}
+	if e != nil {
+		return 0, true, linuxerr.ConvertIntr(e.ToError(), linuxerr.ERESTARTSYS)
+	}
+
+	for i, m := range msgs {
+		msg := &hdrs[i].msgHdr
+		mp := msgPtr + hostarch.Addr((start+uint64(i))*multipleMessageHeader64Len)
+
+		// Copy the address to the caller.
+		if msg.NameLen != 0 {
+			if err := writeAddress(t, m.SenderAddr, m.SenderAddrLen, hostarch.Addr(msg.Name), mp+nameLenOffset); err != nil {
+				return uint64(i), true, err
+			}
+		}
+
+		if int(msg.Flags) != m.MsgFlags {
+			// Copy out the flags to the caller.
+			if _, err := primitive.CopyInt32Out(t, mp+flagsOffset, int32(m.MsgFlags)); err != nil {
+				return uint64(i), true, err
+			}
+		}
+
+		// Copy the received length to the caller.
+		if _, err := primitive.CopyUint32Out(t, mp+hostarch.Addr(messageHeader64Len), uint32(m.N)); err != nil {
+			return uint64(i), true, err
+		}
+	}
+	return uint64(len(msgs)), true, nil
+}
+
+// getSCMRights returns rights as a control.SCMRights. If rights contains host
+// FDs, truncated is true if not all of them could be imported.
+func getSCMRights(t *kernel.Task, rights transport.RightsControlMessage) (scmRights control.SCMRights, truncated bool) {
 	switch v := rights.(type) {
 	case control.SCMRights:
-		return v
+		return v, false
 	case *transport.SCMRights:
 		rf := control.RightsFiles(fdsToHostFiles(t, v.FDs))
-		return &rf
+		truncated = len(rf) < len(v.FDs)
+		// Ownership of all host FDs has been transferred or released.
+		v.FDs = nil
+		return &rf, truncated
 	default:
 		panic(fmt.Sprintf("rights of type %T must be *transport.SCMRights or implement SCMRights", rights))
 	}
 }
 
-// If an error is encountered, only files created before the error will be
-// returned. This is what Linux does.
+// fdsToHostFiles takes ownership of fds and returns files representing them.
+//
+// If an error is encountered, or an FD isn't allowed by
+// transport.HostRightsAllowed, only files created before it will be returned,
+// and the remaining FDs are closed. This is what Linux does.
 func fdsToHostFiles(ctx context.Context, fds []int) []*vfs.FileDescription {
 	files := make([]*vfs.FileDescription, 0, len(fds))
-	for _, fd := range fds {
-		// Get flags. We do it here because they may be modified
-		// by subsequent functions.
-		fileFlags, _, errno := unix.Syscall(unix.SYS_FCNTL, uintptr(fd), unix.F_GETFL, 0)
-		if errno != 0 {
-			ctx.Warningf("Error retrieving host FD flags: %v", error(errno))
-			break
+	for i, fd := range fds {
+		if file := fdToHostFile(ctx, fd); file != nil {
+			files = append(files, file)
+			continue
 		}
-
-		// Create the file backed by hostFD.
-		file, err := host.NewFD(ctx, kernel.KernelFromContext(ctx).HostMount(), fd, &host.NewFDOptions{})
-		if err != nil {
-			ctx.Warningf("Error creating file from host FD: %v", err)
-			break
+		for _, fd := range fds[i+1:] {
+			unix.Close(fd)
 		}
+		break
+	}
+	return files
+}
 
-		if err := file.SetStatusFlags(ctx, auth.CredentialsFromContext(ctx), uint32(fileFlags&linux.O_NONBLOCK)); err != nil {
-			ctx.Warningf("Error setting flags on host FD file: %v", err)
-			break
-		}
+// fdToHostFile takes ownership of fd and returns a file representing it, or
+// nil if it can't be imported.
+func fdToHostFile(ctx context.Context, fd int) *vfs.FileDescription {
+	if !transport.HostRightsAllowed.AllowsFD(fd) {
+		ctx.Infof("Host FD received from host Unix socket is not allowed by policy")
+		unix.Close(fd)
+		return nil
+	}
+
+	// Get flags. We do it here because they may be modified
+	// by subsequent functions.
+	fileFlags, _, errno := unix.Syscall(unix.SYS_FCNTL, uintptr(fd), unix.F_GETFL, 0)
+	if errno != 0 {
+		ctx.Warningf("Error retrieving host FD flags: %v", error(errno))
+		unix.Close(fd)
+		return nil
+	}
 
-		files = append(files, file)
+	// Create the file backed by hostFD.
+	file, err := host.NewFD(ctx, kernel.KernelFromContext(ctx).HostMount(), fd, &host.NewFDOptions{})
+	if err != nil {
+		// host.NewFD closes fd on most failures, so leave it alone.
+		ctx.Warningf("Error creating file from host FD: %v", err)
+		return nil
 	}
-	return files
+
+	if err := file.SetStatusFlags(ctx, auth.CredentialsFromContext(ctx), uint32(fileFlags&linux.O_NONBLOCK)); err != nil {
+		ctx.Warningf("Error setting flags on host FD file: %v", err)
+		// Dropping the file closes fd.
+		file.DecRef(ctx)
+		return nil
+	}
+	return file
 }
 
 func recvSingleMsg(t *kernel.Task, s socket.Socket, msgPtr hostarch.Addr, flags int32, haveDeadline bool, deadline ktime.Time) (uintptr, error) {
@@ -841,13 +981,16 @@ func recvSingleMsg(t *kernel.Task, s socket.Socket, msgPtr hostarch.Addr, flags
 	controlData = control.PackControlMessages(t, cms, controlData)
 
 	if cr, ok := s.(transport.Credentialer); ok && cr.Passcred() {
-		creds, _ := cms.Unix.Credentials.(control.SCMCredentials)
-		controlData, mflags = control.PackCredentials(t, creds, controlData, mflags)
+		controlData, mflags = control.PackCredentials(t, cms.Unix.Credentials, controlData, mflags)
 	}
 
 	if cms.Unix.Rights != nil {
-		cms.Unix.Rights = getSCMRights(t, cms.Unix.Rights)
-		controlData, mflags = control.PackRights(t, cms.Unix.Rights.(control.SCMRights), flags&linux.MSG_CMSG_CLOEXEC != 0, controlData, mflags)
+		rights, truncated := getSCMRights(t, cms.Unix.Rights)
+		if truncated {
+			mflags |= linux.MSG_CTRUNC
+		}
+		cms.Unix.Rights = rights
+		controlData, mflags = control.PackRights(t, rights, flags&linux.MSG_CMSG_CLOEXEC != 0, controlData, mflags)
 	}
 
 	// Copy the address to the caller.
@@ -1024,15 +1167,28 @@ func SendMMsg(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintpt
 		flags |= linux.MSG_DONTWAIT
 	}
 
+	bm, batch := s.(socket.BatchMessenger)
 	var count uint32
 	var err error
-	for i := uint64(0); i < uint64(vlen); i++ {
+	for i := uint64(0); i < uint64(vlen); {
+		// Send as many messages as possible at once. The message a batch
+		// stops at is sent on its own, which blocks or reports its error.
+		var n uint64
+		if batch {
+			n, batch, err = sendMMsgBatch(t, bm, file, msgPtr, i, uint64(vlen), flags)
+			i += n
+			count += uint32(n)
+			if err != nil || i == uint64(vlen) {
+				break
+			}
+		}
+
 		mp, ok := msgPtr.AddLength(i * multipleMessageHeader64Len)
 		if !ok {
 			return 0, nil, linuxerr.EFAULT
 		}
-		var n uintptr
-		if n, err = sendSingleMsg(t, s, file, mp, flags); err != nil {
+		var sn uintptr
+		if sn, err = sendSingleMsg(t, s, file, mp, flags); err != nil {
 			break
 		}
 
@@ -1041,9 +1197,10 @@ func SendMMsg(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintpt
 		if !ok {
 			return 0, nil, linuxerr.EFAULT
 		}
-		if _, err = primitive.CopyUint32Out(t, lp, uint32(n)); err != nil {
+		if _, err = primitive.CopyUint32Out(t, lp, uint32(sn)); err != nil {
 			break
 		}
+		i++
 		count++
 	}
 
@@ -1053,6 +1210,69 @@ func SendMMsg(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintpt
 	return uintptr(count), nil, nil
 }
 
+// sendMMsgBatch sends without blocking the messages of the mmsghdr array at
+// msgPtr starting at index start, and ending at index end or at the first
+// message that can't be sent in the same batch. It returns the number of
+// messages sent, and false if s can't send batches at all.
+func sendMMsgBatch(t *kernel.Task, s socket.BatchMessenger, file *vfs.FileDescription, msgPtr hostarch.Addr, start, end uint64, flags int32) (uint64, bool, error) {
+	hdrs, err := copyInMultipleMessageHeaders(t, msgPtr, start, end)
+	if err != nil {
+		return 0, true, err
+	}
+
+	// Batches don't carry control messages, and all their messages go to the
+	// same destination.
+	var (
+		srcs []usermem.IOSequence
+		to   []byte
+	)
+	for i, hdr := range hdrs {
+		msg := &hdr.msgHdr
+		if msg.ControlLen != 0 || msg.IovLen > linux.UIO_MAXIOV || msg.NameLen != hdrs[0].msgHdr.NameLen {
+			break
+		}
+		if msg.NameLen != 0 {
+			addr, err := CaptureAddress(t, hostarch.Addr(msg.Name), msg.NameLen)
+			if err != nil {
+				break
+			}
+			if i == 0 {
+				to = addr
+			} else if !bytes.Equal(addr, to) {
+				break
+			}
+		}
+		src, err := t.IovecsIOSequence(hostarch.Addr(msg.Iov), int(msg.IovLen), usermem.IOOpts{
+			AddressSpaceActive: true,
+		})
+		if err != nil {
+			break
+		}
+		srcs = append(srcs, src)
+	}
+	if len(srcs) < 2 {
+		// Not worth a batch.
+		return 0, true, nil
+	}
+
+	n, ok, e := s.SendMsgBatch(t, srcs, to, int(flags))
+	if !ok {
+		return 0, false, nil
+	}
+	for i := 0; i < n; i++ {
+		// Copy the sent length to the caller.
+		lp := msgPtr + hostarch.Addr((start+uint64(i))*multipleMessageHeader64Len+messageHeader64Len)
+		if _, err := primitive.CopyUint32Out(t, lp, uint32(srcs[i].NumBytes())); err != nil {
+			return uint64(i), true, err
+		}
+	}
+	if e != nil && e != syserr.ErrWouldBlock {
+		// Report the error of the message that stopped the batch.
+		return uint64(n), true, HandleIOError(t, false, e.ToError(), linuxerr.ERESTARTSYS, "sendmmsg", file)
+	}
+	return uint64(n), true, nil
+}
+
 func sendSingleMsg(t *kernel.Task, s socket.Socket, file *vfs.FileDescription, msgPtr hostarch.Addr, flags int32) (uintptr, error) {
 	// Capture the message header.
 	var msg MessageHeader64
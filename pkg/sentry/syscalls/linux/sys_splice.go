@@ -24,6 +24,7 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/pipe"
+	"gvisor.dev/gvisor/pkg/sentry/socket"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/usermem"
 	"gvisor.dev/gvisor/pkg/waiter"
@@ -355,7 +356,13 @@ func Sendfile(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintpt
 	// block device. We only need to check if writing to the output file
 	// can block.
 	nonBlock := outFile.StatusFlags()&linux.O_NONBLOCK != 0
-	if outIsPipe {
+	spliced := false
+	if splicer, ok := outFile.Impl().(socket.FileSplicer); ok {
+		total, offset, spliced, err = sendfileToSocket(t, &dw, inFile, splicer, offset, count, nonBlock)
+	}
+	if spliced {
+		// The data was written by sendfileToSocket.
+	} else if outIsPipe {
 		for {
 			var n int64
 			n, err = outPipeFD.SpliceFromNonPipe(t, inFile, offset, count-total)
@@ -471,6 +478,50 @@ func Sendfile(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintpt
 	return uintptr(total), nil, HandleIOError(t, total != 0, err, linuxerr.ERESTARTSYS, "sendfile", inFile)
 }
 
+// sendfileToSocket implements sendfile(2) from inFile to a socket that
+// supports splicing from files, without copying data into an intermediate
+// buffer. It returns the number of bytes written and the updated offset. If
+// the socket or inFile don't support this, sendfileToSocket returns ok ==
+// false without writing anything.
+func sendfileToSocket(t *kernel.Task, dw *dualWaiter, inFile *vfs.FileDescription, splicer socket.FileSplicer, offset, count int64, nonBlock bool) (total, newOffset int64, ok bool, err error) {
+	stat, err := inFile.Stat(t, vfs.StatOptions{Mask: linux.STATX_TYPE | linux.STATX_SIZE})
+	if err != nil || stat.Mask&(linux.STATX_TYPE|linux.STATX_SIZE) != linux.STATX_TYPE|linux.STATX_SIZE || stat.Mode&linux.S_IFMT != linux.S_IFREG {
+		return 0, offset, false, nil
+	}
+
+	// If no offset was provided, the splicer reads from and advances the
+	// file offset itself.
+	for total < count {
+		var n int64
+		n, ok, err = splicer.SpliceFromFile(t, inFile, offset, count-total)
+		if !ok {
+			return 0, offset, false, nil
+		}
+		if offset != -1 {
+			offset += n
+		}
+		total += n
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if total == count {
+			break
+		}
+		if err == nil && t.Interrupted() {
+			err = linuxerr.ErrInterrupted
+			break
+		}
+		if linuxerr.Equals(linuxerr.ErrWouldBlock, err) && !nonBlock {
+			err = dw.waitForOut(t)
+		}
+		if err != nil {
+			break
+		}
+	}
+	return total, offset, true, err
+}
+
 // dualWaiter is used to wait on one or both vfs.FileDescriptions. It is not
 // thread-safe, and does not take a reference on the vfs.FileDescriptions.
 //
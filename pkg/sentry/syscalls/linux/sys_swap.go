@@ -0,0 +1,79 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+)
+
+// Swapon implements Linux syscall swapon(2).
+//
+// The only swap device is the virtual swap device configured by the operator,
+// which has no file in the sandbox, so no path names a valid swap area.
+func Swapon(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	addr := args[0].Pointer()
+	flags := args[1].Int()
+
+	if flags&^linux.SWAP_FLAGS_VALID != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if !t.CheckCapability(linux.CAP_SYS_ADMIN) {
+		return 0, nil, linuxerr.EPERM
+	}
+	if err := statSwapArea(t, addr); err != nil {
+		return 0, nil, err
+	}
+	// Linux returns EINVAL for files that don't contain a swap signature.
+	return 0, nil, linuxerr.EINVAL
+}
+
+// Swapoff implements Linux syscall swapoff(2).
+func Swapoff(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
+	addr := args[0].Pointer()
+
+	if !t.CheckCapability(linux.CAP_SYS_ADMIN) {
+		return 0, nil, linuxerr.EPERM
+	}
+	if err := statSwapArea(t, addr); err != nil {
+		return 0, nil, err
+	}
+	// Linux returns EINVAL for files that aren't active swap areas, and
+	// Swapon never activates any.
+	return 0, nil, linuxerr.EINVAL
+}
+
+// statSwapArea checks that the path at addr refers to an existing file, such
+// that swapon(2) and swapoff(2) report path resolution errors consistently
+// with Linux.
+func statSwapArea(t *kernel.Task, addr hostarch.Addr) error {
+	path, err := copyInPath(t, addr)
+	if err != nil {
+		return err
+	}
+	tpop, err := getTaskPathOperation(t, linux.AT_FDCWD, path, disallowEmptyPath, followFinalSymlink)
+	if err != nil {
+		return err
+	}
+	defer tpop.Release(t)
+	_, err = t.Kernel().VFS().StatAt(t, t.Credentials(), &tpop.pop, &vfs.StatOptions{
+		Mask: linux.STATX_TYPE,
+	})
+	return err
+}
@@ -47,6 +47,9 @@ func Sysinfo(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr
 		FreeRAM:  memFree,
 		Unit:     1,
 	}
+	swapTotal, swapUsed := t.Kernel().SwapUsage()
+	si.TotalSwap = swapTotal
+	si.FreeSwap = swapTotal - swapUsed
 	_, err = si.CopyOut(t, addr)
 	return 0, nil, err
 }
@@ -15,47 +15,79 @@
 package linux
 
 import (
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/waiter"
 )
 
-const (
-	_SYSLOG_ACTION_READ_ALL    = 3
-	_SYSLOG_ACTION_SIZE_BUFFER = 10
-)
-
-// logBufLen is the default syslog buffer size on Linux.
-const logBufLen = 1 << 17
-
-// Syslog implements part of Linux syscall syslog.
-//
-// Only the unprivileged commands are implemented, allowing applications to
-// read a fun dmesg.
+// Syslog implements Linux syscall syslog.
 func Syslog(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
 	command := args[0].Int()
 	buf := args[1].Pointer()
 	size := int(args[2].Int())
 
+	if t.Kernel().SyslogActionRestricted(command) && !t.MayReadSyslog() {
+		return 0, nil, linuxerr.EPERM
+	}
+
+	log := t.Kernel().Syslog()
 	switch command {
-	case _SYSLOG_ACTION_READ_ALL:
-		if size < 0 {
+	case linux.SYSLOG_ACTION_CLOSE, linux.SYSLOG_ACTION_OPEN:
+		return 0, nil, nil
+	case linux.SYSLOG_ACTION_READ:
+		if buf == 0 || size < 0 {
 			return 0, nil, linuxerr.EINVAL
 		}
-		if size > logBufLen {
-			size = logBufLen
+		if size == 0 {
+			return 0, nil, nil
 		}
-
-		log := t.Kernel().Syslog().Log()
-		if len(log) > size {
-			log = log[:size]
+		text, err := log.Read(size)
+		if linuxerr.Equals(linuxerr.ErrWouldBlock, err) {
+			w, ch := waiter.NewChannelEntry(waiter.ReadableEvents)
+			log.EventRegister(&w)
+			for {
+				if text, err = log.Read(size); !linuxerr.Equals(linuxerr.ErrWouldBlock, err) {
+					break
+				}
+				if err = t.Block(ch); err != nil {
+					break
+				}
+			}
+			log.EventUnregister(&w)
+			if err != nil {
+				return 0, nil, linuxerr.ConvertIntr(err, linuxerr.ERESTARTSYS)
+			}
 		}
-
-		n, err := t.CopyOutBytes(buf, log)
+		n, err := t.CopyOutBytes(buf, text)
+		return uintptr(n), nil, err
+	case linux.SYSLOG_ACTION_READ_ALL, linux.SYSLOG_ACTION_READ_CLEAR:
+		if buf == 0 || size < 0 {
+			return 0, nil, linuxerr.EINVAL
+		}
+		if size == 0 {
+			return 0, nil, nil
+		}
+		text := log.ReadAll(size, command == linux.SYSLOG_ACTION_READ_CLEAR)
+		n, err := t.CopyOutBytes(buf, text)
 		return uintptr(n), nil, err
-	case _SYSLOG_ACTION_SIZE_BUFFER:
-		return logBufLen, nil, nil
+	case linux.SYSLOG_ACTION_CLEAR:
+		log.Clear()
+		return 0, nil, nil
+	case linux.SYSLOG_ACTION_CONSOLE_OFF:
+		log.ConsoleOff()
+		return 0, nil, nil
+	case linux.SYSLOG_ACTION_CONSOLE_ON:
+		log.ConsoleOn()
+		return 0, nil, nil
+	case linux.SYSLOG_ACTION_CONSOLE_LEVEL:
+		return 0, nil, log.SetConsoleLevel(int32(size))
+	case linux.SYSLOG_ACTION_SIZE_UNREAD:
+		return uintptr(log.SizeUnread()), nil, nil
+	case linux.SYSLOG_ACTION_SIZE_BUFFER:
+		return kernel.SyslogBufLen, nil, nil
 	default:
-		return 0, nil, linuxerr.ENOSYS
+		return 0, nil, linuxerr.EINVAL
 	}
 }
@@ -15,18 +15,21 @@
 package linux
 
 import (
+	"fmt"
+	"math"
+
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/hostarch"
 	"gvisor.dev/gvisor/pkg/marshal/primitive"
 	"gvisor.dev/gvisor/pkg/sentry/arch"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/pidfd"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/sched"
 	"gvisor.dev/gvisor/pkg/sentry/loader"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
-	"gvisor.dev/gvisor/pkg/usermem"
 )
 
 var (
@@ -114,6 +117,8 @@ func execveat(t *kernel.Task, dirfd int32, pathnameAddr, argvAddr, envvAddr host
 		}
 	}()
 	closeOnExec := false
+	nameFromFile := false
+	filename := pathname
 	if path := fspath.Parse(pathname); dirfd != linux.AT_FDCWD && !path.Absolute {
 		// We must open the executable ourselves since dirfd is used as the
 		// starting point while resolving path, but the task working directory
@@ -146,6 +151,17 @@ func execveat(t *kernel.Task, dirfd int32, pathnameAddr, argvAddr, envvAddr host
 			return 0, nil, err
 		}
 		executable = file
+		// As in Linux's fs/exec.c:alloc_bprm(), the executable is named by
+		// its path relative to /dev/fd, which is what interpreters see and
+		// what is passed in AT_EXECFN. When executing dirfd itself
+		// (fexecve(3)), the image is named after the file instead, since
+		// the file may be anonymous (e.g. a memfd) or opened with O_PATH.
+		if path.HasComponents() {
+			filename = fmt.Sprintf("/dev/fd/%d/%s", dirfd, pathname)
+		} else {
+			filename = fmt.Sprintf("/dev/fd/%d", dirfd)
+			nameFromFile = true
+		}
 		pathname = executable.MappedName(t)
 	}
 
@@ -158,8 +174,9 @@ func execveat(t *kernel.Task, dirfd int32, pathnameAddr, argvAddr, envvAddr host
 		WorkingDir:          wd,
 		RemainingTraversals: &remainingTraversals,
 		ResolveFinal:        flags&linux.AT_SYMLINK_NOFOLLOW == 0,
-		Filename:            pathname,
+		Filename:            filename,
 		File:                executable,
+		NameFromFile:        nameFromFile,
 		CloseOnExec:         closeOnExec,
 		Argv:                argv,
 		Envv:                envv,
@@ -238,15 +255,52 @@ func Clone3(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 	cloneArgsPointer := args[0].Pointer()
 	size := args[1].SizeT()
 
-	if int(size) < linux.CLONE_ARGS_SIZE_VER0 || int(size) > linux.CLONE_ARGS_SIZE_VER2 {
+	if size < linux.CLONE_ARGS_SIZE_VER0 {
 		return 0, nil, linuxerr.EINVAL
 	}
+	if size > hostarch.PageSize {
+		return 0, nil, linuxerr.E2BIG
+	}
 
 	var cloneArgs linux.CloneArgs
 	if cloneArgsPointer != 0 {
-		if _, err := cloneArgs.CopyInN(t, cloneArgsPointer, int(size)); err != nil {
+		if _, err := cloneArgs.CopyInN(t, cloneArgsPointer, int(min(size, linux.CLONE_ARGS_SIZE_VER2))); err != nil {
 			return 0, nil, err
 		}
+		// Newer versions of struct clone_args are accepted as long as the
+		// fields that we don't know about are zero.
+		if size > linux.CLONE_ARGS_SIZE_VER2 {
+			rest := make([]byte, size-linux.CLONE_ARGS_SIZE_VER2)
+			if _, err := t.CopyInBytes(cloneArgsPointer+linux.CLONE_ARGS_SIZE_VER2, rest); err != nil {
+				return 0, nil, err
+			}
+			for _, b := range rest {
+				if b != 0 {
+					return 0, nil, linuxerr.E2BIG
+				}
+			}
+		}
+	}
+
+	// Checks that apply only to clone3. See Linux's
+	// kernel/fork.c:copy_clone_args_from_user() and clone3_args_valid().
+	if cloneArgs.Flags&linux.CLONE_DETACHED != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if cloneArgs.ExitSignal&^linux.CSIGNAL != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if cloneArgs.Flags&(linux.CLONE_THREAD|linux.CLONE_PARENT) != 0 && cloneArgs.ExitSignal != 0 {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if (cloneArgs.Stack == 0) != (cloneArgs.StackSize == 0) {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if cloneArgs.SetTIDSize > linux.MAX_PID_NS_LEVEL || (cloneArgs.SetTID == 0) != (cloneArgs.SetTIDSize == 0) {
+		return 0, nil, linuxerr.EINVAL
+	}
+	if cloneArgs.Flags&linux.CLONE_INTO_CGROUP != 0 && (cloneArgs.Cgroup > math.MaxInt32 || size < linux.CLONE_ARGS_SIZE_VER2) {
+		return 0, nil, linuxerr.EINVAL
 	}
 
 	ntid, ctrl, err := t.Clone(&cloneArgs)
@@ -476,6 +530,9 @@ func Setns(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 	defer file.DecRef(t)
 
 	flags := args[1].Int()
+	if pfd, ok := file.Impl().(*pidfd.FileDescription); ok {
+		return 0, nil, t.SetnsTask(pfd.ThreadGroup().Leader(), flags)
+	}
 	return 0, nil, t.Setns(file, flags)
 }
 
@@ -569,16 +626,15 @@ func Getcpu(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 	node := args[1].Pointer()
 	// third argument to this system call is nowadays unused.
 
+	c := t.CPU()
 	if cpu != 0 {
-		if _, err := primitive.CopyInt32Out(t, cpu, t.CPU()); err != nil {
+		if _, err := primitive.CopyInt32Out(t, cpu, c); err != nil {
 			return 0, nil, err
 		}
 	}
-	// We always return node 0.
 	if node != 0 {
-		if _, err := t.MemoryManager().ZeroOut(t, node, 4, usermem.IOOpts{
-			AddressSpaceActive: true,
-		}); err != nil {
+		n := t.Kernel().CPUTopology().Node(uint(c))
+		if _, err := primitive.CopyInt32Out(t, node, int32(n)); err != nil {
 			return 0, nil, err
 		}
 	}
@@ -779,5 +835,6 @@ func Ptrace(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr,
 	addr := args[2].Pointer()
 	data := args[3].Pointer()
 
-	return 0, nil, t.Ptrace(req, pid, addr, data)
+	n, err := t.Ptrace(req, pid, addr, data)
+	return n, nil, err
 }
@@ -65,6 +65,29 @@ func targetTask(t *kernel.Task, c int32) *kernel.Task {
 	return t.PIDNamespace().TaskWithID(pid)
 }
 
+// cpuClockTarget returns the task or thread group whose CPU time is measured
+// by the given CPU clock id, or nil if there is none. Like Linux
+// (kernel/time/posix-cpu-timers.c:pid_for_clock()), thread clocks may only be
+// used from the same thread group, and process clocks must name a thread
+// group leader.
+func cpuClockTarget(t *kernel.Task, c int32) cpuClocker {
+	if isCPUClockPerThread(c) {
+		target := targetTask(t, c)
+		if target == nil || target.ThreadGroup() != t.ThreadGroup() {
+			return nil
+		}
+		return target
+	}
+	pid := pidOfClockID(c)
+	if pid == 0 {
+		return t.ThreadGroup()
+	}
+	if tg := t.PIDNamespace().ThreadGroupWithID(pid); tg != nil {
+		return tg
+	}
+	return nil
+}
+
 // ClockGetres implements linux syscall clock_getres(2).
 func ClockGetres(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uintptr, *kernel.SyscallControl, error) {
 	clockID := int32(args[0].Int())
@@ -86,9 +109,26 @@ func ClockGetres(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uin
 	return 0, nil, copyTimespecOut(t, addr, &r)
 }
 
+// isAlarmClock returns true if clockID is CLOCK_REALTIME_ALARM or
+// CLOCK_BOOTTIME_ALARM.
+func isAlarmClock(clockID int32) bool {
+	return clockID == linux.CLOCK_REALTIME_ALARM || clockID == linux.CLOCK_BOOTTIME_ALARM
+}
+
+// checkAlarmClock returns EPERM if t may not arm timers on clockID. Like Linux,
+// arming alarm clock timers requires CAP_WAKE_ALARM in the root user
+// namespace.
+func checkAlarmClock(t *kernel.Task, clockID int32) error {
+	if isAlarmClock(clockID) && !t.CheckCapabilityIn(linux.CAP_WAKE_ALARM, t.Kernel().RootUserNamespace()) {
+		return linuxerr.EPERM
+	}
+	return nil
+}
+
 type cpuClocker interface {
 	UserCPUClock() ktime.Clock
 	CPUClock() ktime.Clock
+	SchedClock() ktime.Clock
 }
 
 func getClock(t *kernel.Task, clockID int32) (ktime.Clock, error) {
@@ -97,34 +137,30 @@ func getClock(t *kernel.Task, clockID int32) (ktime.Clock, error) {
 			return nil, linuxerr.EINVAL
 		}
 
-		targetTask := targetTask(t, clockID)
-		if targetTask == nil {
+		target := cpuClockTarget(t, clockID)
+		if target == nil {
 			return nil, linuxerr.EINVAL
 		}
 
-		var target cpuClocker
-		if isCPUClockPerThread(clockID) {
-			target = targetTask
-		} else {
-			target = targetTask.ThreadGroup()
-		}
-
 		switch whichCPUClock(clockID) {
 		case linux.CPUCLOCK_VIRT:
 			return target.UserCPUClock(), nil
-		case linux.CPUCLOCK_PROF, linux.CPUCLOCK_SCHED:
-			// CPUCLOCK_SCHED is approximated by CPUCLOCK_PROF.
+		case linux.CPUCLOCK_PROF:
 			return target.CPUClock(), nil
+		case linux.CPUCLOCK_SCHED:
+			return target.SchedClock(), nil
 		default:
 			return nil, linuxerr.EINVAL
 		}
 	}
 
 	switch clockID {
-	case linux.CLOCK_REALTIME, linux.CLOCK_REALTIME_COARSE:
+	case linux.CLOCK_REALTIME, linux.CLOCK_REALTIME_COARSE, linux.CLOCK_REALTIME_ALARM:
+		// The alarm clocks only differ from their base clocks in that their
+		// timers wake the system from suspend, which gVisor doesn't have.
 		return t.Kernel().RealtimeClock(), nil
 	case linux.CLOCK_MONOTONIC, linux.CLOCK_MONOTONIC_COARSE,
-		linux.CLOCK_MONOTONIC_RAW, linux.CLOCK_BOOTTIME:
+		linux.CLOCK_MONOTONIC_RAW, linux.CLOCK_BOOTTIME, linux.CLOCK_BOOTTIME_ALARM:
 		// CLOCK_MONOTONIC approximates CLOCK_MONOTONIC_RAW.
 		// CLOCK_BOOTTIME is internally mapped to CLOCK_MONOTONIC, as:
 		//	- CLOCK_BOOTTIME should behave as CLOCK_MONOTONIC while also
@@ -134,9 +170,9 @@ func getClock(t *kernel.Task, clockID int32) (ktime.Clock, error) {
 		//		the closest to suspend time.
 		return t.Kernel().MonotonicClock(), nil
 	case linux.CLOCK_PROCESS_CPUTIME_ID:
-		return t.ThreadGroup().CPUClock(), nil
+		return t.ThreadGroup().SchedClock(), nil
 	case linux.CLOCK_THREAD_CPUTIME_ID:
-		return t.CPUClock(), nil
+		return t.SchedClock(), nil
 	default:
 		return nil, linuxerr.EINVAL
 	}
@@ -218,7 +254,8 @@ func ClockNanosleep(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (
 		if clockID != linux.CLOCK_REALTIME &&
 			clockID != linux.CLOCK_MONOTONIC &&
 			clockID != linux.CLOCK_BOOTTIME &&
-			clockID != linux.CLOCK_PROCESS_CPUTIME_ID {
+			clockID != linux.CLOCK_PROCESS_CPUTIME_ID &&
+			!isAlarmClock(clockID) {
 			return 0, nil, linuxerr.EINVAL
 		}
 	}
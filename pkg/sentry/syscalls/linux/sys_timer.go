@@ -109,6 +109,9 @@ func TimerCreate(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uin
 	if err != nil {
 		return 0, nil, err
 	}
+	if err := checkAlarmClock(t, clockID); err != nil {
+		return 0, nil, err
+	}
 
 	var sev *linux.Sigevent
 	if sevp != 0 {
@@ -42,15 +42,18 @@ func TimerfdCreate(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (u
 
 	var clock ktime.Clock
 	switch clockID {
-	case linux.CLOCK_REALTIME:
+	case linux.CLOCK_REALTIME, linux.CLOCK_REALTIME_ALARM:
 		clock = t.Kernel().RealtimeClock()
-	case linux.CLOCK_MONOTONIC, linux.CLOCK_BOOTTIME:
+	case linux.CLOCK_MONOTONIC, linux.CLOCK_BOOTTIME, linux.CLOCK_BOOTTIME_ALARM:
 		clock = t.Kernel().MonotonicClock()
 	default:
 		return 0, nil, linuxerr.EINVAL
 	}
+	if err := checkAlarmClock(t, clockID); err != nil {
+		return 0, nil, err
+	}
 	vfsObj := t.Kernel().VFS()
-	file, err := timerfd.New(t, vfsObj, clock, fileFlags)
+	file, err := timerfd.New(t, vfsObj, clockID, clock, fileFlags)
 	if err != nil {
 		return 0, nil, err
 	}
@@ -94,7 +97,7 @@ func TimerfdSettime(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (
 	if err != nil {
 		return 0, nil, err
 	}
-	tm, oldS := tfd.SetTime(newS)
+	tm, oldS := tfd.SetTime(newS, flags)
 	if oldValAddr != 0 {
 		oldVal := ktime.ItimerspecFromSetting(tm, oldS)
 		if _, err := oldVal.CopyOut(t, oldValAddr); err != nil {
@@ -56,7 +56,7 @@ func Setdomainname(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (u
 	size := args[1].Int()
 
 	utsns := t.UTSNamespace()
-	if !t.HasCapabilityIn(linux.CAP_SYS_ADMIN, utsns.UserNamespace()) {
+	if !t.CheckCapabilityIn(linux.CAP_SYS_ADMIN, utsns.UserNamespace()) {
 		return 0, nil, linuxerr.EPERM
 	}
 	if size < 0 || size > linux.UTSLen {
@@ -78,7 +78,7 @@ func Sethostname(t *kernel.Task, sysno uintptr, args arch.SyscallArguments) (uin
 	size := args[1].Int()
 
 	utsns := t.UTSNamespace()
-	if !t.HasCapabilityIn(linux.CAP_SYS_ADMIN, utsns.UserNamespace()) {
+	if !t.CheckCapabilityIn(linux.CAP_SYS_ADMIN, utsns.UserNamespace()) {
 		return 0, nil, linuxerr.EPERM
 	}
 	if size < 0 || size > linux.UTSLen {
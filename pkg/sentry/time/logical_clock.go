@@ -0,0 +1,126 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package time
+
+import (
+	"container/heap"
+
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// LogicalClockStep is the amount of time by which LogicalClocks advance each
+// time they are read.
+const LogicalClockStep = 1000 // 1 µs
+
+// LogicalClocks is a Clocks implementation that is independent of host time,
+// for deterministic execution of the sandbox.
+//
+// Logical time starts at a fixed point and advances by LogicalClockStep each
+// time it is read. Time also advances to the next deadline registered with
+// AddDeadline when AdvanceToNextDeadline is called, which should happen when
+// all tasks are blocked.
+//
+// LogicalClocks never provide parameters for the VDSO, which therefore falls
+// back to syscalls to read the time.
+type LogicalClocks struct {
+	mu sync.Mutex
+
+	// realtimeBase is the realtime when the clocks were created, in
+	// nanoseconds since the Unix epoch. realtimeBase is immutable.
+	realtimeBase int64
+
+	// elapsed is the logical time elapsed since the clocks were created.
+	// elapsed is protected by mu.
+	elapsed int64
+
+	// deadlines contains values of elapsed that timers are waiting for. It
+	// may contain deadlines of timers that were since reset. deadlines is
+	// protected by mu.
+	deadlines deadlineHeap
+}
+
+// NewLogicalClocks returns new LogicalClocks whose realtime starts at
+// realtimeBase nanoseconds since the Unix epoch.
+func NewLogicalClocks(realtimeBase int64) *LogicalClocks {
+	return &LogicalClocks{realtimeBase: realtimeBase}
+}
+
+// Update implements Clocks.Update.
+func (c *LogicalClocks) Update() (Parameters, bool, Parameters, bool) {
+	return Parameters{}, false, Parameters{}, false
+}
+
+// GetTime implements Clocks.GetTime.
+func (c *LogicalClocks) GetTime(id ClockID) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.elapsed += LogicalClockStep
+	switch id {
+	case Monotonic:
+		return c.elapsed, nil
+	case Realtime:
+		return c.realtimeBase + c.elapsed, nil
+	default:
+		return 0, linuxerr.EINVAL
+	}
+}
+
+// AddDeadline records that a timer is waiting until clock id reaches t.
+func (c *LogicalClocks) AddDeadline(id ClockID, t int64) {
+	if id == Realtime {
+		t -= c.realtimeBase
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t > c.elapsed {
+		heap.Push(&c.deadlines, t)
+	}
+}
+
+// AdvanceToNextDeadline advances time to the earliest deadline recorded by
+// AddDeadline that hasn't been reached yet. It returns false if there is no
+// such deadline.
+func (c *LogicalClocks) AdvanceToNextDeadline() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.deadlines.Len() > 0 {
+		t := heap.Pop(&c.deadlines).(int64)
+		if t > c.elapsed {
+			c.elapsed = t
+			return true
+		}
+	}
+	return false
+}
+
+// deadlineHeap is a min-heap of deadlines, implementing heap.Interface.
+type deadlineHeap []int64
+
+func (h deadlineHeap) Len() int           { return len(h) }
+func (h deadlineHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h deadlineHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *deadlineHeap) Push(x any) {
+	*h = append(*h, x.(int64))
+}
+
+func (h *deadlineHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package time
+
+import (
+	"testing"
+)
+
+func TestLogicalClocks(t *testing.T) {
+	const realtimeBase = 946684800 * 1e9
+	c := NewLogicalClocks(realtimeBase)
+
+	if _, ok, _, ok2 := c.Update(); ok || ok2 {
+		t.Errorf("Update() returned ready parameters, want none")
+	}
+
+	m1, err := c.GetTime(Monotonic)
+	if err != nil {
+		t.Fatalf("GetTime(Monotonic): %v", err)
+	}
+	r, err := c.GetTime(Realtime)
+	if err != nil {
+		t.Fatalf("GetTime(Realtime): %v", err)
+	}
+	m2, err := c.GetTime(Monotonic)
+	if err != nil {
+		t.Fatalf("GetTime(Monotonic): %v", err)
+	}
+	if m1 != LogicalClockStep || m2 != 3*LogicalClockStep {
+		t.Errorf("got monotonic times %d, %d, want %d, %d", m1, m2, LogicalClockStep, 3*LogicalClockStep)
+	}
+	if want := int64(realtimeBase + 2*LogicalClockStep); r != want {
+		t.Errorf("got realtime %d, want %d", r, want)
+	}
+
+	// Deadlines are reached in order, and deadlines in the past are ignored.
+	c.AddDeadline(Monotonic, 5e9)
+	c.AddDeadline(Realtime, realtimeBase+2e9)
+	c.AddDeadline(Monotonic, 1)
+	for _, want := range []int64{2e9, 5e9} {
+		if !c.AdvanceToNextDeadline() {
+			t.Fatalf("AdvanceToNextDeadline() = false, want true")
+		}
+		got, err := c.GetTime(Monotonic)
+		if err != nil {
+			t.Fatalf("GetTime(Monotonic): %v", err)
+		}
+		if got != want+LogicalClockStep {
+			t.Errorf("after AdvanceToNextDeadline(), got monotonic time %d, want %d", got, want+LogicalClockStep)
+		}
+	}
+	if c.AdvanceToNextDeadline() {
+		t.Errorf("AdvanceToNextDeadline() = true with no deadlines left, want false")
+	}
+}
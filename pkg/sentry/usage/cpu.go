@@ -15,6 +15,7 @@
 package usage
 
 import (
+	"math/bits"
 	"time"
 )
 
@@ -29,6 +30,12 @@ type CPUStats struct {
 	// SysTime is the amount of time spent executing sentry code.
 	SysTime time.Duration
 
+	// Runtime is the amount of time spent executing either application or
+	// sentry code. Unlike UserTime and SysTime, which are sampled at
+	// linux.ClockTick granularity, it is measured precisely, like Linux's
+	// struct task_cputime::sum_exec_runtime.
+	Runtime time.Duration
+
 	// VoluntarySwitches is the number of times control has been voluntarily
 	// ceded due to blocking, etc.
 	VoluntarySwitches uint64
@@ -42,6 +49,7 @@ type CPUStats struct {
 func (s *CPUStats) Accumulate(s2 CPUStats) {
 	s.UserTime += s2.UserTime
 	s.SysTime += s2.SysTime
+	s.Runtime += s2.Runtime
 	s.VoluntarySwitches += s2.VoluntarySwitches
 }
 
@@ -52,6 +60,61 @@ func (s *CPUStats) DifferenceSince(earlierSample CPUStats) CPUStats {
 	return CPUStats{
 		UserTime:          s.UserTime - earlierSample.UserTime,
 		SysTime:           s.SysTime - earlierSample.SysTime,
+		Runtime:           s.Runtime - earlierSample.Runtime,
 		VoluntarySwitches: s.VoluntarySwitches - earlierSample.VoluntarySwitches,
 	}
 }
+
+// PrevCPUTime holds the CPU times last reported for a task, thread group or
+// sandbox, so that times reported by Adjust never decrease. It is equivalent
+// to Linux's struct prev_cputime.
+//
+// +stateify savable
+type PrevCPUTime struct {
+	mu prevCPUTimeMutex `state:"nosave"`
+
+	// userTime and sysTime are the last times returned by Adjust.
+	userTime time.Duration
+	sysTime  time.Duration
+}
+
+// Adjust returns s with UserTime and SysTime scaled so that they add up to
+// s.Runtime. UserTime and SysTime are sampled at linux.ClockTick granularity,
+// so while their ratio is representative, their sum is not; Runtime is
+// measured precisely. This is consistent with Linux's
+// kernel/sched/cputime.c:cputime_adjust().
+func (p *PrevCPUTime) Adjust(s CPUStats) CPUStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rtime := s.Runtime
+	if p.userTime+p.sysTime >= rtime {
+		s.UserTime, s.SysTime = p.userTime, p.sysTime
+		return s
+	}
+
+	var stime time.Duration
+	switch {
+	case s.SysTime <= 0:
+		stime = 0
+	case s.UserTime <= 0:
+		stime = rtime
+	default:
+		// stime = rtime * SysTime / (UserTime + SysTime), which can't
+		// overflow since SysTime <= UserTime + SysTime.
+		hi, lo := bits.Mul64(uint64(rtime), uint64(s.SysTime))
+		q, _ := bits.Div64(hi, lo, uint64(s.UserTime+s.SysTime))
+		stime = time.Duration(q)
+	}
+	// Make sure neither time decreases.
+	if stime < p.sysTime {
+		stime = p.sysTime
+	}
+	utime := rtime - stime
+	if utime < p.userTime {
+		utime = p.userTime
+		stime = rtime - utime
+	}
+	p.userTime, p.sysTime = utime, stime
+	s.UserTime, s.SysTime = utime, stime
+	return s
+}
@@ -366,7 +366,8 @@ func (m *MemoryLocked) CopyPerCg(memCgID uint32) (MemoryStats, uint64) {
 
 // These options control how much total memory the is reported to the
 // application. They may only be set before the application starts executing,
-// and must not be modified.
+// and must not be modified. Use SetTotalMemoryLimit to change the total memory
+// at runtime.
 var (
 	// MinimumTotalMemoryBytes is the minimum reported total system memory.
 	MinimumTotalMemoryBytes uint64 = 2 << 30 // 2 GB
@@ -376,6 +377,27 @@ var (
 	MaximumTotalMemoryBytes uint64
 )
 
+// totalMemoryLimit, if non-zero, overrides MinimumTotalMemoryBytes and
+// MaximumTotalMemoryBytes. It is set when the sandbox memory is resized at
+// runtime.
+var totalMemoryLimit atomicbitops.Uint64
+
+// SetTotalMemoryLimit sets the total system memory reported to the application
+// to bytes, similar to memory hotplug on Linux. The 0 value restores the
+// limits set by MinimumTotalMemoryBytes and MaximumTotalMemoryBytes.
+func SetTotalMemoryLimit(bytes uint64) {
+	totalMemoryLimit.Store(bytes)
+}
+
+// TotalMemoryLimit returns the maximum total system memory reported to the
+// application. The 0 value indicates no maximum.
+func TotalMemoryLimit() uint64 {
+	if limit := totalMemoryLimit.Load(); limit != 0 {
+		return limit
+	}
+	return MaximumTotalMemoryBytes
+}
+
 // TotalMemory returns the "total usable memory" available.
 //
 // This number doesn't really have a true value so it's based on the following
@@ -384,7 +406,12 @@ var (
 //
 // memSize should be the platform.Memory size reported by platform.Memory.TotalSize()
 // used is the total memory reported by MemoryLocked.Total()
+//
+// If the total memory was set by SetTotalMemoryLimit, it is returned as is.
 func TotalMemory(memSize, used uint64) uint64 {
+	if limit := totalMemoryLimit.Load(); limit != 0 {
+		return limit
+	}
 	if memSize < MinimumTotalMemoryBytes {
 		memSize = MinimumTotalMemoryBytes
 	}
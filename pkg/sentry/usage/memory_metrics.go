@@ -0,0 +1,74 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usage
+
+import (
+	"gvisor.dev/gvisor/pkg/metric"
+)
+
+// Field values for the "kind" field of the /memory/usage metric. These
+// correspond to MemoryKind.
+var (
+	memoryKindSystem    = metric.FieldValue{"system"}
+	memoryKindAnonymous = metric.FieldValue{"anonymous"}
+	memoryKindPageCache = metric.FieldValue{"page_cache"}
+	memoryKindTmpfs     = metric.FieldValue{"tmpfs"}
+	memoryKindRamdiskfs = metric.FieldValue{"ramdiskfs"}
+	memoryKindMapped    = metric.FieldValue{"mapped"}
+)
+
+func init() {
+	metric.MustRegisterCustomUint64Metric("/memory/usage", false /* cumulative */, false /* sync */, "Memory accounted to the sandbox, in bytes, broken down by kind.",
+		memoryUsageValue,
+		metric.NewField("kind",
+			&memoryKindSystem,
+			&memoryKindAnonymous,
+			&memoryKindPageCache,
+			&memoryKindTmpfs,
+			&memoryKindRamdiskfs,
+			&memoryKindMapped))
+	metric.MustRegisterCustomUint64Metric("/memory/total_usage", false /* cumulative */, false /* sync */, "Total memory accounted to the sandbox, in bytes.",
+		func(...*metric.FieldValue) uint64 {
+			if MemoryAccounting == nil {
+				return 0
+			}
+			return MemoryAccounting.Total()
+		})
+}
+
+// memoryUsageValue returns the current value of /memory/usage for the given
+// kind.
+func memoryUsageValue(fields ...*metric.FieldValue) uint64 {
+	if MemoryAccounting == nil {
+		return 0
+	}
+	ms, _ := MemoryAccounting.Copy()
+	switch fields[0] {
+	case &memoryKindSystem:
+		return ms.System
+	case &memoryKindAnonymous:
+		return ms.Anonymous
+	case &memoryKindPageCache:
+		return ms.PageCache
+	case &memoryKindTmpfs:
+		return ms.Tmpfs
+	case &memoryKindRamdiskfs:
+		return ms.Ramdiskfs
+	case &memoryKindMapped:
+		return ms.Mapped
+	default:
+		panic("unknown memory kind field value")
+	}
+}
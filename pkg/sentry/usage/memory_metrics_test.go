@@ -0,0 +1,79 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usage
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/metric"
+)
+
+func TestMemoryUsageValue(t *testing.T) {
+	saved := MemoryAccounting
+	defer func() { MemoryAccounting = saved }()
+
+	kinds := []struct {
+		kind  MemoryKind
+		field *metric.FieldValue
+	}{
+		{System, &memoryKindSystem},
+		{Anonymous, &memoryKindAnonymous},
+		{PageCache, &memoryKindPageCache},
+		{Tmpfs, &memoryKindTmpfs},
+		{Ramdiskfs, &memoryKindRamdiskfs},
+		{Mapped, &memoryKindMapped},
+	}
+
+	// Without memory accounting, all kinds report 0.
+	MemoryAccounting = nil
+	for _, k := range kinds {
+		if got := memoryUsageValue(k.field); got != 0 {
+			t.Errorf("memoryUsageValue(%q) without accounting = %d, want 0", k.field.Value, got)
+		}
+	}
+
+	if err := Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer MemoryAccounting.File.Close()
+	// Account a distinct amount to each kind, some of it to a cgroup.
+	for i, k := range kinds {
+		MemoryAccounting.Inc(uint64(i+1)<<12, k.kind, 0)
+		MemoryAccounting.Inc(uint64(i+1)<<20, k.kind, 1 /* memCgID */)
+	}
+	MemoryAccounting.Dec(1<<20, Anonymous, 1 /* memCgID */)
+	for i, k := range kinds {
+		want := uint64(i+1)<<12 + uint64(i+1)<<20
+		if k.kind == Anonymous {
+			want -= 1 << 20
+		}
+		if got := memoryUsageValue(k.field); got != want {
+			t.Errorf("memoryUsageValue(%q) = %d, want %d", k.field.Value, got, want)
+		}
+	}
+}
+
+func TestMemoryUsageValueUnknownKind(t *testing.T) {
+	saved := MemoryAccounting
+	defer func() { MemoryAccounting = saved }()
+	MemoryAccounting = &MemoryLocked{}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("memoryUsageValue with an unknown field value did not panic")
+		}
+	}()
+	memoryUsageValue(&metric.FieldValue{"system"})
+}
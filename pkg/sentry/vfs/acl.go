@@ -0,0 +1,303 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"encoding/binary"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+)
+
+// ACLEntry is an entry in a POSIX access control list.
+//
+// +stateify savable
+type ACLEntry struct {
+	// Tag is the type of the entry, one of linux.ACL_*.
+	Tag uint16
+
+	// Perms is the set of permissions granted by the entry.
+	Perms AccessTypes
+
+	// ID is the auth.KUID of the user represented by a linux.ACL_USER entry,
+	// or the auth.KGID of the group represented by a linux.ACL_GROUP entry.
+	// ID is unused for other entries.
+	ID uint32
+}
+
+// ACL is a POSIX access control list, as stored in the
+// "system.posix_acl_access" and "system.posix_acl_default" extended
+// attributes. ACL entries are sorted by tag as required by ParseACL. ACLs are
+// immutable; functions that modify ACLs return a new ACL.
+//
+// +stateify savable
+type ACL struct {
+	Entries []ACLEntry
+}
+
+// IsACLXattr returns true if name is the name of an extended attribute that
+// stores a POSIX ACL.
+func IsACLXattr(name string) bool {
+	return name == linux.XATTR_NAME_POSIX_ACL_ACCESS || name == linux.XATTR_NAME_POSIX_ACL_DEFAULT
+}
+
+// ParseACL parses the value of a POSIX ACL extended attribute, with user and
+// group IDs in the given user namespace. If value contains no entries,
+// ParseACL returns a nil ACL, which represents the removal of the ACL as in
+// Linux.
+//
+// This corresponds to Linux's fs/posix_acl.c:posix_acl_from_xattr() and
+// posix_acl_valid().
+func ParseACL(ns *auth.UserNamespace, value string) (*ACL, error) {
+	if len(value) < linux.POSIX_ACL_XATTR_HEADER_SIZE {
+		return nil, linuxerr.EINVAL
+	}
+	if binary.LittleEndian.Uint32([]byte(value[:linux.POSIX_ACL_XATTR_HEADER_SIZE])) != linux.POSIX_ACL_XATTR_VERSION {
+		return nil, linuxerr.EOPNOTSUPP
+	}
+	value = value[linux.POSIX_ACL_XATTR_HEADER_SIZE:]
+	if len(value)%linux.POSIX_ACL_XATTR_ENTRY_SIZE != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	acl := &ACL{Entries: make([]ACLEntry, 0, len(value)/linux.POSIX_ACL_XATTR_ENTRY_SIZE)}
+	var (
+		state     uint16 = linux.ACL_USER_OBJ
+		needsMask bool
+	)
+	for ; len(value) != 0; value = value[linux.POSIX_ACL_XATTR_ENTRY_SIZE:] {
+		b := []byte(value[:linux.POSIX_ACL_XATTR_ENTRY_SIZE])
+		e := ACLEntry{
+			Tag:   binary.LittleEndian.Uint16(b[0:]),
+			Perms: AccessTypes(binary.LittleEndian.Uint16(b[2:])),
+		}
+		id := binary.LittleEndian.Uint32(b[4:])
+		if e.Perms&^(MayRead|MayWrite|MayExec) != 0 {
+			return nil, linuxerr.EINVAL
+		}
+		// Entries must appear in the order user owner, named users, group
+		// owner, named groups, mask, other.
+		switch e.Tag {
+		case linux.ACL_USER_OBJ:
+			if state != linux.ACL_USER_OBJ {
+				return nil, linuxerr.EINVAL
+			}
+			state = linux.ACL_USER
+		case linux.ACL_USER:
+			if state != linux.ACL_USER {
+				return nil, linuxerr.EINVAL
+			}
+			kuid := ns.MapToKUID(auth.UID(id))
+			if !kuid.Ok() {
+				return nil, linuxerr.EINVAL
+			}
+			e.ID = uint32(kuid)
+			needsMask = true
+		case linux.ACL_GROUP_OBJ:
+			if state != linux.ACL_USER {
+				return nil, linuxerr.EINVAL
+			}
+			state = linux.ACL_GROUP
+		case linux.ACL_GROUP:
+			if state != linux.ACL_GROUP {
+				return nil, linuxerr.EINVAL
+			}
+			kgid := ns.MapToKGID(auth.GID(id))
+			if !kgid.Ok() {
+				return nil, linuxerr.EINVAL
+			}
+			e.ID = uint32(kgid)
+			needsMask = true
+		case linux.ACL_MASK:
+			if state != linux.ACL_GROUP {
+				return nil, linuxerr.EINVAL
+			}
+			state = linux.ACL_OTHER
+		case linux.ACL_OTHER:
+			if state == linux.ACL_OTHER || (state == linux.ACL_GROUP && !needsMask) {
+				state = 0
+				break
+			}
+			return nil, linuxerr.EINVAL
+		default:
+			return nil, linuxerr.EINVAL
+		}
+		acl.Entries = append(acl.Entries, e)
+	}
+	if state != 0 {
+		return nil, linuxerr.EINVAL
+	}
+	return acl, nil
+}
+
+// Encode returns the value of the extended attribute representing acl, with
+// user and group IDs in the given user namespace.
+//
+// This corresponds to Linux's fs/posix_acl.c:posix_acl_to_xattr().
+func (acl *ACL) Encode(ns *auth.UserNamespace) string {
+	b := make([]byte, linux.POSIX_ACL_XATTR_HEADER_SIZE+len(acl.Entries)*linux.POSIX_ACL_XATTR_ENTRY_SIZE)
+	binary.LittleEndian.PutUint32(b, linux.POSIX_ACL_XATTR_VERSION)
+	eb := b[linux.POSIX_ACL_XATTR_HEADER_SIZE:]
+	for _, e := range acl.Entries {
+		id := uint32(linux.ACL_UNDEFINED_ID)
+		switch e.Tag {
+		case linux.ACL_USER:
+			id = uint32(auth.KUID(e.ID).In(ns).OrOverflow())
+		case linux.ACL_GROUP:
+			id = uint32(auth.KGID(e.ID).In(ns).OrOverflow())
+		}
+		binary.LittleEndian.PutUint16(eb[0:], e.Tag)
+		binary.LittleEndian.PutUint16(eb[2:], uint16(e.Perms))
+		binary.LittleEndian.PutUint32(eb[4:], id)
+		eb = eb[linux.POSIX_ACL_XATTR_ENTRY_SIZE:]
+	}
+	return string(b)
+}
+
+// groupClassEntry returns the index of the entry in acl whose permissions are
+// reflected by the group permission bits of the file mode: the mask entry if
+// it exists, and the group owner entry otherwise.
+func (acl *ACL) groupClassEntry() int {
+	group := -1
+	for i, e := range acl.Entries {
+		switch e.Tag {
+		case linux.ACL_MASK:
+			return i
+		case linux.ACL_GROUP_OBJ:
+			group = i
+		}
+	}
+	return group
+}
+
+// Mode returns mode with its permission bits replaced by those represented by
+// acl, and true if acl is fully represented by the resulting mode, such that
+// it doesn't need to be stored.
+//
+// This corresponds to Linux's fs/posix_acl.c:posix_acl_equiv_mode().
+func (acl *ACL) Mode(mode linux.FileMode) (linux.FileMode, bool) {
+	equiv := true
+	for _, e := range acl.Entries {
+		perms := linux.FileMode(e.Perms)
+		switch e.Tag {
+		case linux.ACL_USER_OBJ:
+			mode = (mode &^ 0700) | perms<<6
+		case linux.ACL_GROUP_OBJ:
+			mode = (mode &^ 0070) | perms<<3
+		case linux.ACL_OTHER:
+			mode = (mode &^ 0007) | perms
+		case linux.ACL_MASK:
+			// The mask entry follows the group owner entry, so it takes
+			// precedence.
+			mode = (mode &^ 0070) | perms<<3
+			equiv = false
+		default:
+			equiv = false
+		}
+	}
+	return mode, equiv
+}
+
+// Chmod returns a copy of acl updated to reflect a change of the file's
+// permission bits to those in mode.
+//
+// This corresponds to Linux's fs/posix_acl.c:__posix_acl_chmod_masq().
+func (acl *ACL) Chmod(mode linux.FileMode) *ACL {
+	newACL := &ACL{Entries: append([]ACLEntry(nil), acl.Entries...)}
+	group := newACL.groupClassEntry()
+	for i := range newACL.Entries {
+		e := &newACL.Entries[i]
+		switch {
+		case e.Tag == linux.ACL_USER_OBJ:
+			e.Perms = AccessTypes(mode>>6) & 07
+		case e.Tag == linux.ACL_OTHER:
+			e.Perms = AccessTypes(mode) & 07
+		case i == group:
+			e.Perms = AccessTypes(mode>>3) & 07
+		}
+	}
+	return newACL
+}
+
+// Create returns the access ACL of a file created with the given mode in a
+// directory whose default ACL is acl, and the mode of the created file. The
+// returned ACL is nil if it is fully represented by the returned mode. Since
+// the default ACL replaces the umask, mode should not have the umask of the
+// creating process applied.
+//
+// This corresponds to Linux's fs/posix_acl.c:posix_acl_create_masq().
+func (acl *ACL) Create(mode linux.FileMode) (*ACL, linux.FileMode) {
+	newACL := &ACL{Entries: append([]ACLEntry(nil), acl.Entries...)}
+	group := newACL.groupClassEntry()
+	for i := range newACL.Entries {
+		e := &newACL.Entries[i]
+		switch {
+		case e.Tag == linux.ACL_USER_OBJ:
+			e.Perms &= AccessTypes(mode>>6) & 07
+		case e.Tag == linux.ACL_OTHER:
+			e.Perms &= AccessTypes(mode) & 07
+		case i == group:
+			e.Perms &= AccessTypes(mode>>3) & 07
+		}
+	}
+	mode, equiv := newACL.Mode(mode)
+	if equiv {
+		return nil, mode
+	}
+	return newACL, mode
+}
+
+// permits returns true if acl grants creds the given access rights on a file
+// with the given owning group, subject to the rules of Linux's
+// fs/posix_acl.c:posix_acl_permission(). The file owner is checked by the
+// caller.
+func (acl *ACL) permits(creds *auth.Credentials, ats AccessTypes, kgid auth.KGID) bool {
+	var mask AccessTypes = MayRead | MayWrite | MayExec
+	for _, e := range acl.Entries {
+		if e.Tag == linux.ACL_MASK {
+			mask = e.Perms
+		}
+	}
+	foundGroup := false
+	for _, e := range acl.Entries {
+		switch e.Tag {
+		case linux.ACL_USER:
+			if creds.EffectiveKUID == auth.KUID(e.ID) {
+				return e.Perms&mask&ats == ats
+			}
+		case linux.ACL_GROUP_OBJ:
+			if creds.InGroup(kgid) {
+				foundGroup = true
+				if e.Perms&mask&ats == ats {
+					return true
+				}
+			}
+		case linux.ACL_GROUP:
+			if creds.InGroup(auth.KGID(e.ID)) {
+				foundGroup = true
+				if e.Perms&mask&ats == ats {
+					return true
+				}
+			}
+		case linux.ACL_OTHER:
+			return !foundGroup && e.Perms&ats == ats
+		}
+	}
+	return false
+}
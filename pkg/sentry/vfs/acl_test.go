@@ -0,0 +1,150 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+)
+
+// testACL is an ACL granting rw to the owner, r to user 1000, rx to the owning
+// group, with a mask of r and no permissions to others.
+var testACL = &ACL{Entries: []ACLEntry{
+	{Tag: linux.ACL_USER_OBJ, Perms: MayRead | MayWrite},
+	{Tag: linux.ACL_USER, Perms: MayRead, ID: 1000},
+	{Tag: linux.ACL_GROUP_OBJ, Perms: MayRead | MayExec},
+	{Tag: linux.ACL_MASK, Perms: MayRead},
+	{Tag: linux.ACL_OTHER},
+}}
+
+func TestACLEncodeParse(t *testing.T) {
+	ns := auth.NewRootUserNamespace()
+	acl, err := ParseACL(ns, testACL.Encode(ns))
+	if err != nil {
+		t.Fatalf("ParseACL failed: %v", err)
+	}
+	if len(acl.Entries) != len(testACL.Entries) {
+		t.Fatalf("got %d entries, want %d", len(acl.Entries), len(testACL.Entries))
+	}
+	for i := range acl.Entries {
+		if acl.Entries[i] != testACL.Entries[i] {
+			t.Errorf("entry %d: got %+v, want %+v", i, acl.Entries[i], testACL.Entries[i])
+		}
+	}
+}
+
+func TestACLParseInvalid(t *testing.T) {
+	ns := auth.NewRootUserNamespace()
+	for _, tc := range []struct {
+		name    string
+		entries []ACLEntry
+	}{
+		{
+			name: "missing group owner",
+			entries: []ACLEntry{
+				{Tag: linux.ACL_USER_OBJ},
+				{Tag: linux.ACL_OTHER},
+			},
+		},
+		{
+			name: "named user without mask",
+			entries: []ACLEntry{
+				{Tag: linux.ACL_USER_OBJ},
+				{Tag: linux.ACL_USER, ID: 1000},
+				{Tag: linux.ACL_GROUP_OBJ},
+				{Tag: linux.ACL_OTHER},
+			},
+		},
+		{
+			name: "out of order",
+			entries: []ACLEntry{
+				{Tag: linux.ACL_GROUP_OBJ},
+				{Tag: linux.ACL_USER_OBJ},
+				{Tag: linux.ACL_OTHER},
+			},
+		},
+		{
+			name: "invalid permissions",
+			entries: []ACLEntry{
+				{Tag: linux.ACL_USER_OBJ, Perms: 010},
+				{Tag: linux.ACL_GROUP_OBJ},
+				{Tag: linux.ACL_OTHER},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			value := (&ACL{Entries: tc.entries}).Encode(ns)
+			if _, err := ParseACL(ns, value); !linuxerr.Equals(linuxerr.EINVAL, err) {
+				t.Errorf("ParseACL: got error %v, want EINVAL", err)
+			}
+		})
+	}
+}
+
+func TestACLPermissions(t *testing.T) {
+	ns := auth.NewRootUserNamespace()
+	const (
+		owner = auth.KUID(1)
+		group = auth.KGID(1)
+	)
+	mode, _ := testACL.Mode(linux.S_IFREG)
+	for _, tc := range []struct {
+		name string
+		uid  auth.KUID
+		gid  auth.KGID
+		ats  AccessTypes
+		want error
+	}{
+		{name: "owner", uid: owner, gid: 2, ats: MayRead | MayWrite},
+		{name: "named user", uid: 1000, gid: 2, ats: MayRead},
+		{name: "named user write", uid: 1000, gid: 2, ats: MayWrite, want: linuxerr.EACCES},
+		{name: "group masked", uid: 2, gid: group, ats: MayExec, want: linuxerr.EACCES},
+		{name: "group", uid: 2, gid: group, ats: MayRead},
+		{name: "other", uid: 2, gid: 2, ats: MayRead, want: linuxerr.EACCES},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			creds := auth.NewUserCredentials(tc.uid, tc.gid, nil, nil, ns)
+			if err := GenericCheckPermissionsACL(creds, tc.ats, mode, owner, group, testACL); err != tc.want {
+				t.Errorf("GenericCheckPermissionsACL: got %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestACLCreate(t *testing.T) {
+	acl, mode := testACL.Create(0777)
+	if acl == nil {
+		t.Fatalf("Create returned nil ACL for a default ACL with a named user")
+	}
+	if want := linux.FileMode(0640); mode != want {
+		t.Errorf("got mode %#o, want %#o", mode, want)
+	}
+
+	minimal := &ACL{Entries: []ACLEntry{
+		{Tag: linux.ACL_USER_OBJ, Perms: MayRead | MayWrite | MayExec},
+		{Tag: linux.ACL_GROUP_OBJ, Perms: MayRead | MayExec},
+		{Tag: linux.ACL_OTHER, Perms: MayRead | MayExec},
+	}}
+	acl, mode = minimal.Create(0666)
+	if acl != nil {
+		t.Errorf("Create returned non-nil ACL %+v for a minimal default ACL", acl)
+	}
+	if want := linux.FileMode(0644); mode != want {
+		t.Errorf("got mode %#o, want %#o", mode, want)
+	}
+}
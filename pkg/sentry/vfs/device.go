@@ -180,3 +180,10 @@ func (vfs *VirtualFilesystem) PutAnonBlockDevMinor(minor uint32) {
 		vfs.anonBlockDevMinorNext = minor
 	}
 }
+
+// kernelDevID returns the device number with the given major and minor
+// numbers in the Linux kernel's internal encoding (include/linux/kdev_t.h:
+// MKDEV()), which is the encoding used in /proc/[pid]/fdinfo.
+func kernelDevID(major, minor uint32) uint32 {
+	return major<<20 | minor
+}
@@ -15,6 +15,10 @@
 package vfs
 
 import (
+	"bytes"
+	"fmt"
+	"sort"
+
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
@@ -227,6 +231,46 @@ func (ep *EpollInstance) Seek(ctx context.Context, offset int64, whence int32) (
 	return 0, nil
 }
 
+// FDInfo implements FileDescriptionImplFDInfoExtension.FDInfo.
+func (ep *EpollInstance) FDInfo(ctx context.Context, buf *bytes.Buffer) {
+	type target struct {
+		file     *FileDescription
+		num      int32
+		mask     uint32
+		userData [2]int32
+	}
+	ep.interestMu.Lock()
+	targets := make([]target, 0, len(ep.interest))
+	for key, epi := range ep.interest {
+		// No reference is held on registered files, which may be concurrently
+		// released.
+		if !key.file.TryIncRef() {
+			continue
+		}
+		targets = append(targets, target{
+			file:     key.file,
+			num:      key.num,
+			mask:     epi.mask,
+			userData: epi.userData,
+		})
+	}
+	ep.interestMu.Unlock()
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].num < targets[j].num
+	})
+
+	// Linux: fs/eventpoll.c:ep_show_fdinfo()
+	for _, t := range targets {
+		stat, err := t.file.Stat(ctx, StatOptions{Mask: linux.STATX_INO})
+		if err != nil {
+			stat = linux.Statx{}
+		}
+		data := uint64(uint32(t.userData[0])) | uint64(uint32(t.userData[1]))<<32
+		fmt.Fprintf(buf, "tfd: %8d events: %8x data: %16x  pos:%d ino:%x sdev:%x\n", t.num, t.mask, data, t.file.FDInfoPos(ctx), stat.Ino, kernelDevID(stat.DevMajor, stat.DevMinor))
+		t.file.DecRef(ctx)
+	}
+}
+
 // AddInterest implements the semantics of EPOLL_CTL_ADD.
 //
 // Preconditions: A reference must be held on file.
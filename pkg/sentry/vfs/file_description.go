@@ -15,6 +15,7 @@
 package vfs
 
 import (
+	"bytes"
 	"io"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
@@ -153,6 +154,7 @@ func (fd *FileDescription) Init(impl FileDescriptionImpl, flags uint32, mnt *Mou
 	fd.readable = MayReadFileWithOpenFlags(flags)
 	fd.writable = writable
 	fd.impl = impl
+	mnt.vfs.openFiles.Add(1)
 	return nil
 }
 
@@ -198,6 +200,7 @@ func (fd *FileDescription) DecRef(ctx context.Context) {
 		if fd.writable {
 			fd.vd.mount.EndWrite()
 		}
+		fd.vd.mount.vfs.openFiles.Add(-1)
 		fd.vd.DecRef(ctx)
 		fd.flagsMu.Lock()
 		if fd.statusFlags.RacyLoad()&linux.O_ASYNC != 0 && fd.asyncHandler != nil {
@@ -484,6 +487,32 @@ type FileDescriptionImpl interface {
 	UnregisterFileAsyncHandler(fd *FileDescription)
 }
 
+// FileDescriptionImplFDInfoExtension is an optional extension to
+// FileDescriptionImpl for file types that report their state in
+// /proc/[pid]/fdinfo/[fd].
+type FileDescriptionImplFDInfoExtension interface {
+	// FDInfo writes lines describing the file's state to buf, following the
+	// fields that are common to all files. It is analogous to Linux's
+	// struct file_operations::show_fdinfo.
+	FDInfo(ctx context.Context, buf *bytes.Buffer)
+}
+
+// FDInfoPos returns the file offset reported for fd in /proc/[pid]/fdinfo,
+// or 0 if fd has no meaningful offset.
+func (fd *FileDescription) FDInfoPos(ctx context.Context) int64 {
+	// Seeking a DynamicBytesFileDescriptionImpl regenerates its contents, and
+	// fdinfo may be generated for fd while its contents are being generated.
+	if dfd, ok := fd.impl.(interface{ tryOffset() (int64, bool) }); ok {
+		off, _ := dfd.tryOffset()
+		return off
+	}
+	pos, err := fd.impl.Seek(ctx, 0, linux.SEEK_CUR)
+	if err != nil {
+		return 0
+	}
+	return pos
+}
+
 // Dirent holds the information contained in struct linux_dirent64.
 //
 // +stateify savable
@@ -546,13 +575,18 @@ func (fd *FileDescription) Stat(ctx context.Context, opts StatOptions) (linux.St
 
 // SetStat updates metadata for the file represented by fd.
 func (fd *FileDescription) SetStat(ctx context.Context, opts SetStatOptions) error {
+	fs := fd.vd.mount.fs
+	if err := fs.StartWrite(ctx); err != nil {
+		return err
+	}
+	defer fs.EndWrite()
 	if fd.opts.UseDentryMetadata {
 		vfsObj := fd.vd.mount.vfs
 		rp := vfsObj.getResolvingPath(auth.CredentialsFromContext(ctx), &PathOperation{
 			Root:  fd.vd,
 			Start: fd.vd,
 		})
-		err := fd.vd.mount.fs.impl.SetStatAt(ctx, rp, opts)
+		err := fs.impl.SetStatAt(ctx, rp, opts)
 		rp.Release(ctx)
 		return err
 	}
@@ -586,6 +620,11 @@ func (fd *FileDescription) Allocate(ctx context.Context, mode, offset, length ui
 	if !fd.IsWritable() {
 		return linuxerr.EBADF
 	}
+	fs := fd.vd.mount.fs
+	if err := fs.StartWrite(ctx); err != nil {
+		return err
+	}
+	defer fs.EndWrite()
 	if err := fd.impl.Allocate(ctx, mode, offset, length); err != nil {
 		return err
 	}
@@ -765,13 +804,18 @@ func (fd *FileDescription) GetXattr(ctx context.Context, opts *GetXattrOptions)
 // SetXattr changes the value associated with the given extended attribute for
 // the file represented by fd.
 func (fd *FileDescription) SetXattr(ctx context.Context, opts *SetXattrOptions) error {
+	fs := fd.vd.mount.fs
+	if err := fs.StartWrite(ctx); err != nil {
+		return err
+	}
+	defer fs.EndWrite()
 	if fd.opts.UseDentryMetadata {
 		vfsObj := fd.vd.mount.vfs
 		rp := vfsObj.getResolvingPath(auth.CredentialsFromContext(ctx), &PathOperation{
 			Root:  fd.vd,
 			Start: fd.vd,
 		})
-		err := fd.vd.mount.fs.impl.SetXattrAt(ctx, rp, *opts)
+		err := fs.impl.SetXattrAt(ctx, rp, *opts)
 		rp.Release(ctx)
 		return err
 	}
@@ -785,13 +829,18 @@ func (fd *FileDescription) SetXattr(ctx context.Context, opts *SetXattrOptions)
 // RemoveXattr removes the given extended attribute from the file represented
 // by fd.
 func (fd *FileDescription) RemoveXattr(ctx context.Context, name string) error {
+	fs := fd.vd.mount.fs
+	if err := fs.StartWrite(ctx); err != nil {
+		return err
+	}
+	defer fs.EndWrite()
 	if fd.opts.UseDentryMetadata {
 		vfsObj := fd.vd.mount.vfs
 		rp := vfsObj.getResolvingPath(auth.CredentialsFromContext(ctx), &PathOperation{
 			Root:  fd.vd,
 			Start: fd.vd,
 		})
-		err := fd.vd.mount.fs.impl.RemoveXattrAt(ctx, rp, name)
+		err := fs.impl.RemoveXattrAt(ctx, rp, name)
 		rp.Release(ctx)
 		return err
 	}
@@ -372,6 +372,15 @@ func (fd *DynamicBytesFileDescriptionImpl) Seek(ctx context.Context, offset int6
 	return offset, nil
 }
 
+// tryOffset returns the file offset, or false if fd.mu is locked.
+func (fd *DynamicBytesFileDescriptionImpl) tryOffset() (int64, bool) {
+	if !fd.mu.TryLock() {
+		return 0, false
+	}
+	defer fd.mu.Unlock()
+	return fd.off, true
+}
+
 // Preconditions: fd.mu must be locked.
 func (fd *DynamicBytesFileDescriptionImpl) pwriteLocked(ctx context.Context, src usermem.IOSequence, offset int64, opts WriteOptions) (int64, error) {
 	if opts.Flags&^(linux.RWF_HIPRI|linux.RWF_DSYNC|linux.RWF_SYNC) != 0 {
@@ -0,0 +1,125 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"encoding/binary"
+
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/rand"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+)
+
+// FileHandleType is the type of all file handles returned by
+// EncodeFileHandle. Linux's file handle types are filesystem-specific, and
+// only identify the handle format to the filesystem that created it.
+const FileHandleType = 0x67
+
+// fileHandleIDSize is the size of the filesystem ID at the beginning of each
+// file handle.
+const fileHandleIDSize = 8
+
+// FileHandleFilesystemImpl is an optional interface implemented by
+// FilesystemImpls that support file handles, as used by name_to_handle_at(2)
+// and open_by_handle_at(2).
+type FileHandleFilesystemImpl interface {
+	// EncodeFileHandle returns an opaque handle identifying the file
+	// represented by d, which is a Dentry in this filesystem. The handle must
+	// remain valid for as long as the filesystem exists and the file is not
+	// deleted, and must not be larger than MaxFileHandleSize().
+	EncodeFileHandle(ctx context.Context, d *Dentry) ([]byte, error)
+
+	// DecodeFileHandle returns the file identified by handle, which was
+	// returned by EncodeFileHandle, with a reference taken on it. root is the
+	// root of a mount of this filesystem, and the returned file must be on
+	// root's mount. If the file no longer exists, DecodeFileHandle returns
+	// ESTALE.
+	DecodeFileHandle(ctx context.Context, creds *auth.Credentials, root VirtualDentry, handle []byte) (VirtualDentry, error)
+}
+
+// MaxFileHandleSize returns the maximum size of the handles returned by
+// FileHandleFilesystemImpl.EncodeFileHandle.
+func MaxFileHandleSize() int {
+	return linux.MAX_HANDLE_SZ - fileHandleIDSize
+}
+
+// fileHandleID returns the ID that file handles of fs start with. IDs are
+// random, so that handles are only valid for the filesystem that created them
+// and can't be guessed across sandboxes. The ID is saved along with fs, so
+// handles remain valid across checkpoint/restore.
+func (fs *Filesystem) fileHandleID() (uint64, error) {
+	if id := fs.handleID.Load(); id != 0 {
+		return id, nil
+	}
+	var buf [8]byte
+	for {
+		if _, err := rand.Read(buf[:]); err != nil {
+			return 0, err
+		}
+		if id := binary.LittleEndian.Uint64(buf[:]); id != 0 {
+			if fs.handleID.CompareAndSwap(0, id) {
+				return id, nil
+			}
+			return fs.handleID.Load(), nil
+		}
+	}
+}
+
+// EncodeFileHandle returns the type and contents of a handle for the file at
+// vd, as returned by name_to_handle_at(2). It returns EOPNOTSUPP if the file's
+// filesystem doesn't support file handles.
+func (vfs *VirtualFilesystem) EncodeFileHandle(ctx context.Context, vd VirtualDentry) (int32, []byte, error) {
+	fs := vd.mount.fs
+	impl, ok := fs.impl.(FileHandleFilesystemImpl)
+	if !ok {
+		return 0, nil, linuxerr.EOPNOTSUPP
+	}
+	id, err := fs.fileHandleID()
+	if err != nil {
+		return 0, nil, err
+	}
+	fh, err := impl.EncodeFileHandle(ctx, vd.dentry)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(fh) > MaxFileHandleSize() {
+		return 0, nil, linuxerr.EOPNOTSUPP
+	}
+	handle := make([]byte, fileHandleIDSize, fileHandleIDSize+len(fh))
+	binary.LittleEndian.PutUint64(handle, id)
+	return FileHandleType, append(handle, fh...), nil
+}
+
+// DecodeFileHandle returns the file on mnt identified by a handle returned by
+// EncodeFileHandle, as done by open_by_handle_at(2). A reference is taken on
+// the returned VirtualDentry. Handles that were not created by mnt's
+// filesystem, or whose file no longer exists, are rejected with ESTALE.
+func (vfs *VirtualFilesystem) DecodeFileHandle(ctx context.Context, creds *auth.Credentials, mnt *Mount, handleType int32, handle []byte) (VirtualDentry, error) {
+	fs := mnt.fs
+	impl, ok := fs.impl.(FileHandleFilesystemImpl)
+	if !ok {
+		return VirtualDentry{}, linuxerr.EOPNOTSUPP
+	}
+	if handleType != FileHandleType || len(handle) < fileHandleIDSize {
+		return VirtualDentry{}, linuxerr.ESTALE
+	}
+	if id := fs.handleID.Load(); id == 0 || binary.LittleEndian.Uint64(handle) != id {
+		return VirtualDentry{}, linuxerr.ESTALE
+	}
+	root := MakeVirtualDentry(mnt, mnt.root)
+	return impl.DecodeFileHandle(ctx, creds, root, handle[fileHandleIDSize:])
+}
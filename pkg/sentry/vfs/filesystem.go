@@ -16,10 +16,12 @@ package vfs
 
 import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
+	"gvisor.dev/gvisor/pkg/sync"
 )
 
 // A Filesystem is a tree of nodes represented by Dentries, which forms part of
@@ -41,6 +43,33 @@ type Filesystem struct {
 	// fsType is the FilesystemType of this Filesystem.
 	fsType FilesystemType
 
+	// freezable is true if fs may be frozen by Freeze. freezable is
+	// immutable after fs is initialized.
+	freezable bool
+
+	// freezeWriters is the number of in-progress write operations that have
+	// called StartWrite but not yet EndWrite. It is only maintained if
+	// freezable is true.
+	freezeWriters atomicbitops.Int64 `state:"nosave"`
+
+	// frozen is true if fs is frozen. frozen is only set if freezable is
+	// true. Frozen filesystems are thawed by save/restore.
+	frozen atomicbitops.Bool `state:"nosave"`
+
+	// freezeMu protects thawed and drained.
+	freezeMu sync.Mutex `state:"nosave"`
+
+	// thawed is non-nil while fs is frozen, and is closed when fs is thawed.
+	thawed chan struct{} `state:"nosave"`
+
+	// drained is non-nil while Freeze waits for in-progress write operations
+	// to finish, and is closed when freezeWriters reaches zero.
+	drained chan struct{} `state:"nosave"`
+
+	// handleID identifies fs in the file handles of its files. It is zero
+	// until the first file handle is encoded, and immutable afterwards.
+	handleID atomicbitops.Uint64
+
 	// impl is the FilesystemImpl associated with this Filesystem. impl is
 	// immutable. This should be the last field in Dentry.
 	impl FilesystemImpl
@@ -0,0 +1,156 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+)
+
+// SetFreezable marks fs as supporting Freeze. It must be called by the
+// FilesystemImpl before fs is used, and only if all operations that modify fs
+// call StartWrite or TryStartWrite before doing so, either directly or via
+// VirtualFilesystem and FileDescription methods.
+func (fs *Filesystem) SetFreezable() {
+	fs.freezable = true
+}
+
+// StartWrite must be called before an operation that modifies fs, without
+// holding any locks that may be required to read fs. If fs is frozen,
+// StartWrite blocks until fs is thawed or ctx is interrupted.
+//
+// If StartWrite succeeds, EndWrite must be called when the operation is
+// finished.
+//
+// StartWrite is analogous to Linux's sb_start_write(). Unlike
+// Mount.CheckBeginWrite, it must not be held for the lifetime of writable
+// file descriptions.
+func (fs *Filesystem) StartWrite(ctx context.Context) error {
+	for !fs.TryStartWrite() {
+		fs.freezeMu.Lock()
+		thawed := fs.thawed
+		fs.freezeMu.Unlock()
+		if thawed == nil {
+			// fs was thawed after TryStartWrite failed.
+			continue
+		}
+		if err := ctx.Block(thawed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TryStartWrite is equivalent to StartWrite, except that it returns false
+// instead of blocking if fs is frozen. It is used for modifications that may
+// be skipped on frozen filesystems, such as access time updates.
+func (fs *Filesystem) TryStartWrite() bool {
+	if !fs.freezable {
+		return true
+	}
+	fs.freezeWriters.Add(1)
+	if fs.frozen.Load() {
+		fs.EndWrite()
+		return false
+	}
+	return true
+}
+
+// EndWrite indicates that an operation signaled by a previous successful call
+// to StartWrite or TryStartWrite has finished.
+func (fs *Filesystem) EndWrite() {
+	if !fs.freezable {
+		return
+	}
+	if fs.freezeWriters.Add(-1) == 0 && fs.frozen.Load() {
+		fs.freezeMu.Lock()
+		fs.maybeDrainedLocked()
+		fs.freezeMu.Unlock()
+	}
+}
+
+// Preconditions: fs.freezeMu must be locked.
+func (fs *Filesystem) maybeDrainedLocked() {
+	if fs.drained != nil && fs.freezeWriters.Load() == 0 {
+		close(fs.drained)
+		fs.drained = nil
+	}
+}
+
+// Freeze blocks new operations that modify fs until Thaw is called, waits for
+// in-progress operations to finish, and then syncs fs, such that the state of
+// fs is consistent until it is thawed. It returns EOPNOTSUPP if fs doesn't
+// support freezing, and EBUSY if fs is already frozen.
+//
+// Writes through shared memory mappings of files in fs are not blocked.
+//
+// Freeze is analogous to Linux's fs/super.c:freeze_super().
+func (fs *Filesystem) Freeze(ctx context.Context) error {
+	if !fs.freezable {
+		return linuxerr.EOPNOTSUPP
+	}
+	fs.freezeMu.Lock()
+	if fs.thawed != nil {
+		fs.freezeMu.Unlock()
+		return linuxerr.EBUSY
+	}
+	fs.thawed = make(chan struct{})
+	drained := make(chan struct{})
+	fs.drained = drained
+	fs.frozen.Store(true)
+	fs.maybeDrainedLocked()
+	fs.freezeMu.Unlock()
+
+	if err := ctx.Block(drained); err != nil {
+		fs.freezeMu.Lock()
+		fs.drained = nil
+		fs.thawLocked()
+		fs.freezeMu.Unlock()
+		return err
+	}
+	if err := fs.impl.Sync(ctx); err != nil {
+		fs.freezeMu.Lock()
+		fs.thawLocked()
+		fs.freezeMu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Thaw unblocks operations that modify fs after a previous call to Freeze. It
+// returns EINVAL if fs is not frozen.
+//
+// Thaw is analogous to Linux's fs/super.c:thaw_super().
+func (fs *Filesystem) Thaw() error {
+	fs.freezeMu.Lock()
+	defer fs.freezeMu.Unlock()
+	// Like Linux, don't allow thawing a filesystem that is still being
+	// frozen.
+	if fs.thawed == nil || fs.drained != nil {
+		return linuxerr.EINVAL
+	}
+	fs.thawLocked()
+	return nil
+}
+
+// Preconditions: fs.freezeMu must be locked.
+func (fs *Filesystem) thawLocked() {
+	if fs.thawed == nil {
+		return
+	}
+	fs.frozen.Store(false)
+	close(fs.thawed)
+	fs.thawed = nil
+}
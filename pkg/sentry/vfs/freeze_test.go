@@ -0,0 +1,102 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/sentry/contexttest"
+)
+
+// blockedTimeout is how long tests wait to verify that an operation blocks.
+const blockedTimeout = 100 * time.Millisecond
+
+func TestFreezeNotFreezable(t *testing.T) {
+	ctx := contexttest.Context(t)
+	vfsObj := &VirtualFilesystem{}
+	if err := vfsObj.Init(ctx); err != nil {
+		t.Fatalf("VFS init: %v", err)
+	}
+	fs := vfsObj.anonMount.Filesystem()
+	if err := fs.Freeze(ctx); !linuxerr.Equals(linuxerr.EOPNOTSUPP, err) {
+		t.Errorf("Freeze: got error %v, want EOPNOTSUPP", err)
+	}
+	if err := fs.Thaw(); !linuxerr.Equals(linuxerr.EINVAL, err) {
+		t.Errorf("Thaw: got error %v, want EINVAL", err)
+	}
+}
+
+func TestFreezeThaw(t *testing.T) {
+	ctx := contexttest.Context(t)
+	vfsObj := &VirtualFilesystem{}
+	if err := vfsObj.Init(ctx); err != nil {
+		t.Fatalf("VFS init: %v", err)
+	}
+	fs := vfsObj.anonMount.Filesystem()
+	fs.SetFreezable()
+
+	// Freeze must wait for in-progress writes.
+	if err := fs.StartWrite(ctx); err != nil {
+		t.Fatalf("StartWrite: %v", err)
+	}
+	frozen := make(chan error, 1)
+	go func() {
+		frozen <- fs.Freeze(ctx)
+	}()
+	select {
+	case err := <-frozen:
+		t.Fatalf("Freeze returned %v with a write in progress", err)
+	case <-time.After(blockedTimeout):
+	}
+	fs.EndWrite()
+	if err := <-frozen; err != nil {
+		t.Fatalf("Freeze: %v", err)
+	}
+
+	if fs.TryStartWrite() {
+		t.Errorf("TryStartWrite succeeded on frozen filesystem")
+	}
+	if err := fs.Freeze(ctx); !linuxerr.Equals(linuxerr.EBUSY, err) {
+		t.Errorf("Freeze: got error %v, want EBUSY", err)
+	}
+
+	// New writes must wait for Thaw.
+	started := make(chan error, 1)
+	go func() {
+		started <- fs.StartWrite(ctx)
+	}()
+	select {
+	case err := <-started:
+		t.Fatalf("StartWrite returned %v on frozen filesystem", err)
+	case <-time.After(blockedTimeout):
+	}
+	if err := fs.Thaw(); err != nil {
+		t.Fatalf("Thaw: %v", err)
+	}
+	if err := <-started; err != nil {
+		t.Fatalf("StartWrite: %v", err)
+	}
+	fs.EndWrite()
+
+	if err := fs.Thaw(); !linuxerr.Equals(linuxerr.EINVAL, err) {
+		t.Errorf("Thaw: got error %v, want EINVAL", err)
+	}
+	if !fs.TryStartWrite() {
+		t.Errorf("TryStartWrite failed on thawed filesystem")
+	}
+	fs.EndWrite()
+}
@@ -17,6 +17,7 @@ package vfs
 import (
 	"bytes"
 	"fmt"
+	"sort"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/atomicbitops"
@@ -272,6 +273,24 @@ func (i *Inotify) Ioctl(ctx context.Context, uio usermem.IO, sysno uintptr, args
 	}
 }
 
+// FDInfo implements FileDescriptionImplFDInfoExtension.FDInfo.
+func (i *Inotify) FDInfo(ctx context.Context, buf *bytes.Buffer) {
+	i.mu.Lock()
+	watches := make([]*Watch, 0, len(i.watches))
+	for _, w := range i.watches {
+		watches = append(watches, w)
+	}
+	i.mu.Unlock()
+	sort.Slice(watches, func(a, b int) bool {
+		return watches[a].wd < watches[b].wd
+	})
+
+	// Linux: fs/notify/fdinfo.c:inotify_fdinfo()
+	for _, w := range watches {
+		fmt.Fprintf(buf, "inotify wd:%x ino:%x sdev:%x mask:%x ignored_mask:0\n", w.wd, w.ino, kernelDevID(w.devMajor, w.devMinor), w.mask.Load())
+	}
+}
+
 func (i *Inotify) queueEvent(ev *Event) {
 	i.evMu.Lock()
 
@@ -299,12 +318,15 @@ func (i *Inotify) queueEvent(ev *Event) {
 // newWatchLocked creates and adds a new watch to target.
 //
 // Precondition: i.mu must be locked. ws must be the watch set for target d.
-func (i *Inotify) newWatchLocked(d *Dentry, ws *Watches, mask uint32) *Watch {
+func (i *Inotify) newWatchLocked(d *Dentry, ws *Watches, mask uint32, stat *linux.Statx) *Watch {
 	w := &Watch{
-		owner:  i,
-		wd:     i.nextWatchIDLocked(),
-		target: d,
-		mask:   atomicbitops.FromUint32(mask),
+		owner:    i,
+		wd:       i.nextWatchIDLocked(),
+		target:   d,
+		ino:      stat.Ino,
+		devMajor: stat.DevMajor,
+		devMinor: stat.DevMinor,
+		mask:     atomicbitops.FromUint32(mask),
 	}
 
 	// Hold the watch in this inotify instance as well as the watch set on the
@@ -323,10 +345,12 @@ func (i *Inotify) nextWatchIDLocked() int32 {
 }
 
 // AddWatch constructs a new inotify watch and adds it to the target. It
-// returns the watch descriptor returned by inotify_add_watch(2).
+// returns the watch descriptor returned by inotify_add_watch(2). stat must
+// contain the target's inode and device numbers, which are reported in
+// /proc/[pid]/fdinfo.
 //
 // The caller must hold a reference on target.
-func (i *Inotify) AddWatch(target *Dentry, mask uint32) int32 {
+func (i *Inotify) AddWatch(target *Dentry, mask uint32, stat *linux.Statx) int32 {
 	// Note: Locking this inotify instance protects the result returned by
 	// Lookup() below. With the lock held, we know for sure the lookup result
 	// won't become stale because it's impossible for *this* instance to
@@ -348,7 +372,7 @@ func (i *Inotify) AddWatch(target *Dentry, mask uint32) int32 {
 	}
 
 	// No existing watch, create a new watch.
-	w := i.newWatchLocked(target, ws, mask)
+	w := i.newWatchLocked(target, ws, mask, stat)
 	return w.wd
 }
 
@@ -558,6 +582,14 @@ type Watch struct {
 	// This field is immutable after creation.
 	target *Dentry
 
+	// ino, devMajor, and devMinor are the inode and device numbers of the
+	// watch target, reported in /proc/[pid]/fdinfo.
+	//
+	// These fields are immutable after creation.
+	ino      uint64
+	devMajor uint32
+	devMinor uint32
+
 	// Events being monitored via this watch.
 	mask atomicbitops.Uint32
 
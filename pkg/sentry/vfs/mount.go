@@ -606,6 +606,61 @@ func (vfs *VirtualFilesystem) BindAt(ctx context.Context, creds *auth.Credential
 	return nil
 }
 
+// MoveMountAt moves the mount at source, along with all mounts stacked under
+// it, to target. It is analogous to fs/namespace.c:do_move_mount() in Linux,
+// except that moves involving shared mounts are not supported.
+func (vfs *VirtualFilesystem) MoveMountAt(ctx context.Context, creds *auth.Credentials, source, target *PathOperation) error {
+	sourceVd, err := vfs.GetDentryAt(ctx, creds, source, &GetDentryOptions{})
+	if err != nil {
+		return err
+	}
+	defer sourceVd.DecRef(ctx)
+	targetVd, err := vfs.GetDentryAt(ctx, creds, target, &GetDentryOptions{})
+	if err != nil {
+		return err
+	}
+
+	vfs.lockMounts()
+	defer vfs.unlockMounts(ctx)
+	mp, err := vfs.lockMountpoint(targetVd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		mp.dentry.mu.Unlock()
+		vfs.delayDecRef(mp) // +checklocksforce
+	}()
+
+	mnt := sourceVd.mount
+	// The source must be the root of an attached mount in the caller's mount
+	// namespace, and so must the target.
+	if sourceVd.dentry != mnt.root || mnt.umounted || mnt.parent() == nil || mnt.locked {
+		return linuxerr.EINVAL
+	}
+	if !vfs.validInMountNS(ctx, mnt) || !vfs.validInMountNS(ctx, mp.mount) {
+		return linuxerr.EINVAL
+	}
+	// Moving the mount would require propagating its removal from the
+	// source's peers and its addition to the target's peers.
+	if mnt.parent().isShared || mp.mount.isShared {
+		return linuxerr.EINVAL
+	}
+	// A mount can't be moved underneath itself.
+	for m := mp.mount; m != nil; m = m.parent() {
+		if m == mnt {
+			return linuxerr.ELOOP
+		}
+	}
+
+	vfs.mounts.seq.BeginWrite()
+	vfs.delayDecRef(vfs.disconnectLocked(mnt))
+	vfs.delayDecRef(mnt)
+	mp.IncRef()
+	vfs.connectLocked(mnt, mp, mp.mount.ns)
+	vfs.mounts.seq.EndWrite()
+	return nil
+}
+
 // RemountAt changes the mountflags and data of an existing mount without having to unmount and remount the filesystem.
 func (vfs *VirtualFilesystem) RemountAt(ctx context.Context, creds *auth.Credentials, pop *PathOperation, opts *MountOptions) error {
 	vd, err := vfs.getMountpoint(ctx, creds, pop)
@@ -1204,8 +1259,14 @@ func (vfs *VirtualFilesystem) PivotRoot(ctx context.Context, creds *auth.Credent
 	if !vfs.validInMountNS(ctx, oldRoot.mount) || !vfs.validInMountNS(ctx, newRoot.mount) {
 		return newRoot, oldRoot, linuxerr.EINVAL
 	}
-	// The current root and the new root cannot be on the rootfs mount.
-	if oldRoot.mount.parent() == nil || newRoot.mount.parent() == nil {
+	// The new root cannot be on the rootfs mount. Unlike Linux, the root
+	// mount of a namespace is not stacked on top of rootfs, so it may be
+	// pivoted away from; this is what container runtimes do after
+	// unshare(CLONE_NEWNS).
+	if newRoot.mount.parent() == nil {
+		return newRoot, oldRoot, linuxerr.EINVAL
+	}
+	if oldRoot.mount.parent() == nil && oldRoot.mount != oldRoot.mount.ns.root {
 		return newRoot, oldRoot, linuxerr.EINVAL
 	}
 	// Either the mount point at new_root, or the parent mount of that mount
@@ -1222,6 +1283,25 @@ func (vfs *VirtualFilesystem) PivotRoot(ctx context.Context, creds *auth.Credent
 	vfs.mounts.seq.BeginWrite()
 	mp := vfs.disconnectLocked(newRoot.mount)
 	vfs.delayDecRef(mp)
+	if ns := oldRoot.mount.ns; oldRoot.mount == ns.root {
+		// newRoot becomes the namespace root, and keeps the reference that
+		// was held by its mount point.
+		ns.root = newRoot.mount
+		if oldRoot.mount.locked {
+			newRoot.mount.locked = true
+			oldRoot.mount.locked = false
+		}
+		putOld.IncRef()
+		vfs.connectLocked(oldRoot.mount, putOld, putOld.mount.ns)
+		putOld.dentry.mu.Unlock()
+		vfs.mounts.seq.EndWrite()
+
+		vfs.delayDecRef(oldRoot.mount)
+
+		newRoot.IncRef()
+		oldRoot.IncRef()
+		return
+	}
 	rootMp := vfs.disconnectLocked(oldRoot.mount)
 	if oldRoot.mount.locked {
 		newRoot.mount.locked = true
@@ -38,6 +38,12 @@ type MkdirOptions struct {
 	// Mode is the file mode bits for the created directory.
 	Mode linux.FileMode
 
+	// Umasked is the set of mode bits that were removed from Mode by the
+	// umask of the creating process. Filesystems that support default POSIX
+	// ACLs restore these bits if the parent directory has a default ACL,
+	// which replaces the umask.
+	Umasked linux.FileMode
+
 	// If ForSyntheticMountpoint is true, FilesystemImpl.MkdirAt() may create
 	// the given directory in memory only (as opposed to persistent storage).
 	// The created directory should be able to support the creation of
@@ -66,6 +72,12 @@ type MknodOptions struct {
 	// Mode is the file type and mode bits for the created file.
 	Mode linux.FileMode
 
+	// Umasked is the set of mode bits that were removed from Mode by the
+	// umask of the creating process. Filesystems that support default POSIX
+	// ACLs restore these bits if the parent directory has a default ACL,
+	// which replaces the umask.
+	Umasked linux.FileMode
+
 	// If Mode specifies a character or block device special file, DevMajor and
 	// DevMinor are the major and minor device numbers for the created device.
 	DevMajor uint32
@@ -137,6 +149,12 @@ type OpenOptions struct {
 	// created file.
 	Mode linux.FileMode
 
+	// Umasked is the set of mode bits that were removed from Mode by the
+	// umask of the creating process. Filesystems that support default POSIX
+	// ACLs restore these bits if the parent directory has a default ACL,
+	// which replaces the umask.
+	Umasked linux.FileMode
+
 	// FileExec is set when the file is being opened to be executed.
 	// VirtualFilesystem.OpenAt() checks that the caller has execute permissions
 	// on the file, that the file is a regular file, and that the mount doesn't
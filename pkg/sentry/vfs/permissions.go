@@ -61,16 +61,31 @@ func (a AccessTypes) MayExec() bool {
 // file with the given permissions, UID, and GID, subject to the rules of
 // fs/namei.c:generic_permission().
 func GenericCheckPermissions(creds *auth.Credentials, ats AccessTypes, mode linux.FileMode, kuid auth.KUID, kgid auth.KGID) error {
-	// Check permission bits.
-	perms := uint16(mode.Permissions())
-	if creds.EffectiveKUID == kuid {
-		perms >>= 6
-	} else if creds.InGroup(kgid) {
-		perms >>= 3
-	}
-	if uint16(ats)&perms == uint16(ats) {
-		// All permission bits match, access granted.
-		return nil
+	return GenericCheckPermissionsACL(creds, ats, mode, kuid, kgid, nil /* acl */)
+}
+
+// GenericCheckPermissionsACL is equivalent to GenericCheckPermissions, except
+// that the group and other permission bits of mode are replaced by the access
+// ACL acl if it is not nil.
+func GenericCheckPermissionsACL(creds *auth.Credentials, ats AccessTypes, mode linux.FileMode, kuid auth.KUID, kgid auth.KGID, acl *ACL) error {
+	if acl != nil && creds.EffectiveKUID != kuid {
+		// The ACL_USER_OBJ entry is always reflected by the owner permission
+		// bits, so the ACL only needs to be consulted for other users.
+		if acl.permits(creds, ats, kgid) {
+			return nil
+		}
+	} else {
+		// Check permission bits.
+		perms := uint16(mode.Permissions())
+		if creds.EffectiveKUID == kuid {
+			perms >>= 6
+		} else if creds.InGroup(kgid) {
+			perms >>= 3
+		}
+		if uint16(ats)&perms == uint16(ats) {
+			// All permission bits match, access granted.
+			return nil
+		}
 	}
 
 	// Caller capabilities require that the file's KUID and KGID are mapped in
@@ -219,7 +234,7 @@ func CheckSetStat(ctx context.Context, creds *auth.Credentials, opts *SetStatOpt
 			return linuxerr.EPERM
 		}
 	}
-	if opts.NeedWritePerm && !creds.HasCapability(linux.CAP_DAC_OVERRIDE) {
+	if opts.NeedWritePerm && !HasCapabilityOnFile(creds, linux.CAP_DAC_OVERRIDE, kuid, kgid) {
 		if err := GenericCheckPermissions(creds, MayWrite, mode, kuid, kgid); err != nil {
 			return err
 		}
@@ -300,9 +315,9 @@ func CheckLimit(ctx context.Context, offset, size int64) (int64, error) {
 func CheckXattrPermissions(creds *auth.Credentials, ats AccessTypes, mode linux.FileMode, kuid auth.KUID, name string) error {
 	switch {
 	case strings.HasPrefix(name, linux.XATTR_TRUSTED_PREFIX):
-		// The trusted.* namespace can only be accessed by privileged
-		// users.
-		if creds.HasCapability(linux.CAP_SYS_ADMIN) {
+		// The trusted.* namespace can only be accessed by users that are
+		// privileged in the root user namespace, like Linux's capable().
+		if creds.HasCapabilityIn(linux.CAP_SYS_ADMIN, creds.UserNamespace.Root()) {
 			return nil
 		}
 		if ats.MayWrite() {
@@ -153,6 +153,13 @@ type VirtualFilesystem struct {
 	// groupIDBitmap tracks which mount group IDs are available for allocation.
 	groupIDBitmap bitmap.Bitmap
 
+	// openFiles is the number of FileDescriptions that have been initialized
+	// and not yet released. openFiles is accessed using atomic memory
+	// operations.
+	//
+	// openFiles is analogous to Linux's nr_files.
+	openFiles atomicbitops.Int64
+
 	// mountPromises contains all unresolved mount promises.
 	mountPromises sync.Map `state:".(map[VirtualDentry]*mountPromise)"`
 
@@ -210,6 +217,12 @@ func (vfs *VirtualFilesystem) Release(ctx context.Context) {
 	}
 }
 
+// OpenFiles returns the number of open FileDescriptions, as reported by
+// /proc/sys/fs/file-nr.
+func (vfs *VirtualFilesystem) OpenFiles() int64 {
+	return vfs.openFiles.Load()
+}
+
 // PathOperation specifies the path operated on by a VFS method.
 //
 // PathOperation is passed to VFS methods by pointer to reduce memory copying:
@@ -333,7 +346,12 @@ func (vfs *VirtualFilesystem) LinkAt(ctx context.Context, creds *auth.Credential
 	rp := vfs.getResolvingPath(creds, newpop)
 	for {
 		vfs.maybeBlockOnMountPromise(ctx, rp)
-		err := rp.mount.fs.impl.LinkAt(ctx, rp, oldVD)
+		fs := rp.mount.fs
+		err := fs.StartWrite(ctx)
+		if err == nil {
+			err = fs.impl.LinkAt(ctx, rp, oldVD)
+			fs.EndWrite()
+		}
 		if err == nil {
 			rp.Release(ctx)
 			oldVD.DecRef(ctx)
@@ -373,7 +391,12 @@ func (vfs *VirtualFilesystem) MkdirAt(ctx context.Context, creds *auth.Credentia
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
 		vfs.maybeBlockOnMountPromise(ctx, rp)
-		err := rp.mount.fs.impl.MkdirAt(ctx, rp, *opts)
+		fs := rp.mount.fs
+		err := fs.StartWrite(ctx)
+		if err == nil {
+			err = fs.impl.MkdirAt(ctx, rp, *opts)
+			fs.EndWrite()
+		}
 		if err == nil {
 			rp.Release(ctx)
 			return nil
@@ -409,7 +432,12 @@ func (vfs *VirtualFilesystem) MknodAt(ctx context.Context, creds *auth.Credentia
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
 		vfs.maybeBlockOnMountPromise(ctx, rp)
-		err := rp.mount.fs.impl.MknodAt(ctx, rp, *opts)
+		fs := rp.mount.fs
+		err := fs.StartWrite(ctx)
+		if err == nil {
+			err = fs.impl.MknodAt(ctx, rp, *opts)
+			fs.EndWrite()
+		}
 		if err == nil {
 			rp.Release(ctx)
 			return nil
@@ -474,9 +502,24 @@ func (vfs *VirtualFilesystem) OpenAt(ctx context.Context, creds *auth.Credential
 	if opts.Flags&linux.O_DIRECTORY != 0 {
 		rp.mustBeDir = true
 	}
+	// Opening a file may create or truncate it.
+	writes := opts.Flags&(linux.O_CREAT|linux.O_TRUNC) != 0
 	for {
 		vfs.maybeBlockOnMountPromise(ctx, rp)
-		fd, err := rp.mount.fs.impl.OpenAt(ctx, rp, *opts)
+		var (
+			fd  *FileDescription
+			err error
+		)
+		fs := rp.mount.fs
+		if writes {
+			err = fs.StartWrite(ctx)
+		}
+		if err == nil {
+			fd, err = fs.impl.OpenAt(ctx, rp, *opts)
+			if writes {
+				fs.EndWrite()
+			}
+		}
 		if err == nil {
 			rp.Release(ctx)
 
@@ -571,7 +614,12 @@ func (vfs *VirtualFilesystem) RenameAt(ctx context.Context, creds *auth.Credenti
 	}
 	for {
 		vfs.maybeBlockOnMountPromise(ctx, rp)
-		err := rp.mount.fs.impl.RenameAt(ctx, rp, oldParentVD, oldName, renameOpts)
+		fs := rp.mount.fs
+		err := fs.StartWrite(ctx)
+		if err == nil {
+			err = fs.impl.RenameAt(ctx, rp, oldParentVD, oldName, renameOpts)
+			fs.EndWrite()
+		}
 		if err == nil {
 			rp.Release(ctx)
 			oldParentVD.DecRef(ctx)
@@ -608,7 +656,12 @@ func (vfs *VirtualFilesystem) RmdirAt(ctx context.Context, creds *auth.Credentia
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
 		vfs.maybeBlockOnMountPromise(ctx, rp)
-		err := rp.mount.fs.impl.RmdirAt(ctx, rp)
+		fs := rp.mount.fs
+		err := fs.StartWrite(ctx)
+		if err == nil {
+			err = fs.impl.RmdirAt(ctx, rp)
+			fs.EndWrite()
+		}
 		if err == nil {
 			rp.Release(ctx)
 			return nil
@@ -630,7 +683,12 @@ func (vfs *VirtualFilesystem) SetStatAt(ctx context.Context, creds *auth.Credent
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
 		vfs.maybeBlockOnMountPromise(ctx, rp)
-		err := rp.mount.fs.impl.SetStatAt(ctx, rp, *opts)
+		fs := rp.mount.fs
+		err := fs.StartWrite(ctx)
+		if err == nil {
+			err = fs.impl.SetStatAt(ctx, rp, *opts)
+			fs.EndWrite()
+		}
 		if err == nil {
 			rp.Release(ctx)
 			return nil
@@ -696,7 +754,12 @@ func (vfs *VirtualFilesystem) SymlinkAt(ctx context.Context, creds *auth.Credent
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
 		vfs.maybeBlockOnMountPromise(ctx, rp)
-		err := rp.mount.fs.impl.SymlinkAt(ctx, rp, target)
+		fs := rp.mount.fs
+		err := fs.StartWrite(ctx)
+		if err == nil {
+			err = fs.impl.SymlinkAt(ctx, rp, target)
+			fs.EndWrite()
+		}
 		if err == nil {
 			rp.Release(ctx)
 			return nil
@@ -731,7 +794,12 @@ func (vfs *VirtualFilesystem) UnlinkAt(ctx context.Context, creds *auth.Credenti
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
 		vfs.maybeBlockOnMountPromise(ctx, rp)
-		err := rp.mount.fs.impl.UnlinkAt(ctx, rp)
+		fs := rp.mount.fs
+		err := fs.StartWrite(ctx)
+		if err == nil {
+			err = fs.impl.UnlinkAt(ctx, rp)
+			fs.EndWrite()
+		}
 		if err == nil {
 			rp.Release(ctx)
 			return nil
@@ -820,7 +888,12 @@ func (vfs *VirtualFilesystem) SetXattrAt(ctx context.Context, creds *auth.Creden
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
 		vfs.maybeBlockOnMountPromise(ctx, rp)
-		err := rp.mount.fs.impl.SetXattrAt(ctx, rp, *opts)
+		fs := rp.mount.fs
+		err := fs.StartWrite(ctx)
+		if err == nil {
+			err = fs.impl.SetXattrAt(ctx, rp, *opts)
+			fs.EndWrite()
+		}
 		if err == nil {
 			rp.Release(ctx)
 			return nil
@@ -837,7 +910,12 @@ func (vfs *VirtualFilesystem) RemoveXattrAt(ctx context.Context, creds *auth.Cre
 	rp := vfs.getResolvingPath(creds, pop)
 	for {
 		vfs.maybeBlockOnMountPromise(ctx, rp)
-		err := rp.mount.fs.impl.RemoveXattrAt(ctx, rp, name)
+		fs := rp.mount.fs
+		err := fs.StartWrite(ctx)
+		if err == nil {
+			err = fs.impl.RemoveXattrAt(ctx, rp, name)
+			fs.EndWrite()
+		}
 		if err == nil {
 			rp.Release(ctx)
 			return nil
@@ -0,0 +1,257 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchdog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/eventchannel"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/stall"
+
+	pb "gvisor.dev/gvisor/pkg/sentry/watchdog/stall_event_go_proto"
+)
+
+// StallAction is a set of actions to take when a stalled subsystem is
+// detected.
+type StallAction int
+
+const (
+	// StallLog logs a warning message followed by a stack dump of all
+	// goroutines.
+	StallLog StallAction = 1 << iota
+
+	// StallEvent emits a StallEvent on the eventchannel.
+	StallEvent
+
+	// StallCheckpoint captures a statefile snapshot of the sandbox using
+	// Opts.Checkpoint. The sandbox resumes once the snapshot is taken.
+	StallCheckpoint
+)
+
+var stallActionNames = []struct {
+	action StallAction
+	name   string
+}{
+	{StallLog, "log"},
+	{StallEvent, "event"},
+	{StallCheckpoint, "checkpoint"},
+}
+
+// ParseStallActions parses a comma-separated list of stall actions, e.g.
+// "log,event".
+func ParseStallActions(v string) (StallAction, error) {
+	var a StallAction
+	if v == "" {
+		return a, nil
+	}
+	for _, name := range strings.Split(v, ",") {
+		found := false
+		for _, n := range stallActionNames {
+			if n.name == name {
+				a |= n.action
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid watchdog stall action %q", name)
+		}
+	}
+	return a, nil
+}
+
+// String returns the StallAction's string representation, which can be
+// parsed by ParseStallActions.
+func (a StallAction) String() string {
+	var names []string
+	for _, n := range stallActionNames {
+		if a&n.action != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// ParseStallTimeouts parses a comma-separated list of <subsystem>=<duration>
+// pairs, e.g. "gofer=30s,netstack=10s". Subsystems must name a registered
+// stall detector.
+func ParseStallTimeouts(v string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	if v == "" {
+		return timeouts, nil
+	}
+	for _, pair := range strings.Split(v, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid watchdog stall timeout %q, must be <subsystem>=<duration>", pair)
+		}
+		if stall.Lookup(name) == nil {
+			return nil, fmt.Errorf("invalid watchdog stall subsystem %q, must be one of: %s", name, strings.Join(stall.Names(), ", "))
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid watchdog stall timeout for %q: %w", name, err)
+		}
+		if timeout <= 0 {
+			return nil, fmt.Errorf("invalid watchdog stall timeout for %q: %v must be positive", name, timeout)
+		}
+		timeouts[name] = timeout
+	}
+	return timeouts, nil
+}
+
+// stallPeriod returns how often stall detectors should be checked.
+func stallPeriod(timeouts map[string]time.Duration) time.Duration {
+	var period time.Duration
+	for _, timeout := range timeouts {
+		if period == 0 || timeout < period {
+			period = timeout
+		}
+	}
+	// 4 is arbitrary, as for the task timeout.
+	return period / 4
+}
+
+// setStallDetectorsEnabled enables or disables all stall detectors configured
+// in w.StallTimeouts.
+func (w *Watchdog) setStallDetectorsEnabled(enabled bool) {
+	for name := range w.StallTimeouts {
+		if d := stall.Lookup(name); d != nil {
+			d.SetEnabled(enabled)
+		}
+	}
+}
+
+// startStallLoop starts the stall detection goroutine, if stall detection is
+// configured and it's not running yet.
+//
+// Preconditions: w.mu is locked.
+func (w *Watchdog) startStallLoop() {
+	if w.stallRunning || len(w.StallTimeouts) == 0 {
+		return
+	}
+	w.setStallDetectorsEnabled(true)
+	log.Infof("Starting watchdog stall detection, period: %v, timeouts: %v, actions: %v", w.stallPeriod, w.StallTimeouts, w.StallActions)
+	go w.stallLoop() // S/R-SAFE: watchdog is stopped during save and restarted after restore.
+	w.stallRunning = true
+}
+
+// stopStallLoop stops the stall detection goroutine and waits for it.
+//
+// Preconditions: w.mu is locked.
+func (w *Watchdog) stopStallLoop() {
+	if !w.stallRunning {
+		return
+	}
+	w.stallStop <- struct{}{}
+	<-w.stallDone
+	w.setStallDetectorsEnabled(false)
+	w.stalled = make(map[string]struct{})
+	w.stallRunning = false
+}
+
+// stallLoop periodically checks all stall detectors. It only returns when
+// 'Stop()' is called.
+func (w *Watchdog) stallLoop() {
+	for {
+		select {
+		case <-w.stallStop:
+			w.stallDone <- struct{}{}
+			return
+		case <-time.After(w.stallPeriod):
+			w.runStallTurn(time.Now())
+		}
+	}
+}
+
+// runStallTurn checks all configured stall detectors and reports subsystems
+// that became stalled since the last turn. Subsystems that remain stalled are
+// not reported again until they recover.
+func (w *Watchdog) runStallTurn(now time.Time) {
+	names := make([]string, 0, len(w.StallTimeouts))
+	for name := range w.StallTimeouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stalled := make(map[string]struct{})
+	for _, name := range names {
+		d := stall.Lookup(name)
+		if d == nil {
+			continue
+		}
+		timeout := w.StallTimeouts[name]
+		oldest, inflight := d.Oldest(now)
+		if inflight == 0 || oldest <= timeout {
+			continue
+		}
+		stalled[name] = struct{}{}
+		if _, ok := w.stalled[name]; ok {
+			continue
+		}
+		w.reportStall(name, inflight, oldest, timeout)
+	}
+	w.stalled = stalled
+}
+
+// reportStall takes the configured stall actions for the given subsystem.
+func (w *Watchdog) reportStall(name string, inflight int, oldest, timeout time.Duration) {
+	msg := fmt.Sprintf("Sentry detected stalled subsystem %q: %d operation(s) in flight, oldest started %v ago (timeout: %v)", name, inflight, oldest, timeout)
+	if w.StallActions&StallLog != 0 {
+		log.TracebackAll(msg)
+	} else {
+		log.Warningf("%s", msg)
+	}
+	if w.StallActions&StallEvent != 0 {
+		eventchannel.Emit(&pb.StallEvent{
+			Subsystem: name,
+			Inflight:  uint64(inflight),
+			OldestMs:  uint64(oldest.Milliseconds()),
+			TimeoutMs: uint64(timeout.Milliseconds()),
+		})
+	}
+	if w.StallActions&StallCheckpoint != 0 {
+		w.checkpoint(msg)
+	}
+}
+
+// checkpoint captures a statefile snapshot using w.Checkpoint. At most one
+// snapshot is in progress at a time.
+//
+// The snapshot is taken asynchronously: saving stops the watchdog, which
+// waits for the stall detection goroutine.
+func (w *Watchdog) checkpoint(reason string) {
+	if w.Checkpoint == nil {
+		log.Warningf("Watchdog stall checkpoint requested, but no checkpoint is configured")
+		return
+	}
+	if !w.checkpointing.CompareAndSwap(false, true) {
+		log.Infof("Watchdog stall checkpoint already in progress")
+		return
+	}
+	go func() { // S/R-SAFE: the goroutine is the one saving.
+		defer w.checkpointing.Store(false)
+		log.Infof("Watchdog capturing stall checkpoint")
+		if err := w.Checkpoint(reason); err != nil {
+			log.Warningf("Watchdog stall checkpoint failed: %v", err)
+			return
+		}
+		log.Infof("Watchdog stall checkpoint captured")
+	}()
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchdog
+
+import (
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/stall"
+)
+
+func TestParseStallTimeouts(t *testing.T) {
+	got, err := ParseStallTimeouts("gofer=30s,reclaim=1m")
+	if err != nil {
+		t.Fatalf("ParseStallTimeouts(): %v", err)
+	}
+	if len(got) != 2 || got["gofer"] != 30*time.Second || got["reclaim"] != time.Minute {
+		t.Errorf("ParseStallTimeouts() = %v", got)
+	}
+	for _, v := range []string{"gofer", "unknown=1s", "gofer=abc", "gofer=0s"} {
+		if _, err := ParseStallTimeouts(v); err == nil {
+			t.Errorf("ParseStallTimeouts(%q) should have failed", v)
+		}
+	}
+}
+
+func TestParseStallActions(t *testing.T) {
+	a, err := ParseStallActions("event,log")
+	if err != nil {
+		t.Fatalf("ParseStallActions(): %v", err)
+	}
+	if a != StallLog|StallEvent {
+		t.Errorf("ParseStallActions() = %v, want log,event", a)
+	}
+	if a.String() != "log,event" {
+		t.Errorf("String() = %q, want %q", a.String(), "log,event")
+	}
+	if _, err := ParseStallActions("log,reboot"); err == nil {
+		t.Errorf("ParseStallActions() of an invalid action should have failed")
+	}
+}
+
+func TestStallCheckpoint(t *testing.T) {
+	d := stall.Register("test-checkpoint")
+	d.SetEnabled(true)
+	defer d.SetEnabled(false)
+
+	reasons := make(chan string, 2)
+	w := New(nil, Opts{
+		StallTimeouts: map[string]time.Duration{d.Name(): time.Second},
+		StallActions:  StallCheckpoint,
+		Checkpoint: func(reason string) error {
+			reasons <- reason
+			return nil
+		},
+	})
+
+	id := d.Begin()
+	w.runStallTurn(time.Now())
+	select {
+	case r := <-reasons:
+		t.Fatalf("checkpoint taken before the stall timeout: %s", r)
+	default:
+	}
+
+	later := time.Now().Add(time.Minute)
+	w.runStallTurn(later)
+	select {
+	case <-reasons:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("checkpoint not taken for stalled subsystem")
+	}
+
+	// A subsystem that remains stalled is only reported once.
+	w.runStallTurn(later)
+	d.End(id)
+	w.runStallTurn(later)
+	select {
+	case r := <-reasons:
+		t.Errorf("checkpoint taken again for the same stall: %s", r)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -26,6 +26,13 @@
 //     If a tasks continues to be stuck, the message will repeat every minute, unless
 //     a new stuck task is detected
 //  2. Panic: same as above, followed by panic()
+//
+// The watchdog also monitors the stall detectors of subsystems such as gofer
+// RPCs, netstack processing and memory reclaim (see package stall). A
+// subsystem is stalled when one of its operations has been in flight for
+// longer than the subsystem's stall timeout, in which case the watchdog can
+// dump all goroutines, emit a StallEvent on the eventchannel and capture a
+// statefile snapshot, as configured by StallActions.
 package watchdog
 
 import (
@@ -34,6 +41,7 @@ import (
 	"time"
 
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/metric"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
@@ -58,6 +66,19 @@ type Opts struct {
 	// StartupTimeoutAction indicates what action to take when
 	// watchdog.Start is not called within the timeout.
 	StartupTimeoutAction Action
+
+	// StallTimeouts maps the name of a stall detector to the amount of time
+	// an operation of that subsystem may be in flight before the subsystem
+	// is declared stalled. Stall detection is disabled if empty.
+	StallTimeouts map[string]time.Duration
+
+	// StallActions indicates what actions to take when a stalled subsystem
+	// is detected.
+	StallActions StallAction
+
+	// Checkpoint captures a statefile snapshot of the sandbox for the
+	// StallCheckpoint action. reason describes the stall.
+	Checkpoint func(reason string) error
 }
 
 // DefaultOpts is a default set of options for the watchdog.
@@ -158,6 +179,28 @@ type Watchdog struct {
 	// startCalled is true if Start has ever been called. It remains true
 	// even if Stop is called.
 	startCalled bool
+
+	// stallPeriod indicates how often to check stall detectors. It's
+	// calculated based on opts.StallTimeouts.
+	stallPeriod time.Duration
+
+	// stallStop is used to notify the stall detection goroutine to stop.
+	stallStop chan struct{}
+
+	// stallDone is used to notify when the stall detection goroutine has
+	// stopped.
+	stallDone chan struct{}
+
+	// stallRunning is true if the stall detection goroutine is running.
+	// Protected by mu.
+	stallRunning bool
+
+	// stalled contains the names of all subsystems that are currently
+	// stalled. It's only accessed by the stall detection goroutine.
+	stalled map[string]struct{}
+
+	// checkpointing is true while a stall checkpoint is being captured.
+	checkpointing atomicbitops.Bool
 }
 
 type offender struct {
@@ -175,6 +218,11 @@ func New(k *kernel.Kernel, opts Opts) *Watchdog {
 		offenders: make(map[*kernel.Task]*offender),
 		stop:      make(chan struct{}),
 		done:      make(chan struct{}),
+
+		stallPeriod: stallPeriod(opts.StallTimeouts),
+		stallStop:   make(chan struct{}),
+		stallDone:   make(chan struct{}),
+		stalled:     make(map[string]struct{}),
 	}
 
 	// Handle StartupTimeout if it exists.
@@ -191,6 +239,7 @@ func (w *Watchdog) Start() {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	w.startCalled = true
+	w.startStallLoop()
 
 	if w.running {
 		return
@@ -209,12 +258,13 @@ func (w *Watchdog) Start() {
 
 // Stop requests the watchdog to stop and wait for it.
 func (w *Watchdog) Stop() {
-	if w.TaskTimeout == 0 {
+	if w.TaskTimeout == 0 && len(w.StallTimeouts) == 0 {
 		return
 	}
 
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	w.stopStallLoop()
 	if !w.running {
 		return
 	}
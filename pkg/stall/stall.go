@@ -0,0 +1,159 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stall tracks in-flight operations of long running subsystems, so
+// that a watchdog can detect subsystems which stop making progress.
+//
+// Subsystems bracket each unit of work with Begin and End. Detectors are
+// disabled by default, in which case Begin and End reduce to an atomic load.
+package stall
+
+import (
+	"sort"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+// Detectors for the subsystems instrumented in gVisor.
+var (
+	// Gofer tracks RPCs made to the gofer.
+	Gofer = Register("gofer")
+
+	// Netstack tracks TCP segment processing by the netstack processors.
+	Netstack = Register("netstack")
+
+	// Reclaim tracks decommits made by the memory file reclaimer.
+	Reclaim = Register("reclaim")
+)
+
+// Detector tracks the in-flight operations of a single subsystem.
+type Detector struct {
+	name string
+
+	// enabled is true if operations are being tracked.
+	enabled atomicbitops.Bool
+
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// nextID is the ID assigned to the next operation. It starts at 1, since
+	// 0 is returned by Begin when the detector is disabled.
+	nextID uint64
+
+	// inflight maps the ID of each in-flight operation to its start time.
+	inflight map[uint64]time.Time
+}
+
+var (
+	detectorsMu sync.Mutex
+	detectors   = make(map[string]*Detector)
+)
+
+// Register returns the detector with the given name, creating it if it
+// doesn't exist yet.
+func Register(name string) *Detector {
+	detectorsMu.Lock()
+	defer detectorsMu.Unlock()
+	if d, ok := detectors[name]; ok {
+		return d
+	}
+	d := &Detector{
+		name:     name,
+		nextID:   1,
+		inflight: make(map[uint64]time.Time),
+	}
+	detectors[name] = d
+	return d
+}
+
+// Lookup returns the detector with the given name, or nil if none exists.
+func Lookup(name string) *Detector {
+	detectorsMu.Lock()
+	defer detectorsMu.Unlock()
+	return detectors[name]
+}
+
+// Names returns the sorted names of all registered detectors.
+func Names() []string {
+	detectorsMu.Lock()
+	defer detectorsMu.Unlock()
+	names := make([]string, 0, len(detectors))
+	for name := range detectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Name returns the name of the detector.
+func (d *Detector) Name() string {
+	return d.name
+}
+
+// SetEnabled enables or disables tracking of operations. Operations in flight
+// when the detector is disabled are forgotten.
+func (d *Detector) SetEnabled(enabled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.enabled.Store(enabled)
+	if !enabled {
+		clear(d.inflight)
+	}
+}
+
+// Enabled returns true if operations are being tracked.
+func (d *Detector) Enabled() bool {
+	return d.enabled.Load()
+}
+
+// Begin records the start of an operation and returns its ID, which must be
+// passed to End once the operation completes.
+func (d *Detector) Begin() uint64 {
+	if !d.enabled.Load() {
+		return 0
+	}
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id := d.nextID
+	d.nextID++
+	d.inflight[id] = now
+	return id
+}
+
+// End records the completion of the operation returned by Begin.
+func (d *Detector) End(id uint64) {
+	if id == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.inflight, id)
+}
+
+// Oldest returns how long the oldest in-flight operation has been running
+// for at now, along with the number of in-flight operations.
+func (d *Detector) Oldest(now time.Time) (time.Duration, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var oldest time.Duration
+	for _, start := range d.inflight {
+		if age := now.Sub(start); age > oldest {
+			oldest = age
+		}
+	}
+	return oldest, len(d.inflight)
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stall
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDisabled(t *testing.T) {
+	d := Register("test-disabled")
+	if id := d.Begin(); id != 0 {
+		t.Fatalf("Begin() on a disabled detector = %d, want 0", id)
+	}
+	if _, n := d.Oldest(time.Now()); n != 0 {
+		t.Errorf("Oldest() on a disabled detector reports %d operations, want 0", n)
+	}
+}
+
+func TestOldest(t *testing.T) {
+	d := Register("test-oldest")
+	d.SetEnabled(true)
+	defer d.SetEnabled(false)
+
+	first := d.Begin()
+	second := d.Begin()
+	if age, n := d.Oldest(time.Now().Add(time.Minute)); n != 2 || age < time.Minute {
+		t.Errorf("Oldest() = %v, %d, want >= 1m, 2", age, n)
+	}
+	d.End(first)
+	d.End(second)
+	if age, n := d.Oldest(time.Now()); n != 0 || age != 0 {
+		t.Errorf("Oldest() after End() = %v, %d, want 0, 0", age, n)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	if Register("gofer") != Gofer {
+		t.Errorf("Register() of an existing name returned a new detector")
+	}
+	if Lookup("test-missing") != nil {
+		t.Errorf("Lookup() of a missing name returned a detector")
+	}
+	found := false
+	for _, name := range Names() {
+		if name == "netstack" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Names() = %v, missing netstack", Names())
+	}
+}
@@ -18,7 +18,6 @@ import (
 	"context"
 	"io"
 	"reflect"
-	"sort"
 
 	"gvisor.dev/gvisor/pkg/state/wire"
 )
@@ -784,29 +783,47 @@ func (es *encodeState) Save(obj reflect.Value) {
 	if err := WriteHeader(es.w, uint64(len(es.pending)), true); err != nil {
 		Failf("error writing header: %w", err)
 	}
+	progress := progressFromContext(es.ctx)
+	if progress != nil {
+		progress.objectsTotal.Add(uint64(len(es.pending)))
+	}
 
-	// Serialize all pending types and pending objects. Note that we don't
-	// bother removing from this list as we walk it because that just
-	// wastes time. It will not change after this point.
+	// The object graph is fully resolved, so the address ranges of objects
+	// are no longer needed. Dropping them allows each object's encoding to
+	// be released once it has been written below, rather than holding the
+	// encoded graph in memory until the end of the save.
+	//
+	// Note that this does not lower the peak memory usage of Save, which is
+	// reached here: an object's encoding may refer to objects that are
+	// merged into containing objects later during resolution, so no object
+	// can be written before the whole graph has been encoded.
+	es.values.RemoveAll()
+	es.zeroValues = nil
+	es.encodedStructs = nil
+
+	// Serialize all pending types and pending objects.
 	if err := safely(func() {
 		for _, wt := range es.pendingTypes {
 			// Encode the type.
 			wire.Save(es.w, &wt)
 		}
-		// Emit objects in ID order.
-		ids := make([]objectID, 0, len(es.pending))
-		for id := range es.pending {
-			ids = append(ids, id)
-		}
-		sort.Slice(ids, func(i, j int) bool {
-			return ids[i] < ids[j]
-		})
-		for _, id := range ids {
+		// Emit objects in ID order. IDs of objects that were merged
+		// into their containing object during resolution are skipped.
+		for id := objectID(1); id <= es.lastID; id++ {
+			var ok bool
+			if oes, ok = es.pending[id]; !ok {
+				continue
+			}
 			// Encode the id.
 			wire.Save(es.w, wire.Uint(id))
 			// Marshal the object.
-			oes := es.pending[id]
 			wire.Save(es.w, oes.encoded)
+			// Release the object.
+			delete(es.pending, id)
+			*oes = objectEncodeState{id: id}
+			if progress != nil {
+				progress.objectsWritten.Add(1)
+			}
 		}
 	}); err != nil {
 		// Include the object and the error.
@@ -0,0 +1,386 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package inspect enumerates the objects of state streams, and summarizes and
+// compares their sizes.
+//
+// Objects are not decoded into their Go types, so streams can be inspected
+// without the types being registered, and without holding the whole object
+// graph in memory.
+package inspect
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gvisor.dev/gvisor/pkg/state"
+	"gvisor.dev/gvisor/pkg/state/wire"
+)
+
+// Object describes an object of a state stream.
+type Object struct {
+	// Graph is the index of the object graph the object belongs to, starting
+	// at 1. Each call to state.Save writes one graph.
+	Graph uint64
+
+	// ID is the ID of the object in its graph.
+	ID uint64
+
+	// Type is the name of the type of the object. Types of structs are named
+	// by their registered name, e.g. "pkg/sentry/kernel.Task". Other types
+	// can't be fully determined from their encoding, and are named after
+	// their Go kind, with element types where they can be determined from the
+	// contents, e.g. "[]pkg/sentry/kernel.Task" or "map[string]?".
+	Type string
+
+	// Size is the encoded size of the object, in bytes.
+	Size uint64
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+// Read implements io.Reader.Read.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += uint64(n)
+	return n, err
+}
+
+// walker walks a state stream.
+type walker struct {
+	r countingReader
+
+	// graph is the index of the current graph, or zero if no graph was
+	// read.
+	graph uint64
+
+	// types are the types of the current graph, indexed by type ID - 1.
+	types []*wire.Type
+
+	// typeBytes is the number of bytes of type specifications.
+	typeBytes uint64
+
+	// rawBytes is the number of bytes of non-object data.
+	rawBytes uint64
+}
+
+// Walk reads the state stream from r and calls fn for each object in it, in
+// stream order. If fn returns an error, Walk stops and returns it.
+func Walk(r io.Reader, fn func(Object) error) error {
+	w := walker{r: countingReader{r: r}}
+	return w.walk(fn)
+}
+
+func (w *walker) walk(fn func(Object) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if rErr, ok := r.(error); ok {
+				err = rErr // Override return.
+				return
+			}
+			panic(r) // Propagate.
+		}
+	}()
+
+	for {
+		length, object, err := state.ReadHeader(&w.r)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !object {
+			if length > 0 {
+				n, err := io.CopyN(io.Discard, &w.r, int64(length))
+				w.rawBytes += uint64(n)
+				if err != nil {
+					return fmt.Errorf("skipping %d bytes of non-object data: %w", length, err)
+				}
+			}
+			continue
+		}
+
+		// Each object header starts a new graph, with its own types.
+		w.graph++
+		w.types = w.types[:0]
+
+		// Note that this loop must match the general structure of the
+		// loop in decode.go.
+		for i := uint64(0); i < length; {
+			start := w.r.n
+			encoded := wire.Load(&w.r)
+			switch we := encoded.(type) {
+			case *wire.Type:
+				w.types = append(w.types, we)
+				w.typeBytes += w.r.n - start
+			case wire.Uint:
+				obj := wire.Load(&w.r)
+				if err := fn(Object{
+					Graph: w.graph,
+					ID:    uint64(we),
+					Type:  w.typeName(obj),
+					Size:  w.r.n - start,
+				}); err != nil {
+					return err
+				}
+				i++
+			default:
+				return fmt.Errorf("wanted type or object ID, got %#v", encoded)
+			}
+		}
+	}
+}
+
+// typeName returns the type name of an encoded object, as described by
+// Object.Type.
+func (w *walker) typeName(obj wire.Object) string {
+	switch x := obj.(type) {
+	case wire.Nil:
+		return "nil"
+	case wire.Bool:
+		return "bool"
+	case wire.Int:
+		return "int"
+	case wire.Uint:
+		return "uint"
+	case wire.Float32:
+		return "float32"
+	case wire.Float64:
+		return "float64"
+	case *wire.Complex64:
+		return "complex64"
+	case *wire.Complex128:
+		return "complex128"
+	case *wire.String:
+		return "string"
+	case *wire.Ref:
+		return "*?"
+	case *wire.Slice:
+		return "[]?"
+	case *wire.Interface:
+		return "interface"
+	case *wire.Array:
+		if len(x.Contents) == 0 {
+			return "[]?"
+		}
+		return "[]" + w.typeName(x.Contents[0])
+	case *wire.Map:
+		if len(x.Keys) == 0 {
+			return "map[?]?"
+		}
+		return "map[" + w.typeName(x.Keys[0]) + "]" + w.typeName(x.Values[0])
+	case *wire.Struct:
+		if id := uint64(x.TypeID); id >= 1 && id <= uint64(len(w.types)) {
+			return w.types[id-1].Name
+		}
+		return "struct"
+	default:
+		return fmt.Sprintf("%T", obj)
+	}
+}
+
+// Usage is the number and encoded size of a set of objects.
+type Usage struct {
+	// Count is the number of objects.
+	Count uint64 `json:"count"`
+
+	// Bytes is the encoded size of the objects.
+	Bytes uint64 `json:"bytes"`
+}
+
+// add adds an object of size bytes to u.
+func (u *Usage) add(bytes uint64) {
+	u.Count++
+	u.Bytes += bytes
+}
+
+// Summary summarizes a state stream.
+type Summary struct {
+	// Graphs is the number of object graphs.
+	Graphs uint64 `json:"graphs"`
+
+	// Objects is the usage of all objects.
+	Objects Usage `json:"objects"`
+
+	// TypeBytes is the size of type specifications.
+	TypeBytes uint64 `json:"type_bytes"`
+
+	// RawBytes is the size of non-object data, e.g. MemoryFile contents
+	// when they are not saved to a separate pages file.
+	RawBytes uint64 `json:"raw_bytes"`
+
+	// TotalBytes is the size of the stream, including headers and object
+	// IDs.
+	TotalBytes uint64 `json:"total_bytes"`
+
+	// Types is the usage of objects by type name.
+	Types map[string]Usage `json:"types"`
+}
+
+// Summarize reads the state stream from r and summarizes it.
+func Summarize(r io.Reader) (*Summary, error) {
+	w := walker{r: countingReader{r: r}}
+	s := &Summary{Types: make(map[string]Usage)}
+	if err := w.walk(func(obj Object) error {
+		s.Objects.add(obj.Size)
+		u := s.Types[obj.Type]
+		u.add(obj.Size)
+		s.Types[obj.Type] = u
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	s.Graphs = w.graph
+	s.TypeBytes = w.typeBytes
+	s.RawBytes = w.rawBytes
+	s.TotalBytes = w.r.n
+	return s, nil
+}
+
+// Package returns the package of the named type, with at most depth path
+// components if depth is positive. Types of other kinds are accounted to the
+// package of their element type, if known, and to "" otherwise.
+func Package(typeName string, depth int) string {
+	name := typeName
+	for {
+		if strings.HasPrefix(name, "[]") {
+			name = name[2:]
+		} else if strings.HasPrefix(name, "*") {
+			name = name[1:]
+		} else if strings.HasPrefix(name, "map[") {
+			// Account maps to their value type.
+			name = name[mapKeyEnd(name)+1:]
+		} else {
+			break
+		}
+	}
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return ""
+	}
+	pkg := name[:i]
+	if depth > 0 {
+		if parts := strings.Split(pkg, "/"); len(parts) > depth {
+			pkg = strings.Join(parts[:depth], "/")
+		}
+	}
+	return pkg
+}
+
+// mapKeyEnd returns the index of the "]" closing the key type of the named map
+// type.
+func mapKeyEnd(name string) int {
+	depth := 0
+	for i, c := range name {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(name) - 1
+}
+
+// ByPackage returns the usage of objects in s by package, as returned by
+// Package.
+func (s *Summary) ByPackage(depth int) map[string]Usage {
+	m := make(map[string]Usage)
+	for name, u := range s.Types {
+		pkg := Package(name, depth)
+		p := m[pkg]
+		p.Count += u.Count
+		p.Bytes += u.Bytes
+		m[pkg] = p
+	}
+	return m
+}
+
+// Entry is the usage of a named set of objects.
+type Entry struct {
+	Name string `json:"name"`
+	Usage
+}
+
+// Sorted returns the entries of m by descending size, and then by name.
+func Sorted(m map[string]Usage) []Entry {
+	entries := make([]Entry, 0, len(m))
+	for name, u := range m {
+		entries = append(entries, Entry{Name: name, Usage: u})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Bytes != entries[j].Bytes {
+			return entries[i].Bytes > entries[j].Bytes
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// Delta is the difference in usage of a named set of objects between two
+// streams.
+type Delta struct {
+	Name string `json:"name"`
+	Old  Usage  `json:"old"`
+	New  Usage  `json:"new"`
+}
+
+// Bytes returns the difference in size.
+func (d Delta) Bytes() int64 {
+	return int64(d.New.Bytes) - int64(d.Old.Bytes)
+}
+
+// Count returns the difference in number of objects.
+func (d Delta) Count() int64 {
+	return int64(d.New.Count) - int64(d.Old.Count)
+}
+
+// Diff returns the differences between oldUsage and newUsage, by descending
+// absolute difference in size, and then by name. Unchanged entries are
+// omitted.
+func Diff(oldUsage, newUsage map[string]Usage) []Delta {
+	var deltas []Delta
+	for name, o := range oldUsage {
+		if n := newUsage[name]; n != o {
+			deltas = append(deltas, Delta{Name: name, Old: o, New: n})
+		}
+	}
+	for name, n := range newUsage {
+		if _, ok := oldUsage[name]; !ok {
+			deltas = append(deltas, Delta{Name: name, New: n})
+		}
+	}
+	abs := func(v int64) int64 {
+		if v < 0 {
+			return -v
+		}
+		return v
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		if bi, bj := abs(deltas[i].Bytes()), abs(deltas[j].Bytes()); bi != bj {
+			return bi > bj
+		}
+		return deltas[i].Name < deltas[j].Name
+	})
+	return deltas
+}
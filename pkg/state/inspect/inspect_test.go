@@ -0,0 +1,127 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inspect
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/state"
+	"gvisor.dev/gvisor/pkg/state/wire"
+)
+
+// testStream returns a stream with a graph of a struct and a string, followed
+// by non-object data.
+func testStream(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := state.WriteHeader(&buf, 2, true); err != nil {
+		t.Fatalf("WriteHeader failed: %v", err)
+	}
+	wire.Save(&buf, &wire.Type{Name: "pkg/sentry/kernel.Task", Fields: []string{"name"}})
+	s := &wire.Struct{TypeID: 1}
+	s.Alloc(1)
+	*s.Field(0) = &wire.Ref{Root: 2}
+	wire.Save(&buf, wire.Uint(1))
+	wire.Save(&buf, s)
+	str := wire.String("init")
+	wire.Save(&buf, wire.Uint(2))
+	wire.Save(&buf, &str)
+	if err := state.WriteHeader(&buf, 3, false); err != nil {
+		t.Fatalf("WriteHeader failed: %v", err)
+	}
+	buf.Write([]byte{1, 2, 3})
+	return buf.Bytes()
+}
+
+func TestWalk(t *testing.T) {
+	var objects []Object
+	if err := Walk(bytes.NewReader(testStream(t)), func(obj Object) error {
+		obj.Size = 0 // Depends on the wire encoding.
+		objects = append(objects, obj)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	want := []Object{
+		{Graph: 1, ID: 1, Type: "pkg/sentry/kernel.Task"},
+		{Graph: 1, ID: 2, Type: "string"},
+	}
+	if !reflect.DeepEqual(objects, want) {
+		t.Errorf("Walk got objects %+v, want %+v", objects, want)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	stream := testStream(t)
+	s, err := Summarize(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+	if s.Graphs != 1 || s.Objects.Count != 2 || s.RawBytes != 3 || s.TotalBytes != uint64(len(stream)) {
+		t.Errorf("Summarize got %+v, want 1 graph, 2 objects, 3 raw bytes and %d total bytes", s, len(stream))
+	}
+	var typeBytes uint64
+	for _, u := range s.Types {
+		typeBytes += u.Bytes
+	}
+	if typeBytes != s.Objects.Bytes {
+		t.Errorf("Summarize got %d bytes of objects by type, want %d", typeBytes, s.Objects.Bytes)
+	}
+	if got := s.ByPackage(2); len(got) != 2 || got["pkg/sentry"].Count != 1 || got[""].Count != 1 {
+		t.Errorf("ByPackage(2) = %+v, want one object in pkg/sentry and one in \"\"", got)
+	}
+}
+
+func TestPackage(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		depth int
+		want  string
+	}{
+		{name: "pkg/sentry/fsimpl/tmpfs.inode", want: "pkg/sentry/fsimpl/tmpfs"},
+		{name: "pkg/sentry/fsimpl/tmpfs.inode", depth: 3, want: "pkg/sentry/fsimpl"},
+		{name: "[]pkg/sentry/kernel.Task", want: "pkg/sentry/kernel"},
+		{name: "map[[]uint]pkg/sentry/kernel.Task", want: "pkg/sentry/kernel"},
+		{name: "map[string]?", want: ""},
+		{name: "string", want: ""},
+	} {
+		if got := Package(tc.name, tc.depth); got != tc.want {
+			t.Errorf("Package(%q, %d) = %q, want %q", tc.name, tc.depth, got, tc.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	oldUsage := map[string]Usage{
+		"same":    {Count: 1, Bytes: 10},
+		"shrunk":  {Count: 2, Bytes: 20},
+		"removed": {Count: 1, Bytes: 5},
+	}
+	newUsage := map[string]Usage{
+		"same":   {Count: 1, Bytes: 10},
+		"shrunk": {Count: 1, Bytes: 8},
+		"added":  {Count: 3, Bytes: 30},
+	}
+	want := []Delta{
+		{Name: "added", New: Usage{Count: 3, Bytes: 30}},
+		{Name: "shrunk", Old: Usage{Count: 2, Bytes: 20}, New: Usage{Count: 1, Bytes: 8}},
+		{Name: "removed", Old: Usage{Count: 1, Bytes: 5}},
+	}
+	if got := Diff(oldUsage, newUsage); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// contextID is this package's type for context.Context.Value keys.
+type contextID int
+
+const (
+	// CtxProgress is a Context.Value key for a *Progress, to which Save
+	// reports its progress.
+	CtxProgress contextID = iota
+)
+
+// Progress accumulates the progress of one or more calls to Save. It may be
+// read concurrently with them.
+type Progress struct {
+	// objectsTotal is the number of objects to be written. Each call to Save
+	// adds its objects once its object graph has been encoded.
+	objectsTotal atomic.Uint64
+
+	// objectsWritten is the number of objects written.
+	objectsWritten atomic.Uint64
+}
+
+// Objects returns the number of objects written, and the number of objects to
+// be written by the calls to Save that have encoded their object graph.
+func (p *Progress) Objects() (written, total uint64) {
+	return p.objectsWritten.Load(), p.objectsTotal.Load()
+}
+
+// progressFromContext returns the Progress used by ctx, or nil.
+func progressFromContext(ctx context.Context) *Progress {
+	if ctx == nil {
+		return nil
+	}
+	p, _ := ctx.Value(CtxProgress).(*Progress)
+	return p
+}
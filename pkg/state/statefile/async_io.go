@@ -19,76 +19,190 @@ import (
 	"sync/atomic"
 
 	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sync"
 )
 
+// AsyncOpts configures AsyncReader and AsyncWriter.
+type AsyncOpts struct {
+	// IOUring enables the io_uring backend, if io_uring is available on the
+	// host. Otherwise, or if io_uring isn't available, I/O is performed by a
+	// pool of goroutines.
+	//
+	// With io_uring, files opened with O_DIRECT may be read into or written
+	// from buffers that aren't page-aligned: these are staged through
+	// page-aligned buffers registered with io_uring.
+	IOUring bool
+}
+
 type chunk struct {
 	dst []byte
 	off int64
 }
 
-// AsyncReader can be used to do reads asynchronously. It does not change the
-// underlying file's offset.
-type AsyncReader struct {
-	// in is the backing file which contains all pages.
-	in *fd.FD
-	// off is the offset being read.
+// asyncFile performs asynchronous I/O on a file, at increasing offsets.
+type asyncFile struct {
+	// f is the file.
+	f *fd.FD
+	// write is true if the I/O writes to f, and false if it reads from it.
+	write bool
+	// off is the offset of the next I/O.
 	off int64
+	// ring performs the I/O if it is non-nil. Otherwise, the I/O is
+	// performed by workers through q.
+	ring *ioUring
 	// q is the work queue.
 	q chan chunk
-	// err stores the latest IO error that occured during async read.
+	// err stores the latest IO error that occured during async I/O.
 	err atomic.Pointer[error]
 	// wg tracks all in flight work.
 	wg sync.WaitGroup
 }
 
-// NewAsyncReader initializes a new AsyncReader.
-func NewAsyncReader(in *fd.FD, off int64) *AsyncReader {
-	workers := runtime.GOMAXPROCS(0)
-	r := &AsyncReader{
-		in:  in,
-		off: off,
-		q:   make(chan chunk, workers),
+func (a *asyncFile) init(f *fd.FD, off int64, write bool, opts AsyncOpts) {
+	a.f = f
+	a.off = off
+	a.write = write
+	if opts.IOUring {
+		ring, err := newIOUring(int32(f.FD()), write)
+		if err == nil {
+			a.ring = ring
+			return
+		}
+		log.Warningf("io_uring unavailable, falling back to synchronous I/O workers: %v", err)
 	}
+	workers := runtime.GOMAXPROCS(0)
+	a.q = make(chan chunk, workers)
 	for i := 0; i < workers; i++ {
-		go r.work()
+		go a.work()
 	}
-	return r
 }
 
-// ReadAsync schedules a read of len(p) bytes from current offset into p.
-func (r *AsyncReader) ReadAsync(p []byte) {
-	r.wg.Add(1)
-	r.q <- chunk{off: r.off, dst: p}
-	r.off += int64(len(p))
+// submit schedules I/O of len(p) bytes at the current offset.
+func (a *asyncFile) submit(p []byte) {
+	if len(p) == 0 {
+		return
+	}
+	if a.ring != nil {
+		a.ring.submit(p, a.off)
+	} else {
+		a.wg.Add(1)
+		a.q <- chunk{off: a.off, dst: p}
+	}
+	a.off += int64(len(p))
 }
 
-// Wait blocks until all in flight work is complete and then returns any IO
-// errors that occurred since the last call to Wait().
-func (r *AsyncReader) Wait() error {
-	r.wg.Wait()
-	if err := r.err.Swap(nil); err != nil {
+// wait blocks until all in flight work is complete and then returns any IO
+// errors that occurred since the last call to wait.
+func (a *asyncFile) wait() error {
+	if a.ring != nil {
+		return a.ring.wait()
+	}
+	a.wg.Wait()
+	if err := a.err.Swap(nil); err != nil {
 		return *err
 	}
 	return nil
 }
 
-// Close calls Wait() and additionally cleans up all worker goroutines.
-func (r *AsyncReader) Close() error {
-	err := r.Wait()
-	close(r.q)
+// close calls wait and additionally cleans up all resources.
+func (a *asyncFile) close() error {
+	if a.ring != nil {
+		return a.ring.close()
+	}
+	err := a.wait()
+	close(a.q)
 	return err
 }
 
-func (r *AsyncReader) work() {
+func (a *asyncFile) work() {
 	for {
-		c := <-r.q
+		c := <-a.q
 		if c.dst == nil {
 			return
 		}
-		if _, err := r.in.ReadAt(c.dst, c.off); err != nil {
-			r.err.Store(&err)
+		var err error
+		if a.write {
+			_, err = a.f.WriteAt(c.dst, c.off)
+		} else {
+			_, err = a.f.ReadAt(c.dst, c.off)
+		}
+		if err != nil {
+			a.err.Store(&err)
 		}
-		r.wg.Done()
+		a.wg.Done()
+	}
+}
+
+// AsyncReader can be used to do reads asynchronously. It does not change the
+// underlying file's offset.
+//
+// AsyncReader methods must not be called concurrently.
+type AsyncReader struct {
+	a asyncFile
+}
+
+// NewAsyncReader initializes a new AsyncReader reading from in at off.
+func NewAsyncReader(in *fd.FD, off int64, opts AsyncOpts) *AsyncReader {
+	r := &AsyncReader{}
+	r.a.init(in, off, false /* write */, opts)
+	return r
+}
+
+// ReadAsync schedules a read of len(p) bytes from current offset into p.
+func (r *AsyncReader) ReadAsync(p []byte) {
+	r.a.submit(p)
+}
+
+// Wait blocks until all in flight work is complete and then returns any IO
+// errors that occurred since the last call to Wait().
+func (r *AsyncReader) Wait() error {
+	return r.a.wait()
+}
+
+// Close calls Wait() and additionally cleans up all worker goroutines.
+func (r *AsyncReader) Close() error {
+	return r.a.close()
+}
+
+// AsyncWriter can be used to do writes asynchronously. It does not change the
+// underlying file's offset.
+//
+// AsyncWriter methods must not be called concurrently.
+type AsyncWriter struct {
+	a asyncFile
+}
+
+// NewAsyncWriter initializes a new AsyncWriter writing to out at off.
+func NewAsyncWriter(out *fd.FD, off int64, opts AsyncOpts) *AsyncWriter {
+	w := &AsyncWriter{}
+	w.a.init(out, off, true /* write */, opts)
+	return w
+}
+
+// WriteAsync schedules a write of p at the current offset. p must not be
+// modified until Wait returns.
+func (w *AsyncWriter) WriteAsync(p []byte) {
+	w.a.submit(p)
+}
+
+// Write implements io.Writer.Write. Unlike WriteAsync, it waits for all
+// writes to complete.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	w.a.submit(p)
+	if err := w.a.wait(); err != nil {
+		return 0, err
 	}
+	return len(p), nil
+}
+
+// Wait blocks until all in flight work is complete and then returns any IO
+// errors that occurred since the last call to Wait().
+func (w *AsyncWriter) Wait() error {
+	return w.a.wait()
+}
+
+// Close calls Wait() and additionally cleans up all worker goroutines.
+func (w *AsyncWriter) Close() error {
+	return w.a.close()
 }
@@ -18,12 +18,40 @@ import (
 	"bytes"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/fd"
 )
 
+// ioUringAvailable returns an error if io_uring isn't available.
+func ioUringAvailable() error {
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := newIOUring(int32(f.Fd()), false /* write */)
+	if err != nil {
+		return err
+	}
+	r.release()
+	return nil
+}
+
+// asyncOpts returns the AsyncOpts to test, omitting io_uring if it isn't
+// available.
+func asyncOpts(t *testing.T) map[string]AsyncOpts {
+	opts := map[string]AsyncOpts{"workers": {}}
+	if err := ioUringAvailable(); err != nil {
+		t.Logf("io_uring unavailable: %v", err)
+	} else {
+		opts["io_uring"] = AsyncOpts{IOUring: true}
+	}
+	return opts
+}
+
 func TestAsyncReader(t *testing.T) {
 	// Create random data.
 	const chunkSize = 4096
@@ -44,21 +72,97 @@ func TestAsyncReader(t *testing.T) {
 		t.Fatalf("failed to close temp source file: %v", err)
 	}
 
-	// Read the data from the file using async reads.
-	sourceFD, err := fd.Open(testFilePath, unix.O_RDONLY, 0)
+	for name, opts := range asyncOpts(t) {
+		t.Run(name, func(t *testing.T) {
+			// Read the data from the file using async reads.
+			sourceFD, err := fd.Open(testFilePath, unix.O_RDONLY, 0)
+			if err != nil {
+				t.Fatalf("failed to open source file %q: %v", testFilePath, err)
+			}
+			defer sourceFD.Close()
+			ar := NewAsyncReader(sourceFD, 0 /* off */, opts)
+			defer ar.Close()
+			p := make([]byte, dataLen)
+			for i := 0; i < dataLen; i += chunkSize {
+				ar.ReadAsync(p[i : i+chunkSize])
+			}
+			if err := ar.Wait(); err != nil {
+				t.Fatalf("AsyncReader.Wait failed: %v", err)
+			}
+			if ret := bytes.Compare(p, data); ret != 0 {
+				t.Errorf("bytes differ")
+			}
+
+			// Reads past the end of the file fail.
+			ar.ReadAsync(make([]byte, chunkSize))
+			if err := ar.Wait(); err == nil {
+				t.Errorf("AsyncReader.Wait succeeded after reading past EOF")
+			}
+		})
+	}
+}
+
+func TestAsyncWriter(t *testing.T) {
+	// Create random data, in chunks of varying, unaligned sizes.
+	const dataLen = 4 << 20
+	data := make([]byte, dataLen)
+	_, _ = rand.Read(data)
+
+	for name, opts := range asyncOpts(t) {
+		t.Run(name, func(t *testing.T) {
+			testFilePath := filepath.Join(t.TempDir(), "dest")
+			destFD, err := fd.Open(testFilePath, unix.O_WRONLY|unix.O_CREAT, 0644)
+			if err != nil {
+				t.Fatalf("failed to open dest file %q: %v", testFilePath, err)
+			}
+			defer destFD.Close()
+			aw := NewAsyncWriter(destFD, 0 /* off */, opts)
+			for i, n := 0, 1; i < dataLen; i, n = i+n, n*3+1 {
+				aw.WriteAsync(data[i:min(i+n, dataLen)])
+			}
+			if err := aw.Close(); err != nil {
+				t.Fatalf("AsyncWriter.Close failed: %v", err)
+			}
+			got, err := os.ReadFile(testFilePath)
+			if err != nil {
+				t.Fatalf("failed to read dest file: %v", err)
+			}
+			if ret := bytes.Compare(got, data); ret != 0 {
+				t.Errorf("bytes differ")
+			}
+		})
+	}
+}
+
+func TestAsyncWriterDirect(t *testing.T) {
+	if err := ioUringAvailable(); err != nil {
+		t.Skipf("io_uring unavailable: %v", err)
+	}
+	testFilePath := filepath.Join(t.TempDir(), "dest")
+	destFD, err := fd.Open(testFilePath, unix.O_WRONLY|unix.O_CREAT|unix.O_DIRECT, 0644)
 	if err != nil {
-		t.Fatalf("failed to open source file %q: %v", testFilePath, err)
+		t.Skipf("O_DIRECT unsupported: %v", err)
 	}
-	ar := NewAsyncReader(sourceFD, 0 /* off */)
-	defer ar.Close()
-	p := make([]byte, dataLen)
+	defer destFD.Close()
+
+	// Write page-sized chunks from unaligned buffers, which must be staged.
+	const chunkSize = 4096
+	const dataLen = 256 * chunkSize
+	buf := make([]byte, dataLen+1)
+	_, _ = rand.Read(buf)
+	data := buf[1:]
+	aw := NewAsyncWriter(destFD, 0 /* off */, AsyncOpts{IOUring: true})
 	for i := 0; i < dataLen; i += chunkSize {
-		ar.ReadAsync(p[i : i+chunkSize])
+		aw.WriteAsync(data[i : i+chunkSize])
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatalf("AsyncWriter.Close failed: %v", err)
 	}
-	if err := ar.Wait(); err != nil {
-		t.Fatalf("AsyncReader.Wait failed: %v", err)
+	got, err := os.ReadFile(testFilePath)
+	if err != nil {
+		t.Fatalf("failed to read dest file: %v", err)
 	}
-	if ret := bytes.Compare(p, data); ret != 0 {
+	if ret := bytes.Compare(got, data); ret != 0 {
 		t.Errorf("bytes differ")
 	}
 }
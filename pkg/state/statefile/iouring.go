@@ -0,0 +1,439 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statefile
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/log"
+)
+
+const (
+	// ioUringEntries is the number of submission queue entries, and thus the
+	// maximum number of operations in flight.
+	ioUringEntries = 64
+
+	// ioUringMaxLen is the maximum length of an operation.
+	ioUringMaxLen = 1 << 30
+
+	// stagingBuffers is the number of staging buffers.
+	stagingBuffers = 16
+
+	// stagingBufferSize is the size of each staging buffer.
+	stagingBufferSize = 256 << 10
+)
+
+// ioUringOp is an operation in flight.
+type ioUringOp struct {
+	// buf is the caller's buffer. Once the operation completes, it has been
+	// read into or written from entirely.
+	buf []byte
+
+	// off is the file offset of buf.
+	off int64
+
+	// done is the number of bytes of buf read or written so far.
+	done int
+
+	// staging is the index of the staging buffer used for the operation, or
+	// -1 if the operation is performed on buf directly.
+	staging int
+}
+
+// ioUring performs I/O on a file with io_uring. Operations are performed on
+// the callers' buffers directly, unless the file was opened with O_DIRECT and
+// the buffers aren't aligned as required for direct I/O, in which case they
+// are staged through page-aligned buffers registered with io_uring. Note that
+// staging doesn't align file offsets, which must still be aligned for direct
+// I/O.
+//
+// ioUring is not thread-safe.
+type ioUring struct {
+	// ringFD is the io_uring file descriptor.
+	ringFD int
+
+	// fd is the file I/O is performed on.
+	fd int32
+
+	// write is true if ioUring writes to fd, and false if it reads from it.
+	write bool
+
+	// direct is true if fd was opened with O_DIRECT.
+	direct bool
+
+	// sqRing, cqRing and sqes are the mappings of the rings. If singleMmap
+	// is true, cqRing aliases sqRing.
+	sqRing     []byte
+	cqRing     []byte
+	sqes       []byte
+	singleMmap bool
+
+	// Pointers into the rings.
+	sqHead  *uint32
+	sqTail  *uint32
+	sqMask  uint32
+	sqArray unsafe.Pointer
+	cqHead  *uint32
+	cqTail  *uint32
+	cqMask  uint32
+	cqes    unsafe.Pointer
+
+	// pending is the number of entries queued but not yet submitted.
+	pending uint32
+
+	// ops are the operations in flight, indexed by user data. freeOps are
+	// the indexes of unused ops.
+	ops     [ioUringEntries]ioUringOp
+	freeOps []uint64
+
+	// staging is the mapping of the staging buffers, or nil if they haven't
+	// been allocated yet. freeStaging are the indexes of unused staging
+	// buffers. fixed is true if they are registered with io_uring.
+	staging     []byte
+	freeStaging []int
+	fixed       bool
+
+	// err is the first error since the last call to wait.
+	err error
+}
+
+// newIOUring returns an ioUring reading from or writing to f. It returns an
+// error if io_uring isn't available.
+func newIOUring(f int32, write bool) (*ioUring, error) {
+	var params linux.IOUringParams
+	ringFD, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP, ioUringEntries, uintptr(unsafe.Pointer(&params)), 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("io_uring_setup: %w", errno)
+	}
+	r := &ioUring{
+		ringFD: int(ringFD),
+		fd:     f,
+		write:  write,
+	}
+	if err := r.mapRings(&params); err != nil {
+		r.release()
+		return nil, err
+	}
+	flags, err := unix.FcntlInt(uintptr(f), unix.F_GETFL, 0)
+	if err != nil {
+		r.release()
+		return nil, fmt.Errorf("fcntl(F_GETFL): %w", err)
+	}
+	r.direct = flags&unix.O_DIRECT != 0
+	r.freeOps = make([]uint64, 0, ioUringEntries)
+	for i := ioUringEntries - 1; i >= 0; i-- {
+		r.freeOps = append(r.freeOps, uint64(i))
+	}
+	return r, nil
+}
+
+// mapRings maps the rings of r.
+func (r *ioUring) mapRings(params *linux.IOUringParams) error {
+	sqSize := int(params.SqOff.Array + params.SqEntries*4)
+	cqSize := int(params.CqOff.Cqes + params.CqEntries*uint32(unsafe.Sizeof(linux.IOUringCqe{})))
+	r.singleMmap = params.Features&linux.IORING_FEAT_SINGLE_MMAP != 0
+	if r.singleMmap {
+		sqSize = max(sqSize, cqSize)
+	}
+	var err error
+	if r.sqRing, err = unix.Mmap(r.ringFD, linux.IORING_OFF_SQ_RING, sqSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED); err != nil {
+		return fmt.Errorf("mapping io_uring submission queue: %w", err)
+	}
+	if r.singleMmap {
+		r.cqRing = r.sqRing
+	} else if r.cqRing, err = unix.Mmap(r.ringFD, linux.IORING_OFF_CQ_RING, cqSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED); err != nil {
+		return fmt.Errorf("mapping io_uring completion queue: %w", err)
+	}
+	if r.sqes, err = unix.Mmap(r.ringFD, linux.IORING_OFF_SQES, int(params.SqEntries)*int(unsafe.Sizeof(linux.IOUringSqe{})), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED); err != nil {
+		return fmt.Errorf("mapping io_uring submission queue entries: %w", err)
+	}
+	r.sqHead = (*uint32)(unsafe.Pointer(&r.sqRing[params.SqOff.Head]))
+	r.sqTail = (*uint32)(unsafe.Pointer(&r.sqRing[params.SqOff.Tail]))
+	r.sqMask = *(*uint32)(unsafe.Pointer(&r.sqRing[params.SqOff.RingMask]))
+	r.sqArray = unsafe.Pointer(&r.sqRing[params.SqOff.Array])
+	r.cqHead = (*uint32)(unsafe.Pointer(&r.cqRing[params.CqOff.Head]))
+	r.cqTail = (*uint32)(unsafe.Pointer(&r.cqRing[params.CqOff.Tail]))
+	r.cqMask = *(*uint32)(unsafe.Pointer(&r.cqRing[params.CqOff.RingMask]))
+	r.cqes = unsafe.Pointer(&r.cqRing[params.CqOff.Cqes])
+	return nil
+}
+
+// close waits for all operations in flight and releases r.
+func (r *ioUring) close() error {
+	err := r.wait()
+	r.release()
+	return err
+}
+
+// release releases the resources of r.
+func (r *ioUring) release() {
+	if r.fixed {
+		unix.Syscall6(unix.SYS_IO_URING_REGISTER, uintptr(r.ringFD), linux.IORING_UNREGISTER_BUFFERS, 0, 0, 0, 0)
+	}
+	if r.staging != nil {
+		unix.Munmap(r.staging)
+	}
+	if r.sqes != nil {
+		unix.Munmap(r.sqes)
+	}
+	if r.cqRing != nil && !r.singleMmap {
+		unix.Munmap(r.cqRing)
+	}
+	if r.sqRing != nil {
+		unix.Munmap(r.sqRing)
+	}
+	unix.Close(r.ringFD)
+}
+
+// aligned returns true if p at off can be read or written directly.
+func (r *ioUring) aligned(p []byte, off int64) bool {
+	if !r.direct {
+		return true
+	}
+	return uintptr(unsafe.Pointer(&p[0]))%hostarch.PageSize == 0 && len(p)%hostarch.PageSize == 0 && off%hostarch.PageSize == 0
+}
+
+// submit starts reading into or writing from p at off.
+func (r *ioUring) submit(p []byte, off int64) {
+	for len(p) > 0 {
+		n := min(len(p), ioUringMaxLen)
+		staging := -1
+		if !r.aligned(p[:n], off) {
+			n = min(n, stagingBufferSize)
+			staging = r.getStaging()
+		}
+		op := ioUringOp{buf: p[:n], off: off, staging: staging}
+		if staging >= 0 && r.write {
+			copy(r.stagingBuffer(staging), op.buf)
+		}
+		r.queue(op)
+		p = p[n:]
+		off += int64(n)
+	}
+	// Start the I/O without waiting for it.
+	r.enter(false)
+}
+
+// stagingBuffer returns the staging buffer i.
+func (r *ioUring) stagingBuffer(i int) []byte {
+	return r.staging[i*stagingBufferSize : (i+1)*stagingBufferSize]
+}
+
+// getStaging returns the index of a free staging buffer, waiting for one if
+// needed.
+func (r *ioUring) getStaging() int {
+	if r.staging == nil {
+		r.allocStaging()
+	}
+	for len(r.freeStaging) == 0 {
+		r.reap(1)
+	}
+	i := r.freeStaging[len(r.freeStaging)-1]
+	r.freeStaging = r.freeStaging[:len(r.freeStaging)-1]
+	return i
+}
+
+// allocStaging allocates the staging buffers, and registers them with
+// io_uring.
+func (r *ioUring) allocStaging() {
+	m, err := unix.Mmap(-1, 0, stagingBuffers*stagingBufferSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS)
+	if err != nil {
+		panic(fmt.Sprintf("mapping io_uring staging buffers: %v", err))
+	}
+	r.staging = m
+	iovecs := make([]unix.Iovec, stagingBuffers)
+	for i := range iovecs {
+		iovecs[i].Base = &r.stagingBuffer(i)[0]
+		iovecs[i].SetLen(stagingBufferSize)
+		r.freeStaging = append(r.freeStaging, i)
+	}
+	// Registration may fail, e.g. due to RLIMIT_MEMLOCK, in which case the
+	// staging buffers are used as regular buffers.
+	if _, _, errno := unix.Syscall6(unix.SYS_IO_URING_REGISTER, uintptr(r.ringFD), linux.IORING_REGISTER_BUFFERS, uintptr(unsafe.Pointer(&iovecs[0])), uintptr(len(iovecs)), 0, 0); errno != 0 {
+		log.Infof("Registering io_uring staging buffers failed, using them unregistered: %v", errno)
+	} else {
+		r.fixed = true
+	}
+}
+
+// queue queues op, submitting queued operations and waiting for completions
+// as needed.
+func (r *ioUring) queue(op ioUringOp) {
+	for len(r.freeOps) == 0 {
+		r.reap(1)
+	}
+	id := r.freeOps[len(r.freeOps)-1]
+	r.freeOps = r.freeOps[:len(r.freeOps)-1]
+	r.ops[id] = op
+	r.push(id)
+}
+
+// push pushes a submission queue entry for the remainder of op id. The
+// submission queue can't be full, since there are as many entries as ops.
+func (r *ioUring) push(id uint64) {
+	op := &r.ops[id]
+	sqe := linux.IOUringSqe{
+		Fd:               r.fd,
+		OffOrAddrOrCmdOp: uint64(op.off) + uint64(op.done),
+		UserData:         id,
+	}
+	if op.staging < 0 {
+		sqe.Opcode = linux.IORING_OP_READ
+		if r.write {
+			sqe.Opcode = linux.IORING_OP_WRITE
+		}
+		rest := op.buf[op.done:]
+		sqe.AddrOrSpliceOff = uint64(uintptr(unsafe.Pointer(&rest[0])))
+		sqe.Len = uint32(len(rest))
+	} else {
+		switch {
+		case r.write && r.fixed:
+			sqe.Opcode = linux.IORING_OP_WRITE_FIXED
+		case r.write:
+			sqe.Opcode = linux.IORING_OP_WRITE
+		case r.fixed:
+			sqe.Opcode = linux.IORING_OP_READ_FIXED
+		default:
+			sqe.Opcode = linux.IORING_OP_READ
+		}
+		buf := r.stagingBuffer(op.staging)
+		n := len(op.buf)
+		if !r.write {
+			// Reads into staging buffers are rounded up, so that they
+			// remain aligned; the excess is discarded.
+			n = int(hostarch.Addr(n).MustRoundUp())
+		}
+		rest := buf[op.done:n]
+		sqe.AddrOrSpliceOff = uint64(uintptr(unsafe.Pointer(&rest[0])))
+		sqe.Len = uint32(len(rest))
+		sqe.BufIndexOrGroup = uint16(op.staging)
+	}
+
+	tail := *r.sqTail
+	idx := tail & r.sqMask
+	*(*linux.IOUringSqe)(unsafe.Add(unsafe.Pointer(&r.sqes[0]), uintptr(idx)*unsafe.Sizeof(sqe))) = sqe
+	*(*uint32)(unsafe.Add(r.sqArray, uintptr(idx)*4)) = idx
+	atomic.StoreUint32(r.sqTail, tail+1)
+	r.pending++
+}
+
+// inflight returns the number of operations in flight.
+func (r *ioUring) inflight() int {
+	return ioUringEntries - len(r.freeOps)
+}
+
+// reap submits queued operations, and waits for at least min completions.
+func (r *ioUring) reap(min int) {
+	for completed := 0; ; {
+		completed += r.complete()
+		if completed >= min || r.inflight() == 0 {
+			return
+		}
+		r.enter(true)
+	}
+}
+
+// enter submits queued operations, and waits for a completion if wait is
+// true.
+func (r *ioUring) enter(wait bool) {
+	var flags, minComplete uintptr
+	if wait {
+		flags = linux.IORING_ENTER_GETEVENTS
+		minComplete = 1
+	}
+	for {
+		n, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(r.ringFD), uintptr(r.pending), minComplete, flags, 0, 0)
+		if errno == unix.EINTR || errno == unix.EAGAIN || errno == unix.EBUSY {
+			continue
+		}
+		if errno != 0 {
+			panic(fmt.Sprintf("io_uring_enter: %v", errno))
+		}
+		r.pending -= uint32(n)
+		if r.pending == 0 || wait {
+			return
+		}
+	}
+}
+
+// complete handles available completions, and returns the number of
+// operations completed.
+func (r *ioUring) complete() int {
+	completed := 0
+	head := *r.cqHead
+	for tail := atomic.LoadUint32(r.cqTail); head != tail; head++ {
+		cqe := *(*linux.IOUringCqe)(unsafe.Add(r.cqes, uintptr(head&r.cqMask)*unsafe.Sizeof(linux.IOUringCqe{})))
+		if r.handle(cqe) {
+			completed++
+		}
+	}
+	atomic.StoreUint32(r.cqHead, head)
+	return completed
+}
+
+// handle handles cqe, and returns true if its operation completed.
+func (r *ioUring) handle(cqe linux.IOUringCqe) bool {
+	id := cqe.UserData
+	op := &r.ops[id]
+	var err error
+	switch {
+	case cqe.Res == -int32(unix.EINTR) || cqe.Res == -int32(unix.EAGAIN):
+		// Retry.
+	case cqe.Res < 0:
+		err = unix.Errno(-cqe.Res)
+	case cqe.Res == 0:
+		err = io.ErrUnexpectedEOF
+		if r.write {
+			err = io.ErrShortWrite
+		}
+	default:
+		op.done += int(cqe.Res)
+	}
+	if err == nil && op.done < len(op.buf) {
+		// Short read or write; continue with the remainder.
+		r.push(id)
+		return false
+	}
+	if err != nil {
+		if r.err == nil {
+			r.err = fmt.Errorf("io_uring I/O at offset %d: %w", op.off, err)
+		}
+	} else if op.staging >= 0 && !r.write {
+		copy(op.buf, r.stagingBuffer(op.staging))
+	}
+	if op.staging >= 0 {
+		r.freeStaging = append(r.freeStaging, op.staging)
+	}
+	*op = ioUringOp{}
+	r.freeOps = append(r.freeOps, id)
+	return true
+}
+
+// wait waits for all operations in flight, and returns the first error that
+// occurred since the last call to wait.
+func (r *ioUring) wait() error {
+	for r.inflight() > 0 {
+		r.reap(r.inflight())
+	}
+	err := r.err
+	r.err = nil
+	return err
+}
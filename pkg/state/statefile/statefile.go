@@ -110,6 +110,10 @@ type Options struct {
 	// Resume indicates if the sandbox process should continue running
 	// after checkpointing.
 	Resume bool
+
+	// RateLimit is the maximum rate at which the image is written, in bytes
+	// per second. If zero, the rate isn't limited.
+	RateLimit uint64
 }
 
 // WriteToMetadata save options to the metadata storage.  Method returns the
@@ -253,7 +257,7 @@ func metadata(r io.Reader, h hash.Hash) (map[string]string, error) {
 
 	// Read and validate magic header.
 	b := make([]byte, len(magicHeader))
-	if _, err := r.Read(b); err != nil {
+	if _, err := io.ReadFull(r, b); err != nil {
 		return nil, err
 	}
 	if !bytes.Equal(b, magicHeader) {
@@ -0,0 +1,131 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/state"
+	"gvisor.dev/gvisor/pkg/state/inspect"
+)
+
+func TestProgress(t *testing.T) {
+	ofs := outerSame{inner{1}}
+	oa := outerArray{[2]inner{{2}, {3}}}
+	cs1 := cycleStruct{nil}
+	cs2 := cycleStruct{nil}
+	cs1.c = &cs2
+	cs2.c = &cs1
+
+	for i, root := range []any{
+		&inner{1},
+		&system{&ofs, &ofs.inner},
+		&system{&ofs.inner, &ofs},
+		// The objects resolved first are merged into oa, leaving gaps
+		// in the object IDs.
+		&system3{&oa.inner[0], &oa.inner[1], &oa},
+		&system3{&oa.inner[1], &oa.inner[0], &oa},
+		&cs1,
+		&mapContainer{v: map[int]any{0: &afterLoadStruct{v: 1}}},
+	} {
+		t.Run(fmt.Sprintf("progress%d", i), func(t *testing.T) {
+			var p state.Progress
+			ctx := context.WithValue(context.Background(), state.CtxProgress, &p)
+			var buf bytes.Buffer
+			if _, err := state.Save(ctx, &buf, root); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+
+			summary, err := inspect.Summarize(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("Summarize failed: %v", err)
+			}
+			written, total := p.Objects()
+			if total != summary.Objects.Count {
+				t.Errorf("Progress total objects got %d, want %d", total, summary.Objects.Count)
+			}
+			if written != total {
+				t.Errorf("Progress written objects got %d, want %d", written, total)
+			}
+		})
+	}
+}
+
+func TestProgressAccumulates(t *testing.T) {
+	var p state.Progress
+	ctx := context.WithValue(context.Background(), state.CtxProgress, &p)
+	var want uint64
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+		if _, err := state.Save(ctx, &buf, &system{&inner{int64(i)}, &inner{int64(i)}}); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		summary, err := inspect.Summarize(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("Summarize failed: %v", err)
+		}
+		want += summary.Objects.Count
+		if written, total := p.Objects(); written != want || total != want {
+			t.Errorf("After %d saves, Progress.Objects() got (%d, %d), want (%d, %d)", i+1, written, total, want, want)
+		}
+	}
+}
+
+// failingWriter fails all writes after the first n bytes.
+type failingWriter struct {
+	n int
+}
+
+// Write implements io.Writer.Write.
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, fmt.Errorf("write failed")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestProgressWriteError(t *testing.T) {
+	var p state.Progress
+	ctx := context.WithValue(context.Background(), state.CtxProgress, &p)
+	root := &system3{&inner{1}, &inner{2}, &inner{3}}
+
+	var buf bytes.Buffer
+	if _, err := state.Save(ctx, &buf, root); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	_, total := p.Objects()
+
+	// Fail once the header has been written, so that not all objects are
+	// written.
+	var failedP state.Progress
+	ctx = context.WithValue(context.Background(), state.CtxProgress, &failedP)
+	w := &failingWriter{n: buf.Len() / 2}
+	if _, err := state.Save(ctx, w, root); err == nil {
+		t.Fatalf("Save succeeded unexpectedly")
+	}
+	written, gotTotal := failedP.Objects()
+	if gotTotal != total {
+		t.Errorf("Progress total objects got %d, want %d", gotTotal, total)
+	}
+	if written >= total {
+		t.Errorf("Progress written objects got %d, want < %d", written, total)
+	}
+}
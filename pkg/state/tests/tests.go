@@ -24,6 +24,7 @@ import (
 	"testing"
 
 	"gvisor.dev/gvisor/pkg/state"
+	"gvisor.dev/gvisor/pkg/state/inspect"
 	"gvisor.dev/gvisor/pkg/state/pretty"
 )
 
@@ -109,6 +110,14 @@ func runTestCases(t *testing.T, shouldFail bool, prefix string, objects []any) {
 					t.Errorf("PrettyPrint(html=true) failed unexpected: %v", err)
 				}
 			}
+			if summary, err := inspect.Summarize(bytes.NewReader(saveBuffer.Bytes())); err != nil {
+				// See above.
+				if !shouldFail {
+					t.Errorf("Summarize failed unexpectedly: %v", err)
+				}
+			} else if summary.TotalBytes != uint64(saveBuffer.Len()) {
+				t.Errorf("Summarize read %d bytes, want %d", summary.TotalBytes, saveBuffer.Len())
+			}
 			t.Logf("Encoded state:\n%s", ppBuf.String())
 			t.Logf("Save stats:\n%s", saveStats.String())
 
@@ -0,0 +1,151 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// A Dialer creates connections through a netstack Stack. Its DialContext
+// method has the same signature as net.Dialer.DialContext, so it can be used
+// wherever the standard library accepts a dial function, e.g.
+// http.Transport.DialContext.
+//
+// Dialer does not resolve host names; addresses must contain IP literals.
+// Callers that race connection attempts to several addresses (Happy
+// Eyeballs, RFC 8305) should call DialContext once per address, canceling
+// the contexts of attempts that lose the race. Canceling the context of an
+// in-progress TCP connection attempt aborts it and releases its endpoint.
+type Dialer struct {
+	// Stack is the stack connections are created on.
+	Stack *stack.Stack
+
+	// LocalAddr is the local address to bind connections to. If it is the
+	// zero value, a local address is chosen automatically.
+	LocalAddr tcpip.FullAddress
+
+	// KeepAlive, if positive, enables TCP keep-alive probes on connections
+	// with the given period. Otherwise keep-alives are left disabled.
+	KeepAlive time.Duration
+
+	// NoDelay controls whether Nagle's algorithm is disabled on TCP
+	// connections, as for TCPConn.SetNoDelay. Note that the zero value leaves
+	// Nagle's algorithm enabled, unlike net.Dialer.
+	NoDelay bool
+}
+
+// Dial connects to address on the named network. See DialContext.
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to address on the named network using the provided
+// context. Supported networks are "tcp", "tcp4", "tcp6", "udp", "udp4" and
+// "udp6". address has the form "host:port", where host is an IP literal.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	remote, proto, err := parseDialAddress(network, address)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		c, err := DialTCPWithBind(ctx, d.Stack, d.LocalAddr, remote, proto)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.SetNoDelay(d.NoDelay); err != nil {
+			c.Close()
+			return nil, err
+		}
+		if d.KeepAlive > 0 {
+			if err := c.SetKeepAlivePeriod(d.KeepAlive); err != nil {
+				c.Close()
+				return nil, err
+			}
+			if err := c.SetKeepAlive(true); err != nil {
+				c.Close()
+				return nil, err
+			}
+		}
+		return c, nil
+	default:
+		// UDP "connections" complete immediately, so ctx only needs to be
+		// checked once.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var laddr *tcpip.FullAddress
+		if d.LocalAddr != (tcpip.FullAddress{}) {
+			laddr = &d.LocalAddr
+		}
+		return DialUDP(d.Stack, laddr, &remote, proto)
+	}
+}
+
+// parseDialAddress parses address for the given network, returning the
+// remote address and the network protocol to use.
+func parseDialAddress(network, address string) (tcpip.FullAddress, tcpip.NetworkProtocolNumber, error) {
+	var want4, want6 bool
+	switch network {
+	case "tcp", "udp":
+		want4, want6 = true, true
+	case "tcp4", "udp4":
+		want4 = true
+	case "tcp6", "udp6":
+		want6 = true
+	default:
+		return tcpip.FullAddress{}, 0, net.UnknownNetworkError(network)
+	}
+
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return tcpip.FullAddress{}, 0, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return tcpip.FullAddress{}, 0, fmt.Errorf("invalid port %q", portStr)
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return tcpip.FullAddress{}, 0, fmt.Errorf("host %q is not an IP address", host)
+	}
+	ip = ip.Unmap()
+
+	switch {
+	case ip.Is4() && want4:
+		return tcpip.FullAddress{
+			Addr: tcpip.AddrFrom4(ip.As4()),
+			Port: uint16(port),
+		}, ipv4.ProtocolNumber, nil
+	case ip.Is6() && want6:
+		return tcpip.FullAddress{
+			Addr: tcpip.AddrFrom16(ip.As16()),
+			Port: uint16(port),
+		}, ipv6.ProtocolNumber, nil
+	default:
+		return tcpip.FullAddress{}, 0, &net.AddrError{Err: "address family mismatch", Addr: host}
+	}
+}
@@ -298,8 +298,9 @@ type opErrorer interface {
 }
 
 // commonRead implements the common logic between net.Conn.Read and
-// net.PacketConn.ReadFrom.
-func commonRead(b []byte, ep tcpip.Endpoint, wq *waiter.Queue, deadline <-chan struct{}, addr *tcpip.FullAddress, errorer opErrorer) (int, error) {
+// net.PacketConn.ReadFrom. If addr or cm are non-nil, they are populated with
+// the remote address and control messages of the read data respectively.
+func commonRead(b []byte, ep tcpip.Endpoint, wq *waiter.Queue, deadline <-chan struct{}, addr *tcpip.FullAddress, cm *tcpip.ReceivableControlMessages, errorer opErrorer) (int, error) {
 	select {
 	case <-deadline:
 		return 0, errorer.newOpError("read", &timeoutError{})
@@ -339,6 +340,9 @@ func commonRead(b []byte, ep tcpip.Endpoint, wq *waiter.Queue, deadline <-chan s
 	if addr != nil {
 		*addr = res.RemoteAddr
 	}
+	if cm != nil {
+		*cm = res.ControlMessages
+	}
 	return res.Count, nil
 }
 
@@ -349,7 +353,7 @@ func (c *TCPConn) Read(b []byte) (int, error) {
 
 	deadline := c.readCancel()
 
-	n, err := commonRead(b, c.ep, c.wq, deadline, nil, c)
+	n, err := commonRead(b, c.ep, c.wq, deadline, nil /* addr */, nil /* cm */, c)
 	if n != 0 {
 		c.ep.ModerateRecvBuf(n)
 	}
@@ -501,6 +505,7 @@ func DialTCPWithBind(ctx context.Context, s *stack.Stack, localAddr, remoteAddr
 
 	select {
 	case <-ctx.Done():
+		ep.Close()
 		return nil, ctx.Err()
 	default:
 	}
@@ -508,6 +513,7 @@ func DialTCPWithBind(ctx context.Context, s *stack.Stack, localAddr, remoteAddr
 	// Bind before connect if requested.
 	if localAddr != (tcpip.FullAddress{}) {
 		if err = ep.Bind(localAddr); err != nil {
+			ep.Close()
 			return nil, fmt.Errorf("ep.Bind(%+v) = %s", localAddr, err)
 		}
 	}
@@ -636,19 +642,44 @@ func (c *UDPConn) ReadFrom(b []byte) (int, net.Addr, error) {
 	deadline := c.readCancel()
 
 	var addr tcpip.FullAddress
-	n, err := commonRead(b, c.ep, c.wq, deadline, &addr, c)
+	n, err := commonRead(b, c.ep, c.wq, deadline, &addr, nil /* cm */, c)
 	if err != nil {
 		return 0, nil, err
 	}
 	return n, fullToUDPAddr(addr), nil
 }
 
+// ReadMsg reads a packet from c, copying its payload into b. It returns the
+// number of bytes copied into b, the source address of the packet, and the
+// control messages received with it. Control messages are only populated for
+// options enabled on c.SocketOptions(), e.g. SetReceiveTOS.
+func (c *UDPConn) ReadMsg(b []byte) (int, *net.UDPAddr, tcpip.ReceivableControlMessages, error) {
+	deadline := c.readCancel()
+
+	var (
+		addr tcpip.FullAddress
+		cm   tcpip.ReceivableControlMessages
+	)
+	n, err := commonRead(b, c.ep, c.wq, deadline, &addr, &cm, c)
+	if err != nil {
+		return 0, nil, tcpip.ReceivableControlMessages{}, err
+	}
+	return n, fullToUDPAddr(addr), cm, nil
+}
+
 func (c *UDPConn) Write(b []byte) (int, error) {
 	return c.WriteTo(b, nil)
 }
 
 // WriteTo implements net.PacketConn.WriteTo.
 func (c *UDPConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return c.WriteMsg(b, addr, tcpip.SendableControlMessages{})
+}
+
+// WriteMsg writes a packet with payload b to addr, overriding per-socket
+// options with those set in cm. If addr is nil, the packet is sent to the
+// address c is connected to.
+func (c *UDPConn) WriteMsg(b []byte, addr net.Addr, cm tcpip.SendableControlMessages) (int, error) {
 	deadline := c.writeCancel()
 
 	// Check if deadline has already expired.
@@ -659,7 +690,7 @@ func (c *UDPConn) WriteTo(b []byte, addr net.Addr) (int, error) {
 	}
 
 	// If we're being called by Write, there is no addr
-	writeOptions := tcpip.WriteOptions{}
+	writeOptions := tcpip.WriteOptions{ControlMessages: cm}
 	if addr != nil {
 		ua := addr.(*net.UDPAddr)
 		writeOptions.To = &tcpip.FullAddress{
@@ -1026,3 +1026,199 @@ func TestDeadlineTimerAfterZeroValue(t *testing.T) {
 	timer.SetReadDeadline(time.Unix(1, 0))
 	wg.Wait()
 }
+
+func TestTCPConnOptions(t *testing.T) {
+	c1, _, stop, err := makePipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	c := c1.(*TCPConn)
+
+	if err := c.SetNoDelay(false); err != nil {
+		t.Fatalf("SetNoDelay(false) = %v", err)
+	}
+	if !c.SocketOptions().GetDelayOption() {
+		t.Errorf("got GetDelayOption() = false after SetNoDelay(false), want = true")
+	}
+	if err := c.SetNoDelay(true); err != nil {
+		t.Fatalf("SetNoDelay(true) = %v", err)
+	}
+	if c.SocketOptions().GetDelayOption() {
+		t.Errorf("got GetDelayOption() = true after SetNoDelay(true), want = false")
+	}
+
+	if err := c.SetKeepAlive(true); err != nil {
+		t.Fatalf("SetKeepAlive(true) = %v", err)
+	}
+	if !c.SocketOptions().GetKeepAlive() {
+		t.Errorf("got GetKeepAlive() = false, want = true")
+	}
+	const period = 42 * time.Second
+	if err := c.SetKeepAlivePeriod(period); err != nil {
+		t.Fatalf("SetKeepAlivePeriod(%s) = %v", period, err)
+	}
+	var idle tcpip.KeepaliveIdleOption
+	if err := c.ep.GetSockOpt(&idle); err != nil || time.Duration(idle) != period {
+		t.Errorf("got GetSockOpt(&KeepaliveIdleOption) = %s, %v, want = %s, nil", time.Duration(idle), err, period)
+	}
+	var interval tcpip.KeepaliveIntervalOption
+	if err := c.ep.GetSockOpt(&interval); err != nil || time.Duration(interval) != period {
+		t.Errorf("got GetSockOpt(&KeepaliveIntervalOption) = %s, %v, want = %s, nil", time.Duration(interval), err, period)
+	}
+
+	for _, test := range []struct {
+		sec  int
+		want tcpip.LingerOption
+	}{
+		{-1, tcpip.LingerOption{}},
+		{0, tcpip.LingerOption{Enabled: true}},
+		{5, tcpip.LingerOption{Enabled: true, Timeout: 5 * time.Second}},
+	} {
+		if err := c.SetLinger(test.sec); err != nil {
+			t.Fatalf("SetLinger(%d) = %v", test.sec, err)
+		}
+		if got := c.SocketOptions().GetLinger(); got != test.want {
+			t.Errorf("got GetLinger() = %+v after SetLinger(%d), want = %+v", got, test.sec, test.want)
+		}
+	}
+
+	if err := c.SetReadBuffer(-1); err == nil {
+		t.Errorf("got SetReadBuffer(-1) = nil, want error")
+	}
+	_, max := c.SocketOptions().SendBufferLimits()
+	if err := c.SetWriteBuffer(int(max) + 1); err != nil {
+		t.Fatalf("SetWriteBuffer(%d) = %v", max+1, err)
+	}
+	if got := c.SocketOptions().GetSendBufferSize(); got > max {
+		t.Errorf("got GetSendBufferSize() = %d, want <= %d", got, max)
+	}
+}
+
+func TestUDPConnControlMessages(t *testing.T) {
+	s, e := newLoopbackStack()
+	if e != nil {
+		t.Fatalf("newLoopbackStack() = %v", e)
+	}
+	defer func() {
+		s.Close()
+		s.Wait()
+	}()
+
+	ip := tcpip.AddrFromSlice(net.IPv4(169, 254, 10, 1).To4())
+	addr := tcpip.FullAddress{NIC: NICID, Addr: ip, Port: 11211}
+	protocolAddr := tcpip.ProtocolAddress{
+		Protocol:          ipv4.ProtocolNumber,
+		AddressWithPrefix: ip.WithPrefix(),
+	}
+	if err := s.AddProtocolAddress(NICID, protocolAddr, stack.AddressProperties{}); err != nil {
+		t.Fatalf("AddProtocolAddress(%d, %+v, {}): %s", NICID, protocolAddr, err)
+	}
+
+	c1, err := DialUDP(s, &addr, nil, ipv4.ProtocolNumber)
+	if err != nil {
+		t.Fatal("DialUDP(bind):", err)
+	}
+	defer c1.Close()
+	c2, err := DialUDP(s, nil, &addr, ipv4.ProtocolNumber)
+	if err != nil {
+		t.Fatal("DialUDP(connect):", err)
+	}
+	defer c2.Close()
+
+	c1.SetDeadline(time.Now().Add(time.Second))
+	c2.SetDeadline(time.Now().Add(time.Second))
+	c1.SocketOptions().SetReceiveTTL(true)
+
+	const (
+		sent = "abc123"
+		ttl  = 7
+	)
+	cm := tcpip.SendableControlMessages{HasTTL: true, TTL: ttl}
+	if n, err := c2.WriteMsg([]byte(sent), nil, cm); err != nil || n != len(sent) {
+		t.Fatalf("got c2.WriteMsg(%q, nil, %+v) = %d, %v, want = %d, nil", sent, cm, n, err, len(sent))
+	}
+	recv := make([]byte, len(sent))
+	n, from, rcm, err := c1.ReadMsg(recv)
+	if err != nil || n != len(sent) {
+		t.Fatalf("got c1.ReadMsg() = %d, %v, want = %d, nil", n, err, len(sent))
+	}
+	if got := string(recv); got != sent {
+		t.Errorf("got recv = %q, want = %q", got, sent)
+	}
+	if want := c2.LocalAddr(); !reflect.DeepEqual(from, want) {
+		t.Errorf("got from = %v, want = %v", from, want)
+	}
+	if !rcm.HasTTL || rcm.TTL != ttl {
+		t.Errorf("got control messages HasTTL = %t, TTL = %d, want = true, %d", rcm.HasTTL, rcm.TTL, ttl)
+	}
+}
+
+func TestDialer(t *testing.T) {
+	s, e := newLoopbackStack()
+	if e != nil {
+		t.Fatalf("newLoopbackStack() = %v", e)
+	}
+	defer func() {
+		s.Close()
+		s.Wait()
+	}()
+
+	ip := tcpip.AddrFromSlice(net.IPv4(169, 254, 10, 1).To4())
+	addr := tcpip.FullAddress{NIC: NICID, Addr: ip, Port: 11211}
+	protocolAddr := tcpip.ProtocolAddress{
+		Protocol:          ipv4.ProtocolNumber,
+		AddressWithPrefix: ip.WithPrefix(),
+	}
+	if err := s.AddProtocolAddress(NICID, protocolAddr, stack.AddressProperties{}); err != nil {
+		t.Fatalf("AddProtocolAddress(%d, %+v, {}): %s", NICID, protocolAddr, err)
+	}
+	l, err := ListenTCP(s, addr, ipv4.ProtocolNumber)
+	if err != nil {
+		t.Fatalf("ListenTCP() = %v", err)
+	}
+	defer l.Close()
+
+	d := Dialer{Stack: s, KeepAlive: time.Minute, NoDelay: true}
+	for _, test := range []struct {
+		network string
+		address string
+		wantErr bool
+	}{
+		{"tcp", "169.254.10.1:11211", false},
+		{"tcp4", "169.254.10.1:11211", false},
+		{"tcp6", "169.254.10.1:11211", true},
+		{"udp", "169.254.10.1:11211", false},
+		{"tcp", "localhost:11211", true},
+		{"tcp", "169.254.10.1:http", true},
+		{"sctp", "169.254.10.1:11211", true},
+	} {
+		c, err := d.Dial(test.network, test.address)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("got Dial(%q, %q) = %v, want error = %t", test.network, test.address, err, test.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if tc, ok := c.(*TCPConn); ok {
+			if !tc.SocketOptions().GetKeepAlive() {
+				t.Errorf("got GetKeepAlive() = false on Dial(%q, %q), want = true", test.network, test.address)
+			}
+			if tc.SocketOptions().GetDelayOption() {
+				t.Errorf("got GetDelayOption() = true on Dial(%q, %q), want = false", test.network, test.address)
+			}
+			a, err := l.Accept()
+			if err != nil {
+				t.Fatalf("l.Accept() = %v", err)
+			}
+			a.Close()
+		}
+		c.Close()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := d.DialContext(ctx, "tcp", "169.254.10.1:11211"); err != context.Canceled {
+		t.Errorf("got DialContext(canceled, ...) = %v, want = %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gonet
+
+import (
+	"errors"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+// The methods in this file mirror the socket option setters of net.TCPConn
+// and net.UDPConn, so that code written against the standard library can
+// use netstack connections without modification. Options without a standard
+// library equivalent are available through SocketOptions and the endpoint's
+// SetSockOpt/SetSockOptInt methods.
+
+// SocketOptions returns the socket options of the endpoint underlying c.
+func (c *TCPConn) SocketOptions() *tcpip.SocketOptions {
+	return c.ep.SocketOptions()
+}
+
+// SetNoDelay controls whether the operating system should delay packet
+// transmission in hopes of sending fewer packets (Nagle's algorithm). As with
+// net.TCPConn, the default is true (no delay).
+func (c *TCPConn) SetNoDelay(noDelay bool) error {
+	c.ep.SocketOptions().SetDelayOption(!noDelay)
+	return nil
+}
+
+// SetKeepAlive sets whether the endpoint should send keep-alive messages.
+func (c *TCPConn) SetKeepAlive(keepalive bool) error {
+	c.ep.SocketOptions().SetKeepAlive(keepalive)
+	return nil
+}
+
+// SetKeepAlivePeriod sets the idle duration before the first keep-alive probe
+// and the interval between subsequent probes.
+func (c *TCPConn) SetKeepAlivePeriod(d time.Duration) error {
+	idle := tcpip.KeepaliveIdleOption(d)
+	if err := c.ep.SetSockOpt(&idle); err != nil {
+		return c.newOpError("set", errors.New(err.String()))
+	}
+	interval := tcpip.KeepaliveIntervalOption(d)
+	if err := c.ep.SetSockOpt(&interval); err != nil {
+		return c.newOpError("set", errors.New(err.String()))
+	}
+	return nil
+}
+
+// SetLinger sets the behavior of Close on a connection which still has data
+// waiting to be sent or to be acknowledged, with the semantics of
+// net.TCPConn.SetLinger:
+//
+//   - If sec < 0 (the default), data is sent in the background.
+//   - If sec == 0, unsent or unacknowledged data is discarded and the
+//     connection is reset.
+//   - If sec > 0, Close lingers for up to sec seconds.
+func (c *TCPConn) SetLinger(sec int) error {
+	var linger tcpip.LingerOption
+	if sec >= 0 {
+		linger.Enabled = true
+		linger.Timeout = time.Duration(sec) * time.Second
+	}
+	c.ep.SocketOptions().SetLinger(linger)
+	return nil
+}
+
+// SetReadBuffer sets the size of the endpoint's receive buffer.
+func (c *TCPConn) SetReadBuffer(bytes int) error {
+	return setReadBuffer(c.ep, bytes)
+}
+
+// SetWriteBuffer sets the size of the endpoint's send buffer.
+func (c *TCPConn) SetWriteBuffer(bytes int) error {
+	return setWriteBuffer(c.ep, bytes)
+}
+
+// SocketOptions returns the socket options of the endpoint underlying c.
+func (c *UDPConn) SocketOptions() *tcpip.SocketOptions {
+	return c.ep.SocketOptions()
+}
+
+// SetReadBuffer sets the size of the endpoint's receive buffer.
+func (c *UDPConn) SetReadBuffer(bytes int) error {
+	return setReadBuffer(c.ep, bytes)
+}
+
+// SetWriteBuffer sets the size of the endpoint's send buffer.
+func (c *UDPConn) SetWriteBuffer(bytes int) error {
+	return setWriteBuffer(c.ep, bytes)
+}
+
+var errNegativeBufferSize = errors.New("negative buffer size")
+
+// setReadBuffer sets ep's receive buffer size, clamped to the limits
+// configured on the stack.
+func setReadBuffer(ep tcpip.Endpoint, bytes int) error {
+	if bytes < 0 {
+		return errNegativeBufferSize
+	}
+	so := ep.SocketOptions()
+	min, max := so.ReceiveBufferLimits()
+	so.SetReceiveBufferSize(clampBufferSize(int64(bytes), min, max), true /* notify */)
+	return nil
+}
+
+// setWriteBuffer sets ep's send buffer size, clamped to the limits configured
+// on the stack.
+func setWriteBuffer(ep tcpip.Endpoint, bytes int) error {
+	if bytes < 0 {
+		return errNegativeBufferSize
+	}
+	so := ep.SocketOptions()
+	min, max := so.SendBufferLimits()
+	so.SetSendBufferSize(clampBufferSize(int64(bytes), min, max), true /* notify */)
+	return nil
+}
+
+func clampBufferSize(v, min, max int64) int64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
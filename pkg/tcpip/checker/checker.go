@@ -311,6 +311,30 @@ func NoTClassReceived() ControlMessagesChecker {
 	}
 }
 
+// ReceiveFlowInfo creates a checker that checks the FlowInfo field in
+// ControlMessages.
+func ReceiveFlowInfo(want uint32) ControlMessagesChecker {
+	return func(t *testing.T, cm tcpip.ReceivableControlMessages) {
+		t.Helper()
+		if !cm.HasFlowInfo {
+			t.Error("got cm.HasFlowInfo = false, want = true")
+		} else if got := cm.FlowInfo; got != want {
+			t.Errorf("got cm.FlowInfo = %#x, want %#x", got, want)
+		}
+	}
+}
+
+// NoFlowInfoReceived creates a checker that checks the absence of the
+// FlowInfo field in ControlMessages.
+func NoFlowInfoReceived() ControlMessagesChecker {
+	return func(t *testing.T, cm tcpip.ReceivableControlMessages) {
+		t.Helper()
+		if cm.HasFlowInfo {
+			t.Error("got cm.HasFlowInfo = true, want = false")
+		}
+	}
+}
+
 // ReceiveTOS creates a checker that checks the TOS field in ControlMessages.
 func ReceiveTOS(want uint8) ControlMessagesChecker {
 	return func(t *testing.T, cm tcpip.ReceivableControlMessages) {
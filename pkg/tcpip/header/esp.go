@@ -0,0 +1,60 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"encoding/binary"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+const (
+	espSPI    = 0
+	espSeqNum = 4
+)
+
+const (
+	// ESPProtocolNumber is the IP protocol number of the Encapsulating
+	// Security Payload.
+	ESPProtocolNumber tcpip.TransportProtocolNumber = 50
+
+	// ESPHeaderSize is the size of the ESP header, which precedes the IV and
+	// the encrypted payload.
+	ESPHeaderSize = 8
+
+	// ESPTrailerMinimumSize is the size of the Pad Length and Next Header
+	// fields that end the encrypted payload.
+	ESPTrailerMinimumSize = 2
+)
+
+// ESP represents an Encapsulating Security Payload header stored in a byte
+// array, as described in RFC 4303 section 2.
+type ESP []byte
+
+// SPI returns the Security Parameters Index.
+func (b ESP) SPI() uint32 {
+	return binary.BigEndian.Uint32(b[espSPI:])
+}
+
+// SequenceNumber returns the sequence number.
+func (b ESP) SequenceNumber() uint32 {
+	return binary.BigEndian.Uint32(b[espSeqNum:])
+}
+
+// Encode encodes the ESP header with the given SPI and sequence number.
+func (b ESP) Encode(spi, seq uint32) {
+	binary.BigEndian.PutUint32(b[espSPI:], spi)
+	binary.BigEndian.PutUint32(b[espSeqNum:], seq)
+}
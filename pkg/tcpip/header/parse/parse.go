@@ -163,6 +163,17 @@ func UDP(pkt *stack.PacketBuffer) bool {
 	return ok
 }
 
+// UDPLite parses a UDP-Lite packet found in pkt.Data and populates pkt's
+// transport header with the UDP-Lite header, which has the same layout as the
+// UDP header.
+//
+// Returns true if the header was successfully parsed.
+func UDPLite(pkt *stack.PacketBuffer) bool {
+	_, ok := pkt.TransportHeader().Consume(header.UDPMinimumSize)
+	pkt.TransportProtocolNumber = header.UDPLiteProtocolNumber
+	return ok
+}
+
 // TCP parses a TCP packet found in pkt.Data and populates pkt's transport
 // header with the TCP header.
 //
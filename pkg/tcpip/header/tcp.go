@@ -91,6 +91,8 @@ const (
 	TCPOptionTS            = 8
 	TCPOptionSACKPermitted = 4
 	TCPOptionSACK          = 5
+	TCPOptionMD5           = 19
+	TCPOptionAO            = 29
 )
 
 // Option Lengths.
@@ -99,8 +101,14 @@ const (
 	TCPOptionTSLength            = 10
 	TCPOptionWSLength            = 3
 	TCPOptionSackPermittedLength = 2
+	TCPOptionMD5Length           = 18
+	TCPOptionAOMinimumLength     = 4
 )
 
+// TCPMD5DigestSize is the size of the digest carried in the TCP MD5 signature
+// option, as described in RFC 2385.
+const TCPMD5DigestSize = 16
+
 // TCPFields contains the fields of a TCP packet. It is used to describe the
 // fields of a packet that needs to be encoded.
 type TCPFields struct {
@@ -662,6 +670,69 @@ func EncodeSACKBlocks(sackBlocks []SACKBlock, b []byte) int {
 	return int(b[1])
 }
 
+// EncodeMD5Option encodes a TCP MD5 signature option with a zero digest,
+// preceded by two NOPs as done by Linux, into the provided buffer. The digest
+// is at offset 4 of the encoded option and must be filled in once the rest of
+// the segment is known. If the buffer is smaller than required it just returns
+// without encoding anything. It returns the number of bytes written to the
+// provided buffer.
+func EncodeMD5Option(b []byte) int {
+	const size = 2 + TCPOptionMD5Length
+	if len(b) < size {
+		return 0
+	}
+	b[0], b[1], b[2], b[3] = TCPOptionNOP, TCPOptionNOP, TCPOptionMD5, TCPOptionMD5Length
+	clear(b[4:size])
+	return size
+}
+
+// EncodeAOOption encodes a TCP Authentication Option, as described in RFC
+// 5925, with a zero MAC of macLen bytes into the provided buffer. The MAC is
+// at offset 4 of the encoded option and must be filled in once the rest of the
+// segment is known. The option is padded with NOPs to a multiple of four
+// bytes. If the buffer is smaller than required it just returns without
+// encoding anything. It returns the number of bytes written to the provided
+// buffer.
+func EncodeAOOption(keyID, rNextKeyID uint8, macLen int, b []byte) int {
+	optLen := TCPOptionAOMinimumLength + macLen
+	size := (optLen + 3) &^ 3
+	if len(b) < size {
+		return 0
+	}
+	b[0], b[1], b[2], b[3] = TCPOptionAO, uint8(optLen), keyID, rNextKeyID
+	clear(b[TCPOptionAOMinimumLength:optLen])
+	AddTCPOptionPadding(b, optLen)
+	return size
+}
+
+// FindTCPOption returns the first option of the given kind in opts, including
+// its kind and length bytes. It returns false if there is no such option or
+// the options are malformed before it is found.
+func FindTCPOption(opts []byte, kind uint8) ([]byte, bool) {
+	limit := len(opts)
+	for i := 0; i < limit; {
+		switch opts[i] {
+		case TCPOptionEOL:
+			return nil, false
+		case TCPOptionNOP:
+			i++
+		default:
+			if i+2 > limit {
+				return nil, false
+			}
+			l := int(opts[i+1])
+			if l < 2 || i+l > limit {
+				return nil, false
+			}
+			if opts[i] == kind {
+				return opts[i : i+l], true
+			}
+			i += l
+		}
+	}
+	return nil, false
+}
+
 // EncodeNOP adds an explicit NOP to the option list.
 func EncodeNOP(b []byte) int {
 	if len(b) == 0 {
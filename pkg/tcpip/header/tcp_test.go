@@ -169,3 +169,68 @@ func TestTCPFlags(t *testing.T) {
 		}
 	}
 }
+
+func TestEncodeAuthOptions(t *testing.T) {
+	var b [header.TCPOptionsMaximumSize]byte
+	n := header.EncodeMD5Option(b[:])
+	if n != 20 {
+		t.Fatalf("EncodeMD5Option(_) = %d, want = 20", n)
+	}
+	opt, ok := header.FindTCPOption(b[:n], header.TCPOptionMD5)
+	if !ok || len(opt) != header.TCPOptionMD5Length {
+		t.Errorf("FindTCPOption(%v, TCPOptionMD5) = (%v, %t), want an option of length %d", b[:n], opt, ok, header.TCPOptionMD5Length)
+	}
+
+	for _, tc := range []struct {
+		macLen int
+		want   int
+	}{
+		{12, 16},
+		{16, 20},
+		{10, 16},
+	} {
+		b := make([]byte, header.TCPOptionsMaximumSize)
+		n := header.EncodeAOOption(1, 2, tc.macLen, b)
+		if n != tc.want {
+			t.Errorf("EncodeAOOption(1, 2, %d, _) = %d, want = %d", tc.macLen, n, tc.want)
+			continue
+		}
+		opt, ok := header.FindTCPOption(b[:n], header.TCPOptionAO)
+		if !ok {
+			t.Errorf("FindTCPOption(%v, TCPOptionAO) didn't find the option", b[:n])
+			continue
+		}
+		if got, want := opt[:4], []byte{header.TCPOptionAO, uint8(header.TCPOptionAOMinimumLength + tc.macLen), 1, 2}; !slices.Equal(got, want) {
+			t.Errorf("got AO option header = %v, want = %v", got, want)
+		}
+		if got := len(opt); got != header.TCPOptionAOMinimumLength+tc.macLen {
+			t.Errorf("got AO option length = %d, want = %d", got, header.TCPOptionAOMinimumLength+tc.macLen)
+		}
+	}
+
+	if n := header.EncodeAOOption(1, 2, 16, b[:19]); n != 0 {
+		t.Errorf("EncodeAOOption(1, 2, 16, <19 byte buffer>) = %d, want = 0", n)
+	}
+}
+
+func TestFindTCPOption(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []byte
+		kind uint8
+		want []byte
+	}{
+		{"Found", []byte{header.TCPOptionNOP, header.TCPOptionWS, 3, 7, header.TCPOptionMSS, 4, 5, 0}, header.TCPOptionMSS, []byte{header.TCPOptionMSS, 4, 5, 0}},
+		{"Missing", []byte{header.TCPOptionNOP, header.TCPOptionWS, 3, 7}, header.TCPOptionMSS, nil},
+		{"AfterEOL", []byte{header.TCPOptionEOL, header.TCPOptionMSS, 4, 5, 0}, header.TCPOptionMSS, nil},
+		{"Truncated", []byte{header.TCPOptionWS, 3, 7, header.TCPOptionMSS, 4, 5}, header.TCPOptionMSS, nil},
+		{"BadLength", []byte{header.TCPOptionWS, 1, header.TCPOptionMSS, 4, 5, 0}, header.TCPOptionMSS, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := header.FindTCPOption(tc.opts, tc.kind)
+			if ok != (tc.want != nil) || !slices.Equal(got, tc.want) {
+				t.Errorf("FindTCPOption(%v, %d) = (%v, %t), want = %v", tc.opts, tc.kind, got, ok, tc.want)
+			}
+		})
+	}
+}
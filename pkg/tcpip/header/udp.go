@@ -63,6 +63,9 @@ const (
 
 	// UDPProtocolNumber is UDP's transport protocol number.
 	UDPProtocolNumber tcpip.TransportProtocolNumber = 17
+
+	// UDPLiteProtocolNumber is UDP-Lite's transport protocol number.
+	UDPLiteProtocolNumber tcpip.TransportProtocolNumber = 136
 )
 
 // SourcePort returns the "source port" field of the UDP header.
@@ -124,6 +127,39 @@ func (b UDP) IsChecksumValid(src, dst tcpip.Address, payloadChecksum uint16) boo
 	return b.CalculateChecksum(xsum) == 0xffff
 }
 
+// UDPLiteValid performs basic validation of a UDP-Lite packet, whose length
+// field holds the number of bytes covered by the checksum, as described in
+// RFC 3828 section 3.1. payloadChecksum is called with the number of payload
+// bytes covered by the checksum.
+//
+// It returns the checksum coverage and whether it is valid, as well as
+// whether the checksum is valid.
+func UDPLiteValid(hdr UDP, payloadChecksum func(covered int) uint16, payloadSize uint16, srcAddr, dstAddr tcpip.Address, skipChecksumValidation bool) (coverage uint16, coverageValid, csumValid bool) {
+	length := payloadSize + UDPMinimumSize
+	coverage = hdr.Length()
+	// A coverage of zero means the entire packet is covered.
+	if coverage == 0 {
+		coverage = length
+	}
+	if coverage < UDPMinimumSize || coverage > length {
+		return coverage, false, false
+	}
+
+	if skipChecksumValidation {
+		return coverage, true, true
+	}
+
+	// Unlike UDP, the checksum is mandatory.
+	if hdr.Checksum() == 0 {
+		return coverage, true, false
+	}
+
+	// The pseudo header holds the length of the packet, not the coverage.
+	xsum := PseudoHeaderChecksum(UDPLiteProtocolNumber, dstAddr, srcAddr, length)
+	xsum = checksum.Combine(xsum, payloadChecksum(int(coverage-UDPMinimumSize)))
+	return coverage, true, hdr.CalculateChecksum(xsum) == 0xffff
+}
+
 // Encode encodes all the fields of the UDP header.
 func (b UDP) Encode(u *UDPFields) {
 	b.SetSourcePort(u.SrcPort)
@@ -154,6 +154,10 @@ type endpoint struct {
 	// wg keeps track of running goroutines.
 	wg sync.WaitGroup
 
+	// processorQueueDrops is the number of inbound packets dropped because
+	// a processor's queue was full.
+	processorQueueDrops atomicbitops.Uint64
+
 	// gsoKind is the supported kind of GSO.
 	gsoKind stack.SupportedGSO
 
@@ -235,6 +239,12 @@ type Options struct {
 	// ProcessorsPerChannel is the number of goroutines used to handle packets
 	// from each FD.
 	ProcessorsPerChannel int
+
+	// ProcessorQueueLen is the maximum number of inbound packets that may be
+	// queued for each processor goroutine. Packets received while a
+	// processor's queue is full are dropped. If ProcessorQueueLen is 0, queues
+	// are unbounded.
+	ProcessorQueueLen int
 }
 
 // fanoutID is used for AF_PACKET based endpoints to enable PACKET_FANOUT
@@ -801,6 +811,12 @@ func (e *endpoint) SupportedGSO() stack.SupportedGSO {
 	return e.gsoKind
 }
 
+// ProcessorQueueDrops returns the number of inbound packets that were dropped
+// because they were steered to a processor whose queue was full.
+func (e *endpoint) ProcessorQueueDrops() uint64 {
+	return e.processorQueueDrops.Load()
+}
+
 // ARPHardwareType implements stack.LinkEndpoint.ARPHardwareType.
 func (e *endpoint) ARPHardwareType() header.ARPHardwareType {
 	if e.hdrSize > 0 {
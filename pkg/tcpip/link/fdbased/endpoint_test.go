@@ -704,6 +704,77 @@ func TestDispatchPacketFormat(t *testing.T) {
 	}
 }
 
+func newTCPIPv4Packet(srcPort, dstPort uint16) *stack.PacketBuffer {
+	b := make([]byte, header.IPv4MinimumSize+header.TCPMinimumSize)
+	header.IPv4(b).Encode(&header.IPv4Fields{
+		TotalLength: uint16(len(b)),
+		TTL:         64,
+		Protocol:    uint8(header.TCPProtocolNumber),
+		SrcAddr:     tcpip.AddrFrom4([4]byte{10, 0, 0, 1}),
+		DstAddr:     tcpip.AddrFrom4([4]byte{10, 0, 0, 2}),
+	})
+	header.TCP(b[header.IPv4MinimumSize:]).Encode(&header.TCPFields{
+		SrcPort:    srcPort,
+		DstPort:    dstPort,
+		DataOffset: header.TCPMinimumSize,
+	})
+	return stack.NewPacketBuffer(stack.PacketBufferOptions{
+		Payload: buffer.MakeWithData(b),
+	})
+}
+
+func TestProcessorQueueLen(t *testing.T) {
+	e := &endpoint{}
+	const queueLen = 2
+	m := newProcessorManager(&Options{ProcessorsPerChannel: 1, ProcessorQueueLen: queueLen}, e)
+	for i := 0; i < queueLen+1; i++ {
+		pkt := newTCPIPv4Packet(1000, 2000)
+		m.queuePacket(pkt, false /* hasEthHeader */)
+		pkt.DecRef()
+	}
+	p := &m.processors[0]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if got := p.pkts.Len(); got != queueLen {
+		t.Errorf("got %d queued packets, want %d", got, queueLen)
+	}
+	if got := e.ProcessorQueueDrops(); got != 1 {
+		t.Errorf("got %d dropped packets, want 1", got)
+	}
+	p.pkts.Reset()
+}
+
+func TestIPv6FragmentConnectionID(t *testing.T) {
+	b := make([]byte, header.IPv6MinimumSize+header.IPv6FragmentHeaderSize+header.UDPMinimumSize)
+	header.IPv6(b).Encode(&header.IPv6Fields{
+		PayloadLength:     uint16(header.IPv6FragmentHeaderSize + header.UDPMinimumSize),
+		TransportProtocol: header.IPv6FragmentHeader,
+		HopLimit:          64,
+		SrcAddr:           tcpip.AddrFrom16([16]byte{1: 1}),
+		DstAddr:           tcpip.AddrFrom16([16]byte{1: 2}),
+	})
+	copy(b[header.IPv6MinimumSize:], []byte{
+		uint8(header.UDPProtocolNumber), 0, // Next header, reserved.
+		0, 1, // Fragment offset 0, more fragments.
+		0, 0, 0, 1, // Identification.
+	})
+	header.UDP(b[header.IPv6MinimumSize+header.IPv6FragmentHeaderSize:]).Encode(&header.UDPFields{
+		SrcPort: 1000,
+		DstPort: 2000,
+	})
+	pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{
+		Payload: buffer.MakeWithData(b),
+	})
+	defer pkt.DecRef()
+	cid, nonConnectionPkt := tcpipConnectionID(pkt)
+	if nonConnectionPkt {
+		t.Fatalf("tcpipConnectionID() returned nonConnectionPkt for an IPv6 fragment")
+	}
+	if cid.srcPort != 0 || cid.dstPort != 0 {
+		t.Errorf("got ports (%d, %d) for an IPv6 fragment, want (0, 0)", cid.srcPort, cid.dstPort)
+	}
+}
+
 func TestMain(m *testing.M) {
 	refs.SetLeakMode(refs.LeaksPanic)
 	code := m.Run()
@@ -89,6 +89,10 @@ type processorManager struct {
 	wg         sync.WaitGroup
 	e          *endpoint
 	ready      []bool
+
+	// queueLen is the maximum number of packets queued for each processor,
+	// or 0 if queues are unbounded.
+	queueLen int
 }
 
 // newProcessorManager creates a new processor manager.
@@ -98,6 +102,7 @@ func newProcessorManager(opts *Options, e *endpoint) *processorManager {
 	m.ready = make([]bool, opts.ProcessorsPerChannel)
 	m.processors = make([]processor, opts.ProcessorsPerChannel)
 	m.e = e
+	m.queueLen = opts.ProcessorQueueLen
 	m.wg.Add(opts.ProcessorsPerChannel)
 
 	for i := range m.processors {
@@ -157,6 +162,12 @@ func (m *processorManager) queuePacket(pkt *stack.PacketBuffer, hasEthHeader boo
 	p := &m.processors[pIdx]
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	if m.queueLen > 0 && p.pkts.Len() >= m.queueLen {
+		// Drop the packet rather than steering it to another processor,
+		// which could reorder packets within its flow.
+		m.e.processorQueueDrops.Add(1)
+		return
+	}
 	pkt.IncRef()
 	p.pkts.PushBack(pkt)
 	m.ready[pIdx] = true
@@ -208,9 +219,12 @@ func tcpipConnectionID(pkt *stack.PacketBuffer) (connectionID, bool) {
 		ipHdr := header.IPv6(h)
 
 		var tcpHdr header.TCP
-		if tcpip.TransportProtocolNumber(ipHdr.NextHeader()) == header.TCPProtocolNumber {
+		isFragment := false
+		switch tcpip.TransportProtocolNumber(ipHdr.NextHeader()) {
+		case header.TCPProtocolNumber, header.UDPProtocolNumber:
+			// TCP and UDP ports are at the same offsets.
 			tcpHdr = header.TCP(h[header.IPv6FixedHeaderSize:][:tcpSrcDstPortLen])
-		} else {
+		default:
 			// Slow path for IPv6 extension headers :(.
 			dataBuf := pkt.Data().ToBuffer()
 			dataBuf.TrimFront(header.IPv6MinimumSize)
@@ -221,18 +235,27 @@ func tcpipConnectionID(pkt *stack.PacketBuffer) (connectionID, bool) {
 				if done || err != nil {
 					break
 				}
+				if _, ok := hdr.(header.IPv6FragmentExtHdr); ok {
+					isFragment = true
+				}
 				hdr.Release()
 			}
-			h, ok = pkt.Data().PullUp(int(it.HeaderOffset()) + tcpSrcDstPortLen)
-			if !ok {
-				return cid, true
+			if !isFragment {
+				h, ok = pkt.Data().PullUp(int(it.HeaderOffset()) + tcpSrcDstPortLen)
+				if !ok {
+					return cid, true
+				}
+				tcpHdr = header.TCP(h[it.HeaderOffset():][:tcpSrcDstPortLen])
 			}
-			tcpHdr = header.TCP(h[it.HeaderOffset():][:tcpSrcDstPortLen])
 		}
 		cid.srcAddr = ipHdr.SourceAddressSlice()
 		cid.dstAddr = ipHdr.DestinationAddressSlice()
-		cid.srcPort = tcpHdr.SourcePort()
-		cid.dstPort = tcpHdr.DestinationPort()
+		// As for IPv4, all fragments of a packet must be processed by the
+		// same goroutine, so only record ports if this is not a fragment.
+		if !isFragment {
+			cid.srcPort = tcpHdr.SourcePort()
+			cid.dstPort = tcpHdr.DestinationPort()
+		}
 		cid.proto = header.IPv6ProtocolNumber
 	default:
 		return cid, true
@@ -17,6 +17,7 @@ package tun
 import (
 	"fmt"
 
+	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/buffer"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/errors/linuxerr"
@@ -36,8 +37,20 @@ const (
 	// Queue length for outbound packet, arriving at fd side for read. Overflow
 	// causes packet drops. gVisor implementation-specific.
 	defaultDevOutQueueLen = 1024
+
+	// maxGSOPacketSize is the maximum size of a packet written to a device
+	// with IFF_VNET_HDR, excluding the virtio header, packet information
+	// header and Ethernet header. GSO packets may be larger than the MTU.
+	maxGSOPacketSize = 1<<16 - 1
+
+	// supportedOffloads are the TUNSETOFFLOAD flags accepted by SetOffload.
+	supportedOffloads = linux.TUN_F_CSUM | linux.TUN_F_TSO4 | linux.TUN_F_TSO6 | linux.TUN_F_TSO_ECN | linux.TUN_F_USO4 | linux.TUN_F_USO6
 )
 
+// NoOwner is the owner or group of a NIC that has no owner or group set with
+// TUNSETOWNER or TUNSETGROUP.
+const NoOwner = ^uint32(0)
+
 var zeroMAC [6]byte
 
 // Device is an opened /dev/net/tun device.
@@ -50,6 +63,15 @@ type Device struct {
 	endpoint     *tunEndpoint
 	notifyHandle *channel.NotificationHandle
 	flags        Flags
+
+	// vnetHdrSize is the size of the virtio header that precedes each packet
+	// if flags.VnetHdr is set. If zero, linux.VIRTIO_NET_HDR_LEN is used.
+	vnetHdrSize int32
+
+	// offloads are the TUN_F_* flags set with TUNSETOFFLOAD. Outbound
+	// packets are never checksum or segmentation offloaded, so offloads
+	// currently has no effect.
+	offloads uint32
 }
 
 // Flags set properties of a Device
@@ -59,6 +81,10 @@ type Flags struct {
 	TUN          bool
 	TAP          bool
 	NoPacketInfo bool
+
+	// VnetHdr indicates that each packet is preceded by a struct
+	// virtio_net_hdr (IFF_VNET_HDR).
+	VnetHdr bool
 }
 
 // beforeSave is invoked by stateify.
@@ -121,6 +147,112 @@ func (d *Device) SetIff(s *stack.Stack, name string, flags Flags) error {
 	return nil
 }
 
+// Owner returns the owner and group of the NIC created by a tun device with
+// the given name, as set by TUNSETOWNER and TUNSETGROUP. ok is false if no
+// such NIC exists.
+func Owner(s *stack.Stack, name string) (owner, group uint32, ok bool) {
+	endpoint, isTun := s.GetLinkEndpointByName(name).(*tunEndpoint)
+	if !isTun {
+		return NoOwner, NoOwner, false
+	}
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	return endpoint.owner, endpoint.group, true
+}
+
+// SetPersistent services TUNSETPERSIST ioctl(2) request. A persistent NIC
+// is not removed when the last device attached to it is released.
+func (d *Device) SetPersistent(ctx context.Context, persistent bool) error {
+	d.mu.RLock()
+	endpoint := d.endpoint
+	d.mu.RUnlock()
+	if endpoint == nil {
+		return linuxerr.EBADFD
+	}
+	endpoint.mu.Lock()
+	changed := endpoint.persistent != persistent
+	endpoint.persistent = persistent
+	endpoint.mu.Unlock()
+	if !changed {
+		return nil
+	}
+	// A persistent NIC holds a reference on itself.
+	if persistent {
+		endpoint.IncRef()
+	} else {
+		endpoint.DecRef(ctx)
+	}
+	return nil
+}
+
+// SetOwner services TUNSETOWNER ioctl(2) request.
+func (d *Device) SetOwner(owner uint32) error {
+	d.mu.RLock()
+	endpoint := d.endpoint
+	d.mu.RUnlock()
+	if endpoint == nil {
+		return linuxerr.EBADFD
+	}
+	endpoint.mu.Lock()
+	endpoint.owner = owner
+	endpoint.mu.Unlock()
+	return nil
+}
+
+// SetGroup services TUNSETGROUP ioctl(2) request.
+func (d *Device) SetGroup(group uint32) error {
+	d.mu.RLock()
+	endpoint := d.endpoint
+	d.mu.RUnlock()
+	if endpoint == nil {
+		return linuxerr.EBADFD
+	}
+	endpoint.mu.Lock()
+	endpoint.group = group
+	endpoint.mu.Unlock()
+	return nil
+}
+
+// SetOffload services TUNSETOFFLOAD ioctl(2) request.
+func (d *Device) SetOffload(offloads uint32) error {
+	if offloads&^supportedOffloads != 0 {
+		return linuxerr.EINVAL
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.endpoint == nil {
+		return linuxerr.EBADFD
+	}
+	d.offloads = offloads
+	return nil
+}
+
+// VnetHdrSize returns the size of the virtio header used if IFF_VNET_HDR is
+// set.
+func (d *Device) VnetHdrSize() int32 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.vnetHdrSizeLocked()
+}
+
+func (d *Device) vnetHdrSizeLocked() int32 {
+	if d.vnetHdrSize == 0 {
+		return linux.VIRTIO_NET_HDR_LEN
+	}
+	return d.vnetHdrSize
+}
+
+// SetVnetHdrSize services TUNSETVNETHDRSZ ioctl(2) request.
+func (d *Device) SetVnetHdrSize(size int32) error {
+	if size < linux.VIRTIO_NET_HDR_LEN {
+		return linuxerr.EINVAL
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.vnetHdrSize = size
+	return nil
+}
+
 func attachOrCreateNIC(s *stack.Stack, name, prefix string, linkCaps stack.LinkEndpointCapabilities) (*tunEndpoint, error) {
 	for {
 		// 1. Try to attach to an existing NIC.
@@ -147,6 +279,8 @@ func attachOrCreateNIC(s *stack.Stack, name, prefix string, linkCaps stack.LinkE
 			nicID:    id,
 			name:     name,
 			isTap:    prefix == "tap",
+			owner:    NoOwner,
+			group:    NoOwner,
 		}
 		endpoint.InitRefs()
 		endpoint.Endpoint.LinkEPCapabilities = linkCaps
@@ -182,10 +316,38 @@ func (d *Device) MTU() (uint32, error) {
 	return endpoint.MTU(), nil
 }
 
+// WriteLimit returns the maximum number of bytes that may be written to d in
+// a single write.
+func (d *Device) WriteLimit() (int64, error) {
+	d.mu.RLock()
+	endpoint := d.endpoint
+	flags := d.flags
+	vnetHdrSize := d.vnetHdrSizeLocked()
+	d.mu.RUnlock()
+	if endpoint == nil {
+		return 0, linuxerr.EBADFD
+	}
+	if !endpoint.IsAttached() {
+		return 0, linuxerr.EIO
+	}
+	if !flags.VnetHdr {
+		return int64(endpoint.MTU()), nil
+	}
+	limit := int64(vnetHdrSize) + maxGSOPacketSize
+	if !flags.NoPacketInfo {
+		limit += PacketInfoHeaderSize
+	}
+	if flags.TAP {
+		limit += header.EthernetMinimumSize
+	}
+	return limit, nil
+}
+
 // Write inject one inbound packet to the network interface.
 func (d *Device) Write(data *buffer.View) (int64, error) {
 	d.mu.RLock()
 	endpoint := d.endpoint
+	vnetHdrSize := d.vnetHdrSizeLocked()
 	d.mu.RUnlock()
 	if endpoint == nil {
 		return 0, linuxerr.EBADFD
@@ -210,6 +372,30 @@ func (d *Device) Write(data *buffer.View) (int64, error) {
 		data.TrimFront(PacketInfoHeaderSize)
 	}
 
+	// Virtio header.
+	checksumValidated := false
+	if d.flags.VnetHdr {
+		if int64(data.Size()) < int64(vnetHdrSize) {
+			return 0, linuxerr.EINVAL
+		}
+		vnetHdr := VirtioNetHeader(data.AsSlice()[:linux.VIRTIO_NET_HDR_LEN])
+		switch vnetHdr.GSOType() &^ linux.VIRTIO_NET_HDR_GSO_ECN {
+		case linux.VIRTIO_NET_HDR_GSO_NONE, linux.VIRTIO_NET_HDR_GSO_TCPV4, linux.VIRTIO_NET_HDR_GSO_TCPV6, linux.VIRTIO_NET_HDR_GSO_UDP_L4:
+		default:
+			return 0, linuxerr.EINVAL
+		}
+		// Packets that need checksums have only a partial checksum, so they
+		// can't be validated by the stack. Segmentation offloaded packets
+		// are injected without being segmented; the stack accepts packets
+		// larger than the MTU.
+		checksumValidated = vnetHdr.Flags()&(linux.VIRTIO_NET_HDR_F_NEEDS_CSUM|linux.VIRTIO_NET_HDR_F_DATA_VALID) != 0
+		data.TrimFront(int(vnetHdrSize))
+		if data.Size() == 0 {
+			// Ignore bad packet.
+			return dataLen, nil
+		}
+	}
+
 	// Ethernet header (TAP only).
 	var ethHdr header.Ethernet
 	if d.flags.TAP {
@@ -247,6 +433,7 @@ func (d *Device) Write(data *buffer.View) (int64, error) {
 		Payload:            buffer.MakeWithView(data.Clone()),
 	})
 	defer pkt.DecRef()
+	pkt.RXChecksumValidated = checksumValidated
 	copy(pkt.LinkHeader().Push(len(ethHdr)), ethHdr)
 	endpoint.InjectInbound(protocol, pkt)
 	return dataLen, nil
@@ -272,23 +459,45 @@ func (d *Device) Read() (*buffer.View, error) {
 
 // encodePkt encodes packet for fd side.
 func (d *Device) encodePkt(pkt *stack.PacketBuffer) *buffer.View {
-	var view *buffer.View
+	d.mu.RLock()
+	flags := d.flags
+	vnetHdrSize := int(d.vnetHdrSizeLocked())
+	d.mu.RUnlock()
+
+	var hdrSize int
+	if !flags.NoPacketInfo {
+		hdrSize += PacketInfoHeaderSize
+	}
+	if flags.VnetHdr {
+		hdrSize += vnetHdrSize
+	}
+	if hdrSize == 0 {
+		return pkt.ToView()
+	}
+
+	view := buffer.NewView(hdrSize + pkt.Size())
+	view.Grow(hdrSize)
+	hdrs := view.AsSlice()
 
 	// Packet information.
-	if !d.flags.NoPacketInfo {
-		view = buffer.NewView(PacketInfoHeaderSize + pkt.Size())
-		view.Grow(PacketInfoHeaderSize)
-		hdr := PacketInfoHeader(view.AsSlice())
+	if !flags.NoPacketInfo {
+		hdr := PacketInfoHeader(hdrs[:PacketInfoHeaderSize])
 		hdr.Encode(&PacketInfoFields{
 			Protocol: pkt.NetworkProtocolNumber,
 		})
-		pktView := pkt.ToView()
-		view.Write(pktView.AsSlice())
-		pktView.Release()
-	} else {
-		view = pkt.ToView()
+		hdrs = hdrs[PacketInfoHeaderSize:]
 	}
 
+	// Virtio header. Outbound packets are fully checksummed and never
+	// segmentation offloaded, so the header is all zeroes
+	// (VIRTIO_NET_HDR_GSO_NONE).
+	if flags.VnetHdr {
+		clear(hdrs[:vnetHdrSize])
+	}
+
+	pktView := pkt.ToView()
+	view.Write(pktView.AsSlice())
+	pktView.Release()
 	return view
 }
 
@@ -340,6 +549,22 @@ type tunEndpoint struct {
 	nicID tcpip.NICID
 	name  string
 	isTap bool
+
+	mu sync.Mutex
+
+	// persistent is true if the NIC was made persistent with TUNSETPERSIST,
+	// in which case the NIC holds a reference on itself.
+	//
+	// +checklocks:mu
+	persistent bool
+
+	// owner and group are the UID and GID set with TUNSETOWNER and
+	// TUNSETGROUP, or NoOwner.
+	//
+	// +checklocks:mu
+	owner uint32
+	// +checklocks:mu
+	group uint32
 }
 
 // DecRef decrements refcount of e, removing NIC if it reaches 0.
@@ -54,3 +54,17 @@ func (h PacketInfoHeader) Flags() uint16 {
 func (h PacketInfoHeader) Protocol() tcpip.NetworkProtocolNumber {
 	return tcpip.NetworkProtocolNumber(binary.BigEndian.Uint16(h[offsetProtocol:]))
 }
+
+// VirtioNetHeader is the wire representation of the struct virtio_net_hdr that
+// precedes packets if the IFF_VNET_HDR flag is set.
+type VirtioNetHeader []byte
+
+// Flags returns the flags field in h.
+func (h VirtioNetHeader) Flags() uint8 {
+	return h[0]
+}
+
+// GSOType returns the gso_type field in h.
+func (h VirtioNetHeader) GSOType() uint8 {
+	return h[1]
+}
@@ -516,6 +516,18 @@ func (e *endpoint) handleFragments(_ *stack.Route, networkMTU uint32, pkt *stack
 
 // WritePacket writes a packet to the given destination address and protocol.
 func (e *endpoint) WritePacket(r *stack.Route, params stack.NetworkHeaderParams, pkt *stack.PacketBuffer) tcpip.Error {
+	if e.protocol.stack.XFRMEnabled() {
+		espPkt, err := e.protocol.stack.XFRMOutput(ProtocolNumber, r.LocalAddress(), r.RemoteAddress(), params.Protocol, pkt, int(e.MaxHeaderLength()))
+		if err != nil {
+			return err
+		}
+		if espPkt != nil {
+			defer espPkt.DecRef()
+			pkt = espPkt
+			params.Protocol = header.ESPProtocolNumber
+		}
+	}
+
 	if err := e.addIPHeader(r.LocalAddress(), r.RemoteAddress(), pkt, params, nil /* options */); err != nil {
 		return err
 	}
@@ -1292,6 +1304,17 @@ func (e *endpoint) deliverPacketLocally(h header.IPv4, pkt *stack.PacketBuffer,
 		// Now that the packet is reassembled, it can be sent to raw sockets.
 		e.dispatcher.DeliverRawPacket(h.TransportProtocol(), pkt)
 	}
+	if e.protocol.stack.XFRMEnabled() {
+		espPkt, ok := e.xfrmInput(h, pkt)
+		if !ok {
+			return
+		}
+		if espPkt != nil {
+			defer espPkt.DecRef()
+			pkt = espPkt
+			h = header.IPv4(pkt.NetworkHeader().Slice())
+		}
+	}
 	stats.ip.PacketsDelivered.Increment()
 
 	p := h.TransportProtocol()
@@ -1353,6 +1376,49 @@ func (e *endpoint) deliverPacketLocally(h header.IPv4, pkt *stack.PacketBuffer,
 	}
 }
 
+// xfrmInput decapsulates a locally delivered ESP packet and applies the
+// inbound IPsec policies to the packet. It returns the decapsulated packet, or
+// nil if pkt is not an ESP packet, and false if the packet must be dropped.
+func (e *endpoint) xfrmInput(h header.IPv4, pkt *stack.PacketBuffer) (*stack.PacketBuffer, bool) {
+	s := e.protocol.stack
+	src, dst := h.SourceAddress(), h.DestinationAddress()
+	proto := h.TransportProtocol()
+	var sp stack.XFRMSecPath
+	var espPkt *stack.PacketBuffer
+	if proto == header.ESPProtocolNumber {
+		payload, nextProto, secPath, ok := s.XFRMDecapsulate(src, dst, pkt.Data().AsRange().ToSlice())
+		if !ok {
+			return nil, false
+		}
+		// Replace the ESP packet with its payload, fixing up a copy of the
+		// network header.
+		hdr := header.IPv4(append([]byte(nil), h...))
+		hdr.SetProtocol(uint8(nextProto))
+		hdr.SetTotalLength(uint16(len(hdr) + len(payload)))
+		hdr.SetChecksum(0)
+		hdr.SetChecksum(^hdr.CalculateChecksum())
+		espPkt = stack.NewPacketBuffer(stack.PacketBufferOptions{
+			Payload: buffer.MakeWithData(append(hdr, payload...)),
+		})
+		espPkt.NetworkProtocolNumber = ProtocolNumber
+		espPkt.NICID = pkt.NICID
+		espPkt.PktType = pkt.PktType
+		espPkt.NetworkPacketInfo = pkt.NetworkPacketInfo
+		if _, ok := espPkt.NetworkHeader().Consume(len(hdr)); !ok {
+			panic(fmt.Sprintf("failed to consume %d bytes of network header", len(hdr)))
+		}
+		e.protocol.parseTransport(espPkt, nextProto)
+		pkt, proto, sp = espPkt, nextProto, secPath
+	}
+	if !s.XFRMCheckInput(ProtocolNumber, src, dst, proto, pkt.TransportHeader().Slice(), sp) {
+		if espPkt != nil {
+			espPkt.DecRef()
+		}
+		return nil, false
+	}
+	return espPkt, true
+}
+
 // Close cleans up resources associated with the endpoint.
 func (e *endpoint) Close() {
 	e.mu.Lock()
@@ -744,6 +744,7 @@ func addIPHeader(srcAddr, dstAddr tcpip.Address, pkt *stack.PacketBuffer, params
 		TransportProtocol: params.Protocol,
 		HopLimit:          params.TTL,
 		TrafficClass:      params.TOS,
+		FlowLabel:         params.FlowLabel,
 		SrcAddr:           srcAddr,
 		DstAddr:           dstAddr,
 		ExtensionHeaders:  extensionHeaders,
@@ -806,6 +807,17 @@ func (e *endpoint) handleFragments(r *stack.Route, networkMTU uint32, pkt *stack
 // WritePacket writes a packet to the given destination address and protocol.
 func (e *endpoint) WritePacket(r *stack.Route, params stack.NetworkHeaderParams, pkt *stack.PacketBuffer) tcpip.Error {
 	dstAddr := r.RemoteAddress()
+	if e.protocol.stack.XFRMEnabled() {
+		espPkt, err := e.protocol.stack.XFRMOutput(ProtocolNumber, r.LocalAddress(), dstAddr, params.Protocol, pkt, int(e.MaxHeaderLength()))
+		if err != nil {
+			return err
+		}
+		if espPkt != nil {
+			defer espPkt.DecRef()
+			pkt = espPkt
+			params.Protocol = header.ESPProtocolNumber
+		}
+	}
 	if err := addIPHeader(r.LocalAddress(), dstAddr, pkt, params, nil /* extensionHeaders */); err != nil {
 		return err
 	}
@@ -1526,6 +1538,17 @@ func (e *endpoint) processIPv6RawPayloadHeader(extHdr *header.IPv6RawPayloadHead
 		e.protocol.parseTransport(pkt, proto)
 	}
 
+	if e.protocol.stack.XFRMEnabled() {
+		espPkt, espProto, ok := e.xfrmInput(proto, pkt)
+		if !ok {
+			return fmt.Errorf("packet dropped by IPsec")
+		}
+		if espPkt != nil {
+			defer espPkt.DecRef()
+			pkt, proto = espPkt, espProto
+		}
+	}
+
 	stats.PacketsDelivered.Increment()
 	if proto == header.ICMPv6ProtocolNumber {
 		e.handleICMP(pkt, hasFragmentHeader, routerAlert)
@@ -1579,6 +1602,50 @@ func (e *endpoint) processIPv6RawPayloadHeader(extHdr *header.IPv6RawPayloadHead
 	}
 }
 
+// xfrmInput decapsulates a locally delivered ESP packet and applies the
+// inbound IPsec policies to the packet. pkt must hold the upper layer header
+// and data of the packet. xfrmInput returns the decapsulated packet and its
+// transport protocol, or nil if pkt is not an ESP packet, and false if the
+// packet must be dropped.
+func (e *endpoint) xfrmInput(proto tcpip.TransportProtocolNumber, pkt *stack.PacketBuffer) (*stack.PacketBuffer, tcpip.TransportProtocolNumber, bool) {
+	s := e.protocol.stack
+	h := header.IPv6(pkt.NetworkHeader().Slice())
+	src, dst := h.SourceAddress(), h.DestinationAddress()
+	var sp stack.XFRMSecPath
+	var espPkt *stack.PacketBuffer
+	if proto == header.ESPProtocolNumber {
+		payload, nextProto, secPath, ok := s.XFRMDecapsulate(src, dst, pkt.Data().AsRange().ToSlice())
+		if !ok {
+			return nil, 0, false
+		}
+		// Replace the ESP packet with its payload. The extension headers
+		// preceding the ESP header were already processed, so only a copy of
+		// the fixed header is kept.
+		hdr := header.IPv6(append([]byte(nil), h[:header.IPv6MinimumSize]...))
+		hdr.SetNextHeader(uint8(nextProto))
+		hdr.SetPayloadLength(uint16(len(payload)))
+		espPkt = stack.NewPacketBuffer(stack.PacketBufferOptions{
+			Payload: buffer.MakeWithData(append(hdr, payload...)),
+		})
+		espPkt.NetworkProtocolNumber = ProtocolNumber
+		espPkt.NICID = pkt.NICID
+		espPkt.PktType = pkt.PktType
+		espPkt.NetworkPacketInfo = pkt.NetworkPacketInfo
+		if _, ok := espPkt.NetworkHeader().Consume(len(hdr)); !ok {
+			panic(fmt.Sprintf("failed to consume %d bytes of network header", len(hdr)))
+		}
+		e.protocol.parseTransport(espPkt, nextProto)
+		pkt, proto, sp = espPkt, nextProto, secPath
+	}
+	if !s.XFRMCheckInput(ProtocolNumber, src, dst, proto, pkt.TransportHeader().Slice(), sp) {
+		if espPkt != nil {
+			espPkt.DecRef()
+		}
+		return nil, 0, false
+	}
+	return espPkt, proto, true
+}
+
 func (e *endpoint) processIPv6RoutingExtHeader(extHdr *header.IPv6RoutingExtHdr, it *header.IPv6PayloadIterator, pkt *stack.PacketBuffer) error {
 	// As per RFC 8200 section 4.4, if a node encounters a routing header with
 	// an unrecognized routing type value, with a non-zero Segments Left
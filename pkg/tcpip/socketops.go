@@ -188,6 +188,14 @@ type SocketOptions struct {
 	// message is passed with incoming packets.
 	receiveTClassEnabled atomicbitops.Uint32
 
+	// receiveFlowInfoEnabled is used to specify if the IPV6_FLOWINFO
+	// ancillary message is passed with incoming packets.
+	receiveFlowInfoEnabled atomicbitops.Uint32
+
+	// sendFlowInfoEnabled is used to specify if the flow information of the
+	// destination address is used for outgoing IPv6 packets.
+	sendFlowInfoEnabled atomicbitops.Uint32
+
 	// receivePacketInfoEnabled is used to specify if more information is
 	// provided with incoming IPv4 packets.
 	receivePacketInfoEnabled atomicbitops.Uint32
@@ -400,6 +408,26 @@ func (so *SocketOptions) SetReceiveTClass(v bool) {
 	storeAtomicBool(&so.receiveTClassEnabled, v)
 }
 
+// GetReceiveFlowInfo gets value for IPV6_FLOWINFO option.
+func (so *SocketOptions) GetReceiveFlowInfo() bool {
+	return so.receiveFlowInfoEnabled.Load() != 0
+}
+
+// SetReceiveFlowInfo sets value for IPV6_FLOWINFO option.
+func (so *SocketOptions) SetReceiveFlowInfo(v bool) {
+	storeAtomicBool(&so.receiveFlowInfoEnabled, v)
+}
+
+// GetSendFlowInfo gets value for IPV6_FLOWINFO_SEND option.
+func (so *SocketOptions) GetSendFlowInfo() bool {
+	return so.sendFlowInfoEnabled.Load() != 0
+}
+
+// SetSendFlowInfo sets value for IPV6_FLOWINFO_SEND option.
+func (so *SocketOptions) SetSendFlowInfo(v bool) {
+	storeAtomicBool(&so.sendFlowInfoEnabled, v)
+}
+
 // GetReceivePacketInfo gets value for IP_PKTINFO option.
 func (so *SocketOptions) GetReceivePacketInfo() bool {
 	return so.receivePacketInfoEnabled.Load() != 0
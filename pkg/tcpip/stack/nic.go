@@ -67,6 +67,10 @@ type nic struct {
 	// promiscuous indicates whether the NIC is promiscuous.
 	promiscuous atomicbitops.Bool
 
+	// mtu is the MTU configured for the NIC. If zero, the MTU of the link
+	// endpoint is used.
+	mtu atomicbitops.Uint32
+
 	// linkResQueue holds packets that are waiting for link resolution to
 	// complete.
 	linkResQueue packetsPendingLinkResolution
@@ -87,6 +91,9 @@ type nic struct {
 	// deliverLinkPackets is off by default because some users already
 	// deliver link packets by explicitly calling nic.DeliverLinkPackets.
 	deliverLinkPackets bool
+
+	// vrf indicates whether the NIC is a VRF. It is immutable.
+	vrf bool
 }
 
 // makeNICStats initializes the NIC statistics and associates them to the global
@@ -185,6 +192,7 @@ func newNIC(stack *Stack, id tcpip.NICID, ep LinkEndpoint, opts NICOptions) *nic
 		duplicateAddressDetectors: make(map[tcpip.NetworkProtocolNumber]DuplicateAddressDetector),
 		qDisc:                     qDisc,
 		deliverLinkPackets:        opts.DeliverLinkPackets,
+		vrf:                       opts.VRF,
 	}
 	nic.linkResQueue.init(nic)
 
@@ -340,6 +348,23 @@ func (n *nic) Promiscuous() bool {
 	return n.promiscuous.Load()
 }
 
+// setMTU sets the MTU configured for the NIC.
+func (n *nic) setMTU(mtu uint32) {
+	n.mtu.Store(mtu)
+}
+
+// MTU implements NetworkInterface.
+//
+// The MTU of the link endpoint is returned unless a smaller MTU was
+// configured for the NIC.
+func (n *nic) MTU() uint32 {
+	linkMTU := n.NetworkLinkEndpoint.MTU()
+	if mtu := n.mtu.Load(); mtu != 0 && mtu < linkMTU {
+		return mtu
+	}
+	return linkMTU
+}
+
 // IsLoopback implements NetworkInterface.
 func (n *nic) IsLoopback() bool {
 	return n.NetworkLinkEndpoint.Capabilities()&CapabilityLoopback != 0
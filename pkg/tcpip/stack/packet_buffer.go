@@ -20,6 +20,7 @@ import (
 	"gvisor.dev/gvisor/pkg/buffer"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/checksum"
 	"gvisor.dev/gvisor/pkg/tcpip/header"
 )
 
@@ -712,6 +713,15 @@ func (r Range) Capped(max int) Range {
 	}
 }
 
+// Checksum returns a checksum over the data in r.
+func (r Range) Checksum() uint16 {
+	var c checksum.Checksumer
+	r.iterate(func(v *buffer.View) {
+		c.Add(v.AsSlice())
+	})
+	return c.Checksum()
+}
+
 // ToSlice returns a caller-owned copy of data in r.
 func (r Range) ToSlice() []byte {
 	if r.length == 0 {
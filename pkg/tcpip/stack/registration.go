@@ -320,6 +320,10 @@ type NetworkHeaderParams struct {
 	// TOS refers to TypeOfService or TrafficClass field of the IP-header.
 	TOS uint8
 
+	// FlowLabel refers to the FlowLabel field of the IPv6 header. It is
+	// ignored by IPv4.
+	FlowLabel uint32
+
 	// DF indicates whether the DF bit should be set.
 	DF bool
 }
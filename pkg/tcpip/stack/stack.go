@@ -125,6 +125,14 @@ type Stack struct {
 	// TODO(gvisor.dev/issue/4595): S/R this field.
 	tables *IPTables `state:"nosave"`
 
+	// xfrm holds the IPsec security associations and policies.
+	// TODO(gvisor.dev/issue/4595): S/R this field.
+	xfrm xfrmDatabase `state:"nosave"`
+
+	// l3mdev tracks the NICs enslaved to VRFs.
+	// TODO(gvisor.dev/issue/4595): S/R this field.
+	l3mdev l3mdevTable `state:"nosave"`
+
 	// restoredEndpoints is a list of endpoints that need to be restored if the
 	// stack is being restored.
 	restoredEndpoints []RestoredEndpoint
@@ -841,6 +849,11 @@ type NICOptions struct {
 	// DeliverLinkPackets specifies whether the NIC is responsible for
 	// delivering raw packets to packet sockets.
 	DeliverLinkPackets bool
+
+	// VRF specifies whether the NIC is a VRF that other NICs can be enslaved
+	// to with SetNICMaster. Traffic scoped to a VRF only uses the routes
+	// through its members.
+	VRF bool
 }
 
 // CreateNICWithOptions creates a NIC with the provided id, LinkEndpoint, and
@@ -963,6 +976,7 @@ func (s *Stack) removeNICLocked(id tcpip.NICID) tcpip.Error {
 		return &tcpip.ErrUnknownNICID{}
 	}
 	delete(s.nics, id)
+	s.l3mdev.removeNIC(id)
 
 	// Remove routes in-place. n tracks the number of routes written.
 	s.routeMu.Lock()
@@ -1014,6 +1028,12 @@ type NICInfo struct {
 	// MulticastForwarding holds the forwarding status for each network endpoint
 	// that supports multicast forwarding.
 	MulticastForwarding map[tcpip.NetworkProtocolNumber]bool
+
+	// VRF is true if the NIC is a VRF.
+	VRF bool
+
+	// Master is the ID of the VRF the NIC is enslaved to, or 0.
+	Master tcpip.NICID
 }
 
 // HasNIC returns true if the NICID is defined in the stack.
@@ -1047,7 +1067,7 @@ func (s *Stack) NICInfo() map[tcpip.NICID]NICInfo {
 	nics := make(map[tcpip.NICID]NICInfo)
 	for id, nic := range s.nics {
 		flags := NICStateFlags{
-			Up:          true, // Netstack interfaces are always up.
+			Up:          nic.Enabled(),
 			Running:     nic.Enabled(),
 			Promiscuous: nic.Promiscuous(),
 			Loopback:    nic.IsLoopback(),
@@ -1063,13 +1083,15 @@ func (s *Stack) NICInfo() map[tcpip.NICID]NICInfo {
 			LinkAddress:         nic.NetworkLinkEndpoint.LinkAddress(),
 			ProtocolAddresses:   nic.primaryAddresses(),
 			Flags:               flags,
-			MTU:                 nic.NetworkLinkEndpoint.MTU(),
+			MTU:                 nic.MTU(),
 			Stats:               nic.stats.local,
 			NetworkStats:        netStats,
 			Context:             nic.context,
 			ARPHardwareType:     nic.NetworkLinkEndpoint.ARPHardwareType(),
 			Forwarding:          make(map[tcpip.NetworkProtocolNumber]bool),
 			MulticastForwarding: make(map[tcpip.NetworkProtocolNumber]bool),
+			VRF:                 nic.vrf,
+			Master:              s.l3mdev.master(id),
 		}
 
 		for proto := range s.networkProtocols {
@@ -1259,8 +1281,12 @@ func (s *Stack) findLocalRouteRLocked(localAddressNICID tcpip.NICID, localAddr,
 		localAddr = remoteAddr
 	}
 
-	if localAddressNICID == 0 {
-		for _, localAddressNIC := range s.nics {
+	if localAddressNICID == 0 || s.isVRFRLocked(localAddressNICID) {
+		// Only consider the NICs in the same VRF.
+		for id, localAddressNIC := range s.nics {
+			if id != localAddressNICID && s.l3mdev.master(id) != localAddressNICID {
+				continue
+			}
 			if r := s.findLocalRouteFromNICRLocked(localAddressNIC, localAddr, remoteAddr, netProto); r != nil {
 				return r
 			}
@@ -1276,6 +1302,14 @@ func (s *Stack) findLocalRouteRLocked(localAddressNICID tcpip.NICID, localAddr,
 	return nil
 }
 
+// isVRFRLocked returns true if the NIC is a VRF.
+//
+// +checklocksread:s.mu
+func (s *Stack) isVRFRLocked(id tcpip.NICID) bool {
+	nic, ok := s.nics[id]
+	return ok && nic.vrf
+}
+
 // HandleLocal returns true if non-loopback interfaces are allowed to loop packets.
 func (s *Stack) HandleLocal() bool {
 	return s.handleLocal
@@ -1376,6 +1410,7 @@ func (s *Stack) FindRoute(id tcpip.NICID, localAddr, remoteAddr tcpip.Address, n
 	}
 
 	onlyGlobalAddresses := !header.IsV6LinkLocalUnicastAddress(localAddr) && !isLinkLocal
+	idIsVRF := s.isVRFRLocked(id)
 
 	// Find a route to the remote with the route table.
 	var chosenRoute tcpip.Route
@@ -1393,7 +1428,14 @@ func (s *Stack) FindRoute(id tcpip.NICID, localAddr, remoteAddr tcpip.Address, n
 				continue
 			}
 
-			if id == 0 || id == route.NIC {
+			// Routes through a VRF's members are only usable by traffic scoped to
+			// the VRF or the member itself.
+			usable, direct := s.l3mdev.scope(id, idIsVRF, route.NIC)
+			if !usable {
+				continue
+			}
+
+			if direct {
 				if addressEndpoint := s.getAddressEP(nic, localAddr, remoteAddr, route.SourceHint, netProto); addressEndpoint != nil {
 					var gateway tcpip.Address
 					if needRoute {
@@ -1528,6 +1570,14 @@ func (s *Stack) CheckLocalAddress(nicID tcpip.NICID, protocol tcpip.NetworkProto
 		if nic.CheckLocalAddress(protocol, addr) {
 			return nic.id
 		}
+		if nic.vrf {
+			// The addresses of a VRF's members are local to the VRF.
+			for id, member := range s.nics {
+				if s.l3mdev.master(id) == nicID && member.CheckLocalAddress(protocol, addr) {
+					return nic.id
+				}
+			}
+		}
 		return 0
 	}
 
@@ -1556,6 +1606,26 @@ func (s *Stack) SetPromiscuousMode(nicID tcpip.NICID, enable bool) tcpip.Error {
 	return nil
 }
 
+// SetNICMTU sets the MTU of the given NIC. The MTU may not be larger than
+// the MTU of the NIC's link endpoint. An MTU of zero restores the MTU of the
+// link endpoint.
+func (s *Stack) SetNICMTU(nicID tcpip.NICID, mtu uint32) tcpip.Error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	nic, ok := s.nics[nicID]
+	if !ok {
+		return &tcpip.ErrUnknownNICID{}
+	}
+
+	if mtu > nic.NetworkLinkEndpoint.MTU() {
+		return &tcpip.ErrInvalidOptionValue{}
+	}
+	nic.setMTU(mtu)
+
+	return nil
+}
+
 // SetSpoofing enables or disables address spoofing in the given NIC, allowing
 // endpoints to bind to any address in the NIC.
 func (s *Stack) SetSpoofing(nicID tcpip.NICID, enable bool) tcpip.Error {
@@ -1712,6 +1712,56 @@ func TestPromiscuousMode(t *testing.T) {
 	testFailingRecv(t, fakeNet, localAddrByte, ep, buf)
 }
 
+func TestSetNICMTU(t *testing.T) {
+	const nicID = 1
+	s := stack.New(stack.Options{
+		NetworkProtocols: []stack.NetworkProtocolFactory{fakeNetFactory},
+	})
+
+	ep := channel.New(10, defaultMTU, "")
+	if err := s.CreateNIC(nicID, ep); err != nil {
+		t.Fatal("CreateNIC failed:", err)
+	}
+
+	nicMTU := func() uint32 {
+		t.Helper()
+		info, ok := s.NICInfo()[nicID]
+		if !ok {
+			t.Fatalf("NICInfo() missing NIC %d", nicID)
+		}
+		return info.MTU
+	}
+
+	const smallMTU = defaultMTU / 2
+	if err := s.SetNICMTU(nicID, smallMTU); err != nil {
+		t.Fatalf("SetNICMTU(%d, %d): %s", nicID, smallMTU, err)
+	}
+	if got := nicMTU(); got != smallMTU {
+		t.Errorf("got NIC MTU = %d, want = %d", got, smallMTU)
+	}
+
+	// The MTU may not exceed the link endpoint's MTU.
+	err := s.SetNICMTU(nicID, defaultMTU+1)
+	if _, ok := err.(*tcpip.ErrInvalidOptionValue); !ok {
+		t.Errorf("got SetNICMTU(%d, %d) = %v, want = %s", nicID, defaultMTU+1, err, &tcpip.ErrInvalidOptionValue{})
+	}
+	if got := nicMTU(); got != smallMTU {
+		t.Errorf("got NIC MTU = %d, want = %d", got, smallMTU)
+	}
+
+	if err := s.SetNICMTU(nicID, 0); err != nil {
+		t.Fatalf("SetNICMTU(%d, 0): %s", nicID, err)
+	}
+	if got := nicMTU(); got != defaultMTU {
+		t.Errorf("got NIC MTU = %d, want = %d", got, defaultMTU)
+	}
+
+	err = s.SetNICMTU(nicID+1, smallMTU)
+	if _, ok := err.(*tcpip.ErrUnknownNICID); !ok {
+		t.Errorf("got SetNICMTU(%d, %d) = %v, want = %s", nicID+1, smallMTU, err, &tcpip.ErrUnknownNICID{})
+	}
+}
+
 // TestExternalSendWithHandleLocal tests that the stack creates a non-local
 // route when spoofing or promiscuous mode are enabled.
 //
@@ -368,9 +368,10 @@ type RcvBufAutoTuneParams struct {
 	// RcvBufSize is the auto tuned receive buffer size.
 	RcvBufSize int
 
-	// RTT is the smoothed RTT as measured by observing the time between
-	// when a byte is first acknowledged and the receipt of data that is at
-	// least one window beyond the sequence number that was acknowledged.
+	// RTT is the receiver's RTT estimate, as measured by observing the time
+	// between when a byte is first acknowledged and the receipt of data that
+	// is at least one window beyond the sequence number that was
+	// acknowledged, and from timestamps if they are in use.
 	RTT time.Duration
 
 	// RTTVar is the "round-trip time variation" as defined in section 2 of
@@ -385,6 +386,10 @@ type RcvBufAutoTuneParams struct {
 	// measurement period began.
 	RTTMeasureTime tcpip.MonotonicTime
 
+	// RTTLastTSEcr is the TSEcr of the last segment from which RTT was
+	// sampled using timestamps.
+	RTTLastTSEcr uint32
+
 	// Disabled is true if an explicit receive buffer is set for the
 	// endpoint.
 	Disabled bool
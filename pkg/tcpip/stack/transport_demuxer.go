@@ -156,18 +156,38 @@ func (epsByNIC *endpointsByNIC) transportEndpoints() []TransportEndpoint {
 	return eps
 }
 
+// lookupLocked returns the endpoints that receive packets arriving on the NIC:
+// those bound to the NIC, else those bound to its VRF, else those that aren't
+// bound to any device. Unbound endpoints only receive packets arriving on VRF
+// members if l3mdev accept is enabled.
+//
+// +checklocksread:epsByNIC.mu
+func (epsByNIC *endpointsByNIC) lookupLocked(l3mdev *l3mdevTable, nicID tcpip.NICID) (*multiPortEndpoint, bool) {
+	if mpep, ok := epsByNIC.endpoints[nicID]; ok {
+		return mpep, true
+	}
+	if master := l3mdev.master(nicID); master != 0 {
+		if mpep, ok := epsByNIC.endpoints[master]; ok {
+			return mpep, true
+		}
+		if !l3mdev.accept.Load() {
+			return nil, false
+		}
+	}
+	mpep, ok := epsByNIC.endpoints[0]
+	return mpep, ok
+}
+
 // handlePacket is called by the stack when new packets arrive to this transport
 // endpoint. It returns false if the packet could not be matched to any
 // transport endpoint, true otherwise.
-func (epsByNIC *endpointsByNIC) handlePacket(id TransportEndpointID, pkt *PacketBuffer) bool {
+func (epsByNIC *endpointsByNIC) handlePacket(l3mdev *l3mdevTable, id TransportEndpointID, pkt *PacketBuffer) bool {
 	epsByNIC.mu.RLock()
 
-	mpep, ok := epsByNIC.endpoints[pkt.NICID]
+	mpep, ok := epsByNIC.lookupLocked(l3mdev, pkt.NICID)
 	if !ok {
-		if mpep, ok = epsByNIC.endpoints[0]; !ok {
-			epsByNIC.mu.RUnlock() // Don't use defer for performance reasons.
-			return false
-		}
+		epsByNIC.mu.RUnlock() // Don't use defer for performance reasons.
+		return false
 	}
 
 	// If this is a broadcast or multicast datagram, deliver the datagram to all
@@ -194,10 +214,7 @@ func (epsByNIC *endpointsByNIC) handlePacket(id TransportEndpointID, pkt *Packet
 func (epsByNIC *endpointsByNIC) handleError(n *nic, id TransportEndpointID, transErr TransportError, pkt *PacketBuffer) {
 	epsByNIC.mu.RLock()
 
-	mpep, ok := epsByNIC.endpoints[n.ID()]
-	if !ok {
-		mpep, ok = epsByNIC.endpoints[0]
-	}
+	mpep, ok := epsByNIC.lookupLocked(&n.stack.l3mdev, n.ID())
 	if !ok {
 		epsByNIC.mu.RUnlock()
 		return
@@ -573,10 +590,10 @@ func (d *transportDemuxer) deliverPacket(protocol tcpip.TransportProtocolNumber,
 		// copy except for the final one.
 		for _, ep := range destEPs[:len(destEPs)-1] {
 			clone := pkt.Clone()
-			ep.handlePacket(id, clone)
+			ep.handlePacket(&d.stack.l3mdev, id, clone)
 			clone.DecRef()
 		}
-		destEPs[len(destEPs)-1].handlePacket(id, pkt)
+		destEPs[len(destEPs)-1].handlePacket(&d.stack.l3mdev, id, pkt)
 		return true
 	}
 
@@ -600,7 +617,7 @@ func (d *transportDemuxer) deliverPacket(protocol tcpip.TransportProtocolNumber,
 		}
 		return false
 	}
-	return ep.handlePacket(id, pkt)
+	return ep.handlePacket(&d.stack.l3mdev, id, pkt)
 }
 
 // deliverRawPacket attempts to deliver the given packet and returns whether it
@@ -673,12 +690,10 @@ func (d *transportDemuxer) findTransportEndpoint(netProto tcpip.NetworkProtocolN
 	epsByNIC.mu.RLock()
 	eps.mu.RUnlock()
 
-	mpep, ok := epsByNIC.endpoints[nicID]
+	mpep, ok := epsByNIC.lookupLocked(&d.stack.l3mdev, nicID)
 	if !ok {
-		if mpep, ok = epsByNIC.endpoints[0]; !ok {
-			epsByNIC.mu.RUnlock() // Don't use defer for performance reasons.
-			return nil
-		}
+		epsByNIC.mu.RUnlock() // Don't use defer for performance reasons.
+		return nil
 	}
 
 	ep := mpep.selectEndpoint(id, epsByNIC.seed)
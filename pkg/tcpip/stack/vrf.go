@@ -0,0 +1,143 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip"
+)
+
+// l3mdevTable tracks which NICs are enslaved to a VRF (an L3 master device).
+//
+// A VRF partitions the stack's routes and sockets: routes through a member
+// NIC are only used by traffic scoped to the member or to its VRF, and
+// sockets bound to a VRF receive the packets arriving on its members.
+type l3mdevTable struct {
+	// enabled is true if any NIC is enslaved to a VRF. It lets the packet
+	// paths skip the table entirely in the common case.
+	enabled atomicbitops.Bool
+
+	// accept is true if sockets that aren't bound to a device receive packets
+	// arriving on VRF members, like Linux's tcp_l3mdev_accept and
+	// udp_l3mdev_accept.
+	accept atomicbitops.Bool
+
+	mu sync.RWMutex
+
+	// masters maps the ID of each enslaved NIC to the ID of its VRF.
+	//
+	// +checklocks:mu
+	masters map[tcpip.NICID]tcpip.NICID
+}
+
+// master returns the ID of the VRF the NIC is enslaved to, or 0 if it isn't
+// enslaved to any.
+func (t *l3mdevTable) master(id tcpip.NICID) tcpip.NICID {
+	if !t.enabled.Load() {
+		return 0
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.masters[id]
+}
+
+// setMaster enslaves the NIC to master, or releases it if master is 0.
+func (t *l3mdevTable) setMaster(id, master tcpip.NICID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if master == 0 {
+		delete(t.masters, id)
+	} else {
+		if t.masters == nil {
+			t.masters = make(map[tcpip.NICID]tcpip.NICID)
+		}
+		t.masters[id] = master
+	}
+	t.enabled.Store(len(t.masters) != 0)
+}
+
+// removeNIC forgets the NIC along with its members if it is a VRF.
+func (t *l3mdevTable) removeNIC(id tcpip.NICID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for member, master := range t.masters {
+		if member == id || master == id {
+			delete(t.masters, member)
+		}
+	}
+	t.enabled.Store(len(t.masters) != 0)
+}
+
+// scope returns whether traffic scoped to the NIC id may use a route leaving
+// through routeNIC, and whether it may do so directly rather than through
+// forwarding. idIsVRF is true if id is a VRF.
+func (t *l3mdevTable) scope(id tcpip.NICID, idIsVRF bool, routeNIC tcpip.NICID) (usable, direct bool) {
+	if id == routeNIC {
+		return true, true
+	}
+	if master := t.master(routeNIC); master != 0 {
+		return id == master, id == master
+	}
+	if idIsVRF {
+		return false, false
+	}
+	return true, id == 0
+}
+
+// SetNICMaster enslaves the NIC to the VRF master, or releases it from its VRF
+// if master is 0. The master must have been created with NICOptions.VRF set.
+func (s *Stack) SetNICMaster(id, master tcpip.NICID) tcpip.Error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	nic, ok := s.nics[id]
+	if !ok {
+		return &tcpip.ErrUnknownNICID{}
+	}
+	if nic.vrf {
+		// VRFs can't be nested.
+		return &tcpip.ErrNotSupported{}
+	}
+	if master != 0 {
+		m, ok := s.nics[master]
+		if !ok {
+			return &tcpip.ErrUnknownNICID{}
+		}
+		if !m.vrf {
+			return &tcpip.ErrNotSupported{}
+		}
+	}
+	s.l3mdev.setMaster(id, master)
+	return nil
+}
+
+// NICMaster returns the ID of the VRF the NIC is enslaved to, or 0 if it isn't
+// enslaved to any.
+func (s *Stack) NICMaster(id tcpip.NICID) tcpip.NICID {
+	return s.l3mdev.master(id)
+}
+
+// SetL3MDevAccept sets whether sockets that aren't bound to a device receive
+// packets arriving on NICs enslaved to a VRF.
+func (s *Stack) SetL3MDevAccept(accept bool) {
+	s.l3mdev.accept.Store(accept)
+}
+
+// L3MDevAccept returns whether sockets that aren't bound to a device receive
+// packets arriving on NICs enslaved to a VRF.
+func (s *Stack) L3MDevAccept() bool {
+	return s.l3mdev.accept.Load()
+}
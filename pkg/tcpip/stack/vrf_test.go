@@ -0,0 +1,206 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/channel"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	vrfMgmtNICID = 1
+	vrfDataNICID = 2
+	vrfNICID     = 3
+)
+
+func TestVRFRoutes(t *testing.T) {
+	s := stack.New(stack.Options{
+		NetworkProtocols: []stack.NetworkProtocolFactory{ipv4.NewProtocol},
+	})
+	defer s.Close()
+
+	mgmtAddr := tcpip.AddrFrom4([4]byte{10, 0, 0, 1})
+	dataAddr := tcpip.AddrFrom4([4]byte{192, 168, 0, 1})
+	for _, nic := range []struct {
+		id   tcpip.NICID
+		addr tcpip.Address
+	}{
+		{vrfMgmtNICID, mgmtAddr},
+		{vrfDataNICID, dataAddr},
+	} {
+		if err := s.CreateNIC(nic.id, channel.New(1, defaultMTU, "")); err != nil {
+			t.Fatalf("CreateNIC(%d, _): %s", nic.id, err)
+		}
+		protocolAddr := tcpip.ProtocolAddress{
+			Protocol:          ipv4.ProtocolNumber,
+			AddressWithPrefix: tcpip.AddressWithPrefix{Address: nic.addr, PrefixLen: 24},
+		}
+		if err := s.AddProtocolAddress(nic.id, protocolAddr, stack.AddressProperties{}); err != nil {
+			t.Fatalf("AddProtocolAddress(%d, %+v, {}): %s", nic.id, protocolAddr, err)
+		}
+	}
+	if err := s.CreateNICWithOptions(vrfNICID, channel.New(1, defaultMTU, ""), stack.NICOptions{VRF: true}); err != nil {
+		t.Fatalf("CreateNICWithOptions(%d, _, {VRF: true}): %s", vrfNICID, err)
+	}
+	if err := s.SetNICMaster(vrfDataNICID, vrfNICID); err != nil {
+		t.Fatalf("SetNICMaster(%d, %d): %s", vrfDataNICID, vrfNICID, err)
+	}
+	s.SetRouteTable([]tcpip.Route{
+		{Destination: header.IPv4EmptySubnet, Gateway: tcpip.AddrFrom4([4]byte{192, 168, 0, 254}), NIC: vrfDataNICID},
+		{Destination: header.IPv4EmptySubnet, Gateway: tcpip.AddrFrom4([4]byte{10, 0, 0, 254}), NIC: vrfMgmtNICID},
+	})
+
+	remoteAddr := tcpip.AddrFrom4([4]byte{8, 8, 8, 8})
+	for _, tc := range []struct {
+		name      string
+		id        tcpip.NICID
+		wantNICID tcpip.NICID
+		wantLocal tcpip.Address
+	}{
+		{"Unbound", 0, vrfMgmtNICID, mgmtAddr},
+		{"BoundToVRF", vrfNICID, vrfDataNICID, dataAddr},
+		{"BoundToMember", vrfDataNICID, vrfDataNICID, dataAddr},
+		{"BoundToManagement", vrfMgmtNICID, vrfMgmtNICID, mgmtAddr},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := s.FindRoute(tc.id, tcpip.Address{}, remoteAddr, ipv4.ProtocolNumber, false /* multicastLoop */)
+			if err != nil {
+				t.Fatalf("FindRoute(%d, '', %s, %d, false): %s", tc.id, remoteAddr, ipv4.ProtocolNumber, err)
+			}
+			defer r.Release()
+			if r.NICID() != tc.wantNICID || r.LocalAddress() != tc.wantLocal {
+				t.Errorf("got route through NIC %d from %s, want through NIC %d from %s", r.NICID(), r.LocalAddress(), tc.wantNICID, tc.wantLocal)
+			}
+		})
+	}
+
+	// Once released, the member's routes belong to the main table again and
+	// the VRF has no routes left.
+	if err := s.SetNICMaster(vrfDataNICID, 0); err != nil {
+		t.Fatalf("SetNICMaster(%d, 0): %s", vrfDataNICID, err)
+	}
+	if _, err := s.FindRoute(vrfNICID, tcpip.Address{}, remoteAddr, ipv4.ProtocolNumber, false /* multicastLoop */); err == nil {
+		t.Errorf("FindRoute(%d, ...) succeeded through an empty VRF", vrfNICID)
+	}
+	r, err := s.FindRoute(0, tcpip.Address{}, remoteAddr, ipv4.ProtocolNumber, false /* multicastLoop */)
+	if err != nil {
+		t.Fatalf("FindRoute(0, ...): %s", err)
+	}
+	defer r.Release()
+	if r.NICID() != vrfDataNICID {
+		t.Errorf("got route through NIC %d, want through NIC %d", r.NICID(), vrfDataNICID)
+	}
+}
+
+func TestSetNICMaster(t *testing.T) {
+	s := stack.New(stack.Options{})
+	defer s.Close()
+
+	for _, id := range []tcpip.NICID{vrfMgmtNICID, vrfDataNICID} {
+		if err := s.CreateNIC(id, channel.New(1, defaultMTU, "")); err != nil {
+			t.Fatalf("CreateNIC(%d, _): %s", id, err)
+		}
+	}
+	if err := s.CreateNICWithOptions(vrfNICID, channel.New(1, defaultMTU, ""), stack.NICOptions{VRF: true}); err != nil {
+		t.Fatalf("CreateNICWithOptions(%d, _, {VRF: true}): %s", vrfNICID, err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		id, master tcpip.NICID
+		want       tcpip.Error
+	}{
+		{"UnknownNIC", 10, vrfNICID, &tcpip.ErrUnknownNICID{}},
+		{"UnknownMaster", vrfDataNICID, 10, &tcpip.ErrUnknownNICID{}},
+		{"MasterNotVRF", vrfDataNICID, vrfMgmtNICID, &tcpip.ErrNotSupported{}},
+		{"NestedVRF", vrfNICID, vrfNICID, &tcpip.ErrNotSupported{}},
+		{"Valid", vrfDataNICID, vrfNICID, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, s.SetNICMaster(tc.id, tc.master)); diff != "" {
+				t.Errorf("SetNICMaster(%d, %d) mismatch (-want +got):\n%s", tc.id, tc.master, diff)
+			}
+		})
+	}
+
+	info := s.NICInfo()
+	if !info[vrfNICID].VRF || info[vrfDataNICID].Master != vrfNICID {
+		t.Errorf("got NICInfo()[%d].VRF = %t, NICInfo()[%d].Master = %d, want = true, %d", vrfNICID, info[vrfNICID].VRF, vrfDataNICID, info[vrfDataNICID].Master, vrfNICID)
+	}
+
+	if err := s.RemoveNIC(vrfNICID); err != nil {
+		t.Fatalf("RemoveNIC(%d): %s", vrfNICID, err)
+	}
+	if got := s.NICMaster(vrfDataNICID); got != 0 {
+		t.Errorf("got NICMaster(%d) = %d after removing the VRF, want = 0", vrfDataNICID, got)
+	}
+}
+
+func TestVRFDelivery(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		bindToDevice tcpip.NICID
+		accept       bool
+		device       tcpip.NICID
+		want         bool
+	}{
+		{"UnboundOnManagement", 0, false, vrfMgmtNICID, true},
+		{"UnboundOnMember", 0, false, vrfDataNICID, false},
+		{"UnboundOnMemberWithAccept", 0, true, vrfDataNICID, true},
+		{"BoundToVRFOnMember", vrfNICID, false, vrfDataNICID, true},
+		{"BoundToVRFOnManagement", vrfNICID, false, vrfMgmtNICID, false},
+		{"BoundToMemberOnMember", vrfDataNICID, false, vrfDataNICID, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newDualTestContextMultiNIC(t, defaultMTU, []tcpip.NICID{vrfMgmtNICID, vrfDataNICID})
+			defer c.s.Close()
+			if err := c.s.CreateNICWithOptions(vrfNICID, channel.New(1, defaultMTU, ""), stack.NICOptions{VRF: true}); err != nil {
+				t.Fatalf("CreateNICWithOptions(%d, _, {VRF: true}): %s", vrfNICID, err)
+			}
+			if err := c.s.SetNICMaster(vrfDataNICID, vrfNICID); err != nil {
+				t.Fatalf("SetNICMaster(%d, %d): %s", vrfDataNICID, vrfNICID, err)
+			}
+			c.s.SetL3MDevAccept(tc.accept)
+
+			var wq waiter.Queue
+			ep, err := c.s.NewEndpoint(udp.ProtocolNumber, ipv4.ProtocolNumber, &wq)
+			if err != nil {
+				t.Fatalf("NewEndpoint failed: %s", err)
+			}
+			defer ep.Close()
+			if err := ep.SocketOptions().SetBindToDevice(int32(tc.bindToDevice)); err != nil {
+				t.Fatalf("SetBindToDevice(%d): %s", tc.bindToDevice, err)
+			}
+			if err := ep.Bind(tcpip.FullAddress{Port: testDstPort}); err != nil {
+				t.Fatalf("Bind(_): %s", err)
+			}
+
+			c.sendV4Packet(newPayload(), &headers{srcPort: testSrcPort, dstPort: testDstPort}, tc.device)
+			_, err = ep.Read(ioutil.Discard, tcpip.ReadOptions{})
+			if got := err == nil; got != tc.want {
+				t.Errorf("got delivered = %t (Read(_, _) = %v), want = %t", got, err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,959 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"hash"
+	"io"
+
+	"gvisor.dev/gvisor/pkg/atomicbitops"
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+)
+
+// This file implements a minimal IPsec Security Association Database and
+// Security Policy Database, modelled after Linux's XFRM framework. Only ESP in
+// transport mode is supported, which is enough to protect host-to-host
+// traffic with SAs negotiated by a user space key manager such as strongSwan.
+//
+// Policies are only matched against packets; there are no per-socket
+// policies and no XFRM_MSG_ACQUIRE upcalls, so the key manager must install
+// its SAs before traffic flows and must bypass its own IKE traffic with
+// global (port based) policies.
+
+// XFRMMode is the encapsulation mode of a security association.
+type XFRMMode uint8
+
+const (
+	// XFRMModeTransport protects the payload of the IP packet.
+	XFRMModeTransport XFRMMode = iota
+
+	// XFRMModeTunnel protects a whole inner IP packet. It is not supported.
+	XFRMModeTunnel
+)
+
+// XFRMDirection is the direction of traffic a policy applies to.
+type XFRMDirection uint8
+
+const (
+	// XFRMDirIn applies to packets delivered locally.
+	XFRMDirIn XFRMDirection = iota
+
+	// XFRMDirOut applies to locally generated packets.
+	XFRMDirOut
+
+	// XFRMDirFwd applies to forwarded packets. Such policies are stored but
+	// not enforced.
+	XFRMDirFwd
+)
+
+// XFRMAction is the action a policy takes on matching packets.
+type XFRMAction uint8
+
+const (
+	// XFRMActionAllow lets matching packets through after applying the
+	// policy's templates.
+	XFRMActionAllow XFRMAction = iota
+
+	// XFRMActionBlock drops matching packets.
+	XFRMActionBlock
+)
+
+// XFRMSelector selects the packets a policy or a state applies to. Zero
+// fields match any packet.
+type XFRMSelector struct {
+	// NetProto is the network protocol of matching packets.
+	NetProto tcpip.NetworkProtocolNumber
+
+	// Src and Dst are the source and destination subnets of matching
+	// packets. A zero prefix length matches any address.
+	Src tcpip.AddressWithPrefix
+	Dst tcpip.AddressWithPrefix
+
+	// SrcPort and DstPort are compared with the transport ports of matching
+	// packets after applying SrcPortMask and DstPortMask. For ICMP, the
+	// source port is the message type and the destination port is the code.
+	SrcPort     uint16
+	SrcPortMask uint16
+	DstPort     uint16
+	DstPortMask uint16
+
+	// Protocol is the transport protocol of matching packets.
+	Protocol tcpip.TransportProtocolNumber
+}
+
+func (s *XFRMSelector) match(netProto tcpip.NetworkProtocolNumber, src, dst tcpip.Address, proto tcpip.TransportProtocolNumber, srcPort, dstPort uint16) bool {
+	if s.NetProto != 0 && s.NetProto != netProto {
+		return false
+	}
+	if s.Src.PrefixLen > 0 {
+		if sub := s.Src.Subnet(); !sub.Contains(src) {
+			return false
+		}
+	}
+	if s.Dst.PrefixLen > 0 {
+		if sub := s.Dst.Subnet(); !sub.Contains(dst) {
+			return false
+		}
+	}
+	if s.Protocol != 0 && s.Protocol != proto {
+		return false
+	}
+	return (srcPort^s.SrcPort)&s.SrcPortMask == 0 && (dstPort^s.DstPort)&s.DstPortMask == 0
+}
+
+// xfrmPorts returns the values matched against the selector ports of a packet
+// with the given transport header.
+func xfrmPorts(proto tcpip.TransportProtocolNumber, hdr []byte) (uint16, uint16) {
+	switch proto {
+	case header.TCPProtocolNumber, header.UDPProtocolNumber:
+		if len(hdr) >= 4 {
+			return binary.BigEndian.Uint16(hdr), binary.BigEndian.Uint16(hdr[2:])
+		}
+	case header.ICMPv4ProtocolNumber, header.ICMPv6ProtocolNumber:
+		if len(hdr) >= 2 {
+			return uint16(hdr[0]), uint16(hdr[1])
+		}
+	}
+	return 0, 0
+}
+
+// XFRMAlgorithm is a cryptographic algorithm of a security association.
+type XFRMAlgorithm struct {
+	// Name is the Linux crypto API name of the algorithm, e.g. "cbc(aes)" or
+	// "rfc4106(gcm(aes))".
+	Name string
+
+	// Key is the key of the algorithm. For rfc4106(gcm(aes)), it is followed
+	// by the 4 byte salt.
+	Key []byte
+
+	// ICVBits is the length of the integrity check value of authentication
+	// and AEAD algorithms. Zero selects the default for the algorithm.
+	ICVBits int
+}
+
+// XFRMStateStats holds the counters of a security association.
+type XFRMStateStats struct {
+	// ReplayWindow is the number of packets dropped because they were too old
+	// for the replay window.
+	ReplayWindow uint32
+
+	// Replay is the number of replayed packets dropped.
+	Replay uint32
+
+	// IntegrityFailed is the number of packets dropped because they failed
+	// the integrity check.
+	IntegrityFailed uint32
+
+	// Packets and Bytes count the packets processed by the state and the
+	// size of their payloads.
+	Packets uint64
+	Bytes   uint64
+}
+
+// XFRMState is a security association.
+type XFRMState struct {
+	// Dst, SPI and Protocol identify the state.
+	Dst      tcpip.Address
+	SPI      uint32
+	Protocol tcpip.TransportProtocolNumber
+
+	// Src is the address of the peer that sends packets protected by the
+	// state.
+	Src tcpip.Address
+
+	// NetProto is the network protocol of Src and Dst.
+	NetProto tcpip.NetworkProtocolNumber
+
+	// Mode is the encapsulation mode. Only XFRMModeTransport is supported.
+	Mode XFRMMode
+
+	// ReqID ties the state to the policy templates with the same ReqID.
+	ReqID uint32
+
+	// ReplayWindow is the size of the anti-replay window of inbound states.
+	// It is capped at 64 packets.
+	ReplayWindow uint32
+
+	// Selector restricts the packets the state may protect.
+	Selector XFRMSelector
+
+	// AEAD is the combined mode algorithm of the state. If it is nil,
+	// Encryption and Auth are used instead.
+	AEAD       *XFRMAlgorithm
+	Encryption *XFRMAlgorithm
+	Auth       *XFRMAlgorithm
+
+	// Stats holds the counters of the state. It is ignored when adding a
+	// state.
+	Stats XFRMStateStats
+
+	// Larval is true for states created by AllocXFRMSPI that haven't been
+	// updated with keys yet.
+	Larval bool
+}
+
+// XFRMTemplate describes the security association a policy requires.
+type XFRMTemplate struct {
+	// Dst and Src are the endpoints of the security association. Zero values
+	// use the addresses of the packet.
+	Dst tcpip.Address
+	Src tcpip.Address
+
+	// SPI, if not zero, selects a specific state.
+	SPI uint32
+
+	// Protocol is the IPsec protocol. Only ESP is supported.
+	Protocol tcpip.TransportProtocolNumber
+
+	// Mode is the encapsulation mode. Only XFRMModeTransport is supported.
+	Mode XFRMMode
+
+	// ReqID, if not zero, selects the states with the same ReqID.
+	ReqID uint32
+
+	// Optional templates are applied only if a matching state exists.
+	Optional bool
+}
+
+// XFRMPolicy is a security policy.
+type XFRMPolicy struct {
+	// Selector selects the packets the policy applies to.
+	Selector XFRMSelector
+
+	// Dir is the direction of the traffic the policy applies to.
+	Dir XFRMDirection
+
+	// Action is the action taken on matching packets.
+	Action XFRMAction
+
+	// Priority orders overlapping policies; lower values take precedence.
+	Priority uint32
+
+	// Index identifies the policy. It is assigned by the stack.
+	Index uint32
+
+	// Templates are the transformations applied to matching packets.
+	Templates []XFRMTemplate
+}
+
+// XFRMSecPath records the security association that protected an inbound
+// packet.
+type XFRMSecPath struct {
+	// Transformed is true if the packet was protected.
+	Transformed bool
+
+	// SPI and ReqID identify the security association.
+	SPI   uint32
+	ReqID uint32
+}
+
+// espTransform holds the keyed algorithms of an ESP security association.
+type espTransform struct {
+	aead    cipher.AEAD
+	salt    []byte
+	block   cipher.Block
+	newHash func() hash.Hash
+	authKey []byte
+
+	ivLen     int
+	icvLen    int
+	blockSize int
+}
+
+// espAuthAlgorithms maps the supported authentication algorithms to their
+// hash function and the default ICV length in bits, as used by Linux.
+var espAuthAlgorithms = map[string]struct {
+	newHash func() hash.Hash
+	icvBits int
+}{
+	"hmac(sha1)":   {sha1.New, 96},
+	"hmac(sha256)": {sha256.New, 96},
+	"hmac(sha384)": {sha512.New384, 192},
+	"hmac(sha512)": {sha512.New, 256},
+	"digest_null":  {nil, 0},
+}
+
+func newESPTransform(st *XFRMState) (*espTransform, tcpip.Error) {
+	t := &espTransform{blockSize: 4}
+	if st.AEAD != nil {
+		if st.Encryption != nil || st.Auth != nil || st.AEAD.Name != "rfc4106(gcm(aes))" {
+			return nil, &tcpip.ErrInvalidOptionValue{}
+		}
+		keyLen := len(st.AEAD.Key) - 4
+		if keyLen != 16 && keyLen != 24 && keyLen != 32 {
+			return nil, &tcpip.ErrInvalidOptionValue{}
+		}
+		icvBits := st.AEAD.ICVBits
+		if icvBits == 0 {
+			icvBits = 128
+		}
+		if icvBits != 96 && icvBits != 128 {
+			return nil, &tcpip.ErrInvalidOptionValue{}
+		}
+		block, err := aes.NewCipher(st.AEAD.Key[:keyLen])
+		if err != nil {
+			return nil, &tcpip.ErrInvalidOptionValue{}
+		}
+		if t.aead, err = cipher.NewGCMWithTagSize(block, icvBits/8); err != nil {
+			return nil, &tcpip.ErrInvalidOptionValue{}
+		}
+		t.salt = append([]byte(nil), st.AEAD.Key[keyLen:]...)
+		t.ivLen = 8
+		t.icvLen = icvBits / 8
+		return t, nil
+	}
+
+	if st.Encryption == nil {
+		return nil, &tcpip.ErrInvalidOptionValue{}
+	}
+	switch st.Encryption.Name {
+	case "cbc(aes)":
+		block, err := aes.NewCipher(st.Encryption.Key)
+		if err != nil {
+			return nil, &tcpip.ErrInvalidOptionValue{}
+		}
+		t.block = block
+		t.ivLen = aes.BlockSize
+		t.blockSize = aes.BlockSize
+	case "ecb(cipher_null)", "cipher_null":
+		if len(st.Encryption.Key) != 0 {
+			return nil, &tcpip.ErrInvalidOptionValue{}
+		}
+	default:
+		return nil, &tcpip.ErrInvalidOptionValue{}
+	}
+
+	if st.Auth == nil {
+		// Encryption without integrity protection is insecure and Linux only
+		// allows it for testing; require an explicit digest_null instead.
+		return nil, &tcpip.ErrInvalidOptionValue{}
+	}
+	alg, ok := espAuthAlgorithms[st.Auth.Name]
+	if !ok {
+		return nil, &tcpip.ErrInvalidOptionValue{}
+	}
+	if alg.newHash == nil {
+		return t, nil
+	}
+	icvBits := st.Auth.ICVBits
+	if icvBits == 0 {
+		icvBits = alg.icvBits
+	}
+	if icvBits < 96 || icvBits%8 != 0 || icvBits/8 > alg.newHash().Size() {
+		return nil, &tcpip.ErrInvalidOptionValue{}
+	}
+	t.newHash = alg.newHash
+	t.authKey = append([]byte(nil), st.Auth.Key...)
+	t.icvLen = icvBits / 8
+	return t, nil
+}
+
+func (t *espTransform) icv(b []byte) []byte {
+	mac := hmac.New(t.newHash, t.authKey)
+	mac.Write(b)
+	return mac.Sum(nil)[:t.icvLen]
+}
+
+// seal returns an ESP packet carrying payload, which is a transport header
+// and its data.
+func (t *espTransform) seal(spi, seq uint32, nextHdr tcpip.TransportProtocolNumber, payload []byte, rand io.Reader) ([]byte, tcpip.Error) {
+	padLen := t.blockSize - (len(payload)+header.ESPTrailerMinimumSize)%t.blockSize
+	if padLen == t.blockSize {
+		padLen = 0
+	}
+	ptLen := len(payload) + padLen + header.ESPTrailerMinimumSize
+	out := make([]byte, header.ESPHeaderSize+t.ivLen+ptLen+t.icvLen)
+	header.ESP(out).Encode(spi, seq)
+	iv := out[header.ESPHeaderSize:][:t.ivLen]
+	if _, err := io.ReadFull(rand, iv); err != nil {
+		return nil, &tcpip.ErrNoBufferSpace{}
+	}
+
+	pt := out[header.ESPHeaderSize+t.ivLen:][:ptLen]
+	copy(pt, payload)
+	// RFC 4303 section 2.4: the padding bytes are initialized with a series
+	// of one-byte integer values.
+	for i := 0; i < padLen; i++ {
+		pt[len(payload)+i] = byte(i + 1)
+	}
+	pt[ptLen-2] = byte(padLen)
+	pt[ptLen-1] = byte(nextHdr)
+
+	switch {
+	case t.aead != nil:
+		// RFC 4106: the nonce is the salt followed by the IV, and the
+		// additional authenticated data is the SPI and the sequence number.
+		nonce := append(append(make([]byte, 0, 12), t.salt...), iv...)
+		t.aead.Seal(pt[:0], nonce, pt, out[:header.ESPHeaderSize])
+		return out, nil
+	case t.block != nil:
+		cipher.NewCBCEncrypter(t.block, iv).CryptBlocks(pt, pt)
+	}
+	if t.icvLen > 0 {
+		n := len(out) - t.icvLen
+		copy(out[n:], t.icv(out[:n]))
+	}
+	return out, nil
+}
+
+// open verifies and decrypts the ESP packet b. It returns the payload and the
+// protocol of its transport header, and whether the integrity check passed.
+func (t *espTransform) open(b []byte) ([]byte, tcpip.TransportProtocolNumber, bool) {
+	if len(b) < header.ESPHeaderSize+t.ivLen+header.ESPTrailerMinimumSize+t.icvLen {
+		return nil, 0, false
+	}
+	iv := b[header.ESPHeaderSize:][:t.ivLen]
+	var pt []byte
+	switch {
+	case t.aead != nil:
+		nonce := append(append(make([]byte, 0, 12), t.salt...), iv...)
+		var err error
+		if pt, err = t.aead.Open(nil, nonce, b[header.ESPHeaderSize+t.ivLen:], b[:header.ESPHeaderSize]); err != nil {
+			return nil, 0, false
+		}
+	default:
+		n := len(b) - t.icvLen
+		if t.icvLen > 0 && !hmac.Equal(t.icv(b[:n]), b[n:]) {
+			return nil, 0, false
+		}
+		pt = append([]byte(nil), b[header.ESPHeaderSize+t.ivLen:n]...)
+		if t.block != nil {
+			if len(pt)%t.blockSize != 0 {
+				return nil, 0, false
+			}
+			cipher.NewCBCDecrypter(t.block, iv).CryptBlocks(pt, pt)
+		}
+	}
+
+	if len(pt) < header.ESPTrailerMinimumSize {
+		return nil, 0, false
+	}
+	padLen := int(pt[len(pt)-2])
+	nextHdr := tcpip.TransportProtocolNumber(pt[len(pt)-1])
+	payloadLen := len(pt) - header.ESPTrailerMinimumSize - padLen
+	if payloadLen < 0 {
+		return nil, 0, false
+	}
+	for i, b := range pt[payloadLen : payloadLen+padLen] {
+		if b != byte(i+1) {
+			return nil, 0, false
+		}
+	}
+	return pt[:payloadLen], nextHdr, true
+}
+
+// xfrmState is a security association in the database.
+type xfrmState struct {
+	XFRMState
+
+	// esp is nil for larval states.
+	esp *espTransform
+
+	// seq is the sequence number of the last packet sent with the state.
+	seq uint32
+
+	// replaySeq is the highest sequence number received and replayBitmap
+	// records the packets received in the window below it; bit i is set if
+	// replaySeq - i was received.
+	replaySeq    uint32
+	replayBitmap uint64
+}
+
+// checkReplay returns true if a packet with sequence number seq may be
+// accepted, as described in RFC 4303 section 3.4.3.
+func (s *xfrmState) checkReplay(seq uint32) bool {
+	if seq == 0 {
+		return false
+	}
+	if seq > s.replaySeq {
+		return true
+	}
+	diff := s.replaySeq - seq
+	if diff >= s.ReplayWindow {
+		s.Stats.ReplayWindow++
+		return false
+	}
+	if s.replayBitmap&(1<<diff) != 0 {
+		s.Stats.Replay++
+		return false
+	}
+	return true
+}
+
+// advanceReplay records an authenticated packet with sequence number seq.
+func (s *xfrmState) advanceReplay(seq uint32) {
+	if seq > s.replaySeq {
+		if shift := seq - s.replaySeq; shift < 64 {
+			s.replayBitmap = s.replayBitmap<<shift | 1
+		} else {
+			s.replayBitmap = 1
+		}
+		s.replaySeq = seq
+		return
+	}
+	s.replayBitmap |= 1 << (s.replaySeq - seq)
+}
+
+type xfrmStateKey struct {
+	dst   tcpip.Address
+	spi   uint32
+	proto tcpip.TransportProtocolNumber
+}
+
+// xfrmDatabase holds the security associations and policies of a stack.
+type xfrmDatabase struct {
+	// enabled is true if the database holds any state or policy. It lets the
+	// packet paths skip the database entirely in the common case.
+	enabled atomicbitops.Bool
+
+	mu sync.Mutex
+
+	// states holds the security associations.
+	//
+	// +checklocks:mu
+	states map[xfrmStateKey]*xfrmState
+
+	// policies holds the security policies, ordered by priority.
+	//
+	// +checklocks:mu
+	policies []*XFRMPolicy
+
+	// policyGen generates policy indices.
+	//
+	// +checklocks:mu
+	policyGen uint32
+}
+
+// +checklocks:db.mu
+func (db *xfrmDatabase) updateEnabledLocked() {
+	db.enabled.Store(len(db.states) != 0 || len(db.policies) != 0)
+}
+
+// +checklocks:db.mu
+func (db *xfrmDatabase) findPolicyLocked(dir XFRMDirection, sel *XFRMSelector, index uint32) int {
+	for i, p := range db.policies {
+		if index != 0 {
+			if p.Index == index {
+				return i
+			}
+			continue
+		}
+		if p.Dir == dir && p.Selector == *sel {
+			return i
+		}
+	}
+	return -1
+}
+
+// +checklocks:db.mu
+func (db *xfrmDatabase) lookupPolicyLocked(dir XFRMDirection, netProto tcpip.NetworkProtocolNumber, src, dst tcpip.Address, proto tcpip.TransportProtocolNumber, transportHdr []byte) *XFRMPolicy {
+	srcPort, dstPort := xfrmPorts(proto, transportHdr)
+	for _, p := range db.policies {
+		if p.Dir == dir && p.Selector.match(netProto, src, dst, proto, srcPort, dstPort) {
+			return p
+		}
+	}
+	return nil
+}
+
+// +checklocks:db.mu
+func (db *xfrmDatabase) findStateLocked(tmpl *XFRMTemplate, src, dst tcpip.Address) *xfrmState {
+	if tmpl.Dst.Len() != 0 {
+		dst = tmpl.Dst
+	}
+	if tmpl.Src.Len() != 0 {
+		src = tmpl.Src
+	}
+	for k, st := range db.states {
+		if k.dst != dst || k.proto != tmpl.Protocol || st.Src != src || st.esp == nil {
+			continue
+		}
+		if (tmpl.SPI != 0 && tmpl.SPI != k.spi) || (tmpl.ReqID != 0 && tmpl.ReqID != st.ReqID) {
+			continue
+		}
+		return st
+	}
+	return nil
+}
+
+func (db *xfrmDatabase) checkState(st *XFRMState) tcpip.Error {
+	if st.Protocol != header.ESPProtocolNumber {
+		return &tcpip.ErrNotSupported{}
+	}
+	if st.Mode != XFRMModeTransport {
+		return &tcpip.ErrNotSupported{}
+	}
+	if st.Dst.Len() == 0 || st.Src.Len() != st.Dst.Len() {
+		return &tcpip.ErrInvalidOptionValue{}
+	}
+	return nil
+}
+
+// AddXFRMState adds a security association. If update is true, it replaces an
+// existing state with the same destination, SPI and protocol, and fails with
+// ErrNoSuchFile if there is none; otherwise, it fails with
+// ErrDuplicateAddress if such a state exists.
+func (s *Stack) AddXFRMState(st XFRMState, update bool) tcpip.Error {
+	if err := s.xfrm.checkState(&st); err != nil {
+		return err
+	}
+	esp, err := newESPTransform(&st)
+	if err != nil {
+		return err
+	}
+	if st.ReplayWindow > 64 {
+		st.ReplayWindow = 64
+	}
+	st.Stats = XFRMStateStats{}
+	st.Larval = false
+
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	key := xfrmStateKey{dst: st.Dst, spi: st.SPI, proto: st.Protocol}
+	if _, ok := db.states[key]; ok != update {
+		if update {
+			return &tcpip.ErrNoSuchFile{}
+		}
+		return &tcpip.ErrDuplicateAddress{}
+	}
+	if db.states == nil {
+		db.states = make(map[xfrmStateKey]*xfrmState)
+	}
+	db.states[key] = &xfrmState{XFRMState: st, esp: esp}
+	db.updateEnabledLocked()
+	return nil
+}
+
+// AllocXFRMSPI reserves a random SPI in [min, max] for a security association
+// from src to dst by adding a larval state, which is completed later with
+// AddXFRMState(_, true).
+func (s *Stack) AllocXFRMSPI(st XFRMState, min, max uint32) (uint32, tcpip.Error) {
+	if err := s.xfrm.checkState(&st); err != nil {
+		return 0, err
+	}
+	// SPIs 1 to 255 are reserved by RFC 4303 section 2.1.
+	if min < 256 {
+		min = 256
+	}
+	if min > max {
+		return 0, &tcpip.ErrInvalidOptionValue{}
+	}
+
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.states == nil {
+		db.states = make(map[xfrmStateKey]*xfrmState)
+	}
+	for i := 0; i < 100; i++ {
+		spi := min
+		if min != max {
+			spi += s.secureRNG.Uint32() % (max - min + 1)
+		}
+		key := xfrmStateKey{dst: st.Dst, spi: spi, proto: st.Protocol}
+		if _, ok := db.states[key]; ok {
+			if min == max {
+				break
+			}
+			continue
+		}
+		st.SPI = spi
+		st.AEAD, st.Encryption, st.Auth = nil, nil, nil
+		st.Stats = XFRMStateStats{}
+		st.Larval = true
+		db.states[key] = &xfrmState{XFRMState: st}
+		db.updateEnabledLocked()
+		return spi, nil
+	}
+	return 0, &tcpip.ErrNoSuchFile{}
+}
+
+// GetXFRMState returns the security association with the given destination,
+// SPI and protocol.
+func (s *Stack) GetXFRMState(dst tcpip.Address, spi uint32, proto tcpip.TransportProtocolNumber) (XFRMState, tcpip.Error) {
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	st, ok := db.states[xfrmStateKey{dst: dst, spi: spi, proto: proto}]
+	if !ok {
+		return XFRMState{}, &tcpip.ErrNoSuchFile{}
+	}
+	return st.XFRMState, nil
+}
+
+// DeleteXFRMState removes the security association with the given
+// destination, SPI and protocol.
+func (s *Stack) DeleteXFRMState(dst tcpip.Address, spi uint32, proto tcpip.TransportProtocolNumber) tcpip.Error {
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	key := xfrmStateKey{dst: dst, spi: spi, proto: proto}
+	if _, ok := db.states[key]; !ok {
+		return &tcpip.ErrNoSuchFile{}
+	}
+	delete(db.states, key)
+	db.updateEnabledLocked()
+	return nil
+}
+
+// XFRMStates returns all security associations.
+func (s *Stack) XFRMStates() []XFRMState {
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	states := make([]XFRMState, 0, len(db.states))
+	for _, st := range db.states {
+		states = append(states, st.XFRMState)
+	}
+	return states
+}
+
+// FlushXFRMStates removes all security associations of the given protocol,
+// or all of them if proto is zero.
+func (s *Stack) FlushXFRMStates(proto tcpip.TransportProtocolNumber) {
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for k := range db.states {
+		if proto == 0 || k.proto == proto {
+			delete(db.states, k)
+		}
+	}
+	db.updateEnabledLocked()
+}
+
+// AddXFRMPolicy adds a security policy and returns its index. If a policy
+// with the same selector and direction exists, it is replaced if update is
+// true; otherwise, AddXFRMPolicy fails with ErrDuplicateAddress.
+func (s *Stack) AddXFRMPolicy(p XFRMPolicy, update bool) (uint32, tcpip.Error) {
+	if p.Dir > XFRMDirFwd || p.Action > XFRMActionBlock {
+		return 0, &tcpip.ErrInvalidOptionValue{}
+	}
+	for _, t := range p.Templates {
+		if t.Protocol != header.ESPProtocolNumber || t.Mode != XFRMModeTransport {
+			return 0, &tcpip.ErrNotSupported{}
+		}
+	}
+	p.Templates = append([]XFRMTemplate(nil), p.Templates...)
+
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if i := db.findPolicyLocked(p.Dir, &p.Selector, 0); i >= 0 {
+		if !update {
+			return 0, &tcpip.ErrDuplicateAddress{}
+		}
+		p.Index = db.policies[i].Index
+		db.policies = append(db.policies[:i], db.policies[i+1:]...)
+	} else {
+		// Like Linux, encode the direction in the low bits of the index.
+		db.policyGen++
+		p.Index = db.policyGen<<3 | uint32(p.Dir)
+	}
+
+	i := 0
+	for i < len(db.policies) && db.policies[i].Priority <= p.Priority {
+		i++
+	}
+	db.policies = append(db.policies, nil)
+	copy(db.policies[i+1:], db.policies[i:])
+	db.policies[i] = &p
+	db.updateEnabledLocked()
+	return p.Index, nil
+}
+
+// GetXFRMPolicy returns the security policy with the given index or, if index
+// is zero, with the given direction and selector.
+func (s *Stack) GetXFRMPolicy(dir XFRMDirection, sel XFRMSelector, index uint32) (XFRMPolicy, tcpip.Error) {
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	i := db.findPolicyLocked(dir, &sel, index)
+	if i < 0 {
+		return XFRMPolicy{}, &tcpip.ErrNoSuchFile{}
+	}
+	return *db.policies[i], nil
+}
+
+// DeleteXFRMPolicy removes the security policy with the given index or, if
+// index is zero, with the given direction and selector.
+func (s *Stack) DeleteXFRMPolicy(dir XFRMDirection, sel XFRMSelector, index uint32) (XFRMPolicy, tcpip.Error) {
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	i := db.findPolicyLocked(dir, &sel, index)
+	if i < 0 {
+		return XFRMPolicy{}, &tcpip.ErrNoSuchFile{}
+	}
+	p := db.policies[i]
+	db.policies = append(db.policies[:i], db.policies[i+1:]...)
+	db.updateEnabledLocked()
+	return *p, nil
+}
+
+// XFRMPolicies returns all security policies, ordered by priority.
+func (s *Stack) XFRMPolicies() []XFRMPolicy {
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	policies := make([]XFRMPolicy, 0, len(db.policies))
+	for _, p := range db.policies {
+		policies = append(policies, *p)
+	}
+	return policies
+}
+
+// FlushXFRMPolicies removes all security policies.
+func (s *Stack) FlushXFRMPolicies() {
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.policies = nil
+	db.updateEnabledLocked()
+}
+
+// XFRMEnabled returns true if the stack has any security association or
+// policy, in which case the network protocols must call XFRMOutput and
+// XFRMCheckInput.
+func (s *Stack) XFRMEnabled() bool {
+	return s.xfrm.enabled.Load()
+}
+
+// XFRMOutput applies the outbound policies to a locally generated packet from
+// src to dst. pkt must hold the transport header and data of the packet.
+//
+// If the packet must be protected, XFRMOutput returns a new packet holding
+// the ESP packet that replaces the transport payload, with reserved bytes
+// for the network and link headers. It returns ErrNotPermitted if a policy
+// blocks the packet, and ErrWouldBlock if a required security association is
+// missing.
+func (s *Stack) XFRMOutput(netProto tcpip.NetworkProtocolNumber, src, dst tcpip.Address, proto tcpip.TransportProtocolNumber, pkt *PacketBuffer, reserveHeaderBytes int) (*PacketBuffer, tcpip.Error) {
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	p := db.lookupPolicyLocked(XFRMDirOut, netProto, src, dst, proto, pkt.TransportHeader().Slice())
+	if p == nil {
+		return nil, nil
+	}
+	if p.Action == XFRMActionBlock {
+		return nil, &tcpip.ErrNotPermitted{}
+	}
+
+	// A single transport mode ESP transformation is applied, so the first
+	// template with a matching state wins.
+	for i := range p.Templates {
+		t := &p.Templates[i]
+		st := db.findStateLocked(t, src, dst)
+		if st == nil {
+			if t.Optional {
+				continue
+			}
+			return nil, &tcpip.ErrWouldBlock{}
+		}
+		if st.seq == ^uint32(0) {
+			// RFC 4303 section 3.3.3: the sequence number must not cycle;
+			// the key manager has to rekey the association.
+			return nil, &tcpip.ErrWouldBlock{}
+		}
+		st.seq++
+		v := pkt.ToView()
+		b, err := st.esp.seal(st.SPI, st.seq, proto, v.AsSlice(), s.secureRNG.Reader)
+		v.Release()
+		if err != nil {
+			return nil, err
+		}
+		st.Stats.Packets++
+		st.Stats.Bytes += uint64(pkt.Size())
+
+		espPkt := NewPacketBuffer(PacketBufferOptions{
+			ReserveHeaderBytes: reserveHeaderBytes,
+			Payload:            buffer.MakeWithData(b),
+		})
+		espPkt.NetworkProtocolNumber = pkt.NetworkProtocolNumber
+		espPkt.TransportProtocolNumber = header.ESPProtocolNumber
+		espPkt.Hash = pkt.Hash
+		espPkt.Owner = pkt.Owner
+		espPkt.EgressRoute = pkt.EgressRoute
+		return espPkt, nil
+	}
+	return nil, nil
+}
+
+// XFRMDecapsulate verifies and decrypts an inbound ESP packet from src to
+// dst. It returns the payload of the packet and the protocol of its transport
+// header.
+func (s *Stack) XFRMDecapsulate(src, dst tcpip.Address, esp []byte) ([]byte, tcpip.TransportProtocolNumber, XFRMSecPath, bool) {
+	if len(esp) < header.ESPHeaderSize {
+		return nil, 0, XFRMSecPath{}, false
+	}
+	h := header.ESP(esp)
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	st, ok := db.states[xfrmStateKey{dst: dst, spi: h.SPI(), proto: header.ESPProtocolNumber}]
+	if !ok || st.esp == nil || st.Src != src {
+		return nil, 0, XFRMSecPath{}, false
+	}
+	seq := h.SequenceNumber()
+	if !st.checkReplay(seq) {
+		return nil, 0, XFRMSecPath{}, false
+	}
+	payload, proto, ok := st.esp.open(esp)
+	if !ok {
+		st.Stats.IntegrityFailed++
+		return nil, 0, XFRMSecPath{}, false
+	}
+	st.advanceReplay(seq)
+	st.Stats.Packets++
+	st.Stats.Bytes += uint64(len(payload))
+	return payload, proto, XFRMSecPath{Transformed: true, SPI: st.SPI, ReqID: st.ReqID}, true
+}
+
+// XFRMCheckInput returns true if the inbound policies allow the delivery of
+// a packet from src to dst with the given transport header. sp records how
+// the packet was protected.
+func (s *Stack) XFRMCheckInput(netProto tcpip.NetworkProtocolNumber, src, dst tcpip.Address, proto tcpip.TransportProtocolNumber, transportHdr []byte, sp XFRMSecPath) bool {
+	db := &s.xfrm
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	p := db.lookupPolicyLocked(XFRMDirIn, netProto, src, dst, proto, transportHdr)
+	if p == nil {
+		return true
+	}
+	if p.Action == XFRMActionBlock {
+		return false
+	}
+	for _, t := range p.Templates {
+		if t.Optional {
+			continue
+		}
+		if !sp.Transformed || (t.SPI != 0 && t.SPI != sp.SPI) || (t.ReqID != 0 && t.ReqID != sp.ReqID) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,283 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+)
+
+var (
+	xfrmTestSrc = tcpip.AddrFrom4([4]byte{10, 0, 0, 1})
+	xfrmTestDst = tcpip.AddrFrom4([4]byte{10, 0, 0, 2})
+)
+
+func xfrmTestState(spi uint32) XFRMState {
+	return XFRMState{
+		Dst:          xfrmTestDst,
+		SPI:          spi,
+		Protocol:     header.ESPProtocolNumber,
+		Src:          xfrmTestSrc,
+		NetProto:     header.IPv4ProtocolNumber,
+		ReplayWindow: 32,
+		AEAD: &XFRMAlgorithm{
+			Name: "rfc4106(gcm(aes))",
+			Key:  bytes.Repeat([]byte{1}, 20),
+		},
+	}
+}
+
+func TestESPSealOpen(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		state XFRMState
+	}{
+		{
+			name: "AES-GCM",
+			state: XFRMState{
+				AEAD: &XFRMAlgorithm{Name: "rfc4106(gcm(aes))", Key: bytes.Repeat([]byte{1}, 36), ICVBits: 96},
+			},
+		},
+		{
+			name: "AES-CBC HMAC-SHA256",
+			state: XFRMState{
+				Encryption: &XFRMAlgorithm{Name: "cbc(aes)", Key: bytes.Repeat([]byte{2}, 16)},
+				Auth:       &XFRMAlgorithm{Name: "hmac(sha256)", Key: bytes.Repeat([]byte{3}, 32), ICVBits: 128},
+			},
+		},
+		{
+			name: "NULL HMAC-SHA1",
+			state: XFRMState{
+				Encryption: &XFRMAlgorithm{Name: "ecb(cipher_null)"},
+				Auth:       &XFRMAlgorithm{Name: "hmac(sha1)", Key: bytes.Repeat([]byte{4}, 20)},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			esp, err := newESPTransform(&tc.state)
+			if err != nil {
+				t.Fatalf("newESPTransform(_) = %s", err)
+			}
+			for _, size := range []int{0, 1, 13, 14, 100} {
+				payload := bytes.Repeat([]byte{0xab}, size)
+				b, err := esp.seal(0x1234, 7, header.UDPProtocolNumber, payload, rand.Reader)
+				if err != nil {
+					t.Fatalf("seal(_) = %s", err)
+				}
+				if h := header.ESP(b); h.SPI() != 0x1234 || h.SequenceNumber() != 7 {
+					t.Errorf("got SPI = %#x, sequence number = %d, want = 0x1234, 7", h.SPI(), h.SequenceNumber())
+				}
+				got, proto, ok := esp.open(b)
+				if !ok {
+					t.Fatalf("open(seal(%d bytes)) failed", size)
+				}
+				if proto != header.UDPProtocolNumber || !bytes.Equal(got, payload) {
+					t.Errorf("open(seal(%d bytes)) = (%x, %d), want = (%x, %d)", size, got, proto, payload, header.UDPProtocolNumber)
+				}
+
+				b[len(b)-1] ^= 1
+				if _, _, ok := esp.open(b); ok {
+					t.Errorf("open succeeded on a corrupted packet of %d bytes", size)
+				}
+			}
+		})
+	}
+}
+
+func TestESPTransformInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		state XFRMState
+	}{
+		{"NoAlgorithm", XFRMState{}},
+		{"UnknownAEAD", XFRMState{AEAD: &XFRMAlgorithm{Name: "rfc4309(ccm(aes))", Key: make([]byte, 20)}}},
+		{"ShortGCMKey", XFRMState{AEAD: &XFRMAlgorithm{Name: "rfc4106(gcm(aes))", Key: make([]byte, 16)}}},
+		{"NoAuth", XFRMState{Encryption: &XFRMAlgorithm{Name: "cbc(aes)", Key: make([]byte, 16)}}},
+		{"ShortTruncation", XFRMState{
+			Encryption: &XFRMAlgorithm{Name: "cbc(aes)", Key: make([]byte, 16)},
+			Auth:       &XFRMAlgorithm{Name: "hmac(sha256)", Key: make([]byte, 32), ICVBits: 64},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := newESPTransform(&tc.state); err == nil {
+				t.Errorf("newESPTransform(_) succeeded")
+			}
+		})
+	}
+}
+
+func TestXFRMReplayWindow(t *testing.T) {
+	st := xfrmState{XFRMState: XFRMState{ReplayWindow: 4}}
+	for _, tc := range []struct {
+		seq  uint32
+		want bool
+	}{
+		{0, false},
+		{1, true},
+		{1, false},
+		{5, true},
+		{3, true},
+		{3, false},
+		// Outside the window of 4 packets below 5.
+		{1, false},
+		{100, true},
+		{97, true},
+		{96, false},
+	} {
+		got := st.checkReplay(tc.seq)
+		if got != tc.want {
+			t.Errorf("checkReplay(%d) = %t, want = %t", tc.seq, got, tc.want)
+		}
+		if got {
+			st.advanceReplay(tc.seq)
+		}
+	}
+	if st.Stats.Replay != 2 || st.Stats.ReplayWindow != 2 {
+		t.Errorf("got Replay = %d, ReplayWindow = %d, want = 2, 2", st.Stats.Replay, st.Stats.ReplayWindow)
+	}
+}
+
+func TestXFRMStates(t *testing.T) {
+	s := New(Options{})
+	defer s.Close()
+
+	if s.XFRMEnabled() {
+		t.Fatalf("XFRM enabled on a new stack")
+	}
+	if _, ok := s.AddXFRMState(xfrmTestState(0x100), true /* update */).(*tcpip.ErrNoSuchFile); !ok {
+		t.Errorf("updating a missing state didn't fail with ErrNoSuchFile")
+	}
+	if err := s.AddXFRMState(xfrmTestState(0x100), false /* update */); err != nil {
+		t.Fatalf("AddXFRMState(_, false) = %s", err)
+	}
+	if _, ok := s.AddXFRMState(xfrmTestState(0x100), false /* update */).(*tcpip.ErrDuplicateAddress); !ok {
+		t.Errorf("adding a duplicate state didn't fail with ErrDuplicateAddress")
+	}
+	if !s.XFRMEnabled() {
+		t.Errorf("XFRM not enabled after adding a state")
+	}
+
+	spi, err := s.AllocXFRMSPI(xfrmTestState(0), 0x1000, 0x1000)
+	if err != nil {
+		t.Fatalf("AllocXFRMSPI(_, 0x1000, 0x1000) = %s", err)
+	}
+	if spi != 0x1000 {
+		t.Errorf("got SPI = %#x, want = 0x1000", spi)
+	}
+	if st, err := s.GetXFRMState(xfrmTestDst, spi, header.ESPProtocolNumber); err != nil || !st.Larval {
+		t.Errorf("GetXFRMState(_, %#x, _) = (%+v, %v), want a larval state", spi, st, err)
+	}
+	if _, err := s.AllocXFRMSPI(xfrmTestState(0), 0x1000, 0x1000); err == nil {
+		t.Errorf("AllocXFRMSPI succeeded with an exhausted range")
+	}
+	if err := s.AddXFRMState(xfrmTestState(spi), true /* update */); err != nil {
+		t.Fatalf("AddXFRMState(_, true) = %s", err)
+	}
+	if got := len(s.XFRMStates()); got != 2 {
+		t.Errorf("got len(XFRMStates()) = %d, want = 2", got)
+	}
+
+	if err := s.DeleteXFRMState(xfrmTestDst, 0x100, header.ESPProtocolNumber); err != nil {
+		t.Fatalf("DeleteXFRMState(_, 0x100, _) = %s", err)
+	}
+	s.FlushXFRMStates(0)
+	if s.XFRMEnabled() {
+		t.Errorf("XFRM enabled after flushing all states")
+	}
+}
+
+func TestXFRMPolicies(t *testing.T) {
+	s := New(Options{})
+	defer s.Close()
+
+	sel := XFRMSelector{
+		NetProto: header.IPv4ProtocolNumber,
+		Src:      tcpip.AddressWithPrefix{Address: xfrmTestSrc, PrefixLen: 32},
+		Dst:      tcpip.AddressWithPrefix{Address: xfrmTestDst, PrefixLen: 32},
+	}
+	require := XFRMPolicy{
+		Selector:  sel,
+		Dir:       XFRMDirIn,
+		Priority:  100,
+		Templates: []XFRMTemplate{{Protocol: header.ESPProtocolNumber, ReqID: 1}},
+	}
+	index, err := s.AddXFRMPolicy(require, false /* update */)
+	if err != nil {
+		t.Fatalf("AddXFRMPolicy(_, false) = %s", err)
+	}
+	if index&7 != uint32(XFRMDirIn) {
+		t.Errorf("got index = %#x, want the direction in the low bits", index)
+	}
+	if _, err := s.AddXFRMPolicy(require, false /* update */); err == nil {
+		t.Errorf("adding a duplicate policy succeeded")
+	}
+
+	// IKE traffic bypasses the policy thanks to a higher priority policy.
+	bypass := XFRMPolicy{
+		Selector: XFRMSelector{Protocol: header.UDPProtocolNumber, DstPort: 500, DstPortMask: 0xffff},
+		Dir:      XFRMDirIn,
+		Priority: 1,
+	}
+	if _, err := s.AddXFRMPolicy(bypass, false /* update */); err != nil {
+		t.Fatalf("AddXFRMPolicy(_, false) = %s", err)
+	}
+
+	udpHdr := func(dstPort uint16) []byte {
+		b := make([]byte, header.UDPMinimumSize)
+		header.UDP(b).SetDestinationPort(dstPort)
+		return b
+	}
+	for _, tc := range []struct {
+		name string
+		hdr  []byte
+		sp   XFRMSecPath
+		want bool
+	}{
+		{"Plaintext", udpHdr(53), XFRMSecPath{}, false},
+		{"Protected", udpHdr(53), XFRMSecPath{Transformed: true, SPI: 0x100, ReqID: 1}, true},
+		{"WrongReqID", udpHdr(53), XFRMSecPath{Transformed: true, SPI: 0x100, ReqID: 2}, false},
+		{"Bypass", udpHdr(500), XFRMSecPath{}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := s.XFRMCheckInput(header.IPv4ProtocolNumber, xfrmTestSrc, xfrmTestDst, header.UDPProtocolNumber, tc.hdr, tc.sp); got != tc.want {
+				t.Errorf("XFRMCheckInput(...) = %t, want = %t", got, tc.want)
+			}
+		})
+	}
+
+	// Traffic from other hosts isn't subject to the policy.
+	other := tcpip.AddrFrom4([4]byte{10, 0, 0, 3})
+	if !s.XFRMCheckInput(header.IPv4ProtocolNumber, other, xfrmTestDst, header.UDPProtocolNumber, udpHdr(53), XFRMSecPath{}) {
+		t.Errorf("XFRMCheckInput rejected a packet matching no policy")
+	}
+
+	if policies := s.XFRMPolicies(); len(policies) != 2 || policies[0].Priority != 1 {
+		t.Errorf("got XFRMPolicies() = %+v, want the bypass policy first", policies)
+	}
+	if _, err := s.DeleteXFRMPolicy(0, XFRMSelector{}, index); err != nil {
+		t.Fatalf("DeleteXFRMPolicy(_, _, %#x) = %s", index, err)
+	}
+	if _, err := s.GetXFRMPolicy(XFRMDirIn, sel, 0); err == nil {
+		t.Errorf("GetXFRMPolicy found a deleted policy")
+	}
+	s.FlushXFRMPolicies()
+	if s.XFRMEnabled() {
+		t.Errorf("XFRM enabled after flushing all policies")
+	}
+}
@@ -607,6 +607,26 @@ type SendableControlMessages struct {
 	// HopLimit is the IPv6 Hop Limit of the associated packet.
 	HopLimit uint8
 
+	// HasTOS indicates whether TOS is valid/set.
+	HasTOS bool
+
+	// TOS is the IPv4 type of service of the associated packet, including
+	// its ECN bits.
+	TOS uint8
+
+	// HasTClass indicates whether TClass is valid/set.
+	HasTClass bool
+
+	// TClass is the IPv6 traffic class of the associated packet, including
+	// its ECN bits.
+	TClass uint8
+
+	// HasFlowLabel indicates whether FlowLabel is valid/set.
+	HasFlowLabel bool
+
+	// FlowLabel is the IPv6 flow label of the associated packet.
+	FlowLabel uint32
+
 	// HasIPv6PacketInfo indicates whether IPv6PacketInfo is set.
 	HasIPv6PacketInfo bool
 
@@ -656,6 +676,13 @@ type ReceivableControlMessages struct {
 	// TClass is the IPv6 traffic class of the associated packet.
 	TClass uint32
 
+	// HasFlowInfo indicates whether FlowInfo is valid/set.
+	HasFlowInfo bool
+
+	// FlowInfo holds the traffic class and flow label of the associated IPv6
+	// packet, as laid out in the first word of its header.
+	FlowInfo uint32
+
 	// HasIPPacketInfo indicates whether PacketInfo is set.
 	HasIPPacketInfo bool
 
@@ -867,6 +894,26 @@ type EndpointWithPreflight interface {
 	Preflight(WriteOptions) Error
 }
 
+// EndpointWithBatchRead is the interface implemented by datagram endpoints
+// that can dequeue several datagrams with a single acquisition of their
+// receive queue lock.
+type EndpointWithBatchRead interface {
+	// ReadBatch reads up to len(dsts) datagrams, writing the i-th one to
+	// dsts[i], and returns their results. It doesn't block, and returns an
+	// error only if no datagram could be read. Peeking is not supported.
+	ReadBatch(dsts []io.Writer, opts ReadOptions) ([]ReadResult, Error)
+}
+
+// EndpointWithBatchWrite is the interface implemented by datagram endpoints
+// that can send several datagrams to the same destination with a single route
+// lookup.
+type EndpointWithBatchWrite interface {
+	// WriteBatch sends each payload in ps as a separate datagram, using the
+	// same options for all of them. It returns the number of datagrams sent
+	// and the error that stopped the batch, if any.
+	WriteBatch(ps []Payloader, opts WriteOptions) (int, Error)
+}
+
 // LinkPacketInfo holds Link layer information for a received packet.
 //
 // +stateify savable
@@ -985,6 +1032,16 @@ const (
 	// IPv6Checksum is used to request the stack to populate and validate the IPv6
 	// checksum for transport level headers.
 	IPv6Checksum
+
+	// UDPLiteSendCoverageOption is used by SetSockOptInt/GetSockOptInt to
+	// specify the number of bytes of outgoing UDP-Lite packets covered by
+	// the checksum. Zero means the entire packet.
+	UDPLiteSendCoverageOption
+
+	// UDPLiteReceiveCoverageOption is used by SetSockOptInt/GetSockOptInt to
+	// specify the minimum checksum coverage of incoming UDP-Lite packets.
+	// Zero means packets must be entirely covered.
+	UDPLiteReceiveCoverageOption
 )
 
 const (
@@ -1061,6 +1118,28 @@ func (*TCPSACKEnabled) isGettableTransportProtocolOption() {}
 
 func (*TCPSACKEnabled) isSettableTransportProtocolOption() {}
 
+// TCPECNOption controls Explicit Congestion Notification for TCP, like Linux's
+// net.ipv4.tcp_ecn.
+//
+// See: https://tools.ietf.org/html/rfc3168.
+type TCPECNOption int32
+
+const (
+	// TCPECNDisabled disables ECN.
+	TCPECNDisabled TCPECNOption = iota
+
+	// TCPECNEnabled requests ECN on outgoing connections and accepts it on
+	// incoming ones.
+	TCPECNEnabled
+
+	// TCPECNPassive accepts ECN on incoming connections only.
+	TCPECNPassive
+)
+
+func (*TCPECNOption) isGettableTransportProtocolOption() {}
+
+func (*TCPECNOption) isSettableTransportProtocolOption() {}
+
 // TCPRecovery is the loss deteoction algorithm used by TCP.
 type TCPRecovery int32
 
@@ -1304,6 +1383,133 @@ func (*TCPDeferAcceptOption) isGettableSocketOption() {}
 
 func (*TCPDeferAcceptOption) isSettableSocketOption() {}
 
+// TCPMD5SigOption is used by SetSockOpt to add or remove a key used to sign
+// segments exchanged with a peer using the TCP MD5 signature option, as
+// described in RFC 2385.
+type TCPMD5SigOption struct {
+	// Addr is the address of the peer.
+	Addr Address
+
+	// PrefixLen is the number of leading bits of Addr that a peer address
+	// must match for the key to be used.
+	PrefixLen int
+
+	// Key is the key. An empty key removes the key previously added for
+	// Addr and PrefixLen.
+	Key []byte
+}
+
+func (*TCPMD5SigOption) isSettableSocketOption() {}
+
+// TCPAOAddKeyOption is used by SetSockOpt to add a master key for the TCP
+// Authentication Option (TCP-AO), as described in RFC 5925.
+type TCPAOAddKeyOption struct {
+	// Addr is the address of the peer.
+	Addr Address
+
+	// PrefixLen is the number of leading bits of Addr that a peer address
+	// must match for the key to be used.
+	PrefixLen int
+
+	// Algorithm is the name of the MAC algorithm, using the Linux crypto
+	// API names, e.g. "hmac(sha1)" or "cmac(aes128)".
+	Algorithm string
+
+	// SendID is the KeyID used in outgoing segments.
+	SendID uint8
+
+	// RecvID is the KeyID expected in incoming segments.
+	RecvID uint8
+
+	// MACLen is the length of the MAC in bytes, or zero to use the
+	// default of 12 bytes.
+	MACLen uint8
+
+	// ExcludeOptions excludes TCP options other than TCP-AO from the MAC.
+	ExcludeOptions bool
+
+	// Key is the master key.
+	Key []byte
+
+	// SetCurrent makes the key the one used for outgoing segments.
+	SetCurrent bool
+
+	// SetRNext makes the key the one requested from the peer.
+	SetRNext bool
+}
+
+func (*TCPAOAddKeyOption) isSettableSocketOption() {}
+
+// TCPAODelKeyOption is used by SetSockOpt to remove a TCP-AO master key.
+type TCPAODelKeyOption struct {
+	// Addr is the address of the peer the key was added for.
+	Addr Address
+
+	// PrefixLen is the prefix length the key was added with.
+	PrefixLen int
+
+	// SendID is the KeyID used in outgoing segments.
+	SendID uint8
+
+	// RecvID is the KeyID expected in incoming segments.
+	RecvID uint8
+
+	// SetCurrent changes the current key to CurrentKey before the key is
+	// deleted.
+	SetCurrent bool
+
+	// CurrentKey is the SendID of the new current key.
+	CurrentKey uint8
+
+	// SetRNext changes the key requested from the peer to RNext before the
+	// key is deleted.
+	SetRNext bool
+
+	// RNext is the RecvID of the new key requested from the peer.
+	RNext uint8
+}
+
+func (*TCPAODelKeyOption) isSettableSocketOption() {}
+
+// TCPAOInfoOption is used by SetSockOpt/GetSockOpt to configure and query the
+// TCP-AO state of an endpoint.
+type TCPAOInfoOption struct {
+	// SetCurrent changes the current key to CurrentKey.
+	SetCurrent bool
+
+	// CurrentKey is the SendID of the key used for outgoing segments.
+	CurrentKey uint8
+
+	// SetRNext changes the key requested from the peer to RNext.
+	SetRNext bool
+
+	// RNext is the RecvID of the key requested from the peer.
+	RNext uint8
+
+	// Required drops segments without TCP-AO or TCP MD5 signatures.
+	Required bool
+
+	// SetCounters resets the counters below to the given values.
+	SetCounters bool
+
+	// PacketsGood is the number of segments with a valid MAC.
+	PacketsGood uint64
+
+	// PacketsBad is the number of segments with an invalid MAC.
+	PacketsBad uint64
+
+	// KeyNotFound is the number of segments for which no key matched.
+	KeyNotFound uint64
+
+	// AORequired is the number of segments dropped because they lacked
+	// TCP-AO.
+	AORequired uint64
+}
+
+func (*TCPAOInfoOption) isGettableSocketOption() {}
+
+func (*TCPAOInfoOption) isSettableSocketOption() {}
+
 // TCPMinRTOOption is use by SetSockOpt/GetSockOpt to allow overriding
 // default MinRTO used by the Stack.
 type TCPMinRTOOption time.Duration
@@ -2197,6 +2403,10 @@ type TCPStats struct {
 	// recover from packet loss.
 	SACKRecovery *StatCounter
 
+	// ECNReductions is the number of times the congestion window was reduced
+	// in response to an ECN-Echo from the peer.
+	ECNReductions *StatCounter
+
 	// TLPRecovery is the number of times recovery was accomplished by the tail
 	// loss probe.
 	TLPRecovery *StatCounter
@@ -2234,6 +2444,33 @@ type TCPStats struct {
 	// dropped due to exceeding the maximum number of in-flight connection
 	// requests.
 	ForwardMaxInFlightDrop *StatCounter
+
+	// MD5NotFound is the number of segments dropped because they lacked a
+	// TCP MD5 signature expected for the peer.
+	MD5NotFound *StatCounter
+
+	// MD5Unexpected is the number of segments dropped because they carried
+	// a TCP MD5 signature that no key was configured for.
+	MD5Unexpected *StatCounter
+
+	// MD5Failure is the number of segments dropped because their TCP MD5
+	// signature was invalid.
+	MD5Failure *StatCounter
+
+	// AOGood is the number of segments with a valid TCP-AO MAC.
+	AOGood *StatCounter
+
+	// AOBad is the number of segments dropped because their TCP-AO MAC was
+	// invalid.
+	AOBad *StatCounter
+
+	// AOKeyNotFound is the number of segments dropped because no TCP-AO
+	// key matched them.
+	AOKeyNotFound *StatCounter
+
+	// AORequired is the number of segments dropped because they lacked
+	// TCP-AO.
+	AORequired *StatCounter
 }
 
 // UDPStats collects UDP-specific stats.
@@ -2466,6 +2703,9 @@ type Stats struct {
 
 	// UDP holds UDP-specific stats.
 	UDP UDPStats
+
+	// UDPLite holds UDP-Lite-specific stats.
+	UDPLite UDPStats
 }
 
 // ReceiveErrors collects packet receive errors within transport endpoint.
@@ -225,10 +225,11 @@ func (e *Endpoint) calculateTTL(route *stack.Route) uint8 {
 
 // WriteContext holds the context for a write.
 type WriteContext struct {
-	e     *Endpoint
-	route *stack.Route
-	ttl   uint8
-	tos   uint8
+	e         *Endpoint
+	route     *stack.Route
+	ttl       uint8
+	tos       uint8
+	flowLabel uint32
 }
 
 func (c *WriteContext) MTU() uint32 {
@@ -345,9 +346,10 @@ func (c *WriteContext) WritePacket(pkt *stack.PacketBuffer, headerIncluded bool)
 	}
 
 	err := c.route.WritePacket(stack.NetworkHeaderParams{
-		Protocol: c.e.transProto,
-		TTL:      c.ttl,
-		TOS:      c.tos,
+		Protocol:  c.e.transProto,
+		TTL:       c.ttl,
+		TOS:       c.tos,
+		FlowLabel: c.flowLabel,
 	}, pkt)
 
 	if _, ok := err.(*tcpip.ErrNoBufferSpace); ok {
@@ -534,9 +536,13 @@ func (e *Endpoint) AcquireContextForWrite(opts tcpip.WriteOptions) (WriteContext
 
 	var tos uint8
 	var ttl uint8
+	var flowLabel uint32
 	switch netProto := route.NetProto(); netProto {
 	case header.IPv4ProtocolNumber:
 		tos = e.ipv4TOS
+		if opts.ControlMessages.HasTOS {
+			tos = opts.ControlMessages.TOS
+		}
 		if opts.ControlMessages.HasTTL {
 			ttl = opts.ControlMessages.TTL
 		} else {
@@ -544,20 +550,27 @@ func (e *Endpoint) AcquireContextForWrite(opts tcpip.WriteOptions) (WriteContext
 		}
 	case header.IPv6ProtocolNumber:
 		tos = e.ipv6TClass
+		if opts.ControlMessages.HasTClass {
+			tos = opts.ControlMessages.TClass
+		}
 		if opts.ControlMessages.HasHopLimit {
 			ttl = opts.ControlMessages.HopLimit
 		} else {
 			ttl = e.calculateTTL(route)
 		}
+		if opts.ControlMessages.HasFlowLabel {
+			flowLabel = opts.ControlMessages.FlowLabel
+		}
 	default:
 		panic(fmt.Sprintf("invalid protocol number = %d", netProto))
 	}
 
 	return WriteContext{
-		e:     e,
-		route: route,
-		ttl:   ttl,
-		tos:   tos,
+		e:         e,
+		route:     route,
+		ttl:       ttl,
+		tos:       tos,
+		flowLabel: flowLabel,
 	}, nil
 }
 
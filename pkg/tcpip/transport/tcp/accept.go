@@ -208,6 +208,7 @@ func (l *listenContext) createConnectingEndpoint(s *segment, rcvdSynOpts header.
 
 	n.maybeEnableTimestamp(rcvdSynOpts)
 	n.maybeEnableSACKPermitted(rcvdSynOpts)
+	n.maybeEnableECN(s)
 
 	n.initGSO()
 
@@ -363,6 +364,12 @@ func (e *Endpoint) propagateInheritableOptionsLocked(n *Endpoint) {
 	n.boundBindToDevice = e.boundBindToDevice
 	n.boundPortFlags = e.boundPortFlags
 	n.userMSS = e.userMSS
+	n.auth.inherit(&e.auth)
+	if n.gso.Type == stack.GSOTCPv4 || n.gso.Type == stack.GSOTCPv6 {
+		// Host GSO can't sign the segments it produces.
+		n.gso = stack.GSO{}
+		n.initGSO()
+	}
 }
 
 // reserveTupleLocked reserves an accepted endpoint's tuple.
@@ -0,0 +1,856 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"hash"
+
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/seqnum"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+const (
+	// maxAuthKeyLen is the maximum length of TCP MD5 and TCP-AO keys, as
+	// TCP_MD5SIG_MAXKEYLEN and TCP_AO_MAXKEYLEN in Linux.
+	maxAuthKeyLen = 80
+
+	// defaultAOMACLen is the MAC length used when none is specified, as
+	// recommended by RFC 5926 for both HMAC-SHA-1-96 and AES-128-CMAC-96.
+	defaultAOMACLen = 12
+)
+
+// aoAlgorithm is a MAC algorithm usable with TCP-AO.
+type aoAlgorithm struct {
+	// newMAC returns a MAC keyed with key.
+	newMAC func(key []byte) hash.Hash
+
+	// size is the size of the MAC, and of traffic keys, in bytes.
+	size int
+
+	// cmac indicates that master keys that aren't the AES key size must
+	// first be condensed as described in RFC 5926 section 3.1.1.2.
+	cmac bool
+}
+
+// aoAlgorithms maps the Linux crypto API names accepted by TCP_AO_ADD_KEY to
+// the algorithms supported by netstack.
+var aoAlgorithms = map[string]aoAlgorithm{
+	"hmac(sha1)": {
+		newMAC: func(key []byte) hash.Hash { return hmac.New(sha1.New, key) },
+		size:   sha1.Size,
+	},
+	"hmac(sha256)": {
+		newMAC: func(key []byte) hash.Hash { return hmac.New(sha256.New, key) },
+		size:   sha256.Size,
+	},
+	"cmac(aes128)": {
+		newMAC: newCMAC,
+		size:   aes.BlockSize,
+		cmac:   true,
+	},
+	"cmac(aes)": {
+		newMAC: newCMAC,
+		size:   aes.BlockSize,
+		cmac:   true,
+	},
+}
+
+// md5Key is a TCP MD5 signature key.
+//
+// +stateify savable
+type md5Key struct {
+	addr      tcpip.Address
+	prefixLen int
+	key       []byte
+}
+
+// aoKey is a TCP-AO master key tuple, as described in RFC 5925 section 3.1.
+//
+// +stateify savable
+type aoKey struct {
+	addr           tcpip.Address
+	prefixLen      int
+	alg            string
+	sendID         uint8
+	recvID         uint8
+	macLen         int
+	excludeOptions bool
+	key            []byte
+
+	// sendTrafficKey and recvTrafficKey are the traffic keys for segments
+	// other than SYNs. They are derived once both ISNs are known.
+	sendTrafficKey []byte
+	recvTrafficKey []byte
+}
+
+// addrMatches returns true if addr is within addr/prefixLen of a key.
+func addrMatches(keyAddr tcpip.Address, prefixLen int, addr tcpip.Address) bool {
+	if keyAddr.Len() != addr.Len() {
+		return false
+	}
+	subnet := tcpip.AddressWithPrefix{Address: keyAddr, PrefixLen: prefixLen}.Subnet()
+	return subnet.Contains(addr)
+}
+
+// sneTracker tracks the sequence number extension of a direction of a
+// connection, as described in RFC 5925 section 6.2.
+//
+// +stateify savable
+type sneTracker struct {
+	sne uint32
+	seq seqnum.Value
+}
+
+// computeSNE returns the sequence number extension of seq given that seq
+// refSeq has extension refSNE. seq must be within 2^31 of refSeq.
+func computeSNE(refSNE uint32, refSeq, seq seqnum.Value) uint32 {
+	if seq.LessThan(refSeq) {
+		if seq > refSeq {
+			return refSNE - 1
+		}
+	} else if seq < refSeq {
+		return refSNE + 1
+	}
+	return refSNE
+}
+
+// compute returns the sequence number extension of seq.
+func (t *sneTracker) compute(seq seqnum.Value) uint32 {
+	return computeSNE(t.sne, t.seq, seq)
+}
+
+// update records that seq, with extension sne, was sent or received.
+func (t *sneTracker) update(sne uint32, seq seqnum.Value) {
+	if t.seq.LessThan(seq) {
+		t.sne, t.seq = sne, seq
+	}
+}
+
+// tcpAuth holds the TCP MD5 signature (RFC 2385) and TCP Authentication
+// Option (RFC 5925) state of an endpoint.
+//
+// +stateify savable
+type tcpAuth struct {
+	mu sync.Mutex `state:"nosave"`
+
+	// +checklocks:mu
+	md5Keys []md5Key
+
+	// +checklocks:mu
+	aoKeys []*aoKey
+
+	// current is the TCP-AO key used for outgoing segments.
+	//
+	// +checklocks:mu
+	current *aoKey
+
+	// rnext is the TCP-AO key requested from the peer.
+	//
+	// +checklocks:mu
+	rnext *aoKey
+
+	// aoRequired drops unsigned segments even if no key matches the peer.
+	//
+	// +checklocks:mu
+	aoRequired bool
+
+	// haveISNs is true once the initial sequence numbers of both
+	// directions are known.
+	//
+	// +checklocks:mu
+	haveISNs bool
+
+	// +checklocks:mu
+	iss seqnum.Value
+
+	// +checklocks:mu
+	irs seqnum.Value
+
+	// +checklocks:mu
+	sndSNE sneTracker
+
+	// +checklocks:mu
+	rcvSNE sneTracker
+
+	// The following are the per-endpoint TCP-AO counters reported by
+	// TCP_AO_INFO.
+	//
+	// +checklocks:mu
+	pktGood uint64
+	// +checklocks:mu
+	pktBad uint64
+	// +checklocks:mu
+	pktKeyNotFound uint64
+	// +checklocks:mu
+	pktAORequired uint64
+}
+
+// enabled returns true if any key is configured.
+func (a *tcpAuth) enabled() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.md5Keys) != 0 || len(a.aoKeys) != 0
+}
+
+// inherit copies the keys of a listening endpoint to an endpoint created
+// from it.
+func (a *tcpAuth) inherit(l *tcpAuth) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.md5Keys = append([]md5Key(nil), l.md5Keys...)
+	a.aoKeys = nil
+	for _, k := range l.aoKeys {
+		nk := *k
+		nk.sendTrafficKey, nk.recvTrafficKey = nil, nil
+		a.aoKeys = append(a.aoKeys, &nk)
+		if k == l.current {
+			a.current = &nk
+		}
+		if k == l.rnext {
+			a.rnext = &nk
+		}
+	}
+	a.aoRequired = l.aoRequired
+}
+
+// setMD5Key implements tcpip.TCPMD5SigOption.
+func (a *tcpAuth) setMD5Key(opt *tcpip.TCPMD5SigOption) tcpip.Error {
+	if len(opt.Key) > maxAuthKeyLen || opt.PrefixLen < 0 || opt.PrefixLen > opt.Addr.BitLen() {
+		return &tcpip.ErrInvalidOptionValue{}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := range a.md5Keys {
+		k := &a.md5Keys[i]
+		if k.addr != opt.Addr || k.prefixLen != opt.PrefixLen {
+			continue
+		}
+		if len(opt.Key) == 0 {
+			a.md5Keys = append(a.md5Keys[:i], a.md5Keys[i+1:]...)
+		} else {
+			k.key = append([]byte(nil), opt.Key...)
+		}
+		return nil
+	}
+	if len(opt.Key) == 0 {
+		return &tcpip.ErrNoSuchFile{}
+	}
+	a.md5Keys = append(a.md5Keys, md5Key{
+		addr:      opt.Addr,
+		prefixLen: opt.PrefixLen,
+		key:       append([]byte(nil), opt.Key...),
+	})
+	return nil
+}
+
+// addAOKey implements tcpip.TCPAOAddKeyOption.
+func (a *tcpAuth) addAOKey(opt *tcpip.TCPAOAddKeyOption) tcpip.Error {
+	alg, ok := aoAlgorithms[opt.Algorithm]
+	if !ok {
+		// Linux fails to allocate the crypto transform.
+		return &tcpip.ErrNoSuchFile{}
+	}
+	macLen := int(opt.MACLen)
+	if macLen == 0 {
+		macLen = defaultAOMACLen
+	}
+	// The option must fit alongside all other SYN options, which leaves as
+	// much room as a TCP MD5 option.
+	if macLen > alg.size || header.TCPOptionAOMinimumLength+macLen > 2+header.TCPOptionMD5Length {
+		return &tcpip.ErrInvalidOptionValue{}
+	}
+	if len(opt.Key) > maxAuthKeyLen || opt.PrefixLen < 0 || opt.PrefixLen > opt.Addr.BitLen() {
+		return &tcpip.ErrInvalidOptionValue{}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, k := range a.aoKeys {
+		if k.addr == opt.Addr && k.prefixLen == opt.PrefixLen && (k.sendID == opt.SendID || k.recvID == opt.RecvID) {
+			return &tcpip.ErrDuplicateAddress{}
+		}
+	}
+	k := &aoKey{
+		addr:           opt.Addr,
+		prefixLen:      opt.PrefixLen,
+		alg:            opt.Algorithm,
+		sendID:         opt.SendID,
+		recvID:         opt.RecvID,
+		macLen:         macLen,
+		excludeOptions: opt.ExcludeOptions,
+		key:            append([]byte(nil), opt.Key...),
+	}
+	a.aoKeys = append(a.aoKeys, k)
+	if opt.SetCurrent || a.current == nil {
+		a.current = k
+	}
+	if opt.SetRNext || a.rnext == nil {
+		a.rnext = k
+	}
+	return nil
+}
+
+// delAOKey implements tcpip.TCPAODelKeyOption.
+func (a *tcpAuth) delAOKey(opt *tcpip.TCPAODelKeyOption) tcpip.Error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	idx := -1
+	for i, k := range a.aoKeys {
+		if k.addr == opt.Addr && k.prefixLen == opt.PrefixLen && k.sendID == opt.SendID && k.recvID == opt.RecvID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return &tcpip.ErrNoSuchFile{}
+	}
+	k := a.aoKeys[idx]
+	current, rnext := a.current, a.rnext
+	if opt.SetCurrent {
+		if current = a.findAOKeyLocked(func(k *aoKey) bool { return k.sendID == opt.CurrentKey }); current == nil {
+			return &tcpip.ErrNoSuchFile{}
+		}
+	}
+	if opt.SetRNext {
+		if rnext = a.findAOKeyLocked(func(k *aoKey) bool { return k.recvID == opt.RNext }); rnext == nil {
+			return &tcpip.ErrNoSuchFile{}
+		}
+	}
+	// Linux fails with EBUSY if the key is still in use.
+	if len(a.aoKeys) > 1 && (current == k || rnext == k) {
+		return &tcpip.ErrInvalidOptionValue{}
+	}
+	a.aoKeys = append(a.aoKeys[:idx], a.aoKeys[idx+1:]...)
+	if len(a.aoKeys) == 0 {
+		current, rnext = nil, nil
+	}
+	a.current, a.rnext = current, rnext
+	return nil
+}
+
+// +checklocks:a.mu
+func (a *tcpAuth) findAOKeyLocked(match func(*aoKey) bool) *aoKey {
+	for _, k := range a.aoKeys {
+		if match(k) {
+			return k
+		}
+	}
+	return nil
+}
+
+// aoInfo implements getting tcpip.TCPAOInfoOption.
+func (a *tcpAuth) aoInfo(opt *tcpip.TCPAOInfoOption) tcpip.Error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.aoKeys) == 0 {
+		return &tcpip.ErrNoSuchFile{}
+	}
+	*opt = tcpip.TCPAOInfoOption{
+		CurrentKey:  a.current.sendID,
+		RNext:       a.rnext.recvID,
+		Required:    a.aoRequired,
+		PacketsGood: a.pktGood,
+		PacketsBad:  a.pktBad,
+		KeyNotFound: a.pktKeyNotFound,
+		AORequired:  a.pktAORequired,
+	}
+	return nil
+}
+
+// setAOInfo implements setting tcpip.TCPAOInfoOption.
+func (a *tcpAuth) setAOInfo(opt *tcpip.TCPAOInfoOption) tcpip.Error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	current, rnext := a.current, a.rnext
+	if opt.SetCurrent {
+		if current = a.findAOKeyLocked(func(k *aoKey) bool { return k.sendID == opt.CurrentKey }); current == nil {
+			return &tcpip.ErrNoSuchFile{}
+		}
+	}
+	if opt.SetRNext {
+		if rnext = a.findAOKeyLocked(func(k *aoKey) bool { return k.recvID == opt.RNext }); rnext == nil {
+			return &tcpip.ErrNoSuchFile{}
+		}
+	}
+	a.current, a.rnext = current, rnext
+	a.aoRequired = opt.Required
+	if opt.SetCounters {
+		a.pktGood = opt.PacketsGood
+		a.pktBad = opt.PacketsBad
+		a.pktKeyNotFound = opt.KeyNotFound
+		a.pktAORequired = opt.AORequired
+	}
+	return nil
+}
+
+// setISNs records the initial sequence numbers of both directions of the
+// connection and derives the TCP-AO traffic keys for id.
+func (a *tcpAuth) setISNs(id stack.TransportEndpointID, iss, irs seqnum.Value) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.iss, a.irs, a.haveISNs = iss, irs, true
+	a.sndSNE = sneTracker{seq: iss}
+	a.rcvSNE = sneTracker{seq: irs}
+	for _, k := range a.aoKeys {
+		if !addrMatches(k.addr, k.prefixLen, id.RemoteAddress) {
+			continue
+		}
+		k.sendTrafficKey = k.trafficKey(id.LocalAddress, id.RemoteAddress, id.LocalPort, id.RemotePort, iss, irs)
+		k.recvTrafficKey = k.trafficKey(id.RemoteAddress, id.LocalAddress, id.RemotePort, id.LocalPort, irs, iss)
+	}
+}
+
+// +checklocks:a.mu
+func (a *tcpAuth) md5KeyLocked(addr tcpip.Address) *md5Key {
+	var best *md5Key
+	for i := range a.md5Keys {
+		k := &a.md5Keys[i]
+		if addrMatches(k.addr, k.prefixLen, addr) && (best == nil || k.prefixLen > best.prefixLen) {
+			best = k
+		}
+	}
+	return best
+}
+
+// +checklocks:a.mu
+func (a *tcpAuth) aoKeyMatchesLocked(addr tcpip.Address) bool {
+	return a.findAOKeyLocked(func(k *aoKey) bool { return addrMatches(k.addr, k.prefixLen, addr) }) != nil
+}
+
+// trafficKey derives a TCP-AO traffic key as described in RFC 5926 section
+// 3.1.1, with the connection described from the point of view of the sender
+// of the segments it protects.
+func (k *aoKey) trafficKey(srcAddr, dstAddr tcpip.Address, srcPort, dstPort uint16, srcISN, dstISN seqnum.Value) []byte {
+	alg := aoAlgorithms[k.alg]
+	masterKey := k.key
+	if alg.cmac && len(masterKey) != aes.BlockSize {
+		mac := alg.newMAC(make([]byte, aes.BlockSize))
+		mac.Write(masterKey)
+		masterKey = mac.Sum(nil)
+	}
+	mac := alg.newMAC(masterKey)
+	mac.Write([]byte{1})
+	mac.Write([]byte("TCP-AO"))
+	mac.Write(srcAddr.AsSlice())
+	mac.Write(dstAddr.AsSlice())
+	var b [14]byte
+	binary.BigEndian.PutUint16(b[0:], srcPort)
+	binary.BigEndian.PutUint16(b[2:], dstPort)
+	binary.BigEndian.PutUint32(b[4:], uint32(srcISN))
+	binary.BigEndian.PutUint32(b[8:], uint32(dstISN))
+	binary.BigEndian.PutUint16(b[12:], uint16(alg.size*8))
+	mac.Write(b[:])
+	return mac.Sum(nil)
+}
+
+// segmentSigner signs an outgoing segment. The zero value signs nothing.
+type segmentSigner struct {
+	// md5Key is set if segments are signed with TCP MD5.
+	md5Key []byte
+
+	// The following fields are set if segments are signed with TCP-AO.
+	aoKey          []byte
+	aoAlg          string
+	keyID          uint8
+	rNextKeyID     uint8
+	macLen         int
+	excludeOptions bool
+
+	// sne is the sequence number extension of sequence number seq.
+	sne uint32
+	seq seqnum.Value
+}
+
+// optionLen returns the number of option bytes needed by the signature.
+func (s *segmentSigner) optionLen() int {
+	switch {
+	case s.md5Key != nil:
+		return 2 + header.TCPOptionMD5Length
+	case s.aoKey != nil:
+		return (header.TCPOptionAOMinimumLength + s.macLen + 3) &^ 3
+	default:
+		return 0
+	}
+}
+
+// encodeOption encodes the signature option, with the signature itself left
+// empty, into b. It returns the number of bytes written.
+func (s *segmentSigner) encodeOption(b []byte) int {
+	switch {
+	case s.md5Key != nil:
+		return header.EncodeMD5Option(b)
+	case s.aoKey != nil:
+		return header.EncodeAOOption(s.keyID, s.rNextKeyID, s.macLen, b)
+	default:
+		return 0
+	}
+}
+
+// sign fills in the signature of tcp, whose options must start with the
+// option encoded by encodeOption and whose checksum must not be set yet.
+func (s *segmentSigner) sign(tcp header.TCP, srcAddr, dstAddr tcpip.Address, data stack.PacketData) {
+	switch {
+	case s.md5Key != nil:
+		sig := tcp[header.TCPMinimumSize+4:][:header.TCPMD5DigestSize]
+		copy(sig, md5Digest(s.md5Key, tcp, srcAddr, dstAddr, data))
+	case s.aoKey != nil:
+		mac := tcp[header.TCPMinimumSize+header.TCPOptionAOMinimumLength:][:s.macLen]
+		sne := computeSNE(s.sne, s.seq, seqnum.Value(tcp.SequenceNumber()))
+		copy(mac, aoMAC(aoAlgorithms[s.aoAlg], s.aoKey, sne, s.excludeOptions, tcp, srcAddr, dstAddr, data)[:s.macLen])
+	}
+}
+
+// optionSigner returns a segmentSigner that encodes the same option as signed
+// segments sent to addr, but doesn't sign them.
+func (a *tcpAuth) optionSigner(addr tcpip.Address) segmentSigner {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, k := range a.aoKeys {
+		if addrMatches(k.addr, k.prefixLen, addr) {
+			return segmentSigner{aoKey: []byte{}, macLen: k.macLen}
+		}
+	}
+	if a.md5KeyLocked(addr) != nil {
+		return segmentSigner{md5Key: []byte{}}
+	}
+	return segmentSigner{}
+}
+
+// signer returns a segmentSigner for a segment sent to id.RemoteAddress with
+// the given flags, sequence and acknowledgement numbers.
+func (a *tcpAuth) signer(id stack.TransportEndpointID, flags header.TCPFlags, seq, ack seqnum.Value) segmentSigner {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.aoKeys) != 0 {
+		k := a.current
+		if k == nil || !addrMatches(k.addr, k.prefixLen, id.RemoteAddress) {
+			k = a.findAOKeyLocked(func(k *aoKey) bool { return addrMatches(k.addr, k.prefixLen, id.RemoteAddress) })
+		}
+		if k != nil {
+			return a.aoSignerLocked(k, id, flags, seq, ack)
+		}
+	}
+	if k := a.md5KeyLocked(id.RemoteAddress); k != nil {
+		return segmentSigner{md5Key: k.key}
+	}
+	return segmentSigner{}
+}
+
+// +checklocks:a.mu
+func (a *tcpAuth) aoSignerLocked(k *aoKey, id stack.TransportEndpointID, flags header.TCPFlags, seq, ack seqnum.Value) segmentSigner {
+	s := segmentSigner{
+		aoAlg:          k.alg,
+		keyID:          k.sendID,
+		rNextKeyID:     k.recvID,
+		macLen:         k.macLen,
+		excludeOptions: k.excludeOptions,
+		seq:            seq,
+	}
+	if a.rnext != nil && addrMatches(a.rnext.addr, a.rnext.prefixLen, id.RemoteAddress) {
+		s.rNextKeyID = a.rnext.recvID
+	}
+	switch {
+	case flags.Contains(header.TCPFlagSyn):
+		// SYNs use the sender's ISN and, for SYN-ACKs, the peer's ISN.
+		var dstISN seqnum.Value
+		if flags.Contains(header.TCPFlagAck) {
+			dstISN = ack - 1
+		}
+		s.aoKey = k.trafficKey(id.LocalAddress, id.RemoteAddress, id.LocalPort, id.RemotePort, seq, dstISN)
+	case a.haveISNs:
+		if k.sendTrafficKey == nil {
+			k.sendTrafficKey = k.trafficKey(id.LocalAddress, id.RemoteAddress, id.LocalPort, id.RemotePort, a.iss, a.irs)
+		}
+		s.aoKey = k.sendTrafficKey
+		s.sne = a.sndSNE.compute(seq)
+		a.sndSNE.update(s.sne, seq)
+	default:
+		// Without ISNs there are no traffic keys, so the segment can't be
+		// signed.
+		return segmentSigner{}
+	}
+	return s
+}
+
+// verifySegmentAuth checks the TCP MD5 signature or TCP-AO MAC of an incoming
+// segment against the keys configured for its sender, and returns false if
+// the segment must be dropped.
+func (e *Endpoint) verifySegmentAuth(s *segment) bool {
+	a := &e.auth
+	stats := e.stack.Stats().TCP
+	hdr := header.TCP(s.pkt.TransportHeader().Slice())
+	hdr = hdr[:hdr.DataOffset()]
+	aoOpt, hasAO := header.FindTCPOption(hdr.Options(), header.TCPOptionAO)
+	md5Opt, hasMD5 := header.FindTCPOption(hdr.Options(), header.TCPOptionMD5)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.md5Keys) == 0 && len(a.aoKeys) == 0 && !hasAO && !hasMD5 {
+		return true
+	}
+
+	if hasAO {
+		return a.verifyAOLocked(stats, s, hdr, aoOpt, hasMD5)
+	}
+
+	md5Key := a.md5KeyLocked(s.id.RemoteAddress)
+	switch {
+	case md5Key == nil && !hasMD5:
+		if a.aoRequired || a.aoKeyMatchesLocked(s.id.RemoteAddress) {
+			a.pktAORequired++
+			stats.AORequired.Increment()
+			return false
+		}
+		return true
+	case md5Key == nil:
+		stats.MD5Unexpected.Increment()
+		return false
+	case !hasMD5:
+		stats.MD5NotFound.Increment()
+		return false
+	}
+	if len(md5Opt) != header.TCPOptionMD5Length {
+		stats.MD5Failure.Increment()
+		return false
+	}
+	digest := md5Digest(md5Key.key, hdr, s.id.RemoteAddress, s.id.LocalAddress, s.pkt.Data())
+	if subtle.ConstantTimeCompare(digest, md5Opt[2:]) != 1 {
+		stats.MD5Failure.Increment()
+		return false
+	}
+	return true
+}
+
+// +checklocks:a.mu
+func (a *tcpAuth) verifyAOLocked(stats tcpip.TCPStats, s *segment, hdr header.TCP, opt []byte, hasMD5 bool) bool {
+	if len(opt) < header.TCPOptionAOMinimumLength || hasMD5 {
+		a.pktBad++
+		stats.AOBad.Increment()
+		return false
+	}
+	keyID, rNextKeyID := opt[2], opt[3]
+	k := a.findAOKeyLocked(func(k *aoKey) bool {
+		return k.recvID == keyID && addrMatches(k.addr, k.prefixLen, s.id.RemoteAddress)
+	})
+	if k == nil {
+		a.pktKeyNotFound++
+		stats.AOKeyNotFound.Increment()
+		return false
+	}
+	if len(opt)-header.TCPOptionAOMinimumLength != k.macLen {
+		a.pktBad++
+		stats.AOBad.Increment()
+		return false
+	}
+
+	id := s.id
+	var (
+		trafficKey []byte
+		sne        uint32
+	)
+	switch {
+	case s.flags.Contains(header.TCPFlagSyn):
+		var dstISN seqnum.Value
+		if s.flags.Contains(header.TCPFlagAck) {
+			dstISN = s.ackNumber - 1
+		}
+		trafficKey = k.trafficKey(id.RemoteAddress, id.LocalAddress, id.RemotePort, id.LocalPort, s.sequenceNumber, dstISN)
+	case a.haveISNs:
+		if k.recvTrafficKey == nil {
+			k.recvTrafficKey = k.trafficKey(id.RemoteAddress, id.LocalAddress, id.RemotePort, id.LocalPort, a.irs, a.iss)
+		}
+		trafficKey = k.recvTrafficKey
+		sne = a.rcvSNE.compute(s.sequenceNumber)
+	default:
+		// A listening endpoint receiving the final ACK of a handshake
+		// derives the ISNs from the segment.
+		trafficKey = k.trafficKey(id.RemoteAddress, id.LocalAddress, id.RemotePort, id.LocalPort, s.sequenceNumber-1, s.ackNumber-1)
+	}
+
+	mac := aoMAC(aoAlgorithms[k.alg], trafficKey, sne, k.excludeOptions, hdr, id.RemoteAddress, id.LocalAddress, s.pkt.Data())
+	if subtle.ConstantTimeCompare(mac[:k.macLen], opt[header.TCPOptionAOMinimumLength:]) != 1 {
+		a.pktBad++
+		stats.AOBad.Increment()
+		return false
+	}
+	a.pktGood++
+	stats.AOGood.Increment()
+	if a.haveISNs {
+		a.rcvSNE.update(sne, s.sequenceNumber)
+	}
+
+	// Switch to the key the peer asked for, as described in RFC 5925
+	// section 7.5.2.
+	if a.current == nil || a.current.sendID != rNextKeyID {
+		if next := a.findAOKeyLocked(func(k *aoKey) bool {
+			return k.sendID == rNextKeyID && addrMatches(k.addr, k.prefixLen, id.RemoteAddress)
+		}); next != nil {
+			a.current = next
+		}
+	}
+	return true
+}
+
+// writePseudoHeader writes the pseudo-header covered by TCP MD5 signatures and
+// TCP-AO MACs to h.
+func writePseudoHeader(h hash.Hash, srcAddr, dstAddr tcpip.Address, length int) {
+	h.Write(srcAddr.AsSlice())
+	h.Write(dstAddr.AsSlice())
+	if srcAddr.Len() == header.IPv4AddressSize {
+		var b [4]byte
+		b[1] = uint8(header.TCPProtocolNumber)
+		binary.BigEndian.PutUint16(b[2:], uint16(length))
+		h.Write(b[:])
+		return
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint32(b[0:], uint32(length))
+	b[7] = uint8(header.TCPProtocolNumber)
+	h.Write(b[:])
+}
+
+// md5Digest returns the TCP MD5 signature of a segment, as described in RFC
+// 2385 section 2.0.
+func md5Digest(key []byte, tcp header.TCP, srcAddr, dstAddr tcpip.Address, data stack.PacketData) []byte {
+	h := md5.New()
+	writePseudoHeader(h, srcAddr, dstAddr, int(tcp.DataOffset())+data.Size())
+	// The header is covered without options and with a zero checksum.
+	var fixed [header.TCPMinimumSize]byte
+	copy(fixed[:], tcp)
+	binary.BigEndian.PutUint16(fixed[header.TCPChecksumOffset:], 0)
+	h.Write(fixed[:])
+	data.ReadTo(h, true /* peek */)
+	h.Write(key)
+	return h.Sum(nil)
+}
+
+// aoMAC returns the TCP-AO MAC of a segment, as described in RFC 5925 section
+// 5.1. The result is not truncated to the MAC length of the key.
+func aoMAC(alg aoAlgorithm, trafficKey []byte, sne uint32, excludeOptions bool, tcp header.TCP, srcAddr, dstAddr tcpip.Address, data stack.PacketData) []byte {
+	h := alg.newMAC(trafficKey)
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], sne)
+	h.Write(b[:])
+	hdrLen := int(tcp.DataOffset())
+	writePseudoHeader(h, srcAddr, dstAddr, hdrLen+data.Size())
+
+	// The header is covered with a zero checksum and MAC.
+	hdr := make([]byte, hdrLen)
+	copy(hdr, tcp)
+	binary.BigEndian.PutUint16(hdr[header.TCPChecksumOffset:], 0)
+	opt, _ := header.FindTCPOption(hdr[header.TCPMinimumSize:], header.TCPOptionAO)
+	clear(opt[min(len(opt), header.TCPOptionAOMinimumLength):])
+	if excludeOptions {
+		h.Write(hdr[:header.TCPMinimumSize])
+		h.Write(opt)
+	} else {
+		h.Write(hdr)
+	}
+	data.ReadTo(h, true /* peek */)
+	return h.Sum(nil)
+}
+
+// cmac implements AES-CMAC as described in RFC 4493. The whole message is
+// buffered until Sum is called.
+type cmac struct {
+	block  cipher.Block
+	k1, k2 [aes.BlockSize]byte
+	msg    []byte
+}
+
+// newCMAC returns an AES-128-CMAC keyed with key, which must be 16 bytes.
+func newCMAC(key []byte) hash.Hash {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	c := &cmac{block: block}
+	var l [aes.BlockSize]byte
+	block.Encrypt(l[:], l[:])
+	cmacShift(&c.k1, &l)
+	cmacShift(&c.k2, &c.k1)
+	return c
+}
+
+// cmacShift sets dst to src shifted left by one bit, and XORed with Rb if the
+// most significant bit of src was set.
+func cmacShift(dst, src *[aes.BlockSize]byte) {
+	msb := src[0] >> 7
+	for i := 0; i < aes.BlockSize-1; i++ {
+		dst[i] = src[i]<<1 | src[i+1]>>7
+	}
+	dst[aes.BlockSize-1] = src[aes.BlockSize-1] << 1
+	dst[aes.BlockSize-1] ^= 0x87 * msb
+}
+
+// Write implements hash.Hash.Write.
+func (c *cmac) Write(p []byte) (int, error) {
+	c.msg = append(c.msg, p...)
+	return len(p), nil
+}
+
+// Sum implements hash.Hash.Sum.
+func (c *cmac) Sum(b []byte) []byte {
+	var x, last [aes.BlockSize]byte
+	msg := c.msg
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	complete := n != 0 && len(msg)%aes.BlockSize == 0
+	if n == 0 {
+		n = 1
+	}
+	for i := 0; i < n-1; i++ {
+		subtle.XORBytes(x[:], x[:], msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		c.block.Encrypt(x[:], x[:])
+	}
+	tail := msg[(n-1)*aes.BlockSize:]
+	copy(last[:], tail)
+	if complete {
+		subtle.XORBytes(last[:], last[:], c.k1[:])
+	} else {
+		last[len(tail)] = 0x80
+		subtle.XORBytes(last[:], last[:], c.k2[:])
+	}
+	subtle.XORBytes(x[:], x[:], last[:])
+	c.block.Encrypt(x[:], x[:])
+	return append(b, x[:]...)
+}
+
+// Reset implements hash.Hash.Reset.
+func (c *cmac) Reset() {
+	c.msg = c.msg[:0]
+}
+
+// Size implements hash.Hash.Size.
+func (c *cmac) Size() int {
+	return aes.BlockSize
+}
+
+// BlockSize implements hash.Hash.BlockSize.
+func (c *cmac) BlockSize() int {
+	return aes.BlockSize
+}
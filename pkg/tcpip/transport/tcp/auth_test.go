@@ -0,0 +1,160 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/seqnum"
+)
+
+// TestCMAC checks the AES-CMAC implementation against the test vectors of RFC
+// 4493 section 4.
+func TestCMAC(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+	for _, tc := range []struct {
+		len  int
+		want string
+	}{
+		{0, "bb1d6929e95937287fa37d129b756746"},
+		{16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{40, "dfa66747de9ae63030ca32611497c827"},
+		{64, "51f0bebf7e3b9d92fc49741779363cfe"},
+	} {
+		mac := newCMAC(key)
+		mac.Write(msg[:tc.len])
+		if got := hex.EncodeToString(mac.Sum(nil)); got != tc.want {
+			t.Errorf("AES-CMAC of %d bytes = %s, want = %s", tc.len, got, tc.want)
+		}
+	}
+}
+
+func TestComputeSNE(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		refSNE uint32
+		refSeq seqnum.Value
+		seq    seqnum.Value
+		want   uint32
+	}{
+		{"Same", 3, 100, 200, 3},
+		{"Wrapped", 3, 0xffffff00, 0x10, 4},
+		{"BeforeWrap", 4, 0x10, 0xffffff00, 3},
+		{"Behind", 4, 200, 100, 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := computeSNE(tc.refSNE, tc.refSeq, tc.seq); got != tc.want {
+				t.Errorf("computeSNE(%d, %d, %d) = %d, want = %d", tc.refSNE, tc.refSeq, tc.seq, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAOKeys(t *testing.T) {
+	peer := tcpip.AddrFrom4([4]byte{10, 0, 0, 1})
+	addKey := func(a *tcpAuth, sendID, recvID uint8) tcpip.Error {
+		return a.addAOKey(&tcpip.TCPAOAddKeyOption{
+			Addr:      peer,
+			PrefixLen: 32,
+			Algorithm: "hmac(sha1)",
+			SendID:    sendID,
+			RecvID:    recvID,
+			Key:       []byte("secret"),
+		})
+	}
+
+	var a tcpAuth
+	if err := a.addAOKey(&tcpip.TCPAOAddKeyOption{Addr: peer, PrefixLen: 32, Algorithm: "hmac(md4)"}); err == nil {
+		t.Errorf("addAOKey with an unknown algorithm succeeded")
+	}
+	if err := addKey(&a, 1, 1); err != nil {
+		t.Fatalf("addAOKey(1, 1) = %s", err)
+	}
+	if _, ok := addKey(&a, 1, 2).(*tcpip.ErrDuplicateAddress); !ok {
+		t.Errorf("addAOKey with a duplicate SendID didn't fail with ErrDuplicateAddress")
+	}
+	if err := addKey(&a, 2, 2); err != nil {
+		t.Fatalf("addAOKey(2, 2) = %s", err)
+	}
+
+	var info tcpip.TCPAOInfoOption
+	if err := a.aoInfo(&info); err != nil {
+		t.Fatalf("aoInfo(_) = %s", err)
+	}
+	if info.CurrentKey != 1 || info.RNext != 1 {
+		t.Errorf("got current key = %d, rnext = %d, want = 1, 1", info.CurrentKey, info.RNext)
+	}
+
+	del := tcpip.TCPAODelKeyOption{Addr: peer, PrefixLen: 32, SendID: 1, RecvID: 1}
+	if _, ok := a.delAOKey(&del).(*tcpip.ErrInvalidOptionValue); !ok {
+		t.Errorf("delAOKey of the current key didn't fail with ErrInvalidOptionValue")
+	}
+	del.SetCurrent, del.CurrentKey, del.SetRNext, del.RNext = true, 2, true, 2
+	if err := a.delAOKey(&del); err != nil {
+		t.Fatalf("delAOKey(1, 1) = %s", err)
+	}
+	if err := a.aoInfo(&info); err != nil {
+		t.Fatalf("aoInfo(_) = %s", err)
+	}
+	if info.CurrentKey != 2 || info.RNext != 2 {
+		t.Errorf("got current key = %d, rnext = %d, want = 2, 2", info.CurrentKey, info.RNext)
+	}
+	if _, ok := a.delAOKey(&del).(*tcpip.ErrNoSuchFile); !ok {
+		t.Errorf("delAOKey of a missing key didn't fail with ErrNoSuchFile")
+	}
+}
+
+func TestMD5KeyLongestPrefix(t *testing.T) {
+	var a tcpAuth
+	for _, opt := range []tcpip.TCPMD5SigOption{
+		{Addr: tcpip.AddrFrom4([4]byte{10, 0, 0, 0}), PrefixLen: 8, Key: []byte("wide")},
+		{Addr: tcpip.AddrFrom4([4]byte{10, 1, 0, 0}), PrefixLen: 16, Key: []byte("narrow")},
+	} {
+		if err := a.setMD5Key(&opt); err != nil {
+			t.Fatalf("setMD5Key(%+v) = %s", opt, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		addr tcpip.Address
+		want string
+	}{
+		{tcpip.AddrFrom4([4]byte{10, 1, 2, 3}), "narrow"},
+		{tcpip.AddrFrom4([4]byte{10, 2, 2, 3}), "wide"},
+		{tcpip.AddrFrom4([4]byte{11, 1, 2, 3}), ""},
+	} {
+		a.mu.Lock()
+		k := a.md5KeyLocked(tc.addr)
+		a.mu.Unlock()
+		var got string
+		if k != nil {
+			got = string(k.key)
+		}
+		if got != tc.want {
+			t.Errorf("got key for %s = %q, want = %q", tc.addr, got, tc.want)
+		}
+	}
+
+	// An empty key deletes the key.
+	if err := a.setMD5Key(&tcpip.TCPMD5SigOption{Addr: tcpip.AddrFrom4([4]byte{10, 1, 0, 0}), PrefixLen: 16}); err != nil {
+		t.Fatalf("deleting key failed: %s", err)
+	}
+	if _, ok := a.setMD5Key(&tcpip.TCPMD5SigOption{Addr: tcpip.AddrFrom4([4]byte{10, 1, 0, 0}), PrefixLen: 16}).(*tcpip.ErrNoSuchFile); !ok {
+		t.Errorf("deleting a missing key didn't fail with ErrNoSuchFile")
+	}
+}
@@ -205,7 +205,7 @@ func FindWndScale(wnd seqnum.Size) int {
 // ready for a new 3-way handshake.
 func (h *handshake) resetState() {
 	h.state = handshakeSynSent
-	h.flags = header.TCPFlagSyn
+	h.flags = header.TCPFlagSyn | h.ep.ecnSynFlags()
 	h.ackNum = 0
 	h.mss = 0
 	h.iss = generateSecureISN(h.ep.TransportEndpointInfo.ID, h.ep.stack.Clock(), h.ep.protocol.seqnumSecret)
@@ -259,6 +259,9 @@ func (h *handshake) resetToSynRcvd(iss seqnum.Value, irs seqnum.Value, opts head
 	h.active = false
 	h.state = handshakeSynRcvd
 	h.flags = header.TCPFlagSyn | header.TCPFlagAck
+	if h.ep.ecn.enabled {
+		h.flags |= header.TCPFlagEce
+	}
 	h.iss = iss
 	h.ackNum = irs + 1
 	h.mss = opts.MSS
@@ -315,6 +318,13 @@ func (h *handshake) synSentState(s *segment) tcpip.Error {
 	// Remember if the SACKPermitted option was negotiated.
 	h.ep.maybeEnableSACKPermitted(rcvSynOpts)
 
+	// Remember if ECN was negotiated, which is only the case if the peer
+	// answers our ECN-setup SYN with an ECN-setup SYN-ACK. See RFC 3168
+	// section 6.1.1.
+	h.ep.ecn.enabled = h.flags.Contains(header.TCPFlagEce|header.TCPFlagCwr) &&
+		s.flags.Contains(header.TCPFlagAck|header.TCPFlagEce) && !s.flags.Contains(header.TCPFlagCwr)
+	h.flags &^= header.TCPFlagEce | header.TCPFlagCwr
+
 	// Remember the sequence we'll ack from now on.
 	h.ackNum = s.sequenceNumber + 1
 	h.flags |= header.TCPFlagAck
@@ -643,6 +653,7 @@ func (h *handshake) transitionToStateEstablishedLocked(s *segment) {
 	// receive window scaling if the peer doesn't support it
 	// (indicated by a negative send window scale).
 	h.ep.snd = newSender(h.ep, h.iss, h.ackNum-1, h.sndWnd, h.mss, h.sndWndScale)
+	h.ep.auth.setISNs(h.ep.TransportEndpointInfo.ID, h.iss, h.ackNum-1)
 
 	now := h.ep.stack.Clock().NowMonotonic()
 
@@ -729,10 +740,11 @@ func putOptions(options []byte) {
 	optionPool.Put(optionsToArray(options))
 }
 
-func makeSynOptions(opts header.TCPSynOptions) []byte {
+func makeSynOptions(opts header.TCPSynOptions, signer *segmentSigner) []byte {
 	// Emulate linux option order. This is as follows:
 	//
 	// if md5: NOP NOP MD5SIG 18 md5sig(16)
+	// elif ao: AO (4 + maclen) keyid(1) rnextkeyid(1) mac(maclen) [padding]
 	// if mss: MSS 4 mss(2)
 	// if ts and sack_advertise:
 	//	SACK 2 TIMESTAMP 2 timestamp(8)
@@ -747,9 +759,10 @@ func makeSynOptions(opts header.TCPSynOptions) []byte {
 	//	cookie(variable) [padding to four bytes]
 	//
 	options := getOptions()
+	offset := signer.encodeOption(options)
 
 	// Always encode the mss.
-	offset := header.EncodeMSSOption(uint32(opts.MSS), options)
+	offset += header.EncodeMSSOption(uint32(opts.MSS), options[offset:])
 
 	// Special ordering is required here. If both TS and SACK are enabled,
 	// then the SACK option precedes TS, with no padding. If they are
@@ -795,10 +808,15 @@ type tcpFields struct {
 	opts   []byte
 	txHash uint32
 	df     bool
+
+	// signer signs the segment if TCP MD5 or TCP-AO is in use. Its option
+	// must be the first one in opts.
+	signer segmentSigner
 }
 
 func (e *Endpoint) sendSynTCP(r *stack.Route, tf tcpFields, opts header.TCPSynOptions) tcpip.Error {
-	tf.opts = makeSynOptions(opts)
+	tf.signer = e.auth.signer(tf.id, tf.flags, tf.seq, tf.ack)
+	tf.opts = makeSynOptions(opts, &tf.signer)
 	// We ignore SYN send errors and let the callers re-attempt send.
 	p := stack.NewPacketBuffer(stack.PacketBufferOptions{ReserveHeaderBytes: header.TCPMinimumSize + int(r.MaxHeaderLength()) + len(tf.opts)})
 	defer p.DecRef()
@@ -834,6 +852,7 @@ func buildTCPHdr(r *stack.Route, tf tcpFields, pkt *stack.PacketBuffer, gso stac
 		WindowSize: uint16(tf.rcvWnd),
 	})
 	copy(tcp[header.TCPMinimumSize:], tf.opts)
+	tf.signer.sign(tcp, r.LocalAddress(), r.RemoteAddress(), pkt.Data())
 
 	xsum := r.PseudoHeaderChecksum(ProtocolNumber, uint16(pkt.Size()))
 	// Only calculate the checksum if offloading isn't supported.
@@ -925,10 +944,10 @@ func sendTCP(r *stack.Route, tf tcpFields, pkt *stack.PacketBuffer, gso stack.GS
 	return nil
 }
 
-// makeOptions makes an options slice.
-func (e *Endpoint) makeOptions(sackBlocks []header.SACKBlock) []byte {
+// makeOptions makes an options slice, starting with the option of signer.
+func (e *Endpoint) makeOptions(sackBlocks []header.SACKBlock, signer *segmentSigner) []byte {
 	options := getOptions()
-	offset := 0
+	offset := signer.encodeOption(options)
 
 	// N.B. the ordering here matches the ordering used by Linux internally
 	// and described in the raw makeOptions function. We don't include
@@ -950,7 +969,9 @@ func (e *Endpoint) makeOptions(sackBlocks []header.SACKBlock) []byte {
 		offset += header.EncodeNOP(options[offset:])
 		offset += header.EncodeTSOption(e.tsValNow(), e.recentTimestamp(), options[offset:])
 	}
-	if e.SACKPermitted && len(sackBlocks) > 0 {
+	// Only include SACK blocks if at least one fits alongside the other
+	// options, which isn't the case for signed segments with timestamps.
+	if e.SACKPermitted && len(sackBlocks) > 0 && len(options)-offset >= 4+8 {
 		offset += header.EncodeNOP(options[offset:])
 		offset += header.EncodeNOP(options[offset:])
 		offset += header.EncodeSACKBlocks(sackBlocks, options[offset:])
@@ -971,32 +992,44 @@ func (e *Endpoint) makeOptions(sackBlocks []header.SACKBlock) []byte {
 func (e *Endpoint) sendEmptyRaw(flags header.TCPFlags, seq, ack seqnum.Value, rcvWnd seqnum.Size) tcpip.Error {
 	pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{})
 	defer pkt.DecRef()
-	return e.sendRaw(pkt, flags, seq, ack, rcvWnd)
+	return e.sendRaw(pkt, flags, seq, ack, rcvWnd, false /* ect */)
 }
 
 // sendRaw sends a TCP segment to the endpoint's peer. This method takes
-// ownership of pkt. pkt must not have any headers set.
+// ownership of pkt. pkt must not have any headers set. If ect is true, the
+// segment is marked as ECN-capable.
 //
 // +checklocks:e.mu
 // +checklocksalias:e.snd.ep.mu=e.mu
-func (e *Endpoint) sendRaw(pkt *stack.PacketBuffer, flags header.TCPFlags, seq, ack seqnum.Value, rcvWnd seqnum.Size) tcpip.Error {
+func (e *Endpoint) sendRaw(pkt *stack.PacketBuffer, flags header.TCPFlags, seq, ack seqnum.Value, rcvWnd seqnum.Size, ect bool) tcpip.Error {
+	tos := e.sendTOS
+	if ect {
+		tos |= ecnECT0
+	}
+	// Echo congestion on all ACKs until the peer reduces its window. See
+	// RFC 3168 section 6.1.3.
+	if e.ecn.echo && flags.Contains(header.TCPFlagAck) && !flags.Contains(header.TCPFlagSyn) {
+		flags |= header.TCPFlagEce
+	}
 	var sackBlocks []header.SACKBlock
 	if e.EndpointState() == StateEstablished && e.rcv.pendingRcvdSegments.Len() > 0 && (flags&header.TCPFlagAck != 0) {
 		sackBlocks = e.sack.Blocks[:e.sack.NumBlocks]
 	}
-	options := e.makeOptions(sackBlocks)
+	signer := e.auth.signer(e.TransportEndpointInfo.ID, flags, seq, ack)
+	options := e.makeOptions(sackBlocks, &signer)
 	defer putOptions(options)
 	pkt.ReserveHeaderBytes(header.TCPMinimumSize + int(e.route.MaxHeaderLength()) + len(options))
 	return e.sendTCP(e.route, tcpFields{
 		id:     e.TransportEndpointInfo.ID,
 		ttl:    calculateTTL(e.route, e.ipv4TTL, e.ipv6HopLimit),
-		tos:    e.sendTOS,
+		tos:    tos,
 		flags:  flags,
 		seq:    seq,
 		ack:    ack,
 		rcvWnd: rcvWnd,
 		opts:   options,
 		df:     e.pmtud == tcpip.PMTUDiscoveryWant || e.pmtud == tcpip.PMTUDiscoveryDo,
+		signer: signer,
 	}, pkt, e.gso)
 }
 
@@ -20,6 +20,7 @@ import (
 	"math/rand"
 
 	"gvisor.dev/gvisor/pkg/sleep"
+	"gvisor.dev/gvisor/pkg/stall"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/tcpip"
 	"gvisor.dev/gvisor/pkg/tcpip/hash/jenkins"
@@ -310,6 +311,7 @@ func (p *processor) start(wg *sync.WaitGroup) {
 				if ep.segmentQueue.empty() {
 					continue
 				}
+				stallID := stall.Netstack.Begin()
 				switch state := ep.EndpointState(); {
 				case state.connecting():
 					handleConnecting(ep)
@@ -331,6 +333,7 @@ func (p *processor) start(wg *sync.WaitGroup) {
 				default:
 					panic(fmt.Sprintf("unexpected tcp state in processor: %v", state))
 				}
+				stall.Netstack.End(stallID)
 				// If there are more segments to process and the
 				// endpoint lock is not held by user then
 				// requeue this endpoint for processing.
@@ -441,6 +444,10 @@ func (d *dispatcher) queuePacket(stackEP stack.TransportEndpoint, id stack.Trans
 		return
 	}
 
+	if !ep.verifySegmentAuth(s) {
+		return
+	}
+
 	ep.stack.Stats().TCP.ValidSegmentsReceived.Increment()
 	ep.stats.SegmentsReceived.Increment()
 	if (s.flags & header.TCPFlagRst) != 0 {
@@ -0,0 +1,102 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/seqnum"
+)
+
+// ECN codepoints of the IP header. See RFC 3168 section 5.
+const (
+	ecnMask = 0x3
+	ecnECT0 = 0x2
+	ecnCE   = 0x3
+)
+
+// ecnState holds the Explicit Congestion Notification state of a connection,
+// as described in RFC 3168.
+//
+// +stateify savable
+type ecnState struct {
+	// enabled is true if ECN was negotiated during the handshake.
+	enabled bool
+
+	// echo is true if a segment marked with CE was received and the peer
+	// hasn't sent CWR since. All ACKs carry ECE while it's set.
+	echo bool
+
+	// sendCWR is true if the congestion window was reduced in response to
+	// ECE and the next new data segment must carry CWR.
+	sendCWR bool
+
+	// reduced is true if the congestion window was reduced in response to
+	// ECE. It isn't reduced again until recover is acknowledged.
+	reduced bool
+	recover seqnum.Value
+}
+
+// ecnSynFlags returns the ECN flags to set on the SYN of an active open.
+func (e *Endpoint) ecnSynFlags() header.TCPFlags {
+	var v tcpip.TCPECNOption
+	if err := e.stack.TransportProtocolOption(ProtocolNumber, &v); err != nil || v != tcpip.TCPECNEnabled {
+		return 0
+	}
+	return header.TCPFlagEce | header.TCPFlagCwr
+}
+
+// maybeEnableECN marks ECN enabled for this endpoint if the peer's SYN
+// requests it and the TCP stack is configured to accept it.
+func (e *Endpoint) maybeEnableECN(syn *segment) {
+	var v tcpip.TCPECNOption
+	if err := e.stack.TransportProtocolOption(ProtocolNumber, &v); err != nil || v == tcpip.TCPECNDisabled {
+		return
+	}
+	e.ecn.enabled = syn.flags.Contains(header.TCPFlagSyn | header.TCPFlagEce | header.TCPFlagCwr)
+}
+
+// ecnSegmentReceived updates the receiver's ECN state with an acceptable
+// segment.
+//
+// +checklocks:e.mu
+func (e *Endpoint) ecnSegmentReceived(s *segment) {
+	if !e.ecn.enabled {
+		return
+	}
+	if s.flags.Contains(header.TCPFlagCwr) {
+		e.ecn.echo = false
+	}
+	if tos, _ := s.pkt.Network().TOS(); tos&ecnMask == ecnCE {
+		e.ecn.echo = true
+	}
+}
+
+// handleECNEcho reduces the congestion window in response to an ACK carrying
+// ECE, at most once per window of data. See RFC 3168 section 6.1.2.
+//
+// +checklocks:s.ep.mu
+func (s *sender) handleECNEcho(ack seqnum.Value) {
+	ecn := &s.ep.ecn
+	if ecn.reduced && ack.LessThan(ecn.recover) {
+		return
+	}
+	s.cc.HandleLossDetected()
+	s.SndCwnd = s.Ssthresh
+	ecn.reduced = true
+	ecn.recover = s.SndNxt
+	ecn.sendCWR = true
+	s.ep.stack.Stats().TCP.ECNReductions.Increment()
+}
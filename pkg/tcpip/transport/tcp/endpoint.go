@@ -502,6 +502,9 @@ type Endpoint struct {
 	// without hearing a response, the connection is closed.
 	keepalive keepalive
 
+	// auth holds the TCP MD5 signature and TCP-AO keys of the endpoint.
+	auth tcpAuth
+
 	// userTimeout if non-zero specifies a user specified timeout for
 	// a connection w/ pending data to send. A connection that has pending
 	// unacked data will be forcibily aborted if the timeout is reached
@@ -554,6 +557,9 @@ type Endpoint struct {
 	// applied while sending packets. Defaults to 0 as on Linux.
 	sendTOS uint8
 
+	// ecn is the Explicit Congestion Notification state of the connection.
+	ecn ecnState
+
 	gso stack.GSO
 
 	stats Stats
@@ -1254,6 +1260,12 @@ func wndFromSpace(space int) int {
 	return space >> rcvAdvWndScale
 }
 
+// spaceFromWnd returns the receive buffer space required to advertise a
+// receive window of wnd bytes. It is the inverse of wndFromSpace.
+func spaceFromWnd(wnd int) int {
+	return wnd << rcvAdvWndScale
+}
+
 // initialReceiveWindow returns the initial receive window to advertise in the
 // SYN/SYN-ACK.
 func (e *Endpoint) initialReceiveWindow() int {
@@ -1326,20 +1338,23 @@ func (e *Endpoint) ModerateRecvBuf(copied int) {
 			rcvWnd = minRcvWnd
 		}
 
-		// Cap the auto tuned buffer size by the maximum permissible
+		// Only part of the receive buffer is advertised as window, the
+		// rest accounts for segment overhead. Size the buffer so that
+		// rcvWnd can be advertised, capped by the maximum permissible
 		// receive buffer size.
-		if max := e.maxReceiveBufferSize(); rcvWnd > max {
-			rcvWnd = max
+		newRcvBufSize := spaceFromWnd(rcvWnd)
+		if max := e.maxReceiveBufferSize(); newRcvBufSize > max {
+			newRcvBufSize = max
 		}
 
 		// We do not adjust downwards as that can cause the receiver to
 		// reject valid data that might already be in flight as the
 		// acceptable window will shrink.
 		rcvBufSize := int(e.ops.GetReceiveBufferSize())
-		if rcvWnd > rcvBufSize {
+		if newRcvBufSize > rcvBufSize {
 			availBefore := wndFromSpace(e.receiveBufferAvailableLocked(rcvBufSize))
-			e.ops.SetReceiveBufferSize(int64(rcvWnd), false /* notify */)
-			availAfter := wndFromSpace(e.receiveBufferAvailableLocked(rcvWnd))
+			e.ops.SetReceiveBufferSize(int64(newRcvBufSize), false /* notify */)
+			availAfter := wndFromSpace(e.receiveBufferAvailableLocked(newRcvBufSize))
 			if crossed, above := e.windowCrossedACKThresholdLocked(availAfter-availBefore, rcvBufSize); crossed && above {
 				sendNonZeroWindowUpdate = true
 			}
@@ -1981,6 +1996,18 @@ func (e *Endpoint) SetSockOpt(opt tcpip.SettableSocketOption) tcpip.Error {
 		e.userTimeout = time.Duration(*v)
 		e.UnlockUser()
 
+	case *tcpip.TCPMD5SigOption:
+		return e.auth.setMD5Key(v)
+
+	case *tcpip.TCPAOAddKeyOption:
+		return e.auth.addAOKey(v)
+
+	case *tcpip.TCPAODelKeyOption:
+		return e.auth.delAOKey(v)
+
+	case *tcpip.TCPAOInfoOption:
+		return e.auth.setAOInfo(v)
+
 	case *tcpip.CongestionControlOption:
 		// Query the available cc algorithms in the stack and
 		// validate that the specified algorithm is actually
@@ -2190,6 +2217,9 @@ func (e *Endpoint) GetSockOpt(opt tcpip.GettableSocketOption) tcpip.Error {
 		*o = tcpip.TCPUserTimeoutOption(e.userTimeout)
 		e.UnlockUser()
 
+	case *tcpip.TCPAOInfoOption:
+		return e.auth.aoInfo(o)
+
 	case *tcpip.CongestionControlOption:
 		e.LockUser()
 		*o = e.cc
@@ -3142,7 +3172,8 @@ func (e *Endpoint) maybeEnableSACKPermitted(synOpts header.TCPSynOptions) {
 // maxOptionSize return the maximum size of TCP options.
 func (e *Endpoint) maxOptionSize() (size int) {
 	var maxSackBlocks [header.TCPMaxSACKBlocks]header.SACKBlock
-	options := e.makeOptions(maxSackBlocks[:])
+	signer := e.auth.optionSigner(e.TransportEndpointInfo.ID.RemoteAddress)
+	options := e.makeOptions(maxSackBlocks[:], &signer)
 	size = len(options)
 	putOptions(options)
 
@@ -3208,7 +3239,12 @@ func (e *Endpoint) initHostGSO() {
 }
 
 func (e *Endpoint) initGSO() {
-	if e.route.HasHostGSOCapability() {
+	// ESP encapsulation happens below the transport layer and can't
+	// segment packets, so segmentation offload is disabled with IPsec.
+	if e.stack.XFRMEnabled() {
+		return
+	}
+	if e.route.HasHostGSOCapability() && !e.auth.enabled() {
 		e.initHostGSO()
 	} else if e.route.HasGVisorGSOCapability() {
 		e.gso = stack.GSO{
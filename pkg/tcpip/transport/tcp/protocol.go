@@ -92,6 +92,7 @@ type protocol struct {
 
 	mu                         sync.RWMutex `state:"nosave"`
 	sackEnabled                bool
+	ecn                        tcpip.TCPECNOption
 	recovery                   tcpip.TCPRecovery
 	delayEnabled               bool
 	alwaysUseSynCookies        bool
@@ -249,6 +250,15 @@ func (p *protocol) SetOption(option tcpip.SettableTransportProtocolOption) tcpip
 		p.mu.Unlock()
 		return nil
 
+	case *tcpip.TCPECNOption:
+		if *v < tcpip.TCPECNDisabled || *v > tcpip.TCPECNPassive {
+			return &tcpip.ErrInvalidOptionValue{}
+		}
+		p.mu.Lock()
+		p.ecn = *v
+		p.mu.Unlock()
+		return nil
+
 	case *tcpip.TCPRecovery:
 		p.mu.Lock()
 		p.recovery = *v
@@ -386,6 +396,12 @@ func (p *protocol) Option(option tcpip.GettableTransportProtocolOption) tcpip.Er
 		p.mu.RUnlock()
 		return nil
 
+	case *tcpip.TCPECNOption:
+		p.mu.RLock()
+		*v = p.ecn
+		p.mu.RUnlock()
+		return nil
+
 	case *tcpip.TCPRecovery:
 		p.mu.RLock()
 		*v = p.recovery
@@ -537,6 +553,7 @@ func NewProtocol(s *stack.Stack) stack.TransportProtocol {
 			Max:     MaxBufferSize,
 		},
 		sackEnabled:                true,
+		ecn:                        tcpip.TCPECNPassive,
 		congestionControl:          ccReno,
 		availableCongestionControl: []string{ccReno, ccCubic},
 		moderateReceiveBuffer:      true,
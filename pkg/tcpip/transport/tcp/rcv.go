@@ -17,6 +17,7 @@ package tcp
 import (
 	"container/heap"
 	"math"
+	"time"
 
 	"gvisor.dev/gvisor/pkg/tcpip"
 	"gvisor.dev/gvisor/pkg/tcpip/header"
@@ -320,37 +321,58 @@ func (r *receiver) consumeSegment(s *segment, segSeq seqnum.Value, segLen seqnum
 	return true
 }
 
-// updateRTT updates the receiver RTT measurement based on the sequence number
-// of the received segment.
-func (r *receiver) updateRTT() {
+// updateRTT updates the receiver RTT measurement based on the received
+// segment.
+func (r *receiver) updateRTT(s *segment) {
 	// From: https://public.lanl.gov/radiant/pubs/drs/sc2001-poster.pdf
 	//
 	// A system that is only transmitting acknowledgements can still
 	// estimate the round-trip time by observing the time between when a byte
 	// is first acknowledged and the receipt of data that is at least one
 	// window beyond the sequence number that was acknowledged.
+	//
+	// If timestamps are in use, the RTT can additionally be sampled from
+	// the TSEcr of full-sized segments, as in Linux's
+	// net/ipv4/tcp_input.c:tcp_rcv_rtt_measure_ts().
 	r.ep.rcvQueueMu.Lock()
-	if r.ep.RcvAutoParams.RTTMeasureTime == (tcpip.MonotonicTime{}) {
+	defer r.ep.rcvQueueMu.Unlock()
+	now := r.ep.stack.Clock().NowMonotonic()
+	params := &r.ep.RcvAutoParams
+	if r.ep.SendTSOk && s.parsedOptions.TSEcr != 0 && s.parsedOptions.TSEcr != params.RTTLastTSEcr && s.payloadSize() >= int(r.ep.amss) {
+		params.RTTLastTSEcr = s.parsedOptions.TSEcr
+		// Ignore bogus TSEcrs. Timestamps can't resolve sub-millisecond
+		// RTTs, which are rounded up to the timestamp granularity.
+		if rtt := r.ep.elapsed(now, s.parsedOptions.TSEcr); rtt <= MaxRTO {
+			updateRcvRTT(params, max(rtt, time.Millisecond), false /* windowBased */)
+		}
+	}
+	if params.RTTMeasureTime == (tcpip.MonotonicTime{}) {
 		// New measurement.
-		r.ep.RcvAutoParams.RTTMeasureTime = r.ep.stack.Clock().NowMonotonic()
-		r.ep.RcvAutoParams.RTTMeasureSeqNumber = r.RcvNxt.Add(r.rcvWnd)
-		r.ep.rcvQueueMu.Unlock()
+		params.RTTMeasureTime = now
+		params.RTTMeasureSeqNumber = r.RcvNxt.Add(r.rcvWnd)
 		return
 	}
-	if r.RcvNxt.LessThan(r.ep.RcvAutoParams.RTTMeasureSeqNumber) {
-		r.ep.rcvQueueMu.Unlock()
+	if r.RcvNxt.LessThan(params.RTTMeasureSeqNumber) {
 		return
 	}
-	rtt := r.ep.stack.Clock().NowMonotonic().Sub(r.ep.RcvAutoParams.RTTMeasureTime)
-	// We only store the minimum observed RTT here as this is only used in
-	// absence of a SRTT available from either timestamps or a sender
-	// measurement of RTT.
-	if r.ep.RcvAutoParams.RTT == 0 || rtt < r.ep.RcvAutoParams.RTT {
-		r.ep.RcvAutoParams.RTT = rtt
+	updateRcvRTT(params, now.Sub(params.RTTMeasureTime), true /* windowBased */)
+	params.RTTMeasureTime = now
+	params.RTTMeasureSeqNumber = r.RcvNxt.Add(r.rcvWnd)
+}
+
+// updateRcvRTT incorporates an RTT sample into params.RTT. Window-based
+// samples include the time taken by the sender to fill the window, so they
+// only ever lower the estimate; timestamp-based samples are smoothed. This
+// follows Linux's net/ipv4/tcp_input.c:tcp_rcv_rtt_update().
+func updateRcvRTT(params *stack.RcvBufAutoTuneParams, sample time.Duration, windowBased bool) {
+	switch {
+	case params.RTT == 0:
+		params.RTT = sample
+	case windowBased:
+		params.RTT = min(params.RTT, sample)
+	default:
+		params.RTT += (sample - params.RTT) / 8
 	}
-	r.ep.RcvAutoParams.RTTMeasureTime = r.ep.stack.Clock().NowMonotonic()
-	r.ep.RcvAutoParams.RTTMeasureSeqNumber = r.RcvNxt.Add(r.rcvWnd)
-	r.ep.rcvQueueMu.Unlock()
 }
 
 // +checklocks:r.ep.mu
@@ -475,6 +497,8 @@ func (r *receiver) handleRcvdSegment(s *segment) (drop bool, err tcpip.Error) {
 		}
 	}
 
+	r.ep.ecnSegmentReceived(s)
+
 	// Store the time of the last ack.
 	r.lastRcvdAckTime = r.ep.stack.Clock().NowMonotonic()
 
@@ -519,7 +543,7 @@ func (r *receiver) handleRcvdSegment(s *segment) (drop bool, err tcpip.Error) {
 	// Since we consumed a segment update the receiver's RTT estimate
 	// if required.
 	if segLen > 0 {
-		r.updateRTT()
+		r.updateRTT(s)
 	}
 
 	// By consuming the current segment, we may have filled a gap in the
@@ -0,0 +1,55 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcp
+
+import (
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+func TestUpdateRcvRTT(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		rtt         time.Duration
+		sample      time.Duration
+		windowBased bool
+		want        time.Duration
+	}{
+		{"first window sample", 0, 40 * time.Millisecond, true, 40 * time.Millisecond},
+		{"first timestamp sample", 0, 40 * time.Millisecond, false, 40 * time.Millisecond},
+		{"window sample lowers", 40 * time.Millisecond, 20 * time.Millisecond, true, 20 * time.Millisecond},
+		{"window sample doesn't raise", 40 * time.Millisecond, 80 * time.Millisecond, true, 40 * time.Millisecond},
+		{"timestamp sample raises", 40 * time.Millisecond, 120 * time.Millisecond, false, 50 * time.Millisecond},
+		{"timestamp sample lowers", 40 * time.Millisecond, 8 * time.Millisecond, false, 36 * time.Millisecond},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			params := stack.RcvBufAutoTuneParams{RTT: test.rtt}
+			updateRcvRTT(&params, test.sample, test.windowBased)
+			if params.RTT != test.want {
+				t.Errorf("got RTT %v, want %v", params.RTT, test.want)
+			}
+		})
+	}
+}
+
+func TestSpaceFromWnd(t *testing.T) {
+	for _, wnd := range []int{0, 1, 65535, 1 << 20} {
+		if got := wndFromSpace(spaceFromWnd(wnd)); got != wnd {
+			t.Errorf("wndFromSpace(spaceFromWnd(%d)) = %d, want %d", wnd, got, wnd)
+		}
+	}
+}
@@ -945,7 +945,7 @@ func (s *sender) sendZeroWindowProbe() {
 		Payload: buffer.MakeWithData(zeroProbeJunk),
 	})
 	defer pkt.DecRef()
-	s.sendSegmentFromPacketBuffer(pkt, header.TCPFlagAck, s.SndUna-1)
+	s.sendSegmentFromPacketBuffer(pkt, header.TCPFlagAck, s.SndUna-1, false /* ect */)
 
 	// Rearm the timer to continue probing.
 	s.resendTimer.enable(s.RTO)
@@ -1654,6 +1654,12 @@ func (s *sender) handleRcvdSegment(rcvdSeg *segment) {
 			s.resendTimer.disable()
 			s.probeTimer.disable()
 		}
+
+		// Respond to congestion experienced by the peer, unless loss
+		// recovery already reduced the window.
+		if s.ep.ecn.enabled && rcvdSeg.flags.Contains(header.TCPFlagEce) && !s.FastRecovery.Active {
+			s.handleECNEcho(ack)
+		}
 	}
 
 	if s.ep.SACKPermitted && s.ep.tcpRecovery&tcpip.TCPRACKLossDetection != 0 {
@@ -1713,7 +1719,16 @@ func (s *sender) sendSegment(seg *segment) tcpip.Error {
 	seg.xmitCount++
 	seg.lost = false
 
-	err := s.sendSegmentFromPacketBuffer(seg.pkt, seg.flags, seg.sequenceNumber)
+	// Only the first transmission of new data is ECN-capable. See RFC 3168
+	// section 6.1.5.
+	flags := seg.flags
+	ect := s.ep.ecn.enabled && seg.payloadSize() != 0 && seg.xmitCount == 1
+	if ect && s.ep.ecn.sendCWR {
+		flags |= header.TCPFlagCwr
+		s.ep.ecn.sendCWR = false
+	}
+
+	err := s.sendSegmentFromPacketBuffer(seg.pkt, flags, seg.sequenceNumber, ect)
 
 	// Every time a packet containing data is sent (including a
 	// retransmission), if SACK is enabled and we are retransmitting data
@@ -1733,10 +1748,10 @@ func (s *sender) sendSegment(seg *segment) tcpip.Error {
 }
 
 // sendSegmentFromPacketBuffer sends a new segment containing the given payload,
-// flags and sequence number.
+// flags and sequence number, marked as ECN-capable if ect is true.
 // +checklocks:s.ep.mu
 // +checklocksalias:s.ep.rcv.ep.mu=s.ep.mu
-func (s *sender) sendSegmentFromPacketBuffer(pkt *stack.PacketBuffer, flags header.TCPFlags, seq seqnum.Value) tcpip.Error {
+func (s *sender) sendSegmentFromPacketBuffer(pkt *stack.PacketBuffer, flags header.TCPFlags, seq seqnum.Value, ect bool) tcpip.Error {
 	s.LastSendTime = s.ep.stack.Clock().NowMonotonic()
 	if seq == s.RTTMeasureSeqNum {
 		s.RTTMeasureTime = s.LastSendTime
@@ -1752,7 +1767,7 @@ func (s *sender) sendSegmentFromPacketBuffer(pkt *stack.PacketBuffer, flags head
 	pkt = pkt.Clone()
 	defer pkt.DecRef()
 
-	return s.ep.sendRaw(pkt, flags, seq, rcvNxt, rcvWnd)
+	return s.ep.sendRaw(pkt, flags, seq, rcvNxt, rcvWnd, ect)
 }
 
 // sendEmptySegment sends a new empty segment, flags and sequence number.
@@ -3948,6 +3948,124 @@ func TestSynOptionsOnActiveConnect(t *testing.T) {
 	}
 }
 
+func TestECN(t *testing.T) {
+	c := context.New(t, e2e.DefaultMTU)
+	defer c.Cleanup()
+
+	opt := tcpip.TCPECNEnabled
+	if err := c.Stack().SetTransportProtocolOption(tcp.ProtocolNumber, &opt); err != nil {
+		t.Fatalf("SetTransportProtocolOption(%d, &%d): %s", tcp.ProtocolNumber, opt, err)
+	}
+
+	var err tcpip.Error
+	c.EP, err = c.Stack().NewEndpoint(tcp.ProtocolNumber, ipv4.ProtocolNumber, &c.WQ)
+	if err != nil {
+		t.Fatalf("NewEndpoint failed: %s", err)
+	}
+	we, ch := waiter.NewChannelEntry(waiter.WritableEvents)
+	c.WQ.EventRegister(&we)
+	defer c.WQ.EventUnregister(&we)
+	if err := c.EP.Connect(tcpip.FullAddress{Addr: context.TestAddr, Port: context.TestPort}); cmp.Diff(&tcpip.ErrConnectStarted{}, err) != "" {
+		t.Fatalf("Connect(...) = %v, want = %s", err, &tcpip.ErrConnectStarted{})
+	}
+
+	// The SYN requests ECN.
+	b := c.GetPacket()
+	defer b.Release()
+	checker.IPv4(t, b, checker.TCP(checker.TCPFlags(header.TCPFlagSyn|header.TCPFlagEce|header.TCPFlagCwr)))
+	tcpHdr := header.TCP(header.IPv4(b.AsSlice()).Payload())
+	c.IRS = seqnum.Value(tcpHdr.SequenceNumber())
+	c.Port = tcpHdr.SourcePort()
+
+	iss := seqnum.Value(context.TestInitialSequenceNumber)
+	c.SendPacket(nil, &context.Headers{
+		SrcPort: context.TestPort,
+		DstPort: c.Port,
+		Flags:   header.TCPFlagSyn | header.TCPFlagAck | header.TCPFlagEce,
+		SeqNum:  iss,
+		AckNum:  c.IRS.Add(1),
+		RcvWnd:  30000,
+	})
+	v := c.GetPacket()
+	defer v.Release()
+	checker.IPv4(t, v, checker.TCP(checker.TCPFlags(header.TCPFlagAck)))
+	select {
+	case <-ch:
+		if err := c.EP.LastError(); err != nil {
+			t.Fatalf("Connect failed: %s", err)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatalf("Timed out waiting for connection")
+	}
+
+	write := func(data []byte) {
+		t.Helper()
+		var r bytes.Reader
+		r.Reset(data)
+		if _, err := c.EP.Write(&r, tcpip.WriteOptions{}); err != nil {
+			t.Fatalf("Write failed: %s", err)
+		}
+	}
+
+	// New data is sent as ECN-capable.
+	data := []byte{1, 2, 3}
+	write(data)
+	v = c.GetPacket()
+	defer v.Release()
+	checker.IPv4(t, v,
+		checker.TOS(2 /* ECT(0) */, 0),
+		checker.TCP(checker.TCPFlags(header.TCPFlagAck|header.TCPFlagPsh)),
+	)
+
+	// The window is reduced in response to ECE, which is acknowledged with
+	// CWR on the next new data.
+	c.SendPacket(nil, &context.Headers{
+		SrcPort: context.TestPort,
+		DstPort: c.Port,
+		Flags:   header.TCPFlagAck | header.TCPFlagEce,
+		SeqNum:  iss.Add(1),
+		AckNum:  c.IRS.Add(1 + seqnum.Size(len(data))),
+		RcvWnd:  30000,
+	})
+	write(data)
+	v = c.GetPacket()
+	defer v.Release()
+	checker.IPv4(t, v, checker.TCP(checker.TCPFlags(header.TCPFlagAck|header.TCPFlagPsh|header.TCPFlagCwr)))
+	if got := c.Stack().Stats().TCP.ECNReductions.Value(); got != 1 {
+		t.Errorf("got stats.TCP.ECNReductions = %d, want = 1", got)
+	}
+
+	// Congestion experienced by received data is echoed until the peer
+	// sends CWR.
+	sendCE := func(seq seqnum.Value, flags header.TCPFlags, tos uint8) {
+		t.Helper()
+		c.SendPacket(data, &context.Headers{
+			SrcPort: context.TestPort,
+			DstPort: c.Port,
+			Flags:   flags,
+			SeqNum:  seq,
+			AckNum:  c.IRS.Add(1 + seqnum.Size(2*len(data))),
+			RcvWnd:  30000,
+			TOS:     tos,
+		})
+	}
+	sendCE(iss.Add(1), header.TCPFlagAck|header.TCPFlagPsh, 3 /* CE */)
+	v = c.GetPacket()
+	defer v.Release()
+	checker.IPv4(t, v, checker.TCP(
+		checker.TCPAckNum(uint32(iss)+1+uint32(len(data))),
+		checker.TCPFlags(header.TCPFlagAck|header.TCPFlagEce),
+	))
+
+	sendCE(iss.Add(1+seqnum.Size(len(data))), header.TCPFlagAck|header.TCPFlagPsh|header.TCPFlagCwr, 2 /* ECT(0) */)
+	v = c.GetPacket()
+	defer v.Release()
+	checker.IPv4(t, v, checker.TCP(
+		checker.TCPAckNum(uint32(iss)+1+uint32(2*len(data))),
+		checker.TCPFlags(header.TCPFlagAck),
+	))
+}
+
 func TestCloseListener(t *testing.T) {
 	c := context.New(t, e2e.DefaultMTU)
 	defer c.Cleanup()
@@ -114,6 +114,9 @@ type Headers struct {
 	// TCPOpts holds the options to be sent in the option field of the TCP
 	// header.
 	TCPOpts []byte
+
+	// TOS is the value of the type of service field in the IPv4 header.
+	TOS uint8
 }
 
 // Options contains options for creating a new test context.
@@ -462,6 +465,7 @@ func (c *Context) BuildSegmentWithAddrs(payload []byte, h *Headers, src, dst tcp
 	// Initialize the IP header.
 	ip := header.IPv4(buf)
 	ip.Encode(&header.IPv4Fields{
+		TOS:         h.TOS,
 		TotalLength: uint16(len(buf)),
 		TTL:         65,
 		Protocol:    uint8(tcp.ProtocolNumber),
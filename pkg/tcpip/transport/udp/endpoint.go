@@ -21,6 +21,7 @@ import (
 	"math"
 	"time"
 
+	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/tcpip"
 	"gvisor.dev/gvisor/pkg/tcpip/checksum"
@@ -46,6 +47,8 @@ type udpPacket struct {
 	tosOrTClass uint8
 	// ttlOrHopLimit stores either the TTL for IPv4 or the HopLimit for IPv6
 	ttlOrHopLimit uint8
+	// flowLabel stores the Flow Label for IPv6.
+	flowLabel uint32
 }
 
 // endpoint represents a UDP endpoint. This struct serves as the interface
@@ -68,6 +71,14 @@ type endpoint struct {
 	stats       tcpip.TransportEndpointStats
 	ops         tcpip.SocketOptions
 
+	// transProto is either UDP or UDP-Lite.
+	transProto tcpip.TransportProtocolNumber
+
+	// sndCoverage and rcvCoverage hold the UDP-Lite checksum coverage
+	// options. rcvCoverage is negative if it was never set.
+	sndCoverage atomicbitops.Int32
+	rcvCoverage atomicbitops.Int32
+
 	// The following fields are used to manage the receive queue, and are
 	// protected by rcvMu.
 	rcvMu      sync.Mutex `state:"nosave"`
@@ -106,17 +117,19 @@ type endpoint struct {
 	remotePort uint16
 }
 
-func newEndpoint(s *stack.Stack, netProto tcpip.NetworkProtocolNumber, waiterQueue *waiter.Queue) *endpoint {
+func newEndpoint(s *stack.Stack, transProto tcpip.TransportProtocolNumber, netProto tcpip.NetworkProtocolNumber, waiterQueue *waiter.Queue) *endpoint {
 	e := &endpoint{
 		stack:       s,
 		waiterQueue: waiterQueue,
 		uniqueID:    s.UniqueID(),
+		transProto:  transProto,
+		rcvCoverage: atomicbitops.FromInt32(-1),
 	}
 	e.ops.InitHandler(e, e.stack, tcpip.GetStackSendBufferLimits, tcpip.GetStackReceiveBufferLimits)
 	e.ops.SetMulticastLoop(true)
 	e.ops.SetSendBufferSize(32*1024, false /* notify */)
 	e.ops.SetReceiveBufferSize(32*1024, false /* notify */)
-	e.net.Init(s, netProto, header.UDPProtocolNumber, &e.ops, waiterQueue)
+	e.net.Init(s, netProto, transProto, &e.ops, waiterQueue)
 
 	// Override with stack defaults.
 	var ss tcpip.SendBufferSizeOption
@@ -177,10 +190,10 @@ func (e *endpoint) Close() {
 		id := e.net.Info().ID
 		id.LocalPort = e.localPort
 		id.RemotePort = e.remotePort
-		e.stack.UnregisterTransportEndpoint(e.effectiveNetProtos, ProtocolNumber, id, e, e.boundPortFlags, e.boundBindToDevice)
+		e.stack.UnregisterTransportEndpoint(e.effectiveNetProtos, e.transProto, id, e, e.boundPortFlags, e.boundBindToDevice)
 		portRes := ports.Reservation{
 			Networks:     e.effectiveNetProtos,
-			Transport:    ProtocolNumber,
+			Transport:    e.transProto,
 			Addr:         id.LocalAddress,
 			Port:         id.LocalPort,
 			Flags:        e.boundPortFlags,
@@ -242,6 +255,72 @@ func (e *endpoint) Read(dst io.Writer, opts tcpip.ReadOptions) (tcpip.ReadResult
 	}
 	e.rcvMu.Unlock()
 
+	return e.readPacket(p, dst, opts)
+}
+
+var _ tcpip.EndpointWithBatchRead = (*endpoint)(nil)
+
+// ReadBatch implements tcpip.EndpointWithBatchRead.
+func (e *endpoint) ReadBatch(dsts []io.Writer, opts tcpip.ReadOptions) ([]tcpip.ReadResult, tcpip.Error) {
+	if opts.Peek {
+		return nil, &tcpip.ErrInvalidOptionValue{}
+	}
+	if err := e.LastError(); err != nil {
+		return nil, err
+	}
+
+	e.rcvMu.Lock()
+	if e.rcvList.Empty() {
+		var err tcpip.Error = &tcpip.ErrWouldBlock{}
+		if e.rcvClosed {
+			e.stats.ReadErrors.ReadClosed.Increment()
+			err = &tcpip.ErrClosedForReceive{}
+		}
+		e.rcvMu.Unlock()
+		return nil, err
+	}
+	ps := make([]*udpPacket, 0, len(dsts))
+	for len(ps) < len(dsts) && !e.rcvList.Empty() {
+		p := e.rcvList.Front()
+		e.rcvList.Remove(p)
+		e.rcvBufSize -= p.pkt.Data().Size()
+		ps = append(ps, p)
+	}
+	e.rcvMu.Unlock()
+
+	results := make([]tcpip.ReadResult, 0, len(ps))
+	for i, p := range ps {
+		res, err := e.readPacket(p, dsts[i], opts)
+		if err != nil {
+			if i == 0 {
+				p.pkt.DecRef()
+				return nil, err
+			}
+			// Return the datagrams read so far, and leave the rest to be read
+			// by the next call.
+			e.requeue(ps[i:])
+			break
+		}
+		p.pkt.DecRef()
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// requeue puts back dequeued packets at the front of the receive queue, in
+// order.
+func (e *endpoint) requeue(ps []*udpPacket) {
+	e.rcvMu.Lock()
+	defer e.rcvMu.Unlock()
+	for i := len(ps) - 1; i >= 0; i-- {
+		e.rcvList.PushFront(ps[i])
+		e.rcvBufSize += ps[i].pkt.Data().Size()
+	}
+}
+
+// readPacket writes the payload of a dequeued (or peeked) packet to dst and
+// returns the corresponding read result.
+func (e *endpoint) readPacket(p *udpPacket, dst io.Writer, opts tcpip.ReadOptions) (tcpip.ReadResult, tcpip.Error) {
 	// Control Messages
 	// TODO(https://gvisor.dev/issue/7012): Share control message code with other
 	// network endpoints.
@@ -273,6 +352,10 @@ func (e *endpoint) Read(dst io.Writer, opts tcpip.ReadOptions) (tcpip.ReadResult
 			cm.HasHopLimit = true
 			cm.HopLimit = p.ttlOrHopLimit
 		}
+		if e.ops.GetReceiveFlowInfo() {
+			cm.HasFlowInfo = true
+			cm.FlowInfo = uint32(p.tosOrTClass)<<20 | p.flowLabel
+		}
 		if e.ops.GetIPv6ReceivePacketInfo() {
 			cm.HasIPv6PacketInfo = true
 			cm.IPv6PacketInfo = tcpip.IPv6PacketInfo{
@@ -363,6 +446,45 @@ func (e *endpoint) Preflight(opts tcpip.WriteOptions) tcpip.Error {
 // if the data cannot be written.
 func (e *endpoint) Write(p tcpip.Payloader, opts tcpip.WriteOptions) (int64, tcpip.Error) {
 	n, err := e.write(p, opts)
+	e.updateWriteStats(err)
+	return n, err
+}
+
+var _ tcpip.EndpointWithBatchWrite = (*endpoint)(nil)
+
+// WriteBatch implements tcpip.EndpointWithBatchWrite.
+func (e *endpoint) WriteBatch(ps []tcpip.Payloader, opts tcpip.WriteOptions) (int, tcpip.Error) {
+	if len(ps) == 0 {
+		return 0, nil
+	}
+	if err := e.LastError(); err != nil {
+		e.updateWriteStats(err)
+		return 0, err
+	}
+
+	udpInfo, err := e.prepareForWrite(ps[0], opts)
+	if err != nil {
+		e.updateWriteStats(err)
+		return 0, err
+	}
+	defer udpInfo.ctx.Release()
+
+	for i, p := range ps {
+		if p.Len() > header.UDPMaximumPacketSize {
+			err = &tcpip.ErrMessageTooLong{}
+		} else {
+			_, err = e.writePacket(udpInfo, p)
+		}
+		e.updateWriteStats(err)
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(ps), nil
+}
+
+// updateWriteStats accounts for the outcome of writing a datagram.
+func (e *endpoint) updateWriteStats(err tcpip.Error) {
 	switch err.(type) {
 	case nil:
 		e.stats.PacketsSent.Increment()
@@ -379,7 +501,6 @@ func (e *endpoint) Write(p tcpip.Payloader, opts tcpip.WriteOptions) (int64, tcp
 		// For all other errors when writing to the network layer.
 		e.stats.SendErrors.SendToNetworkFailed.Increment()
 	}
-	return n, err
 }
 
 func (e *endpoint) prepareForWrite(p tcpip.Payloader, opts tcpip.WriteOptions) (udpPacketInfo, tcpip.Error) {
@@ -464,6 +585,12 @@ func (e *endpoint) write(p tcpip.Payloader, opts tcpip.WriteOptions) (int64, tcp
 	}
 	defer udpInfo.ctx.Release()
 
+	return e.writePacket(udpInfo, p)
+}
+
+// writePacket builds a datagram with the payload p and sends it using the
+// prepared write context.
+func (e *endpoint) writePacket(udpInfo udpPacketInfo, p tcpip.Payloader) (int64, tcpip.Error) {
 	dataSz := p.Len()
 	pktInfo := udpInfo.ctx.PacketInfo()
 	pkt := udpInfo.ctx.TryNewPacketBufferFromPayloader(header.UDPMinimumSize+int(pktInfo.MaxHeaderLength), p)
@@ -474,7 +601,7 @@ func (e *endpoint) write(p tcpip.Payloader, opts tcpip.WriteOptions) (int64, tcp
 
 	// Initialize the UDP header.
 	udp := header.UDP(pkt.TransportHeader().Push(header.UDPMinimumSize))
-	pkt.TransportProtocolNumber = ProtocolNumber
+	pkt.TransportProtocolNumber = e.transProto
 
 	length := uint16(pkt.Size())
 	udp.Encode(&header.UDPFields{
@@ -483,14 +610,16 @@ func (e *endpoint) write(p tcpip.Payloader, opts tcpip.WriteOptions) (int64, tcp
 		Length:  length,
 	})
 
-	// Set the checksum field unless TX checksum offload is enabled.
-	// On IPv4, UDP checksum is optional, and a zero value indicates the
-	// transmitter skipped the checksum generation (RFC768).
-	// On IPv6, UDP checksum is not optional (RFC2460 Section 8.1).
-	if pktInfo.RequiresTXTransportChecksum &&
+	if e.transProto == header.UDPLiteProtocolNumber {
+		e.setUDPLiteChecksum(pkt, udp, length, pktInfo.LocalAddress, pktInfo.RemoteAddress)
+	} else if pktInfo.RequiresTXTransportChecksum &&
 		(!e.ops.GetNoChecksum() || pktInfo.NetProto == header.IPv6ProtocolNumber) {
+		// Set the checksum field unless TX checksum offload is enabled.
+		// On IPv4, UDP checksum is optional, and a zero value indicates the
+		// transmitter skipped the checksum generation (RFC768).
+		// On IPv6, UDP checksum is not optional (RFC2460 Section 8.1).
 		xsum := udp.CalculateChecksum(checksum.Combine(
-			header.PseudoHeaderChecksum(ProtocolNumber, pktInfo.LocalAddress, pktInfo.RemoteAddress, length),
+			header.PseudoHeaderChecksum(e.transProto, pktInfo.LocalAddress, pktInfo.RemoteAddress, length),
 			pkt.Data().Checksum(),
 		))
 		// As per RFC 768 page 2,
@@ -518,15 +647,44 @@ func (e *endpoint) write(p tcpip.Payloader, opts tcpip.WriteOptions) (int64, tcp
 		udp.SetChecksum(xsum)
 	}
 	if err := udpInfo.ctx.WritePacket(pkt, false /* headerIncluded */); err != nil {
-		e.stack.Stats().UDP.PacketSendErrors.Increment()
+		e.protocolStats().PacketSendErrors.Increment()
 		return 0, err
 	}
 
 	// Track count of packets sent.
-	e.stack.Stats().UDP.PacketsSent.Increment()
+	e.protocolStats().PacketsSent.Increment()
 	return int64(dataSz), nil
 }
 
+// setUDPLiteChecksum sets the checksum coverage and the checksum of a
+// UDP-Lite packet of the given length. The checksum is mandatory for UDP-Lite
+// and can't be offloaded, as it may not cover the entire packet. See RFC 3828
+// section 3.1.
+func (e *endpoint) setUDPLiteChecksum(pkt *stack.PacketBuffer, udp header.UDP, length uint16, src, dst tcpip.Address) {
+	coverage := length
+	if v := uint16(e.sndCoverage.Load()); v != 0 && v < length {
+		coverage = v
+	}
+	udp.SetLength(coverage)
+	xsum := udp.CalculateChecksum(checksum.Combine(
+		header.PseudoHeaderChecksum(header.UDPLiteProtocolNumber, src, dst, length),
+		pkt.Data().AsRange().Capped(int(coverage-header.UDPMinimumSize)).Checksum(),
+	))
+	// A zero checksum is transmitted as all ones, as for UDP.
+	if xsum != math.MaxUint16 {
+		xsum = ^xsum
+	}
+	udp.SetChecksum(xsum)
+}
+
+// protocolStats returns the stack-wide stats of the endpoint's protocol.
+func (e *endpoint) protocolStats() tcpip.UDPStats {
+	if e.transProto == header.UDPLiteProtocolNumber {
+		return e.stack.Stats().UDPLite
+	}
+	return e.stack.Stats().UDP
+}
+
 // OnReuseAddressSet implements tcpip.SocketOptionsHandler.
 func (e *endpoint) OnReuseAddressSet(v bool) {
 	e.mu.Lock()
@@ -543,7 +701,28 @@ func (e *endpoint) OnReusePortSet(v bool) {
 
 // SetSockOptInt implements tcpip.Endpoint.
 func (e *endpoint) SetSockOptInt(opt tcpip.SockOptInt, v int) tcpip.Error {
-	return e.net.SetSockOptInt(opt, v)
+	switch opt {
+	case tcpip.UDPLiteSendCoverageOption, tcpip.UDPLiteReceiveCoverageOption:
+		if e.transProto != header.UDPLiteProtocolNumber {
+			return &tcpip.ErrUnknownProtocolOption{}
+		}
+		// Like Linux, coverages smaller than the header are raised to the
+		// header size and larger ones are capped to the maximum packet size.
+		if v != 0 && v < header.UDPMinimumSize {
+			v = header.UDPMinimumSize
+		} else if v > header.UDPMaximumSize {
+			v = header.UDPMaximumSize
+		}
+		if opt == tcpip.UDPLiteSendCoverageOption {
+			e.sndCoverage.Store(int32(v))
+		} else {
+			e.rcvCoverage.Store(int32(v))
+		}
+		return nil
+
+	default:
+		return e.net.SetSockOptInt(opt, v)
+	}
 }
 
 var _ tcpip.SocketOptionsHandler = (*endpoint)(nil)
@@ -571,6 +750,18 @@ func (e *endpoint) GetSockOptInt(opt tcpip.SockOptInt) (int, tcpip.Error) {
 		e.rcvMu.Unlock()
 		return v, nil
 
+	case tcpip.UDPLiteSendCoverageOption:
+		if e.transProto != header.UDPLiteProtocolNumber {
+			return -1, &tcpip.ErrUnknownProtocolOption{}
+		}
+		return int(e.sndCoverage.Load()), nil
+
+	case tcpip.UDPLiteReceiveCoverageOption:
+		if e.transProto != header.UDPLiteProtocolNumber {
+			return -1, &tcpip.ErrUnknownProtocolOption{}
+		}
+		return max(int(e.rcvCoverage.Load()), 0), nil
+
 	default:
 		return e.net.GetSockOptInt(opt)
 	}
@@ -625,7 +816,7 @@ func (e *endpoint) Disconnect() tcpip.Error {
 			// Release the ephemeral port.
 			portRes := ports.Reservation{
 				Networks:     e.effectiveNetProtos,
-				Transport:    ProtocolNumber,
+				Transport:    e.transProto,
 				Addr:         info.ID.LocalAddress,
 				Port:         info.ID.LocalPort,
 				Flags:        boundPortFlags,
@@ -637,7 +828,7 @@ func (e *endpoint) Disconnect() tcpip.Error {
 		}
 	}
 
-	e.stack.UnregisterTransportEndpoint(e.effectiveNetProtos, ProtocolNumber, info.ID, e, boundPortFlags, e.boundBindToDevice)
+	e.stack.UnregisterTransportEndpoint(e.effectiveNetProtos, e.transProto, info.ID, e, boundPortFlags, e.boundBindToDevice)
 	e.boundBindToDevice = btd
 	e.localPort = id.LocalPort
 	e.remotePort = id.RemotePort
@@ -673,7 +864,7 @@ func (e *endpoint) Connect(addr tcpip.FullAddress) tcpip.Error {
 		if e.localPort != 0 {
 			previousID.LocalPort = e.localPort
 			previousID.RemotePort = e.remotePort
-			e.stack.UnregisterTransportEndpoint(e.effectiveNetProtos, ProtocolNumber, previousID, e, oldPortFlags, e.boundBindToDevice)
+			e.stack.UnregisterTransportEndpoint(e.effectiveNetProtos, e.transProto, previousID, e, oldPortFlags, e.boundBindToDevice)
 		}
 
 		nextID, btd, err := e.registerWithStack(netProtos, nextID)
@@ -756,7 +947,7 @@ func (e *endpoint) registerWithStack(netProtos []tcpip.NetworkProtocolNumber, id
 	if e.localPort == 0 {
 		portRes := ports.Reservation{
 			Networks:     netProtos,
-			Transport:    ProtocolNumber,
+			Transport:    e.transProto,
 			Addr:         id.LocalAddress,
 			Port:         id.LocalPort,
 			Flags:        e.portFlags,
@@ -771,11 +962,11 @@ func (e *endpoint) registerWithStack(netProtos []tcpip.NetworkProtocolNumber, id
 	}
 	e.boundPortFlags = e.portFlags
 
-	err := e.stack.RegisterTransportEndpoint(netProtos, ProtocolNumber, id, e, e.boundPortFlags, bindToDevice)
+	err := e.stack.RegisterTransportEndpoint(netProtos, e.transProto, id, e, e.boundPortFlags, bindToDevice)
 	if err != nil {
 		portRes := ports.Reservation{
 			Networks:     netProtos,
-			Transport:    ProtocolNumber,
+			Transport:    e.transProto,
 			Addr:         id.LocalAddress,
 			Port:         id.LocalPort,
 			Flags:        e.boundPortFlags,
@@ -896,13 +1087,21 @@ func (e *endpoint) Readiness(mask waiter.EventMask) waiter.EventMask {
 	return result
 }
 
-// HandlePacket is called by the stack when new packets arrive to this transport
-// endpoint.
-func (e *endpoint) HandlePacket(id stack.TransportEndpointID, pkt *stack.PacketBuffer) {
-	// Get the header then trim it from the view.
+// validatePacket validates the length and the checksum of a UDP or UDP-Lite
+// packet. For UDP-Lite packets, it also returns the checksum coverage.
+func validatePacket(transProto tcpip.TransportProtocolNumber, pkt *stack.PacketBuffer) (coverage uint16, lengthValid, csumValid bool) {
 	hdr := header.UDP(pkt.TransportHeader().Slice())
 	netHdr := pkt.Network()
-	lengthValid, csumValid := header.UDPValid(
+	if transProto == header.UDPLiteProtocolNumber {
+		return header.UDPLiteValid(
+			hdr,
+			func(covered int) uint16 { return pkt.Data().AsRange().Capped(covered).Checksum() },
+			uint16(pkt.Data().Size()),
+			netHdr.SourceAddress(),
+			netHdr.DestinationAddress(),
+			pkt.RXChecksumValidated)
+	}
+	lengthValid, csumValid = header.UDPValid(
 		hdr,
 		func() uint16 { return pkt.Data().Checksum() },
 		uint16(pkt.Data().Size()),
@@ -910,27 +1109,51 @@ func (e *endpoint) HandlePacket(id stack.TransportEndpointID, pkt *stack.PacketB
 		netHdr.SourceAddress(),
 		netHdr.DestinationAddress(),
 		pkt.RXChecksumValidated)
-	if !lengthValid {
+	return hdr.Length(), lengthValid, csumValid
+}
+
+// coverageAcceptable returns whether a UDP-Lite packet with the given checksum
+// coverage and payload size satisfies the minimum coverage requested with
+// UDPLiteReceiveCoverageOption. It is always true for UDP.
+func (e *endpoint) coverageAcceptable(coverage uint16, payloadSize int) bool {
+	minCoverage := e.rcvCoverage.Load()
+	if e.transProto != header.UDPLiteProtocolNumber || minCoverage < 0 {
+		return true
+	}
+	if int(coverage) == payloadSize+header.UDPMinimumSize {
+		return true
+	}
+	// A minimum coverage of zero requires full coverage.
+	return minCoverage != 0 && int32(coverage) >= minCoverage
+}
+
+// HandlePacket is called by the stack when new packets arrive to this transport
+// endpoint.
+func (e *endpoint) HandlePacket(id stack.TransportEndpointID, pkt *stack.PacketBuffer) {
+	// Get the header then trim it from the view.
+	hdr := header.UDP(pkt.TransportHeader().Slice())
+	coverage, lengthValid, csumValid := validatePacket(e.transProto, pkt)
+	if !lengthValid || !e.coverageAcceptable(coverage, pkt.Data().Size()) {
 		// Malformed packet.
-		e.stack.Stats().UDP.MalformedPacketsReceived.Increment()
+		e.protocolStats().MalformedPacketsReceived.Increment()
 		e.stats.ReceiveErrors.MalformedPacketsReceived.Increment()
 		return
 	}
 
 	if !csumValid {
-		e.stack.Stats().UDP.ChecksumErrors.Increment()
+		e.protocolStats().ChecksumErrors.Increment()
 		e.stats.ReceiveErrors.ChecksumErrors.Increment()
 		return
 	}
 
-	e.stack.Stats().UDP.PacketsReceived.Increment()
+	e.protocolStats().PacketsReceived.Increment()
 	e.stats.PacketsReceived.Increment()
 
 	e.rcvMu.Lock()
 	// Drop the packet if our buffer is not ready to receive packets.
 	if !e.rcvReady || e.rcvClosed {
 		e.rcvMu.Unlock()
-		e.stack.Stats().UDP.ReceiveBufferErrors.Increment()
+		e.protocolStats().ReceiveBufferErrors.Increment()
 		e.stats.ReceiveErrors.ClosedReceiver.Increment()
 		return
 	}
@@ -939,7 +1162,7 @@ func (e *endpoint) HandlePacket(id stack.TransportEndpointID, pkt *stack.PacketB
 	// Drop the packet if our buffer is currently full.
 	if e.frozen || e.rcvBufSize >= int(rcvBufSize) {
 		e.rcvMu.Unlock()
-		e.stack.Stats().UDP.ReceiveBufferErrors.Increment()
+		e.protocolStats().ReceiveBufferErrors.Increment()
 		e.stats.ReceiveErrors.ReceiveBufferOverflow.Increment()
 		return
 	}
@@ -965,7 +1188,7 @@ func (e *endpoint) HandlePacket(id stack.TransportEndpointID, pkt *stack.PacketB
 	e.rcvBufSize += pkt.Data().Size()
 
 	// Save any useful information from the network header to the packet.
-	packet.tosOrTClass, _ = pkt.Network().TOS()
+	packet.tosOrTClass, packet.flowLabel = pkt.Network().TOS()
 	switch pkt.NetworkProtocolNumber {
 	case header.IPv4ProtocolNumber:
 		packet.ttlOrHopLimit = header.IPv4(pkt.NetworkHeader().Slice()).TTL()
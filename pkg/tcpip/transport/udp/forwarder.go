@@ -70,7 +70,7 @@ func (r *ForwarderRequest) ID() stack.TransportEndpointID {
 
 // CreateEndpoint creates a connected UDP endpoint for the session request.
 func (r *ForwarderRequest) CreateEndpoint(queue *waiter.Queue) (tcpip.Endpoint, tcpip.Error) {
-	ep := newEndpoint(r.stack, r.pkt.NetworkProtocolNumber, queue)
+	ep := newEndpoint(r.stack, ProtocolNumber, r.pkt.NetworkProtocolNumber, queue)
 	ep.mu.Lock()
 	defer ep.mu.Unlock()
 
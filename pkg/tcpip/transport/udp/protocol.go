@@ -12,7 +12,8 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package udp contains the implementation of the UDP transport protocol.
+// Package udp contains the implementation of the UDP and UDP-Lite transport
+// protocols.
 package udp
 
 import (
@@ -28,6 +29,9 @@ const (
 	// ProtocolNumber is the udp protocol number.
 	ProtocolNumber = header.UDPProtocolNumber
 
+	// LiteProtocolNumber is the UDP-Lite protocol number.
+	LiteProtocolNumber = header.UDPLiteProtocolNumber
+
 	// MinBufferSize is the smallest size of a receive or send buffer.
 	MinBufferSize = 4 << 10 // 4KiB bytes.
 
@@ -46,22 +50,25 @@ const (
 // +stateify savable
 type protocol struct {
 	stack *stack.Stack
+
+	// number is either ProtocolNumber or LiteProtocolNumber.
+	number tcpip.TransportProtocolNumber
 }
 
-// Number returns the udp protocol number.
-func (*protocol) Number() tcpip.TransportProtocolNumber {
-	return ProtocolNumber
+// Number returns the udp or UDP-Lite protocol number.
+func (p *protocol) Number() tcpip.TransportProtocolNumber {
+	return p.number
 }
 
 // NewEndpoint creates a new udp endpoint.
 func (p *protocol) NewEndpoint(netProto tcpip.NetworkProtocolNumber, waiterQueue *waiter.Queue) (tcpip.Endpoint, tcpip.Error) {
-	return newEndpoint(p.stack, netProto, waiterQueue), nil
+	return newEndpoint(p.stack, p.number, netProto, waiterQueue), nil
 }
 
 // NewRawEndpoint creates a new raw UDP endpoint. It implements
 // stack.TransportProtocol.NewRawEndpoint.
 func (p *protocol) NewRawEndpoint(netProto tcpip.NetworkProtocolNumber, waiterQueue *waiter.Queue) (tcpip.Endpoint, tcpip.Error) {
-	return raw.NewEndpoint(p.stack, netProto, header.UDPProtocolNumber, waiterQueue)
+	return raw.NewEndpoint(p.stack, netProto, p.number, waiterQueue)
 }
 
 // MinimumPacketSize returns the minimum valid udp packet size.
@@ -79,23 +86,18 @@ func (*protocol) ParsePorts(v []byte) (src, dst uint16, err tcpip.Error) {
 // HandleUnknownDestinationPacket handles packets that are targeted at this
 // protocol but don't match any existing endpoint.
 func (p *protocol) HandleUnknownDestinationPacket(id stack.TransportEndpointID, pkt *stack.PacketBuffer) stack.UnknownDestinationPacketDisposition {
-	hdr := header.UDP(pkt.TransportHeader().Slice())
-	netHdr := pkt.Network()
-	lengthValid, csumValid := header.UDPValid(
-		hdr,
-		func() uint16 { return pkt.Data().Checksum() },
-		uint16(pkt.Data().Size()),
-		pkt.NetworkProtocolNumber,
-		netHdr.SourceAddress(),
-		netHdr.DestinationAddress(),
-		pkt.RXChecksumValidated)
+	stats := p.stack.Stats().UDP
+	if p.number == LiteProtocolNumber {
+		stats = p.stack.Stats().UDPLite
+	}
+	_, lengthValid, csumValid := validatePacket(p.number, pkt)
 	if !lengthValid {
-		p.stack.Stats().UDP.MalformedPacketsReceived.Increment()
+		stats.MalformedPacketsReceived.Increment()
 		return stack.UnknownDestinationPacketMalformed
 	}
 
 	if !csumValid {
-		p.stack.Stats().UDP.ChecksumErrors.Increment()
+		stats.ChecksumErrors.Increment()
 		return stack.UnknownDestinationPacketMalformed
 	}
 
@@ -125,11 +127,20 @@ func (*protocol) Pause() {}
 func (*protocol) Resume() {}
 
 // Parse implements stack.TransportProtocol.Parse.
-func (*protocol) Parse(pkt *stack.PacketBuffer) bool {
+func (p *protocol) Parse(pkt *stack.PacketBuffer) bool {
+	if p.number == LiteProtocolNumber {
+		return parse.UDPLite(pkt)
+	}
 	return parse.UDP(pkt)
 }
 
 // NewProtocol returns a UDP transport protocol.
 func NewProtocol(s *stack.Stack) stack.TransportProtocol {
-	return &protocol{stack: s}
+	return &protocol{stack: s, number: ProtocolNumber}
+}
+
+// NewLiteProtocol returns a UDP-Lite transport protocol, as described in
+// RFC 3828.
+func NewLiteProtocol(s *stack.Stack) stack.TransportProtocol {
+	return &protocol{stack: s, number: LiteProtocolNumber}
 }
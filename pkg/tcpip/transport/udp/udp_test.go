@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
 	"math/rand"
@@ -1126,6 +1127,107 @@ func TestWriteIncrementsPacketsSent(t *testing.T) {
 	}
 }
 
+func TestReadBatch(t *testing.T) {
+	c := context.New(t, []stack.TransportProtocolFactory{udp.NewProtocol, icmp.NewProtocol6, icmp.NewProtocol4})
+	defer c.Cleanup()
+
+	c.CreateEndpointForFlow(context.UnicastV4, udp.ProtocolNumber)
+	if err := c.EP.Bind(tcpip.FullAddress{Port: context.StackPort}); err != nil {
+		c.T.Fatalf("Bind failed: %s", err)
+	}
+	ep, ok := c.EP.(tcpip.EndpointWithBatchRead)
+	if !ok {
+		c.T.Fatalf("endpoint of type %T doesn't implement tcpip.EndpointWithBatchRead", c.EP)
+	}
+
+	if _, err := ep.ReadBatch(make([]io.Writer, 4), tcpip.ReadOptions{}); err == nil {
+		c.T.Fatalf("ReadBatch on an empty queue succeeded")
+	} else if _, ok := err.(*tcpip.ErrWouldBlock); !ok {
+		c.T.Fatalf("ReadBatch on an empty queue returned %s, want %s", err, &tcpip.ErrWouldBlock{})
+	}
+
+	var payloads [][]byte
+	for i := 0; i < 3; i++ {
+		payload := newRandomPayload(arbitraryPayloadSize + i)
+		payloads = append(payloads, payload)
+		c.InjectPacket(header.IPv4ProtocolNumber, context.BuildUDPPacket(payload, context.UnicastV4, context.Incoming, testTOS, testTTL, false))
+	}
+
+	// Read the first two datagrams in a batch, then the last one.
+	for _, want := range [][][]byte{payloads[:2], payloads[2:]} {
+		bufs := make([]bytes.Buffer, 2)
+		dsts := []io.Writer{&bufs[0], &bufs[1]}
+		res, err := ep.ReadBatch(dsts, tcpip.ReadOptions{NeedRemoteAddr: true})
+		if err != nil {
+			c.T.Fatalf("ReadBatch failed: %s", err)
+		}
+		if len(res) != len(want) {
+			c.T.Fatalf("got len(ReadBatch(...)) = %d, want = %d", len(res), len(want))
+		}
+		for i := range want {
+			if res[i].Count != len(want[i]) || !bytes.Equal(bufs[i].Bytes(), want[i]) {
+				c.T.Errorf("datagram %d: got %x (Count = %d), want %x", i, bufs[i].Bytes(), res[i].Count, want[i])
+			}
+			if got, want := res[i].RemoteAddr.Addr, context.TestAddr; got != want {
+				c.T.Errorf("datagram %d: got RemoteAddr.Addr = %s, want = %s", i, got, want)
+			}
+		}
+	}
+
+	if n, err := c.EP.GetSockOptInt(tcpip.ReceiveQueueSizeOption); err != nil || n != 0 {
+		c.T.Errorf("got GetSockOptInt(ReceiveQueueSizeOption) = (%d, %v), want = (0, nil)", n, err)
+	}
+}
+
+func TestWriteBatch(t *testing.T) {
+	c := context.New(t, []stack.TransportProtocolFactory{udp.NewProtocol, icmp.NewProtocol6, icmp.NewProtocol4})
+	defer c.Cleanup()
+
+	c.CreateEndpointForFlow(context.UnicastV4, udp.ProtocolNumber)
+	ep, ok := c.EP.(tcpip.EndpointWithBatchWrite)
+	if !ok {
+		c.T.Fatalf("endpoint of type %T doesn't implement tcpip.EndpointWithBatchWrite", c.EP)
+	}
+
+	var (
+		payloads [][]byte
+		ps       []tcpip.Payloader
+	)
+	for i := 0; i < 3; i++ {
+		payload := newRandomPayload(arbitraryPayloadSize + i)
+		payloads = append(payloads, payload)
+		ps = append(ps, bytes.NewReader(payload))
+	}
+	// The oversized datagram stops the batch.
+	ps = append(ps, bytes.NewReader(make([]byte, header.UDPMaximumPacketSize+1)), bytes.NewReader(payloads[0]))
+
+	n, err := ep.WriteBatch(ps, getWriteOptionsForFlow(context.UnicastV4))
+	if _, ok := err.(*tcpip.ErrMessageTooLong); !ok || n != len(payloads) {
+		c.T.Fatalf("got WriteBatch(...) = (%d, %v), want = (%d, %s)", n, err, len(payloads), &tcpip.ErrMessageTooLong{})
+	}
+	if got, want := c.Stack.Stats().UDP.PacketsSent.Value(), uint64(len(payloads)); got != want {
+		c.T.Errorf("got PacketsSent = %d, want = %d", got, want)
+	}
+
+	for i, payload := range payloads {
+		p := c.LinkEP.Read()
+		if p == nil {
+			c.T.Fatalf("datagram %d wasn't written out", i)
+		}
+		v := p.ToView()
+		udpH := header.UDP(header.IPv4(v.AsSlice()).Payload())
+		if !bytes.Equal(udpH.Payload(), payload) {
+			c.T.Errorf("datagram %d: got payload %x, want %x", i, udpH.Payload(), payload)
+		}
+		v.Release()
+		p.DecRef()
+	}
+	if p := c.LinkEP.Read(); p != nil {
+		p.DecRef()
+		c.T.Errorf("unexpected datagram written after the batch failed")
+	}
+}
+
 func TestNoChecksum(t *testing.T) {
 	for _, writeOpSequence := range writeOpSequences {
 		for _, flow := range []context.TestFlow{context.UnicastV4, context.UnicastV6} {
@@ -1332,6 +1434,60 @@ func TestSetTClass(t *testing.T) {
 	}
 }
 
+func TestWriteControlMessages(t *testing.T) {
+	const (
+		tos       = testTOS
+		flowLabel = 0x12345
+	)
+	for _, test := range []struct {
+		flow    context.TestFlow
+		cm      tcpip.SendableControlMessages
+		checker checker.NetworkChecker
+	}{
+		{
+			flow:    context.UnicastV4,
+			cm:      tcpip.SendableControlMessages{HasTOS: true, TOS: tos},
+			checker: checker.TOS(tos, 0),
+		},
+		{
+			flow:    context.UnicastV6,
+			cm:      tcpip.SendableControlMessages{HasTClass: true, TClass: tos, HasFlowLabel: true, FlowLabel: flowLabel},
+			checker: checker.TOS(tos, flowLabel),
+		},
+		{
+			// The traffic class of the other family is ignored.
+			flow:    context.UnicastV6Only,
+			cm:      tcpip.SendableControlMessages{HasTOS: true, TOS: tos},
+			checker: checker.TOS(0, 0),
+		},
+	} {
+		t.Run(test.flow.String(), func(t *testing.T) {
+			c := context.New(t, []stack.TransportProtocolFactory{udp.NewProtocol, icmp.NewProtocol6, icmp.NewProtocol4})
+			defer c.Cleanup()
+
+			c.CreateEndpointForFlow(test.flow, udp.ProtocolNumber)
+
+			opts := getWriteOptionsForFlow(test.flow)
+			opts.ControlMessages = test.cm
+			payload := newRandomPayload(arbitraryPayloadSize)
+			if _, err := c.EP.Write(bytes.NewReader(payload), opts); err != nil {
+				c.T.Fatalf("Write(_, %+v): %s", opts, err)
+			}
+			p := c.LinkEP.Read()
+			if p == nil {
+				c.T.Fatalf("Packet wasn't written out")
+			}
+			defer p.DecRef()
+			v := p.ToView()
+			defer v.Release()
+			test.flow.CheckerFn()(c.T, v, test.checker)
+
+			// The control messages only apply to the packet they were sent with.
+			testWriteAndVerifyInternal(c, test.flow, true /* setDest */, checker.TOS(0, 0))
+		})
+	}
+}
+
 func TestReceiveControlMessage(t *testing.T) {
 	for _, flow := range []context.TestFlow{context.UnicastV4, context.UnicastV6, context.UnicastV6Only, context.MulticastV4, context.MulticastV6, context.MulticastV6Only, context.Broadcast} {
 		t.Run(flow.String(), func(t *testing.T) {
@@ -1359,6 +1515,14 @@ func TestReceiveControlMessage(t *testing.T) {
 					presenceChecker:  checker.ReceiveTClass(testTOS),
 					absenceChecker:   checker.NoTClassReceived(),
 				},
+				{
+					name:             "FlowInfo",
+					optionProtocol:   header.IPv6ProtocolNumber,
+					getReceiveOption: func(ep tcpip.Endpoint) bool { return ep.SocketOptions().GetReceiveFlowInfo() },
+					setReceiveOption: func(ep tcpip.Endpoint, value bool) { ep.SocketOptions().SetReceiveFlowInfo(value) },
+					presenceChecker:  checker.ReceiveFlowInfo(testTOS << 20),
+					absenceChecker:   checker.NoFlowInfoReceived(),
+				},
 				{
 					name:             "TTL",
 					optionProtocol:   header.IPv4ProtocolNumber,
@@ -2293,6 +2457,135 @@ func TestWritePayloadSizeTooBig(t *testing.T) {
 	}
 }
 
+func TestUDPLiteChecksumCoverage(t *testing.T) {
+	tests := []struct {
+		name        string
+		sndCoverage int
+		rcvCoverage int
+		wantLength  uint16
+		wantRead    bool
+	}{
+		{
+			name:       "full coverage",
+			wantLength: header.UDPMinimumSize + 10,
+			wantRead:   true,
+		},
+		{
+			name:        "partial coverage accepted",
+			sndCoverage: header.UDPMinimumSize + 4,
+			rcvCoverage: header.UDPMinimumSize + 4,
+			wantLength:  header.UDPMinimumSize + 4,
+			wantRead:    true,
+		},
+		{
+			name:        "partial coverage below minimum",
+			sndCoverage: header.UDPMinimumSize + 4,
+			rcvCoverage: header.UDPMinimumSize + 5,
+			wantLength:  header.UDPMinimumSize + 4,
+			wantRead:    false,
+		},
+		{
+			name:        "coverage raised to header size",
+			sndCoverage: 1,
+			rcvCoverage: 1,
+			wantLength:  header.UDPMinimumSize,
+			wantRead:    true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := context.New(t, []stack.TransportProtocolFactory{udp.NewProtocol, udp.NewLiteProtocol})
+			defer c.Cleanup()
+
+			c.CreateEndpoint(ipv6.ProtocolNumber, udp.LiteProtocolNumber)
+			if err := c.EP.SetSockOptInt(tcpip.UDPLiteSendCoverageOption, test.sndCoverage); err != nil {
+				t.Fatalf("SetSockOptInt(UDPLiteSendCoverageOption, %d): %s", test.sndCoverage, err)
+			}
+			if test.rcvCoverage != 0 {
+				if err := c.EP.SetSockOptInt(tcpip.UDPLiteReceiveCoverageOption, test.rcvCoverage); err != nil {
+					t.Fatalf("SetSockOptInt(UDPLiteReceiveCoverageOption, %d): %s", test.rcvCoverage, err)
+				}
+			}
+			if err := c.EP.Connect(tcpip.FullAddress{Addr: context.TestV6Addr, Port: context.TestPort}); err != nil {
+				t.Fatalf("Connect failed: %s", err)
+			}
+
+			payload := newRandomPayload(10)
+			var r bytes.Reader
+			r.Reset(payload)
+			if _, err := c.EP.Write(&r, tcpip.WriteOptions{}); err != nil {
+				t.Fatalf("Write failed: %s", err)
+			}
+
+			pkt := c.LinkEP.Read()
+			if pkt == nil {
+				t.Fatal("Packet wasn't written out")
+			}
+			v := stack.PayloadSince(pkt.NetworkHeader())
+			defer v.Release()
+			pkt.DecRef()
+			checker.IPv6(t, v)
+
+			ip := header.IPv6(v.AsSlice())
+			if got := ip.TransportProtocol(); got != udp.LiteProtocolNumber {
+				t.Fatalf("got ip.TransportProtocol() = %d, want = %d", got, udp.LiteProtocolNumber)
+			}
+			hdr := header.UDP(ip.Payload())
+			if got := hdr.Length(); got != test.wantLength {
+				t.Errorf("got hdr.Length() = %d, want = %d", got, test.wantLength)
+			}
+			coverage, coverageValid, csumValid := header.UDPLiteValid(
+				hdr,
+				func(covered int) uint16 { return checksum.Checksum(hdr.Payload()[:covered], 0) },
+				uint16(len(hdr.Payload())),
+				ip.SourceAddress(),
+				ip.DestinationAddress(),
+				false /* skipChecksumValidation */)
+			if !coverageValid || !csumValid {
+				t.Fatalf("got header.UDPLiteValid(...) = (%d, %t, %t), want = (%d, true, true)", coverage, coverageValid, csumValid, test.wantLength)
+			}
+
+			// Send the packet back to the endpoint. Swapping the addresses and
+			// ports leaves the checksum valid.
+			src, dst := ip.SourceAddress(), ip.DestinationAddress()
+			ip.SetSourceAddress(dst)
+			ip.SetDestinationAddress(src)
+			srcPort, dstPort := hdr.SourcePort(), hdr.DestinationPort()
+			hdr.SetSourcePort(dstPort)
+			hdr.SetDestinationPort(srcPort)
+			c.InjectPacket(ipv6.ProtocolNumber, v.AsSlice())
+
+			var buf bytes.Buffer
+			_, err := c.EP.Read(&buf, tcpip.ReadOptions{})
+			if test.wantRead {
+				if err != nil {
+					t.Fatalf("Read failed: %s", err)
+				}
+				if !bytes.Equal(buf.Bytes(), payload) {
+					t.Errorf("got payload = %x, want = %x", buf.Bytes(), payload)
+				}
+			} else if _, ok := err.(*tcpip.ErrWouldBlock); !ok {
+				t.Fatalf("got Read(...) = %s, want = %s", err, &tcpip.ErrWouldBlock{})
+			}
+		})
+	}
+}
+
+func TestUDPLiteCoverageOptionsOnUDP(t *testing.T) {
+	c := context.New(t, []stack.TransportProtocolFactory{udp.NewProtocol, udp.NewLiteProtocol})
+	defer c.Cleanup()
+
+	c.CreateEndpoint(ipv4.ProtocolNumber, udp.ProtocolNumber)
+	for _, opt := range []tcpip.SockOptInt{tcpip.UDPLiteSendCoverageOption, tcpip.UDPLiteReceiveCoverageOption} {
+		if err := c.EP.SetSockOptInt(opt, header.UDPMinimumSize); err == nil {
+			t.Errorf("got SetSockOptInt(%d, _) = nil, want = %s", opt, &tcpip.ErrUnknownProtocolOption{})
+		}
+		if _, err := c.EP.GetSockOptInt(opt); err == nil {
+			t.Errorf("got GetSockOptInt(%d) = nil, want = %s", opt, &tcpip.ErrUnknownProtocolOption{})
+		}
+	}
+}
+
 func TestMain(m *testing.M) {
 	refs.SetLeakMode(refs.LeaksPanic)
 	code := m.Run()
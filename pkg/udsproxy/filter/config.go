@@ -0,0 +1,133 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/seccomp"
+)
+
+// allowedSyscalls is the set of syscalls executed by the proxy. Besides the
+// Go runtime, the proxy only receives requests and sends responses over its
+// unet.Socket, and creates and connects Unix-domain sockets.
+var allowedSyscalls = seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
+	unix.SYS_CLOCK_GETTIME: seccomp.MatchAll{},
+	unix.SYS_CLOSE:         seccomp.MatchAll{},
+	unix.SYS_CONNECT: seccomp.PerArg{
+		seccomp.NonNegativeFD{},
+		seccomp.AnyValue{},
+		seccomp.AnyValue{},
+	},
+	unix.SYS_EPOLL_CTL: seccomp.MatchAll{},
+	unix.SYS_EPOLL_PWAIT: seccomp.PerArg{
+		seccomp.AnyValue{},
+		seccomp.AnyValue{},
+		seccomp.AnyValue{},
+		seccomp.AnyValue{},
+		seccomp.EqualTo(0),
+	},
+	unix.SYS_EXIT:       seccomp.MatchAll{},
+	unix.SYS_EXIT_GROUP: seccomp.MatchAll{},
+	unix.SYS_FUTEX: seccomp.Or{
+		seccomp.PerArg{
+			seccomp.AnyValue{},
+			seccomp.EqualTo(linux.FUTEX_WAIT | linux.FUTEX_PRIVATE_FLAG),
+			seccomp.AnyValue{},
+			seccomp.AnyValue{},
+			seccomp.EqualTo(0),
+		},
+		seccomp.PerArg{
+			seccomp.AnyValue{},
+			seccomp.EqualTo(linux.FUTEX_WAKE | linux.FUTEX_PRIVATE_FLAG),
+			seccomp.AnyValue{},
+			seccomp.AnyValue{},
+			seccomp.EqualTo(0),
+		},
+	},
+	// getcpu is used by some versions of the Go runtime.
+	unix.SYS_GETCPU: seccomp.PerArg{
+		seccomp.AnyValue{},
+		seccomp.EqualTo(0),
+		seccomp.EqualTo(0),
+	},
+	unix.SYS_GETPID:       seccomp.MatchAll{},
+	unix.SYS_GETRANDOM:    seccomp.MatchAll{},
+	unix.SYS_GETTID:       seccomp.MatchAll{},
+	unix.SYS_GETTIMEOFDAY: seccomp.MatchAll{},
+	unix.SYS_MADVISE:      seccomp.MatchAll{},
+	unix.SYS_MMAP: seccomp.Or{
+		seccomp.PerArg{
+			seccomp.AnyValue{},
+			seccomp.AnyValue{},
+			seccomp.AnyValue{},
+			seccomp.EqualTo(unix.MAP_PRIVATE | unix.MAP_ANONYMOUS),
+		},
+		seccomp.PerArg{
+			seccomp.AnyValue{},
+			seccomp.AnyValue{},
+			seccomp.AnyValue{},
+			seccomp.EqualTo(unix.MAP_PRIVATE | unix.MAP_ANONYMOUS | unix.MAP_FIXED),
+		},
+	},
+	unix.SYS_MPROTECT:  seccomp.MatchAll{},
+	unix.SYS_MUNMAP:    seccomp.MatchAll{},
+	unix.SYS_NANOSLEEP: seccomp.MatchAll{},
+	// Used by unet.Socket to block.
+	unix.SYS_PPOLL: seccomp.MatchAll{},
+	// Used by unet.SocketReader.ReadVec().
+	unix.SYS_RECVMSG: seccomp.PerArg{
+		seccomp.AnyValue{},
+		seccomp.AnyValue{},
+		seccomp.EqualTo(unix.MSG_DONTWAIT | unix.MSG_TRUNC),
+	},
+	unix.SYS_RESTART_SYSCALL: seccomp.MatchAll{},
+	// May be used by the runtime during panic().
+	unix.SYS_RT_SIGACTION:   seccomp.MatchAll{},
+	unix.SYS_RT_SIGPROCMASK: seccomp.MatchAll{},
+	unix.SYS_RT_SIGRETURN:   seccomp.MatchAll{},
+	unix.SYS_SCHED_YIELD:    seccomp.MatchAll{},
+	// Used by unet.SocketWriter.WriteVec().
+	unix.SYS_SENDMSG: seccomp.PerArg{
+		seccomp.AnyValue{},
+		seccomp.AnyValue{},
+		seccomp.EqualTo(unix.MSG_DONTWAIT | unix.MSG_NOSIGNAL),
+	},
+	unix.SYS_SIGALTSTACK: seccomp.MatchAll{},
+	unix.SYS_SOCKET: seccomp.Or{
+		seccomp.PerArg{
+			seccomp.EqualTo(unix.AF_UNIX),
+			seccomp.EqualTo(unix.SOCK_STREAM | unix.SOCK_NONBLOCK | unix.SOCK_CLOEXEC),
+			seccomp.EqualTo(0),
+		},
+		seccomp.PerArg{
+			seccomp.EqualTo(unix.AF_UNIX),
+			seccomp.EqualTo(unix.SOCK_DGRAM | unix.SOCK_NONBLOCK | unix.SOCK_CLOEXEC),
+			seccomp.EqualTo(0),
+		},
+		seccomp.PerArg{
+			seccomp.EqualTo(unix.AF_UNIX),
+			seccomp.EqualTo(unix.SOCK_SEQPACKET | unix.SOCK_NONBLOCK | unix.SOCK_CLOEXEC),
+			seccomp.EqualTo(0),
+		},
+	},
+	unix.SYS_TGKILL: seccomp.PerArg{
+		seccomp.EqualTo(uint64(os.Getpid())),
+	},
+	// Used for logging.
+	unix.SYS_WRITE: seccomp.MatchAll{},
+})
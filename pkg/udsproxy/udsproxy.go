@@ -0,0 +1,225 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package udsproxy implements a mediating proxy that connects to abstract
+// Unix domain sockets on the host on behalf of the sandbox.
+//
+// Abstract sockets are scoped to network namespaces, so the sandbox can't
+// connect to abstract sockets in the host network namespace itself. Instead,
+// the proxy runs in the host network namespace, and the sandbox sends it
+// connection requests over a SOCK_SEQPACKET socket. Each request is a single
+// message containing the socket type as a native-endian uint32, followed by
+// the abstract socket name without its leading NUL byte. Each response is a
+// single message containing an errno as a native-endian uint32; if the errno
+// is 0, the connected socket is attached as SCM_RIGHTS.
+package udsproxy
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/unet"
+)
+
+// MaxNameLen is the maximum length of an abstract socket name, excluding its
+// leading NUL byte.
+const MaxNameLen = len(unix.RawSockaddrUnix{}.Path) - 1
+
+// sizeofHeader is the size of the socket type in requests and the errno in
+// responses.
+const sizeofHeader = 4
+
+// Match returns true if name matches any of patterns. A pattern ending in "*"
+// matches all names that start with the rest of the pattern; other patterns
+// only match identical names.
+func Match(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isSocketTypeSupported(stype int) bool {
+	switch stype {
+	case unix.SOCK_STREAM, unix.SOCK_DGRAM, unix.SOCK_SEQPACKET:
+		return true
+	default:
+		return false
+	}
+}
+
+// Client sends connection requests to a proxy.
+type Client struct {
+	// mu serializes requests, so that responses can be matched to requests.
+	mu sync.Mutex
+
+	// sock is the connection to the proxy.
+	sock *unet.Socket
+
+	// patterns are the names of abstract sockets that the proxy connects to.
+	// patterns is immutable.
+	patterns []string
+}
+
+// NewClient returns a Client that sends requests to the proxy connected to
+// fd, which connects to abstract sockets whose names match patterns. It takes
+// ownership of fd.
+func NewClient(fd int, patterns []string) (*Client, error) {
+	sock, err := unet.NewSocket(fd)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{sock: sock, patterns: patterns}, nil
+}
+
+// Bridged returns true if the proxy connects to the abstract socket name.
+func (c *Client) Bridged(name string) bool {
+	return Match(c.patterns, name)
+}
+
+// Connect returns a host socket of type stype connected to the abstract
+// socket name on the host.
+func (c *Client) Connect(name string, stype int) (int, error) {
+	if len(name) == 0 || len(name) > MaxNameLen || !isSocketTypeSupported(stype) {
+		return -1, unix.EINVAL
+	}
+	req := make([]byte, sizeofHeader+len(name))
+	binary.NativeEndian.PutUint32(req, uint32(stype))
+	copy(req[sizeofHeader:], name)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := c.sock.Writer(true /* blocking */)
+	if _, err := w.WriteVec([][]byte{req}); err != nil {
+		return -1, fmt.Errorf("sending request: %w", err)
+	}
+
+	var resp [sizeofHeader]byte
+	r := c.sock.Reader(true /* blocking */)
+	r.EnableFDs(1)
+	n, err := r.ReadVec([][]byte{resp[:]})
+	if err != nil {
+		r.CloseFDs()
+		return -1, fmt.Errorf("receiving response: %w", err)
+	}
+	fds, err := r.ExtractFDs()
+	if err != nil {
+		return -1, fmt.Errorf("receiving response: %w", err)
+	}
+	if n != sizeofHeader {
+		closeFDs(fds)
+		return -1, fmt.Errorf("response has invalid length %d", n)
+	}
+	if errno := unix.Errno(binary.NativeEndian.Uint32(resp[:])); errno != 0 {
+		closeFDs(fds)
+		return -1, errno
+	}
+	if len(fds) != 1 {
+		closeFDs(fds)
+		return -1, fmt.Errorf("response has %d FDs, want 1", len(fds))
+	}
+	return fds[0], nil
+}
+
+// Close closes the connection to the proxy.
+func (c *Client) Close() error {
+	return c.sock.Close()
+}
+
+func closeFDs(fds []int) {
+	for _, fd := range fds {
+		unix.Close(fd)
+	}
+}
+
+// Serve handles requests received on sock, connecting only to abstract
+// sockets whose names match patterns, until the client closes the connection.
+func Serve(sock *unet.Socket, patterns []string) error {
+	req := make([]byte, sizeofHeader+MaxNameLen)
+	for {
+		r := sock.Reader(true /* blocking */)
+		n, err := r.ReadVec([][]byte{req})
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("receiving request: %w", err)
+		}
+
+		fd, errno := connect(req[:n], patterns)
+		var resp [sizeofHeader]byte
+		binary.NativeEndian.PutUint32(resp[:], uint32(errno))
+		w := sock.Writer(true /* blocking */)
+		if fd >= 0 {
+			w.PackFDs(fd)
+		}
+		_, err = w.WriteVec([][]byte{resp[:]})
+		if fd >= 0 {
+			unix.Close(fd)
+		}
+		if err != nil {
+			return fmt.Errorf("sending response: %w", err)
+		}
+	}
+}
+
+// connect handles the request req.
+func connect(req []byte, patterns []string) (int, unix.Errno) {
+	if len(req) <= sizeofHeader {
+		return -1, unix.EINVAL
+	}
+	stype := int(binary.NativeEndian.Uint32(req))
+	name := string(req[sizeofHeader:])
+	if !isSocketTypeSupported(stype) {
+		return -1, unix.EINVAL
+	}
+	if !Match(patterns, name) {
+		log.Warningf("Refusing connection to abstract socket %q, which is not allowed", name)
+		return -1, unix.ECONNREFUSED
+	}
+
+	// Use a non-blocking socket so that connecting to a socket with a full
+	// backlog can't stall other requests.
+	fd, err := unix.Socket(unix.AF_UNIX, stype|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, errnoOf(err)
+	}
+	// A leading '@' denotes an abstract socket.
+	if err := unix.Connect(fd, &unix.SockaddrUnix{Name: "@" + name}); err != nil {
+		unix.Close(fd)
+		return -1, errnoOf(err)
+	}
+	return fd, 0
+}
+
+func errnoOf(err error) unix.Errno {
+	var errno unix.Errno
+	if errors.As(err, &errno) {
+		return errno
+	}
+	return unix.EIO
+}
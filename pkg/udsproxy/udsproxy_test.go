@@ -0,0 +1,130 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package udsproxy
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/unet"
+)
+
+func TestMatch(t *testing.T) {
+	patterns := []string{"dbus", "/tmp/dbus-*"}
+	for _, test := range []struct {
+		name string
+		want bool
+	}{
+		{"dbus", true},
+		{"dbus2", false},
+		{"/tmp/dbus-AbCdEf", true},
+		{"/tmp/dbus-", true},
+		{"/tmp/dbus", false},
+		{"containerd", false},
+	} {
+		if got := Match(patterns, test.name); got != test.want {
+			t.Errorf("Match(%q, %q) = %t, want %t", patterns, test.name, got, test.want)
+		}
+	}
+}
+
+// listenAbstract returns a listening abstract socket with a unique name.
+func listenAbstract(t *testing.T, suffix string) (int, string) {
+	t.Helper()
+	name := fmt.Sprintf("udsproxy_test.%d.%s", os.Getpid(), suffix)
+	fd, err := unix.Socket(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("socket failed: %v", err)
+	}
+	t.Cleanup(func() { unix.Close(fd) })
+	if err := unix.Bind(fd, &unix.SockaddrUnix{Name: "@" + name}); err != nil {
+		t.Fatalf("bind(%q) failed: %v", name, err)
+	}
+	if err := unix.Listen(fd, 1); err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	return fd, name
+}
+
+func TestConnect(t *testing.T) {
+	lfd, name := listenAbstract(t, "allowed")
+	_, denied := listenAbstract(t, "denied")
+
+	clientSock, serverSock, err := unet.SocketPair(true /* packet */)
+	if err != nil {
+		t.Fatalf("SocketPair failed: %v", err)
+	}
+	patterns := []string{name, "udsproxy_test.missing*"}
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(serverSock, patterns)
+		serverSock.Close()
+	}()
+	clientFD, err := clientSock.Release()
+	if err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	c, err := NewClient(clientFD, patterns)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if !c.Bridged(name) || c.Bridged(denied) {
+		t.Errorf("Bridged(%q), Bridged(%q) = %t, %t, want true, false", name, denied, c.Bridged(name), c.Bridged(denied))
+	}
+
+	fd, err := c.Connect(name, unix.SOCK_STREAM)
+	if err != nil {
+		t.Fatalf("Connect(%q) failed: %v", name, err)
+	}
+	defer unix.Close(fd)
+	afd, _, err := unix.Accept(lfd)
+	if err != nil {
+		t.Fatalf("accept failed: %v", err)
+	}
+	defer unix.Close(afd)
+	if _, err := unix.Write(afd, []byte("x")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	var buf [1]byte
+	if n, err := unix.Read(fd, buf[:]); err != nil || n != 1 || buf[0] != 'x' {
+		t.Errorf("read from proxied socket = (%d, %v, %q), want (1, nil, \"x\")", n, err, buf[:n])
+	}
+
+	for _, test := range []struct {
+		name  string
+		stype int
+		want  error
+	}{
+		{denied, unix.SOCK_STREAM, unix.ECONNREFUSED},
+		{"udsproxy_test.missing", unix.SOCK_STREAM, unix.ECONNREFUSED},
+		{"", unix.SOCK_STREAM, unix.EINVAL},
+		{name, unix.SOCK_RAW, unix.EINVAL},
+	} {
+		if fd, err := c.Connect(test.name, test.stype); err != test.want {
+			if err == nil {
+				unix.Close(fd)
+			}
+			t.Errorf("Connect(%q, %d) = %v, want %v", test.name, test.stype, err, test.want)
+		}
+	}
+
+	c.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Serve failed: %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usermem
+
+import (
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/hostarch"
+	"gvisor.dev/gvisor/pkg/safemem"
+)
+
+// BlockSeqIO implements IO using a safemem.BlockSeq. Addresses are
+// interpreted as offsets into the BlockSeq. Reads and writes beyond the end of
+// the BlockSeq return EFAULT.
+//
+// BlockSeqIO is typically used to perform I/O to memory that was pinned in
+// advance, e.g. by mm.MemoryManager.PinIOVecs.
+type BlockSeqIO struct {
+	Blocks safemem.BlockSeq
+}
+
+// CopyOut implements IO.CopyOut.
+func (b *BlockSeqIO) CopyOut(ctx context.Context, addr hostarch.Addr, src []byte, opts IOOpts) (int, error) {
+	dsts, rngErr := b.rangeCheck(addr, uint64(len(src)))
+	if dsts.IsEmpty() {
+		return 0, rngErr
+	}
+	n, err := safemem.CopySeq(dsts, safemem.BlockSeqOf(safemem.BlockFromSafeSlice(src)))
+	if err != nil {
+		return int(n), linuxerr.EFAULT
+	}
+	return int(n), rngErr
+}
+
+// CopyIn implements IO.CopyIn.
+func (b *BlockSeqIO) CopyIn(ctx context.Context, addr hostarch.Addr, dst []byte, opts IOOpts) (int, error) {
+	srcs, rngErr := b.rangeCheck(addr, uint64(len(dst)))
+	if srcs.IsEmpty() {
+		return 0, rngErr
+	}
+	n, err := safemem.CopySeq(safemem.BlockSeqOf(safemem.BlockFromSafeSlice(dst)), srcs)
+	if err != nil {
+		return int(n), linuxerr.EFAULT
+	}
+	return int(n), rngErr
+}
+
+// ZeroOut implements IO.ZeroOut.
+func (b *BlockSeqIO) ZeroOut(ctx context.Context, addr hostarch.Addr, toZero int64, opts IOOpts) (int64, error) {
+	if toZero < 0 {
+		return 0, linuxerr.EINVAL
+	}
+	dsts, rngErr := b.rangeCheck(addr, uint64(toZero))
+	if dsts.IsEmpty() {
+		return 0, rngErr
+	}
+	n, err := safemem.ZeroSeq(dsts)
+	if err != nil {
+		return int64(n), linuxerr.EFAULT
+	}
+	return int64(n), rngErr
+}
+
+// CopyOutFrom implements IO.CopyOutFrom.
+func (b *BlockSeqIO) CopyOutFrom(ctx context.Context, ars hostarch.AddrRangeSeq, src safemem.Reader, opts IOOpts) (int64, error) {
+	dsts, rngErr := b.blocksFromAddrRanges(ars)
+	n, err := src.ReadToBlocks(dsts)
+	if err != nil {
+		return int64(n), err
+	}
+	return int64(n), rngErr
+}
+
+// CopyInTo implements IO.CopyInTo.
+func (b *BlockSeqIO) CopyInTo(ctx context.Context, ars hostarch.AddrRangeSeq, dst safemem.Writer, opts IOOpts) (int64, error) {
+	srcs, rngErr := b.blocksFromAddrRanges(ars)
+	n, err := dst.WriteFromBlocks(srcs)
+	if err != nil {
+		return int64(n), err
+	}
+	return int64(n), rngErr
+}
+
+// SwapUint32 implements IO.SwapUint32.
+func (b *BlockSeqIO) SwapUint32(ctx context.Context, addr hostarch.Addr, new uint32, opts IOOpts) (uint32, error) {
+	blk, err := b.atomicBlock(addr)
+	if err != nil {
+		return 0, err
+	}
+	old, err := safemem.SwapUint32(blk, new)
+	if err != nil {
+		return 0, linuxerr.EFAULT
+	}
+	return old, nil
+}
+
+// CompareAndSwapUint32 implements IO.CompareAndSwapUint32.
+func (b *BlockSeqIO) CompareAndSwapUint32(ctx context.Context, addr hostarch.Addr, old, new uint32, opts IOOpts) (uint32, error) {
+	blk, err := b.atomicBlock(addr)
+	if err != nil {
+		return 0, err
+	}
+	prev, err := safemem.CompareAndSwapUint32(blk, old, new)
+	if err != nil {
+		return 0, linuxerr.EFAULT
+	}
+	return prev, nil
+}
+
+// LoadUint32 implements IO.LoadUint32.
+func (b *BlockSeqIO) LoadUint32(ctx context.Context, addr hostarch.Addr, opts IOOpts) (uint32, error) {
+	blk, err := b.atomicBlock(addr)
+	if err != nil {
+		return 0, err
+	}
+	val, err := safemem.LoadUint32(blk)
+	if err != nil {
+		return 0, linuxerr.EFAULT
+	}
+	return val, nil
+}
+
+// rangeCheck returns the subset of b.Blocks representing [addr, addr+length).
+// If this is shorter than length, it also returns EFAULT.
+func (b *BlockSeqIO) rangeCheck(addr hostarch.Addr, length uint64) (safemem.BlockSeq, error) {
+	if length == 0 {
+		return safemem.BlockSeq{}, nil
+	}
+	max := b.Blocks.NumBytes()
+	if uint64(addr) >= max {
+		return safemem.BlockSeq{}, linuxerr.EFAULT
+	}
+	bs := b.Blocks.DropFirst64(uint64(addr)).TakeFirst64(length)
+	if bs.NumBytes() < length {
+		return bs, linuxerr.EFAULT
+	}
+	return bs, nil
+}
+
+func (b *BlockSeqIO) blocksFromAddrRanges(ars hostarch.AddrRangeSeq) (safemem.BlockSeq, error) {
+	if ars.NumRanges() <= 1 {
+		if ars.IsEmpty() {
+			return safemem.BlockSeq{}, nil
+		}
+		ar := ars.Head()
+		return b.rangeCheck(ar.Start, uint64(ar.Length()))
+	}
+	var blocks []safemem.Block
+	for ; !ars.IsEmpty(); ars = ars.Tail() {
+		ar := ars.Head()
+		bs, err := b.rangeCheck(ar.Start, uint64(ar.Length()))
+		for ; !bs.IsEmpty(); bs = bs.Tail() {
+			blocks = append(blocks, bs.Head())
+		}
+		if err != nil {
+			return safemem.BlockSeqFromSlice(blocks), err
+		}
+	}
+	return safemem.BlockSeqFromSlice(blocks), nil
+}
+
+// atomicBlock returns the Block representing the 4 bytes at addr.
+func (b *BlockSeqIO) atomicBlock(addr hostarch.Addr) (safemem.Block, error) {
+	bs, err := b.rangeCheck(addr, 4)
+	if err != nil {
+		return safemem.Block{}, err
+	}
+	if bs.NumBlocks() != 1 {
+		// Atomicity is unachievable across Blocks.
+		return safemem.Block{}, linuxerr.EFAULT
+	}
+	return bs.Head(), nil
+}
+
+// BlockSeqIOSequence returns an IOSequence representing the given BlockSeq.
+func BlockSeqIOSequence(bs safemem.BlockSeq) IOSequence {
+	return IOSequence{
+		IO:    &BlockSeqIO{bs},
+		Addrs: hostarch.AddrRangeSeqOf(hostarch.AddrRange{0, hostarch.Addr(bs.NumBytes())}),
+	}
+}
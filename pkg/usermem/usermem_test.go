@@ -174,6 +174,48 @@ type testStruct struct {
 	Uint64 uint64
 }
 
+func newBlockSeqIOStrings(ss ...string) *BlockSeqIO {
+	var blocks []safemem.Block
+	for _, s := range ss {
+		blocks = append(blocks, safemem.BlockFromSafeSlice([]byte(s)))
+	}
+	return &BlockSeqIO{safemem.BlockSeqFromSlice(blocks)}
+}
+
+func TestBlockSeqIOCopyOutAcrossBlocks(t *testing.T) {
+	b := newBlockSeqIOStrings("AB", "CDE")
+	n, err := b.CopyOut(newContext(), 1, []byte("foo"), IOOpts{})
+	if wantN := 3; n != wantN || err != nil {
+		t.Errorf("CopyOut: got (%v, %v), wanted (%v, nil)", n, err, wantN)
+	}
+	var got [5]byte
+	if _, err := b.CopyIn(newContext(), 0, got[:], IOOpts{}); err != nil {
+		t.Fatalf("CopyIn: got error %v, wanted nil", err)
+	}
+	if want := []byte("AfooE"); !bytes.Equal(got[:], want) {
+		t.Errorf("Blocks: got %q, wanted %q", got, want)
+	}
+}
+
+func TestBlockSeqIOCopyInFailure(t *testing.T) {
+	b := newBlockSeqIOStrings("A", "fo")
+	var dst [3]byte
+	n, err := b.CopyIn(newContext(), 1, dst[:], IOOpts{})
+	if wantN, wantErr := 2, linuxerr.EFAULT; n != wantN || err != wantErr {
+		t.Errorf("CopyIn: got (%v, %v), wanted (%v, %v)", n, err, wantN, wantErr)
+	}
+	if got, want := dst[:2], []byte("fo"); !bytes.Equal(got, want) {
+		t.Errorf("dst: got %q, wanted %q", got, want)
+	}
+}
+
+func TestBlockSeqIOAtomicAcrossBlocks(t *testing.T) {
+	b := newBlockSeqIOStrings("AB", "CD")
+	if _, err := b.LoadUint32(newContext(), 0, IOOpts{}); err != linuxerr.EFAULT {
+		t.Errorf("LoadUint32: got error %v, wanted %v", err, linuxerr.EFAULT)
+	}
+}
+
 func TestCopyStringInShort(t *testing.T) {
 	// Tests for string length <= copyStringIncrement.
 	want := strings.Repeat("A", copyStringIncrement-2)
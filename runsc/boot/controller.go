@@ -15,25 +15,35 @@
 package boot
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"path"
 	"strconv"
+	"strings"
 	"sync"
 	gtime "time"
 
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/abi/nvgpu"
 	"gvisor.dev/gvisor/pkg/cleanup"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/control/server"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
 	"gvisor.dev/gvisor/pkg/fd"
 	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/control"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/dev"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/erofs"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/gofer"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/verity"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/policy"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netstack"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/state/statefile"
@@ -104,12 +114,28 @@ const (
 	// ContMgrListTraceSessions lists a trace session.
 	ContMgrListTraceSessions = "containerManager.ListTraceSessions"
 
+	// ContMgrReloadPolicy replaces the rules of policy trace sinks.
+	ContMgrReloadPolicy = "containerManager.ReloadPolicy"
+
 	// ContMgrProcfsDump dumps sandbox procfs state.
 	ContMgrProcfsDump = "containerManager.ProcfsDump"
 
 	// ContMgrMount mounts a filesystem in a container.
 	ContMgrMount = "containerManager.Mount"
 
+	// ContMgrUnmount unmounts a filesystem in a container.
+	ContMgrUnmount = "containerManager.Unmount"
+
+	// ContMgrAttachGPU makes an NVIDIA GPU available to a running container.
+	ContMgrAttachGPU = "containerManager.AttachGPU"
+
+	// ContMgrDetachGPU removes an NVIDIA GPU attached with AttachGPU from a
+	// container.
+	ContMgrDetachGPU = "containerManager.DetachGPU"
+
+	// ContMgrEnableVerity enables verification of a directory in a container.
+	ContMgrEnableVerity = "containerManager.EnableVerity"
+
 	// ContMgrContainerRuntimeState returns the runtime state of a container.
 	ContMgrContainerRuntimeState = "containerManager.ContainerRuntimeState"
 )
@@ -118,17 +144,39 @@ const (
 	// NetworkCreateLinksAndRoutes creates links and routes in a network stack.
 	NetworkCreateLinksAndRoutes = "Network.CreateLinksAndRoutes"
 
+	// NetworkAddLinksAndRoutes adds links and routes to a network stack that
+	// was already set up.
+	NetworkAddLinksAndRoutes = "Network.AddLinksAndRoutes"
+
 	// DebugStacks collects sandbox stacks for debugging.
 	DebugStacks = "debug.Stacks"
 )
 
 // Profiling related commands (see pprof.go for more details).
 const (
-	ProfileCPU   = "Profile.CPU"
-	ProfileHeap  = "Profile.Heap"
-	ProfileBlock = "Profile.Block"
-	ProfileMutex = "Profile.Mutex"
-	ProfileTrace = "Profile.Trace"
+	ProfileCPU       = "Profile.CPU"
+	ProfileHeap      = "Profile.Heap"
+	ProfileBlock     = "Profile.Block"
+	ProfileMutex     = "Profile.Mutex"
+	ProfileTrace     = "Profile.Trace"
+	ProfileGoroutine = "Profile.Goroutine"
+)
+
+// Clock related commands (see clock.go for more details).
+const (
+	ClockSetSkew = "Clock.SetSkew"
+	ClockGetSkew = "Clock.GetSkew"
+)
+
+// State related commands (see pkg/sentry/control/state.go for more details).
+const (
+	StateSaveProgress = "State.SaveProgress"
+)
+
+// Fault injection related commands (see fault_injection.go for more details).
+const (
+	FaultInjectionSet = "FaultInjection.Set"
+	FaultInjectionGet = "FaultInjection.Get"
 )
 
 // Logging related commands (see logging.go for more details).
@@ -136,10 +184,17 @@ const (
 	LoggingChange = "Logging.Change"
 )
 
+// Syscall path related commands (see syscall_path.go for more details).
+const (
+	SyscallPathsSet   = "SyscallPaths.Set"
+	SyscallPathsStats = "SyscallPaths.Stats"
+)
+
 // Usage related commands (see usage.go for more details).
 const (
-	UsageCollect = "Usage.Collect"
-	UsageUsageFD = "Usage.UsageFD"
+	UsageCollect      = "Usage.Collect"
+	UsageUsageFD      = "Usage.UsageFD"
+	UsageResizeMemory = "Usage.ResizeMemory"
 )
 
 // Metrics related commands (see metrics.go).
@@ -182,10 +237,13 @@ func newController(fd int, l *Loader) (*controller, error) {
 	}
 	ctrl.srv.Register(ctrl.manager)
 	ctrl.srv.Register(&control.Cgroups{Kernel: l.k})
+	ctrl.srv.Register(&control.Clock{Kernel: l.k})
+	ctrl.srv.Register(&control.FaultInjection{Kernel: l.k})
 	ctrl.srv.Register(&control.Lifecycle{Kernel: l.k})
 	ctrl.srv.Register(&control.Logging{})
 	ctrl.srv.Register(&control.Proc{Kernel: l.k})
-	ctrl.srv.Register(&control.State{Kernel: l.k})
+	ctrl.srv.Register(&control.State{Kernel: l.k, AsyncIO: statefile.AsyncOpts{IOUring: l.root.conf.StateIOUring}})
+	ctrl.srv.Register(&control.SyscallPaths{Kernel: l.k})
 	ctrl.srv.Register(&control.Usage{Kernel: l.k})
 	ctrl.srv.Register(&control.Metrics{})
 	ctrl.srv.Register(&debug{})
@@ -456,9 +514,11 @@ type RestoreOpts struct {
 	// 2. optional checkpoint pages metadata file.
 	// 3. optional checkpoint pages file.
 	// 4. optional platform device file.
+	// 5. optional FD resolver socket (see FDResolverResolve).
 	urpc.FilePayload
 	HavePagesFile  bool
 	HaveDeviceFile bool
+	HaveFDResolver bool
 }
 
 // Restore loads a container from a statefile.
@@ -487,7 +547,9 @@ func (cm *containerManager) Restore(o *RestoreOpts, _ *struct{}) error {
 	if err := unix.Fstat(stateFile.FD(), &stat); err != nil {
 		return err
 	}
-	if stat.Size == 0 {
+	// The statefile may be a pipe, e.g. when the image is streamed from
+	// object storage.
+	if stat.Mode&unix.S_IFMT == unix.S_IFREG && stat.Size == 0 {
 		return fmt.Errorf("statefile cannot be empty")
 	}
 
@@ -518,6 +580,14 @@ func (cm *containerManager) Restore(o *RestoreOpts, _ *struct{}) error {
 		fileIdx++
 	}
 
+	if o.HaveFDResolver {
+		cm.restorer.fdResolver, err = o.ReleaseFD(fileIdx)
+		if err != nil {
+			return err
+		}
+		fileIdx++
+	}
+
 	if fileIdx < len(o.Files) {
 		return fmt.Errorf("more files passed to Restore than expected")
 	}
@@ -525,7 +595,10 @@ func (cm *containerManager) Restore(o *RestoreOpts, _ *struct{}) error {
 	// Pause the kernel while we build a new one.
 	cm.l.k.Pause()
 
-	metadata, err := statefile.MetadataUnsafe(cm.restorer.stateFile)
+	// Since the statefile may not be seekable, keep what is read of it to
+	// be read again by the restorer.
+	var header bytes.Buffer
+	metadata, err := statefile.MetadataUnsafe(io.TeeReader(stateFile, &header))
 	if err != nil {
 		return fmt.Errorf("reading metadata from statefile: %w", err)
 	}
@@ -550,9 +623,10 @@ func (cm *containerManager) Restore(o *RestoreOpts, _ *struct{}) error {
 	cm.restorer.totalContainers = count
 	log.Infof("Restoring a total of %d containers", cm.restorer.totalContainers)
 
-	if _, err := unix.Seek(stateFile.FD(), 0, 0); err != nil {
-		return fmt.Errorf("rewinding state file: %w", err)
-	}
+	cm.restorer.stateFile = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(&header, stateFile), stateFile}
 
 	return cm.restorer.restoreContainerInfo(cm.l, &cm.l.root)
 }
@@ -561,6 +635,9 @@ func (cm *containerManager) onRestoreDone() error {
 	if err := cm.onStart(); err != nil {
 		return err
 	}
+	cm.l.startOOMKiller()
+	cm.l.startSwapper()
+	cm.l.startCompactor()
 
 	cm.l.restoreWaiters.Broadcast()
 	cm.restorer = nil
@@ -773,6 +850,23 @@ func (cm *containerManager) ListTraceSessions(_ *struct{}, out *[]seccheck.Sessi
 	return nil
 }
 
+// ReloadPolicyArgs are arguments to the ReloadPolicy method.
+type ReloadPolicyArgs struct {
+	// FilePayload contains the rules file.
+	urpc.FilePayload
+}
+
+// ReloadPolicy replaces the rules of all policy sinks in trace sessions with
+// the rules in the file. If the rules are invalid, the current rules are kept.
+func (cm *containerManager) ReloadPolicy(args *ReloadPolicyArgs, _ *struct{}) error {
+	log.Debugf("containerManager.ReloadPolicy")
+	if len(args.Files) != 1 {
+		return fmt.Errorf("rules file must be provided")
+	}
+	defer args.Files[0].Close()
+	return policy.Reload(args.Files[0])
+}
+
 // ProcfsDump dumps procfs state of the sandbox.
 func (cm *containerManager) ProcfsDump(_ *struct{}, out *[]procfs.ProcessProcfsDump) error {
 	log.Debugf("containerManager.ProcfsDump")
@@ -805,7 +899,16 @@ type MountArgs struct {
 	// FsType is the filesystem type.
 	FsType string
 
-	// FilePayload contains the source image FD, if required by the filesystem.
+	// VerityRootHash is the root hash used to verify an EROFS image, if
+	// non-empty.
+	VerityRootHash []byte
+
+	// Readonly indicates that the filesystem must be mounted read-only. EROFS
+	// filesystems are always read-only.
+	Readonly bool
+
+	// FilePayload contains the source image FD, if required by the filesystem,
+	// or the lisafs socket FD connected to the gofer for gofer filesystems.
 	urpc.FilePayload
 }
 
@@ -818,18 +921,9 @@ func (cm *containerManager) Mount(args *MountArgs, _ *struct{}) error {
 	var cu cleanup.Cleanup
 	defer cu.Clean()
 
-	eid := execID{cid: args.ContainerID}
-	ep, ok := cm.l.processes[eid]
-	if !ok {
-		return fmt.Errorf("container %v is deleted", args.ContainerID)
-	}
-	if ep.tg == nil {
-		return fmt.Errorf("container %v isn't started", args.ContainerID)
-	}
-
-	t := ep.tg.PIDNamespace().TaskWithID(initTID)
-	if t == nil {
-		return fmt.Errorf("failed to find init process")
+	t, err := cm.initTask(args.ContainerID)
+	if err != nil {
+		return err
 	}
 
 	source := args.Source
@@ -858,6 +952,30 @@ func (cm *containerManager) Mount(args *MountArgs, _ *struct{}) error {
 			GetFilesystemOptions: vfs.GetFilesystemOptions{
 				InternalMount: true,
 				Data:          fmt.Sprintf("ifd=%d", imageFD),
+				InternalData: erofs.InternalFilesystemOptions{
+					VerityRootHash: args.VerityRootHash,
+				},
+			},
+		}
+
+	case gofer.Name:
+		if len(args.FilePayload.Files) != 1 {
+			return fmt.Errorf("exactly one gofer socket must be provided")
+		}
+
+		sockFD, err := unix.Dup(int(args.FilePayload.Files[0].Fd()))
+		if err != nil {
+			return fmt.Errorf("failed to dup gofer socket FD: %v", err)
+		}
+		cu.Add(func() { unix.Close(sockFD) })
+
+		// Mounts added at runtime are typically updated from the host (e.g.
+		// rotated secrets), so always revalidate cached state.
+		data := goferMountData(sockFD, config.FileAccessShared, cm.l.root.conf)
+		opts = vfs.MountOptions{
+			ReadOnly: args.Readonly,
+			GetFilesystemOptions: vfs.GetFilesystemOptions{
+				Data: strings.Join(data, ","),
 			},
 		}
 
@@ -875,6 +993,15 @@ func (cm *containerManager) Mount(args *MountArgs, _ *struct{}) error {
 		Path:  fspath.Parse(dest),
 	}
 
+	if fstype == gofer.Name {
+		// Create the mount point if needed, like for mounts in the spec.
+		if vd, err := t.Kernel().VFS().GetDentryAt(ctx, t.Credentials(), &pop, &vfs.GetDentryOptions{}); err == nil {
+			vd.DecRef(ctx)
+		} else if err := t.Kernel().VFS().MakeSyntheticMountpoint(ctx, dest, root, t.Credentials()); err != nil {
+			return fmt.Errorf("creating mount point %q: %w", dest, err)
+		}
+	}
+
 	if _, err := t.Kernel().VFS().MountAt(ctx, t.Credentials(), source, &pop, fstype, &opts); err != nil {
 		return err
 	}
@@ -883,6 +1010,244 @@ func (cm *containerManager) Mount(args *MountArgs, _ *struct{}) error {
 	return nil
 }
 
+// initTask returns the init process of the given container.
+func (cm *containerManager) initTask(cid string) (*kernel.Task, error) {
+	cm.l.mu.Lock()
+	ep, ok := cm.l.processes[execID{cid: cid}]
+	cm.l.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("container %v is deleted", cid)
+	}
+	if ep.tg == nil {
+		return nil, fmt.Errorf("container %v isn't started", cid)
+	}
+	t := ep.tg.PIDNamespace().TaskWithID(initTID)
+	if t == nil {
+		return nil, fmt.Errorf("failed to find init process")
+	}
+	return t, nil
+}
+
+// UnmountArgs contains arguments to the Unmount method.
+type UnmountArgs struct {
+	// ContainerID is the container in which the filesystem is unmounted.
+	ContainerID string
+
+	// Destination is the mount target.
+	Destination string
+}
+
+// Unmount lazily unmounts the filesystem mounted at the given destination in
+// a container, like umount2(MNT_DETACH).
+func (cm *containerManager) Unmount(args *UnmountArgs, _ *struct{}) error {
+	log.Debugf("containerManager.Unmount, cid: %s, args: %+v", args.ContainerID, args)
+
+	t, err := cm.initTask(args.ContainerID)
+	if err != nil {
+		return err
+	}
+	dest := path.Clean(args.Destination)
+	if dest[0] != '/' {
+		return fmt.Errorf("absolute path must be provided for destination")
+	}
+
+	ctx := context.Background()
+	root := t.FSContext().RootDirectory()
+	defer root.DecRef(ctx)
+
+	pop := vfs.PathOperation{
+		Root:  root,
+		Start: root,
+		Path:  fspath.Parse(dest),
+	}
+	if err := t.Kernel().VFS().UmountAt(ctx, t.Credentials(), &pop, &vfs.UmountOptions{Flags: linux.MNT_DETACH}); err != nil {
+		return err
+	}
+	log.Infof("Unmounted %q in container %q", dest, args.ContainerID)
+	return nil
+}
+
+// AttachGPUArgs contains arguments to the AttachGPU method.
+type AttachGPUArgs struct {
+	// FilePayload contains the socket on which the gofer serves the host
+	// device file of the GPU.
+	urpc.FilePayload
+
+	// ContainerID is the container to which the GPU is attached.
+	ContainerID string
+
+	// Minor is the device minor number of the GPU, i.e. N in /dev/nvidiaN.
+	Minor uint32
+}
+
+// AttachGPU makes an NVIDIA GPU available to a running container by creating
+// /dev/nvidiaN in it, which is opened through the gofer connection in the
+// payload. nvproxy must be enabled.
+//
+// Only the device file is created, since the sentry's procfs does not provide
+// /proc/driver/nvidia. The gofer connection is not saved, so GPUs must be
+// attached again after restore; the restored /dev/nvidiaN is then reused.
+func (cm *containerManager) AttachGPU(args *AttachGPUArgs, _ *struct{}) error {
+	log.Debugf("containerManager.AttachGPU, cid: %s, minor: %d", args.ContainerID, args.Minor)
+	if !specutils.NVProxyEnabled(cm.l.root.spec, cm.l.root.conf) {
+		return fmt.Errorf("GPUs can only be attached with nvproxy enabled")
+	}
+	if args.Minor >= nvgpu.NV_CONTROL_DEVICE_MINOR {
+		return fmt.Errorf("invalid GPU minor number %d", args.Minor)
+	}
+	if len(args.FilePayload.Files) != 1 {
+		return fmt.Errorf("exactly one gofer socket must be provided")
+	}
+
+	t, err := cm.initTask(args.ContainerID)
+	if err != nil {
+		return err
+	}
+	devClient := t.Kernel().GetDevGoferClient(t.Kernel().ContainerName(args.ContainerID))
+	if devClient == nil {
+		return fmt.Errorf("container %q has no device gofer", args.ContainerID)
+	}
+	sockFD, err := unix.Dup(int(args.FilePayload.Files[0].Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to dup gofer socket FD: %v", err)
+	}
+
+	ctx := context.Background()
+	name := fmt.Sprintf("nvidia%d", args.Minor)
+	if err := devClient.AddDevice(ctx, name, sockFD); err != nil {
+		return err
+	}
+	root := t.FSContext().RootDirectory()
+	defer root.DecRef(ctx)
+	if err := dev.CreateDeviceFile(ctx, t.Kernel().VFS(), t.Credentials(), root, "/dev/"+name, nvgpu.NV_MAJOR_DEVICE_NUMBER, args.Minor, linux.S_IFCHR|0666, nil, nil); err != nil {
+		_ = devClient.RemoveDevice(name)
+		return fmt.Errorf("creating /dev/%s: %w", name, err)
+	}
+	log.Infof("Attached GPU /dev/%s to container %q", name, args.ContainerID)
+	return nil
+}
+
+// DetachGPUArgs contains arguments to the DetachGPU method.
+type DetachGPUArgs struct {
+	// ContainerID is the container from which the GPU is detached.
+	ContainerID string
+
+	// Minor is the device minor number of the GPU.
+	Minor uint32
+}
+
+// DetachGPU removes /dev/nvidiaN, attached with AttachGPU, from a running
+// container. Files already opened from it remain usable until closed.
+func (cm *containerManager) DetachGPU(args *DetachGPUArgs, _ *struct{}) error {
+	log.Debugf("containerManager.DetachGPU, cid: %s, minor: %d", args.ContainerID, args.Minor)
+
+	t, err := cm.initTask(args.ContainerID)
+	if err != nil {
+		return err
+	}
+	devClient := t.Kernel().GetDevGoferClient(t.Kernel().ContainerName(args.ContainerID))
+	if devClient == nil {
+		return fmt.Errorf("container %q has no device gofer", args.ContainerID)
+	}
+
+	ctx := context.Background()
+	name := fmt.Sprintf("nvidia%d", args.Minor)
+	if err := devClient.RemoveDevice(name); err != nil {
+		return err
+	}
+	root := t.FSContext().RootDirectory()
+	defer root.DecRef(ctx)
+	pop := vfs.PathOperation{
+		Root:  root,
+		Start: root,
+		Path:  fspath.Parse("/dev/" + name),
+	}
+	if err := t.Kernel().VFS().UnlinkAt(ctx, t.Credentials(), &pop); err != nil && !linuxerr.Equals(linuxerr.ENOENT, err) {
+		return fmt.Errorf("removing /dev/%s: %w", name, err)
+	}
+	log.Infof("Detached GPU /dev/%s from container %q", name, args.ContainerID)
+	return nil
+}
+
+// EnableVerityArgs contains arguments to the EnableVerity method.
+type EnableVerityArgs struct {
+	// ContainerID is the container in which verification is enabled.
+	ContainerID string
+
+	// Path is the path of the directory to verify in the container.
+	Path string
+
+	// RootHash is the expected root hash of the directory. If it is
+	// non-empty and the directory's root hash differs, EnableVerity fails.
+	RootHash []byte
+}
+
+// EnableVerity mounts a verity filesystem on top of a directory in a
+// container, which measures all files in the directory's filesystem below the
+// directory and verifies them when they're read, and returns the measured
+// root hash.
+func (cm *containerManager) EnableVerity(args *EnableVerityArgs, rootHash *[]byte) error {
+	log.Debugf("containerManager.EnableVerity, cid: %s, args: %+v", args.ContainerID, args)
+
+	t, err := cm.initTask(args.ContainerID)
+	if err != nil {
+		return err
+	}
+
+	dest := path.Clean(args.Path)
+	if dest[0] != '/' {
+		return fmt.Errorf("absolute path must be provided")
+	}
+
+	// Use the supervisor context, which provides the memory file used by the
+	// verity filesystem.
+	ctx := cm.l.k.SupervisorContext()
+	creds := t.Credentials()
+	vfsObj := t.Kernel().VFS()
+	root := t.FSContext().RootDirectory()
+	defer root.DecRef(ctx)
+
+	pop := vfs.PathOperation{
+		Root:               root,
+		Start:              root,
+		Path:               fspath.Parse(dest),
+		FollowFinalSymlink: true,
+	}
+	lowerRoot, err := vfsObj.GetDentryAt(ctx, creds, &pop, &vfs.GetDentryOptions{
+		CheckSearchable: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resolve %q: %w", dest, err)
+	}
+	defer lowerRoot.DecRef(ctx)
+
+	mnt, err := vfsObj.MountDisconnected(ctx, creds, "", verity.Name, &vfs.MountOptions{
+		ReadOnly: true,
+		GetFilesystemOptions: vfs.GetFilesystemOptions{
+			InternalMount: true,
+			InternalData: verity.InternalFilesystemOptions{
+				LowerRoot: lowerRoot,
+				Measure:   true,
+				RootHash:  args.RootHash,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create verity filesystem for %q: %w", dest, err)
+	}
+	defer mnt.DecRef(ctx)
+	hash, err := verity.RootHash(mnt.Filesystem())
+	if err != nil {
+		return err
+	}
+	if err := vfsObj.ConnectMountAt(ctx, creds, mnt, &pop); err != nil {
+		return fmt.Errorf("failed to mount verity filesystem at %q: %w", dest, err)
+	}
+	log.Infof("Enabled verity for %q in container %q, root hash: %x", dest, args.ContainerID, hash)
+	*rootHash = hash
+	return nil
+}
+
 // ContainerRuntimeState returns the runtime state of a container.
 func (cm *containerManager) ContainerRuntimeState(cid *string, state *ContainerRuntimeState) error {
 	log.Debugf("containerManager.ContainerRuntimeState: cid: %s", *cid)
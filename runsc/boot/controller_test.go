@@ -0,0 +1,174 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/nvgpu"
+	"gvisor.dev/gvisor/pkg/urpc"
+	"gvisor.dev/gvisor/runsc/specutils"
+)
+
+// testContainerManager returns a containerManager for a sandbox in which
+// container "created" has an init process that hasn't started, as no
+// application is actually run.
+func testContainerManager(nvproxy bool) *containerManager {
+	conf := testConfig()
+	conf.NVProxy = nvproxy
+	return &containerManager{
+		l: &Loader{
+			root: containerInfo{
+				conf: conf,
+				spec: testSpec(),
+			},
+			processes: map[execID]*execProcess{
+				{cid: "created"}: {},
+			},
+		},
+	}
+}
+
+// testGoferSocket returns one end of a socket pair, standing in for the
+// gofer connection serving a GPU.
+func testGoferSocket(t *testing.T) *os.File {
+	t.Helper()
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("socketpair failed: %v", err)
+	}
+	unix.Close(fds[1])
+	f := os.NewFile(uintptr(fds[0]), "gofer socket")
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestAttachGPUErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		nvproxy bool
+		spec    map[string]string
+		args    func(t *testing.T) *AttachGPUArgs
+		want    string
+	}{
+		{
+			name:    "nvproxy disabled",
+			nvproxy: false,
+			args: func(t *testing.T) *AttachGPUArgs {
+				return &AttachGPUArgs{
+					ContainerID: "created",
+					FilePayload: urpc.FilePayload{Files: []*os.File{testGoferSocket(t)}},
+				}
+			},
+			want: "nvproxy enabled",
+		},
+		{
+			name:    "nvproxy enabled by annotation",
+			nvproxy: false,
+			spec:    map[string]string{specutils.AnnotationNVProxy: "true"},
+			args: func(t *testing.T) *AttachGPUArgs {
+				return &AttachGPUArgs{
+					ContainerID: "created",
+					FilePayload: urpc.FilePayload{Files: []*os.File{testGoferSocket(t)}},
+				}
+			},
+			want: "isn't started",
+		},
+		{
+			name:    "control device",
+			nvproxy: true,
+			args: func(t *testing.T) *AttachGPUArgs {
+				return &AttachGPUArgs{
+					ContainerID: "created",
+					Minor:       nvgpu.NV_CONTROL_DEVICE_MINOR,
+					FilePayload: urpc.FilePayload{Files: []*os.File{testGoferSocket(t)}},
+				}
+			},
+			want: "invalid GPU minor number",
+		},
+		{
+			name:    "no gofer socket",
+			nvproxy: true,
+			args: func(*testing.T) *AttachGPUArgs {
+				return &AttachGPUArgs{ContainerID: "created"}
+			},
+			want: "exactly one gofer socket",
+		},
+		{
+			name:    "two gofer sockets",
+			nvproxy: true,
+			args: func(t *testing.T) *AttachGPUArgs {
+				return &AttachGPUArgs{
+					ContainerID: "created",
+					FilePayload: urpc.FilePayload{Files: []*os.File{testGoferSocket(t), testGoferSocket(t)}},
+				}
+			},
+			want: "exactly one gofer socket",
+		},
+		{
+			name:    "unknown container",
+			nvproxy: true,
+			args: func(t *testing.T) *AttachGPUArgs {
+				return &AttachGPUArgs{
+					ContainerID: "unknown",
+					FilePayload: urpc.FilePayload{Files: []*os.File{testGoferSocket(t)}},
+				}
+			},
+			want: "is deleted",
+		},
+		{
+			name:    "container not started",
+			nvproxy: true,
+			args: func(t *testing.T) *AttachGPUArgs {
+				return &AttachGPUArgs{
+					ContainerID: "created",
+					Minor:       1,
+					FilePayload: urpc.FilePayload{Files: []*os.File{testGoferSocket(t)}},
+				}
+			},
+			want: "isn't started",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cm := testContainerManager(tc.nvproxy)
+			cm.l.root.spec.Annotations = tc.spec
+			err := cm.AttachGPU(tc.args(t), nil)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("AttachGPU() got error %v, want error containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestDetachGPUErrors(t *testing.T) {
+	for _, tc := range []struct {
+		cid  string
+		want string
+	}{
+		{cid: "unknown", want: "is deleted"},
+		{cid: "created", want: "isn't started"},
+	} {
+		t.Run(tc.cid, func(t *testing.T) {
+			cm := testContainerManager(true /* nvproxy */)
+			err := cm.DetachGPU(&DetachGPUArgs{ContainerID: tc.cid, Minor: 1}, nil)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("DetachGPU() got error %v, want error containing %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsproxy
+
+import (
+	"container/list"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const (
+	// maxCacheTTL caps how long a response is cached, regardless of the TTLs
+	// of its records.
+	maxCacheTTL = time.Hour
+
+	// negativeCacheTTL is how long responses without records (e.g. NXDOMAIN)
+	// are cached if they carry no SOA record to take a TTL from.
+	negativeCacheTTL = 30 * time.Second
+)
+
+// cacheKey identifies a cached question.
+type cacheKey struct {
+	name  string
+	typ   dnsmessage.Type
+	class dnsmessage.Class
+}
+
+func keyOf(q dnsmessage.Question) cacheKey {
+	return cacheKey{
+		name:  strings.ToLower(q.Name.String()),
+		typ:   q.Type,
+		class: q.Class,
+	}
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	msg     dnsmessage.Message
+	stored  time.Time
+	expires time.Time
+}
+
+// cache is a fixed-size LRU cache of DNS responses.
+type cache struct {
+	// now returns the current time. It is a field so tests can control
+	// expiry.
+	now func() time.Time
+
+	mu sync.Mutex
+
+	// maxEntries is the maximum number of entries in the cache. It is
+	// immutable.
+	maxEntries int
+
+	// entries maps cache keys to elements of lru. Elements are ordered from
+	// most to least recently used.
+	entries map[cacheKey]*list.Element
+	lru     list.List
+}
+
+func newCache(maxEntries int) *cache {
+	return &cache{
+		now:        time.Now,
+		maxEntries: maxEntries,
+		entries:    make(map[cacheKey]*list.Element),
+	}
+}
+
+// get returns a copy of the cached response to q, with record TTLs reduced by
+// the time the response has spent in the cache.
+func (c *cache) get(q dnsmessage.Question) (dnsmessage.Message, bool) {
+	key := keyOf(q)
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return dnsmessage.Message{}, false
+	}
+	e := el.Value.(*cacheEntry)
+	if !now.Before(e.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return dnsmessage.Message{}, false
+	}
+	c.lru.MoveToFront(el)
+
+	elapsed := uint32(now.Sub(e.stored) / time.Second)
+	msg := e.msg
+	msg.Answers = agedResources(e.msg.Answers, elapsed)
+	msg.Authorities = agedResources(e.msg.Authorities, elapsed)
+	msg.Additionals = agedResources(e.msg.Additionals, elapsed)
+	return msg, true
+}
+
+// put caches msg as the response to q, if msg is cacheable.
+func (c *cache) put(q dnsmessage.Question, msg dnsmessage.Message) {
+	ttl, ok := cacheTTL(&msg)
+	if !ok {
+		return
+	}
+	key := keyOf(q)
+	now := c.now()
+	e := &cacheEntry{
+		key:     key,
+		msg:     msg,
+		stored:  now,
+		expires: now.Add(ttl),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	for c.lru.Len() >= c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+	c.entries[key] = c.lru.PushFront(e)
+}
+
+// cacheTTL returns how long msg may be cached, following RFC 2181 section 5.2
+// (the minimum TTL of its records) and RFC 2308 for negative responses.
+func cacheTTL(msg *dnsmessage.Message) (time.Duration, bool) {
+	if !msg.Response || msg.Truncated {
+		return 0, false
+	}
+	switch msg.RCode {
+	case dnsmessage.RCodeSuccess, dnsmessage.RCodeNameError:
+	default:
+		// Server failures and refusals are not cached, so that the next
+		// query can be retried against the upstream.
+		return 0, false
+	}
+
+	ttl := maxCacheTTL
+	found := false
+	for _, rrs := range [][]dnsmessage.Resource{msg.Answers, msg.Authorities} {
+		for i := range rrs {
+			d := time.Duration(rrs[i].Header.TTL) * time.Second
+			if soa, ok := rrs[i].Body.(*dnsmessage.SOAResource); ok {
+				// RFC 2308 section 5: negative responses are cached for the
+				// minimum of the SOA record's TTL and its MINIMUM field.
+				d = min(d, time.Duration(soa.MinTTL)*time.Second)
+			}
+			ttl = min(ttl, d)
+			found = true
+		}
+	}
+	if !found {
+		ttl = negativeCacheTTL
+	}
+	return ttl, ttl > 0
+}
+
+// agedResources returns a copy of rrs with TTLs reduced by elapsed seconds.
+// EDNS(0) OPT pseudo-records, whose TTL field holds flags, are left
+// unchanged.
+func agedResources(rrs []dnsmessage.Resource, elapsed uint32) []dnsmessage.Resource {
+	if len(rrs) == 0 {
+		return nil
+	}
+	aged := make([]dnsmessage.Resource, len(rrs))
+	copy(aged, rrs)
+	for i := range aged {
+		h := &aged[i].Header
+		if h.Type == dnsmessage.TypeOPT {
+			continue
+		}
+		if h.TTL > elapsed {
+			h.TTL -= elapsed
+		} else {
+			h.TTL = 0
+		}
+	}
+	return aged
+}
@@ -0,0 +1,338 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsproxy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"gvisor.dev/gvisor/pkg/tcpip/adapters/gonet"
+)
+
+var testQuestion = dnsmessage.Question{
+	Name:  dnsmessage.MustNewName("example.com."),
+	Type:  dnsmessage.TypeA,
+	Class: dnsmessage.ClassINET,
+}
+
+func newAResponse(id uint16, ttls ...uint32) dnsmessage.Message {
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 id,
+			Response:           true,
+			RecursionAvailable: true,
+		},
+		Questions: []dnsmessage.Question{testQuestion},
+	}
+	for i, ttl := range ttls {
+		msg.Answers = append(msg.Answers, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{
+				Name:  testQuestion.Name,
+				Type:  dnsmessage.TypeA,
+				Class: dnsmessage.ClassINET,
+				TTL:   ttl,
+			},
+			Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, byte(i + 1)}},
+		})
+	}
+	return msg
+}
+
+// fakeClock is a controllable time source for cache tests.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func newTestCache(maxEntries int) (*cache, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	c := newCache(maxEntries)
+	c.now = clock.now
+	return c, clock
+}
+
+func TestCacheTTL(t *testing.T) {
+	c, clock := newTestCache(10)
+	c.put(testQuestion, newAResponse(1, 300, 60))
+
+	clock.t = clock.t.Add(20 * time.Second)
+	msg, ok := c.get(testQuestion)
+	if !ok {
+		t.Fatalf("get() after 20s missed, want hit")
+	}
+	for i, want := range []uint32{280, 40} {
+		if got := msg.Answers[i].Header.TTL; got != want {
+			t.Errorf("got Answers[%d] TTL = %d, want = %d", i, got, want)
+		}
+	}
+
+	// The entry expires with its shortest-lived record.
+	clock.t = clock.t.Add(40 * time.Second)
+	if _, ok := c.get(testQuestion); ok {
+		t.Errorf("get() after 60s hit, want miss")
+	}
+}
+
+func TestCacheCaseInsensitive(t *testing.T) {
+	c, _ := newTestCache(10)
+	c.put(testQuestion, newAResponse(1, 300))
+	q := testQuestion
+	q.Name = dnsmessage.MustNewName("EXAMPLE.com.")
+	if _, ok := c.get(q); !ok {
+		t.Errorf("get(%v) missed, want hit", q.Name)
+	}
+}
+
+func TestCacheNotCacheable(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		msg  dnsmessage.Message
+	}{
+		{
+			name: "server failure",
+			msg: func() dnsmessage.Message {
+				m := newAResponse(1)
+				m.RCode = dnsmessage.RCodeServerFailure
+				return m
+			}(),
+		},
+		{
+			name: "truncated",
+			msg: func() dnsmessage.Message {
+				m := newAResponse(1, 300)
+				m.Truncated = true
+				return m
+			}(),
+		},
+		{
+			name: "zero TTL",
+			msg:  newAResponse(1, 300, 0),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c, _ := newTestCache(10)
+			c.put(testQuestion, test.msg)
+			if _, ok := c.get(testQuestion); ok {
+				t.Errorf("get() hit, want miss")
+			}
+		})
+	}
+}
+
+func TestCacheNegative(t *testing.T) {
+	c, clock := newTestCache(10)
+	msg := newAResponse(1)
+	msg.RCode = dnsmessage.RCodeNameError
+	msg.Authorities = []dnsmessage.Resource{{
+		Header: dnsmessage.ResourceHeader{
+			Name:  dnsmessage.MustNewName("com."),
+			Type:  dnsmessage.TypeSOA,
+			Class: dnsmessage.ClassINET,
+			TTL:   900,
+		},
+		Body: &dnsmessage.SOAResource{
+			NS:     dnsmessage.MustNewName("ns.com."),
+			MBox:   dnsmessage.MustNewName("hostmaster.com."),
+			MinTTL: 10,
+		},
+	}}
+	c.put(testQuestion, msg)
+
+	clock.t = clock.t.Add(9 * time.Second)
+	if _, ok := c.get(testQuestion); !ok {
+		t.Fatalf("get() after 9s missed, want hit")
+	}
+	clock.t = clock.t.Add(time.Second)
+	if _, ok := c.get(testQuestion); ok {
+		t.Errorf("get() after SOA MINIMUM expired hit, want miss")
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c, _ := newTestCache(2)
+	questions := make([]dnsmessage.Question, 3)
+	for i := range questions {
+		questions[i] = testQuestion
+		questions[i].Name = dnsmessage.MustNewName(fmt.Sprintf("host%d.example.com.", i))
+	}
+	c.put(questions[0], newAResponse(1, 300))
+	c.put(questions[1], newAResponse(1, 300))
+	// Use questions[0] so that questions[1] is least recently used.
+	if _, ok := c.get(questions[0]); !ok {
+		t.Fatalf("get(%v) missed, want hit", questions[0].Name)
+	}
+	c.put(questions[2], newAResponse(1, 300))
+
+	for i, want := range []bool{true, false, true} {
+		if _, ok := c.get(questions[i]); ok != want {
+			t.Errorf("got get(%v) hit = %t, want = %t", questions[i].Name, ok, want)
+		}
+	}
+}
+
+func TestParseUpstream(t *testing.T) {
+	d := &gonet.Dialer{}
+	for _, test := range []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{spec: "192.0.2.1", want: "udp://192.0.2.1:53"},
+		{spec: "192.0.2.1:5353", want: "udp://192.0.2.1:5353"},
+		{spec: "2001:db8::1", want: "udp://[2001:db8::1]:53"},
+		{spec: "[2001:db8::1]", want: "udp://[2001:db8::1]:53"},
+		{spec: "tcp://192.0.2.1", want: "tcp://192.0.2.1:53"},
+		{spec: "tls://192.0.2.1#dns.example", want: "tls://192.0.2.1:853#dns.example"},
+		{spec: "tls://192.0.2.1", wantErr: true},
+		{spec: "https://192.0.2.1", wantErr: true},
+		{spec: "dns.example:53", wantErr: true},
+	} {
+		u, err := parseUpstream(d, test.spec, nil /* rootCAs */)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseUpstream(%q) = %v, want error", test.spec, u)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseUpstream(%q) failed: %v", test.spec, err)
+			continue
+		}
+		if got := u.String(); got != test.want {
+			t.Errorf("got parseUpstream(%q) = %q, want = %q", test.spec, got, test.want)
+		}
+	}
+}
+
+// fakeUpstream answers every query with a fixed A record.
+type fakeUpstream struct {
+	queries int
+	fail    bool
+}
+
+func (f *fakeUpstream) exchange(ctx context.Context, q []byte) ([]byte, error) {
+	f.queries++
+	if f.fail {
+		return nil, fmt.Errorf("upstream unavailable")
+	}
+	var query dnsmessage.Message
+	if err := query.Unpack(q); err != nil {
+		return nil, err
+	}
+	resp := newAResponse(query.ID, 300)
+	resp.Questions = query.Questions
+	return resp.Pack()
+}
+
+func (f *fakeUpstream) String() string {
+	return "fake"
+}
+
+func packQuery(t *testing.T, id uint16) []byte {
+	t.Helper()
+	q := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{testQuestion},
+	}
+	b, err := q.Pack()
+	if err != nil {
+		t.Fatalf("Pack() failed: %v", err)
+	}
+	return b
+}
+
+func TestResolve(t *testing.T) {
+	failing := &fakeUpstream{fail: true}
+	working := &fakeUpstream{}
+	p := &Proxy{
+		upstreams: []upstream{failing, working},
+		cache:     newCache(10),
+	}
+
+	for i, id := range []uint16{1, 2} {
+		b := p.resolve(packQuery(t, id), true /* udp */)
+		var resp dnsmessage.Message
+		if err := resp.Unpack(b); err != nil {
+			t.Fatalf("query %d: Unpack() failed: %v", i, err)
+		}
+		if resp.ID != id {
+			t.Errorf("query %d: got ID = %d, want = %d", i, resp.ID, id)
+		}
+		if resp.RCode != dnsmessage.RCodeSuccess || len(resp.Answers) != 1 {
+			t.Errorf("query %d: got RCode = %v with %d answers, want success with 1 answer", i, resp.RCode, len(resp.Answers))
+		}
+	}
+	// The second query should have been served from the cache.
+	if failing.queries != 1 || working.queries != 1 {
+		t.Errorf("got %d, %d upstream queries, want 1, 1", failing.queries, working.queries)
+	}
+
+	// With no working upstream, uncached queries get SERVFAIL.
+	p = &Proxy{
+		upstreams: []upstream{failing},
+		cache:     newCache(10),
+	}
+	var resp dnsmessage.Message
+	if err := resp.Unpack(p.resolve(packQuery(t, 3), true /* udp */)); err != nil {
+		t.Fatalf("Unpack() failed: %v", err)
+	}
+	if resp.RCode != dnsmessage.RCodeServerFailure {
+		t.Errorf("got RCode = %v, want = %v", resp.RCode, dnsmessage.RCodeServerFailure)
+	}
+}
+
+func TestMaybeTruncate(t *testing.T) {
+	query := dnsmessage.Message{Questions: []dnsmessage.Question{testQuestion}}
+	ttls := make([]uint32, 40)
+	for i := range ttls {
+		ttls[i] = 300
+	}
+	resp := newAResponse(1, ttls...)
+	b, err := resp.Pack()
+	if err != nil {
+		t.Fatalf("Pack() failed: %v", err)
+	}
+	if len(b) <= minUDPResponseSize {
+		t.Fatalf("test response is %d bytes, want > %d", len(b), minUDPResponseSize)
+	}
+
+	if got := maybeTruncate(&query, &resp, b, false /* udp */); len(got) != len(b) {
+		t.Errorf("got %d byte TCP response, want %d bytes", len(got), len(b))
+	}
+	var truncated dnsmessage.Message
+	if err := truncated.Unpack(maybeTruncate(&query, &resp, b, true /* udp */)); err != nil {
+		t.Fatalf("Unpack() failed: %v", err)
+	}
+	if !truncated.Truncated || len(truncated.Answers) != 0 {
+		t.Errorf("got Truncated = %t with %d answers, want true with 0 answers", truncated.Truncated, len(truncated.Answers))
+	}
+
+	// A client advertising a larger EDNS(0) payload size gets the full
+	// response.
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(4096, dnsmessage.RCodeSuccess, false); err != nil {
+		t.Fatalf("SetEDNS0() failed: %v", err)
+	}
+	query.Additionals = []dnsmessage.Resource{{Header: opt, Body: &dnsmessage.OPTResource{}}}
+	if got := maybeTruncate(&query, &resp, b, true /* udp */); len(got) != len(b) {
+		t.Errorf("got %d byte EDNS(0) response, want %d bytes", len(got), len(b))
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dnsproxy implements an optional caching DNS forwarder that runs in
+// the sandbox. It listens on netstack's loopback interface, like
+// systemd-resolved's stub resolver, and forwards cache misses to configured
+// upstream servers through netstack.
+package dnsproxy
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/adapters/gonet"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// ListenAddr is the address the proxy serves queries on.
+var ListenAddr = tcpip.FullAddress{
+	Addr: tcpip.AddrFrom4([4]byte{127, 0, 0, 53}),
+	Port: 53,
+}
+
+const (
+	// defaultCacheEntries is the number of responses cached if
+	// Options.CacheEntries is 0.
+	defaultCacheEntries = 4096
+
+	// upstreamTimeout bounds the time spent forwarding a single query,
+	// across all upstreams.
+	upstreamTimeout = 5 * time.Second
+
+	// maxInflight is the maximum number of queries resolved concurrently.
+	// Queries received beyond this limit are dropped, and will be retried by
+	// the client.
+	maxInflight = 256
+
+	// maxQuerySize is the size of the buffer queries are received into.
+	maxQuerySize = 4096
+
+	// minUDPResponseSize is the maximum response size for clients that
+	// don't advertise a larger one using EDNS(0) (RFC 1035 section 4.2.1).
+	minUDPResponseSize = 512
+)
+
+// Options configures a Proxy.
+type Options struct {
+	// Upstreams are the servers that queries are forwarded to, tried in
+	// order. See parseUpstream for the accepted forms.
+	Upstreams []string
+
+	// RootCAs contains PEM-encoded certificates used to verify DNS over TLS
+	// upstreams.
+	RootCAs []byte
+
+	// CacheEntries is the maximum number of cached responses. If 0, a
+	// default is used.
+	CacheEntries int
+}
+
+// Proxy is a caching DNS forwarder. It serves queries over both UDP and TCP,
+// so that clients can retry truncated responses.
+type Proxy struct {
+	conn      *gonet.UDPConn
+	listener  *gonet.TCPListener
+	upstreams []upstream
+	cache     *cache
+	inflight  chan struct{}
+}
+
+// New creates a Proxy listening on ListenAddr in s. The loopback interface
+// must already be configured. Call Start to begin serving queries.
+func New(s *stack.Stack, opts Options) (*Proxy, error) {
+	if len(opts.Upstreams) == 0 {
+		return nil, fmt.Errorf("no DNS upstreams configured")
+	}
+	var rootCAs *x509.CertPool
+	if len(opts.RootCAs) > 0 {
+		rootCAs = x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(opts.RootCAs) {
+			return nil, fmt.Errorf("no valid root certificates for DNS over TLS")
+		}
+	}
+	d := &gonet.Dialer{Stack: s}
+	p := &Proxy{
+		cache:    newCache(defaultCacheEntries),
+		inflight: make(chan struct{}, maxInflight),
+	}
+	if opts.CacheEntries > 0 {
+		p.cache = newCache(opts.CacheEntries)
+	}
+	for _, spec := range opts.Upstreams {
+		u, err := parseUpstream(d, spec, rootCAs)
+		if err != nil {
+			return nil, err
+		}
+		p.upstreams = append(p.upstreams, u)
+	}
+
+	laddr := ListenAddr
+	conn, err := gonet.DialUDP(s, &laddr, nil /* raddr */, ipv4.ProtocolNumber)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s:%d: %w", ListenAddr.Addr, ListenAddr.Port, err)
+	}
+	listener, err := gonet.ListenTCP(s, ListenAddr, ipv4.ProtocolNumber)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("listening on %s:%d: %w", ListenAddr.Addr, ListenAddr.Port, err)
+	}
+	p.conn = conn
+	p.listener = listener
+	return p, nil
+}
+
+// Start starts serving queries in a new goroutine.
+func (p *Proxy) Start() {
+	log.Infof("DNS proxy listening on %s:%d, upstreams: %v", ListenAddr.Addr, ListenAddr.Port, p.upstreams)
+	go p.serveUDP()
+	go p.serveTCP()
+}
+
+// Close stops serving queries.
+func (p *Proxy) Close() error {
+	p.listener.Close()
+	return p.conn.Close()
+}
+
+func (p *Proxy) serveUDP() {
+	for {
+		buf := make([]byte, maxQuerySize)
+		n, from, err := p.conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Warningf("DNS proxy stopped: %v", err)
+			}
+			return
+		}
+		select {
+		case p.inflight <- struct{}{}:
+		default:
+			log.Debugf("DNS proxy dropping query from %v: too many queries in flight", from)
+			continue
+		}
+		go func() {
+			defer func() { <-p.inflight }()
+			if resp := p.resolve(buf[:n], true /* udp */); resp != nil {
+				if _, err := p.conn.WriteTo(resp, from); err != nil {
+					log.Debugf("DNS proxy failed to respond to %v: %v", from, err)
+				}
+			}
+		}()
+	}
+}
+
+func (p *Proxy) serveTCP() {
+	for {
+		c, err := p.listener.Accept()
+		if err != nil {
+			log.Debugf("DNS proxy stopped accepting TCP connections: %v", err)
+			return
+		}
+		go p.serveTCPConn(c)
+	}
+}
+
+// serveTCPConn serves length-prefixed queries on c (RFC 1035 section 4.2.2)
+// until the client closes the connection or is idle for upstreamTimeout.
+func (p *Proxy) serveTCPConn(c net.Conn) {
+	defer c.Close()
+	for {
+		c.SetReadDeadline(time.Now().Add(upstreamTimeout))
+		var lenBuf [2]byte
+		if _, err := io.ReadFull(c, lenBuf[:]); err != nil {
+			return
+		}
+		q := make([]byte, binary.BigEndian.Uint16(lenBuf[:]))
+		if _, err := io.ReadFull(c, q); err != nil {
+			return
+		}
+		resp := p.resolve(q, false /* udp */)
+		if resp == nil {
+			return
+		}
+		msg := make([]byte, 2+len(resp))
+		binary.BigEndian.PutUint16(msg, uint16(len(resp)))
+		copy(msg[2:], resp)
+		if _, err := c.Write(msg); err != nil {
+			return
+		}
+	}
+}
+
+// resolve returns the packed response to the packed query q, or nil if no
+// response should be sent. If udp is true, responses that exceed the
+// client's UDP payload size are truncated.
+func (p *Proxy) resolve(q []byte, udp bool) []byte {
+	var query dnsmessage.Message
+	if err := query.Unpack(q); err != nil || query.Response {
+		// Drop malformed queries, as well as responses that were
+		// presumably sent to us by mistake.
+		return nil
+	}
+	// Only standard queries for a single question are cached, which covers
+	// everything sent by common stub resolvers.
+	cacheable := query.OpCode == 0 && len(query.Questions) == 1
+	if cacheable {
+		if resp, ok := p.cache.get(query.Questions[0]); ok {
+			resp.ID = query.ID
+			resp.RecursionDesired = query.RecursionDesired
+			b, err := resp.Pack()
+			if err == nil {
+				return maybeTruncate(&query, &resp, b, udp)
+			}
+		}
+	}
+
+	b, err := p.forward(q)
+	if err != nil {
+		log.Debugf("DNS proxy failed to forward query: %v", err)
+		return serverFailure(&query)
+	}
+	var resp dnsmessage.Message
+	if err := resp.Unpack(b); err != nil || resp.ID != query.ID {
+		return serverFailure(&query)
+	}
+	if cacheable && len(resp.Questions) == 1 && keyOf(resp.Questions[0]) == keyOf(query.Questions[0]) {
+		p.cache.put(query.Questions[0], resp)
+	}
+	return maybeTruncate(&query, &resp, b, udp)
+}
+
+// forward sends q to each upstream in turn, returning the first response.
+func (p *Proxy) forward(q []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamTimeout)
+	defer cancel()
+	var errs []error
+	for _, u := range p.upstreams {
+		resp, err := u.exchange(ctx, q)
+		if err == nil {
+			return resp, nil
+		}
+		errs = append(errs, fmt.Errorf("%v: %w", u, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// serverFailure returns a packed SERVFAIL response to query.
+func serverFailure(query *dnsmessage.Message) []byte {
+	resp := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 query.ID,
+			Response:           true,
+			OpCode:             query.OpCode,
+			RecursionDesired:   query.RecursionDesired,
+			RecursionAvailable: true,
+			RCode:              dnsmessage.RCodeServerFailure,
+		},
+		Questions: query.Questions,
+	}
+	b, err := resp.Pack()
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// maybeTruncate returns the packed response b if it was requested over TCP or
+// fits in the UDP payload size advertised by query. Otherwise, it returns resp
+// with only its header and question and the TC bit set, prompting the client
+// to retry over TCP.
+func maybeTruncate(query, resp *dnsmessage.Message, b []byte, udp bool) []byte {
+	if !udp {
+		return b
+	}
+	size := minUDPResponseSize
+	for _, rr := range query.Additionals {
+		if rr.Header.Type == dnsmessage.TypeOPT {
+			// The CLASS field of an OPT record holds the requestor's UDP
+			// payload size (RFC 6891 section 6.1.2).
+			size = max(size, int(rr.Header.Class))
+		}
+	}
+	if len(b) <= size {
+		return b
+	}
+	t := dnsmessage.Message{
+		Header:    resp.Header,
+		Questions: resp.Questions,
+	}
+	t.Truncated = true
+	tb, err := t.Pack()
+	if err != nil {
+		return nil
+	}
+	return tb
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsproxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"gvisor.dev/gvisor/pkg/tcpip/adapters/gonet"
+)
+
+// maxUDPResponseSize is the largest response accepted over UDP. Larger
+// responses may be received over stream transports.
+const maxUDPResponseSize = 4096
+
+// upstream is a DNS server that queries are forwarded to.
+type upstream interface {
+	// exchange sends the packed query q and returns the packed response.
+	exchange(ctx context.Context, q []byte) ([]byte, error)
+
+	// String returns the upstream specification, for logging.
+	String() string
+}
+
+// parseUpstream parses an upstream specification. Supported forms are:
+//
+//   - "ip:port" or "udp://ip:port": plain DNS over UDP, retried over TCP if
+//     the response is truncated.
+//   - "tcp://ip:port": plain DNS over TCP.
+//   - "tls://ip:port#server-name": DNS over TLS (RFC 7858), verifying the
+//     server's certificate against server-name.
+//
+// The port defaults to 53, or 853 for DNS over TLS.
+//
+// rootCAs is the certificate pool used to verify DNS over TLS servers. The
+// sandbox cannot read the host's certificate store, so the pool must be
+// provided by the caller.
+func parseUpstream(d *gonet.Dialer, spec string, rootCAs *x509.CertPool) (upstream, error) {
+	scheme, rest, ok := strings.Cut(spec, "://")
+	if !ok {
+		scheme, rest = "udp", spec
+	}
+	var serverName string
+	if scheme == "tls" {
+		rest, serverName, ok = strings.Cut(rest, "#")
+		if !ok || serverName == "" {
+			return nil, fmt.Errorf("DNS over TLS upstream %q must specify a server name after '#'", spec)
+		}
+	}
+	defaultPort := "53"
+	if scheme == "tls" {
+		defaultPort = "853"
+	}
+	addr := rest
+	if _, _, err := net.SplitHostPort(rest); err != nil {
+		addr = net.JoinHostPort(strings.Trim(rest, "[]"), defaultPort)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream %q: %w", spec, err)
+	}
+	if net.ParseIP(host) == nil {
+		return nil, fmt.Errorf("invalid upstream %q: host must be an IP address", spec)
+	}
+
+	switch scheme {
+	case "udp":
+		return &udpUpstream{dialer: d, addr: addr}, nil
+	case "tcp":
+		return &streamUpstream{dialer: d, addr: addr}, nil
+	case "tls":
+		return &streamUpstream{
+			dialer: d,
+			addr:   addr,
+			tlsConfig: &tls.Config{
+				ServerName: serverName,
+				RootCAs:    rootCAs,
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid upstream %q: unsupported scheme %q", spec, scheme)
+	}
+}
+
+// udpUpstream forwards queries over UDP.
+type udpUpstream struct {
+	dialer *gonet.Dialer
+	addr   string
+}
+
+// exchange implements upstream.exchange.
+func (u *udpUpstream) exchange(ctx context.Context, q []byte) ([]byte, error) {
+	c, err := u.dialer.DialContext(ctx, "udp", u.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		c.SetDeadline(deadline)
+	}
+	if _, err := c.Write(q); err != nil {
+		return nil, err
+	}
+
+	id := binary.BigEndian.Uint16(q)
+	buf := make([]byte, maxUDPResponseSize)
+	for {
+		n, err := c.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// Ignore stray datagrams that don't match the query, as a
+		// resolver would.
+		if n < 12 || binary.BigEndian.Uint16(buf) != id {
+			continue
+		}
+		var p dnsmessage.Parser
+		h, err := p.Start(buf[:n])
+		if err != nil {
+			continue
+		}
+		if h.Truncated {
+			// Retry over TCP to get the full response (RFC 7766).
+			s := streamUpstream{dialer: u.dialer, addr: u.addr}
+			return s.exchange(ctx, q)
+		}
+		return buf[:n], nil
+	}
+}
+
+// String implements upstream.String.
+func (u *udpUpstream) String() string {
+	return "udp://" + u.addr
+}
+
+// streamUpstream forwards queries over TCP, optionally wrapped in TLS.
+type streamUpstream struct {
+	dialer    *gonet.Dialer
+	addr      string
+	tlsConfig *tls.Config
+}
+
+// exchange implements upstream.exchange.
+func (s *streamUpstream) exchange(ctx context.Context, q []byte) ([]byte, error) {
+	c, err := s.dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	if s.tlsConfig != nil {
+		c = tls.Client(c, s.tlsConfig)
+	}
+	defer c.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		c.SetDeadline(deadline)
+	}
+
+	// Messages over stream transports are prefixed with their length
+	// (RFC 1035 section 4.2.2).
+	msg := make([]byte, 2+len(q))
+	binary.BigEndian.PutUint16(msg, uint16(len(q)))
+	copy(msg[2:], q)
+	if _, err := c.Write(msg); err != nil {
+		return nil, err
+	}
+	var lenBuf [2]byte
+	if _, err := io.ReadFull(c, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(lenBuf[:]))
+	if _, err := io.ReadFull(c, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// String implements upstream.String.
+func (s *streamUpstream) String() string {
+	if s.tlsConfig != nil {
+		return fmt.Sprintf("tls://%s#%s", s.addr, s.tlsConfig.ServerName)
+	}
+	return "tcp://" + s.addr
+}
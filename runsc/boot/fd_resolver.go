@@ -0,0 +1,101 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"context"
+	"fmt"
+
+	"gvisor.dev/gvisor/pkg/fd"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/host"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/unet"
+	"gvisor.dev/gvisor/pkg/urpc"
+)
+
+// FDResolverResolve is the urpc method called on the FD resolver passed to
+// the sandbox on restore, for host FDs of checkpointed containers that were
+// not passed to the sandbox, e.g. host files and sockets imported with
+// --pass-fd. Its argument is a *ResolveFDArgs and its result a
+// *ResolveFDResult.
+//
+// The FD resolver is served by runsc, which re-establishes host FDs matching
+// --restore-host-fds and forwards the others to the service listening on
+// --restore-fd-resolver, if any. Such a service must implement the same
+// method.
+const FDResolverResolve = "FDResolver.Resolve"
+
+// ResolveFDArgs are the arguments to FDResolverResolve.
+type ResolveFDArgs struct {
+	// ContainerName is the name of the container that the host FD belongs
+	// to.
+	ContainerName string `json:"container_name"`
+
+	// FD is the application FD that the host FD was imported as.
+	FD int `json:"fd"`
+
+	// Info describes the host FD when it was saved.
+	Info host.FDInfo `json:"info"`
+}
+
+// ResolveFDResult is the result of FDResolverResolve. It contains exactly one
+// file, the re-established host FD.
+type ResolveFDResult struct {
+	urpc.FilePayload
+}
+
+// restoreFDResolver implements host.FDResolver by calling FDResolverResolve on
+// the FD resolver passed to the sandbox on restore.
+type restoreFDResolver struct {
+	client *urpc.Client
+}
+
+func newRestoreFDResolver(f *fd.FD) (*restoreFDResolver, error) {
+	sock, err := unet.NewSocket(f.Release())
+	if err != nil {
+		return nil, fmt.Errorf("creating FD resolver socket: %w", err)
+	}
+	return &restoreFDResolver{client: urpc.NewClient(sock)}, nil
+}
+
+// ResolveFD implements host.FDResolver.ResolveFD.
+func (r *restoreFDResolver) ResolveFD(ctx context.Context, id vfs.RestoreID, info host.FDInfo) (int, error) {
+	args := ResolveFDArgs{ContainerName: id.ContainerName, Info: info}
+	if _, err := fmt.Sscanf(id.Path, "host:%d", &args.FD); err != nil {
+		return -1, fmt.Errorf("invalid host FD restore ID %q: %w", id, err)
+	}
+	var res ResolveFDResult
+	if err := r.client.Call(FDResolverResolve, &args, &res); err != nil {
+		return -1, err
+	}
+	defer func() {
+		for _, f := range res.Files {
+			f.Close()
+		}
+	}()
+	if len(res.Files) != 1 {
+		return -1, fmt.Errorf("FD resolver returned %d files, want 1", len(res.Files))
+	}
+	f, err := res.ReleaseFD(0)
+	if err != nil {
+		return -1, err
+	}
+	return f.Release(), nil
+}
+
+// Close closes the connection to the FD resolver.
+func (r *restoreFDResolver) Close() error {
+	return r.client.Close()
+}
@@ -26,7 +26,10 @@ import (
 	"gvisor.dev/gvisor/pkg/seccomp"
 	"gvisor.dev/gvisor/pkg/seccomp/precompiledseccomp"
 	"gvisor.dev/gvisor/pkg/sentry/devices/accel"
+	"gvisor.dev/gvisor/pkg/sentry/devices/drmproxy"
+	"gvisor.dev/gvisor/pkg/sentry/devices/hostdev"
 	"gvisor.dev/gvisor/pkg/sentry/devices/nvproxy"
+	"gvisor.dev/gvisor/pkg/sentry/devices/sndproxy"
 	"gvisor.dev/gvisor/pkg/sentry/devices/tpuproxy"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
 )
@@ -37,10 +40,20 @@ type Options struct {
 	HostNetwork           bool
 	HostNetworkRawSockets bool
 	HostFilesystem        bool
+	HostMemfd             bool
+	HostUDSCredentials    bool
 	ProfileEnable         bool
 	NVProxy               bool
 	TPUProxy              bool
+	DRMProxy              bool
+	SNDProxy              bool
+	IOUring               bool
+	HugetlbMemoryFile     bool
 	ControllerFD          uint32
+
+	// HostCharDevIoctls are the ioctl commands that may be forwarded to host
+	// character devices passed through to the sandbox.
+	HostCharDevIoctls []uint32
 }
 
 // isInstrumentationEnabled returns whether there are any
@@ -62,10 +75,17 @@ func (opt Options) ConfigKey() string {
 	sb.WriteString(fmt.Sprintf("HostNetwork=%t ", opt.HostNetwork))
 	sb.WriteString(fmt.Sprintf("HostNetworkRawSockets=%t ", opt.HostNetworkRawSockets))
 	sb.WriteString(fmt.Sprintf("HostFilesystem=%t ", opt.HostFilesystem))
+	sb.WriteString(fmt.Sprintf("HostMemfd=%t ", opt.HostMemfd))
+	sb.WriteString(fmt.Sprintf("HostUDSCredentials=%t ", opt.HostUDSCredentials))
 	sb.WriteString(fmt.Sprintf("ProfileEnable=%t ", opt.ProfileEnable))
 	sb.WriteString(fmt.Sprintf("Instrumentation=%t ", isInstrumentationEnabled()))
 	sb.WriteString(fmt.Sprintf("NVProxy=%t ", opt.NVProxy))
 	sb.WriteString(fmt.Sprintf("TPUProxy=%t ", opt.TPUProxy))
+	sb.WriteString(fmt.Sprintf("DRMProxy=%t ", opt.DRMProxy))
+	sb.WriteString(fmt.Sprintf("SNDProxy=%t ", opt.SNDProxy))
+	sb.WriteString(fmt.Sprintf("IOUring=%t ", opt.IOUring))
+	sb.WriteString(fmt.Sprintf("HugetlbMemoryFile=%t ", opt.HugetlbMemoryFile))
+	sb.WriteString(fmt.Sprintf("HostCharDevIoctls=%#x ", opt.HostCharDevIoctls))
 	return strings.TrimSpace(sb.String())
 }
 
@@ -86,6 +106,12 @@ func Warnings(opt Options) []string {
 	if opt.HostFilesystem {
 		warnings = append(warnings, "host filesystem enabled: syscall filters less restrictive!")
 	}
+	if opt.HostMemfd {
+		warnings = append(warnings, "host memfd enabled: syscall filters less restrictive!")
+	}
+	if opt.HostUDSCredentials {
+		warnings = append(warnings, "host UDS credentials enabled: syscall filters less restrictive!")
+	}
 	if isInstrumentationEnabled() {
 		warnings = append(warnings, "instrumentation enabled: syscall filters less restrictive!")
 	}
@@ -95,6 +121,21 @@ func Warnings(opt Options) []string {
 	if opt.TPUProxy {
 		warnings = append(warnings, "TPU device proxy enabled: syscall filters less restrictive!")
 	}
+	if opt.DRMProxy {
+		warnings = append(warnings, "DRM device proxy enabled: syscall filters less restrictive!")
+	}
+	if opt.SNDProxy {
+		warnings = append(warnings, "sound device proxy enabled: syscall filters less restrictive!")
+	}
+	if opt.IOUring {
+		warnings = append(warnings, "io_uring checkpoint I/O enabled: syscall filters less restrictive!")
+	}
+	if opt.HugetlbMemoryFile {
+		warnings = append(warnings, "hugetlb memory file enabled: syscall filters less restrictive!")
+	}
+	if len(opt.HostCharDevIoctls) > 0 {
+		warnings = append(warnings, "host character device ioctls enabled: syscall filters less restrictive!")
+	}
 	return warnings
 }
 
@@ -136,6 +177,12 @@ func rules(opt Options, vars precompiledseccomp.Values) (seccomp.SyscallRules, s
 	if opt.HostFilesystem {
 		s.Merge(hostFilesystemFilters())
 	}
+	if opt.HostMemfd {
+		s.Merge(hostMemfdFilters())
+	}
+	if opt.HostUDSCredentials {
+		s.Merge(hostUDSCredentialsFilters())
+	}
 	if opt.NVProxy {
 		s.Merge(nvproxy.Filters())
 	}
@@ -143,6 +190,21 @@ func rules(opt Options, vars precompiledseccomp.Values) (seccomp.SyscallRules, s
 		s.Merge(accel.Filters())
 		s.Merge(tpuproxy.Filters())
 	}
+	if opt.DRMProxy {
+		s.Merge(drmproxy.Filters())
+	}
+	if opt.SNDProxy {
+		s.Merge(sndproxy.Filters())
+	}
+	if opt.IOUring {
+		s.Merge(ioUringFilters())
+	}
+	if opt.HugetlbMemoryFile {
+		s.Merge(hugetlbMemoryFileFilters())
+	}
+	if len(opt.HostCharDevIoctls) > 0 {
+		s.Merge(hostdev.Filters(opt.HostCharDevIoctls))
+	}
 
 	s.Merge(opt.Platform.SyscallFilters(vars))
 	return s, seccomp.DenyNewExecMappings
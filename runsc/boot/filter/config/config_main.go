@@ -61,6 +61,10 @@ var allowedSyscalls = seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
 			seccomp.AnyValue{},
 			seccomp.EqualTo(unix.F_GETFD),
 		},
+		seccomp.PerArg{
+			seccomp.AnyValue{},
+			seccomp.EqualTo(unix.F_GET_SEALS),
+		},
 	},
 	unix.SYS_FSTAT:     seccomp.MatchAll{},
 	unix.SYS_FSYNC:     seccomp.MatchAll{},
@@ -380,6 +384,13 @@ func hostFilesystemFilters() seccomp.SyscallRules {
 			seccomp.NonNegativeFD{},
 			seccomp.AnyValue{},
 		},
+		unix.SYS_NAME_TO_HANDLE_AT: seccomp.PerArg{
+			seccomp.NonNegativeFD{},
+			seccomp.AnyValue{},
+			seccomp.AnyValue{},
+			seccomp.AnyValue{},
+			seccomp.EqualTo(unix.AT_EMPTY_PATH),
+		},
 		unix.SYS_FSTATFS: seccomp.PerArg{
 			seccomp.NonNegativeFD{},
 			seccomp.AnyValue{},
@@ -396,11 +407,12 @@ func hostFilesystemFilters() seccomp.SyscallRules {
 			seccomp.AnyValue{},
 			seccomp.AnyValue{},
 		},
-		unix.SYS_RENAMEAT: seccomp.PerArg{
+		unix.SYS_RENAMEAT2: seccomp.PerArg{
 			seccomp.NonNegativeFD{},
 			seccomp.AnyValue{},
 			seccomp.NonNegativeFD{},
 			seccomp.AnyValue{},
+			seccomp.MaskedEqual(^uintptr(unix.RENAME_NOREPLACE), 0),
 		},
 		archFstatAtSysNo(): seccomp.PerArg{
 			seccomp.NonNegativeFD{},
@@ -414,5 +426,95 @@ func hostFilesystemFilters() seccomp.SyscallRules {
 			seccomp.AnyValue{},
 			seccomp.AnyValue{},
 		},
+		// The attribute name can't be inspected by seccomp; the gofer client
+		// only passes "user.*" and POSIX ACL names through (see
+		// gofer.isXattrPassedThrough).
+		unix.SYS_FSETXATTR: seccomp.PerArg{
+			seccomp.NonNegativeFD{},
+			seccomp.AnyValue{},
+			seccomp.AnyValue{},
+			seccomp.LessThanOrEqual(linux.XATTR_SIZE_MAX),
+			seccomp.MaskedEqual(^uintptr(unix.XATTR_CREATE|unix.XATTR_REPLACE), 0),
+		},
+		unix.SYS_FLISTXATTR: seccomp.PerArg{
+			seccomp.NonNegativeFD{},
+			seccomp.AnyValue{},
+			seccomp.EqualTo(linux.XATTR_LIST_MAX),
+		},
+		unix.SYS_FREMOVEXATTR: seccomp.PerArg{
+			seccomp.NonNegativeFD{},
+			seccomp.AnyValue{},
+		},
+	})
+}
+
+// hostMemfdFilters returns syscall rules required to create memfds on the host
+// for memfd_create(2).
+func hostMemfdFilters() seccomp.SyscallRules {
+	return seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
+		unix.SYS_MEMFD_CREATE: seccomp.PerArg{
+			seccomp.AnyValue{}, /* name */
+			seccomp.EqualTo(unix.MFD_CLOEXEC),
+		},
+	})
+}
+
+// hugetlbMemoryFileFilters returns syscall rules required to map chunks of a
+// memory file backed by host hugetlbfs pages, which are mapped without
+// reserving huge pages for the whole chunk.
+func hugetlbMemoryFileFilters() seccomp.SyscallRules {
+	return seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
+		unix.SYS_MMAP: seccomp.PerArg{
+			seccomp.AnyValue{},
+			seccomp.AnyValue{},
+			seccomp.AnyValue{},
+			seccomp.EqualTo(unix.MAP_SHARED | unix.MAP_NORESERVE),
+		},
+	})
+}
+
+// ioUringFilters returns syscall rules required to perform checkpoint and
+// restore I/O with io_uring.
+func ioUringFilters() seccomp.SyscallRules {
+	return seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
+		unix.SYS_IO_URING_SETUP: seccomp.MatchAll{},
+		unix.SYS_IO_URING_ENTER: seccomp.Or{
+			seccomp.PerArg{
+				seccomp.NonNegativeFD{},
+				seccomp.AnyValue{},
+				seccomp.AnyValue{},
+				seccomp.EqualTo(0),
+			},
+			seccomp.PerArg{
+				seccomp.NonNegativeFD{},
+				seccomp.AnyValue{},
+				seccomp.AnyValue{},
+				seccomp.EqualTo(linux.IORING_ENTER_GETEVENTS),
+			},
+		},
+		unix.SYS_IO_URING_REGISTER: seccomp.Or{
+			seccomp.PerArg{
+				seccomp.NonNegativeFD{},
+				seccomp.EqualTo(linux.IORING_REGISTER_BUFFERS),
+			},
+			seccomp.PerArg{
+				seccomp.NonNegativeFD{},
+				seccomp.EqualTo(linux.IORING_UNREGISTER_BUFFERS),
+			},
+		},
+	})
+}
+
+// hostUDSCredentialsFilters returns syscall rules required to receive
+// credentials from host Unix sockets.
+func hostUDSCredentialsFilters() seccomp.SyscallRules {
+	return seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
+		unix.SYS_SETSOCKOPT: seccomp.PerArg{
+			seccomp.AnyValue{},
+			seccomp.EqualTo(unix.SOL_SOCKET),
+			seccomp.EqualTo(unix.SO_PASSCRED),
+			seccomp.AnyValue{},
+			seccomp.EqualTo(4),
+		},
 	})
 }
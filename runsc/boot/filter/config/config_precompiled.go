@@ -86,6 +86,30 @@ func optionsToPrecompile() ([]Options, error) {
 			return []Options{opt}, nil
 		},
 
+		// Only precompile options with host memfds disabled.
+		func(opt Options) ([]Options, error) {
+			opt.HostMemfd = false
+			return []Options{opt}, nil
+		},
+
+		// Only precompile options with host UDS credentials disabled.
+		func(opt Options) ([]Options, error) {
+			opt.HostUDSCredentials = false
+			return []Options{opt}, nil
+		},
+
+		// Only precompile options with io_uring disabled.
+		func(opt Options) ([]Options, error) {
+			opt.IOUring = false
+			return []Options{opt}, nil
+		},
+
+		// Only precompile options with the hugetlb memory file disabled.
+		func(opt Options) ([]Options, error) {
+			opt.HugetlbMemoryFile = false
+			return []Options{opt}, nil
+		},
+
 		// Expand NVProxy vs not.
 		func(opt Options) ([]Options, error) {
 			nvProxyYes := opt
@@ -103,6 +127,24 @@ func optionsToPrecompile() ([]Options, error) {
 			tpuProxyNo.TPUProxy = false
 			return []Options{tpuProxyYes, tpuProxyNo}, nil
 		},
+
+		// Expand DRMProxy vs not.
+		func(opt Options) ([]Options, error) {
+			drmProxyYes := opt
+			drmProxyYes.DRMProxy = true
+			drmProxyNo := opt
+			drmProxyNo.DRMProxy = false
+			return []Options{drmProxyYes, drmProxyNo}, nil
+		},
+
+		// Expand SNDProxy vs not.
+		func(opt Options) ([]Options, error) {
+			sndProxyYes := opt
+			sndProxyYes.SNDProxy = true
+			sndProxyNo := opt
+			sndProxyNo.SNDProxy = false
+			return []Options{sndProxyYes, sndProxyNo}, nil
+		},
 	} {
 		var newOpts []Options
 		for _, opt := range opts {
@@ -20,6 +20,8 @@ import (
 	"testing"
 
 	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/bpf"
 	"gvisor.dev/gvisor/pkg/seccomp"
 	"gvisor.dev/gvisor/pkg/sentry/platform/kvm"
 	"gvisor.dev/gvisor/pkg/sentry/platform/systrap"
@@ -41,6 +43,30 @@ func TestIoctlFirstArgumentIsNonNegativeFD(t *testing.T) {
 			Platform: (&systrap.Systrap{}).SeccompInfo(),
 			TPUProxy: true,
 		},
+		"drmproxy": Options{
+			Platform: (&systrap.Systrap{}).SeccompInfo(),
+			DRMProxy: true,
+		},
+		"sndproxy": Options{
+			Platform: (&systrap.Systrap{}).SeccompInfo(),
+			SNDProxy: true,
+		},
+		"host memfd": Options{
+			Platform:  (&systrap.Systrap{}).SeccompInfo(),
+			HostMemfd: true,
+		},
+		"host uds credentials": Options{
+			Platform:           (&systrap.Systrap{}).SeccompInfo(),
+			HostUDSCredentials: true,
+		},
+		"hugetlb memory file": Options{
+			Platform:          (&systrap.Systrap{}).SeccompInfo(),
+			HugetlbMemoryFile: true,
+		},
+		"host chardev": Options{
+			Platform:          (&systrap.Systrap{}).SeccompInfo(),
+			HostCharDevIoctls: []uint32{0x400454ca},
+		},
 		"host network": Options{
 			Platform:    (&systrap.Systrap{}).SeccompInfo(),
 			HostNetwork: true,
@@ -103,6 +129,15 @@ func TestOptionsConfigKey(t *testing.T) {
 		"ProfileEnable":         func(opt *Options) { opt.ProfileEnable = !opt.ProfileEnable },
 		"NVProxy":               func(opt *Options) { opt.NVProxy = !opt.NVProxy },
 		"TPUProxy":              func(opt *Options) { opt.TPUProxy = !opt.TPUProxy },
+		"DRMProxy":              func(opt *Options) { opt.DRMProxy = !opt.DRMProxy },
+		"SNDProxy":              func(opt *Options) { opt.SNDProxy = !opt.SNDProxy },
+		"HostMemfd":             func(opt *Options) { opt.HostMemfd = !opt.HostMemfd },
+		"HostUDSCredentials":    func(opt *Options) { opt.HostUDSCredentials = !opt.HostUDSCredentials },
+		"IOUring":               func(opt *Options) { opt.IOUring = !opt.IOUring },
+		"HugetlbMemoryFile":     func(opt *Options) { opt.HugetlbMemoryFile = !opt.HugetlbMemoryFile },
+		"HostCharDevIoctls": func(opt *Options) {
+			opt.HostCharDevIoctls = append(opt.HostCharDevIoctls, uint32(len(opt.HostCharDevIoctls)+1))
+		},
 	}
 
 	// Map of `Options` struct field names mapped to a function to mutate them.
@@ -159,3 +194,65 @@ func TestOptionsConfigKey(t *testing.T) {
 		}
 	})
 }
+
+// TestHugetlbMemoryFileMmap verifies that chunks of a hugetlb-backed memory
+// file, which are mapped with MAP_NORESERVE, may only be mapped if the
+// HugetlbMemoryFile option is set.
+func TestHugetlbMemoryFileMmap(t *testing.T) {
+	for _, hugetlb := range []bool{false, true} {
+		t.Run(fmt.Sprintf("HugetlbMemoryFile=%t", hugetlb), func(t *testing.T) {
+			opt := Options{
+				Platform:          (&systrap.Systrap{}).SeccompInfo(),
+				HugetlbMemoryFile: hugetlb,
+			}
+			rules, denyRules := Rules(opt)
+			seccompOpts := SeccompOptions(opt)
+			seccompOpts.DefaultAction = linux.SECCOMP_RET_KILL_PROCESS
+			seccompOpts.BadArchAction = linux.SECCOMP_RET_KILL_PROCESS
+			insns, _, err := seccomp.BuildProgram([]seccomp.RuleSet{
+				{
+					Rules:  denyRules,
+					Action: linux.SECCOMP_RET_KILL_PROCESS,
+				},
+				{
+					Rules:  rules,
+					Action: linux.SECCOMP_RET_ALLOW,
+				},
+			}, seccompOpts)
+			if err != nil {
+				t.Fatalf("BuildProgram failed: %v", err)
+			}
+			p, err := bpf.Compile(insns, true /* optimize */)
+			if err != nil {
+				t.Fatalf("bpf.Compile failed: %v", err)
+			}
+
+			buf := make([]byte, (&linux.SeccompData{}).SizeBytes())
+			for _, tc := range []struct {
+				name  string
+				flags uint64
+				allow bool
+			}{
+				{name: "MAP_SHARED", flags: unix.MAP_SHARED, allow: true},
+				{name: "MAP_SHARED|MAP_NORESERVE", flags: unix.MAP_SHARED | unix.MAP_NORESERVE, allow: hugetlb},
+			} {
+				data := linux.SeccompData{
+					Nr:   unix.SYS_MMAP,
+					Arch: seccomp.LINUX_AUDIT_ARCH,
+					Args: [6]uint64{0, 1 << 30, unix.PROT_READ | unix.PROT_WRITE, tc.flags, 3, 0},
+				}
+				got, err := bpf.Exec[bpf.NativeEndian](p, seccomp.DataAsBPFInput(&data, buf))
+				if err != nil {
+					t.Fatalf("bpf.Exec failed: %v", err)
+				}
+				want := linux.SECCOMP_RET_KILL_PROCESS
+				if tc.allow {
+					want = linux.SECCOMP_RET_ALLOW
+				}
+				if got != uint32(want) {
+					t.Errorf("mmap(%s) got action %#x, want %#x", tc.name, got, uint32(want))
+				}
+			}
+		})
+	}
+}
@@ -129,6 +129,9 @@ func createLimitSet(spec *specs.Spec, enableTPUProxy bool) (*limits.LimitSet, er
 		if !ok {
 			return nil, fmt.Errorf("unknown resource %q", rl.Type)
 		}
+		if rl.Soft > rl.Hard {
+			return nil, fmt.Errorf("soft limit %d of resource %q is greater than hard limit %d", rl.Soft, rl.Type, rl.Hard)
+		}
 		ls.SetUnchecked(lt, limits.Limit{
 			Cur: rl.Soft,
 			Max: rl.Hard,
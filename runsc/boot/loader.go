@@ -48,12 +48,16 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/compactor"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/oomkiller"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/swapper"
 	"gvisor.dev/gvisor/pkg/sentry/loader"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 	pb "gvisor.dev/gvisor/pkg/sentry/seccheck/points/points_go_proto"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netfilter"
+	"gvisor.dev/gvisor/pkg/sentry/socket/unix/transport"
 	"gvisor.dev/gvisor/pkg/sentry/time"
 	"gvisor.dev/gvisor/pkg/sentry/usage"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
@@ -72,6 +76,7 @@ import (
 	"gvisor.dev/gvisor/pkg/tcpip/transport/raw"
 	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
 	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+	"gvisor.dev/gvisor/pkg/udsproxy"
 	"gvisor.dev/gvisor/runsc/boot/filter"
 	_ "gvisor.dev/gvisor/runsc/boot/platforms" // register all platforms.
 	pf "gvisor.dev/gvisor/runsc/boot/portforward"
@@ -89,6 +94,7 @@ import (
 	_ "gvisor.dev/gvisor/pkg/sentry/socket/netlink"
 	_ "gvisor.dev/gvisor/pkg/sentry/socket/netlink/route"
 	_ "gvisor.dev/gvisor/pkg/sentry/socket/netlink/uevent"
+	_ "gvisor.dev/gvisor/pkg/sentry/socket/netlink/xfrm"
 	_ "gvisor.dev/gvisor/pkg/sentry/socket/unix"
 )
 
@@ -147,6 +153,11 @@ type containerInfo struct {
 	// nvidiaUVMDevMajor is the device major number used for nvidia-uvm.
 	nvidiaUVMDevMajor uint32
 
+	// hostCharDevMajor is the device major number used for host character
+	// devices passed through with --host-chardev. The minor number of each
+	// device is its index in Config.HostCharDevs.
+	hostCharDevMajor uint32
+
 	// nvidiaDriverVersion is the NVIDIA driver ABI version to use for
 	// communicating with NVIDIA devices on the host.
 	nvidiaDriverVersion string
@@ -177,6 +188,22 @@ type Loader struct {
 
 	watchdog *watchdog.Watchdog
 
+	// oomKiller is the in-sandbox OOM killer. It is nil if the OOM killer is
+	// disabled or not started yet.
+	oomKiller *oomkiller.OOMKiller
+
+	// swapFile is the swap tier of the kernel's MemoryFile. It is nil if
+	// swapping is disabled.
+	swapFile *pgalloc.MemoryFile
+
+	// swapper migrates cold memory to swapFile. It is nil if swapping is
+	// disabled or not started yet.
+	swapper *swapper.Swapper
+
+	// compactor performs memory compaction. It is nil if compaction is
+	// disabled or not started yet.
+	compactor *compactor.Compactor
+
 	// stopSignalForwarding disables forwarding of signals to the sandboxed
 	// container. It should be called when a sandbox is destroyed.
 	stopSignalForwarding func()
@@ -299,6 +326,9 @@ type Args struct {
 	// DevGoferFD is the FD for the dev gofer connection. The Loader takes
 	// ownership of this FD and may close it at any time.
 	DevGoferFD int
+	// HostAbstractUDSFD is the FD for the host abstract UDS proxy connection.
+	// The Loader takes ownership of this FD.
+	HostAbstractUDSFD int
 	// StdioFDs is the stdio for the application. The Loader takes ownership of
 	// these FDs and may close them at any time.
 	StdioFDs []int
@@ -338,6 +368,13 @@ type Args struct {
 	// NvidiaDriverVersion is the NVIDIA driver ABI version to use for
 	// communicating with NVIDIA devices on the host.
 	NvidiaDriverVersion string
+	// WatchdogStallCheckpointFD is the file descriptor to write a statefile
+	// snapshot to when the watchdog detects a stalled subsystem. -1 disables
+	// the snapshot.
+	WatchdogStallCheckpointFD int
+	// SwapFD is the file descriptor of the file backing the swap tier of
+	// sandbox memory. It is only used if Conf.SwapDir is set.
+	SwapFD int
 }
 
 // make sure stdioFDs are always the same on initial start and on restore
@@ -397,6 +434,10 @@ func New(args Args) (*Loader, error) {
 	}
 
 	kernel.IOUringEnabled = args.Conf.IOUring
+	kernel.HostMemfdEnabled = args.Conf.HostMemfd
+	transport.HostRightsAllowed = hostRightsPolicy(args.Conf.HostUDSRights)
+	transport.HostUIDMap = transport.HostIDMap(args.Conf.HostUDSUIDMap)
+	transport.HostGIDMap = transport.HostIDMap(args.Conf.HostUDSGIDMap)
 
 	eid := execID{cid: args.ID}
 	l := &Loader{
@@ -471,10 +512,17 @@ func New(args Args) (*Loader, error) {
 	l.k = &kernel.Kernel{Platform: p}
 
 	// Create memory file.
-	mf, err := createMemoryFile()
+	mf, err := createMemoryFile(args.Conf)
 	if err != nil {
 		return nil, fmt.Errorf("creating memory file: %w", err)
 	}
+	if args.Conf.SwapDir != "" && args.SwapFD >= 0 {
+		l.swapFile, err = createSwapFile(os.NewFile(uintptr(args.SwapFD), "swap"))
+		if err != nil {
+			return nil, fmt.Errorf("creating swap file: %w", err)
+		}
+		mf.SetSwapFile(l.swapFile)
+	}
 	l.k.SetMemoryFile(mf)
 
 	// Create VDSO.
@@ -487,7 +535,13 @@ func New(args Args) (*Loader, error) {
 
 	// Create timekeeper.
 	tk := kernel.NewTimekeeper(l.k.MemoryFile(), vdso.ParamPage.FileRange())
-	tk.SetClocks(time.NewCalibratedClocks())
+	tk.SetClocks(newClocks(args.Conf))
+	if skew := clockSkew(args.Conf); skew != (kernel.ClockSkew{Scale: 1}) {
+		log.Infof("Clock skew: %+v", skew)
+		if err := tk.SetClockSkew(skew); err != nil {
+			return nil, fmt.Errorf("setting clock skew: %w", err)
+		}
+	}
 
 	if err := enableStrace(args.Conf); err != nil {
 		return nil, fmt.Errorf("enabling strace: %w", err)
@@ -498,17 +552,51 @@ func New(args Args) (*Loader, error) {
 		return nil, fmt.Errorf("getting root credentials")
 	}
 	// Create root network namespace/stack.
-	netns, err := newRootNetworkNamespace(args.Conf, tk, l.k, creds.UserNamespace)
+	var netClock tcpip.Clock = tk
+	if args.Conf.Deterministic {
+		// Logical time only advances when tasks are idle, which would make
+		// netstack timers, e.g. TCP retransmissions, expire early.
+		netClock = tcpip.NewStdClock()
+	}
+	netns, err := newRootNetworkNamespace(args.Conf, netClock, l.k, creds.UserNamespace)
 	if err != nil {
 		return nil, fmt.Errorf("creating network: %w", err)
 	}
+	if args.HostAbstractUDSFD >= 0 {
+		c, err := udsproxy.NewClient(args.HostAbstractUDSFD, args.Conf.HostAbstractUDSNames())
+		if err != nil {
+			return nil, fmt.Errorf("creating host abstract UDS proxy client: %w", err)
+		}
+		netns.AbstractSockets().SetHostConnector(c)
+	}
 
-	if args.NumCPU == 0 {
+	if args.Conf.Deterministic {
+		args.NumCPU = 1
+	} else if args.NumCPU == 0 {
 		args.NumCPU = runtime.NumCPU()
 	}
 	log.Infof("CPUs: %d", args.NumCPU)
 	runtime.GOMAXPROCS(args.NumCPU)
 
+	var cpuTopology cpuid.Topology
+	if args.Conf.CPUTopology != "" {
+		cpuTopology, err = cpuid.ParseTopology(args.Conf.CPUTopology, uint(args.NumCPU))
+		if err != nil {
+			return nil, fmt.Errorf("parsing CPU topology: %w", err)
+		}
+		log.Infof("CPU topology: %d sockets, %d cores per socket, %d threads per core, %d NUMA nodes", cpuTopology.Sockets, cpuTopology.CoresPerSocket, cpuTopology.ThreadsPerCore, cpuTopology.NUMANodes)
+	}
+
+	featureSet := cpuid.HostFeatureSet().Fixed()
+	if args.Conf.CPUFeatureMask != "" {
+		features, err := cpuid.ParseFeatures(args.Conf.CPUFeatureMask)
+		if err != nil {
+			return nil, fmt.Errorf("parsing CPU feature mask: %w", err)
+		}
+		featureSet = featureSet.WithoutFeatures(features)
+		log.Infof("CPU features masked: %s", args.Conf.CPUFeatureMask)
+	}
+
 	if args.TotalHostMem > 0 {
 		// As per tmpfs(5), the default size limit is 50% of total physical RAM.
 		// See mm/shmem.c:shmem_default_max_blocks().
@@ -539,11 +627,13 @@ func New(args Args) (*Loader, error) {
 	// Initiate the Kernel object, which is required by the Context passed
 	// to createVFS in order to mount (among other things) procfs.
 	if err = l.k.Init(kernel.InitKernelArgs{
-		FeatureSet:           cpuid.HostFeatureSet().Fixed(),
+		FeatureSet:           featureSet,
 		Timekeeper:           tk,
 		RootUserNamespace:    creds.UserNamespace,
 		RootNetworkNamespace: netns,
 		ApplicationCores:     uint(args.NumCPU),
+		CPUTopology:          cpuTopology,
+		SwapSize:             uint64(args.Conf.SwapSizeMB) << 20,
 		Vdso:                 vdso,
 		RootUTSNamespace:     kernel.NewUTSNamespace(args.Spec.Hostname, args.Spec.Hostname, creds.UserNamespace),
 		RootIPCNamespace:     kernel.NewIPCNamespace(creds.UserNamespace),
@@ -552,6 +642,11 @@ func New(args Args) (*Loader, error) {
 	}); err != nil {
 		return nil, fmt.Errorf("initializing kernel: %w", err)
 	}
+	if args.Conf.Deterministic {
+		log.Infof("Deterministic mode enabled, seed: %d", args.Conf.DeterministicSeed)
+		l.k.EnableDeterministicMode(args.Conf.DeterministicSeed)
+	}
+	l.k.LazyForkMemory = args.Conf.LazyFork
 
 	if err := registerFilesystems(l.k, &l.root); err != nil {
 		return nil, fmt.Errorf("registering filesystems: %w", err)
@@ -569,6 +664,19 @@ func New(args Args) (*Loader, error) {
 	// Create a watchdog.
 	dogOpts := watchdog.DefaultOpts
 	dogOpts.TaskTimeoutAction = args.Conf.WatchdogAction
+	stallTimeouts, err := watchdog.ParseStallTimeouts(args.Conf.WatchdogStallTimeouts)
+	if err != nil {
+		return nil, fmt.Errorf("parsing watchdog stall timeouts: %w", err)
+	}
+	dogOpts.StallTimeouts = stallTimeouts
+	stallActions, err := watchdog.ParseStallActions(args.Conf.WatchdogStallActions)
+	if err != nil {
+		return nil, fmt.Errorf("parsing watchdog stall actions: %w", err)
+	}
+	dogOpts.StallActions = stallActions
+	if stallActions&watchdog.StallCheckpoint != 0 && args.WatchdogStallCheckpointFD >= 0 {
+		dogOpts.Checkpoint = l.stallCheckpoint(os.NewFile(uintptr(args.WatchdogStallCheckpointFD), "watchdog stall checkpoint"))
+	}
 	l.watchdog = watchdog.New(l.k, dogOpts)
 
 	procArgs, err := createProcessArgs(args.ID, args.Spec, args.Conf, creds, l.k, l.k.RootPIDNamespace())
@@ -627,6 +735,27 @@ func New(args Args) (*Loader, error) {
 	return l, nil
 }
 
+// hostRightsPolicy returns the sentry policy for FDs received from host
+// Unix-domain sockets corresponding to rights.
+func hostRightsPolicy(rights config.HostUDSRights) transport.HostRightsPolicy {
+	var policy transport.HostRightsPolicy
+	for _, r := range []struct {
+		config config.HostUDSRights
+		policy transport.HostRightsPolicy
+	}{
+		{config.HostUDSRightsFile, transport.HostRightsFile},
+		{config.HostUDSRightsMemfd, transport.HostRightsMemfd},
+		{config.HostUDSRightsSocket, transport.HostRightsSocket},
+		{config.HostUDSRightsFIFO, transport.HostRightsFIFO},
+		{config.HostUDSRightsCharDev, transport.HostRightsCharDev},
+	} {
+		if rights&r.config != 0 {
+			policy |= r.policy
+		}
+	}
+	return policy
+}
+
 // createProcessArgs creates args that can be used with kernel.CreateProcess.
 func createProcessArgs(id string, spec *specs.Spec, conf *config.Config, creds *auth.Credentials, k *kernel.Kernel, pidns *kernel.PIDNamespace) (kernel.CreateProcessArgs, error) {
 	// Create initial limits.
@@ -672,6 +801,9 @@ func (l *Loader) Destroy() {
 		l.stopSignalForwarding()
 	}
 	l.watchdog.Stop()
+	l.stopOOMKiller()
+	l.stopSwapper()
+	l.stopCompactor()
 
 	ctx := l.k.SupervisorContext()
 	for _, m := range l.sharedMounts {
@@ -723,21 +855,54 @@ func createPlatform(conf *config.Config, deviceFile *fd.FD) (platform.Platform,
 	return p.New(deviceFile)
 }
 
-func createMemoryFile() (*pgalloc.MemoryFile, error) {
+func createMemoryFile(conf *config.Config) (*pgalloc.MemoryFile, error) {
 	const memfileName = "runsc-memory"
-	memfd, err := memutil.CreateMemFD(memfileName, 0)
+	var (
+		memfdFlags int
+		// We can't enable pgalloc.MemoryFileOpts.UseHostMemcgPressure even if
+		// there are memory cgroups specified, because at this point we're
+		// already in a mount namespace in which the relevant cgroupfs is not
+		// visible.
+		opts pgalloc.MemoryFileOpts
+	)
+	switch conf.MemoryFileHugePages {
+	case config.MemoryFileHugePagesTHP:
+		opts.AdviseHugepage = true
+	case config.MemoryFileHugePagesHugetlb:
+		// pgalloc assumes that host huge pages are hostarch.HugePageSize,
+		// which is not necessarily the host's default huge page size.
+		memfdFlags |= unix.MFD_HUGETLB | unix.MFD_HUGE_2MB
+		opts.Hugetlb = true
+	}
+	memfd, err := memutil.CreateMemFD(memfileName, memfdFlags)
 	if err != nil {
 		return nil, fmt.Errorf("error creating memfd: %w", err)
 	}
 	memfile := os.NewFile(uintptr(memfd), memfileName)
-	// We can't enable pgalloc.MemoryFileOpts.UseHostMemcgPressure even if
-	// there are memory cgroups specified, because at this point we're already
-	// in a mount namespace in which the relevant cgroupfs is not visible.
-	mf, err := pgalloc.NewMemoryFile(memfile, pgalloc.MemoryFileOpts{})
+	mf, err := pgalloc.NewMemoryFile(memfile, opts)
 	if err != nil {
 		_ = memfile.Close()
 		return nil, fmt.Errorf("error creating pgalloc.MemoryFile: %w", err)
 	}
+	if conf.SwapCompressedPercent > 0 {
+		mf.SetCompressionPercent(uint64(conf.SwapCompressedPercent))
+	}
+	return mf, nil
+}
+
+func createSwapFile(file *os.File) (*pgalloc.MemoryFile, error) {
+	mf, err := pgalloc.NewMemoryFile(file, pgalloc.MemoryFileOpts{
+		DiskBackedFile: true,
+		// Disk backed files need to be decommited on destroy to release disk space.
+		DecommitOnDestroy: true,
+		// The IMA work around is performed outside the sandbox, like for
+		// private memory files.
+		DisableIMAWorkAround: true,
+	})
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("error creating pgalloc.MemoryFile: %w", err)
+	}
 	return mf, nil
 }
 
@@ -755,10 +920,17 @@ func (l *Loader) installSeccompFilters() error {
 			HostNetwork:           hostnet,
 			HostNetworkRawSockets: hostnet && l.root.conf.EnableRaw,
 			HostFilesystem:        l.root.conf.DirectFS,
+			HostMemfd:             l.root.conf.HostMemfd,
+			HostUDSCredentials:    transport.HostCredentialsEnabled(),
 			ProfileEnable:         l.root.conf.ProfileEnable,
 			NVProxy:               specutils.NVProxyEnabled(l.root.spec, l.root.conf),
 			TPUProxy:              specutils.TPUProxyIsEnabled(l.root.spec, l.root.conf),
+			DRMProxy:              l.root.conf.DRMProxy,
+			SNDProxy:              l.root.conf.SNDProxy,
+			IOUring:               l.root.conf.StateIOUring,
+			HugetlbMemoryFile:     l.root.conf.MemoryFileHugePages == config.MemoryFileHugePagesHugetlb,
 			ControllerFD:          uint32(l.ctrl.srv.FD()),
+			HostCharDevIoctls:     l.root.conf.HostCharDevs.Ioctls(),
 		}
 		if err := filter.Install(opts); err != nil {
 			return fmt.Errorf("installing seccomp filters: %w", err)
@@ -837,6 +1009,9 @@ func (l *Loader) run() error {
 			if fields.Local.Contains(seccheck.FieldContainerStartEnv) {
 				evt.Env = l.root.spec.Process.Env
 			}
+			if fields.Local.Contains(seccheck.FieldContainerStartAnnotations) {
+				evt.Annotations = l.root.spec.Annotations
+			}
 			if !fields.Context.Empty() {
 				evt.ContextData = &pb.ContextData{}
 				kernel.LoadSeccheckData(tg.Leader(), fields.Context, evt.ContextData)
@@ -876,6 +1051,9 @@ func (l *Loader) run() error {
 
 	log.Infof("Process should have started...")
 	l.watchdog.Start()
+	l.startOOMKiller()
+	l.startSwapper()
+	l.startCompactor()
 	if err := l.k.Start(); err != nil {
 		return err
 	}
@@ -883,6 +1061,68 @@ func (l *Loader) run() error {
 	return nil
 }
 
+// startOOMKiller starts the in-sandbox OOM killer for l.k, if enabled.
+func (l *Loader) startOOMKiller() {
+	if !l.root.conf.OOMKiller {
+		return
+	}
+	l.stopOOMKiller()
+	if usage.TotalMemoryLimit() == 0 {
+		log.Warningf("OOM killer enabled, but the sandbox has no total memory limit")
+		return
+	}
+	l.oomKiller = oomkiller.New(l.k, oomkiller.DefaultPeriod)
+	l.oomKiller.Start()
+}
+
+// stopOOMKiller stops the in-sandbox OOM killer, if running.
+func (l *Loader) stopOOMKiller() {
+	if l.oomKiller != nil {
+		l.oomKiller.Stop()
+		l.oomKiller = nil
+	}
+}
+
+// startSwapper starts the swapper for l.k, if swapping is enabled.
+func (l *Loader) startSwapper() {
+	if l.swapFile == nil && l.root.conf.SwapCompressedPercent == 0 {
+		return
+	}
+	l.stopSwapper()
+	if usage.TotalMemoryLimit() == 0 {
+		log.Warningf("Swapping enabled, but the sandbox has no total memory limit")
+		return
+	}
+	l.swapper = swapper.New(l.k, uint64(l.root.conf.SwapWatermark), uint32(l.root.conf.SwapAge), swapper.DefaultPeriod)
+	l.swapper.Start()
+}
+
+// stopSwapper stops the swapper, if running.
+func (l *Loader) stopSwapper() {
+	if l.swapper != nil {
+		l.swapper.Stop()
+		l.swapper = nil
+	}
+}
+
+// startCompactor starts the memory compactor for l.k, if enabled.
+func (l *Loader) startCompactor() {
+	if !l.root.conf.MemoryCompaction {
+		return
+	}
+	l.stopCompactor()
+	l.compactor = compactor.New(l.k, compactor.DefaultMaxUsed, compactor.DefaultPeriod)
+	l.compactor.Start()
+}
+
+// stopCompactor stops the memory compactor, if running.
+func (l *Loader) stopCompactor() {
+	if l.compactor != nil {
+		l.compactor.Stop()
+		l.compactor = nil
+	}
+}
+
 // createSubcontainer creates a new container inside the sandbox.
 func (l *Loader) createSubcontainer(cid string, tty *fd.FD) error {
 	l.mu.Lock()
@@ -947,6 +1187,7 @@ func (l *Loader) startSubcontainer(spec *specs.Spec, conf *config.Config, cid st
 		goferFilestoreFDs:   goferFilestoreFDs,
 		goferMountConfs:     goferMountConfs,
 		nvidiaUVMDevMajor:   l.root.nvidiaUVMDevMajor,
+		hostCharDevMajor:    l.root.hostCharDevMajor,
 		nvidiaDriverVersion: l.root.nvidiaDriverVersion,
 	}
 	var err error
@@ -996,6 +1237,9 @@ func (l *Loader) startSubcontainer(spec *specs.Spec, conf *config.Config, cid st
 		if fields.Local.Contains(seccheck.FieldContainerStartEnv) {
 			evt.Env = spec.Process.Env
 		}
+		if fields.Local.Contains(seccheck.FieldContainerStartAnnotations) {
+			evt.Annotations = spec.Annotations
+		}
 		if !fields.Context.Empty() {
 			evt.ContextData = &pb.ContextData{}
 			kernel.LoadSeccheckData(ep.tg.Leader(), fields.Context, evt.ContextData)
@@ -1075,6 +1319,14 @@ func (l *Loader) createContainerProcess(info *containerInfo) (*kernel.ThreadGrou
 		return nil, nil, err
 	}
 
+	// Set the syscall policy before creating the process, so that it applies
+	// to all tasks in the container.
+	sysPolicy, err := syscallPolicy(info.spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	l.k.SetSyscallPolicy(info.procArgs.ContainerID, sysPolicy)
+
 	// Create and start the new process.
 	tg, _, err := l.k.CreateProcess(info.procArgs)
 	if err != nil {
@@ -1368,6 +1620,28 @@ func (l *Loader) WaitExit() linux.WaitStatus {
 	return l.k.GlobalInit().ExitStatus()
 }
 
+// deterministicEpoch is the realtime at which the sandbox starts in
+// deterministic mode, in nanoseconds since the Unix epoch
+// (2000-01-01T00:00:00Z).
+const deterministicEpoch = 946684800 * 1e9
+
+// newClocks returns the clocks that back the sandbox timekeeper.
+func newClocks(conf *config.Config) time.Clocks {
+	if conf.Deterministic {
+		return time.NewLogicalClocks(deterministicEpoch)
+	}
+	return time.NewCalibratedClocks()
+}
+
+// clockSkew returns the skew of the sandbox clocks configured by conf.
+func clockSkew(conf *config.Config) kernel.ClockSkew {
+	return kernel.ClockSkew{
+		RealtimeOffset:  conf.ClockRealtimeOffset,
+		MonotonicOffset: conf.ClockMonotonicOffset,
+		Scale:           conf.ClockScale,
+	}
+}
+
 func newRootNetworkNamespace(conf *config.Config, clock tcpip.Clock, uniqueID stack.UniqueID, userns *auth.UserNamespace) (*inet.Namespace, error) {
 	// Create an empty network stack because the network namespace may be empty at
 	// this point. Netns is configured before Run() is called. Netstack is
@@ -1407,6 +1681,7 @@ func newEmptySandboxNetworkStack(clock tcpip.Clock, uniqueID stack.UniqueID, all
 	transProtos := []stack.TransportProtocolFactory{
 		tcp.NewProtocol,
 		udp.NewProtocol,
+		udp.NewLiteProtocol,
 		icmp.NewProtocol4,
 		icmp.NewProtocol6,
 	}
@@ -15,6 +15,8 @@
 package boot
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -228,10 +230,15 @@ func (p *PodMountHints) FindMount(mountSrc string) *MountHint {
 }
 
 // RootfsHint represents extra information about rootfs that are provided via
-// annotations. They can provide mount source, mount type and overlay config.
+// annotations. They can provide mount source, mount type, overlay config and
+// the root hash used to verify EROFS images.
 type RootfsHint struct {
 	Mount   specs.Mount
 	Overlay config.OverlayMedium
+
+	// VerityRootHash is the root hash of the Merkle tree appended to an EROFS
+	// image. If it is set, the image is verified as it is read.
+	VerityRootHash []byte
 }
 
 func (r *RootfsHint) setSource(val string) error {
@@ -252,6 +259,19 @@ func (r *RootfsHint) setType(val string) error {
 	return nil
 }
 
+func (r *RootfsHint) setVerityRoot(val string) error {
+	hash, err := hex.DecodeString(val)
+	if err != nil {
+		return fmt.Errorf("invalid verity root hash %q: %v", val, err)
+	}
+	// EROFS images are verified using SHA-256.
+	if len(hash) != sha256.Size {
+		return fmt.Errorf("invalid verity root hash %q: want %d bytes, got %d", val, sha256.Size, len(hash))
+	}
+	r.VerityRootHash = hash
+	return nil
+}
+
 func (r *RootfsHint) setField(key, val string) error {
 	switch key {
 	case "source":
@@ -260,6 +280,8 @@ func (r *RootfsHint) setField(key, val string) error {
 		return r.setType(val)
 	case "overlay":
 		return r.Overlay.Set(val)
+	case "verity_root":
+		return r.setVerityRoot(val)
 	default:
 		return fmt.Errorf("invalid rootfs annotation: %s=%s", key, val)
 	}
@@ -288,6 +310,9 @@ func NewRootfsHint(spec *specs.Spec) (*RootfsHint, error) {
 		if len(hint.Mount.Source) == 0 || len(hint.Mount.Type) == 0 {
 			return nil, fmt.Errorf("rootfs annotations missing required field(s): %+v", hint)
 		}
+		if len(hint.VerityRootHash) != 0 && hint.Mount.Type != erofs.Name {
+			return nil, fmt.Errorf("rootfs verity root hash requires type %q, got %q", erofs.Name, hint.Mount.Type)
+		}
 	}
 	return hint, nil
 }
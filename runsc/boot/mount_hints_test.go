@@ -15,6 +15,8 @@
 package boot
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"slices"
 	"strings"
 	"testing"
@@ -255,9 +257,10 @@ func TestRootfsHintHappy(t *testing.T) {
 	const imagePath = "/tmp/rootfs.img"
 	spec := &specs.Spec{
 		Annotations: map[string]string{
-			RootfsPrefix + "source":  imagePath,
-			RootfsPrefix + "type":    erofs.Name,
-			RootfsPrefix + "overlay": config.MemoryOverlay.String(),
+			RootfsPrefix + "source":      imagePath,
+			RootfsPrefix + "type":        erofs.Name,
+			RootfsPrefix + "overlay":     config.MemoryOverlay.String(),
+			RootfsPrefix + "verity_root": strings.Repeat("ab", sha256.Size),
 		},
 	}
 	hint, err := NewRootfsHint(spec)
@@ -275,6 +278,9 @@ func TestRootfsHintHappy(t *testing.T) {
 	if hint.Overlay != config.MemoryOverlay {
 		t.Errorf("rootfs overlay, want: %q, got: %q", config.MemoryOverlay, hint.Overlay)
 	}
+	if want := bytes.Repeat([]byte{0xab}, sha256.Size); !bytes.Equal(hint.VerityRootHash, want) {
+		t.Errorf("rootfs verity root hash, want: %x, got: %x", want, hint.VerityRootHash)
+	}
 }
 
 // TestRootfsHintErrors tests that proper errors will be returned when parsing
@@ -321,6 +327,33 @@ func TestRootfsHintErrors(t *testing.T) {
 			},
 			error: "invalid rootfs annotation",
 		},
+		{
+			name: "invalid verity root",
+			annotations: map[string]string{
+				RootfsPrefix + "source":      imagePath,
+				RootfsPrefix + "type":        erofs.Name,
+				RootfsPrefix + "verity_root": "invalid",
+			},
+			error: "invalid rootfs annotation",
+		},
+		{
+			name: "short verity root",
+			annotations: map[string]string{
+				RootfsPrefix + "source":      imagePath,
+				RootfsPrefix + "type":        erofs.Name,
+				RootfsPrefix + "verity_root": "abcd",
+			},
+			error: "invalid rootfs annotation",
+		},
+		{
+			name: "verity root without erofs",
+			annotations: map[string]string{
+				RootfsPrefix + "source":      imagePath,
+				RootfsPrefix + "type":        Bind,
+				RootfsPrefix + "verity_root": strings.Repeat("ab", sha256.Size),
+			},
+			error: "requires type",
+		},
 		{
 			name: "missing source",
 			annotations: map[string]string{
@@ -38,6 +38,7 @@ import (
 	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
 	"gvisor.dev/gvisor/pkg/urpc"
+	"gvisor.dev/gvisor/runsc/boot/dnsproxy"
 	"gvisor.dev/gvisor/runsc/config"
 )
 
@@ -113,6 +114,10 @@ type FDBasedLink struct {
 	// ProcessorsPerChannel controls how many goroutines are used to handle
 	// packets on each channel.
 	ProcessorsPerChannel int
+
+	// ProcessorQueueLen limits the number of packets queued for each
+	// processor goroutine.
+	ProcessorQueueLen int
 }
 
 // BindOpt indicates whether the sentry or runsc process is responsible for
@@ -182,6 +187,14 @@ type CreateLinksAndRoutesArgs struct {
 	// DisconnectOk indicates that link endpoints should have the capability
 	// CapabilityDisconnectOk set.
 	DisconnectOk bool
+
+	// DNSProxyUpstreams, if not empty, starts a caching DNS proxy on the
+	// loopback interface that forwards to these servers.
+	DNSProxyUpstreams []string
+
+	// DNSProxyRootCAs contains PEM-encoded root certificates used by the DNS
+	// proxy to verify DNS over TLS upstreams.
+	DNSProxyRootCAs []byte
 }
 
 // IPWithPrefix is an address with its subnet prefix length.
@@ -217,34 +230,8 @@ func (r *Route) toTcpipRoute(id tcpip.NICID) (tcpip.Route, error) {
 // CreateLinksAndRoutes creates links and routes in a network stack.  It should
 // only be called once.
 func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct{}) error {
-	if len(args.FDBasedLinks) > 0 && len(args.XDPLinks) > 0 {
-		return fmt.Errorf("received both fdbased and XDP links, but only one can be used at a time")
-	}
-	wantFDs := 0
-	for _, l := range args.FDBasedLinks {
-		wantFDs += l.NumChannels
-	}
-	for _, link := range args.XDPLinks {
-		// We have to keep several FDs alive when the sentry is
-		// responsible for binding, but when runsc binds we only expect
-		// the AF_XDP socket itself.
-		switch v := link.Bind; v {
-		case BindSentry:
-			wantFDs += 4
-		case BindRunsc:
-			wantFDs++
-		default:
-			return fmt.Errorf("unknown bind value: %d", v)
-		}
-	}
-	if args.PCAP {
-		wantFDs++
-	}
-	if args.NATBlob {
-		wantFDs++
-	}
-	if got := len(args.FilePayload.Files); got != wantFDs {
-		return fmt.Errorf("args.FilePayload.Files has %d FDs but we need %d entries based on FDBasedLinks, XDPLinks, and PCAP", got, wantFDs)
+	if err := checkLinkFiles(args); err != nil {
+		return err
 	}
 
 	var nicID tcpip.NICID
@@ -279,6 +266,134 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 		}
 	}
 
+	linkRoutes, err := n.createLinks(args, nicID, nicids)
+	if err != nil {
+		return err
+	}
+	routes = append(routes, linkRoutes...)
+
+	log.Infof("Setting routes %+v", routes)
+	n.Stack.SetRouteTable(routes)
+
+	// Set NAT table rules if necessary.
+	if args.NATBlob {
+		log.Infof("Replacing NAT table")
+		iptReplaceBlob, err := io.ReadAll(args.FilePayload.Files[len(args.FilePayload.Files)-1])
+		if err != nil {
+			return fmt.Errorf("failed to read iptables blob: %v", err)
+		}
+		if err := netfilter.SetEntries(n.Kernel.RootUserNamespace(), n.Stack, iptReplaceBlob, false); err != nil {
+			return fmt.Errorf("failed to SetEntries: %v", err)
+		}
+	}
+
+	if len(args.DNSProxyUpstreams) > 0 {
+		p, err := dnsproxy.New(n.Stack, dnsproxy.Options{
+			Upstreams: args.DNSProxyUpstreams,
+			RootCAs:   args.DNSProxyRootCAs,
+		})
+		if err != nil {
+			return fmt.Errorf("creating DNS proxy: %w", err)
+		}
+		p.Start()
+	}
+
+	return nil
+}
+
+// AddLinksAndRoutes adds fdbased or XDP links and their routes to a network
+// stack that was already set up by CreateLinksAndRoutes. Existing links and
+// routes are left untouched. Loopback links, PCAP logging, NAT rules and the
+// DNS proxy can only be set up by CreateLinksAndRoutes.
+func (n *Network) AddLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct{}) error {
+	if len(args.LoopbackLinks) > 0 {
+		return fmt.Errorf("loopback links cannot be added to a running sandbox")
+	}
+	if args.PCAP || args.NATBlob {
+		return fmt.Errorf("PCAP logging and NAT rules cannot be added to a running sandbox")
+	}
+	if len(args.DNSProxyUpstreams) > 0 {
+		return fmt.Errorf("the DNS proxy cannot be started in a running sandbox")
+	}
+	if err := checkLinkFiles(args); err != nil {
+		return err
+	}
+
+	// Pick NIC IDs above the ones in use, and refuse to shadow existing
+	// interface names.
+	var nicID tcpip.NICID
+	for id, info := range n.Stack.NICInfo() {
+		if id > nicID {
+			nicID = id
+		}
+		for _, link := range args.FDBasedLinks {
+			if link.Name == info.Name {
+				return fmt.Errorf("interface %q already exists", link.Name)
+			}
+		}
+		for _, link := range args.XDPLinks {
+			if link.Name == info.Name {
+				return fmt.Errorf("interface %q already exists", link.Name)
+			}
+		}
+	}
+
+	nicids := make(map[string]tcpip.NICID)
+	routes, err := n.createLinks(args, nicID, nicids)
+	if err != nil {
+		for _, id := range nicids {
+			_ = n.Stack.RemoveNIC(id)
+		}
+		return err
+	}
+
+	log.Infof("Adding routes %+v", routes)
+	n.Stack.SetRouteTable(insertRoutes(n.Stack.GetRouteTable(), routes))
+	return nil
+}
+
+// checkLinkFiles returns an error if args.FilePayload doesn't have the number
+// of FDs required by the links in args.
+func checkLinkFiles(args *CreateLinksAndRoutesArgs) error {
+	if len(args.FDBasedLinks) > 0 && len(args.XDPLinks) > 0 {
+		return fmt.Errorf("received both fdbased and XDP links, but only one can be used at a time")
+	}
+	wantFDs := 0
+	for _, l := range args.FDBasedLinks {
+		wantFDs += l.NumChannels
+	}
+	for _, link := range args.XDPLinks {
+		// We have to keep several FDs alive when the sentry is
+		// responsible for binding, but when runsc binds we only expect
+		// the AF_XDP socket itself.
+		switch v := link.Bind; v {
+		case BindSentry:
+			wantFDs += 4
+		case BindRunsc:
+			wantFDs++
+		default:
+			return fmt.Errorf("unknown bind value: %d", v)
+		}
+	}
+	if args.PCAP {
+		wantFDs++
+	}
+	if args.NATBlob {
+		wantFDs++
+	}
+	if got := len(args.FilePayload.Files); got != wantFDs {
+		return fmt.Errorf("args.FilePayload.Files has %d FDs but we need %d entries based on FDBasedLinks, XDPLinks, and PCAP", got, wantFDs)
+	}
+	return nil
+}
+
+// createLinks creates the fdbased or XDP links in args, using NIC IDs above
+// lastNICID, and records the NIC ID of each link in nicids. It returns the
+// routes of the new links, including the default routes in args.
+func (n *Network) createLinks(args *CreateLinksAndRoutesArgs, lastNICID tcpip.NICID, nicids map[string]tcpip.NICID) ([]tcpip.Route, error) {
+	nicID := lastNICID
+	var routes []tcpip.Route
+
 	// Setup fdbased or XDP links.
 	fdOffset := 0
 	if len(args.FDBasedLinks) > 0 {
@@ -286,7 +401,7 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 		dispatchMode := fdbased.RecvMMsg
 		version, err := hostos.KernelVersion()
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if version.AtLeast(5, 6) {
 			// TODO(b/333120887): Switch back to using the packet mmap dispatcher when
@@ -307,7 +422,7 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 				oldFD := args.FilePayload.Files[fdOffset].Fd()
 				newFD, err := unix.Dup(int(oldFD))
 				if err != nil {
-					return fmt.Errorf("failed to dup FD %v: %v", oldFD, err)
+					return nil, fmt.Errorf("failed to dup FD %v: %v", oldFD, err)
 				}
 				FDs = append(FDs, newFD)
 				fdOffset++
@@ -328,22 +443,23 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 				RXChecksumOffload:    link.RXChecksumOffload,
 				GRO:                  link.GVisorGRO,
 				ProcessorsPerChannel: link.ProcessorsPerChannel,
+				ProcessorQueueLen:    link.ProcessorQueueLen,
 				DisconnectOk:         args.DisconnectOk,
 			})
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			// Setup packet logging if requested.
 			if args.PCAP {
 				newFD, err := unix.Dup(int(args.FilePayload.Files[fdOffset].Fd()))
 				if err != nil {
-					return fmt.Errorf("failed to dup pcap FD: %v", err)
+					return nil, fmt.Errorf("failed to dup pcap FD: %v", err)
 				}
 				const packetTruncateSize = 4096
 				linkEP, err = sniffer.NewWithWriter(linkEP, os.NewFile(uintptr(newFD), "pcap-file"), packetTruncateSize)
 				if err != nil {
-					return fmt.Errorf("failed to create PCAP logger: %v", err)
+					return nil, fmt.Errorf("failed to create PCAP logger: %v", err)
 				}
 				fdOffset++
 			} else if args.LogPackets {
@@ -365,14 +481,14 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 				DeliverLinkPackets: true,
 			}
 			if err := n.createNICWithAddrs(nicID, linkEP, opts, link.Addresses); err != nil {
-				return err
+				return nil, err
 			}
 
 			// Collect the routes from this link.
 			for _, r := range link.Routes {
 				route, err := r.toTcpipRoute(nicID)
 				if err != nil {
-					return err
+					return nil, err
 				}
 				routes = append(routes, route)
 			}
@@ -384,7 +500,7 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 		}
 	} else if len(args.XDPLinks) > 0 {
 		if nlinks := len(args.XDPLinks); nlinks > 1 {
-			return fmt.Errorf("XDP only supports one link device, but got %d", nlinks)
+			return nil, fmt.Errorf("XDP only supports one link device, but got %d", nlinks)
 		}
 		link := args.XDPLinks[0]
 		nicID++
@@ -394,7 +510,7 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 		oldFD := args.FilePayload.Files[fdOffset].Fd()
 		fd, err := unix.Dup(int(oldFD))
 		if err != nil {
-			return fmt.Errorf("failed to dup AF_XDP fd %v: %v", oldFD, err)
+			return nil, fmt.Errorf("failed to dup AF_XDP fd %v: %v", oldFD, err)
 		}
 		fdOffset++
 
@@ -406,7 +522,7 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 			for _, fdName := range []string{"program-fd", "sockmap-fd", "link-fd"} {
 				oldFD := args.FilePayload.Files[fdOffset].Fd()
 				if _, err := unix.Dup(int(oldFD)); err != nil {
-					return fmt.Errorf("failed to dup %s with FD %d: %v", fdName, oldFD, err)
+					return nil, fmt.Errorf("failed to dup %s with FD %d: %v", fdName, oldFD, err)
 				}
 				fdOffset++
 			}
@@ -425,18 +541,18 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 			DisconnectOk:      args.DisconnectOk,
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if args.PCAP {
 			newFD, err := unix.Dup(int(args.FilePayload.Files[fdOffset].Fd()))
 			if err != nil {
-				return fmt.Errorf("failed to dup pcap FD: %v", err)
+				return nil, fmt.Errorf("failed to dup pcap FD: %v", err)
 			}
 			const packetTruncateSize = 4096
 			linkEP, err = sniffer.NewWithWriter(linkEP, os.NewFile(uintptr(newFD), "pcap-file"), packetTruncateSize)
 			if err != nil {
-				return fmt.Errorf("failed to create PCAP logger: %v", err)
+				return nil, fmt.Errorf("failed to create PCAP logger: %v", err)
 			}
 			fdOffset++
 		} else if args.LogPackets {
@@ -458,14 +574,14 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 			DeliverLinkPackets: true,
 		}
 		if err := n.createNICWithAddrs(nicID, linkEP, opts, link.Addresses); err != nil {
-			return err
+			return nil, err
 		}
 
 		// Collect the routes from this link.
 		for _, r := range link.Routes {
 			route, err := r.toTcpipRoute(nicID)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			routes = append(routes, route)
 		}
@@ -479,11 +595,11 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 	if !args.Defaultv4Gateway.Route.Empty() {
 		nicID, ok := nicids[args.Defaultv4Gateway.Name]
 		if !ok {
-			return fmt.Errorf("invalid interface name %q for default route", args.Defaultv4Gateway.Name)
+			return nil, fmt.Errorf("invalid interface name %q for default route", args.Defaultv4Gateway.Name)
 		}
 		route, err := args.Defaultv4Gateway.Route.toTcpipRoute(nicID)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		routes = append(routes, route)
 	}
@@ -491,32 +607,32 @@ func (n *Network) CreateLinksAndRoutes(args *CreateLinksAndRoutesArgs, _ *struct
 	if !args.Defaultv6Gateway.Route.Empty() {
 		nicID, ok := nicids[args.Defaultv6Gateway.Name]
 		if !ok {
-			return fmt.Errorf("invalid interface name %q for default route", args.Defaultv6Gateway.Name)
+			return nil, fmt.Errorf("invalid interface name %q for default route", args.Defaultv6Gateway.Name)
 		}
 		route, err := args.Defaultv6Gateway.Route.toTcpipRoute(nicID)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		routes = append(routes, route)
 	}
+	return routes, nil
+}
 
-	log.Infof("Setting routes %+v", routes)
-	n.Stack.SetRouteTable(routes)
-
-	// Set NAT table rules if necessary.
-	if args.NATBlob {
-		log.Infof("Replacing NAT table")
-		iptReplaceBlob, err := io.ReadAll(args.FilePayload.Files[fdOffset])
-		if err != nil {
-			return fmt.Errorf("failed to read iptables blob: %v", err)
-		}
-		fdOffset++
-		if err := netfilter.SetEntries(n.Kernel.RootUserNamespace(), n.Stack, iptReplaceBlob, false); err != nil {
-			return fmt.Errorf("failed to SetEntries: %v", err)
+// insertRoutes inserts routes into table, each before the first route of
+// table with a shorter destination prefix. Routes are looked up in order, so
+// this keeps more specific routes, like the ones of an added link, ahead of
+// existing default routes.
+func insertRoutes(table, routes []tcpip.Route) []tcpip.Route {
+	for _, r := range routes {
+		i := 0
+		for ; i < len(table); i++ {
+			if table[i].Destination.Prefix() < r.Destination.Prefix() {
+				break
+			}
 		}
+		table = append(table[:i], append([]tcpip.Route{r}, table[i:]...)...)
 	}
-
-	return nil
+	return table
 }
 
 // createNICWithAddrs creates a NIC in the network stack and adds the given
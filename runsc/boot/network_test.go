@@ -0,0 +1,138 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/urpc"
+)
+
+func mustRoute(t *testing.T, cidr string, nic tcpip.NICID) tcpip.Route {
+	t.Helper()
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatalf("net.ParseCIDR(%q): %v", cidr, err)
+	}
+	route, err := (&Route{Destination: *ipNet}).toTcpipRoute(nic)
+	if err != nil {
+		t.Fatalf("toTcpipRoute(%q): %v", cidr, err)
+	}
+	return route
+}
+
+func TestInsertRoutes(t *testing.T) {
+	table := []tcpip.Route{
+		mustRoute(t, "10.0.0.0/8", 1),
+		mustRoute(t, "0.0.0.0/0", 1),
+	}
+	routes := []tcpip.Route{
+		mustRoute(t, "10.1.0.0/16", 2),
+		mustRoute(t, "0.0.0.0/0", 2),
+	}
+	got := insertRoutes(table, routes)
+	want := []tcpip.Route{
+		mustRoute(t, "10.1.0.0/16", 2),
+		mustRoute(t, "10.0.0.0/8", 1),
+		mustRoute(t, "0.0.0.0/0", 1),
+		mustRoute(t, "0.0.0.0/0", 2),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("insertRoutes() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("insertRoutes()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAddLinksAndRoutes(t *testing.T) {
+	s := stack.New(stack.Options{
+		NetworkProtocols: []stack.NetworkProtocolFactory{ipv4.NewProtocol, ipv6.NewProtocol},
+	})
+	defer s.Destroy()
+	n := &Network{Stack: s}
+
+	if err := n.CreateLinksAndRoutes(&CreateLinksAndRoutesArgs{
+		LoopbackLinks: []LoopbackLink{DefaultLoopbackLink},
+	}, nil); err != nil {
+		t.Fatalf("CreateLinksAndRoutes(): %v", err)
+	}
+
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("unix.Socketpair(): %v", err)
+	}
+	sandboxEnd := os.NewFile(uintptr(fds[0]), "sandbox-end")
+	defer sandboxEnd.Close()
+	defer unix.Close(fds[1])
+
+	_, subnet, err := net.ParseCIDR("10.1.0.0/24")
+	if err != nil {
+		t.Fatalf("net.ParseCIDR(): %v", err)
+	}
+	args := &CreateLinksAndRoutesArgs{
+		FilePayload: urpc.FilePayload{Files: []*os.File{sandboxEnd}},
+		FDBasedLinks: []FDBasedLink{{
+			Name:        "net1",
+			MTU:         1500,
+			NumChannels: 1,
+			Addresses:   []IPWithPrefix{{Address: net.ParseIP("10.1.0.2").To4(), PrefixLen: 24}},
+			Routes:      []Route{{Destination: *subnet}},
+		}},
+	}
+	if err := n.AddLinksAndRoutes(args, nil); err != nil {
+		t.Fatalf("AddLinksAndRoutes(): %v", err)
+	}
+
+	var nicID tcpip.NICID
+	for id, info := range s.NICInfo() {
+		if info.Name == "net1" {
+			nicID = id
+		}
+	}
+	if nicID == 0 {
+		t.Fatalf("interface net1 not found in %+v", s.NICInfo())
+	}
+	if len(s.NICInfo()) != 2 {
+		t.Errorf("got %d NICs, want 2", len(s.NICInfo()))
+	}
+
+	want := mustRoute(t, "10.1.0.0/24", nicID)
+	table := s.GetRouteTable()
+	if len(table) == 0 || table[0] != want {
+		t.Errorf("route table = %v, want %v first", table, want)
+	}
+
+	// Adding an interface with the same name must fail.
+	if err := n.AddLinksAndRoutes(args, nil); err == nil {
+		t.Errorf("AddLinksAndRoutes() with duplicate interface succeeded")
+	}
+
+	// Loopback links can only be created when the stack is set up.
+	if err := n.AddLinksAndRoutes(&CreateLinksAndRoutesArgs{
+		LoopbackLinks: []LoopbackLink{DefaultLoopbackLink},
+	}, nil); err == nil {
+		t.Errorf("AddLinksAndRoutes() with loopback link succeeded")
+	}
+}
@@ -18,11 +18,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strconv"
 	time2 "time"
 
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/atomicbitops"
 	"gvisor.dev/gvisor/pkg/cleanup"
 	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/devutil"
@@ -36,11 +38,12 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/socket/hostinet"
 	"gvisor.dev/gvisor/pkg/sentry/socket/netstack"
 	"gvisor.dev/gvisor/pkg/sentry/state"
-	"gvisor.dev/gvisor/pkg/sentry/time"
 	"gvisor.dev/gvisor/pkg/sentry/vfs"
 	"gvisor.dev/gvisor/pkg/sentry/watchdog"
+	"gvisor.dev/gvisor/pkg/state/statefile"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/urpc"
 	"gvisor.dev/gvisor/runsc/boot/pprof"
 	"gvisor.dev/gvisor/runsc/config"
 )
@@ -79,6 +82,10 @@ type restorer struct {
 	// deviceFile is the required to start the platform.
 	deviceFile *fd.FD
 
+	// fdResolver is the socket of the FD resolver, if any, used for host FDs
+	// that aren't passed to the sandbox. See FDResolverResolve.
+	fdResolver *fd.FD
+
 	// restoreDone is a callback triggered when restore is successful.
 	restoreDone func() error
 }
@@ -172,6 +179,12 @@ func (r *restorer) restore(l *Loader) error {
 	// Start the old watchdog before replacing it with a new one below.
 	l.watchdog.Start()
 
+	// The OOM killer, swapper and compactor refer to the old kernel. They are
+	// restarted once the restore is done.
+	l.stopOOMKiller()
+	l.stopSwapper()
+	l.stopCompactor()
+
 	// Release the kernel and replace it with a new one that will be restored into.
 	if l.k != nil {
 		l.k.Release()
@@ -180,10 +193,13 @@ func (r *restorer) restore(l *Loader) error {
 		Platform: p,
 	}
 
-	mf, err := createMemoryFile()
+	mf, err := createMemoryFile(l.root.conf)
 	if err != nil {
 		return fmt.Errorf("creating memory file: %v", err)
 	}
+	if l.swapFile != nil {
+		mf.SetSwapFile(l.swapFile)
+	}
 	l.k.SetMemoryFile(mf)
 
 	if l.root.conf.ProfileEnable {
@@ -225,20 +241,49 @@ func (r *restorer) restore(l *Loader) error {
 
 	log.Debugf("Restore using fdmap: %v", fdmap)
 	ctx = context.WithValue(ctx, vfs.CtxRestoreFilesystemFDMap, fdmap)
+	if r.fdResolver != nil {
+		resolver, err := newRestoreFDResolver(r.fdResolver)
+		if err != nil {
+			return err
+		}
+		// Closing the resolver once restore is done lets runsc stop serving
+		// it.
+		defer resolver.Close()
+		ctx = context.WithValue(ctx, host.CtxRestoreFDResolver, resolver)
+	}
 	log.Debugf("Restore using mfmap: %v", mfmap)
 	ctx = context.WithValue(ctx, pgalloc.CtxMemoryFileMap, mfmap)
 	ctx = context.WithValue(ctx, devutil.CtxDevGoferClientProvider, l.k)
 
 	// Load the state.
-	loadOpts := state.LoadOpts{Source: r.stateFile, PagesMetadata: r.pagesMetadata, PagesFile: r.pagesFile}
-	if err := loadOpts.Load(ctx, l.k, nil, netns.Stack(), time.NewCalibratedClocks(), &vfs.CompleteRestoreOptions{}); err != nil {
+	loadOpts := state.LoadOpts{
+		Source:        r.stateFile,
+		PagesMetadata: r.pagesMetadata,
+		PagesFile:     r.pagesFile,
+		AsyncIO:       statefile.AsyncOpts{IOUring: l.root.conf.StateIOUring},
+	}
+	if err := loadOpts.Load(ctx, l.k, nil, netns.Stack(), newClocks(l.root.conf), &vfs.CompleteRestoreOptions{}); err != nil {
 		return err
 	}
+	if l.root.conf.Deterministic {
+		l.k.EnableDeterministicMode(l.root.conf.DeterministicSeed)
+	}
+	// Clock skew isn't saved. The monotonic clock offset is already part of
+	// the restored monotonic time.
+	if skew := clockSkew(l.root.conf); skew.RealtimeOffset != 0 || skew.Scale != 1 {
+		skew.MonotonicOffset = 0
+		if err := l.k.Timekeeper().SetClockSkew(skew); err != nil {
+			return fmt.Errorf("setting clock skew: %w", err)
+		}
+	}
 
 	// Since we have a new kernel we also must make a new watchdog.
 	dogOpts := watchdog.DefaultOpts
 	dogOpts.TaskTimeoutAction = l.root.conf.WatchdogAction
 	dogOpts.StartupTimeout = 3 * time2.Minute // Give extra time for all containers to restore.
+	dogOpts.StallTimeouts = l.watchdog.StallTimeouts
+	dogOpts.StallActions = l.watchdog.StallActions
+	dogOpts.Checkpoint = l.watchdog.Checkpoint
 	dog := watchdog.New(l.k, dogOpts)
 
 	// Change the loader fields to reflect the changes made when restoring.
@@ -316,6 +361,23 @@ func (r *restorer) restore(l *Loader) error {
 	return nil
 }
 
+// stallCheckpoint returns a watchdog checkpoint function, which saves the
+// sandbox to stateFile and resumes it. Only the first snapshot is written,
+// since stateFile is consumed by the save.
+func (l *Loader) stallCheckpoint(stateFile *os.File) func(reason string) error {
+	var taken atomicbitops.Bool
+	return func(reason string) error {
+		if !taken.CompareAndSwap(false, true) {
+			return errors.New("stall checkpoint has already been written")
+		}
+		return l.save(&control.SaveOpts{
+			Metadata:    map[string]string{"watchdog_stall": reason},
+			FilePayload: urpc.FilePayload{Files: []*os.File{stateFile}},
+			Resume:      true,
+		})
+	}
+}
+
 func (l *Loader) save(o *control.SaveOpts) error {
 	// TODO(gvisor.dev/issues/6243): save/restore not supported w/ hostinet
 	if l.root.conf.Network == config.NetworkHost {
@@ -334,6 +396,7 @@ func (l *Loader) save(o *control.SaveOpts) error {
 	state := control.State{
 		Kernel:   l.k,
 		Watchdog: l.watchdog,
+		AsyncIO:  statefile.AsyncOpts{IOUring: l.root.conf.StateIOUring},
 	}
 	if err := state.Save(o, nil); err != nil {
 		return err
@@ -23,7 +23,10 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
 
 	// Register supported of sinks.
+	_ "gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/audit"
 	_ "gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/null"
+	_ "gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/otlp"
+	_ "gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/policy"
 	_ "gvisor.dev/gvisor/pkg/sentry/seccheck/sinks/remote"
 )
 
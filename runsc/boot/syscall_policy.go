@@ -0,0 +1,66 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"encoding/json"
+	"fmt"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/runsc/specutils/seccomp"
+)
+
+// SyscallPolicyAnnotation is the annotation that sets the syscall policy of a
+// container. Its value is a JSON object in the format of the seccomp
+// configuration of the OCI runtime spec (linux.seccomp), e.g. to only allow
+// some syscalls:
+//
+//	{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": [{"names": ["read", "write"], "action": "SCMP_ACT_ALLOW"}]}
+//
+// Unlike linux.seccomp, which is installed as a seccomp filter of the
+// container's init process, the syscall policy is enforced by the sentry. The
+// application can't observe it, and it applies to all processes in the
+// container, including those started with "runsc exec". As with linux.seccomp,
+// SCMP_ACT_ERRNO always fails with EPERM.
+const SyscallPolicyAnnotation = "dev.gvisor.spec.syscall-policy"
+
+// syscallPolicy returns the syscall policy set in the spec, or nil if there
+// is none.
+func syscallPolicy(spec *specs.Spec) (*kernel.SyscallPolicy, error) {
+	val, ok := spec.Annotations[SyscallPolicyAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var s specs.LinuxSeccomp
+	if err := json.Unmarshal([]byte(val), &s); err != nil {
+		return nil, fmt.Errorf("parsing annotation %q: %w", SyscallPolicyAnnotation, err)
+	}
+	// Applications aren't traced by the sentry, so there is no tracer to
+	// notify.
+	if s.DefaultAction == specs.ActTrace {
+		return nil, fmt.Errorf("annotation %q: action %q is not supported", SyscallPolicyAnnotation, s.DefaultAction)
+	}
+	for _, syscall := range s.Syscalls {
+		if syscall.Action == specs.ActTrace {
+			return nil, fmt.Errorf("annotation %q: action %q is not supported", SyscallPolicyAnnotation, syscall.Action)
+		}
+	}
+	program, err := seccomp.BuildProgram(&s)
+	if err != nil {
+		return nil, fmt.Errorf("annotation %q: %w", SyscallPolicyAnnotation, err)
+	}
+	return kernel.NewSyscallPolicy(program), nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"testing"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestSyscallPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		annotation string
+		want       bool
+		wantErr    bool
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:       "allowlist",
+			annotation: `{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": [{"names": ["read", "write"], "action": "SCMP_ACT_ALLOW"}]}`,
+			want:       true,
+		},
+		{
+			name:       "arguments",
+			annotation: `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["personality"], "action": "SCMP_ACT_KILL", "args": [{"index": 0, "value": 8, "op": "SCMP_CMP_NE"}]}]}`,
+			want:       true,
+		},
+		{
+			name:       "invalid json",
+			annotation: `{"defaultAction": `,
+			wantErr:    true,
+		},
+		{
+			name:       "missing default action",
+			annotation: `{"syscalls": [{"names": ["read"], "action": "SCMP_ACT_ALLOW"}]}`,
+			wantErr:    true,
+		},
+		{
+			name:       "trace default action",
+			annotation: `{"defaultAction": "SCMP_ACT_TRACE"}`,
+			wantErr:    true,
+		},
+		{
+			name:       "trace action",
+			annotation: `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["read"], "action": "SCMP_ACT_TRACE"}]}`,
+			wantErr:    true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &specs.Spec{}
+			if tc.annotation != "" {
+				spec.Annotations = map[string]string{SyscallPolicyAnnotation: tc.annotation}
+			}
+			got, err := syscallPolicy(spec)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("syscallPolicy() succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("syscallPolicy() failed: %v", err)
+			}
+			if (got != nil) != tc.want {
+				t.Errorf("syscallPolicy() = %v, want policy: %t", got, tc.want)
+			}
+		})
+	}
+}
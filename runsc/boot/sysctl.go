@@ -0,0 +1,101 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
+	"gvisor.dev/gvisor/pkg/errors/linuxerr"
+	"gvisor.dev/gvisor/pkg/fspath"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/vfs"
+	"gvisor.dev/gvisor/pkg/usermem"
+)
+
+// sysctlPath returns the path of the given sysctl under /proc/sys. Like
+// sysctl(8), names are separated by either dots or slashes; if a name contains
+// slashes, dots are part of the name components.
+func sysctlPath(name string) (string, error) {
+	if !strings.Contains(name, "/") {
+		name = strings.ReplaceAll(name, ".", "/")
+	}
+	p := path.Join("/proc/sys", name)
+	if !strings.HasPrefix(p, "/proc/sys/") {
+		return "", fmt.Errorf("invalid sysctl name %q", name)
+	}
+	return p, nil
+}
+
+// applySysctls sets the sysctls from the spec by writing them to the sentry's
+// /proc/sys in the container. Host sysctls are never visible in the sandbox,
+// so sysctls that the sentry doesn't implement, or only implements
+// read-only, are ignored with a warning.
+func applySysctls(ctx context.Context, creds *auth.Credentials, spec *specs.Spec, vfsObj *vfs.VirtualFilesystem, root vfs.VirtualDentry) error {
+	if spec.Linux == nil || len(spec.Linux.Sysctl) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(spec.Linux.Sysctl))
+	for name := range spec.Linux.Sysctl {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		val := spec.Linux.Sysctl[name]
+		p, err := sysctlPath(name)
+		if err != nil {
+			return err
+		}
+		pop := vfs.PathOperation{
+			Root:  root,
+			Start: root,
+			Path:  fspath.Parse(p),
+		}
+		stat, err := vfsObj.StatAt(ctx, creds, &pop, &vfs.StatOptions{Mask: linux.STATX_TYPE | linux.STATX_MODE})
+		if err != nil {
+			if linuxerr.Equals(linuxerr.ENOENT, err) {
+				log.Warningf("Sysctl %q is not supported, ignoring value %q", name, val)
+				continue
+			}
+			return fmt.Errorf("looking up sysctl %q: %w", name, err)
+		}
+		if stat.Mode&linux.S_IFMT != linux.S_IFREG {
+			return fmt.Errorf("invalid sysctl name %q", name)
+		}
+		if stat.Mode&0222 == 0 {
+			log.Warningf("Sysctl %q is read-only, ignoring value %q", name, val)
+			continue
+		}
+
+		fd, err := vfsObj.OpenAt(ctx, creds, &pop, &vfs.OpenOptions{Flags: linux.O_WRONLY})
+		if err != nil {
+			return fmt.Errorf("opening sysctl %q: %w", name, err)
+		}
+		_, err = fd.Write(ctx, usermem.BytesIOSequence([]byte(val)), vfs.WriteOptions{})
+		fd.DecRef(ctx)
+		if err != nil {
+			return fmt.Errorf("setting sysctl %s=%q: %w", name, val, err)
+		}
+		log.Infof("Set sysctl %s=%q", name, val)
+	}
+	return nil
+}
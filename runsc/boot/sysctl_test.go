@@ -0,0 +1,49 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boot
+
+import (
+	"testing"
+)
+
+func TestSysctlPath(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "net.ipv4.tcp_sack", want: "/proc/sys/net/ipv4/tcp_sack"},
+		{name: "net/ipv4/tcp_sack", want: "/proc/sys/net/ipv4/tcp_sack"},
+		{name: "net/ipv4/conf/eth0.100/forwarding", want: "/proc/sys/net/ipv4/conf/eth0.100/forwarding"},
+		{name: "../../etc/passwd", wantErr: true},
+		{name: "", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := sysctlPath(tc.name)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("sysctlPath(%q) = %q, want error", tc.name, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sysctlPath(%q) failed: %v", tc.name, err)
+			}
+			if got != tc.want {
+				t.Errorf("sysctlPath(%q) = %q, want %q", tc.name, got, tc.want)
+			}
+		})
+	}
+}
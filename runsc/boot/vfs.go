@@ -26,6 +26,7 @@ import (
 	"strings"
 
 	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"gvisor.dev/gvisor/pkg/abi/drm"
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/abi/nvgpu"
 	"gvisor.dev/gvisor/pkg/abi/tpu"
@@ -37,16 +38,23 @@ import (
 	"gvisor.dev/gvisor/pkg/fspath"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/devices/accel"
+	"gvisor.dev/gvisor/pkg/sentry/devices/drmproxy"
+	"gvisor.dev/gvisor/pkg/sentry/devices/hostdev"
+	"gvisor.dev/gvisor/pkg/sentry/devices/hwrngdev"
+	"gvisor.dev/gvisor/pkg/sentry/devices/loopdev"
 	"gvisor.dev/gvisor/pkg/sentry/devices/memdev"
 	"gvisor.dev/gvisor/pkg/sentry/devices/nvproxy"
+	"gvisor.dev/gvisor/pkg/sentry/devices/sndproxy"
 	"gvisor.dev/gvisor/pkg/sentry/devices/tpuproxy"
 	"gvisor.dev/gvisor/pkg/sentry/devices/ttydev"
 	"gvisor.dev/gvisor/pkg/sentry/devices/tundev"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/binfmtmisc"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/cgroupfs"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/dev"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/devpts"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/devtmpfs"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/erofs"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/ext4"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/fuse"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/gofer"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/mqfs"
@@ -54,7 +62,9 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/proc"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/sys"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/tmpfs"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/tracefs"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/user"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/verity"
 	"gvisor.dev/gvisor/pkg/sentry/inet"
 	"gvisor.dev/gvisor/pkg/sentry/kernel"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
@@ -99,6 +109,9 @@ func registerFilesystems(k *kernel.Kernel, info *containerInfo) error {
 	ctx := k.SupervisorContext()
 	vfsObj := k.VFS()
 
+	vfsObj.MustRegisterFilesystemType(binfmtmisc.Name, &binfmtmisc.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
+		AllowUserList: true,
+	})
 	vfsObj.MustRegisterFilesystemType(cgroupfs.Name, &cgroupfs.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
 		AllowUserMount: true,
 		AllowUserList:  true,
@@ -117,6 +130,10 @@ func registerFilesystems(k *kernel.Kernel, info *containerInfo) error {
 	vfsObj.MustRegisterFilesystemType(erofs.Name, &erofs.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
 		AllowUserList: true,
 	})
+	vfsObj.MustRegisterFilesystemType(ext4.Name, &ext4.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
+		AllowUserMount: true,
+		AllowUserList:  true,
+	})
 	vfsObj.MustRegisterFilesystemType(fuse.Name, &fuse.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
 		AllowUserMount: true,
 		AllowUserList:  true,
@@ -140,6 +157,14 @@ func registerFilesystems(k *kernel.Kernel, info *containerInfo) error {
 		AllowUserMount: true,
 		AllowUserList:  true,
 	})
+	vfsObj.MustRegisterFilesystemType(tracefs.Name, &tracefs.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
+		AllowUserMount: true,
+		AllowUserList:  true,
+	})
+	vfsObj.MustRegisterFilesystemType(verity.Name, &verity.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
+		AllowUserMount: true,
+		AllowUserList:  true,
+	})
 	vfsObj.MustRegisterFilesystemType(mqfs.Name, &mqfs.FilesystemType{}, &vfs.RegisterFilesystemTypeOptions{
 		AllowUserMount: true,
 		AllowUserList:  true,
@@ -152,6 +177,12 @@ func registerFilesystems(k *kernel.Kernel, info *containerInfo) error {
 	if err := ttydev.Register(vfsObj); err != nil {
 		return fmt.Errorf("registering ttydev: %w", err)
 	}
+	if err := hwrngdev.Register(vfsObj); err != nil {
+		return fmt.Errorf("registering hwrngdev: %w", err)
+	}
+	if err := loopdev.Register(vfsObj); err != nil {
+		return fmt.Errorf("registering loopdev: %w", err)
+	}
 	tunSupported := tundev.IsNetTunSupported(inet.StackFromContext(ctx))
 	if tunSupported {
 		if err := tundev.Register(vfsObj); err != nil {
@@ -170,6 +201,18 @@ func registerFilesystems(k *kernel.Kernel, info *containerInfo) error {
 		return err
 	}
 
+	if err := drmProxyRegisterDevices(info, vfsObj); err != nil {
+		return err
+	}
+
+	if err := sndProxyRegisterDevices(info, vfsObj); err != nil {
+		return err
+	}
+
+	if err := hostCharDevRegisterDevices(info, vfsObj); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -213,6 +256,18 @@ func setupContainerVFS(ctx context.Context, info *containerInfo, mntr *container
 		return fmt.Errorf("failed to create device files: %w", err)
 	}
 
+	// Like runc, set sysctls before /proc/sys is made read-only, and mask paths
+	// last so that they are masked in read-only paths too.
+	if err := applySysctls(rootCtx, rootCreds, info.spec, mntr.k.VFS(), mnsRoot); err != nil {
+		return fmt.Errorf("failed to apply sysctls: %w", err)
+	}
+	if err := setReadonlyPaths(rootCtx, rootCreds, info.spec, mntr.k.VFS(), mnsRoot); err != nil {
+		return fmt.Errorf("failed to set read-only paths: %w", err)
+	}
+	if err := maskPaths(rootCtx, rootCreds, info.spec, mntr.k.VFS(), mnsRoot); err != nil {
+		return fmt.Errorf("failed to mask paths: %w", err)
+	}
+
 	// We are executing a file directly. Do not resolve the executable path.
 	if procArgs.File != nil {
 		return nil
@@ -453,7 +508,7 @@ func getMountAccessType(conf *config.Config, hint *MountHint) config.FileAccessT
 func (c *containerMounter) mountAll(rootCtx context.Context, rootCreds *auth.Credentials, spec *specs.Spec, conf *config.Config, rootProcArgs *kernel.CreateProcessArgs) (*vfs.MountNamespace, error) {
 	log.Infof("Configuring container's file system")
 
-	mns, err := c.createMountNamespace(rootCtx, conf, rootCreds)
+	mns, err := c.createMountNamespace(rootCtx, spec, conf, rootCreds)
 	if err != nil {
 		return nil, fmt.Errorf("creating mount namespace: %w", err)
 	}
@@ -483,7 +538,7 @@ func (c *containerMounter) mountAll(rootCtx context.Context, rootCreds *auth.Cre
 }
 
 // createMountNamespace creates the container's root mount and namespace.
-func (c *containerMounter) createMountNamespace(ctx context.Context, conf *config.Config, creds *auth.Credentials) (*vfs.MountNamespace, error) {
+func (c *containerMounter) createMountNamespace(ctx context.Context, spec *specs.Spec, conf *config.Config, creds *auth.Credentials) (*vfs.MountNamespace, error) {
 	ioFD := c.goferFDs.remove()
 	rootfsConf := c.goferMountConfs[0]
 
@@ -521,6 +576,14 @@ func (c *containerMounter) createMountNamespace(ctx context.Context, conf *confi
 
 	case rootfsConf.ShouldUseErofs():
 		fsName = erofs.Name
+		rootfsHint, err := NewRootfsHint(spec)
+		if err != nil {
+			return nil, err
+		}
+		var verityRootHash []byte
+		if rootfsHint != nil {
+			verityRootHash = rootfsHint.VerityRootHash
+		}
 		opts = &vfs.MountOptions{
 			ReadOnly: c.root.Readonly,
 			GetFilesystemOptions: vfs.GetFilesystemOptions{
@@ -531,6 +594,7 @@ func (c *containerMounter) createMountNamespace(ctx context.Context, conf *confi
 						ContainerName: c.containerName,
 						Path:          "/",
 					},
+					VerityRootHash: verityRootHash,
 				},
 			},
 		}
@@ -1175,6 +1239,12 @@ func (c *containerMounter) mountCgroupSubmounts(ctx context.Context, spec *specs
 			log.Infof("error in creating directory %v", err)
 			return err
 		}
+		if conf.NestedContainers && spec.Process != nil {
+			uid, gid := auth.KUID(spec.Process.User.UID), auth.KGID(spec.Process.User.GID)
+			if err := delegateCgroup(mountCtx, creds, c.k.VFS(), &sourcePop, uid, gid); err != nil {
+				return fmt.Errorf("delegating cgroup %q of controller %s: %w", c.containerID, ctrlName, err)
+			}
+		}
 
 		// Bind mount the new cgroup directory into the container's mount namespace.
 		destination := "/sys/fs/cgroup/" + ctrlName
@@ -1197,6 +1267,30 @@ func (c *containerMounter) mountCgroupSubmounts(ctx context.Context, spec *specs
 	return nil
 }
 
+// delegateCgroup gives the cgroup directory at pop, and the files needed to
+// move processes into it, to uid and gid. The owner can then create child
+// cgroups and move its own processes between them, like with systemd's
+// Delegate= setting.
+func delegateCgroup(ctx context.Context, creds *auth.Credentials, vfsObj *vfs.VirtualFilesystem, pop *vfs.PathOperation, uid auth.KUID, gid auth.KGID) error {
+	opts := &vfs.SetStatOptions{
+		Stat: linux.Statx{
+			Mask: linux.STATX_UID | linux.STATX_GID,
+			UID:  uint32(uid),
+			GID:  uint32(gid),
+		},
+	}
+	for _, name := range []string{"", "cgroup.procs", "tasks"} {
+		filePop := *pop
+		if name != "" {
+			filePop.Path = fspath.Parse(path.Join(pop.Path.String(), name))
+		}
+		if err := vfsObj.SetStatAt(ctx, creds, &filePop, opts); err != nil {
+			return fmt.Errorf("chown %q: %w", filePop.Path, err)
+		}
+	}
+	return nil
+}
+
 // mountSharedMaster mounts the master of a volume that is shared among
 // containers in a pod.
 func (c *containerMounter) mountSharedMaster(ctx context.Context, spec *specs.Spec, conf *config.Config, mntInfo *mountInfo, creds *auth.Credentials) (*vfs.Mount, error) {
@@ -1346,6 +1440,109 @@ func createDeviceFiles(ctx context.Context, creds *auth.Credentials, info *conta
 			}
 		}
 	}
+	if info.conf.SNDProxy && sndProxyNeedsStubTimer(info.spec) {
+		mode := os.FileMode(0666)
+		timerDev := specs.LinuxDevice{Path: sndproxy.TimerPath, Type: "c", Major: linux.SND_MAJOR, Minor: linux.SNDRV_MINOR_TIMER, FileMode: &mode}
+		if err := createDeviceFile(ctx, creds, info, vfsObj, root, timerDev); err != nil {
+			return err
+		}
+	}
+	mode := os.FileMode(0666)
+	for i, hostDev := range info.conf.HostCharDevs {
+		// Replace any device file that already exists at the same path, e.g.
+		// /dev/net/tun, so that the host device takes precedence.
+		if err := vfsObj.UnlinkAt(ctx, creds, &vfs.PathOperation{
+			Root:  root,
+			Start: root,
+			Path:  fspath.Parse(hostDev.Path),
+		}); err != nil && !linuxerr.Equals(linuxerr.ENOENT, err) {
+			return fmt.Errorf("removing existing device file %q: %w", hostDev.Path, err)
+		}
+		devSpec := specs.LinuxDevice{Path: hostDev.Path, Type: "c", Major: int64(info.hostCharDevMajor), Minor: int64(i), FileMode: &mode}
+		if err := createDeviceFile(ctx, creds, info, vfsObj, root, devSpec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setReadonlyPaths makes the paths in the spec's readonlyPaths read-only in
+// the container by bind mounting each of them onto itself read-only. Paths
+// that don't exist are skipped.
+func setReadonlyPaths(ctx context.Context, creds *auth.Credentials, spec *specs.Spec, vfsObj *vfs.VirtualFilesystem, root vfs.VirtualDentry) error {
+	if spec.Linux == nil {
+		return nil
+	}
+	for _, p := range spec.Linux.ReadonlyPaths {
+		pop := vfs.PathOperation{
+			Root:               root,
+			Start:              root,
+			Path:               fspath.Parse(p),
+			FollowFinalSymlink: true,
+		}
+		if err := vfsObj.BindAt(ctx, creds, &pop, &pop, true /* recursive */); err != nil {
+			if linuxerr.Equals(linuxerr.ENOENT, err) {
+				continue
+			}
+			return fmt.Errorf("bind mounting %q: %w", p, err)
+		}
+		vd, err := vfsObj.GetDentryAt(ctx, creds, &pop, &vfs.GetDentryOptions{})
+		if err != nil {
+			return fmt.Errorf("getting bind mount at %q: %w", p, err)
+		}
+		err = vfsObj.SetMountReadOnly(vd.Mount(), true)
+		vd.DecRef(ctx)
+		if err != nil {
+			return fmt.Errorf("making %q read-only: %w", p, err)
+		}
+		log.Infof("Made %q read-only", p)
+	}
+	return nil
+}
+
+// maskPaths makes the paths in the spec's maskedPaths inaccessible in the
+// container. Like in runc, directories are covered with an empty read-only
+// tmpfs and other files with /dev/null. Paths that don't exist are skipped.
+func maskPaths(ctx context.Context, creds *auth.Credentials, spec *specs.Spec, vfsObj *vfs.VirtualFilesystem, root vfs.VirtualDentry) error {
+	if spec.Linux == nil {
+		return nil
+	}
+	for _, p := range spec.Linux.MaskedPaths {
+		pop := vfs.PathOperation{
+			Root:               root,
+			Start:              root,
+			Path:               fspath.Parse(p),
+			FollowFinalSymlink: true,
+		}
+		stat, err := vfsObj.StatAt(ctx, creds, &pop, &vfs.StatOptions{Mask: linux.STATX_TYPE})
+		if err != nil {
+			if linuxerr.Equals(linuxerr.ENOENT, err) {
+				continue
+			}
+			return fmt.Errorf("masking %q: %w", p, err)
+		}
+		if stat.Mode&linux.S_IFMT == linux.S_IFDIR {
+			opts := vfs.MountOptions{
+				ReadOnly: true,
+				GetFilesystemOptions: vfs.GetFilesystemOptions{
+					InternalMount: true,
+				},
+			}
+			if _, err := vfsObj.MountAt(ctx, creds, "tmpfs", &pop, tmpfs.Name, &opts); err != nil {
+				return fmt.Errorf("masking directory %q: %w", p, err)
+			}
+		} else {
+			devNull := vfs.PathOperation{
+				Root:  root,
+				Start: root,
+				Path:  fspath.Parse("/dev/null"),
+			}
+			if err := vfsObj.BindAt(ctx, creds, &devNull, &pop, false /* recursive */); err != nil {
+				return fmt.Errorf("masking file %q: %w", p, err)
+			}
+		}
+		log.Infof("Masked %q", p)
+	}
 	return nil
 }
 
@@ -1437,6 +1634,83 @@ func tpuProxyRegisterDevices(info *containerInfo, vfsObj *vfs.VirtualFilesystem)
 	return nil
 }
 
+func drmProxyRegisterDevices(info *containerInfo, vfsObj *vfs.VirtualFilesystem) error {
+	if !info.conf.DRMProxy || info.spec.Linux == nil {
+		return nil
+	}
+	for _, dev := range info.spec.Linux.Devices {
+		if !drmproxy.IsSupportedDevice(dev.Path) {
+			continue
+		}
+		if dev.Type != "c" || dev.Major != drm.DRM_MAJOR {
+			log.Warningf("Not proxying DRM device %q with unexpected type %q and major number %d", dev.Path, dev.Type, dev.Major)
+			continue
+		}
+		if err := drmproxy.Register(vfsObj, dev.Path, uint32(dev.Minor)); err != nil {
+			return fmt.Errorf("registering DRM device %q: %w", dev.Path, err)
+		}
+	}
+	return nil
+}
+
+// sndProxyNeedsStubTimer returns true if a stub ALSA timer device should be
+// registered because spec doesn't pass through a host timer device, and the
+// timer's device number isn't used by another ALSA device.
+func sndProxyNeedsStubTimer(spec *specs.Spec) bool {
+	if spec.Linux == nil {
+		return true
+	}
+	for _, dev := range spec.Linux.Devices {
+		if dev.Path == sndproxy.TimerPath || (dev.Major == linux.SND_MAJOR && dev.Minor == linux.SNDRV_MINOR_TIMER) {
+			return false
+		}
+	}
+	return true
+}
+
+func sndProxyRegisterDevices(info *containerInfo, vfsObj *vfs.VirtualFilesystem) error {
+	if !info.conf.SNDProxy {
+		return nil
+	}
+	if info.spec.Linux != nil {
+		for _, dev := range info.spec.Linux.Devices {
+			if !sndproxy.IsSupportedDevice(dev.Path) {
+				continue
+			}
+			if dev.Type != "c" || dev.Major != linux.SND_MAJOR {
+				log.Warningf("Not proxying ALSA device %q with unexpected type %q and major number %d", dev.Path, dev.Type, dev.Major)
+				continue
+			}
+			if err := sndproxy.Register(vfsObj, dev.Path, uint32(dev.Minor)); err != nil {
+				return fmt.Errorf("registering ALSA device %q: %w", dev.Path, err)
+			}
+		}
+	}
+	if sndProxyNeedsStubTimer(info.spec) {
+		if err := sndproxy.RegisterStubTimer(vfsObj); err != nil {
+			return fmt.Errorf("registering stub ALSA timer device: %w", err)
+		}
+	}
+	return nil
+}
+
+func hostCharDevRegisterDevices(info *containerInfo, vfsObj *vfs.VirtualFilesystem) error {
+	if len(info.conf.HostCharDevs) == 0 {
+		return nil
+	}
+	major, err := vfsObj.GetDynamicCharDevMajor()
+	if err != nil {
+		return fmt.Errorf("reserving device major number for host character devices: %w", err)
+	}
+	for i, hostDev := range info.conf.HostCharDevs {
+		if err := hostdev.Register(vfsObj, hostDev.Path, major, uint32(i), hostDev.Ioctls); err != nil {
+			return fmt.Errorf("registering host character device %q: %w", hostDev.Path, err)
+		}
+	}
+	info.hostCharDevMajor = major
+	return nil
+}
+
 func nvproxyRegisterDevices(info *containerInfo, vfsObj *vfs.VirtualFilesystem) error {
 	if !specutils.NVProxyEnabled(info.spec, info.conf) {
 		return nil
@@ -323,6 +323,7 @@ type Cgroup interface {
 	CPUUsage() (uint64, error)
 	NumCPU() (int, error)
 	MemoryLimit() (uint64, error)
+	SetMemoryLimit(limit uint64) error
 	MakePath(controllerName string) string
 }
 
@@ -691,6 +692,11 @@ func (c *cgroupV1) MemoryLimit() (uint64, error) {
 	return strconv.ParseUint(strings.TrimSpace(limStr), 10, 64)
 }
 
+// SetMemoryLimit sets the memory limit.
+func (c *cgroupV1) SetMemoryLimit(limit uint64) error {
+	return setValue(c.MakePath("memory"), "memory.limit_in_bytes", strconv.FormatUint(limit, 10))
+}
+
 // MakePath builds a path to the given controller.
 func (c *cgroupV1) MakePath(controllerName string) string {
 	path := c.Name
@@ -347,6 +347,11 @@ func (c *cgroupV2) MemoryLimit() (uint64, error) {
 	return strconv.ParseUint(limStr, 10, 64)
 }
 
+// SetMemoryLimit sets the memory limit.
+func (c *cgroupV2) SetMemoryLimit(limit uint64) error {
+	return setValue(c.MakePath(""), memoryLimitCgroup, strconv.FormatUint(limit, 10))
+}
+
 // MakePath builds a path to the given controller.
 func (c *cgroupV2) MakePath(string) string {
 	return filepath.Join(c.Mountpoint, c.Path)
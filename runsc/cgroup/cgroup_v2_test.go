@@ -268,6 +268,38 @@ func TestGetLimits(t *testing.T) {
 	}
 }
 
+func TestSetMemoryLimit(t *testing.T) {
+	dir, err := ioutil.TempDir(testutil.TmpDir(), "cgroup")
+	if err != nil {
+		t.Fatalf("error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cg := cgroupV2{
+		Mountpoint: dir,
+		Path:       "user.slice",
+	}
+	if err := os.MkdirAll(cg.MakePath(""), 0o777); err != nil {
+		t.Fatalf("os.MkdirAll(): %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cg.MakePath(""), "memory.max"), []byte("max"), 0o777); err != nil {
+		t.Fatalf("os.WriteFile(): %v", err)
+	}
+
+	for _, limit := range []uint64{1 << 30, 512 << 20} {
+		if err := cg.SetMemoryLimit(limit); err != nil {
+			t.Fatalf("cg.SetMemoryLimit(%d): %v", limit, err)
+		}
+		got, err := cg.MemoryLimit()
+		if err != nil {
+			t.Fatalf("cg.MemoryLimit(): %v", err)
+		}
+		if got != limit {
+			t.Errorf("cg.MemoryLimit() = %d, want %d", got, limit)
+		}
+	}
+}
+
 func TestNumToStr(t *testing.T) {
 	cases := map[int64]string{
 		0:  "",
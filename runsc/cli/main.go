@@ -60,7 +60,8 @@ var (
 	coverageFD = flag.Int("coverage-fd", -1, "file descriptor to write Go coverage output.")
 )
 
-// Main is the main entrypoint.
+// Main is the main entrypoint. Binaries that link in additional platforms
+// (see package platform) call it from their own main package.
 func Main() {
 	// Register all commands.
 	forEachCmd(subcommands.Register)
@@ -159,8 +160,8 @@ func Main() {
 		}
 		// Quick sanity check to make sure no other commands get passed
 		// a log fd (they should use log dir instead).
-		if subcommand != "boot" && subcommand != "gofer" {
-			util.Fatalf("flags --debug-log-fd and --panic-log-fd should only be passed to 'boot' and 'gofer' command, but was passed to %q", subcommand)
+		if subcommand != "boot" && subcommand != "gofer" && subcommand != "uds-proxy" {
+			util.Fatalf("flags --debug-log-fd and --panic-log-fd should only be passed to 'boot', 'gofer' and 'uds-proxy' command, but was passed to %q", subcommand)
 		}
 
 		// If we are the boot process, then we own our stdio FDs and can do what we
@@ -238,17 +239,22 @@ func forEachCmd(cb func(cmd subcommands.Command, group string)) {
 	cb(subcommands.FlagsCommand(), "")
 
 	// Register OCI user-facing runsc commands.
+	cb(new(cmd.AddInterface), "")
 	cb(new(cmd.Checkpoint), "")
 	cb(new(cmd.Create), "")
 	cb(new(cmd.Delete), "")
 	cb(new(cmd.Do), "")
 	cb(new(cmd.Events), "")
 	cb(new(cmd.Exec), "")
+	cb(new(cmd.Fork), "")
+	cb(new(cmd.GPU), "")
 	cb(new(cmd.Kill), "")
 	cb(new(cmd.List), "")
+	cb(new(cmd.Mount), "")
 	cb(new(cmd.PS), "")
 	cb(new(cmd.Pause), "")
 	cb(new(cmd.PortForward), "")
+	cb(new(cmd.ResizeMemory), "")
 	cb(new(cmd.Restore), "")
 	cb(new(cmd.Resume), "")
 	cb(new(cmd.Run), "")
@@ -256,6 +262,7 @@ func forEachCmd(cb func(cmd subcommands.Command, group string)) {
 	cb(new(cmd.Start), "")
 	cb(new(cmd.State), "")
 	cb(new(cmd.Wait), "")
+	cb(new(cmd.Zygote), "")
 
 	// Helpers.
 	const helperGroup = "helpers"
@@ -282,6 +289,7 @@ func forEachCmd(cb func(cmd subcommands.Command, group string)) {
 	const internalGroup = "internal use only"
 	cb(new(cmd.Boot), internalGroup)
 	cb(new(cmd.Gofer), internalGroup)
+	cb(new(cmd.UDSProxy), internalGroup)
 	cb(new(cmd.Umount), internalGroup)
 }
 
@@ -0,0 +1,70 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/container"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// AddInterface implements subcommands.Command for the "add-interface" command.
+type AddInterface struct{}
+
+// Name implements subcommands.Command.
+func (*AddInterface) Name() string {
+	return "add-interface"
+}
+
+// Synopsis implements subcommands.Command.
+func (*AddInterface) Synopsis() string {
+	return "move a network interface into a running sandbox"
+}
+
+// Usage implements subcommands.Command.
+func (*AddInterface) Usage() string {
+	return `add-interface <container id> <interface> - move the network interface
+from the sandbox's network namespace into the running sandbox, along with its
+addresses and routes. This allows networks to be attached after the sandbox
+was created, e.g. by CNI plugins adding secondary interfaces to a pod. The
+sandbox must run with --network=sandbox.
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (*AddInterface) SetFlags(*flag.FlagSet) {}
+
+// Execute implements subcommands.Command.
+func (*AddInterface) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if f.NArg() != 2 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	id, name := f.Arg(0), f.Arg(1)
+	conf := args[0].(*config.Config)
+
+	c, err := container.Load(conf.RootDir, container.FullID{ContainerID: id}, container.LoadOpts{})
+	if err != nil {
+		return util.Errorf("loading container: %v", err)
+	}
+	if err := c.AddNetworkInterface(conf, name); err != nil {
+		return util.Errorf("adding network interface: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
@@ -85,6 +85,9 @@ type Boot struct {
 	// devIoFD is the FD to connect to dev gofer.
 	devIoFD int
 
+	// hostAbstractUDSFD is the FD to connect to the host abstract UDS proxy.
+	hostAbstractUDSFD int
+
 	// goferFilestoreFDs are FDs to the regular files that will back the tmpfs or
 	// overlayfs mount for certain gofer mounts.
 	goferFilestoreFDs intFlags
@@ -155,6 +158,14 @@ type Boot struct {
 	// FDs for profile data.
 	profileFDs profile.FDArgs
 
+	// watchdogStallCheckpointFD is the file descriptor to write a statefile
+	// snapshot to when the watchdog detects a stalled subsystem.
+	watchdogStallCheckpointFD int
+
+	// swapFD is the file descriptor of the file backing the swap tier of
+	// sandbox memory.
+	swapFD int
+
 	// profilingMetricsFD is a file descriptor to write Sentry metrics data to.
 	profilingMetricsFD int
 
@@ -211,6 +222,7 @@ func (b *Boot) SetFlags(f *flag.FlagSet) {
 	f.IntVar(&b.deviceFD, "device-fd", -1, "FD for the platform device file")
 	f.Var(&b.ioFDs, "io-fds", "list of image FDs and/or socket FDs to connect gofer clients. They must follow this order: root first, then mounts as defined in the spec")
 	f.IntVar(&b.devIoFD, "dev-io-fd", -1, "FD to connect dev gofer client")
+	f.IntVar(&b.hostAbstractUDSFD, "host-abstract-uds-fd", -1, "FD to connect host abstract UDS proxy client")
 	f.Var(&b.stdioFDs, "stdio-fds", "list of FDs containing sandbox stdin, stdout, and stderr in that order")
 	f.Var(&b.passFDs, "pass-fd", "mapping of host to guest FDs. They must be in M:N format. M is the host and N the guest descriptor.")
 	f.IntVar(&b.execFD, "exec-fd", -1, "host file descriptor used for program execution.")
@@ -226,6 +238,8 @@ func (b *Boot) SetFlags(f *flag.FlagSet) {
 	// Profiling flags.
 	b.profileFDs.SetFromFlags(f)
 	f.IntVar(&b.profilingMetricsFD, "profiling-metrics-fd", -1, "file descriptor to write sentry profiling metrics.")
+	f.IntVar(&b.watchdogStallCheckpointFD, "watchdog-stall-checkpoint-fd", -1, "file descriptor to write a statefile snapshot to when the watchdog detects a stalled subsystem.")
+	f.IntVar(&b.swapFD, "swap-fd", -1, "file descriptor of the file backing the swap tier of sandbox memory.")
 	f.BoolVar(&b.profilingMetricsLossy, "profiling-metrics-fd-lossy", false, "if true, treat the sentry profiling metrics FD as lossy and write a checksum to it.")
 }
 
@@ -442,6 +456,7 @@ func (b *Boot) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomma
 		Device:              fd.New(b.deviceFD),
 		GoferFDs:            b.ioFDs.GetArray(),
 		DevGoferFD:          b.devIoFD,
+		HostAbstractUDSFD:   b.hostAbstractUDSFD,
 		StdioFDs:            b.stdioFDs.GetArray(),
 		PassFDs:             b.passFDs.GetArray(),
 		ExecFD:              b.execFD,
@@ -456,6 +471,9 @@ func (b *Boot) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomma
 		SinkFDs:             b.sinkFDs.GetArray(),
 		ProfileOpts:         b.profileFDs.ToOpts(),
 		NvidiaDriverVersion: b.nvidiaDriverVersion,
+
+		WatchdogStallCheckpointFD: b.watchdogStallCheckpointFD,
+		SwapFD:                    b.swapFD,
 	}
 	l, err := boot.New(bootArgs)
 	if err != nil {
@@ -18,14 +18,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/state/statefile"
 	"gvisor.dev/gvisor/runsc/cmd/util"
 	"gvisor.dev/gvisor/runsc/config"
 	"gvisor.dev/gvisor/runsc/container"
 	"gvisor.dev/gvisor/runsc/flag"
+	"gvisor.dev/gvisor/runsc/imagestore"
 )
 
 // Checkpoint implements subcommands.Command for the "checkpoint" command.
@@ -41,6 +44,14 @@ type Checkpoint struct {
 	// For example, if the checkpoint files will be stored on a network block
 	// device, which will be detached after the checkpoint is done.
 	direct bool
+
+	// rateLimit is the maximum rate at which the checkpoint is written, in
+	// bytes per second. If zero, the rate isn't limited.
+	rateLimit uint64
+
+	// progress is the interval at which the checkpoint progress is reported.
+	// If zero, progress isn't reported.
+	progress time.Duration
 }
 
 // Name implements subcommands.Command.Name.
@@ -61,11 +72,13 @@ func (*Checkpoint) Usage() string {
 
 // SetFlags implements subcommands.Command.SetFlags.
 func (c *Checkpoint) SetFlags(f *flag.FlagSet) {
-	f.StringVar(&c.imagePath, "image-path", "", "directory path to saved container image")
+	f.StringVar(&c.imagePath, "image-path", "", "directory path to saved container image, or URL of an image in object storage, e.g. s3://bucket/prefix or gs://bucket/prefix")
 	f.BoolVar(&c.leaveRunning, "leave-running", false, "restart the container after checkpointing")
 	f.Var(newCheckpointCompressionValue(statefile.CompressionLevelDefault, &c.compression), "compression", "compress checkpoint image on disk. Values: none|flate-best-speed.")
 	f.BoolVar(&c.excludeCommittedZeroPages, "exclude-committed-zero-pages", false, "exclude committed zero-filled pages from checkpoint")
 	f.BoolVar(&c.direct, "direct", false, "use O_DIRECT for writing checkpoint pages file")
+	f.Uint64Var(&c.rateLimit, "rate-limit", 0, "maximum rate at which the checkpoint is written, in bytes per second. 0 means unlimited.")
+	f.DurationVar(&c.progress, "progress", 0, "interval at which the checkpoint progress is reported. 0 disables progress reports.")
 
 	// Unimplemented flags necessary for compatibility with docker.
 	var wp string
@@ -91,12 +104,15 @@ func (c *Checkpoint) Execute(_ context.Context, f *flag.FlagSet, args ...any) su
 		util.Fatalf("image-path flag must be provided")
 	}
 
-	if err := os.MkdirAll(c.imagePath, 0755); err != nil {
-		util.Fatalf("making directories at path provided: %v", err)
+	if !imagestore.IsRemote(c.imagePath) {
+		if err := os.MkdirAll(c.imagePath, 0755); err != nil {
+			util.Fatalf("making directories at path provided: %v", err)
+		}
 	}
 
 	sOpts := statefile.Options{
 		Compression: c.compression.Level(),
+		RateLimit:   c.rateLimit,
 	}
 	mfOpts := pgalloc.SaveOpts{
 		ExcludeCommittedZeroPages: c.excludeCommittedZeroPages,
@@ -107,6 +123,12 @@ func (c *Checkpoint) Execute(_ context.Context, f *flag.FlagSet, args ...any) su
 		sOpts.Resume = true
 	}
 
+	if c.progress > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go reportCheckpointProgress(cont, c.progress, done)
+	}
+
 	if err := cont.Checkpoint(c.imagePath, c.direct, sOpts, mfOpts); err != nil {
 		util.Fatalf("checkpoint failed: %v", err)
 	}
@@ -114,6 +136,29 @@ func (c *Checkpoint) Execute(_ context.Context, f *flag.FlagSet, args ...any) su
 	return subcommands.ExitSuccess
 }
 
+// reportCheckpointProgress reports the checkpoint progress of cont every
+// interval until done is closed.
+func reportCheckpointProgress(cont *container.Container, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		p, err := cont.Sandbox.SaveProgress()
+		if err != nil {
+			log.Warningf("Getting checkpoint progress: %v", err)
+			continue
+		}
+		if !p.InProgress {
+			continue
+		}
+		util.Infof("Checkpoint progress: %d/%d objects, %d bytes written in %v", p.ObjectsWritten, p.ObjectsTotal, p.BytesWritten, p.Elapsed.Round(time.Millisecond))
+	}
+}
+
 // CheckpointCompression represents checkpoint image writer behavior. The
 // default behavior is to compress because the default behavior used to be to
 // always compress.
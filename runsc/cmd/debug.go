@@ -16,17 +16,22 @@ package cmd
 
 import (
 	"context"
+	"encoding/hex"
+	"fmt"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/subcommands"
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/control"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/platform"
 	"gvisor.dev/gvisor/runsc/cmd/util"
 	"gvisor.dev/gvisor/runsc/config"
 	"gvisor.dev/gvisor/runsc/container"
@@ -35,21 +40,30 @@ import (
 
 // Debug implements subcommands.Command for the "debug" command.
 type Debug struct {
-	pid          int
-	stacks       bool
-	signal       int
-	profileBlock string
-	profileCPU   string
-	profileHeap  string
-	profileMutex string
-	trace        string
-	strace       string
-	logLevel     string
-	logPackets   string
-	delay        time.Duration
-	duration     time.Duration
-	ps           bool
-	mount        string
+	pid              int
+	stacks           bool
+	signal           int
+	profileBlock     string
+	profileCPU       string
+	profileHeap      string
+	profileHeapDelta bool
+	profileMutex     string
+	profileGoroutine string
+	profileInterval  time.Duration
+	trace            string
+	strace           string
+	logLevel         string
+	logPackets       string
+	delay            time.Duration
+	duration         time.Duration
+	ps               bool
+	mount            string
+	mountVerityRoot  string
+	verity           string
+	injectFaults     string
+	clockSkew        string
+	syscallPaths     string
+	syscallStats     bool
 }
 
 // Name implements subcommands.Command.
@@ -75,6 +89,9 @@ func (d *Debug) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&d.profileCPU, "profile-cpu", "", "writes CPU profile to the given file.")
 	f.StringVar(&d.profileHeap, "profile-heap", "", "writes heap profile to the given file.")
 	f.StringVar(&d.profileMutex, "profile-mutex", "", "writes mutex profile to the given file.")
+	f.BoolVar(&d.profileHeapDelta, "profile-heap-delta", false, "if true, the heap profile only contains the allocations made during -delay.")
+	f.StringVar(&d.profileGoroutine, "profile-goroutine", "", "writes the stacks of all goroutines to the given file, annotated with the guest task that each task goroutine runs.")
+	f.DurationVar(&d.profileInterval, "profile-interval", 0, "if set, block, CPU, heap and mutex profiles are collected continuously until -duration elapses, each covering the given interval. The Nth profile is written to <file>.N.")
 	f.DurationVar(&d.delay, "delay", time.Hour, "amount of time to delay for collecting heap and goroutine profiles.")
 	f.DurationVar(&d.duration, "duration", time.Hour, "amount of time to wait for CPU and trace profiles.")
 	f.StringVar(&d.trace, "trace", "", "writes an execution trace to the given file.")
@@ -82,8 +99,14 @@ func (d *Debug) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&d.strace, "strace", "", `A comma separated list of syscalls to trace. "all" enables all traces, "off" disables all.`)
 	f.StringVar(&d.logLevel, "log-level", "", "The log level to set: warning (0), info (1), or debug (2).")
 	f.StringVar(&d.logPackets, "log-packets", "", "A boolean value to enable or disable packet logging: true or false.")
+	f.StringVar(&d.clockSkew, "clock-skew", "", `Comma separated clock skew options, e.g. "realtime=8760h,monotonic=1h,scale=2". Options are realtime and monotonic (offsets added to CLOCK_REALTIME and CLOCK_MONOTONIC) and scale (rate at which time elapses). "off" removes the skew.`)
+	f.StringVar(&d.injectFaults, "inject-faults", "", `Semicolon separated list of syscall fault injection rules, e.g. "write:errno=ENOSPC,probability=0.01;connect:delay=50ms,pids=1:2". Options are errno, delay, probability, container and pids. "off" disables fault injection.`)
+	f.StringVar(&d.syscallPaths, "syscall-paths", "", `Comma separated list of syscall wait strategies, e.g. "futex=fast,recvmsg=slow". After returning from a syscall marked "fast" the platform polls for the next trap, after one marked "slow" it sleeps, and "auto" restores the adaptive default. Only supported by systrap.`)
+	f.BoolVar(&d.syscallStats, "syscall-path-stats", false, "prints the wait strategy and switch latency observed after syscalls. Only supported by systrap.")
 	f.BoolVar(&d.ps, "ps", false, "lists processes")
 	f.StringVar(&d.mount, "mount", "", "Mount a filesystem (-mount fstype:source:destination).")
+	f.StringVar(&d.mountVerityRoot, "mount-verity-root", "", "hex-encoded root hash used to verify the EROFS image mounted with -mount.")
+	f.StringVar(&d.verity, "verity", "", "Enable verification of all files below a directory and print its root hash (-verity path[:root-hash]). If a root hash is given, or was pinned for the path, verification fails if the root hash differs.")
 }
 
 // Execute implements subcommands.Command.Execute.
@@ -97,6 +120,9 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 	if conf.TraceFile != "" {
 		return util.Errorf("global -trace flag has no effect on runsc debug. Pass runsc debug -trace instead")
 	}
+	if d.profileInterval != 0 && d.trace != "" {
+		return util.Errorf("-trace can't be used with -profile-interval")
+	}
 
 	if d.pid == 0 {
 		// No pid, container ID must have been provided.
@@ -158,6 +184,18 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		}
 		util.Infof("     *** Stack dump ***\n%s", stacks)
 	}
+	if d.profileGoroutine != "" {
+		util.Infof("Retrieving sandbox goroutines")
+		f, err := os.OpenFile(d.profileGoroutine, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return util.Errorf("error opening goroutine profile output: %v", err)
+		}
+		defer f.Close()
+		if err := c.Sandbox.GoroutineProfile(f, true /* annotate */); err != nil {
+			os.Remove(f.Name())
+			return util.Errorf("retrieving goroutines: %v", err)
+		}
+	}
 	if d.strace != "" || len(d.logLevel) != 0 || len(d.logPackets) != 0 {
 		args := control.LoggingArgs{}
 		switch strings.ToLower(d.strace) {
@@ -214,6 +252,61 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		}
 		util.Infof("Logging options changed")
 	}
+	if d.clockSkew != "" {
+		args := control.ClockSkewArgs{}
+		if strings.ToLower(d.clockSkew) == "off" {
+			util.Infof("Removing clock skew")
+		} else {
+			skew, err := parseClockSkew(d.clockSkew)
+			if err != nil {
+				return util.Errorf("%v", err)
+			}
+			args.Skew = skew
+			util.Infof("Setting clock skew: %+v", skew)
+		}
+		if err := c.Sandbox.SetClockSkew(args); err != nil {
+			return util.Errorf(err.Error())
+		}
+		util.Infof("Clock skew changed")
+	}
+	if d.injectFaults != "" {
+		args := control.FaultInjectionArgs{}
+		if strings.ToLower(d.injectFaults) == "off" {
+			util.Infof("Disabling fault injection")
+		} else {
+			rules, err := parseFaultInjectionRules(d.injectFaults)
+			if err != nil {
+				return util.Errorf("%v", err)
+			}
+			args.Rules = rules
+			util.Infof("Injecting faults: %+v", rules)
+		}
+		if err := c.Sandbox.SetFaultInjection(args); err != nil {
+			return util.Errorf(err.Error())
+		}
+		util.Infof("Fault injection rules changed")
+	}
+	if d.syscallPaths != "" {
+		paths, err := parseSyscallPaths(d.syscallPaths)
+		if err != nil {
+			return util.Errorf("%v", err)
+		}
+		util.Infof("Setting syscall paths: %v", paths)
+		if err := c.Sandbox.SetSyscallPaths(control.SyscallPathArgs{Paths: paths}); err != nil {
+			return util.Errorf(err.Error())
+		}
+		util.Infof("Syscall paths changed")
+	}
+	if d.syscallStats {
+		util.Infof("Retrieving syscall path stats")
+		stats, err := c.Sandbox.SyscallPathStats()
+		if err != nil {
+			return util.Errorf(err.Error())
+		}
+		for _, s := range stats {
+			util.Infof("%s: path %s, waits %d (slow %d), mean latency %d cycles", s.Name, s.Path, s.Waits, s.SlowWaits, s.MeanLatencyCycles)
+		}
+	}
 	if d.ps {
 		util.Infof("Retrieving process list")
 		pList, err := c.Processes()
@@ -234,10 +327,30 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		fstype := opts[0]
 		src := opts[1]
 		dest := opts[2]
-		if err := c.Sandbox.Mount(c.ID, fstype, src, dest); err != nil {
+		verityRootHash, err := hex.DecodeString(d.mountVerityRoot)
+		if err != nil {
+			util.Fatalf("Mount failed: invalid verity root hash: %v", err)
+		}
+		if err := c.Sandbox.Mount(c.ID, fstype, src, dest, verityRootHash); err != nil {
 			util.Fatalf(err.Error())
 		}
 	}
+	if d.verity != "" {
+		path, hash, _ := strings.Cut(d.verity, ":")
+		rootHash, err := hex.DecodeString(hash)
+		if err != nil {
+			util.Fatalf("Enabling verity failed: invalid root hash: %v", err)
+		}
+		got, err := c.EnableVerity(path, rootHash)
+		if err != nil {
+			util.Fatalf("Enabling verity failed: %v", err)
+		}
+		util.Infof("Verity enabled for %q, root hash: %x", path, got)
+	}
+
+	if d.profileInterval != 0 {
+		return d.profileContinuously(c)
+	}
 
 	// Open profiling files.
 	var (
@@ -314,7 +427,7 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			heapErr = c.Sandbox.HeapProfile(heapFile, d.delay)
+			heapErr = c.Sandbox.HeapProfile(heapFile, d.delay, d.profileHeapDelta)
 		}()
 	}
 	if mutexFile != nil {
@@ -332,9 +445,50 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		}()
 	}
 
+	waitInterruptible(&wg, func() {})
+
+	// Collect all errors.
+	errorCount := 0
+	if blockErr != nil {
+		errorCount++
+		util.Infof("error collecting block profile: %v", blockErr)
+		os.Remove(blockFile.Name())
+	}
+	if cpuErr != nil {
+		errorCount++
+		util.Infof("error collecting cpu profile: %v", cpuErr)
+		os.Remove(cpuFile.Name())
+	}
+	if heapErr != nil {
+		errorCount++
+		util.Infof("error collecting heap profile: %v", heapErr)
+		os.Remove(heapFile.Name())
+	}
+	if mutexErr != nil {
+		errorCount++
+		util.Infof("error collecting mutex profile: %v", mutexErr)
+		os.Remove(mutexFile.Name())
+	}
+	if traceErr != nil {
+		errorCount++
+		util.Infof("error collecting trace profile: %v", traceErr)
+		os.Remove(traceFile.Name())
+	}
+
+	if errorCount > 0 {
+		return subcommands.ExitFailure
+	}
+
+	return subcommands.ExitSuccess
+}
+
+// waitInterruptible waits for wg. If a signal is caught before wg is done,
+// interrupted is called and the process exits unless wg is done within one
+// more second.
+func waitInterruptible(wg *sync.WaitGroup, interrupted func()) {
 	// Before sleeping, allow us to catch signals and try to exit
 	// gracefully before just exiting. If we can't wait for wg, then
-	// we will not be able to read the errors below safely.
+	// we will not be able to read the errors safely.
 	readyChan := make(chan struct{})
 	go func() {
 		defer close(readyChan)
@@ -342,10 +496,12 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 	}()
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, unix.SIGTERM, unix.SIGINT)
+	defer signal.Stop(signals)
 	select {
 	case <-readyChan:
 		break // Safe to proceed.
 	case <-signals:
+		interrupted()
 		util.Infof("caught signal, waiting at most one more second.")
 		select {
 		case <-signals:
@@ -358,38 +514,121 @@ func (d *Debug) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 			break // Safe to proceed.
 		}
 	}
+}
 
-	// Collect all errors.
-	errorCount := 0
-	if blockErr != nil {
-		errorCount++
-		util.Infof("error collecting block profile: %v", blockErr)
-		os.Remove(blockFile.Name())
+// profileContinuously collects consecutive block, CPU, heap and mutex
+// profiles, each covering d.profileInterval, until d.duration elapses or a
+// signal is caught. The Nth profile of each kind is written to <file>.N.
+func (d *Debug) profileContinuously(c *container.Container) subcommands.ExitStatus {
+	type profile struct {
+		name    string
+		path    string
+		collect func(f *os.File, period time.Duration) error
 	}
-	if cpuErr != nil {
-		errorCount++
-		util.Infof("error collecting cpu profile: %v", cpuErr)
-		os.Remove(cpuFile.Name())
+	var profiles []profile
+	if d.profileBlock != "" {
+		profiles = append(profiles, profile{name: "block", path: d.profileBlock, collect: c.Sandbox.BlockProfile})
 	}
-	if heapErr != nil {
-		errorCount++
-		util.Infof("error collecting heap profile: %v", heapErr)
-		os.Remove(heapFile.Name())
+	if d.profileCPU != "" {
+		profiles = append(profiles, profile{name: "cpu", path: d.profileCPU, collect: c.Sandbox.CPUProfile})
 	}
-	if mutexErr != nil {
-		errorCount++
-		util.Infof("error collecting mutex profile: %v", mutexErr)
-		os.Remove(mutexFile.Name())
+	if d.profileHeap != "" {
+		profiles = append(profiles, profile{name: "heap", path: d.profileHeap, collect: func(f *os.File, period time.Duration) error {
+			return c.Sandbox.HeapProfile(f, period, d.profileHeapDelta)
+		}})
 	}
-	if traceErr != nil {
-		errorCount++
-		util.Infof("error collecting trace profile: %v", traceErr)
-		os.Remove(traceFile.Name())
+	if d.profileMutex != "" {
+		profiles = append(profiles, profile{name: "mutex", path: d.profileMutex, collect: c.Sandbox.MutexProfile})
 	}
 
-	if errorCount > 0 {
-		return subcommands.ExitFailure
+	var (
+		wg         sync.WaitGroup
+		errorCount atomic.Int32
+	)
+	stop := make(chan struct{})
+	deadline := time.Now().Add(d.duration)
+	for _, p := range profiles {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				remaining := time.Until(deadline)
+				if remaining <= 0 {
+					return
+				}
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				path := fmt.Sprintf("%s.%d", p.path, i)
+				f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+				if err != nil {
+					util.Infof("error opening %s profile output: %v", p.name, err)
+					errorCount.Add(1)
+					return
+				}
+				err = p.collect(f, min(d.profileInterval, remaining))
+				f.Close()
+				if err != nil {
+					util.Infof("error collecting %s profile: %v", p.name, err)
+					os.Remove(path)
+					errorCount.Add(1)
+					return
+				}
+				util.Infof("Wrote %s profile to %s", p.name, path)
+			}
+		}()
 	}
+	waitInterruptible(&wg, func() { close(stop) })
 
+	if errorCount.Load() > 0 {
+		return subcommands.ExitFailure
+	}
 	return subcommands.ExitSuccess
 }
+
+// parseClockSkew parses clock skew options in the format accepted by
+// "runsc debug -clock-skew".
+func parseClockSkew(spec string) (kernel.ClockSkew, error) {
+	var skew kernel.ClockSkew
+	for _, opt := range strings.Split(spec, ",") {
+		key, val, ok := strings.Cut(opt, "=")
+		if !ok {
+			return kernel.ClockSkew{}, fmt.Errorf("invalid clock skew option %q", opt)
+		}
+		var err error
+		switch key {
+		case "realtime":
+			skew.RealtimeOffset, err = time.ParseDuration(val)
+		case "monotonic":
+			skew.MonotonicOffset, err = time.ParseDuration(val)
+		case "scale":
+			skew.Scale, err = strconv.ParseFloat(val, 64)
+		default:
+			return kernel.ClockSkew{}, fmt.Errorf("unknown clock skew option %q", key)
+		}
+		if err != nil {
+			return kernel.ClockSkew{}, fmt.Errorf("invalid clock skew option %q: %w", opt, err)
+		}
+	}
+	return skew, nil
+}
+
+// parseSyscallPaths parses syscall wait strategies in the format accepted by
+// "runsc debug -syscall-paths".
+func parseSyscallPaths(spec string) (map[string]platform.SyscallPath, error) {
+	paths := make(map[string]platform.SyscallPath)
+	for _, opt := range strings.Split(spec, ",") {
+		name, val, ok := strings.Cut(opt, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid syscall path %q: want <syscall>=<path>", opt)
+		}
+		path := platform.SyscallPath(val)
+		if err := path.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid syscall path %q: %w", opt, err)
+		}
+		paths[name] = path
+	}
+	return paths, nil
+}
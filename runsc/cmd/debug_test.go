@@ -0,0 +1,118 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+	"gvisor.dev/gvisor/pkg/sentry/platform"
+)
+
+func TestParseClockSkew(t *testing.T) {
+	for _, tc := range []struct {
+		spec    string
+		want    kernel.ClockSkew
+		wantErr bool
+	}{
+		{
+			spec: "realtime=-1h",
+			want: kernel.ClockSkew{RealtimeOffset: -time.Hour},
+		},
+		{
+			spec: "realtime=8760h,monotonic=1s,scale=0.5",
+			want: kernel.ClockSkew{RealtimeOffset: 8760 * time.Hour, MonotonicOffset: time.Second, Scale: 0.5},
+		},
+		{
+			spec:    "realtime",
+			wantErr: true,
+		},
+		{
+			spec:    "foo=1",
+			wantErr: true,
+		},
+		{
+			spec:    "scale=fast",
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			got, err := parseClockSkew(tc.spec)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseClockSkew(%q) succeeded, want error", tc.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseClockSkew(%q): %v", tc.spec, err)
+			}
+			if got != tc.want {
+				t.Errorf("parseClockSkew(%q) = %+v, want %+v", tc.spec, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSyscallPaths(t *testing.T) {
+	for _, tc := range []struct {
+		spec    string
+		want    map[string]platform.SyscallPath
+		wantErr bool
+	}{
+		{
+			spec: "futex=fast",
+			want: map[string]platform.SyscallPath{"futex": platform.SyscallPathFast},
+		},
+		{
+			spec: "futex=fast,recvmsg=slow,clock_gettime=auto",
+			want: map[string]platform.SyscallPath{
+				"futex":         platform.SyscallPathFast,
+				"recvmsg":       platform.SyscallPathSlow,
+				"clock_gettime": platform.SyscallPathAuto,
+			},
+		},
+		{
+			spec:    "futex",
+			wantErr: true,
+		},
+		{
+			spec:    "=fast",
+			wantErr: true,
+		},
+		{
+			spec:    "futex=faster",
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			got, err := parseSyscallPaths(tc.spec)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseSyscallPaths(%q) succeeded, want error", tc.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSyscallPaths(%q): %v", tc.spec, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseSyscallPaths(%q) = %v, want %v", tc.spec, got, tc.want)
+			}
+		})
+	}
+}
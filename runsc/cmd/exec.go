@@ -32,12 +32,14 @@ import (
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/sentry/control"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/limits"
 	"gvisor.dev/gvisor/runsc/cmd/util"
 	"gvisor.dev/gvisor/runsc/config"
 	"gvisor.dev/gvisor/runsc/console"
 	"gvisor.dev/gvisor/runsc/container"
 	"gvisor.dev/gvisor/runsc/flag"
 	"gvisor.dev/gvisor/runsc/specutils"
+	"gvisor.dev/gvisor/runsc/specutils/seccomp"
 )
 
 // Exec implements subcommands.Command for the "exec" command.
@@ -64,6 +66,22 @@ type Exec struct {
 
 	// execFD is the host file descriptor used for program execution.
 	execFD int
+
+	// capBound, if set, restricts all capability sets of the process to
+	// these capabilities.
+	capBound stringSlice
+
+	// seccompProfile is the path to an OCI seccomp profile to install on the
+	// process.
+	seccompProfile string
+
+	// cgroup is the path of the cgroup in which to place the process,
+	// relative to the container's cgroup in the sentry.
+	cgroup string
+
+	// rlimits are resource limits for the process, in the format
+	// <resource>=<soft>:<hard>.
+	rlimits stringSlice
 }
 
 // Name implements subcommands.Command.Name.
@@ -109,6 +127,10 @@ func (ex *Exec) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&ex.consoleSocket, "console-socket", "", "path to an AF_UNIX socket which will receive a file descriptor referencing the master end of the console's pseudoterminal")
 	f.Var(&ex.passFDs, "pass-fd", "file descriptor passed to the container in M:N format, where M is the host and N is the guest descriptor (can be supplied multiple times)")
 	f.IntVar(&ex.execFD, "exec-fd", -1, "host file descriptor used for program execution")
+	f.Var(&ex.capBound, "cap-bound", "restrict the capability bounding set, and all other capability sets, of the process to these capabilities")
+	f.StringVar(&ex.seccompProfile, "seccomp-profile", "", "path to an OCI seccomp profile (the JSON of the spec's linux.seccomp) to install on the process, in addition to any filter it installs itself")
+	f.StringVar(&ex.cgroup, "cgroup", "", "path of the cgroup in which to place the process, relative to the container's cgroup in the sandbox")
+	f.Var(&ex.rlimits, "rlimit", "set a resource limit for the process, in the format <resource>=<soft>:<hard>, e.g. RLIMIT_NOFILE=1024:4096 (can be supplied multiple times)")
 }
 
 // Execute implements subcommands.Command.Execute. It starts a process in an
@@ -147,6 +169,9 @@ func (ex *Exec) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		}
 		log.Infof("Using exec capabilities from container: %+v", e.Capabilities)
 	}
+	if err := ex.applySecurityContext(conf, e); err != nil {
+		util.Fatalf("%v", err)
+	}
 
 	// Create the file descriptor map for the process in the container.
 	fdMap := map[int]*os.File{
@@ -413,6 +438,18 @@ func argsFromProcess(p *specs.Process, enableRaw bool) (*control.ExecArgs, error
 		}
 	}
 
+	var rlimits map[limits.LimitType]limits.Limit
+	for _, rl := range p.Rlimits {
+		lt, ok := limits.FromLinuxResourceName[rl.Type]
+		if !ok {
+			return nil, fmt.Errorf("unknown resource %q", rl.Type)
+		}
+		if rlimits == nil {
+			rlimits = make(map[limits.LimitType]limits.Limit)
+		}
+		rlimits[lt] = limits.Limit{Cur: rl.Soft, Max: rl.Hard}
+	}
+
 	// Convert the spec's additional GIDs to KGIDs.
 	extraKGIDs := make([]auth.KGID, 0, len(p.User.AdditionalGids))
 	for _, GID := range p.User.AdditionalGids {
@@ -427,6 +464,7 @@ func argsFromProcess(p *specs.Process, enableRaw bool) (*control.ExecArgs, error
 		KGID:             auth.KGID(p.User.GID),
 		ExtraKGIDs:       extraKGIDs,
 		Capabilities:     caps,
+		Rlimits:          rlimits,
 		StdioIsPty:       p.Terminal,
 		FilePayload: control.NewFilePayload(map[int]*os.File{
 			0: os.Stdin,
@@ -436,6 +474,89 @@ func argsFromProcess(p *specs.Process, enableRaw bool) (*control.ExecArgs, error
 	}, nil
 }
 
+// applySecurityContext restricts e by the capability bounding set, seccomp
+// profile, cgroup and resource limits given on the command line.
+func (ex *Exec) applySecurityContext(conf *config.Config, e *control.ExecArgs) error {
+	if len(ex.capBound) > 0 {
+		bound, err := specutils.Capabilities(conf.EnableRaw, &specs.LinuxCapabilities{Bounding: ex.capBound})
+		if err != nil {
+			return fmt.Errorf("capability bounding set error: %v", err)
+		}
+		caps := e.Capabilities
+		caps.BoundingCaps &= bound.BoundingCaps
+		caps.PermittedCaps &= bound.BoundingCaps
+		caps.EffectiveCaps &= bound.BoundingCaps
+		caps.InheritableCaps &= bound.BoundingCaps
+		caps.AmbientCaps &= bound.BoundingCaps
+	}
+
+	if ex.seccompProfile != "" {
+		data, err := os.ReadFile(ex.seccompProfile)
+		if err != nil {
+			return fmt.Errorf("reading seccomp profile: %v", err)
+		}
+		var profile specs.LinuxSeccomp
+		if err := json.Unmarshal(data, &profile); err != nil {
+			return fmt.Errorf("parsing seccomp profile %q: %v", ex.seccompProfile, err)
+		}
+		e.SeccompFilter, err = seccomp.BuildInstructions(&profile)
+		if err != nil {
+			return fmt.Errorf("seccomp profile %q: %v", ex.seccompProfile, err)
+		}
+	}
+
+	if ex.cgroup != "" {
+		e.CgroupPath = ex.cgroup
+	}
+
+	for _, s := range ex.rlimits {
+		lt, l, err := parseRlimit(s)
+		if err != nil {
+			return err
+		}
+		if e.Rlimits == nil {
+			e.Rlimits = make(map[limits.LimitType]limits.Limit)
+		}
+		e.Rlimits[lt] = l
+	}
+	return nil
+}
+
+// parseRlimit parses a resource limit in the format <resource>=<soft>:<hard>,
+// where <soft> and <hard> are integers or "unlimited".
+func parseRlimit(s string) (limits.LimitType, limits.Limit, error) {
+	name, values, ok := strings.Cut(s, "=")
+	if !ok {
+		return 0, limits.Limit{}, fmt.Errorf("invalid rlimit %q: want <resource>=<soft>:<hard>", s)
+	}
+	lt, ok := limits.FromLinuxResourceName[name]
+	if !ok {
+		return 0, limits.Limit{}, fmt.Errorf("unknown resource %q", name)
+	}
+	soft, hard, ok := strings.Cut(values, ":")
+	if !ok {
+		return 0, limits.Limit{}, fmt.Errorf("invalid rlimit %q: want <resource>=<soft>:<hard>", s)
+	}
+	parse := func(v string) (uint64, error) {
+		if v == "unlimited" {
+			return limits.Infinity, nil
+		}
+		return strconv.ParseUint(v, 10, 64)
+	}
+	var l limits.Limit
+	var err error
+	if l.Cur, err = parse(soft); err != nil {
+		return 0, limits.Limit{}, fmt.Errorf("invalid soft limit in rlimit %q: %v", s, err)
+	}
+	if l.Max, err = parse(hard); err != nil {
+		return 0, limits.Limit{}, fmt.Errorf("invalid hard limit in rlimit %q: %v", s, err)
+	}
+	if l.Cur > l.Max {
+		return 0, limits.Limit{}, fmt.Errorf("invalid rlimit %q: soft limit exceeds hard limit", s)
+	}
+	return lt, l, nil
+}
+
 // capabilities takes a list of capabilities as strings and returns an
 // auth.TaskCapabilities struct with those capabilities in every capability set.
 // This mimics runc's behavior.
@@ -24,6 +24,8 @@ import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/sentry/control"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
+	"gvisor.dev/gvisor/pkg/sentry/limits"
+	"gvisor.dev/gvisor/runsc/config"
 )
 
 func TestUser(t *testing.T) {
@@ -159,3 +161,98 @@ func TestJSONArgs(t *testing.T) {
 		}
 	}
 }
+
+func TestJSONArgsRlimits(t *testing.T) {
+	p := specs.Process{
+		Args: []string{"ls", "/"},
+		Rlimits: []specs.POSIXRlimit{
+			{Type: "RLIMIT_NOFILE", Soft: 1024, Hard: 4096},
+		},
+	}
+	e, err := argsFromProcess(&p, true)
+	if err != nil {
+		t.Fatalf("argsFromProcess(%+v): got error: %v", p, err)
+	}
+	want := map[limits.LimitType]limits.Limit{
+		limits.NumberOfFiles: {Cur: 1024, Max: 4096},
+	}
+	if !cmp.Equal(e.Rlimits, want) {
+		t.Errorf("argsFromProcess(%+v): got rlimits %+v, want %+v", p, e.Rlimits, want)
+	}
+
+	p.Rlimits = []specs.POSIXRlimit{{Type: "RLIMIT_FOO"}}
+	if _, err := argsFromProcess(&p, true); err == nil {
+		t.Errorf("argsFromProcess(%+v): got no error, but wanted one", p)
+	}
+}
+
+func TestParseRlimit(t *testing.T) {
+	for _, tc := range []struct {
+		input   string
+		lt      limits.LimitType
+		want    limits.Limit
+		wantErr bool
+	}{
+		{input: "RLIMIT_NOFILE=1024:4096", lt: limits.NumberOfFiles, want: limits.Limit{Cur: 1024, Max: 4096}},
+		{input: "RLIMIT_CORE=0:unlimited", lt: limits.Core, want: limits.Limit{Cur: 0, Max: limits.Infinity}},
+		{input: "RLIMIT_NOFILE=1024", wantErr: true},
+		{input: "RLIMIT_NOFILE", wantErr: true},
+		{input: "RLIMIT_FOO=1:2", wantErr: true},
+		{input: "RLIMIT_NOFILE=foo:2", wantErr: true},
+		{input: "RLIMIT_NOFILE=2:1", wantErr: true},
+	} {
+		lt, l, err := parseRlimit(tc.input)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseRlimit(%q): got no error, but wanted one", tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRlimit(%q): got error %v, but wanted none", tc.input, err)
+		} else if lt != tc.lt || l != tc.want {
+			t.Errorf("parseRlimit(%q): got (%v, %+v), but wanted (%v, %+v)", tc.input, lt, l, tc.lt, tc.want)
+		}
+	}
+}
+
+func TestApplySecurityContext(t *testing.T) {
+	ex := Exec{
+		capBound: []string{"CAP_CHOWN", "CAP_KILL"},
+		cgroup:   "debug",
+		rlimits:  []string{"RLIMIT_NPROC=10:20"},
+	}
+	all := auth.CapabilitySetOfMany([]linux.Capability{linux.CAP_CHOWN, linux.CAP_DAC_OVERRIDE, linux.CAP_KILL})
+	e := control.ExecArgs{
+		Capabilities: &auth.TaskCapabilities{
+			BoundingCaps:    all,
+			EffectiveCaps:   all,
+			InheritableCaps: all,
+			PermittedCaps:   all,
+			AmbientCaps:     auth.CapabilitySetOf(linux.CAP_DAC_OVERRIDE),
+		},
+	}
+	if err := ex.applySecurityContext(&config.Config{}, &e); err != nil {
+		t.Fatalf("applySecurityContext(): got error: %v", err)
+	}
+
+	bound := auth.CapabilitySetOfMany([]linux.Capability{linux.CAP_CHOWN, linux.CAP_KILL})
+	wantCaps := auth.TaskCapabilities{
+		BoundingCaps:    bound,
+		EffectiveCaps:   bound,
+		InheritableCaps: bound,
+		PermittedCaps:   bound,
+	}
+	if *e.Capabilities != wantCaps {
+		t.Errorf("applySecurityContext(): got capabilities %+v, want %+v", *e.Capabilities, wantCaps)
+	}
+	if e.CgroupPath != "debug" {
+		t.Errorf("applySecurityContext(): got cgroup path %q, want %q", e.CgroupPath, "debug")
+	}
+	wantRlimits := map[limits.LimitType]limits.Limit{
+		limits.ProcessCount: {Cur: 10, Max: 20},
+	}
+	if !cmp.Equal(e.Rlimits, wantRlimits) {
+		t.Errorf("applySecurityContext(): got rlimits %+v, want %+v", e.Rlimits, wantRlimits)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+)
+
+// parseFaultInjectionRules parses fault injection rules in the format accepted
+// by "runsc debug -inject-faults". Rules are separated by semicolons, and each
+// rule is a syscall name followed by comma separated options, e.g.:
+//
+//	write:errno=ENOSPC,probability=0.01;connect:delay=50ms,pids=1:2
+//
+// Supported options are errno (name or number), delay, probability, container
+// and pids (separated by colons).
+func parseFaultInjectionRules(spec string) ([]kernel.FaultInjectionRule, error) {
+	var rules []kernel.FaultInjectionRule
+	for _, ruleSpec := range strings.Split(spec, ";") {
+		ruleSpec = strings.TrimSpace(ruleSpec)
+		if ruleSpec == "" {
+			continue
+		}
+		syscall, opts, ok := strings.Cut(ruleSpec, ":")
+		if !ok || syscall == "" {
+			return nil, fmt.Errorf("invalid fault injection rule %q: want <syscall>:<options>", ruleSpec)
+		}
+		rule := kernel.FaultInjectionRule{Syscall: syscall}
+		for _, opt := range strings.Split(opts, ",") {
+			key, val, ok := strings.Cut(opt, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid option %q in fault injection rule %q", opt, ruleSpec)
+			}
+			var err error
+			switch key {
+			case "errno":
+				rule.Errno, err = parseErrno(val)
+			case "delay":
+				rule.Delay, err = time.ParseDuration(val)
+			case "probability":
+				rule.Probability, err = strconv.ParseFloat(val, 64)
+			case "container":
+				rule.ContainerID = val
+			case "pids":
+				for _, p := range strings.Split(val, ":") {
+					var pid int64
+					if pid, err = strconv.ParseInt(p, 10, 32); err != nil {
+						break
+					}
+					rule.PIDs = append(rule.PIDs, kernel.ThreadID(pid))
+				}
+			default:
+				return nil, fmt.Errorf("unknown option %q in fault injection rule %q", key, ruleSpec)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid option %q in fault injection rule %q: %w", opt, ruleSpec, err)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parseErrno parses an errno given either as a number or by name, e.g.
+// "ENOSPC".
+func parseErrno(s string) (int, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+	name := strings.ToUpper(s)
+	for e := unix.Errno(1); e < 4096; e++ {
+		if unix.ErrnoName(e) == name {
+			return int(e), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown errno %q", s)
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/sentry/kernel"
+)
+
+func TestParseFaultInjectionRules(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		spec    string
+		want    []kernel.FaultInjectionRule
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			spec: "",
+		},
+		{
+			name: "errno by name",
+			spec: "write:errno=ENOSPC,probability=0.01",
+			want: []kernel.FaultInjectionRule{
+				{Syscall: "write", Errno: int(unix.ENOSPC), Probability: 0.01},
+			},
+		},
+		{
+			name: "multiple rules",
+			spec: "write:errno=28;connect:delay=50ms,pids=1:2,container=foo",
+			want: []kernel.FaultInjectionRule{
+				{Syscall: "write", Errno: int(unix.ENOSPC)},
+				{Syscall: "connect", Delay: 50 * time.Millisecond, PIDs: []kernel.ThreadID{1, 2}, ContainerID: "foo"},
+			},
+		},
+		{
+			name:    "missing options",
+			spec:    "write",
+			wantErr: true,
+		},
+		{
+			name:    "unknown option",
+			spec:    "write:foo=bar",
+			wantErr: true,
+		},
+		{
+			name:    "unknown errno",
+			spec:    "write:errno=EFOO",
+			wantErr: true,
+		},
+		{
+			name:    "invalid pid",
+			spec:    "write:errno=EIO,pids=1:x",
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseFaultInjectionRules(tc.spec)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseFaultInjectionRules(%q) succeeded, want error", tc.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFaultInjectionRules(%q): %v", tc.spec, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("parseFaultInjectionRules(%q) mismatch (-want +got):\n%s", tc.spec, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/container"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// Fork implements subcommands.Command for the "fork" command.
+type Fork struct {
+	// imagePath is the directory where the image shared by the children is
+	// saved.
+	imagePath string
+
+	compression               CheckpointCompression
+	excludeCommittedZeroPages bool
+}
+
+// Name implements subcommands.Command.Name.
+func (*Fork) Name() string {
+	return "fork"
+}
+
+// Synopsis implements subcommands.Command.Synopsis.
+func (*Fork) Synopsis() string {
+	return "clone a running sandbox into new sandboxes (experimental)"
+}
+
+// Usage implements subcommands.Command.Usage.
+func (*Fork) Usage() string {
+	return `fork [flags] <container id> <child id>... - save the running sandbox
+of the given root container and restore one new sandbox per child ID from it.
+
+The original sandbox keeps running. Children share its bundle and spec, and
+resume from the state it was in when the command was run. Only sandboxes with a
+single container can be forked.
+`
+}
+
+// SetFlags implements subcommands.Command.SetFlags.
+func (fk *Fork) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&fk.imagePath, "image-path", "", "directory path to save the image that children are restored from")
+	f.Var(newCheckpointCompressionValue(statefile.CompressionLevelNone, &fk.compression), "compression", "compress image on disk. Values: none|flate-best-speed.")
+	f.BoolVar(&fk.excludeCommittedZeroPages, "exclude-committed-zero-pages", false, "exclude committed zero-filled pages from image")
+}
+
+// Execute implements subcommands.Command.Execute.
+func (fk *Fork) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if f.NArg() < 2 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+
+	id := f.Arg(0)
+	conf := args[0].(*config.Config)
+
+	if conf.Rootless {
+		return util.Errorf("Rootless mode not supported with %q", fk.Name())
+	}
+	if fk.imagePath == "" {
+		return util.Errorf("image-path flag must be provided")
+	}
+
+	cont, err := container.Load(conf.RootDir, container.FullID{ContainerID: id}, container.LoadOpts{})
+	if err != nil {
+		return util.Errorf("loading container: %v", err)
+	}
+	sfOpts := statefile.Options{
+		Compression: fk.compression.Level(),
+	}
+	mfOpts := pgalloc.SaveOpts{
+		ExcludeCommittedZeroPages: fk.excludeCommittedZeroPages,
+	}
+	if _, err := container.Fork(conf, cont, f.Args()[1:], fk.imagePath, sfOpts, mfOpts); err != nil {
+		return util.Errorf("forking container: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
@@ -30,6 +30,8 @@ import (
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/devices/drmproxy"
+	"gvisor.dev/gvisor/pkg/sentry/devices/sndproxy"
 	"gvisor.dev/gvisor/pkg/sentry/devices/tpuproxy"
 	"gvisor.dev/gvisor/pkg/unet"
 	"gvisor.dev/gvisor/runsc/boot"
@@ -91,6 +93,8 @@ type Gofer struct {
 
 	specFD        int
 	mountsFD      int
+	sharedCacheFD int
+	mountCtrlFD   int
 	profileFDs    profile.FDArgs
 	syncFDs       goferSyncFDs
 	stopProfiling func()
@@ -123,6 +127,8 @@ func (g *Gofer) SetFlags(f *flag.FlagSet) {
 	f.IntVar(&g.devIoFD, "dev-io-fd", -1, "optional FD to connect /dev gofer server")
 	f.IntVar(&g.specFD, "spec-fd", -1, "required fd with the container spec")
 	f.IntVar(&g.mountsFD, "mounts-fd", -1, "mountsFD is the file descriptor to write list of mounts after they have been resolved (direct paths, no symlinks).")
+	f.IntVar(&g.sharedCacheFD, "shared-page-cache-fd", -1, "optional FD of the shared page cache directory")
+	f.IntVar(&g.mountCtrlFD, "mount-control-fd", -1, "optional FD of a listening socket to receive mounts to serve at runtime")
 
 	// Add synchronization FD flags.
 	g.syncFDs.setFlags(f)
@@ -245,6 +251,8 @@ func (g *Gofer) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomm
 		UDSCreateEnabled: conf.GetHostUDS().AllowCreate(),
 		ProfileEnabled:   len(profileOpts) > 0,
 		DirectFS:         conf.DirectFS,
+		SharedPageCache:  g.sharedCacheFD >= 0,
+		MountControl:     g.mountCtrlFD >= 0,
 	}
 	if err := filter.Install(opts); err != nil {
 		util.Fatalf("installing seccomp filters: %v", err)
@@ -268,12 +276,18 @@ func (g *Gofer) serve(spec *specs.Spec, conf *config.Config, root string) subcom
 		readonly  bool
 	}
 	cfgs := make([]connectionConfig, 0, len(spec.Mounts)+1)
+	var sharedPageCache *fsgofer.SharedPageCache
+	if g.sharedCacheFD >= 0 {
+		sharedPageCache = fsgofer.NewSharedPageCache(g.sharedCacheFD)
+	}
 	server := fsgofer.NewLisafsServer(fsgofer.Config{
 		// These are global options. Ignore readonly configuration, that is set on
 		// a per connection basis.
 		HostUDS:            conf.GetHostUDS(),
 		HostFifo:           conf.HostFifo,
 		DonateMountPointFD: conf.DirectFS,
+		CacheReadonly:      conf.GoferCache,
+		SharedPageCache:    sharedPageCache,
 	})
 
 	ioFDs := g.ioFDs
@@ -336,6 +350,13 @@ func (g *Gofer) serve(spec *specs.Spec, conf *config.Config, root string) subcom
 		}
 		server.StartConnection(conn)
 	}
+	if g.mountCtrlFD >= 0 {
+		control, err := unet.NewServerSocket(g.mountCtrlFD)
+		if err != nil {
+			util.Fatalf("creating mount control socket on FD %d: %v", g.mountCtrlFD, err)
+		}
+		go server.ServeMountRequests(control)
+	}
 	server.Wait()
 	server.Destroy()
 	log.Infof("All lisafs servers exited.")
@@ -559,6 +580,21 @@ func (g *Gofer) setupDev(spec *specs.Spec, conf *config.Config, root, procPath s
 	if err := os.MkdirAll(filepath.Join(root, "dev"), 0777); err != nil {
 		return fmt.Errorf("creating dev directory: %v", err)
 	}
+	// Mount any host character devices that are passed through.
+	for _, dev := range conf.HostCharDevs {
+		var stat unix.Stat_t
+		if err := unix.Stat(dev.Path, &stat); err != nil {
+			return fmt.Errorf("stat host character device %q: %v", dev.Path, err)
+		}
+		if stat.Mode&unix.S_IFMT != unix.S_IFCHR {
+			return fmt.Errorf("host character device %q is not a character device", dev.Path)
+		}
+		dst := filepath.Join(root, dev.Path)
+		log.Infof("Mounting host character device %q as bind mount at %q", dev.Path, dst)
+		if err := specutils.SafeSetupAndMount(dev.Path, dst, "bind", unix.MS_BIND, procPath); err != nil {
+			return fmt.Errorf("mounting %q: %v", dev.Path, err)
+		}
+	}
 	// Mount any devices specified in the spec.
 	if spec.Linux == nil {
 		return nil
@@ -567,7 +603,9 @@ func (g *Gofer) setupDev(spec *specs.Spec, conf *config.Config, root, procPath s
 	tpuproxyEnabled := specutils.TPUProxyIsEnabled(spec, conf)
 	for _, dev := range spec.Linux.Devices {
 		shouldMount := (nvproxyEnabled && shouldExposeNvidiaDevice(dev.Path)) ||
-			(tpuproxyEnabled && shouldExposeTpuDevice(dev.Path))
+			(tpuproxyEnabled && shouldExposeTpuDevice(dev.Path)) ||
+			(conf.DRMProxy && drmproxy.IsSupportedDevice(dev.Path)) ||
+			(conf.SNDProxy && sndproxy.IsSupportedDevice(dev.Path))
 		if !shouldMount {
 			continue
 		}
@@ -0,0 +1,170 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/container"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// GPU implements subcommands.Command for the "gpu" command.
+type GPU struct{}
+
+// Name implements subcommands.Command.
+func (*GPU) Name() string {
+	return "gpu"
+}
+
+// Synopsis implements subcommands.Command.
+func (*GPU) Synopsis() string {
+	return "attach or detach NVIDIA GPUs in a running container"
+}
+
+// Usage implements subcommands.Command.
+func (*GPU) Usage() string {
+	buf := bytes.Buffer{}
+	buf.WriteString("Usage: gpu <flags> <subcommand> <subcommand args>\n\n")
+	buf.WriteString("The container must have been created with --nvproxy and --dynamic-mounts.\n\n")
+
+	cdr := createGPUCommander(&flag.FlagSet{})
+	cdr.VisitGroups(func(grp *subcommands.CommandGroup) {
+		cdr.ExplainGroup(&buf, grp)
+	})
+
+	return buf.String()
+}
+
+// SetFlags implements subcommands.Command.
+func (*GPU) SetFlags(*flag.FlagSet) {}
+
+// Execute implements subcommands.Command.
+func (*GPU) Execute(ctx context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	return createGPUCommander(f).Execute(ctx, args...)
+}
+
+func createGPUCommander(f *flag.FlagSet) *subcommands.Commander {
+	cdr := subcommands.NewCommander(f, "gpu")
+	cdr.Register(cdr.HelpCommand(), "")
+	cdr.Register(cdr.FlagsCommand(), "")
+	cdr.Register(new(gpuAttach), "")
+	cdr.Register(new(gpuDetach), "")
+	return cdr
+}
+
+// parseGPUArgs returns the container and GPU minor number from the command
+// line arguments.
+func parseGPUArgs(f *flag.FlagSet, conf *config.Config) (*container.Container, uint32, error) {
+	minor, err := strconv.ParseUint(f.Arg(1), 10, 32)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid GPU minor number %q: %v", f.Arg(1), err)
+	}
+	c, err := container.Load(conf.RootDir, container.FullID{ContainerID: f.Arg(0)}, container.LoadOpts{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("loading container: %v", err)
+	}
+	return c, uint32(minor), nil
+}
+
+// gpuAttach implements subcommands.Command for the "gpu attach" command.
+type gpuAttach struct{}
+
+// Name implements subcommands.Command.
+func (*gpuAttach) Name() string {
+	return "attach"
+}
+
+// Synopsis implements subcommands.Command.
+func (*gpuAttach) Synopsis() string {
+	return "attach a host NVIDIA GPU to a running container"
+}
+
+// Usage implements subcommands.Command.
+func (*gpuAttach) Usage() string {
+	return `attach <container id> <minor> - make the host GPU /dev/nvidia<minor>
+available in the container. Attached GPUs are not checkpointed, and must be
+attached again after restore.
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (*gpuAttach) SetFlags(*flag.FlagSet) {}
+
+// Execute implements subcommands.Command.
+func (*gpuAttach) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if f.NArg() != 2 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	conf := args[0].(*config.Config)
+
+	c, minor, err := parseGPUArgs(f, conf)
+	if err != nil {
+		return util.Errorf("%v", err)
+	}
+	if err := c.AttachGPU(conf, minor); err != nil {
+		return util.Errorf("attaching GPU: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
+
+// gpuDetach implements subcommands.Command for the "gpu detach" command.
+type gpuDetach struct{}
+
+// Name implements subcommands.Command.
+func (*gpuDetach) Name() string {
+	return "detach"
+}
+
+// Synopsis implements subcommands.Command.
+func (*gpuDetach) Synopsis() string {
+	return "detach a GPU attached with \"gpu attach\" from a running container"
+}
+
+// Usage implements subcommands.Command.
+func (*gpuDetach) Usage() string {
+	return `detach <container id> <minor> - remove /dev/nvidia<minor> from the
+container. Applications that have the device open keep using it until they
+close it.
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (*gpuDetach) SetFlags(*flag.FlagSet) {}
+
+// Execute implements subcommands.Command.
+func (*gpuDetach) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if f.NArg() != 2 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	conf := args[0].(*config.Config)
+
+	c, minor, err := parseGPUArgs(f, conf)
+	if err != nil {
+		return util.Errorf("%v", err)
+	}
+	if err := c.DetachGPU(minor); err != nil {
+		return util.Errorf("detaching GPU: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/container"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// Mount implements subcommands.Command for the "mount" command.
+type Mount struct{}
+
+// Name implements subcommands.Command.
+func (*Mount) Name() string {
+	return "mount"
+}
+
+// Synopsis implements subcommands.Command.
+func (*Mount) Synopsis() string {
+	return "add or remove bind mounts in a running container"
+}
+
+// Usage implements subcommands.Command.
+func (*Mount) Usage() string {
+	buf := bytes.Buffer{}
+	buf.WriteString("Usage: mount <flags> <subcommand> <subcommand args>\n\n")
+	buf.WriteString("The container must have been created with --dynamic-mounts.\n\n")
+
+	cdr := createMountCommander(&flag.FlagSet{})
+	cdr.VisitGroups(func(grp *subcommands.CommandGroup) {
+		cdr.ExplainGroup(&buf, grp)
+	})
+
+	return buf.String()
+}
+
+// SetFlags implements subcommands.Command.
+func (*Mount) SetFlags(*flag.FlagSet) {}
+
+// Execute implements subcommands.Command.
+func (*Mount) Execute(ctx context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	return createMountCommander(f).Execute(ctx, args...)
+}
+
+func createMountCommander(f *flag.FlagSet) *subcommands.Commander {
+	cdr := subcommands.NewCommander(f, "mount")
+	cdr.Register(cdr.HelpCommand(), "")
+	cdr.Register(cdr.FlagsCommand(), "")
+	cdr.Register(new(mountAdd), "")
+	cdr.Register(new(mountRemove), "")
+	return cdr
+}
+
+// mountAdd implements subcommands.Command for the "mount add" command.
+type mountAdd struct {
+	readonly bool
+}
+
+// Name implements subcommands.Command.
+func (*mountAdd) Name() string {
+	return "add"
+}
+
+// Synopsis implements subcommands.Command.
+func (*mountAdd) Synopsis() string {
+	return "bind mount a host file or directory in a running container"
+}
+
+// Usage implements subcommands.Command.
+func (*mountAdd) Usage() string {
+	return `add [flags] <container id> <source> <destination> - bind mount the host
+file or directory at source to destination in the container.
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (m *mountAdd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&m.readonly, "readonly", false, "mount read-only")
+}
+
+// Execute implements subcommands.Command.
+func (m *mountAdd) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if f.NArg() != 3 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	id, source, dest := f.Arg(0), f.Arg(1), f.Arg(2)
+	conf := args[0].(*config.Config)
+
+	c, err := container.Load(conf.RootDir, container.FullID{ContainerID: id}, container.LoadOpts{})
+	if err != nil {
+		return util.Errorf("loading container: %v", err)
+	}
+	if err := c.AddBindMount(source, dest, m.readonly); err != nil {
+		return util.Errorf("adding mount: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
+
+// mountRemove implements subcommands.Command for the "mount remove" command.
+type mountRemove struct{}
+
+// Name implements subcommands.Command.
+func (*mountRemove) Name() string {
+	return "remove"
+}
+
+// Synopsis implements subcommands.Command.
+func (*mountRemove) Synopsis() string {
+	return "remove a bind mount added with \"mount add\" from a running container"
+}
+
+// Usage implements subcommands.Command.
+func (*mountRemove) Usage() string {
+	return `remove <container id> <destination> - unmount the bind mount at
+destination in the container.
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (*mountRemove) SetFlags(*flag.FlagSet) {}
+
+// Execute implements subcommands.Command.
+func (*mountRemove) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if f.NArg() != 2 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	id, dest := f.Arg(0), f.Arg(1)
+	conf := args[0].(*config.Config)
+
+	c, err := container.Load(conf.RootDir, container.FullID{ContainerID: id}, container.LoadOpts{})
+	if err != nil {
+		return util.Errorf("loading container: %v", err)
+	}
+	if err := c.RemoveBindMount(dest); err != nil {
+		return util.Errorf("removing mount: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/container"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// ResizeMemory implements subcommands.Command for the "resize-memory" command.
+type ResizeMemory struct{}
+
+// Name implements subcommands.Command.Name.
+func (*ResizeMemory) Name() string {
+	return "resize-memory"
+}
+
+// Synopsis implements subcommands.Command.Synopsis.
+func (*ResizeMemory) Synopsis() string {
+	return "change the total memory of a running sandbox"
+}
+
+// Usage implements subcommands.Command.Usage.
+func (*ResizeMemory) Usage() string {
+	return `resize-memory <container-id> <bytes>
+
+Where "<container-id>" is the name for the instance of the container and
+"<bytes>" is the new total memory of the sandbox. The new total is reported
+by /proc/meminfo and sysinfo(2) inside the sandbox, and set as the memory
+limit of the root memory cgroup inside the sandbox and of the sandbox's host
+cgroup, if any. Shrinking below the current usage reclaims memory before
+returning.
+
+EXAMPLE:
+       # runsc resize-memory <container-id> 1073741824
+`
+}
+
+// SetFlags implements subcommands.Command.SetFlags.
+func (*ResizeMemory) SetFlags(*flag.FlagSet) {}
+
+// Execute implements subcommands.Command.Execute.
+func (*ResizeMemory) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if f.NArg() != 2 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+
+	id := f.Arg(0)
+	conf := args[0].(*config.Config)
+
+	bytes, err := strconv.ParseUint(f.Arg(1), 10, 64)
+	if err != nil {
+		util.Fatalf("invalid memory size %q: %v", f.Arg(1), err)
+	}
+
+	c, err := container.Load(conf.RootDir, container.FullID{ContainerID: id}, container.LoadOpts{SkipCheck: true})
+	if err != nil {
+		util.Fatalf("loading container: %v", err)
+	}
+
+	res, err := c.Sandbox.ResizeMemory(bytes)
+	if err != nil {
+		util.Fatalf("resize-memory failed: %v", err)
+	}
+	encoder := json.NewEncoder(&util.Writer{})
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(res); err != nil {
+		util.Fatalf("Encode ResizeMemoryResult failed: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
@@ -46,6 +46,10 @@ type Restore struct {
 	// network block device). Usually the restore is done only once, so the cost
 	// of adding the checkpoint files to the page cache can be redundant.
 	direct bool
+
+	// allContainers indicates that all containers recorded in the checkpoint
+	// manifest must be restored, re-creating the subcontainers of the sandbox.
+	allContainers bool
 }
 
 // Name implements subcommands.Command.Name.
@@ -67,9 +71,10 @@ func (*Restore) Usage() string {
 // SetFlags implements subcommands.Command.SetFlags.
 func (r *Restore) SetFlags(f *flag.FlagSet) {
 	r.Create.SetFlags(f)
-	f.StringVar(&r.imagePath, "image-path", "", "directory path to saved container image")
+	f.StringVar(&r.imagePath, "image-path", "", "directory path to saved container image, or URL of an image in object storage, e.g. s3://bucket/prefix or gs://bucket/prefix")
 	f.BoolVar(&r.detach, "detach", false, "detach from the container's process")
 	f.BoolVar(&r.direct, "direct", false, "use O_DIRECT for reading checkpoint pages file")
+	f.BoolVar(&r.allContainers, "all-containers", false, "restore all containers of the saved sandbox, re-creating subcontainers from the checkpoint manifest. The container ID must be that of the root container")
 
 	// Unimplemented flags necessary for compatibility with docker.
 
@@ -106,6 +111,21 @@ func (r *Restore) Execute(_ context.Context, f *flag.FlagSet, args ...any) subco
 	var cu cleanup.Cleanup
 	defer cu.Clean()
 
+	var manifest *container.CheckpointManifest
+	if r.allContainers {
+		var err error
+		if manifest, err = container.LoadCheckpointManifest(r.imagePath); err != nil {
+			return util.Errorf("loading checkpoint manifest: %v", err)
+		}
+		if r.bundleDir == "" {
+			root, err := manifest.Root()
+			if err != nil {
+				return util.Errorf("loading checkpoint manifest: %v", err)
+			}
+			bundleDir = root.BundleDir
+		}
+	}
+
 	runArgs := container.Args{
 		ID:            id,
 		Spec:          nil,
@@ -150,6 +170,18 @@ func (r *Restore) Execute(_ context.Context, f *flag.FlagSet, args ...any) subco
 		return util.Errorf("starting container: %v", err)
 	}
 
+	if manifest != nil {
+		subs, err := container.RestoreSubcontainers(conf, c, manifest, r.imagePath, r.direct)
+		if err != nil {
+			return util.Errorf("restoring subcontainers: %v", err)
+		}
+		for _, sub := range subs {
+			cu.Add(func() {
+				sub.Destroy()
+			})
+		}
+	}
+
 	// If we allocate a terminal, forward signals to the sandbox process.
 	// Otherwise, Ctrl+C will terminate this process and its children,
 	// including the terminal.
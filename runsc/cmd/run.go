@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	"github.com/google/subcommands"
@@ -42,6 +43,11 @@ type Run struct {
 
 	// execFD is the host file descriptor used for program execution.
 	execFD int
+
+	// zygoteImage is the path to a zygote image created by "runsc zygote". If
+	// set, the sandbox is restored from the zygote and the container process
+	// is executed in it, instead of booting a new sandbox.
+	zygoteImage string
 }
 
 // Name implements subcommands.Command.Name.
@@ -65,6 +71,7 @@ func (r *Run) SetFlags(f *flag.FlagSet) {
 	f.BoolVar(&r.detach, "detach", false, "detach from the container's process")
 	f.Var(&r.passFDs, "pass-fd", "file descriptor passed to the container in M:N format, where M is the host and N is the guest descriptor (can be supplied multiple times)")
 	f.IntVar(&r.execFD, "exec-fd", -1, "host file descriptor used for program execution")
+	f.StringVar(&r.zygoteImage, "zygote-image", "", "restore the sandbox from the zygote image at this path and execute the container process in it")
 	r.Create.SetFlags(f)
 }
 
@@ -140,6 +147,20 @@ func (r *Run) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomman
 		PassFiles:     fdMap,
 		ExecFile:      execFile,
 	}
+	if r.zygoteImage != "" {
+		if r.detach {
+			return util.Errorf("--detach is not supported with --zygote-image")
+		}
+		if len(fdMap) > 0 || execFile != nil {
+			return util.Errorf("--pass-fd and --exec-fd are not supported with --zygote-image")
+		}
+		ws, err := runFromZygote(conf, runArgs, r.zygoteImage)
+		if err != nil {
+			return util.Errorf("running container from zygote: %v", err)
+		}
+		*waitStatus = ws
+		return subcommands.ExitSuccess
+	}
 	ws, err := container.Run(conf, runArgs)
 	if err != nil {
 		return util.Errorf("running container: %v", err)
@@ -148,3 +169,36 @@ func (r *Run) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcomman
 	*waitStatus = ws
 	return subcommands.ExitSuccess
 }
+
+// runFromZygote restores a sandbox from the zygote at imagePath, executes the
+// process from the container spec in it and waits for the process to exit.
+// Process configuration, such as arguments, environment, user and limits, is
+// taken from the container spec, while the sandbox itself comes from the
+// zygote.
+func runFromZygote(conf *config.Config, args container.Args, imagePath string) (unix.WaitStatus, error) {
+	e, err := argsFromProcess(args.Spec.Process, conf.EnableRaw)
+	if err != nil {
+		return 0, err
+	}
+	if e.Capabilities == nil {
+		if e.Capabilities, err = specutils.Capabilities(conf.EnableRaw, nil); err != nil {
+			return 0, fmt.Errorf("creating capabilities: %v", err)
+		}
+	}
+
+	c, err := container.NewFromZygote(conf, args, imagePath, false /* direct */)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Destroy()
+
+	pid, err := c.Execute(conf, e)
+	if err != nil {
+		return 0, fmt.Errorf("executing container process: %v", err)
+	}
+	if e.StdioIsPty {
+		stopForwarding := c.ForwardSignals(pid, true /* fgProcess */)
+		defer stopForwarding()
+	}
+	return c.WaitPID(pid)
+}
@@ -17,6 +17,7 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 
 	"github.com/google/subcommands"
@@ -42,14 +43,29 @@ func (*State) Synopsis() string {
 
 // Usage implements subcommands.Command.Usage.
 func (*State) Usage() string {
-	return `state [flags] <container id> - get the state of a container`
+	return `state [flags] <container id> - get the state of a container
+state inspect [flags] <image path or statefile> - print a size breakdown of a checkpoint
+`
 }
 
 // SetFlags implements subcommands.Command.SetFlags.
 func (*State) SetFlags(*flag.FlagSet) {}
 
 // Execute implements subcommands.Command.Execute.
-func (*State) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+func (*State) Execute(ctx context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	// "inspect" is only a subcommand when followed by its arguments, so
+	// that the state of a container named "inspect" can still be queried.
+	if f.NArg() > 1 && f.Arg(0) == "inspect" {
+		var inspect StateInspect
+		fs := flag.NewFlagSet(inspect.Name(), flag.ContinueOnError)
+		fs.Usage = func() { fmt.Fprint(os.Stderr, inspect.Usage()) }
+		inspect.SetFlags(fs)
+		if err := fs.Parse(f.Args()[1:]); err != nil {
+			return subcommands.ExitUsageError
+		}
+		return inspect.Execute(ctx, fs, args...)
+	}
+
 	if f.NArg() != 1 {
 		f.Usage()
 		return subcommands.ExitUsageError
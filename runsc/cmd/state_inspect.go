@@ -0,0 +1,217 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/pkg/state/inspect"
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/runsc/boot"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/flag"
+	"gvisor.dev/gvisor/runsc/imagestore"
+)
+
+// StateInspect implements subcommands.Command for the "state inspect"
+// command.
+type StateInspect struct {
+	key   string
+	depth int
+	top   int
+	diff  string
+	json  bool
+}
+
+// Name implements subcommands.Command.Name.
+func (*StateInspect) Name() string {
+	return "inspect"
+}
+
+// Synopsis implements subcommands.Command.Synopsis.
+func (*StateInspect) Synopsis() string {
+	return "print a size breakdown of a checkpoint, or compare two checkpoints"
+}
+
+// Usage implements subcommands.Command.Usage.
+func (*StateInspect) Usage() string {
+	return `state inspect [flags] <image path, image URL or statefile> - print a size breakdown of a checkpoint.
+`
+}
+
+// SetFlags implements subcommands.Command.SetFlags.
+func (s *StateInspect) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&s.key, "key", "", "the integrity key for the statefiles.")
+	f.IntVar(&s.depth, "depth", 3, "number of package path components to group objects by, e.g. 3 for pkg/sentry/fsimpl. 0 means full package paths.")
+	f.IntVar(&s.top, "top", 20, "number of types to print. 0 means all types.")
+	f.StringVar(&s.diff, "diff", "", "image path, image URL or statefile of an older checkpoint to compare against.")
+	f.BoolVar(&s.json, "json", false, "outputs in JSON format.")
+}
+
+// Execute implements subcommands.Command.Execute.
+func (s *StateInspect) Execute(_ context.Context, f *flag.FlagSet, _ ...any) subcommands.ExitStatus {
+	if f.NArg() != 1 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+
+	summary, err := s.summarize(f.Arg(0))
+	if err != nil {
+		util.Fatalf("%v", err)
+	}
+	if s.diff == "" {
+		if err := s.printSummary(os.Stdout, summary); err != nil {
+			util.Fatalf("error printing summary: %v", err)
+		}
+		return subcommands.ExitSuccess
+	}
+
+	old, err := s.summarize(s.diff)
+	if err != nil {
+		util.Fatalf("%v", err)
+	}
+	if err := s.printDiff(os.Stdout, old, summary); err != nil {
+		util.Fatalf("error printing diff: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
+
+// summarize summarizes the statefile at path, or in the image at path.
+func (s *StateInspect) summarize(path string) (*inspect.Summary, error) {
+	f, err := openStatefile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening statefile: %w", err)
+	}
+	defer f.Close()
+
+	var key []byte
+	if s.key != "" {
+		key = []byte(s.key)
+	}
+	r, _, err := statefile.NewReader(bufio.NewReader(f), key)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing statefile %q: %w", path, err)
+	}
+	summary, err := inspect.Summarize(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading statefile %q: %w", path, err)
+	}
+	return summary, nil
+}
+
+// openStatefile opens the statefile at path, or in the image at path, which
+// may be the URL of an image in object storage.
+func openStatefile(path string) (io.ReadCloser, error) {
+	if imagestore.IsRemote(path) {
+		store, err := imagestore.New(path)
+		if err != nil {
+			return nil, err
+		}
+		return store.Open(context.Background(), boot.CheckpointStateFileName)
+	}
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		path = filepath.Join(path, boot.CheckpointStateFileName)
+	}
+	return os.Open(path)
+}
+
+// limit returns the first s.top entries.
+func (s *StateInspect) limit(entries []inspect.Entry) []inspect.Entry {
+	if s.top > 0 && len(entries) > s.top {
+		return entries[:s.top]
+	}
+	return entries
+}
+
+// packageName returns the printed name of pkg.
+func packageName(pkg string) string {
+	if pkg == "" {
+		return "(unknown)"
+	}
+	return pkg
+}
+
+func (s *StateInspect) printSummary(w io.Writer, summary *inspect.Summary) error {
+	packages := inspect.Sorted(summary.ByPackage(s.depth))
+	types := s.limit(inspect.Sorted(summary.Types))
+	if s.json {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			*inspect.Summary
+			Types    []inspect.Entry `json:"types"`
+			Packages []inspect.Entry `json:"packages"`
+		}{summary, types, packages})
+	}
+
+	fmt.Fprintf(w, "Total: %d bytes\n", summary.TotalBytes)
+	fmt.Fprintf(w, "Objects: %d in %d graphs, %d bytes\n", summary.Objects.Count, summary.Graphs, summary.Objects.Bytes)
+	fmt.Fprintf(w, "Types: %d bytes\n", summary.TypeBytes)
+	fmt.Fprintf(w, "Non-object data: %d bytes\n", summary.RawBytes)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "\nBYTES\tCOUNT\t\tPACKAGE\n")
+	for _, e := range packages {
+		fmt.Fprintf(tw, "%d\t%d\t\t%s\n", e.Bytes, e.Count, packageName(e.Name))
+	}
+	fmt.Fprintf(tw, "\nBYTES\tCOUNT\t\tTYPE\n")
+	for _, e := range types {
+		fmt.Fprintf(tw, "%d\t%d\t\t%s\n", e.Bytes, e.Count, e.Name)
+	}
+	return tw.Flush()
+}
+
+func (s *StateInspect) printDiff(w io.Writer, old, summary *inspect.Summary) error {
+	packages := inspect.Diff(old.ByPackage(s.depth), summary.ByPackage(s.depth))
+	types := inspect.Diff(old.Types, summary.Types)
+	if s.top > 0 && len(types) > s.top {
+		types = types[:s.top]
+	}
+	if s.json {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Old      *inspect.Summary `json:"old"`
+			New      *inspect.Summary `json:"new"`
+			Types    []inspect.Delta  `json:"types"`
+			Packages []inspect.Delta  `json:"packages"`
+		}{old, summary, types, packages})
+	}
+
+	fmt.Fprintf(w, "Total: %d -> %d bytes (%+d)\n", old.TotalBytes, summary.TotalBytes, int64(summary.TotalBytes)-int64(old.TotalBytes))
+	fmt.Fprintf(w, "Objects: %d -> %d (%+d), %d -> %d bytes (%+d)\n",
+		old.Objects.Count, summary.Objects.Count, int64(summary.Objects.Count)-int64(old.Objects.Count),
+		old.Objects.Bytes, summary.Objects.Bytes, int64(summary.Objects.Bytes)-int64(old.Objects.Bytes))
+	fmt.Fprintf(w, "Non-object data: %d -> %d bytes (%+d)\n", old.RawBytes, summary.RawBytes, int64(summary.RawBytes)-int64(old.RawBytes))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "\nOLD BYTES\tNEW BYTES\tDELTA\tCOUNT DELTA\t\tPACKAGE\n")
+	for _, d := range packages {
+		fmt.Fprintf(tw, "%d\t%d\t%+d\t%+d\t\t%s\n", d.Old.Bytes, d.New.Bytes, d.Bytes(), d.Count(), packageName(d.Name))
+	}
+	fmt.Fprintf(tw, "\nOLD BYTES\tNEW BYTES\tDELTA\tCOUNT DELTA\t\tTYPE\n")
+	for _, d := range types {
+		fmt.Fprintf(tw, "%d\t%d\t%+d\t%+d\t\t%s\n", d.Old.Bytes, d.New.Bytes, d.Bytes(), d.Count(), d.Name)
+	}
+	return tw.Flush()
+}
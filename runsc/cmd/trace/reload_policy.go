@@ -0,0 +1,84 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/container"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// reloadPolicy implements subcommands.Command for the "reload-policy" command.
+type reloadPolicy struct {
+	rulesFile string
+}
+
+// Name implements subcommands.Command.
+func (*reloadPolicy) Name() string {
+	return "reload-policy"
+}
+
+// Synopsis implements subcommands.Command.
+func (*reloadPolicy) Synopsis() string {
+	return "replace the rules of policy sinks"
+}
+
+// Usage implements subcommands.Command.
+func (*reloadPolicy) Usage() string {
+	return `reload-policy [flags] <sandbox id> - replace the rules of policy sinks in
+all trace sessions. If the new rules are invalid, the current rules are kept.
+`
+}
+
+// SetFlags implements subcommands.Command.
+func (l *reloadPolicy) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&l.rulesFile, "rules", "", "path to the rules file")
+}
+
+// Execute implements subcommands.Command.
+func (l *reloadPolicy) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if f.NArg() != 1 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	if len(l.rulesFile) == 0 {
+		f.Usage()
+		return util.Errorf("missing rules file, please set --rules")
+	}
+
+	id := f.Arg(0)
+	conf := args[0].(*config.Config)
+
+	opts := container.LoadOpts{
+		SkipCheck:     true,
+		RootContainer: true,
+	}
+	c, err := container.Load(conf.RootDir, container.FullID{ContainerID: id}, opts)
+	if err != nil {
+		util.Fatalf("loading sandbox: %v", err)
+	}
+
+	if err := c.Sandbox.ReloadPolicy(l.rulesFile); err != nil {
+		util.Fatalf("reloading policy: %v", err)
+	}
+
+	fmt.Printf("Policy reloaded from %q.\n", l.rulesFile)
+	return subcommands.ExitSuccess
+}
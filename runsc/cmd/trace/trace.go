@@ -68,5 +68,6 @@ func createCommander(f *flag.FlagSet) *subcommands.Commander {
 	cdr.Register(new(list), "")
 	cdr.Register(new(metadata), "")
 	cdr.Register(new(procfs), "")
+	cdr.Register(new(reloadPolicy), "")
 	return cdr
 }
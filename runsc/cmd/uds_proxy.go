@@ -0,0 +1,101 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/subcommands"
+	"github.com/syndtr/gocapability/capability"
+	"gvisor.dev/gvisor/pkg/udsproxy"
+	"gvisor.dev/gvisor/pkg/udsproxy/filter"
+	"gvisor.dev/gvisor/pkg/unet"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/flag"
+)
+
+// UDSProxy implements subcommands.Command for the "uds-proxy" command.
+type UDSProxy struct {
+	socketFD int
+}
+
+// Name implements subcommands.Command.Name.
+func (*UDSProxy) Name() string {
+	return "uds-proxy"
+}
+
+// Synopsis implements subcommands.Command.Synopsis.
+func (*UDSProxy) Synopsis() string {
+	return "connect to host abstract Unix-domain sockets on behalf of the sandbox"
+}
+
+// Usage implements subcommands.Command.Usage.
+func (*UDSProxy) Usage() string {
+	return `uds-proxy --socket-fd=FD`
+}
+
+// SetFlags implements subcommands.Command.SetFlags.
+func (u *UDSProxy) SetFlags(f *flag.FlagSet) {
+	f.IntVar(&u.socketFD, "socket-fd", -1, "required FD of a SOCK_SEQPACKET socket connected to the sandbox")
+}
+
+// Execute implements subcommands.Command.Execute.
+func (u *UDSProxy) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if f.NArg() != 0 || u.socketFD < 0 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	conf := args[0].(*config.Config)
+
+	sock, err := unet.NewSocket(u.socketFD)
+	if err != nil {
+		util.Fatalf("creating socket: %v", err)
+	}
+	defer sock.Close()
+
+	// The proxy is started as an unprivileged user (see
+	// sandbox.startHostAbstractUDSProxy). Refuse to serve requests if it has
+	// any capabilities, e.g. because it could not change user.
+	if err := checkNoCapabilities(); err != nil {
+		util.Fatalf("%v", err)
+	}
+	if err := filter.Install(); err != nil {
+		util.Fatalf("installing seccomp filters: %v", err)
+	}
+	if err := udsproxy.Serve(sock, conf.HostAbstractUDSNames()); err != nil {
+		util.Fatalf("serving requests: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
+
+// checkNoCapabilities returns an error if the current process has any
+// effective, permitted or ambient capabilities.
+func checkNoCapabilities() error {
+	caps, err := capability.NewPid2(0)
+	if err != nil {
+		return err
+	}
+	if err := caps.Load(); err != nil {
+		return err
+	}
+	for _, which := range []capability.CapType{capability.EFFECTIVE, capability.PERMITTED, capability.AMBIENT} {
+		if !caps.Empty(which) {
+			return fmt.Errorf("uds-proxy must run without capabilities, got: %v", caps)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/google/subcommands"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/runsc/cmd/util"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/container"
+	"gvisor.dev/gvisor/runsc/flag"
+	"gvisor.dev/gvisor/runsc/specutils"
+)
+
+// Zygote implements subcommands.Command for the "zygote" command.
+type Zygote struct {
+	// bundleDir is the path to the bundle directory (defaults to the
+	// current working directory).
+	bundleDir string
+
+	// imagePath is the directory where the zygote image is saved.
+	imagePath string
+
+	compression               CheckpointCompression
+	excludeCommittedZeroPages bool
+}
+
+// Name implements subcommands.Command.Name.
+func (*Zygote) Name() string {
+	return "zygote"
+}
+
+// Synopsis implements subcommands.Command.Synopsis.
+func (*Zygote) Synopsis() string {
+	return "create a pre-initialized sandbox image for fast startup (experimental)"
+}
+
+// Usage implements subcommands.Command.Usage.
+func (*Zygote) Usage() string {
+	return `zygote [flags] <container id> - boot a sandbox from the bundle, save it
+right after its init process started and destroy it.
+
+The init process of the bundle must run until it is killed (e.g. "sleep
+infinity"). Containers started with "runsc run --zygote-image" restore the
+saved sandbox instead of booting a new one, and then execute their own process
+in it. They must use the same root filesystem and mounts as the zygote.
+`
+}
+
+// SetFlags implements subcommands.Command.SetFlags.
+func (z *Zygote) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&z.bundleDir, "bundle", "", "path to the root of the bundle directory, defaults to the current directory")
+	f.StringVar(&z.imagePath, "image-path", "", "directory path to save the zygote image")
+	f.Var(newCheckpointCompressionValue(statefile.CompressionLevelNone, &z.compression), "compression", "compress zygote image on disk. Values: none|flate-best-speed.")
+	f.BoolVar(&z.excludeCommittedZeroPages, "exclude-committed-zero-pages", false, "exclude committed zero-filled pages from zygote image")
+}
+
+// Execute implements subcommands.Command.Execute.
+func (z *Zygote) Execute(_ context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if f.NArg() != 1 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+
+	id := f.Arg(0)
+	conf := args[0].(*config.Config)
+
+	if conf.Rootless {
+		return util.Errorf("Rootless mode not supported with %q", z.Name())
+	}
+	if z.imagePath == "" {
+		return util.Errorf("image-path flag must be provided")
+	}
+
+	bundleDir := z.bundleDir
+	if bundleDir == "" {
+		bundleDir = getwdOrDie()
+	}
+	spec, err := specutils.ReadSpec(bundleDir, conf)
+	if err != nil {
+		return util.Errorf("reading spec: %v", err)
+	}
+	specutils.LogSpecDebug(spec, conf.OCISeccomp)
+
+	zygoteArgs := container.Args{
+		ID:        id,
+		Spec:      spec,
+		BundleDir: bundleDir,
+	}
+	sfOpts := statefile.Options{
+		Compression: z.compression.Level(),
+	}
+	mfOpts := pgalloc.SaveOpts{
+		ExcludeCommittedZeroPages: z.excludeCommittedZeroPages,
+	}
+	if err := container.CreateZygote(conf, zygoteArgs, z.imagePath, sfOpts, mfOpts); err != nil {
+		return util.Errorf("creating zygote: %v", err)
+	}
+	return subcommands.ExitSuccess
+}
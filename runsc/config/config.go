@@ -22,8 +22,10 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"gvisor.dev/gvisor/pkg/log"
 	"gvisor.dev/gvisor/pkg/refs"
@@ -104,6 +106,31 @@ type Config struct {
 	// DO NOT call it directly, use GetHostUDS() instead.
 	HostUDS HostUDS `flag:"host-uds"`
 
+	// HostUDSRights controls which types of FDs may be received as
+	// SCM_RIGHTS from host Unix-domain sockets.
+	HostUDSRights HostUDSRights `flag:"host-uds-rights"`
+
+	// HostUDSUIDMap and HostUDSGIDMap translate the user and group IDs of
+	// peers of host Unix-domain sockets, as reported by SO_PEERCRED and
+	// SCM_CREDENTIALS.
+	HostUDSUIDMap HostUDSIDMap `flag:"host-uds-uid-map"`
+	HostUDSGIDMap HostUDSIDMap `flag:"host-uds-gid-map"`
+
+	// HostMemfd causes memfd_create(2) to create memfds on the host, so that
+	// they can be passed to host processes over host Unix-domain sockets.
+	HostMemfd bool `flag:"host-memfd"`
+
+	// HostAbstractUDS is a comma-separated list of names of abstract
+	// Unix-domain sockets in the host network namespace that the sandbox may
+	// connect to. A name ending in "*" matches all names with that prefix.
+	HostAbstractUDS string `flag:"host-abstract-uds"`
+
+	// HostAbstractUDSUID and HostAbstractUDSGID are the user and group IDs
+	// that the host abstract UDS proxy runs as. Host services see them as the
+	// peer credentials of connections from the sandbox.
+	HostAbstractUDSUID int `flag:"host-abstract-uds-uid"`
+	HostAbstractUDSGID int `flag:"host-abstract-uds-gid"`
+
 	// HostFifo controls permission to access host FIFO (or named pipes).
 	HostFifo HostFifo `flag:"host-fifo"`
 
@@ -207,6 +234,73 @@ type Config struct {
 	// WatchdogAction sets what action the watchdog takes when triggered.
 	WatchdogAction watchdog.Action `flag:"watchdog-action"`
 
+	// WatchdogStallTimeouts is a comma-separated list of <subsystem>=<timeout>
+	// pairs enabling stall detection for the given subsystems, e.g.
+	// "gofer=30s,netstack=10s,reclaim=1m".
+	WatchdogStallTimeouts string `flag:"watchdog-stall-timeouts"`
+
+	// WatchdogStallActions is a comma-separated list of actions the watchdog
+	// takes when a stalled subsystem is detected: log, event, checkpoint.
+	WatchdogStallActions string `flag:"watchdog-stall-actions"`
+
+	// WatchdogStallCheckpoint is the file path a statefile snapshot is
+	// written to by the checkpoint stall action.
+	WatchdogStallCheckpoint string `flag:"watchdog-stall-checkpoint"`
+
+	// RestoreHostFDs is a comma-separated list of [CONTAINER/]FD=SOURCE rules
+	// re-establishing, on restore, host FDs that were imported into
+	// checkpointed containers as application FD FD. SOURCE is either a host
+	// path, which is reopened with the saved file status flags, or a
+	// "unix:PATH", "tcp:HOST:PORT" or "udp:HOST:PORT" address to connect to.
+	RestoreHostFDs string `flag:"restore-host-fds"`
+
+	// RestoreFDResolver is the path of a Unix socket of a service resolving,
+	// on restore, host FDs of checkpointed containers that are not covered
+	// by RestoreHostFDs. See boot.FDResolverResolve.
+	RestoreFDResolver string `flag:"restore-fd-resolver"`
+
+	// StateIOUring makes the sandbox use io_uring, if available on the host,
+	// to write and read checkpoint pages files.
+	StateIOUring bool `flag:"state-io-uring"`
+
+	// OOMKiller enables the in-sandbox OOM killer, which kills the process
+	// with the highest /proc/[pid]/oom_score when the sandbox exceeds its
+	// total memory, instead of letting the host kill the entire sandbox.
+	OOMKiller bool `flag:"oom-killer"`
+
+	// SwapDir is the directory in which a file backing the swap tier of
+	// sandbox memory is created. Empty disables swapping.
+	SwapDir string `flag:"swap-dir"`
+
+	// SwapWatermark is the percentage of the sandbox total memory above which
+	// cold memory is migrated to the swap tier.
+	SwapWatermark int `flag:"swap-watermark"`
+
+	// SwapAge is the number of consecutive swap aging passes during which
+	// memory must not be used to be migrated to the swap tier.
+	SwapAge int `flag:"swap-age"`
+
+	// SwapCompressedPercent is the maximum percentage of the sandbox total
+	// memory used by a compressed in-memory cache tier, into which cold
+	// memory is compressed before being migrated to the swap tier. 0 disables
+	// the compressed cache tier.
+	SwapCompressedPercent int `flag:"swap-compressed-percent"`
+
+	// SwapSizeMB is the size in MiB of the virtual swap device reported to
+	// applications in /proc/swaps, /proc/meminfo and sysinfo(2), whose usage
+	// reflects memory in the swap and compressed cache tiers. 0 reports no
+	// swap.
+	SwapSizeMB int `flag:"swap-size-mb"`
+
+	// MemoryFileHugePages controls whether the sandbox memory file is backed
+	// by host huge pages.
+	MemoryFileHugePages MemoryFileHugePages `flag:"memory-file-hugepages"`
+
+	// MemoryCompaction enables periodic compaction of sandbox memory, which
+	// migrates application memory out of sparsely used huge pages so that free
+	// memory can be returned to the host in larger ranges.
+	MemoryCompaction bool `flag:"memory-compaction"`
+
 	// PanicSignal registers signal handling that panics. Usually set to
 	// SIGUSR2(12) to troubleshoot hangs. -1 disables it.
 	PanicSignal int `flag:"panic-signal"`
@@ -245,6 +339,12 @@ type Config struct {
 	// evenly among each network channel.
 	NetworkProcessorsPerChannel int `flag:"network-processors-per-channel"`
 
+	// NetworkProcessorQueueLen is the maximum number of inbound packets
+	// queued for each network processor goroutine. Flows are steered to
+	// processors by hash, so packets that arrive while their processor's
+	// queue is full are dropped. If this is 0, queues are unbounded.
+	NetworkProcessorQueueLen int `flag:"network-processor-queue-len"`
+
 	// Rootless allows the sandbox to be started with a user that is not root.
 	// Defense in depth measures are weaker in rootless mode. Specifically, the
 	// sandbox and Gofer process run as root inside a user namespace with root
@@ -264,6 +364,46 @@ type Config struct {
 	// E.g. 0.2 CPU quota will result in 1, and 1.9 in 2.
 	CPUNumFromQuota bool `flag:"cpu-num-from-quota"`
 
+	// LimitsFromCgroup makes the total memory and number of CPUs reported to
+	// the application, e.g. in sysinfo(2), /proc/meminfo and /proc/cpuinfo,
+	// reflect the container's cgroup memory limit and CPU quota.
+	LimitsFromCgroup bool `flag:"limits-from-cgroup"`
+
+	// CPUTopology configures the CPU topology presented to the sandbox, in
+	// the format accepted by cpuid.ParseTopology. If empty, all CPUs are
+	// presented as cores of a single socket.
+	CPUTopology string `flag:"cpu-topology"`
+
+	// CPUFeatureMask is a comma-separated list of CPU features, named as in
+	// /proc/cpuinfo, that are hidden from the sandbox, both in CPUID and in
+	// the ELF auxiliary vector.
+	CPUFeatureMask string `flag:"cpu-feature-mask"`
+
+	// ClockRealtimeOffset is added to CLOCK_REALTIME in the sandbox.
+	ClockRealtimeOffset time.Duration `flag:"clock-realtime-offset"`
+
+	// ClockMonotonicOffset is added to CLOCK_MONOTONIC in the sandbox.
+	ClockMonotonicOffset time.Duration `flag:"clock-monotonic-offset"`
+
+	// ClockScale is the rate at which time elapses in the sandbox, relative
+	// to the host.
+	ClockScale float64 `flag:"clock-scale"`
+
+	// Deterministic enables deterministic execution of the sandbox, for
+	// reproducing flaky tests and record/replay debugging. Time starts at a
+	// fixed point and advances logically, random bytes are generated from
+	// DeterministicSeed, and a single virtual CPU runs application code of
+	// one task at a time.
+	Deterministic bool `flag:"deterministic"`
+
+	// DeterministicSeed is the seed of random bytes in deterministic mode.
+	DeterministicSeed uint64 `flag:"deterministic-seed"`
+
+	// LazyFork defers copying private memory of a forked process until the
+	// child accesses it, which speeds up fork+exec-heavy workloads such as
+	// shells and build systems.
+	LazyFork bool `flag:"lazy-fork"`
+
 	// Allows overriding of flags in OCI annotations.
 	AllowFlagOverride bool `flag:"allow-flag-override"`
 
@@ -308,6 +448,27 @@ type Config struct {
 	// exists, but is mostly idle. Not supported in rootless mode.
 	DirectFS bool `flag:"directfs"`
 
+	// GoferCache enables caching of file attributes, symlink targets and
+	// negative lookups of read-only mounts in the gofer. It has no effect on
+	// mounts accessed with directfs.
+	GoferCache bool `flag:"gofer-cache"`
+
+	// SharedPageCache is the path to a host directory, shared by multiple
+	// sandboxes, in which the gofer stores copies of files opened read-only
+	// on read-only mounts, such that sandboxes mapping the same files share
+	// memory. It has no effect on mounts accessed with directfs.
+	SharedPageCache string `flag:"shared-page-cache"`
+
+	// DynamicMounts allows bind mounts to be added to and removed from running
+	// containers with "runsc mount", and GPUs to be attached with "runsc gpu".
+	// Each gofer listens on a control socket for mounts to serve.
+	DynamicMounts bool `flag:"dynamic-mounts"`
+
+	// NestedContainers delegates the container's cgroup subtree to the
+	// container's user, so that container runtimes such as runc can run
+	// inside the sandbox. See g3doc/user_guide/nested_containers.md.
+	NestedContainers bool `flag:"nested-containers"`
+
 	// NVProxy enables support for Nvidia GPUs.
 	NVProxy bool `flag:"nvproxy"`
 
@@ -325,6 +486,17 @@ type Config struct {
 	// TPUProxy enables support for TPUs.
 	TPUProxy bool `flag:"tpuproxy"`
 
+	// DRMProxy enables support for DRM render nodes.
+	DRMProxy bool `flag:"drmproxy"`
+
+	// SNDProxy enables support for ALSA sound devices.
+	SNDProxy bool `flag:"sndproxy"`
+
+	// HostCharDevs is the list of host character devices that are passed
+	// through to the sandbox, along with the ioctls that may be forwarded to
+	// each of them.
+	HostCharDevs HostCharDevs `flag:"host-chardev"`
+
 	// TestOnlyAllowRunAsCurrentUserWithoutChroot should only be used in
 	// tests. It allows runsc to start the sandbox process as the current
 	// user, and without chrooting the sandbox process. This can be
@@ -361,6 +533,11 @@ type Config struct {
 	// disconnected upon save.
 	NetDisconnectOk bool `flag:"net-disconnect-ok"`
 
+	// DNSProxyUpstreams is a comma-separated list of DNS servers. If set, a
+	// caching DNS proxy forwarding to these servers listens on 127.0.0.53
+	// inside the sandbox. Requires network=sandbox.
+	DNSProxyUpstreams string `flag:"dns-proxy-upstreams"`
+
 	// TestOnlyAutosaveImagePath if not empty enables auto save for syscall tests
 	// and stores the directory path to the saved state file.
 	TestOnlyAutosaveImagePath string `flag:"TESTONLY-autosave-image-path"`
@@ -377,9 +554,21 @@ func (c *Config) validate() error {
 	if overlay2 := c.GetOverlay2(); c.FileAccess == FileAccessShared && overlay2.Enabled() {
 		return fmt.Errorf("overlay flag is incompatible with shared file access for rootfs")
 	}
+	if c.ClockMonotonicOffset < 0 {
+		return fmt.Errorf("clock-monotonic-offset must not be negative, got: %v", c.ClockMonotonicOffset)
+	}
+	if c.ClockScale <= 0 {
+		return fmt.Errorf("clock-scale must be > 0, got: %v", c.ClockScale)
+	}
 	if c.NumNetworkChannels <= 0 {
 		return fmt.Errorf("num_network_channels must be > 0, got: %d", c.NumNetworkChannels)
 	}
+	if c.NetworkProcessorQueueLen < 0 {
+		return fmt.Errorf("network_processor_queue_len must be >= 0, got: %d", c.NetworkProcessorQueueLen)
+	}
+	if len(c.DNSProxyUpstreamList()) > 0 && c.Network != NetworkSandbox {
+		return fmt.Errorf("dns-proxy-upstreams requires network=sandbox, got network=%v", c.Network)
+	}
 	// Require profile flags to explicitly opt-in to profiling with
 	// -profile rather than implying it since these options have security
 	// implications.
@@ -395,6 +584,10 @@ func (c *Config) validate() error {
 	if c.ProfileMutex != "" && !c.ProfileEnable {
 		return fmt.Errorf("profile-mutex flag requires enabling profiling with profile flag")
 	}
+	if c.HostAbstractUDSUID <= 0 || c.HostAbstractUDSGID < 0 {
+		// The proxy must not run as root, which would give it capabilities.
+		return fmt.Errorf("host-abstract-uds-uid must be > 0 and host-abstract-uds-gid must be >= 0, got: %d and %d", c.HostAbstractUDSUID, c.HostAbstractUDSGID)
+	}
 	if c.FSGoferHostUDS && c.HostUDS != HostUDSNone {
 		// Deprecated flag was used together with flag that replaced it.
 		return fmt.Errorf("fsgofer-host-uds has been replaced with host-uds flag")
@@ -402,6 +595,33 @@ func (c *Config) validate() error {
 	if len(c.ProfilingMetrics) > 0 && len(c.ProfilingMetricsLog) == 0 {
 		return fmt.Errorf("profiling-metrics flag requires defining a profiling-metrics-log for output")
 	}
+	if _, err := watchdog.ParseStallTimeouts(c.WatchdogStallTimeouts); err != nil {
+		return err
+	}
+	stallActions, err := watchdog.ParseStallActions(c.WatchdogStallActions)
+	if err != nil {
+		return err
+	}
+	if stallActions&watchdog.StallCheckpoint != 0 && c.WatchdogStallCheckpoint == "" {
+		return fmt.Errorf("watchdog-stall-actions=checkpoint requires defining a watchdog-stall-checkpoint file")
+	}
+	if c.SwapCompressedPercent < 0 || c.SwapCompressedPercent > 100 {
+		return fmt.Errorf("swap-compressed-percent must be in [0, 100], got: %d", c.SwapCompressedPercent)
+	}
+	if c.SwapSizeMB < 0 {
+		return fmt.Errorf("swap-size-mb must be >= 0, got: %d", c.SwapSizeMB)
+	}
+	if c.SwapSizeMB != 0 && c.SwapDir == "" && c.SwapCompressedPercent == 0 {
+		return fmt.Errorf("swap-size-mb requires swap-dir or swap-compressed-percent")
+	}
+	if c.SwapDir != "" || c.SwapCompressedPercent != 0 {
+		if c.SwapWatermark <= 0 || c.SwapWatermark > 100 {
+			return fmt.Errorf("swap-watermark must be in (0, 100], got: %d", c.SwapWatermark)
+		}
+		if c.SwapAge < 0 {
+			return fmt.Errorf("swap-age must be >= 0, got: %d", c.SwapAge)
+		}
+	}
 	return nil
 }
 
@@ -451,6 +671,28 @@ func (c *Config) GetHostUDS() HostUDS {
 	return c.HostUDS
 }
 
+// HostAbstractUDSNames returns the list of names in HostAbstractUDS.
+func (c *Config) HostAbstractUDSNames() []string {
+	var names []string
+	for _, name := range strings.Split(c.HostAbstractUDS, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// DNSProxyUpstreamList returns the list of servers in DNSProxyUpstreams.
+func (c *Config) DNSProxyUpstreamList() []string {
+	var upstreams []string
+	for _, u := range strings.Split(c.DNSProxyUpstreams, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			upstreams = append(upstreams, u)
+		}
+	}
+	return upstreams
+}
+
 // GetOverlay2 returns the overlay configuration, taking into consideration all
 // flags that affect the result.
 func (c *Config) GetOverlay2() Overlay2 {
@@ -752,6 +994,151 @@ func (g HostUDS) AllowCreate() bool {
 	return g&HostUDSCreate != 0
 }
 
+// HostUDSRights is a set of types of FDs that may be received as SCM_RIGHTS
+// from host Unix-domain sockets.
+type HostUDSRights int
+
+const (
+	// HostUDSRightsFile allows regular files other than memfds.
+	HostUDSRightsFile HostUDSRights = 1 << iota
+
+	// HostUDSRightsMemfd allows memfds and other shared memory files.
+	HostUDSRightsMemfd
+
+	// HostUDSRightsSocket allows Unix-domain sockets.
+	HostUDSRightsSocket
+
+	// HostUDSRightsFIFO allows pipes and FIFOs.
+	HostUDSRightsFIFO
+
+	// HostUDSRightsCharDev allows character devices.
+	HostUDSRightsCharDev
+
+	// HostUDSRightsNone allows no FDs to be received.
+	HostUDSRightsNone HostUDSRights = 0
+
+	// HostUDSRightsAll allows all types of FDs to be received.
+	HostUDSRightsAll = HostUDSRightsFile | HostUDSRightsMemfd | HostUDSRightsSocket | HostUDSRightsFIFO | HostUDSRightsCharDev
+)
+
+// hostUDSRightsNames maps names accepted by --host-uds-rights to the type they
+// allow, in the order used by HostUDSRights.String.
+var hostUDSRightsNames = []struct {
+	name  string
+	right HostUDSRights
+}{
+	{"file", HostUDSRightsFile},
+	{"memfd", HostUDSRightsMemfd},
+	{"socket", HostUDSRightsSocket},
+	{"fifo", HostUDSRightsFIFO},
+	{"chardev", HostUDSRightsCharDev},
+}
+
+func hostUDSRightsPtr(v HostUDSRights) *HostUDSRights {
+	return &v
+}
+
+// Set implements flag.Value. Set(String()) should be idempotent.
+func (r *HostUDSRights) Set(v string) error {
+	switch v {
+	case "", "none":
+		*r = HostUDSRightsNone
+		return nil
+	case "all":
+		*r = HostUDSRightsAll
+		return nil
+	}
+	var rights HostUDSRights
+	for _, name := range strings.Split(v, ",") {
+		found := false
+		for _, n := range hostUDSRightsNames {
+			if n.name == name {
+				rights |= n.right
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("invalid host UDS rights type %q", name)
+		}
+	}
+	*r = rights
+	return nil
+}
+
+// Get implements flag.Value.
+func (r *HostUDSRights) Get() any {
+	return *r
+}
+
+// String implements flag.Value.
+func (r HostUDSRights) String() string {
+	switch r {
+	case HostUDSRightsNone:
+		return "none"
+	case HostUDSRightsAll:
+		return "all"
+	}
+	var names []string
+	for _, n := range hostUDSRightsNames {
+		if r&n.right != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// HostUDSIDMap maps user or group IDs on the host to IDs in the sandbox. It
+// is used to translate the credentials of peers of host Unix-domain sockets,
+// and is written as a comma-separated list of host:sandbox ID pairs.
+type HostUDSIDMap map[uint32]uint32
+
+// Set implements flag.Value. Set(String()) should be idempotent.
+func (m *HostUDSIDMap) Set(v string) error {
+	ids := make(HostUDSIDMap)
+	if v != "" {
+		for _, pair := range strings.Split(v, ",") {
+			hostStr, sandboxStr, ok := strings.Cut(pair, ":")
+			if !ok {
+				return fmt.Errorf("invalid ID mapping %q, must be host:sandbox", pair)
+			}
+			hostID, err := strconv.ParseUint(hostStr, 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid host ID in mapping %q: %v", pair, err)
+			}
+			sandboxID, err := strconv.ParseUint(sandboxStr, 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid sandbox ID in mapping %q: %v", pair, err)
+			}
+			if _, ok := ids[uint32(hostID)]; ok {
+				return fmt.Errorf("host ID %d is mapped more than once", hostID)
+			}
+			ids[uint32(hostID)] = uint32(sandboxID)
+		}
+	}
+	*m = ids
+	return nil
+}
+
+// Get implements flag.Value.
+func (m *HostUDSIDMap) Get() any {
+	return *m
+}
+
+// String implements flag.Value.
+func (m HostUDSIDMap) String() string {
+	hostIDs := make([]uint32, 0, len(m))
+	for hostID := range m {
+		hostIDs = append(hostIDs, hostID)
+	}
+	sort.Slice(hostIDs, func(i, j int) bool { return hostIDs[i] < hostIDs[j] })
+	pairs := make([]string, 0, len(hostIDs))
+	for _, hostID := range hostIDs {
+		pairs = append(pairs, fmt.Sprintf("%d:%d", hostID, m[hostID]))
+	}
+	return strings.Join(pairs, ",")
+}
+
 // HostFifo tells how much of the host FIFO (or named pipes) the file system has
 // access to.
 type HostFifo int
@@ -803,6 +1190,61 @@ func (g HostFifo) AllowOpen() bool {
 	return g&HostFifoOpen != 0
 }
 
+// MemoryFileHugePages tells how the sandbox memory file uses host huge pages.
+type MemoryFileHugePages int
+
+const (
+	// MemoryFileHugePagesNone doesn't request huge pages. The host may still
+	// use transparent huge pages depending on its configuration.
+	MemoryFileHugePagesNone MemoryFileHugePages = iota
+
+	// MemoryFileHugePagesTHP advises the host to back the memory file with
+	// transparent huge pages, using madvise(MADV_HUGEPAGE).
+	MemoryFileHugePagesTHP
+
+	// MemoryFileHugePagesHugetlb backs the memory file with pages from the
+	// host's hugetlbfs pool, which must be large enough for the sandbox.
+	MemoryFileHugePagesHugetlb
+)
+
+func memoryFileHugePagesPtr(v MemoryFileHugePages) *MemoryFileHugePages {
+	return &v
+}
+
+// Set implements flag.Value. Set(String()) should be idempotent.
+func (m *MemoryFileHugePages) Set(v string) error {
+	switch v {
+	case "", "none":
+		*m = MemoryFileHugePagesNone
+	case "thp":
+		*m = MemoryFileHugePagesTHP
+	case "hugetlb":
+		*m = MemoryFileHugePagesHugetlb
+	default:
+		return fmt.Errorf("invalid memory file huge pages type %q", v)
+	}
+	return nil
+}
+
+// Get implements flag.Value.
+func (m *MemoryFileHugePages) Get() any {
+	return *m
+}
+
+// String implements flag.Value.
+func (m MemoryFileHugePages) String() string {
+	switch m {
+	case MemoryFileHugePagesNone:
+		return "none"
+	case MemoryFileHugePagesTHP:
+		return "thp"
+	case MemoryFileHugePagesHugetlb:
+		return "hugetlb"
+	default:
+		panic(fmt.Sprintf("Invalid memory file huge pages type %d", m))
+	}
+}
+
 // OverlayMedium describes how overlay medium is configured.
 type OverlayMedium string
 
@@ -1024,3 +1466,86 @@ func (xd *XDP) Set(input string) error {
 	}
 	return nil
 }
+
+// HostCharDev is a host character device that is passed through to the
+// sandbox.
+type HostCharDev struct {
+	// Path is the absolute path of the device on the host. It must be under
+	// /dev, and the device is exposed at the same path in the sandbox.
+	Path string
+
+	// Ioctls are the ioctl commands that may be forwarded to the device.
+	Ioctls []uint32
+}
+
+// HostCharDevs is a list of host character devices that are passed through to
+// the sandbox. Its flag format is a semicolon-separated list of
+// "PATH[:IOCTL[,IOCTL...]]" entries, where each IOCTL is a decimal or
+// 0x-prefixed hexadecimal ioctl command number.
+type HostCharDevs []HostCharDev
+
+// Set implements flag.Value. Set(String()) should be idempotent.
+func (h *HostCharDevs) Set(v string) error {
+	var devs HostCharDevs
+	for _, entry := range strings.Split(v, ";") {
+		if entry == "" {
+			continue
+		}
+		path, ioctls, _ := strings.Cut(entry, ":")
+		path = filepath.Clean(path)
+		if !filepath.IsAbs(path) || !strings.HasPrefix(path, "/dev/") {
+			return fmt.Errorf("invalid --host-chardev path %q: must be an absolute path under /dev", path)
+		}
+		dev := HostCharDev{Path: path}
+		if ioctls != "" {
+			for _, cmd := range strings.Split(ioctls, ",") {
+				n, err := strconv.ParseUint(cmd, 0, 32)
+				if err != nil {
+					return fmt.Errorf("invalid --host-chardev ioctl %q for %q: %v", cmd, path, err)
+				}
+				dev.Ioctls = append(dev.Ioctls, uint32(n))
+			}
+		}
+		devs = append(devs, dev)
+	}
+	*h = devs
+	return nil
+}
+
+// Ioctls returns the union of the ioctl commands allowed for all devices in
+// h, without duplicates.
+func (h HostCharDevs) Ioctls() []uint32 {
+	var cmds []uint32
+	seen := make(map[uint32]struct{})
+	for _, dev := range h {
+		for _, cmd := range dev.Ioctls {
+			if _, ok := seen[cmd]; !ok {
+				seen[cmd] = struct{}{}
+				cmds = append(cmds, cmd)
+			}
+		}
+	}
+	return cmds
+}
+
+// Get implements flag.Value.
+func (h *HostCharDevs) Get() any {
+	return *h
+}
+
+// String implements flag.Value.
+func (h HostCharDevs) String() string {
+	entries := make([]string, 0, len(h))
+	for _, dev := range h {
+		entry := dev.Path
+		if len(dev.Ioctls) > 0 {
+			cmds := make([]string, 0, len(dev.Ioctls))
+			for _, cmd := range dev.Ioctls {
+				cmds = append(cmds, fmt.Sprintf("%#x", cmd))
+			}
+			entry += ":" + strings.Join(cmds, ",")
+		}
+		entries = append(entries, entry)
+	}
+	return strings.Join(entries, ";")
+}
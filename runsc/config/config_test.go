@@ -221,6 +221,11 @@ func TestInvalidFlags(t *testing.T) {
 			value: "invalid",
 			error: "invalid host fifo",
 		},
+		{
+			name:  "memory-file-hugepages",
+			value: "invalid",
+			error: "invalid memory file huge pages type",
+		},
 		{
 			name:  "overlay2",
 			value: "root:/tmp",
@@ -231,6 +236,16 @@ func TestInvalidFlags(t *testing.T) {
 			value: "root:dir=tmp",
 			error: "overlay host file directory should be an absolute path, got \"tmp\"",
 		},
+		{
+			name:  "host-chardev",
+			value: "/tmp/tun",
+			error: "must be an absolute path under /dev",
+		},
+		{
+			name:  "host-chardev",
+			value: "/dev/net/tun:TUNSETIFF",
+			error: "invalid --host-chardev ioctl",
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			testFlags := flag.NewFlagSet("test", flag.ContinueOnError)
@@ -303,6 +318,13 @@ func TestValidationFail(t *testing.T) {
 			},
 			error: "overlay flag has been replaced with overlay2 flag",
 		},
+		{
+			name: "host-abstract-uds-uid:root",
+			flags: map[string]string{
+				"host-abstract-uds-uid": "0",
+			},
+			error: "host-abstract-uds-uid must be > 0",
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			testFlags := flag.NewFlagSet("test", flag.ContinueOnError)
@@ -778,3 +800,147 @@ root = "%s"
 	}
 
 }
+
+func TestHostCharDevs(t *testing.T) {
+	var devs HostCharDevs
+	if err := devs.Set("/dev/net/tun:0x400454ca,0x800454cf;/dev/hwrng"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	want := HostCharDevs{
+		{Path: "/dev/net/tun", Ioctls: []uint32{0x400454ca, 0x800454cf}},
+		{Path: "/dev/hwrng"},
+	}
+	if diff := cmp.Diff(want, devs); diff != "" {
+		t.Errorf("Set() mismatch (-want +got):\n%s", diff)
+	}
+
+	var roundTrip HostCharDevs
+	if err := roundTrip.Set(devs.String()); err != nil {
+		t.Fatalf("Set(%q) failed: %v", devs.String(), err)
+	}
+	if diff := cmp.Diff(devs, roundTrip); diff != "" {
+		t.Errorf("Set(String()) mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHostUDSRights(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  HostUDSRights
+		str   string
+	}{
+		{value: "", want: HostUDSRightsNone, str: "none"},
+		{value: "none", want: HostUDSRightsNone, str: "none"},
+		{value: "all", want: HostUDSRightsAll, str: "all"},
+		{value: "memfd", want: HostUDSRightsMemfd, str: "memfd"},
+		{value: "socket,file", want: HostUDSRightsFile | HostUDSRightsSocket, str: "file,socket"},
+		{value: "file,memfd,socket,fifo,chardev", want: HostUDSRightsAll, str: "all"},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			var r HostUDSRights
+			if err := r.Set(tc.value); err != nil {
+				t.Fatalf("Set(%q) failed: %v", tc.value, err)
+			}
+			if r != tc.want {
+				t.Errorf("Set(%q) = %#x, want %#x", tc.value, r, tc.want)
+			}
+			if got := r.String(); got != tc.str {
+				t.Errorf("String() = %q, want %q", got, tc.str)
+			}
+		})
+	}
+
+	var r HostUDSRights
+	if err := r.Set("file,dir"); err == nil {
+		t.Errorf("Set(%q) succeeded, want error", "file,dir")
+	}
+}
+
+func TestHostUDSIDMap(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  HostUDSIDMap
+		str   string
+	}{
+		{value: "", want: HostUDSIDMap{}, str: ""},
+		{value: "1000:0", want: HostUDSIDMap{1000: 0}, str: "1000:0"},
+		{value: "1001:1001,1000:0", want: HostUDSIDMap{1000: 0, 1001: 1001}, str: "1000:0,1001:1001"},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			var m HostUDSIDMap
+			if err := m.Set(tc.value); err != nil {
+				t.Fatalf("Set(%q) failed: %v", tc.value, err)
+			}
+			if !reflect.DeepEqual(m, tc.want) {
+				t.Errorf("Set(%q) = %v, want %v", tc.value, m, tc.want)
+			}
+			if got := m.String(); got != tc.str {
+				t.Errorf("String() = %q, want %q", got, tc.str)
+			}
+		})
+	}
+
+	for _, value := range []string{"1000", "1000:x", "-1:0", "1000:0,1000:1"} {
+		var m HostUDSIDMap
+		if err := m.Set(value); err == nil {
+			t.Errorf("Set(%q) succeeded, want error", value)
+		}
+	}
+}
+
+func TestMemoryFileHugePages(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  MemoryFileHugePages
+		str   string
+	}{
+		{value: "", want: MemoryFileHugePagesNone, str: "none"},
+		{value: "none", want: MemoryFileHugePagesNone, str: "none"},
+		{value: "thp", want: MemoryFileHugePagesTHP, str: "thp"},
+		{value: "hugetlb", want: MemoryFileHugePagesHugetlb, str: "hugetlb"},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			var m MemoryFileHugePages
+			if err := m.Set(tc.value); err != nil {
+				t.Fatalf("Set(%q) failed: %v", tc.value, err)
+			}
+			if m != tc.want {
+				t.Errorf("Set(%q) = %d, want %d", tc.value, m, tc.want)
+			}
+			if got := m.String(); got != tc.str {
+				t.Errorf("String() = %q, want %q", got, tc.str)
+			}
+		})
+	}
+
+	// Non-default values must be passed on to child processes.
+	for _, want := range []MemoryFileHugePages{MemoryFileHugePagesTHP, MemoryFileHugePagesHugetlb} {
+		t.Run("round-trip-"+want.String(), func(t *testing.T) {
+			testFlags := flag.NewFlagSet("test", flag.ContinueOnError)
+			RegisterFlags(testFlags)
+			if err := testFlags.Set("memory-file-hugepages", want.String()); err != nil {
+				t.Fatalf("Set(%q) failed: %v", want, err)
+			}
+			c, err := NewFromFlags(testFlags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.MemoryFileHugePages != want {
+				t.Errorf("MemoryFileHugePages = %v, want %v", c.MemoryFileHugePages, want)
+			}
+
+			childFlags := flag.NewFlagSet("child", flag.ContinueOnError)
+			RegisterFlags(childFlags)
+			if err := childFlags.Parse(c.ToFlags()); err != nil {
+				t.Fatalf("Parse(%q) failed: %v", c.ToFlags(), err)
+			}
+			child, err := NewFromFlags(childFlags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if child.MemoryFileHugePages != want {
+				t.Errorf("MemoryFileHugePages after round-trip through %q = %v, want %v", c.ToFlags(), child.MemoryFileHugePages, want)
+			}
+		})
+	}
+}
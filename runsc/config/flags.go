@@ -76,6 +76,20 @@ func RegisterFlags(flagSet *flag.FlagSet) {
 	flagSet.String("platform", "systrap", "specifies which platform to use: systrap (default), ptrace, kvm.")
 	flagSet.String("platform_device_path", "", "path to a platform-specific device file (e.g. /dev/kvm for KVM platform). If unset, will use a sane platform-specific default.")
 	flagSet.Var(watchdogActionPtr(watchdog.LogWarning), "watchdog-action", "sets what action the watchdog takes when triggered: log (default), panic.")
+	flagSet.String("watchdog-stall-timeouts", "", "comma-separated list of <subsystem>=<timeout> pairs enabling watchdog stall detection for the given subsystems: gofer, netstack, reclaim. E.g. gofer=30s,reclaim=1m.")
+	flagSet.String("watchdog-stall-actions", "log", "comma-separated list of actions the watchdog takes when a stalled subsystem is detected: log (default), event, checkpoint.")
+	flagSet.String("watchdog-stall-checkpoint", "", "file path to write a statefile snapshot to when a stalled subsystem is detected. Requires -watchdog-stall-actions to include checkpoint.")
+	flagSet.String("restore-host-fds", "", "comma-separated list of [container/]fd=source rules re-establishing host FDs of checkpointed containers on restore, where source is a host path or a unix:path, tcp:host:port or udp:host:port address.")
+	flagSet.String("restore-fd-resolver", "", "path of a Unix socket serving FDResolver.Resolve urpc calls, asked on restore for host FDs of checkpointed containers that are not covered by -restore-host-fds.")
+	flagSet.Bool("state-io-uring", false, "use io_uring, if available on the host, to write and read checkpoint pages files. Combined with checkpoint and restore -direct, this avoids caching checkpoints in the host page cache.")
+	flagSet.Bool("oom-killer", false, "enables the in-sandbox OOM killer, which kills the process with the highest oom_score when the sandbox memory usage exceeds its total memory.")
+	flagSet.String("swap-dir", "", "directory in which to create a file backing a swap tier for sandbox memory. Under memory pressure, cold application memory is migrated to this file instead of relying on host swap. Empty disables swapping.")
+	flagSet.Int("swap-watermark", 80, "percentage of the sandbox total memory above which cold memory is migrated to the swap tier. Requires -swap-dir or -swap-compressed-percent.")
+	flagSet.Int("swap-age", 2, "number of consecutive swap aging passes, performed every second under memory pressure, during which memory must not be used to be migrated to the swap tier. Requires -swap-dir or -swap-compressed-percent.")
+	flagSet.Int("swap-compressed-percent", 0, "maximum percentage of the sandbox total memory used by a compressed in-memory cache tier. Under memory pressure, cold application memory is LZ4-compressed into this tier, and decompressed when accessed, trading CPU for lower host memory usage. Can be used with or without -swap-dir. 0 disables the compressed cache tier.")
+	flagSet.Int("swap-size-mb", 0, "size in MiB of the virtual swap device reported to applications in /proc/swaps, /proc/meminfo and sysinfo(2). Its usage reflects application memory in the swap and compressed cache tiers. Requires -swap-dir or -swap-compressed-percent. 0 reports no swap.")
+	flagSet.Var(memoryFileHugePagesPtr(MemoryFileHugePagesNone), "memory-file-hugepages", "controls whether sandbox memory is backed by host huge pages, reducing TLB misses (particularly on the KVM platform). Values: none (default), thp (advise transparent huge pages), hugetlb (allocate from the host hugetlbfs pool).")
+	flagSet.Bool("memory-compaction", false, "periodically migrates application memory out of sparsely used huge pages, so that free memory can be returned to the host in larger ranges. Most useful with -memory-file-hugepages.")
 	flagSet.Int("panic-signal", -1, "register signal handling that panics. Usually set to SIGUSR2(12) to troubleshoot hangs. -1 disables it.")
 	flagSet.Bool("profile", false, "prepares the sandbox to use Golang profiler. Note that enabling profiler loosens the seccomp protection added to the sandbox (DO NOT USE IN PRODUCTION).")
 	flagSet.String("profile-block", "", "collects a block profile to this file path for the duration of the container execution. Requires -profile=true.")
@@ -86,6 +100,15 @@ func RegisterFlags(flagSet *flag.FlagSet) {
 	flagSet.Bool("rootless", false, "it allows the sandbox to be started with a user that is not root. Sandbox and Gofer processes may run with same privileges as current user.")
 	flagSet.Var(leakModePtr(refs.NoLeakChecking), "ref-leak-mode", "sets reference leak check mode: disabled (default), log-names, log-traces.")
 	flagSet.Bool("cpu-num-from-quota", false, "set cpu number to cpu quota (least integer greater or equal to quota value, but not less than 2)")
+	flagSet.Bool("limits-from-cgroup", false, "make sysinfo(2), /proc/meminfo and /proc/cpuinfo reflect the container's cgroup memory limit and CPU quota (least integer greater or equal to quota value) instead of host resources, so that applications which size themselves from these values behave as they would in a container on Linux.")
+	flagSet.String("cpu-topology", "", "CPU topology presented to the sandbox, as a comma-separated list of sockets=N, cores=N (per socket), threads=N (per core), numa=N (nodes), and l1d, l1i, l2, l3=SIZE (cache sizes, e.g. 32K). Unspecified cores are derived from the number of CPUs, e.g. threads=2 presents CPUs as pairs of hardware threads.")
+	flagSet.String("cpu-feature-mask", "", "comma-separated list of CPU features, as named in /proc/cpuinfo flags (e.g. avx512f,amx_tile), to hide from the sandbox in both CPUID and the ELF auxiliary vector.")
+	flagSet.Duration("clock-realtime-offset", 0, "offset added to CLOCK_REALTIME in the sandbox, e.g. 8760h to test certificate expiry. It can be changed at runtime with 'runsc debug -clock-skew'.")
+	flagSet.Duration("clock-monotonic-offset", 0, "offset added to CLOCK_MONOTONIC in the sandbox. It must not be negative.")
+	flagSet.Float64("clock-scale", 1, "rate at which time elapses in the sandbox relative to the host, e.g. 2 makes time elapse twice as fast.")
+	flagSet.Bool("deterministic", false, "run the sandbox deterministically, for reproducing flaky tests and record/replay debugging: time starts at a fixed point and advances logically, random bytes are generated from -deterministic-seed, and application code runs on a single virtual CPU. Executions are still affected by external events such as network traffic.")
+	flagSet.Uint64("deterministic-seed", 0, "seed of random bytes returned to applications when -deterministic is set.")
+	flagSet.Bool("lazy-fork", false, "defer copying private memory mappings of forked processes until they are accessed by the child, which speeds up fork+exec-heavy workloads such as shells and build systems.")
 	flagSet.Bool("oci-seccomp", false, "Enables loading OCI seccomp filters inside the sandbox.")
 	flagSet.Bool("enable-core-tags", false, "enables core tagging. Requires host linux kernel >= 5.14.")
 	flagSet.String("pod-init-config", "", "path to configuration file with additional steps to take during pod creation.")
@@ -97,6 +120,13 @@ func RegisterFlags(flagSet *flag.FlagSet) {
 	flagSet.Var(defaultOverlay2(), "overlay2", "wrap mounts with overlayfs. Format is {mount}:{medium}, where 'mount' can be 'root' or 'all' and medium can be 'memory', 'self' or 'dir=/abs/dir/path' in which filestore will be created. 'none' will turn overlay mode off.")
 	flagSet.Bool("fsgofer-host-uds", false, "DEPRECATED: use host-uds=all")
 	flagSet.Var(hostUDSPtr(HostUDSNone), "host-uds", "controls permission to access host Unix-domain sockets. Values: none|open|create|all, default: none")
+	flagSet.Bool("host-memfd", false, "EXPERIMENTAL: back memfd_create(2) files with host memfds, so that shared memory buffers (e.g. Wayland wl_shm pools) can be passed to host processes over host Unix-domain sockets. File seals are not supported on these memfds.")
+	flagSet.String("host-abstract-uds", "", "EXPERIMENTAL: comma-separated list of names of abstract Unix-domain sockets in the host network namespace that the sandbox may connect to, without the leading '@'. A name ending in '*' matches all names with that prefix.")
+	flagSet.Int("host-abstract-uds-uid", 65534, "user ID that the proxy for host abstract Unix-domain sockets runs as. Must not be 0.")
+	flagSet.Int("host-abstract-uds-gid", 65534, "group ID that the proxy for host abstract Unix-domain sockets runs as.")
+	flagSet.Var(&HostUDSIDMap{}, "host-uds-uid-map", "comma-separated list of host:sandbox user ID pairs used to translate the credentials of peers of host Unix-domain sockets. Unmapped user IDs appear as nobody.")
+	flagSet.Var(&HostUDSIDMap{}, "host-uds-gid-map", "comma-separated list of host:sandbox group ID pairs used to translate the credentials of peers of host Unix-domain sockets. Unmapped group IDs appear as nobody.")
+	flagSet.Var(hostUDSRightsPtr(HostUDSRightsAll), "host-uds-rights", "controls which types of FDs may be received as SCM_RIGHTS from host Unix-domain sockets. Values: none|all or a comma-separated list of file|memfd|socket|fifo|chardev, default: all")
 	flagSet.Var(hostFifoPtr(HostFifoNone), "host-fifo", "controls permission to access host FIFOs (or named pipes). Values: none|open, default: none")
 
 	flagSet.Bool("vfs2", true, "DEPRECATED: this flag has no effect.")
@@ -108,6 +138,10 @@ func RegisterFlags(flagSet *flag.FlagSet) {
 	flagSet.Int("dcache", -1, "Set the global dentry cache size. This acts as a coarse-grained control on the number of host FDs simultaneously open by the sentry. If negative, per-mount caches are used.")
 	flagSet.Bool("iouring", false, "TEST ONLY; Enables io_uring syscalls in the sentry. Support is experimental and very limited.")
 	flagSet.Bool("directfs", true, "directly access the container filesystems from the sentry. Sentry runs with higher privileges.")
+	flagSet.Bool("gofer-cache", false, "cache file attributes, symlink targets and negative lookups of read-only mounts in the gofer. Changes made to read-only mounts outside of the sandbox may not be observed. Has no effect with -directfs.")
+	flagSet.String("shared-page-cache", "", "path to a host directory, preferably on tmpfs, shared by sandboxes to store copies of files opened read-only on read-only mounts, such that sandboxes using the same images share memory. Its size must be bounded externally. Has no effect with -directfs.")
+	flagSet.Bool("dynamic-mounts", false, "allow bind mounts to be added to and removed from running containers with 'runsc mount', and GPUs to be attached with 'runsc gpu'.")
+	flagSet.Bool("nested-containers", false, "delegate the container's cgroups to the container's user, to allow container runtimes such as runc to run inside the sandbox.")
 
 	// Flags that control sandbox runtime behavior: network related.
 	flagSet.Var(networkTypePtr(NetworkSandbox), "network", "specifies which network to use: sandbox (default), host, none. Using network inside the sandbox is more secure because it's isolated from the host network.")
@@ -119,12 +153,14 @@ func RegisterFlags(flagSet *flag.FlagSet) {
 	flagSet.Bool("rx-checksum-offload", true, "enable RX checksum offload.")
 	flagSet.Var(queueingDisciplinePtr(QDiscFIFO), "qdisc", "specifies which queueing discipline to apply by default to the non loopback nics used by the sandbox.")
 	flagSet.Int("num-network-channels", 1, "number of underlying channels(FDs) to use for network link endpoints.")
+	flagSet.Int("network-processor-queue-len", 0, "maximum number of inbound packets queued for each network processor goroutine. Packets received while the queue is full are dropped. If 0, queues are unbounded.")
 	flagSet.Int("network-processors-per-channel", 0, "number of goroutines in each channel for processng inbound packets. If 0, the link endpoint will divide GOMAXPROCS evenly among the number of channels specified by num-network-channels.")
 	flagSet.Bool("buffer-pooling", true, "DEPRECATED: this flag has no effect. Buffer pooling is always enabled.")
 	flagSet.Var(&xdpConfig, "EXPERIMENTAL-xdp", `whether and how to use XDP. Can be one of: "off" (default), "ns", "redirect:<device name>", or "tunnel:<device name>"`)
 	flagSet.Bool("EXPERIMENTAL-xdp-need-wakeup", true, "EXPERIMENTAL. Use XDP_USE_NEED_WAKEUP with XDP sockets.") // TODO(b/240191988): Figure out whether this helps and remove it as a flag.
 	flagSet.Bool("reproduce-nat", false, "Scrape the host netns NAT table and reproduce it in the sandbox.")
 	flagSet.Bool("reproduce-nftables", false, "Attempt to scrape and reproduce nftable rules inside the sandbox. Overrides reproduce-nat when true.")
+	flagSet.String("dns-proxy-upstreams", "", "comma-separated list of DNS servers to forward queries to from a caching DNS proxy listening on 127.0.0.53 in the sandbox. Servers are specified as IP[:port], tcp://IP[:port] or tls://IP[:port]#server-name. The container's resolv.conf must point at 127.0.0.53 to use the proxy. Requires network=sandbox.")
 	flagSet.Bool("net-disconnect-ok", false, "Indicates whether the link endpoint capability CapabilityDisconnectOk should be set. This allows open connections to be disconnected upon save.")
 
 	// Flags that control sandbox runtime behavior: accelerator related.
@@ -132,6 +168,9 @@ func RegisterFlags(flagSet *flag.FlagSet) {
 	flagSet.Bool("nvproxy-docker", false, "DEPRECATED: use nvidia-container-runtime or `docker run --gpus` directly. Or manually add nvidia-container-runtime-hook as a prestart hook and set up NVIDIA_VISIBLE_DEVICES container environment variable.")
 	flagSet.String("nvproxy-driver-version", "", "NVIDIA driver ABI version to use. If empty, autodetect installed driver version. The special value 'latest' may also be used to use the latest ABI.")
 	flagSet.Bool("tpuproxy", false, "EXPERIMENTAL: enable support for TPU device passthrough.")
+	flagSet.Bool("drmproxy", false, "EXPERIMENTAL: enable support for DRM render node passthrough for AMD and Intel GPUs. Render nodes under /dev/dri in the OCI spec are proxied to the host.")
+	flagSet.Bool("sndproxy", false, "EXPERIMENTAL: enable support for ALSA sound device passthrough. Control, PCM and timer devices under /dev/snd in the OCI spec are proxied to the host.")
+	flagSet.Var(&HostCharDevs{}, "host-chardev", "EXPERIMENTAL: host character devices to pass through to the sandbox. Format is a semicolon-separated list of PATH[:IOCTL,...], where PATH is under /dev and each IOCTL is an ioctl command number that may be forwarded to the device.")
 
 	// Test flags, not to be used outside tests, ever.
 	flagSet.Bool("TESTONLY-unsafe-nonroot", false, "TEST ONLY; do not ever use! This skips many security measures that isolate the host from the sandbox.")
@@ -494,6 +533,8 @@ func getVal(field reflect.Value) string {
 		return strconv.FormatInt(field.Int(), 10)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 		return strconv.FormatUint(field.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(field.Float(), 'g', -1, 64)
 	case reflect.String:
 		return field.String()
 	default:
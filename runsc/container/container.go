@@ -18,6 +18,7 @@ package container
 import (
 	"bufio"
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -25,6 +26,7 @@ import (
 	"os/exec"
 	"path"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
@@ -42,11 +44,13 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sighandling"
 	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/pkg/unet"
 	"gvisor.dev/gvisor/runsc/boot"
 	"gvisor.dev/gvisor/runsc/cgroup"
 	"gvisor.dev/gvisor/runsc/config"
 	"gvisor.dev/gvisor/runsc/console"
 	"gvisor.dev/gvisor/runsc/donation"
+	"gvisor.dev/gvisor/runsc/fsgofer"
 	"gvisor.dev/gvisor/runsc/sandbox"
 	"gvisor.dev/gvisor/runsc/specutils"
 )
@@ -141,6 +145,25 @@ type Container struct {
 	// following entries are for bind mounts in Spec.Mounts (in the same order).
 	GoferMountConfs boot.GoferMountConfFlags `json:"goferMountConfs"`
 
+	// VerityRootHashes maps paths in the container for which verification has
+	// been enabled with EnableVerity to their hex-encoded root hashes. Root
+	// hashes are pinned: verification can't be enabled again for the same
+	// path with a different root hash.
+	VerityRootHashes map[string]string `json:"verityRootHashes,omitempty"`
+
+	// GoferMountControlPath is the path to the socket on which the gofer
+	// accepts mounts to serve at runtime. It is only set with
+	// --dynamic-mounts.
+	GoferMountControlPath string `json:"goferMountControlPath,omitempty"`
+
+	// DynamicMounts maps the destinations of bind mounts added with
+	// AddBindMount to their host sources.
+	DynamicMounts map[string]string `json:"dynamicMounts,omitempty"`
+
+	// AttachedGPUs lists the minor numbers of the GPUs attached with
+	// AttachGPU. They are not preserved across checkpoint and restore.
+	AttachedGPUs []uint32 `json:"attachedGPUs,omitempty"`
+
 	//
 	// Fields below this line are not saved in the state file and will not
 	// be preserved across commands.
@@ -702,11 +725,16 @@ func (c *Container) ForwardSignals(pid int32, fgProcess bool) func() {
 
 // Checkpoint sends the checkpoint call to the container.
 // The statefile will be written to f, the file at the specified image-path.
+// A manifest of all containers in the sandbox is written alongside it, see
+// CheckpointManifest.
 func (c *Container) Checkpoint(imagePath string, direct bool, sfOpts statefile.Options, mfOpts pgalloc.SaveOpts) error {
 	log.Debugf("Checkpoint container, cid: %s", c.ID)
 	if err := c.requireStatus("checkpoint", Created, Running, Paused); err != nil {
 		return err
 	}
+	if err := writeCheckpointManifest(c.Saver.RootDir, c.Sandbox.ID, imagePath); err != nil {
+		return err
+	}
 	return c.Sandbox.Checkpoint(c.ID, imagePath, direct, sfOpts, mfOpts)
 }
 
@@ -730,6 +758,215 @@ func (c *Container) Pause() error {
 	return c.saveLocked()
 }
 
+// EnableVerity enables verification of all files below the directory at path
+// in the container, and returns the directory's root hash. If rootHash is
+// non-empty, or a root hash was pinned for the same path by a previous call,
+// EnableVerity fails if the directory's root hash differs from it.
+func (c *Container) EnableVerity(path string, rootHash []byte) ([]byte, error) {
+	log.Debugf("Enabling verity in container, cid: %s, path: %q", c.ID, path)
+	if err := c.Saver.lock(BlockAcquire); err != nil {
+		return nil, err
+	}
+	defer c.Saver.UnlockOrDie()
+
+	if c.Status != Running {
+		return nil, fmt.Errorf("cannot enable verity in container %q in state %v", c.ID, c.Status)
+	}
+	if pinned, ok := c.VerityRootHashes[path]; ok {
+		if len(rootHash) != 0 && hex.EncodeToString(rootHash) != pinned {
+			return nil, fmt.Errorf("root hash %x differs from root hash %s pinned for %q", rootHash, pinned, path)
+		}
+		var err error
+		if rootHash, err = hex.DecodeString(pinned); err != nil {
+			return nil, fmt.Errorf("invalid pinned root hash %q: %w", pinned, err)
+		}
+	}
+	got, err := c.Sandbox.EnableVerity(c.ID, path, rootHash)
+	if err != nil {
+		return nil, err
+	}
+	if c.VerityRootHashes == nil {
+		c.VerityRootHashes = make(map[string]string)
+	}
+	c.VerityRootHashes[path] = hex.EncodeToString(got)
+	if err := c.saveLocked(); err != nil {
+		return nil, err
+	}
+	return got, nil
+}
+
+// AddBindMount bind mounts the host file or directory at source to dest in
+// the running container. The mount is served by the container's gofer, like
+// bind mounts in the spec. Mounts added at runtime can't be restored from a
+// checkpoint, and must be removed before checkpointing.
+func (c *Container) AddBindMount(source, dest string, readonly bool) error {
+	log.Debugf("Adding bind mount in container, cid: %s, source: %q, dest: %q, readonly: %t", c.ID, source, dest, readonly)
+	if !path.IsAbs(dest) {
+		return fmt.Errorf("mount destination must be absolute: %q", dest)
+	}
+	dest = path.Clean(dest)
+
+	if err := c.Saver.lock(BlockAcquire); err != nil {
+		return err
+	}
+	defer c.Saver.UnlockOrDie()
+
+	if c.Status != Running {
+		return fmt.Errorf("cannot add mount in container %q in state %v", c.ID, c.Status)
+	}
+	if c.GoferMountControlPath == "" {
+		return fmt.Errorf("container %q was not created with --dynamic-mounts", c.ID)
+	}
+	if src, ok := c.DynamicMounts[dest]; ok {
+		return fmt.Errorf("%q is already mounted from %q", dest, src)
+	}
+
+	hostFD, err := unix.Open(source, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("opening mount source %q: %w", source, err)
+	}
+	defer unix.Close(hostFD)
+
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	sandEnd := os.NewFile(uintptr(fds[0]), "sandbox IO FD")
+	defer sandEnd.Close()
+	goferEnd := os.NewFile(uintptr(fds[1]), "gofer IO FD")
+	defer goferEnd.Close()
+
+	if err := fsgofer.RequestMount(c.GoferMountControlPath, hostFD, int(goferEnd.Fd()), readonly); err != nil {
+		return err
+	}
+	if err := c.Sandbox.MountGofer(c.ID, dest, sandEnd, readonly); err != nil {
+		return fmt.Errorf("mounting %q in container %q: %w", dest, c.ID, err)
+	}
+
+	if c.DynamicMounts == nil {
+		c.DynamicMounts = make(map[string]string)
+	}
+	c.DynamicMounts[dest] = source
+	return c.saveLocked()
+}
+
+// RemoveBindMount unmounts a bind mount added with AddBindMount from the
+// running container.
+func (c *Container) RemoveBindMount(dest string) error {
+	log.Debugf("Removing bind mount in container, cid: %s, dest: %q", c.ID, dest)
+	dest = path.Clean(dest)
+
+	if err := c.Saver.lock(BlockAcquire); err != nil {
+		return err
+	}
+	defer c.Saver.UnlockOrDie()
+
+	if c.Status != Running {
+		return fmt.Errorf("cannot remove mount in container %q in state %v", c.ID, c.Status)
+	}
+	if _, ok := c.DynamicMounts[dest]; !ok {
+		return fmt.Errorf("%q was not mounted with AddBindMount", dest)
+	}
+	if err := c.Sandbox.Unmount(c.ID, dest); err != nil {
+		return fmt.Errorf("unmounting %q in container %q: %w", dest, c.ID, err)
+	}
+	delete(c.DynamicMounts, dest)
+	return c.saveLocked()
+}
+
+// AttachGPU makes the host NVIDIA GPU /dev/nvidia<minor> available to the
+// running container. The GPU is served by the container's gofer, so the
+// container must have been created with nvproxy and --dynamic-mounts.
+func (c *Container) AttachGPU(conf *config.Config, minor uint32) error {
+	log.Debugf("Attaching GPU to container, cid: %s, minor: %d", c.ID, minor)
+	if !specutils.NVProxyEnabled(c.Spec, conf) {
+		return fmt.Errorf("GPUs can only be attached with nvproxy enabled")
+	}
+
+	if err := c.Saver.lock(BlockAcquire); err != nil {
+		return err
+	}
+	defer c.Saver.UnlockOrDie()
+
+	if c.Status != Running {
+		return fmt.Errorf("cannot attach GPU to container %q in state %v", c.ID, c.Status)
+	}
+	if c.GoferMountControlPath == "" {
+		return fmt.Errorf("container %q was not created with --dynamic-mounts", c.ID)
+	}
+	if slices.Contains(c.AttachedGPUs, minor) {
+		return fmt.Errorf("GPU %d is already attached to container %q", minor, c.ID)
+	}
+
+	devPath := fmt.Sprintf("/dev/nvidia%d", minor)
+	hostFD, err := unix.Open(devPath, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("opening %q: %w", devPath, err)
+	}
+	defer unix.Close(hostFD)
+
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	sandEnd := os.NewFile(uintptr(fds[0]), "sandbox device FD")
+	defer sandEnd.Close()
+	goferEnd := os.NewFile(uintptr(fds[1]), "gofer device FD")
+	defer goferEnd.Close()
+
+	// The gofer checks access mode on open, so the device connection itself
+	// is not read-only.
+	if err := fsgofer.RequestMount(c.GoferMountControlPath, hostFD, int(goferEnd.Fd()), false /* readonly */); err != nil {
+		return err
+	}
+	if err := c.Sandbox.AttachGPU(c.ID, minor, sandEnd); err != nil {
+		return fmt.Errorf("attaching %q to container %q: %w", devPath, c.ID, err)
+	}
+	c.AttachedGPUs = append(c.AttachedGPUs, minor)
+	return c.saveLocked()
+}
+
+// DetachGPU removes a GPU attached with AttachGPU from the running container.
+// Applications that still have the device open keep using it until they
+// close it.
+func (c *Container) DetachGPU(minor uint32) error {
+	log.Debugf("Detaching GPU from container, cid: %s, minor: %d", c.ID, minor)
+	if err := c.Saver.lock(BlockAcquire); err != nil {
+		return err
+	}
+	defer c.Saver.UnlockOrDie()
+
+	if c.Status != Running {
+		return fmt.Errorf("cannot detach GPU from container %q in state %v", c.ID, c.Status)
+	}
+	i := slices.Index(c.AttachedGPUs, minor)
+	if i < 0 {
+		return fmt.Errorf("GPU %d was not attached with AttachGPU", minor)
+	}
+	if err := c.Sandbox.DetachGPU(c.ID, minor); err != nil {
+		return fmt.Errorf("detaching GPU %d from container %q: %w", minor, c.ID, err)
+	}
+	c.AttachedGPUs = slices.Delete(c.AttachedGPUs, i, i+1)
+	return c.saveLocked()
+}
+
+// AddNetworkInterface moves the network interface with the given name from
+// the sandbox's net namespace into the running sandbox, along with its
+// addresses and routes. The interface is shared by all containers in the
+// sandbox.
+func (c *Container) AddNetworkInterface(conf *config.Config, name string) error {
+	log.Debugf("Adding network interface to sandbox, cid: %s, interface: %q", c.ID, name)
+	if err := c.Saver.lock(BlockAcquire); err != nil {
+		return err
+	}
+	defer c.Saver.UnlockOrDie()
+
+	if c.Status != Running {
+		return fmt.Errorf("cannot add network interface to container %q in state %v", c.ID, c.Status)
+	}
+	return c.Sandbox.AddNetworkInterface(conf, name)
+}
+
 // Resume unpauses the container and its kernel.
 // The call only succeeds if the container's status is paused.
 func (c *Container) Resume() error {
@@ -1095,6 +1332,13 @@ func (c *Container) stop() error {
 		return err
 	}
 
+	if c.GoferMountControlPath != "" {
+		if err := os.Remove(c.GoferMountControlPath); err != nil && !os.IsNotExist(err) {
+			log.Warningf("Failed to delete gofer mount control socket %q: %v", c.GoferMountControlPath, err)
+		}
+		c.GoferMountControlPath = ""
+	}
+
 	// Delete container cgroup if any.
 	if c.CompatCgroup.Cgroup != nil {
 		if err := c.CompatCgroup.Cgroup.Uninstall(); err != nil {
@@ -1148,7 +1392,7 @@ func (c *Container) waitForStopped() error {
 // shouldCreateDeviceGofer indicates whether a device gofer connection should
 // be created.
 func shouldCreateDeviceGofer(spec *specs.Spec, conf *config.Config) bool {
-	return specutils.GPUFunctionalityRequested(spec, conf) || specutils.TPUFunctionalityRequested(spec, conf)
+	return specutils.GPUFunctionalityRequested(spec, conf) || specutils.TPUFunctionalityRequested(spec, conf) || specutils.DRMFunctionalityRequested(spec, conf) || specutils.SoundFunctionalityRequested(spec, conf) || len(conf.HostCharDevs) > 0
 }
 
 // shouldSpawnGofer indicates whether the gofer process should be spawned.
@@ -1245,6 +1489,23 @@ func (c *Container) createGoferProcess(spec *specs.Spec, conf *config.Config, bu
 	}
 	donations.DonateAndClose("mounts-fd", mountsGofer)
 
+	// The shared page cache is only used by the gofer for mounts that are not
+	// accessed with directfs.
+	if !conf.DirectFS {
+		if err := donations.OpenAndDonate("shared-page-cache-fd", conf.SharedPageCache, os.O_RDONLY|unix.O_DIRECTORY); err != nil {
+			return nil, nil, nil, fmt.Errorf("opening shared page cache directory %q: %w", conf.SharedPageCache, err)
+		}
+	}
+
+	if conf.DynamicMounts {
+		path, sockFD, err := createGoferMountControlSocket(conf.RootDir, c.ID)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("creating gofer mount control socket: %w", err)
+		}
+		c.GoferMountControlPath = path
+		donations.DonateAndClose("mount-control-fd", os.NewFile(uintptr(sockFD), "gofer mount control socket"))
+	}
+
 	// Count the number of mounts that needs an IO file.
 	ioFileCount := 0
 	for _, cfg := range c.GoferMountConfs {
@@ -1359,6 +1620,33 @@ func (c *Container) createGoferProcess(spec *specs.Spec, conf *config.Config, bu
 	return sandEnds, devSandEnd, mountsSand, nil
 }
 
+// createGoferMountControlSocket creates the listening socket on which the
+// gofer of container id accepts mounts to serve at runtime.
+func createGoferMountControlSocket(rootDir, id string) (string, int, error) {
+	name := fmt.Sprintf("runsc-%s-gofer.sock", id)
+
+	// Only use absolute paths to guarantee resolution from anywhere.
+	for _, dir := range []string{rootDir, "/var/run", "/run", "/tmp"} {
+		path := path.Join(dir, name)
+		if len(path) >= linux.UnixPathMax {
+			log.Debugf("Socket file path %q is too long", path)
+			continue
+		}
+		sock, err := unet.BindAndListen(path, true /* packet */)
+		if err != nil {
+			log.Debugf("Failed to create socket file %q: %v", path, err)
+			continue
+		}
+		fd, err := sock.Release()
+		if err != nil {
+			_ = os.Remove(path)
+			return "", -1, err
+		}
+		return path, fd, nil
+	}
+	return "", -1, fmt.Errorf("unable to find location to write socket file")
+}
+
 // changeStatus transitions from one status to another ensuring that the
 // transition is valid.
 func (c *Container) changeStatus(s Status) {
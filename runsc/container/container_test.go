@@ -3258,7 +3258,7 @@ find $dir -type l -o -type f | sort | xargs cat | md5sum`), 0755); err != nil {
 	for _, i := range images {
 		// Mount the EROFS image in the container.
 		imageFile := filepath.Join(testDir, i.name)
-		if err := c.Sandbox.Mount(c.ID, erofs.Name, imageFile, targetDir); err != nil {
+		if err := c.Sandbox.Mount(c.ID, erofs.Name, imageFile, targetDir, nil); err != nil {
 			t.Fatalf("error mounting EROFS image %q at %q, err: %v", imageFile, targetDir, err)
 		}
 
@@ -3511,7 +3511,7 @@ func TestLookupEROFS(t *testing.T) {
 		}
 
 		// Mount the EROFS image in the container.
-		if err := c.Sandbox.Mount(c.ID, erofs.Name, imageFile, targetDir); err != nil {
+		if err := c.Sandbox.Mount(c.ID, erofs.Name, imageFile, targetDir, nil); err != nil {
 			t.Fatalf("error mounting EROFS image %q at %q, err: %v", imageFile, targetDir, err)
 		}
 
@@ -3538,3 +3538,159 @@ func TestLookupEROFS(t *testing.T) {
 		}
 	}
 }
+
+// TestDynamicBindMount checks that bind mounts can be added to and removed
+// from a running container.
+func TestDynamicBindMount(t *testing.T) {
+	for name, conf := range configs(t, false /* noOverlay */) {
+		t.Run(name, func(t *testing.T) {
+			conf.DynamicMounts = true
+			spec, _ := sleepSpecConf(t)
+			_, bundleDir, cleanup, err := testutil.SetupContainer(spec, conf)
+			if err != nil {
+				t.Fatalf("error setting up container: %v", err)
+			}
+			defer cleanup()
+
+			hostDir, err := os.MkdirTemp(testutil.TmpDir(), "dynamic-mount")
+			if err != nil {
+				t.Fatalf("os.MkdirTemp() failed: %v", err)
+			}
+			defer os.RemoveAll(hostDir)
+			if err := os.Chmod(hostDir, 0755); err != nil {
+				t.Fatalf("error chmoding dir: %q, %v", hostDir, err)
+			}
+			secret := filepath.Join(hostDir, "secret")
+			if err := os.WriteFile(secret, []byte("v1"), 0644); err != nil {
+				t.Fatalf("os.WriteFile() failed: %v", err)
+			}
+
+			args := Args{
+				ID:        testutil.RandomContainerID(),
+				Spec:      spec,
+				BundleDir: bundleDir,
+			}
+			c, err := New(conf, args)
+			if err != nil {
+				t.Fatalf("error creating container: %v", err)
+			}
+			defer c.Destroy()
+			if err := c.Start(conf); err != nil {
+				t.Fatalf("error starting container: %v", err)
+			}
+
+			const dest = "/mnt/dynamic"
+			if err := c.AddBindMount(hostDir, dest, true /* readonly */); err != nil {
+				t.Fatalf("AddBindMount() failed: %v", err)
+			}
+			if err := c.AddBindMount(hostDir, dest, true /* readonly */); err == nil {
+				t.Errorf("AddBindMount() twice at the same destination succeeded")
+			}
+			if out, err := executeCombinedOutput(conf, c, nil, "/bin/cat", dest+"/secret"); err != nil {
+				t.Fatalf("exec: cat, err: %v, out: %s", err, out)
+			} else if string(out) != "v1" {
+				t.Errorf("secret: got %q, want %q", out, "v1")
+			}
+
+			// Changes on the host must be visible in the container.
+			if err := os.WriteFile(secret, []byte("v2"), 0644); err != nil {
+				t.Fatalf("os.WriteFile() failed: %v", err)
+			}
+			if out, err := executeCombinedOutput(conf, c, nil, "/bin/cat", dest+"/secret"); err != nil {
+				t.Fatalf("exec: cat, err: %v, out: %s", err, out)
+			} else if string(out) != "v2" {
+				t.Errorf("rotated secret: got %q, want %q", out, "v2")
+			}
+
+			// The mount is read-only.
+			if ws, err := execute(conf, c, "/bin/touch", dest+"/new"); err != nil {
+				t.Fatalf("exec: touch, err: %v", err)
+			} else if ws.ExitStatus() == 0 {
+				t.Errorf("touch in read-only mount succeeded")
+			}
+
+			if err := c.RemoveBindMount(dest); err != nil {
+				t.Fatalf("RemoveBindMount() failed: %v", err)
+			}
+			if ws, err := execute(conf, c, "/bin/cat", dest+"/secret"); err != nil {
+				t.Fatalf("exec: cat, err: %v", err)
+			} else if ws.ExitStatus() == 0 {
+				t.Errorf("secret is still accessible after RemoveBindMount()")
+			}
+			if err := c.RemoveBindMount(dest); err == nil {
+				t.Errorf("RemoveBindMount() twice succeeded")
+			}
+		})
+	}
+}
+
+// TestDynamicBindMountDisabled checks that bind mounts can't be added without
+// --dynamic-mounts.
+func TestDynamicBindMountDisabled(t *testing.T) {
+	spec, conf := sleepSpecConf(t)
+	_, bundleDir, cleanup, err := testutil.SetupContainer(spec, conf)
+	if err != nil {
+		t.Fatalf("error setting up container: %v", err)
+	}
+	defer cleanup()
+
+	args := Args{
+		ID:        testutil.RandomContainerID(),
+		Spec:      spec,
+		BundleDir: bundleDir,
+	}
+	c, err := New(conf, args)
+	if err != nil {
+		t.Fatalf("error creating container: %v", err)
+	}
+	defer c.Destroy()
+	if err := c.Start(conf); err != nil {
+		t.Fatalf("error starting container: %v", err)
+	}
+	if err := c.AddBindMount(testutil.TmpDir(), "/mnt/dynamic", false /* readonly */); err == nil {
+		t.Fatalf("AddBindMount() without --dynamic-mounts succeeded")
+	}
+}
+
+// TestMaskedAndReadonlyPaths checks that the masked and read-only paths from
+// the spec are enforced inside the sandbox.
+func TestMaskedAndReadonlyPaths(t *testing.T) {
+	spec, conf := sleepSpecConf(t)
+	spec.Linux = &specs.Linux{
+		MaskedPaths:   []string{"/proc/version", "/proc/sys/kernel", "/does/not/exist"},
+		ReadonlyPaths: []string{"/tmp", "/does/not/exist"},
+	}
+	_, bundleDir, cleanup, err := testutil.SetupContainer(spec, conf)
+	if err != nil {
+		t.Fatalf("error setting up container: %v", err)
+	}
+	defer cleanup()
+
+	args := Args{
+		ID:        testutil.RandomContainerID(),
+		Spec:      spec,
+		BundleDir: bundleDir,
+	}
+	c, err := New(conf, args)
+	if err != nil {
+		t.Fatalf("error creating container: %v", err)
+	}
+	defer c.Destroy()
+	if err := c.Start(conf); err != nil {
+		t.Fatalf("error starting container: %v", err)
+	}
+
+	if out, err := executeCombinedOutput(conf, c, nil, "/bin/cat", "/proc/version"); err != nil {
+		t.Fatalf("exec: cat, err: %v, out: %s", err, out)
+	} else if len(out) != 0 {
+		t.Errorf("masked /proc/version: got %q, want empty", out)
+	}
+	if out, err := executeCombinedOutput(conf, c, nil, "/bin/ls", "/proc/sys/kernel"); err != nil {
+		t.Fatalf("exec: ls, err: %v, out: %s", err, out)
+	} else if len(out) != 0 {
+		t.Errorf("masked /proc/sys/kernel: got %q, want empty", out)
+	}
+	if out, err := executeCombinedOutput(conf, c, nil, "/bin/touch", "/tmp/file"); err == nil {
+		t.Errorf("touch in read-only /tmp succeeded, out: %s", out)
+	}
+}
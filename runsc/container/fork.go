@@ -0,0 +1,89 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"fmt"
+	"os"
+
+	"gvisor.dev/gvisor/pkg/cleanup"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/runsc/config"
+)
+
+// Fork clones the running sandbox of root container c into one new sandbox
+// per ID in ids. c is checkpointed to imagePath and keeps running, then every
+// child is restored from the same image, so all children resume from the
+// state c was in at the time of the call.
+//
+// Children use c's spec and bundle, including its network configuration, so
+// the spec must not pin a network namespace that can only be used once.
+// Application memory is shared only through the image: each child loads its
+// own copy of it on restore. Placing imagePath on a memory-backed filesystem
+// keeps a single saved copy and makes restores fast.
+//
+// Only single-container sandboxes can be forked. The caller must call
+// Destroy() on the returned containers.
+func Fork(conf *config.Config, c *Container, ids []string, imagePath string, sfOpts statefile.Options, mfOpts pgalloc.SaveOpts) ([]*Container, error) {
+	if !c.IsSandboxRoot() {
+		return nil, fmt.Errorf("container %q is not the root container of its sandbox", c.ID)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("at least one child container ID must be provided")
+	}
+	containers, err := LoadSandbox(c.Saver.RootDir, c.Sandbox.ID, LoadOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("loading containers of sandbox %q: %w", c.Sandbox.ID, err)
+	}
+	if len(containers) != 1 {
+		return nil, fmt.Errorf("sandbox %q has %d containers, only single-container sandboxes can be forked", c.Sandbox.ID, len(containers))
+	}
+	if err := os.MkdirAll(imagePath, 0755); err != nil {
+		return nil, fmt.Errorf("creating fork image directory %q: %w", imagePath, err)
+	}
+
+	// The parent must keep running after the image is taken.
+	sfOpts.Resume = true
+	if err := c.Checkpoint(imagePath, false /* direct */, sfOpts, mfOpts); err != nil {
+		return nil, fmt.Errorf("checkpointing container %q: %w", c.ID, err)
+	}
+
+	cu := cleanup.Cleanup{}
+	defer cu.Clean()
+
+	var children []*Container
+	for _, id := range ids {
+		log.Infof("Forking container %q into %q", c.ID, id)
+		spec := *c.Spec
+		child, err := New(conf, Args{
+			ID:        id,
+			Spec:      &spec,
+			BundleDir: c.BundleDir,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("creating child container %q: %w", id, err)
+		}
+		cu.Add(func() { child.Destroy() })
+		children = append(children, child)
+
+		if err := child.Restore(conf, imagePath, false /* direct */); err != nil {
+			return nil, fmt.Errorf("restoring child container %q: %w", id, err)
+		}
+	}
+	cu.Release()
+	return children, nil
+}
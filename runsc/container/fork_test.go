@@ -0,0 +1,91 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"os"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/sentry/control"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/pkg/test/testutil"
+)
+
+// TestFork checks that a running sandbox can be cloned into several children
+// while the parent keeps running.
+func TestFork(t *testing.T) {
+	spec, conf := sleepSpecConf(t)
+	_, bundleDir, cleanup, err := testutil.SetupContainer(spec, conf)
+	if err != nil {
+		t.Fatalf("error setting up container: %v", err)
+	}
+	defer cleanup()
+
+	dir, err := os.MkdirTemp(testutil.TmpDir(), "fork-test")
+	if err != nil {
+		t.Fatalf("os.MkdirTemp() failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatalf("error chmoding file: %q, %v", dir, err)
+	}
+
+	args := Args{
+		ID:        testutil.RandomContainerID(),
+		Spec:      spec,
+		BundleDir: bundleDir,
+	}
+	parent, err := New(conf, args)
+	if err != nil {
+		t.Fatalf("error creating container: %v", err)
+	}
+	defer parent.Destroy()
+	if err := parent.Start(conf); err != nil {
+		t.Fatalf("error starting container: %v", err)
+	}
+
+	ids := []string{testutil.RandomContainerID(), testutil.RandomContainerID()}
+	children, err := Fork(conf, parent, ids, dir, statefile.Options{Compression: statefile.CompressionLevelNone}, pgalloc.SaveOpts{})
+	if err != nil {
+		t.Fatalf("error forking container: %v", err)
+	}
+	for _, child := range children {
+		defer child.Destroy()
+	}
+	if len(children) != len(ids) {
+		t.Fatalf("Fork() returned %d children, want %d", len(children), len(ids))
+	}
+
+	expectedPL := []*control.Process{
+		newProcessBuilder().Cmd("sleep").PID(1).Process(),
+	}
+	for _, c := range append([]*Container{parent}, children...) {
+		if err := waitForProcessList(c, expectedPL); err != nil {
+			t.Fatalf("container %q: init process not found: %v", c.ID, err)
+		}
+		execArgs := &control.ExecArgs{
+			Filename: "/bin/sh",
+			Argv:     []string{"/bin/sh", "-c", "exit 3"},
+		}
+		ws, err := c.executeSync(conf, execArgs)
+		if err != nil {
+			t.Fatalf("container %q: error executing: %v", c.ID, err)
+		}
+		if got := ws.ExitStatus(); got != 3 {
+			t.Errorf("container %q: exec exit status: got %d, want 3", c.ID, got)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"gvisor.dev/gvisor/pkg/cleanup"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/imagestore"
+	"gvisor.dev/gvisor/runsc/specutils"
+)
+
+// CheckpointManifestFileName is the name of the file in a checkpoint image
+// directory that describes the containers saved in the image.
+const CheckpointManifestFileName = "containers.json"
+
+// CheckpointManifest describes all containers of a sandbox that were saved
+// to a checkpoint image. It allows the entire container hierarchy of a pod to
+// be re-created from the image alone.
+type CheckpointManifest struct {
+	// SandboxID is the ID of the sandbox at checkpoint time, which is also
+	// the ID of its root container.
+	SandboxID string `json:"sandboxID"`
+
+	// Containers lists the containers in the sandbox. The root container is
+	// always first, followed by subcontainers in creation order.
+	Containers []CheckpointManifestEntry `json:"containers"`
+}
+
+// CheckpointManifestEntry describes a single container in a checkpoint
+// manifest.
+type CheckpointManifestEntry struct {
+	// ID is the container ID at checkpoint time.
+	ID string `json:"id"`
+
+	// BundleDir is the container bundle directory at checkpoint time.
+	BundleDir string `json:"bundleDir"`
+
+	// Spec is the OCI spec of the container.
+	Spec *specs.Spec `json:"spec"`
+}
+
+// Root returns the manifest entry of the root container.
+func (m *CheckpointManifest) Root() (*CheckpointManifestEntry, error) {
+	for i := range m.Containers {
+		if m.Containers[i].ID == m.SandboxID {
+			return &m.Containers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("checkpoint manifest has no root container for sandbox %q", m.SandboxID)
+}
+
+// Subcontainers returns the manifest entries of all subcontainers, in the
+// order in which they must be restored.
+func (m *CheckpointManifest) Subcontainers() []CheckpointManifestEntry {
+	var subs []CheckpointManifestEntry
+	for _, e := range m.Containers {
+		if e.ID != m.SandboxID {
+			subs = append(subs, e)
+		}
+	}
+	return subs
+}
+
+// newCheckpointManifest builds the manifest of the given sandbox containers.
+// Containers that were never started in the sandbox are skipped, since they
+// are not part of the saved state.
+func newCheckpointManifest(sandboxID string, containers []*Container) (*CheckpointManifest, error) {
+	sort.SliceStable(containers, func(i, j int) bool {
+		return containers[i].CreatedAt.Before(containers[j].CreatedAt)
+	})
+	m := &CheckpointManifest{SandboxID: sandboxID}
+	for _, c := range containers {
+		if c.Status == Creating {
+			continue
+		}
+		e := CheckpointManifestEntry{
+			ID:        c.ID,
+			BundleDir: c.BundleDir,
+			Spec:      c.Spec,
+		}
+		if c.ID == sandboxID {
+			m.Containers = append([]CheckpointManifestEntry{e}, m.Containers...)
+		} else {
+			m.Containers = append(m.Containers, e)
+		}
+	}
+	if _, err := m.Root(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// writeCheckpointManifest writes the manifest of all containers in sandbox
+// sandboxID to imagePath.
+func writeCheckpointManifest(rootDir, sandboxID, imagePath string) error {
+	containers, err := LoadSandbox(rootDir, sandboxID, LoadOpts{})
+	if err != nil {
+		return fmt.Errorf("loading containers of sandbox %q: %w", sandboxID, err)
+	}
+	m, err := newCheckpointManifest(sandboxID, containers)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("marshaling checkpoint manifest: %w", err)
+	}
+	if err := imagestore.WriteFile(imagePath, CheckpointManifestFileName, data); err != nil {
+		return fmt.Errorf("writing checkpoint manifest to %q: %w", imagePath, err)
+	}
+	return nil
+}
+
+// LoadCheckpointManifest reads the container manifest from the checkpoint
+// image at imagePath.
+func LoadCheckpointManifest(imagePath string) (*CheckpointManifest, error) {
+	data, err := imagestore.ReadFile(imagePath, CheckpointManifestFileName)
+	if err != nil {
+		return nil, fmt.Errorf("reading checkpoint manifest from %q: %w", imagePath, err)
+	}
+	m := &CheckpointManifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parsing checkpoint manifest from %q: %w", imagePath, err)
+	}
+	if _, err := m.Root(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// subcontainerSpec returns a copy of the subcontainer spec from e that joins
+// the sandbox with ID sandboxID.
+func (e *CheckpointManifestEntry) subcontainerSpec(sandboxID string) *specs.Spec {
+	spec := *e.Spec
+	spec.Annotations = make(map[string]string, len(e.Spec.Annotations))
+	for k, v := range e.Spec.Annotations {
+		spec.Annotations[k] = v
+	}
+	if _, ok := spec.Annotations[specutils.CRIOSandboxIDAnnotation]; ok {
+		spec.Annotations[specutils.CRIOSandboxIDAnnotation] = sandboxID
+	} else {
+		spec.Annotations[specutils.ContainerdSandboxIDAnnotation] = sandboxID
+	}
+	return &spec
+}
+
+// RestoreSubcontainers re-creates the subcontainers recorded in the checkpoint
+// manifest inside root's sandbox and restores them from imagePath. root must
+// be the root container of the sandbox and must already have been restored
+// from the same image. The caller must call Destroy() on the returned
+// containers.
+func RestoreSubcontainers(conf *config.Config, root *Container, m *CheckpointManifest, imagePath string, direct bool) ([]*Container, error) {
+	if !root.IsSandboxRoot() {
+		return nil, fmt.Errorf("container %q is not the root container of its sandbox", root.ID)
+	}
+
+	cu := cleanup.Cleanup{}
+	defer cu.Clean()
+
+	var containers []*Container
+	for _, e := range m.Subcontainers() {
+		log.Infof("Restoring subcontainer %q from checkpoint manifest, sandbox: %q", e.ID, root.ID)
+		c, err := New(conf, Args{
+			ID:        e.ID,
+			Spec:      e.subcontainerSpec(root.ID),
+			BundleDir: e.BundleDir,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("creating subcontainer %q: %w", e.ID, err)
+		}
+		cu.Add(func() { c.Destroy() })
+		containers = append(containers, c)
+
+		if err := c.Restore(conf, imagePath, direct); err != nil {
+			return nil, fmt.Errorf("restoring subcontainer %q: %w", e.ID, err)
+		}
+	}
+	cu.Release()
+	return containers, nil
+}
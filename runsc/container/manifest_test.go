@@ -0,0 +1,116 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"gvisor.dev/gvisor/runsc/specutils"
+)
+
+func TestNewCheckpointManifest(t *testing.T) {
+	now := time.Now()
+	containers := []*Container{
+		{ID: "sub2", BundleDir: "/b/sub2", Status: Running, CreatedAt: now.Add(2 * time.Second), Spec: &specs.Spec{}},
+		{ID: "creating", Status: Creating, CreatedAt: now.Add(3 * time.Second), Spec: &specs.Spec{}},
+		{ID: "sub1", BundleDir: "/b/sub1", Status: Stopped, CreatedAt: now.Add(time.Second), Spec: &specs.Spec{}},
+		{ID: "root", BundleDir: "/b/root", Status: Running, CreatedAt: now, Spec: &specs.Spec{}},
+	}
+	m, err := newCheckpointManifest("root", containers)
+	if err != nil {
+		t.Fatalf("newCheckpointManifest(): %v", err)
+	}
+	var got []string
+	for _, e := range m.Containers {
+		got = append(got, e.ID)
+	}
+	want := []string{"root", "sub1", "sub2"}
+	if len(got) != len(want) {
+		t.Fatalf("manifest containers: got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("manifest containers: got %v, want %v", got, want)
+		}
+	}
+	if subs := m.Subcontainers(); len(subs) != 2 || subs[0].ID != "sub1" || subs[1].ID != "sub2" {
+		t.Errorf("Subcontainers(): got %+v, want sub1 and sub2", subs)
+	}
+
+	if _, err := newCheckpointManifest("missing", containers); err == nil {
+		t.Errorf("newCheckpointManifest() without root container succeeded")
+	}
+}
+
+func TestLoadCheckpointManifest(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadCheckpointManifest(dir); err == nil {
+		t.Fatalf("LoadCheckpointManifest() with no manifest succeeded")
+	}
+
+	want := CheckpointManifest{
+		SandboxID: "root",
+		Containers: []CheckpointManifestEntry{
+			{ID: "root", BundleDir: "/b/root", Spec: &specs.Spec{Hostname: "root"}},
+			{ID: "sub", BundleDir: "/b/sub", Spec: &specs.Spec{Hostname: "sub"}},
+		},
+	}
+	data, err := json.Marshal(&want)
+	if err != nil {
+		t.Fatalf("json.Marshal(): %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, CheckpointManifestFileName), data, 0644); err != nil {
+		t.Fatalf("os.WriteFile(): %v", err)
+	}
+	got, err := LoadCheckpointManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadCheckpointManifest(): %v", err)
+	}
+	root, err := got.Root()
+	if err != nil {
+		t.Fatalf("Root(): %v", err)
+	}
+	if root.BundleDir != "/b/root" || root.Spec.Hostname != "root" {
+		t.Errorf("Root(): got %+v, want root entry", root)
+	}
+	if subs := got.Subcontainers(); len(subs) != 1 || subs[0].Spec.Hostname != "sub" {
+		t.Errorf("Subcontainers(): got %+v, want sub entry", subs)
+	}
+}
+
+func TestCheckpointManifestSubcontainerSpec(t *testing.T) {
+	for _, annotation := range []string{specutils.ContainerdSandboxIDAnnotation, specutils.CRIOSandboxIDAnnotation} {
+		t.Run(annotation, func(t *testing.T) {
+			e := CheckpointManifestEntry{
+				ID: "sub",
+				Spec: &specs.Spec{
+					Annotations: map[string]string{annotation: "old"},
+				},
+			}
+			spec := e.subcontainerSpec("new")
+			if id, ok := specutils.SandboxID(spec); !ok || id != "new" {
+				t.Errorf("SandboxID(): got %q, %t, want %q", id, ok, "new")
+			}
+			if got := e.Spec.Annotations[annotation]; got != "old" {
+				t.Errorf("manifest spec was modified, annotation: %q", got)
+			}
+		})
+	}
+}
@@ -2766,6 +2766,20 @@ func testMultiContainerCheckpointRestore(t *testing.T, conf *config.Config, comp
 	}
 	defer os.RemoveAll(dir)
 
+	// The checkpoint manifest must describe every container in the sandbox.
+	manifest, err := LoadCheckpointManifest(dir)
+	if err != nil {
+		t.Fatalf("error loading checkpoint manifest: %v", err)
+	}
+	if len(manifest.Containers) != len(ids) {
+		t.Fatalf("checkpoint manifest has %d containers, want %d", len(manifest.Containers), len(ids))
+	}
+	for i, e := range manifest.Containers {
+		if e.ID != ids[i] {
+			t.Errorf("checkpoint manifest container %d: got %q, want %q", i, e.ID, ids[i])
+		}
+	}
+
 	lastNum, err := readOutputNum(outputPath, -1)
 	if err != nil {
 		t.Fatalf("error with outputFile: %v", err)
@@ -0,0 +1,159 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gvisor.dev/gvisor/pkg/cleanup"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/runsc/config"
+	"gvisor.dev/gvisor/runsc/version"
+)
+
+// ZygoteInfoFileName is the name of the file in a zygote image directory that
+// describes how the zygote was created.
+const ZygoteInfoFileName = "zygote.json"
+
+// ZygoteInfo describes a zygote image: a checkpoint of a freshly started
+// sandbox whose init process idles until the sandbox is torn down. New
+// sandboxes restore from the zygote instead of booting from scratch, and
+// then execute their own workload in it.
+type ZygoteInfo struct {
+	// Version is the runsc version that created the zygote.
+	Version string `json:"version"`
+
+	// Platform is the platform the zygote was created with.
+	Platform string `json:"platform"`
+
+	// Network is the network mode the zygote was created with.
+	Network string `json:"network"`
+}
+
+func newZygoteInfo(conf *config.Config) *ZygoteInfo {
+	return &ZygoteInfo{
+		Version:  version.Version(),
+		Platform: conf.Platform,
+		Network:  conf.Network.String(),
+	}
+}
+
+// check returns an error if sandboxes created with conf cannot be restored
+// from the zygote.
+func (z *ZygoteInfo) check(conf *config.Config) error {
+	want := newZygoteInfo(conf)
+	if z.Version != want.Version {
+		return fmt.Errorf("zygote was created by runsc version %q, current version is %q", z.Version, want.Version)
+	}
+	if z.Platform != want.Platform {
+		return fmt.Errorf("zygote was created with platform %q, current platform is %q", z.Platform, want.Platform)
+	}
+	if z.Network != want.Network {
+		return fmt.Errorf("zygote was created with network %q, current network is %q", z.Network, want.Network)
+	}
+	return nil
+}
+
+// LoadZygoteInfo reads the zygote description from the zygote image at
+// imagePath.
+func LoadZygoteInfo(imagePath string) (*ZygoteInfo, error) {
+	path := filepath.Join(imagePath, ZygoteInfoFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading zygote info %q: %w", path, err)
+	}
+	z := &ZygoteInfo{}
+	if err := json.Unmarshal(data, z); err != nil {
+		return nil, fmt.Errorf("parsing zygote info %q: %w", path, err)
+	}
+	return z, nil
+}
+
+// CreateZygote boots a sandbox for the root container described by args,
+// checkpoints it to imagePath right after its init process started and
+// destroys it. The init process of args.Spec must not exit on its own, since
+// every sandbox restored from the zygote inherits it.
+func CreateZygote(conf *config.Config, args Args, imagePath string, sfOpts statefile.Options, mfOpts pgalloc.SaveOpts) error {
+	if !isRoot(args.Spec) {
+		return fmt.Errorf("zygote must be created from a root container spec")
+	}
+	if err := os.MkdirAll(imagePath, 0755); err != nil {
+		return fmt.Errorf("creating zygote image directory %q: %w", imagePath, err)
+	}
+
+	// The sandbox must not outlive the zygote creation, regardless of what
+	// the caller asked for.
+	args.Attached = true
+	c, err := New(conf, args)
+	if err != nil {
+		return fmt.Errorf("creating zygote container: %w", err)
+	}
+	defer c.Destroy()
+
+	if err := c.Start(conf); err != nil {
+		return fmt.Errorf("starting zygote container: %w", err)
+	}
+	sfOpts.Resume = false
+	if err := c.Checkpoint(imagePath, false /* direct */, sfOpts, mfOpts); err != nil {
+		return fmt.Errorf("checkpointing zygote container: %w", err)
+	}
+
+	data, err := json.Marshal(newZygoteInfo(conf))
+	if err != nil {
+		return fmt.Errorf("marshaling zygote info: %w", err)
+	}
+	path := filepath.Join(imagePath, ZygoteInfoFileName)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing zygote info %q: %w", path, err)
+	}
+	log.Infof("Zygote created from container %q at %q", c.ID, imagePath)
+	return nil
+}
+
+// NewFromZygote creates a new root container described by args, and restores
+// its sandbox from the zygote image at imagePath. The container's init
+// process is the zygote's, so the caller is expected to execute the
+// container's workload with Execute. The caller must call Destroy() on the
+// container.
+func NewFromZygote(conf *config.Config, args Args, imagePath string, direct bool) (*Container, error) {
+	if !isRoot(args.Spec) {
+		return nil, fmt.Errorf("only root containers can be restored from a zygote")
+	}
+	z, err := LoadZygoteInfo(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := z.check(conf); err != nil {
+		return nil, err
+	}
+
+	c, err := New(conf, args)
+	if err != nil {
+		return nil, err
+	}
+	cu := cleanup.Make(func() { c.Destroy() })
+	defer cu.Clean()
+
+	if err := c.Restore(conf, imagePath, direct); err != nil {
+		return nil, fmt.Errorf("restoring from zygote %q: %w", imagePath, err)
+	}
+	cu.Release()
+	return c, nil
+}
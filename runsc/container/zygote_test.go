@@ -0,0 +1,134 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gvisor.dev/gvisor/pkg/sentry/control"
+	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
+	"gvisor.dev/gvisor/pkg/state/statefile"
+	"gvisor.dev/gvisor/pkg/test/testutil"
+	"gvisor.dev/gvisor/runsc/config"
+)
+
+func TestZygoteInfoCheck(t *testing.T) {
+	conf := testutil.TestConfig(t)
+	if err := newZygoteInfo(conf).check(conf); err != nil {
+		t.Fatalf("check() with the same config failed: %v", err)
+	}
+
+	other := *conf
+	other.Platform = "other"
+	if err := newZygoteInfo(&other).check(conf); err == nil {
+		t.Errorf("check() with a different platform succeeded")
+	}
+
+	other = *conf
+	if other.Network == config.NetworkNone {
+		other.Network = config.NetworkHost
+	} else {
+		other.Network = config.NetworkNone
+	}
+	if err := newZygoteInfo(&other).check(conf); err == nil {
+		t.Errorf("check() with a different network succeeded")
+	}
+
+	z := newZygoteInfo(conf)
+	z.Version = "other"
+	if err := z.check(conf); err == nil {
+		t.Errorf("check() with a different version succeeded")
+	}
+}
+
+// TestZygote checks that containers restored from a zygote keep the zygote's
+// init process and can execute their own processes.
+func TestZygote(t *testing.T) {
+	spec, conf := sleepSpecConf(t)
+	_, bundleDir, cleanup, err := testutil.SetupContainer(spec, conf)
+	if err != nil {
+		t.Fatalf("error setting up container: %v", err)
+	}
+	defer cleanup()
+
+	dir, err := os.MkdirTemp(testutil.TmpDir(), "zygote-test")
+	if err != nil {
+		t.Fatalf("os.MkdirTemp() failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatalf("error chmoding file: %q, %v", dir, err)
+	}
+
+	zygoteArgs := Args{
+		ID:        testutil.RandomContainerID(),
+		Spec:      spec,
+		BundleDir: bundleDir,
+	}
+	if err := CreateZygote(conf, zygoteArgs, dir, statefile.Options{Compression: statefile.CompressionLevelNone}, pgalloc.SaveOpts{}); err != nil {
+		t.Fatalf("error creating zygote: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ZygoteInfoFileName)); err != nil {
+		t.Fatalf("zygote info missing: %v", err)
+	}
+
+	// Start two instances from the same zygote.
+	for i := 0; i < 2; i++ {
+		args := Args{
+			ID:        testutil.RandomContainerID(),
+			Spec:      spec,
+			BundleDir: bundleDir,
+		}
+		cont, err := NewFromZygote(conf, args, dir, false /* direct */)
+		if err != nil {
+			t.Fatalf("error restoring from zygote: %v", err)
+		}
+		defer cont.Destroy()
+
+		expectedPL := []*control.Process{
+			newProcessBuilder().Cmd("sleep").PID(1).Process(),
+		}
+		if err := waitForProcessList(cont, expectedPL); err != nil {
+			t.Fatalf("zygote init process not found: %v", err)
+		}
+
+		execArgs := &control.ExecArgs{
+			Filename: "/bin/sh",
+			Argv:     []string{"/bin/sh", "-c", "exit 3"},
+		}
+		ws, err := cont.executeSync(conf, execArgs)
+		if err != nil {
+			t.Fatalf("error executing in container: %v", err)
+		}
+		if got := ws.ExitStatus(); got != 3 {
+			t.Errorf("exec exit status: got %d, want 3", got)
+		}
+	}
+}
+
+// TestZygoteMissingInfo checks that containers can't be restored from a
+// directory without a zygote.
+func TestZygoteMissingInfo(t *testing.T) {
+	spec, conf := sleepSpecConf(t)
+	args := Args{
+		ID:   testutil.RandomContainerID(),
+		Spec: spec,
+	}
+	if _, err := NewFromZygote(conf, args, t.TempDir(), false /* direct */); err == nil {
+		t.Fatalf("NewFromZygote() without zygote info succeeded")
+	}
+}
@@ -215,13 +215,37 @@ var udsCreateSyscalls = seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule
 	unix.SYS_LISTEN:  seccomp.MatchAll{},
 })
 
+// mountControlSyscalls are used to accept requests to serve mounts added at
+// runtime, see fsgofer.LisafsServer.ServeMountRequests.
+var mountControlSyscalls = seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
+	unix.SYS_ACCEPT4: seccomp.PerArg{
+		seccomp.AnyValue{},
+		seccomp.AnyValue{},
+		seccomp.AnyValue{},
+		seccomp.EqualTo(0),
+	},
+})
+
 var lisafsFilters = seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
 	unix.SYS_FALLOCATE: seccomp.PerArg{
 		seccomp.AnyValue{},
 		seccomp.EqualTo(0),
 	},
-	unix.SYS_FCHMODAT:   seccomp.MatchAll{},
-	unix.SYS_FGETXATTR:  seccomp.MatchAll{},
+	unix.SYS_FCHMODAT:  seccomp.MatchAll{},
+	unix.SYS_FGETXATTR: seccomp.MatchAll{},
+	unix.SYS_FLISTXATTR: seccomp.PerArg{
+		seccomp.NonNegativeFD{},
+		seccomp.AnyValue{},
+		seccomp.EqualTo(linux.XATTR_LIST_MAX),
+	},
+	unix.SYS_FREMOVEXATTR: seccomp.MatchAll{},
+	unix.SYS_FSETXATTR: seccomp.PerArg{
+		seccomp.NonNegativeFD{},
+		seccomp.AnyValue{},
+		seccomp.AnyValue{},
+		seccomp.LessThanOrEqual(linux.XATTR_SIZE_MAX),
+		seccomp.MaskedEqual(^uintptr(unix.XATTR_CREATE|unix.XATTR_REPLACE), 0),
+	},
 	unix.SYS_FSTATFS:    seccomp.MatchAll{},
 	unix.SYS_GETDENTS64: seccomp.MatchAll{},
 	unix.SYS_LINKAT: seccomp.PerArg{
@@ -231,8 +255,20 @@ var lisafsFilters = seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
 		seccomp.AnyValue{},
 		seccomp.EqualTo(0),
 	},
-	unix.SYS_MKDIRAT:    seccomp.MatchAll{},
-	unix.SYS_MKNODAT:    seccomp.MatchAll{},
+	unix.SYS_MKDIRAT: seccomp.MatchAll{},
+	unix.SYS_MKNODAT: seccomp.MatchAll{},
+	unix.SYS_NAME_TO_HANDLE_AT: seccomp.PerArg{
+		seccomp.NonNegativeFD{},
+		seccomp.AnyValue{},
+		seccomp.AnyValue{},
+		seccomp.AnyValue{},
+		seccomp.EqualTo(unix.AT_EMPTY_PATH),
+	},
+	unix.SYS_OPEN_BY_HANDLE_AT: seccomp.PerArg{
+		seccomp.NonNegativeFD{},
+		seccomp.AnyValue{},
+		seccomp.EqualTo(unix.O_PATH | unix.O_CLOEXEC),
+	},
 	unix.SYS_READLINKAT: seccomp.MatchAll{},
 	unix.SYS_RENAMEAT:   seccomp.MatchAll{},
 	unix.SYS_SYMLINKAT:  seccomp.MatchAll{},
@@ -240,3 +276,13 @@ var lisafsFilters = seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
 	unix.SYS_UNLINKAT:   seccomp.MatchAll{},
 	unix.SYS_UTIMENSAT:  seccomp.MatchAll{},
 })
+
+var sharedPageCacheFilters = seccomp.MakeSyscallRules(map[uintptr]seccomp.SyscallRule{
+	unix.SYS_LINKAT: seccomp.PerArg{
+		seccomp.NonNegativeFD{},
+		seccomp.AnyValue{},
+		seccomp.NonNegativeFD{},
+		seccomp.AnyValue{},
+		seccomp.EqualTo(unix.AT_SYMLINK_FOLLOW),
+	},
+})
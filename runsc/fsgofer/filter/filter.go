@@ -28,6 +28,8 @@ type Options struct {
 	UDSCreateEnabled bool
 	ProfileEnabled   bool
 	DirectFS         bool
+	SharedPageCache  bool
+	MountControl     bool
 }
 
 // Install installs seccomp filters.
@@ -50,6 +52,10 @@ func Install(opt Options) error {
 		}
 	}
 
+	if opt.MountControl {
+		s.Merge(mountControlSyscalls)
+	}
+
 	// Set of additional filters used by -race and -msan. Returns empty
 	// when not enabled.
 	s.Merge(instrumentationFilters())
@@ -57,6 +63,9 @@ func Install(opt Options) error {
 	// When DirectFS is not enabled, filters for LisaFS are installed.
 	if !opt.DirectFS {
 		s.Merge(lisafsFilters)
+		if opt.SharedPageCache {
+			s.Merge(sharedPageCacheFilters)
+		}
 	}
 
 	return seccomp.Install(s, seccomp.DenyNewExecMappings, seccomp.DefaultProgramOptions())
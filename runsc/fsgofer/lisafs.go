@@ -24,6 +24,7 @@ import (
 	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 
 	"golang.org/x/sys/unix"
@@ -64,6 +65,14 @@ type Config struct {
 	// DonateMountPointFD indicates whether a host FD to the mount point should
 	// be donated to the client on Mount RPC.
 	DonateMountPointFD bool
+
+	// CacheReadonly indicates whether the server may cache state of readonly
+	// mounts. See lisafs.ServerOpts.CacheReadonly.
+	CacheReadonly bool
+
+	// SharedPageCache, if not nil, is used for host FDs donated for regular
+	// files opened read-only on readonly mounts.
+	SharedPageCache *SharedPageCache
 }
 
 var procSelfFD *rwfd.FD
@@ -83,6 +92,17 @@ func OpenProcSelfFD(path string) error {
 type LisafsServer struct {
 	lisafs.Server
 	config Config
+
+	// dynamicMountsMu protects dynamicMounts and nextDynamicMount.
+	dynamicMountsMu sync.Mutex
+
+	// dynamicMounts maps the mount paths of connections serving mounts added
+	// at runtime to the host FDs of their roots. See ServeMountRequests.
+	dynamicMounts map[string]int
+
+	// nextDynamicMount is used to generate unique mount paths for mounts
+	// added at runtime.
+	nextDynamicMount int
 }
 
 var _ lisafs.ServerImpl = (*LisafsServer)(nil)
@@ -94,6 +114,7 @@ func NewLisafsServer(config Config) *LisafsServer {
 		WalkStatSupported: true,
 		SetAttrOnDeleted:  true,
 		AllocateOnDeleted: true,
+		CacheReadonly:     config.CacheReadonly,
 	})
 	return s
 }
@@ -101,9 +122,17 @@ func NewLisafsServer(config Config) *LisafsServer {
 // Mount implements lisafs.ServerImpl.Mount.
 func (s *LisafsServer) Mount(c *lisafs.Connection, mountNode *lisafs.Node) (*lisafs.ControlFD, linux.Statx, int, error) {
 	mountPath := mountNode.FilePath()
-	rootHostFD, err := tryOpen(func(flags int) (int, error) {
+	open := func(flags int) (int, error) {
 		return unix.Open(mountPath, flags, 0)
-	})
+	}
+	if hostFD, ok := s.takeDynamicMount(mountPath); ok {
+		// The mount was added at runtime, reopen the donated host FD.
+		defer unix.Close(hostFD)
+		open = func(flags int) (int, error) {
+			return unix.Openat(int(procSelfFD.FD()), strconv.Itoa(hostFD), flags&^unix.O_NOFOLLOW, 0)
+		}
+	}
+	rootHostFD, err := tryOpen(open)
 	if err != nil {
 		return nil, linux.Statx{}, -1, err
 	}
@@ -148,7 +177,7 @@ func (s *LisafsServer) MaxMessageSize() uint32 {
 
 // SupportedMessages implements lisafs.ServerImpl.SupportedMessages.
 func (s *LisafsServer) SupportedMessages() []lisafs.MID {
-	// Note that Flush, FListXattr and FRemoveXattr are not supported.
+	// Note that Flush is not supported.
 	return []lisafs.MID{
 		lisafs.Mount,
 		lisafs.Channel,
@@ -175,9 +204,15 @@ func (s *LisafsServer) SupportedMessages() []lisafs.MID {
 		lisafs.Getdents64,
 		lisafs.FGetXattr,
 		lisafs.FSetXattr,
+		lisafs.FListXattr,
+		lisafs.FRemoveXattr,
 		lisafs.BindAt,
 		lisafs.Listen,
 		lisafs.Accept,
+		lisafs.InvalidateCache,
+		lisafs.ReadFile,
+		lisafs.NameToHandle,
+		lisafs.OpenByHandle,
 	}
 }
 
@@ -517,7 +552,15 @@ func (fd *controlFDLisa) Open(flags uint32) (*lisafs.OpenFD, int, error) {
 	switch {
 	case ftype == unix.S_IFREG:
 		// Best effort to donate file to the Sentry (for performance only).
-		hostFDToDonate, _ = unix.Dup(openHostFD)
+		if cache := server.config.SharedPageCache; cache != nil && fd.Conn().Readonly() && flags&unix.O_ACCMODE == unix.O_RDONLY {
+			hostFDToDonate, err = cache.Open(openHostFD)
+			if err != nil {
+				log.Debugf("Failed to open %q in the shared page cache: %v", fd.ControlFD.Node().FilePath(), err)
+				hostFDToDonate, _ = unix.Dup(openHostFD)
+			}
+		} else {
+			hostFDToDonate, _ = unix.Dup(openHostFD)
+		}
 
 	case ftype == unix.S_IFIFO,
 		ftype == unix.S_IFCHR,
@@ -773,6 +816,72 @@ func (fd *controlFDLisa) Readlink(getLinkBuf func(uint32) []byte) (uint16, error
 	return 0, unix.ENOMEM
 }
 
+// NameToHandle implements lisafs.ControlFDImpl.NameToHandle.
+func (fd *controlFDLisa) NameToHandle() (lisafs.FileHandle, error) {
+	handle, _, err := unix.NameToHandleAt(fd.hostFD, "", unix.AT_EMPTY_PATH)
+	if err != nil {
+		return lisafs.FileHandle{}, err
+	}
+	b := handle.Bytes()
+	if len(b) > lisafs.MaxHandleSize {
+		return lisafs.FileHandle{}, unix.EOVERFLOW
+	}
+	h := lisafs.FileHandle{
+		Type: handle.Type(),
+		Size: uint32(len(b)),
+	}
+	copy(h.Bytes[:], b)
+	return h, nil
+}
+
+// OpenByHandle implements lisafs.ControlFDImpl.OpenByHandle.
+func (fd *controlFDLisa) OpenByHandle(h lisafs.FileHandle) (string, error) {
+	handle := unix.NewFileHandle(h.Type, h.Bytes[:h.Size])
+	hostFD, err := unix.OpenByHandleAt(fd.hostFD, handle, unix.O_PATH|unix.O_CLOEXEC)
+	if err != nil {
+		return "", err
+	}
+	defer unix.Close(hostFD)
+
+	// The handle may identify any file on the host filesystem. Only reveal
+	// files that are reachable from this directory.
+	dirPath, err := hostFDPath(fd.hostFD)
+	if err != nil {
+		return "", err
+	}
+	filePath, err := hostFDPath(hostFD)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(filePath, " (deleted)") {
+		return "", unix.ESTALE
+	}
+	rel, err := filepath.Rel(dirPath, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", unix.ESTALE
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return rel, nil
+}
+
+// hostFDPath returns the path of the file represented by hostFD, as reported
+// by /proc/self/fd.
+func hostFDPath(hostFD int) (string, error) {
+	for linkLen := 128; linkLen < math.MaxUint16; linkLen *= 2 {
+		b := make([]byte, linkLen)
+		n, err := unix.Readlinkat(int(procSelfFD.FD()), strconv.Itoa(hostFD), b)
+		if err != nil {
+			return "", err
+		}
+		if n < linkLen {
+			return string(b[:n]), nil
+		}
+	}
+	return "", unix.ENAMETOOLONG
+}
+
 func isSockTypeSupported(sockType uint32) bool {
 	switch sockType {
 	case unix.SOCK_STREAM, unix.SOCK_DGRAM, unix.SOCK_SEQPACKET:
@@ -922,6 +1031,9 @@ func (fd *controlFDLisa) Renamed() {
 
 // GetXattr implements lisafs.ControlFDImpl.GetXattr.
 func (fd *controlFDLisa) GetXattr(name string, size uint32, getValueBuf func(uint32) []byte) (uint16, error) {
+	if !isXattrAllowed(name) {
+		return 0, unix.EOPNOTSUPP
+	}
 	data := getValueBuf(size)
 	xattrSize, err := unix.Fgetxattr(fd.hostFD, name, data)
 	return uint16(xattrSize), err
@@ -929,17 +1041,51 @@ func (fd *controlFDLisa) GetXattr(name string, size uint32, getValueBuf func(uin
 
 // SetXattr implements lisafs.ControlFDImpl.SetXattr.
 func (fd *controlFDLisa) SetXattr(name string, value string, flags uint32) error {
-	return unix.EOPNOTSUPP
+	if !isXattrAllowed(name) {
+		return unix.EOPNOTSUPP
+	}
+	return unix.Fsetxattr(fd.hostFD, name, []byte(value), int(flags))
 }
 
 // ListXattr implements lisafs.ControlFDImpl.ListXattr.
 func (fd *controlFDLisa) ListXattr(size uint64) (lisafs.StringArray, error) {
-	return nil, unix.EOPNOTSUPP
+	// Always fetch the whole list: names that are filtered out below still
+	// count towards the host's size limit, so size is checked by the client
+	// against the filtered list instead.
+	buf := make([]byte, linux.XATTR_LIST_MAX)
+	n, err := unix.Flistxattr(fd.hostFD, buf)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	var names lisafs.StringArray
+	for _, name := range strings.Split(strings.TrimSuffix(string(buf[:n]), "\x00"), "\x00") {
+		if isXattrAllowed(name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
 }
 
 // RemoveXattr implements lisafs.ControlFDImpl.RemoveXattr.
 func (fd *controlFDLisa) RemoveXattr(name string) error {
-	return unix.EOPNOTSUPP
+	if !isXattrAllowed(name) {
+		return unix.EOPNOTSUPP
+	}
+	return unix.Fremovexattr(fd.hostFD, name)
+}
+
+// isXattrAllowed returns true if clients may access the extended attribute
+// with the given name. Only the "user" namespace and POSIX ACLs are exposed;
+// the "security", "trusted" and remaining "system" namespaces can change how
+// the host kernel treats the file (e.g. LSM labels and file capabilities), so
+// they are never reachable from the sandbox.
+func isXattrAllowed(name string) bool {
+	return strings.HasPrefix(name, linux.XATTR_USER_PREFIX) ||
+		name == linux.XATTR_NAME_POSIX_ACL_ACCESS ||
+		name == linux.XATTR_NAME_POSIX_ACL_DEFAULT
 }
 
 // openFDLisa implements lisafs.OpenFDImpl.
@@ -15,11 +15,18 @@
 package lisafs_test
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/context"
 	"gvisor.dev/gvisor/pkg/lisafs"
 	"gvisor.dev/gvisor/pkg/lisafs/testsuite"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/unet"
 	"gvisor.dev/gvisor/runsc/fsgofer"
 )
 
@@ -61,3 +68,167 @@ func (tester) BindSupported() bool {
 func TestFSGofer(t *testing.T) {
 	testsuite.RunAllLocalFSTests(t, tester{})
 }
+
+func TestSharedPageCache(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	if err := os.Mkdir(cacheDir, 0755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	dirFD, err := unix.Open(cacheDir, unix.O_RDONLY|unix.O_DIRECTORY, 0)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer unix.Close(dirFD)
+	cache := fsgofer.NewSharedPageCache(dirFD)
+
+	data := []byte("shared page cache")
+	path := filepath.Join(dir, "file")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	hostFD, err := unix.Open(path, unix.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer unix.Close(hostFD)
+
+	var stats [2]unix.Stat_t
+	for i := range stats {
+		fd, err := cache.Open(hostFD)
+		if err == unix.EOPNOTSUPP {
+			t.Skipf("O_TMPFILE is not supported in %q", cacheDir)
+		}
+		if err != nil {
+			t.Fatalf("SharedPageCache.Open failed: %v", err)
+		}
+		defer unix.Close(fd)
+		buf := make([]byte, 2*len(data))
+		if n, err := unix.Pread(fd, buf, 0); err != nil {
+			t.Errorf("Pread failed: %v", err)
+		} else if !bytes.Equal(buf[:n], data) {
+			t.Errorf("cached file contents differ: want = %q, got = %q", data, buf[:n])
+		}
+		if err := unix.Fstat(fd, &stats[i]); err != nil {
+			t.Fatalf("Fstat failed: %v", err)
+		}
+	}
+	// The second Open must return the copy created by the first.
+	if stats[0].Dev != stats[1].Dev || stats[0].Ino != stats[1].Ino {
+		t.Errorf("SharedPageCache.Open returned different files: %+v, %+v", stats[0], stats[1])
+	}
+}
+
+func TestMountRequest(t *testing.T) {
+	dir := t.TempDir()
+	mountDir := filepath.Join(dir, "mount")
+	if err := os.Mkdir(mountDir, 0755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(mountDir, "file"), []byte("data"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	// Use a short path for the control socket, bind(2) fails if the path is
+	// too long.
+	controlDir, err := os.MkdirTemp("/tmp", "mnt-ctl")
+	if err != nil {
+		t.Fatalf("MkdirTemp failed: %v", err)
+	}
+	defer os.RemoveAll(controlDir)
+	controlPath := filepath.Join(controlDir, "sock")
+	control, err := unet.BindAndListen(controlPath, true /* packet */)
+	if err != nil {
+		t.Fatalf("BindAndListen failed: %v", err)
+	}
+
+	server := fsgofer.NewLisafsServer(fsgofer.Config{})
+	done := make(chan struct{})
+	go func() {
+		server.ServeMountRequests(control)
+		close(done)
+	}()
+
+	hostFD, err := unix.Open(mountDir, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer unix.Close(hostFD)
+	socks, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("Socketpair failed: %v", err)
+	}
+	defer unix.Close(socks[1])
+	if err := fsgofer.RequestMount(controlPath, hostFD, socks[1], true /* readonly */); err != nil {
+		t.Fatalf("RequestMount failed: %v", err)
+	}
+
+	sock, err := unet.NewSocket(socks[0])
+	if err != nil {
+		t.Fatalf("NewSocket failed: %v", err)
+	}
+	client, root, _, err := lisafs.NewClient(sock)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	ctx := context.Background()
+	rootFD := client.NewFD(root.ControlFD)
+	if root.Stat.Mode&linux.S_IFMT != linux.S_IFDIR {
+		t.Errorf("mount root mode: got %#o, want directory", root.Stat.Mode)
+	}
+	file, err := rootFD.Walk(ctx, "file")
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	if got := file.Stat.Size; got != 4 {
+		t.Errorf("file size: got %d, want 4", got)
+	}
+	fileFD := client.NewFD(file.ControlFD)
+	fileFD.Close(ctx, false /* flush */)
+	rootFD.Close(ctx, false /* flush */)
+	client.Close()
+
+	control.Close()
+	<-done
+	server.Wait()
+	server.Destroy()
+}
+
+func TestXattrAllowlist(t *testing.T) {
+	testsuite.RunTest(t, tester{}, "XattrAllowlist", func(ctx context.Context, t *testing.T, _ testsuite.Tester, root lisafs.ClientFD) {
+		if err := root.SetXattr(ctx, "user.test", "value", 0); err == unix.EOPNOTSUPP {
+			t.Skipf("user xattrs are not supported by the host filesystem")
+		} else if err != nil {
+			t.Fatalf("SetXattr(user.test) failed: %v", err)
+		}
+		if got, err := root.GetXattr(ctx, "user.test", 64); err != nil || got != "value" {
+			t.Errorf("GetXattr(user.test): got (%q, %v), want (%q, nil)", got, err, "value")
+		}
+
+		for _, name := range []string{"security.test", "trusted.test", "system.test"} {
+			if err := root.SetXattr(ctx, name, "value", 0); err != unix.EOPNOTSUPP {
+				t.Errorf("SetXattr(%s): got %v, want %v", name, err, unix.EOPNOTSUPP)
+			}
+			if _, err := root.GetXattr(ctx, name, 64); err != unix.EOPNOTSUPP {
+				t.Errorf("GetXattr(%s): got %v, want %v", name, err, unix.EOPNOTSUPP)
+			}
+			if err := root.RemoveXattr(ctx, name); err != unix.EOPNOTSUPP {
+				t.Errorf("RemoveXattr(%s): got %v, want %v", name, err, unix.EOPNOTSUPP)
+			}
+		}
+
+		// The host may attach attributes of its own (e.g. security.selinux),
+		// which must not be listed.
+		names, err := root.ListXattr(ctx, 0)
+		if err != nil {
+			t.Fatalf("ListXattr failed: %v", err)
+		}
+		if len(names) != 1 || names[0] != "user.test" {
+			t.Errorf("ListXattr: got %q, want [user.test]", names)
+		}
+
+		if err := root.RemoveXattr(ctx, "user.test"); err != nil {
+			t.Errorf("RemoveXattr(user.test) failed: %v", err)
+		}
+	}, t.TempDir())
+}
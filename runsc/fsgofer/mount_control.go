@@ -0,0 +1,224 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsgofer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/abi/linux"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/unet"
+)
+
+// maxMountControlMessageSize is the maximum size of a message exchanged on
+// the mount control socket.
+const maxMountControlMessageSize = 4096
+
+// dynamicMountPathPrefix is the prefix of the mount paths given to
+// connections serving mounts added at runtime. These paths don't exist in the
+// gofer's root; Mount resolves them to the donated host FD instead.
+const dynamicMountPathPrefix = "/.gvisor-dynamic-mount-"
+
+// mountRequest is sent by the host on the mount control socket, along with
+// the host FD of the mount source and the gofer end of the lisafs socket.
+type mountRequest struct {
+	// Readonly indicates that the mount must be served as read-only.
+	Readonly bool `json:"readonly"`
+}
+
+// mountResponse is sent by the gofer in reply to a mountRequest.
+type mountResponse struct {
+	// Error is the error that occurred while serving the mount, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// addDynamicMount records hostFD as the root of a new mount added at runtime,
+// and returns the mount path that the lisafs connection must use to serve it.
+func (s *LisafsServer) addDynamicMount(hostFD int) string {
+	s.dynamicMountsMu.Lock()
+	defer s.dynamicMountsMu.Unlock()
+	if s.dynamicMounts == nil {
+		s.dynamicMounts = make(map[string]int)
+	}
+	mountPath := dynamicMountPathPrefix + strconv.Itoa(s.nextDynamicMount)
+	s.nextDynamicMount++
+	s.dynamicMounts[mountPath] = hostFD
+	return mountPath
+}
+
+// takeDynamicMount returns the host FD of the root of the mount added at
+// runtime with the given mount path. Ownership of the FD is transferred to
+// the caller.
+func (s *LisafsServer) takeDynamicMount(mountPath string) (int, bool) {
+	s.dynamicMountsMu.Lock()
+	defer s.dynamicMountsMu.Unlock()
+	hostFD, ok := s.dynamicMounts[mountPath]
+	if ok {
+		delete(s.dynamicMounts, mountPath)
+	}
+	return hostFD, ok
+}
+
+// ServeMountRequests accepts connections on the mount control socket and
+// starts serving the mounts requested on them, until the socket is closed.
+// See RequestMount.
+func (s *LisafsServer) ServeMountRequests(control *unet.ServerSocket) {
+	for {
+		conn, err := control.Accept()
+		if err != nil {
+			if !errors.Is(err, unix.EBADF) {
+				log.Warningf("Accepting on mount control socket failed: %v", err)
+			}
+			return
+		}
+		resp := mountResponse{}
+		if err := s.handleMountRequest(conn); err != nil {
+			log.Warningf("Mount request failed: %v", err)
+			resp.Error = err.Error()
+		}
+		if err := writeMountControlMessage(conn, &resp, nil); err != nil {
+			log.Warningf("Replying to mount request failed: %v", err)
+		}
+		conn.Close()
+	}
+}
+
+func (s *LisafsServer) handleMountRequest(conn *unet.Socket) error {
+	var req mountRequest
+	fds, err := readMountControlMessage(conn, &req, 2)
+	if err != nil {
+		return err
+	}
+	if len(fds) != 2 {
+		for _, fd := range fds {
+			_ = unix.Close(fd)
+		}
+		return fmt.Errorf("mount request must carry 2 FDs, got %d", len(fds))
+	}
+	hostFD, sockFD := fds[0], fds[1]
+
+	sock, err := unet.NewSocket(sockFD)
+	if err != nil {
+		_ = unix.Close(hostFD)
+		_ = unix.Close(sockFD)
+		return fmt.Errorf("creating lisafs socket: %w", err)
+	}
+	mountPath := s.addDynamicMount(hostFD)
+	lisafsConn, err := s.CreateConnection(sock, mountPath, req.Readonly)
+	if err != nil {
+		if fd, ok := s.takeDynamicMount(mountPath); ok {
+			_ = unix.Close(fd)
+		}
+		sock.Close()
+		return fmt.Errorf("creating lisafs connection: %w", err)
+	}
+	s.StartConnection(lisafsConn)
+	log.Infof("Serving mount added at runtime as %q (ro: %t)", mountPath, req.Readonly)
+	return nil
+}
+
+// RequestMount asks the gofer listening on the mount control socket at
+// controlPath to serve the host file hostFD over the lisafs socket sockFD.
+// The FDs are not consumed.
+func RequestMount(controlPath string, hostFD, sockFD int, readonly bool) error {
+	if len(controlPath) >= linux.UnixPathMax {
+		// UDS connect fails when the path is too long. Refer to the socket via
+		// /proc instead, like the sandbox control socket does.
+		pathFD, err := unix.Open(controlPath, unix.O_PATH|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return fmt.Errorf("opening mount control socket %q: %w", controlPath, err)
+		}
+		defer unix.Close(pathFD)
+		controlPath = fmt.Sprintf("/proc/self/fd/%d", pathFD)
+	}
+	conn, err := unet.Connect(controlPath, true /* packet */)
+	if err != nil {
+		return fmt.Errorf("connecting to mount control socket: %w", err)
+	}
+	defer conn.Close()
+
+	if err := writeMountControlMessage(conn, &mountRequest{Readonly: readonly}, []int{hostFD, sockFD}); err != nil {
+		return fmt.Errorf("sending mount request: %w", err)
+	}
+	var resp mountResponse
+	if _, err := readMountControlMessage(conn, &resp, 0); err != nil {
+		return fmt.Errorf("reading mount response: %w", err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("gofer failed to serve mount: %s", resp.Error)
+	}
+	return nil
+}
+
+// writeMountControlMessage sends msg with fds attached. The mount control
+// socket is a SOCK_SEQPACKET socket, so each message is sent at once.
+func writeMountControlMessage(conn *unet.Socket, msg any, fds []int) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxMountControlMessageSize {
+		return fmt.Errorf("mount control message too large: %d bytes", len(data))
+	}
+	w := conn.Writer(true)
+	if len(fds) > 0 {
+		w.PackFDs(fds...)
+	}
+	n, err := w.WriteVec([][]byte{data})
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return fmt.Errorf("short write of mount control message: %d of %d bytes", n, len(data))
+	}
+	return nil
+}
+
+// readMountControlMessage receives a single message into msg, and returns up
+// to maxFDs FDs attached to it. The caller owns the returned FDs.
+func readMountControlMessage(conn *unet.Socket, msg any, maxFDs int) ([]int, error) {
+	r := conn.Reader(true)
+	if maxFDs > 0 {
+		r.EnableFDs(maxFDs)
+	}
+	buf := make([]byte, maxMountControlMessageSize)
+	n, err := r.ReadVec([][]byte{buf})
+	var fds []int
+	if maxFDs > 0 {
+		var extractErr error
+		if fds, extractErr = r.ExtractFDs(); extractErr != nil && err == nil {
+			err = extractErr
+		}
+	}
+	if err == nil && n == 0 {
+		err = fmt.Errorf("mount control socket closed")
+	}
+	if err == nil {
+		if err = json.Unmarshal(buf[:n], msg); err != nil {
+			err = fmt.Errorf("parsing mount control message: %w", err)
+		}
+	}
+	if err != nil {
+		for _, fd := range fds {
+			_ = unix.Close(fd)
+		}
+		return nil, err
+	}
+	return fds, nil
+}
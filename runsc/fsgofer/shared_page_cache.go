@@ -0,0 +1,113 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsgofer
+
+import (
+	"fmt"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// copyBufferSize is the size of the buffer used to copy files into the shared
+// page cache.
+const copyBufferSize = 1 << 20
+
+// SharedPageCache is a host directory, shared by the gofers of multiple
+// sandboxes, that contains copies of read-only files keyed by the device,
+// inode number, modification time and size of the original file.
+//
+// Host FDs donated to the sentry for read-only files refer to these copies
+// instead of the original files. When the directory is backed by memory (e.g.
+// tmpfs), sandboxes that map the same file thus share the memory backing it,
+// reducing the memory usage of each sandbox when many sandboxes use the same
+// images.
+//
+// Copies are never removed by the gofer; it is up to the administrator to
+// bound the size of the directory.
+type SharedPageCache struct {
+	// dirFD is a host FD of the shared page cache directory. dirFD is
+	// immutable.
+	dirFD int
+}
+
+// NewSharedPageCache returns a SharedPageCache using the directory represented
+// by dirFD.
+func NewSharedPageCache(dirFD int) *SharedPageCache {
+	return &SharedPageCache{dirFD: dirFD}
+}
+
+func sharedPageCacheKey(stat *unix.Stat_t) string {
+	return fmt.Sprintf("%x-%x-%d.%09d-%d", stat.Dev, stat.Ino, stat.Mtim.Sec, stat.Mtim.Nsec, stat.Size)
+}
+
+// Open returns a new read-only host FD for a copy of the regular file
+// represented by hostFD in c, creating the copy if it doesn't exist.
+func (c *SharedPageCache) Open(hostFD int) (int, error) {
+	var stat unix.Stat_t
+	if err := unix.Fstat(hostFD, &stat); err != nil {
+		return -1, err
+	}
+	key := sharedPageCacheKey(&stat)
+	fd, err := unix.Openat(c.dirFD, key, unix.O_RDONLY|openFlags, 0)
+	if err != unix.ENOENT {
+		return fd, err
+	}
+
+	// Copy the file into an unnamed file, and only link it into the directory
+	// once it is complete, so that other gofers never observe a partial copy.
+	tmpFD, err := unix.Openat(c.dirFD, ".", unix.O_TMPFILE|unix.O_RDWR|unix.O_CLOEXEC, 0444)
+	if err != nil {
+		return -1, err
+	}
+	defer unix.Close(tmpFD)
+	if err := copyFile(tmpFD, hostFD, stat.Size); err != nil {
+		return -1, err
+	}
+	// The file may have been modified while it was being copied.
+	if err := unix.Fstat(hostFD, &stat); err != nil {
+		return -1, err
+	}
+	if sharedPageCacheKey(&stat) != key {
+		return -1, unix.EAGAIN
+	}
+	// If another gofer raced with us to copy the same file, use its copy so
+	// that memory is shared.
+	if err := unix.Linkat(int(procSelfFD.FD()), strconv.Itoa(tmpFD), c.dirFD, key, unix.AT_SYMLINK_FOLLOW); err != nil && err != unix.EEXIST {
+		return -1, err
+	}
+	return unix.Openat(c.dirFD, key, unix.O_RDONLY|openFlags, 0)
+}
+
+// copyFile copies size bytes from srcFD to dstFD.
+func copyFile(dstFD, srcFD int, size int64) error {
+	buf := make([]byte, min(size, copyBufferSize))
+	for off := int64(0); off < size; {
+		n, err := unix.Pread(srcFD, buf, off)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// The file was truncated; this is detected by the caller.
+			return nil
+		}
+		w, err := unix.Pwrite(dstFD, buf[:n], off)
+		if err != nil {
+			return err
+		}
+		off += int64(w)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+func init() {
+	Register("gs", newGCSStore)
+}
+
+// newGCSStore returns the Store at a gs://bucket/prefix URL, which is
+// accessed through the XML API of Google Cloud Storage.
+//
+// Requests are authorized with the OAuth 2.0 access token in the
+// GOOGLE_OAUTH_ACCESS_TOKEN environment variable if it is set, and otherwise
+// with tokens of the default service account obtained from the metadata
+// server of the instance, as on Compute Engine and GKE. A different service
+// URL can be set in the "endpoint" URL query parameter.
+func newGCSStore(u *url.URL) (Store, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("no bucket in URL")
+	}
+	endpoint := firstNonEmpty(u.Query().Get("endpoint"), "https://storage.googleapis.com")
+	e, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing endpoint %q: %w", endpoint, err)
+	}
+	ts := &gcsTokenSource{token: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")}
+	authorize := func(req *http.Request, _ string) error {
+		token, err := ts.get(req.Context())
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+	return newObjectStore(e.JoinPath(u.Host), u.Path, authorize), nil
+}
+
+// gcsTokenSource provides OAuth 2.0 access tokens.
+type gcsTokenSource struct {
+	mu sync.Mutex
+
+	// token is the current access token, which is valid until expiry if
+	// expiry is non-zero, and forever otherwise.
+	token  string
+	expiry time.Time
+}
+
+// get returns a valid access token, fetching a new one from the metadata
+// server if needed.
+func (ts *gcsTokenSource) get(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.token != "" && (ts.expiry.IsZero() || time.Now().Before(ts.expiry)) {
+		return ts.token, nil
+	}
+
+	host := firstNonEmpty(os.Getenv("GCE_METADATA_HOST"), "metadata.google.internal")
+	u := fmt.Sprintf("http://%s/computeMetadata/v1/instance/service-accounts/default/token", host)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("getting access token from metadata server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("getting access token from metadata server: %s", resp.Status)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("parsing access token from metadata server: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("metadata server returned no access token")
+	}
+	// Renew tokens ahead of their expiry, so that they don't expire while
+	// requests are in flight.
+	ts.token = token.AccessToken
+	ts.expiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return ts.token, nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package imagestore provides storage backends for checkpoint images, which
+// allow images to be saved to and restored from object storage rather than
+// local directories.
+//
+// Images in a Store are named by URLs, whose scheme selects the backend, e.g.
+// s3://bucket/prefix or gs://bucket/prefix. The files of an image are stored
+// as objects named after the files, under the prefix.
+package imagestore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store stores the files of a checkpoint image.
+type Store interface {
+	// Create returns a Writer for the file name, replacing any existing file
+	// once the Writer is closed.
+	Create(ctx context.Context, name string) (Writer, error)
+
+	// Open returns a reader for the file name.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+// Writer writes a file to a Store. Writes may be buffered and performed
+// asynchronously; the file is only stored once Close returns successfully.
+type Writer interface {
+	io.Writer
+
+	// Close completes the file, and returns any error that occurred while
+	// writing it. If it fails, the file is discarded.
+	Close() error
+
+	// Abort discards the file. It has no effect after Close.
+	Abort()
+}
+
+// Backend returns the Store at the image URL u.
+type Backend func(u *url.URL) (Store, error)
+
+// backends are the registered backends, by URL scheme.
+var backends = make(map[string]Backend)
+
+// Register registers the backend for image URLs with the given scheme. It
+// must be called from init functions.
+func Register(scheme string, b Backend) {
+	if _, ok := backends[scheme]; ok {
+		panic(fmt.Sprintf("image store backend for scheme %q registered twice", scheme))
+	}
+	backends[scheme] = b
+}
+
+// IsRemote returns true if imagePath is the URL of an image in a Store,
+// rather than the path of a local directory.
+func IsRemote(imagePath string) bool {
+	return strings.Contains(imagePath, "://")
+}
+
+// New returns the Store of the image at the URL imagePath.
+func New(imagePath string) (Store, error) {
+	u, err := url.Parse(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("parsing image URL %q: %w", imagePath, err)
+	}
+	b, ok := backends[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("image URL %q has unsupported scheme %q", imagePath, u.Scheme)
+	}
+	s, err := b(u)
+	if err != nil {
+		return nil, fmt.Errorf("opening image store %q: %w", imagePath, err)
+	}
+	return s, nil
+}
+
+// ReadFile returns the contents of the file name of the image at imagePath,
+// which is either a local directory or the URL of an image in a Store.
+func ReadFile(imagePath, name string) ([]byte, error) {
+	if !IsRemote(imagePath) {
+		return os.ReadFile(filepath.Join(imagePath, name))
+	}
+	s, err := New(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.Open(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// WriteFile writes data to the file name of the image at imagePath, which is
+// either a local directory or the URL of an image in a Store.
+func WriteFile(imagePath, name string, data []byte) error {
+	if !IsRemote(imagePath) {
+		return os.WriteFile(filepath.Join(imagePath, name), data, 0644)
+	}
+	s, err := New(imagePath)
+	if err != nil {
+		return err
+	}
+	w, err := s.Create(context.Background(), name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, bytes.NewReader(data)); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
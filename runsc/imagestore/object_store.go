@@ -0,0 +1,508 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagestore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gvisor.dev/gvisor/pkg/sync"
+)
+
+const (
+	// defaultPartSize is the size of the parts in which files are uploaded
+	// and downloaded. Object storage services require parts of multipart
+	// uploads, but the last, to be at least 5 MiB.
+	defaultPartSize = 16 << 20
+
+	// defaultParallelism is the number of parts uploaded or downloaded
+	// concurrently per file.
+	defaultParallelism = 8
+
+	// maxAttempts is the number of times a request is attempted before
+	// failing, if it fails with a transient error.
+	maxAttempts = 3
+)
+
+// objectStore is a Store backed by a bucket of an object storage service
+// implementing the S3 REST API, including multipart uploads. Files are
+// uploaded in parts, and downloaded by ranges, in parallel.
+type objectStore struct {
+	// bucket is the URL of the bucket. The URLs of objects are formed by
+	// appending their key to its path.
+	bucket *url.URL
+
+	// prefix is prepended to file names to form object keys.
+	prefix string
+
+	// authorize authorizes req, whose body has the given hex-encoded
+	// SHA-256 hash.
+	authorize func(req *http.Request, payloadHash string) error
+
+	client      *http.Client
+	partSize    int
+	parallelism int
+}
+
+func newObjectStore(bucket *url.URL, prefix string, authorize func(*http.Request, string) error) *objectStore {
+	return &objectStore{
+		bucket:      bucket,
+		prefix:      strings.Trim(prefix, "/"),
+		authorize:   authorize,
+		client:      http.DefaultClient,
+		partSize:    defaultPartSize,
+		parallelism: defaultParallelism,
+	}
+}
+
+// objectURL returns the URL of the object of file name, with query q.
+func (s *objectStore) objectURL(name string, q url.Values) *url.URL {
+	key := name
+	if s.prefix != "" {
+		key = s.prefix + "/" + name
+	}
+	u := *s.bucket
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	u.RawPath = escape(u.Path, false /* escapeSlash */)
+	u.RawQuery = q.Encode()
+	return &u
+}
+
+// escape escapes s as required by S3 canonical requests: all bytes but
+// unreserved characters, and slashes unless escapeSlash is true, are
+// percent-encoded.
+func escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// do sends a request for the object of file name, retrying on transient
+// failures, and returns its response if it succeeds. The caller must close
+// the response body.
+func (s *objectStore) do(ctx context.Context, method, name string, q url.Values, header http.Header, body []byte) (*http.Response, error) {
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	u := s.objectURL(name, q)
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+		var resp *http.Response
+		resp, err = s.doOnce(ctx, method, u, header, body, payloadHash)
+		if err == nil {
+			if resp.StatusCode/100 == 2 {
+				return resp, nil
+			}
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusNotFound {
+				return nil, fmt.Errorf("%s %s: %w", method, u.Redacted(), fs.ErrNotExist)
+			}
+			err = fmt.Errorf("%s %s: %s: %s", method, u.Redacted(), resp.Status, bytes.TrimSpace(msg))
+			if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+				return nil, err
+			}
+		}
+	}
+	return nil, err
+}
+
+func (s *objectStore) doOnce(ctx context.Context, method string, u *url.URL, header http.Header, body []byte, payloadHash string) (*http.Response, error) {
+	var r io.Reader = http.NoBody
+	if len(body) > 0 {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if err := s.authorize(req, payloadHash); err != nil {
+		return nil, fmt.Errorf("authorizing %s %s: %w", method, u.Redacted(), err)
+	}
+	return s.client.Do(req)
+}
+
+// Create implements Store.Create.
+func (s *objectStore) Create(ctx context.Context, name string) (Writer, error) {
+	resp, err := s.do(ctx, http.MethodPost, name, url.Values{"uploads": {""}}, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating multipart upload of %q: %w", name, err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("parsing multipart upload of %q: %w", name, err)
+	}
+	if result.UploadID == "" {
+		return nil, fmt.Errorf("multipart upload of %q has no upload ID", name)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w := &multipartWriter{
+		s:        s,
+		ctx:      ctx,
+		cancel:   cancel,
+		name:     name,
+		uploadID: result.UploadID,
+		parts:    make(chan part),
+	}
+	for i := 0; i < s.parallelism; i++ {
+		w.wg.Add(1)
+		go w.upload()
+	}
+	return w, nil
+}
+
+// part is a part of a multipart upload.
+type part struct {
+	number int
+	data   []byte
+}
+
+// multipartWriter is a Writer uploading a file in parts. Parts are uploaded
+// by s.parallelism goroutines, which bounds the memory used for buffering.
+//
+// multipartWriter methods must not be called concurrently.
+type multipartWriter struct {
+	s        *objectStore
+	ctx      context.Context
+	cancel   context.CancelFunc
+	name     string
+	uploadID string
+
+	// buf is the part being written.
+	buf []byte
+
+	// numParts is the number of parts queued for upload.
+	numParts int
+
+	// parts is the queue of parts to upload.
+	parts chan part
+
+	// wg tracks upload goroutines.
+	wg sync.WaitGroup
+
+	// closed is true after Close or Abort.
+	closed bool
+
+	mu sync.Mutex
+
+	// etags are the entity tags of uploaded parts, indexed by part number
+	// minus 1. Protected by mu.
+	etags []string
+
+	// err is the first error that occurred. Protected by mu.
+	err error
+}
+
+// Write implements io.Writer.Write.
+func (w *multipartWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		if err := w.error(); err != nil {
+			return n, err
+		}
+		if w.buf == nil {
+			w.buf = make([]byte, 0, w.s.partSize)
+		}
+		c := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+c]
+		p = p[c:]
+		n += c
+		if len(w.buf) == cap(w.buf) {
+			w.flush()
+		}
+	}
+	return n, nil
+}
+
+// flush queues the part being written for upload.
+func (w *multipartWriter) flush() {
+	w.numParts++
+	w.parts <- part{number: w.numParts, data: w.buf}
+	w.buf = nil
+}
+
+// error returns the first error that occurred.
+func (w *multipartWriter) error() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// upload uploads queued parts.
+func (w *multipartWriter) upload() {
+	defer w.wg.Done()
+	for p := range w.parts {
+		if w.error() != nil {
+			continue
+		}
+		etag, err := w.uploadPart(p)
+		w.mu.Lock()
+		if err != nil {
+			if w.err == nil {
+				w.err = err
+			}
+		} else {
+			for len(w.etags) < p.number {
+				w.etags = append(w.etags, "")
+			}
+			w.etags[p.number-1] = etag
+		}
+		w.mu.Unlock()
+	}
+}
+
+// uploadPart uploads p, and returns its entity tag.
+func (w *multipartWriter) uploadPart(p part) (string, error) {
+	q := url.Values{
+		"partNumber": {strconv.Itoa(p.number)},
+		"uploadId":   {w.uploadID},
+	}
+	resp, err := w.s.do(w.ctx, http.MethodPut, w.name, q, nil, p.data)
+	if err != nil {
+		return "", fmt.Errorf("uploading part %d of %q: %w", p.number, w.name, err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("uploaded part %d of %q has no ETag", p.number, w.name)
+	}
+	return etag, nil
+}
+
+// finish stops uploading parts.
+func (w *multipartWriter) finish() {
+	w.closed = true
+	close(w.parts)
+	w.wg.Wait()
+}
+
+// completeMultipartUpload is the body of a request completing a multipart
+// upload.
+type completeMultipartUpload struct {
+	XMLName xml.Name       `xml:"CompleteMultipartUpload"`
+	Parts   []completePart `xml:"Part"`
+}
+
+type completePart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// Close implements Writer.Close.
+func (w *multipartWriter) Close() error {
+	if w.closed {
+		return fmt.Errorf("upload of %q already closed", w.name)
+	}
+	// A multipart upload needs at least one part, even if empty.
+	if len(w.buf) > 0 || w.numParts == 0 {
+		w.flush()
+	}
+	w.finish()
+	if err := w.error(); err != nil {
+		w.abort()
+		return err
+	}
+
+	var c completeMultipartUpload
+	for i, etag := range w.etags {
+		c.Parts = append(c.Parts, completePart{PartNumber: i + 1, ETag: etag})
+	}
+	body, err := xml.Marshal(&c)
+	if err != nil {
+		w.abort()
+		return err
+	}
+	header := http.Header{"Content-Type": {"application/xml"}}
+	resp, err := w.s.do(w.ctx, http.MethodPost, w.name, url.Values{"uploadId": {w.uploadID}}, header, body)
+	if err != nil {
+		w.abort()
+		return fmt.Errorf("completing upload of %q: %w", w.name, err)
+	}
+	defer resp.Body.Close()
+	// Completion may fail after the response status was sent, in which
+	// case the response body is an error.
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err == nil && result.XMLName.Local == "Error" {
+		w.abort()
+		return fmt.Errorf("completing upload of %q: %s: %s", w.name, result.Code, result.Message)
+	}
+	w.cancel()
+	return nil
+}
+
+// Abort implements Writer.Abort.
+func (w *multipartWriter) Abort() {
+	if w.closed {
+		return
+	}
+	w.finish()
+	w.abort()
+}
+
+// abort aborts the multipart upload, releasing uploaded parts.
+func (w *multipartWriter) abort() {
+	defer w.cancel()
+	resp, err := w.s.do(w.ctx, http.MethodDelete, w.name, url.Values{"uploadId": {w.uploadID}}, nil, nil)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+// Open implements Store.Open.
+func (s *objectStore) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodHead, name, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("opening %q: %w", name, err)
+	}
+	resp.Body.Close()
+	size := resp.ContentLength
+	if size < 0 {
+		return nil, fmt.Errorf("opening %q: unknown size", name)
+	}
+	// Fail rather than mix the contents of different versions of the
+	// object if it is replaced while being read.
+	header := make(http.Header)
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		header.Set("If-Match", etag)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r := &rangeReader{
+		cancel: cancel,
+		ranges: make(chan chan rangeResult, s.parallelism),
+	}
+	go func() {
+		defer close(r.ranges)
+		for off := int64(0); off < size; off += int64(s.partSize) {
+			end := min(off+int64(s.partSize), size)
+			c := make(chan rangeResult, 1)
+			select {
+			case r.ranges <- c:
+			case <-ctx.Done():
+				return
+			}
+			go func() {
+				data, err := s.getRange(ctx, name, header, off, end)
+				c <- rangeResult{data, err}
+			}()
+		}
+	}()
+	return r, nil
+}
+
+// getRange returns the bytes of the object of file name in [off, end).
+func (s *objectStore) getRange(ctx context.Context, name string, header http.Header, off, end int64) ([]byte, error) {
+	h := header.Clone()
+	h.Set("Range", fmt.Sprintf("bytes=%d-%d", off, end-1))
+	resp, err := s.do(ctx, http.MethodGet, name, nil, h, nil)
+	if err != nil {
+		return nil, fmt.Errorf("reading %q at offset %d: %w", name, off, err)
+	}
+	defer resp.Body.Close()
+	data := make([]byte, end-off)
+	if _, err := io.ReadFull(resp.Body, data); err != nil {
+		return nil, fmt.Errorf("reading %q at offset %d: %w", name, off, err)
+	}
+	return data, nil
+}
+
+// rangeResult is the result of reading a range of an object.
+type rangeResult struct {
+	data []byte
+	err  error
+}
+
+// rangeReader reads an object by ranges, which are read in parallel ahead of
+// the reader.
+//
+// rangeReader methods must not be called concurrently.
+type rangeReader struct {
+	cancel context.CancelFunc
+
+	// ranges are the results of reads of consecutive ranges, in order.
+	ranges chan chan rangeResult
+
+	// cur is the unread data of the current range.
+	cur []byte
+
+	// err is returned once cur is exhausted.
+	err error
+}
+
+// Read implements io.Reader.Read.
+func (r *rangeReader) Read(p []byte) (int, error) {
+	for len(r.cur) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		c, ok := <-r.ranges
+		if !ok {
+			r.err = io.EOF
+			continue
+		}
+		res := <-c
+		r.cur, r.err = res.data, res.err
+	}
+	n := copy(p, r.cur)
+	r.cur = r.cur[n:]
+	return n, nil
+}
+
+// Close implements io.Closer.Close.
+func (r *rangeReader) Close() error {
+	r.cancel()
+	r.cur = nil
+	r.err = fs.ErrClosed
+	return nil
+}
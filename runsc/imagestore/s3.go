@@ -0,0 +1,175 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagestore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("s3", newS3Store)
+}
+
+// newS3Store returns the Store at an s3://bucket/prefix URL.
+//
+// Requests are signed with the credentials in the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and optional AWS_SESSION_TOKEN environment variables.
+// The region is given by the "region" URL query parameter, or the AWS_REGION
+// or AWS_DEFAULT_REGION environment variables, and defaults to us-east-1.
+// S3-compatible services are used by setting their URL in the "endpoint"
+// query parameter, or the AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL
+// environment variables.
+func newS3Store(u *url.URL) (Store, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("no bucket in URL")
+	}
+	signer := &sigV4{
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		service:      "s3",
+		now:          time.Now,
+	}
+	if signer.accessKey == "" || signer.secretKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	q := u.Query()
+	signer.region = firstNonEmpty(q.Get("region"), os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+
+	var bucket *url.URL
+	if endpoint := firstNonEmpty(q.Get("endpoint"), os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")); endpoint != "" {
+		e, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("parsing endpoint %q: %w", endpoint, err)
+		}
+		bucket = e.JoinPath(u.Host)
+	} else if strings.Contains(u.Host, ".") {
+		// Virtual-hosted-style URLs of buckets with dots in their names
+		// don't match the certificate of the service.
+		bucket = &url.URL{Scheme: "https", Host: fmt.Sprintf("s3.%s.amazonaws.com", signer.region), Path: "/" + u.Host}
+	} else {
+		bucket = &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", u.Host, signer.region)}
+	}
+	return newObjectStore(bucket, u.Path, signer.authorize), nil
+}
+
+// firstNonEmpty returns the first non-empty string of values.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// sigV4 signs requests with AWS Signature Version 4.
+type sigV4 struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	region       string
+	service      string
+	now          func() time.Time
+}
+
+// authorize signs req, whose body has the given hex-encoded SHA-256 hash.
+// All headers of req are signed.
+func (s *sigV4) authorize(req *http.Request, payloadHash string) error {
+	t := s.now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", k, headers[k])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, s.region, s.service)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, v := range []string{date, s.region, s.service, "aws4_request"} {
+		key = hmacSHA256(key, v)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.accessKey, scope, signedHeaders, signature))
+	return nil
+}
+
+// canonicalQuery returns the canonical query string of q.
+func canonicalQuery(q url.Values) string {
+	var params []string
+	for k, vs := range q {
+		for _, v := range vs {
+			params = append(params, escape(k, true /* escapeSlash */)+"="+escape(v, true /* escapeSlash */))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
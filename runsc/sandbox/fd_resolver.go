@@ -0,0 +1,242 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sandbox
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sync"
+	"gvisor.dev/gvisor/pkg/unet"
+	"gvisor.dev/gvisor/pkg/urpc"
+	"gvisor.dev/gvisor/runsc/boot"
+	"gvisor.dev/gvisor/runsc/config"
+)
+
+// fdResolverRule re-establishes a host FD of a checkpointed container from a
+// source, as configured by --restore-host-fds.
+type fdResolverRule struct {
+	// containerName is the name of the container the rule applies to. If
+	// empty, the rule applies to all containers.
+	containerName string
+
+	// fd is the application FD.
+	fd int
+
+	// source is a host path or a "unix:", "tcp:" or "udp:" address.
+	source string
+}
+
+// parseFDResolverRules parses a comma-separated list of [CONTAINER/]FD=SOURCE
+// rules.
+func parseFDResolverRules(s string) ([]fdResolverRule, error) {
+	var rules []fdResolverRule
+	for _, r := range strings.Split(s, ",") {
+		if r == "" {
+			continue
+		}
+		key, source, ok := strings.Cut(r, "=")
+		if !ok || source == "" {
+			return nil, fmt.Errorf("invalid host FD rule %q, must be [container/]fd=source", r)
+		}
+		var rule fdResolverRule
+		if i := strings.LastIndex(key, "/"); i >= 0 {
+			rule.containerName, key = key[:i], key[i+1:]
+		}
+		fd, err := strconv.Atoi(key)
+		if err != nil || fd < 0 {
+			return nil, fmt.Errorf("invalid FD in host FD rule %q", r)
+		}
+		rule.fd = fd
+		if !strings.HasPrefix(source, "/") {
+			switch network, _, _ := strings.Cut(source, ":"); network {
+			case "unix", "tcp", "udp":
+			default:
+				return nil, fmt.Errorf("invalid source in host FD rule %q, must be an absolute path or a unix:, tcp: or udp: address", r)
+			}
+		}
+		rule.source = source
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// FDResolver serves boot.FDResolverResolve calls from the sandbox on restore.
+// Host FDs matching its rules are re-established by runsc, others are
+// forwarded to the operator-supplied resolver, if any.
+type FDResolver struct {
+	rules []fdResolverRule
+
+	// operator is the client of the resolver listening on
+	// --restore-fd-resolver, or nil.
+	operator *urpc.Client
+
+	mu sync.Mutex
+
+	// files are the files sent to the sandbox. They are closed once the
+	// sandbox is done with the resolver.
+	files []*os.File
+}
+
+// newFDResolver returns the FDResolver configured by conf, or nil if host FDs
+// aren't to be resolved.
+func newFDResolver(conf *config.Config) (*FDResolver, error) {
+	if conf.RestoreHostFDs == "" && conf.RestoreFDResolver == "" {
+		return nil, nil
+	}
+	rules, err := parseFDResolverRules(conf.RestoreHostFDs)
+	if err != nil {
+		return nil, err
+	}
+	r := &FDResolver{rules: rules}
+	if conf.RestoreFDResolver != "" {
+		sock, err := unet.Connect(conf.RestoreFDResolver, false /* packet */)
+		if err != nil {
+			return nil, fmt.Errorf("connecting to FD resolver %q: %w", conf.RestoreFDResolver, err)
+		}
+		r.operator = urpc.NewClient(sock)
+	}
+	return r, nil
+}
+
+// serve starts serving r to the sandbox, and returns the socket to pass to
+// it. r is released once the sandbox closes the socket.
+func (r *FDResolver) serve() (*os.File, error) {
+	server, client, err := unet.SocketPair(false /* packet */)
+	if err != nil {
+		return nil, fmt.Errorf("creating FD resolver socket pair: %w", err)
+	}
+	clientFD, err := client.Release()
+	if err != nil {
+		server.Close()
+		return nil, err
+	}
+	srv := urpc.NewServer()
+	srv.Register(r)
+	go func() {
+		defer r.release()
+		srv.Handle(server)
+	}()
+	return os.NewFile(uintptr(clientFD), "fd resolver"), nil
+}
+
+// release closes the files sent to the sandbox and the connection to the
+// operator-supplied resolver.
+func (r *FDResolver) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, f := range r.files {
+		f.Close()
+	}
+	r.files = nil
+	if r.operator != nil {
+		r.operator.Close()
+	}
+}
+
+// Resolve implements boot.FDResolverResolve.
+func (r *FDResolver) Resolve(args *boot.ResolveFDArgs, res *boot.ResolveFDResult) error {
+	log.Debugf("Resolving host FD %d of container %q: %+v", args.FD, args.ContainerName, args.Info)
+	var (
+		f   *os.File
+		err error
+	)
+	if rule, ok := r.match(args); ok {
+		f, err = resolveFDSource(rule.source, args)
+	} else if r.operator != nil {
+		var opRes boot.ResolveFDResult
+		if err := r.operator.Call(boot.FDResolverResolve, args, &opRes); err != nil {
+			return err
+		}
+		if len(opRes.Files) != 1 {
+			for _, f := range opRes.Files {
+				f.Close()
+			}
+			return fmt.Errorf("FD resolver returned %d files, want 1", len(opRes.Files))
+		}
+		f = opRes.Files[0]
+	} else {
+		err = fmt.Errorf("no rule to resolve host FD %d of container %q", args.FD, args.ContainerName)
+	}
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.files = append(r.files, f)
+	r.mu.Unlock()
+	res.Files = []*os.File{f}
+	return nil
+}
+
+// match returns the rule applying to the host FD described by args. Rules for
+// a specific container take precedence.
+func (r *FDResolver) match(args *boot.ResolveFDArgs) (fdResolverRule, bool) {
+	var (
+		match fdResolverRule
+		found bool
+	)
+	for _, rule := range r.rules {
+		if rule.fd != args.FD {
+			continue
+		}
+		if rule.containerName == args.ContainerName {
+			return rule, true
+		}
+		if rule.containerName == "" && !found {
+			match, found = rule, true
+		}
+	}
+	return match, found
+}
+
+// resolveFDSource opens source for the host FD described by args.
+func resolveFDSource(source string, args *boot.ResolveFDArgs) (*os.File, error) {
+	if strings.HasPrefix(source, "/") {
+		// Reopen the path with the saved status flags.
+		const keep = unix.O_ACCMODE | unix.O_APPEND | unix.O_DIRECT | unix.O_DSYNC | unix.O_NOATIME | unix.O_NONBLOCK | unix.O_SYNC
+		flags := int(args.Info.Flags)&keep | unix.O_CLOEXEC | unix.O_NOCTTY
+		f, err := os.OpenFile(source, flags, 0)
+		if err != nil {
+			return nil, fmt.Errorf("reopening host FD %d of container %q: %w", args.FD, args.ContainerName, err)
+		}
+		return f, nil
+	}
+
+	network, addr, _ := strings.Cut(source, ":")
+	if network == "unix" {
+		switch args.Info.SocketType {
+		case unix.SOCK_DGRAM:
+			network = "unixgram"
+		case unix.SOCK_SEQPACKET:
+			network = "unixpacket"
+		}
+	}
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting host FD %d of container %q: %w", args.FD, args.ContainerName, err)
+	}
+	defer conn.Close()
+	fc, ok := conn.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, fmt.Errorf("connection of type %T has no file", conn)
+	}
+	return fc.File()
+}
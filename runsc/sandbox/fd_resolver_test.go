@@ -0,0 +1,101 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sandbox
+
+import (
+	"reflect"
+	"testing"
+
+	"gvisor.dev/gvisor/runsc/boot"
+)
+
+func TestParseFDResolverRules(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		rules   string
+		want    []fdResolverRule
+		wantErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "path",
+			rules: "3=/var/log/app.log",
+			want:  []fdResolverRule{{fd: 3, source: "/var/log/app.log"}},
+		},
+		{
+			name:  "container",
+			rules: "app/4=unix:/run/app.sock,5=tcp:10.0.0.1:80",
+			want: []fdResolverRule{
+				{containerName: "app", fd: 4, source: "unix:/run/app.sock"},
+				{fd: 5, source: "tcp:10.0.0.1:80"},
+			},
+		},
+		{
+			name:    "no-source",
+			rules:   "3=",
+			wantErr: true,
+		},
+		{
+			name:    "bad-fd",
+			rules:   "x=/tmp/file",
+			wantErr: true,
+		},
+		{
+			name:    "relative-path",
+			rules:   "3=tmp/file",
+			wantErr: true,
+		},
+		{
+			name:    "bad-network",
+			rules:   "3=sctp:10.0.0.1:80",
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseFDResolverRules(tc.rules)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("parseFDResolverRules(%q) = %v, wantErr: %t", tc.rules, err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseFDResolverRules(%q) = %+v, want %+v", tc.rules, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFDResolverMatch(t *testing.T) {
+	rules, err := parseFDResolverRules("3=/any,app/3=/app,4=/four")
+	if err != nil {
+		t.Fatalf("parseFDResolverRules: %v", err)
+	}
+	r := &FDResolver{rules: rules}
+	for _, tc := range []struct {
+		container string
+		fd        int
+		want      string
+	}{
+		{container: "app", fd: 3, want: "/app"},
+		{container: "other", fd: 3, want: "/any"},
+		{container: "app", fd: 4, want: "/four"},
+		{container: "app", fd: 5},
+	} {
+		rule, ok := r.match(&boot.ResolveFDArgs{ContainerName: tc.container, FD: tc.fd})
+		if got := rule.source; ok != (tc.want != "") || got != tc.want {
+			t.Errorf("match(%q, %d) = %q, %t, want %q", tc.container, tc.fd, got, ok, tc.want)
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/vishvananda/netlink"
@@ -197,143 +198,217 @@ func createInterfacesAndRoutesFromNS(conn *urpc.Client, nsPath string, conf *con
 			continue
 		}
 
-		// Collect data from the ARP table.
-		dump, err := netlink.NeighList(iface.Index, 0)
-		if err != nil {
-			return fmt.Errorf("fetching ARP table for %q: %w", iface.Name, err)
+		if err := addInterfaceLink(&args, iface, ipAddrs, conf); err != nil {
+			return err
 		}
+	}
 
-		var neighbors []boot.Neighbor
-		for _, n := range dump {
-			// There are only two "good" states NUD_PERMANENT and NUD_REACHABLE,
-			// but NUD_REACHABLE is fully dynamic and will be re-probed anyway.
-			if n.State == netlink.NUD_PERMANENT {
-				log.Debugf("Copying a static ARP entry: %+v %+v", n.IP, n.HardwareAddr)
-				// No flags are copied because Stack.AddStaticNeighbor does not support flags right now.
-				neighbors = append(neighbors, boot.Neighbor{IP: n.IP, HardwareAddr: n.HardwareAddr})
-			}
+	if err := pcapAndNAT(&args, conf); err != nil {
+		return err
+	}
+	if err := dnsProxy(&args, conf); err != nil {
+		return err
+	}
+
+	log.Debugf("Setting up network, config: %+v", args)
+	if err := conn.Call(boot.NetworkCreateLinksAndRoutes, &args, nil); err != nil {
+		return fmt.Errorf("creating links and routes: %w", err)
+	}
+	return nil
+}
+
+// addInterfaceFromNS moves the interface with the given name from the net
+// namespace with the given path to an already configured sandbox, along with
+// its addresses and routes. It's used to attach networks to the sandbox after
+// it was created, e.g. by CNI plugins that add secondary interfaces to a pod.
+func addInterfaceFromNS(conn *urpc.Client, nsPath, name string, conf *config.Config) error {
+	switch conf.XDP.Mode {
+	case config.XDPModeOff:
+	case config.XDPModeNS:
+	default:
+		return fmt.Errorf("interfaces cannot be added in XDP mode %v", conf.XDP.Mode)
+	}
+
+	restore, err := joinNetNS(nsPath)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return fmt.Errorf("querying interface %q: %w", name, err)
+	}
+	if iface.Flags&net.FlagUp == 0 {
+		return fmt.Errorf("interface %q is down", name)
+	}
+	if iface.Flags&net.FlagLoopback != 0 {
+		return fmt.Errorf("interface %q is a loopback interface", name)
+	}
+
+	allAddrs, err := iface.Addrs()
+	if err != nil {
+		return fmt.Errorf("fetching interface addresses for %q: %w", iface.Name, err)
+	}
+	var ipAddrs []*net.IPNet
+	for _, ifaddr := range allAddrs {
+		ipNet, ok := ifaddr.(*net.IPNet)
+		if !ok {
+			return fmt.Errorf("address is not IPNet: %+v", ifaddr)
 		}
+		ipAddrs = append(ipAddrs, ipNet)
+	}
+	if len(ipAddrs) == 0 {
+		return fmt.Errorf("no usable IP addresses found for interface %q", iface.Name)
+	}
 
-		// Scrape the routes before removing the address, since that
-		// will remove the routes as well.
-		routes, defv4, defv6, err := routesForIface(iface)
-		if err != nil {
-			return fmt.Errorf("getting routes for interface %q: %v", iface.Name, err)
+	args := boot.CreateLinksAndRoutesArgs{
+		DisconnectOk: conf.NetDisconnectOk,
+		LogPackets:   conf.LogPackets,
+	}
+	if err := addInterfaceLink(&args, *iface, ipAddrs, conf); err != nil {
+		return err
+	}
+
+	log.Debugf("Adding network interface, config: %+v", args)
+	if err := conn.Call(boot.NetworkAddLinksAndRoutes, &args, nil); err != nil {
+		return fmt.Errorf("adding links and routes: %w", err)
+	}
+	return nil
+}
+
+// addInterfaceLink collects the neighbors and routes of iface, moves its
+// addresses ipAddrs from the host to the sandbox, and adds a link for it to
+// args, along with the FDs backing the link.
+func addInterfaceLink(args *boot.CreateLinksAndRoutesArgs, iface net.Interface, ipAddrs []*net.IPNet, conf *config.Config) error {
+	// Collect data from the ARP table.
+	dump, err := netlink.NeighList(iface.Index, 0)
+	if err != nil {
+		return fmt.Errorf("fetching ARP table for %q: %w", iface.Name, err)
+	}
+
+	var neighbors []boot.Neighbor
+	for _, n := range dump {
+		// There are only two "good" states NUD_PERMANENT and NUD_REACHABLE,
+		// but NUD_REACHABLE is fully dynamic and will be re-probed anyway.
+		if n.State == netlink.NUD_PERMANENT {
+			log.Debugf("Copying a static ARP entry: %+v %+v", n.IP, n.HardwareAddr)
+			// No flags are copied because Stack.AddStaticNeighbor does not support flags right now.
+			neighbors = append(neighbors, boot.Neighbor{IP: n.IP, HardwareAddr: n.HardwareAddr})
 		}
-		if defv4 != nil {
-			if !args.Defaultv4Gateway.Route.Empty() {
-				return fmt.Errorf("more than one default route found, interface: %v, route: %v, default route: %+v", iface.Name, defv4, args.Defaultv4Gateway)
-			}
-			args.Defaultv4Gateway.Route = *defv4
-			args.Defaultv4Gateway.Name = iface.Name
+	}
+
+	// Scrape the routes before removing the address, since that
+	// will remove the routes as well.
+	routes, defv4, defv6, err := routesForIface(iface)
+	if err != nil {
+		return fmt.Errorf("getting routes for interface %q: %v", iface.Name, err)
+	}
+	if defv4 != nil {
+		if !args.Defaultv4Gateway.Route.Empty() {
+			return fmt.Errorf("more than one default route found, interface: %v, route: %v, default route: %+v", iface.Name, defv4, args.Defaultv4Gateway)
 		}
+		args.Defaultv4Gateway.Route = *defv4
+		args.Defaultv4Gateway.Name = iface.Name
+	}
 
-		if defv6 != nil {
-			if !args.Defaultv6Gateway.Route.Empty() {
-				return fmt.Errorf("more than one default route found, interface: %v, route: %v, default route: %+v", iface.Name, defv6, args.Defaultv6Gateway)
+	if defv6 != nil {
+		if !args.Defaultv6Gateway.Route.Empty() {
+			return fmt.Errorf("more than one default route found, interface: %v, route: %v, default route: %+v", iface.Name, defv6, args.Defaultv6Gateway)
+		}
+		args.Defaultv6Gateway.Route = *defv6
+		args.Defaultv6Gateway.Name = iface.Name
+	}
+
+	// Get the link for the interface.
+	ifaceLink, err := netlink.LinkByName(iface.Name)
+	if err != nil {
+		return fmt.Errorf("getting link for interface %q: %w", iface.Name, err)
+	}
+	linkAddress := ifaceLink.Attrs().HardwareAddr
+
+	// Collect the addresses for the interface, enable forwarding,
+	// and remove them from the host.
+	var addresses []boot.IPWithPrefix
+	for _, addr := range ipAddrs {
+		prefix, _ := addr.Mask.Size()
+		addresses = append(addresses, boot.IPWithPrefix{Address: addr.IP, PrefixLen: prefix})
+
+		// Steal IP address from NIC.
+		if err := removeAddress(ifaceLink, addr.String()); err != nil {
+			// If we encounter an error while deleting the ip,
+			// verify the ip is still present on the interface.
+			if present, err := isAddressOnInterface(iface.Name, addr); err != nil {
+				return fmt.Errorf("checking if address %v is on interface %q: %w", addr, iface.Name, err)
+			} else if !present {
+				continue
 			}
-			args.Defaultv6Gateway.Route = *defv6
-			args.Defaultv6Gateway.Name = iface.Name
+			return fmt.Errorf("removing address %v from device %q: %w", addr, iface.Name, err)
 		}
+	}
 
-		// Get the link for the interface.
-		ifaceLink, err := netlink.LinkByName(iface.Name)
+	if conf.XDP.Mode == config.XDPModeNS {
+		xdpSockFDs, err := createSocketXDP(iface)
 		if err != nil {
-			return fmt.Errorf("getting link for interface %q: %w", iface.Name, err)
-		}
-		linkAddress := ifaceLink.Attrs().HardwareAddr
-
-		// Collect the addresses for the interface, enable forwarding,
-		// and remove them from the host.
-		var addresses []boot.IPWithPrefix
-		for _, addr := range ipAddrs {
-			prefix, _ := addr.Mask.Size()
-			addresses = append(addresses, boot.IPWithPrefix{Address: addr.IP, PrefixLen: prefix})
-
-			// Steal IP address from NIC.
-			if err := removeAddress(ifaceLink, addr.String()); err != nil {
-				// If we encounter an error while deleting the ip,
-				// verify the ip is still present on the interface.
-				if present, err := isAddressOnInterface(iface.Name, addr); err != nil {
-					return fmt.Errorf("checking if address %v is on interface %q: %w", addr, iface.Name, err)
-				} else if !present {
-					continue
-				}
-				return fmt.Errorf("removing address %v from device %q: %w", addr, iface.Name, err)
-			}
+			return fmt.Errorf("failed to create XDP socket: %v", err)
+		}
+		args.FilePayload.Files = append(args.FilePayload.Files, xdpSockFDs...)
+		args.XDPLinks = append(args.XDPLinks, boot.XDPLink{
+			Name:              iface.Name,
+			InterfaceIndex:    iface.Index,
+			Routes:            routes,
+			TXChecksumOffload: conf.TXChecksumOffload,
+			RXChecksumOffload: conf.RXChecksumOffload,
+			NumChannels:       conf.NumNetworkChannels,
+			QDisc:             conf.QDisc,
+			Neighbors:         neighbors,
+			LinkAddress:       linkAddress,
+			Addresses:         addresses,
+			GVisorGRO:         conf.GVisorGRO,
+		})
+	} else {
+		link := boot.FDBasedLink{
+			Name:                 iface.Name,
+			MTU:                  iface.MTU,
+			Routes:               routes,
+			TXChecksumOffload:    conf.TXChecksumOffload,
+			RXChecksumOffload:    conf.RXChecksumOffload,
+			NumChannels:          conf.NumNetworkChannels,
+			ProcessorsPerChannel: conf.NetworkProcessorsPerChannel,
+			ProcessorQueueLen:    conf.NetworkProcessorQueueLen,
+			QDisc:                conf.QDisc,
+			Neighbors:            neighbors,
+			LinkAddress:          linkAddress,
+			Addresses:            addresses,
 		}
 
-		if conf.XDP.Mode == config.XDPModeNS {
-			xdpSockFDs, err := createSocketXDP(iface)
+		log.Debugf("Setting up network channels")
+		// Create the socket for the device.
+		for i := 0; i < link.NumChannels; i++ {
+			log.Debugf("Creating Channel %d", i)
+			socketEntry, err := createSocket(iface, ifaceLink, conf.HostGSO)
 			if err != nil {
-				return fmt.Errorf("failed to create XDP socket: %v", err)
-			}
-			args.FilePayload.Files = append(args.FilePayload.Files, xdpSockFDs...)
-			args.XDPLinks = append(args.XDPLinks, boot.XDPLink{
-				Name:              iface.Name,
-				InterfaceIndex:    iface.Index,
-				Routes:            routes,
-				TXChecksumOffload: conf.TXChecksumOffload,
-				RXChecksumOffload: conf.RXChecksumOffload,
-				NumChannels:       conf.NumNetworkChannels,
-				QDisc:             conf.QDisc,
-				Neighbors:         neighbors,
-				LinkAddress:       linkAddress,
-				Addresses:         addresses,
-				GVisorGRO:         conf.GVisorGRO,
-			})
-		} else {
-			link := boot.FDBasedLink{
-				Name:                 iface.Name,
-				MTU:                  iface.MTU,
-				Routes:               routes,
-				TXChecksumOffload:    conf.TXChecksumOffload,
-				RXChecksumOffload:    conf.RXChecksumOffload,
-				NumChannels:          conf.NumNetworkChannels,
-				ProcessorsPerChannel: conf.NetworkProcessorsPerChannel,
-				QDisc:                conf.QDisc,
-				Neighbors:            neighbors,
-				LinkAddress:          linkAddress,
-				Addresses:            addresses,
+				return fmt.Errorf("failed to createSocket for %s : %w", iface.Name, err)
 			}
-
-			log.Debugf("Setting up network channels")
-			// Create the socket for the device.
-			for i := 0; i < link.NumChannels; i++ {
-				log.Debugf("Creating Channel %d", i)
-				socketEntry, err := createSocket(iface, ifaceLink, conf.HostGSO)
-				if err != nil {
-					return fmt.Errorf("failed to createSocket for %s : %w", iface.Name, err)
-				}
-				if i == 0 {
-					link.GSOMaxSize = socketEntry.gsoMaxSize
-				} else {
-					if link.GSOMaxSize != socketEntry.gsoMaxSize {
-						return fmt.Errorf("inconsistent gsoMaxSize %d and %d when creating multiple channels for same interface: %s",
-							link.GSOMaxSize, socketEntry.gsoMaxSize, iface.Name)
-					}
+			if i == 0 {
+				link.GSOMaxSize = socketEntry.gsoMaxSize
+			} else {
+				if link.GSOMaxSize != socketEntry.gsoMaxSize {
+					return fmt.Errorf("inconsistent gsoMaxSize %d and %d when creating multiple channels for same interface: %s",
+						link.GSOMaxSize, socketEntry.gsoMaxSize, iface.Name)
 				}
-				args.FilePayload.Files = append(args.FilePayload.Files, socketEntry.deviceFile)
-			}
-
-			if link.GSOMaxSize == 0 && conf.GVisorGSO {
-				// Host GSO is disabled. Let's enable gVisor GSO.
-				link.GSOMaxSize = stack.GVisorGSOMaxSize
-				link.GVisorGSOEnabled = true
 			}
-			link.GVisorGRO = conf.GVisorGRO
+			args.FilePayload.Files = append(args.FilePayload.Files, socketEntry.deviceFile)
+		}
 
-			args.FDBasedLinks = append(args.FDBasedLinks, link)
+		if link.GSOMaxSize == 0 && conf.GVisorGSO {
+			// Host GSO is disabled. Let's enable gVisor GSO.
+			link.GSOMaxSize = stack.GVisorGSOMaxSize
+			link.GVisorGSOEnabled = true
 		}
-	}
+		link.GVisorGRO = conf.GVisorGRO
 
-	if err := pcapAndNAT(&args, conf); err != nil {
-		return err
-	}
-
-	log.Debugf("Setting up network, config: %+v", args)
-	if err := conn.Call(boot.NetworkCreateLinksAndRoutes, &args, nil); err != nil {
-		return fmt.Errorf("creating links and routes: %w", err)
+		args.FDBasedLinks = append(args.FDBasedLinks, link)
 	}
 	return nil
 }
@@ -564,6 +639,37 @@ func pcapAndNAT(args *boot.CreateLinksAndRoutesArgs, conf *config.Config) error
 	return nil
 }
 
+// rootCAFiles are the locations of the host's root certificate bundle on
+// common distributions, as searched by crypto/x509.
+var rootCAFiles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",                // Debian/Ubuntu/Gentoo etc.
+	"/etc/pki/tls/certs/ca-bundle.crt",                  // Fedora/RHEL 6
+	"/etc/ssl/ca-bundle.pem",                            // OpenSUSE
+	"/etc/pki/tls/cacert.pem",                           // OpenELEC
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", // CentOS/RHEL 7
+	"/etc/ssl/cert.pem",                                 // Alpine Linux
+}
+
+// dnsProxy configures the in-sandbox DNS proxy if requested. The sandbox
+// cannot read the host's certificate store, so root certificates are passed
+// along if any upstream uses DNS over TLS.
+func dnsProxy(args *boot.CreateLinksAndRoutesArgs, conf *config.Config) error {
+	args.DNSProxyUpstreams = conf.DNSProxyUpstreamList()
+	for _, u := range args.DNSProxyUpstreams {
+		if !strings.HasPrefix(u, "tls://") {
+			continue
+		}
+		for _, name := range rootCAFiles {
+			if pem, err := os.ReadFile(name); err == nil {
+				args.DNSProxyRootCAs = pem
+				return nil
+			}
+		}
+		return fmt.Errorf("DNS over TLS upstream %q requires a root certificate bundle, none found in %v", u, rootCAFiles)
+	}
+	return nil
+}
+
 // The below is a work around to generate iptables-legacy rules on machines
 // that use iptables-nftables. The logic goes something like this:
 //
@@ -26,6 +26,7 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"syscall"
@@ -48,9 +49,11 @@ import (
 	"gvisor.dev/gvisor/pkg/sentry/control"
 	"gvisor.dev/gvisor/pkg/sentry/devices/nvproxy"
 	"gvisor.dev/gvisor/pkg/sentry/fsimpl/erofs"
+	"gvisor.dev/gvisor/pkg/sentry/fsimpl/gofer"
 	"gvisor.dev/gvisor/pkg/sentry/pgalloc"
 	"gvisor.dev/gvisor/pkg/sentry/platform"
 	"gvisor.dev/gvisor/pkg/sentry/seccheck"
+	"gvisor.dev/gvisor/pkg/sentry/state"
 	"gvisor.dev/gvisor/pkg/state/statefile"
 	"gvisor.dev/gvisor/pkg/sync"
 	"gvisor.dev/gvisor/pkg/urpc"
@@ -60,6 +63,7 @@ import (
 	"gvisor.dev/gvisor/runsc/config"
 	"gvisor.dev/gvisor/runsc/console"
 	"gvisor.dev/gvisor/runsc/donation"
+	"gvisor.dev/gvisor/runsc/imagestore"
 	"gvisor.dev/gvisor/runsc/specutils"
 )
 
@@ -447,10 +451,22 @@ func (s *Sandbox) StartSubcontainer(spec *specs.Spec, conf *config.Config, cid s
 func (s *Sandbox) Restore(conf *config.Config, cid string, imagePath string, direct bool) error {
 	log.Debugf("Restore sandbox %q from path %q", s.ID, imagePath)
 
-	stateFileName := path.Join(imagePath, boot.CheckpointStateFileName)
-	sf, err := os.Open(stateFileName)
-	if err != nil {
-		return fmt.Errorf("opening state file %q failed: %v", stateFileName, err)
+	var (
+		sf          *os.File
+		downloadErr func() error
+		err         error
+	)
+	if imagestore.IsRemote(imagePath) {
+		sf, downloadErr, err = downloadStateFile(imagePath)
+		if err != nil {
+			return err
+		}
+	} else {
+		stateFileName := path.Join(imagePath, boot.CheckpointStateFileName)
+		sf, err = os.Open(stateFileName)
+		if err != nil {
+			return fmt.Errorf("opening state file %q failed: %v", stateFileName, err)
+		}
 	}
 	defer sf.Close()
 
@@ -460,25 +476,28 @@ func (s *Sandbox) Restore(conf *config.Config, cid string, imagePath string, dir
 		},
 	}
 
-	// If the pages file exists, we must pass it in.
-	pagesFileName := path.Join(imagePath, boot.CheckpointPagesFileName)
-	pagesReadFlags := os.O_RDONLY
-	if direct {
-		// The contents are page-aligned, so it can be opened with O_DIRECT.
-		pagesReadFlags |= syscall.O_DIRECT
-	}
-	if pf, err := os.OpenFile(pagesFileName, pagesReadFlags, 0); err == nil {
-		defer pf.Close()
-		pagesMetadataFileName := path.Join(imagePath, boot.CheckpointPagesMetadataFileName)
-		pmf, err := os.Open(pagesMetadataFileName)
-		if err != nil {
-			return fmt.Errorf("opening restore image file %q failed: %v", pagesMetadataFileName, err)
+	// If the pages file exists, we must pass it in. Images in object storage
+	// have none.
+	if downloadErr == nil {
+		pagesFileName := path.Join(imagePath, boot.CheckpointPagesFileName)
+		pagesReadFlags := os.O_RDONLY
+		if direct {
+			// The contents are page-aligned, so it can be opened with O_DIRECT.
+			pagesReadFlags |= syscall.O_DIRECT
+		}
+		if pf, err := os.OpenFile(pagesFileName, pagesReadFlags, 0); err == nil {
+			defer pf.Close()
+			pagesMetadataFileName := path.Join(imagePath, boot.CheckpointPagesMetadataFileName)
+			pmf, err := os.Open(pagesMetadataFileName)
+			if err != nil {
+				return fmt.Errorf("opening restore image file %q failed: %v", pagesMetadataFileName, err)
+			}
+			defer pmf.Close()
+			opt.HavePagesFile = true
+			opt.FilePayload.Files = append(opt.FilePayload.Files, pmf, pf)
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("opening restore image file %q failed: %v", pagesFileName, err)
 		}
-		defer pmf.Close()
-		opt.HavePagesFile = true
-		opt.FilePayload.Files = append(opt.FilePayload.Files, pmf, pf)
-	} else if !os.IsNotExist(err) {
-		return fmt.Errorf("opening restore image file %q failed: %v", pagesFileName, err)
 	}
 
 	// If the platform needs a device FD we must pass it in.
@@ -490,6 +509,21 @@ func (s *Sandbox) Restore(conf *config.Config, cid string, imagePath string, dir
 		opt.FilePayload.Files = append(opt.FilePayload.Files, deviceFile.ReleaseToFile("device file"))
 	}
 
+	// If host FDs are to be resolved, serve the FD resolver to the sandbox.
+	resolver, err := newFDResolver(conf)
+	if err != nil {
+		return err
+	}
+	if resolver != nil {
+		rf, err := resolver.serve()
+		if err != nil {
+			return err
+		}
+		defer rf.Close()
+		opt.HaveFDResolver = true
+		opt.FilePayload.Files = append(opt.FilePayload.Files, rf)
+	}
+
 	conn, err := s.sandboxConnect()
 	if err != nil {
 		return err
@@ -503,6 +537,12 @@ func (s *Sandbox) Restore(conf *config.Config, cid string, imagePath string, dir
 
 	// Restore the container and start the root container.
 	if err := conn.Call(boot.ContMgrRestore, &opt, nil); err != nil {
+		if downloadErr != nil {
+			// The restore may have failed because the download did.
+			if dlErr := downloadErr(); dlErr != nil {
+				return fmt.Errorf("restoring container %q: %v: %v", cid, err, dlErr)
+			}
+		}
 		return fmt.Errorf("restoring container %q: %v", cid, err)
 	}
 
@@ -604,6 +644,26 @@ func (s *Sandbox) ListTraceSessions() ([]seccheck.SessionConfig, error) {
 	return sessions, nil
 }
 
+// ReloadPolicy replaces the rules of policy sinks with the rules in rulesFile.
+func (s *Sandbox) ReloadPolicy(rulesFile string) error {
+	log.Debugf("Reloading policy in sandbox %q from %q", s.ID, rulesFile)
+	f, err := os.Open(rulesFile)
+	if err != nil {
+		return fmt.Errorf("opening rules file: %w", err)
+	}
+	defer f.Close()
+
+	arg := boot.ReloadPolicyArgs{
+		FilePayload: urpc.FilePayload{
+			Files: []*os.File{f},
+		},
+	}
+	if err := s.call(boot.ContMgrReloadPolicy, &arg, nil); err != nil {
+		return fmt.Errorf("reloading policy: %w", err)
+	}
+	return nil
+}
+
 // ProcfsDump collects and returns a procfs dump for the sandbox.
 func (s *Sandbox) ProcfsDump() ([]procfs.ProcessProcfsDump, error) {
 	log.Debugf("Procfs dump %q", s.ID)
@@ -804,6 +864,13 @@ func (s *Sandbox) createSandboxProcess(conf *config.Config, args *Args, startSyn
 	// If there is a gofer, sends all socket ends to the sandbox.
 	donations.DonateAndClose("io-fds", args.IOFiles...)
 	donations.DonateAndClose("dev-io-fd", args.DevIOFile)
+	if len(conf.HostAbstractUDSNames()) > 0 {
+		proxyFile, err := startHostAbstractUDSProxy(conf)
+		if err != nil {
+			return fmt.Errorf("starting host abstract UDS proxy: %w", err)
+		}
+		donations.DonateAndClose("host-abstract-uds-fd", proxyFile)
+	}
 	donations.DonateAndClose("gofer-filestore-fds", args.GoferFilestoreFiles...)
 	donations.DonateAndClose("mounts-fd", args.MountsFile)
 	donations.Donate("start-sync-fd", startSyncFile)
@@ -826,6 +893,9 @@ func (s *Sandbox) createSandboxProcess(conf *config.Config, args *Args, startSyn
 	if err := donations.OpenAndDonate("trace-fd", conf.TraceFile, profFlags); err != nil {
 		return err
 	}
+	if err := donations.OpenAndDonate("watchdog-stall-checkpoint-fd", conf.WatchdogStallCheckpoint, os.O_CREATE|os.O_WRONLY|os.O_TRUNC); err != nil {
+		return err
+	}
 
 	// Pass gofer mount configs.
 	cmd.Args = append(cmd.Args, "--gofer-mount-confs="+args.GoferMountConfs.String())
@@ -850,6 +920,14 @@ func (s *Sandbox) createSandboxProcess(conf *config.Config, args *Args, startSyn
 	}
 	donations.DonateAndClose("sink-fds", args.SinkFiles...)
 
+	if conf.SwapDir != "" {
+		swapFile, err := createSwapFile(conf.SwapDir)
+		if err != nil {
+			return err
+		}
+		donations.DonateAndClose("swap-fd", swapFile)
+	}
+
 	if len(conf.TestOnlyAutosaveImagePath) != 0 {
 		files, err := createSaveFiles(conf.TestOnlyAutosaveImagePath, false, statefile.CompressionLevelFlateBestSpeed)
 		if err != nil {
@@ -1098,32 +1176,29 @@ func (s *Sandbox) createSandboxProcess(conf *config.Config, args *Args, startSyn
 	cmd.Args = append(cmd.Args, "--total-host-memory", strconv.FormatUint(totalSysMem, 10))
 
 	mem := totalSysMem
+	cpuNum := 0 // Use all host CPUs.
 	if s.CgroupJSON.Cgroup != nil {
-		cpuNum, err := s.CgroupJSON.Cgroup.NumCPU()
+		cpuNum, err = s.CgroupJSON.Cgroup.NumCPU()
 		if err != nil {
 			return fmt.Errorf("getting cpu count from cgroups: %v", err)
 		}
-		if conf.CPUNumFromQuota {
+		if conf.CPUNumFromQuota || conf.LimitsFromCgroup {
 			// Dropping below 2 CPUs can trigger application to disable
 			// locks that can lead do hard to debug errors, so just
-			// leaving two cores as reasonable default.
-			const minCPUs = 2
+			// leaving two cores as reasonable default. When reporting
+			// limits from cgroups, report the same CPU count as Linux
+			// applications compute from the quota instead.
+			minCPUs := 2
+			if conf.LimitsFromCgroup {
+				minCPUs = 1
+			}
 
 			quota, err := s.CgroupJSON.Cgroup.CPUQuota()
 			if err != nil {
 				return fmt.Errorf("getting cpu quota from cgroups: %v", err)
 			}
-			if n := int(math.Ceil(quota)); n > 0 {
-				if n < minCPUs {
-					n = minCPUs
-				}
-				if n < cpuNum {
-					// Only lower the cpu number.
-					cpuNum = n
-				}
-			}
+			cpuNum = cpuNumFromQuota(quota, cpuNum, minCPUs)
 		}
-		cmd.Args = append(cmd.Args, "--cpu-num", strconv.Itoa(cpuNum))
 
 		memLimit, err := s.CgroupJSON.Cgroup.MemoryLimit()
 		if err != nil {
@@ -1133,6 +1208,17 @@ func (s *Sandbox) createSandboxProcess(conf *config.Config, args *Args, startSyn
 			mem = memLimit
 		}
 	}
+	if conf.LimitsFromCgroup {
+		// The sandbox cgroup may be shared with other containers, or not
+		// be configured at all, so also apply the container's own limits.
+		if cpuNum == 0 {
+			cpuNum = runtime.NumCPU()
+		}
+		cpuNum, mem = applySpecLimits(args.Spec, cpuNum, mem)
+	}
+	if cpuNum != 0 {
+		cmd.Args = append(cmd.Args, "--cpu-num", strconv.Itoa(cpuNum))
+	}
 	cmd.Args = append(cmd.Args, "--total-memory", strconv.FormatUint(mem, 10))
 
 	if args.Attached {
@@ -1336,7 +1422,18 @@ func (s *Sandbox) SignalProcess(cid string, pid int32, sig unix.Signal, fgProces
 func (s *Sandbox) Checkpoint(cid string, imagePath string, direct bool, sfOpts statefile.Options, mfOpts pgalloc.SaveOpts) error {
 	log.Debugf("Checkpoint sandbox %q, statefile options %+v, MemoryFile options %+v", s.ID, sfOpts, mfOpts)
 
-	files, err := createSaveFiles(imagePath, direct, sfOpts.Compression)
+	var (
+		files  []*os.File
+		upload func(saveErr error) error
+		err    error
+	)
+	if imagestore.IsRemote(imagePath) {
+		var f *os.File
+		f, upload, err = uploadStateFile(imagePath)
+		files = []*os.File{f}
+	} else {
+		files, err = createSaveFiles(imagePath, direct, sfOpts.Compression)
+	}
 	if err != nil {
 		return err
 	}
@@ -1354,14 +1451,31 @@ func (s *Sandbox) Checkpoint(cid string, imagePath string, direct bool, sfOpts s
 		},
 		HavePagesFile: len(files) > 1,
 		Resume:        sfOpts.Resume,
+		RateLimit:     sfOpts.RateLimit,
 	}
 
-	if err := s.call(boot.ContMgrCheckpoint, &opt, nil); err != nil {
+	err = s.call(boot.ContMgrCheckpoint, &opt, nil)
+	if upload != nil {
+		if uploadErr := upload(err); err == nil {
+			err = uploadErr
+		}
+	}
+	if err != nil {
 		return fmt.Errorf("checkpointing container %q: %w", cid, err)
 	}
 	return nil
 }
 
+// SaveProgress returns the progress of the last checkpoint of the sandbox.
+func (s *Sandbox) SaveProgress() (state.SaveProgress, error) {
+	log.Debugf("Getting checkpoint progress of sandbox %q", s.ID)
+	var p state.SaveProgress
+	if err := s.call(boot.StateSaveProgress, nil, &p); err != nil {
+		return state.SaveProgress{}, fmt.Errorf("getting checkpoint progress of sandbox %q: %w", s.ID, err)
+	}
+	return p, nil
+}
+
 // createSaveFiles creates the files used by checkpoint to save the state. They are returned in
 // the following order: sentry state, page metadata, page file. This is the same order expected by
 // RPCs and argument passing to the sandbox.
@@ -1402,6 +1516,142 @@ func createSaveFiles(path string, direct bool, compression statefile.Compression
 	return files, nil
 }
 
+// uploadStateFile returns the write end of a pipe whose contents are uploaded
+// as the state file of the image at the URL imagePath. Images in object
+// storage have no separate pages files, so that they can be streamed. The
+// returned function must be called once the sandbox is done writing the state
+// file, with the error of the save; it completes the upload if the save
+// succeeded, and aborts it otherwise.
+func uploadStateFile(imagePath string) (*os.File, func(saveErr error) error, error) {
+	store, err := imagestore.New(imagePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	w, err := store.Create(context.Background(), boot.CheckpointStateFileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating checkpoint state file in %q: %w", imagePath, err)
+	}
+	r, wf, err := os.Pipe()
+	if err != nil {
+		w.Abort()
+		return nil, nil, fmt.Errorf("creating checkpoint state file pipe: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(w, r)
+		// Fail writes of the sandbox if the upload failed.
+		_ = r.Close()
+		done <- err
+	}()
+	finish := func(saveErr error) error {
+		// The sandbox closed its end of the pipe, so the upload ends once
+		// this end is closed.
+		_ = wf.Close()
+		if err := <-done; err != nil || saveErr != nil {
+			w.Abort()
+			if err != nil {
+				return fmt.Errorf("uploading checkpoint state file to %q: %w", imagePath, err)
+			}
+			return nil
+		}
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("uploading checkpoint state file to %q: %w", imagePath, err)
+		}
+		return nil
+	}
+	return wf, finish, nil
+}
+
+// downloadStateFile returns the read end of a pipe into which the state file
+// of the image at the URL imagePath is downloaded. The returned function
+// returns the error that ended the download, if it ended with one.
+func downloadStateFile(imagePath string) (*os.File, func() error, error) {
+	store, err := imagestore.New(imagePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := store.Open(context.Background(), boot.CheckpointStateFileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening state file in %q: %w", imagePath, err)
+	}
+	rf, w, err := os.Pipe()
+	if err != nil {
+		_ = rc.Close()
+		return nil, nil, fmt.Errorf("creating state file pipe: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(w, rc)
+		_ = rc.Close()
+		if err != nil {
+			err = fmt.Errorf("downloading state file from %q: %w", imagePath, err)
+		}
+		// Report the error before the sandbox sees the end of the
+		// state file.
+		done <- err
+		_ = w.Close()
+	}()
+	downloadErr := func() error {
+		select {
+		case err := <-done:
+			return err
+		default:
+			return nil
+		}
+	}
+	return rf, downloadErr, nil
+}
+
+// cpuNumFromQuota returns the number of CPUs to create inside the sandbox
+// given a CPU quota in CPUs, or -1 for no quota. The result is the least
+// integer greater than or equal to quota, bounded to [minCPUs, cpuNum].
+func cpuNumFromQuota(quota float64, cpuNum, minCPUs int) int {
+	if n := int(math.Ceil(quota)); n > 0 {
+		if n < minCPUs {
+			n = minCPUs
+		}
+		if n < cpuNum {
+			// Only lower the cpu number.
+			return n
+		}
+	}
+	return cpuNum
+}
+
+// applySpecLimits lowers cpuNum and mem to the CPU quota and memory limit in
+// spec's resources, if any.
+func applySpecLimits(spec *specs.Spec, cpuNum int, mem uint64) (int, uint64) {
+	if spec == nil || spec.Linux == nil || spec.Linux.Resources == nil {
+		return cpuNum, mem
+	}
+	res := spec.Linux.Resources
+	if cpu := res.CPU; cpu != nil && cpu.Quota != nil && *cpu.Quota > 0 && cpu.Period != nil && *cpu.Period > 0 {
+		cpuNum = cpuNumFromQuota(float64(*cpu.Quota)/float64(*cpu.Period), cpuNum, 1)
+	}
+	if m := res.Memory; m != nil && m.Limit != nil && *m.Limit > 0 && uint64(*m.Limit) < mem {
+		mem = uint64(*m.Limit)
+	}
+	return cpuNum, mem
+}
+
+// createSwapFile creates the file backing the swap tier of sandbox memory in
+// dir. Like overlay filestores, the file is unlinked immediately so that it's
+// deleted when the sandbox exits.
+func createSwapFile(dir string) (*os.File, error) {
+	f, err := os.CreateTemp(dir, "runsc-swap-")
+	if err != nil {
+		return nil, fmt.Errorf("creating swap file in %q: %w", dir, err)
+	}
+	if err := unix.Unlink(f.Name()); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("unlinking swap file %q: %w", f.Name(), err)
+	}
+	// Perform this work around outside the sandbox. The sandbox may already be
+	// running with seccomp filters that do not allow this.
+	pgalloc.IMAWorkAroundForMemFile(f.Fd())
+	return f, nil
+}
+
 // Pause sends the pause call for a container in the sandbox.
 func (s *Sandbox) Pause(cid string) error {
 	log.Debugf("Pause sandbox %q", s.ID)
@@ -1446,6 +1696,48 @@ func (s *Sandbox) UsageFD() (*control.MemoryUsageRecord, error) {
 	return control.NewMemoryUsageRecord(*m.FilePayload.Files[0], *m.FilePayload.Files[1])
 }
 
+// ResizeMemory changes the total memory of the sandbox to the given number of
+// bytes. The host memory cgroup limit of the sandbox, if any, is updated such
+// that it's never below the memory the sandbox may use: it's raised before
+// growing the sandbox and lowered after shrinking it.
+func (s *Sandbox) ResizeMemory(bytes uint64) (control.ResizeMemoryResult, error) {
+	log.Debugf("Resize memory sandbox %q to %d bytes", s.ID, bytes)
+	if bytes == 0 {
+		return control.ResizeMemoryResult{}, fmt.Errorf("total memory must be greater than 0")
+	}
+	cg := s.CgroupJSON.Cgroup
+	if cg != nil {
+		cur, err := cg.MemoryLimit()
+		if err != nil {
+			return control.ResizeMemoryResult{}, fmt.Errorf("getting memory cgroup limit: %w", err)
+		}
+		if bytes > cur {
+			if err := cg.SetMemoryLimit(bytes); err != nil {
+				return control.ResizeMemoryResult{}, fmt.Errorf("raising memory cgroup limit: %w", err)
+			}
+		}
+	}
+
+	args := control.ResizeMemoryArgs{TotalBytes: bytes}
+	var res control.ResizeMemoryResult
+	if err := s.call(boot.UsageResizeMemory, &args, &res); err != nil {
+		return control.ResizeMemoryResult{}, fmt.Errorf("resizing memory: %w", err)
+	}
+
+	if cg != nil {
+		cur, err := cg.MemoryLimit()
+		if err != nil {
+			return res, fmt.Errorf("getting memory cgroup limit: %w", err)
+		}
+		if bytes < cur {
+			if err := cg.SetMemoryLimit(bytes); err != nil {
+				return res, fmt.Errorf("lowering memory cgroup limit: %w", err)
+			}
+		}
+	}
+	return res, nil
+}
+
 // GetRegisteredMetrics returns metric registration data from the sandbox.
 // This data is meant to be used as a way to sanity-check any exported metrics data during the
 // lifetime of the sandbox in order to avoid a compromised sandbox from being able to produce
@@ -1494,16 +1786,30 @@ func (s *Sandbox) Stacks() (string, error) {
 	return stacks, nil
 }
 
-// HeapProfile writes a heap profile to the given file.
-func (s *Sandbox) HeapProfile(f *os.File, delay time.Duration) error {
+// HeapProfile writes a heap profile to the given file. If delta is true, the
+// profile only contains the allocations made during delay.
+func (s *Sandbox) HeapProfile(f *os.File, delay time.Duration, delta bool) error {
 	log.Debugf("Heap profile %q", s.ID)
 	opts := control.HeapProfileOpts{
 		FilePayload: urpc.FilePayload{Files: []*os.File{f}},
 		Delay:       delay,
+		Delta:       delta,
 	}
 	return s.call(boot.ProfileHeap, &opts, nil)
 }
 
+// GoroutineProfile writes the stacks of all sandbox goroutines to the given
+// file. If annotate is true, task goroutines are annotated with the task that
+// they run.
+func (s *Sandbox) GoroutineProfile(f *os.File, annotate bool) error {
+	log.Debugf("Goroutine profile %q", s.ID)
+	opts := control.GoroutineProfileOpts{
+		FilePayload: urpc.FilePayload{Files: []*os.File{f}},
+		Annotate:    annotate,
+	}
+	return s.call(boot.ProfileGoroutine, &opts, nil)
+}
+
 // CPUProfile collects a CPU profile.
 func (s *Sandbox) CPUProfile(f *os.File, duration time.Duration) error {
 	log.Debugf("CPU profile %q", s.ID)
@@ -1553,6 +1859,44 @@ func (s *Sandbox) ChangeLogging(args control.LoggingArgs) error {
 	return nil
 }
 
+// SetClockSkew replaces the skew of the sandbox clocks.
+func (s *Sandbox) SetClockSkew(args control.ClockSkewArgs) error {
+	log.Debugf("Set clock skew %q", s.ID)
+	if err := s.call(boot.ClockSetSkew, &args, nil); err != nil {
+		return fmt.Errorf("setting sandbox %q clock skew: %w", s.ID, err)
+	}
+	return nil
+}
+
+// SetFaultInjection replaces the syscall fault injection rules of the sandbox.
+func (s *Sandbox) SetFaultInjection(args control.FaultInjectionArgs) error {
+	log.Debugf("Set fault injection %q", s.ID)
+	if err := s.call(boot.FaultInjectionSet, &args, nil); err != nil {
+		return fmt.Errorf("setting sandbox %q fault injection: %w", s.ID, err)
+	}
+	return nil
+}
+
+// SetSyscallPaths selects how the platform waits for applications after the
+// given syscalls.
+func (s *Sandbox) SetSyscallPaths(args control.SyscallPathArgs) error {
+	log.Debugf("Set syscall paths %q", s.ID)
+	if err := s.call(boot.SyscallPathsSet, &args, nil); err != nil {
+		return fmt.Errorf("setting sandbox %q syscall paths: %w", s.ID, err)
+	}
+	return nil
+}
+
+// SyscallPathStats returns the syscall path statistics of the sandbox.
+func (s *Sandbox) SyscallPathStats() ([]control.SyscallPathStat, error) {
+	log.Debugf("Syscall path stats %q", s.ID)
+	var stats control.SyscallPathStats
+	if err := s.call(boot.SyscallPathsStats, nil, &stats); err != nil {
+		return nil, fmt.Errorf("getting sandbox %q syscall path stats: %w", s.ID, err)
+	}
+	return stats.Stats, nil
+}
+
 // DestroyContainer destroys the given container. If it is the root container,
 // then the entire sandbox is destroyed.
 func (s *Sandbox) DestroyContainer(cid string) error {
@@ -1760,6 +2104,69 @@ func (s *Sandbox) fixPidns(spec *specs.Spec) {
 	panic("unreachable")
 }
 
+// startHostAbstractUDSProxy starts a proxy that connects to abstract
+// Unix-domain sockets in the current network namespace on behalf of the
+// sandbox, and returns a socket connected to it. The proxy runs as
+// conf.HostAbstractUDSUID and conf.HostAbstractUDSGID without capabilities,
+// and exits when the socket is closed.
+func startHostAbstractUDSProxy(conf *config.Config) (*os.File, error) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("creating socket pair: %w", err)
+	}
+	sandboxEnd := os.NewFile(uintptr(fds[0]), "host abstract UDS proxy client")
+	proxyEnd := os.NewFile(uintptr(fds[1]), "host abstract UDS proxy server")
+	defer proxyEnd.Close()
+
+	cmd := exec.Command(specutils.ExePath, conf.ToFlags()...)
+	cmd.SysProcAttr = &unix.SysProcAttr{
+		// Detach from this session, otherwise cmd will get SIGHUP and SIGCONT
+		// when re-parented.
+		Setsid: true,
+	}
+	if specutils.HasCapabilities(capability.CAP_SETUID, capability.CAP_SETGID) {
+		// Changing to a non-root user on execve clears all capabilities.
+		cmd.SysProcAttr.Credential = &syscall.Credential{
+			Uid:    uint32(conf.HostAbstractUDSUID),
+			Gid:    uint32(conf.HostAbstractUDSGID),
+			Groups: []uint32{},
+		}
+	} else {
+		log.Warningf("Host abstract UDS proxy will run as the current user (uid=%d gid=%d), since changing user requires CAP_SETUID and CAP_SETGID", os.Getuid(), os.Getgid())
+	}
+	cmd.Args[0] = "runsc-uds-proxy"
+	cmd.Env = []string{}
+
+	// Log files are opened here, since the proxy may not have permission to
+	// open them after changing user.
+	donations := donation.Agency{}
+	defer donations.Close()
+	if err := donations.OpenAndDonate("log-fd", conf.LogFilename, os.O_CREATE|os.O_WRONLY|os.O_APPEND); err != nil {
+		sandboxEnd.Close()
+		return nil, err
+	}
+	if specutils.IsDebugCommand(conf, "uds-proxy") {
+		if err := donations.DonateDebugLogFile("debug-log-fd", conf.DebugLog, "uds-proxy", ""); err != nil {
+			sandboxEnd.Close()
+			return nil, err
+		}
+	}
+	// Start at 3 because 0, 1, and 2 are taken by stdin/out/err.
+	nextFD := donations.Transfer(cmd, 3)
+	cmd.Args = append(cmd.Args, "uds-proxy")
+	donations.Donate("socket-fd", proxyEnd)
+	donations.Transfer(cmd, nextFD)
+
+	if err := cmd.Start(); err != nil {
+		sandboxEnd.Close()
+		return nil, err
+	}
+	log.Infof("Host abstract UDS proxy started, PID: %d", cmd.Process.Pid)
+	// Reap the proxy if it exits while this process is still running.
+	go cmd.Wait()
+	return sandboxEnd, nil
+}
+
 // ConfigureCmdForRootless configures cmd to donate a socket FD that can be
 // used to synchronize userns configuration.
 func ConfigureCmdForRootless(cmd *exec.Cmd, donations *donation.Agency) (*os.File, error) {
@@ -1830,8 +2237,9 @@ func SetUserMappings(spec *specs.Spec, pid int) error {
 	return nil
 }
 
-// Mount mounts a filesystem in a container.
-func (s *Sandbox) Mount(cid, fstype, src, dest string) error {
+// Mount mounts a filesystem in a container. If verityRootHash is non-empty,
+// the EROFS image at src is verified against it.
+func (s *Sandbox) Mount(cid, fstype, src, dest string, verityRootHash []byte) error {
 	var files []*os.File
 	switch fstype {
 	case erofs.Name:
@@ -1845,16 +2253,104 @@ func (s *Sandbox) Mount(cid, fstype, src, dest string) error {
 		return fmt.Errorf("unsupported filesystem type: %v", fstype)
 	}
 
+	args := boot.MountArgs{
+		ContainerID:    cid,
+		Source:         src,
+		Destination:    dest,
+		FsType:         fstype,
+		VerityRootHash: verityRootHash,
+		FilePayload:    urpc.FilePayload{Files: files},
+	}
+	return s.call(boot.ContMgrMount, &args, nil)
+}
+
+// MountGofer mounts the filesystem served by the gofer on sock at dest in a
+// container.
+func (s *Sandbox) MountGofer(cid, dest string, sock *os.File, readonly bool) error {
+	log.Debugf("MountGofer, sandbox: %q, cid: %q, dest: %q, readonly: %t", s.ID, cid, dest, readonly)
 	args := boot.MountArgs{
 		ContainerID: cid,
-		Source:      src,
+		Source:      "runsc-dynamic-mount",
 		Destination: dest,
-		FsType:      fstype,
-		FilePayload: urpc.FilePayload{Files: files},
+		FsType:      gofer.Name,
+		Readonly:    readonly,
+		FilePayload: urpc.FilePayload{Files: []*os.File{sock}},
 	}
 	return s.call(boot.ContMgrMount, &args, nil)
 }
 
+// Unmount unmounts the filesystem mounted at dest in a container.
+func (s *Sandbox) Unmount(cid, dest string) error {
+	log.Debugf("Unmount, sandbox: %q, cid: %q, dest: %q", s.ID, cid, dest)
+	args := boot.UnmountArgs{
+		ContainerID: cid,
+		Destination: dest,
+	}
+	return s.call(boot.ContMgrUnmount, &args, nil)
+}
+
+// AttachGPU makes the NVIDIA GPU with the given minor number, served by the
+// gofer on sock, available to a container.
+func (s *Sandbox) AttachGPU(cid string, minor uint32, sock *os.File) error {
+	log.Debugf("AttachGPU, sandbox: %q, cid: %q, minor: %d", s.ID, cid, minor)
+	args := boot.AttachGPUArgs{
+		ContainerID: cid,
+		Minor:       minor,
+		FilePayload: urpc.FilePayload{Files: []*os.File{sock}},
+	}
+	return s.call(boot.ContMgrAttachGPU, &args, nil)
+}
+
+// DetachGPU removes the NVIDIA GPU with the given minor number, attached with
+// AttachGPU, from a container.
+func (s *Sandbox) DetachGPU(cid string, minor uint32) error {
+	log.Debugf("DetachGPU, sandbox: %q, cid: %q, minor: %d", s.ID, cid, minor)
+	args := boot.DetachGPUArgs{
+		ContainerID: cid,
+		Minor:       minor,
+	}
+	return s.call(boot.ContMgrDetachGPU, &args, nil)
+}
+
+// AddNetworkInterface moves the network interface with the given name from
+// the sandbox's net namespace to the sandbox's network stack, along with its
+// addresses and routes.
+func (s *Sandbox) AddNetworkInterface(conf *config.Config, name string) error {
+	pid := s.Pid.load()
+	log.Debugf("AddNetworkInterface, sandbox: %q, PID: %d, interface: %q", s.ID, pid, name)
+	if conf.Network != config.NetworkSandbox {
+		return fmt.Errorf("network interfaces can only be added with --network=%v, got %v", config.NetworkSandbox, conf.Network)
+	}
+	conn, err := s.sandboxConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	nsPath := filepath.Join("/proc", strconv.Itoa(pid), "ns/net")
+	if err := addInterfaceFromNS(conn, nsPath, name, conf); err != nil {
+		return fmt.Errorf("adding interface %q from net namespace %q: %w", name, nsPath, err)
+	}
+	return nil
+}
+
+// EnableVerity enables verification of all files below the directory at path
+// in a container, and returns the directory's root hash. If rootHash is
+// non-empty, EnableVerity fails if the directory's root hash differs from it.
+func (s *Sandbox) EnableVerity(cid, path string, rootHash []byte) ([]byte, error) {
+	log.Debugf("EnableVerity, sandbox: %q, cid: %q, path: %q", s.ID, cid, path)
+	args := boot.EnableVerityArgs{
+		ContainerID: cid,
+		Path:        path,
+		RootHash:    rootHash,
+	}
+	var got []byte
+	if err := s.call(boot.ContMgrEnableVerity, &args, &got); err != nil {
+		return nil, fmt.Errorf("enabling verity for %q in container %q: %w", path, cid, err)
+	}
+	return got, nil
+}
+
 // ContainerRuntimeState returns the runtime state of a container.
 func (s *Sandbox) ContainerRuntimeState(cid string) (boot.ContainerRuntimeState, error) {
 	log.Debugf("ContainerRuntimeState, sandbox: %q, cid: %q", s.ID, cid)
@@ -0,0 +1,95 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sandbox
+
+import (
+	"testing"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestCPUNumFromQuota(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		quota   float64
+		cpuNum  int
+		minCPUs int
+		want    int
+	}{
+		{name: "no-quota", quota: -1, cpuNum: 8, minCPUs: 1, want: 8},
+		{name: "fraction", quota: 0.2, cpuNum: 8, minCPUs: 1, want: 1},
+		{name: "round-up", quota: 1.9, cpuNum: 8, minCPUs: 1, want: 2},
+		{name: "min-cpus", quota: 0.5, cpuNum: 8, minCPUs: 2, want: 2},
+		{name: "above-cpuset", quota: 16, cpuNum: 8, minCPUs: 1, want: 8},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cpuNumFromQuota(tc.quota, tc.cpuNum, tc.minCPUs); got != tc.want {
+				t.Errorf("cpuNumFromQuota(%v, %d, %d) = %d, want %d", tc.quota, tc.cpuNum, tc.minCPUs, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplySpecLimits(t *testing.T) {
+	int64Ptr := func(v int64) *int64 { return &v }
+	uint64Ptr := func(v uint64) *uint64 { return &v }
+	for _, tc := range []struct {
+		name    string
+		res     *specs.LinuxResources
+		wantCPU int
+		wantMem uint64
+	}{
+		{
+			name:    "no-resources",
+			wantCPU: 8,
+			wantMem: 16 << 30,
+		},
+		{
+			name: "limits",
+			res: &specs.LinuxResources{
+				CPU:    &specs.LinuxCPU{Quota: int64Ptr(150000), Period: uint64Ptr(100000)},
+				Memory: &specs.LinuxMemory{Limit: int64Ptr(1 << 30)},
+			},
+			wantCPU: 2,
+			wantMem: 1 << 30,
+		},
+		{
+			name: "unlimited",
+			res: &specs.LinuxResources{
+				CPU:    &specs.LinuxCPU{Quota: int64Ptr(-1), Period: uint64Ptr(100000)},
+				Memory: &specs.LinuxMemory{Limit: int64Ptr(-1)},
+			},
+			wantCPU: 8,
+			wantMem: 16 << 30,
+		},
+		{
+			name: "above-cgroup",
+			res: &specs.LinuxResources{
+				CPU:    &specs.LinuxCPU{Quota: int64Ptr(1600000), Period: uint64Ptr(100000)},
+				Memory: &specs.LinuxMemory{Limit: int64Ptr(32 << 30)},
+			},
+			wantCPU: 8,
+			wantMem: 16 << 30,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &specs.Spec{Linux: &specs.Linux{Resources: tc.res}}
+			gotCPU, gotMem := applySpecLimits(spec, 8, 16<<30)
+			if gotCPU != tc.wantCPU || gotMem != tc.wantMem {
+				t.Errorf("applySpecLimits() = (%d, %d), want (%d, %d)", gotCPU, gotMem, tc.wantCPU, tc.wantMem)
+			}
+		})
+	}
+}
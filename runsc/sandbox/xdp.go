@@ -72,6 +72,9 @@ func createRedirectInterfacesAndRoutes(conn *urpc.Client, conf *config.Config) e
 	if err := pcapAndNAT(&args, conf); err != nil {
 		return err
 	}
+	if err := dnsProxy(&args, conf); err != nil {
+		return err
+	}
 
 	log.Infof("Setting up network, config: %+v", args)
 	if err := conn.Call(boot.NetworkCreateLinksAndRoutes, &args, nil); err != nil {
@@ -534,6 +537,9 @@ func createXDPTunnel(conn *urpc.Client, nsPath string, conf *config.Config) erro
 	if err := pcapAndNAT(&args, conf); err != nil {
 		return err
 	}
+	if err := dnsProxy(&args, conf); err != nil {
+		return err
+	}
 
 	log.Debugf("Setting up network, config: %+v", args)
 	if err := conn.Call(boot.NetworkCreateLinksAndRoutes, &args, nil); err != nil {
@@ -42,13 +42,29 @@ var (
 // BuildProgram generates a bpf program based on the given OCI seccomp
 // config.
 func BuildProgram(s *specs.LinuxSeccomp) (bpf.Program, error) {
+	instrs, err := BuildInstructions(s)
+	if err != nil {
+		return bpf.Program{}, err
+	}
+
+	program, err := bpf.Compile(instrs, true /* optimize */)
+	if err != nil {
+		return bpf.Program{}, fmt.Errorf("compiling seccomp program: %w", err)
+	}
+
+	return program, nil
+}
+
+// BuildInstructions generates the uncompiled bpf instructions of the program
+// returned by BuildProgram, e.g. to send them to the sandbox.
+func BuildInstructions(s *specs.LinuxSeccomp) ([]bpf.Instruction, error) {
 	defaultAction, err := convertAction(s.DefaultAction)
 	if err != nil {
-		return bpf.Program{}, fmt.Errorf("secomp default action: %w", err)
+		return nil, fmt.Errorf("secomp default action: %w", err)
 	}
 	ruleset, err := convertRules(s)
 	if err != nil {
-		return bpf.Program{}, fmt.Errorf("invalid seccomp rules: %w", err)
+		return nil, fmt.Errorf("invalid seccomp rules: %w", err)
 	}
 
 	instrs, _, err := seccomp.BuildProgram(ruleset, seccomp.ProgramOptions{
@@ -56,15 +72,9 @@ func BuildProgram(s *specs.LinuxSeccomp) (bpf.Program, error) {
 		BadArchAction: killThreadAction,
 	})
 	if err != nil {
-		return bpf.Program{}, fmt.Errorf("building seccomp program: %w", err)
+		return nil, fmt.Errorf("building seccomp program: %w", err)
 	}
-
-	program, err := bpf.Compile(instrs, true /* optimize */)
-	if err != nil {
-		return bpf.Program{}, fmt.Errorf("compiling seccomp program: %w", err)
-	}
-
-	return program, nil
+	return instrs, nil
 }
 
 // lookupSyscallNo gets the syscall number for the syscall with the given name
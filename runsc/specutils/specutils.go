@@ -35,6 +35,8 @@ import (
 	"gvisor.dev/gvisor/pkg/abi/linux"
 	"gvisor.dev/gvisor/pkg/bits"
 	"gvisor.dev/gvisor/pkg/log"
+	"gvisor.dev/gvisor/pkg/sentry/devices/drmproxy"
+	"gvisor.dev/gvisor/pkg/sentry/devices/sndproxy"
 	"gvisor.dev/gvisor/pkg/sentry/devices/tpuproxy"
 	"gvisor.dev/gvisor/pkg/sentry/kernel/auth"
 	"gvisor.dev/gvisor/runsc/config"
@@ -611,6 +613,34 @@ func TPUFunctionalityRequested(spec *specs.Spec, conf *config.Config) bool {
 	return false
 }
 
+// DRMFunctionalityRequested returns true if the container should have access
+// to host DRM render nodes.
+func DRMFunctionalityRequested(spec *specs.Spec, conf *config.Config) bool {
+	if !conf.DRMProxy || spec.Linux == nil {
+		return false
+	}
+	for _, dev := range spec.Linux.Devices {
+		if drmproxy.IsSupportedDevice(dev.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// SoundFunctionalityRequested returns true if the container should have access
+// to host ALSA sound devices.
+func SoundFunctionalityRequested(spec *specs.Spec, conf *config.Config) bool {
+	if !conf.SNDProxy || spec.Linux == nil {
+		return false
+	}
+	for _, dev := range spec.Linux.Devices {
+		if sndproxy.IsSupportedDevice(dev.Path) {
+			return true
+		}
+	}
+	return false
+}
+
 // SafeSetupAndMount creates the mount point and calls Mount with the given
 // flags. procPath is the path to procfs. If it is "", procfs is assumed to be
 // mounted at /proc.
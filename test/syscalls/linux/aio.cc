@@ -14,6 +14,8 @@
 
 #include <fcntl.h>
 #include <linux/aio_abi.h>
+#include <poll.h>
+#include <signal.h>
 #include <sys/mman.h>
 #include <sys/syscall.h>
 #include <sys/types.h>
@@ -30,15 +32,32 @@
 #include "test/util/memory_util.h"
 #include "test/util/posix_error.h"
 #include "test/util/proc_util.h"
+#include "test/util/signal_util.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
 
 using ::testing::_;
 
+#ifndef __NR_io_pgetevents
+#if defined(__x86_64__)
+#define __NR_io_pgetevents 333
+#elif defined(__aarch64__)
+#define __NR_io_pgetevents 292
+#endif
+#endif  // __NR_io_pgetevents
+
 namespace gvisor {
 namespace testing {
 namespace {
 
+// struct __aio_sigset, which may not be defined by older headers.
+struct AIOSigset {
+  const sigset_t* sigmask;
+  size_t sigsetsize;
+};
+
+unsigned kSigsetSize = SIGRTMAX / 8;
+
 // Returns the size of the VMA containing the given address.
 PosixErrorOr<size_t> VmaSizeAt(uintptr_t addr) {
   ASSIGN_OR_RETURN_ERRNO(std::string proc_self_maps,
@@ -83,6 +102,17 @@ class AIOTest : public FileTest {
                                timeout);
   }
 
+  int PGetEvents(long min, long max, struct io_event* events,
+                 struct timespec* timeout, AIOSigset* sigset) {
+    return syscall(__NR_io_pgetevents, ctx_, min, max, events, timeout,
+                   sigset);
+  }
+
+  int Cancel(struct iocb* cb) {
+    struct io_event event = {};
+    return syscall(__NR_io_cancel, ctx_, cb, &event);
+  }
+
   int DestroyContext() { return syscall(__NR_io_destroy, ctx_); }
 
   void TearDown() override {
@@ -93,6 +123,15 @@ class AIOTest : public FileTest {
     }
   }
 
+  struct iocb CreatePollCallback(int fd, int events) {
+    struct iocb cb = {};
+    cb.aio_data = 0x456;
+    cb.aio_fildes = fd;
+    cb.aio_lio_opcode = IOCB_CMD_POLL;
+    cb.aio_buf = events;
+    return cb;
+  }
+
   struct iocb CreateCallback() {
     struct iocb cb = {};
     cb.aio_data = 0x123;
@@ -357,6 +396,186 @@ TEST_F(AIOTest, Timeout) {
   ASSERT_THAT(GetEvents(1, 1, events, &timeout), SyscallSucceedsWithValue(0));
 }
 
+TEST_F(AIOTest, PollReady) {
+  ASSERT_THAT(SetupContext(128), SyscallSucceeds());
+
+  int pipe_fds[2];
+  ASSERT_THAT(pipe(pipe_fds), SyscallSucceeds());
+  FileDescriptor rfd(pipe_fds[0]);
+  FileDescriptor wfd(pipe_fds[1]);
+  ASSERT_THAT(WriteFd(wfd.get(), kData, 1), SyscallSucceedsWithValue(1));
+
+  struct iocb cb = CreatePollCallback(rfd.get(), POLLIN);
+  struct iocb* cbs[1] = {&cb};
+  ASSERT_THAT(Submit(1, cbs), SyscallSucceedsWithValue(1));
+
+  struct io_event events[1];
+  ASSERT_THAT(GetEvents(1, 1, events, nullptr), SyscallSucceedsWithValue(1));
+  EXPECT_EQ(events[0].data, 0x456);
+  EXPECT_EQ(events[0].obj, reinterpret_cast<uint64_t>(&cb));
+  EXPECT_EQ(events[0].res, POLLIN);
+}
+
+TEST_F(AIOTest, PollWaitsForEvents) {
+  ASSERT_THAT(SetupContext(128), SyscallSucceeds());
+
+  int pipe_fds[2];
+  ASSERT_THAT(pipe(pipe_fds), SyscallSucceeds());
+  FileDescriptor rfd(pipe_fds[0]);
+  FileDescriptor wfd(pipe_fds[1]);
+
+  struct iocb cb = CreatePollCallback(rfd.get(), POLLIN);
+  struct iocb* cbs[1] = {&cb};
+  ASSERT_THAT(Submit(1, cbs), SyscallSucceedsWithValue(1));
+
+  // The pipe is empty, so the request must not complete.
+  struct timespec timeout = {.tv_sec = 0, .tv_nsec = 100 * 1000 * 1000};
+  struct io_event events[1];
+  ASSERT_THAT(GetEvents(1, 1, events, &timeout), SyscallSucceedsWithValue(0));
+
+  ASSERT_THAT(WriteFd(wfd.get(), kData, 1), SyscallSucceedsWithValue(1));
+  ASSERT_THAT(GetEvents(1, 1, events, nullptr), SyscallSucceedsWithValue(1));
+  EXPECT_EQ(events[0].data, 0x456);
+  EXPECT_EQ(events[0].res, POLLIN);
+}
+
+TEST_F(AIOTest, PollHangup) {
+  ASSERT_THAT(SetupContext(128), SyscallSucceeds());
+
+  int pipe_fds[2];
+  ASSERT_THAT(pipe(pipe_fds), SyscallSucceeds());
+  FileDescriptor rfd(pipe_fds[0]);
+  FileDescriptor wfd(pipe_fds[1]);
+
+  // POLLHUP is reported even though it isn't requested.
+  struct iocb cb = CreatePollCallback(rfd.get(), POLLIN);
+  struct iocb* cbs[1] = {&cb};
+  ASSERT_THAT(Submit(1, cbs), SyscallSucceedsWithValue(1));
+  wfd.reset();
+
+  struct io_event events[1];
+  ASSERT_THAT(GetEvents(1, 1, events, nullptr), SyscallSucceedsWithValue(1));
+  EXPECT_EQ(events[0].res & POLLHUP, POLLHUP);
+}
+
+TEST_F(AIOTest, PollInvalidArgs) {
+  ASSERT_THAT(SetupContext(128), SyscallSucceeds());
+
+  int pipe_fds[2];
+  ASSERT_THAT(pipe(pipe_fds), SyscallSucceeds());
+  FileDescriptor rfd(pipe_fds[0]);
+  FileDescriptor wfd(pipe_fds[1]);
+
+  struct iocb cb = CreatePollCallback(rfd.get(), POLLIN);
+  struct iocb* cbs[1] = {&cb};
+
+  cb.aio_nbytes = 1;
+  EXPECT_THAT(Submit(1, cbs), SyscallFailsWithErrno(EINVAL));
+  cb.aio_nbytes = 0;
+
+  cb.aio_offset = 1;
+  EXPECT_THAT(Submit(1, cbs), SyscallFailsWithErrno(EINVAL));
+  cb.aio_offset = 0;
+
+  cb.aio_buf = 1ULL << 16;
+  EXPECT_THAT(Submit(1, cbs), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(AIOTest, PollCancel) {
+  ASSERT_THAT(SetupContext(128), SyscallSucceeds());
+
+  int pipe_fds[2];
+  ASSERT_THAT(pipe(pipe_fds), SyscallSucceeds());
+  FileDescriptor rfd(pipe_fds[0]);
+  FileDescriptor wfd(pipe_fds[1]);
+
+  struct iocb cb = CreatePollCallback(rfd.get(), POLLIN);
+  struct iocb* cbs[1] = {&cb};
+  ASSERT_THAT(Submit(1, cbs), SyscallSucceedsWithValue(1));
+
+  EXPECT_THAT(Cancel(&cb), SyscallFailsWithErrno(EINPROGRESS));
+
+  // The cancelled request completes without any events.
+  struct io_event events[1];
+  ASSERT_THAT(GetEvents(1, 1, events, nullptr), SyscallSucceedsWithValue(1));
+  EXPECT_EQ(events[0].data, 0x456);
+  EXPECT_EQ(events[0].res, 0);
+
+  // It can't be cancelled again.
+  EXPECT_THAT(Cancel(&cb), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(AIOTest, CancelNotCancelable) {
+  ASSERT_THAT(SetupContext(128), SyscallSucceeds());
+
+  struct iocb cb = CreateCallback();
+  EXPECT_THAT(Cancel(&cb), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(AIOTest, DestroyWithPendingPoll) {
+  ASSERT_THAT(SetupContext(128), SyscallSucceeds());
+
+  int pipe_fds[2];
+  ASSERT_THAT(pipe(pipe_fds), SyscallSucceeds());
+  FileDescriptor rfd(pipe_fds[0]);
+  FileDescriptor wfd(pipe_fds[1]);
+
+  struct iocb cb = CreatePollCallback(rfd.get(), POLLIN);
+  struct iocb* cbs[1] = {&cb};
+  ASSERT_THAT(Submit(1, cbs), SyscallSucceedsWithValue(1));
+
+  // Destroying the context must not wait for the pipe to become readable.
+  ASSERT_THAT(DestroyContext(), SyscallSucceeds());
+  ctx_ = 0;
+}
+
+TEST_F(AIOTest, PGetEventsTimeout) {
+  ASSERT_THAT(SetupContext(128), SyscallSucceeds());
+
+  sigset_t mask;
+  sigemptyset(&mask);
+  AIOSigset sigset = {&mask, kSigsetSize};
+  struct timespec timeout = {.tv_sec = 0, .tv_nsec = 10};
+  struct io_event events[1];
+  EXPECT_THAT(PGetEvents(1, 1, events, &timeout, &sigset),
+              SyscallSucceedsWithValue(0));
+  EXPECT_THAT(PGetEvents(1, 1, events, &timeout, nullptr),
+              SyscallSucceedsWithValue(0));
+}
+
+TEST_F(AIOTest, PGetEventsInvalidSigsetSize) {
+  ASSERT_THAT(SetupContext(128), SyscallSucceeds());
+
+  sigset_t mask;
+  sigemptyset(&mask);
+  AIOSigset sigset = {&mask, kSigsetSize + 1};
+  struct timespec timeout = {.tv_sec = 0, .tv_nsec = 10};
+  struct io_event events[1];
+  EXPECT_THAT(PGetEvents(1, 1, events, &timeout, &sigset),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(AIOTest, PGetEventsUnblocksSignal) {
+  ASSERT_THAT(SetupContext(128), SyscallSucceeds());
+
+  // Block SIGUSR1 and make it pending; io_pgetevents should be interrupted
+  // once its signal mask unblocks it.
+  struct sigaction sa = {};
+  sa.sa_handler = [](int) {};
+  const auto cleanup_sa =
+      ASSERT_NO_ERRNO_AND_VALUE(ScopedSigaction(SIGUSR1, sa));
+  const auto cleanup_mask =
+      ASSERT_NO_ERRNO_AND_VALUE(ScopedSignalMask(SIG_BLOCK, SIGUSR1));
+  ASSERT_THAT(raise(SIGUSR1), SyscallSucceeds());
+
+  sigset_t mask;
+  sigemptyset(&mask);
+  AIOSigset sigset = {&mask, kSigsetSize};
+  struct io_event events[1];
+  EXPECT_THAT(PGetEvents(1, 1, events, nullptr, &sigset),
+              SyscallFailsWithErrno(EINTR));
+}
+
 class AIOReadWriteParamTest : public AIOTest,
                               public ::testing::WithParamInterface<int> {};
 
@@ -15,26 +15,35 @@
 // All tests in this file rely on being about to mount and unmount cgroupfs,
 // which isn't expected to work, or be safe on a general linux system.
 
+#include <fcntl.h>
 #include <limits.h>
 #include <linux/magic.h>
 #include <sys/mount.h>
 #include <sys/statfs.h>
+#include <sys/syscall.h>
+#include <sys/wait.h>
 #include <unistd.h>
 
 #include <cerrno>
+#include <csignal>
 #include <cstdint>
 
 #include "gmock/gmock.h"
 #include "gtest/gtest.h"
 #include "absl/container/flat_hash_map.h"
 #include "absl/container/flat_hash_set.h"
+#include "absl/strings/str_cat.h"
 #include "absl/strings/str_split.h"
 #include "absl/synchronization/notification.h"
 #include "absl/time/time.h"
+#include "test/util/capability_util.h"
 #include "test/util/cgroup_util.h"
 #include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
 #include "test/util/linux_capability_util.h"
+#include "test/util/logging.h"
 #include "test/util/mount_util.h"
+#include "test/util/multiprocess_util.h"
 #include "test/util/posix_error.h"
 #include "test/util/temp_path.h"
 #include "test/util/test_util.h"
@@ -319,6 +328,33 @@ TEST(Cgroup, MigrateInvalidPID) {
               PosixErrorIs(EINVAL));
 }
 
+// A cgroup chowned to an unprivileged user can be delegated: its owner can
+// move its own processes into it, but not other users' processes.
+TEST(Cgroup, MigrateDelegated) {
+  SKIP_IF(!CgroupsAvailable());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SETUID)));
+  constexpr uid_t kNobody = 65534;
+  Cgroup c = Cgroup::RootCgroup("/sys/fs/cgroup/cpuacct");
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(c.CreateChild("delegated"));
+  ASSERT_THAT(chown(child.Path().c_str(), kNobody, kNobody), SyscallSucceeds());
+  const std::string procs = child.Relpath("cgroup.procs");
+  ASSERT_THAT(chown(procs.c_str(), kNobody, kNobody), SyscallSucceeds());
+
+  const pid_t parent = getpid();
+  const auto rest = [&] {
+    TEST_CHECK_SUCCESS(syscall(SYS_setresuid, kNobody, kNobody, kNobody));
+    int fd = open(procs.c_str(), O_WRONLY);
+    TEST_PCHECK(fd >= 0);
+    const std::string self = "0";
+    TEST_CHECK(write(fd, self.c_str(), self.size()) ==
+               static_cast<ssize_t>(self.size()));
+    const std::string other = absl::StrCat(parent);
+    TEST_CHECK_ERRNO(write(fd, other.c_str(), other.size()), EACCES);
+    close(fd);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
 // Regression test for b/222278194.
 TEST(Cgroup, DuplicateUnlinkOnDirFD) {
   SKIP_IF(!CgroupsAvailable());
@@ -445,6 +481,87 @@ TEST(Cgroup, TIDZeroMovesSelf) {
   EXPECT_FALSE(tasks.contains(syscall(SYS_gettid)));
 }
 
+// struct clone_args is a Linux clone struct. Old versions of glibc do not
+// expose it. See include/uapi/linux/sched.h
+struct clone_args {
+  uint64_t flags;
+  uint64_t pidfd;
+  uint64_t child_tid;
+  uint64_t parent_tid;
+  uint64_t exit_signal;
+  uint64_t stack;
+  uint64_t stack_size;
+  uint64_t tls;
+  uint64_t set_tid;
+  uint64_t set_tid_size;
+  uint64_t cgroup;
+};
+
+#ifndef CLONE_INTO_CGROUP
+#define CLONE_INTO_CGROUP 0x200000000ULL
+#endif  // CLONE_INTO_CGROUP
+
+// CloneIntoCgroup creates a child process in the cgroup open as fd. The child
+// waits to be killed.
+PosixErrorOr<pid_t> CloneIntoCgroup(int fd) {
+  clone_args ca = {};
+  ca.flags = CLONE_INTO_CGROUP;
+  ca.exit_signal = SIGCHLD;
+  ca.cgroup = fd;
+  pid_t pid = syscall(SYS_clone3, &ca, sizeof(ca));
+  if (pid < 0) {
+    return PosixError(errno, "clone3");
+  }
+  if (pid == 0) {
+    while (true) {
+      pause();
+    }
+  }
+  return pid;
+}
+
+TEST(Cgroup, CloneIntoCgroup) {
+  SKIP_IF(!CgroupsAvailable());
+  Cgroup c = Cgroup::RootCgroup("/sys/fs/cgroup/cpuacct");
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(c.CreateChild("child"));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(child.Path(), O_RDONLY | O_DIRECTORY));
+
+  const pid_t pid = ASSERT_NO_ERRNO_AND_VALUE(CloneIntoCgroup(fd.get()));
+  auto cleanup = Cleanup([pid] {
+    EXPECT_THAT(kill(pid, SIGKILL), SyscallSucceeds());
+    EXPECT_THAT(waitpid(pid, nullptr, 0), SyscallSucceedsWithValue(pid));
+  });
+
+  // The new process starts in child, while this process stays in c.
+  auto procs = ASSERT_NO_ERRNO_AND_VALUE(child.Procs());
+  EXPECT_TRUE(procs.contains(pid));
+  EXPECT_FALSE(procs.contains(getpid()));
+  EXPECT_NO_ERRNO(c.ContainsCallingProcess());
+}
+
+TEST(Cgroup, CloneIntoCgroupChargesTarget) {
+  SKIP_IF(!CgroupsAvailable());
+  Cgroup c = Cgroup::RootCgroup("/sys/fs/cgroup/pids");
+  Cgroup child = ASSERT_NO_ERRNO_AND_VALUE(c.CreateChild("child"));
+  ASSERT_NO_ERRNO(child.WriteIntegerControlFile("pids.max", 0));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(child.Path(), O_RDONLY | O_DIRECTORY));
+
+  EXPECT_THAT(CloneIntoCgroup(fd.get()), PosixErrorIs(EAGAIN, _));
+  EXPECT_THAT(child.ReadIntegerControlFile("pids.current"),
+              IsPosixErrorOkAndHolds(0));
+}
+
+TEST(Cgroup, CloneIntoCgroupNotDirectory) {
+  SKIP_IF(!CgroupsAvailable());
+  Cgroup c = Cgroup::RootCgroup("/sys/fs/cgroup/cpuacct");
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(
+      Open(c.Relpath("cgroup.procs"), O_RDONLY));
+
+  EXPECT_THAT(CloneIntoCgroup(fd.get()), PosixErrorIs(EBADF, _));
+}
+
 TEST(Cgroup, NamedHierarchies) {
   SKIP_IF(!CgroupsAvailable());
 
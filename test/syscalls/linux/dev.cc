@@ -170,6 +170,25 @@ TEST(DevTest, ReadDevFuseWithoutMount) {
               SyscallFailsWithErrno(EPERM));
 }
 
+TEST(DevTest, ReadDevHwrng) {
+  int fd = open("/dev/hwrng", O_RDONLY);
+  // The host may not have a hardware RNG.
+  SKIP_IF(!IsRunningOnGvisor() && fd < 0 && errno == ENOENT);
+  ASSERT_THAT(fd, SyscallSucceeds());
+  FileDescriptor f(fd);
+
+  std::vector<char> buf(64);
+  ASSERT_THAT(ReadFd(f.get(), buf.data(), buf.size()), SyscallSucceeds());
+  EXPECT_THAT(lseek(f.get(), 0, SEEK_CUR), SyscallSucceedsWithValue(0));
+}
+
+TEST(DevTest, OpenDevHwrngWritable) {
+  struct stat st;
+  SKIP_IF(!IsRunningOnGvisor() && stat("/dev/hwrng", &st) < 0);
+  EXPECT_THAT(open("/dev/hwrng", O_WRONLY), SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(open("/dev/hwrng", O_RDWR), SyscallFailsWithErrno(EINVAL));
+}
+
 }  // namespace
 }  // namespace testing
 
@@ -18,6 +18,7 @@
 #include <fcntl.h>
 #include <sys/eventfd.h>
 #include <sys/resource.h>
+#include <sys/syscall.h>
 #include <sys/time.h>
 #include <unistd.h>
 
@@ -789,6 +790,153 @@ TEST(ExecveatTest, InterpreterScriptWithCloexecDirFD) {
   EXPECT_EQ(execve_errno, ENOENT);
 }
 
+// Returns a read-only memfd named name with the contents of the file at path.
+//
+// The memfd is reopened read-only since executing a file that is open for
+// writing fails with ETXTBSY.
+PosixErrorOr<FileDescriptor> MemfdCopy(const std::string& name,
+                                       const std::string& path) {
+  ASSIGN_OR_RETURN_ERRNO(std::string contents, GetContents(path));
+  int fd = syscall(__NR_memfd_create, name.c_str(), 0);
+  if (fd < 0) {
+    return PosixError(errno, "memfd_create");
+  }
+  FileDescriptor memfd(fd);
+  if (WriteFd(memfd.get(), contents.data(), contents.size()) !=
+      static_cast<ssize_t>(contents.size())) {
+    return PosixError(errno, "write");
+  }
+  return Open(absl::StrCat("/proc/self/fd/", memfd.get()), O_RDONLY);
+}
+
+// Returns true if the comm of an image executed with execveat(AT_EMPTY_PATH) is
+// derived from the name of the executed file. Before Linux 6.14, it is the
+// number of the file descriptor instead.
+PosixErrorOr<bool> EmptyPathCommFromFile() {
+  if (IsRunningOnGvisor()) {
+    return true;
+  }
+  ASSIGN_OR_RETURN_ERRNO(KernelVersion version, GetKernelVersion());
+  return version.major > 6 || (version.major == 6 && version.minor >= 14);
+}
+
+// AT_EXECFN names the file relative to /dev/fd.
+TEST(ExecveatTest, EmptyPathExecFn) {
+  std::string path = RunfilePath(kStateWorkload);
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path, O_PATH));
+
+  CheckExecveat(fd.get(), "", {path, "PrintExecFn"}, {}, AT_EMPTY_PATH,
+                ArgEnvExitStatus(0, 0),
+                absl::StrCat("/dev/fd/", fd.get(), "\n"));
+}
+
+TEST(ExecveatTest, RelativePathExecFn) {
+  std::string absolute_path = RunfilePath(kStateWorkload);
+  std::string parent_dir = std::string(Dirname(absolute_path));
+  std::string base = std::string(Basename(absolute_path));
+  const FileDescriptor dirfd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(parent_dir, O_DIRECTORY));
+
+  CheckExecveat(dirfd.get(), base, {absolute_path, "PrintExecFn"}, {},
+                /*flags=*/0, ArgEnvExitStatus(0, 0),
+                absl::StrCat("/dev/fd/", dirfd.get(), "/", base, "\n"));
+}
+
+// The image is named after the executed file rather than /dev/fd/N.
+TEST(ExecveatTest, EmptyPathExecName) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(EmptyPathCommFromFile()));
+
+  std::string path = RunfilePath(kStateWorkload);
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path, O_PATH));
+
+  CheckExecveat(fd.get(), "", {path, "PrintExecName"}, {}, AT_EMPTY_PATH,
+                ArgEnvExitStatus(0, 0),
+                absl::StrCat(Basename(path).substr(0, 15), "\n"));
+}
+
+TEST(ExecveatTest, EmptyPathMemfd) {
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(
+      MemfdCopy("exec_test", RunfilePath(kStateWorkload)));
+
+  CheckExecveat(fd.get(), "", {"exec_test", "PrintExecFn"}, {}, AT_EMPTY_PATH,
+                ArgEnvExitStatus(0, 0),
+                absl::StrCat("/dev/fd/", fd.get(), "\n"));
+  CheckExecveat(fd.get(), "", {"exec_test", "PrintExe"}, {}, AT_EMPTY_PATH,
+                ArgEnvExitStatus(0, 0), "/memfd:exec_test (deleted)\n");
+  CheckExecveat(fd.get(), "", {"exec_test", "PrintExeMapping"}, {},
+                AT_EMPTY_PATH, ArgEnvExitStatus(0, 0),
+                "/memfd:exec_test (deleted)\n");
+}
+
+TEST(ExecveatTest, EmptyPathMemfdExecName) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(EmptyPathCommFromFile()));
+
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(
+      MemfdCopy("exec_test", RunfilePath(kStateWorkload)));
+
+  CheckExecveat(fd.get(), "", {"exec_test", "PrintExecName"}, {},
+                AT_EMPTY_PATH, ArgEnvExitStatus(0, 0), "memfd:exec_test\n");
+}
+
+TEST(ExecveatTest, EmptyPathDeletedFile) {
+  std::string contents =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents(RunfilePath(kStateWorkload)));
+  TempPath file = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(GetAbsoluteTestTmpdir(), contents, 0755));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+  const std::string base = std::string(Basename(file.path()));
+  file.reset();
+
+  CheckExecveat(fd.get(), "", {base, "PrintExe"}, {}, AT_EMPTY_PATH,
+                ArgEnvExitStatus(0, 0), absl::StrCat(base, " (deleted)\n"));
+  CheckExecveat(fd.get(), "", {base, "PrintExeMapping"}, {}, AT_EMPTY_PATH,
+                ArgEnvExitStatus(0, 0), absl::StrCat(base, " (deleted)\n"));
+}
+
+// The comm of a deleted file doesn't include the " (deleted)" suffix.
+TEST(ExecveatTest, EmptyPathDeletedFileExecName) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(EmptyPathCommFromFile()));
+
+  std::string contents =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents(RunfilePath(kStateWorkload)));
+  TempPath file = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(GetAbsoluteTestTmpdir(), contents, 0755));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+  const std::string base = std::string(Basename(file.path()));
+  file.reset();
+
+  CheckExecveat(fd.get(), "", {base, "PrintExecName"}, {}, AT_EMPTY_PATH,
+                ArgEnvExitStatus(0, 0), absl::StrCat(base.substr(0, 15), "\n"));
+}
+
+// The interpreter of a script executed through an O_PATH file descriptor opens
+// the script through /dev/fd/N.
+TEST(ExecveatTest, InterpreterScriptWithOPathFD) {
+  std::string path = RunfilePath(kExitScript);
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path, O_PATH));
+
+  CheckExecveat(fd.get(), "", {path, "25"}, {}, AT_EMPTY_PATH,
+                ArgEnvExitStatus(25, 0), "");
+}
+
+TEST(ExecveatTest, InterpreterScriptWithOPathFDExecFn) {
+  // Symlink through /tmp to ensure the path is short enough.
+  TempPath link = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateSymlinkTo(
+      GetShortTestTmpdir(), RunfilePath(kStateWorkload)));
+
+  TempPath script = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetShortTestTmpdir(), absl::StrCat("#!", link.path(), " PrintExecFn"),
+      0755));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(script.path(), O_PATH));
+
+  CheckExecveat(fd.get(), "", {script.path()}, {}, AT_EMPTY_PATH,
+                ArgEnvExitStatus(0, 0),
+                absl::StrCat("/dev/fd/", fd.get(), "\n"));
+}
+
 TEST(ExecveatTest, InvalidFlags) {
   int execve_errno;
   ASSERT_NO_ERRNO_AND_VALUE(ForkAndExecveat(
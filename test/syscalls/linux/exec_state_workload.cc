@@ -12,6 +12,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+#include <limits.h>
 #include <signal.h>
 #include <stdint.h>
 #include <stdio.h>
@@ -19,7 +20,9 @@
 #include <sys/auxv.h>
 #include <sys/prctl.h>
 #include <sys/time.h>
+#include <unistd.h>
 
+#include <fstream>
 #include <iostream>
 #include <ostream>
 #include <string>
@@ -121,13 +124,50 @@ int PrintExecName() {
   return 0;
 }
 
+int PrintExe() {
+  char exe[PATH_MAX + 1] = {0};
+  if (readlink("/proc/self/exe", exe, PATH_MAX) < 0) {
+    perror("readlink");
+    return 1;
+  }
+
+  std::cerr << exe << std::endl;
+  return 0;
+}
+
+// Print the name of the mapping containing our own text, as shown in
+// /proc/self/maps.
+int PrintExeMapping() {
+  const uintptr_t addr = reinterpret_cast<uintptr_t>(&PrintExeMapping);
+  std::ifstream maps("/proc/self/maps");
+  std::string line;
+  while (std::getline(maps, line)) {
+    unsigned long start, end;
+    int name_offset = 0;
+    if (sscanf(line.c_str(), "%lx-%lx %*s %*s %*s %*s %n", &start, &end,
+               &name_offset) < 2) {
+      std::cerr << "invalid maps line: " << line << std::endl;
+      return 1;
+    }
+    if (start <= addr && addr < end) {
+      std::cerr << line.substr(name_offset) << std::endl;
+      return 0;
+    }
+  }
+
+  std::cerr << "no mapping contains " << std::hex << addr << std::endl;
+  return 1;
+}
+
 void usage(const std::string& prog) {
   std::cerr << "usage:\n"
             << "\t" << prog << " CheckSigHandler <signo> <handler addr (hex)>\n"
             << "\t" << prog << " CheckSigBlocked <signo>\n"
             << "\t" << prog << " CheckTimerDisabled <timer>\n"
             << "\t" << prog << " PrintExecFn\n"
-            << "\t" << prog << " PrintExecName" << std::endl;
+            << "\t" << prog << " PrintExecName\n"
+            << "\t" << prog << " PrintExe\n"
+            << "\t" << prog << " PrintExeMapping" << std::endl;
 }
 
 int main(int argc, char** argv) {
@@ -200,6 +240,14 @@ int main(int argc, char** argv) {
     return PrintExecName();
   }
 
+  if (func == "PrintExe") {
+    return PrintExe();
+  }
+
+  if (func == "PrintExeMapping") {
+    return PrintExeMapping();
+  }
+
   std::cerr << "Invalid function: " << func << std::endl;
   return 1;
 }
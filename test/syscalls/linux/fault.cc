@@ -14,10 +14,14 @@
 
 #define _GNU_SOURCE 1
 #include <signal.h>
+#include <sys/mman.h>
 #include <ucontext.h>
 #include <unistd.h>
 
 #include "gtest/gtest.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/logging.h"
+#include "test/util/memory_util.h"
 #include "test/util/test_util.h"
 
 namespace gvisor {
@@ -75,6 +79,70 @@ TEST(FaultTest, InRange) {
   Fault();
 }
 
+// The address expected in si_addr by exit_with_code_handler.
+void* volatile expected_fault_addr = nullptr;
+
+// exit_with_code_handler exits with the si_code of the fault, or 100 if the
+// fault didn't occur at expected_fault_addr.
+void exit_with_code_handler(int sig, siginfo_t* siginfo, void* context) {
+  _exit(siginfo->si_addr == expected_fault_addr ? siginfo->si_code : 100);
+}
+
+// TouchAndExit accesses addr, expecting sig, and exits with the si_code of
+// the signal.
+void TouchAndExit(int sig, void* addr, bool write) {
+  struct sigaction sa = {};
+  sa.sa_sigaction = exit_with_code_handler;
+  sa.sa_flags = SA_SIGINFO;
+  TEST_PCHECK(sigaction(sig, &sa, nullptr) == 0);
+
+  expected_fault_addr = addr;
+  if (write) {
+    *static_cast<volatile char*>(addr) = 0;
+  } else {
+    *static_cast<volatile char*>(addr);
+  }
+  _exit(101);
+}
+
+TEST(FaultTest, SegvMapErr) {
+  Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  void* addr = m.ptr();
+  m.reset();
+
+  EXPECT_EXIT(TouchAndExit(SIGSEGV, addr, false),
+              ::testing::ExitedWithCode(SEGV_MAPERR), "");
+}
+
+TEST(FaultTest, SegvAccErrProtNone) {
+  Mapping m =
+      ASSERT_NO_ERRNO_AND_VALUE(MmapAnon(kPageSize, PROT_NONE, MAP_PRIVATE));
+
+  EXPECT_EXIT(TouchAndExit(SIGSEGV, m.ptr(), false),
+              ::testing::ExitedWithCode(SEGV_ACCERR), "");
+}
+
+TEST(FaultTest, SegvAccErrReadOnly) {
+  Mapping m =
+      ASSERT_NO_ERRNO_AND_VALUE(MmapAnon(kPageSize, PROT_READ, MAP_PRIVATE));
+
+  EXPECT_EXIT(TouchAndExit(SIGSEGV, m.ptr(), true),
+              ::testing::ExitedWithCode(SEGV_ACCERR), "");
+}
+
+TEST(FaultTest, BusAdrErrBeyondEOF) {
+  // Map a page of an empty file.
+  int fd;
+  ASSERT_THAT(fd = memfd_create("fault", 0), SyscallSucceeds());
+  FileDescriptor memfd(fd);
+  Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      Mmap(nullptr, kPageSize, PROT_READ, MAP_SHARED, memfd.get(), 0));
+
+  EXPECT_EXIT(TouchAndExit(SIGBUS, m.ptr(), false),
+              ::testing::ExitedWithCode(BUS_ADRERR), "");
+}
+
 }  // namespace
 
 }  // namespace testing
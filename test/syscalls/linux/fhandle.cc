@@ -0,0 +1,188 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+#include <fcntl.h>
+#include <sys/stat.h>
+#include <unistd.h>
+
+#include <cstdlib>
+#include <string>
+
+#include "gtest/gtest.h"
+#include "test/util/capability_util.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+// FileHandle owns a struct file_handle large enough for any handle.
+class FileHandle {
+ public:
+  FileHandle()
+      : buf_(static_cast<struct file_handle*>(
+            calloc(1, sizeof(struct file_handle) + MAX_HANDLE_SZ))) {
+    buf_->handle_bytes = MAX_HANDLE_SZ;
+  }
+  ~FileHandle() { free(buf_); }
+
+  struct file_handle* get() { return buf_; }
+
+ private:
+  struct file_handle* buf_;
+};
+
+// ASSERT_NAME_TO_HANDLE fills fh with the handle of path, and skips the test
+// if the filesystem containing path doesn't support file handles.
+#define ASSERT_NAME_TO_HANDLE(path, fh)                                \
+  do {                                                                 \
+    int mount_id;                                                      \
+    int ret = name_to_handle_at(AT_FDCWD, (path), (fh), &mount_id, 0); \
+    if (ret < 0 && errno == EOPNOTSUPP) {                              \
+      GTEST_SKIP() << "Filesystem doesn't support file handles";       \
+    }                                                                  \
+    ASSERT_THAT(ret, SyscallSucceeds());                               \
+  } while (0)
+
+TEST(FileHandleTest, BufferTooSmall) {
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  FileHandle fh;
+  int mount_id;
+  fh.get()->handle_bytes = 0;
+  int ret = name_to_handle_at(AT_FDCWD, file.path().c_str(), fh.get(),
+                              &mount_id, 0);
+  if (ret < 0 && errno == EOPNOTSUPP) {
+    GTEST_SKIP() << "Filesystem doesn't support file handles";
+  }
+  EXPECT_THAT(ret, SyscallFailsWithErrno(EOVERFLOW));
+  // The required size is reported back.
+  EXPECT_GT(fh.get()->handle_bytes, 0);
+  EXPECT_LE(fh.get()->handle_bytes, MAX_HANDLE_SZ);
+}
+
+TEST(FileHandleTest, InvalidFlags) {
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFile());
+  FileHandle fh;
+  int mount_id;
+  EXPECT_THAT(name_to_handle_at(AT_FDCWD, file.path().c_str(), fh.get(),
+                                &mount_id, AT_REMOVEDIR),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(FileHandleTest, RoundTrip) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_DAC_READ_SEARCH)));
+
+  constexpr char kContents[] = "handle";
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto file = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(dir.path(), kContents, 0644));
+  FileHandle fh;
+  ASSERT_NAME_TO_HANDLE(file.path().c_str(), fh.get());
+
+  const FileDescriptor mount_fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_RDONLY | O_DIRECTORY));
+  int raw_fd;
+  ASSERT_THAT(raw_fd = open_by_handle_at(mount_fd.get(), fh.get(), O_RDONLY),
+              SyscallSucceeds());
+  const FileDescriptor fd(raw_fd);
+
+  struct stat want, got;
+  ASSERT_THAT(stat(file.path().c_str(), &want), SyscallSucceeds());
+  ASSERT_THAT(fstat(fd.get(), &got), SyscallSucceeds());
+  EXPECT_EQ(got.st_dev, want.st_dev);
+  EXPECT_EQ(got.st_ino, want.st_ino);
+
+  char buf[sizeof(kContents)] = {};
+  EXPECT_THAT(read(fd.get(), buf, sizeof(buf)),
+              SyscallSucceedsWithValue(sizeof(kContents) - 1));
+  EXPECT_STREQ(buf, kContents);
+}
+
+TEST(FileHandleTest, Directory) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_DAC_READ_SEARCH)));
+
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  FileHandle fh;
+  ASSERT_NAME_TO_HANDLE(dir.path().c_str(), fh.get());
+
+  const FileDescriptor mount_fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_RDONLY | O_DIRECTORY));
+  int raw_fd;
+  ASSERT_THAT(raw_fd = open_by_handle_at(mount_fd.get(), fh.get(),
+                                         O_RDONLY | O_DIRECTORY),
+              SyscallSucceeds());
+  const FileDescriptor fd(raw_fd);
+
+  struct stat want, got;
+  ASSERT_THAT(stat(dir.path().c_str(), &want), SyscallSucceeds());
+  ASSERT_THAT(fstat(fd.get(), &got), SyscallSucceeds());
+  EXPECT_EQ(got.st_ino, want.st_ino);
+}
+
+TEST(FileHandleTest, StaleAfterUnlink) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_DAC_READ_SEARCH)));
+
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const std::string path = JoinPath(dir.path(), "file");
+  {
+    const FileDescriptor fd =
+        ASSERT_NO_ERRNO_AND_VALUE(Open(path, O_CREAT | O_RDWR, 0644));
+  }
+  FileHandle fh;
+  ASSERT_NAME_TO_HANDLE(path.c_str(), fh.get());
+  ASSERT_THAT(unlink(path.c_str()), SyscallSucceeds());
+
+  const FileDescriptor mount_fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_RDONLY | O_DIRECTORY));
+  EXPECT_THAT(open_by_handle_at(mount_fd.get(), fh.get(), O_RDONLY),
+              SyscallFailsWithErrno(ESTALE));
+}
+
+TEST(FileHandleTest, CorruptHandle) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_DAC_READ_SEARCH)));
+
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  FileHandle fh;
+  ASSERT_NAME_TO_HANDLE(dir.path().c_str(), fh.get());
+  for (unsigned int i = 0; i < fh.get()->handle_bytes; i++) {
+    fh.get()->f_handle[i] = ~fh.get()->f_handle[i];
+  }
+
+  const FileDescriptor mount_fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_RDONLY | O_DIRECTORY));
+  EXPECT_THAT(open_by_handle_at(mount_fd.get(), fh.get(), O_RDONLY),
+              SyscallFails());
+}
+
+TEST(FileHandleTest, RequiresCapability) {
+  auto dir = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  FileHandle fh;
+  ASSERT_NAME_TO_HANDLE(dir.path().c_str(), fh.get());
+
+  AutoCapability cap(CAP_DAC_READ_SEARCH, false);
+  const FileDescriptor mount_fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir.path(), O_RDONLY | O_DIRECTORY));
+  EXPECT_THAT(open_by_handle_at(mount_fd.get(), fh.get(), O_RDONLY),
+              SyscallFailsWithErrno(EPERM));
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
@@ -24,6 +24,7 @@
 #include <unistd.h>
 
 #include <atomic>
+#include <cstddef>
 #include <cstdint>
 #include <cstdlib>
 
@@ -32,6 +33,8 @@
 #include "absl/time/clock.h"
 #include "absl/time/time.h"
 #include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
 #include "test/util/logging.h"
 #include "test/util/memory_util.h"
 #include "test/util/test_util.h"
@@ -487,6 +490,23 @@ int clone3(struct clone_args* ca, size_t size) {
   return syscall(SYS_clone3, ca, size);
 }
 
+#ifndef CLONE_CLEAR_SIGHAND
+#define CLONE_CLEAR_SIGHAND 0x100000000ULL
+#endif  // CLONE_CLEAR_SIGHAND
+
+#ifndef CLONE_INTO_CGROUP
+#define CLONE_INTO_CGROUP 0x200000000ULL
+#endif  // CLONE_INTO_CGROUP
+
+// Waits for child_pid and checks that it exited successfully.
+void ExpectChildSuccess(pid_t child_pid) {
+  int status;
+  EXPECT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << "status = " << status;
+}
+
 // Checks that clone fails for any unsupported flag.
 TEST(CloneTest, Clone3UnknownFlag) {
   clone_args ca = {};
@@ -536,6 +556,162 @@ TEST(CloneTest, Clone3Basic) {
   EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0);
 }
 
+// Trailing bytes of a larger struct clone_args must be zero.
+TEST(CloneTest, Clone3LargerArgs) {
+  struct {
+    clone_args ca;
+    uint64_t extra;
+  } args = {};
+  args.ca.exit_signal = SIGCHLD;
+  args.extra = 1;
+  EXPECT_THAT(clone3(&args.ca, sizeof(args)), SyscallFailsWithErrno(E2BIG));
+
+  args.extra = 0;
+  int child_pid;
+  ASSERT_THAT(child_pid = clone3(&args.ca, sizeof(args)), SyscallSucceeds());
+  if (child_pid == 0) {
+    _exit(0);
+  }
+  ExpectChildSuccess(child_pid);
+}
+
+TEST(CloneTest, Clone3InvalidArgs) {
+  clone_args ca = {};
+
+  // A stack requires a size.
+  ca.exit_signal = SIGCHLD;
+  ca.stack = reinterpret_cast<uint64_t>(&ca);
+  EXPECT_THAT(clone3(&ca, sizeof(ca)), SyscallFailsWithErrno(EINVAL));
+
+  // exit_signal must be a signal number.
+  ca = {};
+  ca.exit_signal = SIGCHLD | 0x100;
+  EXPECT_THAT(clone3(&ca, sizeof(ca)), SyscallFailsWithErrno(EINVAL));
+
+  // set_tid and set_tid_size must be given together.
+  ca = {};
+  ca.exit_signal = SIGCHLD;
+  ca.set_tid_size = 1;
+  EXPECT_THAT(clone3(&ca, sizeof(ca)), SyscallFailsWithErrno(EINVAL));
+
+  // CLONE_CLEAR_SIGHAND can't be used with shared signal handlers.
+  ca = {};
+  ca.flags = CLONE_CLEAR_SIGHAND | CLONE_SIGHAND | CLONE_VM;
+  ca.exit_signal = SIGCHLD;
+  EXPECT_THAT(clone3(&ca, sizeof(ca)), SyscallFailsWithErrno(EINVAL));
+
+  // CLONE_INTO_CGROUP requires a struct that includes the cgroup field.
+  ca = {};
+  ca.flags = CLONE_INTO_CGROUP;
+  ca.exit_signal = SIGCHLD;
+  EXPECT_THAT(clone3(&ca, offsetof(clone_args, cgroup)),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+// CLONE_INTO_CGROUP requires a cgroup directory.
+TEST(CloneTest, Clone3IntoCgroupNotCgroup) {
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/", O_RDONLY | O_DIRECTORY));
+  clone_args ca = {};
+  ca.flags = CLONE_INTO_CGROUP;
+  ca.exit_signal = SIGCHLD;
+  ca.cgroup = fd.get();
+  EXPECT_THAT(clone3(&ca, sizeof(ca)), SyscallFailsWithErrno(EBADF));
+}
+
+// CLONE_CLEAR_SIGHAND resets handled signals to their default actions, but
+// keeps ignored signals ignored.
+TEST(CloneTest, Clone3ClearSighand) {
+  struct sigaction sa = {};
+  sa.sa_handler = +[](int) {};
+  struct sigaction old_usr1;
+  ASSERT_THAT(sigaction(SIGUSR1, &sa, &old_usr1), SyscallSucceeds());
+  auto restore_usr1 =
+      Cleanup([&] { TEST_PCHECK(sigaction(SIGUSR1, &old_usr1, nullptr) == 0); });
+  sa.sa_handler = SIG_IGN;
+  struct sigaction old_usr2;
+  ASSERT_THAT(sigaction(SIGUSR2, &sa, &old_usr2), SyscallSucceeds());
+  auto restore_usr2 =
+      Cleanup([&] { TEST_PCHECK(sigaction(SIGUSR2, &old_usr2, nullptr) == 0); });
+
+  clone_args ca = {};
+  ca.flags = CLONE_CLEAR_SIGHAND;
+  ca.exit_signal = SIGCHLD;
+  int child_pid;
+  ASSERT_THAT(child_pid = clone3(&ca, sizeof(ca)), SyscallSucceeds());
+  if (child_pid == 0) {
+    struct sigaction cur;
+    TEST_PCHECK(sigaction(SIGUSR1, nullptr, &cur) == 0);
+    TEST_CHECK(cur.sa_handler == SIG_DFL);
+    TEST_PCHECK(sigaction(SIGUSR2, nullptr, &cur) == 0);
+    TEST_CHECK(cur.sa_handler == SIG_IGN);
+    _exit(0);
+  }
+  ExpectChildSuccess(child_pid);
+
+  // The parent's handlers are unaffected.
+  struct sigaction cur;
+  ASSERT_THAT(sigaction(SIGUSR1, nullptr, &cur), SyscallSucceeds());
+  EXPECT_NE(cur.sa_handler, SIG_DFL);
+}
+
+// set_tid chooses the PID of the child.
+TEST(CloneTest, Clone3SetTid) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  // The PID of a reaped child is very likely to be free.
+  pid_t tid = fork();
+  if (tid == 0) {
+    _exit(0);
+  }
+  ASSERT_THAT(tid, SyscallSucceeds());
+  ExpectChildSuccess(tid);
+
+  clone_args ca = {};
+  ca.exit_signal = SIGCHLD;
+  ca.set_tid = reinterpret_cast<uint64_t>(&tid);
+  ca.set_tid_size = 1;
+  int child_pid;
+  ASSERT_THAT(child_pid = clone3(&ca, sizeof(ca)), SyscallSucceeds());
+  if (child_pid == 0) {
+    TEST_CHECK(getpid() == tid);
+    _exit(0);
+  }
+  EXPECT_EQ(child_pid, tid);
+  ExpectChildSuccess(child_pid);
+}
+
+TEST(CloneTest, Clone3SetTidInUse) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  pid_t tid = getpid();
+  clone_args ca = {};
+  ca.exit_signal = SIGCHLD;
+  ca.set_tid = reinterpret_cast<uint64_t>(&tid);
+  ca.set_tid_size = 1;
+  EXPECT_THAT(clone3(&ca, sizeof(ca)), SyscallFailsWithErrno(EEXIST));
+}
+
+TEST(CloneTest, Clone3SetTidTooManyLevels) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  // The test isn't in more than 32 nested PID namespaces, so there are more
+  // TIDs than namespaces.
+  pid_t tids[32];
+  for (pid_t& tid : tids) {
+    tid = getpid();
+  }
+  clone_args ca = {};
+  ca.exit_signal = SIGCHLD;
+  ca.set_tid = reinterpret_cast<uint64_t>(tids);
+  ca.set_tid_size = 32;
+  EXPECT_THAT(clone3(&ca, sizeof(ca)), SyscallFailsWithErrno(EINVAL));
+
+  // More than 32 levels is never valid.
+  ca.set_tid_size = 33;
+  EXPECT_THAT(clone3(&ca, sizeof(ca)), SyscallFailsWithErrno(EINVAL));
+}
+
 }  // namespace
 }  // namespace testing
 }  // namespace gvisor
@@ -15,10 +15,14 @@
 #include <errno.h>
 #include <linux/futex.h>
 #include <linux/types.h>
+#include <pthread.h>
+#include <sys/mman.h>
 #include <sys/syscall.h>
 #include <sys/time.h>
 #include <sys/types.h>
+#include <sys/wait.h>
 #include <syscall.h>
+#include <time.h>
 #include <unistd.h>
 
 #include <algorithm>
@@ -40,11 +44,35 @@
 #include "test/util/time_util.h"
 #include "test/util/timer_util.h"
 
+#ifndef FUTEX_LOCK_PI2
+#define FUTEX_LOCK_PI2 13
+#endif
+
+#ifndef SYS_futex_waitv
+#define SYS_futex_waitv 449
+#endif
+
+#ifndef FUTEX_32
+#define FUTEX_32 2
+#endif
+
+#ifndef FUTEX_WAITV_MAX
+#define FUTEX_WAITV_MAX 128
+#endif
+
 namespace gvisor {
 namespace testing {
 
 namespace {
 
+// struct futex_waitv, which may not be defined by older headers.
+struct FutexWaitv {
+  uint64_t val;
+  uint64_t uaddr;
+  uint32_t flags;
+  uint32_t reserved;
+};
+
 // Amount of time we wait for threads doing futex_wait to start running before
 // doing futex_wake.
 constexpr auto kWaiterStartupDelay = absl::Seconds(3);
@@ -145,6 +173,40 @@ int futex_trylock_pi(bool priv, std::atomic<int>* uaddr) {
   return RetryEINTR(syscall)(SYS_futex, uaddr, op, nullptr, nullptr);
 }
 
+int futex_lock_pi2(bool priv, std::atomic<int>* uaddr,
+                   absl::Time monotonic_deadline) {
+  int op = FUTEX_LOCK_PI2;
+  if (priv) {
+    op |= FUTEX_PRIVATE_FLAG;
+  }
+  struct timespec deadline_ts = absl::ToTimespec(monotonic_deadline);
+  return RetryEINTR(syscall)(SYS_futex, uaddr, op, nullptr, &deadline_ts);
+}
+
+int futex_waitv(FutexWaitv* waiters, unsigned int nr, unsigned int flags,
+                struct timespec* timeout, clockid_t clockid) {
+  return syscall(SYS_futex_waitv, waiters, nr, flags, timeout, clockid);
+}
+
+FutexWaitv MakeFutexWaitv(bool priv, std::atomic<int>* uaddr, int val) {
+  FutexWaitv w = {};
+  w.val = val;
+  w.uaddr = reinterpret_cast<uint64_t>(uaddr);
+  w.flags = FUTEX_32;
+  if (priv) {
+    w.flags |= FUTEX_PRIVATE_FLAG;
+  }
+  return w;
+}
+
+// Returns the current CLOCK_MONOTONIC time, as an absl::Time since that
+// clock's epoch.
+absl::Time MonotonicNow() {
+  struct timespec ts;
+  TEST_PCHECK(clock_gettime(CLOCK_MONOTONIC, &ts) == 0);
+  return absl::TimeFromTimespec(ts);
+}
+
 int futex_unlock_pi(bool priv, std::atomic<int>* uaddr) {
   int op = FUTEX_UNLOCK_PI;
   if (priv) {
@@ -738,6 +800,128 @@ TEST_P(PrivateAndSharedFutexTest, PITryLockConcurrency) {
   }
 }
 
+TEST_P(PrivateAndSharedFutexTest, PI2Timeout) {
+  std::atomic<int> a(0);
+  const bool is_priv = IsPrivate();
+
+  ASSERT_THAT(futex_lock_pi(is_priv, &a), SyscallSucceeds());
+
+  // LOCK_PI2 measures the timeout with CLOCK_MONOTONIC.
+  ScopedThread th([is_priv, &a] {
+    EXPECT_THAT(
+        futex_lock_pi2(is_priv, &a, MonotonicNow() + absl::Milliseconds(100)),
+        SyscallFailsWithErrno(ETIMEDOUT));
+  });
+  th.Join();
+
+  ASSERT_THAT(futex_unlock_pi(is_priv, &a), SyscallSucceeds());
+}
+
+TEST_P(PrivateAndSharedFutexTest, PI2Waiters) {
+  std::atomic<int> a(0);
+  const bool is_priv = IsPrivate();
+
+  ASSERT_THAT(futex_lock_pi(is_priv, &a), SyscallSucceeds());
+
+  ScopedThread th([is_priv, &a] {
+    ASSERT_THAT(
+        futex_lock_pi2(is_priv, &a, MonotonicNow() + absl::Seconds(30)),
+        SyscallSucceeds());
+    EXPECT_EQ(a.load() & FUTEX_TID_MASK, gettid());
+    ASSERT_THAT(futex_unlock_pi(is_priv, &a), SyscallSucceeds());
+  });
+
+  // Wait until the thread blocks on the futex, setting the waiters bit.
+  auto start = absl::Now();
+  while (a.load() != (int)(gettid() | FUTEX_WAITERS)) {
+    ASSERT_LT(absl::Now() - start, absl::Seconds(5));
+    absl::SleepFor(absl::Milliseconds(100));
+  }
+  ASSERT_THAT(futex_unlock_pi(is_priv, &a), SyscallSucceeds());
+}
+
+TEST_P(PrivateAndSharedFutexTest, WaitvWake) {
+  std::atomic<int> a(0);
+  std::atomic<int> b(0);
+  const bool is_priv = IsPrivate();
+
+  ScopedThread th([is_priv, &a, &b] {
+    FutexWaitv waiters[] = {
+        MakeFutexWaitv(is_priv, &a, 0),
+        MakeFutexWaitv(is_priv, &b, 0),
+    };
+    // futex_waitv(2) reports the index of the woken futex.
+    EXPECT_THAT(RetryEINTR(futex_waitv)(waiters, 2, 0, nullptr, 0),
+                SyscallSucceedsWithValue(1));
+  });
+
+  absl::SleepFor(kWaiterStartupDelay);
+  EXPECT_THAT(futex_wake(is_priv, &b, 1), SyscallSucceedsWithValue(1));
+}
+
+TEST_P(PrivateAndSharedFutexTest, WaitvWrongVal) {
+  std::atomic<int> a(0);
+  std::atomic<int> b(1);
+  FutexWaitv waiters[] = {
+      MakeFutexWaitv(IsPrivate(), &a, 0),
+      MakeFutexWaitv(IsPrivate(), &b, 0),
+  };
+  EXPECT_THAT(futex_waitv(waiters, 2, 0, nullptr, 0),
+              SyscallFailsWithErrno(EAGAIN));
+}
+
+TEST_P(PrivateAndSharedFutexTest, WaitvTimeout) {
+  std::atomic<int> a(0);
+  FutexWaitv waiters[] = {MakeFutexWaitv(IsPrivate(), &a, 0)};
+
+  struct timespec deadline =
+      absl::ToTimespec(MonotonicNow() + absl::Milliseconds(100));
+  EXPECT_THAT(RetryEINTR(futex_waitv)(waiters, 1, 0, &deadline,
+                                      CLOCK_MONOTONIC),
+              SyscallFailsWithErrno(ETIMEDOUT));
+
+  deadline = absl::ToTimespec(absl::Now() + absl::Milliseconds(100));
+  EXPECT_THAT(
+      RetryEINTR(futex_waitv)(waiters, 1, 0, &deadline, CLOCK_REALTIME),
+      SyscallFailsWithErrno(ETIMEDOUT));
+}
+
+TEST(FutexWaitvTest, InvalidArgs) {
+  std::atomic<int> a(0);
+  FutexWaitv waiters[FUTEX_WAITV_MAX + 1];
+  for (auto& w : waiters) {
+    w = MakeFutexWaitv(true, &a, 0);
+  }
+  struct timespec deadline = {};
+
+  // Bad flags, or bad number of futexes.
+  EXPECT_THAT(futex_waitv(waiters, 1, 1, nullptr, 0),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(futex_waitv(waiters, 0, 0, nullptr, 0),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(futex_waitv(waiters, FUTEX_WAITV_MAX + 1, 0, nullptr, 0),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(futex_waitv(nullptr, 1, 0, nullptr, 0),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Unsupported clock.
+  EXPECT_THAT(futex_waitv(waiters, 1, 0, &deadline, CLOCK_BOOTTIME),
+              SyscallFailsWithErrno(EINVAL));
+
+  // Bad waiter fields.
+  waiters[0].reserved = 1;
+  EXPECT_THAT(futex_waitv(waiters, 1, 0, nullptr, 0),
+              SyscallFailsWithErrno(EINVAL));
+  waiters[0] = MakeFutexWaitv(true, &a, 0);
+  waiters[0].flags &= ~FUTEX_32;
+  EXPECT_THAT(futex_waitv(waiters, 1, 0, nullptr, 0),
+              SyscallFailsWithErrno(EINVAL));
+  waiters[0] = MakeFutexWaitv(true, &a, 0);
+  waiters[0].uaddr += 1;
+  EXPECT_THAT(futex_waitv(waiters, 1, 0, nullptr, 0),
+              SyscallFailsWithErrno(EINVAL));
+}
+
 // Robust mutex tests are disabled on Android because Bionic (Android's libc)
 // doesn't support robust pthread mutexes.
 #ifndef __ANDROID__
@@ -833,6 +1017,80 @@ TEST(RobustFutexTest, PthreadMutexAttr) {
   }
 }
 
+TEST(RobustFutexTest, PthreadPIMutexOwnerDied) {
+  pthread_mutexattr_t attr;
+  pthread_mutex_t mtx;
+  TEST_PCHECK(pthread_mutexattr_init(&attr) == 0);
+  TEST_PCHECK(pthread_mutexattr_setrobust(&attr, PTHREAD_MUTEX_ROBUST) == 0);
+  TEST_PCHECK(pthread_mutexattr_setprotocol(&attr, PTHREAD_PRIO_INHERIT) == 0);
+  TEST_PCHECK(pthread_mutex_init(&mtx, &attr) == 0);
+
+  // Start a thread that locks the mutex and exits while this thread is
+  // blocked on it.
+  std::atomic<bool> locked(false);
+  ScopedThread t([&] {
+    TEST_PCHECK(pthread_mutex_lock(&mtx) == 0);
+    locked.store(true);
+    absl::SleepFor(absl::Seconds(1));
+    pthread_exit(NULL);
+  });
+  while (!locked.load()) {
+    absl::SleepFor(absl::Milliseconds(10));
+  }
+
+  // The mutex is handed over with the owner died bit set.
+  EXPECT_EQ(pthread_mutex_lock(&mtx), EOWNERDEAD);
+  EXPECT_EQ(pthread_mutex_consistent(&mtx), 0);
+  EXPECT_EQ(pthread_mutex_unlock(&mtx), 0);
+  t.Join();
+
+  // Without waiters, the mutex is released with the owner died bit set.
+  ScopedThread t2([&] {
+    TEST_PCHECK(pthread_mutex_lock(&mtx) == 0);
+    pthread_exit(NULL);
+  });
+  t2.Join();
+  EXPECT_EQ(pthread_mutex_lock(&mtx), EOWNERDEAD);
+  EXPECT_EQ(pthread_mutex_consistent(&mtx), 0);
+  EXPECT_EQ(pthread_mutex_unlock(&mtx), 0);
+}
+
+TEST(RobustFutexTest, ReleasedOnExec) {
+  // Place a process-shared robust mutex in shared memory.
+  Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_SHARED));
+  pthread_mutex_t* mtx = reinterpret_cast<pthread_mutex_t*>(m.ptr());
+  pthread_mutexattr_t attr;
+  TEST_PCHECK(pthread_mutexattr_init(&attr) == 0);
+  TEST_PCHECK(pthread_mutexattr_setrobust(&attr, PTHREAD_MUTEX_ROBUST) == 0);
+  TEST_PCHECK(pthread_mutexattr_setpshared(&attr, PTHREAD_PROCESS_SHARED) ==
+              0);
+  TEST_PCHECK(pthread_mutex_init(mtx, &attr) == 0);
+
+  // The child locks the mutex and then execs. The new image doesn't know
+  // about the robust list, so the mutex must be released by execve(2)
+  // rather than by exit.
+  pid_t child = fork();
+  if (child == 0) {
+    TEST_PCHECK(pthread_mutex_lock(mtx) == 0);
+    execl("/bin/true", "true", nullptr);
+    _exit(1);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << " status " << status;
+
+  struct timespec deadline =
+      absl::ToTimespec(absl::Now() + absl::Seconds(10));
+  EXPECT_EQ(pthread_mutex_timedlock(mtx, &deadline), EOWNERDEAD);
+  EXPECT_EQ(pthread_mutex_consistent(mtx), 0);
+  EXPECT_EQ(pthread_mutex_unlock(mtx), 0);
+}
+
 #endif  // __ANDROID__
 
 }  // namespace
@@ -13,10 +13,17 @@
 // limitations under the License.
 
 #include <sched.h>
+#include <sys/syscall.h>
+#include <unistd.h>
+
+#include <string>
 
 #include "gtest/gtest.h"
+#include "absl/strings/match.h"
+#include "absl/strings/str_cat.h"
 #include "absl/time/clock.h"
 #include "absl/time/time.h"
+#include "test/util/fs_util.h"
 #include "test/util/test_util.h"
 
 namespace gvisor {
@@ -54,6 +61,27 @@ TEST(GetcpuTest, IsValidCpu) {
   }
 }
 
+// The NUMA node reported by getcpu is the one that contains the CPU in sysfs.
+TEST(GetcpuTest, NodeMatchesSysfs) {
+  unsigned cpu, node;
+  ASSERT_THAT(syscall(SYS_getcpu, &cpu, &node, nullptr), SyscallSucceeds());
+  auto entries =
+      ListDir(absl::StrCat("/sys/devices/system/cpu/cpu", cpu), true);
+  if (!entries.ok()) {
+    GTEST_SKIP() << "Can't list sysfs CPU directory: " << entries.error();
+  }
+  bool found = false;
+  for (const std::string& entry : entries.ValueOrDie()) {
+    if (absl::StartsWith(entry, "node")) {
+      found = true;
+      EXPECT_EQ(entry, absl::StrCat("node", node));
+    }
+  }
+  if (!found) {
+    GTEST_SKIP() << "sysfs doesn't report the NUMA node of CPU " << cpu;
+  }
+}
+
 }  // namespace
 
 }  // namespace testing
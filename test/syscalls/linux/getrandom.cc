@@ -12,11 +12,22 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+#include <elf.h>
+#include <link.h>
+#include <string.h>
+#include <sys/auxv.h>
+#include <sys/mman.h>
 #include <sys/syscall.h>
 #include <sys/types.h>
 #include <unistd.h>
 
+#include <cstdint>
+#include <vector>
+
 #include "gtest/gtest.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/memory_util.h"
+#include "test/util/multiprocess_util.h"
 #include "test/util/test_util.h"
 
 namespace gvisor {
@@ -57,6 +68,155 @@ TEST(GetrandomTest, IsRandom) {
   EXPECT_TRUE(SomeByteIsNonZero(random_bytes, n));
 }
 
+#if defined(__x86_64__)
+constexpr char kVgetrandomSymbol[] = "__vdso_getrandom";
+#elif defined(__aarch64__)
+constexpr char kVgetrandomSymbol[] = "__kernel_getrandom";
+#endif
+
+using VgetrandomFn = ssize_t (*)(void* buffer, size_t len, unsigned int flags,
+                                 void* opaque_state, size_t opaque_len);
+
+struct VgetrandomOpaqueParams {
+  uint32_t size_of_opaque_state;
+  uint32_t mmap_prot;
+  uint32_t mmap_flags;
+  uint32_t reserved[13];
+};
+
+// FindVgetrandom returns the VDSO's vgetrandom function, or nullptr if the
+// VDSO doesn't have one.
+VgetrandomFn FindVgetrandom() {
+  auto const base = getauxval(AT_SYSINFO_EHDR);
+  if (base == 0) {
+    return nullptr;
+  }
+  auto const* ehdr = reinterpret_cast<const ElfW(Ehdr)*>(base);
+  auto const* phdrs = reinterpret_cast<const ElfW(Phdr)*>(base + ehdr->e_phoff);
+  uintptr_t load_offset = 0;
+  const ElfW(Dyn)* dyn = nullptr;
+  bool have_load_offset = false;
+  for (int i = 0; i < ehdr->e_phnum; i++) {
+    if (phdrs[i].p_type == PT_LOAD && !have_load_offset) {
+      load_offset = base + phdrs[i].p_offset - phdrs[i].p_vaddr;
+      have_load_offset = true;
+    } else if (phdrs[i].p_type == PT_DYNAMIC) {
+      dyn = reinterpret_cast<const ElfW(Dyn)*>(base + phdrs[i].p_offset);
+    }
+  }
+  if (!have_load_offset || dyn == nullptr) {
+    return nullptr;
+  }
+
+  const ElfW(Sym)* symtab = nullptr;
+  const char* strtab = nullptr;
+  const ElfW(Word)* hash = nullptr;
+  for (; dyn->d_tag != DT_NULL; dyn++) {
+    switch (dyn->d_tag) {
+      case DT_SYMTAB:
+        symtab = reinterpret_cast<const ElfW(Sym)*>(load_offset +
+                                                    dyn->d_un.d_ptr);
+        break;
+      case DT_STRTAB:
+        strtab = reinterpret_cast<const char*>(load_offset + dyn->d_un.d_ptr);
+        break;
+      case DT_HASH:
+        hash = reinterpret_cast<const ElfW(Word)*>(load_offset +
+                                                   dyn->d_un.d_ptr);
+        break;
+    }
+  }
+  if (symtab == nullptr || strtab == nullptr || hash == nullptr) {
+    return nullptr;
+  }
+  // The second word of the hash table is the number of symbols.
+  for (ElfW(Word) i = 0; i < hash[1]; i++) {
+    if (ELF64_ST_TYPE(symtab[i].st_info) == STT_FUNC &&
+        symtab[i].st_shndx != SHN_UNDEF &&
+        strcmp(strtab + symtab[i].st_name, kVgetrandomSymbol) == 0) {
+      return reinterpret_cast<VgetrandomFn>(load_offset + symtab[i].st_value);
+    }
+  }
+  return nullptr;
+}
+
+// NewVgetrandomState returns a mapping containing a vgetrandom state
+// allocated as specified by params.
+PosixErrorOr<Mapping> NewVgetrandomState(VgetrandomFn vgetrandom,
+                                         VgetrandomOpaqueParams* params) {
+  if (vgetrandom(nullptr, 0, 0, params, ~0UL) != 0) {
+    return PosixError(EINVAL, "vgetrandom failed to return parameters");
+  }
+  if (params->size_of_opaque_state == 0 ||
+      params->size_of_opaque_state > kPageSize) {
+    return PosixError(EINVAL, "invalid vgetrandom state size");
+  }
+  return Mmap(nullptr, kPageSize, params->mmap_prot, params->mmap_flags, -1,
+              0);
+}
+
+TEST(VgetrandomTest, Generate) {
+  VgetrandomFn vgetrandom = FindVgetrandom();
+  SKIP_IF(!IsRunningOnGvisor() && vgetrandom == nullptr);
+  ASSERT_NE(vgetrandom, nullptr);
+
+  VgetrandomOpaqueParams params = {};
+  Mapping state =
+      ASSERT_NO_ERRNO_AND_VALUE(NewVgetrandomState(vgetrandom, &params));
+
+  for (size_t len : {1, 32, 63, 64, 65, 200, 4096}) {
+    std::vector<char> first(len), second(len);
+    ASSERT_EQ(vgetrandom(first.data(), len, 0, state.ptr(),
+                         params.size_of_opaque_state),
+              static_cast<ssize_t>(len));
+    ASSERT_EQ(vgetrandom(second.data(), len, 0, state.ptr(),
+                         params.size_of_opaque_state),
+              static_cast<ssize_t>(len));
+    if (len >= 32) {
+      EXPECT_TRUE(SomeByteIsNonZero(first.data(), len));
+      EXPECT_NE(first, second);
+    }
+  }
+}
+
+TEST(VgetrandomTest, ForkedChildGetsDifferentBytes) {
+  VgetrandomFn vgetrandom = FindVgetrandom();
+  SKIP_IF(!IsRunningOnGvisor() && vgetrandom == nullptr);
+  ASSERT_NE(vgetrandom, nullptr);
+
+  VgetrandomOpaqueParams params = {};
+  Mapping state =
+      ASSERT_NO_ERRNO_AND_VALUE(NewVgetrandomState(vgetrandom, &params));
+  char parent_bytes[32];
+  ASSERT_EQ(vgetrandom(parent_bytes, sizeof(parent_bytes), 0, state.ptr(),
+                       params.size_of_opaque_state),
+            static_cast<ssize_t>(sizeof(parent_bytes)));
+
+  // Both the parent and the child generate the next bytes from the state.
+  // They must not be the same.
+  int fds[2];
+  ASSERT_THAT(pipe(fds), SyscallSucceeds());
+  FileDescriptor rfd(fds[0]);
+  FileDescriptor wfd(fds[1]);
+  const auto rest = [&] {
+    char child_bytes[32];
+    TEST_CHECK(vgetrandom(child_bytes, sizeof(child_bytes), 0, state.ptr(),
+                          params.size_of_opaque_state) ==
+               static_cast<ssize_t>(sizeof(child_bytes)));
+    TEST_CHECK(WriteFd(wfd.get(), child_bytes, sizeof(child_bytes)) ==
+               static_cast<ssize_t>(sizeof(child_bytes)));
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+
+  ASSERT_EQ(vgetrandom(parent_bytes, sizeof(parent_bytes), 0, state.ptr(),
+                       params.size_of_opaque_state),
+            static_cast<ssize_t>(sizeof(parent_bytes)));
+  char child_bytes[32];
+  ASSERT_THAT(ReadFd(rfd.get(), child_bytes, sizeof(child_bytes)),
+              SyscallSucceedsWithValue(sizeof(child_bytes)));
+  EXPECT_NE(memcmp(parent_bytes, child_bytes, sizeof(parent_bytes)), 0);
+}
+
 }  // namespace
 
 }  // namespace testing
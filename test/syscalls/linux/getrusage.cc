@@ -17,6 +17,7 @@
 #include <sys/resource.h>
 #include <sys/types.h>
 #include <sys/wait.h>
+#include <time.h>
 
 #include "gtest/gtest.h"
 #include "absl/time/clock.h"
@@ -179,6 +180,29 @@ TEST(GetrusageTest, Wait4) {
   EXPECT_GT(rusage_children.ru_maxrss, 0);
 }
 
+TEST(GetrusageTest, MatchesProcessCPUTime) {
+  // Spin so that both user and system time are non-trivial.
+  const absl::Time deadline = absl::Now() + absl::Milliseconds(100);
+  while (absl::Now() < deadline) {
+    getppid();
+  }
+
+  struct timespec before, after;
+  ASSERT_THAT(clock_gettime(CLOCK_PROCESS_CPUTIME_ID, &before),
+              SyscallSucceeds());
+  struct rusage rusage_self;
+  ASSERT_THAT(getrusage(RUSAGE_SELF, &rusage_self), SyscallSucceeds());
+  ASSERT_THAT(clock_gettime(CLOCK_PROCESS_CPUTIME_ID, &after),
+              SyscallSucceeds());
+
+  // ru_utime and ru_stime add up to the precise CPU time of the process,
+  // truncated to microseconds.
+  const absl::Duration total = absl::DurationFromTimeval(rusage_self.ru_utime) +
+                               absl::DurationFromTimeval(rusage_self.ru_stime);
+  EXPECT_GE(total, absl::DurationFromTimespec(before) - absl::Microseconds(2));
+  EXPECT_LE(total, absl::DurationFromTimespec(after));
+}
+
 }  // namespace
 
 }  // namespace testing
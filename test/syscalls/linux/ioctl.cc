@@ -15,6 +15,8 @@
 #include <arpa/inet.h>
 #include <errno.h>
 #include <fcntl.h>
+#include <linux/ethtool.h>
+#include <linux/sockios.h>
 #include <net/if.h>
 #include <netdb.h>
 #include <signal.h>
@@ -27,6 +29,8 @@
 #include "gtest/gtest.h"
 #include "test/syscalls/linux/ip_socket_test_util.h"
 #include "test/syscalls/linux/unix_domain_socket_test_util.h"
+#include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
 #include "test/util/file_descriptor.h"
 #include "test/util/signal_util.h"
 #include "test/util/socket_util.h"
@@ -430,5 +434,100 @@ TEST_F(IoctlTest, SIOCGPGRPSucceeds) {
   EXPECT_EQ(get, 0);
 }
 
+TEST_F(IoctlTest, EthtoolGLinkLoopback) {
+  SKIP_IF(IsRunningWithHostinet());
+  const FileDescriptor s =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+
+  struct ethtool_value value = {};
+  value.cmd = ETHTOOL_GLINK;
+  struct ifreq ifr = {};
+  strncpy(ifr.ifr_name, "lo", IFNAMSIZ);
+  ifr.ifr_data = reinterpret_cast<char*>(&value);
+  ASSERT_THAT(ioctl(s.get(), SIOCETHTOOL, &ifr), SyscallSucceeds());
+  EXPECT_EQ(value.data, 1);
+}
+
+TEST_F(IoctlTest, SetMTU) {
+  SKIP_IF(IsRunningWithHostinet());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  const FileDescriptor s =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+
+  struct ifreq ifr = {};
+  strncpy(ifr.ifr_name, "lo", IFNAMSIZ);
+  ASSERT_THAT(ioctl(s.get(), SIOCGIFMTU, &ifr), SyscallSucceeds());
+  const int orig_mtu = ifr.ifr_mtu;
+  auto restore = Cleanup([&] {
+    struct ifreq ifr = {};
+    strncpy(ifr.ifr_name, "lo", IFNAMSIZ);
+    ifr.ifr_mtu = orig_mtu;
+    EXPECT_THAT(ioctl(s.get(), SIOCSIFMTU, &ifr), SyscallSucceeds());
+  });
+
+  constexpr int kMTU = 1280;
+  ifr.ifr_mtu = kMTU;
+  ASSERT_THAT(ioctl(s.get(), SIOCSIFMTU, &ifr), SyscallSucceeds());
+
+  ifr.ifr_mtu = 0;
+  ASSERT_THAT(ioctl(s.get(), SIOCGIFMTU, &ifr), SyscallSucceeds());
+  EXPECT_EQ(ifr.ifr_mtu, kMTU);
+
+  ifr.ifr_mtu = -1;
+  EXPECT_THAT(ioctl(s.get(), SIOCSIFMTU, &ifr),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(IoctlTest, SetNonContiguousNetmask) {
+  SKIP_IF(IsRunningWithHostinet());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+  const FileDescriptor s =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+
+  struct ifreq ifr = {};
+  strncpy(ifr.ifr_name, "lo", IFNAMSIZ);
+  struct sockaddr_in* mask = reinterpret_cast<sockaddr_in*>(&ifr.ifr_netmask);
+  mask->sin_family = AF_INET;
+  mask->sin_addr.s_addr = htonl(0xff00ff00);
+  EXPECT_THAT(ioctl(s.get(), SIOCSIFNETMASK, &ifr),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_F(IoctlTest, SetInterfaceWithoutCapability) {
+  SKIP_IF(IsRunningWithHostinet());
+  AutoCapability cap(CAP_NET_ADMIN, false);
+  const FileDescriptor s =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+
+  struct ifreq ifr = {};
+  strncpy(ifr.ifr_name, "lo", IFNAMSIZ);
+  struct sockaddr_in* addr = reinterpret_cast<sockaddr_in*>(&ifr.ifr_addr);
+  addr->sin_family = AF_INET;
+  addr->sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  EXPECT_THAT(ioctl(s.get(), SIOCSIFADDR, &ifr),
+              SyscallFailsWithErrno(EACCES));
+
+  ifr = {};
+  strncpy(ifr.ifr_name, "lo", IFNAMSIZ);
+  ASSERT_THAT(ioctl(s.get(), SIOCGIFFLAGS, &ifr), SyscallSucceeds());
+  EXPECT_THAT(ioctl(s.get(), SIOCSIFFLAGS, &ifr),
+              SyscallFailsWithErrno(EPERM));
+}
+
+TEST_F(IoctlTest, EthtoolGDrvinfoLoopbackNotSupported) {
+  SKIP_IF(IsRunningWithHostinet());
+  const FileDescriptor s =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(AF_INET, SOCK_DGRAM, 0));
+
+  // The loopback device has no driver information on Linux.
+  struct ethtool_drvinfo info = {};
+  info.cmd = ETHTOOL_GDRVINFO;
+  struct ifreq ifr = {};
+  strncpy(ifr.ifr_name, "lo", IFNAMSIZ);
+  ifr.ifr_data = reinterpret_cast<char*>(&info);
+  EXPECT_THAT(ioctl(s.get(), SIOCETHTOOL, &ifr),
+              SyscallFailsWithErrno(EOPNOTSUPP));
+}
+
 }  // namespace testing
 }  // namespace gvisor
@@ -245,6 +245,61 @@ TEST(MadviseDontforkTest, DontforkAnonPrivate) {
   ExpectAllMappingBytes(mp3, 3);
 }
 
+#ifndef MADV_WIPEONFORK
+#define MADV_WIPEONFORK 18
+#endif
+#ifndef MADV_KEEPONFORK
+#define MADV_KEEPONFORK 19
+#endif
+
+TEST(MadviseWipeonforkTest, WipeonforkAnonPrivate) {
+  // Mmap two anonymous pages and MADV_WIPEONFORK the second page.
+  Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize * 2, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  const Mapping mp1 = Mapping(reinterpret_cast<void*>(m.addr()), kPageSize);
+  const Mapping mp2 =
+      Mapping(reinterpret_cast<void*>(m.addr() + kPageSize), kPageSize);
+  m.release();
+
+  ASSERT_THAT(madvise(mp2.ptr(), kPageSize, MADV_WIPEONFORK),
+              SyscallSucceeds());
+  memset(mp1.ptr(), 1, kPageSize);
+  memset(mp2.ptr(), 2, kPageSize);
+
+  const auto rest = [&] {
+    // The first page is copied as usual.
+    CheckAllMappingBytes(mp1, 1);
+
+    // The second page is mapped, but zeroed.
+    TEST_CHECK(IsMapped(mp2.addr()));
+    CheckAllMappingBytes(mp2, 0);
+    memset(mp2.ptr(), 12, kPageSize);
+    CheckAllMappingBytes(mp2, 12);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+
+  ExpectAllMappingBytes(mp1, 1);
+  ExpectAllMappingBytes(mp2, 2);
+}
+
+TEST(MadviseWipeonforkTest, Keeponfork) {
+  Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_PRIVATE));
+  ASSERT_THAT(madvise(m.ptr(), kPageSize, MADV_WIPEONFORK), SyscallSucceeds());
+  ASSERT_THAT(madvise(m.ptr(), kPageSize, MADV_KEEPONFORK), SyscallSucceeds());
+  memset(m.ptr(), 1, kPageSize);
+
+  const auto rest = [&] { CheckAllMappingBytes(m, 1); };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+TEST(MadviseWipeonforkTest, SharedFails) {
+  Mapping m = ASSERT_NO_ERRNO_AND_VALUE(
+      MmapAnon(kPageSize, PROT_READ | PROT_WRITE, MAP_SHARED));
+  EXPECT_THAT(madvise(m.ptr(), kPageSize, MADV_WIPEONFORK),
+              SyscallFailsWithErrno(EINVAL));
+}
+
 }  // namespace
 
 }  // namespace testing
@@ -18,6 +18,7 @@
 #include <linux/unistd.h>
 #include <string.h>
 #include <sys/mman.h>
+#include <sys/stat.h>
 #include <sys/syscall.h>
 
 #include <vector>
@@ -50,6 +51,16 @@ namespace {
 #define F_SEAL_SHRINK 0x0002
 #define F_SEAL_GROW 0x0004
 #define F_SEAL_WRITE 0x0008
+#define F_SEAL_FUTURE_WRITE 0x0010
+#define F_SEAL_EXEC 0x0020
+
+#ifndef MFD_NOEXEC_SEAL
+#define MFD_NOEXEC_SEAL 0x0008
+#endif /* MFD_NOEXEC_SEAL */
+
+#ifndef MFD_EXEC
+#define MFD_EXEC 0x0010
+#endif /* MFD_EXEC */
 
 using ::gvisor::testing::IsTmpfs;
 using ::testing::StartsWith;
@@ -538,6 +549,72 @@ TEST(MemfdTest, MultipleWritableAndNonWritableRefsToSameFileRegion) {
   m2.reset();
 }
 
+// MFD_NOEXEC_SEAL creates a non-executable memfd with F_SEAL_EXEC applied and
+// sealing enabled.
+TEST(MemfdTest, NoexecSeal) {
+  const FileDescriptor memfd =
+      ASSERT_NO_ERRNO_AND_VALUE(MemfdCreate(kMemfdName, MFD_NOEXEC_SEAL));
+  const struct stat st = ASSERT_NO_ERRNO_AND_VALUE(Fstat(memfd.get()));
+  EXPECT_EQ(st.st_mode & 0111, 0);
+  EXPECT_THAT(fcntl(memfd.get(), F_GET_SEALS),
+              SyscallSucceedsWithValue(F_SEAL_EXEC));
+
+  // The execute bits can't be changed, but other mode bits can.
+  EXPECT_THAT(fchmod(memfd.get(), 0777), SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(fchmod(memfd.get(), 0600), SyscallSucceeds());
+
+  // Further seals can be added.
+  EXPECT_THAT(fcntl(memfd.get(), F_ADD_SEALS, F_SEAL_GROW), SyscallSucceeds());
+}
+
+TEST(MemfdTest, ExecAndNoexecSealAreExclusive) {
+  EXPECT_THAT(memfd_create(kMemfdName, MFD_EXEC | MFD_NOEXEC_SEAL),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+// F_SEAL_EXEC on an executable memfd also seals its contents.
+TEST(MemfdTest, SealExecOnExecutableImpliesWriteSeals) {
+  const FileDescriptor memfd = ASSERT_NO_ERRNO_AND_VALUE(
+      MemfdCreate(kMemfdName, MFD_EXEC | MFD_ALLOW_SEALING));
+  ASSERT_THAT(fcntl(memfd.get(), F_ADD_SEALS, F_SEAL_EXEC), SyscallSucceeds());
+  EXPECT_THAT(fcntl(memfd.get(), F_GET_SEALS),
+              SyscallSucceedsWithValue(F_SEAL_EXEC | F_SEAL_SHRINK |
+                                       F_SEAL_GROW | F_SEAL_WRITE |
+                                       F_SEAL_FUTURE_WRITE));
+  EXPECT_THAT(fchmod(memfd.get(), 0666), SyscallFailsWithErrno(EPERM));
+}
+
+// F_SEAL_FUTURE_WRITE prevents writes and new writable shared mappings, but
+// not existing writable mappings.
+TEST(MemfdTest, SealFutureWrite) {
+  const FileDescriptor memfd =
+      ASSERT_NO_ERRNO_AND_VALUE(MemfdCreate(kMemfdName, MFD_ALLOW_SEALING));
+  const std::vector<char> buf(kPageSize);
+  ASSERT_THAT(write(memfd.get(), buf.data(), buf.size()),
+              SyscallSucceedsWithValue(kPageSize));
+  Mapping m = ASSERT_NO_ERRNO_AND_VALUE(Mmap(
+      nullptr, kPageSize, PROT_READ | PROT_WRITE, MAP_SHARED, memfd.get(), 0));
+
+  ASSERT_THAT(fcntl(memfd.get(), F_ADD_SEALS, F_SEAL_FUTURE_WRITE),
+              SyscallSucceeds());
+  EXPECT_THAT(pwrite(memfd.get(), buf.data(), 1, 0),
+              SyscallFailsWithErrno(EPERM));
+
+  // The existing mapping remains writable.
+  *reinterpret_cast<volatile char*>(m.ptr()) = 1;
+
+  void* ret = mmap(nullptr, kPageSize, PROT_READ | PROT_WRITE, MAP_SHARED,
+                   memfd.get(), 0);
+  EXPECT_EQ(ret, MAP_FAILED);
+  EXPECT_EQ(errno, EPERM);
+
+  // Read-only shared mappings can't be made writable.
+  Mapping ro = ASSERT_NO_ERRNO_AND_VALUE(
+      Mmap(nullptr, kPageSize, PROT_READ, MAP_SHARED, memfd.get(), 0));
+  EXPECT_THAT(mprotect(ro.ptr(), kPageSize, PROT_READ | PROT_WRITE),
+              SyscallFailsWithErrno(EACCES));
+}
+
 }  // namespace
 }  // namespace testing
 }  // namespace gvisor
@@ -1769,6 +1769,48 @@ TEST(MMapNoFixtureTest, Map32Bit) {
 
 #endif  // defined(__x86_64__)
 
+#ifndef MAP_DROPPABLE
+#define MAP_DROPPABLE 0x08
+#endif
+
+TEST(MMapNoFixtureTest, MapDroppableWipedOnFork) {
+  void* addr = mmap(nullptr, kPageSize, PROT_READ | PROT_WRITE,
+                    MAP_DROPPABLE | MAP_ANONYMOUS, -1, 0);
+  // MAP_DROPPABLE requires Linux 6.11.
+  SKIP_IF(!IsRunningOnGvisor() && addr == MAP_FAILED && errno == EINVAL);
+  ASSERT_NE(addr, MAP_FAILED);
+  Mapping m(addr, kPageSize);
+  memset(m.ptr(), 1, kPageSize);
+
+  const auto rest = [&] {
+    auto const v = m.view();
+    for (size_t i = 0; i < kPageSize; i++) {
+      TEST_CHECK(v[i] == 0);
+    }
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+  EXPECT_EQ(m.view()[0], 1);
+}
+
+TEST(MMapNoFixtureTest, MapDroppableInvalid) {
+  void* addr = mmap(nullptr, kPageSize, PROT_READ | PROT_WRITE,
+                    MAP_DROPPABLE | MAP_ANONYMOUS, -1, 0);
+  SKIP_IF(!IsRunningOnGvisor() && addr == MAP_FAILED && errno == EINVAL);
+  ASSERT_NE(addr, MAP_FAILED);
+  ASSERT_THAT(munmap(addr, kPageSize), SyscallSucceeds());
+
+  // Droppable mappings must be anonymous, and may not be locked.
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/zero", O_RDWR));
+  EXPECT_THAT(reinterpret_cast<intptr_t>(mmap(nullptr, kPageSize, PROT_READ,
+                                              MAP_DROPPABLE, fd.get(), 0)),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(reinterpret_cast<intptr_t>(
+                  mmap(nullptr, kPageSize, PROT_READ,
+                       MAP_DROPPABLE | MAP_ANONYMOUS | MAP_LOCKED, -1, 0)),
+              SyscallFailsWithErrno(EINVAL));
+}
+
 INSTANTIATE_TEST_SUITE_P(
     ReadWriteSharedPrivate, MMapFileParamTest,
     ::testing::Combine(::testing::ValuesIn({
@@ -2328,6 +2328,71 @@ TEST(MountTest, DetachedMountBindFails) {
               SyscallFailsWithErrno(EINVAL));
 }
 
+TEST(MountTest, MoveMount) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const parent = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const parent_mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", parent.path(), kTmpfs, 0, "", MNT_DETACH));
+  ASSERT_THAT(mount("", parent.path().c_str(), "", MS_PRIVATE, 0),
+              SyscallSucceeds());
+  auto const src =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(parent.path()));
+  auto const dst =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(parent.path()));
+  ASSERT_THAT(mount("", src.path().c_str(), kTmpfs, 0, ""), SyscallSucceeds());
+  auto const sub = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(src.path()));
+  ASSERT_THAT(mount("", sub.path().c_str(), kTmpfs, 0, ""), SyscallSucceeds());
+  auto const file =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileIn(sub.path()));
+
+  ASSERT_THAT(mount(src.path().c_str(), dst.path().c_str(), "", MS_MOVE, 0),
+              SyscallSucceeds());
+
+  // The mount and its submounts are now at dst, and src is empty again.
+  const std::string moved_file =
+      JoinPath(dst.path(), Basename(sub.path()), Basename(file.path()));
+  EXPECT_TRUE(ASSERT_NO_ERRNO_AND_VALUE(Exists(moved_file)));
+  EXPECT_FALSE(ASSERT_NO_ERRNO_AND_VALUE(Exists(file.path())));
+  EXPECT_THAT(umount2(src.path().c_str(), 0), SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(umount2(dst.path().c_str(), MNT_DETACH), SyscallSucceeds());
+}
+
+TEST(MountTest, MoveMountUnderItself) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const parent = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const parent_mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", parent.path(), kTmpfs, 0, "", MNT_DETACH));
+  ASSERT_THAT(mount("", parent.path().c_str(), "", MS_PRIVATE, 0),
+              SyscallSucceeds());
+  auto const src =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(parent.path()));
+  auto const src_mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", src.path(), kTmpfs, 0, "", MNT_DETACH));
+  auto const dst = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(src.path()));
+
+  EXPECT_THAT(mount(src.path().c_str(), dst.path().c_str(), "", MS_MOVE, 0),
+              SyscallFailsWithErrno(ELOOP));
+}
+
+TEST(MountTest, MoveNonMountpoint) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto const parent = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto const parent_mount = ASSERT_NO_ERRNO_AND_VALUE(
+      Mount("", parent.path(), kTmpfs, 0, "", MNT_DETACH));
+  ASSERT_THAT(mount("", parent.path().c_str(), "", MS_PRIVATE, 0),
+              SyscallSucceeds());
+  auto const src =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(parent.path()));
+  auto const dst =
+      ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDirIn(parent.path()));
+
+  EXPECT_THAT(mount(src.path().c_str(), dst.path().c_str(), "", MS_MOVE, 0),
+              SyscallFailsWithErrno(EINVAL));
+}
+
 }  // namespace
 
 }  // namespace testing
@@ -0,0 +1,379 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <errno.h>
+#include <fcntl.h>
+#include <linux/capability.h>
+#include <linux/falloc.h>
+#include <sys/mman.h>
+#include <sys/resource.h>
+#include <sys/stat.h>
+#include <unistd.h>
+
+#include <algorithm>
+#include <cstring>
+#include <string>
+
+#include "gtest/gtest.h"
+#include "absl/strings/str_cat.h"
+#include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/memory_util.h"
+#include "test/util/mount_util.h"
+#include "test/util/posix_error.h"
+#include "test/util/rlimit_util.h"
+#include "test/util/save_util.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+// kChunkSize is the granularity at which gVisor's overlay copies up the data
+// of regular files lazily. Linux copies up all data at once, so these tests
+// only check behavior that is the same in both cases.
+constexpr size_t kChunkSize = 1 << 20;
+
+// kFileSize is the size of the file on the lower layer, which is not a
+// multiple of kChunkSize.
+constexpr size_t kFileSize = 3 * kChunkSize + 1234;
+
+// Pattern returns size bytes of data in which each page of each chunk is
+// distinct, so that data copied to the wrong offset is detected.
+std::string Pattern(size_t size) {
+  std::string s(size, '\0');
+  for (size_t i = 0; i < size; i++) {
+    s[i] = static_cast<char>(i % 251 + i / 4096);
+  }
+  return s;
+}
+
+// ExpectFileContents checks that the file at path contains want.
+void ExpectFileContents(const std::string& path, const std::string& want) {
+  const std::string got = ASSERT_NO_ERRNO_AND_VALUE(GetContents(path));
+  ASSERT_EQ(got.size(), want.size());
+  const auto mismatch = std::mismatch(got.begin(), got.end(), want.begin());
+  EXPECT_TRUE(mismatch.first == got.end())
+      << "contents of " << path << " differ at offset "
+      << (mismatch.first - got.begin());
+}
+
+// OverlayCopyUpTest mounts an overlay whose lower layer contains a regular
+// file, which the tests copy up.
+class OverlayCopyUpTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+    dir_ = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+    tmpfs_mount_ = ASSERT_NO_ERRNO_AND_VALUE(
+        Mount("", dir_.path(), "tmpfs", 0, "mode=0755", 0));
+    const std::string lower = JoinPath(dir_.path(), "lower");
+    const std::string upper = JoinPath(dir_.path(), "upper");
+    const std::string work = JoinPath(dir_.path(), "work");
+    merged_ = JoinPath(dir_.path(), "merged");
+    for (const auto& d : {lower, upper, work, merged_}) {
+      ASSERT_THAT(mkdir(d.c_str(), 0755), SyscallSucceeds());
+    }
+
+    contents_ = Pattern(kFileSize);
+    ASSERT_NO_ERRNO(SetContents(JoinPath(lower, "file"), contents_));
+    ASSERT_THAT(chmod(JoinPath(lower, "file").c_str(), 0644),
+                SyscallSucceeds());
+
+    overlay_mount_ = ASSERT_NO_ERRNO_AND_VALUE(
+        Mount("overlay", merged_, "overlay", 0,
+              absl::StrCat("lowerdir=", lower, ",upperdir=", upper,
+                           ",workdir=", work),
+              0));
+    path_ = JoinPath(merged_, "file");
+  }
+
+  // CopyUpMetadata copies up the file by changing its mode, and checks that
+  // this preserves its size and modification time.
+  void CopyUpMetadata() {
+    struct stat before;
+    ASSERT_THAT(stat(path_.c_str(), &before), SyscallSucceeds());
+    ASSERT_THAT(chmod(path_.c_str(), 0600), SyscallSucceeds());
+    struct stat after;
+    ASSERT_THAT(stat(path_.c_str(), &after), SyscallSucceeds());
+    EXPECT_EQ(after.st_mode & 07777, 0600);
+    EXPECT_EQ(after.st_size, kFileSize);
+    EXPECT_EQ(after.st_mtim.tv_sec, before.st_mtim.tv_sec);
+    EXPECT_EQ(after.st_mtim.tv_nsec, before.st_mtim.tv_nsec);
+  }
+
+  TempPath dir_;
+  Cleanup tmpfs_mount_;
+  Cleanup overlay_mount_;
+  std::string merged_;
+  std::string path_;
+  std::string contents_;
+};
+
+TEST_F(OverlayCopyUpTest, ReadAfterChmod) {
+  ASSERT_NO_FATAL_FAILURE(CopyUpMetadata());
+  struct stat before;
+  ASSERT_THAT(stat(path_.c_str(), &before), SyscallSucceeds());
+
+  ExpectFileContents(path_, contents_);
+
+  // Reading the file must not change its modification time, even if its data
+  // is copied up.
+  struct stat after;
+  ASSERT_THAT(stat(path_.c_str(), &after), SyscallSucceeds());
+  EXPECT_EQ(after.st_mtim.tv_sec, before.st_mtim.tv_sec);
+  EXPECT_EQ(after.st_mtim.tv_nsec, before.st_mtim.tv_nsec);
+}
+
+TEST_F(OverlayCopyUpTest, PreadAfterChown) {
+  ASSERT_THAT(chown(path_.c_str(), getuid(), getgid()), SyscallSucceeds());
+
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_RDONLY));
+  // Read small, unaligned ranges within and across chunks, out of order.
+  for (const size_t off : {2 * kChunkSize - 7, kChunkSize - 100, size_t{13},
+                           kFileSize - 20, 3 * kChunkSize - 1}) {
+    char buf[200];
+    const size_t want = std::min(sizeof(buf), kFileSize - off);
+    ASSERT_THAT(PreadFd(fd.get(), buf, sizeof(buf), off),
+                SyscallSucceedsWithValue(want));
+    EXPECT_EQ(std::string(buf, want), contents_.substr(off, want))
+        << "at offset " << off;
+  }
+  ExpectFileContents(path_, contents_);
+}
+
+TEST_F(OverlayCopyUpTest, PartialWritesAcrossChunks) {
+  ASSERT_NO_FATAL_FAILURE(CopyUpMetadata());
+
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_RDWR));
+  std::string want = contents_;
+  struct {
+    size_t off;
+    size_t len;
+  } const writes[] = {
+      // Within a chunk.
+      {13, 5},
+      // Across a chunk boundary.
+      {kChunkSize - 50, 100},
+      // Exactly one chunk, which doesn't need to be copied up first.
+      {2 * kChunkSize, kChunkSize},
+      // Across the end of the file.
+      {kFileSize - 10, 30},
+  };
+  char c = 'A';
+  for (const auto& w : writes) {
+    const std::string data(w.len, c++);
+    ASSERT_THAT(PwriteFd(fd.get(), data.data(), data.size(), w.off),
+                SyscallSucceedsWithValue(data.size()));
+    if (w.off + w.len > want.size()) {
+      want.resize(w.off + w.len);
+    }
+    want.replace(w.off, w.len, data);
+  }
+
+  // Writes at the file offset.
+  ASSERT_THAT(lseek(fd.get(), kChunkSize + 7, SEEK_SET), SyscallSucceeds());
+  constexpr char kData[] = "hello";
+  ASSERT_THAT(WriteFd(fd.get(), kData, sizeof(kData)),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  want.replace(kChunkSize + 7, sizeof(kData), kData, sizeof(kData));
+
+  ExpectFileContents(path_, want);
+}
+
+TEST_F(OverlayCopyUpTest, AppendingWrite) {
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_WRONLY | O_APPEND));
+  constexpr char kData[] = "appended";
+  ASSERT_THAT(WriteFd(fd.get(), kData, sizeof(kData)),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  ExpectFileContents(path_, contents_ + std::string(kData, sizeof(kData)));
+}
+
+TEST_F(OverlayCopyUpTest, ShortWrite) {
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_RDWR));
+
+  // Write a whole chunk, which is not copied up before the write, but limit
+  // the file size such that only its first bytes are written. The rest of the
+  // chunk must retain its data.
+  constexpr size_t kWritten = 100;
+  const std::string data(kChunkSize, 'X');
+  {
+    const Cleanup limit = ASSERT_NO_ERRNO_AND_VALUE(
+        ScopedSetSoftRlimit(RLIMIT_FSIZE, kChunkSize + kWritten));
+    ASSERT_THAT(pwrite(fd.get(), data.data(), data.size(), kChunkSize),
+                SyscallSucceedsWithValue(kWritten));
+  }
+
+  std::string want = contents_;
+  want.replace(kChunkSize, kWritten, kWritten, 'X');
+  ExpectFileContents(path_, want);
+}
+
+TEST_F(OverlayCopyUpTest, Truncate) {
+  ASSERT_NO_FATAL_FAILURE(CopyUpMetadata());
+
+  // Truncate within a chunk, then extend the file again. The extended part of
+  // the file must read as zeroes, rather than the data of the lower layer.
+  ASSERT_THAT(truncate(path_.c_str(), kChunkSize + 10), SyscallSucceeds());
+  ExpectFileContents(path_, contents_.substr(0, kChunkSize + 10));
+
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_RDWR));
+  ASSERT_THAT(ftruncate(fd.get(), kFileSize), SyscallSucceeds());
+  std::string want = contents_.substr(0, kChunkSize + 10);
+  want.resize(kFileSize, '\0');
+  ExpectFileContents(path_, want);
+}
+
+TEST_F(OverlayCopyUpTest, OpenTrunc) {
+  {
+    const FileDescriptor fd =
+        ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_WRONLY | O_TRUNC));
+    ASSERT_THAT(ftruncate(fd.get(), kFileSize), SyscallSucceeds());
+  }
+  ExpectFileContents(path_, std::string(kFileSize, '\0'));
+}
+
+TEST_F(OverlayCopyUpTest, OpenTruncAfterCopyUp) {
+  ASSERT_NO_FATAL_FAILURE(CopyUpMetadata());
+  {
+    // Copy up part of the data.
+    const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_RDONLY));
+    char buf[10];
+    ASSERT_THAT(PreadFd(fd.get(), buf, sizeof(buf), kChunkSize),
+                SyscallSucceedsWithValue(sizeof(buf)));
+  }
+  {
+    const FileDescriptor fd =
+        ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_WRONLY | O_TRUNC));
+    struct stat st;
+    ASSERT_THAT(fstat(fd.get(), &st), SyscallSucceeds());
+    EXPECT_EQ(st.st_size, 0);
+    ASSERT_THAT(ftruncate(fd.get(), kFileSize), SyscallSucceeds());
+  }
+  ExpectFileContents(path_, std::string(kFileSize, '\0'));
+}
+
+TEST_F(OverlayCopyUpTest, Fallocate) {
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_RDWR));
+
+  // Allocating within the file doesn't change its data.
+  ASSERT_THAT(fallocate(fd.get(), 0, kChunkSize - 10, 20),
+              SyscallSucceeds());
+  ExpectFileContents(path_, contents_);
+
+  // Allocating beyond the end of the file extends it with zeroes.
+  ASSERT_THAT(fallocate(fd.get(), 0, kFileSize - 10, kChunkSize),
+              SyscallSucceeds());
+  std::string want = contents_;
+  want.resize(kFileSize - 10 + kChunkSize, '\0');
+  ExpectFileContents(path_, want);
+}
+
+TEST_F(OverlayCopyUpTest, FallocateNonZeroModes) {
+  ASSERT_NO_FATAL_FAILURE(CopyUpMetadata());
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_RDWR));
+  std::string want = contents_;
+
+  // FALLOC_FL_KEEP_SIZE doesn't change the file's size or data.
+  int ret =
+      fallocate(fd.get(), FALLOC_FL_KEEP_SIZE, kFileSize - 10, kChunkSize);
+  if (ret < 0) {
+    // gVisor doesn't support modes other than 0.
+    EXPECT_EQ(errno, EOPNOTSUPP);
+  }
+  ExpectFileContents(path_, want);
+
+  // FALLOC_FL_PUNCH_HOLE zeroes the range, and the remainder of the chunks
+  // that it partially covers must retain their data.
+  constexpr size_t kHoleOff = kChunkSize - 10;
+  constexpr size_t kHoleLen = kChunkSize + 20;
+  ret = fallocate(fd.get(), FALLOC_FL_PUNCH_HOLE | FALLOC_FL_KEEP_SIZE,
+                  kHoleOff, kHoleLen);
+  if (ret < 0) {
+    EXPECT_EQ(errno, EOPNOTSUPP);
+  } else {
+    want.replace(kHoleOff, kHoleLen, kHoleLen, '\0');
+  }
+  ExpectFileContents(path_, want);
+}
+
+TEST_F(OverlayCopyUpTest, MmapAfterCopyUp) {
+  ASSERT_NO_FATAL_FAILURE(CopyUpMetadata());
+
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_RDWR));
+  const Mapping m = ASSERT_NO_ERRNO_AND_VALUE(Mmap(
+      nullptr, kFileSize, PROT_READ | PROT_WRITE, MAP_SHARED, fd.get(), 0));
+  ASSERT_EQ(m.view().size(), contents_.size());
+  EXPECT_EQ(memcmp(m.ptr(), contents_.data(), contents_.size()), 0);
+
+  // Writes through the mapping are visible to read(2).
+  constexpr size_t kOff = 2 * kChunkSize + 5;
+  memset(static_cast<char*>(m.ptr()) + kOff, 'Z', 10);
+  ASSERT_THAT(msync(m.ptr(), kFileSize, MS_SYNC), SyscallSucceeds());
+  std::string want = contents_;
+  want.replace(kOff, 10, 10, 'Z');
+  ExpectFileContents(path_, want);
+}
+
+TEST_F(OverlayCopyUpTest, LinkPartiallyCopiedFile) {
+  std::string want = contents_;
+  {
+    // Copy up the file and part of its data.
+    const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_RDWR));
+    constexpr char kData[] = "partial";
+    ASSERT_THAT(PwriteFd(fd.get(), kData, sizeof(kData), kChunkSize + 5),
+                SyscallSucceedsWithValue(sizeof(kData)));
+    want.replace(kChunkSize + 5, sizeof(kData), kData, sizeof(kData));
+  }
+
+  const std::string link_path = JoinPath(merged_, "link");
+  ASSERT_THAT(link(path_.c_str(), link_path.c_str()), SyscallSucceeds());
+  struct stat st;
+  ASSERT_THAT(stat(link_path.c_str(), &st), SyscallSucceeds());
+  EXPECT_EQ(st.st_nlink, 2);
+  ExpectFileContents(link_path, want);
+  ExpectFileContents(path_, want);
+}
+
+TEST_F(OverlayCopyUpTest, SaveRestoreWithPendingData) {
+  ASSERT_NO_FATAL_FAILURE(CopyUpMetadata());
+
+  // Copy up some of the data, leaving the remaining data to be copied up
+  // after restore.
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(path_, O_RDWR));
+  std::string want = contents_;
+  constexpr char kData[] = "before save";
+  ASSERT_THAT(PwriteFd(fd.get(), kData, sizeof(kData), kChunkSize - 5),
+              SyscallSucceedsWithValue(sizeof(kData)));
+  want.replace(kChunkSize - 5, sizeof(kData), kData, sizeof(kData));
+
+  MaybeSave();
+
+  constexpr char kData2[] = "after restore";
+  ASSERT_THAT(PwriteFd(fd.get(), kData2, sizeof(kData2), 3 * kChunkSize - 5),
+              SyscallSucceedsWithValue(sizeof(kData2)));
+  want.replace(3 * kChunkSize - 5, sizeof(kData2), kData2, sizeof(kData2));
+  ExpectFileContents(path_, want);
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
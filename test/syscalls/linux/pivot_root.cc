@@ -15,6 +15,7 @@
 #include <errno.h>
 #include <fcntl.h>
 #include <linux/capability.h>
+#include <sched.h>
 #include <stddef.h>
 #include <sys/mman.h>
 #include <sys/mount.h>
@@ -243,6 +244,32 @@ TEST(PivotRootTest, DotDot) {
   EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
 }
 
+// Container runtimes pivot_root from the root of a new mount namespace,
+// without chrooting first.
+TEST(PivotRootTest, FromMountNamespaceRoot) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  auto new_root = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  const std::string file_name = "file";
+
+  const auto rest = [&] {
+    TEST_CHECK_SUCCESS(unshare(CLONE_NEWNS));
+    TEST_CHECK_SUCCESS(mount("", "/", "", MS_REC | MS_PRIVATE, nullptr));
+    TEST_CHECK_SUCCESS(
+        mount("", new_root.path().c_str(), "tmpfs", 0, "mode=0700"));
+    TEST_CHECK_SUCCESS(
+        mknod(JoinPath(new_root.path(), file_name).c_str(), S_IFREG | 0600, 0));
+    TEST_CHECK_SUCCESS(chdir(new_root.path().c_str()));
+    TEST_CHECK_SUCCESS(syscall(__NR_pivot_root, ".", "."));
+    TEST_CHECK_SUCCESS(umount2(".", MNT_DETACH));
+    TEST_CHECK_SUCCESS(chdir("/"));
+    struct stat statbuf;
+    TEST_CHECK_SUCCESS(stat(JoinPath("/", file_name).c_str(), &statbuf));
+    TEST_CHECK_ERRNO(stat(new_root.path().c_str(), &statbuf), ENOENT);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
 TEST(PivotRootTest, NotDir) {
   SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
 
@@ -24,11 +24,13 @@
 #include <stdio.h>
 #include <stdlib.h>
 #include <string.h>
+#include <sys/epoll.h>
 #include <sys/mman.h>
 #include <sys/prctl.h>
 #include <sys/ptrace.h>
 #include <sys/stat.h>
 #include <sys/statfs.h>
+#include <sys/timerfd.h>
 #include <sys/utsname.h>
 #include <syscall.h>
 #include <unistd.h>
@@ -68,6 +70,7 @@
 #include "absl/time/time.h"
 #include "test/util/capability_util.h"
 #include "test/util/cleanup.h"
+#include "test/util/epoll_util.h"
 #include "test/util/eventfd_util.h"
 #include "test/util/file_descriptor.h"
 #include "test/util/fs_util.h"
@@ -1245,6 +1248,66 @@ TEST(ProcSelfFdInfo, Flags) {
   EXPECT_THAT(fd_info, HasSubstr(absl::StrFormat("flags:\t%#o", flags)));
 }
 
+TEST(ProcSelfFdInfo, Pos) {
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(GetAbsoluteTestTmpdir(), "abcdef", 0644));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(file.path(), O_RDONLY));
+  ASSERT_THAT(lseek(fd.get(), 3, SEEK_SET), SyscallSucceedsWithValue(3));
+
+  struct stat st;
+  ASSERT_THAT(fstat(fd.get(), &st), SyscallSucceeds());
+  auto fd_info = ASSERT_NO_ERRNO_AND_VALUE(
+      GetContents(absl::StrCat("/proc/self/fdinfo/", fd.get())));
+  EXPECT_THAT(fd_info, HasSubstr("pos:\t3\n"));
+  EXPECT_THAT(fd_info, HasSubstr(absl::StrCat("ino:\t", st.st_ino, "\n")));
+  EXPECT_THAT(fd_info, HasSubstr("mnt_id:\t"));
+}
+
+// Reading an fdinfo file through the file descriptor that it describes must
+// not deadlock.
+TEST(ProcSelfFdInfo, Self) {
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/null", O_RDONLY));
+  const FileDescriptor self = ASSERT_NO_ERRNO_AND_VALUE(
+      Open(absl::StrCat("/proc/self/fdinfo/", fd.get()), O_RDONLY));
+  // Make fd refer to the fdinfo file itself.
+  ASSERT_THAT(dup2(self.get(), fd.get()), SyscallSucceeds());
+
+  char buf[1024] = {};
+  ASSERT_THAT(read(self.get(), buf, sizeof(buf) - 1), SyscallSucceeds());
+  EXPECT_THAT(buf, HasSubstr("pos:\t"));
+}
+
+TEST(ProcSelfFdInfo, Epoll) {
+  const FileDescriptor epfd = ASSERT_NO_ERRNO_AND_VALUE(NewEpollFD());
+  const FileDescriptor efd = ASSERT_NO_ERRNO_AND_VALUE(NewEventFD());
+  ASSERT_NO_ERRNO(RegisterEpollFD(epfd.get(), efd.get(), EPOLLIN, 0x1234));
+
+  auto fd_info = ASSERT_NO_ERRNO_AND_VALUE(
+      GetContents(absl::StrCat("/proc/self/fdinfo/", epfd.get())));
+  EXPECT_THAT(fd_info,
+              HasSubstr(absl::StrFormat("tfd: %8d events: %8x data: %16x",
+                                        efd.get(), EPOLLIN, 0x1234)));
+}
+
+TEST(ProcSelfFdInfo, Timerfd) {
+  int tfd;
+  ASSERT_THAT(tfd = timerfd_create(CLOCK_MONOTONIC, 0), SyscallSucceeds());
+  const FileDescriptor fd(tfd);
+  struct itimerspec its = {};
+  its.it_value.tv_sec = 100;
+  its.it_interval.tv_sec = 2;
+  ASSERT_THAT(timerfd_settime(fd.get(), 0, &its, nullptr), SyscallSucceeds());
+
+  auto fd_info = ASSERT_NO_ERRNO_AND_VALUE(
+      GetContents(absl::StrCat("/proc/self/fdinfo/", fd.get())));
+  EXPECT_THAT(fd_info, HasSubstr(absl::StrFormat("clockid: %d\n",
+                                                 CLOCK_MONOTONIC)));
+  EXPECT_THAT(fd_info, HasSubstr("settime flags: 00\n"));
+  EXPECT_THAT(fd_info, HasSubstr("it_interval: (2, 0)\n"));
+}
+
 TEST(ProcSelfExe, Absolute) {
   auto exe = ASSERT_NO_ERRNO_AND_VALUE(ReadLink("/proc/self/exe"));
   EXPECT_EQ(exe[0], '/');
@@ -1414,6 +1477,38 @@ TEST(ProcStat, ContainsBasicFields) {
                             "procs_running", "procs_blocked", "softirq"}));
 }
 
+// ProcStatCPUTime returns the sum of the user and system time on the cpu line
+// of /proc/stat, in clock ticks.
+PosixErrorOr<uint64_t> ProcStatCPUTime() {
+  ASSIGN_OR_RETURN_ERRNO(std::string proc_stat, GetContents("/proc/stat"));
+  for (auto const& line : absl::StrSplit(proc_stat, '\n')) {
+    std::vector<std::string> fields =
+        absl::StrSplit(line, ' ', absl::SkipWhitespace());
+    if (fields.size() < 4 || fields[0] != "cpu") {
+      continue;
+    }
+    uint64_t user, system;
+    if (!absl::SimpleAtoi(fields[1], &user) ||
+        !absl::SimpleAtoi(fields[3], &system)) {
+      return PosixError(EINVAL, absl::StrCat("bad cpu line: ", line));
+    }
+    return user + system;
+  }
+  return PosixError(ENOENT, "no cpu line in /proc/stat");
+}
+
+TEST(ProcStat, CPUTimeAdvances) {
+  const uint64_t before = ASSERT_NO_ERRNO_AND_VALUE(ProcStatCPUTime());
+
+  // Spin for well over a clock tick.
+  const absl::Time deadline = absl::Now() + absl::Milliseconds(200);
+  while (absl::Now() < deadline) {
+  }
+
+  const uint64_t after = ASSERT_NO_ERRNO_AND_VALUE(ProcStatCPUTime());
+  EXPECT_GT(after, before);
+}
+
 TEST(ProcStat, EndsWithNewline) {
   std::string proc_stat = ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/stat"));
   EXPECT_EQ(proc_stat.back(), '\n');
@@ -2223,11 +2318,8 @@ TEST(ProcPidFile, SubprocessExited) {
   EXPECT_THAT(ReadWhileExited("uid_map", buf, sizeof(buf)),
               SyscallSucceedsWithValue(sizeof(buf)));
 
-  if (!IsRunningOnGvisor()) {
-    // FIXME(gvisor.dev/issue/164): Succeeds on gVisor.
-    EXPECT_THAT(ReadWhileExited("oom_score", buf, sizeof(buf)),
-                SyscallFailsWithErrno(ESRCH));
-  }
+  EXPECT_THAT(ReadWhileExited("oom_score", buf, sizeof(buf)),
+              SyscallFailsWithErrno(ESRCH));
 
   EXPECT_THAT(ReadWhileExited("oom_score_adj", buf, sizeof(buf)),
               SyscallFailsWithErrno(ESRCH));
@@ -2604,6 +2696,20 @@ TEST(ProcSysKernelHostname, MatchesUname) {
   EXPECT_EQ(procfs_hostname, hostname);
 }
 
+TEST(ProcSysKernelCorePattern, WriteRequiresCapSysAdmin) {
+  // Linux only requires write access to the file, and writing it would change
+  // the host's core_pattern.
+  SKIP_IF(!IsRunningOnGvisor());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(
+      Open("/proc/sys/kernel/core_pattern", O_WRONLY));
+  AutoCapability cap(CAP_SYS_ADMIN, false);
+  constexpr char kPattern[] = "|/bin/true";
+  EXPECT_THAT(write(fd.get(), kPattern, sizeof(kPattern) - 1),
+              SyscallFailsWithErrno(EPERM));
+}
+
 TEST(ProcSysVmMaxmapCount, HasNumericValue) {
   const std::string val_str =
       ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/sys/vm/max_map_count"));
@@ -2631,6 +2737,27 @@ TEST(ProcSysVmOvercommitMemory, HasNumericValue) {
       << overcommit_memory;
 }
 
+TEST(ProcSysFsFileNr, MatchesFileMax) {
+  const std::string file_nr =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/sys/fs/file-nr"));
+  std::vector<std::string> fields =
+      absl::StrSplit(absl::StripTrailingAsciiWhitespace(file_nr), '\t');
+  ASSERT_EQ(fields.size(), 3) << file_nr;
+
+  uint64_t allocated;
+  ASSERT_TRUE(absl::SimpleAtoi(fields[0], &allocated)) << file_nr;
+  // At least the file used to read file-nr is open.
+  EXPECT_GT(allocated, 0);
+  EXPECT_EQ(fields[1], "0");
+
+  const std::string file_max_str =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/sys/fs/file-max"));
+  uint64_t file_max, nr_max;
+  ASSERT_TRUE(absl::SimpleAtoi(file_max_str, &file_max)) << file_max_str;
+  ASSERT_TRUE(absl::SimpleAtoi(fields[2], &nr_max)) << file_nr;
+  EXPECT_EQ(nr_max, file_max);
+}
+
 // Check that link for proc fd entries point the target node, not the
 // symlink itself. Regression test for b/31155070.
 TEST(ProcTaskFd, FstatatFollowsSymlink) {
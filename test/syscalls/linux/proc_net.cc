@@ -19,6 +19,7 @@
 #include <sys/socket.h>
 #include <sys/syscall.h>
 #include <sys/types.h>
+#include <unistd.h>
 
 #include <vector>
 
@@ -477,9 +478,6 @@ TEST(ProcNetSnmp, UdpIn) {
 }
 
 TEST(ProcNetSnmp, CheckNetStat) {
-  // TODO(b/155123175): SNMP and netstat don't work on gVisor.
-  SKIP_IF(IsRunningOnGvisor());
-
   std::string contents =
       ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/net/netstat"));
 
@@ -544,6 +542,32 @@ TEST(ProcNetSnmp, CheckSnmp) {
   EXPECT_EQ(value_count, 1);
 }
 
+TEST(ProcNetSnmp6, Format) {
+  // snmp6 only exists if IPv6 is supported.
+  SKIP_IF(access("/proc/net/snmp6", F_OK) != 0);
+
+  std::string contents =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/net/snmp6"));
+
+  // Each line holds a counter name and its value.
+  int found = 0;
+  std::vector<absl::string_view> lines =
+      absl::StrSplit(contents, '\n', absl::SkipEmpty());
+  for (absl::string_view line : lines) {
+    std::vector<absl::string_view> fields =
+        absl::StrSplit(line, absl::ByAnyChar("\t "), absl::SkipEmpty());
+    ASSERT_EQ(fields.size(), 2) << "unexpected line '" << line << "'";
+    uint64_t val;
+    EXPECT_TRUE(absl::SimpleAtoi(fields[1], &val))
+        << "unexpected value in line '" << line << "'";
+    if (fields[0] == "Ip6InReceives" || fields[0] == "Icmp6InMsgs" ||
+        fields[0] == "Udp6InDatagrams") {
+      ++found;
+    }
+  }
+  EXPECT_EQ(found, 3);
+}
+
 TEST(ProcSysNetIpv4Recovery, Exists) {
   EXPECT_THAT(open("/proc/sys/net/ipv4/tcp_recovery", O_RDONLY),
               SyscallSucceeds());
@@ -13,12 +13,17 @@
 // limitations under the License.
 
 #include <errno.h>
+#include <fcntl.h>
+#include <string.h>
+#include <unistd.h>
 
 #include <exception>
 #include <iostream>
 #include <string>
 
+#include "test/util/capability_util.h"
 #include "test/util/fs_util.h"
+#include "test/util/multiprocess_util.h"
 #include "test/util/test_util.h"
 
 namespace gvisor {
@@ -45,6 +50,40 @@ TEST(ProcPidOomscoreTest, BasicRead) {
   EXPECT_GE(oom_score, -1000);
 }
 
+// SetOomScoreAdj writes adj to /proc/self/oom_score_adj. It is async-signal
+// safe, for use in forked children.
+void SetOomScoreAdj(const char* adj) {
+  int fd = open("/proc/self/oom_score_adj", O_WRONLY);
+  TEST_PCHECK(fd >= 0);
+  TEST_PCHECK(write(fd, adj, strlen(adj)) == static_cast<ssize_t>(strlen(adj)));
+  TEST_PCHECK(close(fd) == 0);
+}
+
+TEST(ProcPidOomscoreTest, MaxAdjRaisesScore) {
+  const auto rest = [] {
+    SetOomScoreAdj("1000");
+    auto const oom_score = ReadProcNumber("/proc/self/oom_score");
+    TEST_CHECK(oom_score.ok());
+    // An oom_score_adj of 1000 counts as using all memory, in addition to
+    // the task's own usage.
+    TEST_CHECK(oom_score.ValueOrDie() >= 1300);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
+TEST(ProcPidOomscoreTest, MinAdjDisablesScore) {
+  // Lowering oom_score_adj below 0 requires CAP_SYS_RESOURCE on Linux.
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_RESOURCE)));
+
+  const auto rest = [] {
+    SetOomScoreAdj("-1000");
+    auto const oom_score = ReadProcNumber("/proc/self/oom_score");
+    TEST_CHECK(oom_score.ok());
+    TEST_CHECK(oom_score.ValueOrDie() == 0);
+  };
+  EXPECT_THAT(InForkedProcess(rest), IsPosixErrorOkAndHolds(0));
+}
+
 TEST(ProcPidOomscoreAdjTest, BasicRead) {
   auto const oom_score =
       ASSERT_NO_ERRNO_AND_VALUE(ReadProcNumber("/proc/self/oom_score_adj"));
@@ -13,6 +13,7 @@
 // limitations under the License.
 
 #include <fcntl.h>
+#include <grp.h>
 #include <sched.h>
 #include <sys/stat.h>
 #include <sys/types.h>
@@ -309,5 +310,87 @@ INSTANTIATE_TEST_SUITE_P(All, ProcPidUidGidMapTest,
                          ::testing::ValuesIn(UidGidMapTestParams()),
                          DescribeTestParam);
 
+// Returns the contents of /proc/self/setgroups without the trailing newline.
+std::string ReadSelfSetgroups() {
+  char buf[16] = {};
+  int fd = open("/proc/self/setgroups", O_RDONLY);
+  TEST_PCHECK(fd >= 0);
+  ssize_t n = read(fd, buf, sizeof(buf) - 1);
+  TEST_PCHECK(n > 0);
+  TEST_PCHECK(close(fd) == 0);
+  return std::string(absl::StripTrailingAsciiWhitespace(buf));
+}
+
+TEST(ProcSelfSetgroupsTest, DenyIsPermanentAndInherited) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(CanCreateUserNamespace()));
+  std::string uid_map = absl::StrCat(geteuid(), " ", geteuid(), " 1");
+  std::string gid_map = absl::StrCat(getegid(), " ", getegid(), " 1");
+  EXPECT_THAT(InNewUserNamespace([&] {
+                TEST_CHECK(ReadSelfSetgroups() == "allow");
+                DenySelfSetgroups();
+                TEST_CHECK(ReadSelfSetgroups() == "deny");
+
+                // "deny" can't be changed back to "allow".
+                int fd = open("/proc/self/setgroups", O_WRONLY);
+                TEST_PCHECK(fd >= 0);
+                char allow[] = "allow";
+                TEST_CHECK(write(fd, allow, sizeof(allow) - 1) < 0);
+                TEST_CHECK(errno == EPERM);
+                TEST_PCHECK(close(fd) == 0);
+
+                // setgroups(2) is disabled.
+                TEST_CHECK(setgroups(0, nullptr) < 0);
+                TEST_CHECK(errno == EPERM);
+
+                // Nested user namespaces inherit the setting. Creating one
+                // requires our IDs to be mapped.
+                for (const auto& [path, map] :
+                     {std::make_pair("/proc/self/uid_map", uid_map),
+                      std::make_pair("/proc/self/gid_map", gid_map)}) {
+                  int fd = open(path, O_WRONLY);
+                  TEST_PCHECK(fd >= 0);
+                  TEST_PCHECK(write(fd, map.c_str(), map.size()) ==
+                              static_cast<ssize_t>(map.size()));
+                  TEST_PCHECK(close(fd) == 0);
+                }
+                TEST_PCHECK(unshare(CLONE_NEWUSER) == 0);
+                TEST_CHECK(ReadSelfSetgroups() == "deny");
+              }),
+              IsPosixErrorOkAndHolds(0));
+}
+
+TEST(ProcSelfSetgroupsTest, UnprivilegedGidMapRequiresDeny) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(CanCreateUserNamespace()));
+  SKIP_IF(ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SETGID)));
+  gid_t gid = getegid();
+  std::string line = absl::StrCat(gid, " ", gid, " 1");
+  EXPECT_THAT(InNewUserNamespace([&] {
+                int fd = open("/proc/self/gid_map", O_WRONLY);
+                TEST_PCHECK(fd >= 0);
+                TEST_CHECK(write(fd, line.c_str(), line.size()) < 0);
+                TEST_CHECK(errno == EPERM);
+                TEST_PCHECK(close(fd) == 0);
+              }),
+              IsPosixErrorOkAndHolds(0));
+}
+
+TEST(ProcSysUserTest, MaxUserNamespaces) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(CanCreateUserNamespace()));
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  SKIP_IF(!IsRunningOnGvisor());
+
+  constexpr char kPath[] = "/proc/sys/user/max_user_namespaces";
+  std::string saved = ASSERT_NO_ERRNO_AND_VALUE(GetContents(kPath));
+  auto restore = Cleanup(
+      [&] { EXPECT_NO_ERRNO(SetContents(kPath, saved)); });
+
+  ASSERT_NO_ERRNO(SetContents(kPath, "0"));
+  EXPECT_THAT(InForkedProcess([] {
+                TEST_CHECK(unshare(CLONE_NEWUSER) < 0);
+                TEST_CHECK(errno == ENOSPC);
+              }),
+              IsPosixErrorOkAndHolds(0));
+}
+
 }  // namespace testing
 }  // namespace gvisor
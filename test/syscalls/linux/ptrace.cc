@@ -13,11 +13,16 @@
 // limitations under the License.
 
 #include <elf.h>
+#include <linux/audit.h>
+#include <linux/filter.h>
+#include <linux/seccomp.h>
 #include <signal.h>
 #include <stddef.h>
+#include <string.h>
 #include <sys/prctl.h>
 #include <sys/ptrace.h>
 #include <sys/socket.h>
+#include <sys/syscall.h>
 #include <sys/time.h>
 #include <sys/types.h>
 #include <sys/user.h>
@@ -105,6 +110,53 @@ constexpr auto kPtraceSysemu = static_cast<__ptrace_request>(31);
 // PTRACE_EVENT_STOP").
 constexpr int kPtraceEventStop = 128;
 
+// PTRACE_GET_SYSCALL_INFO and struct ptrace_syscall_info are not defined until
+// glibc 2.31 (2d2c0ac1ee53 "linux: Add PTRACE_GET_SYSCALL_INFO").
+constexpr auto kPtraceGetSyscallInfo = static_cast<__ptrace_request>(0x420e);
+constexpr uint8_t kPtraceSyscallInfoNone = 0;
+constexpr uint8_t kPtraceSyscallInfoEntry = 1;
+constexpr uint8_t kPtraceSyscallInfoExit = 2;
+constexpr uint8_t kPtraceSyscallInfoSeccomp = 3;
+
+struct PtraceSyscallInfo {
+  uint8_t op;
+  uint8_t pad[3];
+  uint32_t arch;
+  uint64_t instruction_pointer;
+  uint64_t stack_pointer;
+  union {
+    struct {
+      uint64_t nr;
+      uint64_t args[6];
+    } entry;
+    struct {
+      int64_t rval;
+      uint8_t is_error;
+    } exit;
+    struct {
+      uint64_t nr;
+      uint64_t args[6];
+      uint32_t ret_data;
+    } seccomp;
+  };
+};
+
+// Number of bytes of PtraceSyscallInfo that are valid for each op, as returned
+// by PTRACE_GET_SYSCALL_INFO.
+constexpr int kPtraceSyscallInfoNoneSize = offsetof(PtraceSyscallInfo, entry);
+constexpr int kPtraceSyscallInfoEntrySize =
+    kPtraceSyscallInfoNoneSize + 7 * sizeof(uint64_t);
+constexpr int kPtraceSyscallInfoExitSize =
+    kPtraceSyscallInfoNoneSize + sizeof(int64_t) + sizeof(uint8_t);
+constexpr int kPtraceSyscallInfoSeccompSize =
+    kPtraceSyscallInfoEntrySize + sizeof(uint32_t);
+
+#if defined(__x86_64__)
+constexpr uint32_t kAuditArch = AUDIT_ARCH_X86_64;
+#elif defined(__aarch64__)
+constexpr uint32_t kAuditArch = AUDIT_ARCH_AARCH64;
+#endif
+
 // Sends sig to the current process with tgkill(2).
 //
 // glibc's raise(2) may change the signal mask before sending the signal. These
@@ -155,6 +207,24 @@ int CheckPtraceAttach(pid_t pid) {
   return 0;
 }
 
+// Checks that the instruction and stack pointers in info are those of the
+// ptrace-stopped tracee pid.
+void ExpectSyscallInfoRegs(pid_t pid, const PtraceSyscallInfo& info) {
+  struct user_regs_struct regs = {};
+  struct iovec iov;
+  iov.iov_base = &regs;
+  iov.iov_len = sizeof(regs);
+  ASSERT_THAT(ptrace(PTRACE_GETREGSET, pid, NT_PRSTATUS, &iov),
+              SyscallSucceeds());
+#if defined(__x86_64__)
+  EXPECT_EQ(info.instruction_pointer, regs.rip);
+  EXPECT_EQ(info.stack_pointer, regs.rsp);
+#elif defined(__aarch64__)
+  EXPECT_EQ(info.instruction_pointer, regs.pc);
+  EXPECT_EQ(info.stack_pointer, regs.sp);
+#endif
+}
+
 class SimpleSubprocess {
  public:
   explicit SimpleSubprocess(absl::string_view child_flag) {
@@ -1488,6 +1558,108 @@ TEST(PtraceTest, ChangeRegSetInOptSyscall) {
 }
 #endif
 
+#if defined(__aarch64__)
+TEST(PtraceTest, GetRegSet_SetRegSet_FPRegs_TLS) {
+  uint8_t v16_pattern[16];
+  for (size_t i = 0; i < sizeof(v16_pattern); i++) {
+    v16_pattern[i] = 0xa0 + i;
+  }
+
+  pid_t const child_pid = fork();
+  if (child_pid == 0) {
+    // In child process.
+
+    // Enable tracing.
+    TEST_PCHECK(ptrace(PTRACE_TRACEME, 0, 0, 0) == 0);
+    MaybeSave();
+
+    // Send ourselves SIGSTOP, and save V16 as soon as kill(2) returns so that
+    // the value set by the tracer can't be clobbered in between.
+    uint8_t v16[16];
+    pid_t const pid = getpid();
+    register uint64_t x0 asm("x0") = pid;
+    register uint64_t x1 asm("x1") = SIGSTOP;
+    register uint64_t x8 asm("x8") = SYS_kill;
+    __asm__ __volatile__(
+        "svc #0\n"
+        "str q16, [%[v16]]\n"
+        : "+r"(x0)
+        : "r"(x1), "r"(x8), [v16] "r"(v16)
+        : "memory", "v16");
+    TEST_CHECK(memcmp(v16, v16_pattern, sizeof(v16)) == 0);
+    _exit(0);
+  }
+  // In parent process.
+  ASSERT_THAT(child_pid, SyscallSucceeds());
+
+  // Wait for the child to send itself SIGSTOP and enter signal-delivery-stop.
+  int status;
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFSTOPPED(status) && WSTOPSIG(status) == SIGSTOP)
+      << " status " << status;
+
+  // Get the floating point registers.
+  struct user_fpsimd_struct fpregs = {};
+  struct iovec iov;
+  iov.iov_base = &fpregs;
+  iov.iov_len = sizeof(fpregs);
+  ASSERT_THAT(ptrace(PTRACE_GETREGSET, child_pid, NT_PRFPREG, &iov),
+              SyscallSucceeds());
+  EXPECT_EQ(iov.iov_len, sizeof(fpregs));
+
+  // Set V16 and read it back.
+  memcpy(&fpregs.vregs[16], v16_pattern, sizeof(v16_pattern));
+  iov.iov_len = sizeof(fpregs);
+  ASSERT_THAT(ptrace(PTRACE_SETREGSET, child_pid, NT_PRFPREG, &iov),
+              SyscallSucceeds());
+  struct user_fpsimd_struct got_fpregs = {};
+  iov.iov_base = &got_fpregs;
+  iov.iov_len = sizeof(got_fpregs);
+  ASSERT_THAT(ptrace(PTRACE_GETREGSET, child_pid, NT_PRFPREG, &iov),
+              SyscallSucceeds());
+  EXPECT_EQ(memcmp(&got_fpregs.vregs[16], v16_pattern, sizeof(v16_pattern)),
+            0);
+  EXPECT_EQ(got_fpregs.fpsr, fpregs.fpsr);
+  EXPECT_EQ(got_fpregs.fpcr, fpregs.fpcr);
+
+  // The child's thread pointer was inherited from us across fork.
+  uint64_t tls;
+  __asm__("mrs %0, tpidr_el0" : "=r"(tls));
+  uint64_t child_tls = 0;
+  iov.iov_base = &child_tls;
+  iov.iov_len = sizeof(child_tls);
+  ASSERT_THAT(ptrace(PTRACE_GETREGSET, child_pid, NT_ARM_TLS, &iov),
+              SyscallSucceeds());
+  EXPECT_EQ(iov.iov_len, sizeof(child_tls));
+  EXPECT_EQ(child_tls, tls);
+
+  // Change the thread pointer and read it back, then restore it so that the
+  // child can keep using TLS.
+  uint64_t new_tls = tls + kPageSize;
+  iov.iov_base = &new_tls;
+  iov.iov_len = sizeof(new_tls);
+  ASSERT_THAT(ptrace(PTRACE_SETREGSET, child_pid, NT_ARM_TLS, &iov),
+              SyscallSucceeds());
+  iov.iov_base = &child_tls;
+  iov.iov_len = sizeof(child_tls);
+  ASSERT_THAT(ptrace(PTRACE_GETREGSET, child_pid, NT_ARM_TLS, &iov),
+              SyscallSucceeds());
+  EXPECT_EQ(child_tls, new_tls);
+  iov.iov_base = &tls;
+  iov.iov_len = sizeof(tls);
+  ASSERT_THAT(ptrace(PTRACE_SETREGSET, child_pid, NT_ARM_TLS, &iov),
+              SyscallSucceeds());
+
+  // Suppress SIGSTOP and resume the child, which checks V16.
+  ASSERT_THAT(ptrace(PTRACE_CONT, child_pid, 0, 0), SyscallSucceeds());
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << " status " << status;
+}
+#endif  // defined(__aarch64__)
+
 TEST(PtraceTest, AttachingConvertsGroupStopToPtraceStop) {
   pid_t const child_pid = fork();
   if (child_pid == 0) {
@@ -2284,6 +2456,190 @@ TEST(PtraceTest, SeizeSetOptions) {
       << " status " << status;
 }
 
+TEST(PtraceTest, GetSyscallInfo_SyscallStops) {
+  constexpr off_t kOffset = 0x1234;
+
+  pid_t const child_pid = fork();
+  if (child_pid == 0) {
+    // In child process.
+
+    // Enable tracing, then raise SIGSTOP and expect our parent to suppress it.
+    TEST_PCHECK(ptrace(PTRACE_TRACEME, 0, 0, 0) == 0);
+    MaybeSave();
+    RaiseSignal(SIGSTOP);
+
+    // Make a failing and a successful syscall, without any syscalls in
+    // between.
+    TEST_CHECK(syscall(SYS_lseek, -1, kOffset, SEEK_CUR) == -1 &&
+               errno == EBADF);
+    TEST_CHECK(syscall(SYS_getpid) > 0);
+    _exit(0);
+  }
+  // In parent process.
+  ASSERT_THAT(child_pid, SyscallSucceeds());
+
+  // Wait for the child to send itself SIGSTOP and enter signal-delivery-stop.
+  int status;
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFSTOPPED(status) && WSTOPSIG(status) == SIGSTOP)
+      << " status " << status;
+
+  // Outside of syscall-stops, only the common fields are valid.
+  PtraceSyscallInfo info = {};
+  ASSERT_THAT(ptrace(kPtraceGetSyscallInfo, child_pid, sizeof(info), &info),
+              SyscallSucceedsWithValue(kPtraceSyscallInfoNoneSize));
+  EXPECT_EQ(info.op, kPtraceSyscallInfoNone);
+  EXPECT_EQ(info.arch, kAuditArch);
+  ExpectSyscallInfoRegs(child_pid, info);
+
+  // Suppress the SIGSTOP and wait for the child to enter syscall-enter-stop
+  // for lseek().
+  ASSERT_THAT(ptrace(PTRACE_SETOPTIONS, child_pid, 0, PTRACE_O_TRACESYSGOOD),
+              SyscallSucceeds());
+  ASSERT_THAT(ptrace(PTRACE_SYSCALL, child_pid, 0, 0), SyscallSucceeds());
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFSTOPPED(status) && WSTOPSIG(status) == (SIGTRAP | 0x80))
+      << " status " << status;
+
+  info = {};
+  ASSERT_THAT(ptrace(kPtraceGetSyscallInfo, child_pid, sizeof(info), &info),
+              SyscallSucceedsWithValue(kPtraceSyscallInfoEntrySize));
+  EXPECT_EQ(info.op, kPtraceSyscallInfoEntry);
+  EXPECT_EQ(info.arch, kAuditArch);
+  EXPECT_EQ(info.entry.nr, SYS_lseek);
+  EXPECT_EQ(info.entry.args[0], static_cast<uint64_t>(-1));
+  EXPECT_EQ(info.entry.args[1], kOffset);
+  EXPECT_EQ(info.entry.args[2], SEEK_CUR);
+  ExpectSyscallInfoRegs(child_pid, info);
+
+  // A smaller buffer is filled partially, and the full size is still
+  // returned.
+  PtraceSyscallInfo partial_info;
+  memset(&partial_info, 0xff, sizeof(partial_info));
+  ASSERT_THAT(ptrace(kPtraceGetSyscallInfo, child_pid, 1, &partial_info),
+              SyscallSucceedsWithValue(kPtraceSyscallInfoEntrySize));
+  EXPECT_EQ(partial_info.op, kPtraceSyscallInfoEntry);
+  EXPECT_EQ(partial_info.arch, 0xffffffff);
+
+  // Continue to syscall-exit-stop for lseek(), which failed.
+  ASSERT_THAT(ptrace(PTRACE_SYSCALL, child_pid, 0, 0), SyscallSucceeds());
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFSTOPPED(status) && WSTOPSIG(status) == (SIGTRAP | 0x80))
+      << " status " << status;
+
+  info = {};
+  ASSERT_THAT(ptrace(kPtraceGetSyscallInfo, child_pid, sizeof(info), &info),
+              SyscallSucceedsWithValue(kPtraceSyscallInfoExitSize));
+  EXPECT_EQ(info.op, kPtraceSyscallInfoExit);
+  EXPECT_EQ(info.arch, kAuditArch);
+  EXPECT_EQ(info.exit.rval, -EBADF);
+  EXPECT_EQ(info.exit.is_error, 1);
+  ExpectSyscallInfoRegs(child_pid, info);
+
+  // Skip over syscall-enter-stop for getpid() to its syscall-exit-stop, which
+  // succeeded.
+  for (int i = 0; i < 2; i++) {
+    ASSERT_THAT(ptrace(PTRACE_SYSCALL, child_pid, 0, 0), SyscallSucceeds());
+    ASSERT_THAT(waitpid(child_pid, &status, 0),
+                SyscallSucceedsWithValue(child_pid));
+    EXPECT_TRUE(WIFSTOPPED(status) && WSTOPSIG(status) == (SIGTRAP | 0x80))
+        << " status " << status;
+  }
+
+  info = {};
+  ASSERT_THAT(ptrace(kPtraceGetSyscallInfo, child_pid, sizeof(info), &info),
+              SyscallSucceedsWithValue(kPtraceSyscallInfoExitSize));
+  EXPECT_EQ(info.op, kPtraceSyscallInfoExit);
+  EXPECT_EQ(info.exit.rval, child_pid);
+  EXPECT_EQ(info.exit.is_error, 0);
+
+  // Resume the child and wait for it to exit.
+  ASSERT_THAT(ptrace(PTRACE_CONT, child_pid, 0, 0), SyscallSucceeds());
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << " status " << status;
+}
+
+TEST(PtraceTest, GetSyscallInfo_SeccompStop) {
+  constexpr off_t kOffset = 0x1234;
+  constexpr uint32_t kRetData = 0x4321;
+
+  pid_t const child_pid = fork();
+  if (child_pid == 0) {
+    // In child process.
+
+    // Enable tracing, then raise SIGSTOP and expect our parent to suppress it.
+    TEST_PCHECK(ptrace(PTRACE_TRACEME, 0, 0, 0) == 0);
+    MaybeSave();
+    RaiseSignal(SIGSTOP);
+    MaybeSave();
+
+    // Install a seccomp filter that returns SECCOMP_RET_TRACE for lseek() and
+    // allows all other syscalls.
+    struct sock_filter filter[] = {
+        // A = seccomp_data.nr
+        BPF_STMT(BPF_LD | BPF_ABS | BPF_W, offsetof(struct seccomp_data, nr)),
+        // if (A != SYS_lseek) goto allow
+        BPF_JUMP(BPF_JMP | BPF_JEQ | BPF_K, SYS_lseek, 0, 1),
+        // return SECCOMP_RET_TRACE | kRetData
+        BPF_STMT(BPF_RET | BPF_K, SECCOMP_RET_TRACE | kRetData),
+        // allow: return SECCOMP_RET_ALLOW
+        BPF_STMT(BPF_RET | BPF_K, SECCOMP_RET_ALLOW),
+    };
+    struct sock_fprog prog;
+    prog.len = sizeof(filter) / sizeof(filter[0]);
+    prog.filter = filter;
+    TEST_PCHECK(prctl(PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0) == 0);
+    TEST_PCHECK(prctl(PR_SET_SECCOMP, SECCOMP_MODE_FILTER, &prog, 0, 0) == 0);
+    MaybeSave();
+
+    TEST_CHECK(syscall(SYS_lseek, -1, kOffset, SEEK_CUR) == -1 &&
+               errno == EBADF);
+    _exit(0);
+  }
+  // In parent process.
+  ASSERT_THAT(child_pid, SyscallSucceeds());
+
+  // Wait for the child to send itself SIGSTOP and enter signal-delivery-stop.
+  int status;
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFSTOPPED(status) && WSTOPSIG(status) == SIGSTOP)
+      << " status " << status;
+
+  // Suppress the SIGSTOP and wait for the child to enter PTRACE_EVENT_SECCOMP
+  // stop for lseek().
+  ASSERT_THAT(ptrace(PTRACE_SETOPTIONS, child_pid, 0, PTRACE_O_TRACESECCOMP),
+              SyscallSucceeds());
+  ASSERT_THAT(ptrace(PTRACE_CONT, child_pid, 0, 0), SyscallSucceeds());
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_EQ(SIGTRAP | (PTRACE_EVENT_SECCOMP << 8), status >> 8);
+
+  PtraceSyscallInfo info = {};
+  ASSERT_THAT(ptrace(kPtraceGetSyscallInfo, child_pid, sizeof(info), &info),
+              SyscallSucceedsWithValue(kPtraceSyscallInfoSeccompSize));
+  EXPECT_EQ(info.op, kPtraceSyscallInfoSeccomp);
+  EXPECT_EQ(info.arch, kAuditArch);
+  EXPECT_EQ(info.seccomp.nr, SYS_lseek);
+  EXPECT_EQ(info.seccomp.args[0], static_cast<uint64_t>(-1));
+  EXPECT_EQ(info.seccomp.args[1], kOffset);
+  EXPECT_EQ(info.seccomp.args[2], SEEK_CUR);
+  EXPECT_EQ(info.seccomp.ret_data, kRetData);
+  ExpectSyscallInfoRegs(child_pid, info);
+
+  // Let lseek() run, and wait for the child to exit.
+  ASSERT_THAT(ptrace(PTRACE_CONT, child_pid, 0, 0), SyscallSucceeds());
+  ASSERT_THAT(waitpid(child_pid, &status, 0),
+              SyscallSucceedsWithValue(child_pid));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << " status " << status;
+}
+
 TEST(PtraceTest, SetYAMAPtraceScope) {
   // Do not modify the ptrace scope on the host.
   SKIP_IF(!IsRunningOnGvisor());
@@ -14,6 +14,8 @@
 
 #include <fcntl.h>
 #include <stdio.h>
+#include <sys/stat.h>
+#include <unistd.h>
 
 #include <string>
 
@@ -518,6 +520,78 @@ TEST(Renameat2Test, NoReplaceDot) {
       SyscallFailsWithErrno(AnyOf(ENOSYS, EINVAL, EEXIST)));
 }
 
+// Returns true if renameat2(RENAME_NOREPLACE) is unsupported for the given
+// path, in which case it fails with ENOSYS or EINVAL (e.g. gofer mounts in
+// shared mode without directfs).
+bool NoReplaceUnsupported(const std::string& path) {
+  std::string const newpath = NewTempAbsPath();
+  if (renameat2(AT_FDCWD, path.c_str(), AT_FDCWD, newpath.c_str(),
+                RENAME_NOREPLACE) < 0) {
+    return errno == ENOSYS || errno == EINVAL;
+  }
+  // Undo the rename.
+  TEST_PCHECK(rename(newpath.c_str(), path.c_str()) == 0);
+  return false;
+}
+
+TEST(Renameat2Test, NoReplaceMovesFile) {
+  auto f = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(GetAbsoluteTestTmpdir(), "data", 0644));
+  SKIP_IF(NoReplaceUnsupported(f.path()));
+
+  std::string const newpath = NewTempAbsPath();
+  ASSERT_THAT(renameat2(AT_FDCWD, f.path().c_str(), AT_FDCWD, newpath.c_str(),
+                        RENAME_NOREPLACE),
+              SyscallSucceeds());
+  auto cleanup = Cleanup([&] { unlink(newpath.c_str()); });
+
+  struct stat st;
+  EXPECT_THAT(stat(f.path().c_str(), &st), SyscallFailsWithErrno(ENOENT));
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(GetContents(newpath)), "data");
+}
+
+TEST(Renameat2Test, NoReplaceExistingPreservesFiles) {
+  auto f1 = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(GetAbsoluteTestTmpdir(), "old", 0644));
+  auto f2 = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(GetAbsoluteTestTmpdir(), "new", 0644));
+  SKIP_IF(NoReplaceUnsupported(f1.path()));
+
+  EXPECT_THAT(renameat2(AT_FDCWD, f1.path().c_str(), AT_FDCWD,
+                        f2.path().c_str(), RENAME_NOREPLACE),
+              SyscallFailsWithErrno(EEXIST));
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(GetContents(f1.path())), "old");
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(GetContents(f2.path())), "new");
+}
+
+TEST(Renameat2Test, NoReplaceDirectoryFd) {
+  auto dir1 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto dir2 = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateDir());
+  auto f = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(dir1.path(), "data", 0644));
+  auto existing = ASSERT_NO_ERRNO_AND_VALUE(
+      TempPath::CreateFileWith(dir2.path(), "existing", 0644));
+  SKIP_IF(NoReplaceUnsupported(f.path()));
+  const FileDescriptor dirfd1 =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir1.path(), O_RDONLY | O_DIRECTORY));
+  const FileDescriptor dirfd2 =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(dir2.path(), O_RDONLY | O_DIRECTORY));
+
+  std::string const name = std::string(Basename(f.path()));
+  EXPECT_THAT(renameat2(dirfd1.get(), name.c_str(), dirfd2.get(),
+                        std::string(Basename(existing.path())).c_str(),
+                        RENAME_NOREPLACE),
+              SyscallFailsWithErrno(EEXIST));
+  ASSERT_THAT(renameat2(dirfd1.get(), name.c_str(), dirfd2.get(), "moved",
+                        RENAME_NOREPLACE),
+              SyscallSucceeds());
+  EXPECT_EQ(ASSERT_NO_ERRNO_AND_VALUE(
+                GetContents(JoinPath(dir2.path(), "moved"))),
+            "data");
+  ASSERT_THAT(unlink(JoinPath(dir2.path(), "moved").c_str()),
+              SyscallSucceeds());
+}
+
 }  // namespace
 
 }  // namespace testing
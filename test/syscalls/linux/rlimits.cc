@@ -13,6 +13,8 @@
 // limitations under the License.
 
 #include <errno.h>
+#include <fcntl.h>
+#include <signal.h>
 #include <stdlib.h>
 #include <sys/resource.h>
 #include <sys/time.h>
@@ -22,6 +24,7 @@
 
 #include <algorithm>
 #include <climits>
+#include <cstring>
 #include <string>
 #include <vector>
 
@@ -29,6 +32,9 @@
 #include "absl/strings/numbers.h"
 #include "absl/strings/str_split.h"
 #include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/logging.h"
 #include "test/util/proc_util.h"
 #include "test/util/test_util.h"
 #include "test/util/thread_util.h"
@@ -155,6 +161,106 @@ TEST(RlimitTest, RlimitNProc) {
   }).Join();
 }
 
+TEST(RlimitTest, PrlimitRaisesNofileOfOtherProcess) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_RESOURCE)));
+
+  constexpr rlim_t kLowLimit = 64;
+  constexpr rlim_t kHighLimit = 1024;
+
+  int lowered[2], raised[2];
+  ASSERT_THAT(pipe(lowered), SyscallSucceeds());
+  ASSERT_THAT(pipe(raised), SyscallSucceeds());
+
+  pid_t child = fork();
+  if (child == 0) {
+    close(lowered[0]);
+    close(raised[1]);
+    struct rlimit rl = {kLowLimit, kLowLimit};
+    TEST_PCHECK(setrlimit(RLIMIT_NOFILE, &rl) == 0);
+    TEST_PCHECK(dup2(lowered[1], kHighLimit - 1) < 0 && errno == EBADF);
+    char c = 0;
+    TEST_PCHECK(write(lowered[1], &c, 1) == 1);
+    TEST_PCHECK(read(raised[0], &c, 1) == 1);
+    // The limit raised by the parent applies without further action.
+    TEST_PCHECK(dup2(lowered[1], kHighLimit - 1) == kHighLimit - 1);
+    _exit(0);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+  close(lowered[1]);
+  close(raised[0]);
+
+  char c;
+  ASSERT_THAT(read(lowered[0], &c, 1), SyscallSucceedsWithValue(1));
+  struct rlimit old_rl = {};
+  struct rlimit new_rl = {kHighLimit, kHighLimit};
+  ASSERT_THAT(prlimit(child, RLIMIT_NOFILE, &new_rl, &old_rl),
+              SyscallSucceeds());
+  EXPECT_EQ(old_rl.rlim_cur, kLowLimit);
+  EXPECT_EQ(old_rl.rlim_max, kLowLimit);
+  ASSERT_THAT(write(raised[1], &c, 1), SyscallSucceedsWithValue(1));
+
+  int status;
+  ASSERT_THAT(RetryEINTR(waitpid)(child, &status, 0),
+              SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << "status = " << status;
+  close(lowered[0]);
+  close(raised[1]);
+}
+
+TEST(RlimitTest, PrlimitOtherProcessWithoutCapability) {
+  // Both processes have the same credentials, so no capability is needed.
+  AutoCapability cap(CAP_SYS_RESOURCE, false);
+
+  pid_t child = fork();
+  if (child == 0) {
+    while (true) {
+      pause();
+    }
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+  auto kill_child = Cleanup([child] {
+    EXPECT_THAT(kill(child, SIGKILL), SyscallSucceeds());
+    EXPECT_THAT(waitpid(child, nullptr, 0), SyscallSucceeds());
+  });
+
+  struct rlimit rl = {};
+  ASSERT_THAT(prlimit(child, RLIMIT_NOFILE, nullptr, &rl), SyscallSucceeds());
+  rl.rlim_cur = rl.rlim_max / 2;
+  EXPECT_THAT(prlimit(child, RLIMIT_NOFILE, &rl, nullptr), SyscallSucceeds());
+
+  // Raising the hard limit of another process requires CAP_SYS_RESOURCE.
+  rl.rlim_max++;
+  EXPECT_THAT(prlimit(child, RLIMIT_NOFILE, &rl, nullptr),
+              SyscallFailsWithErrno(EPERM));
+}
+
+TEST(RlimitTest, FileMaxLimitsUnprivileged) {
+  // Changing fs.file-max affects the whole host when running natively.
+  SKIP_IF(!IsRunningOnGvisor());
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  const FileDescriptor file_max =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/proc/sys/fs/file-max", O_RDWR));
+  char old_max[32] = {};
+  ASSERT_THAT(pread(file_max.get(), old_max, sizeof(old_max) - 1, 0),
+              SyscallSucceeds());
+  ASSERT_THAT(pwrite(file_max.get(), "1", 1, 0), SyscallSucceedsWithValue(1));
+  auto restore = Cleanup([&] {
+    EXPECT_THAT(pwrite(file_max.get(), old_max, strlen(old_max), 0),
+                SyscallSucceeds());
+  });
+
+  {
+    AutoCapability cap(CAP_SYS_ADMIN, false);
+    EXPECT_THAT(open("/dev/null", O_RDONLY), SyscallFailsWithErrno(ENFILE));
+  }
+
+  // CAP_SYS_ADMIN is exempt from the limit.
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/null", O_RDONLY));
+}
+
 TEST(RlimitTest, ParseProcPidLimits) {
   auto proc_self_limits =
       ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/self/limits"));
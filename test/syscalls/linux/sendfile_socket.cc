@@ -13,6 +13,7 @@
 // limitations under the License.
 
 #include <arpa/inet.h>
+#include <fcntl.h>
 #include <netinet/in.h>
 #include <sys/sendfile.h>
 #include <sys/socket.h>
@@ -23,6 +24,8 @@
 
 #include "gtest/gtest.h"
 #include "absl/strings/string_view.h"
+#include "absl/time/clock.h"
+#include "absl/time/time.h"
 #include "test/syscalls/linux/ip_socket_test_util.h"
 #include "test/util/file_descriptor.h"
 #include "test/util/socket_util.h"
@@ -34,6 +37,37 @@ namespace gvisor {
 namespace testing {
 namespace {
 
+// Reads from fd until EOF and returns the data read.
+PosixErrorOr<std::vector<char>> ReadUntilEOF(int fd) {
+  std::vector<char> data;
+  char buf[10240];
+  while (true) {
+    int n = RetryEINTR(read)(fd, buf, sizeof(buf));
+    if (n < 0) {
+      return PosixError(errno, "read");
+    }
+    if (n == 0) {
+      return data;
+    }
+    data.insert(data.end(), buf, buf + n);
+  }
+}
+
+// Limits the amount of data buffered in the connection between socks, so that
+// sendfile blocks early.
+PosixError LimitBuffers(const SocketPair& socks) {
+  constexpr int kBufSize = 64 << 10;
+  if (setsockopt(socks.second_fd(), SOL_SOCKET, SO_SNDBUF, &kBufSize,
+                 sizeof(kBufSize)) < 0) {
+    return PosixError(errno, "setsockopt(SO_SNDBUF)");
+  }
+  if (setsockopt(socks.first_fd(), SOL_SOCKET, SO_RCVBUF, &kBufSize,
+                 sizeof(kBufSize)) < 0) {
+    return PosixError(errno, "setsockopt(SO_RCVBUF)");
+  }
+  return NoError();
+}
+
 class SendFileTest : public ::testing::TestWithParam<int> {
  protected:
   PosixErrorOr<std::unique_ptr<SocketPair>> Sockets(int type) {
@@ -158,6 +192,198 @@ TEST_P(SendFileTest, SendMultiple) {
   ASSERT_EQ(memcmp(data.data(), actual.data(), data.size()), 0);
 }
 
+// Sends a file with an explicit offset, which must not change the file offset.
+TEST_P(SendFileTest, SendWithOffset) {
+  std::vector<char> data(1024 * 1024);
+  RandomizeBuffer(data.data(), data.size());
+  const TempPath in_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), absl::string_view(data.data(), data.size()),
+      TempPath::kDefaultFileMode));
+  const FileDescriptor inf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file.path(), O_RDONLY));
+
+  auto socks = ASSERT_NO_ERRNO_AND_VALUE(Sockets(SOCK_STREAM));
+  std::vector<char> received;
+  ScopedThread th([&] {
+    received = ASSERT_NO_ERRNO_AND_VALUE(ReadUntilEOF(socks->first_fd()));
+  });
+
+  constexpr off_t kStart = 1000;
+  off_t offset = kStart;
+  while (offset < static_cast<off_t>(data.size())) {
+    ASSERT_THAT(sendfile(socks->second_fd(), inf.get(), &offset,
+                         data.size() - offset),
+                SyscallSucceeds());
+  }
+  EXPECT_EQ(offset, static_cast<off_t>(data.size()));
+  EXPECT_THAT(lseek(inf.get(), 0, SEEK_CUR), SyscallSucceedsWithValue(0));
+
+  // Nothing is left to send past the end of the file.
+  EXPECT_THAT(sendfile(socks->second_fd(), inf.get(), &offset, 1),
+              SyscallSucceedsWithValue(0));
+  EXPECT_EQ(offset, static_cast<off_t>(data.size()));
+
+  ASSERT_THAT(shutdown(socks->second_fd(), SHUT_WR), SyscallSucceeds());
+  th.Join();
+  ASSERT_EQ(received.size(), data.size() - kStart);
+  EXPECT_EQ(memcmp(received.data(), data.data() + kStart, received.size()), 0);
+}
+
+// Sends a file without an offset, which must read from and update the file
+// offset.
+TEST_P(SendFileTest, SendWithoutOffset) {
+  std::vector<char> data(1024 * 1024);
+  RandomizeBuffer(data.data(), data.size());
+  const TempPath in_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), absl::string_view(data.data(), data.size()),
+      TempPath::kDefaultFileMode));
+  const FileDescriptor inf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file.path(), O_RDONLY));
+
+  auto socks = ASSERT_NO_ERRNO_AND_VALUE(Sockets(SOCK_STREAM));
+  std::vector<char> received;
+  ScopedThread th([&] {
+    received = ASSERT_NO_ERRNO_AND_VALUE(ReadUntilEOF(socks->first_fd()));
+  });
+
+  constexpr off_t kStart = 1000;
+  ASSERT_THAT(lseek(inf.get(), kStart, SEEK_SET),
+              SyscallSucceedsWithValue(kStart));
+  size_t sent = kStart;
+  while (sent < data.size()) {
+    int n;
+    ASSERT_THAT(n = sendfile(socks->second_fd(), inf.get(), nullptr,
+                             data.size() - sent),
+                SyscallSucceeds());
+    sent += n;
+    EXPECT_THAT(lseek(inf.get(), 0, SEEK_CUR), SyscallSucceedsWithValue(sent));
+  }
+  EXPECT_THAT(sendfile(socks->second_fd(), inf.get(), nullptr, 1),
+              SyscallSucceedsWithValue(0));
+
+  ASSERT_THAT(shutdown(socks->second_fd(), SHUT_WR), SyscallSucceeds());
+  th.Join();
+  ASSERT_EQ(received.size(), data.size() - kStart);
+  EXPECT_EQ(memcmp(received.data(), data.data() + kStart, received.size()), 0);
+}
+
+// On a non-blocking socket, sendfile sends what fits in the socket buffers and
+// advances the file offset by exactly the amount sent.
+TEST_P(SendFileTest, SendNonBlocking) {
+  std::vector<char> data(8 * 1024 * 1024);
+  RandomizeBuffer(data.data(), data.size());
+  const TempPath in_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), absl::string_view(data.data(), data.size()),
+      TempPath::kDefaultFileMode));
+  const FileDescriptor inf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file.path(), O_RDONLY));
+
+  auto socks = ASSERT_NO_ERRNO_AND_VALUE(Sockets(SOCK_STREAM));
+  ASSERT_NO_ERRNO(LimitBuffers(*socks));
+  int flags;
+  ASSERT_THAT(flags = fcntl(socks->second_fd(), F_GETFL), SyscallSucceeds());
+  ASSERT_THAT(fcntl(socks->second_fd(), F_SETFL, flags | O_NONBLOCK),
+              SyscallSucceeds());
+
+  // Fill the socket buffers.
+  size_t sent = 0;
+  while (true) {
+    int n = sendfile(socks->second_fd(), inf.get(), nullptr,
+                     data.size() - sent);
+    if (n < 0) {
+      ASSERT_EQ(errno, EAGAIN);
+      break;
+    }
+    ASSERT_GT(n, 0);
+    sent += n;
+    ASSERT_LT(sent, data.size());
+  }
+  EXPECT_GT(sent, 0);
+  EXPECT_THAT(lseek(inf.get(), 0, SEEK_CUR), SyscallSucceedsWithValue(sent));
+
+  // Exactly the data sent, and nothing else, is received.
+  ASSERT_THAT(shutdown(socks->second_fd(), SHUT_WR), SyscallSucceeds());
+  std::vector<char> received =
+      ASSERT_NO_ERRNO_AND_VALUE(ReadUntilEOF(socks->first_fd()));
+  ASSERT_EQ(received.size(), sent);
+  EXPECT_EQ(memcmp(received.data(), data.data(), received.size()), 0);
+}
+
+// Data appended to the file while sendfile is blocked is sent.
+TEST_P(SendFileTest, FileAppendedDuringSend) {
+  constexpr size_t kSize = 4 * 1024 * 1024;
+  std::vector<char> data(2 * kSize);
+  RandomizeBuffer(data.data(), data.size());
+  const TempPath in_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), absl::string_view(data.data(), kSize),
+      TempPath::kDefaultFileMode));
+  const FileDescriptor inf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file.path(), O_RDONLY));
+
+  auto socks = ASSERT_NO_ERRNO_AND_VALUE(Sockets(SOCK_STREAM));
+  ASSERT_NO_ERRNO(LimitBuffers(*socks));
+
+  // Nothing reads from the socket yet, so sendfile blocks after filling the
+  // socket buffers.
+  int sendfile_ret = -1;
+  ScopedThread th([&] {
+    off_t offset = 0;
+    sendfile_ret = sendfile(socks->second_fd(), inf.get(), &offset,
+                            data.size());
+    TEST_PCHECK(shutdown(socks->second_fd(), SHUT_WR) == 0);
+  });
+  absl::SleepFor(absl::Seconds(1));
+
+  const FileDescriptor appendf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file.path(), O_WRONLY | O_APPEND));
+  ASSERT_THAT(WriteFd(appendf.get(), data.data() + kSize, kSize),
+              SyscallSucceedsWithValue(kSize));
+
+  std::vector<char> received =
+      ASSERT_NO_ERRNO_AND_VALUE(ReadUntilEOF(socks->first_fd()));
+  th.Join();
+  EXPECT_EQ(sendfile_ret, static_cast<int>(data.size()));
+  ASSERT_EQ(received.size(), data.size());
+  EXPECT_EQ(memcmp(received.data(), data.data(), received.size()), 0);
+}
+
+// A file truncated while sendfile is blocked is sent up to its new size.
+TEST_P(SendFileTest, FileTruncatedDuringSend) {
+  constexpr size_t kTruncatedSize = 4 * 1024 * 1024;
+  std::vector<char> data(2 * kTruncatedSize);
+  RandomizeBuffer(data.data(), data.size());
+  const TempPath in_file = ASSERT_NO_ERRNO_AND_VALUE(TempPath::CreateFileWith(
+      GetAbsoluteTestTmpdir(), absl::string_view(data.data(), data.size()),
+      TempPath::kDefaultFileMode));
+  const FileDescriptor inf =
+      ASSERT_NO_ERRNO_AND_VALUE(Open(in_file.path(), O_RDONLY));
+
+  auto socks = ASSERT_NO_ERRNO_AND_VALUE(Sockets(SOCK_STREAM));
+  ASSERT_NO_ERRNO(LimitBuffers(*socks));
+
+  // Nothing reads from the socket yet, so sendfile blocks after filling the
+  // socket buffers.
+  int sendfile_ret = -1;
+  ScopedThread th([&] {
+    sendfile_ret = sendfile(socks->second_fd(), inf.get(), nullptr,
+                            data.size());
+    TEST_PCHECK(shutdown(socks->second_fd(), SHUT_WR) == 0);
+  });
+  absl::SleepFor(absl::Seconds(1));
+
+  ASSERT_THAT(truncate(in_file.path().c_str(), kTruncatedSize),
+              SyscallSucceeds());
+
+  std::vector<char> received =
+      ASSERT_NO_ERRNO_AND_VALUE(ReadUntilEOF(socks->first_fd()));
+  th.Join();
+  EXPECT_EQ(sendfile_ret, static_cast<int>(kTruncatedSize));
+  EXPECT_THAT(lseek(inf.get(), 0, SEEK_CUR),
+              SyscallSucceedsWithValue(kTruncatedSize));
+  ASSERT_EQ(received.size(), kTruncatedSize);
+  EXPECT_EQ(memcmp(received.data(), data.data(), received.size()), 0);
+}
+
 TEST_P(SendFileTest, Shutdown) {
   // Create a socket.
   auto socks = ASSERT_NO_ERRNO_AND_VALUE(Sockets(SOCK_STREAM));
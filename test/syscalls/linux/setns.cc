@@ -12,7 +12,11 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+#include <poll.h>
 #include <sched.h>
+#include <sys/syscall.h>
+#include <sys/wait.h>
+#include <unistd.h>
 
 #include <cstdint>
 
@@ -27,6 +31,18 @@ namespace gvisor {
 namespace testing {
 namespace {
 
+#ifndef SYS_pidfd_open
+#define SYS_pidfd_open 434
+#endif
+
+PosixErrorOr<FileDescriptor> PidfdOpen(pid_t pid, unsigned int flags) {
+  int fd = syscall(SYS_pidfd_open, pid, flags);
+  if (fd < 0) {
+    return PosixError(errno, "pidfd_open");
+  }
+  return FileDescriptor(fd);
+}
+
 TEST(SetnsTest, ChangeIPCNamespace) {
   SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
 
@@ -71,6 +87,55 @@ TEST(SetnsTest, ChangeUTSNamespace) {
   EXPECT_EQ(utsns1, utsns3);
 }
 
+TEST(SetnsTest, JoinNamespacesViaPidfd) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+
+  constexpr char kHostname[] = "pidfd-setns";
+  int ready[2], done[2];
+  ASSERT_THAT(pipe(ready), SyscallSucceeds());
+  ASSERT_THAT(pipe(done), SyscallSucceeds());
+
+  pid_t child = fork();
+  if (child == 0) {
+    // Move into new UTS and IPC namespaces with a distinct hostname, and wait
+    // for the parent to join them.
+    TEST_PCHECK(unshare(CLONE_NEWUTS | CLONE_NEWIPC) == 0);
+    TEST_PCHECK(sethostname(kHostname, sizeof(kHostname) - 1) == 0);
+    char c = 0;
+    TEST_PCHECK(write(ready[1], &c, 1) == 1);
+    TEST_PCHECK(read(done[0], &c, 1) == 1);
+    _exit(0);
+  }
+  ASSERT_THAT(child, SyscallSucceeds());
+  close(ready[1]);
+  close(done[0]);
+
+  char c;
+  ASSERT_THAT(read(ready[0], &c, 1), SyscallSucceedsWithValue(1));
+  close(ready[0]);
+
+  const FileDescriptor pidfd = ASSERT_NO_ERRNO_AND_VALUE(PidfdOpen(child, 0));
+  EXPECT_THAT(setns(pidfd.get(), 0), SyscallFailsWithErrno(EINVAL));
+  ASSERT_THAT(setns(pidfd.get(), CLONE_NEWUTS | CLONE_NEWIPC),
+              SyscallSucceeds());
+
+  char hostname[64] = {};
+  ASSERT_THAT(gethostname(hostname, sizeof(hostname)), SyscallSucceeds());
+  EXPECT_STREQ(hostname, kHostname);
+
+  // The pidfd becomes readable once the child exits.
+  struct pollfd pfd = {.fd = pidfd.get(), .events = POLLIN};
+  EXPECT_THAT(poll(&pfd, 1, 0), SyscallSucceedsWithValue(0));
+  ASSERT_THAT(write(done[1], &c, 1), SyscallSucceedsWithValue(1));
+  close(done[1]);
+  EXPECT_THAT(poll(&pfd, 1, -1), SyscallSucceedsWithValue(1));
+
+  int status;
+  ASSERT_THAT(waitpid(child, &status, 0), SyscallSucceedsWithValue(child));
+  EXPECT_TRUE(WIFEXITED(status) && WEXITSTATUS(status) == 0)
+      << "status = " << status;
+}
+
 }  // namespace
 }  // namespace testing
 }  // namespace gvisor
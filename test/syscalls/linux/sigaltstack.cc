@@ -16,8 +16,10 @@
 #include <signal.h>
 #include <stdio.h>
 #include <string.h>
+#include <sys/auxv.h>
 #include <unistd.h>
 
+#include <algorithm>
 #include <functional>
 #include <vector>
 
@@ -30,6 +32,14 @@
 #include "test/util/test_util.h"
 #include "test/util/thread_util.h"
 
+#ifndef AT_MINSIGSTKSZ
+#define AT_MINSIGSTKSZ 51
+#endif
+
+#ifndef SS_AUTODISARM
+#define SS_AUTODISARM (1U << 31)
+#endif
+
 namespace gvisor {
 namespace testing {
 
@@ -87,6 +97,37 @@ TEST(SigaltstackTest, Success) {
   EXPECT_NE(0, ss_flags & SS_ONSTACK);
 }
 
+volatile bool got_minsigstksz_signal = false;
+
+void minsigstksz_handler(int sig) { got_minsigstksz_signal = true; }
+
+TEST(SigaltstackTest, MinSigStkSzIsSufficient) {
+  const size_t min_size = getauxval(AT_MINSIGSTKSZ);
+  SKIP_IF(min_size == 0);
+  EXPECT_EQ(min_size % 16, 0);
+
+  // The handler itself may use a little stack below the signal frame; leave
+  // room for it below the alternate stack.
+  const size_t stack_size = std::max<size_t>(min_size, MINSIGSTKSZ);
+  const size_t headroom = 4096;
+  std::vector<char> stack_mem(headroom + stack_size);
+  stack_t stack = {};
+  stack.ss_sp = stack_mem.data() + headroom;
+  stack.ss_size = stack_size;
+  auto const cleanup_sigstack =
+      ASSERT_NO_ERRNO_AND_VALUE(ScopedSigaltstack(stack));
+
+  struct sigaction sa = {};
+  sa.sa_handler = minsigstksz_handler;
+  sa.sa_flags = SA_ONSTACK;
+  auto const cleanup_sa =
+      ASSERT_NO_ERRNO_AND_VALUE(ScopedSigaction(SIGUSR1, sa));
+
+  // If the signal frame didn't fit, the task would be killed by SIGSEGV.
+  EXPECT_THAT(tgkill(getpid(), gettid(), SIGUSR1), SyscallSucceeds());
+  EXPECT_TRUE(got_minsigstksz_signal);
+}
+
 TEST(SigaltstackTest, ResetByExecve) {
   std::vector<char> stack_mem(SIGSTKSZ);
   stack_t stack = {};
@@ -262,6 +303,80 @@ TEST(SigaltstackTest, SetCurrentStack) {
       ::testing::ExitedWithCode(0), "");
 }
 
+TEST(SigaltstackTest, InvalidFlags) {
+  std::vector<char> stack_mem(SIGSTKSZ);
+  stack_t stack = {};
+  stack.ss_sp = stack_mem.data();
+  stack.ss_size = stack_mem.size();
+  stack.ss_flags = 0x10;
+  EXPECT_THAT(sigaltstack(&stack, nullptr), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(SigaltstackTest, TooSmall) {
+  std::vector<char> stack_mem(MINSIGSTKSZ);
+  stack_t stack = {};
+  stack.ss_sp = stack_mem.data();
+  stack.ss_size = MINSIGSTKSZ - 1;
+  EXPECT_THAT(sigaltstack(&stack, nullptr), SyscallFailsWithErrno(ENOMEM));
+
+  // A disabled stack may be of any size.
+  stack.ss_flags = SS_DISABLE;
+  stack_t old_stack;
+  ASSERT_THAT(sigaltstack(&stack, &old_stack), SyscallSucceeds());
+  EXPECT_THAT(sigaltstack(&old_stack, nullptr), SyscallSucceeds());
+}
+
+volatile int autodisarm_flags = 0;   // Set by the handler.
+volatile int autodisarm_retval = 0;  // Set by the handler.
+volatile int autodisarm_errno = 0;   // Set by the handler.
+
+void autodisarm_handler(int sig, siginfo_t* siginfo, void* arg) {
+  stack_t stack;
+  if (sigaltstack(nullptr, &stack) < 0) {
+    autodisarm_flags = -1;
+    return;
+  }
+  autodisarm_flags = stack.ss_flags;
+
+  // The stack is disarmed, so it may be replaced even though the handler is
+  // running on it. The original stack is restored by sigreturn.
+  static char new_stack_mem[SIGSTKSZ];
+  stack.ss_sp = new_stack_mem;
+  stack.ss_size = sizeof(new_stack_mem);
+  stack.ss_flags = 0;
+  autodisarm_retval = sigaltstack(&stack, nullptr);
+  autodisarm_errno = errno;
+}
+
+TEST(SigaltstackTest, AutoDisarm) {
+  std::vector<char> stack_mem(SIGSTKSZ);
+  stack_t stack = {};
+  stack.ss_sp = stack_mem.data();
+  stack.ss_size = stack_mem.size();
+  stack.ss_flags = SS_AUTODISARM;
+  auto const cleanup_sigstack =
+      ASSERT_NO_ERRNO_AND_VALUE(ScopedSigaltstack(stack));
+
+  struct sigaction sa = {};
+  sa.sa_sigaction = autodisarm_handler;
+  sigfillset(&sa.sa_mask);
+  sa.sa_flags = SA_SIGINFO | SA_ONSTACK;
+  auto const cleanup_sa =
+      ASSERT_NO_ERRNO_AND_VALUE(ScopedSigaction(SIGUSR1, sa));
+
+  // Send signal to this thread, as sigaltstack is per-thread.
+  EXPECT_THAT(tgkill(getpid(), gettid(), SIGUSR1), SyscallSucceeds());
+
+  EXPECT_EQ(autodisarm_flags, SS_DISABLE);
+  EXPECT_EQ(autodisarm_retval, 0) << "errno " << autodisarm_errno;
+
+  stack_t cur_stack;
+  ASSERT_THAT(sigaltstack(nullptr, &cur_stack), SyscallSucceeds());
+  EXPECT_EQ(cur_stack.ss_sp, stack.ss_sp);
+  EXPECT_EQ(cur_stack.ss_size, stack.ss_size);
+  EXPECT_EQ(static_cast<unsigned>(cur_stack.ss_flags), SS_AUTODISARM);
+}
+
 }  // namespace
 
 }  // namespace testing
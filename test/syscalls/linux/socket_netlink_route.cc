@@ -17,6 +17,7 @@
 #include <ifaddrs.h>
 #include <linux/fib_rules.h>
 #include <linux/if.h>
+#include <linux/if_link.h>
 #include <linux/netlink.h>
 #include <linux/rtnetlink.h>
 #include <linux/veth.h>
@@ -200,6 +201,74 @@ TEST(NetlinkRouteTest, GetLinkByIndex) {
   EXPECT_TRUE(found) << "Netlink response does not contain any links.";
 }
 
+TEST(NetlinkRouteTest, GetStatsByIndex) {
+  Link loopback_link = ASSERT_NO_ERRNO_AND_VALUE(LoopbackLink());
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_ROUTE));
+
+  struct request {
+    struct nlmsghdr hdr;
+    struct if_stats_msg ifsm;
+  };
+
+  struct request req = {};
+  req.hdr.nlmsg_len = sizeof(req);
+  req.hdr.nlmsg_type = RTM_GETSTATS;
+  req.hdr.nlmsg_flags = NLM_F_REQUEST;
+  req.hdr.nlmsg_seq = kSeq;
+  req.ifsm.family = AF_UNSPEC;
+  req.ifsm.ifindex = loopback_link.index;
+  req.ifsm.filter_mask = IFLA_STATS_FILTER_BIT(IFLA_STATS_LINK_64);
+
+  bool found = false;
+  ASSERT_NO_ERRNO(NetlinkRequestResponse(
+      fd, &req, sizeof(req),
+      [&](const struct nlmsghdr* hdr) {
+        ASSERT_EQ(hdr->nlmsg_type, RTM_NEWSTATS);
+        ASSERT_GE(hdr->nlmsg_len, NLMSG_LENGTH(sizeof(struct if_stats_msg)));
+        const struct if_stats_msg* msg =
+            reinterpret_cast<const struct if_stats_msg*>(NLMSG_DATA(hdr));
+        EXPECT_EQ(msg->ifindex, loopback_link.index);
+
+        const struct rtattr* rta = reinterpret_cast<const struct rtattr*>(
+            reinterpret_cast<const char*>(msg) +
+            NLMSG_ALIGN(sizeof(struct if_stats_msg)));
+        int len = hdr->nlmsg_len - NLMSG_LENGTH(sizeof(struct if_stats_msg));
+        for (; RTA_OK(rta, len); rta = RTA_NEXT(rta, len)) {
+          if (rta->rta_type == IFLA_STATS_LINK_64) {
+            EXPECT_GE(RTA_PAYLOAD(rta), sizeof(struct rtnl_link_stats64));
+            found = true;
+          }
+        }
+      },
+      false));
+  EXPECT_TRUE(found) << "Netlink response does not contain link stats.";
+}
+
+TEST(NetlinkRouteTest, GetStatsNoFilter) {
+  Link loopback_link = ASSERT_NO_ERRNO_AND_VALUE(LoopbackLink());
+
+  FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_ROUTE));
+
+  struct request {
+    struct nlmsghdr hdr;
+    struct if_stats_msg ifsm;
+  };
+
+  struct request req = {};
+  req.hdr.nlmsg_len = sizeof(req);
+  req.hdr.nlmsg_type = RTM_GETSTATS;
+  req.hdr.nlmsg_flags = NLM_F_REQUEST | NLM_F_ACK;
+  req.hdr.nlmsg_seq = kSeq;
+  req.ifsm.family = AF_UNSPEC;
+  req.ifsm.ifindex = loopback_link.index;
+
+  EXPECT_THAT(NetlinkRequestAckOrError(fd, kSeq, &req, sizeof(req)),
+              PosixErrorIs(EINVAL, _));
+}
+
 TEST(NetlinkRouteTest, LinkUp) {
   SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
   SKIP_IF(IsRunningWithHostinet());
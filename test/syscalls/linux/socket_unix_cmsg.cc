@@ -18,6 +18,7 @@
 #include <net/if.h>
 #include <stdio.h>
 #include <sys/ioctl.h>
+#include <sys/resource.h>
 #include <sys/socket.h>
 #include <sys/types.h>
 #include <sys/un.h>
@@ -27,6 +28,9 @@
 #include "gtest/gtest.h"
 #include "absl/strings/string_view.h"
 #include "test/syscalls/linux/unix_domain_socket_test_util.h"
+#include "test/util/capability_util.h"
+#include "test/util/cleanup.h"
+#include "test/util/rlimit_util.h"
 #include "test/util/socket_util.h"
 #include "test/util/test_util.h"
 #include "test/util/thread_util.h"
@@ -120,6 +124,45 @@ TEST_P(UnixSocketPairCmsgTest, BasicThreeFDPass) {
   ASSERT_NO_FATAL_FAILURE(TransferTest(received_fds[2], pair3->first_fd()));
 }
 
+// Sends fds with sendmsg(2) and returns its result.
+int SendFDsRaw(int sock, const std::vector<int>& fds) {
+  std::vector<char> control(CMSG_SPACE(fds.size() * sizeof(int)));
+  char data = 'a';
+  struct iovec iov = {&data, sizeof(data)};
+  struct msghdr msg = {};
+  msg.msg_iov = &iov;
+  msg.msg_iovlen = 1;
+  msg.msg_control = control.data();
+  msg.msg_controllen = control.size();
+  struct cmsghdr* cmsg = CMSG_FIRSTHDR(&msg);
+  cmsg->cmsg_level = SOL_SOCKET;
+  cmsg->cmsg_type = SCM_RIGHTS;
+  cmsg->cmsg_len = CMSG_LEN(fds.size() * sizeof(int));
+  memcpy(CMSG_DATA(cmsg), fds.data(), fds.size() * sizeof(int));
+  return RetryEINTR(sendmsg)(sock, &msg, 0);
+}
+
+TEST_P(UnixSocketPairCmsgTest, InflightFDsLimitedByNofile) {
+  // Privileged users aren't subject to the limit.
+  AutoCapability cap1(CAP_SYS_ADMIN, false);
+  AutoCapability cap2(CAP_SYS_RESOURCE, false);
+
+  auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
+  auto pair =
+      ASSERT_NO_ERRNO_AND_VALUE(UnixDomainSocketPair(SOCK_SEQPACKET).Create());
+
+  constexpr int kNofile = 64;
+  Cleanup reset_nofile =
+      ASSERT_NO_ERRNO_AND_VALUE(ScopedSetSoftRlimit(RLIMIT_NOFILE, kNofile));
+
+  // Like Linux, sending only fails once more files than the limit are in
+  // flight.
+  std::vector<int> fds(kNofile + 1, pair->second_fd());
+  ASSERT_THAT(SendFDsRaw(sockets->first_fd(), fds), SyscallSucceedsWithValue(1));
+  EXPECT_THAT(SendFDsRaw(sockets->first_fd(), {pair->second_fd()}),
+              SyscallFailsWithErrno(ETOOMANYREFS));
+}
+
 TEST_P(UnixSocketPairCmsgTest, BadFDPass) {
   auto sockets = ASSERT_NO_ERRNO_AND_VALUE(NewSocketPair());
 
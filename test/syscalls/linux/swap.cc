@@ -0,0 +1,128 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <sys/syscall.h>
+#include <sys/sysinfo.h>
+#include <unistd.h>
+
+#include <string>
+#include <vector>
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "absl/strings/numbers.h"
+#include "absl/strings/str_split.h"
+#include "test/util/capability_util.h"
+#include "test/util/fs_util.h"
+#include "test/util/temp_path.h"
+#include "test/util/test_util.h"
+
+namespace gvisor {
+namespace testing {
+
+namespace {
+
+// From include/linux/swap.h.
+constexpr int kSwapFlagDiscardPages = 0x40000;
+
+int swapon(const char* path, int flags) {
+  return syscall(SYS_swapon, path, flags);
+}
+
+int swapoff(const char* path) { return syscall(SYS_swapoff, path); }
+
+// Returns a file containing a page of zeroes, which is not a valid swap area.
+PosixErrorOr<TempPath> CreateNonSwapFile() {
+  return TempPath::CreateFileWith(GetAbsoluteTestTmpdir(),
+                                  std::string(kPageSize, '\0'), 0600);
+}
+
+TEST(SwaponTest, InvalidFlags) {
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(CreateNonSwapFile());
+  EXPECT_THAT(swapon(file.path().c_str(), kSwapFlagDiscardPages << 1),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(SwaponTest, NoCapability) {
+  AutoCapability cap(CAP_SYS_ADMIN, false);
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(CreateNonSwapFile());
+  EXPECT_THAT(swapon(file.path().c_str(), 0), SyscallFailsWithErrno(EPERM));
+}
+
+TEST(SwaponTest, NonexistentPath) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  EXPECT_THAT(swapon("/foo/bar", 0), SyscallFailsWithErrno(ENOENT));
+}
+
+TEST(SwaponTest, NotSwapArea) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(CreateNonSwapFile());
+  EXPECT_THAT(swapon(file.path().c_str(), 0), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(SwapoffTest, NoCapability) {
+  AutoCapability cap(CAP_SYS_ADMIN, false);
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(CreateNonSwapFile());
+  EXPECT_THAT(swapoff(file.path().c_str()), SyscallFailsWithErrno(EPERM));
+}
+
+TEST(SwapoffTest, NonexistentPath) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  EXPECT_THAT(swapoff("/foo/bar"), SyscallFailsWithErrno(ENOENT));
+}
+
+TEST(SwapoffTest, NotActiveSwapArea) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYS_ADMIN)));
+  const TempPath file = ASSERT_NO_ERRNO_AND_VALUE(CreateNonSwapFile());
+  EXPECT_THAT(swapoff(file.path().c_str()), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(ProcSwapsTest, ConsistentWithSysinfo) {
+  const std::string contents =
+      ASSERT_NO_ERRNO_AND_VALUE(GetContents("/proc/swaps"));
+  std::vector<std::string> lines =
+      absl::StrSplit(contents, '\n', absl::SkipEmpty());
+  ASSERT_GE(lines.size(), 1);
+  EXPECT_THAT(lines[0], ::testing::StartsWith("Filename"));
+
+  // Sum the sizes and usages of all swap areas, in KiB.
+  uint64_t total_kb = 0;
+  uint64_t used_kb = 0;
+  for (size_t i = 1; i < lines.size(); i++) {
+    std::vector<std::string> fields =
+        absl::StrSplit(lines[i], absl::ByAnyChar(" \t"), absl::SkipEmpty());
+    ASSERT_EQ(fields.size(), 5) << lines[i];
+    uint64_t size, used;
+    ASSERT_TRUE(absl::SimpleAtoi(fields[2], &size)) << lines[i];
+    ASSERT_TRUE(absl::SimpleAtoi(fields[3], &used)) << lines[i];
+    EXPECT_LE(used, size);
+    total_kb += size;
+    used_kb += used;
+  }
+
+  struct sysinfo info = {};
+  ASSERT_THAT(sysinfo(&info), SyscallSucceeds());
+  EXPECT_EQ(info.totalswap * info.mem_unit / 1024, total_kb);
+  // Swap usage may change between reading /proc/swaps and sysinfo(2) on
+  // Linux, so only check it when no swap is configured.
+  if (total_kb == 0) {
+    EXPECT_EQ(info.freeswap, 0);
+    EXPECT_EQ(used_kb, 0);
+  }
+}
+
+}  // namespace
+
+}  // namespace testing
+}  // namespace gvisor
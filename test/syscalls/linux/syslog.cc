@@ -12,11 +12,19 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+#include <fcntl.h>
 #include <sys/klog.h>
 #include <sys/syscall.h>
 #include <unistd.h>
 
+#include <string>
+
 #include "gtest/gtest.h"
+#include "absl/strings/match.h"
+#include "test/util/capability_util.h"
+#include "test/util/file_descriptor.h"
+#include "test/util/fs_util.h"
+#include "test/util/posix_error.h"
 #include "test/util/test_util.h"
 
 namespace gvisor {
@@ -25,15 +33,15 @@ namespace testing {
 namespace {
 
 constexpr int SYSLOG_ACTION_READ_ALL = 3;
+constexpr int SYSLOG_ACTION_CLEAR = 5;
+constexpr int SYSLOG_ACTION_CONSOLE_LEVEL = 8;
+constexpr int SYSLOG_ACTION_SIZE_UNREAD = 9;
 constexpr int SYSLOG_ACTION_SIZE_BUFFER = 10;
 
 int Syslog(int type, char* buf, int len) {
   return syscall(__NR_syslog, type, buf, len);
 }
 
-// Only SYSLOG_ACTION_SIZE_BUFFER and SYSLOG_ACTION_READ_ALL are implemented in
-// gVisor.
-
 TEST(Syslog, Size) {
   EXPECT_THAT(Syslog(SYSLOG_ACTION_SIZE_BUFFER, nullptr, 0), SyscallSucceeds());
 }
@@ -45,6 +53,138 @@ TEST(Syslog, ReadAll) {
               SyscallSucceeds());
 }
 
+TEST(Syslog, ReadAllInvalid) {
+  char buf[100];
+  EXPECT_THAT(Syslog(SYSLOG_ACTION_READ_ALL, nullptr, sizeof(buf)),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(Syslog(SYSLOG_ACTION_READ_ALL, buf, -1),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(Syslog, InvalidAction) {
+  EXPECT_THAT(Syslog(100, nullptr, 0), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(Syslog, PrivilegedActionsRequireCapability) {
+  AutoCapability syslog(CAP_SYSLOG, false);
+  AutoCapability sys_admin(CAP_SYS_ADMIN, false);
+  EXPECT_THAT(Syslog(SYSLOG_ACTION_CLEAR, nullptr, 0),
+              SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(Syslog(SYSLOG_ACTION_SIZE_UNREAD, nullptr, 0),
+              SyscallFailsWithErrno(EPERM));
+}
+
+TEST(Syslog, ConsoleLevelInvalid) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYSLOG)));
+  EXPECT_THAT(Syslog(SYSLOG_ACTION_CONSOLE_LEVEL, nullptr, 0),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(Syslog(SYSLOG_ACTION_CONSOLE_LEVEL, nullptr, 9),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(Syslog, SizeUnread) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYSLOG)));
+  EXPECT_THAT(Syslog(SYSLOG_ACTION_SIZE_UNREAD, nullptr, 0),
+              SyscallSucceeds());
+}
+
+TEST(Kmsg, WriteRead) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYSLOG)));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/kmsg", O_RDWR | O_NONBLOCK));
+  ASSERT_THAT(lseek(fd.get(), 0, SEEK_END), SyscallSucceeds());
+
+  // Priority 5 (LOG_NOTICE) without a facility is logged with facility
+  // LOG_USER.
+  constexpr char kMsg[] = "<5>gvisor kmsg test\n";
+  ASSERT_THAT(write(fd.get(), kMsg, sizeof(kMsg) - 1),
+              SyscallSucceedsWithValue(sizeof(kMsg) - 1));
+
+  // Other messages may be logged concurrently, so look for ours.
+  char buf[8192];
+  bool found = false;
+  while (!found) {
+    int n;
+    ASSERT_THAT(n = read(fd.get(), buf, sizeof(buf)), SyscallSucceeds());
+    std::string rec(buf, n);
+    if (absl::EndsWith(rec, ";gvisor kmsg test\n")) {
+      EXPECT_TRUE(absl::StartsWith(rec, "13,")) << rec;
+      found = true;
+    }
+  }
+}
+
+TEST(Kmsg, ReadEmpty) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYSLOG)));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/kmsg", O_RDONLY | O_NONBLOCK));
+  ASSERT_THAT(lseek(fd.get(), 0, SEEK_END), SyscallSucceeds());
+  char buf[8192];
+  EXPECT_THAT(read(fd.get(), buf, sizeof(buf)), SyscallFailsWithErrno(EAGAIN));
+}
+
+TEST(Kmsg, ReadBufferTooSmall) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYSLOG)));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/kmsg", O_RDWR | O_NONBLOCK));
+  ASSERT_THAT(lseek(fd.get(), 0, SEEK_END), SyscallSucceeds());
+  constexpr char kMsg[] = "gvisor kmsg test";
+  ASSERT_THAT(write(fd.get(), kMsg, sizeof(kMsg) - 1), SyscallSucceeds());
+  char buf[4];
+  EXPECT_THAT(read(fd.get(), buf, sizeof(buf)), SyscallFailsWithErrno(EINVAL));
+}
+
+TEST(Kmsg, SeekNonZero) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_SYSLOG)));
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/kmsg", O_RDONLY | O_NONBLOCK));
+  EXPECT_THAT(lseek(fd.get(), 1, SEEK_SET), SyscallFailsWithErrno(ESPIPE));
+}
+
+// Returns the value of kernel.dmesg_restrict.
+PosixErrorOr<bool> DmesgRestricted() {
+  ASSIGN_OR_RETURN_ERRNO(std::string val,
+                         GetContents("/proc/sys/kernel/dmesg_restrict"));
+  return absl::StartsWith(val, "1");
+}
+
+TEST(Syslog, ReadAllRestricted) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(DmesgRestricted()));
+  AutoCapability syslog(CAP_SYSLOG, false);
+  AutoCapability sys_admin(CAP_SYS_ADMIN, false);
+  char buf[100];
+  EXPECT_THAT(Syslog(SYSLOG_ACTION_READ_ALL, buf, sizeof(buf)),
+              SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(Syslog(SYSLOG_ACTION_SIZE_BUFFER, nullptr, 0),
+              SyscallFailsWithErrno(EPERM));
+}
+
+TEST(Syslog, DmesgRestrictedByDefault) {
+  SKIP_IF(!IsRunningOnGvisor());
+  EXPECT_TRUE(ASSERT_NO_ERRNO_AND_VALUE(DmesgRestricted()));
+}
+
+TEST(Kmsg, ReadRestricted) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(DmesgRestricted()));
+  AutoCapability syslog(CAP_SYSLOG, false);
+  AutoCapability sys_admin(CAP_SYS_ADMIN, false);
+  EXPECT_THAT(open("/dev/kmsg", O_RDONLY), SyscallFailsWithErrno(EPERM));
+  EXPECT_THAT(open("/dev/kmsg", O_RDWR), SyscallFailsWithErrno(EPERM));
+
+  // Logging is still allowed.
+  const FileDescriptor fd =
+      ASSERT_NO_ERRNO_AND_VALUE(Open("/dev/kmsg", O_WRONLY));
+  constexpr char kMsg[] = "gvisor kmsg test";
+  EXPECT_THAT(write(fd.get(), kMsg, sizeof(kMsg) - 1),
+              SyscallSucceedsWithValue(sizeof(kMsg) - 1));
+}
+
+TEST(ProcKmsg, OpenRequiresCapability) {
+  AutoCapability syslog(CAP_SYSLOG, false);
+  AutoCapability sys_admin(CAP_SYS_ADMIN, false);
+  EXPECT_THAT(open("/proc/kmsg", O_RDONLY), SyscallFailsWithErrno(EPERM));
+}
+
 }  // namespace
 
 }  // namespace testing
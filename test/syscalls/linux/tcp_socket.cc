@@ -26,7 +26,9 @@
 #include <sys/socket.h>
 #include <unistd.h>
 
+#include <cstring>
 #include <limits>
+#include <string>
 #include <vector>
 
 #include "gmock/gmock.h"
@@ -2753,6 +2755,84 @@ TEST_P(SimpleTcpSocketTest, SetTCPCorkOff) {
 }
 #endif  // __linux__
 
+// SetMD5Key installs a TCP MD5 signature key for the loopback address on fd.
+PosixError SetMD5Key(int fd, int family, const std::string& key) {
+  struct tcp_md5sig sig = {};
+  sockaddr_storage addr = InetLoopbackAddr(family);
+  memcpy(&sig.tcpm_addr, &addr, sizeof(addr));
+  sig.tcpm_keylen = key.size();
+  memcpy(sig.tcpm_key, key.data(), key.size());
+  RETURN_ERROR_IF_SYSCALL_FAIL(
+      setsockopt(fd, IPPROTO_TCP, TCP_MD5SIG, &sig, sizeof(sig)));
+  return NoError();
+}
+
+TEST_P(SimpleTcpSocketTest, MD5SigKeyTooLong) {
+  FileDescriptor s =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(GetParam(), SOCK_STREAM, IPPROTO_TCP));
+
+  struct tcp_md5sig sig = {};
+  sockaddr_storage addr = InetLoopbackAddr(GetParam());
+  memcpy(&sig.tcpm_addr, &addr, sizeof(addr));
+  sig.tcpm_keylen = TCP_MD5SIG_MAXKEYLEN + 1;
+  EXPECT_THAT(setsockopt(s.get(), IPPROTO_TCP, TCP_MD5SIG, &sig, sizeof(sig)),
+              SyscallFailsWithErrno(EINVAL));
+}
+
+TEST_P(SimpleTcpSocketTest, MD5SigConnect) {
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(GetParam(), SOCK_STREAM, IPPROTO_TCP));
+  ASSERT_NO_ERRNO(SetMD5Key(listener.get(), GetParam(), "secret"));
+  sockaddr_storage addr =
+      ASSERT_NO_ERRNO_AND_VALUE(InetLoopbackAddrZeroPort(GetParam()));
+  socklen_t addrlen = sizeof(addr);
+  ASSERT_THAT(bind(listener.get(), AsSockAddr(&addr), addrlen),
+              SyscallSucceeds());
+  ASSERT_THAT(listen(listener.get(), SOMAXCONN), SyscallSucceeds());
+  ASSERT_THAT(getsockname(listener.get(), AsSockAddr(&addr), &addrlen),
+              SyscallSucceeds());
+
+  FileDescriptor client =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(GetParam(), SOCK_STREAM, IPPROTO_TCP));
+  ASSERT_NO_ERRNO(SetMD5Key(client.get(), GetParam(), "secret"));
+  ASSERT_THAT(RetryEINTR(connect)(client.get(), AsSockAddr(&addr), addrlen),
+              SyscallSucceeds());
+  FileDescriptor accepted =
+      ASSERT_NO_ERRNO_AND_VALUE(Accept(listener.get(), nullptr, nullptr));
+
+  char buf = 'a';
+  ASSERT_THAT(RetryEINTR(write)(client.get(), &buf, 1),
+              SyscallSucceedsWithValue(1));
+  ASSERT_THAT(RetryEINTR(read)(accepted.get(), &buf, 1),
+              SyscallSucceedsWithValue(1));
+  EXPECT_EQ(buf, 'a');
+}
+
+TEST_P(SimpleTcpSocketTest, MD5SigMissingKeyDropsSyn) {
+  FileDescriptor listener =
+      ASSERT_NO_ERRNO_AND_VALUE(Socket(GetParam(), SOCK_STREAM, IPPROTO_TCP));
+  ASSERT_NO_ERRNO(SetMD5Key(listener.get(), GetParam(), "secret"));
+  sockaddr_storage addr =
+      ASSERT_NO_ERRNO_AND_VALUE(InetLoopbackAddrZeroPort(GetParam()));
+  socklen_t addrlen = sizeof(addr);
+  ASSERT_THAT(bind(listener.get(), AsSockAddr(&addr), addrlen),
+              SyscallSucceeds());
+  ASSERT_THAT(listen(listener.get(), SOMAXCONN), SyscallSucceeds());
+  ASSERT_THAT(getsockname(listener.get(), AsSockAddr(&addr), &addrlen),
+              SyscallSucceeds());
+
+  // The client doesn't sign its SYN, so the listener must drop it.
+  FileDescriptor client = ASSERT_NO_ERRNO_AND_VALUE(
+      Socket(GetParam(), SOCK_STREAM | SOCK_NONBLOCK, IPPROTO_TCP));
+  ASSERT_THAT(RetryEINTR(connect)(client.get(), AsSockAddr(&addr), addrlen),
+              SyscallFailsWithErrno(EINPROGRESS));
+  struct pollfd poll_fd = {
+      .fd = client.get(),
+      .events = POLLOUT,
+  };
+  EXPECT_THAT(RetryEINTR(poll)(&poll_fd, 1, 100), SyscallSucceedsWithValue(0));
+}
+
 INSTANTIATE_TEST_SUITE_P(AllInetTests, SimpleTcpSocketTest,
                          ::testing::Values(AF_INET, AF_INET6));
 
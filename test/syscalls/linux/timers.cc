@@ -27,6 +27,7 @@
 #include "absl/time/clock.h"
 #include "absl/time/time.h"
 #include "benchmark/benchmark.h"
+#include "test/util/capability_util.h"
 #include "test/util/cleanup.h"
 #include "test/util/logging.h"
 #include "test/util/multiprocess_util.h"
@@ -373,6 +374,45 @@ TEST(IntervalTimerTest, PeriodicThreadDirectedSignal) {
   sigtimedwait(&mask, &si, &zero_ts);
 }
 
+TEST(IntervalTimerTest, ThreadCPUTimeClock) {
+  constexpr int kSigno = SIGUSR1;
+
+  // Block kSigno so that we can wait for it.
+  sigset_t mask;
+  sigemptyset(&mask);
+  sigaddset(&mask, kSigno);
+  const auto scoped_sigmask =
+      ASSERT_NO_ERRNO_AND_VALUE(ScopedSignalMask(SIG_BLOCK, mask));
+
+  struct sigevent sev = {};
+  sev.sigev_notify = SIGEV_THREAD_ID;
+  sev.sigev_signo = kSigno;
+  sev.sigev_notify_thread_id = gettid();
+  auto timer =
+      ASSERT_NO_ERRNO_AND_VALUE(TimerCreate(CLOCK_THREAD_CPUTIME_ID, sev));
+
+  constexpr absl::Duration kCPUTime = absl::Milliseconds(50);
+  struct itimerspec its = {};
+  its.it_value = absl::ToTimespec(kCPUTime);
+  ASSERT_NO_ERRNO(timer.Set(0, its));
+
+  // The timer doesn't advance while the thread sleeps.
+  absl::SleepFor(kCPUTime * 2);
+  siginfo_t si;
+  struct timespec zero_ts = absl::ToTimespec(absl::ZeroDuration());
+  EXPECT_THAT(sigtimedwait(&mask, &si, &zero_ts),
+              SyscallFailsWithErrno(EAGAIN));
+
+  // Spin until the timer expires.
+  int ret;
+  do {
+    ret = sigtimedwait(&mask, &si, &zero_ts);
+  } while (ret < 0 && errno == EAGAIN);
+  ASSERT_EQ(ret, kSigno);
+  EXPECT_EQ(si.si_code, SI_TIMER);
+  EXPECT_EQ(si.si_timerid, timer.get());
+}
+
 TEST(IntervalTimerTest, OtherThreadGroup) {
   constexpr int kSigno = SIGUSR1;
 
@@ -390,6 +430,19 @@ TEST(IntervalTimerTest, OtherThreadGroup) {
   EXPECT_THAT(TimerCreate(CLOCK_MONOTONIC, sev), PosixErrorIs(EINVAL, _));
 }
 
+TEST(IntervalTimerTest, AlarmClocksRequireCapWakeAlarm) {
+  AutoCapability cap(CAP_WAKE_ALARM, false);
+
+  struct sigevent sev = {};
+  sev.sigev_notify = SIGEV_NONE;
+  // Linux fails with EOPNOTSUPP before checking capabilities if the host has
+  // no RTC.
+  EXPECT_THAT(TimerCreate(CLOCK_REALTIME_ALARM, sev),
+              PosixErrorIs(AnyOf(EPERM, EOPNOTSUPP), _));
+  EXPECT_THAT(TimerCreate(CLOCK_BOOTTIME_ALARM, sev),
+              PosixErrorIs(AnyOf(EPERM, EOPNOTSUPP), _));
+}
+
 TEST(IntervalTimerTest, RealTimeSignalsAreNotDuplicated) {
   const int kSigno = SIGRTMIN;
   constexpr int kSigvalue = 42;
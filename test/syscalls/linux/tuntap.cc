@@ -18,6 +18,7 @@
 #include <linux/if_arp.h>
 #include <linux/if_ether.h>
 #include <linux/if_tun.h>
+#include <linux/virtio_net.h>
 #include <netinet/ip.h>
 #include <netinet/ip_icmp.h>
 #include <poll.h>
@@ -530,6 +531,102 @@ TEST_F(TuntapTest, TUNNoPacketInfo) {
   }
 }
 
+TEST_F(TuntapTest, GetFeatures) {
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(kDevNetTun, O_RDWR));
+
+  unsigned int features = 0;
+  ASSERT_THAT(ioctl(fd.get(), TUNGETFEATURES, &features), SyscallSucceeds());
+  constexpr unsigned int kWant = IFF_TUN | IFF_TAP | IFF_NO_PI | IFF_VNET_HDR;
+  EXPECT_EQ(features & kWant, kWant);
+}
+
+TEST_F(TuntapTest, TUNVnetHdr) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+
+  // Interface creation, as done by wireguard-go.
+  FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(kDevNetTun, O_RDWR));
+
+  struct ifreq ifr_set = {};
+  ifr_set.ifr_flags = IFF_TUN | IFF_NO_PI | IFF_VNET_HDR;
+  strncpy(ifr_set.ifr_name, kTunName, IFNAMSIZ);
+  ASSERT_THAT(ioctl(fd.get(), TUNSETIFF, &ifr_set), SyscallSucceeds());
+
+  struct ifreq ifr_get = {};
+  ASSERT_THAT(ioctl(fd.get(), TUNGETIFF, &ifr_get), SyscallSucceeds());
+  EXPECT_NE(ifr_get.ifr_flags & IFF_VNET_HDR, 0);
+
+  int hdr_size = 0;
+  ASSERT_THAT(ioctl(fd.get(), TUNGETVNETHDRSZ, &hdr_size), SyscallSucceeds());
+  EXPECT_EQ(hdr_size, sizeof(struct virtio_net_hdr));
+  int bad_size = 4;
+  EXPECT_THAT(ioctl(fd.get(), TUNSETVNETHDRSZ, &bad_size),
+              SyscallFailsWithErrno(EINVAL));
+  EXPECT_THAT(
+      ioctl(fd.get(), TUNSETOFFLOAD, TUN_F_CSUM | TUN_F_TSO4 | TUN_F_TSO6),
+      SyscallSucceeds());
+
+  // Interface setup.
+  auto link = ASSERT_NO_ERRNO_AND_VALUE(GetLinkByName(kTunName));
+  const struct in_addr dev_ipv4_addr = {.s_addr = kTapIPAddr};
+  FileDescriptor nlsk =
+      ASSERT_NO_ERRNO_AND_VALUE(NetlinkBoundSocket(NETLINK_ROUTE));
+  EXPECT_NO_ERRNO(LinkAddLocalAddr(nlsk, link.index, AF_INET, 24,
+                                   &dev_ipv4_addr, sizeof(dev_ipv4_addr)));
+
+  struct vnet_ping_pkt {
+    struct virtio_net_hdr vnet;
+    ping_ip_pkt ping;
+  } __attribute__((packed));
+
+  vnet_ping_pkt ping_req = {};
+  ping_req.ping = CreatePingIPPacket(kTapPeerIPAddr, kTapIPAddr);
+
+  // Send ICMP query.
+  EXPECT_THAT(write(fd.get(), &ping_req, sizeof(ping_req)),
+              SyscallSucceedsWithValue(sizeof(ping_req)));
+
+  // Receive loop to process inbound packets.
+  while (1) {
+    vnet_ping_pkt ping_resp = {};
+    EXPECT_THAT(read(fd.get(), &ping_resp, sizeof(ping_resp)),
+                SyscallSucceedsWithValue(sizeof(ping_resp)));
+    EXPECT_EQ(ping_resp.vnet.gso_type, VIRTIO_NET_HDR_GSO_NONE);
+
+    // Process ping response packet.
+    if (!memcmp(&ping_resp.ping.ip.saddr, &ping_req.ping.ip.daddr, kIPLen) &&
+        !memcmp(&ping_resp.ping.ip.daddr, &ping_req.ping.ip.saddr, kIPLen) &&
+        ping_resp.ping.icmp.type == 0 && ping_resp.ping.icmp.code == 0) {
+      // Ends and passes the test.
+      break;
+    }
+  }
+}
+
+TEST_F(TuntapTest, PersistentInterface) {
+  SKIP_IF(!ASSERT_NO_ERRNO_AND_VALUE(HaveCapability(CAP_NET_ADMIN)));
+
+  struct ifreq ifr_set = {};
+  ifr_set.ifr_flags = IFF_TUN | IFF_NO_PI;
+  strncpy(ifr_set.ifr_name, kTunName, IFNAMSIZ);
+
+  {
+    FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(kDevNetTun, O_RDWR));
+    ASSERT_THAT(ioctl(fd.get(), TUNSETIFF, &ifr_set), SyscallSucceeds());
+    ASSERT_THAT(ioctl(fd.get(), TUNSETPERSIST, 1), SyscallSucceeds());
+  }
+  // The interface outlives the file descriptor that created it.
+  EXPECT_THAT(DumpLinkNames(),
+              IsPosixErrorOkAndHolds(::testing::Contains(kTunName)));
+
+  {
+    FileDescriptor fd = ASSERT_NO_ERRNO_AND_VALUE(Open(kDevNetTun, O_RDWR));
+    ASSERT_THAT(ioctl(fd.get(), TUNSETIFF, &ifr_set), SyscallSucceeds());
+    ASSERT_THAT(ioctl(fd.get(), TUNSETPERSIST, 0), SyscallSucceeds());
+  }
+  EXPECT_THAT(DumpLinkNames(), IsPosixErrorOkAndHolds(::testing::Not(
+                                   ::testing::Contains(kTunName))));
+}
+
 // TCPBlockingConnectFailsArpResolution tests for TCP connect to fail on link
 // address resolution failure to a routable, but non existent peer.
 TEST_F(TuntapTest, TCPBlockingConnectFailsArpResolution) {
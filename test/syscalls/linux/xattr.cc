@@ -520,6 +520,41 @@ TEST_F(XattrTest, ListXattr) {
   EXPECT_EQ(got, expected);
 }
 
+TEST_F(XattrTest, ListXattrOmitsHiddenNamespaces) {
+  // On Linux, the host may list attributes such as security.selinux.
+  SKIP_IF(!IsRunningOnGvisor());
+  const char* path = test_file_name_.c_str();
+  const char name[] = "user.test";
+  ASSERT_THAT(setxattr(path, name, nullptr, 0, /*flags=*/0), SyscallSucceeds());
+
+  // Only names that the sandbox may access are listed, and the buffer size is
+  // checked against that list alone, even if the host file carries other
+  // attributes.
+  char list[sizeof(name)];
+  EXPECT_THAT(listxattr(path, nullptr, 0),
+              SyscallSucceedsWithValue(sizeof(name)));
+  EXPECT_THAT(listxattr(path, list, sizeof(list)),
+              SyscallSucceedsWithValue(sizeof(name)));
+  EXPECT_STREQ(list, name);
+  EXPECT_THAT(listxattr(path, list, sizeof(list) - 1),
+              SyscallFailsWithErrno(ERANGE));
+}
+
+TEST_F(XattrTest, SecurityAndTrustedNamespacesNotPassedThrough) {
+  SKIP_IF(!IsRunningOnGvisor());
+  // gVisor tmpfs implements the trusted namespace itself.
+  SKIP_IF(ASSERT_NO_ERRNO_AND_VALUE(IsTmpfs(test_file_name_)));
+  const char* path = test_file_name_.c_str();
+  for (const char* name : {"security.test", "trusted.test"}) {
+    char val = 'a';
+    EXPECT_THAT(setxattr(path, name, &val, sizeof(val), /*flags=*/0),
+                SyscallFailsWithErrno(EOPNOTSUPP));
+    EXPECT_THAT(getxattr(path, name, &val, sizeof(val)),
+                SyscallFailsWithErrno(EOPNOTSUPP));
+    EXPECT_THAT(removexattr(path, name), SyscallFailsWithErrno(EOPNOTSUPP));
+  }
+}
+
 TEST_F(XattrTest, ListXattrNoXattrs) {
   const char* path = test_file_name_.c_str();
 
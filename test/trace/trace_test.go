@@ -106,6 +106,7 @@ func matchPoints(t *testing.T, msgs []test.Message) map[pb.MessageType]*checkers
 		pb.MessageType_MESSAGE_SENTRY_EXEC:               {checker: checkSentryExec},
 		pb.MessageType_MESSAGE_SENTRY_EXIT_NOTIFY_PARENT: {checker: checkSentryExitNotifyParent},
 		pb.MessageType_MESSAGE_SENTRY_TASK_EXIT:          {checker: checkSentryTaskExit},
+		pb.MessageType_MESSAGE_SENTRY_CAPABILITY_DENIED:  {checker: checkSentryCapabilityDenied},
 		pb.MessageType_MESSAGE_SYSCALL_CLOSE:             {checker: checkSyscallClose},
 		pb.MessageType_MESSAGE_SYSCALL_CONNECT:           {checker: checkSyscallConnect},
 		pb.MessageType_MESSAGE_SYSCALL_EXECVE:            {checker: checkSyscallExecve},
@@ -133,6 +134,8 @@ func matchPoints(t *testing.T, msgs []test.Message) map[pb.MessageType]*checkers
 		pb.MessageType_MESSAGE_SYSCALL_INOTIFY_ADD_WATCH: {checker: checkSyscallInotifyInitAddWatch},
 		pb.MessageType_MESSAGE_SYSCALL_INOTIFY_RM_WATCH:  {checker: checkSyscallInotifyInitRmWatch},
 		pb.MessageType_MESSAGE_SYSCALL_CLONE:             {checker: checkSyscallClone},
+		pb.MessageType_MESSAGE_SYSCALL_MOUNT:             {checker: checkSyscallMount},
+		pb.MessageType_MESSAGE_SYSCALL_UMOUNT:            {checker: checkSyscallUmount},
 	}
 	return matchers
 }
@@ -260,6 +263,22 @@ func checkSentryTaskExit(msg test.Message) error {
 	return nil
 }
 
+func checkSentryCapabilityDenied(msg test.Message) error {
+	p := pb.CapabilityDeniedInfo{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	// Capabilities are dropped after chroot(2), which leaves cwd outside of the
+	// root directory.
+	if err := checkContextDataOpts(p.ContextData, contextDataOpts{skipCwd: true}); err != nil {
+		return err
+	}
+	if want := uint32(unix.CAP_SYS_ADMIN); p.Capability != want {
+		return fmt.Errorf("wrong capability, want: %d, got: %d", want, p.Capability)
+	}
+	return nil
+}
+
 func checkSyscallRaw(msg test.Message) error {
 	p := pb.Syscall{}
 	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
@@ -668,6 +687,37 @@ func checkSyscallChroot(msg test.Message) error {
 	return nil
 }
 
+func checkSyscallMount(msg test.Message) error {
+	p := pb.Mount{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if want := "trace_test.mnt"; !strings.Contains(p.Target, want) {
+		return fmt.Errorf("wrong target, want: %q, got: %q", want, p.Target)
+	}
+	if want := "tmpfs"; p.Fstype != want {
+		return fmt.Errorf("wrong fstype, want: %q, got: %q", want, p.Fstype)
+	}
+	return nil
+}
+
+func checkSyscallUmount(msg test.Message) error {
+	p := pb.Umount{}
+	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
+		return err
+	}
+	if err := checkContextData(p.ContextData); err != nil {
+		return err
+	}
+	if want := "trace_test.mnt"; !strings.Contains(p.Target, want) {
+		return fmt.Errorf("wrong target, want: %q, got: %q", want, p.Target)
+	}
+	return nil
+}
+
 func checkSyscallFcntl(msg test.Message) error {
 	p := pb.Fcntl{}
 	if err := proto.Unmarshal(msg.Msg, &p); err != nil {
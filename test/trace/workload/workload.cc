@@ -15,15 +15,18 @@
 #include <bits/types/struct_itimerspec.h>
 #include <err.h>
 #include <fcntl.h>
+#include <linux/capability.h>
 #include <sched.h>
 #include <stdlib.h>
 #include <sys/eventfd.h>
 #include <sys/inotify.h>
 #include <sys/mman.h>
+#include <sys/mount.h>
 #include <sys/resource.h>
 #include <sys/signalfd.h>
 #include <sys/socket.h>
 #include <sys/stat.h>
+#include <sys/syscall.h>
 #include <sys/timerfd.h>
 #include <sys/types.h>
 #include <sys/un.h>
@@ -284,6 +287,37 @@ void runChroot() {
     err(1, "chroot");
   }
 }
+void runMount() {
+  const auto pathname = "trace_test.mnt";
+  static constexpr mode_t kDefaultDirMode = 0755;
+  int path_or_error = mkdir(pathname, kDefaultDirMode);
+  if (path_or_error != 0) {
+    err(1, "mkdir");
+  }
+  if (mount("tmpfs", pathname, "tmpfs", 0, nullptr)) {
+    err(1, "mount");
+  }
+  if (umount2(pathname, 0)) {
+    err(1, "umount2");
+  }
+  rmdir(pathname);
+}
+
+void runCapabilityDenied() {
+  // Drop all capabilities, so that sethostname(2) fails for lack of
+  // CAP_SYS_ADMIN.
+  struct __user_cap_header_struct header = {_LINUX_CAPABILITY_VERSION_3, 0};
+  struct __user_cap_data_struct data[_LINUX_CAPABILITY_U32S_3] = {};
+  if (syscall(SYS_capset, &header, data)) {
+    err(1, "capset");
+  }
+  constexpr char kHostname[] = "trace_test";
+  // Operation is not permitted so we get an error.
+  if (sethostname(kHostname, sizeof(kHostname) - 1) != -1) {
+    err(1, "sethostname");
+  }
+}
+
 void runDup() {
   const auto pathname = "trace_test.abc";
   static constexpr mode_t kDefaultDirMode = 0755;
@@ -697,6 +731,7 @@ int main(int argc, char** argv) {
   ::gvisor::testing::runInotifyInit1();
   ::gvisor::testing::runInotifyAddWatch();
   ::gvisor::testing::runInotifyRmWatch();
+  ::gvisor::testing::runMount();
 // signalfd(2), fork(2), and vfork(2) system calls are not supported in arm
 // architecture.
 #ifdef __x86_64__
@@ -706,5 +741,7 @@ int main(int argc, char** argv) {
 #endif
   // Run chroot at the end since it changes the root for all other tests.
   ::gvisor::testing::runChroot();
+  // Drop capabilities last since other tests need them.
+  ::gvisor::testing::runCapabilityDenied();
   return 0;
 }
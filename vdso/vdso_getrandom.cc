@@ -0,0 +1,296 @@
+// Copyright 2024 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements vgetrandom(), a userspace getrandom() that generates
+// random bytes with ChaCha20 from a key obtained from the kernel, as in Linux's
+// lib/vdso/getrandom.c. The caller (e.g., glibc) allocates a state for each
+// thread using the parameters returned by vgetrandom() itself.
+
+#include "vdso/vdso_getrandom.h"
+
+#include <stddef.h>
+#include <stdint.h>
+#include <sys/types.h>
+
+#include "vdso/barrier.h"
+#include "vdso/params.h"
+#include "vdso/seqlock.h"
+#include "vdso/syscalls.h"
+
+namespace vdso {
+namespace {
+
+// From include/uapi/linux/random.h.
+constexpr unsigned int kGrndNonblock = 0x1;
+constexpr unsigned int kGrndInsecure = 0x4;
+
+// From include/uapi/asm-generic/mman-common.h.
+constexpr uint32_t kProtRead = 0x1;
+constexpr uint32_t kProtWrite = 0x2;
+constexpr uint32_t kMapDroppable = 0x08;
+constexpr uint32_t kMapAnonymous = 0x20;
+
+constexpr size_t kPageSize = 4096;
+constexpr size_t kChaChaBlockSize = 64;
+constexpr size_t kChaChaKeySize = 32;
+constexpr size_t kBatchSize = kChaChaBlockSize * 3 / 2;
+
+// The largest number of bytes returned by a single call, from Linux's
+// include/linux/fs.h:MAX_RW_COUNT.
+constexpr size_t kMaxLen = 0x7ffff000;
+
+// vgetrandom_opaque_params is returned by vgetrandom() to describe how states
+// must be allocated. From Linux's include/uapi/linux/random.h.
+struct vgetrandom_opaque_params {
+  uint32_t size_of_opaque_state;
+  uint32_t mmap_prot;
+  uint32_t mmap_flags;
+  uint32_t reserved[13];
+};
+
+// vgetrandom_state is the per-thread state of vgetrandom(). Callers treat it
+// as opaque. Its layout matches Linux's include/vdso/getrandom.h.
+//
+// States are allocated with MAP_DROPPABLE, which wipes them in child
+// processes, so that a child never reuses its parent's random bytes.
+struct vgetrandom_state {
+  // batch_key holds kBatchSize bytes of random output, followed by the key
+  // used to generate the next batch.
+  uint8_t batch_key[kBatchSize + kChaChaKeySize];
+
+  // generation is the value of params.rng_generation when the key was
+  // obtained from the kernel. params.rng_generation is never 0, so new
+  // (zeroed) states are always reseeded before use.
+  uint64_t generation;
+
+  // pos is the offset in batch_key of the first unused random byte.
+  uint8_t pos;
+
+  // in_use is set while vgetrandom() is using the state, so that a signal
+  // handler that calls vgetrandom() on the same state falls back to the
+  // system call.
+  bool in_use;
+};
+
+// The compiler barriers in the loops below prevent the compiler from replacing
+// them with calls to memcpy() or memset(), which are not available in the
+// VDSO.
+
+// copy_and_zero_src copies n bytes from src to dst and zeroes them in src, so
+// that random bytes are never returned twice.
+inline void copy_and_zero_src(uint8_t* dst, uint8_t* src, size_t n) {
+  for (size_t i = 0; i < n; i++) {
+    dst[i] = src[i];
+    src[i] = 0;
+    barrier();
+  }
+}
+
+inline uint32_t load_le32(const uint8_t* p) {
+  return static_cast<uint32_t>(p[0]) | static_cast<uint32_t>(p[1]) << 8 |
+         static_cast<uint32_t>(p[2]) << 16 | static_cast<uint32_t>(p[3]) << 24;
+}
+
+inline void store_le32(uint8_t* p, uint32_t v) {
+  p[0] = v;
+  p[1] = v >> 8;
+  p[2] = v >> 16;
+  p[3] = v >> 24;
+}
+
+inline uint32_t rotl32(uint32_t v, int c) { return (v << c) | (v >> (32 - c)); }
+
+#define CHACHA_QUARTERROUND(a, b, c, d) \
+  do {                                  \
+    a += b;                             \
+    d = rotl32(d ^ a, 16);              \
+    c += d;                             \
+    b = rotl32(b ^ c, 12);              \
+    a += b;                             \
+    d = rotl32(d ^ a, 8);               \
+    c += d;                             \
+    b = rotl32(b ^ c, 7);               \
+  } while (0)
+
+// chacha20_blocks writes nblocks blocks of ChaCha20 output, generated with
+// key, a zero nonce and a 64-bit block counter starting at *counter, to dst.
+// *counter is advanced by nblocks. dst may overlap key.
+void chacha20_blocks(uint8_t* dst, const uint8_t* key, uint64_t* counter,
+                     size_t nblocks) {
+  uint32_t input[16];
+  // "expand 32-byte k"
+  input[0] = 0x61707865;
+  input[1] = 0x3320646e;
+  input[2] = 0x79622d32;
+  input[3] = 0x6b206574;
+  for (int i = 0; i < 8; i++) {
+    input[4 + i] = load_le32(key + 4 * i);
+  }
+  input[14] = 0;
+  input[15] = 0;
+
+  uint32_t x[16];
+  for (; nblocks > 0; nblocks--) {
+    input[12] = static_cast<uint32_t>(*counter);
+    input[13] = static_cast<uint32_t>(*counter >> 32);
+    for (int i = 0; i < 16; i++) {
+      x[i] = input[i];
+    }
+    for (int i = 0; i < 10; i++) {
+      CHACHA_QUARTERROUND(x[0], x[4], x[8], x[12]);
+      CHACHA_QUARTERROUND(x[1], x[5], x[9], x[13]);
+      CHACHA_QUARTERROUND(x[2], x[6], x[10], x[14]);
+      CHACHA_QUARTERROUND(x[3], x[7], x[11], x[15]);
+      CHACHA_QUARTERROUND(x[0], x[5], x[10], x[15]);
+      CHACHA_QUARTERROUND(x[1], x[6], x[11], x[12]);
+      CHACHA_QUARTERROUND(x[2], x[7], x[8], x[13]);
+      CHACHA_QUARTERROUND(x[3], x[4], x[9], x[14]);
+    }
+    for (int i = 0; i < 16; i++) {
+      store_le32(dst + 4 * i, x[i] + input[i]);
+    }
+    dst += kChaChaBlockSize;
+    (*counter)++;
+  }
+
+  // Don't leave the key or output on the stack.
+  for (int i = 0; i < 16; i++) {
+    input[i] = 0;
+    x[i] = 0;
+    barrier();
+  }
+}
+
+#undef CHACHA_QUARTERROUND
+
+inline uint64_t read_generation(struct params* params) {
+  uint64_t seq;
+  uint64_t generation;
+  do {
+    seq = read_seqcount_begin(&params->seq_count);
+    generation = params->rng_generation;
+  } while (read_seqcount_retry(&params->seq_count, seq));
+  return generation;
+}
+
+}  // namespace
+
+ssize_t GetRandom(void* buffer, size_t len, unsigned int flags,
+                  void* opaque_state, size_t opaque_len) {
+  // Report the parameters for allocating states.
+  if (opaque_len == ~0UL && buffer == nullptr && len == 0 && flags == 0) {
+    auto* params = static_cast<struct vgetrandom_opaque_params*>(opaque_state);
+    params->size_of_opaque_state = sizeof(struct vgetrandom_state);
+    params->mmap_prot = kProtRead | kProtWrite;
+    params->mmap_flags = kMapDroppable | kMapAnonymous;
+    for (int i = 0; i < 13; i++) {
+      params->reserved[i] = 0;
+      barrier();
+    }
+    return 0;
+  }
+
+  auto* state = static_cast<struct vgetrandom_state*>(opaque_state);
+  uintptr_t state_addr = reinterpret_cast<uintptr_t>(opaque_state);
+  // States must not cross a page boundary, since MAP_DROPPABLE may drop
+  // individual pages.
+  if (opaque_len != sizeof(*state) ||
+      (state_addr & (kPageSize - 1)) + sizeof(*state) > kPageSize ||
+      (flags & ~(kGrndNonblock | kGrndInsecure)) != 0) {
+    return sys_getrandom(buffer, len, flags);
+  }
+  if (len == 0) {
+    return 0;
+  }
+  if (state->in_use) {
+    return sys_getrandom(buffer, len, flags);
+  }
+  state->in_use = true;
+  barrier();
+
+  if (len > kMaxLen) {
+    len = kMaxLen;
+  }
+  struct params* params = get_params();
+  uint8_t* const orig_buffer = static_cast<uint8_t*>(buffer);
+  const size_t orig_len = len;
+
+  for (;;) {
+    uint64_t generation = read_generation(params);
+    if (generation == 0) {
+      // The sentry has not yet written the parameter page. Since new states
+      // also have generation 0, their (zero) keys must not be used.
+      barrier();
+      state->in_use = false;
+      return sys_getrandom(buffer, len, flags);
+    }
+    if (state->generation != generation) {
+      // The key is from a different generation (or is unset). Get a new key
+      // from the kernel and discard the batch generated with the old key.
+      ssize_t n = sys_getrandom(state->batch_key + kBatchSize, kChaChaKeySize, 0);
+      if (n != static_cast<ssize_t>(kChaChaKeySize)) {
+        barrier();
+        state->in_use = false;
+        return sys_getrandom(buffer, len, flags);
+      }
+      state->generation = generation;
+      state->pos = kBatchSize;
+    }
+
+    uint8_t* out = orig_buffer;
+    len = orig_len;
+    uint64_t counter = 0;
+    for (;;) {
+      // Use random bytes remaining in the batch first.
+      size_t batch_len = kBatchSize - state->pos;
+      if (batch_len > len) {
+        batch_len = len;
+      }
+      copy_and_zero_src(out, state->batch_key + state->pos, batch_len);
+      state->pos += batch_len;
+      out += batch_len;
+      len -= batch_len;
+      if (len == 0) {
+        break;
+      }
+
+      // Generate whole blocks directly into the buffer.
+      size_t nblocks = len / kChaChaBlockSize;
+      if (nblocks > 0) {
+        chacha20_blocks(out, state->batch_key + kBatchSize, &counter, nblocks);
+        out += nblocks * kChaChaBlockSize;
+        len -= nblocks * kChaChaBlockSize;
+      }
+
+      // Refill the batch and overwrite the key, so that the bytes already
+      // returned can't be recovered from the state.
+      chacha20_blocks(state->batch_key, state->batch_key + kBatchSize, &counter,
+                      sizeof(state->batch_key) / kChaChaBlockSize);
+      state->pos = 0;
+    }
+
+    barrier();
+    // If the generation changed while we were generating random bytes, the
+    // bytes may have been generated from a stale key. Do it again.
+    if (state->generation == read_generation(params)) {
+      break;
+    }
+  }
+
+  barrier();
+  state->in_use = false;
+  return orig_len;
+}
+
+}  // namespace vdso